@@ -2,9 +2,6 @@
 # Generate test coverage statistics for Go packages.
 #
 
-echo "Try update 'cover'"
-GOPATH=`pwd`/Tools go get -u golang.org/x/tools/cmd/cover
-
 set -e
 
 work_dir=.cover
@@ -23,8 +23,10 @@ cp ${BGO_SPACE}/bin/linux_386/ssm-document-worker ${BGO_SPACE}/bin/debian_386/de
 cp ${BGO_SPACE}/bin/linux_386/ssm-session-worker ${BGO_SPACE}/bin/debian_386/debian/usr/bin/
 cp ${BGO_SPACE}/bin/linux_386/ssm-session-logger ${BGO_SPACE}/bin/debian_386/debian/usr/bin/
 cd ${BGO_SPACE}/bin/debian_386/debian/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker; strip --strip-unneeded ssm-session-worker; strip --strip-unneeded ssm-session-logger; cd ~-
+${BGO_SPACE}/Tools/src/create_integrity_manifest.sh ${BGO_SPACE}/bin/debian_386/debian/usr/bin ${BGO_SPACE}/bin/debian_386/debian/etc/amazon/ssm || exit 1
 cp ${BGO_SPACE}/seelog_unix.xml ${BGO_SPACE}/bin/debian_386/debian/etc/amazon/ssm/seelog.xml.template
 cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${BGO_SPACE}/bin/debian_386/debian/etc/amazon/ssm/
+cp ${BGO_SPACE}/amazon-ssm-agent.schema.json ${BGO_SPACE}/bin/debian_386/debian/etc/amazon/ssm/
 cp ${BGO_SPACE}/packaging/ubuntu/amazon-ssm-agent.conf ${BGO_SPACE}/bin/debian_386/debian/etc/init/
 cp ${BGO_SPACE}/packaging/ubuntu/amazon-ssm-agent.service ${BGO_SPACE}/bin/debian_386/debian/lib/systemd/system/
 
@@ -0,0 +1,15 @@
+#!/usr/bin/env bash
+echo "****************************************"
+echo "Creating the binary manifest"
+echo "****************************************"
+
+# the manifest lists the binaries of BIN_FOLDER once stripped and goes to the config folder of the package, it is
+# signed with the PKCS#8 PEM private key INTEGRITY_SIGNING_KEY when set, the release key verifying it goes along
+BIN_FOLDER=$1
+CONFIG_FOLDER=$2
+
+go run ${BGO_SPACE}/agent/integrity/manifestgen/manifest-gen.go `cat ${BGO_SPACE}/VERSION` ${BIN_FOLDER} ${INTEGRITY_SIGNING_KEY} || exit 1
+mv ${BIN_FOLDER}/amazon-ssm-agent.manifest ${CONFIG_FOLDER}/
+if [[ -n "${INTEGRITY_SIGNING_KEY}" ]]; then
+    mv ${BIN_FOLDER}/amazon-ssm-agent.manifest.sig ${BIN_FOLDER}/amazon-ssm-agent-release.pem ${CONFIG_FOLDER}/
+fi
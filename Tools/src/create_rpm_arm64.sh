@@ -18,28 +18,35 @@ mkdir -p ${BGO_SPACE}/bin/linux_arm64/linux/etc/init/
 mkdir -p ${BGO_SPACE}/bin/linux_arm64/linux/etc/systemd/system/
 mkdir -p ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm/
 mkdir -p ${BGO_SPACE}/bin/linux_arm64/linux/var/lib/amazon/ssm/
+mkdir -p ${BGO_SPACE}/bin/linux_arm64/linux/usr/share/selinux/packages/
 
 echo "Copying application files"
 
 cp ${BGO_SPACE}/bin/linux_arm64/amazon-ssm-agent ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/
 cp ${BGO_SPACE}/bin/linux_arm64/ssm-document-worker ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/
 cp ${BGO_SPACE}/bin/linux_arm64/ssm-session-worker ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/
+cp ${BGO_SPACE}/bin/linux_arm64/ssm-agent-helper ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/
 cp ${BGO_SPACE}/bin/linux_arm64/ssm-session-logger ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/
 cp ${BGO_SPACE}/bin/linux_arm64/ssm-cli ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/
 cp ${BGO_SPACE}/seelog_unix.xml ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm/seelog.xml.template
 cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm/
+cp ${BGO_SPACE}/amazon-ssm-agent.schema.json ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm/
 cp ${BGO_SPACE}/RELEASENOTES.md ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm/
 cp ${BGO_SPACE}/README.md ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm/
 cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent.conf ${BGO_SPACE}/bin/linux_arm64/linux/etc/init/
 cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent.service ${BGO_SPACE}/bin/linux_arm64/linux/etc/systemd/system/
-cd ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker; strip --strip-unneeded ssm-session-worker; strip --strip-unneeded ssm-session-logger; cd ~-
+cp ${BGO_SPACE}/packaging/linux/amazon-ssm-agent.socket ${BGO_SPACE}/bin/linux_arm64/linux/etc/systemd/system/
+cp ${BGO_SPACE}/bin/selinux/amazon_ssm_agent.pp ${BGO_SPACE}/bin/linux_arm64/linux/usr/share/selinux/packages/
+cd ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin/; strip --strip-unneeded amazon-ssm-agent; strip --strip-unneeded ssm-cli; strip --strip-unneeded ssm-document-worker; strip --strip-unneeded ssm-session-worker; strip --strip-unneeded ssm-session-logger; strip --strip-unneeded ssm-agent-helper; cd ~-
+${BGO_SPACE}/Tools/src/create_integrity_manifest.sh ${BGO_SPACE}/bin/linux_arm64/linux/usr/bin ${BGO_SPACE}/bin/linux_arm64/linux/etc/amazon/ssm || exit 1
 
 echo "Creating the rpm package"
 
 SPEC_FILE="${BGO_SPACE}/packaging/linux/amazon-ssm-agent.spec"
 BUILD_ROOT="${BGO_SPACE}/bin/linux_arm64/linux"
 
-rpmbuild -bb --target aarch64 --define "rpmversion `cat ${BGO_SPACE}/VERSION`" --define "_topdir bin/linux_arm64/linux/rpmbuild" --buildroot ${BUILD_ROOT} ${SPEC_FILE}
+SIGNED=0; [[ -n "${INTEGRITY_SIGNING_KEY}" ]] && SIGNED=1
+rpmbuild -bb --target aarch64 --define "signed ${SIGNED}" --define "rpmversion `cat ${BGO_SPACE}/VERSION`" --define "_topdir bin/linux_arm64/linux/rpmbuild" --buildroot ${BUILD_ROOT} ${SPEC_FILE}
 
 echo "Copying rpm files to bin"
 
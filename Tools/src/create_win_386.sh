@@ -22,7 +22,9 @@ cp ${BUILD_FOLDER}/ssm-session-worker.exe ${PACKAGE_FOLDER}/ssm-session-worker.e
 cp ${BUILD_FOLDER}/ssm-session-logger.exe ${PACKAGE_FOLDER}/ssm-session-logger.exe
 cp ${BUILD_FOLDER}/ssm-cli.exe ${PACKAGE_FOLDER}/ssm-cli.exe
 cp ${BGO_SPACE}/seelog_windows.xml.template ${PACKAGE_FOLDER}/seelog.xml.template
+cp ${BGO_SPACE}/amazon-ssm-agent-etw.man ${PACKAGE_FOLDER}/amazon-ssm-agent-etw.man
 cp ${BGO_SPACE}/amazon-ssm-agent.json.template ${PACKAGE_FOLDER}/amazon-ssm-agent.json.template
+cp ${BGO_SPACE}/amazon-ssm-agent.schema.json ${PACKAGE_FOLDER}/amazon-ssm-agent.schema.json
 
 echo "Copying windows package config files"
 
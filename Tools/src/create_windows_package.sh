@@ -2,13 +2,17 @@
 
 BUILD_PATH_AMD64=${BGO_SPACE}/bin/windows_amd64
 BUILD_PATH_386=${BGO_SPACE}/bin/windows_386
+BUILD_PATH_ARM64=${BGO_SPACE}/bin/windows_arm64
 PACKAGE_PATH_AMD64=${BUILD_PATH_AMD64}/windows
 PACKAGE_PATH_386=${BUILD_PATH_386}/windows
+PACKAGE_PATH_ARM64=${BUILD_PATH_ARM64}/windows
 
 cp ${BGO_SPACE}/Tools/src/update/windows/install.bat ${PACKAGE_PATH_AMD64}/
 cp ${BGO_SPACE}/Tools/src/update/windows/uninstall.bat ${PACKAGE_PATH_AMD64}/
 cp ${BGO_SPACE}/Tools/src/update/windows/install.bat ${PACKAGE_PATH_386}/
 cp ${BGO_SPACE}/Tools/src/update/windows/uninstall.bat ${PACKAGE_PATH_386}/
+cp ${BGO_SPACE}/Tools/src/update/windows/install.bat ${PACKAGE_PATH_ARM64}/
+cp ${BGO_SPACE}/Tools/src/update/windows/uninstall.bat ${PACKAGE_PATH_ARM64}/
 
 WINDOWS_AMD64_ZIP=${BGO_SPACE}/bin/updates/amazon-ssm-agent/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-windows-amd64.zip
 zip -j ${WINDOWS_AMD64_ZIP} ${PACKAGE_PATH_AMD64}/package.zip
@@ -20,8 +24,16 @@ zip -j ${WINDOWS_386_ZIP} ${PACKAGE_PATH_386}/package.zip
 zip -j ${WINDOWS_386_ZIP} ${PACKAGE_PATH_386}/install.bat
 zip -j ${WINDOWS_386_ZIP} ${PACKAGE_PATH_386}/uninstall.bat
 
+WINDOWS_ARM64_ZIP=${BGO_SPACE}/bin/updates/amazon-ssm-agent/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-windows-arm64.zip
+zip -j ${WINDOWS_ARM64_ZIP} ${PACKAGE_PATH_ARM64}/package.zip
+zip -j ${WINDOWS_ARM64_ZIP} ${PACKAGE_PATH_ARM64}/install.bat
+zip -j ${WINDOWS_ARM64_ZIP} ${PACKAGE_PATH_ARM64}/uninstall.bat
+
 WINDOWS_AMD64_UPDATER_ZIP=${BGO_SPACE}/bin/updates/amazon-ssm-agent-updater/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-updater-windows-amd64.zip
 zip -j ${WINDOWS_AMD64_UPDATER_ZIP} ${BUILD_PATH_AMD64}/updater.exe
 
 WINDOWS_386_UPDATER_ZIP=${BGO_SPACE}/bin/updates/amazon-ssm-agent-updater/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-updater-windows-386.zip
 zip -j ${WINDOWS_386_UPDATER_ZIP} ${BUILD_PATH_386}/updater.exe
+
+WINDOWS_ARM64_UPDATER_ZIP=${BGO_SPACE}/bin/updates/amazon-ssm-agent-updater/`cat ${BGO_SPACE}/VERSION`/amazon-ssm-agent-updater-windows-arm64.zip
+zip -j ${WINDOWS_ARM64_UPDATER_ZIP} ${BUILD_PATH_ARM64}/updater.exe
@@ -40,7 +40,7 @@ sc description %ServiceName% "Amazon SSM Agent"
 if not %errorlevel% == 0 echo [WARN] Failed to add description for %ServiceName% service.
 
 echo [INFO] Configure %ServiceName% recovery settings.
-sc failure %ServiceName% reset= 86400 actions= restart/1000/restart/1000//1000
+"%InstallingFolder%\amazon-ssm-agent.exe" -configure-recovery
 if not %errorlevel% == 0 echo [WARN] Failed to configure recovery settings for %ServiceName% service.
 
 if not defined DoRegister goto START_SVC
@@ -6,7 +6,10 @@ param(
     [string] $Region,
 
     # This switch is to disable SSMAgent after installation
-    [switch] $Disabled
+    [switch] $Disabled,
+
+    # This parameter runs SSMAgent as a group managed service account, DOMAIN\name$
+    [string] $ServiceAccount
 )
 
 function Log-Info {
@@ -103,13 +106,6 @@ if($unpacked -and (Test-Path $ExtractedPackage)) {
 # Copy UnInstaller to the destination
 Copy-Item $UnInstaller $Destination -Force
 
-# Check if register is set in argument
-if($Register) {
-    # Start RegisterManagedInstance process
-    Log-Info("RegisterManagedInstance begins")
-    Invoke-Expression "& '$Executable' -register -code $Code -id $Id -region $Region"
-}
-
 # Register Amazon SSM Agent service to Windows service entry
 Log-Info("Creating $ServiceName Service")
 if(Get-Command sc.exe -ErrorAction SilentlyContinue) {
@@ -122,8 +118,16 @@ if(Get-Command sc.exe -ErrorAction SilentlyContinue) {
             Log-Info("Amazon SSM Agent service is diabled")
         }
         sc.exe description $ServiceName $ServiceDesc
-        sc.exe failureflag $ServiceName 1
-        sc.exe failure $ServiceName reset= 86400 actions= restart/30000/restart/30000/restart/30000
+        if($ServiceAccount) {
+            # the gMSA needs to write the agent data, Windows retrieves its password from the domain
+            $DataFolder = Join-Path $env:ProgramData -ChildPath "Amazon" | Join-Path -ChildPath "SSM"
+            New-Item -ItemType Directory -Force -Path $DataFolder | Out-Null
+            icacls.exe $DataFolder /grant "${ServiceAccount}:(OI)(CI)M"
+            sc.exe config $ServiceName obj= $ServiceAccount
+            Log-Info("Amazon SSM Agent service runs as $ServiceAccount")
+        }
+        # the failure actions come from the Recovery config of the agent
+        & $Executable -configure-recovery
     } catch {
         $ex = $Error[0].Exception
         Log-Warning("{0}.. exit!" -f $ex)
@@ -133,6 +137,14 @@ if(Get-Command sc.exe -ErrorAction SilentlyContinue) {
     Exit 1
 }
 
+# Check if register is set in argument, after the service is created since the DpapiNg key protection
+# seals the registration for the account of the service
+if($Register) {
+    # Start RegisterManagedInstance process
+    Log-Info("RegisterManagedInstance begins")
+    Invoke-Expression "& '$Executable' -register -code $Code -id $Id -region $Region"
+}
+
 if(-not $Disabled) {
     # Start service
     Log-Info("Starting Amazon SSM Agent service")
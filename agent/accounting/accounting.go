@@ -0,0 +1,99 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package accounting measures the resources used by the plugin executions: CPU time, peak memory and disk I/O
+// of the worker and of the commands it runs, and the bytes uploaded to S3.
+package accounting
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// sample is a snapshot of the resource counters of the process
+type sample struct {
+	cpu        time.Duration
+	readBytes  int64
+	writeBytes int64
+}
+
+// Tracker measures the resources used from its start, the plugins running in parallel
+// are each attributed the resources used while they run
+type Tracker struct {
+	start         sample
+	commandCPU    time.Duration
+	peakMemoryKB  int64
+	uploadedBytes int64
+}
+
+// active holds the running trackers
+var active = struct {
+	sync.Mutex
+	trackers map[*Tracker]bool
+}{trackers: make(map[*Tracker]bool)}
+
+// takeSample returns the resource counters of the process, stubbed in tests
+var takeSample = processSample
+
+// Start starts measuring the resources used by a plugin execution
+func Start() *Tracker {
+	tracker := &Tracker{start: takeSample()}
+	active.Lock()
+	defer active.Unlock()
+	active.trackers[tracker] = true
+	return tracker
+}
+
+// Stop stops measuring and returns the resources used since the start of the tracker
+func (t *Tracker) Stop() contracts.ResourceUsage {
+	end := takeSample()
+	active.Lock()
+	defer active.Unlock()
+	delete(active.trackers, t)
+	return contracts.ResourceUsage{
+		CPUTimeMillis:  int64((end.cpu - t.start.cpu + t.commandCPU) / time.Millisecond),
+		PeakMemoryKB:   t.peakMemoryKB,
+		DiskReadBytes:  end.readBytes - t.start.readBytes,
+		DiskWriteBytes: end.writeBytes - t.start.writeBytes,
+		UploadedBytes:  t.uploadedBytes,
+	}
+}
+
+// RecordCommand attributes the resources used by an exited command to the running trackers
+func RecordCommand(state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+	cpu, peakMemoryKB := commandUsage(state)
+	active.Lock()
+	defer active.Unlock()
+	for tracker := range active.trackers {
+		tracker.commandCPU += cpu
+		if peakMemoryKB > tracker.peakMemoryKB {
+			tracker.peakMemoryKB = peakMemoryKB
+		}
+	}
+}
+
+// RecordUpload attributes the bytes uploaded to S3 to the running trackers
+func RecordUpload(bytes int64) {
+	active.Lock()
+	defer active.Unlock()
+	for tracker := range active.trackers {
+		tracker.uploadedBytes += bytes
+	}
+}
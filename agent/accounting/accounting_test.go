@@ -0,0 +1,77 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubSamples(t *testing.T, samples ...sample) {
+	original := takeSample
+	t.Cleanup(func() { takeSample = original })
+	takeSample = func() sample {
+		next := samples[0]
+		samples = samples[1:]
+		return next
+	}
+}
+
+func TestTrackerStop(t *testing.T) {
+	stubSamples(t,
+		sample{cpu: 2 * time.Second, readBytes: 100, writeBytes: 200},
+		sample{cpu: 3500 * time.Millisecond, readBytes: 1124, writeBytes: 4296})
+
+	tracker := Start()
+	RecordUpload(512)
+	usage := tracker.Stop()
+
+	assert.Equal(t, contracts.ResourceUsage{
+		CPUTimeMillis:  1500,
+		DiskReadBytes:  1024,
+		DiskWriteBytes: 4096,
+		UploadedBytes:  512,
+	}, usage)
+	assert.NotContains(t, active.trackers, tracker)
+}
+
+func TestRecordUploadOnlyActiveTrackers(t *testing.T) {
+	stubSamples(t, sample{}, sample{}, sample{}, sample{})
+
+	first := Start()
+	RecordUpload(10)
+	second := Start()
+	RecordUpload(20)
+	firstUsage := first.Stop()
+	RecordUpload(40)
+	secondUsage := second.Stop()
+	RecordUpload(80)
+
+	assert.Equal(t, int64(30), firstUsage.UploadedBytes)
+	assert.Equal(t, int64(60), secondUsage.UploadedBytes)
+}
+
+func TestRecordCommandNilState(t *testing.T) {
+	stubSamples(t, sample{}, sample{})
+
+	tracker := Start()
+	RecordCommand(nil)
+	usage := tracker.Stop()
+
+	assert.Equal(t, contracts.ResourceUsage{}, usage)
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package accounting
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// blockSize is the unit of the block I/O counters of getrusage
+const blockSize = 512
+
+// processSample returns the counters of the process and of its exited commands, the CPU time of the
+// commands is accounted here as they are waited for
+func processSample() (s sample) {
+	for _, who := range []int{syscall.RUSAGE_SELF, syscall.RUSAGE_CHILDREN} {
+		var usage syscall.Rusage
+		if err := syscall.Getrusage(who, &usage); err != nil {
+			continue
+		}
+		s.cpu += time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+		s.readBytes += int64(usage.Inblock) * blockSize
+		s.writeBytes += int64(usage.Oublock) * blockSize
+	}
+	return s
+}
+
+// commandUsage returns the peak memory of the exited command and its waited descendants,
+// its CPU time is part of the process sample
+func commandUsage(state *os.ProcessState) (cpu time.Duration, peakMemoryKB int64) {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage == nil {
+		return 0, 0
+	}
+	peakMemoryKB = int64(usage.Maxrss)
+	if runtime.GOOS == "darwin" {
+		// darwin reports the maximum resident set size in bytes
+		peakMemoryKB /= 1024
+	}
+	return 0, peakMemoryKB
+}
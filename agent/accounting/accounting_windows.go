@@ -0,0 +1,73 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package accounting
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	kernel32                 = syscall.NewLazyDLL("kernel32.dll")
+	procGetProcessIoCounters = kernel32.NewProc("GetProcessIoCounters")
+)
+
+// ioCounters is the IO_COUNTERS structure of GetProcessIoCounters
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+// processSample returns the counters of the process, Windows does not account the commands to their parent
+// so their CPU time is added as they exit and their disk I/O is not measured
+func processSample() (s sample) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return s
+	}
+	var creation, exit, kernel, user syscall.Filetime
+	if err = syscall.GetProcessTimes(process, &creation, &exit, &kernel, &user); err == nil {
+		s.cpu = filetimeDuration(kernel) + filetimeDuration(user)
+	}
+	var counters ioCounters
+	if ret, _, _ := procGetProcessIoCounters.Call(uintptr(process), uintptr(unsafe.Pointer(&counters))); ret != 0 {
+		s.readBytes = int64(counters.ReadTransferCount)
+		s.writeBytes = int64(counters.WriteTransferCount)
+	}
+	return s
+}
+
+// commandUsage returns the CPU time of the exited command, its peak memory is not available once it exited
+func commandUsage(state *os.ProcessState) (cpu time.Duration, peakMemoryKB int64) {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage == nil {
+		return 0, 0
+	}
+	return filetimeDuration(usage.KernelTime) + filetimeDuration(usage.UserTime), 0
+}
+
+// filetimeDuration converts a duration in 100-nanosecond intervals
+func filetimeDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(int64(ft.HighDateTime)<<32+int64(ft.LowDateTime)) * 100
+}
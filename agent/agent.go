@@ -24,16 +24,29 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/agent"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/apparmor"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/configoverrides"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/crashdump"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremodules"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/hibernation"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/privsep"
+	"github.com/aws/amazon-ssm-agent/agent/processaudit"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
+	"github.com/aws/amazon-ssm-agent/agent/resourcelimits"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/amazon-ssm-agent/agent/selfmonitor"
 	"github.com/aws/amazon-ssm-agent/agent/session/utility"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/systemd"
 )
 
 const (
@@ -43,12 +56,23 @@ const (
 	registerFlag            = "register"
 	fingerprintFlag         = "fingerprint"
 	similarityThresholdFlag = "similarityThreshold"
+	validateConfigFlag      = "validate-config"
+	simulationFlag          = "simulation"
+	configureRecoveryFlag   = "configure-recovery"
+)
+
+// Statuses of the agent reported to systemd until the SystemdNotifier core module reports its connection
+const (
+	statusStarting    = "Starting the core modules"
+	statusHibernating = "Hibernating until the Systems Manager service is reachable"
 )
 
 var (
 	instanceIDPtr, regionPtr             *string
 	activationCode, activationID, region string
 	register, clear, force, fpFlag       bool
+	validateConfig, configureRecovery    bool
+	simulationEndpoint                   string
 	similarityThreshold                  int
 	registrationFile                     = filepath.Join(appconfig.DefaultDataStorePath, "registration")
 )
@@ -59,7 +83,43 @@ func start(log logger.T, instanceIDPtr *string, regionPtr *string, shouldCheckHi
 		log.Debugf("appconfig could not be loaded - %v", err)
 		return
 	}
+	// the FIPS mode also applies to the Parameter Store call fetching the overrides, which can turn it on as well
+	if err = fips.Configure(config.Fips.Enabled); err != nil {
+		return
+	}
+	if config, err = configoverrides.Load(log, config, ssm.NewService); err != nil {
+		log.Debugf("appconfig could not be loaded - %v", err)
+		return
+	}
+	if err = fips.Configure(config.Fips.Enabled); err != nil {
+		return
+	}
+	if fips.Enabled() {
+		log.Info("Running in FIPS mode")
+	}
+	if err = integrity.Configure(config.Integrity); err != nil {
+		log.Errorf("Failed to load the binary manifest: %v", err)
+		return
+	}
 	context := context.Default(log, config)
+	logConfigValidationErrors(log)
+	if partition, err := platform.DetectPartition(log); err == nil {
+		log.Infof("Using partition %v with domain %v", partition.ID, partition.DnsSuffix)
+	}
+	logServiceEndpoints(log, config)
+	if config.PrivilegeSeparation.Enabled {
+		// the AppArmor profiles, the cgroups and the proc connector need root, they are set up before the drop
+		err = privsep.DropPrivileges(config.PrivilegeSeparation.User, func(uid int, gid int) {
+			apparmor.Load(context)
+			resourcelimits.Apply(context, uid, gid)
+			processaudit.KeepListening(log, config.Audit)
+		})
+		if err != nil {
+			log.Errorf("Failed to drop the privileges of the agent: %v", err)
+			return
+		}
+		log.Infof("Running as %v, the operations needing root go through %v", config.PrivilegeSeparation.User, privsep.HelperPath())
+	}
 
 	//Reset password for default RunAs user if already exists
 	sessionUtil := &utility.SessionUtil{}
@@ -78,16 +138,51 @@ func start(log logger.T, instanceIDPtr *string, regionPtr *string, shouldCheckHi
 	if status, hibernationErr := healthModule.GetAgentState(); shouldCheckHibernation && status == health.Passive {
 		//Starting hibernate mode
 		context.Log().Info("Entering SSM Agent hibernate - ", hibernationErr)
+		// systemd must not restart a hibernating agent, the watchdog is pinged while it waits for the service
+		systemd.Started(log, statusHibernating)
 		go func() {
 			hibernateState.ExecuteHibernation()
 			err = startAgent(ssmAgent, context, log, instanceIDPtr, regionPtr)
 		}()
 	} else {
+		systemd.Started(log, statusStarting)
 		err = startAgent(ssmAgent, context, log, instanceIDPtr, regionPtr)
 	}
 	return
 }
 
+// logConfigValidationErrors warns about the settings of the config file the agent ignores or replaces by their default
+func logConfigValidationErrors(log logger.T) {
+	path := appconfig.ConfigFilePath()
+	validationErrors, err := appconfig.ValidateFile(path)
+	if err != nil {
+		// the agent runs with the default configuration without config file
+		return
+	}
+	for _, validationErr := range validationErrors {
+		log.Warnf("Invalid setting in %v, %v", path, validationErr)
+	}
+}
+
+// logServiceEndpoints logs the endpoint used for each service
+func logServiceEndpoints(log logger.T, config appconfig.SsmagentConfig) {
+	region := config.Agent.Region
+	if region == "" {
+		var err error
+		if region, err = platform.Region(); err != nil {
+			log.Warnf("Failed to get the region to log the service endpoints, %v", err)
+			return
+		}
+	}
+	for _, endpoint := range rip.GetServiceEndpoints(region) {
+		if endpoint.Overridden {
+			log.Infof("Using %v endpoint %v from appconfig", endpoint.Service, endpoint.Endpoint)
+		} else {
+			log.Infof("Using %v endpoint %v", endpoint.Service, endpoint.Endpoint)
+		}
+	}
+}
+
 func startAgent(ssmAgent agent.ISSMAgent, context context.T, log logger.T, instanceIDPtr *string, regionPtr *string) (err error) {
 	cloudwatchPublisher := &cloudwatchlogspublisher.CloudWatchPublisher{}
 	coreModules := coremodules.RegisteredCoreModules(context)
@@ -104,7 +199,9 @@ func startAgent(ssmAgent agent.ISSMAgent, context context.T, log logger.T, insta
 	return
 }
 
-func blockUntilSignaled(log logger.T) {
+// blockUntilSignaled blocks until the agent is signaled to exit or the self-monitor requested a restart,
+// it returns true for a restart
+func blockUntilSignaled(log logger.T) (restart bool) {
 	// Below channel will handle all machine initiated shutdown/reboot requests.
 
 	// Set up channel on which to receive signal notifications.
@@ -117,18 +214,26 @@ func blockUntilSignaled(log logger.T) {
 	// Otherwise we will continue execution and exit the program.
 	signal.Notify(c, os.Interrupt, os.Kill, syscall.SIGTERM)
 
-	s := <-c
-	log.Info("Got signal:", s, " value:", s.Signal)
+	select {
+	case s := <-c:
+		log.Info("Got signal:", s, " value:", s.Signal)
+		return false
+	case <-selfmonitor.RestartRequested():
+		log.Info("Stopping the agent for the service manager to restart it")
+		return true
+	}
 }
 
 // Run as a single process. Used by Unix systems and when running agent from console.
-func run(log logger.T) {
+// It returns true when the agent stopped to be restarted.
+func run(log logger.T) (restart bool) {
 	defer func() {
 		// recover in case the agent panics
 		// this should handle some kind of seg fault errors.
 		if msg := recover(); msg != nil {
 			log.Errorf("Agent crashed with message %v!", msg)
 			log.Errorf("%s: %s", msg, debug.Stack())
+			crashdump.Capture(log, msg)
 		}
 	}()
 
@@ -138,6 +243,11 @@ func run(log logger.T) {
 		log.Errorf("error occurred when starting amazon-ssm-agent: %v", err)
 		return
 	}
-	blockUntilSignaled(log)
+	restart = blockUntilSignaled(log)
+	if err := systemd.Stopping(); err != nil {
+		log.Warnf("Failed to notify systemd that the agent is stopping: %v", err)
+	}
 	agent.Stop()
+	bandwidth.LogUsage(log)
+	return
 }
@@ -18,6 +18,7 @@ import (
 	"runtime"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/coremanager"
 	"github.com/aws/amazon-ssm-agent/agent/health"
 	"github.com/aws/amazon-ssm-agent/agent/hibernation"
@@ -70,6 +71,9 @@ func (agent *SSMAgent) Start() {
 
 	log.Infof("Starting Agent: %v", version.String())
 	log.Infof("OS: %s, Arch: %s", runtime.GOOS, runtime.GOARCH)
+	if selinuxMode := fileutil.SELinuxMode(); selinuxMode != fileutil.SELinuxDisabled {
+		log.Infof("SELinux: %s", selinuxMode)
+	}
 	log.Flush()
 
 	if agent.coreManager == nil {
@@ -27,6 +27,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fingerprint"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
+	"github.com/aws/amazon-ssm-agent/agent/servicerecovery"
 	"github.com/aws/amazon-ssm-agent/agent/ssm/anonauth"
 )
 
@@ -55,14 +56,35 @@ func parseFlags(log logger.T) {
 	// force flag
 	flag.BoolVar(&force, "y", false, "")
 
+	// config file validation
+	flag.BoolVar(&validateConfig, validateConfigFlag, false, "")
+
+	// restart policy of the agent service
+	flag.BoolVar(&configureRecovery, configureRecoveryFlag, false, "")
+
+	// local simulation mode
+	flag.StringVar(&simulationEndpoint, simulationFlag, "", "")
+
 	flag.Parse()
 
-	if flag.NFlag() > 0 {
+	// the simulation mode runs the agent, the endpoint is passed in the environment
+	// for the config overrides of the agent and of its worker processes to pick it up
+	nFlag := flag.NFlag()
+	if simulationEndpoint != "" {
+		os.Setenv(appconfig.EnvironmentOverridePrefix+"SIMULATION_ENDPOINT", simulationEndpoint)
+		nFlag--
+	}
+
+	if nFlag > 0 {
 		exitCode := 1
 		if register {
 			exitCode = processRegistration(log)
 		} else if fpFlag {
 			exitCode = processFingerprint(log)
+		} else if validateConfig {
+			exitCode = processValidateConfig(flag.Arg(0))
+		} else if configureRecovery {
+			exitCode = processConfigureRecovery(log)
 		} else {
 			flagUsage()
 		}
@@ -81,6 +103,9 @@ func flagUsage() {
 	fmt.Fprintln(os.Stderr, "\t\t-region\tSSM region       \t(REQUIRED)")
 	fmt.Fprintln(os.Stderr, "\n\t\t-clear\tClears the previously saved SSM registration")
 	fmt.Fprintln(os.Stderr, "\n\t-y\tAnswer yes for all questions")
+	fmt.Fprintln(os.Stderr, "\n\t-validate-config [path]\tvalidate the config file, "+appconfig.ConfigFilePath()+" by default")
+	fmt.Fprintln(os.Stderr, "\n\t-configure-recovery\tapply the restart policy of the Recovery config to the agent service")
+	fmt.Fprintln(os.Stderr, "\n\t-simulation url\trun the agent against the mock message delivery service at url, no AWS credentials needed")
 }
 
 // processRegistration handles flags related to the registration category
@@ -118,6 +143,40 @@ func processRegistration(log logger.T) (exitCode int) {
 	return 0
 }
 
+// processValidateConfig reports the unknown keys, type errors and out of range values of a config file
+func processValidateConfig(path string) (exitCode int) {
+	if path == "" {
+		path = appconfig.ConfigFilePath()
+	}
+	validationErrors, err := appconfig.ValidateFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the config file %v: %v\n", path, err)
+		return 1
+	}
+	if len(validationErrors) == 0 {
+		fmt.Printf("%v is valid\n", path)
+		return 0
+	}
+	for _, validationErr := range validationErrors {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", path, validationErr)
+	}
+	return 1
+}
+
+// processConfigureRecovery applies the restart policy of the config to the agent service, the installers run it
+func processConfigureRecovery(log logger.T) (exitCode int) {
+	config, err := appconfig.Config(true)
+	if err != nil {
+		log.Errorf("Failed to load the config: %v", err)
+		return 1
+	}
+	if err = servicerecovery.Configure(log, config.Recovery); err != nil {
+		log.Errorf("Failed to apply the restart policy: %v", err)
+		return 1
+	}
+	return 0
+}
+
 // processFingerprint handles flags related to the fingerprint category
 func processFingerprint(log logger.T) (exitCode int) {
 	if err := fingerprint.SetSimilarityThreshold(similarityThreshold); err != nil {
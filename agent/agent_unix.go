@@ -1,8 +1,14 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 package main
 
-import logger "github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+import (
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	logger "github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+)
 
 func main() {
 	// initialize logger
@@ -13,6 +19,15 @@ func main() {
 	// parse input parameters
 	parseFlags(log)
 
-	// run agent
-	run(log)
+	// change the log level at runtime on signals
+	go handleLogLevelSignals(log)
+	// reload the config file on SIGHUP
+	go handleReloadSignals(log)
+
+	// run agent, it exits with an error on a restart for the service manager to start it again
+	if restart := run(log); restart {
+		log.Flush()
+		log.Close()
+		os.Exit(appconfig.ErrorExitCode)
+	}
 }
@@ -1,3 +1,4 @@
+//go:build windows
 // +build windows
 
 package main
@@ -12,6 +13,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/plugin/cloudwatch"
 	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/selfmonitor"
 	"golang.org/x/sys/windows/registry"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/mgr"
@@ -72,12 +74,19 @@ func main() {
 	// case that agent is running as Windows service most of times
 	switch isIntSess {
 	case true:
-		run(log)
+		if restart := run(log); restart {
+			log.Flush()
+			log.Close()
+			os.Exit(appconfig.ErrorExitCode)
+		}
 	case false:
 		svc.Run(serviceName, &amazonSSMAgentService{log: log})
 	}
 }
 
+// stopWaitHint is the time the agent takes to stop past the drain of the in-flight documents
+const stopWaitHint = 30 * time.Second
+
 type amazonSSMAgentService struct {
 	log logger.T
 }
@@ -192,11 +201,19 @@ func (a *amazonSSMAgentService) Execute(args []string, r <-chan svc.ChangeReques
 	const acceptCmds = svc.AcceptStop | svc.AcceptShutdown
 	s <- svc.Status{State: svc.Running, Accepts: acceptCmds}
 
+	restart := false
 loop:
 	// using an infinite loop to wait for ChangeRequests
 	for {
-		// block and wait for ChangeRequests
-		c := <-r
+		// block and wait for ChangeRequests or a restart requested by the self-monitor
+		var c svc.ChangeRequest
+		select {
+		case c = <-r:
+		case <-selfmonitor.RestartRequested():
+			log.Info("Stopping the agent for the service recovery to restart it")
+			restart = true
+			break loop
+		}
 
 		// handle ChangeRequest, svc.Pause is not supported
 		switch c.Cmd {
@@ -211,7 +228,16 @@ loop:
 			continue loop
 		}
 	}
-	s <- svc.Status{State: svc.StopPending}
+	// the in-flight documents are drained before the agent stops
+	var drainTimeout time.Duration
+	if config, err := appconfig.Config(false); err == nil {
+		drainTimeout = time.Duration(config.Agent.DrainTimeoutSeconds) * time.Second
+	}
+	s <- svc.Status{State: svc.StopPending, WaitHint: uint32((drainTimeout + stopWaitHint) / time.Millisecond)}
 	agent.Stop()
+	if restart {
+		// the service stops with an error, which triggers its recovery actions
+		return true, appconfig.ErrorExitCode
+	}
 	return false, appconfig.SuccessExitCode
 }
@@ -24,9 +24,12 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -36,7 +39,6 @@ import (
 const (
 	stopPolicyErrorThreshold = 10
 	stopPolicyName           = "CloudWatchLogsService"
-	maxRetries               = 5
 	UploadFrequency          = 3 * time.Second
 	NewLineCharacter         = "\n"
 	maxNumberOfEventsPerCall = 4
@@ -73,13 +75,21 @@ func createCloudWatchClientWithCredentials(id, secret string) cloudwatchlogsinte
 // createCloudWatchClientWithConfig creates a client to call CloudWatchLogs APIs using the passed aws config
 func createCloudWatchClientWithConfig(config *aws.Config) cloudwatchlogsinterface.CloudWatchLogsClient {
 	//Adding the AWS SDK Retrier with Exponential Backoff
-	config = request.WithRetryer(config, client.DefaultRetryer{
-		NumMaxRetries: maxRetries,
-	})
+	config = request.WithRetryer(config, retryer.New(cloudwatchlogs.ServiceName))
 
 	appConfig, _ := appconfig.Config(false)
+	if appConfig.Logs.Endpoint != "" {
+		config.Endpoint = &appConfig.Logs.Endpoint
+	} else if defaultEndpoint := appconfig.GetDefaultEndPoint(aws.StringValue(config.Region), "logs"); defaultEndpoint != "" {
+		config.Endpoint = &defaultEndpoint
+	}
+	proxyconfig.ConfigureAwsProxy(log.DefaultLogger(), config, appConfig.Logs.Proxy)
+
 	sess := session.New(config)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
 	return cloudwatchlogs.New(sess)
 }
 
@@ -118,18 +128,32 @@ func (service *CloudWatchLogsService) CreateNewServiceIfUnHealthy() {
 	}
 }
 
-// CreateLogGroup calls the CreateLogGroup API to create a log group
+// logsConfig returns the CloudWatch Logs settings of the agent, it is stubbed in the tests
+var logsConfig = func() appconfig.LogsCfg {
+	appConfig, _ := appconfig.Config(false)
+	return appConfig.Logs
+}
+
+// CreateLogGroup calls the CreateLogGroup API to create a log group, encrypted with the KMS key and expiring
+// its events after the retention of the agent settings
 func (service *CloudWatchLogsService) CreateLogGroup(log log.T, logGroup string) (err error) {
 
 	service.CreateNewServiceIfUnHealthy()
 
 	//Creating the parameters for the API Call
+	logsCfg := logsConfig()
 	params := &cloudwatchlogs.CreateLogGroupInput{
 		LogGroupName: aws.String(logGroup),
 	}
+	if logsCfg.LogGroupKmsKeyId != "" {
+		params.KmsKeyId = aws.String(logsCfg.LogGroupKmsKeyId)
+	}
 
 	//Calling the API
-	if _, err = service.cloudWatchLogsClient.CreateLogGroup(params); err != nil {
+	if _, err = service.cloudWatchLogsClient.CreateLogGroup(params); err == nil {
+		log.Infof("Created log group %v", logGroup)
+		service.putRetentionPolicy(log, logGroup, logsCfg.LogGroupRetentionDays)
+	} else {
 		// Handle the common AWS errors and update the stop policy accordingly
 		sdkutil.HandleAwsError(log, err, service.stopPolicy)
 
@@ -150,6 +174,21 @@ func (service *CloudWatchLogsService) CreateLogGroup(log log.T, logGroup string)
 	return
 }
 
+// putRetentionPolicy sets the retention of a created log group, the events are kept forever when the agent is not
+// allowed to set it
+func (service *CloudWatchLogsService) putRetentionPolicy(log log.T, logGroup string, retentionDays int) {
+	if retentionDays == 0 {
+		return
+	}
+	params := &cloudwatchlogs.PutRetentionPolicyInput{
+		LogGroupName:    aws.String(logGroup),
+		RetentionInDays: aws.Int64(int64(retentionDays)),
+	}
+	if _, err := service.cloudWatchLogsClient.PutRetentionPolicy(params); err != nil {
+		log.Warnf("Failed to set the retention of log group %v to %v days: %v", logGroup, retentionDays, err)
+	}
+}
+
 // CreateLogStream calls the CreateLogStream API to create log stream within the specified log group
 func (service *CloudWatchLogsService) CreateLogStream(log log.T, logGroup, logStream string) (err error) {
 
@@ -252,7 +291,7 @@ func (service *CloudWatchLogsService) DescribeLogStreams(log log.T, logGroup, lo
 
 }
 
-//getLogGroupDetails Calls the DescribeLogGroups API to get the details of the loggroup specified. Returns nil if not found
+// getLogGroupDetails Calls the DescribeLogGroups API to get the details of the loggroup specified. Returns nil if not found
 func (service *CloudWatchLogsService) getLogGroupDetails(log log.T, logGroup string) (logGroupDetails *cloudwatchlogs.LogGroup) {
 
 	// Keeping the nextToken as empty in the beginning. Might get filled from response for subsequent calls
@@ -411,7 +450,7 @@ func (service *CloudWatchLogsService) retryPutWithNewSequenceToken(log log.T, me
 	return service.PutLogEvents(log, messages, logGroupName, logStreamName, sequenceToken)
 }
 
-//IsLogGroupEncryptedWithKMS return true if the log group is encrypted with KMS key.
+// IsLogGroupEncryptedWithKMS return true if the log group is encrypted with KMS key.
 func (service *CloudWatchLogsService) IsLogGroupEncryptedWithKMS(log log.T, logGroupName string) bool {
 	logGroup := service.getLogGroupDetails(log, logGroupName)
 	if logGroup == nil {
@@ -426,7 +465,7 @@ func (service *CloudWatchLogsService) IsLogGroupEncryptedWithKMS(log log.T, logG
 	return false
 }
 
-//StreamData streams data from the absoluteFilePath file to cloudwatch logs.
+// StreamData streams data from the absoluteFilePath file to cloudwatch logs.
 func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string, logStreamName string, absoluteFilePath string, isFileComplete bool, isLogStreamCreated bool) {
 	log.Debugf("Uploading logs at %s to CloudWatch", absoluteFilePath)
 
@@ -463,7 +502,14 @@ func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string,
 		log.Debugf("Uploading message %v to CloudWatch", events)
 
 		if !IsLogStreamCreated {
-			if err := service.CreateLogStream(log, logGroupName, logStreamName); err != nil {
+			err := service.CreateLogStream(log, logGroupName, logStreamName)
+			if sdkutil.GetAwsErrorCode(err) == resourceNotFoundException {
+				// the log group does not exist, it is created with the settings of the agent
+				if err = service.CreateLogGroup(log, logGroupName); err == nil {
+					err = service.CreateLogStream(log, logGroupName, logStreamName)
+				}
+			}
+			if err != nil {
 				log.Errorf("Error Creating Log Stream for CloudWatchLogs output: %v", err)
 				currentLineNumber = lastKnownLineUploadedToCWL
 				log.Debug("Failed to upload message to CloudWatch")
@@ -488,7 +534,7 @@ func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string,
 	}
 }
 
-//getNextMessage gets the next message to be uploaded to cloudwatch.
+// getNextMessage gets the next message to be uploaded to cloudwatch.
 func (service *CloudWatchLogsService) getNextMessage(log log.T, absoluteFilePath string, lastKnownLineUploadedToCWL *int64, currentLineNumber *int64) (allEvents []*cloudwatchlogs.InputLogEvent, eof bool) {
 	// Open file to read.
 	file, err := os.Open(absoluteFilePath)
@@ -16,13 +16,17 @@
 package cloudwatchlogspublisher
 
 import (
+	"errors"
 	"os"
 	"strings"
 	"testing"
 
 	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -94,6 +98,54 @@ func TestCloudWatchLogsService_CreateLogGroup(t *testing.T) {
 
 }
 
+func TestCloudWatchLogsService_CreateLogGroupWithRetentionAndKmsKey(t *testing.T) {
+	clientMock := cloudwatchlogspublisher_mock.NewClientMockDefault()
+	service := CloudWatchLogsService{
+		cloudWatchLogsClient: clientMock,
+		stopPolicy:           sdkutil.NewStopPolicy("Test", 0),
+	}
+	logsConfigOriginal := logsConfig
+	defer func() { logsConfig = logsConfigOriginal }()
+	logsConfig = func() appconfig.LogsCfg {
+		return appconfig.LogsCfg{LogGroupRetentionDays: 30, LogGroupKmsKeyId: "alias/ssm"}
+	}
+
+	clientMock.On("CreateLogGroup", &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String("LogGroup"),
+		KmsKeyId:     aws.String("alias/ssm"),
+	}).Return(&cloudwatchlogs.CreateLogGroupOutput{}, nil)
+	clientMock.On("PutRetentionPolicy", &cloudwatchlogs.PutRetentionPolicyInput{
+		LogGroupName:    aws.String("LogGroup"),
+		RetentionInDays: aws.Int64(30),
+	}).Return(&cloudwatchlogs.PutRetentionPolicyOutput{}, errors.New("AccessDeniedException"))
+
+	err := service.CreateLogGroup(logMock, "LogGroup")
+
+	assert.NoError(t, err, "a retention that cannot be set should not fail the creation")
+	clientMock.AssertExpectations(t)
+}
+
+func TestCloudWatchLogsService_CreateLogGroupAlreadyExists(t *testing.T) {
+	clientMock := cloudwatchlogspublisher_mock.NewClientMockDefault()
+	service := CloudWatchLogsService{
+		cloudWatchLogsClient: clientMock,
+		stopPolicy:           sdkutil.NewStopPolicy("Test", 0),
+	}
+	logsConfigOriginal := logsConfig
+	defer func() { logsConfig = logsConfigOriginal }()
+	logsConfig = func() appconfig.LogsCfg {
+		return appconfig.LogsCfg{LogGroupRetentionDays: 30}
+	}
+
+	clientMock.On("CreateLogGroup", mock.AnythingOfType("*cloudwatchlogs.CreateLogGroupInput")).Return(
+		&cloudwatchlogs.CreateLogGroupOutput{}, awserr.New(resourceAlreadyExistsException, "exists", nil))
+
+	err := service.CreateLogGroup(logMock, "LogGroup")
+
+	assert.NoError(t, err)
+	clientMock.AssertNotCalled(t, "PutRetentionPolicy", mock.Anything)
+}
+
 func TestCloudWatchLogsService_DescribeLogStreams(t *testing.T) {
 	service := CloudWatchLogsService{
 		cloudWatchLogsClient: cwLogsClientMock,
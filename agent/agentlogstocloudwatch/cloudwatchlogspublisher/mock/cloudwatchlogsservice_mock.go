@@ -75,6 +75,12 @@ func (m *CloudWatchLogsClientMock) DescribeLogStreams(input *cloudwatchlogs.Desc
 	return args.Get(0).(*cloudwatchlogs.DescribeLogStreamsOutput), args.Error(1)
 }
 
+// PutRetentionPolicy mocks CloudWatchLogsClient PutRetentionPolicy method
+func (m *CloudWatchLogsClientMock) PutRetentionPolicy(input *cloudwatchlogs.PutRetentionPolicyInput) (*cloudwatchlogs.PutRetentionPolicyOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*cloudwatchlogs.PutRetentionPolicyOutput), args.Error(1)
+}
+
 // CreateNewServiceIfUnHealthy mocks CloudWatchLogsService CreateNewServiceIfUnHealthy method
 func (m *CloudWatchLogsServiceMock) CreateNewServiceIfUnHealthy() {
 
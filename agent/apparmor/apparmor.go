@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package apparmor generates the AppArmor profiles of the document and session workers from the paths the agent
+// is installed to, and loads them at the agent startup on the Ubuntu and Debian instances where AppArmor is enabled.
+// The commands and the shells the workers start run outside of the profiles, like the commands of an administrator.
+package apparmor
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// profilePrefix prefixes the names and the files of the profiles generated by the agent
+const profilePrefix = "amazon-ssm-agent."
+
+// profileDir is the folder the profiles are written to, AppArmor loads them from there at boot
+var profileDir = "/etc/apparmor.d"
+
+// profile is the confinement of a worker executable
+type profile struct {
+	// Name is the name of the profile, it is also the file name of the profile
+	Name string
+	// Executable is the path of the worker the profile attaches to
+	Executable string
+	// Capabilities are the capabilities the worker needs
+	Capabilities []string
+	// Paths are the rules of the files the worker needs, in addition to the agent folders
+	Paths []string
+}
+
+// profileTemplate is the AppArmor profile of a worker, the agent folders are readable or writable by every worker
+var profileTemplate = template.Must(template.New("profile").Parse(`# Generated by amazon-ssm-agent, changes are overwritten at the agent startup
+
+#include <tunables/global>
+
+profile {{.Profile.Name}} {{.Profile.Executable}} {
+  #include <abstractions/base>
+  #include <abstractions/nameservice>
+  #include <abstractions/ssl_certs>
+
+  capability {{.Capabilities}},
+
+  network inet stream,
+  network inet6 stream,
+  network inet dgram,
+  network inet6 dgram,
+  network unix,
+
+  signal,
+  ptrace (read),
+
+  {{.Profile.Executable}} mr,
+  {{.ConfigDir}}/** r,
+  {{.DataDir}}/ rw,
+  {{.DataDir}}/** rwlk,
+  {{.LogDir}}/ rw,
+  {{.LogDir}}/** rwk,
+  /tmp/** rwlk,
+  @{PROC}/** r,
+  /sys/** r,
+{{- range .Profile.Paths}}
+  {{.}},
+{{- end}}
+
+  # the documents and the session shells run the commands of the administrators outside of the profile
+  /** Ux,
+}
+`))
+
+// workerProfiles returns the profiles of the document and session workers
+func workerProfiles() []profile {
+	return []profile{
+		{
+			Name:         profilePrefix + filepath.Base(appconfig.DefaultDocumentWorker),
+			Executable:   appconfig.DefaultDocumentWorker,
+			Capabilities: []string{"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "setgid", "setuid", "sys_resource"},
+		},
+		{
+			Name:         profilePrefix + filepath.Base(appconfig.DefaultSessionWorker),
+			Executable:   appconfig.DefaultSessionWorker,
+			Capabilities: []string{"audit_write", "chown", "dac_override", "dac_read_search", "fowner", "kill", "setgid", "setuid", "sys_resource"},
+			Paths:        []string{"/dev/ptmx rw", "/dev/pts/* rw", "/home/** r"},
+		},
+	}
+}
+
+// generate returns the AppArmor profile of the worker
+func (p profile) generate() (string, error) {
+	var buffer bytes.Buffer
+	err := profileTemplate.Execute(&buffer, struct {
+		Profile      profile
+		Capabilities string
+		ConfigDir    string
+		DataDir      string
+		LogDir       string
+	}{
+		Profile:      p,
+		Capabilities: strings.Join(p.Capabilities, " "),
+		ConfigDir:    strings.TrimSuffix(appconfig.DefaultProgramFolder, "/"),
+		DataDir:      strings.TrimSuffix(appconfig.DefaultDataStorePath, "/"),
+		LogDir:       strings.TrimSuffix(log.DefaultLogDir, "/"),
+	})
+	return buffer.String(), err
+}
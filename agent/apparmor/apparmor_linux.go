@@ -0,0 +1,38 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package apparmor
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// apparmorEnabledPath holds Y when the AppArmor module of the kernel is enabled
+const apparmorEnabledPath = "/sys/module/apparmor/parameters/enabled"
+
+// available returns true when AppArmor is enabled, the profiles of a strictly confined snap are managed by snapd.
+// It is stubbed in the tests.
+var available = func() bool {
+	if appconfig.StrictSnapConfinement() {
+		return false
+	}
+	enabled, err := ioutil.ReadFile(apparmorEnabledPath)
+	return err == nil && strings.TrimSpace(string(enabled)) == "Y"
+}
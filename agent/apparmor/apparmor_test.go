@@ -0,0 +1,127 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apparmor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockContextWithAppArmor(enabled bool, mode string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.AppArmor.Enabled = enabled
+	config.AppArmor.Mode = mode
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+// stubAppArmor makes AppArmor available, writes the profiles to a temporary folder and records the parser calls
+func stubAppArmor(t *testing.T) *[][]string {
+	dir, err := ioutil.TempDir("", "apparmor")
+	require.NoError(t, err)
+	availableOrig, parserOrig, profileDirOrig := available, apparmorParser, profileDir
+	t.Cleanup(func() {
+		available, apparmorParser, profileDir = availableOrig, parserOrig, profileDirOrig
+		loadedAhead = false
+		os.RemoveAll(dir)
+	})
+	var calls [][]string
+	available = func() bool { return true }
+	apparmorParser = func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	}
+	profileDir = dir
+	return &calls
+}
+
+func TestGenerateProfile(t *testing.T) {
+	for _, p := range workerProfiles() {
+		content, err := p.generate()
+		assert.NoError(t, err)
+		assert.Contains(t, content, "profile "+p.Name+" "+p.Executable+" {")
+		assert.Contains(t, content, "capability "+strings.Join(p.Capabilities, " ")+",")
+		assert.Contains(t, content, strings.TrimSuffix(appconfig.DefaultDataStorePath, "/")+"/** rwlk,")
+		assert.Contains(t, content, "/** Ux,")
+		for _, path := range p.Paths {
+			assert.Contains(t, content, "  "+path+",\n")
+		}
+	}
+}
+
+func TestNewLoader_NotAvailable(t *testing.T) {
+	stubAppArmor(t)
+	available = func() bool { return false }
+	assert.Nil(t, NewLoader(mockContextWithAppArmor(true, appconfig.AppArmorModeEnforce)))
+}
+
+func TestNewLoader_DisabledWithoutProfiles(t *testing.T) {
+	stubAppArmor(t)
+	assert.Nil(t, NewLoader(mockContextWithAppArmor(false, appconfig.AppArmorModeEnforce)))
+}
+
+func TestLoader_LoadsProfiles(t *testing.T) {
+	calls := stubAppArmor(t)
+	loader := NewLoader(mockContextWithAppArmor(true, appconfig.AppArmorModeComplain))
+	require.NotNil(t, loader)
+	assert.NoError(t, loader.ModuleExecute(nil))
+
+	profiles := workerProfiles()
+	require.Len(t, *calls, len(profiles))
+	for i, p := range profiles {
+		path := filepath.Join(profileDir, p.Name)
+		assert.Equal(t, []string{"--replace", "--complain", path}, (*calls)[i])
+		assert.True(t, fileExists(path))
+	}
+}
+
+func TestLoader_UnloadsProfilesWhenDisabled(t *testing.T) {
+	calls := stubAppArmor(t)
+	path := filepath.Join(profileDir, profilePrefix+"ssm-document-worker")
+	require.NoError(t, ioutil.WriteFile(path, []byte("profile"), appconfig.ReadWriteAccess))
+
+	loader := NewLoader(mockContextWithAppArmor(false, appconfig.AppArmorModeEnforce))
+	require.NotNil(t, loader)
+	assert.NoError(t, loader.ModuleExecute(nil))
+	assert.Equal(t, [][]string{{"--remove", path}}, *calls)
+	assert.False(t, fileExists(path))
+}
+
+func TestLoad_AheadOfTheModule(t *testing.T) {
+	calls := stubAppArmor(t)
+	ctx := mockContextWithAppArmor(true, appconfig.AppArmorModeEnforce)
+	Load(ctx)
+	assert.Len(t, *calls, len(workerProfiles()))
+	// the profiles are loaded once, before the agent drops its privileges
+	assert.Nil(t, NewLoader(ctx))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apparmor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const name = "AppArmor"
+
+// apparmorParser loads or unloads a profile file with apparmor_parser, it is stubbed in the tests
+var apparmorParser = func(args ...string) ([]byte, error) {
+	return exec.Command("apparmor_parser", args...).CombinedOutput()
+}
+
+// loadedAhead is set when the profiles were loaded ahead of the core module, see Load
+var loadedAhead bool
+
+// Loader is the core module loading the worker profiles when AppArmor is enabled in appconfig,
+// and unloading the profiles it loaded before once it is disabled
+type Loader struct {
+	context  context.T
+	enabled  bool
+	complain bool
+}
+
+// NewLoader creates the loader, nil when AppArmor is not available on the instance, or when it is not enabled
+// in appconfig and no profile of the agent is installed
+func NewLoader(context context.T) *Loader {
+	if loadedAhead {
+		return nil
+	}
+	config := context.AppConfig().AppArmor
+	if !available() {
+		if config.Enabled {
+			context.Log().Warnf("AppArmor is enabled in appconfig but is not available on the instance")
+		}
+		return nil
+	}
+	if !config.Enabled && len(installedProfiles()) == 0 {
+		return nil
+	}
+	return &Loader{
+		context:  context.With("[" + name + "]"),
+		enabled:  config.Enabled,
+		complain: config.Mode == appconfig.AppArmorModeComplain,
+	}
+}
+
+// Load loads or unloads the worker profiles ahead of the core module, the agent running unprivileged loads them
+// before it drops its privileges since only root can load them
+func Load(context context.T) {
+	if loader := NewLoader(context); loader != nil {
+		loader.ModuleExecute(context)
+	}
+	loadedAhead = true
+}
+
+// ModuleName returns the name of the module
+func (l *Loader) ModuleName() string {
+	return name
+}
+
+// ModuleExecute loads the worker profiles, or unloads them when AppArmor is disabled in appconfig.
+// The workers keep running unconfined when their profiles fail to load.
+func (l *Loader) ModuleExecute(context context.T) (err error) {
+	log := l.context.Log()
+	if !l.enabled {
+		unloadProfiles(log)
+		return nil
+	}
+	for _, p := range workerProfiles() {
+		if err := l.loadProfile(p); err != nil {
+			log.Errorf("Failed to load the AppArmor profile %v: %v", p.Name, err)
+			continue
+		}
+		log.Infof("Loaded the AppArmor profile %v of %v", p.Name, p.Executable)
+	}
+	return nil
+}
+
+// ModuleRequestStop does nothing, the profiles stay loaded for the workers still running
+func (l *Loader) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	return nil
+}
+
+// loadProfile writes the profile and replaces the loaded profile by it
+func (l *Loader) loadProfile(p profile) error {
+	content, err := p.generate()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(profileDir, p.Name)
+	if err = ioutil.WriteFile(path, []byte(content), appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	args := []string{"--replace"}
+	if l.complain {
+		args = append(args, "--complain")
+	}
+	if output, err := apparmorParser(append(args, path)...); err != nil {
+		return fmt.Errorf("%v, %v", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// unloadProfiles unloads and removes the profiles generated by the agent
+func unloadProfiles(log log.T) {
+	for _, path := range installedProfiles() {
+		if output, err := apparmorParser("--remove", path); err != nil {
+			log.Warnf("Failed to unload the AppArmor profile %v: %v, %v", path, err, strings.TrimSpace(string(output)))
+		}
+		if err := os.Remove(path); err != nil {
+			log.Warnf("Failed to remove the AppArmor profile %v: %v", path, err)
+			continue
+		}
+		log.Infof("Removed the AppArmor profile %v", path)
+	}
+}
+
+// installedProfiles returns the files of the profiles generated by the agent
+func installedProfiles() []string {
+	paths, _ := filepath.Glob(filepath.Join(profileDir, profilePrefix+"*"))
+	return paths
+}
@@ -18,10 +18,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 
-	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 )
 
@@ -33,26 +33,49 @@ var lock sync.RWMutex
 // otherwise it returns a previous loaded version, if any.
 func Config(reload bool) (SsmagentConfig, error) {
 	if reload || !isLoaded() {
-		var agentConfig SsmagentConfig
-		agentConfig = DefaultConfig()
-		path, pathErr := getAppConfigPath()
-		if pathErr != nil {
-			return agentConfig, nil
+		agentConfig, found, err := readConfig()
+		if !found || err != nil {
+			return agentConfig, err
 		}
-		agentConfig.Os.Name = runtime.GOOS
-		agentConfig.Agent.Version = version.Version
+		cache(agentConfig)
+	}
+	return getCached(), nil
+}
 
+// readConfig returns the default configuration overridden by the config file, Parameter Store and the environment,
+// found is false when nothing overrides the defaults
+func readConfig() (agentConfig SsmagentConfig, found bool, err error) {
+	agentConfig = DefaultConfig()
+	path, pathErr := getAppConfigPath()
+	if pathErr == nil {
 		// Process config override
 		fmt.Printf("Applying config override from %s.\n", path)
 
-		if err := jsonutil.UnmarshalFile(path, &agentConfig); err != nil {
+		if err = unmarshalConfigFile(path, &agentConfig); err != nil {
 			fmt.Println("Failed to unmarshal config override. Fall back to default.")
-			return agentConfig, err
+			return agentConfig, true, err
 		}
-		parser(&agentConfig)
-		cache(agentConfig)
 	}
-	return getCached(), nil
+	if !applyConfigOverrides(&agentConfig) && pathErr != nil {
+		return agentConfig, false, nil
+	}
+	agentConfig.Os.Name = runtime.GOOS
+	agentConfig.Agent.Version = version.Version
+	parser(&agentConfig)
+	return agentConfig, true, nil
+}
+
+// FileConfig returns the default configuration overridden by the config file only, the environment and Parameter Store
+// overrides are not applied. ssm-agent-helper reads it, the environment of its caller can't be trusted.
+func FileConfig(path string) (agentConfig SsmagentConfig, err error) {
+	agentConfig = DefaultConfig()
+	if err = unmarshalConfigFile(path, &agentConfig); err != nil {
+		return DefaultConfig(), err
+	}
+	agentConfig.Os.Name = runtime.GOOS
+	agentConfig.Agent.Version = version.Version
+	parser(&agentConfig)
+	return agentConfig, nil
 }
 
 func isLoaded() bool {
@@ -73,32 +96,73 @@ func getCached() SsmagentConfig {
 	return *loadedConfig
 }
 
-// looks for appconfig in working directory first and then the platform specific folder
-func getAppConfigPath() (path string, err error) {
-	// looking for appconfig in the platform specific folder
-	if _, err = os.Stat(AppConfigPath); err != nil {
-		return "", err
+// looks for appconfig in the platform specific folder, in JSON, YAML or TOML, stubbed in tests
+var getAppConfigPath = func() (path string, err error) {
+	var found []string
+	for _, candidate := range AppConfigPaths() {
+		if _, statErr := os.Stat(candidate); statErr == nil {
+			found = append(found, candidate)
+		}
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("no config file found at %v", AppConfigPath)
+	}
+	if len(found) > 1 {
+		log.Printf("Found several config files %v, only %s is read.\n", found, found[0])
+	}
+	log.Printf("Found config file at %s.\n", found[0])
+	return found[0], nil
+}
+
+// AppConfigPaths returns the paths of the config file in JSON, YAML and TOML in their order of precedence
+func AppConfigPaths() []string {
+	folder := appConfigFolder()
+	return []string{
+		filepath.Join(folder, AppConfigFileName),
+		filepath.Join(folder, AppConfigYamlFileName),
+		filepath.Join(folder, AppConfigTomlFileName),
 	}
+}
+
+// appConfigFolder returns the folder of the config file, stubbed in tests
+var appConfigFolder = func() string {
+	return filepath.Dir(AppConfigPath)
+}
 
-	log.Printf("Found config file at %s.\n", AppConfigPath)
-	return AppConfigPath, err
+// ConfigFilePath returns the path of the config file read by the agent, the path of the JSON config file when
+// there is no config file
+func ConfigFilePath() string {
+	if path, err := getAppConfigPath(); err == nil {
+		return path
+	}
+	return AppConfigPath
 }
 
 // DefaultConfig returns default ssm agent configuration
 func DefaultConfig() SsmagentConfig {
 
 	var credsProfile = CredentialProfile{
-		ShareCreds: true,
+		ShareCreds:                      true,
+		CredentialProcessTimeoutSeconds: DefaultCredentialProcessTimeoutSeconds,
+	}
+	var s3 = S3Cfg{
+		UploadPartSizeMB:  DefaultS3UploadPartSizeMB,
+		UploadConcurrency: DefaultS3UploadConcurrency,
 	}
-	var s3 S3Cfg
 	var mds = MdsCfg{
-		CommandWorkersLimit: DefaultCommandWorkersLimit,
-		StopTimeoutMillis:   DefaultStopTimeoutMillis,
-		CommandRetryLimit:   DefaultCommandRetryLimit,
+		CommandWorkersLimit:  DefaultCommandWorkersLimit,
+		StopTimeoutMillis:    DefaultStopTimeoutMillis,
+		CommandRetryLimit:    DefaultCommandRetryLimit,
+		PollBackoffMinMillis: DefaultPollBackoffMinMillis,
+		PollBackoffMaxMillis: DefaultPollBackoffMaxMillis,
 	}
 	var mgs = MgsConfig{
-		SessionWorkersLimit: DefaultSessionWorkersLimit,
-		StopTimeoutMillis:   DefaultStopTimeoutMillis,
+		SessionWorkersLimit:       DefaultSessionWorkersLimit,
+		StopTimeoutMillis:         DefaultStopTimeoutMillis,
+		ControlChannelTransport:   ControlChannelTransportWebSocket,
+		MaxOutstandingOutputBytes: DefaultMaxOutstandingOutputBytes,
+		ScrollbackBytes:           DefaultScrollbackBytes,
+		SessionRetryLimit:         DefaultSessionRetryLimit,
 	}
 	var ssm = SsmCfg{
 		HealthFrequencyMinutes:                DefaultSsmHealthFrequencyMinutes,
@@ -108,10 +172,13 @@ func DefaultConfig() SsmagentConfig {
 		AssociationLogsRetentionDurationHours: DefaultAssociationLogsRetentionDurationHours,
 		RunCommandLogsRetentionDurationHours:  DefaultRunCommandLogsRetentionDurationHours,
 		SessionLogsRetentionDurationHours:     DefaultSessionLogsRetentionDurationHours,
+		ParallelPackageActionsLimit:           DefaultParallelPackageActionsLimit,
 	}
 	var agent = AgentInfo{
 		Name:                 "amazon-ssm-agent",
 		OrchestrationRootDir: defaultOrchestrationRootDirName,
+		DebugLogMinutes:      DefaultDebugLogMinutes,
+		DrainTimeoutSeconds:  DefaultDrainTimeoutSeconds,
 	}
 	var os = OsInfo{
 		Lang:    "en-US",
@@ -119,17 +186,177 @@ func DefaultConfig() SsmagentConfig {
 	}
 	var birdwatcher BirdwatcherCfg
 	var kms KmsConfig
+	var packageCache = PackageCacheCfg{
+		MaxVersionsPerPackage: DefaultPackageCacheMaxVersionsPerPackage,
+		MaxSizeMB:             DefaultPackageCacheMaxSizeMB,
+		MaxAgeDays:            DefaultPackageCacheMaxAgeDays,
+	}
+	var packageHooks = PackageHooksCfg{
+		TimeoutSeconds: DefaultPackageHooksTimeoutSeconds,
+	}
+	var failover = FailoverCfg{
+		FailureThreshold: DefaultFailoverFailureThreshold,
+		FailbackMinutes:  DefaultFailoverFailbackMinutes,
+	}
+	var registration = RegistrationCfg{
+		KeyProtection: KeyProtectionNone,
+	}
+	var identity = IdentityCfg{
+		Providers: DefaultIdentityProviders(),
+	}
+	var circuitBreaker = CircuitBreakerCfg{
+		FailureThreshold:   DefaultCircuitBreakerFailureThreshold,
+		CooldownSeconds:    DefaultCircuitBreakerCooldownSeconds,
+		MaxCooldownSeconds: DefaultCircuitBreakerMaxCooldownSeconds,
+	}
+	var retry = RetryCfg{
+		RetryPolicyCfg: RetryPolicyCfg{
+			MaxRetries:      DefaultRetryMaxRetries,
+			BaseDelayMillis: DefaultRetryBaseDelayMillis,
+			MaxDelayMillis:  DefaultRetryMaxDelayMillis,
+		},
+		Services: map[string]RetryPolicyCfg{
+			"logs": {MaxRetries: DefaultLogsMaxRetries},
+		},
+	}
+	var network = NetworkCfg{
+		Dns: DnsCfg{
+			CacheSeconds: DefaultDnsCacheSeconds,
+		},
+	}
+
+	var update = UpdateCfg{
+		Channel: UpdateChannelStable,
+		Hooks: UpdateHooksCfg{
+			TimeoutSeconds: DefaultUpdateHooksTimeoutSeconds,
+		},
+	}
+	var metrics = MetricsCfg{
+		Sink:                   MetricsSinkCloudWatch,
+		StatsdAddress:          DefaultMetricsStatsdAddress,
+		PublishIntervalSeconds: DefaultMetricsPublishIntervalSeconds,
+	}
+	var tracing = TracingCfg{
+		Endpoint: DefaultTracingEndpoint,
+	}
+	var audit = AuditCfg{
+		MaxSizeMB:     DefaultAuditMaxSizeMB,
+		MaxRolls:      DefaultAuditMaxRolls,
+		RetentionDays: DefaultAuditRetentionDays,
+	}
+	var crashDump = CrashDumpCfg{
+		MaxDumps:      DefaultCrashDumpMaxDumps,
+		RetentionDays: DefaultCrashDumpRetentionDays,
+	}
+	var watchdog = WatchdogCfg{
+		CheckIntervalSeconds:       DefaultWatchdogCheckIntervalSeconds,
+		HeartbeatTimeoutMinutes:    DefaultWatchdogHeartbeatTimeoutMinutes,
+		DocumentWorkerTimeoutHours: DefaultWatchdogDocumentWorkerTimeoutHours,
+	}
+	var selfMonitor = SelfMonitorCfg{
+		CheckIntervalSeconds: DefaultSelfMonitorCheckIntervalSeconds,
+		MaxGoroutines:        DefaultSelfMonitorMaxGoroutines,
+		MaxOpenFiles:         DefaultSelfMonitorMaxOpenFiles,
+		MaxHeapMB:            DefaultSelfMonitorMaxHeapMB,
+		ConsecutiveChecks:    DefaultSelfMonitorConsecutiveChecks,
+	}
+	var resourceLimits = ResourceLimitsCfg{
+		LoadSheddingPercent: DefaultResourceLimitsLoadSheddingPercent,
+	}
+	var diskGuard = DiskGuardCfg{
+		ReserveMB: DefaultDiskGuardReserveMB,
+	}
+	var appArmor = AppArmorCfg{
+		Mode: AppArmorModeEnforce,
+	}
+	var privilegeSeparation = PrivilegeSeparationCfg{
+		User:       DefaultPrivilegeSeparationUser,
+		RunAsUsers: []string{DefaultRunAsUserName},
+	}
+	var powerShell = PowerShellCfg{
+		LanguageMode: PowerShellLanguageModeFull,
+	}
+	var ipc = IpcCfg{
+		Transport:              IpcTransportFile,
+		PipeSecurityDescriptor: DefaultPipeSecurityDescriptor,
+	}
+	var syslog = SyslogCfg{
+		Transport: SyslogTransportTLS,
+		Facility:  DefaultSyslogFacility,
+	}
+	var securityEvents = SecurityEventsCfg{
+		Transport: SecurityEventsTransportTCP,
+	}
+	var webhook = WebhookCfg{
+		Retry: RetryPolicyCfg{
+			MaxRetries:      DefaultRetryMaxRetries,
+			BaseDelayMillis: DefaultRetryBaseDelayMillis,
+			MaxDelayMillis:  DefaultWebhookMaxDelayMillis,
+		},
+		TimeoutSeconds: DefaultWebhookTimeoutSeconds,
+	}
+	var sessionHooks = SessionHooksCfg{
+		TimeoutSeconds: DefaultSessionHooksTimeoutSeconds,
+	}
+	var firehose = FirehoseCfg{
+		BatchSize: DefaultFirehoseBatchSize,
+	}
+	var outputFailover = OutputFailoverCfg{
+		CloudWatchTimeoutSeconds: DefaultOutputFailoverCloudWatchTimeoutSeconds,
+	}
+	var sshSession = SshSessionCfg{
+		Port: DefaultSshSessionPort,
+	}
+	var bootGate = BootGateCfg{
+		TimeoutSeconds: DefaultBootGateTimeoutSeconds,
+	}
+	var recovery = RecoveryCfg{
+		RestartDelaySeconds: DefaultRecoveryRestartDelaySeconds,
+		FailureThreshold:    DefaultRecoveryFailureThreshold,
+		ResetPeriodSeconds:  DefaultRecoveryResetPeriodSeconds,
+	}
 
 	var ssmagentCfg = SsmagentConfig{
-		Profile:     credsProfile,
-		Mds:         mds,
-		Ssm:         ssm,
-		Mgs:         mgs,
-		Agent:       agent,
-		Os:          os,
-		S3:          s3,
-		Birdwatcher: birdwatcher,
-		Kms:         kms,
+		Profile:        credsProfile,
+		Mds:            mds,
+		Ssm:            ssm,
+		Mgs:            mgs,
+		Agent:          agent,
+		Os:             os,
+		S3:             s3,
+		Birdwatcher:    birdwatcher,
+		Kms:            kms,
+		PackageCache:   packageCache,
+		PackageHooks:   packageHooks,
+		Failover:       failover,
+		Registration:   registration,
+		Identity:       identity,
+		Network:        network,
+		CircuitBreaker: circuitBreaker,
+		Retry:          retry,
+		Update:         update,
+		Metrics:        metrics,
+		Tracing:        tracing,
+		Audit:          audit,
+		CrashDump:      crashDump,
+		Watchdog:       watchdog,
+		SelfMonitor:    selfMonitor,
+		ResourceLimits: resourceLimits,
+		DiskGuard:      diskGuard,
+		AppArmor:       appArmor,
+
+		PrivilegeSeparation: privilegeSeparation,
+		PowerShell:          powerShell,
+		Ipc:                 ipc,
+		Recovery:            recovery,
+		Syslog:              syslog,
+		SecurityEvents:      securityEvents,
+		Webhook:             webhook,
+		SessionHooks:        sessionHooks,
+		Firehose:            firehose,
+		OutputFailover:      outputFailover,
+		BootGate:            bootGate,
+		SshSession:          sshSession,
 	}
 
 	return ssmagentCfg
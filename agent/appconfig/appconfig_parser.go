@@ -16,11 +16,27 @@
 package appconfig
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/times"
 )
 
-//func parser(config *T) {
+// logGroupNamePattern matches the valid CloudWatch Logs group names
+var logGroupNamePattern = regexp.MustCompile(`^[\.\-_/#A-Za-z0-9]{1,512}$`)
+
+// metricsNamespacePattern matches the valid CloudWatch namespaces, the AWS/ namespaces are reserved for AWS services
+var metricsNamespacePattern = regexp.MustCompile(`^[\.\-_/#:A-Za-z0-9]{1,255}$`)
+
+// func parser(config *T) {
 func parser(config *SsmagentConfig) {
 	log.Printf("processing appconfig overrides")
 
@@ -28,6 +44,29 @@ func parser(config *SsmagentConfig) {
 	config.Agent.Name = getStringValue(config.Agent.Name, DefaultAgentName)
 	config.Agent.OrchestrationRootDir = getStringValue(config.Agent.OrchestrationRootDir, defaultOrchestrationRootDirName)
 	config.Agent.Region = getStringValue(config.Agent.Region, "")
+	if _, known := KnownPartition(config.Agent.Partition); config.Agent.Partition != "" && !known {
+		log.Printf("unknown partition %v, detecting the partition of the region", config.Agent.Partition)
+		config.Agent.Partition = ""
+	}
+	if config.Agent.DebugLogMinutes < 0 {
+		log.Printf("ignoring negative debug log duration %v", config.Agent.DebugLogMinutes)
+		config.Agent.DebugLogMinutes = DefaultDebugLogMinutes
+	}
+	if config.Agent.DrainTimeoutSeconds < 0 {
+		log.Printf("ignoring negative drain timeout %v", config.Agent.DrainTimeoutSeconds)
+		config.Agent.DrainTimeoutSeconds = DefaultDrainTimeoutSeconds
+	}
+	config.Agent.LogLevel = strings.ToLower(strings.TrimSpace(config.Agent.LogLevel))
+	if config.Agent.LogLevel != "" && !isLogLevel(config.Agent.LogLevel) {
+		log.Printf("ignoring unknown log level %v, using the level of the seelog configuration", config.Agent.LogLevel)
+		config.Agent.LogLevel = ""
+	}
+
+	// Credential profile config
+	config.Profile.CredentialProcessTimeoutSeconds = getNumericValueAboveMin(
+		config.Profile.CredentialProcessTimeoutSeconds,
+		DefaultCredentialProcessTimeoutSecondsMin,
+		DefaultCredentialProcessTimeoutSeconds)
 
 	// MDS config
 	config.Mds.CommandWorkersLimit = getNumericValue(
@@ -45,10 +84,27 @@ func parser(config *SsmagentConfig) {
 		DefaultStopTimeoutMillisMin,
 		DefaultStopTimeoutMillisMax,
 		DefaultStopTimeoutMillis)
-	config.Mds.Endpoint = getStringValue(config.Mds.Endpoint, "")
+	config.Mds.PollBackoffMinMillis = getNumeric64Value(
+		config.Mds.PollBackoffMinMillis,
+		DefaultPollBackoffMinMillisMin,
+		DefaultPollBackoffMinMillisMax,
+		DefaultPollBackoffMinMillis)
+	config.Mds.PollBackoffMaxMillis = getNumeric64Value(
+		config.Mds.PollBackoffMaxMillis,
+		config.Mds.PollBackoffMinMillis,
+		DefaultPollBackoffMaxMillisMax,
+		DefaultPollBackoffMaxMillis)
+	if config.Mds.ReplyCompressionMinBytes != 0 {
+		config.Mds.ReplyCompressionMinBytes = getNumericValue(
+			config.Mds.ReplyCompressionMinBytes,
+			DefaultReplyCompressionMinBytesMin,
+			DefaultReplyCompressionMinBytesMax,
+			DefaultReplyCompressionMinBytes)
+	}
+	config.Mds.Endpoint = getEndpointValue("Mds", config.Mds.Endpoint)
 
 	// SSM config
-	config.Ssm.Endpoint = getStringValue(config.Ssm.Endpoint, "")
+	config.Ssm.Endpoint = getEndpointValue("Ssm", config.Ssm.Endpoint)
 	config.Ssm.HealthFrequencyMinutes = getNumericValue(
 		config.Ssm.HealthFrequencyMinutes,
 		DefaultSsmHealthFrequencyMinutesMin,
@@ -67,21 +123,750 @@ func parser(config *SsmagentConfig) {
 		config.Ssm.RunCommandLogsRetentionDurationHours,
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
+	config.Ssm.ParallelPackageActionsLimit = getNumericValueAboveMin(
+		config.Ssm.ParallelPackageActionsLimit,
+		DefaultParallelPackageActionsLimitMin,
+		DefaultParallelPackageActionsLimit)
+
+	// Package cache config
+	config.PackageCache.MaxVersionsPerPackage = getNumericValueAboveMin(
+		config.PackageCache.MaxVersionsPerPackage,
+		DefaultPackageCacheMaxVersionsPerPackageMin,
+		DefaultPackageCacheMaxVersionsPerPackage)
+	config.PackageCache.MaxSizeMB = getNumericValueAboveMin(
+		config.PackageCache.MaxSizeMB,
+		DefaultPackageCacheMaxSizeMBMin,
+		DefaultPackageCacheMaxSizeMB)
+	config.PackageCache.MaxAgeDays = getNumericValueAboveMin(
+		config.PackageCache.MaxAgeDays,
+		DefaultPackageCacheMaxAgeDaysMin,
+		DefaultPackageCacheMaxAgeDays)
+	config.PackageHooks.TimeoutSeconds = getNumericValueAboveMin(
+		config.PackageHooks.TimeoutSeconds,
+		DefaultPackageHooksTimeoutSecondsMin,
+		DefaultPackageHooksTimeoutSeconds)
+
+	// Other service endpoints
+	config.Mgs.Endpoint = getEndpointValue("Mgs", config.Mgs.Endpoint)
+	config.S3.Endpoint = getEndpointValue("S3", config.S3.Endpoint)
+	config.S3.UploadPartSizeMB = getNumericValue(
+		config.S3.UploadPartSizeMB,
+		DefaultS3UploadPartSizeMBMin,
+		DefaultS3UploadPartSizeMBMax,
+		DefaultS3UploadPartSizeMB)
+	config.S3.UploadConcurrency = getNumericValue(
+		config.S3.UploadConcurrency,
+		DefaultS3UploadConcurrencyMin,
+		DefaultS3UploadConcurrencyMax,
+		DefaultS3UploadConcurrency)
+	config.Kms.Endpoint = getEndpointValue("Kms", config.Kms.Endpoint)
+	config.Logs.Endpoint = getEndpointValue("Logs", config.Logs.Endpoint)
+	if config.Logs.AgentLogGroup != "" && !logGroupNamePattern.MatchString(config.Logs.AgentLogGroup) {
+		log.Printf("ignoring invalid agent log group %v, the agent logs are not shipped to CloudWatch Logs", config.Logs.AgentLogGroup)
+		config.Logs.AgentLogGroup = ""
+	}
+	if config.Logs.LogGroupRetentionDays != 0 && !isLogGroupRetentionDays(config.Logs.LogGroupRetentionDays) {
+		log.Printf("ignoring invalid log group retention %v days, the created log groups keep their events forever, valid retentions are %v",
+			config.Logs.LogGroupRetentionDays, LogGroupRetentionDays)
+		config.Logs.LogGroupRetentionDays = 0
+	}
+
+	// Failover config
+	config.Failover.FailureThreshold = getNumericValueAboveMin(
+		config.Failover.FailureThreshold,
+		DefaultFailoverFailureThresholdMin,
+		DefaultFailoverFailureThreshold)
+	config.Failover.FailbackMinutes = getNumericValueAboveMin(
+		config.Failover.FailbackMinutes,
+		DefaultFailoverFailbackMinutesMin,
+		DefaultFailoverFailbackMinutes)
+	var failoverTargets []FailoverTarget
+	for _, target := range config.Failover.Targets {
+		if target.Region == "" {
+			log.Printf("ignoring failover target without region")
+			continue
+		}
+		target.MdsEndpoint = getEndpointValue("Failover Mds", target.MdsEndpoint)
+		target.MgsEndpoint = getEndpointValue("Failover Mgs", target.MgsEndpoint)
+		failoverTargets = append(failoverTargets, target)
+	}
+	config.Failover.Targets = failoverTargets
+
+	// Registration config
+	switch config.Registration.KeyProtection {
+	case KeyProtectionNone, KeyProtectionTPM, KeyProtectionKeystore, KeyProtectionDpapiNg:
+	default:
+		if config.Registration.KeyProtection != "" {
+			log.Printf("unknown registration key protection %v, storing the key without protection", config.Registration.KeyProtection)
+		}
+		config.Registration.KeyProtection = KeyProtectionNone
+	}
+
+	// Identity config
+	var identityProviders []string
+	for _, provider := range config.Identity.Providers {
+		if provider == "" || stringInSlice(provider, identityProviders) {
+			continue
+		}
+		identityProviders = append(identityProviders, provider)
+	}
+	if len(identityProviders) == 0 {
+		identityProviders = DefaultIdentityProviders()
+	}
+	config.Identity.Providers = identityProviders
+
+	// Instance metadata config
+	switch config.InstanceMetadata.EndpointMode {
+	case MetadataEndpointModeIPv4, MetadataEndpointModeIPv6:
+	default:
+		if config.InstanceMetadata.EndpointMode != "" {
+			log.Printf("unknown instance metadata endpoint mode %v, selecting the endpoint from the instance addresses", config.InstanceMetadata.EndpointMode)
+		}
+		config.InstanceMetadata.EndpointMode = ""
+	}
+
+	// Control channel transport config, gRPC is only available through a gateway
+	config.Mgs.GrpcGateway = getEndpointValue("Mgs gRPC gateway", config.Mgs.GrpcGateway)
+	switch config.Mgs.ControlChannelTransport {
+	case ControlChannelTransportWebSocket:
+	case ControlChannelTransportGrpc:
+		if config.Mgs.GrpcGateway == "" {
+			log.Printf("no gRPC gateway configured, using the websocket control channel transport")
+			config.Mgs.ControlChannelTransport = ControlChannelTransportWebSocket
+		}
+	default:
+		if config.Mgs.ControlChannelTransport != "" {
+			log.Printf("unknown control channel transport %v, using the websocket control channel transport", config.Mgs.ControlChannelTransport)
+		}
+		config.Mgs.ControlChannelTransport = ControlChannelTransportWebSocket
+	}
+
+	// Session output flow control config, a negative window uses the default
+	config.Mgs.MaxOutstandingOutputBytes = getNumericValueAboveMin(config.Mgs.MaxOutstandingOutputBytes, 0, DefaultMaxOutstandingOutputBytes)
+	if config.Mgs.MaxOutstandingOutputBytes > 0 && config.Mgs.MaxOutstandingOutputBytes < MaxOutstandingOutputBytesMin {
+		log.Printf("raising the session output window %v bytes to the minimum of %v bytes", config.Mgs.MaxOutstandingOutputBytes, MaxOutstandingOutputBytesMin)
+		config.Mgs.MaxOutstandingOutputBytes = MaxOutstandingOutputBytesMin
+	}
+
+	// Session scrollback config, a negative or too large scrollback uses the default
+	config.Mgs.ScrollbackBytes = getNumericValue(config.Mgs.ScrollbackBytes, 0, ScrollbackBytesMax, DefaultScrollbackBytes)
+
+	// Session retry config
+	config.Mgs.SessionRetryLimit = getNumericValue(
+		config.Mgs.SessionRetryLimit,
+		DefaultSessionRetryLimitMin,
+		DefaultSessionRetryLimitMax,
+		DefaultSessionRetryLimit)
+
+	// Session token elevation config, an unknown elevation selects the filtered token
+	switch config.Mgs.TokenElevation {
+	case "", TokenElevationFull, TokenElevationLimited:
+	default:
+		log.Printf("unknown session token elevation %v, using the limited token", config.Mgs.TokenElevation)
+		config.Mgs.TokenElevation = TokenElevationLimited
+	}
+
+	// Bandwidth config, negative caps disable the cap
+	bandwidth := &config.Network.Bandwidth
+	bandwidth.UploadBytesPerSecond = getNumeric64Value(bandwidth.UploadBytesPerSecond, 0, math.MaxInt64, 0)
+	bandwidth.DownloadBytesPerSecond = getNumeric64Value(bandwidth.DownloadBytesPerSecond, 0, math.MaxInt64, 0)
+	bandwidth.SessionBytesPerSecond = getNumeric64Value(bandwidth.SessionBytesPerSecond, 0, math.MaxInt64, 0)
+
+	// Circuit breaker config
+	config.CircuitBreaker.FailureThreshold = getNumericValueAboveMin(
+		config.CircuitBreaker.FailureThreshold,
+		DefaultCircuitBreakerFailureThresholdMin,
+		DefaultCircuitBreakerFailureThreshold)
+	config.CircuitBreaker.CooldownSeconds = getNumericValueAboveMin(
+		config.CircuitBreaker.CooldownSeconds,
+		DefaultCircuitBreakerCooldownSecondsMin,
+		DefaultCircuitBreakerCooldownSeconds)
+	config.CircuitBreaker.MaxCooldownSeconds = getNumericValueAboveMin(
+		config.CircuitBreaker.MaxCooldownSeconds,
+		config.CircuitBreaker.CooldownSeconds,
+		DefaultCircuitBreakerMaxCooldownSeconds)
+
+	// Retry config
+	config.Retry.MaxRetries = getNumericValue(
+		config.Retry.MaxRetries,
+		DefaultRetryMaxRetriesMin,
+		DefaultRetryMaxRetriesMax,
+		DefaultRetryMaxRetries)
+	config.Retry.BaseDelayMillis = getNumericValue(
+		config.Retry.BaseDelayMillis,
+		DefaultRetryBaseDelayMillisMin,
+		DefaultRetryBaseDelayMillisMax,
+		DefaultRetryBaseDelayMillis)
+	config.Retry.MaxDelayMillis = getNumericValue(
+		config.Retry.MaxDelayMillis,
+		config.Retry.BaseDelayMillis,
+		DefaultRetryMaxDelayMillisMax,
+		DefaultRetryMaxDelayMillis)
+	for service, policy := range config.Retry.Services {
+		// invalid overrides inherit the policy
+		policy.MaxRetries = getNumericValue(policy.MaxRetries, 0, DefaultRetryMaxRetriesMax, 0)
+		policy.BaseDelayMillis = getNumericValue(policy.BaseDelayMillis, DefaultRetryBaseDelayMillisMin, DefaultRetryBaseDelayMillisMax, 0)
+		policy.MaxDelayMillis = getNumericValue(policy.MaxDelayMillis, DefaultRetryBaseDelayMillisMin, DefaultRetryMaxDelayMillisMax, 0)
+		config.Retry.Services[service] = policy
+	}
+
+	// DNS config
+	var resolvers []string
+	for _, resolver := range config.Network.Dns.Resolvers {
+		if address, err := getResolverAddress(resolver); err == nil {
+			resolvers = append(resolvers, address)
+		} else {
+			log.Printf("ignoring invalid DNS resolver %v: %v", resolver, err)
+		}
+	}
+	config.Network.Dns.Resolvers = resolvers
+	for host, addresses := range config.Network.Dns.BootstrapHosts {
+		var ips []string
+		for _, address := range addresses {
+			if net.ParseIP(address) != nil {
+				ips = append(ips, address)
+			} else {
+				log.Printf("ignoring invalid bootstrap address %v of host %v", address, host)
+			}
+		}
+		config.Network.Dns.BootstrapHosts[host] = ips
+	}
+	config.Network.Dns.CacheSeconds = getNumericValueAboveMin(
+		config.Network.Dns.CacheSeconds,
+		DefaultDnsCacheSecondsMin,
+		DefaultDnsCacheSeconds)
+
+	// Update config
+	var windows []string
+	for _, window := range config.Update.Windows {
+		if _, err := times.ParseWindow(window); err == nil {
+			windows = append(windows, window)
+		} else {
+			log.Printf("ignoring invalid update window: %v", err)
+		}
+	}
+	config.Update.Windows = windows
+	config.Update.Source = getUpdateSourceValue(config.Update.Source)
+	switch config.Update.Channel {
+	case UpdateChannelStable, UpdateChannelCandidate:
+	default:
+		if config.Update.Channel != "" {
+			log.Printf("unknown update channel %v, using the stable channel", config.Update.Channel)
+		}
+		config.Update.Channel = UpdateChannelStable
+	}
+	config.Update.Hooks.TimeoutSeconds = getNumericValueAboveMin(
+		config.Update.Hooks.TimeoutSeconds,
+		DefaultUpdateHooksTimeoutSecondsMin,
+		DefaultUpdateHooksTimeoutSeconds)
+
+	// Metrics config
+	if config.Metrics.Port < 0 || config.Metrics.Port > MaxMetricsPort {
+		log.Printf("ignoring invalid metrics port %v, the metrics endpoint is disabled", config.Metrics.Port)
+		config.Metrics.Port = 0
+	}
+	if config.Metrics.CloudWatchNamespace != "" &&
+		(!metricsNamespacePattern.MatchString(config.Metrics.CloudWatchNamespace) || strings.HasPrefix(config.Metrics.CloudWatchNamespace, "AWS/")) {
+		log.Printf("ignoring invalid CloudWatch namespace %v, the metrics are not published to CloudWatch", config.Metrics.CloudWatchNamespace)
+		config.Metrics.CloudWatchNamespace = ""
+	}
+	config.Metrics.PublishIntervalSeconds = getNumericValueAboveMin(
+		config.Metrics.PublishIntervalSeconds,
+		DefaultMetricsPublishIntervalSecondsMin,
+		DefaultMetricsPublishIntervalSeconds)
+	switch config.Metrics.Sink {
+	case MetricsSinkCloudWatch, MetricsSinkFile, MetricsSinkStatsd:
+	default:
+		if config.Metrics.Sink != "" {
+			log.Printf("unknown metrics sink %v, using the cloudwatch sink", config.Metrics.Sink)
+		}
+		config.Metrics.Sink = MetricsSinkCloudWatch
+	}
+	if config.Metrics.FilePath != "" && !filepath.IsAbs(config.Metrics.FilePath) {
+		log.Printf("ignoring relative metrics file path %v, the metrics are written to the log directory", config.Metrics.FilePath)
+		config.Metrics.FilePath = ""
+	}
+	if _, _, err := net.SplitHostPort(config.Metrics.StatsdAddress); err != nil {
+		if config.Metrics.StatsdAddress != "" {
+			log.Printf("ignoring invalid StatsD address %v, it must be host:port", config.Metrics.StatsdAddress)
+		}
+		config.Metrics.StatsdAddress = DefaultMetricsStatsdAddress
+	}
+
+	// Health endpoint config
+	if config.HealthEndpoint.Port < 0 || config.HealthEndpoint.Port > MaxHealthEndpointPort {
+		log.Printf("ignoring invalid health endpoint port %v, the health is not served on a port", config.HealthEndpoint.Port)
+		config.HealthEndpoint.Port = 0
+	}
+
+	// Tracing config
+	config.Tracing.Endpoint = getTracingEndpointValue(config.Tracing.Endpoint)
+
+	// Audit config
+	config.Audit.MaxSizeMB = getNumericValueAboveMin(
+		config.Audit.MaxSizeMB,
+		DefaultAuditMaxSizeMBMin,
+		DefaultAuditMaxSizeMB)
+	config.Audit.MaxRolls = getNumericValueAboveMin(
+		config.Audit.MaxRolls,
+		0,
+		DefaultAuditMaxRolls)
+	config.Audit.RetentionDays = getNumericValueAboveMin(
+		config.Audit.RetentionDays,
+		0,
+		DefaultAuditRetentionDays)
+	if config.Audit.LogGroup != "" && !logGroupNamePattern.MatchString(config.Audit.LogGroup) {
+		log.Printf("ignoring invalid audit log group %v, the audit events are not forwarded to CloudWatch Logs", config.Audit.LogGroup)
+		config.Audit.LogGroup = ""
+	}
+
+	// Crash dump config
+	config.CrashDump.MaxDumps = getNumericValueAboveMin(
+		config.CrashDump.MaxDumps,
+		0,
+		DefaultCrashDumpMaxDumps)
+	config.CrashDump.RetentionDays = getNumericValueAboveMin(
+		config.CrashDump.RetentionDays,
+		0,
+		DefaultCrashDumpRetentionDays)
+	config.CrashDump.S3KeyPrefix = strings.Trim(config.CrashDump.S3KeyPrefix, "/")
+
+	// Watchdog config
+	config.Watchdog.CheckIntervalSeconds = getNumericValueAboveMin(
+		config.Watchdog.CheckIntervalSeconds,
+		DefaultWatchdogCheckIntervalSecondsMin,
+		DefaultWatchdogCheckIntervalSeconds)
+	config.Watchdog.HeartbeatTimeoutMinutes = getNumericValueAboveMin(
+		config.Watchdog.HeartbeatTimeoutMinutes,
+		DefaultWatchdogHeartbeatTimeoutMinutesMin,
+		DefaultWatchdogHeartbeatTimeoutMinutes)
+	config.Watchdog.DocumentWorkerTimeoutHours = getNumericValueAboveMin(
+		config.Watchdog.DocumentWorkerTimeoutHours,
+		DefaultWatchdogDocumentWorkerTimeoutHoursMin,
+		DefaultWatchdogDocumentWorkerTimeoutHours)
+	config.Watchdog.SessionWorkerTimeoutHours = getNumericValueAboveMin(
+		config.Watchdog.SessionWorkerTimeoutHours,
+		0,
+		0)
+
+	// Self-monitor config
+	config.SelfMonitor.CheckIntervalSeconds = getNumericValueAboveMin(
+		config.SelfMonitor.CheckIntervalSeconds,
+		DefaultSelfMonitorCheckIntervalSecondsMin,
+		DefaultSelfMonitorCheckIntervalSeconds)
+	config.SelfMonitor.MaxGoroutines = getNumericValueAboveMin(config.SelfMonitor.MaxGoroutines, 0, 0)
+	config.SelfMonitor.MaxOpenFiles = getNumericValueAboveMin(config.SelfMonitor.MaxOpenFiles, 0, 0)
+	config.SelfMonitor.MaxHeapMB = getNumericValueAboveMin(config.SelfMonitor.MaxHeapMB, 0, 0)
+	config.SelfMonitor.ConsecutiveChecks = getNumericValueAboveMin(
+		config.SelfMonitor.ConsecutiveChecks,
+		DefaultSelfMonitorConsecutiveChecksMin,
+		DefaultSelfMonitorConsecutiveChecks)
+
+	// Resource limits config
+	config.ResourceLimits.CPUPercent = getNumericValueAboveMin(config.ResourceLimits.CPUPercent, 0, 0)
+	config.ResourceLimits.MemoryMB = getNumericValueAboveMin(config.ResourceLimits.MemoryMB, 0, 0)
+	if config.ResourceLimits.MemoryMB > 0 && config.ResourceLimits.MemoryMB < ResourceLimitsMemoryMBMin {
+		log.Printf("raising the memory limit %v MB to the minimum of %v MB", config.ResourceLimits.MemoryMB, ResourceLimitsMemoryMBMin)
+		config.ResourceLimits.MemoryMB = ResourceLimitsMemoryMBMin
+	}
+	config.ResourceLimits.LoadSheddingPercent = getNumericValue(
+		config.ResourceLimits.LoadSheddingPercent,
+		DefaultResourceLimitsLoadSheddingPercentMin,
+		DefaultResourceLimitsLoadSheddingPercentMax,
+		DefaultResourceLimitsLoadSheddingPercent)
+
+	// Disk guard config
+	config.DiskGuard.ReserveMB = getNumericValueAboveMin(config.DiskGuard.ReserveMB, 0, DefaultDiskGuardReserveMB)
+
+	// Config overrides config
+	if path := config.ConfigOverrides.ParameterStorePath; path != "" && !strings.HasPrefix(path, "/") {
+		log.Printf("ignoring Parameter Store path %v, it must start with /", path)
+		config.ConfigOverrides.ParameterStorePath = ""
+	}
+
+	// Simulation config, the message delivery and systems manager calls go to the mock service
+	config.Simulation.Endpoint = getSimulationEndpointValue(config.Simulation.Endpoint)
+	if config.Simulation.Endpoint != "" {
+		config.Simulation.InstanceID = getStringValue(config.Simulation.InstanceID, DefaultSimulationInstanceID)
+		config.Agent.Region = getStringValue(config.Agent.Region, DefaultSimulationRegion)
+		config.Mds.Endpoint = config.Simulation.Endpoint
+		config.Ssm.Endpoint = config.Simulation.Endpoint
+	}
+
+	// AppArmor config
+	switch config.AppArmor.Mode {
+	case AppArmorModeEnforce, AppArmorModeComplain:
+	default:
+		if config.AppArmor.Mode != "" {
+			log.Printf("unknown AppArmor mode %v, using the enforce mode", config.AppArmor.Mode)
+		}
+		config.AppArmor.Mode = AppArmorModeEnforce
+	}
+
+	// PowerShell config, an unknown language mode selects the constrained one rather than lifting the restriction
+	switch config.PowerShell.LanguageMode {
+	case PowerShellLanguageModeFull, PowerShellLanguageModeConstrained:
+	case "":
+		config.PowerShell.LanguageMode = PowerShellLanguageModeFull
+	default:
+		log.Printf("unknown PowerShell language mode %v, using the constrained language", config.PowerShell.LanguageMode)
+		config.PowerShell.LanguageMode = PowerShellLanguageModeConstrained
+	}
+	if config.PowerShell.ExecutionPolicy != "" && !stringInSlice(config.PowerShell.ExecutionPolicy, PowerShellExecutionPolicies) {
+		log.Printf("unknown PowerShell execution policy %v, keeping the default policies", config.PowerShell.ExecutionPolicy)
+		config.PowerShell.ExecutionPolicy = ""
+	}
+
+	// Ipc config, the named pipes are only available on Windows
+	switch config.Ipc.Transport {
+	case IpcTransportFile:
+	case IpcTransportNamedPipe:
+		if runtime.GOOS != "windows" {
+			log.Printf("the named pipes are only available on Windows, using the file channels")
+			config.Ipc.Transport = IpcTransportFile
+		}
+	default:
+		if config.Ipc.Transport != "" {
+			log.Printf("unknown IPC transport %v, using the file channels", config.Ipc.Transport)
+		}
+		config.Ipc.Transport = IpcTransportFile
+	}
+	config.Ipc.PipeSecurityDescriptor = getStringValue(config.Ipc.PipeSecurityDescriptor, DefaultPipeSecurityDescriptor)
+
+	// Recovery config
+	config.Recovery.RestartDelaySeconds = getNumericValue(
+		config.Recovery.RestartDelaySeconds,
+		DefaultRecoveryRestartDelaySecondsMin,
+		DefaultRecoveryRestartDelaySecondsMax,
+		DefaultRecoveryRestartDelaySeconds)
+	config.Recovery.FailureThreshold = getNumericValueAboveMin(
+		config.Recovery.FailureThreshold,
+		DefaultRecoveryFailureThresholdMin,
+		DefaultRecoveryFailureThreshold)
+	config.Recovery.ResetPeriodSeconds = getNumericValueAboveMin(
+		config.Recovery.ResetPeriodSeconds,
+		DefaultRecoveryResetPeriodSecondsMin,
+		DefaultRecoveryResetPeriodSeconds)
+
+	// Syslog config, an unknown transport selects TLS rather than sending the events in clear
+	switch config.Syslog.Transport {
+	case SyslogTransportTLS, SyslogTransportTCP:
+	default:
+		if config.Syslog.Transport != "" {
+			log.Printf("unknown syslog transport %v, using TLS", config.Syslog.Transport)
+		}
+		config.Syslog.Transport = SyslogTransportTLS
+	}
+	config.Syslog.Facility = getNumericValue(
+		config.Syslog.Facility,
+		0,
+		MaxSyslogFacility,
+		DefaultSyslogFacility)
+
+	// SecurityEvents config, an unknown format turns the events off
+	switch config.SecurityEvents.Format {
+	case "", SecurityEventsFormatCEF, SecurityEventsFormatLEEF:
+	default:
+		log.Printf("unknown security events format %v, the security events are not written", config.SecurityEvents.Format)
+		config.SecurityEvents.Format = ""
+	}
+	switch config.SecurityEvents.Transport {
+	case SecurityEventsTransportTCP, SecurityEventsTransportUDP:
+	default:
+		if config.SecurityEvents.Transport != "" {
+			log.Printf("unknown security events transport %v, using tcp", config.SecurityEvents.Transport)
+		}
+		config.SecurityEvents.Transport = SecurityEventsTransportTCP
+	}
+
+	// Webhook config, the auth header is only sent over https
+	if config.Webhook.Url != "" {
+		if parsed, err := url.Parse(config.Webhook.Url); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			log.Printf("ignoring invalid webhook url %v, it must be an https url", config.Webhook.Url)
+			config.Webhook.Url = ""
+		}
+	}
+	config.Webhook.Retry.MaxRetries = getNumericValue(
+		config.Webhook.Retry.MaxRetries,
+		DefaultRetryMaxRetriesMin,
+		DefaultRetryMaxRetriesMax,
+		DefaultRetryMaxRetries)
+	config.Webhook.Retry.BaseDelayMillis = getNumericValue(
+		config.Webhook.Retry.BaseDelayMillis,
+		DefaultRetryBaseDelayMillisMin,
+		DefaultRetryBaseDelayMillisMax,
+		DefaultRetryBaseDelayMillis)
+	config.Webhook.Retry.MaxDelayMillis = getNumericValue(
+		config.Webhook.Retry.MaxDelayMillis,
+		config.Webhook.Retry.BaseDelayMillis,
+		DefaultRetryMaxDelayMillisMax,
+		DefaultWebhookMaxDelayMillis)
+	config.Webhook.TimeoutSeconds = getNumericValueAboveMin(
+		config.Webhook.TimeoutSeconds,
+		DefaultWebhookTimeoutSecondsMin,
+		DefaultWebhookTimeoutSeconds)
+
+	// SessionHooks config
+	config.SessionHooks.TimeoutSeconds = getNumericValueAboveMin(
+		config.SessionHooks.TimeoutSeconds,
+		DefaultSessionHooksTimeoutSecondsMin,
+		DefaultSessionHooksTimeoutSeconds)
+
+	// Firehose config
+	config.Firehose.Endpoint = getEndpointValue("Firehose", config.Firehose.Endpoint)
+	config.Firehose.BatchSize = getNumericValue(
+		config.Firehose.BatchSize,
+		DefaultFirehoseBatchSizeMin,
+		DefaultFirehoseBatchSizeMax,
+		DefaultFirehoseBatchSize)
 
+	// OutputFailover config
+	config.OutputFailover.CloudWatchTimeoutSeconds = getNumericValueAboveMin(
+		config.OutputFailover.CloudWatchTimeoutSeconds,
+		DefaultOutputFailoverCloudWatchTimeoutSecondsMin,
+		DefaultOutputFailoverCloudWatchTimeoutSeconds)
+
+	// LocalApi config, the port is only served with a token
+	if config.LocalApi.Port < 0 || config.LocalApi.Port > MaxLocalApiPort {
+		log.Printf("ignoring invalid local API port %v, the local API is not served on a port", config.LocalApi.Port)
+		config.LocalApi.Port = 0
+	} else if config.LocalApi.Port != 0 && config.LocalApi.TokenFile == "" {
+		log.Printf("ignoring local API port %v without a token file, the local API is not served on a port", config.LocalApi.Port)
+		config.LocalApi.Port = 0
+	}
+
+	// BootGate config
+	config.BootGate.TimeoutSeconds = getNumericValueAboveMin(
+		config.BootGate.TimeoutSeconds,
+		DefaultBootGateTimeoutSecondsMin,
+		DefaultBootGateTimeoutSeconds)
+
+	// Profiling config, the port is only served with a token
+	if config.Profiling.Port < 0 || config.Profiling.Port > MaxProfilingPort {
+		log.Printf("ignoring invalid profiling port %v, the profiles are not served on a port", config.Profiling.Port)
+		config.Profiling.Port = 0
+	} else if config.Profiling.Port != 0 && config.Profiling.TokenFile == "" {
+		log.Printf("ignoring profiling port %v without a token file, the profiles are not served on a port", config.Profiling.Port)
+		config.Profiling.Port = 0
+	}
+
+	// SshSession config
+	config.SshSession.Port = getNumericValue(
+		config.SshSession.Port,
+		1,
+		MaxSshSessionPort,
+		DefaultSshSessionPort)
+
+	// PrivilegeSeparation config
+	config.PrivilegeSeparation.User = getStringValue(config.PrivilegeSeparation.User, DefaultPrivilegeSeparationUser)
+	if len(config.PrivilegeSeparation.RunAsUsers) == 0 {
+		config.PrivilegeSeparation.RunAsUsers = []string{DefaultRunAsUserName}
+	}
+
+	// Telemetry config, the opt-out turns off every telemetry setting: the metrics are neither served
+	// nor published, the spans are not exported and the crash dumps are only kept locally
+	if config.Telemetry.OptOut {
+		config.Metrics.Port = 0
+		config.Metrics.Sink = MetricsSinkCloudWatch
+		config.Metrics.CloudWatchNamespace = ""
+		config.Tracing.Enabled = false
+		config.CrashDump.S3BucketName = ""
+	}
+}
+
+// getTracingEndpointValue validates the OTLP/HTTP traces url, the default endpoint is used when it is invalid
+func getTracingEndpointValue(endpoint string) string {
+	if parsed, err := url.Parse(endpoint); err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "" {
+		return endpoint
+	}
+	if endpoint != "" {
+		log.Printf("ignoring invalid tracing endpoint %v, it must be an http(s) url", endpoint)
+	}
+	return DefaultTracingEndpoint
 }
 
-// getDefaultEndPoint returns the default endpoint for a service, it should be empty unless it's a china region
+// getSimulationEndpointValue validates the mock service url, the simulation mode is off when it is invalid
+func getSimulationEndpointValue(endpoint string) string {
+	if endpoint == "" {
+		return ""
+	}
+	if parsed, err := url.Parse(endpoint); err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "" {
+		return endpoint
+	}
+	log.Printf("ignoring invalid simulation endpoint %v, it must be an http(s) url", endpoint)
+	return ""
+}
+
+// getUpdateSourceValue validates the update source, an http(s) url or an absolute local path
+func getUpdateSourceValue(source string) string {
+	if source == "" {
+		return ""
+	}
+	if parsed, err := url.Parse(source); err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "" {
+		return source
+	}
+	if filepath.IsAbs(source) {
+		return source
+	}
+	log.Printf("ignoring invalid update source %v, it must be an http(s) url or an absolute path", source)
+	return ""
+}
+
+// isLogGroupRetentionDays returns true for the retention periods CloudWatch Logs accepts
+func isLogGroupRetentionDays(days int) bool {
+	for _, retention := range LogGroupRetentionDays {
+		if days == retention {
+			return true
+		}
+	}
+	return false
+}
+
+// getResolverAddress returns the ip:port address of a DNS resolver configured as ip or ip:port
+func getResolverAddress(resolver string) (string, error) {
+	if ip := net.ParseIP(resolver); ip != nil {
+		return net.JoinHostPort(resolver, DefaultDnsPort), nil
+	}
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("%v is not an IP address", host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// getDefaultEndPoint returns the default endpoint for a service, it should be empty unless the region is not in
+// the aws partition, dual-stack endpoints are configured or the service is STS, which is always called regionally
 func GetDefaultEndPoint(region string, service string) string {
-	endpoint := ""
+	if useDualStackEndpoints() {
+		if endpoint := GetDualStackEndPoint(region, service); endpoint != "" {
+			return endpoint
+		}
+	}
 
-	parts := strings.Split(region, "-")
-	if len(parts) > 1 && parts[0] == "cn" {
-		endpoint = service + "." + region + ".amazonaws.com.cn"
+	if region == "" || service == "" {
+		return ""
 	}
+	if service == stsServiceName || GetPartition(region).ID != PartitionAws {
+		return GetRegionalEndpoint(region, service)
+	}
+	return ""
+}
+
+// GetDualStackEndPoint returns the endpoint of a service that can be reached over both IPv4 and IPv6,
+// empty when the partition of the region has no dual-stack endpoints
+func GetDualStackEndPoint(region string, service string) string {
+	if region == "" || service == "" {
+		return ""
+	}
+
+	partition := GetPartition(region)
+	if partition.DualStackDnsSuffix == "" {
+		return ""
+	}
+	if service == s3ServiceName {
+		return dualStackS3Prefix + region + "." + partition.DnsSuffix
+	}
+	return service + "." + region + "." + partition.DualStackDnsSuffix
+}
+
+// UseDualStackEndpoints returns true if the agent is configured to use dual-stack endpoints
+func UseDualStackEndpoints() bool {
+	return useDualStackEndpoints()
+}
+
+var useDualStackEndpoints = func() bool {
+	config, err := Config(false)
+	return err == nil && config.Network.UseDualStackEndpoints
+}
+
+// getEndpointValue returns the endpoint if it is valid, else empty so the default endpoint is used
+func getEndpointValue(section string, endpoint string) string {
+	endpoint = formatEndpoint(endpoint)
+	if endpoint == "" {
+		return ""
+	}
+	if err := validateEndpoint(endpoint); err != nil {
+		log.Printf("ignoring invalid %v endpoint %v, the default endpoint is used: %v", section, endpoint, err)
+		return ""
+	}
+	return endpoint
+}
+
+// validateEndpoint checks that an endpoint is a host name or address with an optional protocol and port
+func validateEndpoint(endpoint string) error {
+	endpointURL := endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpointURL = "https://" + endpoint
+	}
+	parsed, err := url.Parse(endpointURL)
+	if err != nil {
+		return err
+	}
+	switch parsed.Scheme {
+	case "https", "http", "wss":
+	default:
+		return fmt.Errorf("unsupported protocol %v", parsed.Scheme)
+	}
+	if parsed.Path != "" && parsed.Path != "/" || parsed.RawQuery != "" || parsed.User != nil {
+		return errors.New("only a host and port are allowed")
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return errors.New("missing host")
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("invalid host name %v", host)
+		}
+		for _, c := range label {
+			if !(c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+				return fmt.Errorf("invalid host name %v", host)
+			}
+		}
+	}
+	return nil
+}
 
+// formatEndpoint encloses IPv6 literal addresses in brackets so the endpoint can be used in a url
+func formatEndpoint(endpoint string) string {
+	endpoint = strings.TrimSpace(endpoint)
+	host := strings.TrimSuffix(endpoint, "/")
+	for _, prefix := range []string{"https://", "http://", "wss://"} {
+		if strings.HasPrefix(host, prefix) {
+			if ip := net.ParseIP(host[len(prefix):]); ip != nil && ip.To4() == nil {
+				return prefix + "[" + ip.String() + "]"
+			}
+			return endpoint
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "[" + ip.String() + "]"
+	}
 	return endpoint
 }
 
+// DefaultIdentityProviders returns the identity providers used when none is configured,
+// the on-premises registration takes precedence over the EC2 instance metadata
+func DefaultIdentityProviders() []string {
+	return []string{IdentityProviderOnPrem, IdentityProviderEC2}
+}
+
+// stringInSlice returns true when the value is in the slice
+func stringInSlice(value string, slice []string) bool {
+	for _, item := range slice {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // getStringValue returns the default value if config is empty, else the config value
 func getStringValue(configValue string, defaultValue string) string {
 	if configValue == "" {
@@ -90,6 +875,16 @@ func getStringValue(configValue string, defaultValue string) string {
 	return configValue
 }
 
+// isLogLevel returns true if the level is one of the seelog log levels
+func isLogLevel(level string) bool {
+	for _, logLevel := range LogLevels {
+		if level == logLevel {
+			return true
+		}
+	}
+	return false
+}
+
 // getNumericValueAboveMin returns the default if config is below minimum
 func getNumericValueAboveMin(configValue int, minValue int, defaultValue int) int {
 	if configValue < minValue {
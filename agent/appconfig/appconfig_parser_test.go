@@ -14,6 +14,8 @@
 package appconfig
 
 import (
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -55,6 +57,10 @@ var (
 		{"val", "test", ""},
 		{"us-east-1", "ssm", ""},
 		{"cn-north-1", "ssm", "ssm.cn-north-1.amazonaws.com.cn"},
+		{"us-gov-west-1", "ssm", "ssm.us-gov-west-1.amazonaws.com"},
+		{"us-iso-east-1", "ssm", "ssm.us-iso-east-1.c2s.ic.gov"},
+		{"us-isob-east-1", "ssm", "ssm.us-isob-east-1.sc2s.sgov.gov"},
+		{"us-east-1", "sts", "sts.us-east-1.amazonaws.com"},
 	}
 )
 
@@ -65,6 +71,535 @@ func TestGetDefaultEndPoint(t *testing.T) {
 	}
 }
 
+func TestGetDefaultEndPoint_DualStack(t *testing.T) {
+	useDualStackEndpointsOrig := useDualStackEndpoints
+	defer func() { useDualStackEndpoints = useDualStackEndpointsOrig }()
+	useDualStackEndpoints = func() bool { return true }
+
+	assert.Equal(t, "ssm.us-east-1.api.aws", GetDefaultEndPoint("us-east-1", "ssm"))
+	assert.Equal(t, "ec2messages.cn-north-1.api.amazonwebservices.com.cn", GetDefaultEndPoint("cn-north-1", "ec2messages"))
+	assert.Equal(t, "s3.dualstack.us-west-2.amazonaws.com", GetDefaultEndPoint("us-west-2", "s3"))
+	assert.Equal(t, "s3.dualstack.cn-northwest-1.amazonaws.com.cn", GetDefaultEndPoint("cn-northwest-1", "s3"))
+	assert.Equal(t, "", GetDefaultEndPoint("", "ssm"))
+}
+
+func TestFormatEndpoint(t *testing.T) {
+	assert.Equal(t, "", formatEndpoint(""))
+	assert.Equal(t, "ssm.us-east-1.amazonaws.com", formatEndpoint(" ssm.us-east-1.amazonaws.com "))
+	assert.Equal(t, "10.0.0.1", formatEndpoint("10.0.0.1"))
+	assert.Equal(t, "[2001:db8::1]", formatEndpoint("2001:db8::1"))
+	assert.Equal(t, "https://[2001:db8::1]", formatEndpoint("https://2001:db8::1/"))
+	assert.Equal(t, "https://[2001:db8::1]:443", formatEndpoint("https://[2001:db8::1]:443"))
+}
+
+func TestGetEndpointValue(t *testing.T) {
+	for _, valid := range []string{
+		"ssm.us-east-1.amazonaws.com",
+		"https://vpce-0123-abcd.ssm.us-east-1.vpce.amazonaws.com",
+		"https://vpce-0123-abcd.ssm.us-east-1.vpce.amazonaws.com/",
+		"ec2messages.internal:8443",
+		"wss://ssmmessages.us-east-1.amazonaws.com",
+		"http://10.0.0.1",
+		"[2001:db8::1]:443",
+	} {
+		assert.Equal(t, valid, getEndpointValue("Ssm", valid), valid)
+	}
+	for _, invalid := range []string{
+		"ftp://ssm.us-east-1.amazonaws.com",
+		"https://ssm.us-east-1.amazonaws.com/path",
+		"https://ssm.us-east-1.amazonaws.com?query=1",
+		"ssm..amazonaws.com",
+		"ssm_us-east-1.amazonaws.com",
+		"-ssm.amazonaws.com",
+		"https://",
+	} {
+		assert.Equal(t, "", getEndpointValue("Ssm", invalid), invalid)
+	}
+}
+
+func TestParserFailover(t *testing.T) {
+	config := DefaultConfig()
+	config.Failover = FailoverCfg{
+		Targets: []FailoverTarget{
+			{Region: "us-west-2", MdsEndpoint: "https://ec2messages.us-west-2.amazonaws.com", MgsEndpoint: "ftp://invalid"},
+			{MdsEndpoint: "ec2messages.eu-west-1.amazonaws.com"},
+		},
+		FailureThreshold: 0,
+		FailbackMinutes:  -1,
+	}
+	parser(&config)
+
+	assert.Equal(t, []FailoverTarget{{Region: "us-west-2", MdsEndpoint: "https://ec2messages.us-west-2.amazonaws.com"}}, config.Failover.Targets)
+	assert.Equal(t, DefaultFailoverFailureThreshold, config.Failover.FailureThreshold)
+	assert.Equal(t, DefaultFailoverFailbackMinutes, config.Failover.FailbackMinutes)
+}
+
+func TestParserIdentity(t *testing.T) {
+	config := DefaultConfig()
+	config.Identity.Providers = []string{"Custom", "", IdentityProviderEC2, "Custom"}
+	parser(&config)
+	assert.Equal(t, []string{"Custom", IdentityProviderEC2}, config.Identity.Providers)
+
+	config.Identity.Providers = []string{}
+	parser(&config)
+	assert.Equal(t, DefaultIdentityProviders(), config.Identity.Providers)
+}
+
+func TestParserPollBackoff(t *testing.T) {
+	config := DefaultConfig()
+	config.Mds.PollBackoffMinMillis = 5000
+	config.Mds.PollBackoffMaxMillis = 1000
+	parser(&config)
+	assert.Equal(t, int64(5000), config.Mds.PollBackoffMinMillis)
+	assert.Equal(t, int64(DefaultPollBackoffMaxMillis), config.Mds.PollBackoffMaxMillis)
+
+	config.Mds.PollBackoffMinMillis = 0
+	config.Mds.PollBackoffMaxMillis = 60000
+	parser(&config)
+	assert.Equal(t, int64(DefaultPollBackoffMinMillis), config.Mds.PollBackoffMinMillis)
+	assert.Equal(t, int64(60000), config.Mds.PollBackoffMaxMillis)
+}
+
+func TestParserReplyCompression(t *testing.T) {
+	config := DefaultConfig()
+	parser(&config)
+	assert.Equal(t, 0, config.Mds.ReplyCompressionMinBytes)
+
+	config.Mds.ReplyCompressionMinBytes = 8192
+	parser(&config)
+	assert.Equal(t, 8192, config.Mds.ReplyCompressionMinBytes)
+
+	config.Mds.ReplyCompressionMinBytes = 10
+	parser(&config)
+	assert.Equal(t, DefaultReplyCompressionMinBytes, config.Mds.ReplyCompressionMinBytes)
+}
+
+func TestParserBandwidth(t *testing.T) {
+	config := DefaultConfig()
+	config.Network.Bandwidth = BandwidthCfg{UploadBytesPerSecond: 1024, DownloadBytesPerSecond: -1}
+	parser(&config)
+
+	assert.Equal(t, BandwidthCfg{UploadBytesPerSecond: 1024}, config.Network.Bandwidth)
+}
+
+func TestParserDns(t *testing.T) {
+	config := DefaultConfig()
+	config.Network.Dns = DnsCfg{
+		Resolvers:      []string{"10.0.0.2", "10.0.0.3:5353", "[fd00::2]:53", "resolver.example.com", "fd00::3"},
+		BootstrapHosts: map[string][]string{"ssmmessages.us-east-1.amazonaws.com": {"52.46.0.1", "invalid"}},
+		CacheSeconds:   -1,
+	}
+	parser(&config)
+
+	assert.Equal(t, []string{"10.0.0.2:53", "10.0.0.3:5353", "[fd00::2]:53", "[fd00::3]:53"}, config.Network.Dns.Resolvers)
+	assert.Equal(t, []string{"52.46.0.1"}, config.Network.Dns.BootstrapHosts["ssmmessages.us-east-1.amazonaws.com"])
+	assert.Equal(t, DefaultDnsCacheSeconds, config.Network.Dns.CacheSeconds)
+}
+
+func TestParserCircuitBreaker(t *testing.T) {
+	config := DefaultConfig()
+	config.CircuitBreaker = CircuitBreakerCfg{FailureThreshold: 0, CooldownSeconds: 120, MaxCooldownSeconds: 60}
+	parser(&config)
+
+	assert.Equal(t, DefaultCircuitBreakerFailureThreshold, config.CircuitBreaker.FailureThreshold)
+	assert.Equal(t, 120, config.CircuitBreaker.CooldownSeconds)
+	assert.Equal(t, DefaultCircuitBreakerMaxCooldownSeconds, config.CircuitBreaker.MaxCooldownSeconds)
+}
+
+func TestParserRetry(t *testing.T) {
+	config := DefaultConfig()
+	config.Retry.MaxRetries = 50
+	config.Retry.BaseDelayMillis = 2000
+	config.Retry.MaxDelayMillis = 1000
+	config.Retry.Services = map[string]RetryPolicyCfg{
+		"ssm": {MaxRetries: 6, BaseDelayMillis: 5},
+	}
+	parser(&config)
+
+	assert.Equal(t, DefaultRetryMaxRetries, config.Retry.MaxRetries)
+	assert.Equal(t, 2000, config.Retry.BaseDelayMillis)
+	assert.Equal(t, DefaultRetryMaxDelayMillis, config.Retry.MaxDelayMillis)
+	assert.Equal(t, RetryPolicyCfg{MaxRetries: 6}, config.Retry.Services["ssm"])
+}
+
+func TestParserControlChannelTransport(t *testing.T) {
+	config := DefaultConfig()
+	config.Mgs.ControlChannelTransport = ControlChannelTransportGrpc
+	config.Mgs.GrpcGateway = "http://gateway.example.com:8443"
+	parser(&config)
+	assert.Equal(t, ControlChannelTransportGrpc, config.Mgs.ControlChannelTransport)
+	assert.Equal(t, "http://gateway.example.com:8443", config.Mgs.GrpcGateway)
+
+	config = DefaultConfig()
+	config.Mgs.ControlChannelTransport = ControlChannelTransportGrpc
+	parser(&config)
+	assert.Equal(t, ControlChannelTransportWebSocket, config.Mgs.ControlChannelTransport)
+
+	config = DefaultConfig()
+	config.Mgs.ControlChannelTransport = "quic"
+	parser(&config)
+	assert.Equal(t, ControlChannelTransportWebSocket, config.Mgs.ControlChannelTransport)
+}
+
+func TestParserUpdateWindows(t *testing.T) {
+	config := DefaultConfig()
+	config.Update.Windows = []string{"Mon-Fri 22:00-04:00", "Someday 10:00-11:00", "Sat,Sun 00:00-00:00"}
+	parser(&config)
+	assert.Equal(t, []string{"Mon-Fri 22:00-04:00", "Sat,Sun 00:00-00:00"}, config.Update.Windows)
+}
+
+func TestParserUpdateChannel(t *testing.T) {
+	for channel, expected := range map[string]string{
+		"":                     UpdateChannelStable,
+		UpdateChannelStable:    UpdateChannelStable,
+		UpdateChannelCandidate: UpdateChannelCandidate,
+		"beta":                 UpdateChannelStable,
+	} {
+		config := DefaultConfig()
+		config.Update.Channel = channel
+		parser(&config)
+		assert.Equal(t, expected, config.Update.Channel, channel)
+	}
+}
+
+func TestParserUpdateHooksTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.Update.Hooks.TimeoutSeconds = 0
+	parser(&config)
+	assert.Equal(t, DefaultUpdateHooksTimeoutSeconds, config.Update.Hooks.TimeoutSeconds)
+
+	config.Update.Hooks.TimeoutSeconds = 30
+	parser(&config)
+	assert.Equal(t, 30, config.Update.Hooks.TimeoutSeconds)
+}
+
+func TestParserMetricsPort(t *testing.T) {
+	for port, expected := range map[int]int{0: 0, 9779: 9779, -1: 0, 70000: 0} {
+		config := DefaultConfig()
+		config.Metrics.Port = port
+		parser(&config)
+		assert.Equal(t, expected, config.Metrics.Port, port)
+	}
+}
+
+func TestParserTracingEndpoint(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"":                                     DefaultTracingEndpoint,
+		"http://collector:4318/v1/traces":      "http://collector:4318/v1/traces",
+		"https://collector.example.com/traces": "https://collector.example.com/traces",
+		"collector:4318":                       DefaultTracingEndpoint,
+		"ftp://collector/traces":               DefaultTracingEndpoint,
+	} {
+		config := DefaultConfig()
+		config.Tracing.Endpoint = endpoint
+		parser(&config)
+		assert.Equal(t, expected, config.Tracing.Endpoint, endpoint)
+	}
+}
+
+func TestParserAgentLogGroup(t *testing.T) {
+	for logGroup, expected := range map[string]string{
+		"":                         "",
+		"/aws/ssm/agent-logs":      "/aws/ssm/agent-logs",
+		"SSMAgentLogs":             "SSMAgentLogs",
+		"agent logs":               "",
+		"agent\"/><custom name=\"": "",
+	} {
+		config := DefaultConfig()
+		config.Logs.AgentLogGroup = logGroup
+		parser(&config)
+		assert.Equal(t, expected, config.Logs.AgentLogGroup, logGroup)
+	}
+}
+
+func TestParserDebugLogMinutes(t *testing.T) {
+	for minutes, expected := range map[int]int{0: 0, 15: 15, -1: DefaultDebugLogMinutes} {
+		config := DefaultConfig()
+		config.Agent.DebugLogMinutes = minutes
+		parser(&config)
+		assert.Equal(t, expected, config.Agent.DebugLogMinutes, minutes)
+	}
+}
+
+func TestParserDrainTimeoutSeconds(t *testing.T) {
+	for seconds, expected := range map[int]int{0: 0, 60: 60, -1: DefaultDrainTimeoutSeconds} {
+		config := DefaultConfig()
+		config.Agent.DrainTimeoutSeconds = seconds
+		parser(&config)
+		assert.Equal(t, expected, config.Agent.DrainTimeoutSeconds, seconds)
+	}
+}
+
+func TestParserAudit(t *testing.T) {
+	config := DefaultConfig()
+	config.Audit.MaxSizeMB = 0
+	config.Audit.MaxRolls = -1
+	config.Audit.RetentionDays = -1
+	config.Audit.LogGroup = "audit logs"
+	parser(&config)
+	assert.Equal(t, DefaultAuditMaxSizeMB, config.Audit.MaxSizeMB)
+	assert.Equal(t, DefaultAuditMaxRolls, config.Audit.MaxRolls)
+	assert.Equal(t, DefaultAuditRetentionDays, config.Audit.RetentionDays)
+	assert.Equal(t, "", config.Audit.LogGroup)
+
+	config.Audit.MaxSizeMB = 50
+	config.Audit.MaxRolls = 0
+	config.Audit.RetentionDays = 0
+	config.Audit.LogGroup = "/aws/ssm/audit"
+	parser(&config)
+	assert.Equal(t, 50, config.Audit.MaxSizeMB)
+	assert.Equal(t, 0, config.Audit.MaxRolls)
+	assert.Equal(t, 0, config.Audit.RetentionDays)
+	assert.Equal(t, "/aws/ssm/audit", config.Audit.LogGroup)
+}
+
+func TestParserCrashDump(t *testing.T) {
+	config := DefaultConfig()
+	config.CrashDump.MaxDumps = -1
+	config.CrashDump.RetentionDays = -1
+	config.CrashDump.S3KeyPrefix = "/ssm/crashdumps/"
+	parser(&config)
+	assert.Equal(t, DefaultCrashDumpMaxDumps, config.CrashDump.MaxDumps)
+	assert.Equal(t, DefaultCrashDumpRetentionDays, config.CrashDump.RetentionDays)
+	assert.Equal(t, "ssm/crashdumps", config.CrashDump.S3KeyPrefix)
+}
+
+func TestParserWatchdog(t *testing.T) {
+	config := DefaultConfig()
+	config.Watchdog.CheckIntervalSeconds = 1
+	config.Watchdog.HeartbeatTimeoutMinutes = 5
+	config.Watchdog.DocumentWorkerTimeoutHours = 0
+	config.Watchdog.SessionWorkerTimeoutHours = -1
+	parser(&config)
+	assert.Equal(t, DefaultWatchdogCheckIntervalSeconds, config.Watchdog.CheckIntervalSeconds)
+	assert.Equal(t, DefaultWatchdogHeartbeatTimeoutMinutes, config.Watchdog.HeartbeatTimeoutMinutes)
+	assert.Equal(t, DefaultWatchdogDocumentWorkerTimeoutHours, config.Watchdog.DocumentWorkerTimeoutHours)
+	assert.Equal(t, 0, config.Watchdog.SessionWorkerTimeoutHours)
+
+	config.Watchdog.DocumentWorkerTimeoutHours = 2
+	config.Watchdog.SessionWorkerTimeoutHours = 12
+	parser(&config)
+	assert.Equal(t, 2, config.Watchdog.DocumentWorkerTimeoutHours)
+	assert.Equal(t, 12, config.Watchdog.SessionWorkerTimeoutHours)
+}
+
+func TestParserMaxOutstandingOutputBytes(t *testing.T) {
+	config := DefaultConfig()
+	config.Mgs.MaxOutstandingOutputBytes = -1
+	parser(&config)
+	assert.Equal(t, DefaultMaxOutstandingOutputBytes, config.Mgs.MaxOutstandingOutputBytes)
+
+	config.Mgs.MaxOutstandingOutputBytes = 1024
+	parser(&config)
+	assert.Equal(t, MaxOutstandingOutputBytesMin, config.Mgs.MaxOutstandingOutputBytes)
+
+	config.Mgs.MaxOutstandingOutputBytes = 0
+	parser(&config)
+	assert.Equal(t, 0, config.Mgs.MaxOutstandingOutputBytes)
+}
+
+func TestParserScrollbackBytes(t *testing.T) {
+	config := DefaultConfig()
+	config.Mgs.ScrollbackBytes = -1
+	parser(&config)
+	assert.Equal(t, DefaultScrollbackBytes, config.Mgs.ScrollbackBytes)
+
+	config.Mgs.ScrollbackBytes = ScrollbackBytesMax + 1
+	parser(&config)
+	assert.Equal(t, DefaultScrollbackBytes, config.Mgs.ScrollbackBytes)
+
+	config.Mgs.ScrollbackBytes = 0
+	parser(&config)
+	assert.Equal(t, 0, config.Mgs.ScrollbackBytes)
+}
+
+func TestParserLogLevel(t *testing.T) {
+	config := DefaultConfig()
+	config.Agent.LogLevel = " Debug "
+	parser(&config)
+	assert.Equal(t, "debug", config.Agent.LogLevel)
+
+	config.Agent.LogLevel = "verbose"
+	parser(&config)
+	assert.Equal(t, "", config.Agent.LogLevel)
+}
+
+func TestParserSelfMonitor(t *testing.T) {
+	config := DefaultConfig()
+	config.SelfMonitor.CheckIntervalSeconds = 5
+	config.SelfMonitor.MaxGoroutines = -1
+	config.SelfMonitor.MaxOpenFiles = 0
+	config.SelfMonitor.MaxHeapMB = 256
+	config.SelfMonitor.ConsecutiveChecks = 0
+	parser(&config)
+	assert.Equal(t, DefaultSelfMonitorCheckIntervalSeconds, config.SelfMonitor.CheckIntervalSeconds)
+	assert.Equal(t, 0, config.SelfMonitor.MaxGoroutines)
+	assert.Equal(t, 0, config.SelfMonitor.MaxOpenFiles)
+	assert.Equal(t, 256, config.SelfMonitor.MaxHeapMB)
+	assert.Equal(t, DefaultSelfMonitorConsecutiveChecks, config.SelfMonitor.ConsecutiveChecks)
+}
+
+func TestParserResourceLimits(t *testing.T) {
+	config := DefaultConfig()
+	config.ResourceLimits.CPUPercent = -10
+	config.ResourceLimits.MemoryMB = 32
+	config.ResourceLimits.LoadSheddingPercent = 150
+	parser(&config)
+	assert.Equal(t, 0, config.ResourceLimits.CPUPercent)
+	assert.Equal(t, ResourceLimitsMemoryMBMin, config.ResourceLimits.MemoryMB)
+	assert.Equal(t, DefaultResourceLimitsLoadSheddingPercent, config.ResourceLimits.LoadSheddingPercent)
+
+	config.ResourceLimits.CPUPercent = 50
+	config.ResourceLimits.MemoryMB = 512
+	config.ResourceLimits.LoadSheddingPercent = 90
+	parser(&config)
+	assert.Equal(t, 50, config.ResourceLimits.CPUPercent)
+	assert.Equal(t, 512, config.ResourceLimits.MemoryMB)
+	assert.Equal(t, 90, config.ResourceLimits.LoadSheddingPercent)
+}
+
+func TestParserDiskGuard(t *testing.T) {
+	config := DefaultConfig()
+	config.DiskGuard.ReserveMB = -1
+	parser(&config)
+	assert.Equal(t, DefaultDiskGuardReserveMB, config.DiskGuard.ReserveMB)
+
+	config.DiskGuard.ReserveMB = 0
+	parser(&config)
+	assert.Equal(t, 0, config.DiskGuard.ReserveMB)
+}
+
+func TestParserHealthEndpoint(t *testing.T) {
+	config := DefaultConfig()
+	config.HealthEndpoint.Port = 70000
+	parser(&config)
+	assert.Equal(t, 0, config.HealthEndpoint.Port)
+
+	config.HealthEndpoint.Port = 9101
+	parser(&config)
+	assert.Equal(t, 9101, config.HealthEndpoint.Port)
+}
+
+func TestParserSshSessionPort(t *testing.T) {
+	config := DefaultConfig()
+	config.SshSession.Port = 0
+	parser(&config)
+	assert.Equal(t, DefaultSshSessionPort, config.SshSession.Port)
+
+	config.SshSession.Port = 2222
+	parser(&config)
+	assert.Equal(t, 2222, config.SshSession.Port)
+}
+
+func TestParserProfiling(t *testing.T) {
+	config := DefaultConfig()
+	config.Profiling.Port = -1
+	parser(&config)
+	assert.Equal(t, 0, config.Profiling.Port)
+
+	// the port is not served without a token
+	config.Profiling.Port = 6060
+	parser(&config)
+	assert.Equal(t, 0, config.Profiling.Port)
+
+	config.Profiling.Port = 6060
+	config.Profiling.TokenFile = "/etc/amazon/ssm/profiling.token"
+	parser(&config)
+	assert.Equal(t, 6060, config.Profiling.Port)
+}
+
+func TestParserMetricsCloudWatch(t *testing.T) {
+	for namespace, expected := range map[string]string{
+		"":                  "",
+		"SSMAgent/Fleet":    "SSMAgent/Fleet",
+		"AWS/SSMAgent":      "",
+		"invalid namespace": "",
+	} {
+		config := DefaultConfig()
+		config.Metrics.CloudWatchNamespace = namespace
+		parser(&config)
+		assert.Equal(t, expected, config.Metrics.CloudWatchNamespace, namespace)
+	}
+
+	config := DefaultConfig()
+	config.Metrics.PublishIntervalSeconds = 10
+	parser(&config)
+	assert.Equal(t, DefaultMetricsPublishIntervalSeconds, config.Metrics.PublishIntervalSeconds)
+
+	config.Metrics.PublishIntervalSeconds = 300
+	parser(&config)
+	assert.Equal(t, 300, config.Metrics.PublishIntervalSeconds)
+}
+
+func TestParserMetricsSink(t *testing.T) {
+	config := DefaultConfig()
+	assert.Equal(t, MetricsSinkCloudWatch, config.Metrics.Sink)
+
+	config.Metrics.Sink = "prometheus"
+	config.Metrics.FilePath = "metrics.log"
+	config.Metrics.StatsdAddress = "127.0.0.1"
+	parser(&config)
+	assert.Equal(t, MetricsSinkCloudWatch, config.Metrics.Sink)
+	assert.Equal(t, "", config.Metrics.FilePath)
+	assert.Equal(t, DefaultMetricsStatsdAddress, config.Metrics.StatsdAddress)
+
+	config.Metrics.Sink = MetricsSinkStatsd
+	config.Metrics.StatsdAddress = "statsd.local:9125"
+	parser(&config)
+	assert.Equal(t, MetricsSinkStatsd, config.Metrics.Sink)
+	assert.Equal(t, "statsd.local:9125", config.Metrics.StatsdAddress)
+}
+
+func TestParserTelemetryOptOut(t *testing.T) {
+	config := DefaultConfig()
+	config.Metrics.Port = 9100
+	config.Metrics.Sink = MetricsSinkFile
+	config.Metrics.CloudWatchNamespace = "SSMAgent"
+	config.Tracing.Enabled = true
+	config.CrashDump.S3BucketName = "crash-dumps"
+	config.Telemetry.OptOut = true
+	parser(&config)
+	assert.Equal(t, 0, config.Metrics.Port)
+	assert.Equal(t, MetricsSinkCloudWatch, config.Metrics.Sink)
+	assert.Equal(t, "", config.Metrics.CloudWatchNamespace)
+	assert.False(t, config.Tracing.Enabled)
+	assert.Equal(t, "", config.CrashDump.S3BucketName)
+}
+
+func TestParserUpdateSource(t *testing.T) {
+	for source, expected := range map[string]string{
+		"":                                      "",
+		"https://mirror.example.com/ssm-agent/": "https://mirror.example.com/ssm-agent/",
+		"http://10.0.0.5/ssm-agent":             "http://10.0.0.5/ssm-agent",
+		filepath.Join(string(filepath.Separator), "srv", "ssm-agent"): filepath.Join(string(filepath.Separator), "srv", "ssm-agent"),
+		"relative/path":                      "",
+		"ftp://mirror.example.com/ssm-agent": "",
+	} {
+		config := DefaultConfig()
+		config.Update.Source = source
+		parser(&config)
+		assert.Equal(t, expected, config.Update.Source, source)
+	}
+}
+
+func TestParserSimulation(t *testing.T) {
+	config := DefaultConfig()
+	config.Simulation.Endpoint = "http://127.0.0.1:8090"
+	parser(&config)
+	assert.Equal(t, "http://127.0.0.1:8090", config.Mds.Endpoint)
+	assert.Equal(t, "http://127.0.0.1:8090", config.Ssm.Endpoint)
+	assert.Equal(t, DefaultSimulationInstanceID, config.Simulation.InstanceID)
+	assert.Equal(t, DefaultSimulationRegion, config.Agent.Region)
+
+	config = DefaultConfig()
+	config.Simulation.Endpoint = "127.0.0.1:8090"
+	config.Simulation.InstanceID = "i-simulation"
+	parser(&config)
+	assert.Equal(t, "", config.Simulation.Endpoint)
+	assert.Equal(t, "", config.Mds.Endpoint)
+	assert.Equal(t, "", config.Agent.Region)
+}
+
 // getNumericValue Tests
 
 type GetNumericValueTest struct {
@@ -116,3 +651,246 @@ func TestGetNumeric64Value(t *testing.T) {
 		assert.Equal(t, test.Output, output)
 	}
 }
+
+func TestParserAppArmorMode(t *testing.T) {
+	config := DefaultConfig()
+	config.AppArmor.Mode = AppArmorModeComplain
+	parser(&config)
+	assert.Equal(t, AppArmorModeComplain, config.AppArmor.Mode)
+
+	config.AppArmor.Mode = "kill"
+	parser(&config)
+	assert.Equal(t, AppArmorModeEnforce, config.AppArmor.Mode)
+
+	config.AppArmor.Mode = ""
+	parser(&config)
+	assert.Equal(t, AppArmorModeEnforce, config.AppArmor.Mode)
+}
+
+func TestParserIpc(t *testing.T) {
+	config := DefaultConfig()
+	config.Ipc.Transport = "shm"
+	config.Ipc.PipeSecurityDescriptor = ""
+	parser(&config)
+	assert.Equal(t, IpcTransportFile, config.Ipc.Transport)
+	assert.Equal(t, DefaultPipeSecurityDescriptor, config.Ipc.PipeSecurityDescriptor)
+
+	config.Ipc.Transport = IpcTransportNamedPipe
+	parser(&config)
+	if runtime.GOOS == "windows" {
+		assert.Equal(t, IpcTransportNamedPipe, config.Ipc.Transport)
+	} else {
+		assert.Equal(t, IpcTransportFile, config.Ipc.Transport)
+	}
+}
+
+func TestParserTokenElevation(t *testing.T) {
+	config := DefaultConfig()
+	parser(&config)
+	assert.Equal(t, "", config.Mgs.TokenElevation)
+
+	config.Mgs.TokenElevation = TokenElevationFull
+	parser(&config)
+	assert.Equal(t, TokenElevationFull, config.Mgs.TokenElevation)
+
+	config.Mgs.TokenElevation = "Elevated"
+	parser(&config)
+	assert.Equal(t, TokenElevationLimited, config.Mgs.TokenElevation)
+}
+
+func TestParserPowerShell(t *testing.T) {
+	config := DefaultConfig()
+	config.PowerShell.LanguageMode = PowerShellLanguageModeConstrained
+	config.PowerShell.ExecutionPolicy = "AllSigned"
+	parser(&config)
+	assert.Equal(t, PowerShellLanguageModeConstrained, config.PowerShell.LanguageMode)
+	assert.Equal(t, "AllSigned", config.PowerShell.ExecutionPolicy)
+
+	config.PowerShell.LanguageMode = "NoLanguage"
+	config.PowerShell.ExecutionPolicy = "Everything"
+	parser(&config)
+	assert.Equal(t, PowerShellLanguageModeConstrained, config.PowerShell.LanguageMode)
+	assert.Equal(t, "", config.PowerShell.ExecutionPolicy)
+
+	config.PowerShell.LanguageMode = ""
+	parser(&config)
+	assert.Equal(t, PowerShellLanguageModeFull, config.PowerShell.LanguageMode)
+}
+
+func TestParserPrivilegeSeparationUser(t *testing.T) {
+	config := DefaultConfig()
+	config.PrivilegeSeparation.User = ""
+	parser(&config)
+	assert.Equal(t, DefaultPrivilegeSeparationUser, config.PrivilegeSeparation.User)
+
+	config.PrivilegeSeparation.User = "ssm-core"
+	parser(&config)
+	assert.Equal(t, "ssm-core", config.PrivilegeSeparation.User)
+}
+
+func TestParserRecovery(t *testing.T) {
+	config := DefaultConfig()
+	config.Recovery.RestartDelaySeconds = 0
+	config.Recovery.FailureThreshold = -1
+	config.Recovery.ResetPeriodSeconds = 10
+	parser(&config)
+	assert.Equal(t, DefaultRecoveryRestartDelaySeconds, config.Recovery.RestartDelaySeconds)
+	assert.Equal(t, DefaultRecoveryFailureThreshold, config.Recovery.FailureThreshold)
+	assert.Equal(t, DefaultRecoveryResetPeriodSeconds, config.Recovery.ResetPeriodSeconds)
+
+	config.Recovery.RestartDelaySeconds = 90
+	config.Recovery.FailureThreshold = 5
+	config.Recovery.ResetPeriodSeconds = 3600
+	parser(&config)
+	assert.Equal(t, 90, config.Recovery.RestartDelaySeconds)
+	assert.Equal(t, 5, config.Recovery.FailureThreshold)
+	assert.Equal(t, 3600, config.Recovery.ResetPeriodSeconds)
+}
+
+func TestParserKeyProtection(t *testing.T) {
+	config := DefaultConfig()
+	config.Registration.KeyProtection = KeyProtectionDpapiNg
+	parser(&config)
+	assert.Equal(t, KeyProtectionDpapiNg, config.Registration.KeyProtection)
+
+	config.Registration.KeyProtection = "HSM"
+	parser(&config)
+	assert.Equal(t, KeyProtectionNone, config.Registration.KeyProtection)
+}
+
+func TestParserSyslog(t *testing.T) {
+	config := DefaultConfig()
+	config.Syslog.Transport = "udp"
+	config.Syslog.Facility = 24
+	parser(&config)
+	assert.Equal(t, SyslogTransportTLS, config.Syslog.Transport)
+	assert.Equal(t, DefaultSyslogFacility, config.Syslog.Facility)
+
+	config.Syslog.Transport = SyslogTransportTCP
+	config.Syslog.Facility = 0
+	parser(&config)
+	assert.Equal(t, SyslogTransportTCP, config.Syslog.Transport)
+	assert.Equal(t, 0, config.Syslog.Facility)
+}
+
+func TestParserSecurityEvents(t *testing.T) {
+	config := DefaultConfig()
+	config.SecurityEvents.Format = "JSON"
+	config.SecurityEvents.Transport = "sctp"
+	parser(&config)
+	assert.Equal(t, "", config.SecurityEvents.Format)
+	assert.Equal(t, SecurityEventsTransportTCP, config.SecurityEvents.Transport)
+
+	config.SecurityEvents.Format = SecurityEventsFormatLEEF
+	config.SecurityEvents.Transport = SecurityEventsTransportUDP
+	parser(&config)
+	assert.Equal(t, SecurityEventsFormatLEEF, config.SecurityEvents.Format)
+	assert.Equal(t, SecurityEventsTransportUDP, config.SecurityEvents.Transport)
+}
+
+func TestParserWebhook(t *testing.T) {
+	config := DefaultConfig()
+	config.Webhook.Url = "http://hooks.example.com/ssm"
+	config.Webhook.Retry = RetryPolicyCfg{MaxRetries: 100, BaseDelayMillis: 1, MaxDelayMillis: 0}
+	config.Webhook.TimeoutSeconds = 0
+	parser(&config)
+	assert.Equal(t, "", config.Webhook.Url)
+	assert.Equal(t, DefaultRetryMaxRetries, config.Webhook.Retry.MaxRetries)
+	assert.Equal(t, DefaultRetryBaseDelayMillis, config.Webhook.Retry.BaseDelayMillis)
+	assert.Equal(t, DefaultWebhookMaxDelayMillis, config.Webhook.Retry.MaxDelayMillis)
+	assert.Equal(t, DefaultWebhookTimeoutSeconds, config.Webhook.TimeoutSeconds)
+
+	config.Webhook.Url = "https://hooks.example.com/ssm"
+	parser(&config)
+	assert.Equal(t, "https://hooks.example.com/ssm", config.Webhook.Url)
+}
+
+func TestParserSessionHooksTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.SessionHooks.TimeoutSeconds = 0
+	parser(&config)
+	assert.Equal(t, DefaultSessionHooksTimeoutSeconds, config.SessionHooks.TimeoutSeconds)
+
+	config.SessionHooks.TimeoutSeconds = 5
+	parser(&config)
+	assert.Equal(t, 5, config.SessionHooks.TimeoutSeconds)
+}
+
+func TestParserFirehose(t *testing.T) {
+	config := DefaultConfig()
+	config.Firehose.BatchSize = 1000
+	config.Firehose.Endpoint = "ftp://firehose.example.com"
+	parser(&config)
+	assert.Equal(t, DefaultFirehoseBatchSize, config.Firehose.BatchSize)
+	assert.Equal(t, "", config.Firehose.Endpoint)
+
+	config.Firehose.BatchSize = 100
+	config.Firehose.Endpoint = "firehose.us-east-1.amazonaws.com"
+	parser(&config)
+	assert.Equal(t, 100, config.Firehose.BatchSize)
+	assert.Equal(t, "firehose.us-east-1.amazonaws.com", config.Firehose.Endpoint)
+}
+
+func TestParserS3Upload(t *testing.T) {
+	config := DefaultConfig()
+	config.S3.UploadPartSizeMB = 1
+	config.S3.UploadConcurrency = 0
+	parser(&config)
+	assert.Equal(t, DefaultS3UploadPartSizeMB, config.S3.UploadPartSizeMB)
+	assert.Equal(t, DefaultS3UploadConcurrency, config.S3.UploadConcurrency)
+
+	config.S3.UploadPartSizeMB = 64
+	config.S3.UploadConcurrency = 16
+	parser(&config)
+	assert.Equal(t, 64, config.S3.UploadPartSizeMB)
+	assert.Equal(t, 16, config.S3.UploadConcurrency)
+}
+
+func TestParserLogGroupRetentionDays(t *testing.T) {
+	config := DefaultConfig()
+	config.Logs.LogGroupRetentionDays = 10
+	parser(&config)
+	assert.Equal(t, 0, config.Logs.LogGroupRetentionDays)
+
+	config.Logs.LogGroupRetentionDays = 30
+	parser(&config)
+	assert.Equal(t, 30, config.Logs.LogGroupRetentionDays)
+}
+
+func TestParserOutputFailoverTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.OutputFailover.CloudWatchTimeoutSeconds = 1
+	parser(&config)
+	assert.Equal(t, DefaultOutputFailoverCloudWatchTimeoutSeconds, config.OutputFailover.CloudWatchTimeoutSeconds)
+
+	config.OutputFailover.CloudWatchTimeoutSeconds = 300
+	parser(&config)
+	assert.Equal(t, 300, config.OutputFailover.CloudWatchTimeoutSeconds)
+}
+
+func TestParserLocalApiPort(t *testing.T) {
+	config := DefaultConfig()
+	config.LocalApi.Port = 8080
+	parser(&config)
+	assert.Equal(t, 0, config.LocalApi.Port)
+
+	config.LocalApi.Port = 70000
+	config.LocalApi.TokenFile = "/etc/amazon/ssm/localapi.token"
+	parser(&config)
+	assert.Equal(t, 0, config.LocalApi.Port)
+
+	config.LocalApi.Port = 8080
+	parser(&config)
+	assert.Equal(t, 8080, config.LocalApi.Port)
+}
+
+func TestParserBootGateTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.BootGate.TimeoutSeconds = 5
+	parser(&config)
+	assert.Equal(t, DefaultBootGateTimeoutSeconds, config.BootGate.TimeoutSeconds)
+
+	config.BootGate.TimeoutSeconds = 1800
+	parser(&config)
+	assert.Equal(t, 1800, config.BootGate.TimeoutSeconds)
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+)
+
+// configFormat returns the format of a config file from its extension: json, yaml or toml
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	return "json"
+}
+
+// unmarshalConfigFile reads a config file in JSON, YAML or TOML into the configuration, the YAML and TOML
+// files are converted to JSON first so their keys and values have the same semantics as in a JSON file
+func unmarshalConfigFile(path string, config *SsmagentConfig) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if content, err = configJSON(configFormat(path), content); err != nil {
+		return err
+	}
+	return json.Unmarshal(content, config)
+}
+
+// configJSON converts the content of a config file in the format to JSON
+func configJSON(format string, content []byte) ([]byte, error) {
+	var document interface{}
+	switch format {
+	case "yaml":
+		var yamlDocument interface{}
+		if err := yaml.Unmarshal(content, &yamlDocument); err != nil {
+			return nil, err
+		}
+		var err error
+		if document, err = yamlToJSONValue(yamlDocument); err != nil {
+			return nil, err
+		}
+	case "toml":
+		var err error
+		if document, err = parseToml(string(content)); err != nil {
+			return nil, err
+		}
+	default:
+		return content, nil
+	}
+	return json.Marshal(document)
+}
+
+// yamlToJSONValue replaces the maps of a YAML document by maps with string keys, which JSON can encode
+func yamlToJSONValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted, err := yamlToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			object[fmt.Sprint(key)] = converted
+		}
+		return object, nil
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := yamlToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = converted
+		}
+		return array, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJsonConfig = `{
+    "Agent": {"Region": "us-west-2", "LogLevel": "debug", "AllowedPlugins": ["aws:runShellScript", "aws:runPowerShellScript"]},
+    "Mds": {"CommandWorkersLimit": 10, "StopTimeoutMillis": 30000},
+    "S3": {"ForcePathStyle": true},
+    "Failover": {"Targets": [{"Region": "us-east-1"}, {"Region": "eu-west-1", "MdsEndpoint": "ec2messages.eu-west-1.amazonaws.com"}]},
+    "Retry": {"Services": {"ssm": {"MaxRetries": 5}}}
+}`
+
+const testYamlConfig = `
+# the config file in YAML
+Agent:
+  Region: us-west-2
+  LogLevel: debug
+  AllowedPlugins:
+    - aws:runShellScript
+    - aws:runPowerShellScript
+Mds:
+  CommandWorkersLimit: 10
+  StopTimeoutMillis: 30000
+S3:
+  ForcePathStyle: true
+Failover:
+  Targets:
+    - Region: us-east-1
+    - Region: eu-west-1
+      MdsEndpoint: ec2messages.eu-west-1.amazonaws.com
+Retry:
+  Services:
+    ssm: {MaxRetries: 5}
+`
+
+const testTomlConfig = `
+# the config file in TOML
+[Agent]
+Region = "us-west-2"
+LogLevel = 'debug'
+AllowedPlugins = [
+    "aws:runShellScript",
+    "aws:runPowerShellScript", # trailing comma
+]
+
+[Mds]
+CommandWorkersLimit = 10
+StopTimeoutMillis = 30_000
+
+[S3]
+ForcePathStyle = true
+
+[[Failover.Targets]]
+Region = "us-east-1"
+
+[[Failover.Targets]]
+Region = "eu-west-1"
+MdsEndpoint = "ec2messages.eu-west-1.amazonaws.com"
+
+[Retry]
+Services.ssm = { MaxRetries = 5 }
+`
+
+// readTestConfig reads the config file content written with the file name
+func readTestConfig(t *testing.T, fileName string, content string) SsmagentConfig {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, fileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	config := DefaultConfig()
+	assert.NoError(t, unmarshalConfigFile(path, &config))
+	parser(&config)
+	return config
+}
+
+func TestUnmarshalConfigFile_Formats(t *testing.T) {
+	expected := readTestConfig(t, AppConfigFileName, testJsonConfig)
+	assert.Equal(t, 10, expected.Mds.CommandWorkersLimit)
+	assert.Len(t, expected.Failover.Targets, 2)
+
+	assert.Equal(t, expected, readTestConfig(t, AppConfigYamlFileName, testYamlConfig))
+	assert.Equal(t, expected, readTestConfig(t, AppConfigTomlFileName, testTomlConfig))
+}
+
+func TestParseToml_Errors(t *testing.T) {
+	for content, message := range map[string]string{
+		"[Agent]\nRegion = \"us-west-2\"\nRegion = \"us-east-1\"": "line 3: duplicate key Region",
+		"[Agent]\n[Agent]":                      "line 2: table Agent is defined twice",
+		"[Agent]\nRegion = \"us-west-2":         "line 2: unterminated string",
+		"[Mds]\nStopTimeoutMillis = 1979-05-27": "line 2: unsupported value 1979-05-27",
+		"Agent = 1\n[Agent.Name]":               "line 2: key Agent is not a table",
+		"[Agent]\nRegion = \"a\" \"b\"":         "line 2: unexpected '\"' at the end of the line",
+	} {
+		_, err := parseToml(content)
+		if assert.Error(t, err, content) {
+			assert.Equal(t, message, err.Error())
+		}
+	}
+}
+
+func TestParseToml_Strings(t *testing.T) {
+	document, err := parseToml("basic = \"tab\\tquote\\\" \\u00e9\"\nliteral = 'C:\\Program Files'\nmultiline = \"\"\"\nfirst \\\n    second\"\"\"\n\"quoted key\" = 1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"basic":      "tab\tquote\" é",
+		"literal":    `C:\Program Files`,
+		"multiline":  "first second",
+		"quoted key": int64(1),
+	}, document)
+}
+
+func TestGetAppConfigPath_Precedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	appConfigFolderOrig := appConfigFolder
+	defer func() { appConfigFolder = appConfigFolderOrig }()
+	appConfigFolder = func() string { return dir }
+	appConfigPaths := AppConfigPaths()
+	assert.Equal(t, filepath.Join(dir, AppConfigTomlFileName), appConfigPaths[2])
+
+	_, err = getAppConfigPath()
+	assert.Error(t, err)
+
+	for i := len(appConfigPaths) - 1; i >= 0; i-- {
+		assert.NoError(t, ioutil.WriteFile(appConfigPaths[i], []byte(""), 0600))
+		path, err := getAppConfigPath()
+		assert.NoError(t, err)
+		assert.Equal(t, appConfigPaths[i], path)
+	}
+}
+
+func TestValidateFile_Yaml(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, AppConfigYamlFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("Mds:\n  CommandRetryLimit: 500\n"), 0600))
+
+	validationErrors, err := ValidateFile(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ValidationError{{Field: "Mds.CommandRetryLimit", Message: "500 is out of range, it must be between 1 and 100"}}, validationErrors)
+	assert.Equal(t, "Mds.CommandRetryLimit: 500 is out of range, it must be between 1 and 100", validationErrors[0].Error())
+}
@@ -27,10 +27,27 @@ const (
 	DefaultCommandRetryLimitMin = 1
 	DefaultCommandRetryLimitMax = 100
 
+	DefaultSessionRetryLimit    = 5
+	DefaultSessionRetryLimitMin = 1
+	DefaultSessionRetryLimitMax = 100
+
 	DefaultStopTimeoutMillis    = 20000
 	DefaultStopTimeoutMillisMin = 10000
 	DefaultStopTimeoutMillisMax = 1000000
 
+	DefaultPollBackoffMinMillis    = 2000
+	DefaultPollBackoffMinMillisMin = 500
+	DefaultPollBackoffMinMillisMax = 60000
+
+	// the backoff stays below the frequency at which the scheduler restarts the message polling
+	DefaultPollBackoffMaxMillis    = 300000
+	DefaultPollBackoffMaxMillisMax = 900000
+
+	// replies smaller than a few KB don't gain enough from the compression to pay for the base64 encoding
+	DefaultReplyCompressionMinBytes    = 4096
+	DefaultReplyCompressionMinBytesMin = 1024
+	DefaultReplyCompressionMinBytesMax = 1000000
+
 	// SSM defaults
 	DefaultSsmHealthFrequencyMinutes    = 5
 	DefaultSsmHealthFrequencyMinutesMin = 5
@@ -156,24 +173,297 @@ const (
 	// PluginNameAwsApplications is the name of the Applications plugin
 	PluginNameAwsApplications = "aws:applications"
 
+	// PluginNameAwsInstallWindowsUpdates is the name of the Windows Update plugin
+	PluginNameAwsInstallWindowsUpdates = "aws:installWindowsUpdates"
+
+	// PluginNameAwsRunWasmModule is the name of the plugin running WebAssembly modules
+	PluginNameAwsRunWasmModule = "aws:runWasmModule"
+
+	// PluginNameAwsRunLuaScript is the name of the plugin running Lua scripts
+	PluginNameAwsRunLuaScript = "aws:runLuaScript"
+
 	AppConfigFileName    = "amazon-ssm-agent.json"
 	SeelogConfigFileName = "seelog.xml"
 
+	// AppConfigYamlFileName and AppConfigTomlFileName are the names of the config file in YAML and TOML,
+	// they are read when there is no JSON config file, in this order
+	AppConfigYamlFileName = "amazon-ssm-agent.yaml"
+	AppConfigTomlFileName = "amazon-ssm-agent.toml"
+
 	// Output truncation limits
 	MaxStdoutLength = 24000
 	MaxStderrLength = 8000
 
+	// Package cache defaults
+	DefaultPackageCacheMaxVersionsPerPackage    = 2
+	DefaultPackageCacheMaxVersionsPerPackageMin = 1
+	DefaultPackageCacheMaxSizeMB                = 1024
+	DefaultPackageCacheMaxSizeMBMin             = 0
+	DefaultPackageCacheMaxAgeDays               = 30
+	DefaultPackageCacheMaxAgeDaysMin            = 1
+
+	// S3 multipart upload defaults, S3 takes parts of 5 MB to 5 GB
+	DefaultS3UploadPartSizeMB     = 5
+	DefaultS3UploadPartSizeMBMin  = 5
+	DefaultS3UploadPartSizeMBMax  = 5120
+	DefaultS3UploadConcurrency    = 5
+	DefaultS3UploadConcurrencyMin = 1
+	DefaultS3UploadConcurrencyMax = 64
+
+	// Package hooks defaults
+	DefaultPackageHooksTimeoutSeconds    = 300
+	DefaultPackageHooksTimeoutSecondsMin = 1
+
+	// DefaultDebugLogMinutes is how long the debug log level turned on at runtime lasts by default
+	DefaultDebugLogMinutes = 60
+
+	// DefaultDrainTimeoutSeconds is how long the in-flight documents are allowed to finish by default when the agent stops,
+	// it stays below the stop timeout of the service managers
+	DefaultDrainTimeoutSeconds = 30
+
+	// MaxMetricsPort is the highest port the metrics endpoint can be served on
+	MaxMetricsPort = 65535
+
+	// Metrics publication defaults, a minute is the standard resolution of CloudWatch metrics
+	DefaultMetricsPublishIntervalSeconds    = 60
+	DefaultMetricsPublishIntervalSecondsMin = 60
+	DefaultMetricsStatsdAddress             = "127.0.0.1:8125"
+
+	// Metrics sinks, the file and statsd sinks keep the metrics on the instance
+	MetricsSinkCloudWatch = "cloudwatch"
+	MetricsSinkFile       = "file"
+	MetricsSinkStatsd     = "statsd"
+
+	// MaxHealthEndpointPort is the highest port the health endpoint can be served on
+	MaxHealthEndpointPort = 65535
+
+	// StatusSocketName is the Unix socket in the data store folder the agent serves its status on for ssm-cli
+	StatusSocketName = "agent-status.sock"
+
+	// DefaultTracingEndpoint is the OTLP/HTTP traces url of a collector running on the instance
+	DefaultTracingEndpoint = "http://127.0.0.1:4318/v1/traces"
+
+	// Audit log defaults
+	DefaultAuditMaxSizeMB     = 10
+	DefaultAuditMaxSizeMBMin  = 1
+	DefaultAuditMaxRolls      = 10
+	DefaultAuditRetentionDays = 90
+
+	// Crash dump defaults
+	DefaultCrashDumpMaxDumps      = 10
+	DefaultCrashDumpRetentionDays = 30
+
+	// Watchdog defaults, the heartbeat timeout is above the poll frequency of the message poll loops
+	DefaultWatchdogCheckIntervalSeconds          = 60
+	DefaultWatchdogCheckIntervalSecondsMin       = 10
+	DefaultWatchdogHeartbeatTimeoutMinutes       = 30
+	DefaultWatchdogHeartbeatTimeoutMinutesMin    = 20
+	DefaultWatchdogDocumentWorkerTimeoutHours    = 48
+	DefaultWatchdogDocumentWorkerTimeoutHoursMin = 1
+
+	// Self-monitor defaults, the thresholds are well above the usage of a healthy agent
+	DefaultSelfMonitorCheckIntervalSeconds    = 300
+	DefaultSelfMonitorCheckIntervalSecondsMin = 10
+	DefaultSelfMonitorMaxGoroutines           = 10000
+	DefaultSelfMonitorMaxOpenFiles            = 4096
+	DefaultSelfMonitorMaxHeapMB               = 1024
+	DefaultSelfMonitorConsecutiveChecks       = 3
+	DefaultSelfMonitorConsecutiveChecksMin    = 1
+
+	// Resource limits defaults, the memory limit leaves room for the agent and a document worker
+	ResourceLimitsMemoryMBMin                   = 64
+	DefaultResourceLimitsLoadSheddingPercent    = 80
+	DefaultResourceLimitsLoadSheddingPercentMin = 1
+	DefaultResourceLimitsLoadSheddingPercentMax = 100
+
+	// DefaultDiskGuardReserveMB is the free disk space kept by default, the agent update needs as much
+	DefaultDiskGuardReserveMB = 100
+
+	// Update hooks defaults
+	DefaultUpdateHooksTimeoutSeconds    = 300
+	DefaultUpdateHooksTimeoutSecondsMin = 1
+
+	// Parallel package actions defaults
+	DefaultParallelPackageActionsLimit    = 4
+	DefaultParallelPackageActionsLimitMin = 1
+
+	// Failover defaults
+	DefaultFailoverFailureThreshold    = 5
+	DefaultFailoverFailureThresholdMin = 1
+	DefaultFailoverFailbackMinutes     = 30
+	DefaultFailoverFailbackMinutesMin  = 1
+
+	// Circuit breaker defaults
+	DefaultCircuitBreakerFailureThreshold    = 5
+	DefaultCircuitBreakerFailureThresholdMin = 1
+	DefaultCircuitBreakerCooldownSeconds     = 30
+	DefaultCircuitBreakerCooldownSecondsMin  = 1
+	DefaultCircuitBreakerMaxCooldownSeconds  = 600
+
+	// Retry defaults
+	DefaultRetryMaxRetries         = 3
+	DefaultRetryMaxRetriesMin      = 0
+	DefaultRetryMaxRetriesMax      = 20
+	DefaultRetryBaseDelayMillis    = 1000
+	DefaultRetryBaseDelayMillisMin = 10
+	DefaultRetryBaseDelayMillisMax = 60000
+	DefaultRetryMaxDelayMillis     = 60000
+	DefaultRetryMaxDelayMillisMax  = 900000
+	DefaultLogsMaxRetries          = 5
+
+	// DNS defaults
+	DefaultDnsCacheSeconds    = 60
+	DefaultDnsCacheSecondsMin = 0
+	DefaultDnsPort            = "53"
+
+	// Credential process defaults
+	DefaultCredentialProcessTimeoutSeconds    = 60
+	DefaultCredentialProcessTimeoutSecondsMin = 1
+
+	// Registration key protections
+	KeyProtectionNone     = "None"
+	KeyProtectionTPM      = "TPM"
+	KeyProtectionKeystore = "Keystore"
+	KeyProtectionDpapiNg  = "DpapiNg"
+
+	// Built-in identity providers
+	IdentityProviderOnPrem = "OnPrem"
+	IdentityProviderEC2    = "EC2"
+
+	// IdentityProviderSimulation is the identity provider used in the local simulation mode
+	IdentityProviderSimulation = "Simulation"
+
+	// Simulation defaults
+	DefaultSimulationInstanceID = "i-00000000000000000"
+	DefaultSimulationRegion     = "us-east-1"
+
+	// AppArmor modes of the worker profiles, the complain mode only logs the denials
+	AppArmorModeEnforce  = "enforce"
+	AppArmorModeComplain = "complain"
+
+	// DefaultPrivilegeSeparationUser is the unprivileged user the agent runs as when the privilege separation is enabled
+	DefaultPrivilegeSeparationUser = "ssm-agent"
+
+	// PowerShell language modes of the sessions and the runPowerShellScript commands
+	PowerShellLanguageModeFull        = "FullLanguage"
+	PowerShellLanguageModeConstrained = "ConstrainedLanguage"
+
+	// Binary integrity manifest installed in the program folder with its signature, and the release key verifying it
+	IntegrityManifestFileName   = "amazon-ssm-agent.manifest"
+	IntegritySignatureExtension = ".sig"
+	IntegrityReleaseKeyFileName = "amazon-ssm-agent-release.pem"
+
+	// PackageManifestFileName is the signed manifest of the package folders ssm-agent-helper installs, its signature
+	// is in the file with the IntegritySignatureExtension
+	PackageManifestFileName = "package-manifest.json"
+
+	// Update channels, stable versions are available to every channel and candidate versions only to the candidate one
+	UpdateChannelStable    = "stable"
+	UpdateChannelCandidate = "candidate"
+
+	// Instance metadata endpoint modes
+	MetadataEndpointModeIPv4 = "IPv4"
+	MetadataEndpointModeIPv6 = "IPv6"
+
 	// Session worker defaults
 	DefaultSessionWorkersLimit    = 1000
 	DefaultSessionWorkersLimitMin = 1
 
+	// Session output flow control defaults, the minimum holds a few stream data messages
+	DefaultMaxOutstandingOutputBytes = 1024 * 1024
+	MaxOutstandingOutputBytesMin     = 16 * 1024
+
+	// Session scrollback defaults
+	DefaultScrollbackBytes = 64 * 1024
+	ScrollbackBytesMax     = 1024 * 1024
+
+	// Control channel transports
+	ControlChannelTransportWebSocket = "websocket"
+	ControlChannelTransportGrpc      = "grpc"
+
+	// IPC transports of the worker channels, the named pipes are only available on Windows
+	IpcTransportFile      = "file"
+	IpcTransportNamedPipe = "namedpipe"
+
+	// DefaultPipeSecurityDescriptor only lets SYSTEM and the Administrators open the named pipes, without inheritance
+	DefaultPipeSecurityDescriptor = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+
+	// Restart policy of the agent service and of the systemd unit
+	DefaultRecoveryRestartDelaySeconds    = 30
+	DefaultRecoveryRestartDelaySecondsMin = 1
+	DefaultRecoveryRestartDelaySecondsMax = 3600
+	DefaultRecoveryFailureThreshold       = 3
+	DefaultRecoveryFailureThresholdMin    = 1
+	DefaultRecoveryResetPeriodSeconds     = 86400
+	DefaultRecoveryResetPeriodSecondsMin  = 60
+
+	// Syslog transports and facility
+	SyslogTransportTLS    = "tls"
+	SyslogTransportTCP    = "tcp"
+	DefaultSyslogFacility = 13
+	MaxSyslogFacility     = 23
+
+	// Formats and collector transports of the security events
+	SecurityEventsFormatCEF    = "CEF"
+	SecurityEventsFormatLEEF   = "LEEF"
+	SecurityEventsTransportTCP = "tcp"
+	SecurityEventsTransportUDP = "udp"
+
+	// Webhook defaults, the retries follow the bounds of the retries of the AWS service clients
+	DefaultWebhookMaxDelayMillis    = 30000
+	DefaultWebhookTimeoutSeconds    = 10
+	DefaultWebhookTimeoutSecondsMin = 1
+
+	// Session hooks defaults, the session waits for its pre-session hook
+	DefaultSessionHooksTimeoutSeconds    = 30
+	DefaultSessionHooksTimeoutSecondsMin = 1
+
+	// Firehose defaults, a PutRecordBatch call takes 500 records at most
+	DefaultFirehoseBatchSize    = 500
+	DefaultFirehoseBatchSizeMin = 1
+	DefaultFirehoseBatchSizeMax = 500
+
+	// Output failover defaults, the streaming of a complete file to CloudWatch Logs is given up after the timeout
+	DefaultOutputFailoverCloudWatchTimeoutSeconds    = 60
+	DefaultOutputFailoverCloudWatchTimeoutSecondsMin = 5
+
+	// MaxLocalApiPort is the highest port the local API can be served on
+	MaxLocalApiPort = 65535
+
+	// Boot gate defaults, the documents are held back 10 minutes at most
+	DefaultBootGateTimeoutSeconds    = 600
+	DefaultBootGateTimeoutSecondsMin = 10
+
+	// DefaultSshSessionPort is the port of the SSH server the Port sessions are checked against
+	DefaultSshSessionPort = 22
+	MaxSshSessionPort     = 65535
+
+	// MaxProfilingPort is the highest port the profiling endpoints can be served on
+	MaxProfilingPort = 65535
+
+	// Token elevations of the Windows sessions run as ssm-user
+	TokenElevationFull    = "Full"
+	TokenElevationLimited = "Limited"
+
 	// PluginNameStandardStream is the name for session manager standard stream plugin aka shell.
 	PluginNameStandardStream = "Standard_Stream"
 
+	// PluginNamePort is the name for session manager port plugin, forwarding the session to a port of the instance.
+	PluginNamePort = "Port"
+
 	// Session default RunAs user name
 	DefaultRunAsUserName = "ssm-user"
 )
 
+// LogLevels are the seelog log levels, from the most to the least verbose
+var LogLevels = []string{"trace", "debug", "info", "warn", "error", "critical", "off"}
+
+// LogGroupRetentionDays are the retention periods CloudWatch Logs accepts for a log group
+var LogGroupRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 3653}
+
+// PowerShellExecutionPolicies are the execution policies the PowerShell sessions and scripts can run with
+var PowerShellExecutionPolicies = []string{"AllSigned", "Bypass", "RemoteSigned", "Restricted", "Unrestricted"}
+
 // Document versions that are supported by this Agent version.
 // Note that 1.1 and 2.1 are deprecated schemas and hence are not added here.
 // Version 2.0.1, 2.0.2, and 2.0.3 are added to support install documents for configurePackage
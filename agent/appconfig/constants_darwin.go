@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin
 // +build darwin
 
 // Package appconfig manages the configuration of the agent.
@@ -29,6 +30,9 @@ const (
 	// PackageLockRoot specifies the directory under which package lock files will reside
 	PackageLockRoot = DefaultProgramFolder + "locks/packages"
 
+	// PackageCacheRoot specifies the directory under which downloaded package artifacts are cached
+	PackageCacheRoot = DefaultProgramFolder + "packagecache"
+
 	// PackagePlatform is the platform name to use when looking for packages
 	PackagePlatform = "darwin"
 
@@ -80,6 +84,9 @@ const (
 	DefaultSessionWorker  = DefaultProgramFolder + "bin/ssm-session-worker"
 	DefaultSessionLogger  = DefaultProgramFolder + "bin/ssm-session-logger"
 
+	// DefaultPrivilegedHelper is the setuid helper performing the operations needing root for the unprivileged agent
+	DefaultPrivilegedHelper = DefaultProgramFolder + "bin/ssm-agent-helper"
+
 	// PowerShellPluginCommandName is the path of the powershell.exe to be used by the runPowerShellScript plugin
 	PowerShellPluginCommandName = "/usr/bin/powershell"
 
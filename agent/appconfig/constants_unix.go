@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build freebsd || linux || netbsd || openbsd
 // +build freebsd linux netbsd openbsd
 
 // Package appconfig manages the configuration of the agent.
@@ -23,61 +24,66 @@ import (
 	"syscall"
 )
 
-const (
-
+// The data directories are under the snap common data directory when the agent runs under snap strict confinement
+var (
 	// PackageRoot specifies the directory under which packages will be downloaded and installed
-	PackageRoot = "/var/lib/amazon/ssm/packages"
+	PackageRoot = SnapPath("/var/lib/amazon/ssm/packages")
 
 	// PackageLockRoot specifies the directory under which package lock files will reside
-	PackageLockRoot = "/var/lib/amazon/ssm/locks/packages"
+	PackageLockRoot = SnapPath("/var/lib/amazon/ssm/locks/packages")
 
-	// PackagePlatform is the platform name to use when looking for packages
-	PackagePlatform = "linux"
+	// PackageCacheRoot specifies the directory under which downloaded package artifacts are cached
+	PackageCacheRoot = SnapPath("/var/lib/amazon/ssm/packagecache")
 
 	// DaemonRoot specifies the directory where daemon registration information is stored
-	DaemonRoot = "/var/lib/amazon/ssm/daemons"
+	DaemonRoot = SnapPath("/var/lib/amazon/ssm/daemons")
 
 	// LocalCommandRoot specifies the directory where users can submit command documents offline
-	LocalCommandRoot = "/var/lib/amazon/ssm/localcommands"
+	LocalCommandRoot = SnapPath("/var/lib/amazon/ssm/localcommands")
 
 	// LocalCommandRootSubmitted is the directory where locally submitted command documents
 	// are moved when they have been picked up
-	LocalCommandRootSubmitted = "/var/lib/amazon/ssm/localcommands/submitted"
-	LocalCommandRootCompleted = "/var/lib/amazon/ssm/localcommands/completed"
+	LocalCommandRootSubmitted = SnapPath("/var/lib/amazon/ssm/localcommands/submitted")
+	LocalCommandRootCompleted = SnapPath("/var/lib/amazon/ssm/localcommands/completed")
 
 	// LocalCommandRootInvalid is the directory where locally submitted command documents
 	// are moved if the service cannot validate the document (generally impossible via cli)
-	LocalCommandRootInvalid = "/var/lib/amazon/ssm/localcommands/invalid"
+	LocalCommandRootInvalid = SnapPath("/var/lib/amazon/ssm/localcommands/invalid")
 
 	// DownloadRoot specifies the directory under which files will be downloaded
-	DownloadRoot = "/var/log/amazon/ssm/download/"
+	DownloadRoot = SnapPath("/var/log/amazon/ssm/download/")
 
 	// DefaultDataStorePath represents the directory for storing system data
-	DefaultDataStorePath = "/var/lib/amazon/ssm/"
+	DefaultDataStorePath = SnapPath("/var/lib/amazon/ssm/")
 
 	// EC2ConfigDataStorePath represents the directory for storing ec2 config data
-	EC2ConfigDataStorePath = "/var/lib/amazon/ec2config/"
+	EC2ConfigDataStorePath = SnapPath("/var/lib/amazon/ec2config/")
 
 	// EC2ConfigSettingPath represents the directory for storing ec2 config settings
-	EC2ConfigSettingPath = "/var/lib/amazon/ec2configservice/"
+	EC2ConfigSettingPath = SnapPath("/var/lib/amazon/ec2configservice/")
 
 	// UpdaterArtifactsRoot represents the directory for storing update related information
-	UpdaterArtifactsRoot = "/var/lib/amazon/ssm/update/"
+	UpdaterArtifactsRoot = SnapPath("/var/lib/amazon/ssm/update/")
 
 	// DefaultPluginPath represents the directory for storing plugins in SSM
-	DefaultPluginPath = "/var/lib/amazon/ssm/plugins"
+	DefaultPluginPath = SnapPath("/var/lib/amazon/ssm/plugins")
 
 	// ManifestCacheDirectory represents the directory for storing all downloaded manifest files
-	ManifestCacheDirectory = "/var/lib/amazon/ssm/manifests"
+	ManifestCacheDirectory = SnapPath("/var/lib/amazon/ssm/manifests")
+
+	// Default Custom Inventory Inventory Folder
+	DefaultCustomInventoryFolder = DefaultDataStorePath + "inventory/custom"
+)
+
+const (
+	// PackagePlatform is the platform name to use when looking for packages
+	PackagePlatform = "linux"
 
 	// List all plugin names, unfortunately golang doesn't support const arrays of strings
 
 	// RebootExitCode that would trigger a Soft Reboot
 	RebootExitCode = 194
 
-	// Default Custom Inventory Inventory Folder
-	DefaultCustomInventoryFolder = DefaultDataStorePath + "inventory/custom"
-
 	// PowerShellPluginCommandArgs is the arguments of powershell.exe to be used by the runPowerShellScript plugin
 	PowerShellPluginCommandArgs = ""
 
@@ -95,11 +101,14 @@ const (
 var PowerShellPluginCommandName string
 
 // DefaultProgramFolder is the default folder for SSM
-var DefaultProgramFolder = "/etc/amazon/ssm/"
+var DefaultProgramFolder = SnapPath("/etc/amazon/ssm/")
 var DefaultDocumentWorker = "/usr/bin/ssm-document-worker"
 var DefaultSessionWorker = "/usr/bin/ssm-session-worker"
 var DefaultSessionLogger = "/usr/bin/ssm-session-logger"
 
+// DefaultPrivilegedHelper is the setuid helper performing the operations needing root for the unprivileged agent
+var DefaultPrivilegedHelper = "/usr/bin/ssm-agent-helper"
+
 // AppConfigPath is the path of the AppConfig
 var AppConfigPath = DefaultProgramFolder + AppConfigFileName
 
@@ -123,6 +132,7 @@ func init() {
 				DefaultDocumentWorker = filepath.Join(curdir, "ssm-document-worker")
 				DefaultSessionWorker = filepath.Join(curdir, "ssm-session-worker")
 				DefaultSessionLogger = filepath.Join(curdir, "ssm-session-logger")
+				DefaultPrivilegedHelper = filepath.Join(curdir, "ssm-agent-helper")
 				DefaultProgramFolder = curdir
 			}
 		}
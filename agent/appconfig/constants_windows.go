@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package appconfig manages the configuration of the agent.
@@ -62,19 +63,19 @@ const (
 	ItemPropertyName = "Environment"
 )
 
-//PowerShellPluginCommandName is the path of the powershell.exe to be used by the runPowerShellScript plugin
+// PowerShellPluginCommandName is the path of the powershell.exe to be used by the runPowerShellScript plugin
 var PowerShellPluginCommandName = filepath.Join(os.Getenv("SystemRoot"), "System32", "WindowsPowerShell", "v1.0", "powershell.exe")
 
 // Program Folder
 var DefaultProgramFolder string
 
-//Document executable path
+// Document executable path
 var DefaultDocumentWorker string
 
-//Session executable path
+// Session executable path
 var DefaultSessionWorker string
 
-//Session logger executable path
+// Session logger executable path
 var DefaultSessionLogger string
 
 // AppConfig Path
@@ -89,6 +90,9 @@ var PackageRoot string
 // PackageLockRoot specifies the directory under which package lock files will reside
 var PackageLockRoot string
 
+// PackageCacheRoot specifies the directory under which downloaded package artifacts are cached
+var PackageCacheRoot string
+
 // DaemonRoot specifies the directory where daemon registration information is stored
 var DaemonRoot string
 
@@ -174,6 +178,7 @@ func init() {
 	DefaultDataStorePath = filepath.Join(SSMDataPath, "InstanceData")
 	PackageRoot = filepath.Join(SSMDataPath, "Packages")
 	PackageLockRoot = filepath.Join(SSMDataPath, "Locks\\Packages")
+	PackageCacheRoot = filepath.Join(SSMDataPath, "PackageCache")
 	DaemonRoot = filepath.Join(SSMDataPath, "Daemons")
 	LocalCommandRoot = filepath.Join(SSMDataPath, "LocalCommands")
 	LocalCommandRootSubmitted = filepath.Join(LocalCommandRoot, "Submitted")
@@ -18,6 +18,10 @@ package appconfig
 type CredentialProfile struct {
 	ShareCreds   bool
 	ShareProfile string
+	// CredentialProcess is a command printing the credentials of the agent in the credential_process format,
+	// empty to use the instance or registration credentials
+	CredentialProcess               string
+	CredentialProcessTimeoutSeconds int
 }
 
 // MdsCfg represents configuration for Message delivery service (MDS)
@@ -26,6 +30,12 @@ type MdsCfg struct {
 	CommandWorkersLimit int
 	StopTimeoutMillis   int64
 	CommandRetryLimit   int
+	Proxy               ProxyCfg
+	// PollBackoffMinMillis and PollBackoffMaxMillis bound the delay between polls when no message is received
+	PollBackoffMinMillis int64
+	PollBackoffMaxMillis int64
+	// ReplyCompressionMinBytes is the size above which command replies are sent gzip compressed, 0 disables the compression
+	ReplyCompressionMinBytes int
 }
 
 // SsmCfg represents configuration for Simple system manager (SSM)
@@ -41,6 +51,9 @@ type SsmCfg struct {
 	AssociationLogsRetentionDurationHours int
 	RunCommandLogsRetentionDurationHours  int
 	SessionLogsRetentionDurationHours     int
+	// maximum number of independent configurePackage steps of a document run at the same time
+	ParallelPackageActionsLimit int
+	Proxy                       ProxyCfg
 }
 
 // AgentInfo represents metadata for amazon-ssm-agent
@@ -50,19 +63,78 @@ type AgentInfo struct {
 	Region               string
 	OrchestrationRootDir string
 	DownloadRootDir      string
+	// Partition overrides the partition of the region detected from the instance metadata or the region name
+	Partition string
+	// DebugLogMinutes is how long the debug log level turned on at runtime lasts, 0 keeps it until it is turned off
+	DebugLogMinutes int
+	// DrainTimeoutSeconds is how long the in-flight documents are allowed to finish when the agent stops,
+	// 0 stops the agent without waiting for them
+	DrainTimeoutSeconds int
+	// LogLevel overrides the minimum level of the seelog configuration, empty to use the seelog configuration
+	LogLevel string
+	// AllowedPlugins lists the plugins, including the session plugins, the documents are allowed to run,
+	// empty allows every plugin
+	AllowedPlugins []string
+	// DisabledPlugins lists the plugins the documents are not allowed to run, it overrides AllowedPlugins,
+	// the session plugins are Standard_Stream, InteractiveCommands, NonInteractiveCommands and Port
+	DisabledPlugins []string
 }
 
 // MgsConfig represents configuration for Message Gateway service
 type MgsConfig struct {
-	Region              string
-	Endpoint            string
-	StopTimeoutMillis   int64
-	SessionWorkersLimit int
+	Region                  string
+	Endpoint                string
+	StopTimeoutMillis       int64
+	SessionWorkersLimit     int
+	Proxy                   ProxyCfg
+	ControlChannelTransport string
+	GrpcGateway             string
+	// MaxOutstandingOutputBytes is the session output sent and not acknowledged yet above which
+	// the reads of the session output are paused, 0 does not pause them
+	MaxOutstandingOutputBytes int
+	// TokenElevation is the token the Windows sessions run as ssm-user get, Full for the administrator token and
+	// Limited for the filtered token, the logon token is kept when it is empty. The session documents can override it.
+	TokenElevation string
+	// TranscriptTiming uploads the output of the shell sessions as a typescript with the timing file of script --timing
+	// next to the session transcripts in S3, for scriptreplay
+	TranscriptTiming bool
+	// WorkingDirectory is the absolute path of the directory the shells of the sessions start in, the one of the agent
+	// when it is empty. The session documents can override it.
+	WorkingDirectory string
+	// EphemeralSudo only keeps the sudoers file of ssm-user while sessions run as ssm-user on Linux and macOS, it is
+	// created when a session starts and removed when the last session ends
+	EphemeralSudo bool
+	// AddGroups are the groups, as names or ids, added to the supplementary groups of ssm-user in the sessions on Linux
+	// and macOS
+	AddGroups []string
+	// DropGroups are the groups, as names or ids, removed from the supplementary groups of ssm-user in the sessions on
+	// Linux and macOS, the primary group of ssm-user is kept
+	DropGroups []string
+	// ScrollbackBytes is the size of the last output of a shell session replayed to a client resuming the session,
+	// 0 replays nothing
+	ScrollbackBytes int
+	// SessionRetryLimit is how many times the restarted agent reconnects to the worker of a session still in progress,
+	// the session is failed afterwards
+	SessionRetryLimit int
 }
 
 // KmsConfig represents configuration for Key Management Service
 type KmsConfig struct {
 	Endpoint string
+	Proxy    ProxyCfg
+}
+
+// LogsCfg represents configuration for CloudWatch Logs
+type LogsCfg struct {
+	Endpoint string
+	Proxy    ProxyCfg
+	// AgentLogGroup is the log group the agent ships its own logs to, in a log stream named by the instance id
+	// The agent logs are not shipped when it is empty.
+	AgentLogGroup string
+	// LogGroupRetentionDays is the retention of the log groups the agent creates, 0 keeps the events forever
+	LogGroupRetentionDays int
+	// LogGroupKmsKeyId is the ARN of the KMS key encrypting the log groups the agent creates
+	LogGroupKmsKeyId string
 }
 
 // OsInfo represents os related information
@@ -78,6 +150,18 @@ type S3Cfg struct {
 	Region    string
 	LogBucket string
 	LogKey    string
+	// ForcePathStyle uses path-style addressing, required by S3-compatible object stores such as MinIO
+	ForcePathStyle bool
+	// CABundle is a PEM file of additional certificate authorities trusted for the S3 endpoint
+	CABundle string
+	Proxy    ProxyCfg
+	// UseAccelerate uploads through the S3 Transfer Acceleration endpoints, the buckets must have acceleration enabled
+	// It is ignored with a custom endpoint.
+	UseAccelerate bool
+	// UploadPartSizeMB is the size of the parts of the multipart uploads
+	UploadPartSizeMB int
+	// UploadConcurrency is the number of parts of an upload sent in parallel
+	UploadConcurrency int
 }
 
 // BirdwatcherCfg represents configuration related to ConfigurePackage Birdwatcher integration
@@ -85,17 +169,618 @@ type BirdwatcherCfg struct {
 	ForceEnable bool
 }
 
+// PackageCacheCfg represents the garbage collection policy of the local package artifact cache
+// Artifacts of package versions that are currently installed are never collected.
+type PackageCacheCfg struct {
+	// MaxVersionsPerPackage is the number of cached versions kept for each package
+	MaxVersionsPerPackage int
+	// MaxSizeMB is the total size of cached artifacts kept, 0 only keeps artifacts in use
+	MaxSizeMB int
+	// MaxAgeDays is the number of days an unused artifact is kept
+	MaxAgeDays int
+}
+
+// PackageHooksCfg represents administrator-defined executables run around every package install and uninstall
+// The hooks receive the package name, version and action in SSM_PACKAGE_* environment variables.
+type PackageHooksCfg struct {
+	// PreAction is run before a package is installed or uninstalled, the action is not performed if it fails
+	PreAction string
+	// PostAction is run after a package is installed or uninstalled, SSM_PACKAGE_STATUS holds the result
+	PostAction string
+	// TimeoutSeconds is the maximum time a hook is allowed to run
+	TimeoutSeconds int
+}
+
+// PackageSigningCfg represents the signing keys pinned by the administrator for package verification
+type PackageSigningCfg struct {
+	// PublicKeys are paths of PEM public keys, packages must carry a valid signature by one of them when set
+	PublicKeys []string
+}
+
+// MirrorCfg represents an internal HTTPS mirror all agent downloads are redirected to, for disconnected environments
+// A file is looked up at <Url>/<original host>/<original path> and verified with its checksum, which is read from
+// <file>.sha256 on the mirror when the download doesn't specify one. A checksum read from the mirror only detects
+// corrupted files, it doesn't protect against a compromised mirror. The mirror can't be listed, so S3 folders and
+// the available package versions can't be looked up through it.
+type MirrorCfg struct {
+	// Url is the https url of the mirror root, downloads are not redirected when empty
+	Url string
+	// CABundle is a PEM file of additional certificate authorities trusted for the mirror
+	CABundle string
+	// RequireSourceChecksum fails the downloads that don't specify a checksum instead of reading it from the mirror
+	RequireSourceChecksum bool
+}
+
+// InstanceMetadataCfg represents configuration of instance metadata access
+type InstanceMetadataCfg struct {
+	// RequireIMDSv2 never falls back to IMDSv1 when no IMDSv2 session token can be fetched
+	RequireIMDSv2 bool
+	// EndpointMode is IPv4 or IPv6, empty to select the endpoint from the addresses of the instance
+	EndpointMode string
+}
+
+// BandwidthCfg represents the caps of the agent traffic in bytes per second, 0 to not cap the traffic
+type BandwidthCfg struct {
+	UploadBytesPerSecond   int64
+	DownloadBytesPerSecond int64
+	SessionBytesPerSecond  int64
+}
+
+// DnsCfg represents configuration of the resolution of the agent endpoints
+type DnsCfg struct {
+	// Resolvers are the DNS servers queried instead of the system resolver, as ip or ip:port
+	Resolvers []string
+	// BootstrapHosts are the addresses of hosts used when they can't be resolved
+	BootstrapHosts map[string][]string
+	// CacheSeconds is how long the answers of the system resolver are cached, which has no TTL, 0 to not cache them
+	CacheSeconds int
+}
+
+// NetworkCfg represents configuration of the network used to reach AWS services
+type NetworkCfg struct {
+	// UseDualStackEndpoints uses the dual-stack (IPv4 and IPv6) endpoints of AWS services by default
+	UseDualStackEndpoints bool
+	// Proxy is used by services without their own proxy configuration
+	Proxy ProxyCfg
+	// Bandwidth caps the traffic of the agent per category, for metered or thin links
+	Bandwidth BandwidthCfg
+	Dns       DnsCfg
+}
+
+// FailoverTarget represents a secondary region of the control plane services
+type FailoverTarget struct {
+	Region string
+	// MdsEndpoint and MgsEndpoint override the endpoints of the region, empty to use the default endpoints
+	MdsEndpoint string
+	MgsEndpoint string
+}
+
+// FailoverCfg represents configuration of the failover of MDS and MGS to secondary regions
+type FailoverCfg struct {
+	// Targets are the secondary regions in order of preference
+	Targets []FailoverTarget
+	// FailureThreshold is the number of consecutive failures in a region before failing over to the next one
+	FailureThreshold int
+	// FailbackMinutes is how long a secondary region is used before the primary region is tried again
+	FailbackMinutes int
+}
+
+// RegistrationCfg represents configuration of the registration of managed (on-premises) instances
+type RegistrationCfg struct {
+	// KeyProtection is None, TPM, Keystore or DpapiNg, the registration private key is sealed by the
+	// TPM 2.0 or the OS keystore of the machine so it cannot be used after being copied to another machine,
+	// or by DPAPI-NG on Windows so that only the account of the agent service can use it
+	KeyProtection string
+	// ProtectionDescriptor is the DPAPI-NG protection descriptor of the DpapiNg key protection,
+	// SID= the account of the agent service, typically a gMSA, when it is empty
+	ProtectionDescriptor string
+}
+
+// ProxyCfg represents the proxy used to reach a service, the proxy environment variables are used when it is empty
+type ProxyCfg struct {
+	// Url of the proxy, "direct" to connect without a proxy
+	Url string
+	// NoProxy lists hosts, domains, IP addresses and CIDR ranges connected to without a proxy
+	NoProxy []string
+	// PacUrl is a file path or http url of a proxy auto-config file selecting the proxy
+	PacUrl string
+}
+
+// IdentityCfg represents configuration of the resolution of the instance identity
+type IdentityCfg struct {
+	// Providers are the names of the identity providers in order of preference
+	Providers []string
+}
+
+// CircuitBreakerCfg represents configuration of the circuit breaker of the AWS service clients
+type CircuitBreakerCfg struct {
+	// FailureThreshold is the number of consecutive throttled or failed calls to a service before calls to it are suspended
+	FailureThreshold int
+	// CooldownSeconds is how long calls are suspended, a random jitter of up to the same duration is added
+	CooldownSeconds    int
+	MaxCooldownSeconds int
+}
+
+// RetryPolicyCfg represents the retries of the calls to an AWS service
+type RetryPolicyCfg struct {
+	MaxRetries int
+	// BaseDelayMillis is the delay before the first retry, it doubles with each retry up to MaxDelayMillis
+	BaseDelayMillis int
+	MaxDelayMillis  int
+}
+
+// RetryCfg represents configuration of the retries of the AWS service clients
+type RetryCfg struct {
+	RetryPolicyCfg
+	// Services override the policy per service endpoint prefix such as ssm, ec2messages or logs, 0 values inherit the policy
+	Services map[string]RetryPolicyCfg
+}
+
+// UpdateCfg represents configuration of the self-updates of the agent
+type UpdateCfg struct {
+	// Windows are the local time windows in which self-updates may occur, as "[days ]HH:MM-HH:MM" such as
+	// "Mon-Fri 22:00-04:00", updates arriving outside of them are deferred to the next one; anytime when empty
+	Windows []string
+	// Source is an internal https url or local directory holding the manifest and packages of the agent with the
+	// layout of the public buckets, they are fetched from it instead of the public buckets when set
+	Source string
+	// PublicKeys are paths of PEM public keys trusted to sign the update manifest besides the AWS update signing keys,
+	// the manifest signature is checked once a key is pinned or the AWS update signing keys are installed
+	PublicKeys []string
+	// Channel is the channel the latest version is resolved from, stable or candidate
+	Channel string
+	// AllowDowngrade allows updates to versions older than the installed one, for intentional rollbacks
+	AllowDowngrade bool
+	// Hooks are run around the agent self-updates
+	Hooks UpdateHooksCfg
+}
+
+// MetricsCfg represents the opt-in Prometheus endpoint of the agent and the opt-in publication of its metrics to CloudWatch
+type MetricsCfg struct {
+	// Port is the localhost port the metrics are served on at /metrics, 0 disables the endpoint
+	Port int
+	// Sink is where the metrics are published: cloudwatch, or file and statsd to keep them on the instance
+	Sink string
+	// CloudWatchNamespace is the CloudWatch namespace the metrics are published to, empty disables the cloudwatch sink
+	CloudWatchNamespace string
+	// FilePath is the file the file sink writes the metrics to, metrics.log in the log directory when empty
+	FilePath string
+	// StatsdAddress is the host:port of the StatsD daemon the statsd sink sends the metrics to
+	StatsdAddress string
+	// PublishIntervalSeconds is the interval at which the metrics are published to the sink
+	PublishIntervalSeconds int
+}
+
+// TelemetryCfg represents the telemetry of the agent: its metrics, traces and crash dumps
+type TelemetryCfg struct {
+	// OptOut turns off all the telemetry, it overrides the metrics, tracing and crash dump upload settings
+	OptOut bool
+}
+
+// HealthEndpointCfg represents the opt-in health endpoint of the agent, for liveness probes and bootstrap scripts
+type HealthEndpointCfg struct {
+	// Port is the localhost port the health is served on at /health, 0 does not serve it on a port
+	Port int
+	// SocketPath is the Unix socket the health is served on at /health, empty does not serve it on a socket
+	SocketPath string
+}
+
+// TracingCfg represents the OpenTelemetry tracing of the document and session lifecycles
+type TracingCfg struct {
+	// Enabled turns on the export of the spans
+	Enabled bool
+	// Endpoint is the OTLP/HTTP traces url of the collector the spans are exported to
+	Endpoint string
+}
+
+// AuditCfg represents the audit log of the security-relevant events, written apart from the agent logs
+type AuditCfg struct {
+	// Enabled turns on the audit log
+	Enabled bool
+	// MaxSizeMB is the size in megabytes the audit log file is rotated at
+	MaxSizeMB int
+	// MaxRolls is the number of rotated audit log files kept, 0 keeps all of them
+	MaxRolls int
+	// RetentionDays is the age in days the rotated audit log files are removed at, 0 keeps them regardless of their age
+	RetentionDays int
+	// LogGroup is the CloudWatch Logs group the audit events are forwarded to, they are only written locally when empty
+	LogGroup string
+	// SessionProcesses records the processes started within the sessions with their command line, user and parents
+	SessionProcesses bool
+}
+
+// CrashDumpCfg represents the crash dumps captured when the agent or its workers panic
+type CrashDumpCfg struct {
+	// MaxDumps is the number of crash dumps kept locally, 0 keeps all of them
+	MaxDumps int
+	// RetentionDays is the age in days the crash dumps are removed at, 0 keeps them regardless of their age
+	RetentionDays int
+	// S3BucketName is the bucket the crash dumps are uploaded to, they are only kept locally when empty
+	S3BucketName string
+	// S3KeyPrefix is the prefix of the uploaded crash dumps, followed by the instance id
+	S3KeyPrefix string
+}
+
+// WatchdogCfg represents the watchdog killing the stuck workers and restarting the stalled core loops of the agent
+type WatchdogCfg struct {
+	// Enabled turns on the watchdog
+	Enabled bool
+	// CheckIntervalSeconds is the interval at which the workers and the core loops are checked
+	CheckIntervalSeconds int
+	// HeartbeatTimeoutMinutes is the time a core loop is restarted at when it stopped heartbeating
+	HeartbeatTimeoutMinutes int
+	// DocumentWorkerTimeoutHours is the time a document worker is killed at when it is still running
+	DocumentWorkerTimeoutHours int
+	// SessionWorkerTimeoutHours is the time a session worker is killed at when it is still running, 0 never kills it
+	SessionWorkerTimeoutHours int
+}
+
+// SelfMonitorCfg represents the periodic self-checks of the goroutines, open file descriptors and heap of the agent
+type SelfMonitorCfg struct {
+	// Enabled turns on the self-checks
+	Enabled bool
+	// CheckIntervalSeconds is the interval at which the agent checks itself
+	CheckIntervalSeconds int
+	// MaxGoroutines is the number of goroutines above which a warning is logged, 0 does not check the goroutines
+	MaxGoroutines int
+	// MaxOpenFiles is the number of open file descriptors, or handles on Windows, above which a warning is logged,
+	// 0 does not check the open files
+	MaxOpenFiles int
+	// MaxHeapMB is the heap in megabytes above which a warning is logged, 0 does not check the heap
+	MaxHeapMB int
+	// ConsecutiveChecks is the number of consecutive checks above a threshold after which the agent is restarted
+	ConsecutiveChecks int
+	// RestartOnThreshold exits the agent with an error for the service manager to restart it
+	// when a threshold is exceeded for the consecutive checks
+	RestartOnThreshold bool
+}
+
+// ResourceLimitsCfg represents the caps on the CPU and memory of the agent, its workers and the documents they run are not limited
+type ResourceLimitsCfg struct {
+	// CPUPercent is the share of a single CPU the agent is limited to, 0 does not limit the CPU
+	CPUPercent int
+	// MemoryMB is the memory in megabytes the agent is limited to, 0 does not limit the memory
+	MemoryMB int
+	// LoadSheddingPercent is the share of the memory limit from which the non-urgent work is deferred
+	LoadSheddingPercent int
+}
+
+// DiskGuardCfg represents the reserve of free disk space the writes of the agent are not allowed to use
+type DiskGuardCfg struct {
+	// ReserveMB is the free disk space in megabytes below which the writes of the agent fail, 0 does not guard the writes
+	ReserveMB int
+}
+
+// UpdateHooksCfg represents administrator-defined executables run around every agent self-update
+// The hooks receive the package name, source and target versions in SSM_UPDATE_* environment variables.
+type UpdateHooksCfg struct {
+	// PreUpdate is run before the old agent stops, the update is not performed if it fails
+	PreUpdate string
+	// PostUpdate is run after the new agent started
+	PostUpdate string
+	// TimeoutSeconds is the maximum time a hook is allowed to run
+	TimeoutSeconds int
+}
+
+// ConfigOverridesCfg represents the settings overriding the config file from outside of the instance image
+// The environment variables named SSM_AGENT_<SECTION>_<SETTING> override the settings fetched from Parameter Store,
+// which override the config file.
+type ConfigOverridesCfg struct {
+	// ParameterStorePath is the Parameter Store path whose parameters, named <Section>/<Setting> under it,
+	// override the settings at the agent startup, empty to not fetch overrides from Parameter Store
+	ParameterStorePath string
+}
+
+// SimulationCfg represents the local simulation mode, in which the agent exchanges messages with a mock
+// message delivery service instead of AWS, no AWS credentials are needed to run documents in this mode
+type SimulationCfg struct {
+	// Endpoint is the http(s) url of the mock service, empty to connect to AWS
+	Endpoint string
+	// InstanceID is the instance id the agent uses in the simulation
+	InstanceID string
+}
+
+// AppArmorCfg represents the AppArmor profiles the agent generates and loads for its document and session workers
+type AppArmorCfg struct {
+	// Enabled loads the worker profiles at the agent startup on the instances where AppArmor is available
+	Enabled bool
+	// Mode is the mode of the profiles, enforce or complain
+	Mode string
+}
+
+// FipsCfg represents the FIPS mode of the agent
+type FipsCfg struct {
+	// Enabled restricts the TLS connections and the checksums of the agent and its workers to FIPS-approved
+	// algorithms, the agent refuses to start when it is not built with a FIPS-validated crypto backend
+	Enabled bool
+}
+
+// PrivilegeSeparationCfg represents the unprivileged user the long-running agent runs as
+type PrivilegeSeparationCfg struct {
+	// Enabled drops the privileges of the agent to the user at startup, the operations needing root
+	// go through the ssm-agent-helper setuid helper
+	Enabled bool
+	// User is the user the agent runs as, the packages create it and give it the agent folders
+	User string
+	// RunAsUsers are the users ssm-agent-helper runs the Session Manager shells as, it never runs them as root
+	RunAsUsers []string
+}
+
+// IntegrityCfg represents the verification of the agent binaries against the signed manifest installed with the package
+type IntegrityCfg struct {
+	// Enabled verifies the agent binaries at startup and the workers before each launch, the workers whose
+	// checksums differ from the manifest are not started
+	Enabled bool
+	// PublicKeys are paths of PEM public keys the manifest must be signed by, the release key installed
+	// with the package is used when there are none
+	PublicKeys []string
+}
+
+// EventLogCfg represents the AmazonSSMAgent channel of the Windows Event Log
+type EventLogCfg struct {
+	// Enabled registers the channel and writes the service start and stop and the audit events to it, on Windows
+	Enabled bool
+}
+
+// PowerShellCfg represents the restrictions of the PowerShell sessions and of the runPowerShellScript commands
+type PowerShellCfg struct {
+	// LanguageMode is FullLanguage, or ConstrainedLanguage to switch the sessions and the scripts
+	// to the constrained language before their first command
+	LanguageMode string
+	// ExecutionPolicy is the execution policy of the sessions and the scripts, the agent keeps its
+	// default policies when it is empty
+	ExecutionPolicy string
+	// AllowedModules are the only modules the sessions and the scripts can import, the modules are
+	// no longer auto-loaded when there are any
+	AllowedModules []string
+}
+
+// IpcCfg represents the channels between the agent and its document and session workers
+type IpcCfg struct {
+	// Transport is file for the file channels, or namedpipe for the named pipes on Windows
+	Transport string
+	// PipeSecurityDescriptor is the SDDL of the named pipes, SYSTEM and the Administrators only by default
+	PipeSecurityDescriptor string
+}
+
+// EtwCfg represents the ETW events of the Session Manager sessions on Windows
+type EtwCfg struct {
+	// Enabled writes the session events through the Amazon-SSM-Agent provider the installer registers
+	Enabled bool
+}
+
+// WslCfg represents the WSL distribution running the aws:runShellScript documents on Windows
+type WslCfg struct {
+	// Distribution is the name of the distribution, the documents are not supported on Windows when it is empty
+	Distribution string
+	// User is the user of the distribution running the scripts, the default user of the distribution when it is empty
+	User string
+}
+
+// RecoveryCfg represents the restart policy the installers give the agent service on Windows and the systemd unit on Linux
+type RecoveryCfg struct {
+	// RestartDelaySeconds is the delay before the failed agent is restarted
+	RestartDelaySeconds int
+	// FailureThreshold is the number of failures within ResetPeriodSeconds the agent is restarted after
+	FailureThreshold int
+	// ResetPeriodSeconds is the period without failure after which the failures are no longer counted
+	ResetPeriodSeconds int
+	// RebootOnRepeatedFailure reboots the instance rather than restarting the agent once the threshold is reached
+	RebootOnRepeatedFailure bool
+}
+
+// SyslogCfg represents the syslog server the audit events and the session transcripts are forwarded to
+type SyslogCfg struct {
+	// Address is the host:port of the syslog server, nothing is forwarded when it is empty
+	Address string
+	// Transport is tls, or tcp for the servers without TLS
+	Transport string
+	// CABundle is a PEM file of additional certificate authorities trusted for the syslog server
+	CABundle string
+	// Facility is the facility of the messages, 13 (log audit) by default
+	Facility int
+	// Audit forwards the events of the audit log, which must be enabled
+	Audit bool
+	// SessionTranscripts forwards the transcripts of the shell sessions when they end
+	SessionTranscripts bool
+}
+
+// SecurityEventsCfg represents the session and command lifecycle events written in the CEF or LEEF format
+// for the SIEMs ingesting them without custom parsers
+type SecurityEventsCfg struct {
+	// Format is CEF or LEEF, the events are not written when it is empty
+	Format string
+	// Path is the file the events are appended to, security-events.log in the log folder by default
+	Path string
+	// Address is the host:port of a collector the events are sent to instead of the file, one event per line
+	Address string
+	// Transport is tcp or udp, the transport of the collector
+	Transport string
+}
+
+// WebhookCfg represents the webhook the session and command lifecycle events are posted to as JSON
+type WebhookCfg struct {
+	// Url is the https url the events are posted to, no event is posted when it is empty
+	Url string
+	// AuthHeaderParameter is the Parameter Store parameter, typically a SecureString, holding the value of the
+	// Authorization header of the posts, no header is sent when it is empty
+	AuthHeaderParameter string
+	// Retry is the retry policy of the failed posts
+	Retry RetryPolicyCfg
+	// TimeoutSeconds is the timeout of a post
+	TimeoutSeconds int
+}
+
+// SessionHooksCfg represents administrator-defined executables run around every Session Manager session
+// The hooks receive the session id, the principal and the user the session runs as in SSM_SESSION_* environment variables.
+type SessionHooksCfg struct {
+	// PreSession is run before the session starts, the session is refused if it fails
+	PreSession string
+	// PostSession is run after the session ended, SSM_SESSION_STATUS holds the status of the session
+	PostSession string
+	// TimeoutSeconds is the maximum time a hook is allowed to run
+	TimeoutSeconds int
+}
+
+// FirehoseCfg represents the Kinesis Data Firehose delivery stream the command output and the session transcripts
+// are sent to, one JSON record per line carrying the partition keys of the line
+type FirehoseCfg struct {
+	Endpoint string
+	Proxy    ProxyCfg
+	// DeliveryStream is the name of the delivery stream, nothing is sent when it is empty
+	DeliveryStream string
+	// CommandOutput sends the standard output and error of the command plugins
+	CommandOutput bool
+	// SessionTranscripts sends the transcripts of the shell sessions
+	SessionTranscripts bool
+	// BatchSize is the maximum number of records sent in one call
+	BatchSize int
+	// PartitionKeys are added to every record, next to the instance, command and session ids, for the dynamic
+	// partitioning of the delivery stream
+	PartitionKeys map[string]string
+}
+
+// OutputFailoverCfg represents the secondary destination of the command output and the session transcripts, used when
+// their S3 upload fails or their CloudWatch Logs streaming does not complete
+type OutputFailoverCfg struct {
+	// S3BucketName receives the output that could not be streamed to CloudWatch Logs
+	S3BucketName string
+	S3KeyPrefix  string
+	// CloudWatchLogGroup receives the output that could not be uploaded to S3
+	CloudWatchLogGroup string
+	// CloudWatchTimeoutSeconds is the time a complete transcript or output file is given to reach CloudWatch Logs
+	CloudWatchTimeoutSeconds int
+}
+
+// OutputEncryptionCfg represents the client-side envelope encryption of the session transcripts and the command output
+// before their S3 upload and their CloudWatch Logs streaming
+type OutputEncryptionCfg struct {
+	// KmsKeyId is the customer managed KMS key the data keys are generated under, the output is sent in clear when it
+	// is empty
+	KmsKeyId string
+}
+
+// LocalApiCfg represents the opt-in local API the software of the instance uses to run the documents of a local
+// folder and to read their results
+type LocalApiCfg struct {
+	// SocketPath is the Unix socket the API is served on, only root and the agent user can use it
+	SocketPath string
+	// Port is the localhost port the API is served on, 0 does not serve it on a port
+	Port int
+	// TokenFile holds the bearer token the requests on the port must carry
+	TokenFile string
+	// DocumentsPath is the folder of the JSON and YAML command documents the API runs
+	DocumentsPath string
+}
+
+// LuaCfg represents the commands the scripts of the aws:runLuaScript documents can run
+type LuaCfg struct {
+	// AllowedCommands are the only commands exec.run runs, as the scripts name them, the scripts run no command when it is empty
+	AllowedCommands []string
+}
+
+// BootGateCfg represents the provisioning of the instance the Run Command and association documents wait for at boot,
+// the documents are not held back when no condition is set
+type BootGateCfg struct {
+	// CloudInit waits for cloud-init to complete its final stage when it runs at this boot
+	CloudInit bool
+	// ReadinessFile waits for the file to exist
+	ReadinessFile string
+	// ReadinessCommand waits for the command to exit with 0, it runs with sh on Linux and PowerShell on Windows
+	ReadinessCommand string
+	// TimeoutSeconds is how long the documents are held back, they run once it expired even when the instance is not ready
+	TimeoutSeconds int
+}
+
+// ShellScriptCfg represents the interpreters the shebang lines of the aws:runShellScript documents can name
+type ShellScriptCfg struct {
+	// AllowedInterpreters are the interpreters, as a path or a file name, the scripts run with when their shebang line
+	// names them, the scripts run with sh when it is empty
+	AllowedInterpreters []string
+}
+
+// ProfilingCfg represents the opt-in profiling endpoints of the agent, serving the runtime profiles of net/http/pprof
+type ProfilingCfg struct {
+	// Port is the localhost port the profiles are served on at /debug/pprof/, 0 does not serve them on a port
+	Port int
+	// TokenFile holds the bearer token the requests on the port must carry
+	TokenFile string
+	// SocketPath is the Unix socket the profiles are served on at /debug/pprof/, empty does not serve them on a socket
+	SocketPath string
+}
+
+// SshSessionCfg represents the policy of the Port sessions forwarded to the SSH server of the instance. The policy is
+// advisory, it checks the user and the key the client announces and the SSH server still authenticates them.
+type SshSessionCfg struct {
+	// Port is the port of the SSH server, the Port sessions forwarded to it on the instance are SSH sessions, and so
+	// are the sessions to the other local ports whose server greets with an SSH identification string
+	Port int
+	// AllowedUsers are the only users the SSH sessions can log in as, the users are not checked when it is empty
+	AllowedUsers []string
+	// AuthorizedKeysFile holds the only public keys the SSH sessions can log in with, in the authorized_keys format,
+	// the keys are not checked when it is empty
+	AuthorizedKeysFile string
+}
+
 // SsmagentConfig stores agent configuration values.
 type SsmagentConfig struct {
-	Profile     CredentialProfile
-	Mds         MdsCfg
-	Ssm         SsmCfg
-	Mgs         MgsConfig
-	Agent       AgentInfo
-	Os          OsInfo
-	S3          S3Cfg
-	Birdwatcher BirdwatcherCfg
-	Kms         KmsConfig
+	Profile             CredentialProfile
+	Mds                 MdsCfg
+	Ssm                 SsmCfg
+	Mgs                 MgsConfig
+	Agent               AgentInfo
+	Os                  OsInfo
+	S3                  S3Cfg
+	Birdwatcher         BirdwatcherCfg
+	Kms                 KmsConfig
+	Logs                LogsCfg
+	PackageCache        PackageCacheCfg
+	PackageHooks        PackageHooksCfg
+	Mirror              MirrorCfg
+	PackageSigning      PackageSigningCfg
+	InstanceMetadata    InstanceMetadataCfg
+	Network             NetworkCfg
+	Failover            FailoverCfg
+	Registration        RegistrationCfg
+	Identity            IdentityCfg
+	CircuitBreaker      CircuitBreakerCfg
+	Retry               RetryCfg
+	Update              UpdateCfg
+	Metrics             MetricsCfg
+	Telemetry           TelemetryCfg
+	Tracing             TracingCfg
+	Audit               AuditCfg
+	CrashDump           CrashDumpCfg
+	Watchdog            WatchdogCfg
+	SelfMonitor         SelfMonitorCfg
+	ResourceLimits      ResourceLimitsCfg
+	DiskGuard           DiskGuardCfg
+	HealthEndpoint      HealthEndpointCfg
+	ConfigOverrides     ConfigOverridesCfg
+	Simulation          SimulationCfg
+	AppArmor            AppArmorCfg
+	Fips                FipsCfg
+	PrivilegeSeparation PrivilegeSeparationCfg
+	Integrity           IntegrityCfg
+	EventLog            EventLogCfg
+	PowerShell          PowerShellCfg
+	Ipc                 IpcCfg
+	Etw                 EtwCfg
+	Wsl                 WslCfg
+	Recovery            RecoveryCfg
+	Syslog              SyslogCfg
+	SecurityEvents      SecurityEventsCfg
+	Webhook             WebhookCfg
+	SessionHooks        SessionHooksCfg
+	Firehose            FirehoseCfg
+	OutputFailover      OutputFailoverCfg
+	OutputEncryption    OutputEncryptionCfg
+	LocalApi            LocalApiCfg
+	Lua                 LuaCfg
+	BootGate            BootGateCfg
+	ShellScript         ShellScriptCfg
+	Profiling           ProfilingCfg
+	SshSession          SshSessionCfg
 }
 
 // AppConstants represents some run time constant variable for various module.
@@ -0,0 +1,177 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvironmentOverridePrefix is the prefix of the environment variables overriding settings, it is followed
+	// by the section and setting names separated by underscores such as SSM_AGENT_MDS_COMMANDWORKERSLIMIT
+	EnvironmentOverridePrefix = "SSM_AGENT_"
+
+	// ParameterOverridesFileName is the file caching the settings fetched from Parameter Store
+	ParameterOverridesFileName = "parameter-overrides.json"
+)
+
+// environ returns the environment variables, stubbed in tests
+var environ = os.Environ
+
+// parameterOverridesPath returns the path of the settings fetched from Parameter Store, which the agent
+// and its workers read, stubbed in tests
+var parameterOverridesPath = func() string {
+	return filepath.Join(DefaultDataStorePath, ParameterOverridesFileName)
+}
+
+// applyConfigOverrides applies the settings fetched from Parameter Store and then the environment variables
+// to the configuration, it returns false when no setting is overridden
+func applyConfigOverrides(config *SsmagentConfig) bool {
+	environmentOverrides := environmentOverrides()
+	// the environment variables can set the Parameter Store path
+	applyOverrides(config, "environment", environmentOverrides)
+	if config.ConfigOverrides.ParameterStorePath == "" {
+		return len(environmentOverrides) > 0
+	}
+	parameterOverrides := readParameterOverrides()
+	applyOverrides(config, "Parameter Store", parameterOverrides)
+	applyOverrides(config, "environment", environmentOverrides)
+	return len(environmentOverrides) > 0 || len(parameterOverrides) > 0
+}
+
+// environmentOverrides returns the settings overridden by environment variables, by setting path
+func environmentOverrides() map[string]string {
+	overrides := make(map[string]string)
+	for _, variable := range environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], EnvironmentOverridePrefix) {
+			continue
+		}
+		setting := strings.Replace(strings.TrimPrefix(parts[0], EnvironmentOverridePrefix), "_", ".", -1)
+		overrides[setting] = parts[1]
+	}
+	return overrides
+}
+
+// readParameterOverrides returns the settings fetched from Parameter Store, by setting path
+func readParameterOverrides() map[string]string {
+	content, err := ioutil.ReadFile(parameterOverridesPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to read the settings fetched from Parameter Store: %v", err)
+		}
+		return nil
+	}
+	var overrides map[string]string
+	if err = json.Unmarshal(content, &overrides); err != nil {
+		log.Printf("failed to read the settings fetched from Parameter Store: %v", err)
+		return nil
+	}
+	return overrides
+}
+
+// SaveParameterOverrides saves the settings fetched from Parameter Store for the agent and its workers,
+// nil overrides remove the saved settings
+func SaveParameterOverrides(overrides map[string]string) error {
+	path := parameterOverridesPath()
+	if overrides == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	content, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), ReadWriteExecuteAccess); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, ReadWriteAccess)
+}
+
+// applyOverrides sets the overridden settings on the configuration, the invalid overrides are logged and ignored
+func applyOverrides(config *SsmagentConfig, source string, overrides map[string]string) {
+	settings := make([]string, 0, len(overrides))
+	for setting := range overrides {
+		settings = append(settings, setting)
+	}
+	sort.Strings(settings)
+	for _, setting := range settings {
+		if err := setSetting(config, setting, overrides[setting]); err != nil {
+			log.Printf("ignoring the %v override of %v: %v", source, setting, err)
+		}
+	}
+}
+
+// setSetting sets the setting at the path such as Mds.CommandWorkersLimit, the names are case-insensitive
+func setSetting(config *SsmagentConfig, setting string, value string) error {
+	field := reflect.ValueOf(config).Elem()
+	for _, name := range strings.Split(setting, ".") {
+		if field.Kind() != reflect.Struct {
+			return fmt.Errorf("unknown setting")
+		}
+		field = field.FieldByNameFunc(func(fieldName string) bool { return strings.EqualFold(fieldName, name) })
+		if !field.IsValid() {
+			return fmt.Errorf("unknown setting")
+		}
+	}
+	return setValue(field, value)
+}
+
+// setValue sets a value given as text, the lists of strings are comma-separated and the sections,
+// maps and other lists are JSON
+func setValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%v is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%v is not an integer", value)
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			return nil
+		}
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	default:
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
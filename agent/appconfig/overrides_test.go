@@ -0,0 +1,128 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setOverrides makes the tests read the environment variables and the settings fetched from Parameter Store
+func setOverrides(t *testing.T, variables []string, parameterOverrides map[string]string) {
+	dir, err := ioutil.TempDir("", "overrides")
+	assert.NoError(t, err)
+	environOrig, parameterOverridesPathOrig := environ, parameterOverridesPath
+	t.Cleanup(func() {
+		environ, parameterOverridesPath = environOrig, parameterOverridesPathOrig
+		os.RemoveAll(dir)
+	})
+	environ = func() []string { return variables }
+	parameterOverridesPath = func() string { return filepath.Join(dir, "data", ParameterOverridesFileName) }
+	assert.NoError(t, SaveParameterOverrides(parameterOverrides))
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	writeConfigFile(t, `{"Mds": {"CommandWorkersLimit": 3}, "Agent": {"Region": "us-east-1"}}`)
+	setOverrides(t, []string{
+		"PATH=/usr/bin",
+		"SSM_AGENT_MDS_COMMANDWORKERSLIMIT=10",
+		"SSM_AGENT_AGENT_ALLOWEDPLUGINS=aws:runShellScript, aws:runDocument",
+		"SSM_AGENT_TELEMETRY_OPTOUT=true",
+		"SSM_AGENT_NETWORK_PROXY={\"Url\": \"http://proxy:3128\"}",
+		"SSM_AGENT_MDS_UNKNOWN=1",
+		"SSM_AGENT_MGS_SESSIONWORKERSLIMIT=many",
+	}, nil)
+
+	config, err := Config(true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, config.Mds.CommandWorkersLimit)
+	assert.Equal(t, "us-east-1", config.Agent.Region)
+	assert.Equal(t, []string{"aws:runShellScript", "aws:runDocument"}, config.Agent.AllowedPlugins)
+	assert.True(t, config.Telemetry.OptOut)
+	assert.Equal(t, "http://proxy:3128", config.Network.Proxy.Url)
+	assert.Equal(t, DefaultConfig().Mgs.SessionWorkersLimit, config.Mgs.SessionWorkersLimit)
+}
+
+func TestFileConfig_IgnoresOverrides(t *testing.T) {
+	writeConfigFile(t, `{"Mds": {"CommandWorkersLimit": 3}, "ConfigOverrides": {"ParameterStorePath": "/agent"}}`)
+	setOverrides(t, []string{"SSM_AGENT_PRIVILEGESEPARATION_USER=root"}, map[string]string{"Mds.CommandWorkersLimit": "8"})
+	path, err := getAppConfigPath()
+	assert.NoError(t, err)
+
+	config, err := FileConfig(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, config.Mds.CommandWorkersLimit)
+	assert.Equal(t, DefaultPrivilegeSeparationUser, config.PrivilegeSeparation.User)
+	assert.Equal(t, []string{DefaultRunAsUserName}, config.PrivilegeSeparation.RunAsUsers)
+}
+
+func TestParameterOverrides(t *testing.T) {
+	writeConfigFile(t, `{"Mds": {"CommandWorkersLimit": 3}, "ConfigOverrides": {"ParameterStorePath": "/agent"}}`)
+	setOverrides(t, []string{"SSM_AGENT_MGS_SESSIONWORKERSLIMIT=20"}, map[string]string{
+		"Mds.CommandWorkersLimit": "8",
+		"Mgs.SessionWorkersLimit": "10",
+	})
+
+	config, err := Config(true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8, config.Mds.CommandWorkersLimit)
+	// the environment variables override Parameter Store
+	assert.Equal(t, 20, config.Mgs.SessionWorkersLimit)
+}
+
+func TestParameterOverrides_NoParameterStorePath(t *testing.T) {
+	writeConfigFile(t, `{"Mds": {"CommandWorkersLimit": 3}}`)
+	setOverrides(t, nil, map[string]string{"Mds.CommandWorkersLimit": "8"})
+
+	config, err := Config(true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, config.Mds.CommandWorkersLimit)
+}
+
+func TestOverrides_NoConfigFile(t *testing.T) {
+	writeConfigFile(t, `{}`)
+	getAppConfigPath = func() (string, error) { return "", os.ErrNotExist }
+	setOverrides(t, []string{"SSM_AGENT_MDS_COMMANDWORKERSLIMIT=10"}, nil)
+
+	config, found, err := readConfig()
+
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 10, config.Mds.CommandWorkersLimit)
+}
+
+func TestSaveParameterOverrides_Remove(t *testing.T) {
+	setOverrides(t, nil, map[string]string{"Mds.CommandWorkersLimit": "8"})
+	assert.Equal(t, map[string]string{"Mds.CommandWorkersLimit": "8"}, readParameterOverrides())
+
+	assert.NoError(t, SaveParameterOverrides(nil))
+	assert.Nil(t, readParameterOverrides())
+	assert.NoError(t, SaveParameterOverrides(nil))
+}
+
+func TestParser_ParameterStorePath(t *testing.T) {
+	config := DefaultConfig()
+	config.ConfigOverrides.ParameterStorePath = "agent/settings"
+	parser(&config)
+	assert.Equal(t, "", config.ConfigOverrides.ParameterStorePath)
+}
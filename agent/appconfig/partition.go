@@ -0,0 +1,99 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"strings"
+	"sync"
+)
+
+// Partition IDs
+const (
+	PartitionAws   = "aws"
+	PartitionChina = "aws-cn"
+	PartitionUsGov = "aws-us-gov"
+	PartitionIso   = "aws-iso"
+	PartitionIsoB  = "aws-iso-b"
+)
+
+const (
+	stsServiceName    = "sts"
+	s3ServiceName     = "s3"
+	dualStackS3Prefix = "s3.dualstack."
+)
+
+// Partition represents a group of AWS regions sharing the same domain
+type Partition struct {
+	ID string
+	// RegionPrefix is the prefix of the names of the regions of the partition
+	RegionPrefix string
+	DnsSuffix    string
+	// DualStackDnsSuffix is the domain of the dual-stack endpoints, empty when the partition has none
+	DualStackDnsSuffix string
+}
+
+// knownPartitions are the partitions whose regions can be recognized by name, the most specific prefix first
+var knownPartitions = []Partition{
+	{ID: PartitionChina, RegionPrefix: "cn-", DnsSuffix: "amazonaws.com.cn", DualStackDnsSuffix: "api.amazonwebservices.com.cn"},
+	{ID: PartitionUsGov, RegionPrefix: "us-gov-", DnsSuffix: "amazonaws.com", DualStackDnsSuffix: "api.aws"},
+	{ID: PartitionIsoB, RegionPrefix: "us-isob-", DnsSuffix: "sc2s.sgov.gov"},
+	{ID: PartitionIso, RegionPrefix: "us-iso-", DnsSuffix: "c2s.ic.gov"},
+	{ID: PartitionAws, DnsSuffix: "amazonaws.com", DualStackDnsSuffix: "api.aws"},
+}
+
+var (
+	partitionLock sync.RWMutex
+	// regionPartitions are the partitions detected for regions, they take precedence over the known partitions
+	regionPartitions = map[string]Partition{}
+)
+
+// KnownPartition returns the known partition with an ID
+func KnownPartition(id string) (Partition, bool) {
+	for _, partition := range knownPartitions {
+		if partition.ID == id {
+			return partition, true
+		}
+	}
+	return Partition{}, false
+}
+
+// SetRegionPartition sets the partition of a region, detected from the instance metadata or the configuration
+func SetRegionPartition(region string, partition Partition) {
+	partitionLock.Lock()
+	defer partitionLock.Unlock()
+	regionPartitions[region] = partition
+}
+
+// GetPartition returns the partition of a region
+func GetPartition(region string) Partition {
+	partitionLock.RLock()
+	partition, ok := regionPartitions[region]
+	partitionLock.RUnlock()
+	if ok {
+		return partition
+	}
+
+	for _, partition := range knownPartitions {
+		if strings.HasPrefix(region, partition.RegionPrefix) {
+			return partition
+		}
+	}
+	return knownPartitions[len(knownPartitions)-1]
+}
+
+// GetRegionalEndpoint returns the regional endpoint of a service in the partition of the region
+func GetRegionalEndpoint(region string, service string) string {
+	return service + "." + region + "." + GetPartition(region).DnsSuffix
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPartition(t *testing.T) {
+	assert.Equal(t, PartitionAws, GetPartition("us-east-1").ID)
+	assert.Equal(t, PartitionChina, GetPartition("cn-northwest-1").ID)
+	assert.Equal(t, PartitionUsGov, GetPartition("us-gov-east-1").ID)
+	assert.Equal(t, PartitionIso, GetPartition("us-iso-east-1").ID)
+	assert.Equal(t, PartitionIsoB, GetPartition("us-isob-east-1").ID)
+	assert.Equal(t, PartitionAws, GetPartition("").ID)
+}
+
+func TestSetRegionPartition(t *testing.T) {
+	t.Cleanup(func() {
+		partitionLock.Lock()
+		defer partitionLock.Unlock()
+		regionPartitions = map[string]Partition{}
+	})
+
+	SetRegionPartition("xx-new-1", Partition{ID: "aws-new", DnsSuffix: "example.com"})
+
+	assert.Equal(t, "aws-new", GetPartition("xx-new-1").ID)
+	assert.Equal(t, "ssm.xx-new-1.example.com", GetRegionalEndpoint("xx-new-1", "ssm"))
+	assert.Equal(t, "ssm.xx-new-1.example.com", GetDefaultEndPoint("xx-new-1", "ssm"))
+	assert.Equal(t, PartitionAws, GetPartition("xx-other-1").ID)
+}
+
+func TestKnownPartition(t *testing.T) {
+	partition, ok := KnownPartition(PartitionChina)
+	assert.True(t, ok)
+	assert.Equal(t, "amazonaws.com.cn", partition.DnsSuffix)
+
+	_, ok = KnownPartition("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// reloadableSetting is a setting applied to the running agent when the config file is reloaded
+type reloadableSetting struct {
+	name string
+	// field returns a pointer to the setting in the configuration
+	field func(config *SsmagentConfig) interface{}
+}
+
+// reloadableSettings are the settings applied without restarting the agent, the components read them when they
+// use them, the other settings need an agent restart
+var reloadableSettings = []reloadableSetting{
+	{"Agent.LogLevel", func(c *SsmagentConfig) interface{} { return &c.Agent.LogLevel }},
+	{"Agent.DebugLogMinutes", func(c *SsmagentConfig) interface{} { return &c.Agent.DebugLogMinutes }},
+	{"Agent.DrainTimeoutSeconds", func(c *SsmagentConfig) interface{} { return &c.Agent.DrainTimeoutSeconds }},
+	{"Agent.AllowedPlugins", func(c *SsmagentConfig) interface{} { return &c.Agent.AllowedPlugins }},
+	{"Agent.DisabledPlugins", func(c *SsmagentConfig) interface{} { return &c.Agent.DisabledPlugins }},
+	{"Mds.StopTimeoutMillis", func(c *SsmagentConfig) interface{} { return &c.Mds.StopTimeoutMillis }},
+	{"Mgs.StopTimeoutMillis", func(c *SsmagentConfig) interface{} { return &c.Mgs.StopTimeoutMillis }},
+	{"Profile.CredentialProcessTimeoutSeconds", func(c *SsmagentConfig) interface{} { return &c.Profile.CredentialProcessTimeoutSeconds }},
+	{"PackageHooks.TimeoutSeconds", func(c *SsmagentConfig) interface{} { return &c.PackageHooks.TimeoutSeconds }},
+	{"Update.Hooks.TimeoutSeconds", func(c *SsmagentConfig) interface{} { return &c.Update.Hooks.TimeoutSeconds }},
+	{"SessionHooks", func(c *SsmagentConfig) interface{} { return &c.SessionHooks }},
+	{"Network.Proxy", func(c *SsmagentConfig) interface{} { return &c.Network.Proxy }},
+	{"Ssm.Proxy", func(c *SsmagentConfig) interface{} { return &c.Ssm.Proxy }},
+	{"Mds.Proxy", func(c *SsmagentConfig) interface{} { return &c.Mds.Proxy }},
+	{"Mgs.Proxy", func(c *SsmagentConfig) interface{} { return &c.Mgs.Proxy }},
+	{"S3.Proxy", func(c *SsmagentConfig) interface{} { return &c.S3.Proxy }},
+	{"Kms.Proxy", func(c *SsmagentConfig) interface{} { return &c.Kms.Proxy }},
+	{"Logs.Proxy", func(c *SsmagentConfig) interface{} { return &c.Logs.Proxy }},
+	{"Firehose.Proxy", func(c *SsmagentConfig) interface{} { return &c.Firehose.Proxy }},
+}
+
+// reloadedConfig is the config file read by the last reload, nil until the config file is reloaded
+var reloadedConfig *SsmagentConfig
+
+// value returns the setting of the configuration
+func (s reloadableSetting) value(config *SsmagentConfig) interface{} {
+	return reflect.ValueOf(s.field(config)).Elem().Interface()
+}
+
+// copy sets the setting of the configuration to the setting of the source configuration
+func (s reloadableSetting) copy(config *SsmagentConfig, source *SsmagentConfig) {
+	reflect.ValueOf(s.field(config)).Elem().Set(reflect.ValueOf(s.field(source)).Elem())
+}
+
+// Reload reads the config file again and applies its reloadable settings to the loaded configuration.
+// It returns the names of the reloadable settings that changed and the sections with other changes,
+// which are only applied when the agent restarts.
+func Reload() (applied []string, restartNeeded []string, err error) {
+	fresh, found, err := readConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("config file %v not found", ConfigFilePath())
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	current := DefaultConfig()
+	if loadedConfig != nil {
+		current = *loadedConfig
+	}
+	for _, setting := range reloadableSettings {
+		if !reflect.DeepEqual(setting.value(&current), setting.value(&fresh)) {
+			applied = append(applied, setting.name)
+			setting.copy(&current, &fresh)
+		}
+	}
+	currentValue, freshValue := reflect.ValueOf(current), reflect.ValueOf(fresh)
+	for i := 0; i < currentValue.NumField(); i++ {
+		if !reflect.DeepEqual(currentValue.Field(i).Interface(), freshValue.Field(i).Interface()) {
+			restartNeeded = append(restartNeeded, currentValue.Type().Field(i).Name)
+		}
+	}
+	loadedConfig = &current
+	reloadedConfig = &fresh
+	return applied, restartNeeded, nil
+}
+
+// WithReloadedSettings returns the configuration with the reloadable settings of the last reload of the config file,
+// the configuration is returned unchanged until the config file is reloaded
+func WithReloadedSettings(config SsmagentConfig) SsmagentConfig {
+	lock.RLock()
+	defer lock.RUnlock()
+	if reloadedConfig == nil {
+		return config
+	}
+	for _, setting := range reloadableSettings {
+		setting.copy(&config, reloadedConfig)
+	}
+	return config
+}
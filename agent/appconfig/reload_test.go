@@ -0,0 +1,95 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfigFile makes the tests read the config file with the content and restores the loaded configuration
+func writeConfigFile(t *testing.T, content string) {
+	dir, err := ioutil.TempDir("", "appconfig")
+	assert.NoError(t, err)
+	path := filepath.Join(dir, AppConfigFileName)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	getAppConfigPathOrig := getAppConfigPath
+	lock.Lock()
+	loadedConfigOrig, reloadedConfigOrig := loadedConfig, reloadedConfig
+	lock.Unlock()
+	t.Cleanup(func() {
+		getAppConfigPath = getAppConfigPathOrig
+		lock.Lock()
+		loadedConfig, reloadedConfig = loadedConfigOrig, reloadedConfigOrig
+		lock.Unlock()
+		os.RemoveAll(dir)
+	})
+	getAppConfigPath = func() (string, error) { return path, nil }
+}
+
+func configPath(t *testing.T) string {
+	path, err := getAppConfigPath()
+	assert.NoError(t, err)
+	return path
+}
+
+func TestReload(t *testing.T) {
+	writeConfigFile(t, `{"Agent": {"LogLevel": "info"}, "Mgs": {"StopTimeoutMillis": 5000}}`)
+	initial, err := Config(true)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(configPath(t), []byte(`{
+		"Agent": {"LogLevel": "debug", "AllowedPlugins": ["aws:runShellScript"]},
+		"Mgs": {"StopTimeoutMillis": 5000, "SessionWorkersLimit": 10},
+		"Network": {"Proxy": {"Url": "http://proxy:3128"}}
+	}`), 0600))
+	applied, restartNeeded, err := Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Agent.LogLevel", "Agent.AllowedPlugins", "Network.Proxy"}, applied)
+	assert.Equal(t, []string{"Mgs"}, restartNeeded)
+
+	// the loaded configuration only has the reloadable settings of the config file
+	reloaded, err := Config(false)
+	assert.NoError(t, err)
+	assert.Equal(t, "debug", reloaded.Agent.LogLevel)
+	assert.Equal(t, []string{"aws:runShellScript"}, reloaded.Agent.AllowedPlugins)
+	assert.Equal(t, "http://proxy:3128", reloaded.Network.Proxy.Url)
+	assert.Equal(t, initial.Mgs.SessionWorkersLimit, reloaded.Mgs.SessionWorkersLimit)
+
+	// configurations loaded before the reload get the reloaded settings
+	initial = WithReloadedSettings(initial)
+	assert.Equal(t, "debug", initial.Agent.LogLevel)
+	assert.Equal(t, "http://proxy:3128", initial.Network.Proxy.Url)
+}
+
+func TestReload_InvalidFileKeepsConfiguration(t *testing.T) {
+	writeConfigFile(t, `{"Agent": {"LogLevel": "info"}}`)
+	_, err := Config(true)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(configPath(t), []byte(`{"Agent": {"LogLevel": `), 0600))
+	_, _, err = Reload()
+
+	assert.Error(t, err)
+	config, _ := Config(false)
+	assert.Equal(t, "info", config.Agent.LogLevel)
+	assert.Equal(t, "warn", WithReloadedSettings(SsmagentConfig{Agent: AgentInfo{LogLevel: "warn"}}).Agent.LogLevel)
+}
@@ -0,0 +1,36 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// schema-gen generates the JSON schema of the agent config file from the configuration types,
+// it is run from the root of the repository
+func main() {
+	content, err := appconfig.SchemaJSON()
+	if err != nil {
+		log.Fatalf("Error generating the config file schema. %v", err)
+	}
+	if err = ioutil.WriteFile(appconfig.SchemaFileName, content, appconfig.ReadWriteAccess); err != nil {
+		log.Fatalf("Error writing the config file schema. %v", err)
+	}
+	fmt.Printf("Config file schema written to %v\n", appconfig.SchemaFileName)
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package appconfig manages the configuration of the agent.
+package appconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// snapCommonEnvironmentVariable is set by snapd to the data directory shared by the revisions of the snap
+	snapCommonEnvironmentVariable = "SNAP_COMMON"
+
+	// appArmorLabelPath holds the AppArmor label and mode of the current process
+	appArmorLabelPath = "/proc/self/attr/current"
+)
+
+// strictSnapConfinement is evaluated once, the confinement of a process does not change
+var strictSnapConfinement = isStrictSnapLabel(readAppArmorLabel()) && os.Getenv(snapCommonEnvironmentVariable) != ""
+
+// StrictSnapConfinement returns true if the agent runs in a snap under strict confinement, where it can only write
+// to the snap data directories and reaches the rest of the system through the interfaces connected to the snap.
+// The agent installed with a classic snap or a package has access to the whole system.
+func StrictSnapConfinement() bool {
+	return strictSnapConfinement
+}
+
+// SnapPath returns the path under the snap common data directory under strict confinement, the path otherwise
+func SnapPath(path string) string {
+	if !StrictSnapConfinement() {
+		return path
+	}
+	return rebasePath(os.Getenv(snapCommonEnvironmentVariable), path)
+}
+
+// rebasePath returns the path under the root directory, keeping the trailing separator of the directories
+func rebasePath(root string, path string) string {
+	rebased := filepath.Join(root, path)
+	if strings.HasSuffix(path, "/") {
+		rebased += "/"
+	}
+	return rebased
+}
+
+// readAppArmorLabel returns the AppArmor label of the agent process, empty when AppArmor is not available
+func readAppArmorLabel() string {
+	label, err := ioutil.ReadFile(appArmorLabelPath)
+	if err != nil {
+		return ""
+	}
+	return string(label)
+}
+
+// isStrictSnapLabel returns true for the AppArmor label of a snap application confined in enforce mode,
+// such as "snap.amazon-ssm-agent.amazon-ssm-agent (enforce)"; devmode snaps are in complain mode and
+// classic snaps are unconfined.
+func isStrictSnapLabel(label string) bool {
+	label = strings.TrimSpace(strings.TrimRight(label, "\x00"))
+	return strings.HasPrefix(label, "snap.") && strings.HasSuffix(label, "(enforce)")
+}
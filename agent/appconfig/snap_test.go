@@ -0,0 +1,41 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsStrictSnapLabel(t *testing.T) {
+	assert.True(t, isStrictSnapLabel("snap.amazon-ssm-agent.amazon-ssm-agent (enforce)\n"))
+	assert.True(t, isStrictSnapLabel("snap.amazon-ssm-agent.amazon-ssm-agent (enforce)\x00"))
+	assert.False(t, isStrictSnapLabel("snap.amazon-ssm-agent.amazon-ssm-agent (complain)"))
+	assert.False(t, isStrictSnapLabel("unconfined"))
+	assert.False(t, isStrictSnapLabel(""))
+}
+
+func TestRebasePath(t *testing.T) {
+	root := "/var/snap/amazon-ssm-agent/common"
+	assert.Equal(t, root+"/var/lib/amazon/ssm/", rebasePath(root, "/var/lib/amazon/ssm/"))
+	assert.Equal(t, root+"/etc/amazon/ssm/seelog.xml", rebasePath(root, "/etc/amazon/ssm/seelog.xml"))
+}
+
+func TestSnapPathWithoutConfinement(t *testing.T) {
+	defer func(confined bool) { strictSnapConfinement = confined }(strictSnapConfinement)
+	strictSnapConfinement = false
+	assert.Equal(t, "/var/lib/amazon/ssm/", SnapPath("/var/lib/amazon/ssm/"))
+}
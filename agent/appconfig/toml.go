@@ -0,0 +1,406 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlParser parses the TOML used by config files: tables, arrays of tables, dotted keys, strings,
+// integers, floats, booleans, arrays and inline tables. Dates and times are not supported.
+type tomlParser struct {
+	input string
+	pos   int
+}
+
+// parseToml returns the tables of a TOML document as maps
+func parseToml(input string) (map[string]interface{}, error) {
+	p := &tomlParser{input: input}
+	root := make(map[string]interface{})
+	current := root
+	for {
+		p.skipBlank(true)
+		if p.done() {
+			return root, nil
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(p.input[p.pos:], "[["):
+			p.pos += 2
+			var keys []string
+			if keys, err = p.key(); err == nil {
+				if err = p.expect("]]"); err == nil {
+					current, err = p.arrayTable(root, keys)
+				}
+			}
+		case p.peek() == '[':
+			p.pos++
+			var keys []string
+			if keys, err = p.key(); err == nil {
+				if err = p.expect("]"); err == nil {
+					current, err = p.table(root, keys, true)
+				}
+			}
+		default:
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.input[:p.pos], "\n") + 1
+	return fmt.Errorf("line %v: %v", line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips the spaces and comments, and the line breaks when newlines is true
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.done() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case newlines && (c == '\n' || c == '\r'):
+			p.pos++
+		case c == '#':
+			for !p.done() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) expect(token string) error {
+	p.skipBlank(false)
+	if !strings.HasPrefix(p.input[p.pos:], token) {
+		return p.errorf("expected %v", token)
+	}
+	p.pos += len(token)
+	return nil
+}
+
+// endOfLine checks that nothing but a comment follows on the line
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank(false)
+	switch p.peek() {
+	case 0, '\n', '\r':
+		return nil
+	}
+	return p.errorf("unexpected %q at the end of the line", p.peek())
+}
+
+// key parses a dotted key
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank(false)
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			value, err := p.stringValue()
+			if err != nil {
+				return nil, err
+			}
+			key = value
+		default:
+			start := p.pos
+			for !p.done() && isTomlBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			key = p.input[start:p.pos]
+		}
+		keys = append(keys, key)
+		p.skipBlank(false)
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isTomlBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// keyValue parses a key = value pair into the table
+func (p *tomlParser) keyValue(table map[string]interface{}) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if err = p.expect("="); err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.table(table, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, found := parent[last]; found {
+		return p.errorf("duplicate key %v", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// table returns the table at the keys, creating the missing tables, the last table of an array of tables is
+// returned for an array of tables. A table header cannot define a table twice.
+func (p *tomlParser) table(root map[string]interface{}, keys []string, header bool) (map[string]interface{}, error) {
+	table := root
+	for i, key := range keys {
+		switch existing := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			if header && i == len(keys)-1 {
+				return nil, p.errorf("table %v is defined twice", strings.Join(keys, "."))
+			}
+			table = existing
+		case []interface{}:
+			last, ok := existing[len(existing)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("key %v is not a table", strings.Join(keys[:i+1], "."))
+			}
+			table = last
+		default:
+			return nil, p.errorf("key %v is not a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+// arrayTable appends a table to the array of tables at the keys
+func (p *tomlParser) arrayTable(root map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	parent, err := p.table(root, keys[:len(keys)-1], false)
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	table := make(map[string]interface{})
+	switch existing := parent[last].(type) {
+	case nil:
+		parent[last] = []interface{}{table}
+	case []interface{}:
+		parent[last] = append(existing, table)
+	default:
+		return nil, p.errorf("key %v is not an array of tables", strings.Join(keys, "."))
+	}
+	return table, nil
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.stringValue()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(p.input[p.pos:], "true"):
+		p.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(p.input[p.pos:], "false"):
+		p.pos += len("false")
+		return false, nil
+	}
+	return p.number()
+}
+
+func (p *tomlParser) number() (interface{}, error) {
+	start := p.pos
+	for !p.done() && strings.IndexByte("0123456789abcdefoxABCDEFOX_+-.", p.peek()) >= 0 {
+		p.pos++
+	}
+	token := p.input[start:p.pos]
+	if token == "" {
+		return nil, p.errorf("expected a value")
+	}
+	if integer, err := strconv.ParseInt(token, 0, 64); err == nil {
+		return integer, nil
+	}
+	if float, err := strconv.ParseFloat(strings.Replace(token, "_", "", -1), 64); err == nil {
+		return float, nil
+	}
+	return nil, p.errorf("unsupported value %v", token)
+}
+
+func (p *tomlParser) array() (interface{}, error) {
+	p.pos++
+	array := make([]interface{}, 0)
+	for {
+		p.skipBlank(true)
+		if p.peek() == ']' {
+			p.pos++
+			return array, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+		p.skipBlank(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	p.skipBlank(false)
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// stringValue parses a basic or literal string, on one or multiple lines
+func (p *tomlParser) stringValue() (string, error) {
+	quote := p.input[p.pos : p.pos+1]
+	multiline := strings.HasPrefix(p.input[p.pos:], strings.Repeat(quote, 3))
+	delimiter := quote
+	if multiline {
+		delimiter = strings.Repeat(quote, 3)
+	}
+	p.pos += len(delimiter)
+	if multiline {
+		// a line break following the opening delimiter is trimmed
+		if strings.HasPrefix(p.input[p.pos:], "\r\n") {
+			p.pos += 2
+		} else if p.peek() == '\n' {
+			p.pos++
+		}
+	}
+
+	var value strings.Builder
+	for {
+		if p.done() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.input[p.pos:], delimiter) {
+			p.pos += len(delimiter)
+			return value.String(), nil
+		}
+		c := p.peek()
+		switch {
+		case c == '\n' && !multiline:
+			return "", p.errorf("unterminated string")
+		case c == '\\' && quote == `"`:
+			if err := p.escape(&value, multiline); err != nil {
+				return "", err
+			}
+		default:
+			value.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// escape writes the character of the escape sequence of a basic string
+func (p *tomlParser) escape(value *strings.Builder, multiline bool) error {
+	p.pos++
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		value.WriteByte('\b')
+	case 't':
+		value.WriteByte('\t')
+	case 'n':
+		value.WriteByte('\n')
+	case 'f':
+		value.WriteByte('\f')
+	case 'r':
+		value.WriteByte('\r')
+	case '"', '\\':
+		value.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.input) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.input[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape")
+		}
+		value.WriteRune(rune(code))
+		p.pos += size
+	case '\n', ' ', '\t', '\r':
+		if !multiline {
+			return p.errorf("invalid escape sequence")
+		}
+		// a line ending backslash trims the following whitespace
+		p.pos--
+		for !p.done() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+			p.pos++
+		}
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
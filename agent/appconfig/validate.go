@@ -0,0 +1,460 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaFileName is the name of the JSON schema of the config file, installed next to the config file template
+const SchemaFileName = "amazon-ssm-agent.schema.json"
+
+// valueRange is the range of the values of a numeric setting
+type valueRange struct {
+	min int64
+	// max is the highest value, 0 when the setting has no maximum
+	max int64
+	// zeroAllowed is true when 0 turns off the feature of the setting besides the range
+	zeroAllowed bool
+}
+
+// settingRanges are the ranges of the numeric settings the parser enforces, by setting,
+// the keys of a map are replaced by *
+var settingRanges = map[string]valueRange{
+	"Profile.CredentialProcessTimeoutSeconds":   {min: DefaultCredentialProcessTimeoutSecondsMin},
+	"Mds.CommandWorkersLimit":                   {min: DefaultCommandWorkersLimitMin},
+	"Mds.CommandRetryLimit":                     {min: DefaultCommandRetryLimitMin, max: DefaultCommandRetryLimitMax},
+	"Mds.StopTimeoutMillis":                     {min: DefaultStopTimeoutMillisMin, max: DefaultStopTimeoutMillisMax},
+	"Mds.PollBackoffMinMillis":                  {min: DefaultPollBackoffMinMillisMin, max: DefaultPollBackoffMinMillisMax},
+	"Mds.PollBackoffMaxMillis":                  {min: DefaultPollBackoffMinMillisMin, max: DefaultPollBackoffMaxMillisMax},
+	"Mds.ReplyCompressionMinBytes":              {min: DefaultReplyCompressionMinBytesMin, max: DefaultReplyCompressionMinBytesMax, zeroAllowed: true},
+	"Ssm.HealthFrequencyMinutes":                {min: DefaultSsmHealthFrequencyMinutesMin, max: DefaultSsmHealthFrequencyMinutesMax},
+	"Ssm.AssociationFrequencyMinutes":           {min: DefaultSsmAssociationFrequencyMinutesMin, max: DefaultSsmAssociationFrequencyMinutesMax},
+	"Ssm.AssociationLogsRetentionDurationHours": {min: DefaultStateOrchestrationLogsRetentionDurationHoursMin},
+	"Ssm.RunCommandLogsRetentionDurationHours":  {min: DefaultStateOrchestrationLogsRetentionDurationHoursMin},
+	"Ssm.ParallelPackageActionsLimit":           {min: DefaultParallelPackageActionsLimitMin},
+	"Mgs.MaxOutstandingOutputBytes":             {min: MaxOutstandingOutputBytesMin, zeroAllowed: true},
+	"Mgs.ScrollbackBytes":                       {min: 0, max: ScrollbackBytesMax},
+	"Mgs.SessionRetryLimit":                     {min: DefaultSessionRetryLimitMin, max: DefaultSessionRetryLimitMax},
+	"Agent.DebugLogMinutes":                     {min: 0},
+	"Agent.DrainTimeoutSeconds":                 {min: 0},
+	"PackageCache.MaxVersionsPerPackage":        {min: DefaultPackageCacheMaxVersionsPerPackageMin},
+	"PackageCache.MaxSizeMB":                    {min: DefaultPackageCacheMaxSizeMBMin},
+	"PackageCache.MaxAgeDays":                   {min: DefaultPackageCacheMaxAgeDaysMin},
+	"PackageHooks.TimeoutSeconds":               {min: DefaultPackageHooksTimeoutSecondsMin},
+	"Network.Bandwidth.UploadBytesPerSecond":    {min: 0},
+	"Network.Bandwidth.DownloadBytesPerSecond":  {min: 0},
+	"Network.Bandwidth.SessionBytesPerSecond":   {min: 0},
+	"Network.Dns.CacheSeconds":                  {min: DefaultDnsCacheSecondsMin},
+	"Failover.FailureThreshold":                 {min: DefaultFailoverFailureThresholdMin},
+	"Failover.FailbackMinutes":                  {min: DefaultFailoverFailbackMinutesMin},
+	"CircuitBreaker.FailureThreshold":           {min: DefaultCircuitBreakerFailureThresholdMin},
+	"CircuitBreaker.CooldownSeconds":            {min: DefaultCircuitBreakerCooldownSecondsMin},
+	"CircuitBreaker.MaxCooldownSeconds":         {min: DefaultCircuitBreakerCooldownSecondsMin},
+	"Retry.MaxRetries":                          {min: DefaultRetryMaxRetriesMin, max: DefaultRetryMaxRetriesMax},
+	"Retry.BaseDelayMillis":                     {min: DefaultRetryBaseDelayMillisMin, max: DefaultRetryBaseDelayMillisMax},
+	"Retry.MaxDelayMillis":                      {min: DefaultRetryBaseDelayMillisMin, max: DefaultRetryMaxDelayMillisMax},
+	"Retry.Services.*.MaxRetries":               {min: DefaultRetryMaxRetriesMin, max: DefaultRetryMaxRetriesMax},
+	"Retry.Services.*.BaseDelayMillis":          {min: DefaultRetryBaseDelayMillisMin, max: DefaultRetryBaseDelayMillisMax, zeroAllowed: true},
+	"Retry.Services.*.MaxDelayMillis":           {min: DefaultRetryBaseDelayMillisMin, max: DefaultRetryMaxDelayMillisMax, zeroAllowed: true},
+	"Update.Hooks.TimeoutSeconds":               {min: DefaultUpdateHooksTimeoutSecondsMin},
+	"Metrics.Port":                              {min: 0, max: MaxMetricsPort},
+	"Metrics.PublishIntervalSeconds":            {min: DefaultMetricsPublishIntervalSecondsMin},
+	"HealthEndpoint.Port":                       {min: 0, max: MaxHealthEndpointPort},
+	"Audit.MaxSizeMB":                           {min: DefaultAuditMaxSizeMBMin},
+	"Audit.MaxRolls":                            {min: 0},
+	"Audit.RetentionDays":                       {min: 0},
+	"CrashDump.MaxDumps":                        {min: 0},
+	"CrashDump.RetentionDays":                   {min: 0},
+	"Watchdog.CheckIntervalSeconds":             {min: DefaultWatchdogCheckIntervalSecondsMin},
+	"Watchdog.HeartbeatTimeoutMinutes":          {min: DefaultWatchdogHeartbeatTimeoutMinutesMin},
+	"Watchdog.DocumentWorkerTimeoutHours":       {min: DefaultWatchdogDocumentWorkerTimeoutHoursMin},
+	"Watchdog.SessionWorkerTimeoutHours":        {min: 0},
+	"SelfMonitor.CheckIntervalSeconds":          {min: DefaultSelfMonitorCheckIntervalSecondsMin},
+	"SelfMonitor.MaxGoroutines":                 {min: 0},
+	"SelfMonitor.MaxOpenFiles":                  {min: 0},
+	"SelfMonitor.MaxHeapMB":                     {min: 0},
+	"SelfMonitor.ConsecutiveChecks":             {min: DefaultSelfMonitorConsecutiveChecksMin},
+	"ResourceLimits.CPUPercent":                 {min: 0},
+	"ResourceLimits.MemoryMB":                   {min: ResourceLimitsMemoryMBMin, zeroAllowed: true},
+	"ResourceLimits.LoadSheddingPercent":        {min: DefaultResourceLimitsLoadSheddingPercentMin, max: DefaultResourceLimitsLoadSheddingPercentMax},
+	"DiskGuard.ReserveMB":                       {min: 0},
+	"Recovery.RestartDelaySeconds":              {min: DefaultRecoveryRestartDelaySecondsMin, max: DefaultRecoveryRestartDelaySecondsMax},
+	"Recovery.FailureThreshold":                 {min: DefaultRecoveryFailureThresholdMin},
+	"Recovery.ResetPeriodSeconds":               {min: DefaultRecoveryResetPeriodSecondsMin},
+	"Syslog.Facility":                           {min: 0, max: MaxSyslogFacility},
+	"Webhook.Retry.MaxRetries":                  {min: DefaultRetryMaxRetriesMin, max: DefaultRetryMaxRetriesMax},
+	"Webhook.Retry.BaseDelayMillis":             {min: DefaultRetryBaseDelayMillisMin, max: DefaultRetryBaseDelayMillisMax},
+	"Webhook.Retry.MaxDelayMillis":              {min: DefaultRetryBaseDelayMillisMin, max: DefaultRetryMaxDelayMillisMax},
+	"Webhook.TimeoutSeconds":                    {min: DefaultWebhookTimeoutSecondsMin},
+	"SessionHooks.TimeoutSeconds":               {min: DefaultSessionHooksTimeoutSecondsMin},
+	"Firehose.BatchSize":                        {min: DefaultFirehoseBatchSizeMin, max: DefaultFirehoseBatchSizeMax},
+	"S3.UploadPartSizeMB":                       {min: DefaultS3UploadPartSizeMBMin, max: DefaultS3UploadPartSizeMBMax},
+	"S3.UploadConcurrency":                      {min: DefaultS3UploadConcurrencyMin, max: DefaultS3UploadConcurrencyMax},
+	"OutputFailover.CloudWatchTimeoutSeconds":   {min: DefaultOutputFailoverCloudWatchTimeoutSecondsMin},
+	"LocalApi.Port":                             {min: 0, max: MaxLocalApiPort},
+	"BootGate.TimeoutSeconds":                   {min: DefaultBootGateTimeoutSecondsMin},
+	"SshSession.Port":                           {min: 1, max: MaxSshSessionPort},
+	"Profiling.Port":                            {min: 0, max: MaxProfilingPort},
+}
+
+// settingValues are the values of the string settings with a fixed set of values, by setting,
+// an empty value always selects the default
+var settingValues = map[string][]string{
+	"Agent.LogLevel":                LogLevels,
+	"Mgs.ControlChannelTransport":   {ControlChannelTransportWebSocket, ControlChannelTransportGrpc},
+	"Mgs.TokenElevation":            {TokenElevationFull, TokenElevationLimited},
+	"Registration.KeyProtection":    {KeyProtectionNone, KeyProtectionTPM, KeyProtectionKeystore, KeyProtectionDpapiNg},
+	"InstanceMetadata.EndpointMode": {MetadataEndpointModeIPv4, MetadataEndpointModeIPv6},
+	"Update.Channel":                {UpdateChannelStable, UpdateChannelCandidate},
+	"Metrics.Sink":                  {MetricsSinkCloudWatch, MetricsSinkFile, MetricsSinkStatsd},
+	"AppArmor.Mode":                 {AppArmorModeEnforce, AppArmorModeComplain},
+	"Ipc.Transport":                 {IpcTransportFile, IpcTransportNamedPipe},
+	"PowerShell.LanguageMode":       {PowerShellLanguageModeFull, PowerShellLanguageModeConstrained},
+	"PowerShell.ExecutionPolicy":    PowerShellExecutionPolicies,
+	"Syslog.Transport":              {SyslogTransportTLS, SyslogTransportTCP},
+	"SecurityEvents.Format":         {SecurityEventsFormatCEF, SecurityEventsFormatLEEF},
+	"SecurityEvents.Transport":      {SecurityEventsTransportTCP, SecurityEventsTransportUDP},
+}
+
+// ValidationError is a setting of the config file the agent ignores or replaces by its default
+type ValidationError struct {
+	// Line is the line of the setting in the config file, 0 when it is unknown
+	Line int
+	// Field is the path of the setting such as Mds.CommandWorkersLimit, empty for syntax errors
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	message := e.Message
+	if e.Field != "" {
+		message = e.Field + ": " + message
+	}
+	if e.Line == 0 {
+		return message
+	}
+	return fmt.Sprintf("line %v: %v", e.Line, message)
+}
+
+// ValidateFile validates the config file in JSON, YAML or TOML, it returns an error when the file cannot be read.
+// The YAML and TOML files are validated once converted to JSON, so their errors have no line.
+func ValidateFile(path string) ([]ValidationError, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := configFormat(path)
+	if format == "json" {
+		return Validate(content), nil
+	}
+	if content, err = configJSON(format, content); err != nil {
+		return []ValidationError{{Message: err.Error()}}, nil
+	}
+	validationErrors := Validate(content)
+	for i := range validationErrors {
+		validationErrors[i].Line = 0
+	}
+	return validationErrors, nil
+}
+
+// Validate returns the unknown keys, values of the wrong type and values out of range of a config file
+func Validate(content []byte) []ValidationError {
+	v := &validator{content: content, decoder: json.NewDecoder(bytes.NewReader(content))}
+	v.decoder.UseNumber()
+	if err := v.value(reflect.TypeOf(SsmagentConfig{}), "", ""); err != nil {
+		line := v.line()
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			line = v.lineAt(syntaxErr.Offset)
+		} else if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		v.errors = append(v.errors, ValidationError{Line: line, Message: err.Error()})
+	}
+	return v.errors
+}
+
+// validator walks the tokens of a config file along the types of the configuration
+type validator struct {
+	content []byte
+	decoder *json.Decoder
+	errors  []ValidationError
+}
+
+// line returns the line of the last token read
+func (v *validator) line() int {
+	return v.lineAt(v.decoder.InputOffset())
+}
+
+func (v *validator) lineAt(offset int64) int {
+	if offset > int64(len(v.content)) {
+		offset = int64(len(v.content))
+	}
+	return bytes.Count(v.content[:offset], []byte("\n")) + 1
+}
+
+func (v *validator) report(field string, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Line: v.line(), Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// value validates the next value against the type, field is the path of the value and
+// setting is the path with the keys of maps replaced by *
+func (v *validator) value(t reflect.Type, field string, setting string) error {
+	token, err := v.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		// null leaves the setting unchanged
+		return nil
+	}
+	delim, isDelim := token.(json.Delim)
+	switch t.Kind() {
+	case reflect.Struct:
+		if !isDelim || delim != '{' {
+			v.report(field, "must be an object")
+			return v.skip(token)
+		}
+		fields := structFields(t)
+		return v.object(func(key string) error {
+			keyField, keySetting := joinPath(field, key), joinPath(setting, key)
+			structField, found := fields[key]
+			if !found {
+				if name := fieldNameFold(fields, key); name != "" {
+					v.report(keyField, "unknown key, it is spelled %v", name)
+				} else {
+					v.report(keyField, "unknown key")
+				}
+				return v.skipValue()
+			}
+			return v.value(structField.Type, keyField, keySetting)
+		})
+	case reflect.Map:
+		if !isDelim || delim != '{' {
+			v.report(field, "must be an object")
+			return v.skip(token)
+		}
+		return v.object(func(key string) error {
+			return v.value(t.Elem(), joinPath(field, key), joinPath(setting, "*"))
+		})
+	case reflect.Slice:
+		if !isDelim || delim != '[' {
+			v.report(field, "must be an array")
+			return v.skip(token)
+		}
+		for i := 0; v.decoder.More(); i++ {
+			if err = v.value(t.Elem(), fmt.Sprintf("%v[%v]", field, i), setting); err != nil {
+				return err
+			}
+		}
+		_, err = v.decoder.Token()
+		return err
+	case reflect.String:
+		value, ok := token.(string)
+		if !ok {
+			v.report(field, "must be a string")
+			return v.skip(token)
+		}
+		if values, found := settingValues[setting]; found && value != "" && !stringInSlice(value, values) {
+			v.report(field, "%q must be one of %v", value, strings.Join(values, ", "))
+		}
+	case reflect.Bool:
+		if _, ok := token.(bool); !ok {
+			v.report(field, "must be true or false")
+			return v.skip(token)
+		}
+	case reflect.Int, reflect.Int64:
+		number, ok := token.(json.Number)
+		if !ok {
+			v.report(field, "must be an integer")
+			return v.skip(token)
+		}
+		value, err := number.Int64()
+		if err != nil {
+			v.report(field, "%v must be an integer", number)
+			return nil
+		}
+		if r, found := settingRanges[setting]; found && !r.contains(value) {
+			v.report(field, "%v is out of range, it must be %v", value, r)
+		}
+	}
+	return nil
+}
+
+// object validates the members of an object whose opening brace was read
+func (v *validator) object(member func(key string) error) error {
+	for v.decoder.More() {
+		token, err := v.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if err = member(token.(string)); err != nil {
+			return err
+		}
+	}
+	_, err := v.decoder.Token()
+	return err
+}
+
+// skipValue skips the next value
+func (v *validator) skipValue() error {
+	token, err := v.decoder.Token()
+	if err != nil {
+		return err
+	}
+	return v.skip(token)
+}
+
+// skip skips the rest of the value starting with the token
+func (v *validator) skip(token json.Token) error {
+	if _, isDelim := token.(json.Delim); !isDelim {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := v.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+func (r valueRange) contains(value int64) bool {
+	if r.zeroAllowed && value == 0 {
+		return true
+	}
+	return value >= r.min && (r.max == 0 || value <= r.max)
+}
+
+func (r valueRange) String() string {
+	var s string
+	if r.max == 0 {
+		s = fmt.Sprintf("at least %v", r.min)
+	} else {
+		s = fmt.Sprintf("between %v and %v", r.min, r.max)
+	}
+	if r.zeroAllowed {
+		s = "0 or " + s
+	}
+	return s
+}
+
+// structFields returns the fields of a struct type by name, including the fields of its embedded structs
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, embedded := range structFields(field.Type) {
+				fields[name] = embedded
+			}
+			continue
+		}
+		fields[field.Name] = field
+	}
+	return fields
+}
+
+// fieldNameFold returns the name of the field matching the key case-insensitively, empty when there is none
+func fieldNameFold(fields map[string]reflect.StructField, key string) string {
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+	}
+	return ""
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Schema returns the JSON schema of the config file
+func Schema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(SsmagentConfig{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "Amazon SSM Agent configuration"
+	return schema
+}
+
+// SchemaJSON returns the JSON schema of the config file, as written to the schema file
+func SchemaJSON() ([]byte, error) {
+	content, err := json.MarshalIndent(Schema(), "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func typeSchema(t reflect.Type, setting string) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := structFields(t)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		properties := make(map[string]interface{})
+		for _, name := range names {
+			properties[name] = typeSchema(fields[name].Type, joinPath(setting, name))
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), joinPath(setting, "*"))}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), setting)}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		schema := map[string]interface{}{"type": "integer"}
+		if r, found := settingRanges[setting]; found {
+			bounds := map[string]interface{}{"minimum": r.min}
+			if r.max != 0 {
+				bounds["maximum"] = r.max
+			}
+			if r.zeroAllowed {
+				schema["anyOf"] = []interface{}{map[string]interface{}{"const": 0}, bounds}
+			} else {
+				for key, bound := range bounds {
+					schema[key] = bound
+				}
+			}
+		}
+		return schema
+	default:
+		schema := map[string]interface{}{"type": "string"}
+		if values, found := settingValues[setting]; found {
+			schema["enum"] = append([]string{""}, values...)
+		}
+		return schema
+	}
+}
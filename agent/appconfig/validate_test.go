@@ -0,0 +1,82 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package appconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	content := `{
+    "Agent": {
+        "LogLevel": "verbose",
+        "Unknown": {"Nested": [1, 2]},
+        "DrainTimeoutSeconds": "30"
+    },
+    "Mds": {
+        "CommandRetryLimit": 500,
+        "ReplyCompressionMinBytes": 0
+    },
+    "mgs": {},
+    "Retry": {
+        "MaxRetries": 3,
+        "Services": {"ssm": {"MaxRetries": 21}}
+    },
+    "Network": {"Proxy": {"NoProxy": "localhost"}},
+    "Metrics": {"Port": 9100.5}
+}`
+
+	assert.Equal(t, []ValidationError{
+		{Line: 3, Field: "Agent.LogLevel", Message: `"verbose" must be one of trace, debug, info, warn, error, critical, off`},
+		{Line: 4, Field: "Agent.Unknown", Message: "unknown key"},
+		{Line: 5, Field: "Agent.DrainTimeoutSeconds", Message: "must be an integer"},
+		{Line: 8, Field: "Mds.CommandRetryLimit", Message: "500 is out of range, it must be between 1 and 100"},
+		{Line: 11, Field: "mgs", Message: "unknown key, it is spelled Mgs"},
+		{Line: 14, Field: "Retry.Services.ssm.MaxRetries", Message: "21 is out of range, it must be between 0 and 20"},
+		{Line: 16, Field: "Network.Proxy.NoProxy", Message: "must be an array"},
+		{Line: 17, Field: "Metrics.Port", Message: "9100.5 must be an integer"},
+	}, Validate([]byte(content)))
+}
+
+func TestValidate_SyntaxError(t *testing.T) {
+	validationErrors := Validate([]byte("{\n    \"Agent\": {\n        \"Region\": \"us-east-1\",\n    }\n}"))
+
+	assert.Len(t, validationErrors, 1)
+	assert.Equal(t, 3, validationErrors[0].Line)
+	assert.Equal(t, "", validationErrors[0].Field)
+	assert.Equal(t, "line 3: invalid character ',' looking for beginning of value", validationErrors[0].Error())
+
+	assert.Equal(t, []ValidationError{{Line: 1, Message: "unexpected EOF"}}, Validate([]byte("")))
+}
+
+func TestValidate_Template(t *testing.T) {
+	validationErrors, err := ValidateFile(filepath.Join("..", "..", "amazon-ssm-agent.json.template"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, validationErrors)
+}
+
+func TestSchemaFileIsUpToDate(t *testing.T) {
+	expected, err := SchemaJSON()
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join("..", "..", SchemaFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(content), "regenerate the schema with go run agent/appconfig/schemagenerator/schema-gen.go")
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/firmware"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
@@ -43,6 +44,7 @@ import (
 
 	AssociationModel "github.com/aws/amazon-ssm-agent/agent/association/model"
 	InventoryModel "github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/resourcelimits"
 )
 
 const (
@@ -72,7 +74,7 @@ func GetFrequentCollector() *FrequentCollector {
 	return frequentCollector
 }
 
-//ClearTicker stops the ticker for frequent collector, and sets it to nil
+// ClearTicker stops the ticker for frequent collector, and sets it to nil
 func (collector *FrequentCollector) ClearTicker() {
 	collector.mutex.RLock()
 	defer collector.mutex.RUnlock()
@@ -83,7 +85,7 @@ func (collector *FrequentCollector) ClearTicker() {
 	}
 }
 
-//resetTicker stop the old ticker for frequent collector and creates a new one
+// resetTicker stop the old ticker for frequent collector and creates a new one
 func (collector *FrequentCollector) resetTicker(d time.Duration) *time.Ticker {
 	collector.ClearTicker()
 
@@ -94,7 +96,7 @@ func (collector *FrequentCollector) resetTicker(d time.Duration) *time.Ticker {
 	return collector.tickerForFrequentCollector
 }
 
-//StartFrequentCollector starts the frequent collector per association configuration.
+// StartFrequentCollector starts the frequent collector per association configuration.
 func (collector *FrequentCollector) StartFrequentCollector(context context.T, docState *contracts.DocumentState, scheduledAssociation *AssociationModel.InstanceAssociation) {
 	collector.mutex.RLock()
 	defer collector.mutex.RUnlock()
@@ -116,12 +118,16 @@ func (collector *FrequentCollector) StartFrequentCollector(context context.T, do
 	go func() {
 		for t := range ticker.C {
 			log.Infof("Frequent collector, tick at %s, ticker address : %p", t.Format(time.UnixDate), collector.tickerForFrequentCollector)
+			if resourcelimits.UnderPressure() {
+				log.Info("Frequent collector, skipping the collection while the agent is approaching its resource limits")
+				continue
+			}
 			collector.collect(context, docState)
 		}
 	}()
 }
 
-//IsFrequentCollectorEnabled return a boolean indicating if the frequent collector is enabled per association configuration.
+// IsFrequentCollectorEnabled return a boolean indicating if the frequent collector is enabled per association configuration.
 func (collector *FrequentCollector) IsFrequentCollectorEnabled(docState *contracts.DocumentState, scheduledAssociation *AssociationModel.InstanceAssociation) bool {
 	log := log.DefaultLogger()
 
@@ -146,7 +152,7 @@ func (collector *FrequentCollector) IsFrequentCollectorEnabled(docState *contrac
 	return frequencyPresent && intervalInSeconds > 0
 }
 
-//GetIntervalInSeconds return the interval in seconds for frequent collector while the change detection frequency is specified
+// GetIntervalInSeconds return the interval in seconds for frequent collector while the change detection frequency is specified
 func (collector *FrequentCollector) GetIntervalInSeconds(changeDetectionFrequency int, parsedExpression scheduleexpression.ScheduleExpression) (bool, int) {
 	if changeDetectionFrequency > 1 {
 		//get the rate in seconds
@@ -162,7 +168,7 @@ func (collector *FrequentCollector) GetIntervalInSeconds(changeDetectionFrequenc
 	}
 }
 
-//getFrequentCollectInformation return frequent collector's frequency and watched inventory types.
+// getFrequentCollectInformation return frequent collector's frequency and watched inventory types.
 func (collector *FrequentCollector) getFrequentCollectInformation(log log.T, docState *contracts.DocumentState) (changeDetectionFrequency int, listOfGathererNames []string) {
 	var pluginState *contracts.PluginState = collector.getInventoryPluginState(docState)
 	if pluginState != nil {
@@ -214,7 +220,7 @@ func (collector *FrequentCollector) getFrequentCollectInformation(log log.T, doc
 	return 0, listOfGathererNames
 }
 
-//getInventoryPluginState return the pointer to the inventory plugin state
+// getInventoryPluginState return the pointer to the inventory plugin state
 func (collector *FrequentCollector) getInventoryPluginState(docState *contracts.DocumentState) *contracts.PluginState {
 	for _, plugin := range docState.InstancePluginsInformation {
 		if plugin.Name == appconfig.PluginNameAwsSoftwareInventory {
@@ -224,12 +230,12 @@ func (collector *FrequentCollector) getInventoryPluginState(docState *contracts.
 	return nil
 }
 
-//IsSoftwareInventoryAssociation return true if it's a software inventory association.
+// IsSoftwareInventoryAssociation return true if it's a software inventory association.
 func (collector *FrequentCollector) IsSoftwareInventoryAssociation(docState *contracts.DocumentState) bool {
 	return collector.getInventoryPluginState(docState) != nil
 }
 
-//collect collects the dirty inventory types and report to SSM if there's any
+// collect collects the dirty inventory types and report to SSM if there's any
 func (collector *FrequentCollector) collect(context context.T, docState *contracts.DocumentState) {
 	log := context.Log()
 
@@ -250,7 +256,7 @@ func (collector *FrequentCollector) collect(context context.T, docState *contrac
 	}
 }
 
-//getGatherersForFrequentCollectTypes return the map of gatherers and InventoryModel.Config
+// getGatherersForFrequentCollectTypes return the map of gatherers and InventoryModel.Config
 func (collector *FrequentCollector) getGatherersForFrequentCollectTypes(context context.T, docState *contracts.DocumentState, plugin *inventory.Plugin, namesOfItems []string) *map[gatherers.T]InventoryModel.Config {
 	log := context.Log()
 	var gatherersMap = make(map[gatherers.T]InventoryModel.Config)
@@ -281,7 +287,7 @@ func (collector *FrequentCollector) getGatherersForFrequentCollectTypes(context
 	return &gatherersMap
 }
 
-//getValidInventoryGathererConfigMap return the map of valid gatherers and their InventoryModel.config
+// getValidInventoryGathererConfigMap return the map of valid gatherers and their InventoryModel.config
 func (collector *FrequentCollector) getValidInventoryGathererConfigMap(context context.T, docState *contracts.DocumentState, plugin *inventory.Plugin) (validGatherers map[gatherers.T]InventoryModel.Config) {
 	log := context.Log()
 	var dataB []byte
@@ -307,7 +313,7 @@ func (collector *FrequentCollector) getValidInventoryGathererConfigMap(context c
 	return
 }
 
-//getSupportedGathererNames returns a map of gatherer names, using the all lower case as key, the normal gatherer name as value. This is to allow customer to input gatherer name ignoring case.
+// getSupportedGathererNames returns a map of gatherer names, using the all lower case as key, the normal gatherer name as value. This is to allow customer to input gatherer name ignoring case.
 func (collector *FrequentCollector) getSupportedGathererNames() map[string]string {
 	var gathererNameMap = make(map[string]string)
 	//only "AWS:Applications" is supported for now
@@ -315,7 +321,7 @@ func (collector *FrequentCollector) getSupportedGathererNames() map[string]strin
 	return gathererNameMap
 }
 
-//getGathererParameterMap returns a map taking gatherer name as key and document parameter as value, used to check if the gatherer is enabled in plugin state.
+// getGathererParameterMap returns a map taking gatherer name as key and document parameter as value, used to check if the gatherer is enabled in plugin state.
 func (collector *FrequentCollector) getGathererParameterMap() map[string]string {
 	var paramGathererMap = make(map[string]string)
 	paramGathererMap[strings.ToLower(application.GathererName)] = "applications"
@@ -327,5 +333,6 @@ func (collector *FrequentCollector) getGathererParameterMap() map[string]string
 	paramGathererMap[strings.ToLower(registry.GathererName)] = "windowsRegistry"
 	paramGathererMap[strings.ToLower(role.GathererName)] = "windowsRoles"
 	paramGathererMap[strings.ToLower(instancedetailedinformation.GathererName)] = "instanceDetailedInformation"
+	paramGathererMap[strings.ToLower(firmware.GathererName)] = "firmwareInformation"
 	return paramGathererMap
 }
@@ -30,14 +30,14 @@ type AssocList []string
 var assocParser parserService = &assocParserService{}
 var assocBookkeeping bookkeepingService = &assocBookkeepingService{}
 
-//PluginAssociationInstances cached the number of associations attached to a specific type of plugin
+// PluginAssociationInstances cached the number of associations attached to a specific type of plugin
 var pluginAssociationInstances = make(map[string]AssocList)
 
 func getPluginAssociationInstances() map[string]AssocList {
 	return pluginAssociationInstances
 }
 
-//TODO in future, platform calls will be stubbed
+// TODO in future, platform calls will be stubbed
 var sys system = &systemImp{}
 
 // bookkeepingService represents the dependency for docmanager
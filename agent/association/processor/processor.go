@@ -36,6 +36,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/resourcelimits"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/carlescere/scheduler"
 )
@@ -272,6 +273,12 @@ func (p *Processor) runScheduledAssociation(log log.T) {
 		}
 	}()
 
+	if resourcelimits.UnderPressure() {
+		log.Infof("The agent is approaching its resource limits, deferring the scheduled associations by %v", resourcelimits.DeferInterval)
+		signal.ResetWaitTimerForNextScheduledAssociation(log, time.Now().Add(resourcelimits.DeferInterval))
+		return
+	}
+
 	var (
 		scheduledAssociation *model.InstanceAssociation
 		err                  error
@@ -655,8 +662,8 @@ func filterByStatus(runtimeStatuses map[string]*contracts.PluginRuntimeStatus, p
 	return result
 }
 
-//This operation is locked by runScheduledAssociation
-//lazy update, update only when the document is ready to run, update will validate and invalidate current attached association
+// This operation is locked by runScheduledAssociation
+// lazy update, update only when the document is ready to run, update will validate and invalidate current attached association
 func updatePluginAssociationInstances(associationID string, docState *contracts.DocumentState) {
 	currentPluginAssociations := getPluginAssociationInstances()
 	for i := 0; i < len(docState.InstancePluginsInformation); i++ {
@@ -0,0 +1,154 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package audit records the security-relevant events of the agent in an append-only audit log,
+// apart from the agent logs, and optionally forwards them to CloudWatch Logs.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+)
+
+// Event types of the audit log
+const (
+	SessionStarted             = "SessionStarted"
+	SessionEnded               = "SessionEnded"
+	DocumentExecutionRequested = "DocumentExecutionRequested"
+	DocumentExecutionCanceled  = "DocumentExecutionCanceled"
+	DocumentExecutionCompleted = "DocumentExecutionCompleted"
+	AgentUpdate                = "AgentUpdate"
+	BinaryIntegrityMismatch    = "BinaryIntegrityMismatch"
+	SshSessionAllowed          = "SshSessionAllowed"
+	SshSessionDenied           = "SshSessionDenied"
+	ProcessStarted             = "ProcessStarted"
+)
+
+// Event is a security-relevant event, written as one JSON object per line in the audit log
+type Event struct {
+	Time         string   `json:"time"`
+	Type         string   `json:"type"`
+	MessageID    string   `json:"messageId,omitempty"`
+	CommandID    string   `json:"commandId,omitempty"`
+	SessionID    string   `json:"sessionId,omitempty"`
+	DocumentName string   `json:"documentName,omitempty"`
+	RunAsUser    string   `json:"runAsUser,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	Detail       string   `json:"detail,omitempty"`
+	Pid          int      `json:"pid,omitempty"`
+	ParentPids   []int    `json:"parentPids,omitempty"`
+	Argv         []string `json:"argv,omitempty"`
+	Uid          string   `json:"uid,omitempty"`
+}
+
+// timeNow returns the current time, it is stubbed in the tests
+var timeNow = time.Now
+
+// sink holds the audit log file, the forwarding queues and the observers by name, the events are dropped when the
+// audit log is not enabled and nothing observes them
+var sink struct {
+	sync.RWMutex
+	file         *log.RotatingFileReceiver
+	events       chan string
+	syslogEvents chan syslogEvent
+	observers    map[string]func(Event)
+}
+
+// syslogEvent is an event queued for the syslog server, its type is the MSGID of the message
+type syslogEvent struct {
+	eventType string
+	line      string
+}
+
+// Enabled returns whether the events are recorded
+func Enabled() bool {
+	sink.RLock()
+	defer sink.RUnlock()
+	return sink.file != nil
+}
+
+// SetObserver sets the function of an observer receiving every event, whether or not the audit log is enabled,
+// nil removes it
+func SetObserver(name string, observer func(Event)) {
+	sink.Lock()
+	defer sink.Unlock()
+	if observer == nil {
+		delete(sink.observers, name)
+		return
+	}
+	if sink.observers == nil {
+		sink.observers = make(map[string]func(Event))
+	}
+	sink.observers[name] = observer
+}
+
+// Record writes the event to the audit log, queues it for forwarding and passes it to the observer
+func Record(event Event) {
+	sink.RLock()
+	defer sink.RUnlock()
+	if sink.file == nil && len(sink.observers) == 0 {
+		return
+	}
+	event.Time = timeNow().UTC().Format(time.RFC3339Nano)
+	for _, observer := range sink.observers {
+		observer(event)
+	}
+	if sink.file == nil {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		fmt.Println("Error encoding audit event: ", err)
+		return
+	}
+	if err = sink.file.ReceiveMessage(string(line)+"\n", seelog.InfoLvl, nil); err != nil {
+		fmt.Println("Error writing audit event: ", err)
+	}
+	if sink.events != nil {
+		select {
+		case sink.events <- string(line):
+		default:
+		}
+	}
+	if sink.syslogEvents != nil {
+		select {
+		case sink.syslogEvents <- syslogEvent{eventType: event.Type, line: string(line)}:
+		default:
+		}
+	}
+}
+
+// RunAsUser returns the user the shell of a session runs as, the agent user when the session runs elevated
+func RunAsUser(runAsElevated bool) string {
+	if !runAsElevated {
+		return appconfig.DefaultRunAsUserName
+	}
+	return agentUser()
+}
+
+// agentUser returns the name of the user the agent runs as
+func agentUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return fmt.Sprint(os.Getuid())
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func setAuditFile(t *testing.T, path string) func() {
+	file := &log.RotatingFileReceiver{}
+	assert.NoError(t, file.AfterParse(seelog.CustomReceiverInitArgs{XmlCustomAttrs: map[string]string{"filename": path}}))
+	sink.Lock()
+	sink.file = file
+	sink.Unlock()
+	return func() {
+		sink.Lock()
+		sink.file = nil
+		sink.Unlock()
+		file.Close()
+	}
+}
+
+func TestRecord_Disabled(t *testing.T) {
+	assert.False(t, Enabled())
+	// recording without an audit log is a no-op
+	Record(Event{Type: SessionStarted, SessionID: "session-id"})
+}
+
+func TestRecord_AppendsJSONLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, FileName)
+
+	timeNow = func() time.Time { return time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	restore := setAuditFile(t, path)
+	assert.True(t, Enabled())
+	Record(Event{Type: SessionStarted, SessionID: "session-id", DocumentName: "SSM-SessionManagerRunShell", RunAsUser: "ssm-user"})
+	Record(Event{Type: SessionEnded, SessionID: "session-id", Status: "Success"})
+	restore()
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `{"time":"2019-03-01T10:00:00Z","type":"SessionStarted","sessionId":"session-id","documentName":"SSM-SessionManagerRunShell","runAsUser":"ssm-user"}`, lines[0])
+
+	var event Event
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, Event{Time: "2019-03-01T10:00:00Z", Type: SessionEnded, SessionID: "session-id", Status: "Success"}, event)
+}
+
+func TestRecord_Observer(t *testing.T) {
+	var observed []Event
+	SetObserver("test", func(event Event) { observed = append(observed, event) })
+	defer SetObserver("test", nil)
+
+	assert.False(t, Enabled())
+	Record(Event{Type: DocumentExecutionCompleted, CommandID: "command-id", Status: "Failed"})
+
+	assert.Len(t, observed, 1)
+	assert.Equal(t, "command-id", observed[0].CommandID)
+	assert.NotEmpty(t, observed[0].Time)
+}
+
+func TestRunAsUser(t *testing.T) {
+	assert.Equal(t, appconfig.DefaultRunAsUserName, RunAsUser(false))
+	assert.NotEmpty(t, RunAsUser(true))
+}
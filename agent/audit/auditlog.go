@@ -0,0 +1,249 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package audit
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/syslog"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/cihub/seelog"
+)
+
+const (
+	name = "AuditLog"
+
+	// FileName is the name of the audit log file in the log directory of the agent
+	FileName = "audit.log"
+
+	// queueSize is the number of events buffered for forwarding, events are not forwarded when it is full
+	queueSize = 1024
+
+	// maxBatchSize is the maximum number of events forwarded in a single request
+	maxBatchSize = 256
+)
+
+// forwardInterval is the interval at which the buffered events are forwarded
+var forwardInterval = 5 * time.Second
+
+// filePath is the path of the audit log file
+var filePath = filepath.Join(log.DefaultLogDir, FileName)
+
+// instanceID returns the instance id, the name of the log stream the events are forwarded to
+var instanceID = platform.InstanceID
+
+// newCloudWatchLogsService creates the CloudWatch Logs client the events are forwarded with
+var newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+	return cloudwatchlogspublisher.NewCloudWatchLogsService()
+}
+
+// AuditLog is the core module writing the audit log and forwarding its events to CloudWatch Logs and syslog
+type AuditLog struct {
+	context      context.T
+	file         *log.RotatingFileReceiver
+	logGroup     string
+	events       chan string
+	syslog       *syslog.Writer
+	syslogEvents chan syslogEvent
+	stop         chan bool
+	done         chan bool
+	syslogDone   chan bool
+}
+
+// NewAuditLog creates the audit log, nil when the audit log is not enabled in appconfig
+func NewAuditLog(context context.T) *AuditLog {
+	config := context.AppConfig().Audit
+	if !config.Enabled {
+		return nil
+	}
+	file := &log.RotatingFileReceiver{}
+	err := file.AfterParse(seelog.CustomReceiverInitArgs{
+		XmlCustomAttrs: map[string]string{
+			"filename":      filePath,
+			"maxsize":       strconv.Itoa(config.MaxSizeMB * 1000000),
+			"maxrolls":      strconv.Itoa(config.MaxRolls),
+			"retentiondays": strconv.Itoa(config.RetentionDays),
+		},
+	})
+	if err != nil {
+		context.Log().Errorf("Failed to configure the audit log: %v", err)
+		return nil
+	}
+	auditLog := &AuditLog{
+		context:    context.With("[" + name + "]"),
+		file:       file,
+		logGroup:   config.LogGroup,
+		stop:       make(chan bool),
+		done:       make(chan bool),
+		syslogDone: make(chan bool),
+	}
+	if auditLog.logGroup != "" {
+		auditLog.events = make(chan string, queueSize)
+	}
+	if syslogConfig := context.AppConfig().Syslog; syslog.AuditEnabled(syslogConfig) {
+		auditLog.syslog = syslog.NewWriter(syslogConfig)
+		auditLog.syslogEvents = make(chan syslogEvent, queueSize)
+	}
+	// the events are recorded from the creation of the module, the other core modules start concurrently
+	sink.Lock()
+	sink.file = auditLog.file
+	sink.events = auditLog.events
+	sink.syslogEvents = auditLog.syslogEvents
+	sink.Unlock()
+	return auditLog
+}
+
+// ModuleName returns the name of the module
+func (a *AuditLog) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts forwarding the events
+func (a *AuditLog) ModuleExecute(context context.T) (err error) {
+	if a.events == nil {
+		close(a.done)
+	} else {
+		a.context.Log().Infof("Forwarding audit events to log group %v", a.logGroup)
+		go a.forward()
+	}
+	if a.syslogEvents == nil {
+		close(a.syslogDone)
+	} else {
+		a.context.Log().Infof("Forwarding audit events to syslog server %v", context.AppConfig().Syslog.Address)
+		go a.forwardToSyslog()
+	}
+	return nil
+}
+
+// ModuleRequestStop stops recording the events, forwards the buffered events and closes the audit log
+func (a *AuditLog) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	sink.Lock()
+	sink.file = nil
+	sink.events = nil
+	sink.syslogEvents = nil
+	sink.Unlock()
+
+	close(a.stop)
+	<-a.done
+	<-a.syslogDone
+	return a.file.Close()
+}
+
+// forward sends the events to the log stream of the instance, when the batch is full or at every forward interval
+func (a *AuditLog) forward() {
+	defer close(a.done)
+	ticker := time.NewTicker(forwardInterval)
+	defer ticker.Stop()
+
+	service := newCloudWatchLogsService()
+	logStream, _ := instanceID()
+	var sequenceToken *string
+	var streamReady bool
+
+	var batch []*cloudwatchlogs.InputLogEvent
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		log := a.context.Log()
+		if !streamReady {
+			if streamReady = a.createLogStream(service, logStream); !streamReady {
+				log.Warnf("dropping %v audit events", len(batch))
+				batch = nil
+				return
+			}
+			sequenceToken = service.GetSequenceTokenForStream(log, a.logGroup, logStream)
+		}
+		next, err := service.PutLogEvents(log, batch, a.logGroup, logStream, sequenceToken)
+		if err != nil {
+			log.Warnf("failed to forward %v audit events: %v", len(batch), err)
+		} else {
+			sequenceToken = next
+		}
+		batch = nil
+	}
+	add := func(event string) {
+		batch = append(batch, &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(event),
+			Timestamp: aws.Int64(timeNow().UnixNano() / int64(time.Millisecond)),
+		})
+		if len(batch) >= maxBatchSize {
+			send()
+		}
+	}
+	for {
+		select {
+		case event := <-a.events:
+			add(event)
+		case <-ticker.C:
+			send()
+		case <-a.stop:
+			for len(a.events) > 0 {
+				add(<-a.events)
+			}
+			send()
+			return
+		}
+	}
+}
+
+// forwardToSyslog sends the events to the syslog server as they are recorded, with their type as MSGID
+func (a *AuditLog) forwardToSyslog() {
+	defer close(a.syslogDone)
+	defer a.syslog.Close()
+
+	send := func(event syslogEvent) {
+		if err := a.syslog.Write(syslog.SeverityNotice, event.eventType, event.line); err != nil {
+			a.context.Log().Warnf("failed to forward the %v audit event: %v", event.eventType, err)
+		}
+	}
+	for {
+		select {
+		case event := <-a.syslogEvents:
+			send(event)
+		case <-a.stop:
+			for len(a.syslogEvents) > 0 {
+				send(<-a.syslogEvents)
+			}
+			return
+		}
+	}
+}
+
+// createLogStream creates the log group and the log stream of the instance when they are not present
+func (a *AuditLog) createLogStream(service cloudwatchlogsinterface.ICloudWatchLogsService, logStream string) bool {
+	log := a.context.Log()
+	if !service.IsLogGroupPresent(log, a.logGroup) {
+		if err := service.CreateLogGroup(log, a.logGroup); err != nil {
+			log.Errorf("Error creating audit log group %v: %v", a.logGroup, err)
+			return false
+		}
+	}
+	if !service.IsLogStreamPresent(log, a.logGroup, logStream) {
+		if err := service.CreateLogStream(log, a.logGroup, logStream); err != nil {
+			log.Errorf("Error creating audit log stream %v: %v", logStream, err)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package audit
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithAudit(enabled bool, logGroup string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.Audit.Enabled = enabled
+	config.Audit.LogGroup = logGroup
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func TestNewAuditLog_Disabled(t *testing.T) {
+	assert.Nil(t, NewAuditLog(mockContextWithAudit(false, "")))
+	assert.False(t, Enabled())
+}
+
+func TestAuditLog_WritesWithoutForwarding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath = filepath.Join(dir, FileName)
+
+	ctx := mockContextWithAudit(true, "")
+	auditLog := NewAuditLog(ctx)
+	assert.NotNil(t, auditLog)
+	assert.True(t, Enabled())
+	assert.Equal(t, name, auditLog.ModuleName())
+	assert.NoError(t, auditLog.ModuleExecute(ctx))
+
+	Record(Event{Type: AgentUpdate, CommandID: "command-id", Status: "Success"})
+	assert.NoError(t, auditLog.ModuleRequestStop(contracts.StopTypeSoftStop))
+	assert.False(t, Enabled())
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"type":"AgentUpdate","commandId":"command-id","status":"Success"`)
+}
+
+func TestAuditLog_ForwardsEventsOnStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath = filepath.Join(dir, FileName)
+
+	service := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	service.On("IsLogGroupPresent", mock.Anything, "audit-group").Return(false)
+	service.On("CreateLogGroup", mock.Anything, "audit-group").Return(nil)
+	service.On("IsLogStreamPresent", mock.Anything, "audit-group", "i-1234567890").Return(true)
+	service.On("GetSequenceTokenForStream", mock.Anything, "audit-group", "i-1234567890").Return(aws.String("token"))
+	service.On("PutLogEvents", mock.Anything, mock.Anything, "audit-group", "i-1234567890", aws.String("token")).Return(aws.String("next"), nil)
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService { return service }
+	instanceID = func() (string, error) { return "i-1234567890", nil }
+
+	ctx := mockContextWithAudit(true, "audit-group")
+	auditLog := NewAuditLog(ctx)
+	assert.NotNil(t, auditLog)
+	assert.NoError(t, auditLog.ModuleExecute(ctx))
+
+	Record(Event{Type: DocumentExecutionRequested, CommandID: "command-id", DocumentName: "AWS-RunShellScript"})
+	Record(Event{Type: DocumentExecutionCompleted, CommandID: "command-id", Status: "Success"})
+	assert.NoError(t, auditLog.ModuleRequestStop(contracts.StopTypeSoftStop))
+
+	service.AssertExpectations(t)
+	events := service.Calls[len(service.Calls)-1].Arguments.Get(1).([]*cloudwatchlogs.InputLogEvent)
+	assert.Len(t, events, 2)
+	assert.Contains(t, *events[0].Message, `"type":"DocumentExecutionRequested"`)
+	assert.Contains(t, *events[1].Message, `"type":"DocumentExecutionCompleted"`)
+}
+
+func TestAuditLog_ForwardsEventsToSyslog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath = filepath.Join(dir, FileName)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		content, _ := ioutil.ReadAll(conn)
+		received <- string(content)
+	}()
+
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.Audit.Enabled = true
+	config.Syslog.Address = listener.Addr().String()
+	config.Syslog.Transport = appconfig.SyslogTransportTCP
+	config.Syslog.Audit = true
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+
+	auditLog := NewAuditLog(ctx)
+	assert.NotNil(t, auditLog)
+	assert.NoError(t, auditLog.ModuleExecute(ctx))
+	Record(Event{Type: SessionStarted, SessionID: "session-id"})
+	assert.NoError(t, auditLog.ModuleRequestStop(contracts.StopTypeSoftStop))
+
+	content := <-received
+	assert.Contains(t, content, "<109>1 ")
+	assert.Contains(t, content, ` amazon-ssm-agent `)
+	assert.Contains(t, content, ` SessionStarted - {"time":`)
+	assert.Contains(t, content, `"sessionId":"session-id"}`)
+}
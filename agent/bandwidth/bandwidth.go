@@ -0,0 +1,170 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bandwidth meters the network traffic of the agent and caps it per category of traffic.
+package bandwidth
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Category is a category of agent traffic
+type Category string
+
+const (
+	// Upload is the traffic of files uploaded to S3
+	Upload Category = "Upload"
+	// Download is the traffic of downloaded artifacts
+	Download Category = "Download"
+	// Session is the traffic of the session manager websocket channels
+	Session Category = "Session"
+)
+
+// Categories are all the categories of agent traffic
+var Categories = []Category{Upload, Download, Session}
+
+// meter counts the bytes of a category and throttles them with a token bucket holding up to one second of traffic
+type meter struct {
+	total int64
+
+	lock           sync.Mutex
+	bytesPerSecond int64
+	tokens         float64
+	last           time.Time
+}
+
+var (
+	meters     = map[Category]*meter{}
+	configure  sync.Once
+	sleep      = time.Sleep
+	now        = time.Now
+	loadConfig = func() (appconfig.BandwidthCfg, error) {
+		config, err := appconfig.Config(false)
+		return config.Network.Bandwidth, err
+	}
+)
+
+func init() {
+	for _, category := range Categories {
+		meters[category] = &meter{}
+	}
+}
+
+// getMeter returns the meter of a category, the caps are loaded from appconfig on first use
+func getMeter(category Category) *meter {
+	configure.Do(func() {
+		if config, err := loadConfig(); err == nil {
+			meters[Upload].setLimit(config.UploadBytesPerSecond)
+			meters[Download].setLimit(config.DownloadBytesPerSecond)
+			meters[Session].setLimit(config.SessionBytesPerSecond)
+		}
+	})
+	return meters[category]
+}
+
+// SetLimit caps the traffic of a category, overriding the cap of appconfig, 0 removes the cap
+func SetLimit(category Category, bytesPerSecond int64) {
+	getMeter(category).setLimit(bytesPerSecond)
+}
+
+func (m *meter) setLimit(bytesPerSecond int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if bytesPerSecond < 0 {
+		bytesPerSecond = 0
+	}
+	m.bytesPerSecond = bytesPerSecond
+	m.tokens = float64(bytesPerSecond)
+	m.last = now()
+}
+
+// Limit returns the cap of a category in bytes per second, 0 when the category is not capped
+func Limit(category Category) int64 {
+	m := getMeter(category)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.bytesPerSecond
+}
+
+// Usage returns the number of bytes of a category transferred by this process
+func Usage(category Category) int64 {
+	return atomic.LoadInt64(&getMeter(category).total)
+}
+
+// Wait counts bytes of a category and blocks as long as needed to keep the category under its cap.
+// Transfers larger than the bucket borrow from the next seconds, so they are never blocked forever.
+func Wait(category Category, n int) {
+	if n <= 0 {
+		return
+	}
+	m := getMeter(category)
+	atomic.AddInt64(&m.total, int64(n))
+
+	m.lock.Lock()
+	if m.bytesPerSecond == 0 {
+		m.lock.Unlock()
+		return
+	}
+	current := now()
+	m.tokens += current.Sub(m.last).Seconds() * float64(m.bytesPerSecond)
+	if m.tokens > float64(m.bytesPerSecond) {
+		m.tokens = float64(m.bytesPerSecond)
+	}
+	m.last = current
+	m.tokens -= float64(n)
+	var delay time.Duration
+	if m.tokens < 0 {
+		delay = time.Duration(-m.tokens / float64(m.bytesPerSecond) * float64(time.Second))
+	}
+	m.lock.Unlock()
+
+	if delay > 0 {
+		sleep(delay)
+	}
+}
+
+// reader meters the bytes read from a reader
+type reader struct {
+	category Category
+	reader   io.Reader
+}
+
+// NewReader returns a reader counting and capping the bytes read as traffic of a category
+func NewReader(category Category, r io.Reader) io.Reader {
+	return &reader{category: category, reader: r}
+}
+
+// Read reads from the underlying reader and waits for the cap of the category
+func (r *reader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	Wait(r.category, n)
+	return
+}
+
+// LogUsage logs the traffic and the cap of every category
+func LogUsage(log log.T) {
+	for _, category := range Categories {
+		if limit := Limit(category); limit > 0 {
+			log.Infof("%v traffic: %v bytes, capped at %v bytes per second", category, Usage(category), limit)
+		} else {
+			log.Infof("%v traffic: %v bytes", category, Usage(category))
+		}
+	}
+}
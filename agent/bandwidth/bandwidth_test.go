@@ -0,0 +1,81 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bandwidth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubClock stubs the clock and records the time slept
+func stubClock(t *testing.T) *time.Duration {
+	sleepOrig, nowOrig := sleep, now
+	t.Cleanup(func() { sleep, now = sleepOrig, nowOrig })
+
+	current := time.Now()
+	slept := time.Duration(0)
+	now = func() time.Time { return current }
+	sleep = func(d time.Duration) {
+		slept += d
+		current = current.Add(d)
+	}
+	return &slept
+}
+
+func TestWaitUnlimited(t *testing.T) {
+	slept := stubClock(t)
+	SetLimit(Upload, 0)
+
+	before := Usage(Upload)
+	Wait(Upload, 1<<20)
+
+	assert.Equal(t, time.Duration(0), *slept)
+	assert.Equal(t, before+1<<20, Usage(Upload))
+}
+
+func TestWaitCapped(t *testing.T) {
+	slept := stubClock(t)
+	SetLimit(Download, 1000)
+	t.Cleanup(func() { SetLimit(Download, 0) })
+
+	// the first second of traffic is served by the bucket
+	Wait(Download, 1000)
+	assert.Equal(t, time.Duration(0), *slept)
+
+	Wait(Download, 500)
+	assert.Equal(t, 500*time.Millisecond, *slept)
+
+	// transfers larger than the bucket borrow from the next seconds
+	Wait(Download, 3000)
+	assert.Equal(t, 3500*time.Millisecond, *slept)
+}
+
+func TestNewReader(t *testing.T) {
+	slept := stubClock(t)
+	SetLimit(Session, 100)
+	t.Cleanup(func() { SetLimit(Session, 0) })
+
+	before := Usage(Session)
+	content, err := ioutil.ReadAll(NewReader(Session, bytes.NewReader(make([]byte, 300))))
+
+	assert.NoError(t, err)
+	assert.Len(t, content, 300)
+	assert.Equal(t, before+300, Usage(Session))
+	assert.Equal(t, 2*time.Second, *slept)
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bootgate holds the Run Command and association documents back until the provisioning of the instance at
+// boot completes: cloud-init, a readiness file or a readiness command, so the early documents do not race the user data.
+package bootgate
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// commandTimeout bounds each run of the readiness command
+const commandTimeout = 30 * time.Second
+
+// cloudInitRunDir is where cloud-init keeps the state of the current boot, it is absent when cloud-init did not run.
+// The result file is written once the final stage completed.
+var cloudInitRunDir = "/run/cloud-init"
+
+const cloudInitResultFile = "result.json"
+
+// pollInterval is the delay between the checks of the conditions, it is stubbed in the tests
+var pollInterval = 5 * time.Second
+
+// gate is opened by the first document seeing the instance ready or the timeout expire, the next documents do not wait
+var gate struct {
+	sync.Mutex
+	open     bool
+	deadline time.Time
+}
+
+// Wait blocks until the instance is provisioned, the timeout of the config expires or the document is canceled.
+// It returns false when the agent shuts down meanwhile, the document is then left to the next start of the agent.
+func Wait(log log.T, config appconfig.BootGateCfg, cancelFlag task.CancelFlag) bool {
+	if !config.CloudInit && config.ReadinessFile == "" && config.ReadinessCommand == "" {
+		return true
+	}
+
+	gate.Lock()
+	defer gate.Unlock()
+	if gate.open {
+		return true
+	}
+	if gate.deadline.IsZero() {
+		gate.deadline = time.Now().Add(time.Duration(config.TimeoutSeconds) * time.Second)
+	}
+
+	waiting := false
+	for !ready(log, config) {
+		if time.Now().After(gate.deadline) {
+			log.Warnf("The instance is not ready after %v seconds, running the documents anyway", config.TimeoutSeconds)
+			break
+		}
+		if !waiting {
+			log.Info("Holding the documents back until the instance is provisioned")
+			waiting = true
+		}
+		time.Sleep(pollInterval)
+		if cancelFlag.ShutDown() {
+			return false
+		}
+		if cancelFlag.Canceled() {
+			return true
+		}
+	}
+	if waiting {
+		log.Info("The instance is provisioned, running the documents")
+	}
+	gate.open = true
+	return true
+}
+
+// ready returns true when all the conditions of the config are met.
+func ready(log log.T, config appconfig.BootGateCfg) bool {
+	if config.CloudInit && !cloudInitDone(log) {
+		return false
+	}
+	if config.ReadinessFile != "" && !fileutil.Exists(config.ReadinessFile) {
+		return false
+	}
+	if config.ReadinessCommand != "" && !commandSucceeds(config.ReadinessCommand) {
+		return false
+	}
+	return true
+}
+
+// cloudInitDone returns true when cloud-init completed its final stage or did not run at this boot, the errors of
+// cloud-init are logged and do not hold the documents back.
+func cloudInitDone(log log.T) bool {
+	if !fileutil.Exists(cloudInitRunDir) {
+		return true
+	}
+	content, err := ioutil.ReadFile(filepath.Join(cloudInitRunDir, cloudInitResultFile))
+	if err != nil {
+		return false
+	}
+	var result struct {
+		V1 struct {
+			Errors []string `json:"errors"`
+		} `json:"v1"`
+	}
+	if err = json.Unmarshal(content, &result); err == nil && len(result.V1.Errors) > 0 {
+		log.Warnf("cloud-init completed with errors: %v", result.V1.Errors)
+	}
+	return true
+}
+
+// commandSucceeds runs the readiness command with the shell of the platform and returns true when it exits with 0.
+func commandSucceeds(command string) bool {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), commandTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, pluginutil.GetShellCommand(), append(pluginutil.GetShellArguments(), command)...).Run() == nil
+}
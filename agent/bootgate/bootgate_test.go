@@ -0,0 +1,97 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bootgate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// resetGate closes the gate and shortens the polls for a test
+func resetGate(t *testing.T) {
+	pollIntervalOrig := pollInterval
+	pollInterval = 10 * time.Millisecond
+	gate.open = false
+	gate.deadline = time.Time{}
+	t.Cleanup(func() { pollInterval = pollIntervalOrig })
+}
+
+func TestWaitReadinessFile(t *testing.T) {
+	resetGate(t)
+	dir, err := ioutil.TempDir("", "bootgate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	readinessFile := filepath.Join(dir, "provisioned")
+
+	time.AfterFunc(50*time.Millisecond, func() { ioutil.WriteFile(readinessFile, nil, 0600) })
+	start := time.Now()
+	assert.True(t, Wait(logger, appconfig.BootGateCfg{ReadinessFile: readinessFile, TimeoutSeconds: 10}, task.NewChanneledCancelFlag()))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	assert.True(t, gate.open)
+
+	// the gate stays open for the next documents
+	os.Remove(readinessFile)
+	assert.True(t, Wait(logger, appconfig.BootGateCfg{ReadinessFile: readinessFile, TimeoutSeconds: 10}, task.NewChanneledCancelFlag()))
+}
+
+func TestCloudInitDone(t *testing.T) {
+	resetGate(t)
+	dir, err := ioutil.TempDir("", "bootgate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cloudInitRunDirOrig := cloudInitRunDir
+	defer func() { cloudInitRunDir = cloudInitRunDirOrig }()
+
+	cloudInitRunDir = filepath.Join(dir, "missing")
+	assert.True(t, cloudInitDone(logger), "cloud-init did not run at this boot")
+
+	cloudInitRunDir = dir
+	assert.False(t, cloudInitDone(logger))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, cloudInitResultFile), []byte(`{"v1": {"datasource": "DataSourceEc2", "errors": []}}`), 0600))
+	assert.True(t, cloudInitDone(logger))
+}
+
+func TestWaitTimeoutAndShutdown(t *testing.T) {
+	resetGate(t)
+	config := appconfig.BootGateCfg{ReadinessFile: filepath.Join(os.TempDir(), "bootgate-missing"), TimeoutSeconds: 10}
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	cancelFlag.Set(task.ShutDown)
+	assert.False(t, Wait(logger, config, cancelFlag), "the document is left to the next start of the agent")
+	assert.False(t, gate.open)
+
+	gate.deadline = time.Now()
+	assert.True(t, Wait(logger, config, task.NewChanneledCancelFlag()))
+	assert.True(t, gate.open, "the gate opens once the timeout expired")
+}
+
+func TestCommandSucceeds(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands run with sh")
+	}
+	assert.True(t, commandSucceeds("test 1 -eq 1"))
+	assert.False(t, commandSucceeds("exit 3"))
+}
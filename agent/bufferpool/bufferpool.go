@@ -0,0 +1,45 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bufferpool provides reusable buffers for the message processing path.
+package bufferpool
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledCapacity is the capacity above which a buffer is dropped instead of pooled,
+// so that an occasional large message doesn't stay in memory
+const maxPooledCapacity = 64 * 1024
+
+var pool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Get returns an empty buffer from the pool
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+// Put returns a buffer to the pool, the buffer must not be used afterwards
+func Put(buffer *bytes.Buffer) {
+	if buffer == nil || buffer.Cap() > maxPooledCapacity {
+		return
+	}
+	buffer.Reset()
+	pool.Put(buffer)
+}
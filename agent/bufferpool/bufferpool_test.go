@@ -0,0 +1,45 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package bufferpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReturnsEmptyBuffer(t *testing.T) {
+	buffer := Get()
+	buffer.WriteString("message")
+	Put(buffer)
+
+	buffer = Get()
+	assert.Equal(t, 0, buffer.Len())
+	Put(buffer)
+}
+
+func TestPutDropsLargeBuffers(t *testing.T) {
+	buffer := Get()
+	buffer.Grow(2 * maxPooledCapacity)
+	Put(buffer)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, Get().Cap() <= maxPooledCapacity)
+	}
+}
+
+func TestPutIgnoresNil(t *testing.T) {
+	assert.NotPanics(t, func() { Put(nil) })
+}
@@ -0,0 +1,361 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+)
+
+const (
+	getDiagnosticsCommand = "get-diagnostics"
+	getDiagnosticsOutput  = "output"
+
+	diagnosticsOutputText = "text"
+	diagnosticsOutputJson = "json"
+
+	diagnosticSuccess = "Success"
+	diagnosticFailed  = "Failed"
+	diagnosticSkipped = "Skipped"
+
+	// endpointTimeout is the timeout of the connectivity check of an endpoint
+	endpointTimeout = 10 * time.Second
+
+	// maxClockSkew is the clock skew beyond which the service requests signed by the agent are rejected
+	maxClockSkew = 5 * time.Minute
+
+	// minDiskSpaceBytes is the available disk space below which documents and updates may fail
+	minDiskSpaceBytes = 100 * 1024 * 1024
+)
+
+const getDiagnosticsCommandHelp = `NAME:
+    {{.GetDiagnosticsCommandName}}
+
+DESCRIPTION
+    Checks the prerequisites of the agent on this instance: instance metadata reachability,
+    credentials, connectivity to the ssm, ssmmessages, ec2messages and s3 endpoints, clock
+    skew, proxy configuration, available disk space, the agent service and its workers.
+
+PARAMETERS
+    {{.OutputFlag}} text|json
+        Format of the report, text by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetDiagnosticsCommandName}}
+
+    Output:
+      Check                         Status   Note
+      EC2 instance metadata         Success  instance i-12345678 in us-west-2
+      AWS credentials               Success  credentials from EC2RoleProvider
+      Connectivity to ssm endpoint  Success  https://ssm.us-west-2.amazonaws.com reached directly
+      ...
+
+    Command:
+
+      {{.SsmCliName}} {{.GetDiagnosticsCommandName}} {{.OutputFlag}} json
+
+    Output:
+      [
+        {
+          "check": "EC2 instance metadata",
+          "status": "Success",
+          "note": "instance i-12345678 in us-west-2"
+        },
+        ...
+      ]
+
+OUTPUT
+    Status of each check with a note on its outcome
+`
+
+type getDiagnosticsHelpParams struct {
+	SsmCliName                string
+	GetDiagnosticsCommandName string
+	OutputFlag                string
+}
+
+// diagnosticResult is the outcome of a check of the report
+type diagnosticResult struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// diagnosticCheck checks a prerequisite of the agent and returns its status and a note on its outcome
+type diagnosticCheck struct {
+	name string
+	run  func() (status string, note string)
+}
+
+// dependencies of the command, replaced by the tests
+var newDiagnosticChecks = defaultDiagnosticChecks
+var newDiagnosticsLogger = log.DefaultLogger
+
+func init() {
+	cliutil.Register(&GetDiagnosticsCommand{})
+}
+
+type GetDiagnosticsCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-diagnostics cli command
+func (c *GetDiagnosticsCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetDiagnosticsCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	logger := newDiagnosticsLogger()
+	defer logger.Flush()
+
+	var results []diagnosticResult
+	for _, check := range newDiagnosticChecks(logger) {
+		status, note := check.run()
+		results = append(results, diagnosticResult{Check: check.name, Status: status, Note: note})
+	}
+
+	if output, ok := parameters[getDiagnosticsOutput]; ok && output[0] == diagnosticsOutputJson {
+		result, _ := jsonutil.Marshal(results)
+		return nil, result
+	}
+	return nil, formatDiagnostics(results)
+}
+
+// formatDiagnostics formats the report as a human-readable table
+func formatDiagnostics(results []diagnosticResult) string {
+	var buf bytes.Buffer
+	writer := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Check\tStatus\tNote")
+	for _, result := range results {
+		fmt.Fprintf(writer, "%v\t%v\t%v\n", result.Check, result.Status, result.Note)
+	}
+	writer.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// Help prints help for the get-diagnostics cli command
+func (c *GetDiagnosticsCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetDiagnosticsCommandHelp").Parse(getDiagnosticsCommandHelp)
+		params := getDiagnosticsHelpParams{cliutil.SsmCliName, getDiagnosticsCommand, cliutil.FormatFlag(getDiagnosticsOutput)}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetDiagnosticsCommand) Name() string {
+	return getDiagnosticsCommand
+}
+
+// validateGetDiagnosticsCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (GetDiagnosticsCommand) validateGetDiagnosticsCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getDiagnosticsCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	for key, values := range parameters {
+		if key != getDiagnosticsOutput {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		} else if len(values) != 1 || (values[0] != diagnosticsOutputText && values[0] != diagnosticsOutputJson) {
+			validation = append(validation, fmt.Sprintf("%v value must be %v or %v", cliutil.FormatFlag(key), diagnosticsOutputText, diagnosticsOutputJson))
+		}
+	}
+	return validation
+}
+
+// defaultDiagnosticChecks returns the checks of the report in the order they are run
+func defaultDiagnosticChecks(log log.T) []diagnosticCheck {
+	config, _ := appconfig.Config(false)
+	region, _ := platform.Region()
+
+	// the clock skew is measured against the Date header of the endpoint responses
+	var serverDate time.Time
+	checkEndpoint := func(service string, endpoint string, proxyCfg appconfig.ProxyCfg) diagnosticCheck {
+		return diagnosticCheck{
+			name: fmt.Sprintf("Connectivity to %v endpoint", service),
+			run: func() (string, string) {
+				date, status, note := checkEndpointConnectivity(log, endpointURL(endpoint, region, service), proxyCfg)
+				if serverDate.IsZero() {
+					serverDate = date
+				}
+				return status, note
+			},
+		}
+	}
+
+	return []diagnosticCheck{
+		{name: "EC2 instance metadata", run: checkInstanceMetadata},
+		{name: "AWS credentials", run: checkCredentials},
+		checkEndpoint("ssm", config.Ssm.Endpoint, config.Ssm.Proxy),
+		checkEndpoint("ssmmessages", config.Mgs.Endpoint, config.Mgs.Proxy),
+		checkEndpoint("ec2messages", config.Mds.Endpoint, config.Mds.Proxy),
+		checkEndpoint("s3", config.S3.Endpoint, config.S3.Proxy),
+		{name: "Clock skew", run: func() (string, string) { return checkClockSkew(serverDate, time.Now()) }},
+		{name: "Proxy configuration", run: func() (string, string) {
+			return checkProxyConfiguration(log, endpointURL(config.Ssm.Endpoint, region, "ssm"), config.Ssm.Proxy)
+		}},
+		{name: "Disk space", run: checkDiskSpace},
+		{name: "Agent service", run: checkAgentService},
+		{name: "Document worker", run: func() (string, string) { return checkWorker(appconfig.DefaultDocumentWorker) }},
+		{name: "Session worker", run: func() (string, string) { return checkWorker(appconfig.DefaultSessionWorker) }},
+	}
+}
+
+// endpointURL returns the https url of the endpoint of a service, its configured endpoint when set
+func endpointURL(configured string, region string, service string) string {
+	endpoint := configured
+	if endpoint == "" {
+		if endpoint = appconfig.GetDefaultEndPoint(region, service); endpoint == "" {
+			endpoint = appconfig.GetRegionalEndpoint(region, service)
+		}
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	return endpoint
+}
+
+func checkInstanceMetadata() (string, string) {
+	if isManaged, err := platform.IsManagedInstance(); err == nil && isManaged {
+		return diagnosticSkipped, "on-premises managed instances have no instance metadata"
+	}
+	document, err := platform.NewEC2MetadataClient().InstanceIdentityDocument()
+	if err != nil {
+		return diagnosticFailed, fmt.Sprintf("%v is not reachable: %v", platform.MetadataServiceURL(), err)
+	}
+	return diagnosticSuccess, fmt.Sprintf("instance %v in %v", document.InstanceID, document.Region)
+}
+
+func checkCredentials() (string, string) {
+	credentials := sdkutil.AwsConfig().Credentials
+	if credentials == nil {
+		return diagnosticFailed, "no credentials provider is available"
+	}
+	value, err := credentials.Get()
+	if err != nil {
+		return diagnosticFailed, fmt.Sprintf("failed to retrieve the credentials: %v", err)
+	}
+	return diagnosticSuccess, fmt.Sprintf("credentials from %v", value.ProviderName)
+}
+
+// checkEndpointConnectivity sends a request to the endpoint through its proxy, any http response shows it is reachable
+func checkEndpointConnectivity(log log.T, endpoint string, proxyCfg appconfig.ProxyCfg) (date time.Time, status string, note string) {
+	client := &http.Client{
+		Transport: proxyconfig.Transport(log, proxyCfg),
+		Timeout:   endpointTimeout,
+	}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return date, diagnosticFailed, fmt.Sprintf("%v is not reachable: %v", endpoint, err)
+	}
+	resp.Body.Close()
+	date, _ = http.ParseTime(resp.Header.Get("Date"))
+	return date, diagnosticSuccess, fmt.Sprintf("%v reached %v", endpoint, describeProxy(log, endpoint, proxyCfg))
+}
+
+// checkClockSkew compares the local time with the time of the service, the signed requests are rejected beyond maxClockSkew
+func checkClockSkew(serverDate time.Time, now time.Time) (string, string) {
+	if serverDate.IsZero() {
+		return diagnosticSkipped, "no endpoint returned its time"
+	}
+	skew := now.Sub(serverDate)
+	if skew < 0 {
+		skew = -skew
+	}
+	// the Date header has a one second resolution
+	skew = skew.Truncate(time.Second)
+	if skew > maxClockSkew {
+		return diagnosticFailed, fmt.Sprintf("the local clock is off by %v, more than the %v the services accept", skew, maxClockSkew)
+	}
+	return diagnosticSuccess, fmt.Sprintf("the local clock is off by %v", skew)
+}
+
+func checkProxyConfiguration(log log.T, endpoint string, proxyCfg appconfig.ProxyCfg) (string, string) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return diagnosticFailed, err.Error()
+	}
+	if _, err = proxyconfig.ProxyFunc(log, proxyCfg)(req); err != nil {
+		return diagnosticFailed, fmt.Sprintf("failed to select the proxy of %v: %v", endpoint, err)
+	}
+	return diagnosticSuccess, fmt.Sprintf("%v is reached %v", endpoint, describeProxy(log, endpoint, proxyCfg))
+}
+
+// describeProxy describes how the requests to an endpoint are sent
+func describeProxy(log log.T, endpoint string, proxyCfg appconfig.ProxyCfg) string {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "directly"
+	}
+	if proxy, err := proxyconfig.ProxyFunc(log, proxyCfg)(req); err == nil && proxy != nil {
+		return "through proxy " + proxy.String()
+	}
+	return "directly"
+}
+
+func checkDiskSpace() (string, string) {
+	diskSpace, err := fileutil.GetDiskSpaceInfo()
+	if err != nil {
+		return diagnosticFailed, fmt.Sprintf("failed to get the disk space: %v", err)
+	}
+	note := fmt.Sprintf("%v MB available", diskSpace.AvailBytes/(1024*1024))
+	if diskSpace.AvailBytes < minDiskSpaceBytes {
+		return diagnosticFailed, note
+	}
+	return diagnosticSuccess, note
+}
+
+func checkAgentService() (string, string) {
+	running, err := isAgentRunning()
+	if err != nil {
+		return diagnosticFailed, fmt.Sprintf("failed to get the status of the agent: %v", err)
+	}
+	if !running {
+		return diagnosticFailed, "the agent is not running"
+	}
+	return diagnosticSuccess, "the agent is running"
+}
+
+func checkWorker(path string) (string, string) {
+	if _, err := os.Stat(path); err != nil {
+		return diagnosticFailed, fmt.Sprintf("%v is missing", path)
+	}
+	return diagnosticSuccess, fmt.Sprintf("%v is installed", path)
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func setDiagnosticChecks(checks []diagnosticCheck) func() {
+	checksOrig, loggerOrig := newDiagnosticChecks, newDiagnosticsLogger
+	newDiagnosticChecks = func(log.T) []diagnosticCheck { return checks }
+	newDiagnosticsLogger = func() log.T { return log.NewMockLog() }
+	return func() {
+		newDiagnosticChecks, newDiagnosticsLogger = checksOrig, loggerOrig
+	}
+}
+
+var stubDiagnosticChecks = []diagnosticCheck{
+	{name: "EC2 instance metadata", run: func() (string, string) { return diagnosticSuccess, "instance i-12345678 in us-west-2" }},
+	{name: "Clock skew", run: func() (string, string) { return diagnosticFailed, "the local clock is off by 10m0s" }},
+}
+
+func TestGetDiagnosticsCommandRejectsSubcommand(t *testing.T) {
+	defer setDiagnosticChecks(stubDiagnosticChecks)()
+
+	err, result := (&GetDiagnosticsCommand{}).Execute([]string{"subcommand"}, map[string][]string{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support subcommand")
+	assert.Empty(t, result)
+}
+
+func TestGetDiagnosticsCommandRejectsInvalidParameters(t *testing.T) {
+	defer setDiagnosticChecks(stubDiagnosticChecks)()
+
+	err, result := (&GetDiagnosticsCommand{}).Execute([]string{}, map[string][]string{"output": {"yaml"}, "force": {"true"}})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "--output value must be text or json")
+	assert.Contains(t, err.Error(), "unknown parameter --force")
+	assert.Empty(t, result)
+}
+
+func TestGetDiagnosticsCommandPrintsTextReport(t *testing.T) {
+	defer setDiagnosticChecks(stubDiagnosticChecks)()
+
+	err, result := (&GetDiagnosticsCommand{}).Execute([]string{}, map[string][]string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Check                  Status   Note\n"+
+		"EC2 instance metadata  Success  instance i-12345678 in us-west-2\n"+
+		"Clock skew             Failed   the local clock is off by 10m0s", result)
+}
+
+func TestGetDiagnosticsCommandPrintsJsonReport(t *testing.T) {
+	defer setDiagnosticChecks(stubDiagnosticChecks)()
+
+	err, result := (&GetDiagnosticsCommand{}).Execute([]string{}, map[string][]string{"output": {"json"}})
+
+	assert.NoError(t, err)
+	var results []diagnosticResult
+	assert.NoError(t, json.Unmarshal([]byte(result), &results))
+	assert.Equal(t, []diagnosticResult{
+		{Check: "EC2 instance metadata", Status: diagnosticSuccess, Note: "instance i-12345678 in us-west-2"},
+		{Check: "Clock skew", Status: diagnosticFailed, Note: "the local clock is off by 10m0s"},
+	}, results)
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	status, _ := checkClockSkew(time.Time{}, now)
+	assert.Equal(t, diagnosticSkipped, status)
+
+	status, note := checkClockSkew(now.Add(-30*time.Second), now)
+	assert.Equal(t, diagnosticSuccess, status)
+	assert.Equal(t, "the local clock is off by 30s", note)
+
+	status, _ = checkClockSkew(now.Add(6*time.Minute), now)
+	assert.Equal(t, diagnosticFailed, status)
+}
+
+func TestEndpointURL(t *testing.T) {
+	assert.Equal(t, "https://ssm.us-east-1.amazonaws.com", endpointURL("", "us-east-1", "ssm"))
+	assert.Equal(t, "https://ssm.cn-north-1.amazonaws.com.cn", endpointURL("", "cn-north-1", "ssm"))
+	assert.Equal(t, "https://vpce-ssm.example.com", endpointURL("vpce-ssm.example.com", "us-east-1", "ssm"))
+	assert.Equal(t, "http://127.0.0.1:8080", endpointURL("http://127.0.0.1:8080", "us-east-1", "ssm"))
+}
+
+func TestCheckEndpointConnectivity(t *testing.T) {
+	serverDate := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverDate.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	date, status, note := checkEndpointConnectivity(log.NewMockLog(), server.URL, appconfig.ProxyCfg{})
+	assert.Equal(t, diagnosticSuccess, status)
+	assert.Equal(t, server.URL+" reached directly", note)
+	assert.Equal(t, serverDate, date)
+
+	server.Close()
+	_, status, _ = checkEndpointConnectivity(log.NewMockLog(), server.URL, appconfig.ProxyCfg{})
+	assert.Equal(t, diagnosticFailed, status)
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// isAgentRunning returns whether the AmazonSSMAgent service is running
+var isAgentRunning = func() (bool, error) {
+	output, err := exec.Command("sc", "query", "AmazonSSMAgent").Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(output), "RUNNING"), nil
+}
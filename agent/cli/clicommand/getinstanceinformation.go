@@ -22,6 +22,7 @@ import (
 	"text/template"
 
 	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -29,6 +30,7 @@ import (
 
 const (
 	getInstanceInformationCommand = "get-instance-information"
+	getInstanceInformationLocal   = "local"
 )
 
 const getInstanceInformationCommandHelp = `NAME:
@@ -52,15 +54,37 @@ EXAMPLES
         "release-version" : "1.0.0"
       }
 
+    This example returns the status of the agent running on this instance, for troubleshooting:
+    its registration, its connectivity, the result of its last heartbeat and the sessions and
+    documents it is running. You will need to have admin rights to run this command.
+
+    Command:
+
+      {{.SsmCliName}} {{.GetInstanceInformationCommandName}} {{.LocalFlag}}
+
+    Output:
+      {
+        "registration": {"instanceId": "i-12345678", "region": "us-west-2", "managedInstance": false, "version": "1.0.0"},
+        "health": {"status": "ok", "controlChannel": {"connected": true, "since": "2019-01-01T00:00:00Z"}, ...},
+        "lastHeartbeat": {"time": "2019-01-01T00:05:00Z"},
+        "sessions": [{"id": "user-0123456789abcdef0", "type": "StartSession", "startedAt": "2019-01-01T00:03:00Z"}],
+        "documents": []
+      }
+
 OUTPUT
-    Instance information containing region, instance ID and version in JSON format
+    Instance information containing region, instance ID and version in JSON format, or the
+    status of the running agent in JSON format with {{.LocalFlag}}
 `
 
 type getInstanceInformationHelpParams struct {
 	SsmCliName                        string
 	GetInstanceInformationCommandName string
+	LocalFlag                         string
 }
 
+// queryAgentStatus returns the status of the running agent, replaced by the tests
+var queryAgentStatus = healthendpoint.QueryStatus
+
 func init() {
 	cliutil.Register(&GetInstanceInformationCommand{})
 }
@@ -77,6 +101,18 @@ func (c *GetInstanceInformationCommand) Execute(subcommands []string, parameters
 		return errors.New(strings.Join(validation, "\n")), ""
 	}
 
+	if _, local := parameters[getInstanceInformationLocal]; local {
+		status, err := queryAgentStatus()
+		if err != nil {
+			return err, ""
+		}
+		result, err := jsonutil.MarshalIndent(status)
+		if err != nil {
+			return err, ""
+		}
+		return nil, result
+	}
+
 	information := make(map[string]string)
 	if region, err := platform.Region(); err != nil {
 		return err, ""
@@ -100,7 +136,7 @@ func (c *GetInstanceInformationCommand) Execute(subcommands []string, parameters
 func (c *GetInstanceInformationCommand) Help() string {
 	if len(c.helpText) == 0 {
 		t, _ := template.New("GetInstanceInformationCommandHelp").Parse(getInstanceInformationCommandHelp)
-		params := getInstanceInformationHelpParams{cliutil.SsmCliName, getInstanceInformationCommand}
+		params := getInstanceInformationHelpParams{cliutil.SsmCliName, getInstanceInformationCommand, cliutil.FormatFlag(getInstanceInformationLocal)}
 		buf := new(bytes.Buffer)
 		t.Execute(buf, params)
 		c.helpText = buf.String()
@@ -122,8 +158,12 @@ func (GetInstanceInformationCommand) validateGetInstanceInformationCommandInput(
 	}
 
 	// look for unsupported parameters
-	for key, _ := range parameters {
-		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	for key, values := range parameters {
+		if key != getInstanceInformationLocal {
+			validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		} else if len(values) > 0 {
+			validation = append(validation, fmt.Sprintf("%v does not take a value", cliutil.FormatFlag(key)))
+		}
 	}
 	return validation
 }
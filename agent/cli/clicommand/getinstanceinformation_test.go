@@ -0,0 +1,64 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
+	"github.com/stretchr/testify/assert"
+)
+
+func setAgentStatus(t *testing.T, status healthendpoint.Status, err error) {
+	queryOrig := queryAgentStatus
+	t.Cleanup(func() { queryAgentStatus = queryOrig })
+	queryAgentStatus = func() (healthendpoint.Status, error) { return status, err }
+}
+
+func TestGetInstanceInformationCommandLocal(t *testing.T) {
+	expected := healthendpoint.Status{
+		Registration:  healthendpoint.RegistrationStatus{InstanceID: "i-12345678", Region: "us-west-2", Version: "1.0.0"},
+		LastHeartbeat: healthendpoint.HeartbeatStatus{Time: "2019-01-01T00:05:00Z"},
+		Sessions:      []healthendpoint.DocumentStatus{{ID: "user-0123", Type: "StartSession", StartedAt: "2019-01-01T00:03:00Z"}},
+		Documents:     []healthendpoint.DocumentStatus{},
+	}
+	setAgentStatus(t, expected, nil)
+
+	err, result := (&GetInstanceInformationCommand{}).Execute(nil, map[string][]string{getInstanceInformationLocal: {}})
+
+	assert.NoError(t, err)
+	var status healthendpoint.Status
+	assert.NoError(t, json.Unmarshal([]byte(result), &status))
+	assert.Equal(t, expected, status)
+}
+
+func TestGetInstanceInformationCommandLocalAgentNotRunning(t *testing.T) {
+	setAgentStatus(t, healthendpoint.Status{}, errors.New("failed to query the agent"))
+
+	err, _ := (&GetInstanceInformationCommand{}).Execute(nil, map[string][]string{getInstanceInformationLocal: {}})
+
+	assert.EqualError(t, err, "failed to query the agent")
+}
+
+func TestGetInstanceInformationCommandValidatesInput(t *testing.T) {
+	validation := GetInstanceInformationCommand{}.validateGetInstanceInformationCommandInput(nil, map[string][]string{
+		getInstanceInformationLocal: {"yes"},
+	})
+
+	assert.Equal(t, []string{"--local does not take a value"}, validation)
+}
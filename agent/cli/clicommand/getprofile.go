@@ -0,0 +1,206 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/profiling"
+)
+
+const (
+	getProfileCommand = "get-profile"
+	getProfileProfile = "profile"
+	getProfileSeconds = "seconds"
+	getProfileOutput  = "output"
+
+	// getProfileDefaultSeconds is the duration of the cpu profile
+	getProfileDefaultSeconds = 30
+)
+
+const getProfileCommandHelp = `NAME:
+    {{.GetProfileCommandName}}
+
+DESCRIPTION
+    Captures a profile of the agent running on this instance from its profiling endpoints,
+    which Profiling.Port or Profiling.SocketPath in amazon-ssm-agent.json turn on, the requests
+    on the port carry the token of Profiling.TokenFile. The profile is written in the format of
+    pprof, "go tool pprof <file>" reads it.
+
+    You will need to have admin rights to run this command.
+
+SYNOPSIS
+    {{.GetProfileCommandName}}
+    {{.ProfileFlag}} cpu|heap|goroutine
+    [{{.SecondsFlag}} <value>]
+    [{{.OutputFlag}} <value>]
+
+PARAMETERS
+    {{.ProfileFlag}} (string) Profile captured: the cpu usage, the memory allocations or the stacks
+        of the goroutines.
+    {{.SecondsFlag}} (integer) Duration of the cpu profile in seconds, 30 by default.
+    {{.OutputFlag}} (string) File the profile is written to, amazon-ssm-agent-<profile>.pprof in the
+        current folder by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.GetProfileCommandName}} {{.ProfileFlag}} cpu {{.SecondsFlag}} 60
+
+    Output:
+
+      cpu profile written to amazon-ssm-agent-cpu.pprof
+
+OUTPUT
+    The file the profile is written to
+`
+
+type getProfileHelpParams struct {
+	SsmCliName            string
+	GetProfileCommandName string
+	ProfileFlag           string
+	SecondsFlag           string
+	OutputFlag            string
+}
+
+// dependencies of the command, replaced by the tests
+var captureProfile = profiling.Capture
+var profilingConfig = func() (appconfig.ProfilingCfg, error) {
+	config, err := appconfig.Config(false)
+	return config.Profiling, err
+}
+
+func init() {
+	cliutil.Register(&GetProfileCommand{})
+}
+
+type GetProfileCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the get-profile cli command
+func (c *GetProfileCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateGetProfileCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	profile := parameters[getProfileProfile][0]
+	seconds := getProfileDefaultSeconds
+	if values, exists := parameters[getProfileSeconds]; exists {
+		seconds, _ = strconv.Atoi(values[0])
+	}
+	path := fmt.Sprintf("amazon-ssm-agent-%v.pprof", profile)
+	if values, exists := parameters[getProfileOutput]; exists {
+		path = values[0]
+	}
+
+	config, err := profilingConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration of the agent: %v", err), ""
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, appconfig.ReadWriteAccess)
+	if err != nil {
+		return err, ""
+	}
+	if err = writeProfile(config, profile, seconds, file); err != nil {
+		os.Remove(path)
+		return err, ""
+	}
+	return nil, fmt.Sprintf("%v profile written to %v", profile, path)
+}
+
+// writeProfile captures the profile into the file and closes it
+func writeProfile(config appconfig.ProfilingCfg, profile string, seconds int, file io.WriteCloser) error {
+	err := captureProfile(config, profile, seconds, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Help prints help for the get-profile cli command
+func (c *GetProfileCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("GetProfileCommandHelp").Parse(getProfileCommandHelp)
+		params := getProfileHelpParams{
+			cliutil.SsmCliName,
+			getProfileCommand,
+			cliutil.FormatFlag(getProfileProfile),
+			cliutil.FormatFlag(getProfileSeconds),
+			cliutil.FormatFlag(getProfileOutput),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (GetProfileCommand) Name() string {
+	return getProfileCommand
+}
+
+// validateGetProfileCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (GetProfileCommand) validateGetProfileCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", getProfileCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for required parameters
+	if _, exists := parameters[getProfileProfile]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(getProfileProfile)))
+	}
+	for _, key := range []string{getProfileProfile, getProfileSeconds, getProfileOutput} {
+		if values, exists := parameters[key]; exists && len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	if values, exists := parameters[getProfileProfile]; exists && len(values) == 1 {
+		if _, known := profiling.Profiles[values[0]]; !known {
+			validation = append(validation, fmt.Sprintf("%v value must be cpu, heap or goroutine", cliutil.FormatFlag(getProfileProfile)))
+		}
+	}
+	if values, exists := parameters[getProfileSeconds]; exists && len(values) == 1 {
+		if seconds, err := strconv.Atoi(values[0]); err != nil || seconds < 1 {
+			validation = append(validation, fmt.Sprintf("%v value must be a positive integer", cliutil.FormatFlag(getProfileSeconds)))
+		}
+	}
+
+	// look for unsupported parameters
+	var unknown []string
+	for key := range parameters {
+		if key != getProfileProfile && key != getProfileSeconds && key != getProfileOutput {
+			unknown = append(unknown, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	sort.Strings(unknown)
+	return append(validation, unknown...)
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// captured is a profile captured by the command
+type captured struct {
+	config  appconfig.ProfilingCfg
+	profile string
+	seconds int
+}
+
+// setProfileDependencies makes the command capture a fake profile with the error and returns the captured profiles
+func setProfileDependencies(t *testing.T, captureErr error) *[]captured {
+	captureOrig, configOrig := captureProfile, profilingConfig
+	t.Cleanup(func() { captureProfile, profilingConfig = captureOrig, configOrig })
+	var profiles []captured
+	profilingConfig = func() (appconfig.ProfilingCfg, error) {
+		return appconfig.ProfilingCfg{SocketPath: "/run/amazon-ssm-agent/pprof.sock"}, nil
+	}
+	captureProfile = func(config appconfig.ProfilingCfg, profile string, seconds int, w io.Writer) error {
+		profiles = append(profiles, captured{config, profile, seconds})
+		w.Write([]byte("profile"))
+		return captureErr
+	}
+	return &profiles
+}
+
+func TestGetProfileCommand_WritesTheProfile(t *testing.T) {
+	profiles := setProfileDependencies(t, nil)
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cpu.pprof")
+
+	err, result := (&GetProfileCommand{}).Execute(nil, map[string][]string{"profile": {"cpu"}, "seconds": {"5"}, "output": {path}})
+	assert.NoError(t, err)
+	assert.Equal(t, "cpu profile written to "+path, result)
+	assert.Equal(t, []captured{{appconfig.ProfilingCfg{SocketPath: "/run/amazon-ssm-agent/pprof.sock"}, "cpu", 5}}, *profiles)
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "profile", string(content))
+}
+
+func TestGetProfileCommand_DefaultsToThirtySeconds(t *testing.T) {
+	profiles := setProfileDependencies(t, nil)
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err, _ = (&GetProfileCommand{}).Execute(nil, map[string][]string{"profile": {"heap"}, "output": {filepath.Join(dir, "heap.pprof")}})
+	assert.NoError(t, err)
+	assert.Equal(t, getProfileDefaultSeconds, (*profiles)[0].seconds)
+}
+
+func TestGetProfileCommand_RemovesTheFileOnFailure(t *testing.T) {
+	setProfileDependencies(t, errors.New("failed to query the agent"))
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "goroutine.pprof")
+
+	err, _ = (&GetProfileCommand{}).Execute(nil, map[string][]string{"profile": {"goroutine"}, "output": {path}})
+	assert.EqualError(t, err, "failed to query the agent")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetProfileCommand_Validation(t *testing.T) {
+	command := GetProfileCommand{}
+	assert.Equal(t, []string{"--profile is required"}, command.validateGetProfileCommandInput(nil, map[string][]string{}))
+	assert.Equal(t, []string{"--profile value must be cpu, heap or goroutine"},
+		command.validateGetProfileCommandInput(nil, map[string][]string{"profile": {"block"}}))
+	assert.Equal(t, []string{"--seconds value must be a positive integer"},
+		command.validateGetProfileCommandInput(nil, map[string][]string{"profile": {"cpu"}, "seconds": {"0"}}))
+	assert.Equal(t, []string{"expected 1 value for parameter --output"},
+		command.validateGetProfileCommandInput(nil, map[string][]string{"profile": {"cpu"}, "output": {}}))
+	assert.Equal(t, []string{"unknown parameter --debug"},
+		command.validateGetProfileCommandInput(nil, map[string][]string{"profile": {"cpu"}, "debug": {}}))
+	assert.Empty(t, command.validateGetProfileCommandInput(nil, map[string][]string{"profile": {"heap"}, "seconds": {"10"}}))
+}
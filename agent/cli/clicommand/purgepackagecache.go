@@ -0,0 +1,136 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+const (
+	purgePackageCacheCommand = "purge-package-cache"
+)
+
+const purgePackageCacheCommandHelp = `NAME:
+    {{.PurgePackageCacheCommandName}}
+
+DESCRIPTION
+    Removes from the local package cache every artifact that is not used by an installed
+    package, or by a package that is being installed or uninstalled.
+
+    Artifacts that are removed are downloaded again the next time they are needed.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.PurgePackageCacheCommandName}}
+
+    Output:
+      {
+        "removed-entries" : "3"
+      }
+
+OUTPUT
+    Number of cache entries removed in JSON format
+`
+
+type purgePackageCacheHelpParams struct {
+	SsmCliName                   string
+	PurgePackageCacheCommandName string
+}
+
+// dependencies of the command, replaced by the tests
+var newPackageCache = packagecache.NewCache
+var newPackageRepository = localpackages.NewRepository
+var newPurgeLogger = log.DefaultLogger
+
+func init() {
+	cliutil.Register(&PurgePackageCacheCommand{})
+}
+
+type PurgePackageCacheCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the purge-package-cache cli command
+func (c *PurgePackageCacheCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validatePurgePackageCacheCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	logger := newPurgeLogger()
+	defer logger.Flush()
+
+	tracer := trace.NewTracer(logger)
+	repository := newPackageRepository()
+	isReferenced := func(packageArn string, version string) bool {
+		return localpackages.IsVersionInUse(tracer, repository, packageArn, version)
+	}
+
+	removed, err := newPackageCache().Purge(tracer, isReferenced)
+	if err != nil {
+		return err, ""
+	}
+
+	information := make(map[string]string)
+	information["removed-entries"] = fmt.Sprintf("%v", removed)
+
+	result, _ := jsonutil.Marshal(information)
+	return nil, result
+}
+
+// Help prints help for the purge-package-cache cli command
+func (c *PurgePackageCacheCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("PurgePackageCacheCommandHelp").Parse(purgePackageCacheCommandHelp)
+		params := purgePackageCacheHelpParams{cliutil.SsmCliName, purgePackageCacheCommand}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (PurgePackageCacheCommand) Name() string {
+	return purgePackageCacheCommand
+}
+
+// validatePurgePackageCacheCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (PurgePackageCacheCommand) validatePurgePackageCacheCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", purgePackageCacheCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for unsupported parameters
+	for key, _ := range parameters {
+		validation = append(validation, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+	}
+	return validation
+}
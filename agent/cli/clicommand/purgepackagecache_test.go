@@ -0,0 +1,92 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache"
+	cacheMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setPurgeDependencies(cache packagecache.Cache, repository localpackages.Repository) func() {
+	cacheOrig, repositoryOrig, loggerOrig := newPackageCache, newPackageRepository, newPurgeLogger
+	newPackageCache = func() packagecache.Cache { return cache }
+	newPackageRepository = func() localpackages.Repository { return repository }
+	newPurgeLogger = func() log.T { return log.NewMockLog() }
+	return func() {
+		newPackageCache, newPackageRepository, newPurgeLogger = cacheOrig, repositoryOrig, loggerOrig
+	}
+}
+
+func TestPurgePackageCacheCommandRejectsSubcommand(t *testing.T) {
+	mockCache := &cacheMock.MockedCache{}
+	defer setPurgeDependencies(mockCache, &repoMock.MockedRepository{})()
+
+	err, result := (&PurgePackageCacheCommand{}).Execute([]string{"subcommand"}, map[string][]string{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support subcommand")
+	assert.Empty(t, result)
+	mockCache.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+}
+
+func TestPurgePackageCacheCommandRejectsUnknownParameter(t *testing.T) {
+	mockCache := &cacheMock.MockedCache{}
+	defer setPurgeDependencies(mockCache, &repoMock.MockedRepository{})()
+
+	err, result := (&PurgePackageCacheCommand{}).Execute([]string{}, map[string][]string{"force": {"true"}})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown parameter --force")
+	assert.Empty(t, result)
+	mockCache.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+}
+
+func TestPurgePackageCacheCommandOutput(t *testing.T) {
+	mockRepo := &repoMock.MockedRepository{}
+	mockRepo.On("GetInstalledVersion", mock.Anything, "package").Return("1.0.0")
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Purge", mock.Anything, mock.Anything).Return(3, nil).Run(func(args mock.Arguments) {
+		// the reference checker must be backed by the local repository
+		isReferenced := args.Get(1).(packagecache.ReferenceChecker)
+		assert.True(t, isReferenced("package", "1.0.0"))
+	}).Once()
+	defer setPurgeDependencies(mockCache, mockRepo)()
+
+	err, result := (&PurgePackageCacheCommand{}).Execute([]string{}, map[string][]string{})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"removed-entries": "3"}`, result)
+	mockCache.AssertExpectations(t)
+}
+
+func TestPurgePackageCacheCommandError(t *testing.T) {
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Purge", mock.Anything, mock.Anything).Return(0, errors.New("package cache is locked by another process")).Once()
+	defer setPurgeDependencies(mockCache, &repoMock.MockedRepository{})()
+
+	err, result := (&PurgePackageCacheCommand{}).Execute([]string{}, map[string][]string{})
+
+	assert.Error(t, err)
+	assert.Empty(t, result)
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,306 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+)
+
+const (
+	replayCommand    = "replay"
+	replayTranscript = "transcript"
+	replaySessionID  = "session-id"
+	replayTiming     = "timing"
+	replaySpeed      = "speed"
+
+	// asciicastVersion is the version of the asciicast recordings that can be replayed
+	asciicastVersion = 2
+
+	// asciicastOutput is the type of the asciicast events written to the terminal
+	asciicastOutput = "o"
+)
+
+const replayCommandHelp = `NAME:
+    {{.ReplayCommandName}}
+
+DESCRIPTION
+    Plays back a recorded session transcript in the terminal. Asciicast v2 recordings and
+    transcripts with a timing file of script(1) are played back with their original timing,
+    or faster with {{.SpeedFlag}}. Transcripts without timing are printed at once.
+
+    You will need to have admin rights to replay the transcript of a session of this instance.
+
+SYNOPSIS
+    {{.ReplayCommandName}}
+    {{.TranscriptFlag}} <value> | {{.SessionIDFlag}} <value>
+    [{{.TimingFlag}} <value>]
+    [{{.SpeedFlag}} <value>]
+
+PARAMETERS
+    {{.TranscriptFlag}} (string) Path of the transcript or of the asciicast recording.
+    {{.SessionIDFlag}} (string) Id of a session of this instance whose transcript is played back.
+    {{.TimingFlag}} (string) Path of the timing file of script(1) recorded with the transcript.
+    {{.SpeedFlag}} (number) Speed of the playback, 2 plays the transcript twice as fast, 1 by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.ReplayCommandName}} {{.TranscriptFlag}} session.cast {{.SpeedFlag}} 4
+
+OUTPUT
+    The session as it was displayed in the terminal
+`
+
+type replayHelpParams struct {
+	SsmCliName        string
+	ReplayCommandName string
+	TranscriptFlag    string
+	SessionIDFlag     string
+	TimingFlag        string
+	SpeedFlag         string
+}
+
+// replayFrame is output of the session written after a delay
+type replayFrame struct {
+	delay time.Duration
+	data  []byte
+}
+
+// dependencies of the command, replaced by the tests
+var replayOutput io.Writer = os.Stdout
+var replaySleep = time.Sleep
+
+func init() {
+	cliutil.Register(&ReplayCommand{})
+}
+
+type ReplayCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the replay cli command
+func (c *ReplayCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateReplayCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	transcriptPath := ""
+	if values, exists := parameters[replayTranscript]; exists {
+		transcriptPath = values[0]
+	} else {
+		orchestrationDir, err := findOrchestrationDir(appconfig.DefaultSessionRootDirName, parameters[replaySessionID][0])
+		if err != nil {
+			return err, ""
+		}
+		transcripts := findOutputs(orchestrationDir, isSessionTranscript, make(map[string]*tailedFile))
+		if len(transcripts) == 0 {
+			return fmt.Errorf("no transcript of %v was found on this instance", parameters[replaySessionID][0]), ""
+		}
+		transcriptPath = transcripts[0].path
+	}
+	speed := 1.0
+	if values, exists := parameters[replaySpeed]; exists {
+		speed, _ = strconv.ParseFloat(values[0], 64)
+	}
+
+	transcript, err := ioutil.ReadFile(transcriptPath)
+	if err != nil {
+		return err, ""
+	}
+	var frames []replayFrame
+	if values, exists := parameters[replayTiming]; exists {
+		timing, err := ioutil.ReadFile(values[0])
+		if err != nil {
+			return err, ""
+		}
+		if frames, err = timingFrames(transcript, timing); err != nil {
+			return err, ""
+		}
+	} else if frames, err = asciicastFrames(transcript); err != nil {
+		return err, ""
+	} else if frames == nil {
+		frames = []replayFrame{{data: transcript}}
+	}
+
+	for _, frame := range frames {
+		if frame.delay > 0 {
+			replaySleep(time.Duration(float64(frame.delay) / speed))
+		}
+		replayOutput.Write(frame.data)
+	}
+	return nil, ""
+}
+
+// asciicastFrames returns the output events of an asciicast v2 recording, nil when the transcript is not a recording
+func asciicastFrames(transcript []byte) ([]replayFrame, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(transcript))
+	scanner.Buffer(make([]byte, 64*1024), len(transcript)+1)
+	if !scanner.Scan() {
+		return nil, nil
+	}
+	var header struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version == nil {
+		return nil, nil
+	}
+	if *header.Version != asciicastVersion {
+		return nil, fmt.Errorf("asciicast version %v is not supported, only version %v is", *header.Version, asciicastVersion)
+	}
+
+	var frames []replayFrame
+	var previous float64
+	for line := 2; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			return nil, fmt.Errorf("line %v of the asciicast recording is not an event", line)
+		}
+		eventTime, isTime := event[0].(float64)
+		eventType, isType := event[1].(string)
+		data, isData := event[2].(string)
+		if !isTime || !isType || !isData {
+			return nil, fmt.Errorf("line %v of the asciicast recording is not an event", line)
+		}
+		if eventType != asciicastOutput {
+			continue
+		}
+		delay := eventTime - previous
+		if delay < 0 {
+			delay = 0
+		}
+		previous = eventTime
+		frames = append(frames, replayFrame{delay: time.Duration(delay * float64(time.Second)), data: []byte(data)})
+	}
+	return frames, scanner.Err()
+}
+
+// timingFrames splits the transcript with a timing file of script(1), whose lines are the delay in seconds
+// before a number of bytes of the transcript
+func timingFrames(transcript []byte, timing []byte) ([]replayFrame, error) {
+	// script writes a header line to the transcript before the session
+	if newline := bytes.IndexByte(transcript, '\n'); bytes.HasPrefix(transcript, []byte("Script started")) && newline >= 0 {
+		transcript = transcript[newline+1:]
+	}
+	var frames []replayFrame
+	offset := 0
+	for line, entry := range strings.Split(string(timing), "\n") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %v of the timing file is not a delay and a size", line+1)
+		}
+		delay, delayErr := strconv.ParseFloat(fields[0], 64)
+		size, sizeErr := strconv.Atoi(fields[1])
+		if delayErr != nil || sizeErr != nil || delay < 0 || size < 0 {
+			return nil, fmt.Errorf("line %v of the timing file is not a delay and a size", line+1)
+		}
+		end := offset + size
+		if end > len(transcript) {
+			end = len(transcript)
+		}
+		frames = append(frames, replayFrame{delay: time.Duration(delay * float64(time.Second)), data: transcript[offset:end]})
+		offset = end
+	}
+	if offset < len(transcript) {
+		frames = append(frames, replayFrame{data: transcript[offset:]})
+	}
+	return frames, nil
+}
+
+// Help prints help for the replay cli command
+func (c *ReplayCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("ReplayCommandHelp").Parse(replayCommandHelp)
+		params := replayHelpParams{
+			cliutil.SsmCliName,
+			replayCommand,
+			cliutil.FormatFlag(replayTranscript),
+			cliutil.FormatFlag(replaySessionID),
+			cliutil.FormatFlag(replayTiming),
+			cliutil.FormatFlag(replaySpeed),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (ReplayCommand) Name() string {
+	return replayCommand
+}
+
+// validateReplayCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (ReplayCommand) validateReplayCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", replayCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for required parameters
+	_, hasTranscript := parameters[replayTranscript]
+	_, hasSessionID := parameters[replaySessionID]
+	if hasTranscript == hasSessionID {
+		validation = append(validation, fmt.Sprintf("either %v or %v is required", cliutil.FormatFlag(replayTranscript), cliutil.FormatFlag(replaySessionID)))
+	}
+	for _, key := range []string{replayTranscript, replaySessionID, replayTiming, replaySpeed} {
+		if values, exists := parameters[key]; exists && len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	if values, exists := parameters[replaySessionID]; exists && len(values) == 1 && (strings.ContainsAny(values[0], `/\*?[`) || values[0] == "..") {
+		validation = append(validation, fmt.Sprintf("%v value %v is not valid", cliutil.FormatFlag(replaySessionID), values[0]))
+	}
+	if values, exists := parameters[replaySpeed]; exists && len(values) == 1 {
+		if speed, err := strconv.ParseFloat(values[0], 64); err != nil || speed <= 0 {
+			validation = append(validation, fmt.Sprintf("%v value must be a positive number", cliutil.FormatFlag(replaySpeed)))
+		}
+	}
+
+	// look for unsupported parameters
+	var unknown []string
+	for key := range parameters {
+		if key != replayTranscript && key != replaySessionID && key != replayTiming && key != replaySpeed {
+			unknown = append(unknown, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	sort.Strings(unknown)
+	return append(validation, unknown...)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// setReplayDependencies records the output and the delays of the playback
+func setReplayDependencies(t *testing.T) (dir string, output *bytes.Buffer, delays *[]time.Duration) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	outputOrig, sleepOrig := replayOutput, replaySleep
+	t.Cleanup(func() {
+		replayOutput, replaySleep = outputOrig, sleepOrig
+		os.RemoveAll(dir)
+	})
+	output, delays = new(bytes.Buffer), &[]time.Duration{}
+	replayOutput = output
+	replaySleep = func(delay time.Duration) { *delays = append(*delays, delay) }
+	return dir, output, delays
+}
+
+func TestReplayCommandAsciicast(t *testing.T) {
+	dir, output, delays := setReplayDependencies(t)
+	path := filepath.Join(dir, "session.cast")
+	writeTailFile(t, path, `{"version": 2, "width": 80, "height": 24}
+[0.5, "o", "$ "]
+[1.0, "i", "w"]
+[2.5, "o", "whoami\r\n"]
+
+[2.5, "o", "root\r\n"]
+`)
+
+	err, _ := (&ReplayCommand{}).Execute(nil, map[string][]string{replayTranscript: {path}, replaySpeed: {"2"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "$ whoami\r\nroot\r\n", output.String())
+	assert.Equal(t, []time.Duration{250 * time.Millisecond, time.Second}, *delays)
+}
+
+func TestReplayCommandTimingFile(t *testing.T) {
+	dir, output, delays := setReplayDependencies(t)
+	transcript, timing := filepath.Join(dir, "typescript"), filepath.Join(dir, "timing")
+	writeTailFile(t, transcript, "Script started on 2019-01-01 00:00:00+00:00\n$ whoami\nroot\n")
+	writeTailFile(t, timing, "0.100000 2\n1.5 7\n")
+
+	err, _ := (&ReplayCommand{}).Execute(nil, map[string][]string{replayTranscript: {transcript}, replayTiming: {timing}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "$ whoami\nroot\n", output.String())
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 1500 * time.Millisecond}, *delays)
+}
+
+func TestReplayCommandSessionTranscript(t *testing.T) {
+	dataStore, _ := setTailDependencies(t)
+	_, output, delays := setReplayDependencies(t)
+	writeTailFile(t, orchestrationPath(dataStore, appconfig.DefaultSessionRootDirName, "session-1", "Standard_Stream", "ipcTempFile.log"), "$ whoami\nroot\n")
+
+	err, _ := (&ReplayCommand{}).Execute(nil, map[string][]string{replaySessionID: {"session-1"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "$ whoami\nroot\n", output.String())
+	assert.Empty(t, *delays)
+}
+
+func TestReplayCommandInvalidRecording(t *testing.T) {
+	dir, _, _ := setReplayDependencies(t)
+	path := filepath.Join(dir, "session.cast")
+	writeTailFile(t, path, "{\"version\": 2}\n[0.5, \"o\"]\n")
+
+	err, _ := (&ReplayCommand{}).Execute(nil, map[string][]string{replayTranscript: {path}})
+
+	assert.EqualError(t, err, "line 2 of the asciicast recording is not an event")
+}
+
+func TestReplayCommandValidatesInput(t *testing.T) {
+	validation := ReplayCommand{}.validateReplayCommandInput(nil, map[string][]string{
+		replaySessionID: {"session-1"},
+		replaySpeed:     {"-1"},
+		"follow":        {},
+	})
+
+	assert.Equal(t, []string{"--speed value must be a positive number", "unknown parameter --follow"}, validation)
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/go-yaml/yaml"
+)
+
+const (
+	runDocumentCommand         = "run-document"
+	runDocumentPath            = "document-path"
+	runDocumentParameters      = "parameters"
+	runDocumentOutputDirectory = "output-directory"
+)
+
+const runDocumentCommandHelp = `NAME:
+    {{.RunDocumentCommandName}}
+
+DESCRIPTION
+    Runs a command document file on this instance with the plugins of the agent, without
+    sending the command through Systems Manager. The document is parsed and its parameters
+    are validated as the service does, so document authors can test a document before
+    creating it.
+
+    The steps run as the user running the command, you will need to have admin rights
+    to run most documents.
+
+SYNOPSIS
+    {{.RunDocumentCommandName}}
+    {{.PathFlag}} <value>
+    [{{.ParametersFlag}} <name=value> [<name=value> ...]]
+    [{{.OutputDirectoryFlag}} <value>]
+
+PARAMETERS
+    {{.PathFlag}} (string) Path of the JSON or YAML command document.
+    {{.ParametersFlag}} (list) Values of the document parameters, repeat a name to give the
+    values of a StringList parameter.
+    {{.OutputDirectoryFlag}} (string) Directory of the step outputs, a temporary directory by default.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.RunDocumentCommandName}} {{.PathFlag}} ./document.yaml {{.ParametersFlag}} commands=hostname
+
+    Output:
+      {
+        "outputDirectory": "/tmp/run-document123456",
+        "steps": [
+          {
+            "name": "runShellScript",
+            "status": "Success",
+            "code": 0,
+            "output": "ip-10-0-0-1\n"
+          }
+        ]
+      }
+
+OUTPUT
+    Status, exit code and output of each step in JSON format
+`
+
+type runDocumentHelpParams struct {
+	SsmCliName             string
+	RunDocumentCommandName string
+	PathFlag               string
+	ParametersFlag         string
+	OutputDirectoryFlag    string
+}
+
+// runDocumentStep is the result of a step in the output of the command
+type runDocumentStep struct {
+	Name   string                 `json:"name"`
+	Status contracts.ResultStatus `json:"status"`
+	Code   int                    `json:"code"`
+	Output interface{}            `json:"output,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// runDocumentOutput is the output of the command
+type runDocumentOutput struct {
+	OutputDirectory string            `json:"outputDirectory"`
+	Steps           []runDocumentStep `json:"steps"`
+}
+
+// dependencies of the command, replaced by the tests
+var newRunDocumentLogger = log.DefaultLogger
+var runDocumentPlugins = func(context context.T, plugins []contracts.PluginState, ioConfig contracts.IOConfiguration) map[string]*contracts.PluginResult {
+	// RunPlugins sends a result per plugin without blocking
+	resChan := make(chan contracts.PluginResult, len(plugins))
+	defer close(resChan)
+	return runpluginutil.RunPlugins(context, plugins, ioConfig, plugin.RegisteredWorkerPlugins(context), resChan, task.NewChanneledCancelFlag())
+}
+
+func init() {
+	cliutil.Register(&RunDocumentCommand{})
+}
+
+type RunDocumentCommand struct {
+	helpText string
+}
+
+// Execute validates and executes the run-document cli command
+func (c *RunDocumentCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateRunDocumentCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	documentRaw, err := ioutil.ReadFile(parameters[runDocumentPath][0])
+	if err != nil {
+		return err, ""
+	}
+	var docContent docparser.DocContent
+	if err = json.Unmarshal(documentRaw, &docContent); err != nil {
+		if err = yaml.Unmarshal(documentRaw, &docContent); err != nil {
+			return fmt.Errorf("the document is neither valid JSON nor valid YAML: %v", err), ""
+		}
+	}
+	documentParameters, err := parseDocumentParameters(parameters[runDocumentParameters], docContent.Parameters)
+	if err != nil {
+		return err, ""
+	}
+
+	outputDirectory := ""
+	if values, exists := parameters[runDocumentOutputDirectory]; exists {
+		outputDirectory = values[0]
+	} else if outputDirectory, err = ioutil.TempDir("", runDocumentCommand); err != nil {
+		return err, ""
+	}
+
+	logger := newRunDocumentLogger()
+	defer logger.Flush()
+	config, err := appconfig.Config(false)
+	if err != nil {
+		logger.Warnf("Failed to load the agent configuration, using the default configuration: %v", err)
+	}
+	ctx := context.Default(logger, config)
+
+	parserInfo := docparser.DocumentParserInfo{
+		OrchestrationDir:  outputDirectory,
+		MessageId:         runDocumentCommand,
+		DocumentId:        runDocumentCommand,
+		DefaultWorkingDir: outputDirectory,
+	}
+	pluginsInfo, err := docContent.ParseDocument(logger, contracts.DocumentInfo{}, parserInfo,
+		docparser.ParseParameters(logger, documentParameters, docContent.Parameters))
+	if err != nil {
+		return err, ""
+	}
+	results := runDocumentPlugins(ctx, pluginsInfo, docContent.GetIOConfiguration(parserInfo))
+
+	output := runDocumentOutput{OutputDirectory: outputDirectory, Steps: []runDocumentStep{}}
+	for _, pluginState := range pluginsInfo {
+		step := runDocumentStep{Name: pluginState.Id, Status: contracts.ResultStatusNotStarted}
+		if result, exists := results[pluginState.Id]; exists {
+			step.Status, step.Code, step.Output, step.Error = result.Status, result.Code, result.Output, result.Error
+		}
+		output.Steps = append(output.Steps, step)
+	}
+	result, err := jsonutil.Marshal(output)
+	if err != nil {
+		return err, ""
+	}
+	return nil, result
+}
+
+// parseDocumentParameters returns the values of the document parameters given as name=value, the service
+// rejects the commands missing a parameter without default value before they reach the agent
+func parseDocumentParameters(values []string, definitions map[string]*contracts.Parameter) (map[string][]*string, error) {
+	parameters := make(map[string][]*string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%v value %v must be name=value", cliutil.FormatFlag(runDocumentParameters), value)
+		}
+		if _, exists := definitions[parts[0]]; !exists {
+			return nil, fmt.Errorf("the document has no parameter %v", parts[0])
+		}
+		parameterValue := parts[1]
+		parameters[parts[0]] = append(parameters[parts[0]], &parameterValue)
+	}
+	var missing []string
+	for name, definition := range definitions {
+		if _, exists := parameters[name]; !exists && (definition == nil || definition.DefaultVal == nil) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing value for the document parameters %v", strings.Join(missing, ", "))
+	}
+	return parameters, nil
+}
+
+// Help prints help for the run-document cli command
+func (c *RunDocumentCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("RunDocumentCommandHelp").Parse(runDocumentCommandHelp)
+		params := runDocumentHelpParams{
+			cliutil.SsmCliName,
+			runDocumentCommand,
+			cliutil.FormatFlag(runDocumentPath),
+			cliutil.FormatFlag(runDocumentParameters),
+			cliutil.FormatFlag(runDocumentOutputDirectory),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (RunDocumentCommand) Name() string {
+	return runDocumentCommand
+}
+
+// validateRunDocumentCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (RunDocumentCommand) validateRunDocumentCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", runDocumentCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for required parameters
+	if _, exists := parameters[runDocumentPath]; !exists {
+		validation = append(validation, fmt.Sprintf("%v is required", cliutil.FormatFlag(runDocumentPath)))
+	} else if len(parameters[runDocumentPath]) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(runDocumentPath)))
+	}
+	if values, exists := parameters[runDocumentOutputDirectory]; exists && len(values) != 1 {
+		validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(runDocumentOutputDirectory)))
+	}
+
+	// look for unsupported parameters
+	var unknown []string
+	for key := range parameters {
+		if key != runDocumentPath && key != runDocumentParameters && key != runDocumentOutputDirectory {
+			unknown = append(unknown, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	sort.Strings(unknown)
+	return append(validation, unknown...)
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const runDocumentTestDocument = `
+schemaVersion: "2.2"
+description: test document
+parameters:
+  commands:
+    type: StringList
+  workingDirectory:
+    type: String
+    default: ""
+mainSteps:
+- action: aws:runShellScript
+  name: first
+  inputs:
+    runCommand: "{{ commands }}"
+    workingDirectory: "{{ workingDirectory }}"
+- action: aws:runShellScript
+  name: second
+  inputs:
+    runCommand: ["echo second"]
+`
+
+// setRunDocumentDependencies writes the test document and records the plugins the command runs
+func setRunDocumentDependencies(t *testing.T, results map[string]*contracts.PluginResult) (documentPath string, ran *[]contracts.PluginState) {
+	dir, err := ioutil.TempDir("", "rundocument")
+	assert.NoError(t, err)
+	documentPath = filepath.Join(dir, "document.yaml")
+	assert.NoError(t, ioutil.WriteFile(documentPath, []byte(runDocumentTestDocument), 0600))
+
+	loggerOrig, runOrig := newRunDocumentLogger, runDocumentPlugins
+	t.Cleanup(func() {
+		newRunDocumentLogger, runDocumentPlugins = loggerOrig, runOrig
+		os.RemoveAll(dir)
+	})
+	ran = &[]contracts.PluginState{}
+	newRunDocumentLogger = func() log.T { return log.NewMockLog() }
+	runDocumentPlugins = func(context context.T, plugins []contracts.PluginState, ioConfig contracts.IOConfiguration) map[string]*contracts.PluginResult {
+		*ran = plugins
+		return results
+	}
+	return documentPath, ran
+}
+
+func TestRunDocumentCommand(t *testing.T) {
+	documentPath, ran := setRunDocumentDependencies(t, map[string]*contracts.PluginResult{
+		"first": {PluginID: "first", Status: contracts.ResultStatusSuccess, Output: "one\ntwo\n"},
+	})
+	outputDirectory := filepath.Dir(documentPath)
+
+	err, result := (&RunDocumentCommand{}).Execute(nil, map[string][]string{
+		runDocumentPath:            {documentPath},
+		runDocumentParameters:      {"commands=echo one", "commands=echo two"},
+		runDocumentOutputDirectory: {outputDirectory},
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, *ran, 2) {
+		assert.Equal(t, "first", (*ran)[0].Id)
+		assert.Equal(t, []string{"echo one", "echo two"}, (*ran)[0].Configuration.Properties.(map[string]interface{})["runCommand"])
+		assert.Equal(t, outputDirectory, (*ran)[0].Configuration.DefaultWorkingDirectory)
+	}
+	var output runDocumentOutput
+	assert.NoError(t, json.Unmarshal([]byte(result), &output))
+	assert.Equal(t, runDocumentOutput{
+		OutputDirectory: outputDirectory,
+		Steps: []runDocumentStep{
+			{Name: "first", Status: contracts.ResultStatusSuccess, Output: "one\ntwo\n"},
+			{Name: "second", Status: contracts.ResultStatusNotStarted},
+		},
+	}, output)
+}
+
+func TestRunDocumentCommandRejectsMissingParameter(t *testing.T) {
+	documentPath, ran := setRunDocumentDependencies(t, nil)
+
+	err, _ := (&RunDocumentCommand{}).Execute(nil, map[string][]string{runDocumentPath: {documentPath}})
+
+	assert.EqualError(t, err, "missing value for the document parameters commands")
+	assert.Empty(t, *ran)
+}
+
+func TestRunDocumentCommandRejectsInvalidParameter(t *testing.T) {
+	documentPath, ran := setRunDocumentDependencies(t, nil)
+
+	err, _ := (&RunDocumentCommand{}).Execute(nil, map[string][]string{
+		runDocumentPath:       {documentPath},
+		runDocumentParameters: {"commands"},
+	})
+
+	assert.EqualError(t, err, "--parameters value commands must be name=value")
+	assert.Empty(t, *ran)
+}
+
+func TestRunDocumentCommandRejectsUnknownParameter(t *testing.T) {
+	documentPath, ran := setRunDocumentDependencies(t, nil)
+
+	err, _ := (&RunDocumentCommand{}).Execute(nil, map[string][]string{
+		runDocumentPath:       {documentPath},
+		runDocumentParameters: {"commands=hostname", "executionTimeout=10"},
+	})
+
+	assert.EqualError(t, err, "the document has no parameter executionTimeout")
+	assert.Empty(t, *ran)
+}
+
+func TestRunDocumentCommandValidatesInput(t *testing.T) {
+	validation := RunDocumentCommand{}.validateRunDocumentCommandInput(nil, map[string][]string{
+		runDocumentPath: {"a.json", "b.json"},
+		"timeout":       {"10"},
+	})
+
+	assert.Equal(t, []string{"expected 1 value for parameter --document-path", "unknown parameter --timeout"}, validation)
+}
@@ -172,7 +172,7 @@ func (SendOfflineCommand) loadContent(rawContent string) (error, contracts.Docum
 	}
 }
 
-//validateContent checks to see that content has at least one runtimeConfig for 1.2 or mainSteps for 2.0 and no unbound parameters
+// validateContent checks to see that content has at least one runtimeConfig for 1.2 or mainSteps for 2.0 and no unbound parameters
 func (SendOfflineCommand) validateContent(content contracts.DocumentContent) error {
 	// TODO:MF: also check for unbound parameters
 	if content.SchemaVersion == "1.2" {
@@ -0,0 +1,324 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/cli/cliutil"
+	sessionconfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+)
+
+const (
+	tailCommand   = "tail"
+	tailCommandID = "command-id"
+	tailSessionID = "session-id"
+	tailFollow    = "follow"
+	tailLines     = "lines"
+
+	// tailDefaultLines is the number of lines printed from the end of each output
+	tailDefaultLines = 10
+
+	// tailMaxBytes is the most bytes read from the end of each output for the first lines
+	tailMaxBytes = 64 * 1024
+)
+
+const tailCommandHelp = `NAME:
+    {{.TailCommandName}}
+
+DESCRIPTION
+    Prints the end of the outputs of a command, or of the transcript of a session, running
+    on this instance, from the orchestration folders of the agent. With {{.FollowFlag}}, the
+    new output is printed until the command or the session ends.
+
+    You will need to have admin rights to run this command.
+
+SYNOPSIS
+    {{.TailCommandName}}
+    {{.CommandIDFlag}} <value> | {{.SessionIDFlag}} <value>
+    [{{.LinesFlag}} <value>]
+    [{{.FollowFlag}}]
+
+PARAMETERS
+    {{.CommandIDFlag}} (string) Id of the command whose step outputs are printed.
+    {{.SessionIDFlag}} (string) Id of the session whose transcript is printed.
+    {{.LinesFlag}} (integer) Number of lines printed from the end of each output, 10 by default.
+    {{.FollowFlag}} Prints the new output until the command or the session ends.
+
+EXAMPLES
+    Command:
+
+      {{.SsmCliName}} {{.TailCommandName}} {{.CommandIDFlag}} 01234567-890a-bcde-f012-34567890abcd {{.FollowFlag}}
+
+    Output:
+
+      ==> runShellScript/0.awsrunShellScript/stdout <==
+      installing packages
+
+OUTPUT
+    The end of each output, preceded by its path when the command has several outputs
+`
+
+type tailHelpParams struct {
+	SsmCliName      string
+	TailCommandName string
+	CommandIDFlag   string
+	SessionIDFlag   string
+	LinesFlag       string
+	FollowFlag      string
+}
+
+// dependencies of the command, replaced by the tests
+var tailOutput io.Writer = os.Stdout
+var tailPollInterval = time.Second
+var tailDataStorePath = func() string { return appconfig.DefaultDataStorePath }
+
+func init() {
+	cliutil.Register(&TailCommand{})
+}
+
+type TailCommand struct {
+	helpText string
+}
+
+// tailedFile is an output being printed
+type tailedFile struct {
+	path   string
+	name   string
+	offset int64
+}
+
+// Execute validates and executes the tail cli command
+func (c *TailCommand) Execute(subcommands []string, parameters map[string][]string) (error, string) {
+	validation := c.validateTailCommandInput(subcommands, parameters)
+	// return validation errors if any were found
+	if len(validation) > 0 {
+		return errors.New(strings.Join(validation, "\n")), ""
+	}
+
+	documentID, rootDirName, isOutput := "", appconfig.DefaultDocumentRootDirName, isCommandOutput
+	if values, exists := parameters[tailCommandID]; exists {
+		documentID = values[0]
+	} else {
+		documentID, rootDirName, isOutput = parameters[tailSessionID][0], appconfig.DefaultSessionRootDirName, isSessionTranscript
+	}
+	lines := tailDefaultLines
+	if values, exists := parameters[tailLines]; exists {
+		lines, _ = strconv.Atoi(values[0])
+	}
+	_, follow := parameters[tailFollow]
+
+	orchestrationDir, err := findOrchestrationDir(rootDirName, documentID)
+	if err != nil {
+		return err, ""
+	}
+
+	files := make(map[string]*tailedFile)
+	var last *tailedFile
+	for {
+		running := isDocumentRunning(documentID)
+		for _, file := range findOutputs(orchestrationDir, isOutput, files) {
+			if content := readNew(file, lines); len(content) > 0 {
+				if last != file && len(files) > 1 {
+					fmt.Fprintf(tailOutput, "==> %v <==\n", file.name)
+				}
+				tailOutput.Write(content)
+				last = file
+			}
+		}
+		if !follow || !running {
+			return nil, ""
+		}
+		time.Sleep(tailPollInterval)
+	}
+}
+
+// findOrchestrationDir returns the orchestration folder of a command or a session of the instance
+func findOrchestrationDir(rootDirName string, documentID string) (string, error) {
+	orchestrationRootDir := appconfig.DefaultConfig().Agent.OrchestrationRootDir
+	if config, err := appconfig.Config(false); err == nil {
+		orchestrationRootDir = config.Agent.OrchestrationRootDir
+	}
+	matches, _ := filepath.Glob(filepath.Join(tailDataStorePath(), "*", rootDirName, orchestrationRootDir, documentID))
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no output of %v was found on this instance", documentID)
+	}
+	return matches[0], nil
+}
+
+// isCommandOutput returns true for the standard output and error files of the steps
+func isCommandOutput(name string) bool {
+	return name == "stdout" || name == "stderr"
+}
+
+// isSessionTranscript returns true for the transcript files of the session plugins
+func isSessionTranscript(name string) bool {
+	return name == sessionconfig.IpcFileName+sessionconfig.LogFileExtension
+}
+
+// findOutputs adds the outputs created in the orchestration folder to the files and returns all the files sorted by name
+func findOutputs(orchestrationDir string, isOutput func(name string) bool, files map[string]*tailedFile) []*tailedFile {
+	filepath.Walk(orchestrationDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isOutput(info.Name()) {
+			return nil
+		}
+		if _, exists := files[path]; !exists {
+			name, _ := filepath.Rel(orchestrationDir, path)
+			files[path] = &tailedFile{path: path, name: filepath.ToSlash(name), offset: -1}
+		}
+		return nil
+	})
+	sorted := make([]*tailedFile, 0, len(files))
+	for _, file := range files {
+		sorted = append(sorted, file)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted
+}
+
+// readNew returns the content written to the file since the last read, the last lines on the first read
+func readNew(file *tailedFile, lines int) []byte {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	first := file.offset < 0
+	start := file.offset
+	if first {
+		start = info.Size() - tailMaxBytes
+		if start < 0 {
+			start = 0
+		}
+	} else if info.Size() < start {
+		// the file was truncated, print it again
+		start = 0
+	}
+	content := make([]byte, info.Size()-start)
+	n, _ := f.ReadAt(content, start)
+	content = content[:n]
+	file.offset = start + int64(n)
+	if first {
+		content = lastLines(content, lines)
+	}
+	return content
+}
+
+// lastLines returns the last lines of the content
+func lastLines(content []byte, lines int) []byte {
+	end := len(content)
+	if end > 0 && content[end-1] == '\n' {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if content[i] == '\n' {
+			if lines--; lines == 0 {
+				return content[i+1:]
+			}
+		}
+	}
+	if lines == 0 {
+		return nil
+	}
+	return content
+}
+
+// isDocumentRunning returns true while the agent has the document or session in its current documents
+func isDocumentRunning(documentID string) bool {
+	matches, _ := filepath.Glob(filepath.Join(tailDataStorePath(), "*", appconfig.DefaultDocumentRootDirName,
+		appconfig.DefaultLocationOfState, appconfig.DefaultLocationOfCurrent, documentID))
+	return len(matches) > 0
+}
+
+// Help prints help for the tail cli command
+func (c *TailCommand) Help() string {
+	if len(c.helpText) == 0 {
+		t, _ := template.New("TailCommandHelp").Parse(tailCommandHelp)
+		params := tailHelpParams{
+			cliutil.SsmCliName,
+			tailCommand,
+			cliutil.FormatFlag(tailCommandID),
+			cliutil.FormatFlag(tailSessionID),
+			cliutil.FormatFlag(tailLines),
+			cliutil.FormatFlag(tailFollow),
+		}
+		buf := new(bytes.Buffer)
+		t.Execute(buf, params)
+		c.helpText = buf.String()
+	}
+	return c.helpText
+}
+
+// Name is the command name used in the cli
+func (TailCommand) Name() string {
+	return tailCommand
+}
+
+// validateTailCommandInput checks the subcommands and parameters for required values, format, and unsupported values
+func (TailCommand) validateTailCommandInput(subcommands []string, parameters map[string][]string) []string {
+	validation := make([]string, 0)
+	if subcommands != nil && len(subcommands) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not support subcommand %v", tailCommand, subcommands), "")
+		return validation // invalid subcommand is an attempt to execute something that really isn't this command, so the rest of the validation is skipped in this case
+	}
+
+	// look for required parameters
+	_, hasCommandID := parameters[tailCommandID]
+	_, hasSessionID := parameters[tailSessionID]
+	if hasCommandID == hasSessionID {
+		validation = append(validation, fmt.Sprintf("either %v or %v is required", cliutil.FormatFlag(tailCommandID), cliutil.FormatFlag(tailSessionID)))
+	}
+	for _, key := range []string{tailCommandID, tailSessionID, tailLines} {
+		if values, exists := parameters[key]; exists && len(values) != 1 {
+			validation = append(validation, fmt.Sprintf("expected 1 value for parameter %v", cliutil.FormatFlag(key)))
+		} else if exists && key != tailLines && (strings.ContainsAny(values[0], `/\*?[`) || values[0] == "..") {
+			validation = append(validation, fmt.Sprintf("%v value %v is not valid", cliutil.FormatFlag(key), values[0]))
+		}
+	}
+	if values, exists := parameters[tailLines]; exists && len(values) == 1 {
+		if lines, err := strconv.Atoi(values[0]); err != nil || lines < 1 {
+			validation = append(validation, fmt.Sprintf("%v value must be a positive integer", cliutil.FormatFlag(tailLines)))
+		}
+	}
+	if values, exists := parameters[tailFollow]; exists && len(values) > 0 {
+		validation = append(validation, fmt.Sprintf("%v does not take a value", cliutil.FormatFlag(tailFollow)))
+	}
+
+	// look for unsupported parameters
+	var unknown []string
+	for key := range parameters {
+		if key != tailCommandID && key != tailSessionID && key != tailLines && key != tailFollow {
+			unknown = append(unknown, fmt.Sprintf("unknown parameter %v", cliutil.FormatFlag(key)))
+		}
+	}
+	sort.Strings(unknown)
+	return append(validation, unknown...)
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clicommand contains the implementation of all commands for the ssm agent cli
+package clicommand
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+const tailTestInstanceID = "i-12345678"
+
+// setTailDependencies makes the command read a temporary data store and returns the data store and the printed output
+func setTailDependencies(t *testing.T) (dataStore string, output *bytes.Buffer) {
+	dir, err := ioutil.TempDir("", "tail")
+	assert.NoError(t, err)
+	outputOrig, intervalOrig, dataStoreOrig := tailOutput, tailPollInterval, tailDataStorePath
+	t.Cleanup(func() {
+		tailOutput, tailPollInterval, tailDataStorePath = outputOrig, intervalOrig, dataStoreOrig
+		os.RemoveAll(dir)
+	})
+	output = new(bytes.Buffer)
+	tailOutput, tailPollInterval = output, 10*time.Millisecond
+	tailDataStorePath = func() string { return dir }
+	return dir, output
+}
+
+func writeTailFile(t *testing.T, path string, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func appendTailFile(t *testing.T, path string, content string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	assert.NoError(t, err)
+}
+
+func orchestrationPath(dataStore, rootDirName, documentID string, elem ...string) string {
+	return filepath.Join(append([]string{dataStore, tailTestInstanceID, rootDirName, "orchestration", documentID}, elem...)...)
+}
+
+func TestTailCommandPrintsLastLines(t *testing.T) {
+	dataStore, output := setTailDependencies(t)
+	writeTailFile(t, orchestrationPath(dataStore, appconfig.DefaultDocumentRootDirName, "command-1", "step1", "stdout"), "1\n2\n3\n")
+	writeTailFile(t, orchestrationPath(dataStore, appconfig.DefaultDocumentRootDirName, "command-1", "step1", "stderr"), "")
+	writeTailFile(t, orchestrationPath(dataStore, appconfig.DefaultDocumentRootDirName, "command-1", "step2", "stdout"), "4\n")
+
+	err, result := (&TailCommand{}).Execute(nil, map[string][]string{tailCommandID: {"command-1"}, tailLines: {"2"}})
+
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, "==> step1/stdout <==\n2\n3\n==> step2/stdout <==\n4\n", output.String())
+}
+
+func TestTailCommandFollowsSession(t *testing.T) {
+	dataStore, output := setTailDependencies(t)
+	transcript := orchestrationPath(dataStore, appconfig.DefaultSessionRootDirName, "session-1", "Standard_Stream", "ipcTempFile.log")
+	writeTailFile(t, transcript, "$ whoami\n")
+	state := filepath.Join(dataStore, tailTestInstanceID, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState,
+		appconfig.DefaultLocationOfCurrent, "session-1")
+	writeTailFile(t, state, "{}")
+
+	done := make(chan bool)
+	go func() {
+		(&TailCommand{}).Execute(nil, map[string][]string{tailSessionID: {"session-1"}, tailFollow: {}})
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	appendTailFile(t, transcript, "root\n")
+	time.Sleep(50 * time.Millisecond)
+	// the command stops following when the session ends
+	assert.NoError(t, os.Remove(state))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "tail did not stop at the end of the session")
+	}
+	assert.Equal(t, "$ whoami\nroot\n", output.String())
+}
+
+func TestTailCommandNoOutput(t *testing.T) {
+	setTailDependencies(t)
+
+	err, _ := (&TailCommand{}).Execute(nil, map[string][]string{tailCommandID: {"command-1"}})
+
+	assert.EqualError(t, err, "no output of command-1 was found on this instance")
+}
+
+func TestTailCommandValidatesInput(t *testing.T) {
+	validation := TailCommand{}.validateTailCommandInput(nil, map[string][]string{
+		tailCommandID: {"../command-1"},
+		tailSessionID: {"session-1"},
+		tailLines:     {"0"},
+		"timeout":     {"10"},
+	})
+
+	assert.Equal(t, []string{
+		"either --command-id or --session-id is required",
+		"--command-id value ../command-1 is not valid",
+		"--lines value must be a positive integer",
+		"unknown parameter --timeout",
+	}, validation)
+}
+
+func TestLastLines(t *testing.T) {
+	assert.Equal(t, "3\n", string(lastLines([]byte("1\n2\n3\n"), 1)))
+	assert.Equal(t, "2\n3", string(lastLines([]byte("1\n2\n3"), 2)))
+	assert.Equal(t, "1\n2\n", string(lastLines([]byte("1\n2\n"), 5)))
+}
@@ -14,8 +14,6 @@
 package compliance
 
 import (
-	"crypto/md5"
-	"encoding/base64"
 	"fmt"
 	"sync"
 	"time"
@@ -26,6 +24,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/compliance/model"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/datauploader"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
@@ -101,9 +100,7 @@ func (u *ComplianceUploader) CreateNewServiceIfUnHealthy(log log.T) {
 }
 
 func calculateCheckSum(data []byte) (checkSum string) {
-	sum := md5.Sum(data)
-	checkSum = base64.StdEncoding.EncodeToString(sum[:])
-	return
+	return fips.ContentHash(data)
 }
 
 /**
@@ -0,0 +1,77 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configoverrides fetches the settings overriding the agent config file from Parameter Store.
+package configoverrides
+
+import (
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssm"
+	ssmsdk "github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// saveOverrides saves the fetched settings for the agent and its workers, stubbed in tests
+var saveOverrides = appconfig.SaveParameterOverrides
+
+// loadConfig loads the agent configuration, stubbed in tests
+var loadConfig = appconfig.Config
+
+// Fetch returns the settings stored under the Parameter Store path, by setting path such as Mds.CommandWorkersLimit
+// for the parameter <path>/Mds/CommandWorkersLimit, the secure strings are not fetched
+func Fetch(log log.T, ssmService ssm.Service, path string) (map[string]string, error) {
+	parameters, err := ssmService.GetParametersByPath(log, path)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	overrides := make(map[string]string)
+	for _, parameter := range parameters {
+		if parameter.Name == nil || parameter.Value == nil || !strings.HasPrefix(*parameter.Name, prefix) {
+			continue
+		}
+		if parameter.Type != nil && *parameter.Type == ssmsdk.ParameterTypeSecureString {
+			log.Warnf("Ignoring the secure string parameter %v, the agent settings are not secret", *parameter.Name)
+			continue
+		}
+		setting := strings.Replace(strings.TrimPrefix(*parameter.Name, prefix), "/", ".", -1)
+		overrides[setting] = *parameter.Value
+	}
+	return overrides, nil
+}
+
+// Load fetches the settings of the Parameter Store path of the configuration and returns the configuration
+// they override, the settings fetched at the previous startup are kept when Parameter Store is unreachable
+func Load(log log.T, config appconfig.SsmagentConfig, newService func() ssm.Service) (appconfig.SsmagentConfig, error) {
+	path := config.ConfigOverrides.ParameterStorePath
+	if path == "" {
+		if err := saveOverrides(nil); err != nil {
+			log.Warnf("Failed to remove the settings fetched from Parameter Store, %v", err)
+		}
+		return config, nil
+	}
+	overrides, err := Fetch(log, newService(), path)
+	if err != nil {
+		log.Warnf("Failed to fetch the settings from Parameter Store path %v, keeping the settings fetched previously, %v", path, err)
+		return config, nil
+	}
+	if err = saveOverrides(overrides); err != nil {
+		log.Warnf("Failed to save the settings fetched from Parameter Store path %v, %v", path, err)
+		return config, nil
+	}
+	log.Infof("Applying %v settings from Parameter Store path %v", len(overrides), path)
+	return loadConfig(true)
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configreload applies the reloadable settings of the agent config file when it changes,
+// the log level, timeouts, proxies and plugin allowlist change without restarting the agent.
+package configreload
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	name = "ConfigReload"
+
+	// reloadDelay is the time without changes to the config file before it is reloaded,
+	// editors and configuration tools write a file in several steps
+	reloadDelay = time.Second
+)
+
+// reloadConfig reloads the config file, stubbed in tests
+var reloadConfig = appconfig.Reload
+
+// setLogLevel sets the log level of the config file on the agent logger, stubbed in tests
+var setLogLevel = ssmlog.SetConfiguredLevel
+
+// ConfigReload is the core module reloading the agent config file when it changes
+type ConfigReload struct {
+	context context.T
+	// configPaths are the paths of the config file in every format
+	configPaths []string
+	watcher     *fsnotify.Watcher
+	stop        chan bool
+	done        chan bool
+}
+
+// NewConfigReload creates the core module reloading the agent config file, the log level of
+// the config file is applied when the module is created
+func NewConfigReload(context context.T) *ConfigReload {
+	context = context.With("[" + name + "]")
+	if level := context.AppConfig().Agent.LogLevel; level != "" {
+		applyLogLevel(context.Log(), level)
+	}
+	var configPaths []string
+	for _, path := range appconfig.AppConfigPaths() {
+		configPaths = append(configPaths, filepath.Clean(path))
+	}
+	return &ConfigReload{
+		context:     context,
+		configPaths: configPaths,
+		stop:        make(chan bool),
+		done:        make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (m *ConfigReload) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts watching the agent config file
+func (m *ConfigReload) ModuleExecute(context context.T) (err error) {
+	log := m.context.Log()
+	// the directory is watched since the config file can be created or replaced
+	folder := filepath.Dir(m.configPaths[0])
+	if m.watcher, err = fsnotify.NewWatcher(); err != nil {
+		log.Errorf("Failed to watch the config file in %v, it is only reloaded on SIGHUP: %v", folder, err)
+		return nil
+	}
+	if err = m.watcher.Add(folder); err != nil {
+		log.Errorf("Failed to watch the config file in %v, it is only reloaded on SIGHUP: %v", folder, err)
+		m.watcher.Close()
+		m.watcher = nil
+		return nil
+	}
+	log.Infof("Reloading the config file in %v when it changes", folder)
+	go m.run(m.watcher.Events)
+	return nil
+}
+
+// ModuleRequestStop stops watching the agent config file
+func (m *ConfigReload) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if m.watcher == nil {
+		return nil
+	}
+	close(m.stop)
+	<-m.done
+	return m.watcher.Close()
+}
+
+func (m *ConfigReload) run(events <-chan fsnotify.Event) {
+	defer close(m.done)
+	var reloadTimer <-chan time.Time
+	for {
+		select {
+		case <-m.stop:
+			return
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if m.isConfigFile(event.Name) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				// the config file is reloaded once it stopped changing
+				reloadTimer = time.After(reloadDelay)
+			}
+		case <-reloadTimer:
+			reloadTimer = nil
+			Reload(m.context.Log())
+		}
+	}
+}
+
+// isConfigFile returns true if the path is the config file in one of its formats
+func (m *ConfigReload) isConfigFile(path string) bool {
+	path = filepath.Clean(path)
+	for _, configPath := range m.configPaths {
+		if path == configPath {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reloads the agent config file and applies its reloadable settings,
+// the other changed settings are logged since they need an agent restart
+func Reload(log log.T) {
+	applied, restartNeeded, err := reloadConfig()
+	if err != nil {
+		log.Errorf("Failed to reload the config file, the current configuration is kept: %v", err)
+		return
+	}
+	if len(applied) == 0 {
+		log.Info("Config file reloaded, no reloadable setting changed")
+	} else {
+		log.Infof("Config file reloaded, applied the settings %v", strings.Join(applied, ", "))
+	}
+	if len(restartNeeded) > 0 {
+		log.Warnf("The changes to the %v sections of the config file are applied when the agent restarts", strings.Join(restartNeeded, ", "))
+	}
+	for _, setting := range applied {
+		if setting == "Agent.LogLevel" {
+			config, _ := appconfig.Config(false)
+			applyLogLevel(log, config.Agent.LogLevel)
+		}
+	}
+}
+
+func applyLogLevel(log log.T, level string) {
+	if err := setLogLevel(level); err != nil {
+		log.Errorf("Failed to apply the log level %v of the config file: %v", level, err)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package configreload
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithLogLevel(level string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.Agent.LogLevel = level
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+// stubReload makes the reloads return the results and records the log levels set
+func stubReload(t *testing.T, applied []string, restartNeeded []string, err error) (reloads *int, levels *[]string) {
+	reloadConfigOrig, setLogLevelOrig := reloadConfig, setLogLevel
+	t.Cleanup(func() { reloadConfig, setLogLevel = reloadConfigOrig, setLogLevelOrig })
+	reloads, levels = new(int), new([]string)
+	reloadConfig = func() ([]string, []string, error) {
+		*reloads++
+		return applied, restartNeeded, err
+	}
+	setLogLevel = func(level string) error {
+		*levels = append(*levels, level)
+		return nil
+	}
+	return reloads, levels
+}
+
+func TestNewConfigReload_AppliesLogLevel(t *testing.T) {
+	_, levels := stubReload(t, nil, nil, nil)
+
+	m := NewConfigReload(mockContextWithLogLevel("warn"))
+	assert.Equal(t, name, m.ModuleName())
+	assert.Equal(t, []string{"warn"}, *levels)
+
+	// the seelog configuration is kept without log level in the config file
+	NewConfigReload(mockContextWithLogLevel(""))
+	assert.Equal(t, []string{"warn"}, *levels)
+}
+
+func TestReload_LogsRestartNeeded(t *testing.T) {
+	reloads, levels := stubReload(t, []string{"Mgs.StopTimeoutMillis"}, []string{"Ssm"}, nil)
+	mockLog := log.NewMockLog()
+
+	Reload(mockLog)
+
+	assert.Equal(t, 1, *reloads)
+	assert.Empty(t, *levels)
+	mockLog.AssertCalled(t, "Infof", "Config file reloaded, applied the settings %v", []interface{}{"Mgs.StopTimeoutMillis"})
+	mockLog.AssertCalled(t, "Warnf", "The changes to the %v sections of the config file are applied when the agent restarts", []interface{}{"Ssm"})
+}
+
+func TestReload_Error(t *testing.T) {
+	_, levels := stubReload(t, nil, nil, errors.New("invalid character"))
+	mockLog := log.NewMockLog()
+
+	Reload(mockLog)
+
+	assert.Empty(t, *levels)
+	mockLog.AssertCalled(t, "Errorf", "Failed to reload the config file, the current configuration is kept: %v", []interface{}{errors.New("invalid character")})
+}
+
+func TestRun_ReloadsOnceAfterChanges(t *testing.T) {
+	reloads, _ := stubReload(t, nil, nil, nil)
+	m := NewConfigReload(mockContextWithLogLevel(""))
+	events := make(chan fsnotify.Event)
+	go m.run(events)
+
+	// the changes to other files are ignored, the changes to the config file are reloaded once
+	events <- fsnotify.Event{Name: m.configPaths[0] + ".bak", Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: m.configPaths[0], Op: fsnotify.Create}
+	events <- fsnotify.Event{Name: m.configPaths[1], Op: fsnotify.Write}
+	time.Sleep(reloadDelay + 500*time.Millisecond)
+	close(m.stop)
+	<-m.done
+
+	assert.Equal(t, 1, *reloads)
+}
@@ -65,7 +65,7 @@ func (c *defaultContext) Log() log.T {
 }
 
 func (c *defaultContext) AppConfig() appconfig.SsmagentConfig {
-	return c.appconfig
+	return appconfig.WithReloadedSettings(c.appconfig)
 }
 
 func (c *defaultContext) CurrentContext() []string {
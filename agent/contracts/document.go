@@ -76,19 +76,26 @@ type DocumentInfo struct {
 	ClientId        string
 }
 
-//CloudWatchConfiguration represents information relevant to command output in cloudWatch
+// CloudWatchConfiguration represents information relevant to command output in cloudWatch
 type CloudWatchConfiguration struct {
 	LogGroupName              string
 	LogStreamPrefix           string
 	LogGroupEncryptionEnabled bool
 }
 
+// FirehoseConfiguration represents the Kinesis Data Firehose delivery stream the output of a plugin is sent to
+type FirehoseConfiguration struct {
+	DeliveryStreamName string
+	PartitionKeys      map[string]string
+}
+
 // IOConfiguration represents information relevant to the output sources of a command
 type IOConfiguration struct {
 	OrchestrationDirectory string
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
 	CloudWatchConfig       CloudWatchConfiguration
+	FirehoseConfig         FirehoseConfiguration
 }
 
 // DocumentState represents information relevant to a command that gets executed by agent
@@ -181,6 +181,7 @@ type InstancePluginConfig struct {
 	Settings      interface{}         `json:"settings" yaml:"settings"`
 	Timeout       int                 `json:"timeoutSeconds" yaml:"timeoutSeconds"`
 	Preconditions map[string][]string `json:"precondition" yaml:"precondition"`
+	Independent   bool                `json:"independent" yaml:"independent"` // may run concurrently with adjacent independent steps
 }
 
 // DocumentContent object which represents ssm document content.
@@ -200,6 +201,12 @@ type SessionInputs struct {
 	CloudWatchLogGroupName      string `json:"cloudWatchLogGroupName" yaml:"cloudWatchLogGroupName"`
 	CloudWatchEncryptionEnabled bool   `json:"cloudWatchEncryptionEnabled" yaml:"cloudWatchEncryptionEnabled"`
 	KmsKeyId                    string `json:"kmsKeyId" yaml:"kmsKeyId"`
+	// TokenElevation overrides the token elevation of the agent config for the Windows sessions run as ssm-user
+	TokenElevation string `json:"tokenElevation" yaml:"tokenElevation"`
+	// NetworkNamespace is the named Linux network namespace the sessions run in, the one of the agent when empty
+	NetworkNamespace string `json:"networkNamespace" yaml:"networkNamespace"`
+	// WorkingDirectory overrides the directory of the agent config the shells of the sessions start in
+	WorkingDirectory string `json:"workingDirectory" yaml:"workingDirectory"`
 }
 
 // SessionDocumentContent object which represents ssm session content.
@@ -210,6 +217,8 @@ type SessionDocumentContent struct {
 	Inputs          SessionInputs         `json:"inputs" yaml:"inputs"`
 	Parameters      map[string]*Parameter `json:"parameters" yaml:"parameters"`
 	SessionCommands []*SessionCommand     `json:"sessionCommands" yaml:"sessionCommands"`
+	// Properties are the properties of the session plugins without commands, like the port of the Port sessions
+	Properties interface{} `json:"properties" yaml:"properties"`
 }
 
 // SessionCommand object represents session manager commands with cross-platform preconditions.
@@ -238,16 +247,17 @@ type AgentInfo struct {
 
 // PluginRuntimeStatus represents plugin runtime status section in agent response
 type PluginRuntimeStatus struct {
-	Status             ResultStatus `json:"status"`
-	Code               int          `json:"code"`
-	Name               string       `json:"name"`
-	Output             string       `json:"output"`
-	StartDateTime      string       `json:"startDateTime"`
-	EndDateTime        string       `json:"endDateTime"`
-	OutputS3BucketName string       `json:"outputS3BucketName"`
-	OutputS3KeyPrefix  string       `json:"outputS3KeyPrefix"`
-	StandardOutput     string       `json:"standardOutput"`
-	StandardError      string       `json:"standardError"`
+	Status             ResultStatus   `json:"status"`
+	Code               int            `json:"code"`
+	Name               string         `json:"name"`
+	Output             string         `json:"output"`
+	StartDateTime      string         `json:"startDateTime"`
+	EndDateTime        string         `json:"endDateTime"`
+	OutputS3BucketName string         `json:"outputS3BucketName"`
+	OutputS3KeyPrefix  string         `json:"outputS3KeyPrefix"`
+	StandardOutput     string         `json:"standardOutput"`
+	StandardError      string         `json:"standardError"`
+	ResourceUsage      *ResourceUsage `json:"resourceUsage,omitempty"`
 }
 
 // AgentConfiguration is a struct that stores information about the agent and instance
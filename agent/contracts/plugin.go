@@ -28,18 +28,28 @@ const (
 
 // PluginResult represents a plugin execution result.
 type PluginResult struct {
-	PluginID           string       `json:"pluginID"`
-	PluginName         string       `json:"pluginName"`
-	Status             ResultStatus `json:"status"`
-	Code               int          `json:"code"`
-	Output             interface{}  `json:"output"`
-	StartDateTime      time.Time    `json:"startDateTime"`
-	EndDateTime        time.Time    `json:"endDateTime"`
-	OutputS3BucketName string       `json:"outputS3BucketName"`
-	OutputS3KeyPrefix  string       `json:"outputS3KeyPrefix"`
-	Error              string       `json:"error"`
-	StandardOutput     string       `json:"standardOutput"`
-	StandardError      string       `json:"standardError"`
+	PluginID           string         `json:"pluginID"`
+	PluginName         string         `json:"pluginName"`
+	Status             ResultStatus   `json:"status"`
+	Code               int            `json:"code"`
+	Output             interface{}    `json:"output"`
+	StartDateTime      time.Time      `json:"startDateTime"`
+	EndDateTime        time.Time      `json:"endDateTime"`
+	OutputS3BucketName string         `json:"outputS3BucketName"`
+	OutputS3KeyPrefix  string         `json:"outputS3KeyPrefix"`
+	Error              string         `json:"error"`
+	StandardOutput     string         `json:"standardOutput"`
+	StandardError      string         `json:"standardError"`
+	ResourceUsage      *ResourceUsage `json:"resourceUsage,omitempty"`
+}
+
+// ResourceUsage is the resources used by a plugin execution
+type ResourceUsage struct {
+	CPUTimeMillis  int64 `json:"cpuTimeMillis"`
+	PeakMemoryKB   int64 `json:"peakMemoryKB"`
+	DiskReadBytes  int64 `json:"diskReadBytes"`
+	DiskWriteBytes int64 `json:"diskWriteBytes"`
+	UploadedBytes  int64 `json:"uploadedBytes"`
 }
 
 // IPlugin is interface for authoring a functionality of work.
@@ -80,12 +90,17 @@ type Configuration struct {
 	DefaultWorkingDirectory     string
 	Preconditions               map[string][]string
 	IsPreconditionEnabled       bool
+	IsIndependent               bool
 	CurrentAssociations         []string
 	SessionId                   string
 	ClientId                    string
 	KmsKeyId                    string
 	Commands                    string
 	RunAsElevated               bool
+	TokenElevation              string
+	NetworkNamespace            string
+	WorkingDirectory            string
+	TempDirectory               string
 }
 
 // Plugin wraps the plugin configuration and plugin result.
@@ -0,0 +1,174 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package crashdump captures the stack traces and the recent logs of a panicking agent or worker process in a
+// bundle kept locally and optionally uploaded to S3, so crashes can be investigated without reproducing them.
+package crashdump
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const (
+	// dumpTimeFormat is the timestamp in the name of the crash dumps, it sorts in capture order
+	dumpTimeFormat = "20060102T150405.000000000"
+
+	// dumpExtension is the extension of the crash dump bundles
+	dumpExtension = ".zip"
+
+	// maxStackSize is the largest buffer the stack traces of all the goroutines are captured in
+	maxStackSize = 64 * 1024 * 1024
+)
+
+// dependencies of the crash dumps, replaced by the tests
+var dumpDir = filepath.Join(appconfig.DefaultDataStorePath, "crashdumps")
+var timeNow = time.Now
+var loadConfig = func() appconfig.CrashDumpCfg {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return config.CrashDump
+}
+var instanceID = platform.InstanceID
+var upload = func(log log.T, bucket string, key string, filePath string) error {
+	return s3util.NewAmazonS3Util(log, bucket).S3Upload(log, bucket, key, filePath)
+}
+
+// Capture writes a crash dump of the panic of the process, removes the crash dumps beyond the retention limits and
+// uploads the crash dump to S3 when configured, it returns the path of the crash dump
+func Capture(log log.T, panicMsg interface{}) (dumpPath string) {
+	defer func() {
+		// the crash dump is best effort, it never adds a panic to the one being handled
+		if msg := recover(); msg != nil {
+			log.Errorf("Failed to capture the crash dump: %v", msg)
+		}
+	}()
+
+	config := loadConfig()
+	process := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	dumpPath, err := write(process, panicMsg)
+	if err != nil {
+		log.Errorf("Failed to write the crash dump: %v", err)
+		return ""
+	}
+	log.Infof("Crash dump written to %v", dumpPath)
+	removeExpired(log, config)
+
+	if config.S3BucketName != "" {
+		instance, _ := instanceID()
+		key := path.Join(config.S3KeyPrefix, instance, filepath.Base(dumpPath))
+		if err = upload(log, config.S3BucketName, key, dumpPath); err != nil {
+			log.Errorf("Failed to upload the crash dump to s3://%v/%v: %v", config.S3BucketName, key, err)
+		} else {
+			log.Infof("Crash dump uploaded to s3://%v/%v", config.S3BucketName, key)
+		}
+	}
+	return dumpPath
+}
+
+// write bundles the panic, the stack traces of all the goroutines and the recent log messages in a zip file
+func write(process string, panicMsg interface{}) (dumpPath string, err error) {
+	if err = os.MkdirAll(dumpDir, 0700); err != nil {
+		return "", err
+	}
+	now := timeNow()
+	dumpPath = filepath.Join(dumpDir, fmt.Sprintf("%v-%v-%v%v", now.UTC().Format(dumpTimeFormat), process, os.Getpid(), dumpExtension))
+	file, err := os.OpenFile(dumpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	bundle := zip.NewWriter(file)
+
+	crash := fmt.Sprintf("process: %v\npid: %v\nagent version: %v\ntime: %v\npanic: %v\n\n%s",
+		process, os.Getpid(), version.Version, now.UTC().Format(time.RFC3339Nano), panicMsg, debug.Stack())
+	entries := []struct {
+		name    string
+		content string
+	}{
+		{"crash.txt", crash},
+		{"goroutines.txt", string(allStacks())},
+		{"recent.log", strings.Join(log.RecentMessages(), "")},
+	}
+	for _, entry := range entries {
+		var writer io.Writer
+		if writer, err = bundle.Create(entry.name); err == nil {
+			_, err = writer.Write([]byte(entry.content))
+		}
+		if err != nil {
+			break
+		}
+	}
+	if closeErr := bundle.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dumpPath)
+		return "", err
+	}
+	return dumpPath, nil
+}
+
+// allStacks returns the stack traces of all the goroutines
+func allStacks() []byte {
+	for size := 1024 * 1024; ; size *= 2 {
+		buf := make([]byte, size)
+		if n := runtime.Stack(buf, true); n < size || size >= maxStackSize {
+			return buf[:n]
+		}
+	}
+}
+
+// removeExpired removes the oldest crash dumps beyond MaxDumps and the crash dumps older than the retention
+func removeExpired(log log.T, config appconfig.CrashDumpCfg) {
+	dumps, err := filepath.Glob(filepath.Join(dumpDir, "*"+dumpExtension))
+	if err != nil {
+		return
+	}
+	// newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(dumps)))
+	retention := time.Duration(config.RetentionDays) * 24 * time.Hour
+	for i, dump := range dumps {
+		expired := config.MaxDumps > 0 && i >= config.MaxDumps
+		if !expired && retention > 0 {
+			if info, err := os.Stat(dump); err == nil && timeNow().Sub(info.ModTime()) >= retention {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(dump); err != nil {
+				log.Warnf("Failed to remove the crash dump %v: %v", dump, err)
+			}
+		}
+	}
+}
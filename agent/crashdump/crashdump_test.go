@@ -0,0 +1,116 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package crashdump
+
+import (
+	"archive/zip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func setDumpDependencies(t *testing.T, config appconfig.CrashDumpCfg, uploadErr error) (dir string, uploads *[]string, restore func()) {
+	dir, err := ioutil.TempDir("", "crashdump")
+	assert.NoError(t, err)
+	dumpDirOrig, loadConfigOrig, instanceIDOrig, uploadOrig := dumpDir, loadConfig, instanceID, upload
+	dumpDir = dir
+	instanceID = func() (string, error) { return "i-1234567890", nil }
+	loadConfig = func() appconfig.CrashDumpCfg { return config }
+	uploads = &[]string{}
+	upload = func(log log.T, bucket string, key string, filePath string) error {
+		*uploads = append(*uploads, bucket+"/"+key)
+		return uploadErr
+	}
+	return dir, uploads, func() {
+		dumpDir, loadConfig, instanceID, upload = dumpDirOrig, loadConfigOrig, instanceIDOrig, uploadOrig
+		os.RemoveAll(dir)
+	}
+}
+
+func readBundle(t *testing.T, path string) map[string]string {
+	reader, err := zip.OpenReader(path)
+	assert.NoError(t, err)
+	defer reader.Close()
+	entries := make(map[string]string)
+	for _, file := range reader.File {
+		content, err := file.Open()
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(content)
+		assert.NoError(t, err)
+		content.Close()
+		entries[file.Name] = string(data)
+	}
+	return entries
+}
+
+func TestCapture_WritesBundle(t *testing.T) {
+	dir, uploads, restore := setDumpDependencies(t, appconfig.CrashDumpCfg{MaxDumps: 10}, nil)
+	defer restore()
+	(&log.RingBufferReceiver{}).ReceiveMessage("last message before the crash\n", 0, nil)
+
+	dumpPath := Capture(log.NewMockLog(), "index out of range")
+
+	assert.Equal(t, dir, filepath.Dir(dumpPath))
+	assert.True(t, strings.HasSuffix(dumpPath, dumpExtension))
+	info, err := os.Stat(dumpPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	entries := readBundle(t, dumpPath)
+	assert.Contains(t, entries["crash.txt"], "panic: index out of range")
+	assert.Contains(t, entries["goroutines.txt"], "TestCapture_WritesBundle")
+	assert.Contains(t, entries["recent.log"], "last message before the crash")
+	assert.Empty(t, *uploads)
+}
+
+func TestCapture_UploadsToS3(t *testing.T) {
+	_, uploads, restore := setDumpDependencies(t, appconfig.CrashDumpCfg{S3BucketName: "bucket", S3KeyPrefix: "crashdumps"}, errors.New("access denied"))
+	defer restore()
+
+	dumpPath := Capture(log.NewMockLog(), "nil pointer dereference")
+
+	// the crash dump is kept locally when the upload fails
+	assert.NotEmpty(t, dumpPath)
+	assert.Len(t, *uploads, 1)
+	assert.Equal(t, "bucket/crashdumps/i-1234567890/"+filepath.Base(dumpPath), (*uploads)[0])
+}
+
+func TestRemoveExpired(t *testing.T) {
+	dir, _, restore := setDumpDependencies(t, appconfig.CrashDumpCfg{}, nil)
+	defer restore()
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	names := []string{"20190101T000000.000000000-amazon-ssm-agent-1.zip", "20190220T000000.000000000-ssm-document-worker-2.zip",
+		"20190225T000000.000000000-ssm-session-worker-3.zip", "20190228T000000.000000000-amazon-ssm-agent-4.zip"}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte{}, 0600))
+		created, _ := time.Parse(dumpTimeFormat, name[:len(dumpTimeFormat)])
+		assert.NoError(t, os.Chtimes(path, created, created))
+	}
+
+	removeExpired(log.NewMockLog(), appconfig.CrashDumpCfg{MaxDumps: 3, RetentionDays: 7})
+
+	remaining, _ := filepath.Glob(filepath.Join(dir, "*"+dumpExtension))
+	assert.Equal(t, []string{filepath.Join(dir, names[2]), filepath.Join(dir, names[3])}, remaining)
+}
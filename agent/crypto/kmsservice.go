@@ -19,7 +19,12 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -33,6 +38,7 @@ const KMSKeySizeInBytes int64 = 64
 
 type IKMSService interface {
 	Decrypt(cipherTextBlob []byte, encryptionContext map[string]*string) (plainText []byte, err error)
+	GenerateDataKey(keyId string, encryptionContext map[string]*string) (plainText []byte, cipherTextBlob []byte, err error)
 }
 
 type KMSService struct {
@@ -53,13 +59,21 @@ func NewKMSService(log log.T) (kmsService *KMSService, err error) {
 		log.Warnf("Failed to load appconfig: %s. Using default config.", err)
 	} else if appConfig.Kms.Endpoint != "" {
 		awsConfig.Endpoint = &appConfig.Kms.Endpoint
+	} else if region, err := platform.Region(); err == nil {
+		if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "kms"); defaultEndpoint != "" {
+			awsConfig.Endpoint = &defaultEndpoint
+		}
 	}
+	proxyconfig.ConfigureAwsProxy(log, awsConfig, appConfig.Kms.Proxy)
 	agentName = appConfig.Agent.Name
 	agentVersion = appConfig.Agent.Version
 	if kmsClientSession, err = session.NewSession(awsConfig); err != nil {
 		return nil, fmt.Errorf("Error creating new aws sdk session: %s", err)
 	}
 	kmsClientSession.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(agentName, agentVersion))
+	clockskew.AddHandlers(&kmsClientSession.Handlers)
+	circuitbreaker.AddHandlers(&kmsClientSession.Handlers)
+	retryer.AddHandlers(&kmsClientSession.Handlers)
 	kmsService = &KMSService{
 		client: kms.New(kmsClientSession),
 	}
@@ -77,3 +91,15 @@ func (kmsService *KMSService) Decrypt(cipherTextBlob []byte, encryptionContext m
 	}
 	return output.Plaintext, nil
 }
+
+// GenerateDataKey generates an AES-256 data key under the KMS key, it returns the key in plaintext and encrypted by KMS
+func (kmsService *KMSService) GenerateDataKey(keyId string, encryptionContext map[string]*string) (plainText []byte, cipherTextBlob []byte, err error) {
+	output, err := kmsService.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:             aws.String(keyId),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: encryptionContext})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error when generating data key %s", err)
+	}
+	return output.Plaintext, output.CiphertextBlob, nil
+}
@@ -42,3 +42,35 @@ func (_m *IKMSService) Decrypt(cipherTextBlob []byte, encryptionContext map[stri
 
 	return r0, r1
 }
+
+// GenerateDataKey provides a mock function with given fields: keyId, encryptionContext
+func (_m *IKMSService) GenerateDataKey(keyId string, encryptionContext map[string]*string) ([]byte, []byte, error) {
+	ret := _m.Called(keyId, encryptionContext)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string, map[string]*string) []byte); ok {
+		r0 = rf(keyId, encryptionContext)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 []byte
+	if rf, ok := ret.Get(1).(func(string, map[string]*string) []byte); ok {
+		r1 = rf(keyId, encryptionContext)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, map[string]*string) error); ok {
+		r2 = rf(keyId, encryptionContext)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diskguard keeps a reserve of free disk space on the volumes the agent writes to, the writes which
+// would use the reserve fail instead of filling the volume.
+package diskguard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+// ExitCode is the exit code of the plugins failed for lack of disk space, the number of the ENOSPC error
+const ExitCode = 28
+
+// InsufficientSpaceError is returned when the available disk space is below the reserve
+type InsufficientSpaceError struct {
+	Path         string
+	AvailBytes   int64
+	ReserveBytes int64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space to write to %v, %v bytes are available and %v bytes are reserved",
+		e.Path, e.AvailBytes, e.ReserveBytes)
+}
+
+// getDiskSpaceInfo returns the disk space of the volume of a path, it is stubbed in the tests
+var getDiskSpaceInfo = fileutil.GetDiskSpaceInfoForPath
+
+var reserve struct {
+	sync.Once
+	bytes int64
+}
+
+// reserveBytes returns the configured reserve, it is stubbed in the tests
+var reserveBytes = func() int64 {
+	reserve.Do(func() {
+		config, err := appconfig.Config(false)
+		if err != nil {
+			config = appconfig.DefaultConfig()
+		}
+		reserve.bytes = int64(config.DiskGuard.ReserveMB) * 1024 * 1024
+	})
+	return reserve.bytes
+}
+
+// Check returns an InsufficientSpaceError when the available space of the volume of the path is below the reserve.
+// The path does not need to exist, the volume of its closest existing parent is checked.
+// The writes are allowed when the available space cannot be determined.
+func Check(path string) error {
+	reserveBytes := reserveBytes()
+	if reserveBytes <= 0 || path == "" {
+		return nil
+	}
+	info, err := getDiskSpaceInfo(existingParent(path))
+	if err != nil {
+		return nil
+	}
+	if info.AvailBytes < reserveBytes {
+		return &InsufficientSpaceError{
+			Path:         path,
+			AvailBytes:   info.AvailBytes,
+			ReserveBytes: reserveBytes,
+		}
+	}
+	return nil
+}
+
+// existingParent returns the path, or its closest parent which exists
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskguard
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubDiskSpace(reserve int64, avail int64, err error) *string {
+	var checkedPath string
+	reserveBytes = func() int64 { return reserve }
+	getDiskSpaceInfo = func(path string) (fileutil.DiskSpaceInfo, error) {
+		checkedPath = path
+		return fileutil.DiskSpaceInfo{AvailBytes: avail}, err
+	}
+	return &checkedPath
+}
+
+func TestCheck_SufficientSpace(t *testing.T) {
+	stubDiskSpace(100, 100, nil)
+	assert.NoError(t, Check(os.TempDir()))
+}
+
+func TestCheck_InsufficientSpace(t *testing.T) {
+	stubDiskSpace(100, 99, nil)
+	err := Check(os.TempDir())
+	assert.Error(t, err)
+	spaceErr, ok := err.(*InsufficientSpaceError)
+	assert.True(t, ok)
+	assert.Equal(t, int64(99), spaceErr.AvailBytes)
+	assert.Equal(t, int64(100), spaceErr.ReserveBytes)
+	assert.Contains(t, err.Error(), "insufficient disk space")
+}
+
+func TestCheck_NoReserve(t *testing.T) {
+	checkedPath := stubDiskSpace(0, 0, nil)
+	assert.NoError(t, Check(os.TempDir()))
+	assert.Empty(t, *checkedPath)
+}
+
+func TestCheck_UnknownSpace(t *testing.T) {
+	stubDiskSpace(100, 0, errors.New("statfs failed"))
+	assert.NoError(t, Check(os.TempDir()))
+}
+
+func TestCheck_ChecksClosestExistingParent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskguard")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkedPath := stubDiskSpace(100, 100, nil)
+	assert.NoError(t, Check(filepath.Join(dir, "orchestration", "plugin")))
+	assert.Equal(t, dir, *checkedPath)
+}
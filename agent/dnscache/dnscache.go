@@ -0,0 +1,160 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package dnscache resolves the hosts of the agent endpoints through an in-process cache,
+// optionally querying custom DNS resolvers and falling back to bootstrap addresses.
+package dnscache
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// entry is a cached resolution of a host
+type entry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// Resolver resolves hosts and caches the answers for their TTL, an expired answer is still used
+// when the host can't be resolved anymore so that reconnects survive an unavailable resolver.
+type Resolver struct {
+	// Resolvers are the ip:port addresses of the DNS servers, empty to use the system resolver
+	Resolvers []string
+	// BootstrapHosts are the addresses used for hosts which can't be resolved and have no cached answer
+	BootstrapHosts map[string][]string
+	// CacheDuration is how long the answers of the system resolver are cached
+	CacheDuration time.Duration
+
+	lock  sync.Mutex
+	cache map[string]entry
+}
+
+var (
+	now = time.Now
+
+	// lookupSystem resolves a host with the system resolver, which doesn't return TTLs
+	lookupSystem = func(host string) ([]net.IP, error) {
+		return net.LookupIP(host)
+	}
+
+	defaultResolver *Resolver
+	configure       sync.Once
+)
+
+// NewResolver creates a resolver from the DNS configuration
+func NewResolver(config appconfig.DnsCfg) *Resolver {
+	return &Resolver{
+		Resolvers:      config.Resolvers,
+		BootstrapHosts: config.BootstrapHosts,
+		CacheDuration:  time.Duration(config.CacheSeconds) * time.Second,
+		cache:          map[string]entry{},
+	}
+}
+
+// Default returns the resolver configured in appconfig, shared by the agent
+func Default() *Resolver {
+	configure.Do(func() {
+		config, _ := appconfig.Config(false)
+		defaultResolver = NewResolver(config.Network.Dns)
+	})
+	return defaultResolver
+}
+
+// LookupIP returns the addresses of a host
+func (r *Resolver) LookupIP(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	r.lock.Lock()
+	cached, found := r.cache[host]
+	r.lock.Unlock()
+	if found && now().Before(cached.expires) {
+		return cached.ips, nil
+	}
+
+	ips, ttl, err := r.resolve(host)
+	if err == nil {
+		if ttl > 0 {
+			r.lock.Lock()
+			r.cache[host] = entry{ips: ips, expires: now().Add(ttl)}
+			r.lock.Unlock()
+		}
+		return ips, nil
+	}
+
+	if found {
+		return cached.ips, nil
+	}
+	if bootstrap := r.bootstrapIPs(host); len(bootstrap) > 0 {
+		return bootstrap, nil
+	}
+	return nil, err
+}
+
+// resolve queries the configured resolvers in order, or the system resolver when none is configured
+func (r *Resolver) resolve(host string) (ips []net.IP, ttl time.Duration, err error) {
+	if len(r.Resolvers) == 0 {
+		ips, err = lookupSystem(host)
+		return ips, r.CacheDuration, err
+	}
+
+	for _, server := range r.Resolvers {
+		if ips, ttl, err = query(server, host); err == nil {
+			return
+		}
+	}
+	return nil, 0, fmt.Errorf("failed to resolve %v with the configured DNS resolvers: %v", host, err)
+}
+
+// bootstrapIPs returns the bootstrap addresses of a host
+func (r *Resolver) bootstrapIPs(host string) (ips []net.IP) {
+	for _, address := range r.BootstrapHosts[host] {
+		if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return
+}
+
+// Dial returns a dial function resolving the host of the address with the resolver,
+// the addresses of the host are tried in order until a connection succeeds.
+func (r *Resolver) Dial(dialer *net.Dialer) func(network, address string) (net.Conn, error) {
+	return func(network, address string) (conn net.Conn, err error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dialer.Dial(network, address)
+		}
+		ips, err := r.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if conn, err = dialer.Dial(network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return
+			}
+		}
+		return
+	}
+}
+
+// Dial returns a dial function resolving hosts with the resolver configured in appconfig
+func Dial(dialer *net.Dialer) func(network, address string) (net.Conn, error) {
+	return Default().Dial(dialer)
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dnscache
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const testHost = "ssmmessages.us-east-1.amazonaws.com"
+
+// stubSystemResolver stubs the clock and the system resolver, which answers with the returned ips until err is set
+func stubSystemResolver(t *testing.T) (current *time.Time, ips *[]net.IP, lookups *int, err *error) {
+	nowOrig, lookupSystemOrig := now, lookupSystem
+	t.Cleanup(func() { now, lookupSystem = nowOrig, lookupSystemOrig })
+
+	current, ips, lookups, err = &time.Time{}, &[]net.IP{net.ParseIP("10.0.0.1")}, new(int), new(error)
+	*current = time.Now()
+	now = func() time.Time { return *current }
+	lookupSystem = func(host string) ([]net.IP, error) {
+		*lookups++
+		return *ips, *err
+	}
+	return
+}
+
+func TestLookupIPCachesSystemAnswers(t *testing.T) {
+	current, _, lookups, _ := stubSystemResolver(t)
+	resolver := NewResolver(appconfig.DnsCfg{CacheSeconds: 60})
+
+	for i := 0; i < 3; i++ {
+		ips, err := resolver.LookupIP(testHost)
+		assert.NoError(t, err)
+		assert.Equal(t, "10.0.0.1", ips[0].String())
+	}
+	assert.Equal(t, 1, *lookups)
+
+	*current = current.Add(2 * time.Minute)
+	resolver.LookupIP(testHost)
+	assert.Equal(t, 2, *lookups)
+}
+
+func TestLookupIPUsesExpiredAnswerOnFailure(t *testing.T) {
+	current, _, _, lookupErr := stubSystemResolver(t)
+	resolver := NewResolver(appconfig.DnsCfg{CacheSeconds: 60})
+	resolver.LookupIP(testHost)
+
+	*current = current.Add(2 * time.Minute)
+	*lookupErr = errors.New("resolver unavailable")
+	ips, err := resolver.LookupIP(testHost)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ips[0].String())
+}
+
+func TestLookupIPFallsBackToBootstrapHosts(t *testing.T) {
+	_, ips, _, lookupErr := stubSystemResolver(t)
+	*ips = nil
+	*lookupErr = errors.New("resolver unavailable")
+	resolver := NewResolver(appconfig.DnsCfg{BootstrapHosts: map[string][]string{testHost: {"52.46.0.1"}}})
+
+	resolved, err := resolver.LookupIP(testHost)
+	assert.NoError(t, err)
+	assert.Equal(t, "52.46.0.1", resolved[0].String())
+
+	_, err = resolver.LookupIP("other.amazonaws.com")
+	assert.Error(t, err)
+}
+
+func TestLookupIPWithCustomResolver(t *testing.T) {
+	current, _, lookups, _ := stubSystemResolver(t)
+	exchangeOrig := exchange
+	t.Cleanup(func() { exchange = exchangeOrig })
+
+	var servers []string
+	exchange = func(server string, request []byte) ([]byte, error) {
+		servers = append(servers, server)
+		if server == "10.0.0.2:53" {
+			return nil, errors.New("timeout")
+		}
+		var message dnsmessage.Message
+		if err := message.Unpack(request); err != nil {
+			return nil, err
+		}
+		message.Response = true
+		question := message.Questions[0]
+		header := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: question.Class, TTL: 30}
+		if question.Type == dnsmessage.TypeA {
+			message.Answers = []dnsmessage.Resource{&dnsmessage.AResource{ResourceHeader: header, A: [4]byte{10, 0, 0, 5}}}
+		}
+		return message.Pack()
+	}
+
+	resolver := NewResolver(appconfig.DnsCfg{Resolvers: []string{"10.0.0.2:53", "10.0.0.3:53"}, CacheSeconds: 600})
+	ips, err := resolver.LookupIP(testHost)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.5"}, []string{ips[0].String()})
+	assert.Equal(t, 0, *lookups)
+	assert.Equal(t, []string{"10.0.0.2:53", "10.0.0.2:53", "10.0.0.3:53", "10.0.0.3:53"}, servers)
+
+	// the TTL of the answer is respected
+	*current = current.Add(time.Minute)
+	resolver.LookupIP(testHost)
+	assert.Len(t, servers, 8)
+}
+
+func TestLookupIPWithIPAddress(t *testing.T) {
+	ips, err := NewResolver(appconfig.DnsCfg{}).LookupIP("127.0.0.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ips[0].String())
+}
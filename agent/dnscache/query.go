@@ -0,0 +1,126 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dnscache
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// queryTimeout is the timeout of a query to a DNS server
+	queryTimeout = 5 * time.Second
+
+	// maxMessageSize is the maximum size of a DNS message over UDP
+	maxMessageSize = 512
+)
+
+// exchange sends a DNS message to a server and returns the response
+var exchange = func(server string, request []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", server, queryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	if _, err = conn.Write(request); err != nil {
+		return nil, err
+	}
+	response := make([]byte, maxMessageSize)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, err
+	}
+	return response[:n], nil
+}
+
+// query resolves the IPv4 and IPv6 addresses of a host with a DNS server,
+// the TTL of the answer is the lowest TTL of its records
+func query(server string, host string) (ips []net.IP, ttl time.Duration, err error) {
+	name := host
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	var lastErr error
+	ttl = -1
+	for _, queryType := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, queryErr := queryRecords(server, name, queryType)
+		if queryErr != nil {
+			lastErr = queryErr
+			continue
+		}
+		for _, answer := range answers {
+			var ip net.IP
+			switch resource := answer.(type) {
+			case *dnsmessage.AResource:
+				ip = net.IP(resource.A[:])
+			case *dnsmessage.AAAAResource:
+				ip = net.IP(resource.AAAA[:])
+			default:
+				continue
+			}
+			ips = append(ips, ip)
+			if recordTTL := time.Duration(answer.Header().TTL) * time.Second; ttl < 0 || recordTTL < ttl {
+				ttl = recordTTL
+			}
+		}
+	}
+
+	if len(ips) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no address found for %v", host)
+		}
+		return nil, 0, lastErr
+	}
+	return ips, ttl, nil
+}
+
+// queryRecords sends a query of a type of record to a DNS server and returns the answers
+func queryRecords(server string, name string, queryType dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	id := uint16(rand.Intn(1 << 16))
+	request := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: queryType, Class: dnsmessage.ClassINET},
+		},
+	}
+	packed, err := request.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := exchange(server, packed)
+	if err != nil {
+		return nil, err
+	}
+	var response dnsmessage.Message
+	if err = response.Unpack(raw); err != nil {
+		return nil, err
+	}
+	if response.ID != id || !response.Response {
+		return nil, fmt.Errorf("unexpected response from DNS server %v", server)
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DNS server %v failed to resolve %v, rcode %v", server, name, response.RCode)
+	}
+	return response.Answers, nil
+}
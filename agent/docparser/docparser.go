@@ -19,6 +19,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/instancevariables"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/parameters"
@@ -154,13 +155,20 @@ func validateAndReplaceSessionDocumentParameters(log log.T, params map[string]in
 		}
 	}
 
+	// Resolves the instance variables of the parameter values before they are validated
+	resolvedParameters, err := instancevariables.Resolve(log, validParameters)
+	if err != nil {
+		return err
+	}
+	validParameters = resolvedParameters.(map[string]interface{})
+
 	log.Info("Validating SSM parameters")
 	// Validates SSM parameters
 	if err := parameterstore.ValidateSSMParameters(log, docContent.Parameters, validParameters); err != nil {
 		return err
 	}
 
-	err := replaceValidatedSessionParameters(docContent, validParameters, log)
+	err = replaceValidatedSessionParameters(docContent, validParameters, log)
 	return err
 }
 
@@ -182,8 +190,8 @@ func replaceValidatedSessionParameters(
 			}
 			resolvedRawData := parameters.ReplaceParameters(rawData, params, logger)
 
-			// Resolve SSM Parameters
-			if resolvedRawData, err = parameterstore.Resolve(logger, resolvedRawData); err != nil {
+			// Resolve the instance variables and the SSM Parameters
+			if resolvedRawData, err = resolveReferences(logger, resolvedRawData); err != nil {
 				return err
 			}
 
@@ -205,8 +213,8 @@ func replaceValidatedSessionParameters(
 	}
 	resolvedRawData := parameters.ReplaceParameters(rawData, params, logger)
 
-	// Resolve SSM Parameters
-	if resolvedRawData, err = parameterstore.Resolve(logger, resolvedRawData); err != nil {
+	// Resolve the instance variables and the SSM Parameters
+	if resolvedRawData, err = resolveReferences(logger, resolvedRawData); err != nil {
 		return err
 	}
 
@@ -217,6 +225,13 @@ func replaceValidatedSessionParameters(
 	}
 	docContent.Inputs = resolvedInputs
 
+	if docContent.Properties != nil {
+		resolvedProperties := parameters.ReplaceParameters(docContent.Properties, params, logger)
+		if docContent.Properties, err = resolveReferences(logger, resolvedProperties); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -330,6 +345,7 @@ func parsePluginStateForV20Schema(
 			PluginID:                instancePluginConfig.Name,
 			Preconditions:           instancePluginConfig.Preconditions,
 			IsPreconditionEnabled:   isPreconditionEnabled,
+			IsIndependent:           instancePluginConfig.Independent,
 			DefaultWorkingDirectory: defaultWorkingDir,
 		}
 
@@ -371,6 +387,9 @@ func (sessionDocContent *SessionDocContent) parsePluginStateForStartSession(
 				IsPreconditionEnabled:       true,
 				Preconditions:               sessionCommandConfig.Preconditions,
 				RunAsElevated:               sessionCommandConfig.RunAsElevated,
+				TokenElevation:              sessionDocContent.Inputs.TokenElevation,
+				NetworkNamespace:            sessionDocContent.Inputs.NetworkNamespace,
+				WorkingDirectory:            sessionDocContent.Inputs.WorkingDirectory,
 			}
 
 			var plugin contracts.PluginState
@@ -395,6 +414,10 @@ func (sessionDocContent *SessionDocContent) parsePluginStateForStartSession(
 			CloudWatchLogGroup:          sessionDocContent.Inputs.CloudWatchLogGroupName,
 			CloudWatchEncryptionEnabled: sessionDocContent.Inputs.CloudWatchEncryptionEnabled,
 			KmsKeyId:                    sessionDocContent.Inputs.KmsKeyId,
+			TokenElevation:              sessionDocContent.Inputs.TokenElevation,
+			NetworkNamespace:            sessionDocContent.Inputs.NetworkNamespace,
+			WorkingDirectory:            sessionDocContent.Inputs.WorkingDirectory,
+			Properties:                  sessionDocContent.Properties,
 		}
 
 		var plugin contracts.PluginState
@@ -443,13 +466,20 @@ func getValidatedParameters(log log.T, params map[string]interface{}, docContent
 		}
 	}
 
+	// Resolves the instance variables of the parameter values before they are validated
+	resolvedParameters, err := instancevariables.Resolve(log, validParameters)
+	if err != nil {
+		return err
+	}
+	validParameters = resolvedParameters.(map[string]interface{})
+
 	log.Info("Validating SSM parameters")
 	// Validates SSM parameters
 	if err := parameterstore.ValidateSSMParameters(log, docContent.Parameters, validParameters); err != nil {
 		return err
 	}
 
-	err := replaceValidatedPluginParameters(docContent, validParameters, log)
+	err = replaceValidatedPluginParameters(docContent, validParameters, log)
 	return err
 }
 
@@ -471,13 +501,13 @@ func replaceValidatedPluginParameters(
 			updatedRuntimeConfig[pluginName].Properties = parameters.ReplaceParameters(pluginConfig.Properties, params, logger)
 
 			logger.Debug("Resolving SSM parameters")
-			// Resolves SSM parameters
-			if updatedRuntimeConfig[pluginName].Settings, err = parameterstore.Resolve(logger, updatedRuntimeConfig[pluginName].Settings); err != nil {
+			// Resolves the instance variables and the SSM parameters
+			if updatedRuntimeConfig[pluginName].Settings, err = resolveReferences(logger, updatedRuntimeConfig[pluginName].Settings); err != nil {
 				return err
 			}
 
-			// Resolves SSM parameters
-			if updatedRuntimeConfig[pluginName].Properties, err = parameterstore.Resolve(logger, updatedRuntimeConfig[pluginName].Properties); err != nil {
+			// Resolves the instance variables and the SSM parameters
+			if updatedRuntimeConfig[pluginName].Properties, err = resolveReferences(logger, updatedRuntimeConfig[pluginName].Properties); err != nil {
 				return err
 			}
 		}
@@ -494,13 +524,13 @@ func replaceValidatedPluginParameters(
 			updatedMainSteps[index].Inputs = parameters.ReplaceParameters(instancePluginConfig.Inputs, params, logger)
 
 			logger.Debug("Resolving SSM parameters")
-			// Resolves SSM parameters
-			if updatedMainSteps[index].Settings, err = parameterstore.Resolve(logger, updatedMainSteps[index].Settings); err != nil {
+			// Resolves the instance variables and the SSM parameters
+			if updatedMainSteps[index].Settings, err = resolveReferences(logger, updatedMainSteps[index].Settings); err != nil {
 				return err
 			}
 
-			// Resolves SSM parameters
-			if updatedMainSteps[index].Inputs, err = parameterstore.Resolve(logger, updatedMainSteps[index].Inputs); err != nil {
+			// Resolves the instance variables and the SSM parameters
+			if updatedMainSteps[index].Inputs, err = resolveReferences(logger, updatedMainSteps[index].Inputs); err != nil {
 				return err
 			}
 		}
@@ -510,6 +540,15 @@ func replaceValidatedPluginParameters(
 	return nil
 }
 
+// resolveReferences resolves the instance variables and then the SSM parameters of the input.
+func resolveReferences(logger log.T, input interface{}) (interface{}, error) {
+	resolved, err := instancevariables.Resolve(logger, input)
+	if err != nil {
+		return nil, err
+	}
+	return parameterstore.Resolve(logger, resolved)
+}
+
 // isPreConditionEnabled checks if precondition support is enabled by checking document schema version
 func isPreconditionEnabled(schemaVersion string) (response bool) {
 	response = false
@@ -340,6 +340,42 @@ func TestInitializeDocStateForStartSessionDocumentWithParameters_Valid(t *testin
 	assert.True(t, pluginInfo[0].Configuration.RunAsElevated)
 }
 
+func TestInitializeDocStateForStartSessionDocumentWithProperties_Valid(t *testing.T) {
+	mockLog := log.NewMockLog()
+
+	testParserInfo := DocumentParserInfo{
+		MessageId:        testMessageID,
+		DocumentId:       testDocumentID,
+		OrchestrationDir: testOrchDir,
+	}
+
+	portNumber := contracts.Parameter{
+		DefaultVal: "80",
+		ParamType:  "String",
+	}
+
+	sessionDocContent := &SessionDocContent{
+		SchemaVersion: "1.0",
+		Parameters:    map[string]*contracts.Parameter{"portNumber": &portNumber},
+		Properties:    map[string]interface{}{"portNumber": "{{ portNumber }}"},
+		SessionType:   appconfig.PluginNamePort,
+	}
+
+	docState, err := InitializeDocState(mockLog,
+		contracts.StartSession,
+		sessionDocContent,
+		contracts.DocumentInfo{DocumentID: testSessionId, ClientId: testClientId},
+		testParserInfo,
+		map[string]interface{}{"portNumber": "22"})
+
+	assert.Nil(t, err)
+
+	pluginInfo := docState.InstancePluginsInformation
+	assert.Equal(t, 1, len(pluginInfo))
+	assert.Equal(t, appconfig.PluginNamePort, pluginInfo[0].Name)
+	assert.Equal(t, map[string]interface{}{"portNumber": "22"}, pluginInfo[0].Configuration.Properties)
+}
+
 func TestParseDocument_EmptyDocContent(t *testing.T) {
 	mockLog := log.NewMockLog()
 	testParserInfo := DocumentParserInfo{
@@ -0,0 +1,205 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package etw writes the ETW events of the Session Manager sessions through the Amazon-SSM-Agent provider, which
+// the Windows installer registers with amazon-ssm-agent-etw.man: the start and the end of the sessions with their
+// runas identity, the volume of their input and output, and the processes created in them, so that the Windows
+// security tooling such as Sysmon or Windows Event Forwarding captures the Session Manager activity.
+package etw
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// ProviderName is the name of the ETW provider of the agent
+const ProviderName = "Amazon-SSM-Agent"
+
+// Event ids of the provider, they are the events of the manifest
+const (
+	SessionStarted        uint16 = 1
+	SessionVolume         uint16 = 2
+	SessionProcessCreated uint16 = 3
+	SessionEnded          uint16 = 4
+)
+
+// volumeInterval is the interval of the SessionVolume events of a session
+var volumeInterval = time.Minute
+
+// processInterval is the interval the processes of a session are listed at, the processes
+// living less than this interval may not be reported
+var processInterval = time.Second
+
+// provider writes the events with their fields, the fields are strings, uint32 or uint64
+// in the order of the template of the event
+type provider interface {
+	write(id uint16, fields ...interface{}) error
+	close() error
+}
+
+// process is a process of the instance
+type process struct {
+	pid       uint32
+	parentPid uint32
+	image     string
+}
+
+// Session writes the events of a session, the methods of a nil Session do nothing
+type Session struct {
+	log      log.T
+	id       string
+	provider provider
+	input    uint64
+	output   uint64
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// StartSession writes the SessionStarted event of the session and starts writing its volume,
+// it returns nil when the ETW events are disabled or the provider is not available
+func StartSession(log log.T, config appconfig.EtwCfg, sessionID string, runAsUser string, tokenElevation string) *Session {
+	if !config.Enabled {
+		return nil
+	}
+	provider, err := openProvider()
+	if err != nil {
+		log.Warnf("ETW events of session %v not written: %v", sessionID, err)
+		return nil
+	}
+	s := &Session{
+		log:      log,
+		id:       sessionID,
+		provider: provider,
+		stop:     make(chan struct{}),
+	}
+	s.write(SessionStarted, sessionID, runAsUser, tokenElevation)
+	s.wg.Add(1)
+	go s.writeVolume()
+	return s
+}
+
+// AddInput adds bytes to the input of the session
+func (s *Session) AddInput(bytes int) {
+	if s != nil {
+		atomic.AddUint64(&s.input, uint64(bytes))
+	}
+}
+
+// AddOutput adds bytes to the output of the session
+func (s *Session) AddOutput(bytes int) {
+	if s != nil {
+		atomic.AddUint64(&s.output, uint64(bytes))
+	}
+}
+
+// WatchProcesses writes a SessionProcessCreated event for every process created under the shell process
+func (s *Session) WatchProcesses(shellPid uint32) {
+	if s == nil || shellPid == 0 {
+		return
+	}
+	s.wg.Add(1)
+	go s.watchProcesses(shellPid)
+}
+
+// End stops watching the session and writes its SessionEnded event
+func (s *Session) End() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+		s.write(SessionEnded, s.id, atomic.LoadUint64(&s.input), atomic.LoadUint64(&s.output))
+		if err := s.provider.close(); err != nil {
+			s.log.Debugf("failed to close the ETW provider: %v", err)
+		}
+	})
+}
+
+func (s *Session) write(id uint16, fields ...interface{}) {
+	if err := s.provider.write(id, fields...); err != nil {
+		s.log.Debugf("failed to write ETW event %v of session %v: %v", id, s.id, err)
+	}
+}
+
+// writeVolume writes the volume of the session at every interval
+func (s *Session) writeVolume() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(volumeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.write(SessionVolume, s.id, atomic.LoadUint64(&s.input), atomic.LoadUint64(&s.output))
+		}
+	}
+}
+
+// watchProcesses lists the processes at every interval and reports the new descendants of the shell
+func (s *Session) watchProcesses(shellPid uint32) {
+	defer s.wg.Done()
+	known := map[uint32]bool{shellPid: true}
+	ticker := time.NewTicker(processInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		processes, err := listProcesses()
+		if err != nil {
+			s.log.Debugf("failed to list the processes of session %v: %v", s.id, err)
+			continue
+		}
+		for _, created := range newDescendants(known, processes) {
+			s.write(SessionProcessCreated, s.id, created.pid, created.parentPid, created.image)
+		}
+	}
+}
+
+// newDescendants returns the processes whose parent is a known process and adds them to the known ones,
+// the known processes which exited are forgotten so that their ids can be reused
+func newDescendants(known map[uint32]bool, processes []process) []process {
+	running := make(map[uint32]bool, len(processes))
+	for _, p := range processes {
+		running[p.pid] = true
+	}
+	for pid := range known {
+		if !running[pid] {
+			delete(known, pid)
+		}
+	}
+
+	var created []process
+	// the parents are not always listed before their children
+	for found := true; found; {
+		found = false
+		for _, p := range processes {
+			if !known[p.pid] && known[p.parentPid] {
+				known[p.pid] = true
+				created = append(created, p)
+				found = true
+			}
+		}
+	}
+	return created
+}
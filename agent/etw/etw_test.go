@@ -0,0 +1,147 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package etw
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	id     uint16
+	fields []interface{}
+}
+
+type fakeProvider struct {
+	mu     sync.Mutex
+	events []event
+	closed bool
+}
+
+func (p *fakeProvider) write(id uint16, fields ...interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event{id: id, fields: fields})
+	return nil
+}
+
+func (p *fakeProvider) close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *fakeProvider) eventsOf(id uint16) []event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var events []event
+	for _, e := range p.events {
+		if e.id == id {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// waitFor waits up to a second for the condition
+func waitFor(t *testing.T, condition func() bool) {
+	for deadline := time.Now().Add(time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+	}
+}
+
+func stubProvider(t *testing.T) *fakeProvider {
+	fake := &fakeProvider{}
+	open := openProvider
+	openProvider = func() (provider, error) { return fake, nil }
+	t.Cleanup(func() { openProvider = open })
+	return fake
+}
+
+func TestStartSession_Disabled(t *testing.T) {
+	stubProvider(t)
+	session := StartSession(log.NewMockLog(), appconfig.EtwCfg{}, "session", "ssm-user", "")
+	assert.Nil(t, session)
+	// the methods of a nil session do nothing
+	session.AddInput(1)
+	session.WatchProcesses(1)
+	session.End()
+}
+
+func TestStartSession_Unavailable(t *testing.T) {
+	open := openProvider
+	openProvider = func() (provider, error) { return nil, errors.New("unavailable") }
+	t.Cleanup(func() { openProvider = open })
+
+	assert.Nil(t, StartSession(log.NewMockLog(), appconfig.EtwCfg{Enabled: true}, "session", "ssm-user", ""))
+}
+
+func TestSession(t *testing.T) {
+	provider := stubProvider(t)
+	interval := volumeInterval
+	volumeInterval = 10 * time.Millisecond
+	t.Cleanup(func() { volumeInterval = interval })
+
+	session := StartSession(log.NewMockLog(), appconfig.EtwCfg{Enabled: true}, "session", "ssm-user", appconfig.TokenElevationLimited)
+	session.AddInput(3)
+	session.AddOutput(100)
+	waitFor(t, func() bool { return len(provider.eventsOf(SessionVolume)) > 0 })
+	session.AddOutput(20)
+	session.End()
+	session.End()
+
+	assert.Equal(t, []event{{SessionStarted, []interface{}{"session", "ssm-user", appconfig.TokenElevationLimited}}}, provider.eventsOf(SessionStarted))
+	assert.Equal(t, "session", provider.eventsOf(SessionVolume)[0].fields[0])
+	assert.Equal(t, []event{{SessionEnded, []interface{}{"session", uint64(3), uint64(120)}}}, provider.eventsOf(SessionEnded))
+	assert.True(t, provider.closed)
+}
+
+func TestWatchProcesses(t *testing.T) {
+	provider := stubProvider(t)
+	interval := processInterval
+	processInterval = time.Millisecond
+	list := listProcesses
+	listProcesses = func() ([]process, error) {
+		return []process{{4, 0, "System"}, {12, 11, "whoami.exe"}, {11, 10, "cmd.exe"}, {10, 1, "powershell.exe"}}, nil
+	}
+	t.Cleanup(func() {
+		processInterval = interval
+		listProcesses = list
+	})
+
+	session := StartSession(log.NewMockLog(), appconfig.EtwCfg{Enabled: true}, "session", "ssm-user", "")
+	session.WatchProcesses(10)
+	waitFor(t, func() bool { return len(provider.eventsOf(SessionProcessCreated)) == 2 })
+	session.End()
+
+	assert.Equal(t, []event{
+		{SessionProcessCreated, []interface{}{"session", uint32(11), uint32(10), "cmd.exe"}},
+		{SessionProcessCreated, []interface{}{"session", uint32(12), uint32(11), "whoami.exe"}},
+	}, provider.eventsOf(SessionProcessCreated))
+}
+
+func TestNewDescendants_ForgetsExitedProcesses(t *testing.T) {
+	known := map[uint32]bool{10: true, 11: true}
+	created := newDescendants(known, []process{{10, 1, "powershell.exe"}, {12, 10, "ping.exe"}})
+	assert.Equal(t, []process{{12, 10, "ping.exe"}}, created)
+	assert.Equal(t, map[uint32]bool{10: true, 12: true}, known)
+}
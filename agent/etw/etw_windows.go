@@ -0,0 +1,168 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package etw
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// providerGUID is the GUID of the provider in the manifest
+var providerGUID = syscall.GUID{
+	Data1: 0x8eed879b,
+	Data2: 0x0ad9,
+	Data3: 0x42f1,
+	Data4: [8]byte{0xa1, 0xe6, 0xf9, 0xb0, 0x7d, 0x8c, 0x78, 0x7a},
+}
+
+// Descriptor values of the events of the manifest
+const (
+	sessionsChannel = 16
+	sessionTask     = 1
+	levelInfo       = 4
+	opcodeInfo      = 0
+	opcodeStart     = 1
+	opcodeStop      = 2
+	// sessionsKeyword is the keyword of the sessions channel, the first channel of the manifest
+	sessionsKeyword = 0x8000000000000000
+)
+
+var (
+	etwAdvapi32         = syscall.NewLazyDLL("advapi32.dll")
+	eventRegisterProc   = etwAdvapi32.NewProc("EventRegister")
+	eventUnregisterProc = etwAdvapi32.NewProc("EventUnregister")
+	eventWriteProc      = etwAdvapi32.NewProc("EventWrite")
+)
+
+// eventDescriptor is the EVENT_DESCRIPTOR of an event
+type eventDescriptor struct {
+	id      uint16
+	version uint8
+	channel uint8
+	level   uint8
+	opcode  uint8
+	task    uint16
+	keyword uint64
+}
+
+// eventDataDescriptor is the EVENT_DATA_DESCRIPTOR of a field
+type eventDataDescriptor struct {
+	ptr      uint64
+	size     uint32
+	reserved uint32
+}
+
+// opcodes are the opcodes of the events
+var opcodes = map[uint16]uint8{
+	SessionStarted:        opcodeStart,
+	SessionVolume:         opcodeInfo,
+	SessionProcessCreated: opcodeInfo,
+	SessionEnded:          opcodeStop,
+}
+
+// windowsProvider writes the events with the ETW API
+type windowsProvider struct {
+	handle uint64
+}
+
+var openProvider = func() (provider, error) {
+	var handle uint64
+	if rc, _, _ := eventRegisterProc.Call(uintptr(unsafe.Pointer(&providerGUID)), 0, 0, uintptr(unsafe.Pointer(&handle))); rc != 0 {
+		return nil, fmt.Errorf("failed to register the %v provider: %v", ProviderName, syscall.Errno(rc))
+	}
+	return &windowsProvider{handle: handle}, nil
+}
+
+func (p *windowsProvider) write(id uint16, fields ...interface{}) error {
+	descriptor := eventDescriptor{
+		id:      id,
+		channel: sessionsChannel,
+		level:   levelInfo,
+		opcode:  opcodes[id],
+		task:    sessionTask,
+		keyword: sessionsKeyword,
+	}
+	// the values stay referenced by the slices until EventWrite returns
+	values := make([][]byte, len(fields))
+	data := make([]eventDataDescriptor, len(fields))
+	for i, field := range fields {
+		switch value := field.(type) {
+		case string:
+			utf16, err := syscall.UTF16FromString(value)
+			if err != nil {
+				return err
+			}
+			values[i] = (*[1 << 30]byte)(unsafe.Pointer(&utf16[0]))[: 2*len(utf16) : 2*len(utf16)]
+		case uint32:
+			values[i] = (*[4]byte)(unsafe.Pointer(&value))[:]
+		case uint64:
+			values[i] = (*[8]byte)(unsafe.Pointer(&value))[:]
+		default:
+			return fmt.Errorf("unsupported field type %T", field)
+		}
+		data[i] = eventDataDescriptor{ptr: uint64(uintptr(unsafe.Pointer(&values[i][0]))), size: uint32(len(values[i]))}
+	}
+	var dataPtr uintptr
+	if len(data) > 0 {
+		dataPtr = uintptr(unsafe.Pointer(&data[0]))
+	}
+	args := append(handleArgs(p.handle), uintptr(unsafe.Pointer(&descriptor)), uintptr(len(data)), dataPtr)
+	if rc, _, _ := eventWriteProc.Call(args...); rc != 0 {
+		return syscall.Errno(rc)
+	}
+	return nil
+}
+
+func (p *windowsProvider) close() error {
+	if rc, _, _ := eventUnregisterProc.Call(handleArgs(p.handle)...); rc != 0 {
+		return syscall.Errno(rc)
+	}
+	return nil
+}
+
+// handleArgs returns the provider handle as arguments, the 64 bits handle takes two arguments on 32 bits
+func handleArgs(handle uint64) []uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return []uintptr{uintptr(handle)}
+	}
+	return []uintptr{uintptr(handle), uintptr(handle >> 32)}
+}
+
+var listProcesses = func() ([]process, error) {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var processes []process
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		processes = append(processes, process{
+			pid:       entry.ProcessID,
+			parentPid: entry.ParentProcessID,
+			image:     syscall.UTF16ToString(entry.ExeFile[:]),
+		})
+	}
+	if err != syscall.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+	return processes, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package eventlog writes the events of the agent to the AmazonSSMAgent channel of the Windows Event Log: the service
+// start and stop, and the audit events of the sessions, the command executions, the updates and the integrity errors,
+// so that the Windows administrators consume the activity of the agent with Event Viewer or Windows Event Forwarding.
+package eventlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+const name = "EventLog"
+
+// ChannelName is the name of the event log channel of the agent and of its event source
+const ChannelName = "AmazonSSMAgent"
+
+// Event ids of the channel, the message file of the source only displays the ids from 1 to 1000
+const (
+	ServiceStarted             uint32 = 100
+	ServiceStopped             uint32 = 101
+	SessionStarted             uint32 = 200
+	SessionEnded               uint32 = 201
+	SshSessionAllowed          uint32 = 203
+	SshSessionDenied           uint32 = 204
+	SessionProcessStarted      uint32 = 202
+	DocumentExecutionRequested uint32 = 300
+	DocumentExecutionCompleted uint32 = 301
+	DocumentExecutionCanceled  uint32 = 302
+	AgentUpdate                uint32 = 400
+	BinaryIntegrityMismatch    uint32 = 900
+	OtherEvent                 uint32 = 999
+)
+
+// Levels of the events
+const (
+	levelInformation = iota
+	levelWarning
+	levelError
+)
+
+// writer writes the events to the channel
+type writer interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+// auditEventIDs are the event ids of the audit events
+var auditEventIDs = map[string]uint32{
+	audit.SessionStarted:             SessionStarted,
+	audit.SessionEnded:               SessionEnded,
+	audit.SshSessionAllowed:          SshSessionAllowed,
+	audit.SshSessionDenied:           SshSessionDenied,
+	audit.ProcessStarted:             SessionProcessStarted,
+	audit.DocumentExecutionRequested: DocumentExecutionRequested,
+	audit.DocumentExecutionCompleted: DocumentExecutionCompleted,
+	audit.DocumentExecutionCanceled:  DocumentExecutionCanceled,
+	audit.AgentUpdate:                AgentUpdate,
+	audit.BinaryIntegrityMismatch:    BinaryIntegrityMismatch,
+}
+
+// Channel is the core module writing the events of the agent to its event log channel
+type Channel struct {
+	context context.T
+	writer  writer
+}
+
+// NewChannel creates the channel, nil when it is not enabled in appconfig or when the event log is not available
+func NewChannel(context context.T) *Channel {
+	if !context.AppConfig().EventLog.Enabled {
+		return nil
+	}
+	if !available {
+		context.Log().Warnf("The event log channel is enabled in appconfig but the Windows Event Log is not available")
+		return nil
+	}
+	return &Channel{context: context.With("[" + name + "]")}
+}
+
+// ModuleName returns the name of the module
+func (c *Channel) ModuleName() string {
+	return name
+}
+
+// ModuleExecute registers the channel when it is not registered yet, writes the service start and the audit events
+func (c *Channel) ModuleExecute(context context.T) (err error) {
+	log := c.context.Log()
+	if c.writer, err = openWriter(); err != nil {
+		log.Errorf("Failed to open the event log channel %v: %v", ChannelName, err)
+		return nil
+	}
+	log.Infof("Writing the agent events to the event log channel %v", ChannelName)
+	write(log, c.writer, levelInformation, ServiceStarted, fmt.Sprintf("Amazon SSM Agent v%v started", version.Version))
+	audit.SetObserver(name, func(event audit.Event) {
+		level, eventID := auditEventLevel(event)
+		write(log, c.writer, level, eventID, auditEventMessage(event))
+	})
+	return nil
+}
+
+// ModuleRequestStop writes the service stop and closes the channel
+func (c *Channel) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if c.writer == nil {
+		return nil
+	}
+	audit.SetObserver(name, nil)
+	write(c.context.Log(), c.writer, levelInformation, ServiceStopped, fmt.Sprintf("Amazon SSM Agent v%v stopped", version.Version))
+	return c.writer.Close()
+}
+
+// write writes the event to the channel, a failure is only logged
+func write(log log.T, w writer, level int, eventID uint32, message string) {
+	var err error
+	switch level {
+	case levelError:
+		err = w.Error(eventID, message)
+	case levelWarning:
+		err = w.Warning(eventID, message)
+	default:
+		err = w.Info(eventID, message)
+	}
+	if err != nil {
+		log.Warnf("Failed to write the event %v to the event log channel: %v", eventID, err)
+	}
+}
+
+// auditEventLevel returns the level and the event id of the audit event, the failed executions and the integrity
+// mismatches are errors and the canceled executions and the denied SSH sessions are warnings
+func auditEventLevel(event audit.Event) (level int, eventID uint32) {
+	eventID, found := auditEventIDs[event.Type]
+	if !found {
+		eventID = OtherEvent
+	}
+	switch {
+	case event.Type == audit.BinaryIntegrityMismatch,
+		event.Status == string(contracts.ResultStatusFailed),
+		event.Status == string(contracts.ResultStatusTimedOut):
+		return levelError, eventID
+	case event.Type == audit.DocumentExecutionCanceled, event.Type == audit.SshSessionDenied:
+		return levelWarning, eventID
+	}
+	return levelInformation, eventID
+}
+
+// auditEventMessage returns the message of the audit event, its type followed by one line per field
+func auditEventMessage(event audit.Event) string {
+	lines := []string{event.Type, ""}
+	for _, field := range []struct{ name, value string }{
+		{"Time", event.Time},
+		{"MessageId", event.MessageID},
+		{"CommandId", event.CommandID},
+		{"SessionId", event.SessionID},
+		{"DocumentName", event.DocumentName},
+		{"RunAsUser", event.RunAsUser},
+		{"Status", event.Status},
+		{"Detail", event.Detail},
+		{"Pid", intsValue(event.Pid)},
+		{"ParentPids", intsValue(event.ParentPids...)},
+		{"Argv", strings.Join(event.Argv, " ")},
+		{"Uid", event.Uid},
+	} {
+		if field.value != "" {
+			lines = append(lines, field.name+": "+field.value)
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// intsValue returns the non-zero ids separated by spaces
+func intsValue(ids ...int) string {
+	var values []string
+	for _, id := range ids {
+		if id != 0 {
+			values = append(values, strconv.Itoa(id))
+		}
+	}
+	return strings.Join(values, " ")
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventlog
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// entry is an event written to the fake channel
+type entry struct {
+	level   int
+	eventID uint32
+	message string
+}
+
+// fakeWriter records the events written to the channel
+type fakeWriter struct {
+	entries []entry
+	closed  bool
+}
+
+func (w *fakeWriter) Info(eid uint32, msg string) error {
+	w.entries = append(w.entries, entry{levelInformation, eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Warning(eid uint32, msg string) error {
+	w.entries = append(w.entries, entry{levelWarning, eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Error(eid uint32, msg string) error {
+	w.entries = append(w.entries, entry{levelError, eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestNewChannel_Disabled(t *testing.T) {
+	assert.Nil(t, NewChannel(context.NewMockDefault()))
+}
+
+func TestChannel_WritesServiceAndAuditEvents(t *testing.T) {
+	w := &fakeWriter{}
+	openWriterOrig := openWriter
+	defer func() { openWriter = openWriterOrig }()
+	openWriter = func() (writer, error) { return w, nil }
+
+	ctx := context.NewMockDefault()
+	channel := &Channel{context: ctx}
+	assert.NoError(t, channel.ModuleExecute(ctx))
+	audit.Record(audit.Event{Type: audit.SessionStarted, SessionID: "session-id", RunAsUser: "ssm-user"})
+	audit.Record(audit.Event{Type: audit.DocumentExecutionCompleted, CommandID: "command-id", Status: string(contracts.ResultStatusFailed)})
+	audit.Record(audit.Event{Type: audit.DocumentExecutionCanceled, CommandID: "command-id"})
+	assert.NoError(t, channel.ModuleRequestStop(contracts.StopTypeSoftStop))
+	// the events recorded after the stop are not written
+	audit.Record(audit.Event{Type: audit.SessionEnded, SessionID: "session-id"})
+
+	assert.True(t, w.closed)
+	assert.Len(t, w.entries, 5)
+	assert.Equal(t, ServiceStarted, w.entries[0].eventID)
+	assert.Equal(t, levelInformation, w.entries[1].level)
+	assert.Equal(t, SessionStarted, w.entries[1].eventID)
+	assert.Contains(t, w.entries[1].message, "SessionStarted\r\n\r\n")
+	assert.Contains(t, w.entries[1].message, "SessionId: session-id\r\nRunAsUser: ssm-user")
+	assert.Equal(t, levelError, w.entries[2].level)
+	assert.Equal(t, DocumentExecutionCompleted, w.entries[2].eventID)
+	assert.Equal(t, levelWarning, w.entries[3].level)
+	assert.Equal(t, ServiceStopped, w.entries[4].eventID)
+}
+
+func TestAuditEventLevel(t *testing.T) {
+	level, eventID := auditEventLevel(audit.Event{Type: audit.BinaryIntegrityMismatch})
+	assert.Equal(t, levelError, level)
+	assert.Equal(t, BinaryIntegrityMismatch, eventID)
+
+	level, eventID = auditEventLevel(audit.Event{Type: audit.SessionEnded, Status: string(contracts.ResultStatusSuccess)})
+	assert.Equal(t, levelInformation, level)
+	assert.Equal(t, SessionEnded, eventID)
+
+	level, eventID = auditEventLevel(audit.Event{Type: audit.SshSessionDenied})
+	assert.Equal(t, levelWarning, level)
+	assert.Equal(t, SshSessionDenied, eventID)
+
+	_, eventID = auditEventLevel(audit.Event{Type: "Unknown"})
+	assert.Equal(t, OtherEvent, eventID)
+}
+
+func TestAuditEventMessage_ProcessStarted(t *testing.T) {
+	message := auditEventMessage(audit.Event{
+		Type:       audit.ProcessStarted,
+		SessionID:  "session-id",
+		Pid:        42,
+		ParentPids: []int{41, 40},
+		Argv:       []string{"cat", "/etc/shadow"},
+		Uid:        "0",
+	})
+	assert.Equal(t, "ProcessStarted\r\n\r\nSessionId: session-id\r\nPid: 42\r\nParentPids: 41 40\r\nArgv: cat /etc/shadow\r\nUid: 0", message)
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package eventlog
+
+import (
+	"golang.org/x/sys/windows/registry"
+	wineventlog "golang.org/x/sys/windows/svc/eventlog"
+)
+
+// available is true, the Windows Event Log is available on every Windows version
+const available = true
+
+// logKeyName is the registry key of the channel
+const logKeyName = `SYSTEM\CurrentControlSet\Services\EventLog\` + ChannelName
+
+// messageFile is the message file of the source, it displays the message of the events with the ids from 1 to 1000
+const messageFile = `%SystemRoot%\System32\EventCreate.exe`
+
+// maxSizeBytes is the maximum size of the channel when it is registered, the oldest events are overwritten
+const maxSizeBytes = 20 * 1024 * 1024
+
+// openWriter registers the channel and opens its source, it is stubbed in the tests
+var openWriter = func() (writer, error) {
+	if err := register(); err != nil {
+		return nil, err
+	}
+	return wineventlog.Open(ChannelName)
+}
+
+// register creates the channel and its event source when they are not registered yet
+func register() error {
+	logKey, logExisted, err := registry.CreateKey(registry.LOCAL_MACHINE, logKeyName, registry.CREATE_SUB_KEY|registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer logKey.Close()
+	if !logExisted {
+		if err = logKey.SetDWordValue("MaxSize", maxSizeBytes); err != nil {
+			return err
+		}
+	}
+	sourceKey, sourceExisted, err := registry.CreateKey(logKey, ChannelName, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer sourceKey.Close()
+	if sourceExisted {
+		return nil
+	}
+	if err = sourceKey.SetExpandStringValue("EventMessageFile", messageFile); err != nil {
+		return err
+	}
+	if err = sourceKey.SetDWordValue("TypesSupported", wineventlog.Error|wineventlog.Warning|wineventlog.Info); err != nil {
+		return err
+	}
+	return sourceKey.SetDWordValue("CustomSource", 1)
+}
@@ -26,6 +26,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/accounting"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -46,8 +47,35 @@ type T interface {
 	StartExe(log.T, string, io.Writer, io.Writer, task.CancelFlag, string, []string) (*os.Process, int, error)
 }
 
+// StdinExecuter is implemented by the executers that can feed the standard input of the commands they run.
+type StdinExecuter interface {
+	WithStdin(stdin io.Reader) T
+}
+
+// EnvironmentExecuter is implemented by the executers that can add environment variables to the commands they run.
+type EnvironmentExecuter interface {
+	WithEnvironment(env []string) T
+}
+
 // ShellCommandExecuter is specially added for testing purposes
 type ShellCommandExecuter struct {
+	// stdin is read into the standard input of the commands run by NewExecute
+	stdin io.Reader
+	// env are the name=value environment variables added to the commands run by NewExecute
+	env []string
+}
+
+// WithStdin returns a copy of the executer feeding stdin to the standard input of the commands run by NewExecute.
+func (e ShellCommandExecuter) WithStdin(stdin io.Reader) T {
+	e.stdin = stdin
+	return e
+}
+
+// WithEnvironment returns a copy of the executer adding the name=value variables of env to the environment of the
+// commands run by NewExecute.
+func (e ShellCommandExecuter) WithEnvironment(env []string) T {
+	e.env = append(append([]string{}, e.env...), env...)
+	return e
 }
 
 type timeoutSignal struct {
@@ -159,7 +187,7 @@ func (ShellCommandExecuter) Execute(
 }
 
 // NewExecute executes a list of shell commands in the given working directory and provides the stdout and stderr writers.
-func (ShellCommandExecuter) NewExecute(
+func (e ShellCommandExecuter) NewExecute(
 	log log.T,
 	workingDir string,
 	stdoutWriter io.Writer,
@@ -169,7 +197,7 @@ func (ShellCommandExecuter) NewExecute(
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
-	exitCode, err = ExecuteCommand(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
+	exitCode, err = executeCommand(log, cancelFlag, workingDir, e.stdin, e.env, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
 	return
 }
 
@@ -254,6 +282,22 @@ func ExecuteCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
+	return executeCommand(log, cancelFlag, workingDir, nil, nil, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments)
+}
+
+// executeCommand executes the given commands like ExecuteCommand, reading stdin into their standard input when not nil
+// and adding env to their environment.
+func executeCommand(log log.T,
+	cancelFlag task.CancelFlag,
+	workingDir string,
+	stdin io.Reader,
+	env []string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+) (exitCode int, err error) {
 
 	stdoutInterruptable, stopStdout := newWriter(stdoutWriter)
 	stderrInterruptable, stopStderr := newWriter(stderrWriter)
@@ -266,6 +310,7 @@ func ExecuteCommand(log log.T,
 	// However, if we run goroutines to copy from the StdoutPipe and StderrPipe we may lose the last write.
 	command.Stdout = stdoutInterruptable
 	command.Stderr = stderrInterruptable
+	command.Stdin = stdin
 	/*
 		stdoutPipe, err := command.StdoutPipe()
 		if err != nil {
@@ -284,6 +329,7 @@ func ExecuteCommand(log log.T,
 
 	// configure environment variables
 	prepareEnvironment(command)
+	command.Env = append(command.Env, env...)
 
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
@@ -340,6 +386,7 @@ func ExecuteCommand(log log.T,
 		}
 	case err = <-done:
 		log.Debug("Process completed.")
+		accounting.RecordCommand(command.ProcessState)
 		if err != nil {
 			exitCode = 1
 			log.Debugf("command returned error %v", err)
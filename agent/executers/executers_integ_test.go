@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build integration
 // +build integration
 
 // Package executers contains general purpose (shell) command executing objects.
@@ -24,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -242,6 +244,30 @@ func TestShellCommandExecuter_cancel(t *testing.T) {
 	}
 }
 
+// TestShellCommandExecuter_stdin tests that ShellCommandExecuter feeds the standard input of the commands
+func TestShellCommandExecuter_stdin(t *testing.T) {
+	var stdoutBuf bytes.Buffer
+	var stderrBuf bytes.Buffer
+	executer := ShellCommandExecuter{}.WithStdin(strings.NewReader("yes\nno\n"))
+	exitCode, err := executer.NewExecute(logger, ".", &stdoutBuf, &stderrBuf, task.NewChanneledCancelFlag(), defaultExecutionTimeout, "sh", []string{"-c", "read first; read second; echo $second $first"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, successExitCode, exitCode)
+	assert.Equal(t, "no yes\n", stdoutBuf.String())
+}
+
+// TestShellCommandExecuter_environment tests that ShellCommandExecuter adds the environment variables to the commands
+func TestShellCommandExecuter_environment(t *testing.T) {
+	var stdoutBuf bytes.Buffer
+	var stderrBuf bytes.Buffer
+	executer := ShellCommandExecuter{}.WithEnvironment([]string{"SSM_TEST_FIRST=first", "SSM_TEST_SECOND=second"})
+	exitCode, err := executer.NewExecute(logger, ".", &stdoutBuf, &stderrBuf, task.NewChanneledCancelFlag(), defaultExecutionTimeout, "sh", []string{"-c", "echo $SSM_TEST_FIRST $SSM_TEST_SECOND"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, successExitCode, exitCode)
+	assert.Equal(t, "first second\n", stdoutBuf.String())
+}
+
 func testCommandInvoker(t *testing.T, invoke CommandInvoker, testCase TestCase) {
 	logger.Infof("testCommandInvoker")
 	stdout, stderr, exitCode, errs := invoke(testCase.Commands)
@@ -253,7 +279,7 @@ func testCommandInvoker(t *testing.T, invoke CommandInvoker, testCase TestCase)
 	assert.Equal(t, exitCode, testCase.ExpectedExitCode)
 }
 
-//using long-running testcases for this test
+// using long-running testcases for this test
 func testCommandInvokerShutdown(t *testing.T, invoke CommandInvoker, cancelFlag task.CancelFlag, testCase TestCase) {
 	go func() {
 		time.Sleep(100 * time.Millisecond)
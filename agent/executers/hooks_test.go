@@ -0,0 +1,61 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package executers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeHook writes a shell script hook to a temporary folder
+func writeHook(t *testing.T, script string) string {
+	dir, err := ioutil.TempDir("", "hook")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "hook.sh")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700))
+	return path
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks of the test are shell scripts")
+	}
+	path := writeHook(t, "echo $SSM_HOOK_TEST\necho failed >&2\nexit 3\n")
+
+	output, err := RunHook(path, []string{"SSM_HOOK_TEST=value"}, time.Minute)
+	assert.Error(t, err)
+	assert.Equal(t, "value\nfailed\n", output)
+
+	path = writeHook(t, "echo done\n")
+	output, err = RunHook(path, nil, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "done\n", output)
+}
+
+func TestRunHook_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks of the test are shell scripts")
+	}
+	path := writeHook(t, "exec sleep 10\n")
+
+	_, err := RunHook(path, nil, 100*time.Millisecond)
+	assert.EqualError(t, err, "timed out after 100ms")
+}
@@ -61,6 +61,18 @@ func (m *MockCommandExecuter) NewExecute(
 	return args.Get(0).(int), args.Error(1)
 }
 
+// WithStdin is a mocked method that just returns what mock tells it to.
+func (m *MockCommandExecuter) WithStdin(stdin io.Reader) T {
+	args := m.Called(stdin)
+	return args.Get(0).(T)
+}
+
+// WithEnvironment is a mocked method that just returns what mock tells it to.
+func (m *MockCommandExecuter) WithEnvironment(env []string) T {
+	args := m.Called(env)
+	return args.Get(0).(T)
+}
+
 // StartExe is a mocked method that just returns what mock tells it to.
 func (m *MockCommandExecuter) StartExe(log log.T,
 	workingDir string,
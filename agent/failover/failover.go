@@ -0,0 +1,106 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package failover selects the region used to reach the control plane services
+// when their primary region is unreachable.
+package failover
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Tracker counts the consecutive failures of a service in its active region and fails over
+// to the next region past a threshold. The primary region is tried again after the failback period.
+type Tracker struct {
+	name      string
+	regions   []string
+	threshold int
+	failback  time.Duration
+	now       func() time.Time
+
+	lock       sync.Mutex
+	active     int
+	failures   int
+	failedOver time.Time
+}
+
+// NewTracker creates a tracker for the primary region and the failover targets, nil when no target is configured
+func NewTracker(name string, primary string, cfg appconfig.FailoverCfg) *Tracker {
+	if len(cfg.Targets) == 0 {
+		return nil
+	}
+	regions := []string{primary}
+	for _, target := range cfg.Targets {
+		regions = append(regions, target.Region)
+	}
+	return &Tracker{
+		name:      name,
+		regions:   regions,
+		threshold: cfg.FailureThreshold,
+		failback:  time.Duration(cfg.FailbackMinutes) * time.Minute,
+		now:       time.Now,
+	}
+}
+
+// Regions returns the primary region followed by the failover regions
+func (t *Tracker) Regions() []string {
+	return t.regions
+}
+
+// Region returns the active region
+func (t *Tracker) Region(log log.T) string {
+	return t.regions[t.Index(log)]
+}
+
+// Index returns the position of the active region in Regions, failing back to the primary region when it is due
+func (t *Tracker) Index(log log.T) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.active != 0 && t.now().Sub(t.failedOver) >= t.failback {
+		log.Infof("%v failing back from region %v to primary region %v", t.name, t.regions[t.active], t.regions[0])
+		t.active = 0
+		// a single failure moves the service back to the failover regions
+		t.failures = t.threshold - 1
+	}
+	return t.active
+}
+
+// ReportSuccess resets the failure count of the active region
+func (t *Tracker) ReportSuccess() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.failures = 0
+}
+
+// ReportFailure counts a failure in the active region and fails over to the next region past the threshold
+func (t *Tracker) ReportFailure(log log.T) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.failures++
+	if t.failures < t.threshold {
+		return
+	}
+	next := (t.active + 1) % len(t.regions)
+	log.Warnf("%v is unreachable in region %v after %v consecutive failures, failing over to region %v",
+		t.name, t.regions[t.active], t.failures, t.regions[next])
+	t.active = next
+	t.failures = 0
+	t.failedOver = t.now()
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package failover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+func newTestTracker(now *time.Time) *Tracker {
+	tracker := NewTracker("test", "us-east-1", appconfig.FailoverCfg{
+		Targets:          []appconfig.FailoverTarget{{Region: "us-west-2"}, {Region: "eu-west-1"}},
+		FailureThreshold: 2,
+		FailbackMinutes:  30,
+	})
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestNewTracker_NoTargets(t *testing.T) {
+	assert.Nil(t, NewTracker("test", "us-east-1", appconfig.FailoverCfg{FailureThreshold: 2, FailbackMinutes: 30}))
+}
+
+func TestTracker_FailsOverPastThreshold(t *testing.T) {
+	now := time.Now()
+	tracker := newTestTracker(&now)
+	assert.Equal(t, []string{"us-east-1", "us-west-2", "eu-west-1"}, tracker.Regions())
+
+	tracker.ReportFailure(logger)
+	assert.Equal(t, "us-east-1", tracker.Region(logger))
+	tracker.ReportSuccess()
+	tracker.ReportFailure(logger)
+	assert.Equal(t, "us-east-1", tracker.Region(logger))
+	tracker.ReportFailure(logger)
+	assert.Equal(t, "us-west-2", tracker.Region(logger))
+
+	tracker.ReportFailure(logger)
+	tracker.ReportFailure(logger)
+	assert.Equal(t, 2, tracker.Index(logger))
+}
+
+func TestTracker_FailsBackAfterPeriod(t *testing.T) {
+	now := time.Now()
+	tracker := newTestTracker(&now)
+	tracker.ReportFailure(logger)
+	tracker.ReportFailure(logger)
+	assert.Equal(t, "us-west-2", tracker.Region(logger))
+
+	now = now.Add(29 * time.Minute)
+	assert.Equal(t, "us-west-2", tracker.Region(logger))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "us-east-1", tracker.Region(logger))
+
+	// a single failure after the failback moves the service back to the failover regions
+	tracker.ReportFailure(logger)
+	assert.Equal(t, "us-west-2", tracker.Region(logger))
+}
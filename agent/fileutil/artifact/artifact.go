@@ -28,9 +28,13 @@ import (
 	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/diskguard"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
@@ -51,10 +55,19 @@ type DownloadInput struct {
 	SourceURL            string
 	DestinationDirectory string
 	SourceChecksums      map[string]string
+	// Resumable downloads http/https sources in ranged chunks, resuming interrupted downloads of the same file
+	Resumable bool
+	// Progress is called after every chunk of a resumable download
+	Progress ProgressFunc
 }
 
 // httpDownload attempts to download a file via http/s call
 func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOutput, err error) {
+	return httpDownloadWithTransport(log, fileURL, destFile, nil)
+}
+
+// httpDownloadWithTransport attempts to download a file via http/s call, using the default transport if transport is nil
+func httpDownloadWithTransport(log log.T, fileURL string, destFile string, transport http.RoundTripper) (output DownloadOutput, err error) {
 	log.Debugf("attempting to download as http/https download %v", destFile)
 	eTagFile := destFile + ".etag"
 	var check http.Client
@@ -70,6 +83,7 @@ func httpDownload(log log.T, fileURL string, destFile string) (output DownloadOu
 	}
 
 	check = http.Client{
+		Transport: transport,
 		CheckRedirect: func(r *http.Request, via []*http.Request) error {
 			r.URL.Opaque = r.URL.Path
 			return nil
@@ -125,6 +139,10 @@ func awsConfig(log log.T, amazonS3URL s3util.AmazonS3URL) (config *aws.Config, e
 	if errConfig != nil {
 		log.Error("failed to read appconfig.")
 	} else {
+		if err = s3util.ApplyCompatibleConfig(config, appConfig.S3); err != nil {
+			log.Errorf("failed to configure the S3 endpoint, %v", err)
+		}
+		proxyconfig.ConfigureAwsProxy(log, config, appConfig.S3.Proxy)
 		if appConfig.S3.Endpoint != "" {
 			config.Endpoint = &appConfig.S3.Endpoint
 		} else {
@@ -137,9 +155,9 @@ func awsConfig(log log.T, amazonS3URL s3util.AmazonS3URL) (config *aws.Config, e
 			}
 		}
 	}
-	config.S3ForcePathStyle = aws.Bool(amazonS3URL.IsPathStyle)
+	config.S3ForcePathStyle = aws.Bool(amazonS3URL.IsPathStyle || appConfig.S3.ForcePathStyle)
 	config.Region = aws.String(amazonS3URL.Region)
-	return config, nil
+	return config, err
 }
 
 // CanGetS3Object returns true if it is possible to fetch an object because it exists, is not deleted, and read permissions exist for this request
@@ -171,6 +189,9 @@ func CanGetS3Object(log log.T, amazonS3URL s3util.AmazonS3URL) bool {
 // ListS3Folders returns the folders under a given S3 URL where folders are keys whose prefix is the URL key
 // and contain a / after the prefix.  The folder name is the part between the prefix and the /.
 func ListS3Folders(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []string, err error) {
+	if mirrorConfig(log).Url != "" {
+		return nil, ErrMirrorListing
+	}
 	config, _ := awsConfig(log, amazonS3URL)
 	prefix := amazonS3URL.Key
 	if !strings.HasSuffix(prefix, "/") {
@@ -205,6 +226,9 @@ func ListS3Folders(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []str
 // ListS3Directory returns all the objects (files and folders) under a given S3 URL where folders are keys whose prefix
 // is the URL key and contain a / after the prefix.
 func ListS3Directory(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []string, err error) {
+	if mirrorConfig(log).Url != "" {
+		return nil, ErrMirrorListing
+	}
 	config, _ := awsConfig(log, amazonS3URL)
 	var params *s3.ListObjectsInput
 	prefix := amazonS3URL.Key
@@ -316,7 +340,7 @@ func FileCopy(log log.T, destinationPath string, src io.Reader) (written int64,
 	}
 	defer file.Close()
 	var size int64
-	size, err = io.Copy(file, src)
+	size, err = io.Copy(file, bandwidth.NewReader(bandwidth.Download, src))
 	log.Infof("%s with %v bytes downloaded", destinationPath, size)
 	return
 }
@@ -343,6 +367,9 @@ func Download(log log.T, input DownloadInput) (output DownloadOutput, err error)
 		err = fmt.Errorf("failed to create directory=%v, err=%v", destinationDir, err)
 		return
 	}
+	if err = diskguard.Check(destinationDir); err != nil {
+		return
+	}
 
 	// process if the url is local file or it has already been downloaded.
 	var isLocalFile = false
@@ -362,11 +389,16 @@ func Download(log log.T, input DownloadInput) (output DownloadOutput, err error)
 		// compute the local filename which is hash of url_filename
 		// Generating a hash_filename will also help against attackers
 		// from specifying a directory and filename to overwrite any ami/built-in files.
-		urlHash := sha1.Sum([]byte(fileURL.String()))
-		output.LocalFilePath = filepath.Join(destinationDir, fmt.Sprintf("%x", urlHash))
+		output.LocalFilePath = filepath.Join(destinationDir, urlHash(fileURL.String()))
 
 		amazonS3URL := s3util.ParseAmazonS3URL(log, fileURL)
-		if amazonS3URL.IsBucketAndKeyPresent() {
+		if mirror := mirrorConfig(log); mirror.Url != "" {
+			// all downloads come from the mirror, which requires a checksum to verify them with
+			output, err = mirrorDownload(log, mirror, &input, fileURL, output.LocalFilePath)
+		} else if input.Resumable && (fileURL.Scheme == "https" || fileURL.Scheme == "http") {
+			// ranged http/https download, which survives interruptions of large files
+			output, err = resumableDownload(log, input, output.LocalFilePath)
+		} else if amazonS3URL.IsBucketAndKeyPresent() {
 			// source is s3
 			var tempOutput DownloadOutput
 			tempOutput, err = s3Download(log, amazonS3URL, output.LocalFilePath)
@@ -420,7 +452,7 @@ func VerifyHash(log log.T, input DownloadInput, output DownloadOutput) (bool, er
 		// check the sha256 algorithm by default
 		if hashAlgorithm == "" || strings.EqualFold(hashAlgorithm, "sha256") {
 			computedHashValue, err = Sha256HashValue(log, output.LocalFilePath)
-		} else if strings.EqualFold(hashAlgorithm, "md5") {
+		} else if strings.EqualFold(hashAlgorithm, "md5") && fips.ApprovedDigest(hashAlgorithm) {
 			computedHashValue, err = Md5HashValue(log, output.LocalFilePath)
 		} else {
 			continue
@@ -445,6 +477,15 @@ func VerifyHash(log log.T, input DownloadInput, output DownloadOutput) (bool, er
 	return true, nil
 }
 
+// urlHash returns the hex digest of the url naming its download, SHA-256 in FIPS mode and SHA-1 otherwise,
+// which keeps the downloads of the instances outside of FIPS mode in place
+func urlHash(fileURL string) string {
+	if fips.Enabled() {
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(fileURL)))
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fileURL)))
+}
+
 // Sha256HashValue gets the sha256 hash value
 func Sha256HashValue(log log.T, filePath string) (hash string, err error) {
 	var exists = false
@@ -0,0 +1,124 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package artifact contains utilities for working downloading files.
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/util"
+)
+
+const (
+	// mirrorChecksumSuffix is the suffix of the file holding the sha256 checksum of a file on the mirror
+	mirrorChecksumSuffix = ".sha256"
+
+	// maxMirrorChecksumSize is the maximum size of a checksum file read from the mirror
+	maxMirrorChecksumSize = 4096
+)
+
+var sha256Pattern = regexp.MustCompile("^[0-9a-fA-F]{64}$")
+
+// ErrMirrorListing is returned when listing S3 while downloads are redirected to a mirror, which can't be listed
+var ErrMirrorListing = errors.New("S3 folders can't be listed while downloads are redirected to a mirror")
+
+// mirrorConfig returns the configured download mirror, its Url is empty when downloads aren't redirected
+var mirrorConfig = func(log log.T) appconfig.MirrorCfg {
+	appConfig, err := appconfig.Config(false)
+	if err != nil {
+		log.Error("failed to read appconfig.")
+	}
+	return appConfig.Mirror
+}
+
+// mirrorURL returns the location of sourceURL on the mirror
+func mirrorURL(mirror appconfig.MirrorCfg, sourceURL *url.URL) (string, error) {
+	base, err := url.Parse(mirror.Url)
+	if err != nil || base.Scheme != "https" || base.Host == "" {
+		return "", fmt.Errorf("invalid mirror url %v, an https url is required", mirror.Url)
+	}
+	return strings.TrimRight(base.String(), "/") + "/" + sourceURL.Host + sourceURL.EscapedPath(), nil
+}
+
+// mirrorDownload downloads the file of fileURL from the mirror and makes sure input has a checksum to verify it with
+// The checksum published next to the file on the mirror is only used when input has none and the mirror config allows it,
+// it detects corrupted files but not files replaced on the mirror.
+func mirrorDownload(log log.T, mirror appconfig.MirrorCfg, input *DownloadInput, fileURL *url.URL, destFile string) (output DownloadOutput, err error) {
+	var sourceURL string
+	if sourceURL, err = mirrorURL(mirror, fileURL); err != nil {
+		return
+	}
+	log.Debugf("downloading %v from mirror %v", fileURL, sourceURL)
+
+	client := &http.Client{}
+	if mirror.CABundle != "" {
+		if client, err = util.NewHTTPClientWithCABundle(mirror.CABundle); err != nil {
+			return
+		}
+	}
+	if !hasChecksum(input.SourceChecksums) {
+		if mirror.RequireSourceChecksum {
+			err = fmt.Errorf("%v has no checksum to verify its download from the mirror with", fileURL)
+			return
+		}
+		var checksum string
+		if checksum, err = mirrorChecksum(client, sourceURL+mirrorChecksumSuffix); err != nil {
+			return
+		}
+		input.SourceChecksums = map[string]string{"sha256": checksum}
+	}
+	return httpDownloadWithTransport(log, sourceURL, destFile, client.Transport)
+}
+
+// mirrorChecksum reads the sha256 checksum published on the mirror, in the format of sha256sum
+func mirrorChecksum(client *http.Client, checksumURL string) (string, error) {
+	resp, err := client.Get(checksumURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get checksum from mirror %v: %v", checksumURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get checksum from mirror %v: status %v", checksumURL, resp.Status)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMirrorChecksumSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum from mirror %v: %v", checksumURL, err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 || !sha256Pattern.MatchString(fields[0]) {
+		return "", fmt.Errorf("invalid sha256 checksum in %v", checksumURL)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// hasChecksum returns true if checksums holds a value VerifyHash verifies a file with
+func hasChecksum(checksums map[string]string) bool {
+	for hashAlgorithm, hashValue := range checksums {
+		if hashAlgorithm != "" && hashValue != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/stretchr/testify/assert"
+)
+
+const mirrorTestContent = "mirrored content"
+
+func mirrorTestChecksum() string {
+	sum := sha256.Sum256([]byte(mirrorTestContent))
+	return hex.EncodeToString(sum[:])
+}
+
+// setupMirror starts a mirror serving files, redirects downloads to it and returns the download directory
+func setupMirror(t *testing.T, files map[string]string) (tmpDir string, cleanup func()) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if content, ok := files[r.URL.Path]; ok {
+			w.Write([]byte(content))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	tmpDir, _ = ioutil.TempDir("", "mirror")
+	caBundle := filepath.Join(tmpDir, "ca.pem")
+	ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	mirrorConfigOrig := mirrorConfig
+	mirrorConfig = func(log log.T) appconfig.MirrorCfg {
+		return appconfig.MirrorCfg{Url: server.URL + "/mirror/", CABundle: caBundle}
+	}
+	return tmpDir, func() {
+		mirrorConfig = mirrorConfigOrig
+		server.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestMirrorURL(t *testing.T) {
+	sourceURL, _ := url.Parse("https://s3.amazonaws.com/bucket/path%20with%20spaces/file.zip")
+
+	mirrored, err := mirrorURL(appconfig.MirrorCfg{Url: "https://mirror.example.com/ssm/"}, sourceURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://mirror.example.com/ssm/s3.amazonaws.com/bucket/path%20with%20spaces/file.zip", mirrored)
+
+	_, err = mirrorURL(appconfig.MirrorCfg{Url: "http://mirror.example.com"}, sourceURL)
+	assert.Error(t, err)
+}
+
+func TestDownloadFromMirror_ChecksumFromMirror(t *testing.T) {
+	tmpDir, cleanup := setupMirror(t, map[string]string{
+		"/mirror/s3.amazonaws.com/bucket/file.txt":        mirrorTestContent,
+		"/mirror/s3.amazonaws.com/bucket/file.txt.sha256": mirrorTestChecksum() + "  file.txt\n",
+	})
+	defer cleanup()
+
+	output, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            "https://s3.amazonaws.com/bucket/file.txt",
+		DestinationDirectory: tmpDir,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsHashMatched)
+	content, _ := ioutil.ReadFile(output.LocalFilePath)
+	assert.Equal(t, mirrorTestContent, string(content))
+}
+
+func TestDownloadFromMirror_ChecksumFromInput(t *testing.T) {
+	tmpDir, cleanup := setupMirror(t, map[string]string{
+		"/mirror/example.com/file.txt": mirrorTestContent,
+	})
+	defer cleanup()
+
+	output, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            "https://example.com/file.txt",
+		DestinationDirectory: tmpDir,
+		SourceChecksums:      map[string]string{"sha256": mirrorTestChecksum()},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsHashMatched)
+}
+
+func TestDownloadFromMirror_ChecksumMismatch(t *testing.T) {
+	tmpDir, cleanup := setupMirror(t, map[string]string{
+		"/mirror/example.com/file.txt":        "tampered content",
+		"/mirror/example.com/file.txt.sha256": mirrorTestChecksum(),
+	})
+	defer cleanup()
+
+	output, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            "https://example.com/file.txt",
+		DestinationDirectory: tmpDir,
+	})
+
+	assert.Error(t, err)
+	assert.False(t, output.IsHashMatched)
+}
+
+func TestDownloadFromMirror_MissingChecksum(t *testing.T) {
+	tmpDir, cleanup := setupMirror(t, map[string]string{
+		"/mirror/example.com/file.txt": mirrorTestContent,
+	})
+	defer cleanup()
+
+	_, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            "https://example.com/file.txt",
+		DestinationDirectory: tmpDir,
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get checksum from mirror")
+}
+
+func TestDownloadFromMirror_RequireSourceChecksum(t *testing.T) {
+	tmpDir, cleanup := setupMirror(t, map[string]string{
+		"/mirror/example.com/file.txt":        mirrorTestContent,
+		"/mirror/example.com/file.txt.sha256": mirrorTestChecksum() + "  file.txt\n",
+	})
+	defer cleanup()
+	mirrorConfigTest := mirrorConfig
+	mirrorConfig = func(log log.T) appconfig.MirrorCfg {
+		mirror := mirrorConfigTest(log)
+		mirror.RequireSourceChecksum = true
+		return mirror
+	}
+
+	_, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            "https://example.com/file.txt",
+		DestinationDirectory: tmpDir,
+	})
+
+	// the checksum published on the mirror isn't trusted
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has no checksum")
+
+	output, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            "https://example.com/file.txt",
+		DestinationDirectory: tmpDir,
+		SourceChecksums:      map[string]string{"sha256": mirrorTestChecksum()},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsHashMatched)
+}
+
+func TestListS3FromMirror(t *testing.T) {
+	_, cleanup := setupMirror(t, map[string]string{})
+	defer cleanup()
+	amazonS3URL := s3util.AmazonS3URL{IsValidS3URI: true, Bucket: "my-bucket", Key: "folder"}
+
+	_, err := ListS3Folders(log.NewMockLog(), amazonS3URL)
+	assert.Equal(t, ErrMirrorListing, err)
+
+	_, err = ListS3Directory(log.NewMockLog(), amazonS3URL)
+	assert.Equal(t, ErrMirrorListing, err)
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package artifact contains utilities for working downloading files.
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// partialFileSuffix is the suffix of the file holding the chunks of an interrupted resumable download
+	partialFileSuffix = ".partial"
+
+	// partialStateSuffix is the suffix of the file holding the state of an interrupted resumable download
+	partialStateSuffix = ".partial.json"
+)
+
+// resumableChunkSize is the size of the ranged requests of resumable downloads
+var resumableChunkSize int64 = 4 * 1024 * 1024
+
+var contentRangePattern = regexp.MustCompile(`^bytes (\d+)-(\d+)/(\d+)$`)
+
+// ProgressFunc is called with the downloaded and total bytes after every chunk of a resumable download
+type ProgressFunc func(downloaded int64, total int64)
+
+// partialDownload is the state of a resumable download, persisted after every chunk
+type partialDownload struct {
+	URL       string `json:"url"`
+	ETag      string `json:"etag"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunkSize"`
+	// Chunks are the sha256 checksums of the chunks written to the partial file
+	Chunks []string `json:"chunks"`
+}
+
+// offset returns the number of bytes written to the partial file
+func (p *partialDownload) offset() int64 {
+	offset := int64(len(p.Chunks)) * p.ChunkSize
+	if p.Size > 0 && offset > p.Size {
+		return p.Size
+	}
+	return offset
+}
+
+// resumableDownload downloads a file via http/s in ranged chunks, an interrupted download resumes
+// from the last chunk matching its checksum as long as the file is unchanged on the server
+func resumableDownload(log log.T, input DownloadInput, destFile string) (output DownloadOutput, err error) {
+	partialFile := destFile + partialFileSuffix
+	stateFile := destFile + partialStateSuffix
+	state := loadPartialDownload(log, input.SourceURL, partialFile, stateFile)
+	if len(state.Chunks) > 0 {
+		log.Infof("resuming download of %v at %v of %v bytes", input.SourceURL, state.offset(), state.Size)
+	}
+
+	var file *os.File
+	if file, err = os.OpenFile(partialFile, os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+		return
+	}
+	defer file.Close()
+	if err = file.Truncate(state.offset()); err != nil {
+		return
+	}
+	if _, err = file.Seek(state.offset(), io.SeekStart); err != nil {
+		return
+	}
+
+	for state.Size == 0 || state.offset() < state.Size {
+		var complete bool
+		if complete, err = downloadChunk(log, input.SourceURL, file, &state); err != nil {
+			return
+		}
+		if err = savePartialDownload(stateFile, &state); err != nil {
+			return
+		}
+		if input.Progress != nil {
+			input.Progress(state.offset(), state.Size)
+		}
+		if complete {
+			break
+		}
+	}
+
+	if err = file.Close(); err != nil {
+		return
+	}
+	fileutil.DeleteFile(destFile)
+	if err = os.Rename(partialFile, destFile); err != nil {
+		return
+	}
+	fileutil.DeleteFile(stateFile)
+	output.LocalFilePath = destFile
+	output.IsUpdated = true
+	return
+}
+
+// downloadChunk appends the next chunk of the download to file, it returns true when the server sent the whole file instead
+func downloadChunk(log log.T, sourceURL string, file *os.File, state *partialDownload) (complete bool, err error) {
+	offset := state.offset()
+	request, err := http.NewRequest("GET", sourceURL, nil)
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", offset, offset+state.ChunkSize-1))
+	if state.ETag != "" {
+		request.Header.Set("If-Range", state.ETag)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the server doesn't support ranges or the file changed, download it at once from the start
+		log.Debugf("%v is not served in ranges, downloading the whole file", sourceURL)
+		if err = file.Truncate(0); err != nil {
+			return false, err
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		hash := sha256.New()
+		written, err := io.Copy(io.MultiWriter(file, hash), bandwidth.NewReader(bandwidth.Download, resp.Body))
+		if err != nil {
+			return false, err
+		}
+		*state = partialDownload{URL: sourceURL, Size: written, ChunkSize: written, Chunks: []string{hex.EncodeToString(hash.Sum(nil))}}
+		return true, nil
+	case http.StatusPartialContent:
+	default:
+		return false, fmt.Errorf("http request failed. status:%v statuscode:%v", resp.Status, resp.StatusCode)
+	}
+
+	start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return false, err
+	}
+	if start != offset || (state.Size != 0 && size != state.Size) {
+		return false, fmt.Errorf("unexpected content range %v for offset %v", resp.Header.Get("Content-Range"), offset)
+	}
+	if state.Size == 0 {
+		state.Size = size
+		state.ETag = resp.Header.Get("Etag")
+	}
+
+	expected := state.ChunkSize
+	if offset+expected > state.Size {
+		expected = state.Size - offset
+	}
+	chunk, err := ioutil.ReadAll(io.LimitReader(bandwidth.NewReader(bandwidth.Download, resp.Body), expected))
+	if err != nil {
+		return false, err
+	}
+	if int64(len(chunk)) != expected {
+		return false, fmt.Errorf("incomplete chunk at offset %v, %v of %v bytes received", offset, len(chunk), expected)
+	}
+	if _, err = file.Write(chunk); err != nil {
+		return false, err
+	}
+	checksum := sha256.Sum256(chunk)
+	state.Chunks = append(state.Chunks, hex.EncodeToString(checksum[:]))
+	return false, nil
+}
+
+// parseContentRange returns the start and the total size of a Content-Range header
+func parseContentRange(contentRange string) (start int64, size int64, err error) {
+	match := contentRangePattern.FindStringSubmatch(contentRange)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid content range %v", contentRange)
+	}
+	if start, err = strconv.ParseInt(match[1], 10, 64); err != nil {
+		return
+	}
+	size, err = strconv.ParseInt(match[3], 10, 64)
+	return
+}
+
+// loadPartialDownload returns the state of an interrupted download of sourceURL, keeping only the chunks
+// of the partial file that still match their checksums, or a new state when there is nothing to resume
+func loadPartialDownload(log log.T, sourceURL string, partialFile string, stateFile string) partialDownload {
+	fresh := partialDownload{URL: sourceURL, ChunkSize: resumableChunkSize}
+	content, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		return fresh
+	}
+	var state partialDownload
+	if err = json.Unmarshal(content, &state); err != nil || state.URL != sourceURL || state.ChunkSize != resumableChunkSize {
+		return fresh
+	}
+	file, err := os.Open(partialFile)
+	if err != nil {
+		return fresh
+	}
+	defer file.Close()
+
+	var valid []string
+	chunk := make([]byte, state.ChunkSize)
+	for _, expected := range state.Chunks {
+		n, _ := io.ReadFull(file, chunk)
+		checksum := sha256.Sum256(chunk[:n])
+		if n == 0 || hex.EncodeToString(checksum[:]) != expected {
+			log.Warnf("chunk %v of the partial download of %v is corrupted, resuming before it", len(valid), sourceURL)
+			break
+		}
+		valid = append(valid, expected)
+	}
+	state.Chunks = valid
+	return state
+}
+
+// savePartialDownload persists the state of a resumable download
+func savePartialDownload(stateFile string, state *partialDownload) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(stateFile, content, 0600)
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const resumableTestContent = "the content of a large installer, served in ranged chunks"
+
+// setupResumable serves resumableTestContent with range support, records the requested ranges and returns the download file
+func setupResumable(t *testing.T) (server *httptest.Server, ranges *[]string, destFile string) {
+	ranges = &[]string{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		w.Header().Set("Etag", `"v1"`)
+		http.ServeContent(w, r, "installer", time.Time{}, strings.NewReader(resumableTestContent))
+	}))
+	resumableChunkSize = 10
+	t.Cleanup(func() {
+		server.Close()
+		resumableChunkSize = 4 * 1024 * 1024
+	})
+	return server, ranges, filepath.Join(t.TempDir(), "installer")
+}
+
+func TestResumableDownload(t *testing.T) {
+	server, ranges, destFile := setupResumable(t)
+	var progress []int64
+
+	output, err := resumableDownload(log.NewMockLog(), DownloadInput{
+		SourceURL: server.URL,
+		Progress: func(downloaded int64, total int64) {
+			assert.Equal(t, int64(len(resumableTestContent)), total)
+			progress = append(progress, downloaded)
+		},
+	}, destFile)
+
+	assert.NoError(t, err)
+	assert.Equal(t, destFile, output.LocalFilePath)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, resumableTestContent, string(content))
+	assert.Equal(t, []int64{10, 20, 30, 40, 50, int64(len(resumableTestContent))}, progress)
+	assert.Equal(t, "bytes=0-9", (*ranges)[0])
+	assert.False(t, fileutil.Exists(destFile+partialFileSuffix))
+	assert.False(t, fileutil.Exists(destFile+partialStateSuffix))
+}
+
+func TestResumableDownloadResumesInterruptedDownload(t *testing.T) {
+	server, ranges, destFile := setupResumable(t)
+	writePartialDownload(t, server.URL, destFile, resumableTestContent[:20])
+
+	_, err := resumableDownload(log.NewMockLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, resumableTestContent, string(content))
+	assert.Equal(t, "bytes=20-29", (*ranges)[0])
+}
+
+func TestResumableDownloadSkipsCorruptedChunks(t *testing.T) {
+	server, ranges, destFile := setupResumable(t)
+	writePartialDownload(t, server.URL, destFile, resumableTestContent[:20])
+	ioutil.WriteFile(destFile+partialFileSuffix, []byte(resumableTestContent[:10]+"corrupted!"), 0600)
+
+	_, err := resumableDownload(log.NewMockLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, resumableTestContent, string(content))
+	assert.Equal(t, "bytes=10-19", (*ranges)[0])
+}
+
+func TestResumableDownloadWithoutRangeSupport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(resumableTestContent))
+	}))
+	defer server.Close()
+	destFile := filepath.Join(t.TempDir(), "installer")
+	var progress []int64
+
+	_, err := resumableDownload(log.NewMockLog(), DownloadInput{
+		SourceURL: server.URL,
+		Progress: func(downloaded int64, total int64) {
+			progress = append(progress, downloaded)
+		},
+	}, destFile)
+
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadFile(destFile)
+	assert.Equal(t, resumableTestContent, string(content))
+	assert.Equal(t, []int64{int64(len(resumableTestContent))}, progress)
+}
+
+func TestResumableDownloadKeepsPartialDownloadOnFailure(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "installer", time.Time{}, strings.NewReader(resumableTestContent))
+	}))
+	defer server.Close()
+	resumableChunkSize = 10
+	defer func() { resumableChunkSize = 4 * 1024 * 1024 }()
+	destFile := filepath.Join(t.TempDir(), "installer")
+
+	_, err := resumableDownload(log.NewMockLog(), DownloadInput{SourceURL: server.URL}, destFile)
+
+	assert.Error(t, err)
+	content, _ := ioutil.ReadFile(destFile + partialFileSuffix)
+	assert.Equal(t, resumableTestContent[:20], string(content))
+	state := loadPartialDownload(log.NewMockLog(), server.URL, destFile+partialFileSuffix, destFile+partialStateSuffix)
+	assert.Len(t, state.Chunks, 2)
+}
+
+func TestDownloadResumable(t *testing.T) {
+	server, _, _ := setupResumable(t)
+	checksum := sha256.Sum256([]byte(resumableTestContent))
+
+	output, err := Download(log.NewMockLog(), DownloadInput{
+		SourceURL:            server.URL + "/installer.tar.gz",
+		DestinationDirectory: t.TempDir(),
+		SourceChecksums:      map[string]string{"sha256": hex.EncodeToString(checksum[:])},
+		Resumable:            true,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, output.IsHashMatched)
+}
+
+// writePartialDownload writes the state and the partial file of an interrupted download of content
+func writePartialDownload(t *testing.T, sourceURL string, destFile string, content string) {
+	state := partialDownload{URL: sourceURL, ETag: `"v1"`, Size: int64(len(resumableTestContent)), ChunkSize: resumableChunkSize}
+	for chunk := []byte(content); len(chunk) > 0; chunk = chunk[resumableChunkSize:] {
+		checksum := sha256.Sum256(chunk[:resumableChunkSize])
+		state.Chunks = append(state.Chunks, hex.EncodeToString(checksum[:]))
+	}
+	assert.NoError(t, ioutil.WriteFile(destFile+partialFileSuffix, []byte(content), 0600))
+	assert.NoError(t, savePartialDownload(destFile+partialStateSuffix, &state))
+}
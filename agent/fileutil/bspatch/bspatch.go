@@ -0,0 +1,88 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bspatch applies binary patches in the bsdiff 4.x format, used for the delta updates of the agent.
+package bspatch
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	magic      = "BSDIFF40"
+	headerSize = 32
+)
+
+// Patch returns the file resulting of the bsdiff patch applied to old
+func Patch(old []byte, patch []byte) ([]byte, error) {
+	if len(patch) < headerSize || string(patch[:len(magic)]) != magic {
+		return nil, fmt.Errorf("invalid bsdiff patch header")
+	}
+	ctrlLen := offtin(patch[8:16])
+	diffLen := offtin(patch[16:24])
+	newSize := offtin(patch[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || headerSize+ctrlLen+diffLen > int64(len(patch)) {
+		return nil, fmt.Errorf("corrupt bsdiff patch header")
+	}
+
+	ctrl := bzip2.NewReader(bytes.NewReader(patch[headerSize : headerSize+ctrlLen]))
+	diff := bzip2.NewReader(bytes.NewReader(patch[headerSize+ctrlLen : headerSize+ctrlLen+diffLen]))
+	extra := bzip2.NewReader(bytes.NewReader(patch[headerSize+ctrlLen+diffLen:]))
+
+	result := make([]byte, newSize)
+	var oldPos, newPos int64
+	var ctrlBuf [24]byte
+	for newPos < newSize {
+		if _, err := io.ReadFull(ctrl, ctrlBuf[:]); err != nil {
+			return nil, fmt.Errorf("failed to read bsdiff control block: %v", err)
+		}
+		diffSize, extraSize, seek := offtin(ctrlBuf[0:8]), offtin(ctrlBuf[8:16]), offtin(ctrlBuf[16:24])
+		if diffSize < 0 || extraSize < 0 || newPos+diffSize+extraSize > newSize {
+			return nil, fmt.Errorf("corrupt bsdiff control block")
+		}
+
+		// the diff block holds the bytewise difference with old
+		if _, err := io.ReadFull(diff, result[newPos:newPos+diffSize]); err != nil {
+			return nil, fmt.Errorf("failed to read bsdiff diff block: %v", err)
+		}
+		for i := int64(0); i < diffSize; i++ {
+			if oldPos+i >= 0 && oldPos+i < int64(len(old)) {
+				result[newPos+i] += old[oldPos+i]
+			}
+		}
+		newPos += diffSize
+		oldPos += diffSize
+
+		// the extra block holds new bytes copied as they are
+		if _, err := io.ReadFull(extra, result[newPos:newPos+extraSize]); err != nil {
+			return nil, fmt.Errorf("failed to read bsdiff extra block: %v", err)
+		}
+		newPos += extraSize
+		oldPos += seek
+	}
+	return result, nil
+}
+
+// offtin decodes the sign and magnitude little endian integers of bsdiff
+func offtin(buf []byte) int64 {
+	value := int64(binary.LittleEndian.Uint64(buf) &^ (1 << 63))
+	if buf[7]&0x80 != 0 {
+		return -value
+	}
+	return value
+}
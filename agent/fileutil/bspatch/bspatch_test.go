@@ -0,0 +1,64 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package bspatch applies binary patches in the bsdiff 4.x format, used for the delta updates of the agent.
+package bspatch
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPatch turns "the quick brown fox jumps over the lazy dog" into "the quick red fox jumps over the lazy cat! the quick",
+// using diff, extra and backward seek control entries
+const testPatch = "QlNESUZGNDA6AAAAAAAAADgAAAAAAAAANAAAAAAAAABCWmg5MUFZJlNZtFUR3gAAEvBAWhAIQACAQAAgADEGTECST0T0TLnx4JF0iia2bwu5IpwoSFoqiO8AQlpoOTFBWSZTWRvCEE8AAABkEMkEAAECCAAEgEQgACIBoZCAaaaMh7bVQXErxdyRThQkBvCEE8BCWmg5MUFZJlNZL4zucAAAAxGAYAAuABQAIAAxDAghpo2ppzhDxdyRThQkC+M7nAA="
+
+func TestPatch(t *testing.T) {
+	patch, err := base64.StdEncoding.DecodeString(testPatch)
+	assert.NoError(t, err)
+
+	result, err := Patch([]byte("the quick brown fox jumps over the lazy dog"), patch)
+	assert.NoError(t, err)
+	assert.Equal(t, "the quick red fox jumps over the lazy cat! the quick", string(result))
+}
+
+func TestPatchInvalidHeader(t *testing.T) {
+	_, err := Patch([]byte("old"), []byte("BSDIFF39 not a patch at all, really"))
+	assert.Error(t, err)
+
+	_, err = Patch([]byte("old"), []byte("BSDIFF40"))
+	assert.Error(t, err)
+}
+
+func TestPatchTruncated(t *testing.T) {
+	patch, err := base64.StdEncoding.DecodeString(testPatch)
+	assert.NoError(t, err)
+
+	// the control and diff blocks are cut
+	_, err = Patch([]byte("the quick brown fox jumps over the lazy dog"), patch[:64])
+	assert.Error(t, err)
+
+	// the blocks produce less than the announced size
+	patch[24]++
+	_, err = Patch([]byte("the quick brown fox jumps over the lazy dog"), patch)
+	assert.Error(t, err)
+}
+
+func TestOfftin(t *testing.T) {
+	assert.Equal(t, int64(5), offtin([]byte{5, 0, 0, 0, 0, 0, 0, 0}))
+	assert.Equal(t, int64(-39), offtin([]byte{39, 0, 0, 0, 0, 0, 0, 0x80}))
+	assert.Equal(t, int64(0x0102), offtin([]byte{2, 1, 0, 0, 0, 0, 0, 0}))
+}
@@ -12,7 +12,7 @@
 // implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-//TODO: This package is a start to migration of the fileutil code to be inside an interface for better mocking.
+// TODO: This package is a start to migration of the fileutil code to be inside an interface for better mocking.
 // Package filemanager have all the file related dependencies used by the execute package
 package filemanager
 
@@ -11,7 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-//Package fileutil contains utilities for working with the file system.
+// Package fileutil contains utilities for working with the file system.
 package fileutil
 
 import (
@@ -205,7 +205,8 @@ func MoveFile(filename, srcPath, dstPath string) (result bool, err error) {
 	return MoveAndRenameFile(srcPath, filename, dstPath, filename)
 }
 
-// MoveAndRenameFile moves a file from the srcPath directory to dstPath directory and gives it a new name
+// MoveAndRenameFile moves a file from the srcPath directory to dstPath directory and gives it a new name,
+// the file is labeled for its new directory when SELinux is enabled
 func MoveAndRenameFile(srcPath, originalName, dstPath, newName string) (result bool, err error) {
 	srcFile := filepath.Join(srcPath, originalName)
 	dstFile := filepath.Join(dstPath, newName)
@@ -213,6 +214,9 @@ func MoveAndRenameFile(srcPath, originalName, dstPath, newName string) (result b
 	if err = fs.Rename(srcFile, dstFile); err != nil {
 		return false, fmt.Errorf("unexpected error encountered while moving the file. Error details - %v", err)
 	}
+	if err = RestoreSELinuxLabel(dstFile); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package fileutil contains utilities for working with the file system.
@@ -83,26 +84,32 @@ func Uncompress(log log.T, src, dest string) error {
 
 // GetDiskSpaceInfo returns DiskSpaceInfo with available, free, and total bytes from system disk space
 func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
-	var stat syscall.Statfs_t
 	var wd string
 
 	// get a rooted path name
 	if wd, err = os.Getwd(); err != nil {
 		return
 	}
+	return GetDiskSpaceInfoForPath(wd)
+}
+
+// GetDiskSpaceInfoForPath returns DiskSpaceInfo with available, free, and total bytes of the file system of the path
+func GetDiskSpaceInfoForPath(path string) (diskSpaceInfo DiskSpaceInfo, err error) {
+	var stat syscall.Statfs_t
 
 	// get filesystem statistics
-	syscall.Statfs(wd, &stat)
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return
+	}
 
 	// get block size
-	bSize := uint64(stat.Bsize)
+	bSize, bAvail, bFree, blocks := statfsBlocks(&stat)
 
 	// return DiskSpaceInfo with calculated bytes
 	return DiskSpaceInfo{
-		// On Linux the struct statfs.f_bavail field is unsigned, but on FreeBSD the field is an int64
-		AvailBytes: (int64)((uint64)(stat.Bavail) * bSize), // available space = # of available blocks * block size
-		FreeBytes:  (int64)(stat.Bfree * bSize),            // free space = # of free blocks * block size
-		TotalBytes: (int64)(stat.Blocks * bSize),           // total space = # of total blocks * block size
+		AvailBytes: (int64)(bAvail * bSize), // available space = # of available blocks * block size
+		FreeBytes:  (int64)(bFree * bSize),  // free space = # of free blocks * block size
+		TotalBytes: (int64)(blocks * bSize), // total space = # of total blocks * block size
 	}, nil
 }
 
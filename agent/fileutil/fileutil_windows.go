@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package fileutil contains utilities for working with the file system.
@@ -33,22 +34,31 @@ func Uncompress(log log.T, src, dest string) error {
 // GetDiskSpaceInfo returns available, free, and total bytes respectively from system disk space
 func GetDiskSpaceInfo() (diskSpaceInfo DiskSpaceInfo, err error) {
 	var wd string
-	var availBytes, totalBytes, freeBytes int64
 
 	// Get a rooted path name
 	if wd, err = os.Getwd(); err != nil {
 		return
 	}
+	return GetDiskSpaceInfoForPath(wd)
+}
+
+// GetDiskSpaceInfoForPath returns available, free, and total bytes respectively of the volume of the path
+func GetDiskSpaceInfoForPath(path string) (diskSpaceInfo DiskSpaceInfo, err error) {
+	var availBytes, totalBytes, freeBytes int64
 
 	// Load kernel32.dll and find GetDiskFreeSpaceEX function
 	getDiskFreeSpace := syscall.MustLoadDLL("kernel32.dll").MustFindProc("GetDiskFreeSpaceExW")
 
 	// Get the available bytes (for arguments, GetDiskFreeSpace function takes dir name, avail, total, and free respectively)
-	_, _, err = getDiskFreeSpace.Call(
-		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(wd))),
+	r1, _, e1 := getDiskFreeSpace.Call(
+		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))),
 		uintptr(unsafe.Pointer(&availBytes)),
 		uintptr(unsafe.Pointer(&totalBytes)),
 		uintptr(unsafe.Pointer(&freeBytes)))
+	if r1 == 0 {
+		err = e1
+		return
+	}
 
 	return DiskSpaceInfo{
 		AvailBytes: availBytes,
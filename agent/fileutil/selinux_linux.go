@@ -0,0 +1,63 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package fileutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+// selinuxEnforcePath holds 1 when SELinux is enforcing and 0 when it is permissive, it is missing when SELinux is disabled
+const selinuxEnforcePath = "/sys/fs/selinux/enforce"
+
+// readSELinuxEnforce reads the SELinux enforcement of the instance, it is stubbed in the tests
+var readSELinuxEnforce = func() ([]byte, error) {
+	return ioutil.ReadFile(selinuxEnforcePath)
+}
+
+// restorecon resets the SELinux labels of the path to the labels of the policy, it is stubbed in the tests
+var restorecon = func(path string) ([]byte, error) {
+	return exec.Command("restorecon", "-R", path).CombinedOutput()
+}
+
+// SELinuxMode returns the SELinux mode of the instance
+func SELinuxMode() string {
+	enforce, err := readSELinuxEnforce()
+	if err != nil {
+		return SELinuxDisabled
+	}
+	if strings.TrimSpace(string(enforce)) == "1" {
+		return SELinuxEnforcing
+	}
+	return SELinuxPermissive
+}
+
+// RestoreSELinuxLabel resets the SELinux labels of the path and its content to the labels of the policy.
+// A file keeps its label when it is renamed, a file created in a temporary folder and moved under the agent
+// folders would be denied to the agent and the workers in enforcing mode.
+func RestoreSELinuxLabel(path string) error {
+	if SELinuxMode() == SELinuxDisabled {
+		return nil
+	}
+	if output, err := restorecon(path); err != nil {
+		return fmt.Errorf("failed to restore the SELinux label of %v: %v, %v", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
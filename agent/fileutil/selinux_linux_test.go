@@ -0,0 +1,71 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSELinux stubs the SELinux enforcement of the instance and records the relabeled paths
+func stubSELinux(t *testing.T, enforce string, restoreErr error) *[]string {
+	readOrig, restoreconOrig := readSELinuxEnforce, restorecon
+	t.Cleanup(func() { readSELinuxEnforce, restorecon = readOrig, restoreconOrig })
+	readSELinuxEnforce = func() ([]byte, error) {
+		if enforce == "" {
+			return nil, os.ErrNotExist
+		}
+		return []byte(enforce), nil
+	}
+	var relabeled []string
+	restorecon = func(path string) ([]byte, error) {
+		relabeled = append(relabeled, path)
+		if restoreErr != nil {
+			return []byte("restorecon: permission denied"), restoreErr
+		}
+		return nil, nil
+	}
+	return &relabeled
+}
+
+func TestSELinuxMode(t *testing.T) {
+	stubSELinux(t, "1\n", nil)
+	assert.Equal(t, SELinuxEnforcing, SELinuxMode())
+	stubSELinux(t, "0\n", nil)
+	assert.Equal(t, SELinuxPermissive, SELinuxMode())
+	stubSELinux(t, "", nil)
+	assert.Equal(t, SELinuxDisabled, SELinuxMode())
+}
+
+func TestRestoreSELinuxLabel(t *testing.T) {
+	relabeled := stubSELinux(t, "1", nil)
+	assert.NoError(t, RestoreSELinuxLabel("/var/lib/amazon/ssm/download"))
+	assert.Equal(t, []string{"/var/lib/amazon/ssm/download"}, *relabeled)
+
+	relabeled = stubSELinux(t, "", nil)
+	assert.NoError(t, RestoreSELinuxLabel("/var/lib/amazon/ssm/download"))
+	assert.Empty(t, *relabeled)
+
+	stubSELinux(t, "1", errors.New("exit status 1"))
+	err := RestoreSELinuxLabel("/var/lib/amazon/ssm/download")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+}
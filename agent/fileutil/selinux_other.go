@@ -0,0 +1,28 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package fileutil
+
+// SELinuxMode returns disabled, SELinux is only available on Linux
+func SELinuxMode() string {
+	return SELinuxDisabled
+}
+
+// RestoreSELinuxLabel does nothing, SELinux is only available on Linux
+func RestoreSELinuxLabel(path string) error {
+	return nil
+}
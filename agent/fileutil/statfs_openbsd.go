@@ -0,0 +1,23 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fileutil
+
+import "syscall"
+
+// statfsBlocks returns the block size and the available, free and total block counts of the file system statistics,
+// the struct statfs fields are prefixed with F_ on OpenBSD
+func statfsBlocks(stat *syscall.Statfs_t) (bSize, bAvail, bFree, blocks uint64) {
+	return uint64(stat.F_bsize), uint64(stat.F_bavail), uint64(stat.F_bfree), uint64(stat.F_blocks)
+}
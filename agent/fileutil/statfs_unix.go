@@ -0,0 +1,26 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd
+// +build darwin freebsd linux netbsd
+
+package fileutil
+
+import "syscall"
+
+// statfsBlocks returns the block size and the available, free and total block counts of the file system statistics
+func statfsBlocks(stat *syscall.Statfs_t) (bSize, bAvail, bFree, blocks uint64) {
+	// On Linux the struct statfs.f_bavail field is unsigned, but on FreeBSD the field is an int64
+	return uint64(stat.Bsize), uint64(stat.Bavail), uint64(stat.Bfree), uint64(stat.Blocks)
+}
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build freebsd || linux || netbsd || openbsd || darwin
 // +build freebsd linux netbsd openbsd darwin
 
 // Package fingerprint contains functions that helps identify an instance
@@ -0,0 +1,24 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build boringcrypto
+// +build boringcrypto
+
+package fips
+
+// fipsonly restricts the TLS configs of the process to the FIPS-approved settings
+import _ "crypto/tls/fipsonly"
+
+// backendValidated is true, the BoringCrypto module is FIPS-validated, it is stubbed in the tests
+var backendValidated = true
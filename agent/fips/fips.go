@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package fips implements the FIPS mode of the agent, which restricts the TLS connections of every client and the
+// digests of the checksums to FIPS-approved algorithms. The mode requires an agent built with the BoringCrypto
+// backend (GOEXPERIMENT=boringcrypto), the agent refuses to start in FIPS mode with the Go crypto.
+package fips
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// EnvironmentVariable passes the FIPS mode of the agent to its worker processes, its name is the appconfig
+// override of the Fips.Enabled setting
+const EnvironmentVariable = "SSM_AGENT_FIPS_ENABLED"
+
+// enabled is 1 once the FIPS mode is configured
+var enabled int32
+
+// approvedCipherSuites are the FIPS-approved TLS 1.2 cipher suites
+var approvedCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// approvedCurves are the FIPS-approved elliptic curves of the key exchange
+var approvedCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// approvedDigests are the FIPS-approved digests of the checksums, by lowercase name
+var approvedDigests = map[string]bool{
+	"sha256": true,
+	"sha384": true,
+	"sha512": true,
+}
+
+// Configure turns the FIPS mode on for the process and its worker processes, it fails when the agent
+// is not built with a FIPS-validated crypto backend
+func Configure(fipsMode bool) error {
+	if !fipsMode {
+		return nil
+	}
+	if !backendValidated {
+		return fmt.Errorf("FIPS mode requires an agent built with the BoringCrypto backend, this agent uses the Go %v crypto", runtime.Version())
+	}
+	atomic.StoreInt32(&enabled, 1)
+	// the clients without their own transport use the default transport
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.TLSClientConfig = TLSConfig(transport.TLSClientConfig)
+	}
+	return os.Setenv(EnvironmentVariable, "true")
+}
+
+// ConfigureWorker turns the FIPS mode on for a worker process started by an agent in FIPS mode
+func ConfigureWorker() error {
+	return Configure(strings.EqualFold(os.Getenv(EnvironmentVariable), "true"))
+}
+
+// Enabled returns true when the process runs in FIPS mode
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// TLSConfig restricts the TLS config to TLS 1.2 with the FIPS-approved cipher suites and curves in FIPS mode,
+// a nil config is replaced by a restricted one. The config is returned unchanged outside of FIPS mode.
+// TLS 1.3 is excluded since its cipher suites cannot be restricted.
+func TLSConfig(config *tls.Config) *tls.Config {
+	if !Enabled() {
+		return config
+	}
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	config.MinVersion = tls.VersionTLS12
+	config.MaxVersion = tls.VersionTLS12
+	config.CipherSuites = approvedCipherSuites
+	config.CurvePreferences = approvedCurves
+	return config
+}
+
+// ApprovedDigest returns false for the digests of the checksums not approved in FIPS mode, every digest is
+// approved outside of FIPS mode
+func ApprovedDigest(algorithm string) bool {
+	return !Enabled() || approvedDigests[strings.ToLower(algorithm)]
+}
+
+// ContentHash returns the base64 digest identifying the content uploaded to the service, SHA-256 in FIPS mode
+// and MD5 otherwise, which keeps the content hashes of the instances outside of FIPS mode unchanged
+func ContentHash(data []byte) string {
+	if Enabled() {
+		sum := sha256.Sum256(data)
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
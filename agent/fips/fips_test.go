@@ -0,0 +1,88 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fips
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubBackend sets whether the crypto backend is FIPS-validated and restores the mode of the process after the test
+func stubBackend(t *testing.T, validated bool) {
+	validatedOrig := backendValidated
+	transport := http.DefaultTransport.(*http.Transport)
+	tlsConfigOrig := transport.TLSClientConfig
+	t.Cleanup(func() {
+		backendValidated = validatedOrig
+		transport.TLSClientConfig = tlsConfigOrig
+		atomic.StoreInt32(&enabled, 0)
+		os.Unsetenv(EnvironmentVariable)
+	})
+	backendValidated = validated
+}
+
+func TestConfigure_Disabled(t *testing.T) {
+	stubBackend(t, false)
+	assert.NoError(t, Configure(false))
+	assert.False(t, Enabled())
+
+	config := &tls.Config{ServerName: "ssm.us-east-1.amazonaws.com"}
+	assert.Equal(t, config, TLSConfig(config))
+	assert.Nil(t, TLSConfig(nil))
+	assert.True(t, ApprovedDigest("md5"))
+	assert.Equal(t, "rL0Y20zC+Fzt72VPzMSk2A==", ContentHash([]byte("foo")))
+}
+
+func TestConfigure_BackendNotValidated(t *testing.T) {
+	stubBackend(t, false)
+	assert.Error(t, Configure(true))
+	assert.False(t, Enabled())
+	assert.Equal(t, "", os.Getenv(EnvironmentVariable))
+}
+
+func TestConfigure_Enabled(t *testing.T) {
+	stubBackend(t, true)
+	assert.NoError(t, Configure(true))
+	assert.True(t, Enabled())
+	assert.Equal(t, "true", os.Getenv(EnvironmentVariable))
+
+	config := TLSConfig(&tls.Config{InsecureSkipVerify: true})
+	assert.True(t, config.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MaxVersion)
+	assert.Equal(t, approvedCipherSuites, config.CipherSuites)
+	assert.Equal(t, approvedCurves, config.CurvePreferences)
+	assert.Equal(t, approvedCipherSuites, http.DefaultTransport.(*http.Transport).TLSClientConfig.CipherSuites)
+
+	assert.False(t, ApprovedDigest("md5"))
+	assert.False(t, ApprovedDigest("sha1"))
+	assert.True(t, ApprovedDigest("SHA256"))
+	assert.Equal(t, "LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564=", ContentHash([]byte("foo")))
+}
+
+func TestConfigureWorker(t *testing.T) {
+	stubBackend(t, true)
+	assert.NoError(t, ConfigureWorker())
+	assert.False(t, Enabled())
+
+	os.Setenv(EnvironmentVariable, "true")
+	assert.NoError(t, ConfigureWorker())
+	assert.True(t, Enabled())
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package firehose sends the command output and the session transcripts to a Kinesis Data Firehose delivery stream,
+// one JSON record per line carrying the partition keys of the line.
+package firehose
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+)
+
+// Streams of the records
+const (
+	StreamStdout     = "stdout"
+	StreamStderr     = "stderr"
+	StreamTranscript = "transcript"
+)
+
+// Partition keys of the records
+const (
+	KeyInstanceID = "instanceId"
+	KeyCommandID  = "commandId"
+	KeySessionID  = "sessionId"
+	KeyPluginName = "pluginName"
+	KeyPluginID   = "pluginId"
+	KeyStream     = "stream"
+)
+
+const (
+	// maxLineSize is the size the longer lines are split at, below the 1000 KiB limit of a record
+	maxLineSize = 64 * 1024
+
+	// maxBatchBytes is the size of the records of a call, below the 4 MiB limit of PutRecordBatch
+	maxBatchBytes = 4 * 1000 * 1024
+
+	// maxAttempts is the number of calls sending the records Firehose failed to put
+	maxAttempts = 3
+)
+
+// timeNow returns the current time, it is stubbed in the tests
+var timeNow = time.Now
+
+// newClient creates the Firehose client, it is stubbed in the tests
+var newClient = func(appConfig appconfig.SsmagentConfig) firehoseiface.FirehoseAPI {
+	config := sdkutil.AwsConfig()
+	config = request.WithRetryer(config, retryer.New(firehose.ServiceName))
+	if appConfig.Firehose.Endpoint != "" {
+		config.Endpoint = &appConfig.Firehose.Endpoint
+	} else if defaultEndpoint := appconfig.GetDefaultEndPoint(aws.StringValue(config.Region), "firehose"); defaultEndpoint != "" {
+		config.Endpoint = &defaultEndpoint
+	}
+	proxyconfig.ConfigureAwsProxy(log.DefaultLogger(), config, appConfig.Firehose.Proxy)
+
+	sess := session.New(config)
+	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
+	return firehose.New(sess)
+}
+
+// CommandOutputEnabled returns whether the output of the command plugins is sent to the delivery stream
+func CommandOutputEnabled(cfg appconfig.FirehoseCfg) bool {
+	return cfg.DeliveryStream != "" && cfg.CommandOutput
+}
+
+// TranscriptsEnabled returns whether the transcripts of the shell sessions are sent to the delivery stream
+func TranscriptsEnabled(cfg appconfig.FirehoseCfg) bool {
+	return cfg.DeliveryStream != "" && cfg.SessionTranscripts
+}
+
+// PartitionKeys returns the partition keys configured for every record along with the keys of a plugin output
+func PartitionKeys(cfg appconfig.FirehoseCfg, keys map[string]string) map[string]string {
+	partitionKeys := make(map[string]string, len(cfg.PartitionKeys)+len(keys))
+	for key, value := range cfg.PartitionKeys {
+		partitionKeys[key] = value
+	}
+	for key, value := range keys {
+		partitionKeys[key] = value
+	}
+	return partitionKeys
+}
+
+// SendFile sends the lines of a file to the delivery stream in batches, each line in a record with the partition keys,
+// the empty lines are skipped
+func SendFile(log log.T, deliveryStream string, partitionKeys map[string]string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	appConfig, _ := appconfig.Config(false)
+	sender := &batchSender{
+		client:         newClient(appConfig),
+		deliveryStream: deliveryStream,
+		batchSize:      appConfig.Firehose.BatchSize,
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	scanner.Split(scanLines)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			if err = sender.add(record(partitionKeys, line)); err != nil {
+				return err
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if err = sender.flush(); err != nil {
+		return err
+	}
+	log.Debugf("Sent %v records of %v to delivery stream %v", sender.sent, path, deliveryStream)
+	return nil
+}
+
+// record returns the JSON record of a line, the partition keys are top-level fields of the record
+func record(partitionKeys map[string]string, line string) []byte {
+	fields := make(map[string]string, len(partitionKeys)+2)
+	for key, value := range partitionKeys {
+		fields[key] = value
+	}
+	fields["time"] = timeNow().UTC().Format(time.RFC3339Nano)
+	fields["line"] = line
+	data, _ := json.Marshal(fields)
+	return append(data, '\n')
+}
+
+// batchSender puts the records in batches of the batch size
+type batchSender struct {
+	client         firehoseiface.FirehoseAPI
+	deliveryStream string
+	batchSize      int
+	records        []*firehose.Record
+	bytes          int
+	sent           int
+}
+
+// add adds a record to the batch, the batch is sent first when the record does not fit in it
+func (s *batchSender) add(data []byte) error {
+	if len(s.records) > 0 && (len(s.records) >= s.batchSize || s.bytes+len(data) > maxBatchBytes) {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	s.records = append(s.records, &firehose.Record{Data: data})
+	s.bytes += len(data)
+	return nil
+}
+
+// flush sends the batch, the records Firehose failed to put are sent again
+func (s *batchSender) flush() error {
+	records := s.records
+	s.records, s.bytes = nil, 0
+	for attempt := 1; len(records) > 0; attempt++ {
+		output, err := s.client.PutRecordBatch(&firehose.PutRecordBatchInput{
+			DeliveryStreamName: aws.String(s.deliveryStream),
+			Records:            records,
+		})
+		if err != nil {
+			return err
+		}
+		var failed []*firehose.Record
+		if aws.Int64Value(output.FailedPutCount) > 0 {
+			for i, response := range output.RequestResponses {
+				if response.ErrorCode != nil && i < len(records) {
+					failed = append(failed, records[i])
+				}
+			}
+		}
+		s.sent += len(records) - len(failed)
+		if len(failed) > 0 && attempt >= maxAttempts {
+			return fmt.Errorf("failed to put %v records in delivery stream %v", len(failed), s.deliveryStream)
+		}
+		records = failed
+	}
+	return nil
+}
+
+// scanLines splits the lines like bufio.ScanLines and the lines longer than maxLineSize in several lines
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if advance, token, err = bufio.ScanLines(data, atEOF); advance == 0 && token == nil && len(data) >= maxLineSize {
+		return maxLineSize, data[:maxLineSize], nil
+	}
+	return
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firehose
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient records the batches and fails the records of the first batches
+type fakeClient struct {
+	firehoseiface.FirehoseAPI
+	batches       [][]*firehose.Record
+	failedBatches int
+	err           error
+}
+
+func (c *fakeClient) PutRecordBatch(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.batches = append(c.batches, input.Records)
+	output := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}
+	for i := range input.Records {
+		response := &firehose.PutRecordBatchResponseEntry{RecordId: aws.String("record")}
+		if c.failedBatches > 0 && i == 0 {
+			response = &firehose.PutRecordBatchResponseEntry{ErrorCode: aws.String("ServiceUnavailableException")}
+			output.FailedPutCount = aws.Int64(1)
+		}
+		output.RequestResponses = append(output.RequestResponses, response)
+	}
+	if c.failedBatches > 0 {
+		c.failedBatches--
+	}
+	return output, nil
+}
+
+func stubClient(client *fakeClient) func() {
+	originalClient, originalTimeNow := newClient, timeNow
+	newClient = func(appConfig appconfig.SsmagentConfig) firehoseiface.FirehoseAPI { return client }
+	timeNow = func() time.Time { return time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC) }
+	return func() { newClient, timeNow = originalClient, originalTimeNow }
+}
+
+func writeFile(t *testing.T, content string) (path string, remove func()) {
+	dir, err := ioutil.TempDir("", "firehose")
+	assert.NoError(t, err)
+	path = filepath.Join(dir, "stdout")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestEnabled(t *testing.T) {
+	cfg := appconfig.FirehoseCfg{CommandOutput: true, SessionTranscripts: true}
+	assert.False(t, CommandOutputEnabled(cfg))
+	assert.False(t, TranscriptsEnabled(cfg))
+	cfg.DeliveryStream = "ssm-output"
+	assert.True(t, CommandOutputEnabled(cfg))
+	assert.True(t, TranscriptsEnabled(cfg))
+}
+
+func TestPartitionKeys(t *testing.T) {
+	cfg := appconfig.FirehoseCfg{PartitionKeys: map[string]string{"environment": "prod", KeyStream: "configured"}}
+	keys := PartitionKeys(cfg, map[string]string{KeyCommandID: "command-id", KeyStream: StreamStdout})
+	assert.Equal(t, map[string]string{"environment": "prod", KeyCommandID: "command-id", KeyStream: StreamStdout}, keys)
+}
+
+func TestSendFile(t *testing.T) {
+	client := &fakeClient{}
+	defer stubClient(client)()
+	path, remove := writeFile(t, "first line\n\nsecond line\n")
+	defer remove()
+
+	err := SendFile(log.NewMockLog(), "ssm-output", map[string]string{KeyCommandID: "command-id", KeyStream: StreamStdout}, path)
+	assert.NoError(t, err)
+	assert.Len(t, client.batches, 1)
+	assert.Len(t, client.batches[0], 2)
+	assert.True(t, strings.HasSuffix(string(client.batches[0][0].Data), "\n"))
+	var fields map[string]string
+	assert.NoError(t, json.Unmarshal(client.batches[0][1].Data, &fields))
+	assert.Equal(t, map[string]string{
+		KeyCommandID: "command-id",
+		KeyStream:    StreamStdout,
+		"time":       "2019-06-01T12:00:00Z",
+		"line":       "second line",
+	}, fields)
+}
+
+func TestBatchSender_SplitsBatches(t *testing.T) {
+	client := &fakeClient{}
+	sender := &batchSender{client: client, deliveryStream: "ssm-output", batchSize: 2}
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sender.add([]byte("record")))
+	}
+	assert.NoError(t, sender.flush())
+	assert.Len(t, client.batches, 3)
+	assert.Len(t, client.batches[2], 1)
+	assert.Equal(t, 5, sender.sent)
+}
+
+func TestBatchSender_RetriesFailedRecords(t *testing.T) {
+	client := &fakeClient{failedBatches: 1}
+	sender := &batchSender{client: client, deliveryStream: "ssm-output", batchSize: 10}
+	assert.NoError(t, sender.add([]byte("first")))
+	assert.NoError(t, sender.add([]byte("second")))
+	assert.NoError(t, sender.flush())
+	assert.Len(t, client.batches, 2)
+	assert.Equal(t, "first", string(client.batches[1][0].Data))
+	assert.Equal(t, 2, sender.sent)
+
+	client = &fakeClient{failedBatches: maxAttempts}
+	sender = &batchSender{client: client, deliveryStream: "ssm-output", batchSize: 10}
+	assert.NoError(t, sender.add([]byte("first")))
+	assert.Error(t, sender.flush())
+	assert.Len(t, client.batches, maxAttempts)
+}
+
+func TestBatchSender_Error(t *testing.T) {
+	sender := &batchSender{client: &fakeClient{err: errors.New("access denied")}, deliveryStream: "ssm-output", batchSize: 10}
+	assert.NoError(t, sender.add([]byte("record")))
+	assert.Error(t, sender.flush())
+}
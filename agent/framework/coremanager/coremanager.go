@@ -30,13 +30,20 @@ import (
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 )
 
 const (
 	rebootPollingInterval = time.Second
 	hardStopTimeout       = time.Second * 5
+	// drainGracePeriod is the time given past the drain timeout to cancel the documents and send the results
+	drainGracePeriod = time.Second * 15
 )
 
+// recordAgentStarted reports the start of the agent to the updater verifying an update
+var recordAgentStarted = updateutil.RecordAgentStarted
+
 type ICoreManager interface {
 	// Start executes the registered core modules
 	Start()
@@ -63,13 +70,6 @@ func NewCoreManager(context context.T, mr coremodules.ModuleRegistry, cwp *cloud
 		}
 	}
 
-	var region string
-	if region, err = platform.Region(); err != nil {
-		log.Errorf("error fetching the region, %v", err)
-		return
-	}
-	log.Debug("Using region:", region)
-
 	// initialize instance ID
 	if *instanceIdPtr != "" {
 		if err = platform.SetInstanceID(*instanceIdPtr); err != nil {
@@ -78,6 +78,16 @@ func NewCoreManager(context context.T, mr coremodules.ModuleRegistry, cwp *cloud
 		}
 	}
 
+	// fetch the instance identity in parallel, the values are cached for the calls below
+	prefetchIdentity()
+
+	var region string
+	if region, err = platform.Region(); err != nil {
+		log.Errorf("error fetching the region, %v", err)
+		return
+	}
+	log.Debug("Using region:", region)
+
 	var instanceId string
 	if instanceId, err = platform.InstanceID(); err != nil {
 		log.Errorf("error fetching the instanceID, %v", err)
@@ -96,10 +106,19 @@ func NewCoreManager(context context.T, mr coremodules.ModuleRegistry, cwp *cloud
 		return
 	}
 
+	// remove the temporary directories of the plugins whose process stopped while running them
+	tempdir.Sweep(log,
+		filepath.Join(appconfig.DefaultDataStorePath, instanceId, appconfig.DefaultDocumentRootDirName, context.AppConfig().Agent.OrchestrationRootDir),
+		filepath.Join(appconfig.DefaultDataStorePath, instanceId, appconfig.DefaultSessionRootDirName, context.AppConfig().Agent.OrchestrationRootDir))
+	tempdir.SweepSessions(log)
+
 	// Initialize the client diagnostics
 	cwp.Init(log)
 	context = context.With("[instanceID=" + instanceId + "]")
-	runpluginutil.SSMPluginRegistry = plugin.RegisteredWorkerPlugins(context)
+	// the plugins are only registered when a document runs in the agent process, most run in the document worker
+	runpluginutil.SetSSMPluginRegistryLoader(func() runpluginutil.PluginRegistry {
+		return plugin.RegisteredWorkerPlugins(context)
+	})
 
 	return &CoreManager{
 		context:             context,
@@ -109,6 +128,19 @@ func NewCoreManager(context context.T, mr coremodules.ModuleRegistry, cwp *cloud
 	}, nil
 }
 
+// prefetchIdentity fetches the region and the instance ID in parallel
+func prefetchIdentity() {
+	var wg sync.WaitGroup
+	for _, fetch := range []func() (string, error){platform.Region, platform.InstanceID} {
+		wg.Add(1)
+		go func(fetch func() (string, error)) {
+			defer wg.Done()
+			fetch()
+		}(fetch)
+	}
+	wg.Wait()
+}
+
 // initializeBookkeepingLocations - initializes all folder locations required for bookkeeping
 func initializeBookkeepingLocations(log logger.T, instanceID string) bool {
 
@@ -227,12 +259,37 @@ func initializeBookkeepingLocations(log logger.T, instanceID string) bool {
 func (c *CoreManager) Start() {
 	go c.watchForReboot()
 	c.executeCoreModules()
+	recordAgentStarted(c.context.Log())
 }
 
 // Stop requests the core modules to stop executing
-// Stop would be called by the agent and should be treated as hard stop
+// Stop would be called by the agent, the core modules are drained up to the drain timeout of appconfig,
+// it is treated as hard stop when there is no drain timeout
 func (c *CoreManager) Stop() {
-	c.stopCoreModules(contracts.StopTypeHardStop)
+	drainTimeout := time.Duration(c.context.AppConfig().Agent.DrainTimeoutSeconds) * time.Second
+	if drainTimeout <= 0 {
+		c.stopCoreModules(contracts.StopTypeHardStop)
+		return
+	}
+	c.drainCoreModules(drainTimeout)
+}
+
+// drainCoreModules soft stops the core modules, which stop accepting new messages, let the in-flight documents
+// finish up to the drain timeout and send their results, the work still running after the grace period is abandoned
+func (c *CoreManager) drainCoreModules(drainTimeout time.Duration) {
+	log := c.context.Log()
+	log.Infof("Draining the in-flight work for up to %v", drainTimeout)
+	drained := make(chan bool)
+	go func() {
+		c.stopCoreModules(contracts.StopTypeSoftStop)
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Info("Drained the in-flight work")
+	case <-time.After(drainTimeout + drainGracePeriod):
+		log.Warnf("The in-flight work did not drain within %v, stopping", drainTimeout+drainGracePeriod)
+	}
 }
 
 // executeCoreModules launches all the core modules
@@ -260,9 +317,11 @@ func (c *CoreManager) stopCoreModules(stopType contracts.StopType) {
 	var wg sync.WaitGroup
 	l := len(c.coreModules)
 	for i := 0; i < l; i++ {
+		if stopType == contracts.StopTypeSoftStop {
+			wg.Add(1)
+		}
 		go func(wgc *sync.WaitGroup, i int) {
 			if stopType == contracts.StopTypeSoftStop {
-				wgc.Add(1)
 				defer wgc.Done()
 			}
 
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	moduleMock "github.com/aws/amazon-ssm-agent/agent/contracts/mocks"
@@ -60,6 +61,7 @@ func (suite *CoreManagerTestSuite) SetupTest() {
 		rebooter:            rebootMock,
 	}
 	suite.coreManager = cm
+	recordAgentStarted = func(log.T) {}
 	suite.moduleMock.On("ModuleRequestStop", mock.Anything).Return(nil)
 	suite.moduleMock.On("ModuleExecute", mock.Anything).Return(nil)
 	suite.moduleMock.On("ModuleName").Return("TestExecuteModule")
@@ -133,6 +135,20 @@ func (suite *CoreManagerTestSuite) TestCoreManager_Stop() {
 	suite.moduleMock.AssertNotCalled(suite.T(), "ModuleName")
 }
 
+// Stop() soft stops the core modules when a drain timeout is configured
+func (suite *CoreManagerTestSuite) TestCoreManager_Stop_Drain() {
+	config := appconfig.SsmagentConfig{}
+	config.Agent.DrainTimeoutSeconds = 1
+	contextMock := new(context.Mock)
+	contextMock.On("Log").Return(suite.logMock)
+	contextMock.On("AppConfig").Return(config)
+	suite.coreManager.(*CoreManager).context = contextMock
+
+	suite.coreManager.Stop()
+	suite.moduleMock.AssertCalled(suite.T(), "ModuleRequestStop", contracts.StopTypeSoftStop)
+	suite.moduleMock.AssertNotCalled(suite.T(), "ModuleRequestStop", contracts.StopTypeHardStop)
+}
+
 func TestCoreManagerTestSuite(t *testing.T) {
 	suite.Run(t, new(CoreManagerTestSuite))
 }
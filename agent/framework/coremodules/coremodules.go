@@ -15,14 +15,33 @@
 package coremodules
 
 import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/apparmor"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/configreload"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/eventlog"
 	"github.com/aws/amazon-ssm-agent/agent/health"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
+	"github.com/aws/amazon-ssm-agent/agent/localapi"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/aws/amazon-ssm-agent/agent/profiling"
+	"github.com/aws/amazon-ssm-agent/agent/resourcelimits"
 	"github.com/aws/amazon-ssm-agent/agent/runcommand"
+	"github.com/aws/amazon-ssm-agent/agent/securityevents"
+	"github.com/aws/amazon-ssm-agent/agent/selfmonitor"
 	"github.com/aws/amazon-ssm-agent/agent/session"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
 	"github.com/aws/amazon-ssm-agent/agent/startup"
+	"github.com/aws/amazon-ssm-agent/agent/systemd"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
+	"github.com/aws/amazon-ssm-agent/agent/webhook"
 )
 
 // ModuleRegistry stores a set of core modules.
@@ -39,28 +58,165 @@ func RegisteredCoreModules(context context.T) *ModuleRegistry {
 	return &registeredCoreModules
 }
 
-// register core modules here
-func loadCoreModules(context context.T) {
-	registeredCoreModules = append(registeredCoreModules, health.NewHealthCheck(context, ssm.NewService()))
-	registeredCoreModules = append(registeredCoreModules, runcommand.NewMDSService(context))
-	sessionCoreModule := session.NewSession(context)
-	if sessionCoreModule != nil {
-		registeredCoreModules = append(registeredCoreModules, sessionCoreModule)
-	}
+// moduleConstructor creates a core module, it returns nil when the core module is not available
+type moduleConstructor func(context context.T) contracts.ICoreModule
 
-	if offlineProcessor, err := runcommand.NewOfflineService(context); err == nil {
-		registeredCoreModules = append(registeredCoreModules, offlineProcessor)
-	} else {
+// coreModuleConstructors create the core modules in their registration order
+var coreModuleConstructors = []moduleConstructor{
+	func(context context.T) contracts.ICoreModule {
+		return health.NewHealthCheck(context, ssm.NewService())
+	},
+	func(context context.T) contracts.ICoreModule {
+		return runcommand.NewMDSService(context)
+	},
+	func(context context.T) contracts.ICoreModule {
+		if sessionCoreModule := session.NewSession(context); sessionCoreModule != nil {
+			return sessionCoreModule
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if offlineProcessor, err := runcommand.NewOfflineService(context); err == nil {
+			return offlineProcessor
+		}
 		context.Log().Errorf("Failed to start offline command document processor")
-	}
-
-	registeredCoreModules = append(registeredCoreModules, startup.NewProcessor(context))
-
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		return startup.NewProcessor(context)
+	},
+	func(context context.T) contracts.ICoreModule {
+		if auditLog := audit.NewAuditLog(context); auditLog != nil {
+			return auditLog
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if eventLogChannel := eventlog.NewChannel(context); eventLogChannel != nil {
+			return eventLogChannel
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if securityEventsWriter := securityevents.NewWriter(context); securityEventsWriter != nil {
+			return securityEventsWriter
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if webhookNotifier := webhook.NewNotifier(context); webhookNotifier != nil {
+			return webhookNotifier
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if selfCheck := integrity.NewSelfCheck(context); selfCheck != nil {
+			return selfCheck
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if metricsServer := metrics.NewServer(context); metricsServer != nil {
+			return metricsServer
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if metricsPublisher := metrics.NewPublisher(context); metricsPublisher != nil {
+			return metricsPublisher
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if healthEndpoint := healthendpoint.NewServer(context); healthEndpoint != nil {
+			return healthEndpoint
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		return healthendpoint.NewStatusServer(context)
+	},
+	func(context context.T) contracts.ICoreModule {
+		if profilingServer := profiling.NewServer(context); profilingServer != nil {
+			return profilingServer
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if localAPI := localapi.NewServer(context); localAPI != nil {
+			return localAPI
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if notifier := systemd.NewNotifier(context, healthendpoint.Connected); notifier != nil {
+			return notifier
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if tracingExporter := tracing.NewExporter(context); tracingExporter != nil {
+			return tracingExporter
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if watchdogModule := watchdog.NewWatchdog(context); watchdogModule != nil {
+			return watchdogModule
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if selfMonitor := selfmonitor.NewSelfMonitor(context); selfMonitor != nil {
+			return selfMonitor
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		return configreload.NewConfigReload(context)
+	},
+	func(context context.T) contracts.ICoreModule {
+		if appArmorLoader := apparmor.NewLoader(context); appArmorLoader != nil {
+			return appArmorLoader
+		}
+		return nil
+	},
+	func(context context.T) contracts.ICoreModule {
+		if resourceLimits := resourcelimits.NewResourceLimits(context); resourceLimits != nil {
+			return resourceLimits
+		}
+		return nil
+	},
 	// registering the long running plugin manager as a core module
-	manager.EnsureInitialization(context)
-	if lrpm, err := manager.GetInstance(); err == nil {
-		registeredCoreModules = append(registeredCoreModules, lrpm)
-	} else {
+	func(context context.T) contracts.ICoreModule {
+		manager.EnsureInitialization(context)
+		if lrpm, err := manager.GetInstance(); err == nil {
+			return lrpm
+		}
 		context.Log().Errorf("Something went wrong during initialization of long running plugin manager")
+		return nil
+	},
+}
+
+// register core modules here, the core modules are created in parallel since their
+// service clients and state are independent, which shortens the agent startup
+func loadCoreModules(context context.T) {
+	start := time.Now()
+	modules := make([]contracts.ICoreModule, len(coreModuleConstructors))
+	var wg sync.WaitGroup
+	for i, constructor := range coreModuleConstructors {
+		wg.Add(1)
+		go func(i int, constructor moduleConstructor) {
+			defer wg.Done()
+			modules[i] = constructor(context)
+		}(i, constructor)
+	}
+	wg.Wait()
+
+	for _, module := range modules {
+		if module != nil {
+			registeredCoreModules = append(registeredCoreModules, module)
+		}
 	}
+	context.Log().Debugf("Loaded %v core modules in %v", len(registeredCoreModules), time.Since(start))
 }
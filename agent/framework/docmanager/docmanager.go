@@ -23,6 +23,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/diskguard"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -42,9 +43,9 @@ type DocumentMgr interface {
 	RemoveDocumentState(log log.T, fileName, instanceID, locationFolder string)
 }
 
-//TODO use class lock instead of global lock?
-//TODO decouple the DocState model to better fit the service-processor-executer architecture
-//DocumentFileMgr encapsulate the file access and perform bookkeeping operations at the specified file location
+// TODO use class lock instead of global lock?
+// TODO decouple the DocState model to better fit the service-processor-executer architecture
+// DocumentFileMgr encapsulate the file access and perform bookkeeping operations at the specified file location
 type DocumentFileMgr struct {
 	dataStorePath string
 	rootDirName   string
@@ -95,6 +96,9 @@ func (d *DocumentFileMgr) PersistDocumentState(log log.T, fileName, instanceID,
 	} else {
 		if fileutil.Exists(absoluteFileName) {
 			log.Debugf("overwriting contents of %v", absoluteFileName)
+		} else if err = diskguard.Check(absoluteFileName); err != nil {
+			log.Errorf("not persisting interim state in %v: %v", locationFolder, err)
+			return
 		}
 		log.Tracef("persisting interim state %v in file %v", jsonutil.Indent(content), absoluteFileName)
 		if s, err := fileutil.WriteIntoFileWithPermissions(absoluteFileName, jsonutil.Indent(content), os.FileMode(int(appconfig.ReadWriteAccess))); s && err == nil {
@@ -143,7 +147,7 @@ func (d *DocumentFileMgr) RemoveDocumentState(log log.T, commandID, instanceID,
 	}
 }
 
-//TODO rework this part
+// TODO rework this part
 // DocumentStateDir returns absolute filename where command states are persisted
 func DocumentStateDir(instanceID, locationFolder string) string {
 	return filepath.Join(appconfig.DefaultDataStorePath,
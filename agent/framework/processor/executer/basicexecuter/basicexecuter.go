@@ -26,7 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
-//TODO currently BasicExecuter.Run() is not idempotent, we should make it so in future
+// TODO currently BasicExecuter.Run() is not idempotent, we should make it so in future
 // BasicExecuter is a thin wrapper over runPlugins().
 type BasicExecuter struct {
 	resChan chan contracts.DocumentResult
@@ -37,7 +37,7 @@ var pluginRunner = func(context context.T,
 	docState contracts.DocumentState,
 	resChan chan contracts.PluginResult,
 	cancelFlag task.CancelFlag) (pluginOutputs map[string]*contracts.PluginResult) {
-	return runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.SSMPluginRegistry, resChan, cancelFlag)
+	return runpluginutil.RunPlugins(context, docState.InstancePluginsInformation, docState.IOConfig, runpluginutil.RegisteredSSMPlugins(), resChan, cancelFlag)
 
 }
 
@@ -21,8 +21,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
-//Executer accepts an DocumentStore object, save when necessary for crash-recovery, and return when finshes the run, while
-//the caller will pick up the result from the same docStore object
+// Executer accepts an DocumentStore object, save when necessary for crash-recovery, and return when finshes the run, while
+// the caller will pick up the result from the same docStore object
 type Executer interface {
 	//TODO in future, docState should be de-composed into static/dynamic && plugin/document informations
 	// Given a document and run it, receiving results from status update channel, return a map of plugin results
@@ -30,14 +30,14 @@ type Executer interface {
 		docStore DocumentStore) chan contracts.DocumentResult
 }
 
-//DocumentStore is an wrapper over the document state class that provides additional persisting functions for the Executer
+// DocumentStore is an wrapper over the document state class that provides additional persisting functions for the Executer
 type DocumentStore interface {
 	Save(contracts.DocumentState)
 	Load() contracts.DocumentState
 }
 
-//TODO need to refactor global lock in docmanager, or discard the entire package and impl the file IO here
-//DocumentFileStore dependent on the current file functions in docmanager to provide file save/load operations
+// TODO need to refactor global lock in docmanager, or discard the entire package and impl the file IO here
+// DocumentFileStore dependent on the current file functions in docmanager to provide file save/load operations
 type DocumentFileStore struct {
 	context     context.T
 	state       contracts.DocumentState
@@ -58,7 +58,7 @@ func NewDocumentFileStore(context context.T, instID, docID, location string, sta
 	}
 }
 
-//Save the document info struct to the current folder, Save() is desired only for crash-recovery
+// Save the document info struct to the current folder, Save() is desired only for crash-recovery
 func (f *DocumentFileStore) Save(docState contracts.DocumentState) {
 	log := f.context.Log()
 	//copy the state struct
@@ -71,7 +71,7 @@ func (f *DocumentFileStore) Save(docState contracts.DocumentState) {
 	return
 }
 
-//Load() should happen in memory
+// Load() should happen in memory
 func (f *DocumentFileStore) Load() contracts.DocumentState {
 	return f.state
 }
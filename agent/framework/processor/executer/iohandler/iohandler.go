@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/firehose"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/iomodule"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -154,6 +155,7 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OutputS3KeyPrefix:      s3KeyPrefix,
 		LogGroupName:           out.ioConfig.CloudWatchConfig.LogGroupName,
 		LogStreamName:          stdOutLogStreamName,
+		FirehoseConfig:         firehoseConfig(out.ioConfig.FirehoseConfig, firehose.StreamStdout),
 	}
 
 	// Initialize console output module
@@ -176,6 +178,7 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OutputS3KeyPrefix:      s3KeyPrefix,
 		LogGroupName:           out.ioConfig.CloudWatchConfig.LogGroupName,
 		LogStreamName:          stdErrLogStreamName,
+		FirehoseConfig:         firehoseConfig(out.ioConfig.FirehoseConfig, firehose.StreamStderr),
 	}
 
 	// Initialize console error module
@@ -191,6 +194,18 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 	out.RegisterOutputSource(log, out.StderrWriter, stderrFile, stderrConsole)
 }
 
+// firehoseConfig returns the Firehose configuration of an output stream, the stream is a partition key of its records
+func firehoseConfig(config contracts.FirehoseConfiguration, stream string) contracts.FirehoseConfiguration {
+	if config.DeliveryStreamName == "" {
+		return config
+	}
+	partitionKeys := map[string]string{firehose.KeyStream: stream}
+	for key, value := range config.PartitionKeys {
+		partitionKeys[key] = value
+	}
+	return contracts.FirehoseConfiguration{DeliveryStreamName: config.DeliveryStreamName, PartitionKeys: partitionKeys}
+}
+
 // RegisterOutputSource returns a new output source by creating a multiwriter for the output modules.
 func (out *DefaultIOHandler) RegisterOutputSource(log log.T, multiWriter multiwriter.DocumentIOMultiWriter, IOModules ...iomodule.IOModule) {
 	if len(IOModules) == 0 {
@@ -174,3 +174,17 @@ func TestAppendFormat(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), testStringFormatted)
 	assert.Contains(t, output.GetStderr(), testStringFormatted)
 }
+
+func TestFirehoseConfig(t *testing.T) {
+	assert.Equal(t, contracts.FirehoseConfiguration{}, firehoseConfig(contracts.FirehoseConfiguration{}, "stdout"))
+
+	config := contracts.FirehoseConfiguration{
+		DeliveryStreamName: "ssm-output",
+		PartitionKeys:      map[string]string{"commandId": "command-id"},
+	}
+	assert.Equal(t, contracts.FirehoseConfiguration{
+		DeliveryStreamName: "ssm-output",
+		PartitionKeys:      map[string]string{"commandId": "command-id", "stream": "stderr"},
+	}, firehoseConfig(config, "stderr"))
+	assert.Len(t, config.PartitionKeys, 1)
+}
@@ -22,8 +22,12 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/firehose"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/outputencryption"
+	"github.com/aws/amazon-ssm-agent/agent/outputfailover"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 )
 
@@ -31,7 +35,7 @@ const (
 	maxCloudWatchUploadRetry = 5
 )
 
-// File handles writing to an output file and upload to s3 and cloudWatch
+// File handles writing to an output file and upload to s3, cloudWatch and Firehose
 type File struct {
 	FileName               string
 	OrchestrationDirectory string
@@ -39,9 +43,10 @@ type File struct {
 	OutputS3KeyPrefix      string
 	LogGroupName           string
 	LogStreamName          string
+	FirehoseConfig         contracts.FirehoseConfiguration
 }
 
-// Read reads from the stream and writes to the output file, s3 and CloudWatchLogs.
+// Read reads from the stream and writes to the output file, s3, CloudWatchLogs and Firehose.
 func (file File) Read(log log.T, reader *io.PipeReader) {
 	defer func() { reader.Close() }()
 
@@ -63,8 +68,11 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 
 	defer fileWriter.Close()
 
+	// the encrypted output is streamed once complete, its plaintext never reaches CloudWatch Logs
+	encryptionConfig := outputEncryptionConfig()
+	encrypted := outputencryption.Enabled(encryptionConfig)
 	cwl := cloudwatchlogspublisher.NewCloudWatchLogsService()
-	if file.LogGroupName != "" {
+	if file.LogGroupName != "" && !encrypted {
 		log.Debugf("Received CloudWatch Configs: LogGroupName: %s\n, LogStreamName: %s\n", file.LogGroupName, file.LogStreamName)
 		//Start CWL logging on different go routine
 		go cwl.StreamData(log, file.LogGroupName, file.LogStreamName, filePath, false, false)
@@ -90,14 +98,49 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 		return
 	}
 
+	// The encrypted copies of the output are sent in its place, nothing is sent when the encryption fails
+	s3Path, cloudWatchPath := filePath, filePath
+	if encrypted && (file.OutputS3BucketName != "" || file.LogGroupName != "") {
+		if s3Path, cloudWatchPath, err = outputencryption.OutputFiles(log, encryptionConfig, filePath); err != nil {
+			log.Errorf("Failed to encrypt the output, it is not sent to s3 and CloudWatchLogs: %v", err)
+		}
+	}
+
 	// Upload output file to S3
-	if file.OutputS3BucketName != "" && fi.Size() > 0 {
+	if file.OutputS3BucketName != "" && fi.Size() > 0 && s3Path != "" {
 		s3Key := fileutil.BuildS3Path(file.OutputS3KeyPrefix, file.FileName)
-		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, filePath); err != nil {
+		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, s3Path); err != nil {
 			log.Errorf("Failed to upload the output to s3: %v", err)
+			if failoverConfig := outputFailoverConfig(); outputfailover.CloudWatchEnabled(failoverConfig) {
+				if err = outputfailover.ToCloudWatch(log, failoverConfig, s3Key, cloudWatchPath); err != nil {
+					log.Errorf("Failed to send the output to the failover log group: %v", err)
+				}
+			}
 		}
 	}
 
+	// Send output file to Firehose
+	if file.FirehoseConfig.DeliveryStreamName != "" && fi.Size() > 0 {
+		if err := firehose.SendFile(log, file.FirehoseConfig.DeliveryStreamName, file.FirehoseConfig.PartitionKeys, filePath); err != nil {
+			log.Errorf("Failed to send the output to Firehose: %v", err)
+		}
+	}
+
+	// Stream the encrypted output now it is complete
+	if file.LogGroupName != "" && encrypted {
+		if cloudWatchPath == "" || fi.Size() == 0 {
+			return
+		}
+		if failoverConfig := outputFailoverConfig(); !outputfailover.S3Enabled(failoverConfig) {
+			cwl.StreamData(log, file.LogGroupName, file.LogStreamName, cloudWatchPath, true, false)
+		} else if !outputfailover.StreamFile(log, failoverConfig, cwl, file.LogGroupName, file.LogStreamName, cloudWatchPath) {
+			if err = outputfailover.ToS3(log, failoverConfig, file.LogStreamName, s3Path); err != nil {
+				log.Errorf("Failed to upload the output to the failover bucket: %v", err)
+			}
+		}
+		return
+	}
+
 	//Block main thread until CloudWatchLogs uploading is complete or until maxCloudWatchUploadRetry is reached
 	//TODO Add unit test to test maxRetry logic
 	if file.LogGroupName != "" {
@@ -107,5 +150,24 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 			retry++
 			time.Sleep(cloudwatchlogspublisher.UploadFrequency)
 		}
+		if !cwl.IsUploadComplete {
+			if failoverConfig := outputFailoverConfig(); outputfailover.S3Enabled(failoverConfig) {
+				if err = outputfailover.ToS3(log, failoverConfig, file.LogStreamName, filePath); err != nil {
+					log.Errorf("Failed to upload the output to the failover bucket: %v", err)
+				}
+			}
+		}
 	}
 }
+
+// outputEncryptionConfig returns the client-side encryption of the output
+func outputEncryptionConfig() appconfig.OutputEncryptionCfg {
+	appConfig, _ := appconfig.Config(false)
+	return appConfig.OutputEncryption
+}
+
+// outputFailoverConfig returns the secondary destination of the output
+func outputFailoverConfig() appconfig.OutputFailoverCfg {
+	appConfig, _ := appconfig.Config(false)
+	return appConfig.OutputFailover
+}
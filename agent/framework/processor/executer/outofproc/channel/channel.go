@@ -20,7 +20,7 @@ const (
 
 type Mode string
 
-//Channel is defined as a persistent interface for raw json datagram transmission, it is designed to adopt both file ad named pipe
+// Channel is defined as a persistent interface for raw json datagram transmission, it is designed to adopt both file ad named pipe
 type Channel interface {
 	//send a raw json datagram to the channel, return when send is "complete" -- message is dropped to the persistent layer
 	Send(string) error
@@ -32,9 +32,19 @@ type Channel interface {
 	Destroy()
 }
 
-//find the folder named as "documentID" under the default root dir
-//if not found, create a new filechannel under the default root dir
-//return the channel and the found flag
+// CreateChannel creates the channel over the IPC transport of the agent config, the named pipes or the files
+// return the channel and the found flag
+func CreateChannel(log log.T, mode Mode, name string) (Channel, error, bool) {
+	config, _ := appconfig.Config(false)
+	if config.Ipc.Transport == appconfig.IpcTransportNamedPipe {
+		return CreateNamedPipeChannel(log, mode, name, config.Ipc.PipeSecurityDescriptor)
+	}
+	return CreateFileChannel(log, mode, name)
+}
+
+// find the folder named as "documentID" under the default root dir
+// if not found, create a new filechannel under the default root dir
+// return the channel and the found flag
 func CreateFileChannel(log log.T, mode Mode, filename string) (Channel, error, bool) {
 	instanceID, err := platform.InstanceID()
 	if err != nil {
@@ -29,7 +29,7 @@ const (
 	consumeRetryIntervalInMilliseconds = 10
 )
 
-//TODO add unittest
+// TODO add unittest
 type fileWatcherChannel struct {
 	logger        log.T
 	path          string
@@ -68,6 +68,13 @@ func NewFileWatcherChannel(logger log.T, mode Mode, name string) (*fileWatcherCh
 		//if err occurs, the channel is not healthy anymore, should return false
 		return nil, err
 	}
+	//a directory created beforehand by another user would let that user drop messages in the channel
+	for _, dir := range []string{name, tmpPath} {
+		if err := verifyChannelDirectory(dir); err != nil {
+			logger.Errorf("refusing to use the channel directory: %v", err)
+			return nil, err
+		}
+	}
 
 	//buffered channel in order not to block listener
 	onMessageChan := make(chan string, defaultChannelBufferSize)
@@ -110,10 +117,9 @@ func createIfNotExist(dir string) (err error) {
 }
 
 /*
-	drop a file in the destination path with the file name as sequence id
-	the file is first named as tmp, then quickly renamed to guarantee atomicity
-	sequence id format: {mode}-{command start time}-{counter} , squence id is guaranteed to be ascending order
-
+drop a file in the destination path with the file name as sequence id
+the file is first named as tmp, then quickly renamed to guarantee atomicity
+sequence id format: {mode}-{command start time}-{counter} , squence id is guaranteed to be ascending order
 */
 func (ch *fileWatcherChannel) Send(rawJson string) error {
 	if ch.closed {
@@ -183,9 +189,9 @@ func (ch *fileWatcherChannel) Close() {
 	return
 }
 
-//parse the counter out of the sequence id, return -1 if parsing fails
-//counter is defined as the padding last element of - separated integer
-//On windows, path.Base() does not work
+// parse the counter out of the sequence id, return -1 if parsing fails
+// counter is defined as the padding last element of - separated integer
+// On windows, path.Base() does not work
 func parseSequenceCounter(filepath string) int {
 	_, name := path.Split(filepath)
 	parts := strings.Split(name, "-")
@@ -196,8 +202,8 @@ func parseSequenceCounter(filepath string) int {
 	return int(counter)
 }
 
-//read all messages in the consuming dir, with order guarantees -- ioutil.ReadDir() sort by name, and name is the lexicographical ascending sequence id.
-//filter out its own sent messages and tmp messages
+// read all messages in the consuming dir, with order guarantees -- ioutil.ReadDir() sort by name, and name is the lexicographical ascending sequence id.
+// filter out its own sent messages and tmp messages
 func (ch *fileWatcherChannel) consumeAll() {
 	ch.logger.Debug("consuming all the messages under: ", ch.path)
 	fileInfos, _ := ioutil.ReadDir(ch.path)
@@ -211,7 +217,7 @@ func (ch *fileWatcherChannel) consumeAll() {
 	}
 }
 
-//TODO add unittest
+// TODO add unittest
 func (ch *fileWatcherChannel) isReadable(filename string) bool {
 	matched, err := regexp.MatchString("[a-zA-Z]+-[0-9]+-[0-9]+", filename)
 	if !matched || err != nil {
@@ -220,7 +226,7 @@ func (ch *fileWatcherChannel) isReadable(filename string) bool {
 	return !strings.Contains(filename, string(ch.mode)) && !strings.Contains(filename, "tmp")
 }
 
-//read and remove a given file
+// read and remove a given file
 func (ch *fileWatcherChannel) consume(filepath string) {
 	log := ch.logger
 	log.Debugf("consuming message under path: %v", filepath)
@@ -228,6 +234,13 @@ func (ch *fileWatcherChannel) consume(filepath string) {
 	var buf []byte
 	var err error
 
+	//only the agent and its workers can send messages, the messages of other users are dropped
+	if info, err := os.Lstat(filepath); err == nil && !trustedSender(info) {
+		log.Warnf("dropping message %v, it was not sent by the agent", filepath)
+		os.Remove(filepath)
+		return
+	}
+
 	for attempt := 0; attempt < consumeAttemptCount; attempt++ {
 		//On windows rename does not guarantee atomic access: https://github.com/golang/go/issues/8914
 		//In exclusive mode we have, this read will for sure fail when it's locked by the other end
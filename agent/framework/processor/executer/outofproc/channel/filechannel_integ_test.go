@@ -11,9 +11,10 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build integration
 // +build integration
 
-//Package channel defines and implements the communication interface between agent and command runner process
+// Package channel defines and implements the communication interface between agent and command runner process
 package channel
 
 import (
@@ -63,7 +64,7 @@ func TestChannelDuplexTransmission(t *testing.T) {
 
 }
 
-//agent channel is reopened, and starts receiving only after re-open
+// agent channel is reopened, and starts receiving only after re-open
 func TestChannelReopen(t *testing.T) {
 	done := make(chan bool)
 	agentChannel, err := NewFileWatcherChannel(log.NewMockLogWithContext("AGENT"), ModeMaster, path.Join(defaultRootDir, channelName))
@@ -94,7 +95,7 @@ func TestChannelReopen(t *testing.T) {
 	newAgentChannel.Destroy()
 }
 
-//verify the given set of messages are received
+// verify the given set of messages are received
 func verifyReceive(t *testing.T, ch Channel, messages []string, name string, done chan bool) {
 
 	//timer := time.After(5 * time.Second)
@@ -107,7 +108,7 @@ func verifyReceive(t *testing.T, ch Channel, messages []string, name string, don
 	done <- true
 }
 
-//send a given set of messages
+// send a given set of messages
 func send(ch Channel, messages []string, name string) {
 
 	for _, testMsg := range messages {
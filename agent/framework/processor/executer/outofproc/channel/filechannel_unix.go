@@ -0,0 +1,53 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package channel
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// othersWriteMask is the permissions letting the group or the other users write to a file
+const othersWriteMask os.FileMode = 0022
+
+// verifyChannelDirectory fails when the channel directory is not a directory owned by the agent user,
+// or when other users can write to it
+func verifyChannelDirectory(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%v is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("%v is owned by the user %v instead of the agent user %v", dir, stat.Uid, os.Geteuid())
+	}
+	if info.Mode().Perm()&othersWriteMask != 0 {
+		return fmt.Errorf("%v is writable by other users, its mode is %v", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// trustedSender returns true when the message file is a regular file owned by the agent user,
+// the workers run as the agent user
+func trustedSender(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return info.Mode().IsRegular() && ok && int(stat.Uid) == os.Geteuid()
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package channel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyChannelDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "channel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	channelDir := filepath.Join(dir, "channel")
+	assert.NoError(t, os.Mkdir(channelDir, 0700))
+	assert.NoError(t, verifyChannelDirectory(channelDir))
+
+	// other users could drop messages in the channel
+	assert.NoError(t, os.Chmod(channelDir, 0777))
+	assert.Error(t, verifyChannelDirectory(channelDir))
+	assert.NoError(t, os.Chmod(channelDir, 0755))
+	assert.NoError(t, verifyChannelDirectory(channelDir))
+
+	// a link could redirect the channel to a directory of another user
+	link := filepath.Join(dir, "link")
+	assert.NoError(t, os.Symlink(channelDir, link))
+	assert.Error(t, verifyChannelDirectory(link))
+
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	assert.Error(t, verifyChannelDirectory(file))
+
+	assert.Error(t, verifyChannelDirectory(filepath.Join(dir, "missing")))
+}
+
+func TestVerifyChannelDirectory_OtherOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a directory requires root")
+	}
+	dir, err := ioutil.TempDir("", "channel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Chown(dir, 1234, 1234))
+
+	assert.Error(t, verifyChannelDirectory(dir))
+}
+
+func TestTrustedSender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "channel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	message := filepath.Join(dir, "message")
+	assert.NoError(t, ioutil.WriteFile(message, []byte("{}"), 0600))
+	link := filepath.Join(dir, "link")
+	assert.NoError(t, os.Symlink(message, link))
+
+	for path, trusted := range map[string]bool{message: true, link: false, dir: false} {
+		info, err := os.Lstat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, trusted, trustedSender(info), path)
+	}
+
+	if os.Geteuid() == 0 {
+		assert.NoError(t, os.Chown(message, 1234, 1234))
+		info, err := os.Lstat(message)
+		assert.NoError(t, err)
+		assert.False(t, trustedSender(info))
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package channel
+
+import "os"
+
+// verifyChannelDirectory does nothing, the ACL of the data folder of the agent only lets the administrators write to it
+func verifyChannelDirectory(dir string) error {
+	return nil
+}
+
+// trustedSender returns true for regular files, the ACL of the data folder of the agent only lets the administrators write to it
+func trustedSender(info os.FileInfo) bool {
+	return info.Mode().IsRegular()
+}
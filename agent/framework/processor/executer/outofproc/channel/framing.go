@@ -0,0 +1,53 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package channel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxFrameSize is the largest message the stream channels accept, a larger length means a corrupted stream
+const maxFrameSize = 64 * 1024 * 1024
+
+// writeFrame writes the message to the stream with its length on 4 bytes in little-endian first
+func writeFrame(w io.Writer, message string) error {
+	if len(message) > maxFrameSize {
+		return fmt.Errorf("message of %v bytes exceeds the %v bytes limit", len(message), maxFrameSize)
+	}
+	frame := make([]byte, 4+len(message))
+	binary.LittleEndian.PutUint32(frame, uint32(len(message)))
+	copy(frame[4:], message)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads the next message written by writeFrame from the stream
+func readFrame(r io.Reader) (string, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", err
+	}
+	size := binary.LittleEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return "", fmt.Errorf("frame of %v bytes exceeds the %v bytes limit", size, maxFrameSize)
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return "", err
+	}
+	return string(message), nil
+}
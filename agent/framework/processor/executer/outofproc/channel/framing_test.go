@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package channel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrames(t *testing.T) {
+	var stream bytes.Buffer
+	messages := []string{`{"version":"1.0"}`, "", strings.Repeat("x", 100000)}
+	for _, message := range messages {
+		assert.NoError(t, writeFrame(&stream, message))
+	}
+	for _, message := range messages {
+		read, err := readFrame(&stream)
+		assert.NoError(t, err)
+		assert.Equal(t, message, read)
+	}
+	_, err := readFrame(&stream)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadFrame_Truncated(t *testing.T) {
+	var stream bytes.Buffer
+	assert.NoError(t, writeFrame(&stream, "message"))
+	_, err := readFrame(bytes.NewReader(stream.Bytes()[:stream.Len()-1]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestReadFrame_Oversized(t *testing.T) {
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint32(header, maxFrameSize+1)
+	_, err := readFrame(bytes.NewReader(header))
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package channel
+
+import (
+	"errors"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// CreateNamedPipeChannel returns an error, the named pipe channels are only available on Windows
+func CreateNamedPipeChannel(logger log.T, mode Mode, name string, securityDescriptor string) (Channel, error, bool) {
+	return nil, errors.New("named pipe channels are only available on Windows"), false
+}
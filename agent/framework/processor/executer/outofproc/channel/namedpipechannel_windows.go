@@ -0,0 +1,459 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package channel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// pipePrefix is the prefix of the named pipes of the worker channels
+	pipePrefix     = `\\.\pipe\amazon-ssm-agent-`
+	pipeBufferSize = 64 * 1024
+	// pipeConnectRetryInterval is the time the master waits before connecting again to the pipe of its worker
+	pipeConnectRetryInterval = 100 * time.Millisecond
+	// pipeWaitMilliseconds is the time the pipe operations wait before checking whether they are canceled
+	pipeWaitMilliseconds = 100
+
+	pipeAccessDuplex               = 0x3
+	fileFlagFirstPipeInstance      = 0x80000
+	pipeRejectRemoteClients        = 0x8
+	securitySqosPresent            = 0x100000
+	securityIdentification         = 0x10000
+	sddlRevision1                  = 1
+	processQueryLimitedInformation = 0x1000
+
+	errorPipeNotConnected = syscall.Errno(233)
+	errorPipeConnected    = syscall.Errno(535)
+)
+
+var (
+	pipeKernel32                    = syscall.NewLazyDLL("kernel32.dll")
+	pipeAdvapi32                    = syscall.NewLazyDLL("advapi32.dll")
+	createNamedPipeProc             = pipeKernel32.NewProc("CreateNamedPipeW")
+	connectNamedPipeProc            = pipeKernel32.NewProc("ConnectNamedPipe")
+	disconnectNamedPipeProc         = pipeKernel32.NewProc("DisconnectNamedPipe")
+	waitNamedPipeProc               = pipeKernel32.NewProc("WaitNamedPipeW")
+	getNamedPipeClientProcessIdProc = pipeKernel32.NewProc("GetNamedPipeClientProcessId")
+	getNamedPipeServerProcessIdProc = pipeKernel32.NewProc("GetNamedPipeServerProcessId")
+	createEventProc                 = pipeKernel32.NewProc("CreateEventW")
+	getOverlappedResultProc         = pipeKernel32.NewProc("GetOverlappedResult")
+	convertSecurityDescriptorProc   = pipeAdvapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+)
+
+// namedPipeChannel is a channel over a named pipe. The worker creates the pipe and the master connects to it, so that
+// a restarted master finds the pipe of a running worker. Only the accounts of the security descriptor of the pipe can
+// open it, both ends only talk to a peer running as the same user, and the master only lets the worker identify it.
+// Unlike the file channel, the messages are kept in memory until they are written to the pipe.
+type namedPipeChannel struct {
+	logger log.T
+	mode   Mode
+	path   string
+	// handle is the pipe instance of the worker, it is 0 on the master
+	handle        syscall.Handle
+	onMessageChan chan string
+	sendChan      chan string
+	// unsent is the message the last connection broke on, only the run go-routine uses it
+	unsent []string
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+}
+
+// pipeConn is a connection of a channel to its peer
+type pipeConn struct {
+	handle syscall.Handle
+	// server is true for the pipe instance of the worker, which is disconnected rather than closed
+	server bool
+	ch     *namedPipeChannel
+	done   chan struct{}
+	once   sync.Once
+}
+
+// CreateNamedPipeChannel creates the channel over the named pipe of the name, the worker creates the pipe with
+// the security descriptor in SDDL. The found flag is true when the master finds the pipe of a running worker.
+func CreateNamedPipeChannel(logger log.T, mode Mode, name string, securityDescriptor string) (Channel, error, bool) {
+	ch := &namedPipeChannel{
+		logger:        logger,
+		mode:          mode,
+		path:          pipePrefix + name,
+		onMessageChan: make(chan string, defaultChannelBufferSize),
+		sendChan:      make(chan string, defaultChannelBufferSize),
+		stop:          make(chan struct{}),
+	}
+	found := false
+	if mode == ModeWorker {
+		handle, err := createPipe(ch.path, securityDescriptor)
+		if err != nil {
+			logger.Errorf("failed to create pipe %v: %v", ch.path, err)
+			return nil, err, false
+		}
+		ch.handle = handle
+	} else {
+		found = pipeExists(ch.path)
+	}
+	ch.wg.Add(1)
+	go ch.run()
+	return ch, nil, found
+}
+
+// Send queues the message, it is written to the pipe once the peer is connected
+func (ch *namedPipeChannel) Send(rawJson string) error {
+	if ch.isClosed() {
+		return errors.New("channel already closed")
+	}
+	select {
+	case ch.sendChan <- rawJson:
+		return nil
+	case <-ch.stop:
+		return errors.New("channel already closed")
+	}
+}
+
+func (ch *namedPipeChannel) GetMessage() <-chan string {
+	return ch.onMessageChan
+}
+
+// Close stops the channel, non-blocking call, the go channel of the messages is closed once the connection is released
+func (ch *namedPipeChannel) Close() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return
+	}
+	log := ch.logger
+	log.Infof("channel %v requested close", ch.path)
+	ch.closed = true
+	close(ch.stop)
+	go func() {
+		ch.wg.Wait()
+		if ch.handle != 0 {
+			syscall.CloseHandle(ch.handle)
+		}
+		close(ch.onMessageChan)
+		log.Infof("channel %v closed", ch.path)
+	}()
+}
+
+// Destroy closes the channel, the pipe disappears with the handle of the worker
+func (ch *namedPipeChannel) Destroy() {
+	ch.Close()
+}
+
+func (ch *namedPipeChannel) isClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.closed
+}
+
+// run connects the channel to its peer and exchanges the messages, it connects again when the connection breaks
+func (ch *namedPipeChannel) run() {
+	defer ch.wg.Done()
+	log := ch.logger
+	log.Debugf("%v listener started on pipe: %v", ch.mode, ch.path)
+	for !ch.isClosed() {
+		conn, err := ch.connect()
+		if err != nil {
+			log.Debugf("channel %v failed to connect: %v", ch.path, err)
+			select {
+			case <-ch.stop:
+				return
+			case <-time.After(pipeConnectRetryInterval):
+			}
+			continue
+		}
+		log.Debugf("channel %v connected", ch.path)
+		ch.serve(conn)
+		conn.Close()
+	}
+}
+
+// connect waits for the master on the worker and opens the pipe on the master, it returns an error when
+// the peer does not run as the same user
+func (ch *namedPipeChannel) connect() (*pipeConn, error) {
+	if ch.mode == ModeWorker {
+		_, err := pipeIO(ch.handle, ch.isClosed, func(overlapped *syscall.Overlapped) error {
+			if rc, _, ec := connectNamedPipeProc.Call(uintptr(ch.handle), uintptr(unsafe.Pointer(overlapped))); rc == 0 {
+				return ec
+			}
+			return nil
+		})
+		if err != nil && err != errorPipeConnected {
+			return nil, err
+		}
+		if err = verifyPeer(ch.handle, getNamedPipeClientProcessIdProc); err != nil {
+			ch.logger.Warnf("rejecting the client of pipe %v: %v", ch.path, err)
+			disconnectNamedPipeProc.Call(uintptr(ch.handle))
+			return nil, err
+		}
+		return newPipeConn(ch, ch.handle, true), nil
+	}
+
+	handle, err := openPipe(ch.path)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyPeer(handle, getNamedPipeServerProcessIdProc); err != nil {
+		ch.logger.Warnf("refusing the server of pipe %v: %v", ch.path, err)
+		syscall.CloseHandle(handle)
+		return nil, err
+	}
+	return newPipeConn(ch, handle, false), nil
+}
+
+// serve writes the queued messages to the connection and receives the messages of the peer until the connection
+// breaks or the channel closes
+func (ch *namedPipeChannel) serve(conn *pipeConn) {
+	received := make(chan error, 1)
+	go func() {
+		received <- ch.receive(conn)
+	}()
+	for {
+		if len(ch.unsent) == 0 {
+			select {
+			case message := <-ch.sendChan:
+				ch.unsent = append(ch.unsent, message)
+			case err := <-received:
+				ch.logger.Debugf("channel %v disconnected: %v", ch.path, err)
+				return
+			case <-ch.stop:
+				<-received
+				return
+			}
+		}
+		if err := writeFrame(conn, ch.unsent[0]); err != nil {
+			ch.logger.Debugf("channel %v failed to write: %v", ch.path, err)
+			conn.cancel()
+			<-received
+			return
+		}
+		ch.unsent = ch.unsent[:0]
+	}
+}
+
+// receive reads the messages of the peer until the connection breaks
+func (ch *namedPipeChannel) receive(conn *pipeConn) error {
+	reader := bufio.NewReaderSize(conn, pipeBufferSize)
+	for {
+		message, err := readFrame(reader)
+		if err != nil {
+			return err
+		}
+		select {
+		case ch.onMessageChan <- message:
+		case <-ch.stop:
+			return errors.New("channel closed")
+		}
+	}
+}
+
+func newPipeConn(ch *namedPipeChannel, handle syscall.Handle, server bool) *pipeConn {
+	return &pipeConn{handle: handle, server: server, ch: ch, done: make(chan struct{})}
+}
+
+// canceled returns true once the connection or its channel is closed
+func (c *pipeConn) canceled() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return c.ch.isClosed()
+	}
+}
+
+func (c *pipeConn) Read(b []byte) (int, error) {
+	done, err := pipeIO(c.handle, c.canceled, func(overlapped *syscall.Overlapped) error {
+		return syscall.ReadFile(c.handle, b, nil, overlapped)
+	})
+	if err == syscall.ERROR_BROKEN_PIPE || err == errorPipeNotConnected {
+		return int(done), io.EOF
+	}
+	return int(done), err
+}
+
+func (c *pipeConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		done, err := pipeIO(c.handle, c.canceled, func(overlapped *syscall.Overlapped) error {
+			return syscall.WriteFile(c.handle, b[written:], nil, overlapped)
+		})
+		written += int(done)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// cancel cancels the pending operations of the connection
+func (c *pipeConn) cancel() {
+	c.once.Do(func() {
+		close(c.done)
+		syscall.CancelIoEx(c.handle, nil)
+	})
+}
+
+// Close cancels the pending operations, it disconnects the worker from the master and closes the pipe on the master
+func (c *pipeConn) Close() error {
+	c.cancel()
+	if c.server {
+		disconnectNamedPipeProc.Call(uintptr(c.handle))
+		return nil
+	}
+	return syscall.CloseHandle(c.handle)
+}
+
+// pipeIO starts the overlapped operation on the handle and waits for it, the operation is canceled once
+// canceled returns true. It returns the number of bytes transferred.
+func pipeIO(handle syscall.Handle, canceled func() bool, operation func(*syscall.Overlapped) error) (uint32, error) {
+	event, _, ec := createEventProc.Call(0, 1, 0, 0)
+	if event == 0 {
+		return 0, ec
+	}
+	defer syscall.CloseHandle(syscall.Handle(event))
+
+	overlapped := syscall.Overlapped{HEvent: syscall.Handle(event)}
+	if err := operation(&overlapped); err != nil && err != syscall.ERROR_IO_PENDING {
+		return 0, err
+	}
+	for {
+		result, err := syscall.WaitForSingleObject(overlapped.HEvent, pipeWaitMilliseconds)
+		if err != nil {
+			return 0, err
+		}
+		if result != syscall.WAIT_TIMEOUT {
+			break
+		}
+		if canceled() {
+			syscall.CancelIoEx(handle, &overlapped)
+			break
+		}
+	}
+	var done uint32
+	if rc, _, ec := getOverlappedResultProc.Call(uintptr(handle), uintptr(unsafe.Pointer(&overlapped)), uintptr(unsafe.Pointer(&done)), 1); rc == 0 {
+		return done, ec
+	}
+	return done, nil
+}
+
+// createPipe creates the only instance of the pipe, it fails when the pipe already exists so that no other
+// process can serve it, and it rejects the remote clients
+func createPipe(path string, securityDescriptor string) (syscall.Handle, error) {
+	sddl, err := syscall.UTF16PtrFromString(securityDescriptor)
+	if err != nil {
+		return 0, err
+	}
+	var descriptor uintptr
+	if rc, _, ec := convertSecurityDescriptorProc.Call(uintptr(unsafe.Pointer(sddl)), sddlRevision1, uintptr(unsafe.Pointer(&descriptor)), 0); rc == 0 {
+		return 0, fmt.Errorf("invalid pipe security descriptor %v: %v", securityDescriptor, ec)
+	}
+	defer syscall.LocalFree(syscall.Handle(descriptor))
+
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	attributes := syscall.SecurityAttributes{SecurityDescriptor: descriptor}
+	attributes.Length = uint32(unsafe.Sizeof(attributes))
+	handle, _, ec := createNamedPipeProc.Call(
+		uintptr(unsafe.Pointer(name)),
+		pipeAccessDuplex|syscall.FILE_FLAG_OVERLAPPED|fileFlagFirstPipeInstance,
+		pipeRejectRemoteClients,
+		1,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		uintptr(unsafe.Pointer(&attributes)))
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		return 0, ec
+	}
+	return syscall.Handle(handle), nil
+}
+
+// openPipe opens the pipe of the worker at the identification level, the worker cannot impersonate the master
+func openPipe(path string) (syscall.Handle, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	return syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING,
+		syscall.FILE_FLAG_OVERLAPPED|securitySqosPresent|securityIdentification, 0)
+}
+
+// pipeExists returns true when a process serves the pipe
+func pipeExists(path string) bool {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	if rc, _, ec := waitNamedPipeProc.Call(uintptr(unsafe.Pointer(name)), 1); rc == 0 {
+		return ec != syscall.ERROR_FILE_NOT_FOUND
+	}
+	return true
+}
+
+// verifyPeer returns an error unless the process at the other end of the pipe runs as the same user as the agent,
+// the proc gets the process id of the peer
+func verifyPeer(handle syscall.Handle, proc *syscall.LazyProc) error {
+	var pid uint32
+	if rc, _, ec := proc.Call(uintptr(handle), uintptr(unsafe.Pointer(&pid))); rc == 0 {
+		return ec
+	}
+	peer, err := processUser(pid)
+	if err != nil {
+		return fmt.Errorf("failed to get the user of process %v: %v", pid, err)
+	}
+	own, err := processUser(uint32(os.Getpid()))
+	if err != nil {
+		return err
+	}
+	if peer != own {
+		return fmt.Errorf("process %v runs as %v rather than %v", pid, peer, own)
+	}
+	return nil
+}
+
+// processUser returns the SID of the user of the process
+func processUser(pid uint32) (string, error) {
+	process, err := syscall.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer syscall.CloseHandle(process)
+	var token syscall.Token
+	if err = syscall.OpenProcessToken(process, syscall.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	return user.User.Sid.String()
+}
@@ -11,7 +11,8 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-//+build integration
+//go:build integration
+// +build integration
 
 // Package outofproc implements Executer interface with out-of-process plugin running capabilities
 package outofproc
@@ -56,7 +57,7 @@ func setup(t *testing.T) *TestCase {
 		t.Fatalf("process already exists: %v", fakeProcess)
 	}
 	fakeProcess = NewFakeProcess(t)
-	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+	processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
 		//fakeProcess is imposed as singleton here
 		if fakeProcess.live {
 			t.Fatalf("start process repeatedly, already exists: %v", fakeProcess)
@@ -149,7 +150,7 @@ func TestOutOfProcExecuter_Success(t *testing.T) {
 	teardown(t)
 }
 
-//TODO test Zombie and Orphan child separately
+// TODO test Zombie and Orphan child separately
 func TestOutOfProcExecuter_ShutdownAndReconnect(t *testing.T) {
 	testCase := setup(t)
 	docState1 := testCase.docState
@@ -334,7 +335,7 @@ func NewFakeProcess(t *testing.T) *FakeProcess {
 	}
 }
 
-//replicate the same procedure as the worker main function
+// replicate the same procedure as the worker main function
 func (p *FakeProcess) fakeWorker(t *testing.T, handle string) {
 	ctx := context.NewMockDefaultWithContext([]string{"FAKE-DOCUMENT-WORKER"})
 	log := ctx.Log()
@@ -355,8 +356,8 @@ func (p *FakeProcess) fakeWorker(t *testing.T, handle string) {
 	p.exitChan <- true
 }
 
-//Make the process to become an orphan when parent dies
-//In reality, Wait() is transferred to OS daemon. In our test cases, Wait() is held by the old Executer so we need to fail the new Executer's Wait() call
+// Make the process to become an orphan when parent dies
+// In reality, Wait() is transferred to OS daemon. In our test cases, Wait() is held by the old Executer so we need to fail the new Executer's Wait() call
 func (p *FakeProcess) detach() {
 	p.attached = false
 }
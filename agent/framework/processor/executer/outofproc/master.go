@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"fmt"
+	"os"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -13,13 +14,18 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/aws/amazon-ssm-agent/agent/processaudit"
+	"github.com/aws/amazon-ssm-agent/agent/resourcelimits"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 )
 
 type Backend messaging.MessagingBackend
 
-//see differences between zombie and orphan: https://www.gmarik.info/blog/2012/orphan-vs-zombie-vs-daemon-processes/
+// see differences between zombie and orphan: https://www.gmarik.info/blog/2012/orphan-vs-zombie-vs-daemon-processes/
 const (
 	//TODO prolong this value once we go to production
 	defaultZombieProcessTimeout = 3 * time.Second
@@ -32,10 +38,12 @@ type OutOfProcExecuter struct {
 	docState   *contracts.DocumentState
 	ctx        context.T
 	cancelFlag task.CancelFlag
+	// processAudit records the processes started by the session worker
+	processAudit *processaudit.Session
 }
 
 var channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
-	return channel.CreateFileChannel(log, mode, documentID)
+	return channel.CreateChannel(log, mode, documentID)
 }
 
 var processFinder = func(log log.T, procinfo contracts.OSProcInfo) bool {
@@ -48,8 +56,8 @@ var processFinder = func(log log.T, procinfo contracts.OSProcInfo) bool {
 	return proc.IsProcessExists(log, procinfo.Pid, procinfo.StartTime)
 }
 
-var processCreator = func(name string, argv []string) (proc.OSProcess, error) {
-	return proc.StartProcess(name, argv)
+var processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
+	return proc.StartProcess(path, name, argv)
 }
 
 func NewOutOfProcExecuter(ctx context.T) *OutOfProcExecuter {
@@ -59,7 +67,7 @@ func NewOutOfProcExecuter(ctx context.T) *OutOfProcExecuter {
 	}
 }
 
-//Run() prepare the ipc channel, create a data processing backend and start messaging with docment worker
+// Run() prepare the ipc channel, create a data processing backend and start messaging with docment worker
 func (e *OutOfProcExecuter) Run(
 	cancelFlag task.CancelFlag,
 	docStore executer.DocumentStore) chan contracts.DocumentResult {
@@ -101,9 +109,9 @@ func (e *OutOfProcExecuter) Run(
 	}
 }
 
-//Executer spins up an ipc transmission worker, it creates a Data processing backend and hands off the backend to the ipc worker
-//ipc worker and data backend act as 2 threads exchange raw json messages, and messaging protocol happened in data backend, data backend is self-contained and exit when command finishes accordingly
-//Executer however does hold a timer to the worker to forcefully termniate both of them
+// Executer spins up an ipc transmission worker, it creates a Data processing backend and hands off the backend to the ipc worker
+// ipc worker and data backend act as 2 threads exchange raw json messages, and messaging protocol happened in data backend, data backend is self-contained and exit when command finishes accordingly
+// Executer however does hold a timer to the worker to forcefully termniate both of them
 func (e *OutOfProcExecuter) messaging(log log.T, ipc channel.Channel, resChan chan contracts.DocumentResult, cancelFlag task.CancelFlag, stopTimer chan bool) {
 
 	//handoff reply functionalities to data backend.
@@ -140,8 +148,8 @@ func (e *OutOfProcExecuter) generateUnexpectedFailResult(errMsg string) contract
 	return docResult
 }
 
-//prepare the channel for messaging as well as launching the document worker process, if the channel already exists, re-open it.
-//launch timeout timer based off the discovered process status
+// prepare the channel for messaging as well as launching the document worker process, if the channel already exists, re-open it.
+// launch timeout timer based off the discovered process status
 func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel, err error) {
 	log := e.ctx.Log()
 	var found bool
@@ -174,7 +182,24 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 			workerName = appconfig.DefaultDocumentWorker
 		}
 		var process proc.OSProcess
-		if process, err = processCreator(workerName, proc.FormArgv(documentID, instanceID)); err != nil {
+		var executable *os.File
+		if executable, err = integrity.OpenExecutable(workerName); err != nil {
+			metrics.WorkerFailures.Inc(e.workerMetricsName())
+			log.Errorf("refusing to start process: %v", err)
+			ipc.Destroy()
+			return
+		}
+		// the worker runs from the file checked against the binary manifest when the verification is enabled
+		workerPath := workerName
+		if executable != nil {
+			workerPath = integrity.ExecutablePath(executable)
+		}
+		process, err = processCreator(workerPath, workerName, proc.FormArgv(documentID, instanceID))
+		if executable != nil {
+			executable.Close()
+		}
+		if err != nil {
+			metrics.WorkerFailures.Inc(e.workerMetricsName())
 			log.Errorf("start process: %v error: %v", workerName, err)
 			//make sure close the channel
 			ipc.Destroy()
@@ -182,11 +207,18 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 		} else {
 			log.Debugf("successfully launched new process: %v", process.Pid())
 		}
+		// the worker and the document it runs are not held to the resource limits of the agent
+		if err = resourcelimits.Release(process.Pid()); err != nil {
+			log.Warnf("failed to release the worker from the resource limits of the agent: %v", err)
+		}
 		e.docState.DocumentInformation.ProcInfo = contracts.OSProcInfo{
 			Pid:       process.Pid(),
 			StartTime: process.StartTime(),
 		}
-		//TODO add command timeout as well, in case process get stuck
+		metrics.WorkerProcesses.Inc(e.workerMetricsName())
+		if e.docState.DocumentType == contracts.StartSession {
+			e.processAudit = processaudit.Watch(log, e.ctx.AppConfig().Audit, documentID, process.Pid())
+		}
 		go e.WaitForProcess(stopTimer, process)
 
 	}
@@ -208,7 +240,14 @@ func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSP
 	//		process.Kill()
 	//	}
 	//}()
-	if err := process.Wait(); err != nil {
+	//the watchdog kills the process when it gets stuck past the worker deadline
+	stopWatching := watchdog.WatchWorker(e.workerMetricsName(), process.Pid(), process.Kill)
+	err := process.Wait()
+	stopWatching()
+	e.processAudit.End()
+	metrics.WorkerProcesses.Dec(e.workerMetricsName())
+	if err != nil {
+		metrics.WorkerFailures.Inc(e.workerMetricsName())
 		log.Errorf("process: %v exited unsuccessfully, error message: %v", process.Pid(), err)
 	} else {
 		log.Debugf("process: %v exited successfully, trying to stop messaging worker", process.Pid())
@@ -217,6 +256,14 @@ func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSP
 	timeout(stopTimer, defaultZombieProcessTimeout, e.cancelFlag)
 }
 
+// workerMetricsName returns the worker label under which the process of this executer is reported
+func (e *OutOfProcExecuter) workerMetricsName() string {
+	if e.docState.DocumentType == contracts.StartSession {
+		return "session"
+	}
+	return "document"
+}
+
 func timeout(stopTimer chan bool, duration time.Duration, cancelFlag task.CancelFlag) {
 	stopChan := make(chan bool)
 	//TODO refactor cancelFlag.Wait() to return channel instead of blocking call
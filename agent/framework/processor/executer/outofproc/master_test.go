@@ -107,7 +107,9 @@ func TestInitializeNewProcess(t *testing.T) {
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, false
 	}
-	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+	processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
+		// the worker runs from its path when the binaries are not verified
+		assert.Equal(t, appconfig.DefaultDocumentWorker, path)
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
 		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
@@ -141,7 +143,7 @@ func TestInitializeNewProcessForSession(t *testing.T) {
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, false
 	}
-	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+	processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultSessionWorker)
 		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
@@ -212,7 +214,7 @@ func TestCreateProcessFailed(t *testing.T) {
 		return channelMock, nil, false
 	}
 	var err = errors.New("failed to create process")
-	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+	processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
 		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return nil, err
@@ -237,7 +239,7 @@ func TestInitializeProcessUnexpectedExited(t *testing.T) {
 		assert.Equal(t, testDocumentID, documentID)
 		return channelMock, nil, false
 	}
-	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+	processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
 		assert.Equal(t, name, appconfig.DefaultDocumentWorker)
 		assert.Equal(t, argv, []string{testDocumentID, testInstanceID})
 		return testCase.processMock, nil
@@ -300,7 +302,7 @@ func TestInitializeConnectOldOrphan(t *testing.T) {
 	}
 	//make sure not create new process
 	isCreateCalled := false
-	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+	processCreator = func(path string, name string, argv []string) (proc.OSProcess, error) {
 		isCreateCalled = true
 		return testCase.processMock, nil
 	}
@@ -328,7 +330,7 @@ func TestInitializeConnectOldOrphan(t *testing.T) {
 
 //TODO add Run() unittest
 
-//this is needed, since after marshal-unmarshalling thru the data channel, the pointer value changed
+// this is needed, since after marshal-unmarshalling thru the data channel, the pointer value changed
 func assertValueEqual(t *testing.T, a map[string]*contracts.PluginResult, b map[string]*contracts.PluginResult) {
 	assert.Equal(t, len(a), len(b))
 	for key, val := range a {
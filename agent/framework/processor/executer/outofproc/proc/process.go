@@ -25,7 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
-//OSProcess is an abstracted interface of os.Process
+// OSProcess is an abstracted interface of os.Process
 type OSProcess interface {
 	//generic ssm visible fields
 	Pid() int
@@ -39,7 +39,7 @@ type OSProcess interface {
 	Wait() error
 }
 
-//impl of OSProcess with os.Process embed
+// impl of OSProcess with os.Process embed
 type WorkerProcess struct {
 	*exec.Cmd
 	startTime time.Time
@@ -53,7 +53,7 @@ func (p *WorkerProcess) StartTime() time.Time {
 	return p.startTime
 }
 
-//TODO use the kill functions provided in executes package
+// TODO use the kill functions provided in executes package
 func (p *WorkerProcess) Kill() error {
 	return p.Cmd.Process.Kill()
 }
@@ -62,10 +62,12 @@ func (p *WorkerProcess) Wait() error {
 	return p.Cmd.Wait()
 }
 
-//start a child process, with the resources attached to its parent
-func StartProcess(name string, argv []string) (OSProcess, error) {
+// start a child process, with the resources attached to its parent. The process runs the executable at path,
+// name is its program name.
+func StartProcess(path string, name string, argv []string) (OSProcess, error) {
 	//TODO connect stdin and stdout to avoid seelog error
-	cmd := exec.Command(name, argv...)
+	cmd := exec.Command(path, argv...)
+	cmd.Args[0] = name
 	prepareProcess(cmd)
 	err := cmd.Start()
 	p := WorkerProcess{
@@ -76,8 +78,8 @@ func StartProcess(name string, argv []string) (OSProcess, error) {
 	return &p, err
 }
 
-//os.FindProcess() doesn't work on Linux: https://groups.google.com/forum/#!topic/golang-nuts/hqrp0UHBK9k
-//what we can only do is check whether it exists
+// os.FindProcess() doesn't work on Linux: https://groups.google.com/forum/#!topic/golang-nuts/hqrp0UHBK9k
+// what we can only do is check whether it exists
 func IsProcessExists(log log.T, pid int, createTime time.Time) bool {
 	found, err := find_process(pid, createTime)
 	if err != nil {
@@ -86,7 +88,7 @@ func IsProcessExists(log log.T, pid int, createTime time.Time) bool {
 	return found
 }
 
-//TODO figure out why sometimes argv does not contain program name
+// TODO figure out why sometimes argv does not contain program name
 func ParseArgv(argv []string) (channelName string, instanceID string, err error) {
 	if len(argv) == 1 {
 		if argv[0] == appconfig.DefaultDocumentWorker || argv[0] == appconfig.DefaultSessionWorker {
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package process wraps up the os.Process interface and also provides os-specific process lookup functions
@@ -24,9 +25,9 @@ import (
 	"time"
 )
 
-//Unix man: http://www.skrenta.com/rt/man/ps.1.html , return the process table of the current user, in agent it'll be root
-//verified on RHEL, Amazon Linux, Ubuntu, Centos, FreeBSD and Darwin
-//TODO optimize this, do not print all processes; what we need is the process belongs to a specific user and no tty attached
+// Unix man: http://www.skrenta.com/rt/man/ps.1.html , return the process table of the current user, in agent it'll be root
+// verified on RHEL, Amazon Linux, Ubuntu, Centos, FreeBSD and Darwin
+// TODO optimize this, do not print all processes; what we need is the process belongs to a specific user and no tty attached
 var ps = func() ([]byte, error) {
 	return exec.Command("ps", "-e", "-o", "pid,lstart").CombinedOutput()
 }
@@ -36,7 +37,7 @@ func prepareProcess(command *exec.Cmd) {
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
-//given the pid and the unix process startTime format string, return whether the process is still alive
+// given the pid and the unix process startTime format string, return whether the process is still alive
 func find_process(pid int, startTime time.Time) (bool, error) {
 	output, err := ps()
 	if err != nil {
@@ -60,8 +61,8 @@ func find_process(pid int, startTime time.Time) (bool, error) {
 	return false, nil
 }
 
-//TODO add time comparison
-//compare the 2 UTC date time, whether the startTime is within one sec
+// TODO add time comparison
+// compare the 2 UTC date time, whether the startTime is within one sec
 func compareTimes(startTime time.Time, timeRaw string) bool {
 	startTime = startTime.UTC()
 	parsedTime, _ := time.Parse(time.ANSIC, timeRaw)
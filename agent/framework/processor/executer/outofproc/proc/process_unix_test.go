@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package process wraps up the os.Process interface and also provides os-specific process lookup functions
@@ -29,7 +30,7 @@ import (
 
 var logger = log.NewMockLog()
 
-//TODO add process start time
+// TODO add process start time
 func TestIsProcessExists(t *testing.T) {
 	cmd := exec.Command("sleep", "10")
 	err := cmd.Start()
@@ -40,7 +41,7 @@ func TestIsProcessExists(t *testing.T) {
 	assert.True(t, IsProcessExists(logger, pid, time.Now()))
 }
 
-//Output format is verified to be identical on RHEL, CENTOS, UBUNTU, AL. However darwin has a different time format
+// Output format is verified to be identical on RHEL, CENTOS, UBUNTU, AL. However darwin has a different time format
 func TestFindProcess(t *testing.T) {
 	testInput := " PID STARTED" + "\n" +
 		"2598 Fri Aug  4 11:39:23 2017" + "\n" +
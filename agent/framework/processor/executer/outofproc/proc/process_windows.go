@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package process wraps up the os.Process interface and also provides os-specific process lookup functions
@@ -33,7 +34,7 @@ func prepareProcess(command *exec.Cmd) {
 	// nothing to do on windows
 }
 
-//given the pid and the high order filetime, look up the process
+// given the pid and the high order filetime, look up the process
 func find_process(pid int, startTime time.Time) (bool, error) {
 	const da = syscall.STANDARD_RIGHTS_READ |
 		syscall.PROCESS_QUERY_INFORMATION | syscall.SYNCHRONIZE
@@ -53,8 +54,8 @@ func find_process(pid int, startTime time.Time) (bool, error) {
 	return true, nil
 }
 
-//TODO add date comparison
-//compare the filetime and Date time, whether they are within 1sec range
+// TODO add date comparison
+// compare the filetime and Date time, whether they are within 1sec range
 func compare(ftime syscall.Filetime, startTime time.Time) bool {
 	parsedTime := windowsBaseTime.Add(time.Duration(ftime.Nanoseconds()))
 	return startTime.Before(parsedTime.Add(time.Second)) && startTime.After(parsedTime.Add(-time.Second))
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package process wraps up the os.Process interface and also provides os-specific process lookup functions
@@ -26,7 +27,7 @@ import (
 	"src/github.com/stretchr/testify/assert"
 )
 
-//TODO add process start time
+// TODO add process start time
 func TestIsProcessExists(t *testing.T) {
 	cmd := exec.Command("cmd", "timeout", "100")
 	err := cmd.Start()
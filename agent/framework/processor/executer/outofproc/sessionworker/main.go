@@ -20,6 +20,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/crashdump"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
@@ -51,7 +53,7 @@ var sessionPluginRunner = func(
 }
 
 // initialize populates session worker information.
-//rule of thumb is, do not trigger extra file operation or other intricate dependencies during this setup, make it light weight
+// rule of thumb is, do not trigger extra file operation or other intricate dependencies during this setup, make it light weight
 func initialize(args []string) (context.T, string, error) {
 	// intialize a light weight logger, use the default seelog config logger
 	logger := ssmlog.SSMLogger(false)
@@ -83,7 +85,20 @@ func main() {
 		log.Errorf("Session worker failed to initialize: %s", err)
 		return
 	}
+	if err = fips.ConfigureWorker(); err != nil {
+		log.Errorf("Session worker failed to configure the FIPS mode: %s", err)
+		return
+	}
 
+	defer func() {
+		// capture a crash dump in case the session worker panics
+		if msg := recover(); msg != nil {
+			log.Errorf("Session worker crashed with message %v!", msg)
+			crashdump.Capture(log, msg)
+			log.Close()
+			os.Exit(1)
+		}
+	}()
 	createFileChannelAndExecutePlugin(context, channelName)
 	log.Info("Session worker closed")
 }
@@ -95,7 +110,7 @@ func createFileChannelAndExecutePlugin(context context.T, channelName string) {
 	log := context.Log()
 	log.Infof("document: %v worker started", channelName)
 	//create channel from the given handle identifier by master
-	ipc, err, _ := channel.CreateFileChannel(log, channel.ModeWorker, channelName)
+	ipc, err, _ := channel.CreateChannel(log, channel.ModeWorker, channelName)
 	if err != nil {
 		log.Errorf("failed to create channel: %v", err)
 		return
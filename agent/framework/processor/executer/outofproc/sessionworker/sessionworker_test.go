@@ -45,7 +45,7 @@ func (suite *SessionWorkerTestSuite) TestSessionWorkerInitialize() {
 		[]string{defaultSessionWorkerContextName, "[" + channelName + "]"})
 }
 
-//Execute the test suite
+// Execute the test suite
 func TestSessionTestSuite(t *testing.T) {
 	suite.Run(t, new(SessionWorkerTestSuite))
 }
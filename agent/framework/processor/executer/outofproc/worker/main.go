@@ -7,6 +7,8 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/crashdump"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
@@ -34,9 +36,9 @@ var pluginRunner = func(
 	close(resChan)
 }
 
-//TODO revisit this, is plugin entitled to use appconfig?
-//TODO add log level to args
-//rule of thumb is, do not trigger extra file operation or other intricate dependencies during this setup, make it light weight
+// TODO revisit this, is plugin entitled to use appconfig?
+// TODO add log level to args
+// rule of thumb is, do not trigger extra file operation or other intricate dependencies during this setup, make it light weight
 func initialize(args []string) (context.T, string, error) {
 	// intialize a light weight logger, use the default seelog config logger
 	logger := ssmlog.SSMLogger(false)
@@ -65,9 +67,23 @@ func main() {
 		logger.Close()
 		return
 	}
+	defer func() {
+		// capture a crash dump in case the worker panics
+		if msg := recover(); msg != nil {
+			logger.Errorf("document worker crashed with message %v!", msg)
+			crashdump.Capture(logger, msg)
+			logger.Close()
+			os.Exit(1)
+		}
+	}()
+	if err = fips.ConfigureWorker(); err != nil {
+		logger.Errorf("document worker failed to configure the FIPS mode: %v", err)
+		logger.Close()
+		return
+	}
 	logger.Infof("document: %v worker started", channelName)
 	//create channel from the given handle identifier by master
-	ipc, err, _ := channel.CreateFileChannel(logger, channel.ModeWorker, channelName)
+	ipc, err, _ := channel.CreateChannel(logger, channel.ModeWorker, channelName)
 	if err != nil {
 		logger.Errorf("failed to create channel: %v", err)
 		logger.Close()
@@ -30,8 +30,11 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/lrpminvoker"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/refreshassociation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/rundocument"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/runlua"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/runwasm"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/updatessmagent"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/port"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/shell"
 )
@@ -40,22 +43,25 @@ import (
 // This allows us to differentiate between the case where a document asks for a plugin that exists but isn't supported on this platform
 // and the case where a plugin name isn't known at all to this version of the agent (and the user should probably upgrade their agent)
 var allPlugins = map[string]struct{}{
-	appconfig.PluginNameAwsAgentUpdate:         {},
-	appconfig.PluginNameAwsApplications:        {},
-	appconfig.PluginNameAwsConfigureDaemon:     {},
-	appconfig.PluginNameAwsConfigurePackage:    {},
-	appconfig.PluginNameAwsPowerShellModule:    {},
-	appconfig.PluginNameAwsRunPowerShellScript: {},
-	appconfig.PluginNameAwsRunShellScript:      {},
-	appconfig.PluginNameAwsSoftwareInventory:   {},
-	appconfig.PluginNameCloudWatch:             {},
-	appconfig.PluginNameConfigureDocker:        {},
-	appconfig.PluginNameDockerContainer:        {},
-	appconfig.PluginNameDomainJoin:             {},
-	appconfig.PluginEC2ConfigUpdate:            {},
-	appconfig.PluginNameRefreshAssociation:     {},
-	appconfig.PluginDownloadContent:            {},
-	appconfig.PluginRunDocument:                {},
+	appconfig.PluginNameAwsAgentUpdate:           {},
+	appconfig.PluginNameAwsApplications:          {},
+	appconfig.PluginNameAwsConfigureDaemon:       {},
+	appconfig.PluginNameAwsConfigurePackage:      {},
+	appconfig.PluginNameAwsInstallWindowsUpdates: {},
+	appconfig.PluginNameAwsPowerShellModule:      {},
+	appconfig.PluginNameAwsRunLuaScript:          {},
+	appconfig.PluginNameAwsRunPowerShellScript:   {},
+	appconfig.PluginNameAwsRunShellScript:        {},
+	appconfig.PluginNameAwsRunWasmModule:         {},
+	appconfig.PluginNameAwsSoftwareInventory:     {},
+	appconfig.PluginNameCloudWatch:               {},
+	appconfig.PluginNameConfigureDocker:          {},
+	appconfig.PluginNameDockerContainer:          {},
+	appconfig.PluginNameDomainJoin:               {},
+	appconfig.PluginEC2ConfigUpdate:              {},
+	appconfig.PluginNameRefreshAssociation:       {},
+	appconfig.PluginDownloadContent:              {},
+	appconfig.PluginRunDocument:                  {},
 }
 
 var once sync.Once
@@ -133,6 +139,20 @@ func (r RunDocumentFactory) Create(context context.T) (runpluginutil.T, error) {
 	return rundocument.NewPlugin()
 }
 
+type RunWasmModuleFactory struct {
+}
+
+func (r RunWasmModuleFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runwasm.NewPlugin()
+}
+
+type RunLuaScriptFactory struct {
+}
+
+func (r RunLuaScriptFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runlua.NewPlugin()
+}
+
 type SessionPluginFactory struct {
 	newPluginFunc sessionplugin.NewPluginFunc
 }
@@ -194,6 +214,9 @@ func loadSessionPlugins() {
 	shellPluginName := appconfig.PluginNameStandardStream
 	sessionPlugins[shellPluginName] = SessionPluginFactory{shell.NewPlugin}
 
+	portPluginName := appconfig.PluginNamePort
+	sessionPlugins[portPluginName] = SessionPluginFactory{port.NewPlugin}
+
 	registeredPlugins = &sessionPlugins
 }
 
@@ -236,5 +259,13 @@ func loadPlatformIndependentPlugins(context context.T) runpluginutil.PluginRegis
 	runDocumentPluginName := rundocument.Name()
 	workerPlugins[runDocumentPluginName] = RunDocumentFactory{}
 
+	//registering aws:runWasmModule
+	runWasmModulePluginName := runwasm.Name()
+	workerPlugins[runWasmModulePluginName] = RunWasmModuleFactory{}
+
+	//registering aws:runLuaScript
+	runLuaScriptPluginName := runlua.Name()
+	workerPlugins[runLuaScriptPluginName] = RunLuaScriptFactory{}
+
 	return workerPlugins
 }
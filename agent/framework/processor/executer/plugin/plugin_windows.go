@@ -14,17 +14,21 @@
 // Package plugin contains general interfaces and types relevant to plugins.
 // It also provides the methods for registering plugins.
 //
+//go:build windows
 // +build windows
 
 package plugin
 
 import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/domainjoin"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/psmodule"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/updateec2config"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/windowsupdate"
 )
 
 type PsModuleFactory struct {
@@ -55,6 +59,20 @@ func (f UpdateEc2ConfigFactory) Create(context context.T) (runpluginutil.T, erro
 	return updateec2config.NewPlugin(updateec2config.GetUpdatePluginConfig(context))
 }
 
+type RunWslShellScriptFactory struct {
+}
+
+func (f RunWslShellScriptFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runscript.NewRunWslShellPlugin(context.AppConfig().Wsl)
+}
+
+type WindowsUpdateFactory struct {
+}
+
+func (f WindowsUpdateFactory) Create(context context.T) (runpluginutil.T, error) {
+	return windowsupdate.NewPlugin()
+}
+
 // loadPlatformDependentPlugins registers platform dependent plugins
 func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistry {
 	var workerPlugins = runpluginutil.PluginRegistry{}
@@ -75,6 +93,15 @@ func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistr
 	updateEC2AgentPluginName := updateec2config.Name()
 	workerPlugins[updateEC2AgentPluginName] = UpdateEc2ConfigFactory{}
 
+	// registering aws:installWindowsUpdates plugin
+	windowsUpdatePluginName := windowsupdate.Name()
+	workerPlugins[windowsUpdatePluginName] = WindowsUpdateFactory{}
+
+	// registering aws:runShellScript plugin when a WSL distribution runs the shell scripts
+	if runscript.WslAvailable(context.AppConfig().Wsl) {
+		workerPlugins[appconfig.PluginNameAwsRunShellScript] = RunWslShellScriptFactory{}
+	}
+
 	//// registering aws:configureDaemon
 	//configureDaemonPluginName := configuredaemon.Name()
 	//configureDaemonPlugin, err := configuredaemon.NewPlugin(pluginutil.DefaultPluginConfig())
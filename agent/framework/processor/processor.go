@@ -24,13 +24,16 @@ import (
 	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bootgate"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
 	"github.com/aws/amazon-ssm-agent/agent/task"
@@ -71,8 +74,8 @@ type EngineProcessor struct {
 	documentMgr       docmanager.DocumentMgr
 }
 
-//TODO worker pool should be triggered in the Start() function
-//supported document types indicate the domain of the documentes the Processor with run upon. There'll be race-conditions if there're multiple Processors in a certain domain.
+// TODO worker pool should be triggered in the Start() function
+// supported document types indicate the domain of the documentes the Processor with run upon. There'll be race-conditions if there're multiple Processors in a certain domain.
 func NewEngineProcessor(ctx context.T, commandWorkerLimit int, cancelWorkerLimit int, supportedDocs []contracts.DocumentType) *EngineProcessor {
 	log := ctx.Log()
 	// sendCommand and cancelCommand will be processed by separate worker pools
@@ -131,7 +134,7 @@ func (p *EngineProcessor) InitialProcessing() (err error) {
 	return
 }
 
-//Submit() is the public interface for sending run document request to processor
+// Submit() is the public interface for sending run document request to processor
 func (p *EngineProcessor) Submit(docState contracts.DocumentState) {
 	log := p.context.Log()
 	//queue up the pending document
@@ -156,7 +159,11 @@ func (p *EngineProcessor) submit(docState *contracts.DocumentState) error {
 	} else {
 		jobID = docState.DocumentInformation.MessageID
 	}
-	return p.sendCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+	// the document is queued until a worker of the pool picks it up
+	documentType := string(docState.DocumentType)
+	metrics.QueuedDocuments.Inc(documentType)
+	err := p.sendCommandPool.Submit(log, jobID, func(cancelFlag task.CancelFlag) {
+		metrics.QueuedDocuments.Dec(documentType)
 		processCommand(
 			p.context,
 			p.executerCreator,
@@ -165,6 +172,10 @@ func (p *EngineProcessor) submit(docState *contracts.DocumentState) error {
 			docState,
 			p.documentMgr)
 	})
+	if err != nil {
+		metrics.QueuedDocuments.Dec(documentType)
+	}
+	return err
 
 }
 
@@ -188,12 +199,17 @@ func (p *EngineProcessor) Cancel(docState contracts.DocumentState) {
 	}
 }
 
-//Stop set the cancel flags of all the running jobs, which are to be captured by the command worker and shutdown gracefully
+// Stop set the cancel flags of all the running jobs, which are to be captured by the command worker and shutdown gracefully
 func (p *EngineProcessor) Stop(stopType contracts.StopType) {
 	var waitTimeout time.Duration
 
 	if stopType == contracts.StopTypeSoftStop {
 		waitTimeout = time.Duration(p.context.AppConfig().Mds.StopTimeoutMillis) * time.Millisecond
+		// the running documents are cancelled after the drain timeout when the agent stops,
+		// the cancelled sessions notify their clients that they are terminating
+		if drainTimeout := time.Duration(p.context.AppConfig().Agent.DrainTimeoutSeconds) * time.Second; drainTimeout > 0 {
+			waitTimeout = drainTimeout
+		}
 	} else {
 		waitTimeout = hardStopTimeout
 	}
@@ -220,7 +236,7 @@ func (p *EngineProcessor) Stop(stopType contracts.StopType) {
 	close(p.resChan)
 }
 
-//TODO remove the direct file dependency once we encapsulate docmanager package
+// TODO remove the direct file dependency once we encapsulate docmanager package
 func (p *EngineProcessor) processPendingDocuments(instanceID string) {
 	log := p.context.Log()
 	files := []os.FileInfo{}
@@ -281,7 +297,13 @@ func (p *EngineProcessor) processInProgressDocuments(instanceID string) {
 		//inspect document state
 		docState := p.documentMgr.GetDocumentState(log, f.Name(), instanceID, appconfig.DefaultLocationOfCurrent)
 
+		// the session worker owns the pty and the data channel of a session and keeps running while the agent restarts,
+		// the out of process executer reconnects to it through its IPC channel, no file descriptor is handed over.
+		// The sessions have their own retry limit, the worker of a session is not rerun like a command is.
 		retryLimit := config.Mds.CommandRetryLimit
+		if docState.DocumentType == contracts.StartSession {
+			retryLimit = config.Mgs.SessionRetryLimit
+		}
 		if docState.DocumentInformation.RunCount >= retryLimit {
 			p.documentMgr.MoveDocumentState(log, f.Name(), instanceID, appconfig.DefaultLocationOfCurrent, appconfig.DefaultLocationOfCorrupt)
 			continue
@@ -314,6 +336,11 @@ func (p *EngineProcessor) isSupportedDocumentType(documentType contracts.Documen
 
 func processCommand(context context.T, executerCreator ExecuterCreator, cancelFlag task.CancelFlag, resChan chan contracts.DocumentResult, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
 	log := context.Log()
+	// the commands and the associations wait for the provisioning of the instance at boot, the sessions do not
+	if docState.DocumentType != contracts.StartSession && !bootgate.Wait(log, context.AppConfig().BootGate, cancelFlag) {
+		log.Infof("document %v not started before the shutdown, it is left pending", docState.DocumentInformation.MessageID)
+		return
+	}
 	//persist the current running document
 	docMgr.MoveDocumentState(log,
 		docState.DocumentInformation.DocumentID,
@@ -322,6 +349,8 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 		appconfig.DefaultLocationOfCurrent)
 	log.Debug("Running executer...")
 	documentID := docState.DocumentInformation.DocumentID
+	healthendpoint.DocumentStarted(*docState)
+	defer healthendpoint.DocumentFinished(documentID)
 	instanceID := docState.DocumentInformation.InstanceID
 	messageID := docState.DocumentInformation.MessageID
 	e := executerCreator(context)
@@ -365,7 +394,7 @@ func processCommand(context context.T, executerCreator ExecuterCreator, cancelFl
 
 }
 
-//TODO CancelCommand is currently treated as a special type of Command by the Processor, but in general Cancel operation should be seen as a probe to existing commands
+// TODO CancelCommand is currently treated as a special type of Command by the Processor, but in general Cancel operation should be seen as a probe to existing commands
 func processCancelCommand(context context.T, sendCommandPool task.Pool, docState *contracts.DocumentState, docMgr docmanager.DocumentMgr) {
 
 	log := context.Log()
@@ -395,8 +424,8 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 
 }
 
-//TODO remove this once CloudWatch plugin is reworked
-//temporary solution on plugins with shared responsibility with agent
+// TODO remove this once CloudWatch plugin is reworked
+// temporary solution on plugins with shared responsibility with agent
 func handleCloudwatchPlugin(context context.T, pluginResults map[string]*contracts.PluginResult, documentID string) {
 	log := context.Log()
 	instanceID, _ := platform.InstanceID()
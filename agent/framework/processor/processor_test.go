@@ -15,13 +15,16 @@
 package processor
 
 import (
-	"testing"
-
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	executermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -30,7 +33,7 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-//TODO implement processor_integ_test once we encapsulate docmanager
+// TODO implement processor_integ_test once we encapsulate docmanager
 func TestEngineProcessor_Submit(t *testing.T) {
 	sendCommandPoolMock := new(task.MockedPool)
 	ctx := context.NewMockDefault()
@@ -94,7 +97,88 @@ func TestEngineProcessor_Stop(t *testing.T) {
 	cancelCommandPoolMock.AssertExpectations(t)
 }
 
-//TODO add shutdown and reboot test once we encapsulate docmanager
+// stubInProgressDocument places a document in the Current folder of a temporary data store
+func stubInProgressDocument(t *testing.T, instanceID, documentID string) func() {
+	dataStorePath := appconfig.DefaultDataStorePath
+	dir, err := ioutil.TempDir("", "processor")
+	assert.NoError(t, err)
+	appconfig.DefaultDataStorePath = dir
+	currentDir := docmanager.DocumentStateDir(instanceID, appconfig.DefaultLocationOfCurrent)
+	assert.NoError(t, os.MkdirAll(currentDir, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(currentDir, documentID), []byte("{}"), 0644))
+	return func() {
+		appconfig.DefaultDataStorePath = dataStorePath
+		os.RemoveAll(dir)
+	}
+}
+
+// mockContextWithDefaultConfig returns a mocked context with the default retry limits
+func mockContextWithDefaultConfig() *context.Mock {
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(appconfig.DefaultConfig())
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+// processInProgressDocument processes an in-progress document which already ran runCount times
+func processInProgressDocument(t *testing.T, ctx context.T, documentType contracts.DocumentType, runCount int) (*task.MockedPool, *DocumentMgrMock) {
+	defer stubInProgressDocument(t, "instanceID", "documentID")()
+	sendCommandPoolMock := new(task.MockedPool)
+	docMock := new(DocumentMgrMock)
+	processor := EngineProcessor{
+		sendCommandPool:   sendCommandPoolMock,
+		context:           ctx,
+		documentMgr:       docMock,
+		supportedDocTypes: []contracts.DocumentType{documentType},
+	}
+	docState := contracts.DocumentState{DocumentType: documentType}
+	docState.DocumentInformation.MessageID = "messageID"
+	docState.DocumentInformation.DocumentID = "documentID"
+	docState.DocumentInformation.RunCount = runCount
+	docMock.On("GetDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent).Return(docState)
+	docMock.On("MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent, appconfig.DefaultLocationOfCorrupt)
+	docMock.On("PersistDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent, mock.Anything)
+	sendCommandPoolMock.On("Submit", ctx.Log(), "messageID", mock.Anything).Return(nil)
+
+	processor.processInProgressDocuments("instanceID")
+	return sendCommandPoolMock, docMock
+}
+
+func TestProcessInProgressDocuments_SessionBelowRetryLimit(t *testing.T) {
+	ctx := mockContextWithDefaultConfig()
+	sessionRetryLimit := ctx.AppConfig().Mgs.SessionRetryLimit
+	sendCommandPoolMock, docMock := processInProgressDocument(t, ctx, contracts.StartSession, sessionRetryLimit-1)
+
+	// the session is submitted again so that the executer reconnects to its worker
+	sendCommandPoolMock.AssertCalled(t, "Submit", ctx.Log(), "messageID", mock.Anything)
+	docMock.AssertCalled(t, "PersistDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent,
+		mock.MatchedBy(func(docState contracts.DocumentState) bool {
+			return docState.DocumentInformation.RunCount == sessionRetryLimit
+		}))
+	docMock.AssertNotCalled(t, "MoveDocumentState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessInProgressDocuments_SessionAtRetryLimit(t *testing.T) {
+	ctx := mockContextWithDefaultConfig()
+	// the sessions are not allowed the retries of the commands
+	assert.True(t, ctx.AppConfig().Mgs.SessionRetryLimit < ctx.AppConfig().Mds.CommandRetryLimit)
+	sendCommandPoolMock, docMock := processInProgressDocument(t, ctx, contracts.StartSession, ctx.AppConfig().Mgs.SessionRetryLimit)
+
+	docMock.AssertCalled(t, "MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent, appconfig.DefaultLocationOfCorrupt)
+	docMock.AssertNotCalled(t, "PersistDocumentState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	sendCommandPoolMock.AssertNotCalled(t, "Submit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessInProgressDocuments_CommandAtRetryLimit(t *testing.T) {
+	ctx := mockContextWithDefaultConfig()
+	sendCommandPoolMock, docMock := processInProgressDocument(t, ctx, contracts.SendCommand, ctx.AppConfig().Mds.CommandRetryLimit)
+
+	docMock.AssertCalled(t, "MoveDocumentState", mock.Anything, "documentID", "instanceID", appconfig.DefaultLocationOfCurrent, appconfig.DefaultLocationOfCorrupt)
+	sendCommandPoolMock.AssertNotCalled(t, "Submit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TODO add shutdown and reboot test once we encapsulate docmanager
 func TestProcessCommand(t *testing.T) {
 	ctx := context.NewMockDefault()
 	docState := contracts.DocumentState{}
@@ -141,7 +225,7 @@ func TestProcessCommand(t *testing.T) {
 
 }
 
-//TODO add shutdown and reboot test once we encapsulate docmanager
+// TODO add shutdown and reboot test once we encapsulate docmanager
 func TestProcessCommand_Shutdown(t *testing.T) {
 	ctx := context.NewMockDefault()
 	docState := contracts.DocumentState{}
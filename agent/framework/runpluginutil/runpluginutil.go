@@ -15,20 +15,28 @@
 package runpluginutil
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/accounting"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/crashdump"
+	"github.com/aws/amazon-ssm-agent/agent/diskguard"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/firehose"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
 )
 
 const (
@@ -51,36 +59,69 @@ type PluginRegistry map[string]PluginFactory
 
 var SSMPluginRegistry PluginRegistry
 
+// ssmPluginRegistryLoader loads SSMPluginRegistry when the plugins of a document first run
+var ssmPluginRegistryLoader func() PluginRegistry
+var ssmPluginRegistryLock sync.Mutex
+
+// SetSSMPluginRegistryLoader defers loading SSMPluginRegistry until the plugins of a document first run
+func SetSSMPluginRegistryLoader(load func() PluginRegistry) {
+	ssmPluginRegistryLock.Lock()
+	defer ssmPluginRegistryLock.Unlock()
+	ssmPluginRegistryLoader = load
+}
+
+// RegisteredSSMPlugins returns SSMPluginRegistry, which is loaded on the first call when a loader is set
+func RegisteredSSMPlugins() PluginRegistry {
+	ssmPluginRegistryLock.Lock()
+	defer ssmPluginRegistryLock.Unlock()
+	if SSMPluginRegistry == nil && ssmPluginRegistryLoader != nil {
+		SSMPluginRegistry = ssmPluginRegistryLoader()
+	}
+	return SSMPluginRegistry
+}
+
 // allPlugins is the list of all known plugins.
 // This allows us to differentiate between the case where a document asks for a plugin that exists but isn't supported on this platform
 // and the case where a plugin name isn't known at all to this version of the agent (and the user should probably upgrade their agent)
 var allPlugins = map[string]struct{}{
-	appconfig.PluginNameAwsAgentUpdate:         {},
-	appconfig.PluginNameAwsApplications:        {},
-	appconfig.PluginNameAwsConfigureDaemon:     {},
-	appconfig.PluginNameAwsConfigurePackage:    {},
-	appconfig.PluginNameAwsPowerShellModule:    {},
-	appconfig.PluginNameAwsRunPowerShellScript: {},
-	appconfig.PluginNameAwsRunShellScript:      {},
-	appconfig.PluginNameAwsSoftwareInventory:   {},
-	appconfig.PluginNameCloudWatch:             {},
-	appconfig.PluginNameConfigureDocker:        {},
-	appconfig.PluginNameDockerContainer:        {},
-	appconfig.PluginNameDomainJoin:             {},
-	appconfig.PluginEC2ConfigUpdate:            {},
-	appconfig.PluginNameRefreshAssociation:     {},
-	appconfig.PluginDownloadContent:            {},
-	appconfig.PluginRunDocument:                {},
+	appconfig.PluginNameAwsAgentUpdate:           {},
+	appconfig.PluginNameAwsApplications:          {},
+	appconfig.PluginNameAwsConfigureDaemon:       {},
+	appconfig.PluginNameAwsConfigurePackage:      {},
+	appconfig.PluginNameAwsInstallWindowsUpdates: {},
+	appconfig.PluginNameAwsPowerShellModule:      {},
+	appconfig.PluginNameAwsRunPowerShellScript:   {},
+	appconfig.PluginNameAwsRunShellScript:        {},
+	appconfig.PluginNameAwsSoftwareInventory:     {},
+	appconfig.PluginNameCloudWatch:               {},
+	appconfig.PluginNameConfigureDocker:          {},
+	appconfig.PluginNameDockerContainer:          {},
+	appconfig.PluginNameDomainJoin:               {},
+	appconfig.PluginEC2ConfigUpdate:              {},
+	appconfig.PluginNameRefreshAssociation:       {},
+	appconfig.PluginDownloadContent:              {},
+	appconfig.PluginRunDocument:                  {},
 }
 
 // allSessionPlugins is the list of all known session plugins.
 var allSessionPlugins = map[string]struct{}{
 	appconfig.PluginNameStandardStream: {},
+	appconfig.PluginNamePort:           {},
 }
 
 // Assign method to global variables to allow unittest to override
 var isSupportedPlugin = IsPluginSupportedForCurrentPlatform
 
+// resourceTracker measures the resources used by a plugin execution until it is stopped
+type resourceTracker interface {
+	Stop() contracts.ResourceUsage
+}
+
+// startAccounting starts measuring the resources used by a plugin execution, it is stubbed in the tests
+var startAccounting = func() resourceTracker {
+	return accounting.Start()
+}
+
 // TODO remove executionID and creation date
 // RunPlugins executes a set of plugins. The plugin configurations are given in a map with pluginId as key.
 // Outputs the results of running the plugins, indexed by pluginId.
@@ -99,125 +140,268 @@ func RunPlugins(
 	//Contains the logStreamPrefix without the pluginID
 	logStreamPrefix := ioConfig.CloudWatchConfig.LogStreamPrefix
 
-	for _, pluginState := range plugins {
-		pluginID := pluginState.Id     // the identifier of the plugin
-		pluginName := pluginState.Name // the name of the plugin
-		pluginOutput := pluginState.Result
-		pluginOutput.PluginID = pluginID
-		pluginOutput.PluginName = pluginName
-		pluginOutputs[pluginID] = &pluginOutput
-		switch pluginOutput.Status {
-		//TODO properly initialize the plugin status
-		case "":
-			context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
-				pluginName)
-			pluginOutput.StartDateTime = time.Now()
-			pluginOutput.Status = contracts.ResultStatusNotStarted
-
-		case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
-			context.Log().Debugf("plugin - %v status %v",
-				pluginName,
-				pluginOutput.Status)
-			pluginOutput.StartDateTime = time.Now()
+	for index := 0; index < len(plugins); {
+		batch := plugins[index : index+independentStepCount(plugins[index:])]
+		index += len(batch)
+
+		batchOutputs := make([]*contracts.PluginResult, len(batch))
+		for i, pluginState := range batch {
+			pluginOutput := pluginState.Result
+			pluginOutput.PluginID = pluginState.Id
+			pluginOutput.PluginName = pluginState.Name
+			pluginOutputs[pluginState.Id] = &pluginOutput
+			batchOutputs[i] = &pluginOutput
+		}
 
-		case contracts.ResultStatusSuccessAndReboot:
-			context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
-				pluginName)
-			pluginOutput.Status = contracts.ResultStatusInProgress
+		var rebootRequested bool
+		if len(batch) == 1 {
+			rebootRequested = runPluginStep(context, batch[0], batchOutputs[0], ioConfig, logStreamPrefix, registry, resChan, cancelFlag)
+		} else {
+			rebootRequested = runPluginStepsInParallel(context, batch, batchOutputs, ioConfig, logStreamPrefix, registry, resChan, cancelFlag)
+		}
 
-		default:
-			context.Log().Debugf("plugin - %v already executed, skipping...",
-				pluginName)
-			continue
+		//TODO handle cancelFlag here
+		if rebootRequested {
+			// do not execute the the next plugin
+			break
 		}
+	}
 
-		context.Log().Debugf("Executing plugin - %v", pluginName)
+	return
+}
 
-		// populate plugin start time and status
-		configuration := pluginState.Configuration
+// independentStepCount returns the number of adjacent configurePackage steps flagged as independent at the start of plugins
+// Steps that are not independent form a batch of their own, so the count is never below 1.
+func independentStepCount(plugins []contracts.PluginState) int {
+	count := 0
+	for _, pluginState := range plugins {
+		if !pluginState.Configuration.IsIndependent || pluginState.Name != appconfig.PluginNameAwsConfigurePackage {
+			break
+		}
+		count++
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
 
-		if ioConfig.OutputS3BucketName != "" {
-			pluginOutputs[pluginID].OutputS3BucketName = ioConfig.OutputS3BucketName
-			if ioConfig.OutputS3KeyPrefix != "" {
-				pluginOutputs[pluginID].OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+// parallelStepsLimit returns the maximum number of independent steps executed at the same time
+var parallelStepsLimit = func() int {
+	if appCfg, err := appconfig.Config(false); err == nil {
+		return appCfg.Ssm.ParallelPackageActionsLimit
+	}
+	return appconfig.DefaultParallelPackageActionsLimit
+}
 
-			}
-		}
-		//Append pluginID to logStreamPrefix. Replace ':' or '*' with '-' since LogStreamNames cannot have those characters
-		if ioConfig.CloudWatchConfig.LogGroupName != "" {
-			ioConfig.CloudWatchConfig.LogStreamPrefix = fmt.Sprintf("%s/%s", logStreamPrefix, pluginID)
-			ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, ":", "-", -1)
-			ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, "*", "-", -1)
+// runPluginStepsInParallel executes independent steps concurrently, at most parallelStepsLimit at a time
+// It returns true if any of the steps requested a reboot, in which case no further step is started.
+func runPluginStepsInParallel(
+	context context.T,
+	plugins []contracts.PluginState,
+	pluginOutputs []*contracts.PluginResult,
+	ioConfig contracts.IOConfiguration,
+	logStreamPrefix string,
+	registry PluginRegistry,
+	resChan chan contracts.PluginResult,
+	cancelFlag task.CancelFlag) bool {
+
+	limit := parallelStepsLimit()
+	if limit < 1 {
+		limit = 1
+	}
+	context.Log().Infof("Running %v independent steps, at most %v at a time", len(plugins), limit)
+
+	var wg sync.WaitGroup
+	var rebootLock sync.Mutex
+	rebootRequested := false
+	semaphore := make(chan struct{}, limit)
+	for i := range plugins {
+		semaphore <- struct{}{}
+		rebootLock.Lock()
+		stop := rebootRequested
+		rebootLock.Unlock()
+		if stop {
+			<-semaphore
+			break
 		}
 
-		var (
-			r                  contracts.PluginResult
-			pluginFactory      PluginFactory
-			pluginHandlerFound bool
-			isKnown            bool
-			isSupported        bool
-		)
-
-		pluginFactory, pluginHandlerFound = registry[pluginName]
-		isKnown, isSupported, _ = isSupportedPlugin(context.Log(), pluginName)
-		operation, logMessage := getStepExecutionOperation(
-			context.Log(),
+		wg.Add(1)
+		go func(pluginState contracts.PluginState, pluginOutput *contracts.PluginResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if runPluginStep(context, pluginState, pluginOutput, ioConfig, logStreamPrefix, registry, resChan, cancelFlag) {
+				rebootLock.Lock()
+				rebootRequested = true
+				rebootLock.Unlock()
+			}
+		}(plugins[i], pluginOutputs[i])
+	}
+	wg.Wait()
+
+	return rebootRequested
+}
+
+// runPluginStep executes a single step of a document, sends its result on resChan and returns true if it requested a reboot
+func runPluginStep(
+	context context.T,
+	pluginState contracts.PluginState,
+	pluginOutput *contracts.PluginResult,
+	ioConfig contracts.IOConfiguration,
+	logStreamPrefix string,
+	registry PluginRegistry,
+	resChan chan contracts.PluginResult,
+	cancelFlag task.CancelFlag) bool {
+
+	pluginID := pluginState.Id     // the identifier of the plugin
+	pluginName := pluginState.Name // the name of the plugin
+	switch pluginOutput.Status {
+	//TODO properly initialize the plugin status
+	case "":
+		context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
+			pluginName)
+		pluginOutput.StartDateTime = time.Now()
+		pluginOutput.Status = contracts.ResultStatusNotStarted
+
+	case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+		context.Log().Debugf("plugin - %v status %v",
 			pluginName,
-			pluginID,
-			isKnown,
-			isSupported,
-			pluginHandlerFound,
-			configuration.IsPreconditionEnabled,
-			configuration.Preconditions)
-
-		switch operation {
-		case executeStep:
-			context.Log().Infof("Running plugin %s", pluginName)
-			r = runPlugin(context, pluginFactory, pluginName, configuration, cancelFlag, ioConfig)
-			pluginOutputs[pluginID].Code = r.Code
-			pluginOutputs[pluginID].Status = r.Status
-			pluginOutputs[pluginID].Error = r.Error
-			pluginOutputs[pluginID].Output = r.Output
-			pluginOutputs[pluginID].StandardOutput = r.StandardOutput
-			pluginOutputs[pluginID].StandardError = r.StandardError
-
-		case skipStep:
-			context.Log().Info(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusSkipped
-			pluginOutputs[pluginID].Code = 0
-			pluginOutputs[pluginID].Output = logMessage
-		case failStep:
-			err := fmt.Errorf(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err.Error()
-			context.Log().Error(err)
-		default:
-			err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err.Error()
-			context.Log().Error(err)
-		}
+			pluginOutput.Status)
+		pluginOutput.StartDateTime = time.Now()
 
-		// set end time.
-		pluginOutputs[pluginID].EndDateTime = time.Now()
-		context.Log().Infof("Sending plugin %v completion message", pluginID)
+	case contracts.ResultStatusSuccessAndReboot:
+		context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
+			pluginName)
+		pluginOutput.Status = contracts.ResultStatusInProgress
 
-		// truncate the result and send it back to buffer channel.
-		result := *pluginOutputs[pluginID]
-		pluginConfig := iohandler.DefaultOutputConfig()
-		result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-		result.StandardError = pluginutil.StringPrefix(result.StandardError, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-		// send to buffer channel, guaranteed to not block since buffer size is plugin number
-		resChan <- result
+	default:
+		context.Log().Debugf("plugin - %v already executed, skipping...",
+			pluginName)
+		return false
+	}
+
+	context.Log().Debugf("Executing plugin - %v", pluginName)
+
+	// populate plugin start time and status
+	configuration := pluginState.Configuration
+
+	if ioConfig.OutputS3BucketName != "" {
+		pluginOutput.OutputS3BucketName = ioConfig.OutputS3BucketName
+		if ioConfig.OutputS3KeyPrefix != "" {
+			pluginOutput.OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
 
-		//TODO handle cancelFlag here
-		if pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot {
-			// do not execute the the next plugin
-			break
+		}
+	}
+	//Append pluginID to logStreamPrefix. Replace ':' or '*' with '-' since LogStreamNames cannot have those characters
+	if ioConfig.CloudWatchConfig.LogGroupName != "" {
+		ioConfig.CloudWatchConfig.LogStreamPrefix = fmt.Sprintf("%s/%s", logStreamPrefix, pluginID)
+		ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, ":", "-", -1)
+		ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, "*", "-", -1)
+	}
+	//Send the output of the command plugins to the Firehose delivery stream, the sessions send their transcripts
+	if firehoseConfig := context.AppConfig().Firehose; firehose.CommandOutputEnabled(firehoseConfig) && configuration.SessionId == "" {
+		instanceID, _ := platform.InstanceID()
+		commandID, _ := messageContracts.GetCommandID(configuration.MessageId)
+		ioConfig.FirehoseConfig = contracts.FirehoseConfiguration{
+			DeliveryStreamName: firehoseConfig.DeliveryStream,
+			PartitionKeys: firehose.PartitionKeys(firehoseConfig, map[string]string{
+				firehose.KeyInstanceID: instanceID,
+				firehose.KeyCommandID:  commandID,
+				firehose.KeyPluginName: pluginName,
+				firehose.KeyPluginID:   pluginID,
+			}),
 		}
 	}
 
-	return
+	var (
+		r                  contracts.PluginResult
+		pluginFactory      PluginFactory
+		pluginHandlerFound bool
+		isKnown            bool
+		isSupported        bool
+	)
+
+	pluginFactory, pluginHandlerFound = registry[pluginName]
+	isKnown, isSupported, _ = isSupportedPlugin(context.Log(), pluginName)
+	operation, logMessage := getStepExecutionOperation(
+		context.Log(),
+		pluginName,
+		pluginID,
+		isKnown,
+		isSupported,
+		pluginHandlerFound,
+		configuration.IsPreconditionEnabled,
+		configuration.Preconditions)
+	if operation == executeStep && !isAllowedPlugin(context.AppConfig(), pluginName) {
+		operation = failStep
+		logMessage = fmt.Sprintf("Plugin with name %s is disabled by instance policy. Step name: %s", pluginName, pluginID)
+	}
+
+	switch operation {
+	case executeStep:
+		context.Log().Infof("Running plugin %s", pluginName)
+		tracker := startAccounting()
+		r = runPlugin(context, pluginFactory, pluginName, configuration, cancelFlag, ioConfig)
+		usage := tracker.Stop()
+		context.Log().Infof("Plugin %s used %vms of CPU, %vKB of peak memory, read %v and wrote %v bytes on disk, uploaded %v bytes",
+			pluginName, usage.CPUTimeMillis, usage.PeakMemoryKB, usage.DiskReadBytes, usage.DiskWriteBytes, usage.UploadedBytes)
+		pluginOutput.ResourceUsage = &usage
+		pluginOutput.Code = r.Code
+		pluginOutput.Status = r.Status
+		pluginOutput.Error = r.Error
+		pluginOutput.Output = r.Output
+		pluginOutput.StandardOutput = r.StandardOutput
+		pluginOutput.StandardError = r.StandardError
+
+	case skipStep:
+		context.Log().Info(logMessage)
+		pluginOutput.Status = contracts.ResultStatusSkipped
+		pluginOutput.Code = 0
+		pluginOutput.Output = logMessage
+	case failStep:
+		err := errors.New(logMessage)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err.Error()
+		context.Log().Error(err)
+	default:
+		err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err.Error()
+		context.Log().Error(err)
+	}
+
+	// set end time.
+	pluginOutput.EndDateTime = time.Now()
+	context.Log().Infof("Sending plugin %v completion message", pluginID)
+
+	// truncate the result and send it back to buffer channel.
+	result := *pluginOutput
+	pluginConfig := iohandler.DefaultOutputConfig()
+	result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
+	result.StandardError = pluginutil.StringPrefix(result.StandardError, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
+	// send to buffer channel, guaranteed to not block since buffer size is plugin number
+	resChan <- result
+
+	return pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot
+}
+
+// isAllowedPlugin returns true if the plugin is in the allowed plugins and not in the disabled plugins of the agent
+// configuration, all plugins are allowed when the allowed plugins are empty
+func isAllowedPlugin(appConfig appconfig.SsmagentConfig, pluginName string) bool {
+	for _, disabled := range appConfig.Agent.DisabledPlugins {
+		if disabled == pluginName {
+			return false
+		}
+	}
+	if len(appConfig.Agent.AllowedPlugins) == 0 {
+		return true
+	}
+	for _, allowed := range appConfig.Agent.AllowedPlugins {
+		if allowed == pluginName {
+			return true
+		}
+	}
+	return false
 }
 
 func runPlugin(
@@ -239,6 +423,7 @@ func runPlugin(
 			res.Code = 1
 			res.Error = fmt.Errorf("Plugin crashed with message %v!", err).Error()
 			log.Error(res.Error)
+			crashdump.Capture(log, err)
 		}
 	}()
 
@@ -257,6 +442,24 @@ func runPlugin(
 	res.StartDateTime = time.Now()
 	defer func() { res.EndDateTime = time.Now() }()
 
+	// the plugin output is written to the orchestration directory
+	if err = diskguard.Check(ioConfig.OrchestrationDirectory); err != nil {
+		res.Status = contracts.ResultStatusFailed
+		res.Code = diskguard.ExitCode
+		res.Error = err.Error()
+		log.Error(res.Error)
+		return
+	}
+
+	// every execution gets its own temporary directory, removed once the plugin completes
+	if config.OrchestrationDirectory != "" {
+		if config.TempDirectory, err = tempdir.Create(config.OrchestrationDirectory); err != nil {
+			log.Warnf("failed to create the temporary directory of the plugin, %v", err)
+		} else {
+			defer tempdir.Remove(log, config.TempDirectory)
+		}
+	}
+
 	output := iohandler.NewDefaultIOHandler(log, ioConfig)
 	//check if properties is a list. If true, then unroll
 	switch config.Properties.(type) {
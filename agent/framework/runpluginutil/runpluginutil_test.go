@@ -16,12 +16,20 @@ package runpluginutil
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/accounting"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -54,10 +62,31 @@ func restoreIsSupported() {
 	isSupportedPlugin = origIsSupported
 }
 
+// stubTracker reports the same resource usage for every plugin execution
+type stubTracker struct {
+	usage contracts.ResourceUsage
+}
+
+func (t stubTracker) Stop() contracts.ResourceUsage {
+	return t.usage
+}
+
+// testResourceUsage is the resource usage of the plugin executions in the tests
+var testResourceUsage = contracts.ResourceUsage{CPUTimeMillis: 20, PeakMemoryKB: 4096, DiskReadBytes: 512, DiskWriteBytes: 1024}
+
+// stubAccounting reports testResourceUsage for the plugin executions instead of measuring them, it returns the
+// function restoring the accounting
+func stubAccounting() func() {
+	original := startAccounting
+	startAccounting = func() resourceTracker { return stubTracker{usage: testResourceUsage} }
+	return func() { startAccounting = original }
+}
+
 // TestRunPlugins tests that RunPluginsWithRegistry calls all the expected plugins.
 func TestRunPluginsWithNewDocument(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -92,6 +121,7 @@ func TestRunPluginsWithNewDocument(t *testing.T) {
 			PluginName:    name,
 			StartDateTime: defaultTime,
 			EndDateTime:   defaultTime,
+			ResourceUsage: &testResourceUsage,
 			Output:        "",
 		}
 
@@ -146,6 +176,7 @@ func TestRunPluginsWithNewDocument(t *testing.T) {
 func TestRunPluginsWithMissingPluginHandler(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -231,10 +262,11 @@ func TestRunPluginsWithMissingPluginHandler(t *testing.T) {
 
 }
 
-//TODO cancelFlag should not fail subsequent plugins
+// TODO cancelFlag should not fail subsequent plugins
 func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginStates := make([]contracts.PluginState, 2)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -264,6 +296,7 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 			PluginID:      name,
 			StartDateTime: defaultTime,
 			EndDateTime:   defaultTime,
+			ResourceUsage: &testResourceUsage,
 		}
 		if name == testPlugin1 {
 			plugins[name].On("Execute", ctx, pluginState.Configuration, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
@@ -303,6 +336,7 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginStates := make([]contracts.PluginState, 2)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -359,6 +393,8 @@ func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 		mockPlugin.AssertExpectations(t)
 	}
 	pluginResults[testPlugin2].Status = ""
+	// only the execution of plugin2 is accounted
+	pluginResults[testPlugin2].ResourceUsage = &testResourceUsage
 	assert.Equal(t, pluginResults[testPlugin1], outputs[testPlugin1])
 	assert.Equal(t, pluginResults[testPlugin2], outputs[testPlugin2])
 }
@@ -399,6 +435,7 @@ func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 func TestRunPluginsWithDuplicatePluginType(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginType := "aws:runShellScript"
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
@@ -434,6 +471,7 @@ func TestRunPluginsWithDuplicatePluginType(t *testing.T) {
 			PluginName:    pluginType,
 			StartDateTime: defaultTime,
 			EndDateTime:   defaultTime,
+			ResourceUsage: &testResourceUsage,
 		}
 
 		pluginFactory := new(PluginFactoryMock)
@@ -486,6 +524,7 @@ func TestRunPluginsWithDuplicatePluginType(t *testing.T) {
 func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -526,6 +565,7 @@ func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 			PluginID:      name,
 			StartDateTime: defaultTime,
 			EndDateTime:   defaultTime,
+			ResourceUsage: &testResourceUsage,
 		}
 
 		pluginFactory := new(PluginFactoryMock)
@@ -576,6 +616,7 @@ func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -616,6 +657,7 @@ func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 			PluginName:    name,
 			StartDateTime: defaultTime,
 			EndDateTime:   defaultTime,
+			ResourceUsage: &testResourceUsage,
 		}
 
 		pluginFactory := new(PluginFactoryMock)
@@ -664,6 +706,7 @@ func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 func TestRunPluginsWithIncompatiblePrecondition(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -753,6 +796,7 @@ func TestRunPluginsWithIncompatiblePrecondition(t *testing.T) {
 func TestRunPluginsWithCompatiblePreconditionButMissingPluginHandler(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -840,6 +884,7 @@ func TestRunPluginsWithCompatiblePreconditionButMissingPluginHandler(t *testing.
 func TestRunPluginsWithMoreThanOnePrecondition(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -938,6 +983,7 @@ func TestRunPluginsWithMoreThanOnePrecondition(t *testing.T) {
 func TestRunPluginsWithUnrecognizedPreconditionOperator(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -1034,6 +1080,7 @@ func TestRunPluginsWithUnrecognizedPreconditionOperator(t *testing.T) {
 func TestRunPluginsWithUnrecognizedPreconditionOperand(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -1131,6 +1178,7 @@ func TestRunPluginsWithUnrecognizedPreconditionOperand(t *testing.T) {
 func TestRunPluginsWithUnrecognizedPreconditionDuplicateVariable(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -1228,6 +1276,7 @@ func TestRunPluginsWithUnrecognizedPreconditionDuplicateVariable(t *testing.T) {
 func TestRunPluginsWithMoreThanTwoPreconditionOperands(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -1325,6 +1374,7 @@ func TestRunPluginsWithMoreThanTwoPreconditionOperands(t *testing.T) {
 func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testUnknownPlugin, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -1386,6 +1436,7 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 				PluginName:    name,
 				StartDateTime: defaultTime,
 				EndDateTime:   defaultTime,
+				ResourceUsage: &testResourceUsage,
 			}
 			pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return(*pluginResults[name])
 		}
@@ -1431,6 +1482,7 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 func TestRunPluginSuccessWithNonTruncatedResult(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
+	defer stubAccounting()()
 	pluginNames := []string{testPlugin1, testPlugin2}
 	pluginConfigs := make(map[string]contracts.PluginState)
 	pluginResults := make(map[string]*contracts.PluginResult)
@@ -1465,6 +1517,7 @@ func TestRunPluginSuccessWithNonTruncatedResult(t *testing.T) {
 			PluginName:     name,
 			StartDateTime:  defaultTime,
 			EndDateTime:    defaultTime,
+			ResourceUsage:  &testResourceUsage,
 			StandardOutput: "",
 		}
 
@@ -1503,3 +1556,246 @@ func TestRunPluginSuccessWithNonTruncatedResult(t *testing.T) {
 		assert.Equal(t, pluginResults[pluginID].StandardOutput, output.StandardOutput)
 	}
 }
+
+// TestRunPluginsAccountsTheResourceUsage tests that the step result holds the resources measured during the execution
+func TestRunPluginsAccountsTheResourceUsage(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	ctx := context.NewMockDefault()
+	config := contracts.Configuration{
+		PluginID:   testPlugin1,
+		PluginName: testPlugin1,
+	}
+	pluginState := contracts.PluginState{
+		Name:          testPlugin1,
+		Id:            testPlugin1,
+		Configuration: config,
+	}
+
+	// the plugin uploads its output while it runs
+	pluginInstance := new(PluginMock)
+	pluginInstance.On("Execute", ctx, config, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+		accounting.RecordUpload(4096)
+	}).Return()
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(pluginInstance, nil)
+	pluginRegistry := PluginRegistry{testPlugin1: pluginFactory}
+
+	ch := make(chan contracts.PluginResult, 1)
+	outputs := RunPlugins(ctx, []contracts.PluginState{pluginState}, contracts.IOConfiguration{}, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	pluginInstance.AssertExpectations(t)
+	if assert.NotNil(t, outputs[testPlugin1].ResourceUsage) {
+		assert.Equal(t, int64(4096), outputs[testPlugin1].ResourceUsage.UploadedBytes)
+		assert.True(t, outputs[testPlugin1].ResourceUsage.CPUTimeMillis >= 0)
+	}
+	// the usage is reported with the completion of the step as well
+	result := <-ch
+	assert.Equal(t, outputs[testPlugin1].ResourceUsage, result.ResourceUsage)
+}
+
+func TestIndependentStepCount(t *testing.T) {
+	independent := func(name string) contracts.PluginState {
+		return contracts.PluginState{Name: name, Configuration: contracts.Configuration{IsIndependent: true}}
+	}
+	dependent := func(name string) contracts.PluginState {
+		return contracts.PluginState{Name: name}
+	}
+	configurePackage := appconfig.PluginNameAwsConfigurePackage
+
+	assert.Equal(t, 1, independentStepCount([]contracts.PluginState{dependent(configurePackage), independent(configurePackage)}))
+	assert.Equal(t, 2, independentStepCount([]contracts.PluginState{independent(configurePackage), independent(configurePackage), dependent(configurePackage)}))
+	assert.Equal(t, 3, independentStepCount([]contracts.PluginState{independent(configurePackage), independent(configurePackage), independent(configurePackage)}))
+	// only configurePackage steps are run in parallel
+	assert.Equal(t, 1, independentStepCount([]contracts.PluginState{independent(testPlugin1), independent(testPlugin2)}))
+	assert.Equal(t, 1, independentStepCount([]contracts.PluginState{independent(configurePackage), independent(testPlugin1)}))
+}
+
+func TestRegisteredSSMPlugins(t *testing.T) {
+	origRegistry := SSMPluginRegistry
+	defer func() {
+		SSMPluginRegistry = origRegistry
+		SetSSMPluginRegistryLoader(nil)
+	}()
+	SSMPluginRegistry = nil
+
+	loads := 0
+	SetSSMPluginRegistryLoader(func() PluginRegistry {
+		loads++
+		return PluginRegistry{testPlugin1: new(PluginFactoryMock)}
+	})
+	assert.Equal(t, 0, loads)
+
+	// the registry is loaded once, when the plugins first run
+	assert.Contains(t, RegisteredSSMPlugins(), testPlugin1)
+	assert.Contains(t, RegisteredSSMPlugins(), testPlugin1)
+	assert.Equal(t, 1, loads)
+}
+
+func TestIsAllowedPlugin(t *testing.T) {
+	var appConfig appconfig.SsmagentConfig
+	// all plugins are allowed without allowlist
+	assert.True(t, isAllowedPlugin(appConfig, testPlugin1))
+
+	appConfig.Agent.AllowedPlugins = []string{appconfig.PluginNameAwsRunShellScript, testPlugin1}
+	assert.True(t, isAllowedPlugin(appConfig, testPlugin1))
+	assert.False(t, isAllowedPlugin(appConfig, testPlugin2))
+
+	// the disabled plugins override the allowed plugins
+	appConfig.Agent.DisabledPlugins = []string{testPlugin1}
+	assert.False(t, isAllowedPlugin(appConfig, testPlugin1))
+	appConfig.Agent.AllowedPlugins = nil
+	assert.False(t, isAllowedPlugin(appConfig, testPlugin1))
+	assert.True(t, isAllowedPlugin(appConfig, testPlugin2))
+}
+
+// pluginFunc is a plugin running a function, which unlike PluginMock can be executed concurrently
+type pluginFunc func(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler)
+
+func (f pluginFunc) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	f(context, config, cancelFlag, output)
+}
+
+// pluginFuncFactory creates a given plugin
+type pluginFuncFactory struct {
+	plugin T
+}
+
+func (f pluginFuncFactory) Create(context context.T) (T, error) {
+	return f.plugin, nil
+}
+
+func TestRunPluginsWithIndependentSteps(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	defer stubAccounting()()
+	origLimit := parallelStepsLimit
+	parallelStepsLimit = func() int { return 2 }
+	defer func() { parallelStepsLimit = origLimit }()
+	// the output of every step creates a CloudWatch Logs client, which would otherwise query the region from the metadata
+	platform.SetRegion("us-east-1")
+
+	stepNames := []string{"installA", "installB", "installC", "installD"}
+	pluginStates := make([]contracts.PluginState, 0, len(stepNames)+1)
+	release := make(map[string]chan struct{})
+	exitCodes := make(map[string]int)
+	for index, name := range stepNames {
+		release[name] = make(chan struct{})
+		exitCodes[name] = index + 1
+		pluginStates = append(pluginStates, contracts.PluginState{
+			Name: appconfig.PluginNameAwsConfigurePackage,
+			Id:   name,
+			Configuration: contracts.Configuration{
+				PluginID:      name,
+				PluginName:    appconfig.PluginNameAwsConfigurePackage,
+				IsIndependent: true,
+			},
+		})
+	}
+	// the step following the batch is not independent and only starts once the whole batch completed
+	pluginStates = append(pluginStates, contracts.PluginState{
+		Name:          testPlugin1,
+		Id:            testPlugin1,
+		Configuration: contracts.Configuration{PluginID: testPlugin1, PluginName: testPlugin1},
+	})
+
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	var events []string
+	started := make(chan string, len(stepNames))
+
+	packagePlugin := pluginFunc(func(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+		name := config.PluginID
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		started <- name
+
+		<-release[name]
+		// the exit code tells which step each result belongs to
+		output.SetExitCode(exitCodes[name])
+		output.SetStatus(contracts.ResultStatusSuccess)
+
+		lock.Lock()
+		running--
+		events = append(events, "end "+name)
+		lock.Unlock()
+	})
+	dependentPlugin := pluginFunc(func(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+		lock.Lock()
+		events = append(events, "start "+config.PluginID)
+		lock.Unlock()
+		output.SetStatus(contracts.ResultStatusSuccess)
+	})
+	pluginRegistry := PluginRegistry{
+		appconfig.PluginNameAwsConfigurePackage: pluginFuncFactory{packagePlugin},
+		testPlugin1:                             pluginFuncFactory{dependentPlugin},
+	}
+
+	waitStarted := func() string {
+		select {
+		case name := <-started:
+			return name
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a step to start")
+			return ""
+		}
+	}
+
+	orchestrationDir, _ := ioutil.TempDir("", "independentsteps")
+	defer os.RemoveAll(orchestrationDir)
+	ioConfig := contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir}
+
+	ch := make(chan contracts.PluginResult, len(pluginStates))
+	done := make(chan map[string]*contracts.PluginResult)
+	go func() {
+		done <- RunPlugins(context.NewMockDefault(), pluginStates, ioConfig, pluginRegistry, ch, task.NewChanneledCancelFlag())
+	}()
+
+	// the first two steps overlap and the limit holds the other ones back
+	firstSteps := []string{waitStarted(), waitStarted()}
+	sort.Strings(firstSteps)
+	assert.Equal(t, []string{"installA", "installB"}, firstSteps)
+	select {
+	case name := <-started:
+		t.Fatalf("step %v started while the limit was reached", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the steps complete out of order, each freeing a slot for the next one
+	close(release["installB"])
+	assert.Equal(t, "installC", waitStarted())
+	close(release["installA"])
+	assert.Equal(t, "installD", waitStarted())
+	close(release["installD"])
+	close(release["installC"])
+
+	var outputs map[string]*contracts.PluginResult
+	select {
+	case outputs = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the steps to complete")
+	}
+	close(ch)
+
+	assert.Equal(t, 2, maxRunning)
+	assert.Equal(t, 5, len(events))
+	assert.Equal(t, []string{"end installB", "end installA"}, events[:2])
+	lastSteps := append([]string{}, events[2:4]...)
+	sort.Strings(lastSteps)
+	assert.Equal(t, []string{"end installC", "end installD"}, lastSteps)
+	assert.Equal(t, "start "+testPlugin1, events[4])
+	assert.Equal(t, len(pluginStates), len(ch))
+	assert.Equal(t, len(pluginStates), len(outputs))
+	for _, name := range stepNames {
+		assert.Equal(t, name, outputs[name].PluginID)
+		assert.Equal(t, contracts.ResultStatusSuccess, outputs[name].Status)
+		assert.Equal(t, exitCodes[name], outputs[name].Code)
+	}
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs[testPlugin1].Status)
+}
@@ -20,6 +20,8 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -89,12 +91,17 @@ func (h *HealthCheck) scheduleUpdateHealth() {
 // updates SSM with the instance health information
 func (h *HealthCheck) updateHealth() {
 	log := h.context.Log()
-	log.Infof("%s reporting agent health.", name)
+	log.Infof("%s reporting agent health, instance metadata mode: %v.", name, platform.MetadataMode())
+	if tokenErr := platform.MetadataTokenError(); tokenErr != nil {
+		log.Warnf("%s instance metadata session token unavailable: %v", name, tokenErr)
+	}
 
 	var err error
 	//TODO when will status become inactive?
 	// If both ssm config and command is inactive => agent is inactive.
-	if _, err = h.service.UpdateInstanceInformation(log, version.Version, "Active", AgentName); err != nil {
+	_, err = h.service.UpdateInstanceInformation(log, version.Version, "Active", AgentName)
+	healthendpoint.SetHeartbeat(err)
+	if err != nil {
 		sdkutil.HandleAwsError(log, err, h.healthCheckStopPolicy)
 	}
 	return
@@ -156,7 +163,7 @@ func (h *HealthCheck) ModuleRequestStop(stopType contracts.StopType) (err error)
 	return nil
 }
 
-//ping sends an empty ping to the health service to identify if the service exists
+// ping sends an empty ping to the health service to identify if the service exists
 func (h *HealthCheck) ping() (err error) {
 	_, err = h.service.UpdateEmptyInstanceInformation(h.context.Log(), version.Version, AgentName)
 	return err
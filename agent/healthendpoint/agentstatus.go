@@ -0,0 +1,161 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// Status is the status of the agent served to ssm-cli for troubleshooting on the instance
+type Status struct {
+	Registration  RegistrationStatus `json:"registration"`
+	Health        Health             `json:"health"`
+	LastHeartbeat HeartbeatStatus    `json:"lastHeartbeat"`
+	Sessions      []DocumentStatus   `json:"sessions"`
+	Documents     []DocumentStatus   `json:"documents"`
+}
+
+// RegistrationStatus is the identity the agent is registered with
+type RegistrationStatus struct {
+	InstanceID      string `json:"instanceId,omitempty"`
+	Region          string `json:"region,omitempty"`
+	ManagedInstance bool   `json:"managedInstance"`
+	Version         string `json:"version"`
+	Error           string `json:"error,omitempty"`
+}
+
+// HeartbeatStatus is the result of the last UpdateInstanceInformation call of the agent
+type HeartbeatStatus struct {
+	Time  string `json:"time,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// DocumentStatus is a document or session running on the agent
+type DocumentStatus struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type"`
+	StartedAt string `json:"startedAt"`
+}
+
+// managedInstancePrefix is the prefix of the ids of the on-premises instances
+const managedInstancePrefix = "mi-"
+
+// activity holds the heartbeats and the running documents recorded by the other modules of the agent
+var activity struct {
+	sync.RWMutex
+	heartbeat HeartbeatStatus
+	documents map[string]DocumentStatus
+}
+
+// instanceIdentity returns the instance id and the region of the agent, it is stubbed in the tests
+var instanceIdentity = func() (instanceID string, region string, err error) {
+	if instanceID, err = platform.InstanceID(); err != nil {
+		return "", "", err
+	}
+	region, err = platform.Region()
+	return instanceID, region, err
+}
+
+// SetHeartbeat records the result of an UpdateInstanceInformation call
+func SetHeartbeat(err error) {
+	activity.Lock()
+	defer activity.Unlock()
+	activity.heartbeat = HeartbeatStatus{Time: timeNow().UTC().Format(time.RFC3339)}
+	if err != nil {
+		activity.heartbeat.Error = err.Error()
+	}
+}
+
+// Connected returns nil when the last UpdateInstanceInformation call of the agent succeeded, its error otherwise
+func Connected() error {
+	activity.RLock()
+	defer activity.RUnlock()
+	switch {
+	case activity.heartbeat.Time == "":
+		return errors.New("no heartbeat was sent yet")
+	case activity.heartbeat.Error != "":
+		return errors.New(activity.heartbeat.Error)
+	}
+	return nil
+}
+
+// DocumentStarted records a document or session the agent starts running
+func DocumentStarted(docState contracts.DocumentState) {
+	activity.Lock()
+	defer activity.Unlock()
+	if activity.documents == nil {
+		activity.documents = make(map[string]DocumentStatus)
+	}
+	activity.documents[docState.DocumentInformation.DocumentID] = DocumentStatus{
+		ID:        docState.DocumentInformation.DocumentID,
+		Name:      docState.DocumentInformation.DocumentName,
+		Type:      string(docState.DocumentType),
+		StartedAt: timeNow().UTC().Format(time.RFC3339),
+	}
+}
+
+// DocumentFinished records the end of a document or session
+func DocumentFinished(documentID string) {
+	activity.Lock()
+	defer activity.Unlock()
+	delete(activity.documents, documentID)
+}
+
+// CurrentStatus returns the status of the agent, the sessions and documents are sorted by start time
+func CurrentStatus() Status {
+	status := Status{
+		Registration: RegistrationStatus{Version: version.Version},
+		Health:       currentHealth(),
+		Sessions:     []DocumentStatus{},
+		Documents:    []DocumentStatus{},
+	}
+	instanceID, region, err := instanceIdentity()
+	if err != nil {
+		status.Registration.Error = err.Error()
+	}
+	status.Registration.InstanceID = instanceID
+	status.Registration.Region = region
+	status.Registration.ManagedInstance = strings.HasPrefix(instanceID, managedInstancePrefix)
+
+	activity.RLock()
+	status.LastHeartbeat = activity.heartbeat
+	for _, document := range activity.documents {
+		if document.Type == string(contracts.StartSession) {
+			status.Sessions = append(status.Sessions, document)
+		} else {
+			status.Documents = append(status.Documents, document)
+		}
+	}
+	activity.RUnlock()
+
+	for _, documents := range [][]DocumentStatus{status.Sessions, status.Documents} {
+		sort.Slice(documents, func(i, j int) bool {
+			if documents[i].StartedAt != documents[j].StartedAt {
+				return documents[i].StartedAt < documents[j].StartedAt
+			}
+			return documents[i].ID < documents[j].ID
+		})
+	}
+	return status
+}
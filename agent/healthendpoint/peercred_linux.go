@@ -0,0 +1,47 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package healthendpoint
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// peerUID returns the user of the process at the other end of a unix socket connection
+func peerUID(conn net.Conn) (uid int, supported bool, err error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, false, nil
+	}
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, true, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, true, err
+	}
+	if credErr != nil {
+		return 0, true, fmt.Errorf("failed to read the credentials of the peer: %v", credErr)
+	}
+	return int(cred.Uid), true, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package healthendpoint
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerUID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peercred")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	listener, err := net.Listen("unix", filepath.Join(dir, "peer.sock"))
+	assert.NoError(t, err)
+	defer listener.Close()
+	client, err := net.Dial("unix", listener.Addr().String())
+	assert.NoError(t, err)
+	defer client.Close()
+	conn, err := listener.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	uid, supported, err := peerUID(conn)
+	assert.NoError(t, err)
+	assert.True(t, supported)
+	assert.Equal(t, os.Geteuid(), uid)
+}
+
+func TestPeerUID_NotUnixSocket(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	_, supported, err := peerUID(server)
+	assert.NoError(t, err)
+	assert.False(t, supported)
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/systemd"
+)
+
+const (
+	name = "HealthEndpoint"
+
+	// healthPath is the path the health is served on
+	healthPath = "/health"
+
+	// healthSocketName is the FileDescriptorName of the health socket passed by the socket activation of systemd
+	healthSocketName = "health"
+)
+
+// credentialsInterval is the interval at which the credentials are retrieved, the probes only read the last result
+var credentialsInterval = time.Minute
+
+// retrieveCredentials retrieves the credentials of the agent and returns their provider, it is stubbed in the tests
+var retrieveCredentials = func() (string, error) {
+	credentials := sdkutil.AwsConfig().Credentials
+	if credentials == nil {
+		return "", fmt.Errorf("no credentials provider is available")
+	}
+	value, err := credentials.Get()
+	return value.ProviderName, err
+}
+
+// Server is the core module serving the health of the agent
+type Server struct {
+	context    context.T
+	address    string
+	socketPath string
+	servers    []*http.Server
+	stop       chan bool
+	done       chan bool
+}
+
+// NewServer creates the health endpoint, nil when neither a port nor a socket is configured in appconfig
+func NewServer(context context.T) *Server {
+	config := context.AppConfig().HealthEndpoint
+	if config.Port == 0 && config.SocketPath == "" {
+		return nil
+	}
+	server := &Server{
+		context:    context.With("[" + name + "]"),
+		socketPath: config.SocketPath,
+		stop:       make(chan bool),
+		done:       make(chan bool),
+	}
+	if config.Port != 0 {
+		server.address = fmt.Sprintf("127.0.0.1:%v", config.Port)
+	}
+	return server
+}
+
+// ModuleName returns the name of the module
+func (s *Server) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts retrieving the credentials and serving the health
+func (s *Server) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	var listeners []net.Listener
+	if s.address != "" {
+		listener, err := net.Listen("tcp", s.address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %v for the health endpoint: %v", s.address, err)
+		}
+		listeners = append(listeners, listener)
+		log.Infof("Serving health on http://%v%v", s.address, healthPath)
+	}
+	if listener := systemd.Listener(healthSocketName); listener != nil {
+		listeners = append(listeners, listener)
+		log.Infof("Serving health on the socket passed by systemd at %v", healthPath)
+	} else if s.socketPath != "" {
+		// a socket left by a previous agent would fail the listen
+		os.Remove(s.socketPath)
+		listener, err := net.Listen("unix", s.socketPath)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("failed to listen on %v for the health endpoint: %v", s.socketPath, err)
+		}
+		listeners = append(listeners, listener)
+		log.Infof("Serving health on unix socket %v at %v", s.socketPath, healthPath)
+	}
+
+	go s.refreshCredentials()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, handleHealth)
+	for _, listener := range listeners {
+		server := &http.Server{Handler: mux}
+		s.servers = append(s.servers, server)
+		go func(listener net.Listener) {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Errorf("health endpoint stopped: %v", err)
+			}
+		}(listener)
+	}
+	return nil
+}
+
+// ModuleRequestStop stops serving the health
+func (s *Server) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	for _, server := range s.servers {
+		if closeErr := server.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	if len(s.servers) > 0 {
+		close(s.stop)
+		<-s.done
+	}
+	return err
+}
+
+// refreshCredentials retrieves the credentials at every credentials interval
+func (s *Server) refreshCredentials() {
+	defer close(s.done)
+	ticker := time.NewTicker(credentialsInterval)
+	defer ticker.Stop()
+	for {
+		provider, err := retrieveCredentials()
+		if err != nil {
+			s.context.Log().Debugf("Failed to retrieve the credentials for the health endpoint: %v", err)
+		}
+		setCredentials(provider, err)
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleHealth writes the health of the agent, with the 503 status code when the agent is not online
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := currentHealth()
+	w.Header().Set("Content-Type", "application/json")
+	if health.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
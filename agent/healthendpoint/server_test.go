@@ -0,0 +1,141 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithHealthEndpoint(port int, socketPath string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.HealthEndpoint.Port = port
+	config.HealthEndpoint.SocketPath = socketPath
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func getHealth(t *testing.T, client *http.Client, url string) (int, Health) {
+	resp, err := client.Get(url)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var health Health
+	assert.NoError(t, json.Unmarshal(body, &health))
+	return resp.StatusCode, health
+}
+
+// stubCredentials stubs the retrieval of the credentials, the returned channel receives a value after the first retrieval
+func stubCredentials(provider string, err error) chan bool {
+	retrieved := make(chan bool, 1)
+	retrieveCredentials = func() (string, error) {
+		setCredentials(provider, err)
+		select {
+		case retrieved <- true:
+		default:
+		}
+		return provider, err
+	}
+	return retrieved
+}
+
+func TestNewServer_Disabled(t *testing.T) {
+	assert.Nil(t, NewServer(mockContextWithHealthEndpoint(0, "")))
+}
+
+func TestServer_ServesHealthOnPort(t *testing.T) {
+	retrieved := stubCredentials("EC2RoleProvider", nil)
+	SetControlChannelConnected(false)
+	metrics.WorkerProcesses.Inc("document")
+	defer metrics.WorkerProcesses.Dec("document")
+
+	ctx := mockContextWithHealthEndpoint(freePort(t), "")
+	server := NewServer(ctx)
+	assert.NotNil(t, server)
+	assert.Equal(t, name, server.ModuleName())
+	assert.NoError(t, server.ModuleExecute(ctx))
+	defer server.ModuleRequestStop(contracts.StopTypeSoftStop)
+	<-retrieved
+
+	url := "http://" + server.address + healthPath
+	status, health := getHealth(t, http.DefaultClient, url)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, statusUnhealthy, health.Status)
+	assert.True(t, health.Credentials.Valid)
+	assert.Equal(t, "EC2RoleProvider", health.Credentials.Provider)
+	assert.Equal(t, int64(1), health.Workers["document"].Running)
+
+	SetControlChannelConnected(true)
+	status, health = getHealth(t, http.DefaultClient, url)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, statusOK, health.Status)
+	assert.True(t, health.ControlChannel.Connected)
+	assert.NotEmpty(t, health.ControlChannel.Since)
+}
+
+func TestServer_ServesHealthOnSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available on all windows versions")
+	}
+	dir, err := ioutil.TempDir("", "health")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "health.sock")
+
+	retrieved := stubCredentials("", errors.New("no credentials"))
+	SetControlChannelConnected(true)
+
+	ctx := mockContextWithHealthEndpoint(0, socketPath)
+	server := NewServer(ctx)
+	assert.NoError(t, server.ModuleExecute(ctx))
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		},
+	}}
+	<-retrieved
+	status, health := getHealth(t, client, "http://unix"+healthPath)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.False(t, health.Credentials.Valid)
+	assert.Equal(t, "no credentials", health.Credentials.Error)
+	assert.NoError(t, server.ModuleRequestStop(contracts.StopTypeSoftStop))
+}
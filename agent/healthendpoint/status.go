@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package healthendpoint serves the health of the agent on a localhost port or a Unix socket, for liveness
+// probes and bootstrap scripts waiting for the agent to be online, and the status of the agent to ssm-cli.
+package healthendpoint
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+)
+
+// Worker kinds reported in the health, they match the worker label of the metrics
+var workerKinds = []string{"document", "session"}
+
+// Health is the health of the agent served on the endpoint
+type Health struct {
+	Status         string                  `json:"status"`
+	ControlChannel ControlChannelHealth    `json:"controlChannel"`
+	Credentials    CredentialsHealth       `json:"credentials"`
+	Workers        map[string]WorkerHealth `json:"workers"`
+}
+
+// ControlChannelHealth is the connectivity of the control channel to the session service
+type ControlChannelHealth struct {
+	Connected bool   `json:"connected"`
+	Since     string `json:"since,omitempty"`
+}
+
+// CredentialsHealth is the result of the last retrieval of the credentials of the agent
+type CredentialsHealth struct {
+	Valid     bool   `json:"valid"`
+	Provider  string `json:"provider,omitempty"`
+	CheckedAt string `json:"checkedAt,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// WorkerHealth is the number of running and failed worker processes of a kind
+type WorkerHealth struct {
+	Running  int64 `json:"running"`
+	Failures int64 `json:"failures"`
+}
+
+// Health statuses
+const (
+	statusOK        = "ok"
+	statusUnhealthy = "unhealthy"
+)
+
+// state holds the health recorded by the other modules of the agent
+var state struct {
+	sync.RWMutex
+	controlChannel ControlChannelHealth
+	credentials    CredentialsHealth
+}
+
+// timeNow returns the current time, it is stubbed in the tests
+var timeNow = time.Now
+
+// SetControlChannelConnected records whether the control channel is connected to the session service
+func SetControlChannelConnected(connected bool) {
+	state.Lock()
+	defer state.Unlock()
+	if state.controlChannel.Connected == connected && state.controlChannel.Since != "" {
+		return
+	}
+	state.controlChannel = ControlChannelHealth{
+		Connected: connected,
+		Since:     timeNow().UTC().Format(time.RFC3339),
+	}
+}
+
+// setCredentials records the result of a retrieval of the credentials
+func setCredentials(provider string, err error) {
+	state.Lock()
+	defer state.Unlock()
+	state.credentials = CredentialsHealth{
+		Valid:     err == nil,
+		Provider:  provider,
+		CheckedAt: timeNow().UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		state.credentials.Error = err.Error()
+	}
+}
+
+// currentHealth returns the health of the agent, which is ok when the control channel is connected with valid credentials
+func currentHealth() Health {
+	state.RLock()
+	health := Health{
+		ControlChannel: state.controlChannel,
+		Credentials:    state.credentials,
+		Workers:        make(map[string]WorkerHealth),
+	}
+	state.RUnlock()
+
+	for _, kind := range workerKinds {
+		health.Workers[kind] = WorkerHealth{
+			Running:  metrics.WorkerProcesses.Value(kind),
+			Failures: metrics.WorkerFailures.Value(kind),
+		}
+	}
+	health.Status = statusUnhealthy
+	if health.ControlChannel.Connected && health.Credentials.Valid {
+		health.Status = statusOK
+	}
+	return health
+}
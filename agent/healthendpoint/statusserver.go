@@ -0,0 +1,206 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/systemd"
+)
+
+const (
+	statusServerName = "StatusServer"
+
+	// statusSocketName is the FileDescriptorName of the status socket passed by the socket activation of systemd
+	statusSocketName = "status"
+
+	// statusPath is the path the status is served on
+	statusPath = "/status"
+
+	// statusQueryTimeout is how long ssm-cli waits for the status of the agent
+	statusQueryTimeout = 10 * time.Second
+)
+
+// StatusSocketPath returns the Unix socket the agent serves its status on, it is stubbed in the tests
+var StatusSocketPath = func() string {
+	return filepath.Join(appconfig.DefaultDataStorePath, appconfig.StatusSocketName)
+}
+
+// StatusServer is the core module serving the status of the agent to ssm-cli on a Unix socket only root can use
+type StatusServer struct {
+	context    context.T
+	socketPath string
+	server     *http.Server
+	activated  bool
+}
+
+// NewStatusServer creates the core module serving the status of the agent
+func NewStatusServer(context context.T) *StatusServer {
+	return &StatusServer{
+		context:    context.With("[" + statusServerName + "]"),
+		socketPath: StatusSocketPath(),
+	}
+}
+
+// ModuleName returns the name of the module
+func (s *StatusServer) ModuleName() string {
+	return statusServerName
+}
+
+// ModuleExecute starts serving the status, the agent runs without it when the socket is not available
+func (s *StatusServer) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	listener := systemd.Listener(statusSocketName)
+	if listener != nil {
+		// the socket unit owns the socket and its access rights
+		s.activated = true
+		log.Infof("Serving the agent status on the socket passed by systemd at %v", statusPath)
+	} else {
+		if listener, err = s.listen(); err != nil {
+			log.Warnf("%v", err)
+			return nil
+		}
+		log.Infof("Serving the agent status on unix socket %v at %v", s.socketPath, statusPath)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(statusPath, handleStatus)
+	s.server = &http.Server{Handler: mux}
+	go func() {
+		if err := s.server.Serve(NewPeerCheckListener(listener, log)); err != nil && err != http.ErrServerClosed {
+			log.Errorf("status server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// ModuleRequestStop stops serving the status
+func (s *StatusServer) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if s.server == nil {
+		return nil
+	}
+	err = s.server.Close()
+	if !s.activated {
+		os.Remove(s.socketPath)
+	}
+	return err
+}
+
+// listen creates the status socket, only root can use it
+func (s *StatusServer) listen() (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), appconfig.ReadWriteExecuteAccess); err != nil {
+		return nil, fmt.Errorf("failed to create the folder of the status socket %v: %v", s.socketPath, err)
+	}
+	// a socket left by a previous agent would fail the listen
+	os.Remove(s.socketPath)
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %v for the status of the agent: %v", s.socketPath, err)
+	}
+	if err = os.Chmod(s.socketPath, appconfig.ReadWriteAccess); err != nil {
+		s.context.Log().Warnf("Failed to restrict the access to the status socket %v: %v", s.socketPath, err)
+	}
+	return listener, nil
+}
+
+// readPeerUID returns the user of the process at the other end of a connection, it is stubbed in the tests
+var readPeerUID = peerUID
+
+// peerCheckListener closes the connections of the users other than root and the agent user,
+// the access rights of the socket can be loosened by the socket unit or by an administrator
+type peerCheckListener struct {
+	net.Listener
+	log log.T
+}
+
+// NewPeerCheckListener returns a listener of the Unix socket accepting the connections of root and the agent user only
+func NewPeerCheckListener(listener net.Listener, log log.T) net.Listener {
+	return &peerCheckListener{Listener: listener, log: log}
+}
+
+// Accept returns the next connection of an allowed user
+func (l *peerCheckListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.allowed(conn) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
+
+// allowed checks the user of the process at the other end of the connection
+func (l *peerCheckListener) allowed(conn net.Conn) bool {
+	uid, supported, err := readPeerUID(conn)
+	if !supported {
+		return true
+	}
+	if err != nil {
+		l.log.Warnf("Rejecting a connection to %v, %v", l.Addr(), err)
+		return false
+	}
+	if uid != 0 && uid != os.Geteuid() {
+		l.log.Warnf("Rejecting a connection to %v from the user %v", l.Addr(), uid)
+		return false
+	}
+	return true
+}
+
+// handleStatus writes the status of the agent
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CurrentStatus())
+}
+
+// QueryStatus returns the status of the agent running on the instance
+func QueryStatus() (status Status, err error) {
+	socketPath := StatusSocketPath()
+	client := &http.Client{
+		Timeout: statusQueryTimeout,
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+	resp, err := client.Get("http://agent" + statusPath)
+	if err != nil {
+		return status, fmt.Errorf("failed to query the agent on %v, make sure the agent is running and that you have admin rights: %v", socketPath, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return status, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("the agent returned %v", resp.Status)
+	}
+	err = json.Unmarshal(body, &status)
+	return status, err
+}
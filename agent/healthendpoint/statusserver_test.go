@@ -0,0 +1,192 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubStatus stubs the identity of the instance and the clock, and clears the recorded activity
+func stubStatus(t *testing.T, instanceID string, err error) {
+	instanceIdentityOrig, timeNowOrig := instanceIdentity, timeNow
+	t.Cleanup(func() {
+		instanceIdentity, timeNow = instanceIdentityOrig, timeNowOrig
+		activity.Lock()
+		activity.heartbeat, activity.documents = HeartbeatStatus{}, nil
+		activity.Unlock()
+	})
+	instanceIdentity = func() (string, string, error) { return instanceID, "us-west-2", err }
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+func documentState(id string, documentType contracts.DocumentType) contracts.DocumentState {
+	return contracts.DocumentState{
+		DocumentInformation: contracts.DocumentInfo{DocumentID: id, DocumentName: "AWS-RunShellScript"},
+		DocumentType:        documentType,
+	}
+}
+
+func TestCurrentStatus(t *testing.T) {
+	stubStatus(t, "mi-0123456789abcdef0", nil)
+	SetHeartbeat(errors.New("AccessDeniedException"))
+	DocumentStarted(documentState("command-2", contracts.SendCommand))
+	DocumentStarted(documentState("session-1", contracts.StartSession))
+	DocumentStarted(documentState("command-1", contracts.Association))
+	DocumentFinished("command-2")
+
+	status := CurrentStatus()
+
+	assert.Equal(t, "mi-0123456789abcdef0", status.Registration.InstanceID)
+	assert.Equal(t, "us-west-2", status.Registration.Region)
+	assert.True(t, status.Registration.ManagedInstance)
+	assert.Equal(t, HeartbeatStatus{Time: "2019-01-01T00:01:00Z", Error: "AccessDeniedException"}, status.LastHeartbeat)
+	assert.Equal(t, []DocumentStatus{
+		{ID: "session-1", Name: "AWS-RunShellScript", Type: "StartSession", StartedAt: "2019-01-01T00:03:00Z"},
+	}, status.Sessions)
+	assert.Equal(t, []DocumentStatus{
+		{ID: "command-1", Name: "AWS-RunShellScript", Type: "Association", StartedAt: "2019-01-01T00:04:00Z"},
+	}, status.Documents)
+}
+
+func TestCurrentStatus_NoIdentity(t *testing.T) {
+	stubStatus(t, "", errors.New("no instance id"))
+
+	status := CurrentStatus()
+
+	assert.Equal(t, "no instance id", status.Registration.Error)
+	assert.False(t, status.Registration.ManagedInstance)
+	assert.Empty(t, status.LastHeartbeat.Time)
+	assert.Empty(t, status.Sessions)
+	assert.Empty(t, status.Documents)
+}
+
+func TestStatusServer_ServesStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available on all windows versions")
+	}
+	dir, err := ioutil.TempDir("", "status")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPathOrig := StatusSocketPath
+	defer func() { StatusSocketPath = socketPathOrig }()
+	StatusSocketPath = func() string { return filepath.Join(dir, "ipc", "status.sock") }
+	stubStatus(t, "i-12345678", nil)
+	DocumentStarted(documentState("session-1", contracts.StartSession))
+
+	ctx := mockContextWithHealthEndpoint(0, "")
+	server := NewStatusServer(ctx)
+	assert.Equal(t, statusServerName, server.ModuleName())
+	assert.NoError(t, server.ModuleExecute(ctx))
+	info, err := os.Stat(StatusSocketPath())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	status, err := QueryStatus()
+	assert.NoError(t, err)
+	assert.Equal(t, "i-12345678", status.Registration.InstanceID)
+	assert.False(t, status.Registration.ManagedInstance)
+	assert.Len(t, status.Sessions, 1)
+
+	assert.NoError(t, server.ModuleRequestStop(contracts.StopTypeSoftStop))
+	_, err = QueryStatus()
+	assert.Error(t, err)
+}
+
+func TestConnected(t *testing.T) {
+	stubStatus(t, "i-0123456789abcdef0", nil)
+	assert.Error(t, Connected())
+	SetHeartbeat(errors.New("AccessDeniedException"))
+	assert.EqualError(t, Connected(), "AccessDeniedException")
+	SetHeartbeat(nil)
+	assert.NoError(t, Connected())
+}
+
+func TestPeerCheckListener(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available on all windows versions")
+	}
+	dir, err := ioutil.TempDir("", "status")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	listener, err := net.Listen("unix", filepath.Join(dir, "status.sock"))
+	assert.NoError(t, err)
+	defer listener.Close()
+	readPeerUIDOrig := readPeerUID
+	defer func() { readPeerUID = readPeerUIDOrig }()
+	// the first peer is another user, then a peer whose credentials can't be read, then the agent user
+	peers := []struct {
+		uid int
+		err error
+	}{{1234, nil}, {0, errors.New("no credentials")}, {os.Geteuid(), nil}}
+	readPeerUID = func(conn net.Conn) (int, bool, error) {
+		peer := peers[0]
+		peers = peers[1:]
+		return peer.uid, true, peer.err
+	}
+
+	var clients []net.Conn
+	for i := 0; i < 3; i++ {
+		client, err := net.Dial("unix", listener.Addr().String())
+		assert.NoError(t, err)
+		defer client.Close()
+		clients = append(clients, client)
+	}
+	conn, err := NewPeerCheckListener(listener, log.NewMockLog()).Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Empty(t, peers)
+
+	// the rejected connections are closed, the accepted one is served
+	for _, client := range clients[:2] {
+		client.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = client.Read(make([]byte, 1))
+		assert.Equal(t, io.EOF, err)
+	}
+	_, err = clients[2].Write([]byte("x"))
+	assert.NoError(t, err)
+	buf := make([]byte, 1)
+	_, err = conn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", string(buf))
+}
+
+func TestPeerCheckListener_NotSupported(t *testing.T) {
+	readPeerUIDOrig := readPeerUID
+	defer func() { readPeerUID = readPeerUIDOrig }()
+	readPeerUID = func(conn net.Conn) (int, bool, error) { return 1234, false, nil }
+	server, client := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// the access rights of the socket restrict the users where the peer can't be checked
+	listener := &peerCheckListener{log: log.NewMockLog()}
+	assert.True(t, listener.allowed(server))
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// bearerPrefix starts the Authorization header of the requests carrying a bearer token
+const bearerPrefix = "Bearer "
+
+// ReadBearerToken reads the bearer token the requests on a localhost port must carry
+func ReadBearerToken(tokenFile string) ([]byte, error) {
+	content, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the bearer token: %v", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return nil, fmt.Errorf("the bearer token file %v is empty", tokenFile)
+	}
+	return []byte(token), nil
+}
+
+// HasBearerToken returns whether the request carries the bearer token in its Authorization header
+func HasBearerToken(r *http.Request, token []byte) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, bearerPrefix)), token) == 1
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package healthendpoint
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBearerToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+
+	_, err = ReadBearerToken(tokenFile)
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte(" \n"), 0600))
+	_, err = ReadBearerToken(tokenFile)
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+	token, err := ReadBearerToken(tokenFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(token))
+}
+
+func TestHasBearerToken(t *testing.T) {
+	token := []byte("secret")
+	for header, expected := range map[string]bool{
+		"":              false,
+		"secret":        false,
+		"Bearer":        false,
+		"Bearer other":  false,
+		"Bearer secret": true,
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		assert.Equal(t, expected, HasBearerToken(r, token), header)
+	}
+}
@@ -11,18 +11,26 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package hibernation is responsible for the agent in hibernate mode.
 package hibernation
 
+import (
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
 var seelogConfig = `<seelog type="adaptive" mininterval="2000000" maxinterval="100000000" critmsgcount="500" minlevel="error">
 	<exceptions>
 		<exception filepattern="*hibernation.go" minlevel="info"/>
+		<exception filepattern="*wake.go" minlevel="info"/>
 	</exceptions>
 	<outputs formatid="fmtinfo">
 		<console formatid="fmtinfo"/>
-		<rollingfile type="size" filename="/var/log/amazon/ssm/hibernate.log" maxsize="30000" maxrolls="2"/>
+		<rollingfile type="size" filename="` + filepath.Join(log.DefaultLogDir, "hibernate.log") + `" maxsize="30000" maxrolls="2"/>
 	</outputs>
 	<formats>
 		<format id="fmtinfo" format="%Date %Time %LEVEL %Msg%n"/>
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package hibernation is responsible for the agent in hibernate mode.
@@ -19,6 +20,7 @@ package hibernation
 var seelogConfig = `<seelog type="adaptive" mininterval="2000000" maxinterval="100000000" critmsgcount="500" minlevel="error">
 	<exceptions>
 		<exception filepattern="*hibernation.go" maxlevel="info"/>
+		<exception filepattern="*wake.go" maxlevel="info"/>
 	</exceptions>
 	<outputs formatid="fmtinfo">
 		<console formatid="fmtinfo"/>
@@ -13,10 +13,11 @@
 
 // Package hibernation is responsible for the agent in hibernate mode.
 // It depends on health pings in an exponential backoff to check if the agent needs
-// to move to active mode.
+// to move to active mode, and on network and clock events to check it right away.
 package hibernation
 
 import (
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -40,6 +41,12 @@ type Hibernate struct {
 	maxInterval         int
 	scheduleBackOff     func(m *Hibernate)
 	schedulePing        func(m *Hibernate)
+	startWakeWatchers   func(m *Hibernate)
+
+	wakeLock    sync.Mutex
+	stopWake    chan bool
+	wakePending bool
+	lastWake    time.Time
 
 	seelogger seelog.LoggerInterface
 	isLogged  bool
@@ -71,6 +78,7 @@ func NewHibernateMode(healthModule health.IHealthCheck, context context.T) *Hibe
 		maxInterval:         maxBackOffInterval,
 		scheduleBackOff:     scheduleBackOffStrategy,
 		schedulePing:        scheduleEmptyHealthPing,
+		startWakeWatchers:   watchWakeEvents,
 	}
 }
 
@@ -78,25 +86,26 @@ func NewHibernateMode(healthModule health.IHealthCheck, context context.T) *Hibe
 func (m *Hibernate) ExecuteHibernation() health.AgentState {
 	next := time.Duration(initialPingRate) * time.Second
 	m.seelogger.Info("Agent is in hibernate mode. Reducing logging. Logging will be reduced to one log per backoff period")
+	// Check the health as soon as the network or the clock changes
+	m.startWakeWatchers(m)
+	defer m.stopWakeWatchers()
 	// Wait backoff time and then schedule health pings
-	<-time.After(next)
-	m.scheduleBackOff(m)
+	backOff := time.After(next)
 
-loop:
 	// using an infinite loop to block the agent from starting
 	for {
-		// block and wait for health mode to be active
-		status := <-modeChan
-		switch status {
-		case health.Active:
-			//Agent mode is now active. Agent can start. Exit loop
-			m.stopEmptyPing()
-			m.seelogger.Flush()
-			return status //returning status for testing purposes.
-		case health.Passive:
-			continue loop
-		default:
-			continue loop
+		select {
+		case <-backOff:
+			m.scheduleBackOff(m)
+			backOff = nil
+		case status := <-modeChan:
+			// block and wait for health mode to be active
+			if status == health.Active {
+				//Agent mode is now active. Agent can start. Exit loop
+				m.stopEmptyPing()
+				m.seelogger.Flush()
+				return status //returning status for testing purposes.
+			}
 		}
 	}
 }
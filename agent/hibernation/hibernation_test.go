@@ -22,6 +22,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/health"
+	healthmock "github.com/aws/amazon-ssm-agent/agent/health/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,6 +33,7 @@ func TestHibernation_ExecuteHibernation_AgentTurnsActive(t *testing.T) {
 
 	hibernate := NewHibernateMode(healthMock, ctx)
 	hibernate.scheduleBackOff = fakeScheduler
+	hibernate.startWakeWatchers = fakeScheduler
 	for i := 0; i < 4; i++ {
 		modeChan <- health.Passive
 	}
@@ -67,6 +69,44 @@ func TestHibernation_scheduleBackOffStrategy(t *testing.T) {
 	assert.Equal(t, 4, hibernate.currentPingInterval) // maxInterval is 4
 }
 
+func TestHibernation_wake_CoalescesEvents(t *testing.T) {
+	healthMock := &healthmock.IHealthCheck{}
+	healthMock.On("GetAgentState").Return(health.Passive, nil)
+
+	hibernate := NewHibernateMode(healthMock, context.NewMockDefault())
+	hibernate.stopWake = make(chan bool)
+
+	hibernate.wake("network address change")
+	hibernate.wake("network route change")
+	assert.True(t, hibernate.wakePending)
+
+	hibernate.stopWakeWatchers()
+	time.Sleep(wakeSettleDelay + time.Second)
+	assert.False(t, hibernate.wakePending)
+	assert.Empty(t, modeChan)
+
+	// events after the watchers are stopped are ignored
+	hibernate.wake("network address change")
+	assert.False(t, hibernate.wakePending)
+}
+
+func TestHibernation_wake_ChecksHealth(t *testing.T) {
+	healthMock := &healthmock.IHealthCheck{}
+	healthMock.On("GetAgentState").Return(health.Passive, nil)
+
+	hibernate := NewHibernateMode(healthMock, context.NewMockDefault())
+	hibernate.stopWake = make(chan bool)
+	hibernate.wake("clock synchronization")
+
+	select {
+	case <-modeChan:
+	case <-time.After(wakeSettleDelay + 5*time.Second):
+		assert.Fail(t, "the health was not checked after the wake event")
+	}
+	assert.False(t, hibernate.lastWake.IsZero())
+	hibernate.stopWakeWatchers()
+}
+
 func fakeScheduler(*Hibernate) {
 	//Do nothing
 }
@@ -0,0 +1,79 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"time"
+)
+
+const (
+	// wakeSettleDelay lets the network settle after a wake event, the events following it are coalesced
+	wakeSettleDelay = 2 * time.Second
+	// wakeMinInterval is the minimum time between two health checks triggered by wake events
+	wakeMinInterval = 30 * time.Second
+)
+
+// wakeWatcher watches a source of events after which the service may be reachable again,
+// calling wake for every event until stop is closed
+type wakeWatcher func(stop chan bool, wake func(reason string)) error
+
+// watchWakeEvents starts the wake watchers of the platform
+func watchWakeEvents(m *Hibernate) {
+	m.stopWake = make(chan bool)
+	for _, watcher := range wakeWatchers {
+		go func(watch wakeWatcher, stop chan bool) {
+			if err := watch(stop, m.wake); err != nil {
+				m.seelogger.Debugf("Unable to watch wake events. %v", err)
+			}
+		}(watcher, m.stopWake)
+	}
+}
+
+// stopWakeWatchers stops the wake watchers
+func (m *Hibernate) stopWakeWatchers() {
+	m.wakeLock.Lock()
+	defer m.wakeLock.Unlock()
+	if m.stopWake != nil {
+		close(m.stopWake)
+		m.stopWake = nil
+	}
+}
+
+// wake schedules a health check once the network settled, without waiting for the next health ping
+func (m *Hibernate) wake(reason string) {
+	m.wakeLock.Lock()
+	defer m.wakeLock.Unlock()
+	if m.wakePending || m.stopWake == nil {
+		return
+	}
+	m.wakePending = true
+
+	delay := wakeSettleDelay
+	if sinceLast := time.Since(m.lastWake); sinceLast < wakeMinInterval-wakeSettleDelay {
+		delay = wakeMinInterval - sinceLast
+	}
+	m.seelogger.Infof("Detected a %v, checking the agent health in %v", reason, delay)
+	time.AfterFunc(delay, func() {
+		m.wakeLock.Lock()
+		m.wakePending = false
+		m.lastWake = time.Now()
+		stopped := m.stopWake == nil
+		m.wakeLock.Unlock()
+		if !stopped {
+			m.healthCheck()
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || netbsd || openbsd
+// +build darwin freebsd netbsd openbsd
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"os"
+	"syscall"
+)
+
+// rtmTypeOffset is the offset of the type in the header of routing messages
+const rtmTypeOffset = 3
+
+// wakeWatchers watch the changes of the network interfaces, addresses and routes, which include DHCP renewals
+var wakeWatchers = []wakeWatcher{watchRouteSocket}
+
+// watchRouteSocket watches the messages of the routing socket
+func watchRouteSocket(stop chan bool, wake func(reason string)) error {
+	fd, err := syscall.Socket(syscall.AF_ROUTE, syscall.SOCK_RAW, syscall.AF_UNSPEC)
+	if err != nil {
+		return err
+	}
+	if err = syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "route")
+	go func() {
+		<-stop
+		file.Close()
+	}()
+
+	buffer := make([]byte, os.Getpagesize())
+	for {
+		n, err := file.Read(buffer)
+		if err != nil {
+			return nil
+		}
+		if n <= rtmTypeOffset {
+			continue
+		}
+		switch buffer[rtmTypeOffset] {
+		case syscall.RTM_IFINFO:
+			wake("network interface change")
+		case syscall.RTM_NEWADDR:
+			wake("network address change")
+		case syscall.RTM_ADD, syscall.RTM_CHANGE:
+			wake("network route change")
+		}
+	}
+}
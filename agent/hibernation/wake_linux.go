@@ -0,0 +1,125 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// clockRealtime is the clock watched for synchronizations
+	clockRealtime = 0
+
+	tfdTimerAbstime     = 1
+	tfdTimerCancelOnSet = 2
+
+	// route netlink multicast groups
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv4Route  = 0x40
+	rtmgrpIPv6IfAddr = 0x100
+	rtmgrpIPv6Route  = 0x400
+)
+
+// itimerspec is the timer specification of timerfd_settime
+type itimerspec struct {
+	interval syscall.Timespec
+	value    syscall.Timespec
+}
+
+// wakeWatchers watch the changes of the network interfaces, addresses and routes, which include DHCP renewals,
+// and the synchronizations of the clock
+var wakeWatchers = []wakeWatcher{watchNetlink, watchClockSet}
+
+// closeOnStop closes a file, which unblocks its reads, when stop is closed
+func closeOnStop(file *os.File, stop chan bool) {
+	<-stop
+	file.Close()
+}
+
+// watchNetlink watches the route netlink groups of the links, addresses and routes
+func watchNetlink(stop chan bool, wake func(reason string)) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpLink | rtmgrpIPv4IfAddr | rtmgrpIPv6IfAddr | rtmgrpIPv4Route | rtmgrpIPv6Route,
+	}
+	if err = syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "netlink")
+	go closeOnStop(file, stop)
+
+	buffer := make([]byte, os.Getpagesize())
+	for {
+		n, err := file.Read(buffer)
+		if err != nil {
+			return nil
+		}
+		messages, err := syscall.ParseNetlinkMessage(buffer[:n])
+		if err != nil {
+			continue
+		}
+		for _, message := range messages {
+			switch message.Header.Type {
+			case syscall.RTM_NEWLINK:
+				wake("network interface change")
+			case syscall.RTM_NEWADDR:
+				wake("network address change")
+			case syscall.RTM_NEWROUTE:
+				wake("network route change")
+			}
+		}
+	}
+}
+
+// watchClockSet watches the clock being set, which happens when NTP synchronizes a clock that was off,
+// with a timer in the far future canceled by the kernel when the clock is set
+func watchClockSet(stop chan bool, wake func(reason string)) error {
+	fd, _, errno := syscall.Syscall(syscall.SYS_TIMERFD_CREATE, clockRealtime, syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if errno != 0 {
+		return errno
+	}
+	file := os.NewFile(fd, "timerfd")
+	go closeOnStop(file, stop)
+
+	buffer := make([]byte, 8)
+	for {
+		spec := itimerspec{value: syscall.NsecToTimespec(time.Now().AddDate(10, 0, 0).UnixNano())}
+		if _, _, errno = syscall.Syscall6(syscall.SYS_TIMERFD_SETTIME, fd, tfdTimerAbstime|tfdTimerCancelOnSet,
+			uintptr(unsafe.Pointer(&spec)), 0, 0, 0); errno != 0 {
+			file.Close()
+			return errno
+		}
+		if _, err := file.Read(buffer); err != nil {
+			if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.ECANCELED {
+				wake("clock synchronization")
+				continue
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+// Package hibernation is responsible for the agent in hibernate mode.
+package hibernation
+
+import (
+	"syscall"
+)
+
+var (
+	iphlpapi             = syscall.NewLazyDLL("iphlpapi.dll")
+	procNotifyAddrChange = iphlpapi.NewProc("NotifyAddrChange")
+)
+
+// wakeWatchers watch the changes of the IP addresses of the network interfaces, which include DHCP renewals
+var wakeWatchers = []wakeWatcher{watchAddrChange}
+
+// watchAddrChange waits for the changes of the IP address table, the synchronous call can't be canceled
+// so the watcher only exits on the first change after stop is closed
+func watchAddrChange(stop chan bool, wake func(reason string)) error {
+	if err := procNotifyAddrChange.Find(); err != nil {
+		return err
+	}
+	for {
+		if ret, _, _ := procNotifyAddrChange.Call(0, 0); ret != 0 {
+			return syscall.Errno(ret)
+		}
+		select {
+		case <-stop:
+			return nil
+		default:
+			wake("network address change")
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package instancevariables resolves the instance variables of the format {{instance:*}} present in the documents:
+// the identity of the instance and the values of its tags, read from the instance metadata.
+package instancevariables
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+)
+
+// tagPrefix is the prefix of the variables of the instance tags, {{instance:tag:<key>}}
+const tagPrefix = "tag:"
+
+// variablePattern matches the instance variables, a tag key cannot hold braces
+var variablePattern = regexp.MustCompile(`\{\{ *instance:([A-Za-z]+|tag:[^{}]*[^{} ]) *\}\}`)
+
+// variables are the instance variables other than the tags, with the function fetching their value
+var variables = map[string]func() (string, error){
+	"instanceId":       platform.InstanceID,
+	"instanceType":     platform.InstanceType,
+	"region":           platform.Region,
+	"availabilityZone": platform.AvailabilityZone,
+	"imageId":          platform.ImageID,
+}
+
+// instanceTag returns the value of an instance tag, it is stubbed in the tests
+var instanceTag = platform.InstanceTag
+
+// resolver fetches the value of each variable once
+type resolver struct {
+	values map[string]string
+	err    error
+}
+
+// Resolve replaces the instance variables of the strings of the input with their values, the input is returned
+// unchanged when a variable cannot be resolved
+func Resolve(log log.T, input interface{}) (interface{}, error) {
+	r := &resolver{values: make(map[string]string)}
+	output := r.replace(input)
+	if r.err != nil {
+		log.Errorf("Failed to resolve the instance variables: %v", r.err)
+		return input, r.err
+	}
+	return output, nil
+}
+
+// replace traverses the maps and the slices of the input and replaces the variables of its strings
+func (r *resolver) replace(input interface{}) interface{} {
+	switch input := input.(type) {
+	case string:
+		if !strings.Contains(input, "instance:") {
+			return input
+		}
+		return variablePattern.ReplaceAllStringFunc(input, r.value)
+
+	case []string:
+		out := make([]string, len(input))
+		for i, v := range input {
+			out[i] = r.replace(v).(string)
+		}
+		return out
+
+	case []interface{}:
+		out := make([]interface{}, len(input))
+		for i, v := range input {
+			out[i] = r.replace(v)
+		}
+		return out
+
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(input))
+		for i, v := range input {
+			out[i] = r.replace(v).(map[string]interface{})
+		}
+		return out
+
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, v := range input {
+			out[k] = r.replace(v)
+		}
+		return out
+
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{})
+		for k, v := range input {
+			out[k] = r.replace(v)
+		}
+		return out
+
+	default:
+		return input
+	}
+}
+
+// value returns the value of a variable, the first failure is recorded and leaves the variable as is
+func (r *resolver) value(match string) string {
+	name := variablePattern.FindStringSubmatch(match)[1]
+	if value, ok := r.values[name]; ok {
+		return value
+	}
+	if r.err != nil {
+		return match
+	}
+
+	var value string
+	var err error
+	if strings.HasPrefix(name, tagPrefix) {
+		value, err = instanceTag(strings.TrimPrefix(name, tagPrefix))
+	} else if fetch, ok := variables[name]; ok {
+		value, err = fetch()
+	} else {
+		err = fmt.Errorf("unknown instance variable %v", name)
+	}
+	if err != nil {
+		r.err = err
+		return match
+	}
+	r.values[name] = value
+	return value
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package instancevariables
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// stubMetadata replaces the metadata reads and returns the function restoring them, it counts the tag reads
+func stubMetadata(tags map[string]string, tagReads *int) func() {
+	variablesOrig := variables
+	instanceTagOrig := instanceTag
+	variables = map[string]func() (string, error){
+		"instanceId": func() (string, error) { return "i-0123456789abcdef0", nil },
+		"region":     func() (string, error) { return "eu-west-1", nil },
+	}
+	instanceTag = func(key string) (string, error) {
+		*tagReads++
+		if value, ok := tags[key]; ok {
+			return value, nil
+		}
+		return "", errors.New("tag not found")
+	}
+	return func() {
+		variables = variablesOrig
+		instanceTag = instanceTagOrig
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tagReads := 0
+	defer stubMetadata(map[string]string{"Environment": "production", "Cost Center": "42"}, &tagReads)()
+
+	input := map[string]interface{}{
+		"commands": []interface{}{
+			"echo {{instance:instanceId}} in {{ instance:region }}",
+			"if [ '{{instance:tag:Environment}}' = production ]; then exit 0; fi",
+		},
+		"env":       "{{instance:tag:Environment}}",
+		"costs":     []string{"{{ instance:tag:Cost Center }}"},
+		"untouched": "{{ssm:parameter}} {{ instance }}",
+		"count":     3,
+	}
+	output, err := Resolve(logger, input)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"commands": []interface{}{
+			"echo i-0123456789abcdef0 in eu-west-1",
+			"if [ 'production' = production ]; then exit 0; fi",
+		},
+		"env":       "production",
+		"costs":     []string{"42"},
+		"untouched": "{{ssm:parameter}} {{ instance }}",
+		"count":     3,
+	}, output)
+	assert.Equal(t, 2, tagReads, "each tag is read once")
+}
+
+func TestResolveFailures(t *testing.T) {
+	tagReads := 0
+	defer stubMetadata(map[string]string{}, &tagReads)()
+
+	for _, input := range []string{"{{instance:tag:Team}}", "{{instance:hostname}}"} {
+		output, err := Resolve(logger, input)
+		assert.Error(t, err, input)
+		assert.Equal(t, input, output)
+	}
+}
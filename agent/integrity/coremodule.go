@@ -0,0 +1,70 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package integrity
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const name = "BinaryIntegrity"
+
+// SelfCheck is the core module verifying the installed binaries at the agent startup, after the audit log started
+type SelfCheck struct {
+	context context.T
+}
+
+// NewSelfCheck creates the self check, nil when the binaries are not verified
+func NewSelfCheck(context context.T) *SelfCheck {
+	if !Enabled() {
+		return nil
+	}
+	return &SelfCheck{context: context.With("[" + name + "]")}
+}
+
+// ModuleName returns the name of the module
+func (s *SelfCheck) ModuleName() string {
+	return name
+}
+
+// ModuleExecute verifies the agent and the binaries installed next to the workers, the agent keeps running
+// on a mismatch but the mismatching workers are not launched
+func (s *SelfCheck) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	folders := []string{filepath.Dir(appconfig.DefaultDocumentWorker)}
+	if executable, err := os.Executable(); err == nil && filepath.Dir(executable) != folders[0] {
+		folders = append(folders, filepath.Dir(executable))
+	}
+	mismatches := 0
+	for _, folder := range folders {
+		for _, err := range VerifyInstallation(folder) {
+			log.Error(err)
+			mismatches++
+		}
+	}
+	if mismatches == 0 {
+		log.Infof("The binaries in %v match the binary manifest", folders)
+	}
+	return nil
+}
+
+// ModuleRequestStop does nothing, the binaries are only verified at startup and before the worker launches
+func (s *SelfCheck) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	return nil
+}
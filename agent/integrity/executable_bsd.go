@@ -0,0 +1,31 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || netbsd || openbsd
+// +build darwin freebsd netbsd openbsd
+
+package integrity
+
+import "os"
+
+// openExecutable opens the executable for reading
+func openExecutable(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+// ExecutablePath returns the path of the opened executable, these systems can't run a file descriptor, the folder
+// of the binaries is only writable by root
+func ExecutablePath(file *os.File) string {
+	return file.Name()
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package integrity
+
+import (
+	"fmt"
+	"os"
+)
+
+// openExecutable opens the executable for reading
+func openExecutable(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+// ExecutablePath returns the path running the opened executable, the file descriptor itself, so that a change
+// of the file at its path after the check does not change what runs
+func ExecutablePath(file *os.File) string {
+	return fmt.Sprintf("/proc/self/fd/%d", file.Fd())
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package integrity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutablePath_FileReplacedAfterTheCheck(t *testing.T) {
+	dir, keyPath, _ := setup(t, map[string]string{"ssm-document-worker": "worker"})
+	assert.NoError(t, Configure(appconfig.IntegrityCfg{Enabled: true, PublicKeys: []string{keyPath}}))
+	path := filepath.Join(dir, "ssm-document-worker")
+	file, err := OpenExecutable(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	replacement := filepath.Join(dir, "replacement")
+	assert.NoError(t, ioutil.WriteFile(replacement, []byte("tampered"), 0700))
+	assert.NoError(t, os.Rename(replacement, path))
+
+	// the process runs the checked file, not the one now at its path
+	content, err := ioutil.ReadFile(ExecutablePath(file))
+	assert.NoError(t, err)
+	assert.Equal(t, "worker", string(content))
+}
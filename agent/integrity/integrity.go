@@ -0,0 +1,212 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package integrity verifies the binaries of the agent against the manifest installed with the package. The manifest
+// lists the sha256 checksum of every binary and is signed by the release key, the agent checks its binaries at startup
+// and refuses to launch the workers whose checksums differ from the manifest, recording every mismatch in the audit log.
+package integrity
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagesigning"
+)
+
+// Manifest lists the sha256 checksums of the binaries of a release, or of the files of a package, by file name
+type Manifest struct {
+	Version string            `json:"version"`
+	Files   map[string]string `json:"files"`
+}
+
+// state holds the verified manifest, it is nil when the verification is not enabled
+var state struct {
+	sync.Mutex
+	manifest *Manifest
+}
+
+// ManifestPath returns the path of the manifest installed with the package, it is stubbed in the tests
+var ManifestPath = func() string {
+	return filepath.Join(appconfig.DefaultProgramFolder, appconfig.IntegrityManifestFileName)
+}
+
+// releaseKeyPath returns the path of the release key installed with the package
+func releaseKeyPath() string {
+	return filepath.Join(appconfig.DefaultProgramFolder, appconfig.IntegrityReleaseKeyFileName)
+}
+
+// Configure loads the manifest and verifies its signature when the verification is enabled, the binaries are
+// verified against it from then on
+func Configure(config appconfig.IntegrityCfg) error {
+	if !config.Enabled {
+		return nil
+	}
+	paths := config.PublicKeys
+	if len(paths) == 0 {
+		paths = []string{releaseKeyPath()}
+	}
+	keys, err := packagesigning.LoadPublicKeys(paths)
+	if err != nil {
+		return err
+	}
+	manifest, err := LoadManifest(keys, ManifestPath())
+	if err != nil {
+		return err
+	}
+	state.Lock()
+	defer state.Unlock()
+	state.manifest = manifest
+	return nil
+}
+
+// Enabled returns true when the binaries are verified
+func Enabled() bool {
+	state.Lock()
+	defer state.Unlock()
+	return state.manifest != nil
+}
+
+// LoadManifest reads the manifest and checks that its signature file carries a valid signature by one of the keys
+func LoadManifest(keys []crypto.PublicKey, path string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifest %v: %v", path, err)
+	}
+	encoded, err := ioutil.ReadFile(path + appconfig.IntegritySignatureExtension)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the signature of the manifest %v: %v", path, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding of the manifest %v: %v", path, err)
+	}
+	if !packagesigning.VerifySignature(keys, content, signature) {
+		return nil, fmt.Errorf("manifest %v is not signed by a trusted key", path)
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %v: %v", path, err)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("manifest %v lists no file", path)
+	}
+	return &manifest, nil
+}
+
+// OpenExecutable opens the executable and checks the opened file against the manifest, a mismatch is recorded in the
+// audit log. The caller runs the returned file with ExecutablePath, so that the content checked is the content run,
+// and closes it once the process started. No file is opened when the verification is not enabled.
+func OpenExecutable(path string) (*os.File, error) {
+	state.Lock()
+	manifest := state.manifest
+	state.Unlock()
+	if manifest == nil {
+		return nil, nil
+	}
+	name := filepath.Base(path)
+	expected, found := manifest.Files[name]
+	if !found {
+		return nil, mismatch(path, fmt.Sprintf("%v is not listed in the binary manifest %v", name, manifest.Version))
+	}
+	file, err := openExecutable(path)
+	if err != nil {
+		return nil, mismatch(path, err.Error())
+	}
+	// the file is hashed every time, its size and times can be set back after a change
+	actual, err := checksum(file)
+	if err != nil {
+		file.Close()
+		return nil, mismatch(path, err.Error())
+	}
+	if !strings.EqualFold(actual, expected) {
+		file.Close()
+		return nil, mismatch(path, fmt.Sprintf("sha256 of %v is %v, the binary manifest %v expects %v", path, actual, manifest.Version, expected))
+	}
+	return file, nil
+}
+
+// VerifyExecutable checks the executable against the manifest, a mismatch is recorded in the audit log.
+// Every executable is valid when the verification is not enabled.
+func VerifyExecutable(path string) error {
+	file, err := OpenExecutable(path)
+	if file != nil {
+		file.Close()
+	}
+	return err
+}
+
+// VerifyInstallation checks every binary of the manifest found in the folder, it returns the mismatches
+func VerifyInstallation(folder string) (errs []error) {
+	state.Lock()
+	var names []string
+	if state.manifest != nil {
+		for name := range state.manifest.Files {
+			names = append(names, name)
+		}
+	}
+	state.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(folder, name)
+		// the manifest lists the binaries of every platform, the ones not installed here are skipped
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := VerifyExecutable(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// mismatch records the mismatch of the executable in the audit log and returns it
+func mismatch(path string, detail string) error {
+	audit.Record(audit.Event{
+		Type:   audit.BinaryIntegrityMismatch,
+		Detail: detail,
+	})
+	return fmt.Errorf("integrity check of %v failed, %v", path, detail)
+}
+
+// Checksum returns the hex encoded sha256 of the file
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return checksum(file)
+}
+
+// checksum returns the hex encoded sha256 of the content of the opened file
+func checksum(file *os.File) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
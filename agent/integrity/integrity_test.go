@@ -0,0 +1,147 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package integrity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// setup writes a worker binary, a manifest signed by a new key and the key, and stubs the manifest path
+func setup(t *testing.T, files map[string]string) (dir string, keyPath string, privateKey ed25519.PrivateKey) {
+	dir, err := ioutil.TempDir("", "integrity")
+	assert.NoError(t, err)
+	manifestPathOrig := ManifestPath
+	t.Cleanup(func() {
+		ManifestPath = manifestPathOrig
+		state.Lock()
+		state.manifest = nil
+		state.Unlock()
+		os.RemoveAll(dir)
+	})
+	ManifestPath = func() string { return filepath.Join(dir, appconfig.IntegrityManifestFileName) }
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(t, err)
+	keyPath = filepath.Join(dir, "release.pem")
+	assert.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	manifest := Manifest{Version: "3.0.0.0", Files: map[string]string{}}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0700))
+		manifest.Files[name], err = Checksum(path)
+		assert.NoError(t, err)
+	}
+	writeManifest(t, manifest, privateKey)
+	return dir, keyPath, privateKey
+}
+
+// writeManifest writes the manifest and its signature
+func writeManifest(t *testing.T, manifest Manifest, privateKey ed25519.PrivateKey) {
+	content, err := json.Marshal(manifest)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(ManifestPath(), content, 0600))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, content))
+	assert.NoError(t, ioutil.WriteFile(ManifestPath()+appconfig.IntegritySignatureExtension, []byte(signature), 0600))
+}
+
+func TestConfigure_Disabled(t *testing.T) {
+	assert.NoError(t, Configure(appconfig.IntegrityCfg{Enabled: false, PublicKeys: []string{"/nonexistent"}}))
+	assert.False(t, Enabled())
+	assert.NoError(t, VerifyExecutable("/nonexistent/ssm-document-worker"))
+}
+
+func TestVerifyExecutable(t *testing.T) {
+	dir, keyPath, _ := setup(t, map[string]string{"ssm-document-worker": "worker", "ssm-session-worker": "session"})
+	assert.NoError(t, Configure(appconfig.IntegrityCfg{Enabled: true, PublicKeys: []string{keyPath}}))
+	assert.True(t, Enabled())
+
+	assert.NoError(t, VerifyExecutable(filepath.Join(dir, "ssm-document-worker")))
+	assert.Empty(t, VerifyInstallation(dir))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ssm-session-worker"), []byte("tampered"), 0700))
+	assert.Error(t, VerifyExecutable(filepath.Join(dir, "ssm-session-worker")))
+	assert.Len(t, VerifyInstallation(dir), 1)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ssm-agent-helper"), []byte("unknown"), 0700))
+	assert.Error(t, VerifyExecutable(filepath.Join(dir, "ssm-agent-helper")))
+}
+
+func TestVerifyExecutable_SameSizeAndTime(t *testing.T) {
+	dir, keyPath, _ := setup(t, map[string]string{"ssm-document-worker": "worker"})
+	assert.NoError(t, Configure(appconfig.IntegrityCfg{Enabled: true, PublicKeys: []string{keyPath}}))
+	path := filepath.Join(dir, "ssm-document-worker")
+	assert.NoError(t, VerifyExecutable(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("WORKER"), 0700))
+	assert.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+	assert.Error(t, VerifyExecutable(path))
+}
+
+func TestOpenExecutable(t *testing.T) {
+	dir, keyPath, _ := setup(t, map[string]string{"ssm-document-worker": "worker"})
+	path := filepath.Join(dir, "ssm-document-worker")
+	file, err := OpenExecutable(path)
+	assert.NoError(t, err)
+	assert.Nil(t, file)
+
+	assert.NoError(t, Configure(appconfig.IntegrityCfg{Enabled: true, PublicKeys: []string{keyPath}}))
+	file, err = OpenExecutable(path)
+	assert.NoError(t, err)
+	if assert.NotNil(t, file) {
+		defer file.Close()
+		content, err := ioutil.ReadFile(ExecutablePath(file))
+		assert.NoError(t, err)
+		assert.Equal(t, "worker", string(content))
+	}
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("tampered"), 0700))
+	file, err = OpenExecutable(path)
+	assert.Error(t, err)
+	assert.Nil(t, file)
+}
+
+func TestConfigure_InvalidSignature(t *testing.T) {
+	_, keyPath, _ := setup(t, map[string]string{"ssm-document-worker": "worker"})
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	writeManifest(t, Manifest{Version: "3.0.0.0", Files: map[string]string{"ssm-document-worker": "00"}}, otherKey)
+
+	assert.Error(t, Configure(appconfig.IntegrityCfg{Enabled: true, PublicKeys: []string{keyPath}}))
+	assert.False(t, Enabled())
+}
+
+func TestConfigure_MissingManifest(t *testing.T) {
+	_, keyPath, _ := setup(t, map[string]string{"ssm-document-worker": "worker"})
+	assert.NoError(t, os.Remove(ManifestPath()+appconfig.IntegritySignatureExtension))
+
+	assert.Error(t, Configure(appconfig.IntegrityCfg{Enabled: true, PublicKeys: []string{keyPath}}))
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package main writes the binary manifest of a build, signed when a private key is given, for the integrity checks
+// of the agent. Usage: manifest-gen <version> <folder> [<PKCS#8 PEM private key>]
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
+)
+
+func main() {
+	if len(os.Args) != 3 && len(os.Args) != 4 {
+		log.Fatalf("Usage: %v <version> <folder> [<signing key>]", filepath.Base(os.Args[0]))
+	}
+	version, folder := os.Args[1], os.Args[2]
+	paths, err := filepath.Glob(filepath.Join(folder, "*"))
+	if err != nil {
+		log.Fatalf("Error listing the binaries of %v. %v", folder, err)
+	}
+	manifest := integrity.Manifest{Version: version, Files: map[string]string{}}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() || isIntegrityFile(filepath.Base(path)) {
+			continue
+		}
+		if manifest.Files[filepath.Base(path)], err = integrity.Checksum(path); err != nil {
+			log.Fatalf("Error computing the checksum of %v. %v", path, err)
+		}
+	}
+	content, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		log.Fatalf("Error encoding the binary manifest. %v", err)
+	}
+	manifestPath := filepath.Join(folder, appconfig.IntegrityManifestFileName)
+	if err = ioutil.WriteFile(manifestPath, content, appconfig.ReadWriteAccess); err != nil {
+		log.Fatalf("Error writing the binary manifest. %v", err)
+	}
+	fmt.Printf("Binary manifest of %v binaries written to %v\n", len(manifest.Files), manifestPath)
+	if len(os.Args) == 4 {
+		signManifest(folder, content, os.Args[3])
+	}
+}
+
+// isIntegrityFile returns true for the manifest, its signature and the release key, which are not binaries
+func isIntegrityFile(name string) bool {
+	return name == appconfig.IntegrityManifestFileName ||
+		name == appconfig.IntegrityManifestFileName+appconfig.IntegritySignatureExtension ||
+		name == appconfig.IntegrityReleaseKeyFileName
+}
+
+// signManifest writes the signature of the manifest by the private key and the public key verifying it
+func signManifest(folder string, content []byte, keyPath string) {
+	encoded, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		log.Fatalf("Error reading the signing key. %v", err)
+	}
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		log.Fatalf("Signing key %v is not PEM encoded", keyPath)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		log.Fatalf("Error parsing the signing key. %v", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		log.Fatalf("Signing key %v can't sign", keyPath)
+	}
+	// the signatures are verified with sha256 digests, the Ed25519 keys sign the manifest itself
+	var signature []byte
+	if _, isEd25519 := key.(ed25519.PrivateKey); isEd25519 {
+		signature, err = key.Sign(rand.Reader, content, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(content)
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		log.Fatalf("Error signing the binary manifest. %v", err)
+	}
+	signaturePath := filepath.Join(folder, appconfig.IntegrityManifestFileName+appconfig.IntegritySignatureExtension)
+	if err = ioutil.WriteFile(signaturePath, []byte(base64.StdEncoding.EncodeToString(signature)), appconfig.ReadWriteAccess); err != nil {
+		log.Fatalf("Error writing the signature of the binary manifest. %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		log.Fatalf("Error encoding the release key. %v", err)
+	}
+	keyFile := filepath.Join(folder, appconfig.IntegrityReleaseKeyFileName)
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), appconfig.ReadWriteAccess); err != nil {
+		log.Fatalf("Error writing the release key. %v", err)
+	}
+	fmt.Printf("Binary manifest signed into %v, verified by %v\n", signaturePath, keyFile)
+}
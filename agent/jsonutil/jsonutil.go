@@ -17,6 +17,10 @@ package jsonutil
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/aws/amazon-ssm-agent/agent/bufferpool"
 )
 
 // jsonFormat json formatIndent
@@ -33,11 +37,12 @@ func Indent(jsonStr string) string {
 // This is useful for example when we want to go from map[string]interface{}
 // to a more specific struct type or if we want a deep copy of the object.
 func Remarshal(obj interface{}, remarshalledObj interface{}) (err error) {
-	b, err := json.Marshal(obj)
-	if err != nil {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	if err = json.NewEncoder(buffer).Encode(obj); err != nil {
 		return
 	}
-	err = json.Unmarshal(b, remarshalledObj)
+	err = UnmarshalReader(buffer, remarshalledObj)
 	if err != nil {
 		return
 	}
@@ -47,12 +52,13 @@ func Remarshal(obj interface{}, remarshalledObj interface{}) (err error) {
 // Marshal marshals an object to a json string.
 // Returns empty string if marshal fails.
 func Marshal(obj interface{}) (result string, err error) {
-	var resultB []byte
-	resultB, err = json.Marshal(obj)
-	if err != nil {
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	if err = json.NewEncoder(buffer).Encode(obj); err != nil {
 		return
 	}
-	result = string(resultB)
+	// the encoder terminates the value with a newline
+	result = string(bytes.TrimSuffix(buffer.Bytes(), []byte("\n")))
 	return
 }
 
@@ -73,6 +79,19 @@ func Unmarshal(jsonContent string, dest interface{}) (err error) {
 	return
 }
 
+// UnmarshalReader decodes a single json value from the reader into an object, without reading
+// the whole content in memory first.
+func UnmarshalReader(reader io.Reader, dest interface{}) (err error) {
+	decoder := json.NewDecoder(reader)
+	if err = decoder.Decode(dest); err != nil {
+		return
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
 // MarshalIndent is like Marshal but applies Indent to format the output.
 // Returns empty string if marshal fails
 func MarshalIndent(obj interface{}) (result string, err error) {
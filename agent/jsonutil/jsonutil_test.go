@@ -16,6 +16,7 @@ package jsonutil
 import (
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -149,6 +150,25 @@ func TestUnmarshal(t *testing.T) {
 	assert.NoError(t, err2, "This is not json format. Error expected")
 }
 
+func TestUnmarshalReader(t *testing.T) {
+	var dest map[string]string
+	err := UnmarshalReader(strings.NewReader(`{"parameter": "1"}`), &dest)
+	assert.NoError(t, err, "Message should parse correctly")
+	assert.Equal(t, map[string]string{"parameter": "1"}, dest)
+
+	err = UnmarshalReader(strings.NewReader(`{"parameter": "1"} {}`), &dest)
+	assert.Error(t, err, "Trailing data after the value. Error expected")
+
+	err = UnmarshalReader(strings.NewReader(`{"parameter": `), &dest)
+	assert.Error(t, err, "Truncated value. Error expected")
+}
+
+func TestMarshalTrimsNewline(t *testing.T) {
+	result, err := Marshal(map[string]string{"parameter": "<1>"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"parameter":"\u003c1\u003e"}`, result)
+}
+
 // ioutil stub
 type ioUtilStub struct {
 	b   []byte
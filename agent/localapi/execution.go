@@ -0,0 +1,335 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/plugin"
+	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/go-yaml/yaml"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// maxRunningExecutions is the number of executions running at once, the API rejects the executions above it
+	maxRunningExecutions = 5
+
+	// maxExecutions is the number of executions kept, the oldest finished executions are forgotten above it
+	maxExecutions = 100
+)
+
+// errTooManyExecutions is returned when maxRunningExecutions executions are running
+var errTooManyExecutions = errors.New("too many executions are running, retry later")
+
+// documentExtensions are the extensions of the documents, in lookup order
+var documentExtensions = []string{".json", ".yaml", ".yml"}
+
+// documentNamePattern matches the names of the documents, a name cannot leave the documents folder
+var documentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]{0,127}$`)
+
+// ExecutionRequest is the body of the requests starting an execution
+type ExecutionRequest struct {
+	// Parameters are the values of the document parameters, a StringList parameter takes several values
+	Parameters map[string][]string `json:"parameters"`
+}
+
+// Execution is the state of a document run by the local API
+type Execution struct {
+	ExecutionID   string                 `json:"executionId"`
+	DocumentName  string                 `json:"documentName"`
+	Status        contracts.ResultStatus `json:"status"`
+	StartDateTime string                 `json:"startDateTime"`
+	EndDateTime   string                 `json:"endDateTime,omitempty"`
+	Steps         []Step                 `json:"steps,omitempty"`
+}
+
+// Step is the result of a step of a finished execution
+type Step struct {
+	Name   string                 `json:"name"`
+	Status contracts.ResultStatus `json:"status"`
+	Code   int                    `json:"code"`
+	Output interface{}            `json:"output,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// execution is an execution with the cancel flag of its steps
+type execution struct {
+	Execution
+	cancelFlag task.CancelFlag
+}
+
+// executionStore keeps the executions of the API in their start order
+type executionStore struct {
+	lock       sync.Mutex
+	executions map[string]*execution
+	order      []string
+}
+
+// runPlugins runs the steps of an execution, it is stubbed in the tests
+var runPlugins = func(context context.T, plugins []contracts.PluginState, ioConfig contracts.IOConfiguration, cancelFlag task.CancelFlag) map[string]*contracts.PluginResult {
+	// RunPlugins sends a result per plugin without blocking
+	resChan := make(chan contracts.PluginResult, len(plugins))
+	defer close(resChan)
+	return runpluginutil.RunPlugins(context, plugins, ioConfig, plugin.RegisteredWorkerPlugins(context), resChan, cancelFlag)
+}
+
+// orchestrationRootDir returns the folder of the execution outputs, next to the command outputs, it is stubbed in the tests
+var orchestrationRootDir = func(context context.T) (string, error) {
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the instance id: %v", err)
+	}
+	return filepath.Join(appconfig.DefaultDataStorePath, instanceID, appconfig.DefaultDocumentRootDirName,
+		context.AppConfig().Agent.OrchestrationRootDir), nil
+}
+
+// newExecutionStore creates a store without executions
+func newExecutionStore() *executionStore {
+	return &executionStore{executions: make(map[string]*execution)}
+}
+
+// add stores a running execution, the oldest finished execution is forgotten above maxExecutions
+func (store *executionStore) add(e *execution) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	running := 0
+	for _, stored := range store.executions {
+		if stored.Status == contracts.ResultStatusInProgress {
+			running++
+		}
+	}
+	if running >= maxRunningExecutions {
+		return errTooManyExecutions
+	}
+	store.executions[e.ExecutionID] = e
+	store.order = append(store.order, e.ExecutionID)
+	for i := 0; len(store.order) > maxExecutions && i < len(store.order); {
+		if id := store.order[i]; store.executions[id].Status != contracts.ResultStatusInProgress {
+			delete(store.executions, id)
+			store.order = append(store.order[:i], store.order[i+1:]...)
+		} else {
+			i++
+		}
+	}
+	return nil
+}
+
+// finish records the end of an execution with the results of its steps
+func (store *executionStore) finish(executionID string, status contracts.ResultStatus, steps []Step) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if e, ok := store.executions[executionID]; ok {
+		e.Status = status
+		e.EndDateTime = times.ToIso8601UTC(time.Now())
+		e.Steps = steps
+	}
+}
+
+// get returns an execution
+func (store *executionStore) get(executionID string) (Execution, bool) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	e, ok := store.executions[executionID]
+	if !ok {
+		return Execution{}, false
+	}
+	return e.Execution, true
+}
+
+// list returns the executions without their steps, in their start order
+func (store *executionStore) list() []Execution {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	executions := make([]Execution, 0, len(store.order))
+	for _, id := range store.order {
+		e := store.executions[id].Execution
+		e.Steps = nil
+		executions = append(executions, e)
+	}
+	return executions
+}
+
+// cancelAll cancels the steps of the running executions
+func (store *executionStore) cancelAll() {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	for _, e := range store.executions {
+		if e.Status == contracts.ResultStatusInProgress {
+			e.cancelFlag.Set(task.ShutDown)
+		}
+	}
+}
+
+// listDocuments returns the names of the documents of the documents folder
+func listDocuments(documentsDir string) ([]string, error) {
+	if documentsDir == "" {
+		return nil, fmt.Errorf("no documents folder is configured")
+	}
+	files, err := ioutil.ReadDir(documentsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the documents folder: %v", err)
+	}
+	names := []string{}
+	found := make(map[string]bool)
+	for _, file := range files {
+		extension := filepath.Ext(file.Name())
+		name := strings.TrimSuffix(file.Name(), extension)
+		if file.IsDir() || !isDocumentExtension(extension) || !documentNamePattern.MatchString(name) || found[name] {
+			continue
+		}
+		found[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// isDocumentExtension returns true for the extensions of the documents
+func isDocumentExtension(extension string) bool {
+	for _, documentExtension := range documentExtensions {
+		if extension == documentExtension {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDocument reads a JSON or YAML document of the documents folder
+func loadDocument(documentsDir, name string) (docContent docparser.DocContent, err error) {
+	if documentsDir == "" {
+		return docContent, fmt.Errorf("no documents folder is configured")
+	}
+	if !documentNamePattern.MatchString(name) {
+		return docContent, fmt.Errorf("invalid document name %v", name)
+	}
+	for _, extension := range documentExtensions {
+		documentRaw, err := ioutil.ReadFile(filepath.Join(documentsDir, name+extension))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return docContent, fmt.Errorf("failed to read document %v: %v", name, err)
+		}
+		if err = json.Unmarshal(documentRaw, &docContent); err != nil {
+			if err = yaml.Unmarshal(documentRaw, &docContent); err != nil {
+				return docContent, fmt.Errorf("document %v is neither valid JSON nor valid YAML: %v", name, err)
+			}
+		}
+		return docContent, nil
+	}
+	return docContent, fmt.Errorf("no document %v", name)
+}
+
+// documentParameters returns the values of the document parameters, the parameters without value must have a default
+func documentParameters(values map[string][]string, definitions map[string]*contracts.Parameter) (map[string][]*string, error) {
+	parameters := make(map[string][]*string)
+	for name, nameValues := range values {
+		if _, exists := definitions[name]; !exists {
+			return nil, fmt.Errorf("the document has no parameter %v", name)
+		}
+		for i := range nameValues {
+			parameters[name] = append(parameters[name], &nameValues[i])
+		}
+	}
+	var missing []string
+	for name, definition := range definitions {
+		if _, exists := parameters[name]; !exists && (definition == nil || definition.DefaultVal == nil) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing value for the document parameters %v", strings.Join(missing, ", "))
+	}
+	return parameters, nil
+}
+
+// start parses a document with the values of its parameters and runs its steps in the background
+func (s *Server) start(documentName string, docContent docparser.DocContent, values map[string][]string) (Execution, error) {
+	log := s.context.Log()
+	parameters, err := documentParameters(values, docContent.Parameters)
+	if err != nil {
+		return Execution{}, err
+	}
+	rootDir, err := orchestrationRootDir(s.context)
+	if err != nil {
+		return Execution{}, err
+	}
+
+	executionID := uuid.NewV4().String()
+	parserInfo := docparser.DocumentParserInfo{
+		OrchestrationDir: filepath.Join(rootDir, executionID),
+		MessageId:        executionID,
+		DocumentId:       executionID,
+	}
+	pluginsInfo, err := docContent.ParseDocument(log, contracts.DocumentInfo{DocumentName: documentName}, parserInfo,
+		docparser.ParseParameters(log, parameters, docContent.Parameters))
+	if err != nil {
+		return Execution{}, err
+	}
+
+	e := &execution{
+		Execution: Execution{
+			ExecutionID:   executionID,
+			DocumentName:  documentName,
+			Status:        contracts.ResultStatusInProgress,
+			StartDateTime: times.ToIso8601UTC(time.Now()),
+		},
+		cancelFlag: task.NewChanneledCancelFlag(),
+	}
+	// the state is copied before the execution can finish
+	state := e.Execution
+	if err = s.executions.add(e); err != nil {
+		return Execution{}, err
+	}
+	log.Infof("Running document %v as execution %v", documentName, executionID)
+	go s.run(executionID, pluginsInfo, docContent.GetIOConfiguration(parserInfo), e.cancelFlag)
+	return state, nil
+}
+
+// run runs the steps of an execution and records their results
+func (s *Server) run(executionID string, pluginsInfo []contracts.PluginState, ioConfig contracts.IOConfiguration, cancelFlag task.CancelFlag) {
+	log := s.context.Log()
+	results := runPlugins(s.context, pluginsInfo, ioConfig, cancelFlag)
+
+	steps := []Step{}
+	for _, pluginState := range pluginsInfo {
+		step := Step{Name: pluginState.Id, Status: contracts.ResultStatusNotStarted}
+		if result, exists := results[pluginState.Id]; exists {
+			step.Status, step.Code, step.Output, step.Error = result.Status, result.Code, result.Output, result.Error
+		}
+		steps = append(steps, step)
+	}
+	status, _, _ := contracts.DocumentResultAggregator(log, "", results)
+	s.executions.finish(executionID, status, steps)
+	log.Infof("Execution %v finished with status %v", executionID, status)
+}
@@ -0,0 +1,253 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localapi implements the opt-in local API the software of the instance uses to run the command documents of
+// a local folder and to read the status and the step results of their executions, on a Unix socket or a localhost port.
+package localapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
+)
+
+const (
+	name = "LocalApi"
+
+	// documentsPath lists the documents, an execution of a document is started at documentsPath/<name>/executions
+	documentsPath = "/documents"
+
+	// executionsPath lists the executions, the state of an execution is served at executionsPath/<execution id>
+	executionsPath = "/executions"
+
+	// maxRequestBytes is the largest request body read
+	maxRequestBytes = 1024 * 1024
+)
+
+// Server is the core module serving the local API
+type Server struct {
+	context    context.T
+	config     appconfig.LocalApiCfg
+	token      []byte
+	servers    []*http.Server
+	executions *executionStore
+}
+
+// apiError is the body of the failed requests
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// NewServer creates the local API, nil when neither a socket nor a port is configured in appconfig
+func NewServer(context context.T) *Server {
+	config := context.AppConfig().LocalApi
+	if config.SocketPath == "" && config.Port == 0 {
+		return nil
+	}
+	return &Server{
+		context:    context.With("[" + name + "]"),
+		config:     config,
+		executions: newExecutionStore(),
+	}
+}
+
+// ModuleName returns the name of the module
+func (s *Server) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts serving the local API
+func (s *Server) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	mux := http.NewServeMux()
+	mux.HandleFunc(documentsPath, s.handleDocuments)
+	mux.HandleFunc(documentsPath+"/", s.handleDocumentExecution)
+	mux.HandleFunc(executionsPath, s.handleExecutions)
+	mux.HandleFunc(executionsPath+"/", s.handleExecution)
+
+	if s.config.SocketPath != "" {
+		listener, err := s.listenSocket()
+		if err != nil {
+			return err
+		}
+		s.serve(healthendpoint.NewPeerCheckListener(listener, log), mux)
+		log.Infof("Serving the local API on unix socket %v", s.config.SocketPath)
+	}
+	if s.config.Port != 0 {
+		if s.token, err = healthendpoint.ReadBearerToken(s.config.TokenFile); err != nil {
+			s.ModuleRequestStop(contracts.StopTypeSoftStop)
+			return err
+		}
+		address := fmt.Sprintf("127.0.0.1:%v", s.config.Port)
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			s.ModuleRequestStop(contracts.StopTypeSoftStop)
+			return fmt.Errorf("failed to listen on %v for the local API: %v", address, err)
+		}
+		s.serve(listener, s.requireToken(mux))
+		log.Infof("Serving the local API on http://%v", address)
+	}
+	return nil
+}
+
+// ModuleRequestStop stops serving the local API and cancels the running executions
+func (s *Server) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	for _, server := range s.servers {
+		if closeErr := server.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	s.servers = nil
+	if s.config.SocketPath != "" {
+		os.Remove(s.config.SocketPath)
+	}
+	s.executions.cancelAll()
+	return err
+}
+
+// listenSocket creates the socket of the API, only root can use it
+func (s *Server) listenSocket() (net.Listener, error) {
+	socketPath := s.config.SocketPath
+	if err := os.MkdirAll(filepath.Dir(socketPath), appconfig.ReadWriteExecuteAccess); err != nil {
+		return nil, fmt.Errorf("failed to create the folder of the local API socket %v: %v", socketPath, err)
+	}
+	// a socket left by a previous agent would fail the listen
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %v for the local API: %v", socketPath, err)
+	}
+	if err = os.Chmod(socketPath, appconfig.ReadWriteAccess); err != nil {
+		s.context.Log().Warnf("Failed to restrict the access to the local API socket %v: %v", socketPath, err)
+	}
+	return listener, nil
+}
+
+// serve serves the API on a listener until the module stops
+func (s *Server) serve(listener net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+	s.servers = append(s.servers, server)
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.context.Log().Errorf("local API stopped: %v", err)
+		}
+	}()
+}
+
+// requireToken rejects the requests without the bearer token of the API
+func (s *Server) requireToken(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthendpoint.HasBearerToken(r, s.token) {
+			s.context.Log().Warnf("Rejecting a local API request from %v without a valid token", r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// handleDocuments writes the names of the documents the API runs
+func (s *Server) handleDocuments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET to list the documents")
+		return
+	}
+	names, err := listDocuments(s.config.DocumentsPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"documents": names})
+}
+
+// handleDocumentExecution starts an execution of a document, on POST documentsPath/<name>/executions
+func (s *Server) handleDocumentExecution(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, documentsPath+"/"), "/")
+	if len(parts) != 2 || parts[1] != strings.TrimPrefix(executionsPath, "/") {
+		writeError(w, http.StatusNotFound, "unknown path "+r.URL.Path)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST to start an execution")
+		return
+	}
+
+	var request ExecutionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+	}
+	docContent, err := loadDocument(s.config.DocumentsPath, parts[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	execution, err := s.start(parts[0], docContent, request.Parameters)
+	switch {
+	case err == errTooManyExecutions:
+		writeError(w, http.StatusTooManyRequests, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		w.Header().Set("Location", executionsPath+"/"+execution.ExecutionID)
+		writeJSON(w, http.StatusAccepted, execution)
+	}
+}
+
+// handleExecutions writes the executions the API keeps, without their steps
+func (s *Server) handleExecutions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET to list the executions")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]Execution{"executions": s.executions.list()})
+}
+
+// handleExecution writes the state and the step results of an execution
+func (s *Server) handleExecution(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET to read an execution")
+		return
+	}
+	executionID := strings.TrimPrefix(r.URL.Path, executionsPath+"/")
+	execution, ok := s.executions.get(executionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no execution %v", executionID))
+		return
+	}
+	writeJSON(w, http.StatusOK, execution)
+}
+
+// writeJSON writes a JSON response with a status code
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes the message of a failed request
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, apiError{Message: message})
+}
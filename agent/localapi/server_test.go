@@ -0,0 +1,262 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testDocument = `{
+  "schemaVersion": "2.2",
+  "description": "Says hello",
+  "parameters": {
+    "message": {"type": "String", "default": "hello"},
+    "name": {"type": "String"}
+  },
+  "mainSteps": [{
+    "action": "aws:runShellScript",
+    "name": "hello",
+    "inputs": {"runCommand": ["echo {{ message }} {{ name }}"]}
+  }]
+}`
+
+func mockContextWithLocalApi(config appconfig.LocalApiCfg) *context.Mock {
+	ctx := new(context.Mock)
+	appConfig := appconfig.SsmagentConfig{}
+	appConfig.LocalApi = config
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(appConfig)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// setUpDocuments creates a documents folder and stubs the orchestration folder and the steps of the executions,
+// the returned channel receives the values of the parameters of the steps run
+func setUpDocuments(t *testing.T) (string, chan []contracts.PluginState) {
+	dir, err := ioutil.TempDir("", "localapi")
+	assert.NoError(t, err)
+	documentsDir := filepath.Join(dir, "documents")
+	assert.NoError(t, os.Mkdir(documentsDir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(documentsDir, "hello.json"), []byte(testDocument), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(documentsDir, "bootstrap.yaml"), []byte("schemaVersion: '2.2'\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(documentsDir, "notes.txt"), []byte("notes"), 0600))
+
+	runPluginsOrig, orchestrationRootDirOrig := runPlugins, orchestrationRootDir
+	ran := make(chan []contracts.PluginState, 1)
+	t.Cleanup(func() {
+		runPlugins, orchestrationRootDir = runPluginsOrig, orchestrationRootDirOrig
+		os.RemoveAll(dir)
+	})
+	orchestrationRootDir = func(context context.T) (string, error) {
+		return filepath.Join(dir, "orchestration"), nil
+	}
+	runPlugins = func(context context.T, plugins []contracts.PluginState, ioConfig contracts.IOConfiguration, cancelFlag task.CancelFlag) map[string]*contracts.PluginResult {
+		ran <- plugins
+		return map[string]*contracts.PluginResult{
+			"hello": {PluginID: "hello", PluginName: "aws:runShellScript", Status: contracts.ResultStatusSuccess, Output: "hello world\n"},
+		}
+	}
+	return documentsDir, ran
+}
+
+func request(t *testing.T, client *http.Client, method, url, token string, body interface{}) (int, map[string]interface{}) {
+	var reader *bytes.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = bytes.NewReader(content)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	assert.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var response map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return resp.StatusCode, response
+}
+
+// startServer serves the local API on a port with a token
+func startServer(t *testing.T, documentsDir string) (*Server, string) {
+	tokenFile := filepath.Join(filepath.Dir(documentsDir), "token")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+	ctx := mockContextWithLocalApi(appconfig.LocalApiCfg{Port: freePort(t), TokenFile: tokenFile, DocumentsPath: documentsDir})
+	server := NewServer(ctx)
+	assert.NotNil(t, server)
+	assert.Equal(t, name, server.ModuleName())
+	assert.NoError(t, server.ModuleExecute(ctx))
+	t.Cleanup(func() { server.ModuleRequestStop(contracts.StopTypeSoftStop) })
+	return server, fmt.Sprintf("http://127.0.0.1:%v", server.config.Port)
+}
+
+func TestNewServer_Disabled(t *testing.T) {
+	assert.Nil(t, NewServer(mockContextWithLocalApi(appconfig.LocalApiCfg{DocumentsPath: "/documents"})))
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	documentsDir, _ := setUpDocuments(t)
+	_, url := startServer(t, documentsDir)
+
+	status, response := request(t, http.DefaultClient, http.MethodGet, url+documentsPath, "", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "missing or invalid bearer token", response["message"])
+
+	status, _ = request(t, http.DefaultClient, http.MethodGet, url+documentsPath, "other", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, response = request(t, http.DefaultClient, http.MethodGet, url+documentsPath, "secret", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{"bootstrap", "hello"}, response["documents"])
+}
+
+func TestServer_MissingTokenFile(t *testing.T) {
+	ctx := mockContextWithLocalApi(appconfig.LocalApiCfg{Port: freePort(t), TokenFile: filepath.Join(os.TempDir(), "missing-localapi-token")})
+	assert.Error(t, NewServer(ctx).ModuleExecute(ctx))
+}
+
+func TestServer_RunsDocument(t *testing.T) {
+	documentsDir, ran := setUpDocuments(t)
+	_, url := startServer(t, documentsDir)
+
+	status, execution := request(t, http.DefaultClient, http.MethodPost, url+"/documents/hello/executions", "secret",
+		ExecutionRequest{Parameters: map[string][]string{"name": {"world"}}})
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "hello", execution["documentName"])
+	assert.Equal(t, string(contracts.ResultStatusInProgress), execution["status"])
+	executionID := execution["executionId"].(string)
+
+	plugins := <-ran
+	assert.Len(t, plugins, 1)
+	assert.Equal(t, "hello", plugins[0].Id)
+	assert.Contains(t, fmt.Sprint(plugins[0].Configuration.Properties), "echo hello world")
+
+	for i := 0; i < 50 && execution["status"] == string(contracts.ResultStatusInProgress); i++ {
+		time.Sleep(10 * time.Millisecond)
+		status, execution = request(t, http.DefaultClient, http.MethodGet, url+executionsPath+"/"+executionID, "secret", nil)
+		assert.Equal(t, http.StatusOK, status)
+	}
+	assert.Equal(t, string(contracts.ResultStatusSuccess), execution["status"])
+	assert.NotEmpty(t, execution["endDateTime"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name": "hello", "status": "Success", "code": float64(0), "output": "hello world\n",
+	}}, execution["steps"])
+
+	status, executions := request(t, http.DefaultClient, http.MethodGet, url+executionsPath, "secret", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, executions["executions"], 1)
+}
+
+func TestServer_RejectsInvalidExecutions(t *testing.T) {
+	documentsDir, _ := setUpDocuments(t)
+	_, url := startServer(t, documentsDir)
+
+	status, response := request(t, http.DefaultClient, http.MethodPost, url+"/documents/hello/executions", "secret", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "missing value for the document parameters name", response["message"])
+
+	status, response = request(t, http.DefaultClient, http.MethodPost, url+"/documents/hello/executions", "secret",
+		ExecutionRequest{Parameters: map[string][]string{"name": {"world"}, "other": {"value"}}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "the document has no parameter other", response["message"])
+
+	status, _ = request(t, http.DefaultClient, http.MethodPost, url+"/documents/missing/executions", "secret", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, response = request(t, http.DefaultClient, http.MethodPost, url+"/documents/..hello/executions", "secret", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "invalid document name ..hello", response["message"])
+
+	status, _ = request(t, http.DefaultClient, http.MethodGet, url+"/documents/hello/executions", "secret", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, _ = request(t, http.DefaultClient, http.MethodGet, url+executionsPath+"/unknown", "secret", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestServer_ServesOnSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available on all windows versions")
+	}
+	documentsDir, _ := setUpDocuments(t)
+	socketPath := filepath.Join(filepath.Dir(documentsDir), "localapi.sock")
+	ctx := mockContextWithLocalApi(appconfig.LocalApiCfg{SocketPath: socketPath, DocumentsPath: documentsDir})
+	server := NewServer(ctx)
+	assert.NoError(t, server.ModuleExecute(ctx))
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		},
+	}}
+	status, response := request(t, client, http.MethodGet, "http://unix"+documentsPath, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{"bootstrap", "hello"}, response["documents"])
+
+	assert.NoError(t, server.ModuleRequestStop(contracts.StopTypeSoftStop))
+	_, err := os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExecutionStore_Limits(t *testing.T) {
+	store := newExecutionStore()
+	for i := 0; i < maxRunningExecutions; i++ {
+		assert.NoError(t, store.add(&execution{Execution: Execution{ExecutionID: strconv.Itoa(i), Status: contracts.ResultStatusInProgress}}))
+	}
+	assert.Equal(t, errTooManyExecutions, store.add(&execution{Execution: Execution{ExecutionID: "other", Status: contracts.ResultStatusInProgress}}))
+
+	store.finish("0", contracts.ResultStatusSuccess, nil)
+	for i := maxRunningExecutions; i < maxExecutions+1; i++ {
+		id := strconv.Itoa(i)
+		assert.NoError(t, store.add(&execution{Execution: Execution{ExecutionID: id, Status: contracts.ResultStatusInProgress}}))
+		store.finish(id, contracts.ResultStatusSuccess, nil)
+	}
+	executions := store.list()
+	assert.Len(t, executions, maxExecutions)
+	_, ok := store.get("0")
+	assert.False(t, ok, "the oldest finished execution is forgotten")
+	_, ok = store.get("1")
+	assert.True(t, ok, "the running executions are kept")
+}
@@ -16,7 +16,16 @@
 
 package log
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// seelogLevelsPattern matches the level attributes of the root element of a seelog configuration
+var seelogLevelsPattern = regexp.MustCompile(`\s(minlevel|maxlevel|levels)="[^"]*"`)
+
+// seelogElementPattern matches the root element of a seelog configuration
+var seelogElementPattern = regexp.MustCompile(`<seelog[^>]*>`)
 
 func DefaultConfig() []byte {
 	return LoadLog(DefaultLogDir, LogFile)
@@ -37,11 +46,11 @@ func LoadLog(defaultLogDir string, logFile string) []byte {
         <console formatid="fmtinfo"/>
 
         `
-	logConfig += `<rollingfile type="size" filename="` + logFilePath + `" maxsize="30000000" maxrolls="5"/>`
+	logConfig += `<custom name="` + RotatingFileReceiverName + `" data-filename="` + logFilePath + `" data-maxsize="30000000" data-maxrolls="5" data-compress="true"/>`
 	logConfig += `
 		<filter levels="error,critical" formatid="fmterror">
 		`
-	logConfig += `<rollingfile type="size" filename="` + errorFilePath + `" maxsize="10000000" maxrolls="5"/>`
+	logConfig += `<custom name="` + RotatingFileReceiverName + `" data-filename="` + errorFilePath + `" data-maxsize="10000000" data-maxrolls="5" data-compress="true"/>`
 	logConfig += `
         </filter>
     </outputs>
@@ -49,8 +58,17 @@ func LoadLog(defaultLogDir string, logFile string) []byte {
         <format id="fmterror" format="%Date %Time %LEVEL [%FuncShort @ %File.%Line] %Msg%n"/>
         <format id="fmtdebug" format="%Date %Time %LEVEL [%FuncShort @ %File.%Line] %Msg%n"/>
         <format id="fmtinfo" format="%Date %Time %LEVEL %Msg%n"/>
+        <format id="fmtjson" format="%JSON%n"/>
     </formats>
 </seelog>
 `
 	return []byte(logConfig)
 }
+
+// WithMinLevel replaces the levels of the seelog configuration with the minimum level, exceptions and filters still apply
+func WithMinLevel(seelogConfig []byte, level string) []byte {
+	return seelogElementPattern.ReplaceAllFunc(seelogConfig, func(element []byte) []byte {
+		attributes := seelogLevelsPattern.ReplaceAll(element[len("<seelog"):], nil)
+		return append([]byte(`<seelog minlevel="`+level+`"`), attributes...)
+	})
+}
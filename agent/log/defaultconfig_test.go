@@ -0,0 +1,32 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMinLevel(t *testing.T) {
+	config := WithMinLevel(LoadLog("logs", LogFile), "debug")
+	assert.Contains(t, string(config), `<seelog minlevel="debug" type="adaptive" mininterval="2000000" maxinterval="100000000" critmsgcount="500">`)
+	assert.Contains(t, string(config), `<exception filepattern="test*" minlevel="error"/>`)
+
+	config = WithMinLevel([]byte(`<seelog levels="info,error" maxlevel="error"><outputs><console/></outputs></seelog>`), "trace")
+	assert.Equal(t, `<seelog minlevel="trace"><outputs><console/></outputs></seelog>`, string(config))
+	_, err := seelog.LoggerFromConfigAsBytes(config)
+	assert.NoError(t, err)
+}
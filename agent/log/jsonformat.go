@@ -0,0 +1,127 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// JSONFormatterName is the seelog custom formatter writing a log message as a JSON object.
+// Use it as format="%JSON%n" in the seelog configuration to write one JSON object per log line.
+const JSONFormatterName = "JSON"
+
+// idContexts are the contexts followed by the id of the document or session they process, with the field of the id
+var idContexts = map[string]string{
+	"ssm-document-worker": "documentId",
+	"ssm-session-worker":  "sessionId",
+	"OutOfProcExecuter":   "documentId",
+}
+
+// jsonLogLine is the JSON object written for a log message
+type jsonLogLine struct {
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Component string            `json:"component,omitempty"`
+	MessageID string            `json:"messageId,omitempty"`
+	CommandID string            `json:"commandId,omitempty"`
+	SessionID string            `json:"sessionId,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+func init() {
+	if err := seelog.RegisterCustomFormatter(JSONFormatterName, createJSONFormatterFunc); err != nil {
+		fmt.Println("Error registering the JSON log formatter: ", err)
+	}
+}
+
+func createJSONFormatterFunc(params string) seelog.FormatterFunc {
+	return formatJSON
+}
+
+// formatJSON writes the log message with its level, caller and the context of the logger as a JSON object
+func formatJSON(message string, level seelog.LogLevel, context seelog.LogContextInterface) interface{} {
+	line := newJSONLogLine(message)
+	line.Level = level.String()
+	if context != nil && context.IsValid() {
+		line.Timestamp = context.CallTime().UTC().Format(time.RFC3339Nano)
+		line.Fields["function"] = context.Func()
+		line.Fields["file"] = context.FileName()
+		line.Fields["line"] = fmt.Sprint(context.Line())
+	} else {
+		line.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	if len(line.Fields) == 0 {
+		line.Fields = nil
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Sprintf(`{"level":%q,"message":%q}`, line.Level, message)
+	}
+	return string(encoded)
+}
+
+// newJSONLogLine splits the contexts the logger wrapper prepends to a message, [name] or [key=value], from the message
+func newJSONLogLine(message string) *jsonLogLine {
+	line := &jsonLogLine{Fields: make(map[string]string)}
+	var components []string
+	idField := ""
+	for strings.HasPrefix(message, "[") {
+		end := strings.Index(message, "] ")
+		if end < 0 {
+			break
+		}
+		context := message[1:end]
+		message = message[end+2:]
+
+		if separator := strings.Index(context, "="); separator > 0 {
+			key, value := context[:separator], context[separator+1:]
+			if key == "messageID" {
+				line.MessageID = value
+				line.CommandID = commandIDFromMessageID(value)
+			} else {
+				line.Fields[key] = value
+			}
+			continue
+		}
+		if idField != "" {
+			if idField == "sessionId" {
+				line.SessionID = context
+			} else {
+				line.Fields[idField] = context
+			}
+			idField = ""
+			continue
+		}
+		components = append(components, context)
+		idField = idContexts[context]
+	}
+	line.Component = strings.Join(components, "/")
+	line.Message = message
+	return line
+}
+
+// commandIDFromMessageID returns the command id of a run command message id, aws.ssm.<commandID>.<instanceID>
+func commandIDFromMessageID(messageID string) string {
+	parts := strings.Split(messageID, ".")
+	if len(parts) < 4 || parts[0] != "aws" || parts[1] != "ssm" {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJSONLogLine_RunCommand(t *testing.T) {
+	line := newJSONLogLine("[instanceID=i-0123456789] [MessagingDeliveryService] [messageID=aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-0123456789] [EngineProcessor] submitting document")
+
+	assert.Equal(t, "MessagingDeliveryService/EngineProcessor", line.Component)
+	assert.Equal(t, "aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-0123456789", line.MessageID)
+	assert.Equal(t, "2b196342-d7d4-436e-8f09-3883a1116ac3", line.CommandID)
+	assert.Equal(t, map[string]string{"instanceID": "i-0123456789"}, line.Fields)
+	assert.Equal(t, "submitting document", line.Message)
+}
+
+func TestNewJSONLogLine_Workers(t *testing.T) {
+	line := newJSONLogLine("[ssm-session-worker] [user-0123abcd] [pluginName=Standard_Stream] opening data channel")
+	assert.Equal(t, "ssm-session-worker", line.Component)
+	assert.Equal(t, "user-0123abcd", line.SessionID)
+	assert.Equal(t, map[string]string{"pluginName": "Standard_Stream"}, line.Fields)
+	assert.Equal(t, "opening data channel", line.Message)
+
+	line = newJSONLogLine("[ssm-document-worker] [2b196342-d7d4-436e-8f09-3883a1116ac3] document complete")
+	assert.Equal(t, "ssm-document-worker", line.Component)
+	assert.Equal(t, map[string]string{"documentId": "2b196342-d7d4-436e-8f09-3883a1116ac3"}, line.Fields)
+	assert.Equal(t, "document complete", line.Message)
+}
+
+func TestNewJSONLogLine_NoContext(t *testing.T) {
+	line := newJSONLogLine("[not a context]")
+	assert.Empty(t, line.Component)
+	assert.Equal(t, "[not a context]", line.Message)
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	seelogger, err := seelog.LoggerFromWriterWithMinLevelAndFormat(&buf, seelog.InfoLvl, "%"+JSONFormatterName+"%n")
+	assert.NoError(t, err)
+	logger := &Wrapper{
+		Format:   &ContextFormatFilter{Context: []string{"[MessagingDeliveryService]"}},
+		M:        new(sync.Mutex),
+		Delegate: &DelegateLogger{BaseLoggerInstance: seelogger},
+	}
+
+	logger.Infof("command %v\ncomplete", "abc")
+	seelogger.Flush()
+
+	var line map[string]interface{}
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "info", line["level"])
+	assert.Equal(t, "MessagingDeliveryService", line["component"])
+	assert.Equal(t, "command abc\ncomplete", line["message"])
+	assert.NotEmpty(t, line["timestamp"])
+	assert.Contains(t, line, "fields")
+}
+
+func TestDefaultConfig_JSONFormat(t *testing.T) {
+	assert.Contains(t, string(DefaultConfig()), `<format id="fmtjson" format="%JSON%n"/>`)
+	_, err := seelog.LoggerFromConfigAsBytes(bytes.Replace(DefaultConfig(), []byte(`formatid="fmtinfo"`), []byte(`formatid="fmtjson"`), -1))
+	assert.NoError(t, err)
+}
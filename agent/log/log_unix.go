@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build freebsd || linux || netbsd || openbsd
 // +build freebsd linux netbsd openbsd
 
 package log
@@ -18,15 +19,17 @@ package log
 import (
 	"fmt"
 	"io/ioutil"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 )
 
-const (
+var (
 	// DefaultSeelogConfigFilePath specifies the default seelog location
 	// The underlying logger is based of https://github.com/cihub/seelog
 	// See Seelog documentation to customize the logger
-	DefaultSeelogConfigFilePath = "/etc/amazon/ssm/seelog.xml"
+	DefaultSeelogConfigFilePath = appconfig.SnapPath("/etc/amazon/ssm/seelog.xml")
 
-	DefaultLogDir = "/var/log/amazon/ssm"
+	DefaultLogDir = appconfig.SnapPath("/var/log/amazon/ssm")
 )
 
 // getLogConfigBytes reads and returns the seelog configs from the config file path if present
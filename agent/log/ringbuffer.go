@@ -0,0 +1,78 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"sync"
+
+	"github.com/cihub/seelog"
+)
+
+// RingBufferReceiverName is the seelog custom receiver keeping the recent log messages in memory for the crash dumps,
+// use it as <custom name="ring_buffer"/>
+const RingBufferReceiverName = "ring_buffer"
+
+// ringBufferSize is the number of recent log messages kept
+const ringBufferSize = 1000
+
+// recent holds the recent log messages of the process, shared by the receivers of the successive loggers
+var recent struct {
+	sync.Mutex
+	messages []string
+	next     int
+}
+
+func init() {
+	seelog.RegisterReceiver(RingBufferReceiverName, &RingBufferReceiver{})
+}
+
+// RingBufferReceiver implements seelog.CustomReceiver, it keeps the last ringBufferSize messages
+type RingBufferReceiver struct {
+}
+
+// ReceiveMessage keeps the formatted message, replacing the oldest message when the buffer is full
+func (r *RingBufferReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	recent.Lock()
+	defer recent.Unlock()
+	if len(recent.messages) < ringBufferSize {
+		recent.messages = append(recent.messages, message)
+		return nil
+	}
+	recent.messages[recent.next] = message
+	recent.next = (recent.next + 1) % ringBufferSize
+	return nil
+}
+
+// AfterParse does nothing, the receiver has no configuration
+func (r *RingBufferReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) error {
+	return nil
+}
+
+// Flush does nothing, the messages are kept in memory
+func (r *RingBufferReceiver) Flush() {
+}
+
+// Close keeps the messages, they outlive the logger for the crash dumps
+func (r *RingBufferReceiver) Close() error {
+	return nil
+}
+
+// RecentMessages returns the recent log messages of the process, oldest first
+func RecentMessages() []string {
+	recent.Lock()
+	defer recent.Unlock()
+	messages := make([]string, 0, len(recent.messages))
+	messages = append(messages, recent.messages[recent.next:]...)
+	return append(messages, recent.messages[:recent.next]...)
+}
@@ -0,0 +1,36 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBufferReceiver_KeepsRecentMessages(t *testing.T) {
+	receiver := &RingBufferReceiver{}
+	assert.NoError(t, receiver.AfterParse(seelog.CustomReceiverInitArgs{}))
+	for i := 0; i < ringBufferSize+5; i++ {
+		assert.NoError(t, receiver.ReceiveMessage(fmt.Sprintf("message %v\n", i), seelog.InfoLvl, nil))
+	}
+	assert.NoError(t, receiver.Close())
+
+	messages := RecentMessages()
+	assert.Len(t, messages, ringBufferSize)
+	assert.Equal(t, "message 5\n", messages[0])
+	assert.Equal(t, fmt.Sprintf("message %v\n", ringBufferSize+4), messages[ringBufferSize-1])
+}
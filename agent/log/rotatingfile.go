@@ -0,0 +1,286 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// RotatingFileReceiverName is the seelog custom receiver writing to a log file rotated by size and age, use it as
+// <custom name="rotating_file" data-filename="..." data-maxsize="..." data-maxagedays="..." data-maxrolls="..." data-retentiondays="..." data-compress="..."/>
+const RotatingFileReceiverName = "rotating_file"
+
+const (
+	// rotatedTimeFormat is the timestamp suffix of the rotated log files, it sorts in rotation order
+	rotatedTimeFormat = "20060102T150405.000000000"
+
+	// compressedSuffix is the suffix of the gzip compressed rotated log files
+	compressedSuffix = ".gz"
+
+	defaultRotatingFileMaxSize  = 30000000
+	defaultRotatingFileMaxRolls = 5
+)
+
+// timeNow returns the current time, it is stubbed in the tests
+var timeNow = time.Now
+
+func init() {
+	seelog.RegisterReceiver(RotatingFileReceiverName, &RotatingFileReceiver{})
+}
+
+// RotatingFileReceiver implements seelog.CustomReceiver, it rotates the log file when it exceeds a size or an age,
+// compresses the rotated files with gzip and removes the rotated files beyond the retention limits.
+type RotatingFileReceiver struct {
+	// filename is the path of the log file
+	filename string
+	// maxSize is the size in bytes the log file is rotated at, 0 disables the rotation by size
+	maxSize int64
+	// maxAge is the age the log file is rotated at, 0 disables the rotation by age
+	maxAge time.Duration
+	// maxRolls is the number of rotated files kept, 0 keeps all of them
+	maxRolls int
+	// retention is the age the rotated files are removed at, 0 keeps them regardless of their age
+	retention time.Duration
+	// compress enables the gzip compression of the rotated files
+	compress bool
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// maintenance serializes the compression and the removal of the rotated files
+	maintenance sync.Mutex
+	pending     sync.WaitGroup
+}
+
+// AfterParse reads the configuration of the receiver from the data- attributes of the custom seelog receiver
+func (r *RotatingFileReceiver) AfterParse(initArgs seelog.CustomReceiverInitArgs) (err error) {
+	attrs := initArgs.XmlCustomAttrs
+	if r.filename = attrs["filename"]; r.filename == "" {
+		return fmt.Errorf("%v receiver requires the data-filename attribute", RotatingFileReceiverName)
+	}
+	r.maxSize = defaultRotatingFileMaxSize
+	r.maxRolls = defaultRotatingFileMaxRolls
+	r.compress = true
+
+	var maxAgeDays, retentionDays int64
+	for name, value := range map[string]*int64{"maxsize": &r.maxSize, "maxagedays": &maxAgeDays, "retentiondays": &retentionDays} {
+		if err = parseNonNegativeAttr(attrs, name, value); err != nil {
+			return err
+		}
+	}
+	maxRolls := int64(r.maxRolls)
+	if err = parseNonNegativeAttr(attrs, "maxrolls", &maxRolls); err != nil {
+		return err
+	}
+	r.maxRolls = int(maxRolls)
+	r.maxAge = time.Duration(maxAgeDays) * 24 * time.Hour
+	r.retention = time.Duration(retentionDays) * 24 * time.Hour
+	if value, ok := attrs["compress"]; ok {
+		if r.compress, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%v receiver has an invalid data-compress attribute %v", RotatingFileReceiverName, value)
+		}
+	}
+	return nil
+}
+
+// parseNonNegativeAttr parses a data- attribute of the receiver when it is set
+func parseNonNegativeAttr(attrs map[string]string, name string, value *int64) error {
+	attr, ok := attrs[name]
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(attr, 10, 64)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("%v receiver has an invalid data-%v attribute %v", RotatingFileReceiverName, name, attr)
+	}
+	*value = parsed
+	return nil
+}
+
+// ReceiveMessage writes the formatted message to the log file, rotating it first when it is due
+func (r *RotatingFileReceiver) ReceiveMessage(message string, level seelog.LogLevel, context seelog.LogContextInterface) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	if r.size > 0 && ((r.maxSize > 0 && r.size+int64(len(message)) > r.maxSize) || (r.maxAge > 0 && timeNow().Sub(r.openedAt) >= r.maxAge)) {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	written, err := r.file.WriteString(message)
+	r.size += int64(written)
+	return err
+}
+
+// open opens the log file for append, the age of an existing file counts from when it is opened
+func (r *RotatingFileReceiver) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = timeNow()
+	return nil
+}
+
+// rotate renames the log file with the rotation timestamp, opens a new log file and
+// compresses and removes the rotated files in the background
+func (r *RotatingFileReceiver) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	stamped := r.filename + "." + timeNow().UTC().Format(rotatedTimeFormat)
+	rotated := stamped
+	for i := 1; fileExists(rotated) || fileExists(rotated+compressedSuffix); i++ {
+		rotated = fmt.Sprintf("%v.%v", stamped, i)
+	}
+	if err := os.Rename(r.filename, rotated); err != nil {
+		return err
+	}
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.maintain(rotated)
+	}()
+	return r.open()
+}
+
+// maintain compresses the rotated file and removes the rotated files beyond the retention limits
+func (r *RotatingFileReceiver) maintain(rotated string) {
+	r.maintenance.Lock()
+	defer r.maintenance.Unlock()
+
+	if r.compress {
+		if err := compressFile(rotated); err != nil {
+			fmt.Println("Error compressing rotated log file: ", err)
+		}
+	}
+	r.removeExpired()
+}
+
+// removeExpired removes the oldest rotated files beyond maxRolls and the rotated files older than the retention
+func (r *RotatingFileReceiver) removeExpired() {
+	rotatedFiles, err := filepath.Glob(r.filename + ".*")
+	if err != nil {
+		return
+	}
+	prefix := filepath.Base(r.filename) + "."
+	var candidates []string
+	for _, rotatedFile := range rotatedFiles {
+		if suffix := strings.TrimPrefix(filepath.Base(rotatedFile), prefix); len(suffix) >= len(rotatedTimeFormat) {
+			if _, err := time.Parse(rotatedTimeFormat, suffix[:len(rotatedTimeFormat)]); err == nil {
+				candidates = append(candidates, rotatedFile)
+			}
+		}
+	}
+	// newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(candidates)))
+	for i, rotatedFile := range candidates {
+		expired := r.maxRolls > 0 && i >= r.maxRolls
+		if !expired && r.retention > 0 {
+			if info, err := os.Stat(rotatedFile); err == nil && timeNow().Sub(info.ModTime()) >= r.retention {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(rotatedFile); err != nil {
+				fmt.Println("Error removing rotated log file: ", err)
+			}
+		}
+	}
+}
+
+// compressFile compresses the file to file.gz with gzip and removes the file
+func compressFile(path string) (err error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	compressedPath := path + compressedSuffix
+	destination, err := os.OpenFile(compressedPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(destination)
+	if _, err = io.Copy(writer, source); err == nil {
+		err = writer.Close()
+	}
+	if closeErr := destination.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(compressedPath)
+		return err
+	}
+	// the compressed file keeps the rotation time for the retention
+	os.Chtimes(compressedPath, info.ModTime(), info.ModTime())
+	source.Close()
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Flush does nothing, the messages are written to the log file without buffering
+func (r *RotatingFileReceiver) Flush() {
+}
+
+// Close closes the log file and waits for the compression and the removal of the rotated files
+func (r *RotatingFileReceiver) Close() (err error) {
+	r.mutex.Lock()
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mutex.Unlock()
+	r.pending.Wait()
+	return err
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cihub/seelog"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRotatingFile creates a receiver on a log file in a temporary directory
+func newTestRotatingFile(t *testing.T, attrs map[string]string) (*RotatingFileReceiver, string) {
+	dir, err := ioutil.TempDir("", "rotatingfile")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	filename := filepath.Join(dir, "logs", "amazon-ssm-agent.log")
+	attrs["filename"] = filename
+	receiver := &RotatingFileReceiver{}
+	assert.NoError(t, receiver.AfterParse(seelog.CustomReceiverInitArgs{XmlCustomAttrs: attrs}))
+	return receiver, filename
+}
+
+// rotatedFiles returns the rotated log files, oldest first
+func rotatedFiles(t *testing.T, filename string) []string {
+	files, err := filepath.Glob(filename + ".*")
+	assert.NoError(t, err)
+	sort.Strings(files)
+	return files
+}
+
+func readCompressed(t *testing.T, path string) string {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestRotatingFileAfterParse(t *testing.T) {
+	receiver, _ := newTestRotatingFile(t, map[string]string{})
+	assert.Equal(t, int64(defaultRotatingFileMaxSize), receiver.maxSize)
+	assert.Equal(t, defaultRotatingFileMaxRolls, receiver.maxRolls)
+	assert.Equal(t, time.Duration(0), receiver.maxAge)
+	assert.True(t, receiver.compress)
+
+	receiver, _ = newTestRotatingFile(t, map[string]string{"maxsize": "100", "maxagedays": "1", "maxrolls": "2", "retentiondays": "7", "compress": "false"})
+	assert.Equal(t, int64(100), receiver.maxSize)
+	assert.Equal(t, 24*time.Hour, receiver.maxAge)
+	assert.Equal(t, 2, receiver.maxRolls)
+	assert.Equal(t, 7*24*time.Hour, receiver.retention)
+	assert.False(t, receiver.compress)
+
+	for _, attrs := range []map[string]string{
+		{},
+		{"filename": "agent.log", "maxsize": "-1"},
+		{"filename": "agent.log", "maxrolls": "five"},
+		{"filename": "agent.log", "compress": "maybe"},
+	} {
+		assert.Error(t, (&RotatingFileReceiver{}).AfterParse(seelog.CustomReceiverInitArgs{XmlCustomAttrs: attrs}), "%v", attrs)
+	}
+}
+
+func TestRotatingFile_RotatesBySizeAndCompresses(t *testing.T) {
+	receiver, filename := newTestRotatingFile(t, map[string]string{"maxsize": "10"})
+
+	assert.NoError(t, receiver.ReceiveMessage("first\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("second\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.Close())
+
+	content, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(content))
+
+	rotated := rotatedFiles(t, filename)
+	assert.Len(t, rotated, 1)
+	assert.Equal(t, compressedSuffix, filepath.Ext(rotated[0]))
+	assert.Equal(t, "first\n", readCompressed(t, rotated[0]))
+}
+
+func TestRotatingFile_RotatesByAge(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	receiver, filename := newTestRotatingFile(t, map[string]string{"maxagedays": "1", "compress": "false"})
+	assert.NoError(t, receiver.ReceiveMessage("today\n", seelog.InfoLvl, nil))
+	now = now.Add(23 * time.Hour)
+	assert.NoError(t, receiver.ReceiveMessage("still today\n", seelog.InfoLvl, nil))
+	assert.Empty(t, rotatedFiles(t, filename))
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, receiver.ReceiveMessage("tomorrow\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.Close())
+
+	rotated := rotatedFiles(t, filename)
+	assert.Len(t, rotated, 1)
+	content, err := ioutil.ReadFile(rotated[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "today\nstill today\n", string(content))
+}
+
+func TestRotatingFile_RemovesRotatedFilesBeyondMaxRolls(t *testing.T) {
+	receiver, filename := newTestRotatingFile(t, map[string]string{"maxsize": "1", "maxrolls": "2"})
+	for _, message := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+		assert.NoError(t, receiver.ReceiveMessage(message, seelog.InfoLvl, nil))
+	}
+	assert.NoError(t, receiver.Close())
+
+	rotated := rotatedFiles(t, filename)
+	assert.Len(t, rotated, 2)
+	assert.Equal(t, "3\n", readCompressed(t, rotated[0]))
+	assert.Equal(t, "4\n", readCompressed(t, rotated[1]))
+}
+
+func TestRotatingFile_RemovesRotatedFilesBeyondRetention(t *testing.T) {
+	receiver, filename := newTestRotatingFile(t, map[string]string{"maxsize": "1", "maxrolls": "0", "retentiondays": "7", "compress": "false"})
+	assert.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	expired := filename + "." + time.Now().Add(-10*24*time.Hour).UTC().Format(rotatedTimeFormat) + compressedSuffix
+	unrelated := filename + ".bak"
+	for _, path := range []string{expired, unrelated} {
+		assert.NoError(t, ioutil.WriteFile(path, []byte("old\n"), 0644))
+		assert.NoError(t, os.Chtimes(path, time.Now().Add(-10*24*time.Hour), time.Now().Add(-10*24*time.Hour)))
+	}
+
+	assert.NoError(t, receiver.ReceiveMessage("1\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.ReceiveMessage("2\n", seelog.InfoLvl, nil))
+	assert.NoError(t, receiver.Close())
+
+	rotated := rotatedFiles(t, filename)
+	assert.Len(t, rotated, 2)
+	assert.NotContains(t, rotated, expired)
+	assert.Contains(t, rotated, unrelated)
+}
+
+func TestDefaultConfig_RotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "defaultconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logger, err := seelog.LoggerFromConfigAsBytes(LoadLog(dir, LogFile))
+	assert.NoError(t, err)
+	logger.Info("rotating file receiver")
+	logger.Close()
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, LogFile))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "rotating file receiver")
+}
@@ -15,10 +15,13 @@
 package ssmlog
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogsqueue"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/cihub/seelog"
 	"github.com/stretchr/testify/assert"
 )
@@ -57,3 +60,32 @@ func TestCloudWatchLogsReceiver(t *testing.T) {
 	assert.Nil(t, messages, "No Messages should be present")
 
 }
+
+func TestWithCloudWatchReceiver(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "ssmlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(logDir)
+
+	config := withCloudWatchReceiver(log.LoadLog(logDir, log.LogFile), "/aws/ssm/agent-logs")
+	assert.Contains(t, string(config), `<outputs formatid="fmtinfo"><custom name="cloudwatch_receiver" data-log-group="/aws/ssm/agent-logs"/>`)
+
+	seelog.RegisterReceiver(cloudWatchReceiverName, &CloudWatchCustomReceiver{})
+	logger, err := seelog.LoggerFromConfigAsBytes(config)
+	assert.NoError(t, err)
+	defer logger.Close()
+	assert.Equal(t, "/aws/ssm/agent-logs", cloudwatchlogsqueue.GetLogGroup())
+
+	logger.Info("shipped to cloudwatch")
+	logger.Flush()
+	messages, _ := cloudwatchlogsqueue.Dequeue(time.Millisecond)
+	assert.Len(t, messages, 1)
+	assert.Contains(t, *messages[0].Message, "shipped to cloudwatch")
+}
+
+func TestWithCloudWatchReceiver_ReceiverAlreadyConfigured(t *testing.T) {
+	config := []byte(`<seelog><outputs><custom name="cloudwatch_receiver" data-log-group="LogGroup"/></outputs></seelog>`)
+	assert.Equal(t, config, withCloudWatchReceiver(config, "/aws/ssm/agent-logs"))
+
+	config = []byte(`<seelog></seelog>`)
+	assert.Equal(t, config, withCloudWatchReceiver(config, "/aws/ssm/agent-logs"))
+}
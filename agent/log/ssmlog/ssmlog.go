@@ -15,18 +15,35 @@
 package ssmlog
 
 import (
+	"bytes"
 	"fmt"
-
+	"regexp"
 	"sync"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/cihub/seelog"
 )
 
+// cloudWatchReceiverName is the seelog custom receiver queuing the agent logs for the CloudWatch Logs publisher
+const cloudWatchReceiverName = "cloudwatch_receiver"
+
+// outputsPattern matches the opening outputs element of a seelog configuration
+var outputsPattern = regexp.MustCompile(`<outputs[^>]*>`)
+
 // loaded logger
 var loadedLogger *log.T
 var lock sync.RWMutex
 
+// levelOverride is the log level set at runtime, empty to use the level of the seelog configuration
+var levelOverride string
+
+// configuredLevel is the log level of the agent config file, empty to use the level of the seelog configuration
+var configuredLevel string
+var levelTimer *time.Timer
+var levelLock sync.Mutex
+
 // pkgMutex is the lock used to serialize calls to the logger.
 var pkgMutex = new(sync.Mutex)
 
@@ -44,7 +61,11 @@ func SSMLogger(useWatcher bool) log.T {
 // initLogger initializes a new logger based on current configurations and starts file watcher on the configurations file
 func initLogger(useWatcher bool) (logger log.T) {
 	// Read the current configurations or get the default configurations
-	logConfigBytes := log.GetLogConfigBytes()
+	logConfigBytes := withRecentMessages(log.GetLogConfigBytes())
+	if useWatcher {
+		// Ship the logs of the agent to CloudWatch Logs when configured
+		logConfigBytes = withAgentLogsToCloudWatch(logConfigBytes)
+	}
 	// Initialize the base seelog logger
 	baseLogger, _ := initBaseLoggerFromBytes(logConfigBytes)
 	// Create the wrapper logger
@@ -135,7 +156,7 @@ func replaceLogger() {
 	logger := getCached()
 
 	//Create new logger
-	logConfigBytes := log.GetLogConfigBytes()
+	logConfigBytes := withLevelOverride(withAgentLogsToCloudWatch(withRecentMessages(log.GetLogConfigBytes())))
 	baseLogger, err := initBaseLoggerFromBytes(logConfigBytes)
 
 	// If err in creating logger, do not replace logger
@@ -158,6 +179,120 @@ func replaceLogger() {
 	wrapper.ReplaceDelegate(baseLogger)
 }
 
+// SetLevel changes the minimum level of the agent logger at runtime, the level of the seelog configuration
+// is restored after revertAfter when it is positive
+func SetLevel(level string, revertAfter time.Duration) error {
+	if _, ok := seelog.LogLevelFromString(level); !ok {
+		return fmt.Errorf("invalid log level %v", level)
+	}
+	if !isLoaded() {
+		return fmt.Errorf("logger is not loaded")
+	}
+	levelLock.Lock()
+	levelOverride = level
+	if levelTimer != nil {
+		levelTimer.Stop()
+		levelTimer = nil
+	}
+	if revertAfter > 0 {
+		levelTimer = time.AfterFunc(revertAfter, ResetLevel)
+	}
+	levelLock.Unlock()
+
+	replaceLogger()
+	return nil
+}
+
+// SetConfiguredLevel sets the log level of the agent config file on the agent logger, an empty level restores
+// the level of the seelog configuration. A level set at runtime with SetLevel takes precedence until it is reset.
+func SetConfiguredLevel(level string) error {
+	if level != "" {
+		if _, ok := seelog.LogLevelFromString(level); !ok {
+			return fmt.Errorf("invalid log level %v", level)
+		}
+	}
+	if !isLoaded() {
+		return fmt.Errorf("logger is not loaded")
+	}
+	levelLock.Lock()
+	changed := configuredLevel != level
+	configuredLevel = level
+	levelLock.Unlock()
+
+	if changed {
+		replaceLogger()
+	}
+	return nil
+}
+
+// ResetLevel restores the level of the seelog configuration on the agent logger
+func ResetLevel() {
+	if !isLoaded() {
+		return
+	}
+	levelLock.Lock()
+	levelOverride = ""
+	if levelTimer != nil {
+		levelTimer.Stop()
+		levelTimer = nil
+	}
+	levelLock.Unlock()
+
+	replaceLogger()
+}
+
+// withLevelOverride sets the level changed at runtime, or else the level of the agent config file,
+// on the seelog configuration
+func withLevelOverride(seelogConfig []byte) []byte {
+	levelLock.Lock()
+	defer levelLock.Unlock()
+	if levelOverride != "" {
+		return log.WithMinLevel(seelogConfig, levelOverride)
+	}
+	if configuredLevel != "" {
+		return log.WithMinLevel(seelogConfig, configuredLevel)
+	}
+	return seelogConfig
+}
+
+// withAgentLogsToCloudWatch adds the CloudWatch Logs receiver to the seelog configuration when an agent log group
+// is configured in appconfig, a receiver already present in the seelog configuration takes precedence
+func withAgentLogsToCloudWatch(seelogConfig []byte) []byte {
+	config, err := appconfig.Config(false)
+	if err != nil || config.Logs.AgentLogGroup == "" {
+		return seelogConfig
+	}
+	return withCloudWatchReceiver(seelogConfig, config.Logs.AgentLogGroup)
+}
+
+// withCloudWatchReceiver adds a CloudWatch Logs receiver of the log group to the outputs of the seelog configuration
+func withCloudWatchReceiver(seelogConfig []byte, logGroup string) []byte {
+	receiver := fmt.Sprintf(`<custom name="%v" data-log-group="%v"/>`, cloudWatchReceiverName, logGroup)
+	return withCustomReceiver(seelogConfig, cloudWatchReceiverName, receiver)
+}
+
+// withRecentMessages adds the receiver keeping the recent log messages for the crash dumps to the seelog configuration
+func withRecentMessages(seelogConfig []byte) []byte {
+	receiver := fmt.Sprintf(`<custom name="%v"/>`, log.RingBufferReceiverName)
+	return withCustomReceiver(seelogConfig, log.RingBufferReceiverName, receiver)
+}
+
+// withCustomReceiver adds the custom receiver element to the outputs of the seelog configuration,
+// unless a custom receiver of the same name is already present
+func withCustomReceiver(seelogConfig []byte, name string, receiver string) []byte {
+	if bytes.Contains(seelogConfig, []byte(`"`+name+`"`)) {
+		return seelogConfig
+	}
+	outputs := outputsPattern.FindIndex(seelogConfig)
+	if outputs == nil {
+		return seelogConfig
+	}
+	withReceiver := make([]byte, 0, len(seelogConfig)+len(receiver))
+	withReceiver = append(withReceiver, seelogConfig[:outputs[1]]...)
+	withReceiver = append(withReceiver, receiver...)
+	return append(withReceiver, seelogConfig[outputs[1]:]...)
+}
+
 // initLoggerFromBytes creates a new wrapper logger from configurations passed
 func initLoggerFromBytes(seelogConfig []byte) log.T {
 	logger, _ := initBaseLoggerFromBytes(seelogConfig)
@@ -168,7 +303,7 @@ func initLoggerFromBytes(seelogConfig []byte) log.T {
 func initBaseLoggerFromBytes(seelogConfig []byte) (seelogger seelog.LoggerInterface, err error) {
 	fmt.Println("Initializing new seelog logger")
 	logReceiver := &CloudWatchCustomReceiver{}
-	seelog.RegisterReceiver("cloudwatch_receiver", logReceiver)
+	seelog.RegisterReceiver(cloudWatchReceiverName, logReceiver)
 	seelogger, err = seelog.LoggerFromConfigAsBytes(seelogConfig)
 	if err != nil {
 		fmt.Println("Error parsing logger config. Creating logger from default config:", err)
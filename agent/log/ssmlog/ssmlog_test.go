@@ -163,3 +163,49 @@ func TestReplaceLogger(t *testing.T) {
 	assert.Equal(t, newOutput, out.String())
 
 }
+
+func TestWithLevelOverride(t *testing.T) {
+	config := []byte(`<seelog minlevel="info"><outputs><console/></outputs></seelog>`)
+	assert.Equal(t, config, withLevelOverride(config))
+
+	levelOverride = "debug"
+	defer func() { levelOverride = "" }()
+	assert.Equal(t, `<seelog minlevel="debug"><outputs><console/></outputs></seelog>`, string(withLevelOverride(config)))
+}
+
+func TestWithLevelOverride_ConfiguredLevel(t *testing.T) {
+	config := []byte(`<seelog minlevel="info"><outputs><console/></outputs></seelog>`)
+	configuredLevel = "warn"
+	defer func() { configuredLevel = "" }()
+	assert.Equal(t, `<seelog minlevel="warn"><outputs><console/></outputs></seelog>`, string(withLevelOverride(config)))
+
+	// the level set at runtime takes precedence over the level of the agent config file
+	levelOverride = "trace"
+	defer func() { levelOverride = "" }()
+	assert.Equal(t, `<seelog minlevel="trace"><outputs><console/></outputs></seelog>`, string(withLevelOverride(config)))
+}
+
+func TestSetConfiguredLevel_InvalidLevel(t *testing.T) {
+	assert.Error(t, SetConfiguredLevel("verbose"))
+	assert.Empty(t, configuredLevel)
+}
+
+func TestSetLevel_InvalidLevel(t *testing.T) {
+	assert.Error(t, SetLevel("verbose", 0))
+	assert.Empty(t, levelOverride)
+}
+
+func TestWithRecentMessages(t *testing.T) {
+	config := withRecentMessages(log.DefaultConfig())
+	assert.Contains(t, string(config), `<custom name="ring_buffer"/>`)
+	// the receiver is added once
+	assert.Equal(t, config, withRecentMessages(config))
+
+	logger, err := seelog.LoggerFromConfigAsBytes(config)
+	assert.NoError(t, err)
+	logger.Info("message kept for the crash dumps")
+	logger.Flush()
+	logger.Close()
+	messages := log.RecentMessages()
+	assert.Contains(t, messages[len(messages)-1], "message kept for the crash dumps")
+}
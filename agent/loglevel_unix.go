@@ -0,0 +1,65 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	logger "github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+)
+
+// debugLogLevel is the log level turned on by SIGUSR1
+const debugLogLevel = "debug"
+
+// handleLogLevelSignals turns the debug log level on at SIGUSR1 and restores the configured log level at SIGUSR2
+// The debug log level is turned off after the DebugLogMinutes of appconfig.
+func handleLogLevelSignals(log logger.T) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+	for s := range c {
+		handleLogLevelSignal(log, s)
+	}
+}
+
+func handleLogLevelSignal(log logger.T, s os.Signal) {
+	switch s {
+	case syscall.SIGUSR1:
+		config, err := appconfig.Config(false)
+		if err != nil {
+			log.Warnf("appconfig could not be loaded, using the default debug log duration - %v", err)
+			config = appconfig.DefaultConfig()
+		}
+		duration := time.Duration(config.Agent.DebugLogMinutes) * time.Minute
+		if err = ssmlog.SetLevel(debugLogLevel, duration); err != nil {
+			log.Errorf("failed to turn on the debug log level: %v", err)
+			return
+		}
+		if duration > 0 {
+			log.Infof("Debug log level turned on for %v", duration)
+		} else {
+			log.Info("Debug log level turned on")
+		}
+	case syscall.SIGUSR2:
+		ssmlog.ResetLevel()
+		log.Info("Configured log level restored")
+	}
+}
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build windows
 // +build windows
 
 // Package cloudwatch implements cloudwatch plugin and its configuration
@@ -75,9 +76,9 @@ var exec = executers.ShellCommandExecuter{}
 
 // var createScript = pluginutil.CreateScriptFile
 
-//todo: honor cancel flag for Start
-//todo: honor cancel flag for Stop
-//todo: Start,Stop -> should return plugin.result or error as well -> so that caller can report the results/errors accordingly.
+// todo: honor cancel flag for Start
+// todo: honor cancel flag for Stop
+// todo: Start,Stop -> should return plugin.result or error as well -> so that caller can report the results/errors accordingly.
 // NewPlugin returns a new instance of Cloudwatch plugin
 func NewPlugin(pluginConfig iohandler.PluginConfig) (*Plugin, error) {
 
@@ -1,3 +1,4 @@
+//go:build windows
 // +build windows
 
 // Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
@@ -13,7 +14,6 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
-//
 // Package rundaemon implements rundaemon plugin and its configuration
 package rundaemon
 
@@ -38,7 +38,7 @@ var StopDaemonExecutor = StopDaemon
 var IsDaemonRunningExecutor = IsDaemonRunning
 var StartDaemonHelperExecutor = StartDaemonHelper
 
-//  RequestedDaemonStateType represents whether the user has explicitly requested to start/stop the daemon
+// RequestedDaemonStateType represents whether the user has explicitly requested to start/stop the daemon
 type RequestedDaemonStateType uint
 
 const (
@@ -46,7 +46,7 @@ const (
 	RequestedEnabled
 )
 
-//  CurrentDaemonStateType represents whether the daemon is currently running or not.
+// CurrentDaemonStateType represents whether the daemon is currently running or not.
 type CurrentDaemonStateType uint
 
 const (
@@ -1,3 +1,4 @@
+//go:build windows
 // +build windows
 
 // Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
@@ -13,7 +14,6 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
-//
 // Package rundaemon implements rundaemon plugin and its configuration
 package rundaemon
 
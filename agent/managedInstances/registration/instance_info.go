@@ -31,6 +31,8 @@ type instanceInfo struct {
 	AvailabilityZone string `json:"availabilityZone"`
 	PrivateKey       string `json:"privateKey"`
 	PrivateKeyType   string `json:"privateKeyType"`
+	// PrivateKeyProtection is the protection sealing the persisted private key, empty when it is stored as is
+	PrivateKeyProtection string `json:"privateKeyProtection,omitempty"`
 }
 
 var (
@@ -145,8 +147,13 @@ func updateServerInfo(info instanceInfo) (err error) {
 	lock.Lock()
 	defer lock.Unlock()
 
+	stored, err := sealPrivateKey(info)
+	if err != nil {
+		return fmt.Errorf("Failed to protect the private key. %v", err)
+	}
+
 	var data []byte
-	if data, err = json.Marshal(info); err != nil {
+	if data, err = json.Marshal(stored); err != nil {
 		return fmt.Errorf("Failed to marshal instance info. %v", err)
 	} else {
 		//call vault apis here and update the refId
@@ -156,6 +163,7 @@ func updateServerInfo(info instanceInfo) (err error) {
 	}
 
 	loadedServerInfo = info
+	loadedServerInfo.PrivateKeyProtection = stored.PrivateKeyProtection
 	return
 }
 
@@ -170,6 +178,9 @@ func loadServerInfo() error {
 		if err = json.Unmarshal(d, &info); err != nil {
 			return fmt.Errorf("Failed to unmarshal instance info. %v", err)
 		}
+		if info, err = unsealPrivateKey(info); err != nil {
+			return fmt.Errorf("Failed to load instance info. %v", err)
+		}
 	}
 
 	loadedServerInfo = info
@@ -0,0 +1,123 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// package registration provides managed instance information
+package registration
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// keyProtector seals the registration private key with a secret that never leaves the machine
+type keyProtector interface {
+	Seal(data []byte) ([]byte, error)
+	Unseal(sealed []byte) ([]byte, error)
+}
+
+// keyProtection returns the protection configured for the registration private key
+var keyProtection = func() string {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return appconfig.KeyProtectionNone
+	}
+	return config.Registration.KeyProtection
+}
+
+// getKeyProtector returns the key protector of the platform for a key protection
+var getKeyProtector = newKeyProtector
+
+// isProtected returns true when the key protection seals the private key
+func isProtected(protection string) bool {
+	return protection != "" && protection != appconfig.KeyProtectionNone
+}
+
+// sealPrivateKey returns the instance info to persist, with the private key sealed by the configured key protection
+func sealPrivateKey(info instanceInfo) (instanceInfo, error) {
+	protection := keyProtection()
+	if info.PrivateKey == "" || !isProtected(protection) {
+		info.PrivateKeyProtection = ""
+		return info, nil
+	}
+
+	protector, err := getKeyProtector(protection)
+	if err != nil {
+		return info, err
+	}
+	sealed, err := protector.Seal([]byte(info.PrivateKey))
+	if err != nil {
+		return info, fmt.Errorf("Failed to seal the private key with %v. %v", protection, err)
+	}
+	info.PrivateKey = base64.StdEncoding.EncodeToString(sealed)
+	info.PrivateKeyProtection = protection
+	return info, nil
+}
+
+// unsealPrivateKey returns the persisted instance info with the private key unsealed
+func unsealPrivateKey(info instanceInfo) (instanceInfo, error) {
+	if info.PrivateKey == "" || !isProtected(info.PrivateKeyProtection) {
+		return info, nil
+	}
+
+	protector, err := getKeyProtector(info.PrivateKeyProtection)
+	if err != nil {
+		return info, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(info.PrivateKey)
+	if err != nil {
+		return info, fmt.Errorf("Failed to decode the sealed private key. %v", err)
+	}
+	privateKey, err := protector.Unseal(sealed)
+	if err != nil {
+		return info, fmt.Errorf("Failed to unseal the private key with %v, the registration may have been copied from another machine. %v", info.PrivateKeyProtection, err)
+	}
+	info.PrivateKey = string(privateKey)
+	return info, nil
+}
+
+// encryptWithKey encrypts data with AES-GCM, used when the protector can only seal small secrets
+func encryptWithKey(key []byte, data []byte) (nonce []byte, ciphertext []byte, err error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	ciphertext = aead.Seal(nil, nonce, data, nil)
+	return
+}
+
+// decryptWithKey decrypts data encrypted by encryptWithKey
+func decryptWithKey(key []byte, nonce []byte, ciphertext []byte) ([]byte, error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// package registration provides managed instance information
+package registration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// keyProtectorStub reverses the data it seals
+type keyProtectorStub struct {
+	err error
+}
+
+func (p keyProtectorStub) Seal(data []byte) ([]byte, error) {
+	return reverse(data), p.err
+}
+
+func (p keyProtectorStub) Unseal(sealed []byte) ([]byte, error) {
+	return reverse(sealed), p.err
+}
+
+func reverse(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+	return reversed
+}
+
+// recordingVault keeps the data stored
+type recordingVault struct {
+	data []byte
+}
+
+func (v *recordingVault) Store(key string, data []byte) error {
+	v.data = data
+	return nil
+}
+
+func (v *recordingVault) Retrieve(key string) ([]byte, error) {
+	return v.data, nil
+}
+
+func setKeyProtection(t *testing.T, protection string, protector keyProtector) {
+	keyProtection = func() string { return protection }
+	getKeyProtector = func(string) (keyProtector, error) { return protector, nil }
+	t.Cleanup(func() {
+		keyProtection = func() string { return appconfig.KeyProtectionNone }
+		getKeyProtector = newKeyProtector
+	})
+}
+
+func TestUpdateServerInfo_SealsPrivateKey(t *testing.T) {
+	setKeyProtection(t, appconfig.KeyProtectionTPM, keyProtectorStub{})
+	store := &recordingVault{}
+	vault = store
+
+	assert.Nil(t, UpdateServerInfo(sampleID, sampleRegion, samplePrivateKey, "Rsa"))
+	assert.Equal(t, samplePrivateKey, PrivateKey())
+
+	var stored instanceInfo
+	assert.Nil(t, json.Unmarshal(store.data, &stored))
+	assert.Equal(t, appconfig.KeyProtectionTPM, stored.PrivateKeyProtection)
+	assert.NotContains(t, string(store.data), samplePrivateKey)
+
+	assert.Nil(t, loadServerInfo())
+	assert.Equal(t, samplePrivateKey, PrivateKey())
+}
+
+func TestLoadServerInfo_UnsealFailure(t *testing.T) {
+	setKeyProtection(t, appconfig.KeyProtectionTPM, keyProtectorStub{})
+	store := &recordingVault{}
+	vault = store
+	assert.Nil(t, UpdateServerInfo(sampleID, sampleRegion, samplePrivateKey, "Rsa"))
+
+	getKeyProtector = func(string) (keyProtector, error) { return keyProtectorStub{err: errors.New("unseal failed")}, nil }
+	assert.NotNil(t, loadServerInfo())
+}
+
+func TestUpdateServerInfo_NoProtection(t *testing.T) {
+	setKeyProtection(t, appconfig.KeyProtectionNone, nil)
+	store := &recordingVault{}
+	vault = store
+
+	assert.Nil(t, UpdateServerInfo(sampleID, sampleRegion, samplePrivateKey, "Rsa"))
+	assert.Contains(t, string(store.data), samplePrivateKey)
+	assert.NotContains(t, string(store.data), "privateKeyProtection")
+}
+
+func TestEncryptWithKey(t *testing.T) {
+	key := make([]byte, 32)
+	nonce, ciphertext, err := encryptWithKey(key, []byte(samplePrivateKey))
+	assert.Nil(t, err)
+	plaintext, err := decryptWithKey(key, nonce, ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePrivateKey, string(plaintext))
+
+	key[0] = 1
+	_, err = decryptWithKey(key, nonce, ciphertext)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+// package registration provides managed instance information
+package registration
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// tpmSealKeySize is the size of the AES key sealed by the TPM, sealed objects are limited to 128 bytes
+const tpmSealKeySize = 32
+
+// newKeyProtector returns the key protector for a key protection, TPM 2.0 through the tpm2-tools
+func newKeyProtector(protection string) (keyProtector, error) {
+	switch protection {
+	case appconfig.KeyProtectionTPM:
+		return tpmKeyProtector{}, nil
+	case appconfig.KeyProtectionKeystore, appconfig.KeyProtectionDpapiNg:
+		return nil, fmt.Errorf("%v key protection is only supported on Windows, use %v", protection, appconfig.KeyProtectionTPM)
+	}
+	return nil, fmt.Errorf("unsupported key protection %v", protection)
+}
+
+// tpmKeyProtector seals an AES key under the storage primary key of the TPM owner hierarchy,
+// the private key is encrypted with the AES key
+type tpmKeyProtector struct{}
+
+type tpmSealedKey struct {
+	Public     []byte
+	Private    []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// runTpmCommand runs a tpm2-tools command in a working directory and returns its output
+var runTpmCommand = func(dir string, stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v failed. %v %v", name, err, stderr.String())
+	}
+	return output, nil
+}
+
+// Seal seals data with the TPM
+func (tpmKeyProtector) Seal(data []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "tpm")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, tpmSealKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err = runTpmCommand(dir, nil, "tpm2_createprimary", "-C", "o", "-c", "primary.ctx"); err != nil {
+		return nil, err
+	}
+	// the AES key is passed through stdin so it is never written to disk
+	if _, err = runTpmCommand(dir, key, "tpm2_create", "-C", "primary.ctx", "-i", "-", "-u", "seal.pub", "-r", "seal.priv"); err != nil {
+		return nil, err
+	}
+
+	var sealed tpmSealedKey
+	if sealed.Public, err = ioutil.ReadFile(filepath.Join(dir, "seal.pub")); err != nil {
+		return nil, err
+	}
+	if sealed.Private, err = ioutil.ReadFile(filepath.Join(dir, "seal.priv")); err != nil {
+		return nil, err
+	}
+	if sealed.Nonce, sealed.Ciphertext, err = encryptWithKey(key, data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// Unseal unseals data sealed with the TPM, it fails on any other machine
+func (tpmKeyProtector) Unseal(data []byte) ([]byte, error) {
+	var sealed tpmSealedKey
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "tpm")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "seal.pub"), sealed.Public, appconfig.ReadWriteAccess); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "seal.priv"), sealed.Private, appconfig.ReadWriteAccess); err != nil {
+		return nil, err
+	}
+	if _, err = runTpmCommand(dir, nil, "tpm2_createprimary", "-C", "o", "-c", "primary.ctx"); err != nil {
+		return nil, err
+	}
+	if _, err = runTpmCommand(dir, nil, "tpm2_load", "-C", "primary.ctx", "-u", "seal.pub", "-r", "seal.priv", "-c", "seal.ctx"); err != nil {
+		return nil, err
+	}
+	key, err := runTpmCommand(dir, nil, "tpm2_unseal", "-c", "seal.ctx")
+	if err != nil {
+		return nil, err
+	}
+	return decryptWithKey(key, sealed.Nonce, sealed.Ciphertext)
+}
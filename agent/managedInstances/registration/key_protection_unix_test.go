@@ -0,0 +1,59 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+// package registration provides managed instance information
+package registration
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTpm stores the sealed key as is in the private part of the sealed object
+func fakeTpm(t *testing.T) *[]string {
+	var commands []string
+	runTpmCommand = func(dir string, stdin []byte, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name)
+		switch name {
+		case "tpm2_create":
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "seal.pub"), []byte("public"), 0600))
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "seal.priv"), stdin, 0600))
+		case "tpm2_unseal":
+			return ioutil.ReadFile(filepath.Join(dir, "seal.priv"))
+		}
+		return nil, nil
+	}
+	return &commands
+}
+
+func TestTpmKeyProtector(t *testing.T) {
+	defer func(run func(string, []byte, string, ...string) ([]byte, error)) { runTpmCommand = run }(runTpmCommand)
+	commands := fakeTpm(t)
+
+	sealed, err := tpmKeyProtector{}.Seal([]byte(samplePrivateKey))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(sealed), samplePrivateKey)
+	assert.Equal(t, []string{"tpm2_createprimary", "tpm2_create"}, *commands)
+
+	data, err := tpmKeyProtector{}.Unseal(sealed)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePrivateKey, string(data))
+	assert.Equal(t, []string{"tpm2_createprimary", "tpm2_create", "tpm2_createprimary", "tpm2_load", "tpm2_unseal"}, *commands)
+}
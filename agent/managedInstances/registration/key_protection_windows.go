@@ -0,0 +1,219 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+// package registration provides managed instance information
+package registration
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	cryptProtectUIForbidden  = 0x1
+	cryptProtectLocalMachine = 0x4
+	ncryptSilentFlag         = 0x40
+)
+
+// agentServiceName is the service whose account the DPAPI-NG descriptor defaults to
+const agentServiceName = "AmazonSSMAgent"
+
+var (
+	crypt32                              = syscall.NewLazyDLL("crypt32.dll")
+	kernel32                             = syscall.NewLazyDLL("kernel32.dll")
+	ncrypt                               = syscall.NewLazyDLL("ncrypt.dll")
+	procCryptProtectData                 = crypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData               = crypt32.NewProc("CryptUnprotectData")
+	procLocalFree                        = kernel32.NewProc("LocalFree")
+	procNCryptCreateProtectionDescriptor = ncrypt.NewProc("NCryptCreateProtectionDescriptor")
+	procNCryptCloseProtectionDescriptor  = ncrypt.NewProc("NCryptCloseProtectionDescriptor")
+	procNCryptProtectSecret              = ncrypt.NewProc("NCryptProtectSecret")
+	procNCryptUnprotectSecret            = ncrypt.NewProc("NCryptUnprotectSecret")
+)
+
+// protectionDescriptor returns the configured DPAPI-NG protection descriptor, it is stubbed in the tests
+var protectionDescriptor = func() string {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		return ""
+	}
+	return config.Registration.ProtectionDescriptor
+}
+
+// newKeyProtector returns the key protector for a key protection, the machine scope of DPAPI or DPAPI-NG
+func newKeyProtector(protection string) (keyProtector, error) {
+	switch protection {
+	case appconfig.KeyProtectionKeystore:
+		return dpapiKeyProtector{}, nil
+	case appconfig.KeyProtectionDpapiNg:
+		return dpapiNgKeyProtector{}, nil
+	case appconfig.KeyProtectionTPM:
+		return nil, fmt.Errorf("%v key protection is not supported on Windows, use %v", protection, appconfig.KeyProtectionKeystore)
+	}
+	return nil, fmt.Errorf("unsupported key protection %v", protection)
+}
+
+// dpapiKeyProtector seals data with the DPAPI machine key, which cannot be exported from the machine
+type dpapiKeyProtector struct{}
+
+type dataBlob struct {
+	cbData uint32
+	pbData *byte
+}
+
+func newDataBlob(data []byte) *dataBlob {
+	if len(data) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{cbData: uint32(len(data)), pbData: &data[0]}
+}
+
+// bytes copies the data of a blob allocated by DPAPI and frees it
+func (blob *dataBlob) bytes() []byte {
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(blob.pbData)))
+	data := make([]byte, blob.cbData)
+	copy(data, (*[1 << 30]byte)(unsafe.Pointer(blob.pbData))[:blob.cbData:blob.cbData])
+	return data
+}
+
+// Seal seals data with DPAPI
+func (dpapiKeyProtector) Seal(data []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procCryptProtectData.Call(
+		uintptr(unsafe.Pointer(newDataBlob(data))),
+		0, 0, 0, 0,
+		cryptProtectUIForbidden|cryptProtectLocalMachine,
+		uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, fmt.Errorf("CryptProtectData failed. %v", err)
+	}
+	return out.bytes(), nil
+}
+
+// Unseal unseals data sealed with DPAPI, it fails on any other machine
+func (dpapiKeyProtector) Unseal(data []byte) ([]byte, error) {
+	var out dataBlob
+	r, _, err := procCryptUnprotectData.Call(
+		uintptr(unsafe.Pointer(newDataBlob(data))),
+		0, 0, 0, 0,
+		cryptProtectUIForbidden,
+		uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, fmt.Errorf("CryptUnprotectData failed. %v", err)
+	}
+	return out.bytes(), nil
+}
+
+// dpapiNgKeyProtector seals data with DPAPI-NG for a protection descriptor, by default the domain account the agent
+// service runs as, typically a gMSA, so that only the machines allowed to retrieve the password of the account can
+// unseal it and not the local administrators of the other machines
+type dpapiNgKeyProtector struct{}
+
+// Seal seals data with DPAPI-NG
+func (dpapiNgKeyProtector) Seal(data []byte) ([]byte, error) {
+	descriptor := protectionDescriptor()
+	if descriptor == "" {
+		var err error
+		if descriptor, err = serviceAccountDescriptor(); err != nil {
+			return nil, err
+		}
+	}
+	descriptorPtr, err := syscall.UTF16PtrFromString(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	var handle uintptr
+	if r, _, _ := procNCryptCreateProtectionDescriptor.Call(
+		uintptr(unsafe.Pointer(descriptorPtr)),
+		0,
+		uintptr(unsafe.Pointer(&handle))); r != 0 {
+		return nil, fmt.Errorf("NCryptCreateProtectionDescriptor failed for %v with 0x%x", descriptor, r)
+	}
+	defer procNCryptCloseProtectionDescriptor.Call(handle)
+
+	var out dataBlob
+	if r, _, _ := procNCryptProtectSecret.Call(
+		handle,
+		ncryptSilentFlag,
+		uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)),
+		0, 0,
+		uintptr(unsafe.Pointer(&out.pbData)),
+		uintptr(unsafe.Pointer(&out.cbData))); r != 0 {
+		return nil, fmt.Errorf("NCryptProtectSecret failed with 0x%x", r)
+	}
+	return out.bytes(), nil
+}
+
+// Unseal unseals data sealed with DPAPI-NG, the descriptor is read from the sealed data
+func (dpapiNgKeyProtector) Unseal(data []byte) ([]byte, error) {
+	var out dataBlob
+	if r, _, _ := procNCryptUnprotectSecret.Call(
+		0,
+		ncryptSilentFlag,
+		uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)),
+		0, 0,
+		uintptr(unsafe.Pointer(&out.pbData)),
+		uintptr(unsafe.Pointer(&out.cbData))); r != 0 {
+		return nil, fmt.Errorf("NCryptUnprotectSecret failed with 0x%x", r)
+	}
+	return out.bytes(), nil
+}
+
+// serviceAccountDescriptor returns the protection descriptor of the account the agent service runs as, the local
+// system accounts are refused since their SIDs are the same on every machine
+func serviceAccountDescriptor() (string, error) {
+	manager, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return "", fmt.Errorf("Failed to connect to the service manager. %v", err)
+	}
+	defer windows.CloseServiceHandle(manager)
+	handle, err := windows.OpenService(manager, syscall.StringToUTF16Ptr(agentServiceName), windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open the %v service. %v", agentServiceName, err)
+	}
+	service := &mgr.Service{Name: agentServiceName, Handle: handle}
+	defer service.Close()
+	config, err := service.Config()
+	if err != nil {
+		return "", fmt.Errorf("Failed to query the %v service. %v", agentServiceName, err)
+	}
+
+	account := config.ServiceStartName
+	if account == "" || strings.EqualFold(account, "LocalSystem") {
+		return "", fmt.Errorf("%v key protection needs the %v service to run as a gMSA, it runs as LocalSystem", appconfig.KeyProtectionDpapiNg, agentServiceName)
+	}
+	sid, domain, _, err := windows.LookupSID("", account)
+	if err != nil {
+		return "", fmt.Errorf("Failed to look up the account %v. %v", account, err)
+	}
+	if strings.EqualFold(domain, "NT AUTHORITY") || strings.EqualFold(domain, "NT SERVICE") {
+		return "", fmt.Errorf("%v key protection needs the %v service to run as a gMSA, it runs as %v", appconfig.KeyProtectionDpapiNg, agentServiceName, account)
+	}
+	sidString, err := sid.String()
+	if err != nil {
+		return "", err
+	}
+	return "SID=" + sidString, nil
+}
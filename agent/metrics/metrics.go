@@ -0,0 +1,205 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package metrics exposes the operational metrics of the agent in the Prometheus text format
+// and publishes them to CloudWatch or to a local file or StatsD sink.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	counterType = "counter"
+	gaugeType   = "gauge"
+)
+
+var (
+	// MessagesReceived counts the messages received from the services, by service
+	MessagesReceived = newMetric("ssm_agent_messages_received_total", "Messages received from the services.", counterType, "service")
+	// DocumentExecutions counts the completed document executions, by status
+	DocumentExecutions = newMetric("ssm_agent_document_executions_total", "Completed document executions.", counterType, "status")
+	// ActiveSessions is the number of sessions running on the instance
+	ActiveSessions = newMetric("ssm_agent_active_sessions", "Sessions running on the instance.", gaugeType, "")
+	// SessionsEnded counts the ended sessions, by status
+	SessionsEnded = newMetric("ssm_agent_sessions_ended_total", "Ended sessions.", counterType, "status")
+	// SessionDurationSeconds counts the seconds the ended sessions ran for
+	SessionDurationSeconds = newMetric("ssm_agent_session_duration_seconds_total", "Seconds the ended sessions ran for.", counterType, "")
+	// QueuedDocuments is the number of documents waiting for a free worker, by document type
+	QueuedDocuments = newMetric("ssm_agent_queued_documents", "Documents waiting for a free worker.", gaugeType, "document_type")
+	// UploadFailures counts the failed uploads of command results and outputs, by destination
+	UploadFailures = newMetric("ssm_agent_upload_failures_total", "Failed uploads of command results and outputs.", counterType, "destination")
+	// Reconnects counts the reconnections of the channels to the services, by channel
+	Reconnects = newMetric("ssm_agent_reconnects_total", "Reconnections of the channels to the services.", counterType, "channel")
+	// WorkerProcesses is the number of running worker processes, by worker
+	WorkerProcesses = newMetric("ssm_agent_worker_processes", "Running worker processes.", gaugeType, "worker")
+	// WorkerFailures counts the worker processes that failed to start or exited unsuccessfully, by worker
+	WorkerFailures = newMetric("ssm_agent_worker_failures_total", "Worker processes that failed to start or exited unsuccessfully.", counterType, "worker")
+	// WatchdogRecoveries counts the stuck workers killed and the stalled core loops restarted by the watchdog, by target
+	WatchdogRecoveries = newMetric("ssm_agent_watchdog_recoveries_total", "Stuck workers killed and stalled core loops restarted by the watchdog.", counterType, "target")
+	// ProcessResources is the usage of the agent process at the last self-check, by resource
+	ProcessResources = newMetric("ssm_agent_process_resources", "Usage of the agent process at the last self-check.", gaugeType, "resource")
+)
+
+// registry holds the metrics in the order they are exposed
+var registry = []*Metric{
+	MessagesReceived,
+	DocumentExecutions,
+	ActiveSessions,
+	SessionsEnded,
+	SessionDurationSeconds,
+	QueuedDocuments,
+	UploadFailures,
+	Reconnects,
+	WorkerProcesses,
+	WorkerFailures,
+	WatchdogRecoveries,
+	ProcessResources,
+}
+
+// sessionStarts holds the start time of the running sessions, by session id
+var sessionStarts = struct {
+	sync.Mutex
+	times map[string]time.Time
+}{times: make(map[string]time.Time)}
+
+// timeNow returns the current time
+var timeNow = time.Now
+
+// Metric is a counter or gauge with an optional label
+type Metric struct {
+	name       string
+	help       string
+	metricType string
+	label      string
+
+	mutex  sync.Mutex
+	values map[string]int64
+}
+
+func newMetric(name string, help string, metricType string, label string) *Metric {
+	return &Metric{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		label:      label,
+		values:     make(map[string]int64),
+	}
+}
+
+// Inc increments the metric for the label value, the label value is ignored for metrics without label
+func (m *Metric) Inc(labelValue string) {
+	m.Add(labelValue, 1)
+}
+
+// Dec decrements the gauge for the label value, the label value is ignored for metrics without label
+func (m *Metric) Dec(labelValue string) {
+	m.Add(labelValue, -1)
+}
+
+// Add adds delta to the metric for the label value, the label value is ignored for metrics without label
+func (m *Metric) Add(labelValue string, delta int64) {
+	if m.label == "" {
+		labelValue = ""
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.values[labelValue] += delta
+}
+
+// Set sets the gauge for the label value, the label value is ignored for metrics without label
+func (m *Metric) Set(labelValue string, value int64) {
+	if m.label == "" {
+		labelValue = ""
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.values[labelValue] = value
+}
+
+// Value returns the value of the metric for the label value
+func (m *Metric) Value(labelValue string) int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.values[labelValue]
+}
+
+// snapshot returns the values of the metric, by label value
+func (m *Metric) snapshot() map[string]int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	values := make(map[string]int64, len(m.values))
+	for labelValue, value := range m.values {
+		values[labelValue] = value
+	}
+	return values
+}
+
+// write writes the metric in the Prometheus text format
+func (m *Metric) write(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", m.name, m.help, m.name, m.metricType)
+	if m.label == "" {
+		fmt.Fprintf(w, "%v %v\n", m.name, m.values[""])
+		return
+	}
+	labelValues := make([]string, 0, len(m.values))
+	for labelValue := range m.values {
+		labelValues = append(labelValues, labelValue)
+	}
+	sort.Strings(labelValues)
+	for _, labelValue := range labelValues {
+		fmt.Fprintf(w, "%v{%v=\"%v\"} %v\n", m.name, m.label, escapeLabelValue(labelValue), m.values[labelValue])
+	}
+}
+
+// WriteTo writes all the metrics of the agent in the Prometheus text format
+func WriteTo(w io.Writer) {
+	for _, metric := range registry {
+		metric.write(w)
+	}
+}
+
+// SessionStarted counts the session as running and records its start for its duration
+func SessionStarted(sessionID string) {
+	ActiveSessions.Inc("")
+	sessionStarts.Lock()
+	defer sessionStarts.Unlock()
+	sessionStarts.times[sessionID] = timeNow()
+}
+
+// SessionEnded counts the session as ended with the status and adds its duration, the duration of
+// a session started before the agent restarted is unknown and not added
+func SessionEnded(sessionID string, status string) {
+	ActiveSessions.Dec("")
+	SessionsEnded.Inc(status)
+	sessionStarts.Lock()
+	defer sessionStarts.Unlock()
+	if start, found := sessionStarts.times[sessionID]; found {
+		delete(sessionStarts.times, sessionID)
+		SessionDurationSeconds.Add("", int64(timeNow().Sub(start)/time.Second))
+	}
+}
+
+// escapeLabelValue escapes a label value as required by the Prometheus text format
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricWrite(t *testing.T) {
+	metric := newMetric("test_total", "Test counter.", counterType, "status")
+	metric.Inc("Success")
+	metric.Inc("Failed")
+	metric.Add("Success", 2)
+
+	var buf bytes.Buffer
+	metric.write(&buf)
+
+	assert.Equal(t, "# HELP test_total Test counter.\n"+
+		"# TYPE test_total counter\n"+
+		"test_total{status=\"Failed\"} 1\n"+
+		"test_total{status=\"Success\"} 3\n", buf.String())
+}
+
+func TestMetricWrite_NoLabel(t *testing.T) {
+	metric := newMetric("test_gauge", "Test gauge.", gaugeType, "")
+	var buf bytes.Buffer
+	metric.write(&buf)
+	assert.Equal(t, "# HELP test_gauge Test gauge.\n# TYPE test_gauge gauge\ntest_gauge 0\n", buf.String())
+
+	metric.Inc("ignored")
+	metric.Inc("")
+	metric.Dec("")
+	assert.Equal(t, int64(1), metric.Value(""))
+
+	buf.Reset()
+	metric.write(&buf)
+	assert.Contains(t, buf.String(), "test_gauge 1\n")
+}
+
+func TestMetricSet(t *testing.T) {
+	metric := newMetric("test_gauge", "Test gauge.", gaugeType, "resource")
+	metric.Set("goroutines", 20)
+	metric.Set("goroutines", 12)
+	metric.Inc("goroutines")
+	assert.Equal(t, int64(13), metric.Value("goroutines"))
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+}
+
+func TestWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	WriteTo(&buf)
+	for _, metric := range registry {
+		assert.Contains(t, buf.String(), "# TYPE "+metric.name+" "+metric.metricType+"\n")
+	}
+}
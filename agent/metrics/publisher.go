@@ -0,0 +1,164 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const publisherName = "MetricsPublisher"
+
+// publishedMetric is a metric published to the sink, its label is published as the dimension
+type publishedMetric struct {
+	metric    *Metric
+	name      string
+	unit      string
+	dimension string
+}
+
+// published are the metrics published to the sink, the counters are published as their increase since the last publication
+var published = []publishedMetric{
+	{ActiveSessions, "ActiveSessions", cloudwatch.StandardUnitCount, ""},
+	{SessionsEnded, "SessionsEnded", cloudwatch.StandardUnitCount, "Status"},
+	{SessionDurationSeconds, "SessionDuration", cloudwatch.StandardUnitSeconds, ""},
+	{DocumentExecutions, "CommandExecutions", cloudwatch.StandardUnitCount, "Status"},
+	{QueuedDocuments, "QueuedDocuments", cloudwatch.StandardUnitCount, "DocumentType"},
+	{UploadFailures, "UploadFailures", cloudwatch.StandardUnitCount, "Destination"},
+	{Reconnects, "Reconnects", cloudwatch.StandardUnitCount, "Channel"},
+	{WorkerFailures, "WorkerFailures", cloudwatch.StandardUnitCount, "Worker"},
+}
+
+// dataPoint is the value of a published metric for a label value
+type dataPoint struct {
+	metric     publishedMetric
+	labelValue string
+	value      int64
+}
+
+// Publisher is the core module publishing the metrics of the agent to CloudWatch or to a local sink
+type Publisher struct {
+	context  context.T
+	config   appconfig.MetricsCfg
+	interval time.Duration
+	sink     sink
+	previous map[*Metric]map[string]int64
+	stop     chan bool
+	done     chan bool
+}
+
+// NewPublisher creates the metrics publisher, nil when the metrics are published to CloudWatch and no namespace is configured in appconfig
+func NewPublisher(context context.T) *Publisher {
+	config := context.AppConfig().Metrics
+	if config.Sink == appconfig.MetricsSinkCloudWatch && config.CloudWatchNamespace == "" {
+		return nil
+	}
+	return &Publisher{
+		context:  context.With("[" + publisherName + "]"),
+		config:   config,
+		interval: time.Duration(config.PublishIntervalSeconds) * time.Second,
+		previous: make(map[*Metric]map[string]int64),
+		stop:     make(chan bool),
+		done:     make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (p *Publisher) ModuleName() string {
+	return publisherName
+}
+
+// ModuleExecute starts publishing the metrics
+func (p *Publisher) ModuleExecute(context context.T) (err error) {
+	log := p.context.Log()
+	if p.sink, err = newSink(log, p.context.AppConfig()); err != nil {
+		close(p.done)
+		return fmt.Errorf("failed to create the %v metrics sink: %v", p.config.Sink, err)
+	}
+	log.Infof("Publishing metrics to the %v sink every %v", p.config.Sink, p.interval)
+	go p.run()
+	return nil
+}
+
+// ModuleRequestStop stops publishing the metrics
+func (p *Publisher) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	close(p.stop)
+	<-p.done
+	if p.sink != nil {
+		return p.sink.close()
+	}
+	return nil
+}
+
+// run publishes the metrics at every interval
+func (p *Publisher) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.publish()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// publish sends the metrics to the sink, the increase of the counters is lost when it fails
+func (p *Publisher) publish() {
+	points := p.points()
+	if err := p.sink.publish(points, timeNow()); err != nil {
+		p.context.Log().Warnf("Failed to publish %v metrics to the %v sink: %v", len(points), p.config.Sink, err)
+	}
+}
+
+// points returns the data points of the published metrics, the counters are reported as their
+// increase since the previous call
+func (p *Publisher) points() []dataPoint {
+	var points []dataPoint
+	for _, m := range published {
+		values := m.metric.snapshot()
+		previous := p.previous[m.metric]
+		p.previous[m.metric] = values
+		if _, found := values[""]; !found && m.dimension == "" {
+			// the metrics without label are published even before they change, for the alarms to have data
+			values[""] = 0
+		}
+
+		labelValues := make([]string, 0, len(values))
+		for labelValue := range values {
+			// the dimension values must not be empty
+			if labelValue != "" || m.dimension == "" {
+				labelValues = append(labelValues, labelValue)
+			}
+		}
+		sort.Strings(labelValues)
+		for _, labelValue := range labelValues {
+			value := values[labelValue]
+			if m.metric.metricType == counterType {
+				value -= previous[labelValue]
+			}
+			points = append(points, dataPoint{metric: m, labelValue: labelValue, value: value})
+		}
+	}
+	return points
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type cloudWatchMock struct {
+	cloudwatchiface.CloudWatchAPI
+	inputs chan *cloudwatch.PutMetricDataInput
+}
+
+func (c *cloudWatchMock) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	select {
+	case c.inputs <- input:
+	default:
+	}
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func mockContextWithMetrics(sink string, namespace string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.Metrics.Sink = sink
+	config.Metrics.CloudWatchNamespace = namespace
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+// findPoint returns the data point of the metric with the label value, nil when it is not present
+func findPoint(points []dataPoint, name string, labelValue string) *dataPoint {
+	for i, point := range points {
+		if point.metric.name == name && point.labelValue == labelValue {
+			return &points[i]
+		}
+	}
+	return nil
+}
+
+func TestNewPublisher_Disabled(t *testing.T) {
+	assert.Nil(t, NewPublisher(mockContextWithMetrics(appconfig.MetricsSinkCloudWatch, "")))
+	assert.NotNil(t, NewPublisher(mockContextWithMetrics(appconfig.MetricsSinkCloudWatch, "SSMAgent")))
+	assert.NotNil(t, NewPublisher(mockContextWithMetrics(appconfig.MetricsSinkStatsd, "")))
+}
+
+func TestPublisher_Points(t *testing.T) {
+	publisher := NewPublisher(mockContextWithMetrics(appconfig.MetricsSinkCloudWatch, "SSMAgent"))
+	assert.NotNil(t, publisher)
+	// the first publication reports the counters since the agent started
+	publisher.points()
+
+	DocumentExecutions.Add("Success", 2)
+	points := publisher.points()
+	point := findPoint(points, "CommandExecutions", "Success")
+	assert.NotNil(t, point)
+	assert.Equal(t, int64(2), point.value)
+	assert.Equal(t, "Status", point.metric.dimension)
+	assert.NotNil(t, findPoint(points, "ActiveSessions", ""))
+	assert.Nil(t, findPoint(points, "CommandExecutions", ""))
+
+	// the counters are published as their increase since the previous publication, the gauges as their value
+	DocumentExecutions.Inc("Success")
+	QueuedDocuments.Inc("SendCommand")
+	defer QueuedDocuments.Dec("SendCommand")
+	points = publisher.points()
+	assert.Equal(t, int64(1), findPoint(points, "CommandExecutions", "Success").value)
+	assert.Equal(t, int64(1), findPoint(points, "QueuedDocuments", "SendCommand").value)
+
+	points = publisher.points()
+	assert.Equal(t, int64(0), findPoint(points, "CommandExecutions", "Success").value)
+	assert.Equal(t, int64(1), findPoint(points, "QueuedDocuments", "SendCommand").value)
+}
+
+func TestSessionDuration(t *testing.T) {
+	start := time.Now()
+	defer func() { timeNow = time.Now }()
+	duration := SessionDurationSeconds.Value("")
+	ended := SessionsEnded.Value("Success")
+
+	timeNow = func() time.Time { return start }
+	SessionStarted("session-id")
+	assert.Equal(t, int64(1), ActiveSessions.Value(""))
+
+	timeNow = func() time.Time { return start.Add(90 * time.Second) }
+	SessionEnded("session-id", "Success")
+	assert.Equal(t, int64(0), ActiveSessions.Value(""))
+	assert.Equal(t, ended+1, SessionsEnded.Value("Success"))
+	assert.Equal(t, duration+90, SessionDurationSeconds.Value(""))
+
+	// the duration of a session started before a restart is unknown
+	SessionStarted("other-session-id")
+	delete(sessionStarts.times, "other-session-id")
+	SessionEnded("other-session-id", "Success")
+	assert.Equal(t, duration+90, SessionDurationSeconds.Value(""))
+}
+
+func TestPublisher_PublishesToCloudWatchInBatches(t *testing.T) {
+	originalInstanceID, originalNewCloudWatchClient := instanceID, newCloudWatchClient
+	defer func() { instanceID, newCloudWatchClient = originalInstanceID, originalNewCloudWatchClient }()
+	instanceID = func() (string, error) { return "i-1234567890", nil }
+	client := &cloudWatchMock{inputs: make(chan *cloudwatch.PutMetricDataInput, 10)}
+	newCloudWatchClient = func(log.T, appconfig.SsmagentConfig) cloudwatchiface.CloudWatchAPI { return client }
+
+	ctx := mockContextWithMetrics(appconfig.MetricsSinkCloudWatch, "SSMAgent")
+	publisher := NewPublisher(ctx)
+	assert.Equal(t, publisherName, publisher.ModuleName())
+	assert.Equal(t, time.Duration(appconfig.DefaultMetricsPublishIntervalSeconds)*time.Second, publisher.interval)
+	publisher.interval = 10 * time.Millisecond
+	for i := 0; i < maxDatumsPerRequest; i++ {
+		Reconnects.Inc(fmt.Sprintf("channel-%v", i))
+	}
+	assert.NoError(t, publisher.ModuleExecute(ctx))
+
+	first := <-client.inputs
+	assert.Equal(t, "SSMAgent", aws.StringValue(first.Namespace))
+	assert.Len(t, first.MetricData, maxDatumsPerRequest)
+	assert.Equal(t, []*cloudwatch.Dimension{
+		{Name: aws.String(instanceIDDimension), Value: aws.String("i-1234567890")},
+	}, first.MetricData[0].Dimensions)
+	second := <-client.inputs
+	assert.NotEmpty(t, second.MetricData)
+	assert.NoError(t, publisher.ModuleRequestStop(contracts.StopTypeSoftStop))
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	name = "MetricsServer"
+
+	// metricsPath is the path the metrics are served on
+	metricsPath = "/metrics"
+
+	// contentType is the content type of the Prometheus text format
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Server is the core module serving the metrics of the agent on a localhost port
+type Server struct {
+	context context.T
+	address string
+	server  *http.Server
+}
+
+// NewServer creates the metrics server, nil when the metrics endpoint is not enabled in appconfig
+func NewServer(context context.T) *Server {
+	port := context.AppConfig().Metrics.Port
+	if port == 0 {
+		return nil
+	}
+	return &Server{
+		context: context.With("[" + name + "]"),
+		address: fmt.Sprintf("127.0.0.1:%v", port),
+	}
+}
+
+// ModuleName returns the name of the module
+func (s *Server) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts serving the metrics
+func (s *Server) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %v for the metrics endpoint: %v", s.address, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, handleMetrics)
+	s.server = &http.Server{Handler: mux}
+	log.Infof("Serving metrics on http://%v%v", s.address, metricsPath)
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("metrics endpoint stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// ModuleRequestStop stops serving the metrics
+func (s *Server) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+// handleMetrics writes the metrics of the agent
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	WriteTo(w)
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithMetricsPort(port int) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.Metrics.Port = port
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func TestNewServer_Disabled(t *testing.T) {
+	assert.Nil(t, NewServer(mockContextWithMetricsPort(0)))
+}
+
+func TestServer_ServesMetrics(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	ctx := mockContextWithMetricsPort(port)
+	server := NewServer(ctx)
+	assert.NotNil(t, server)
+	assert.Equal(t, name, server.ModuleName())
+	assert.NoError(t, server.ModuleExecute(ctx))
+	defer server.ModuleRequestStop(contracts.StopTypeSoftStop)
+
+	resp, err := http.Get("http://" + server.address + metricsPath)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "# TYPE ssm_agent_messages_received_total counter\n")
+}
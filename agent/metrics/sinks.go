@@ -0,0 +1,256 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// maxDatumsPerRequest is the maximum number of data points a PutMetricData request accepts
+	maxDatumsPerRequest = 20
+
+	// instanceIDDimension is the dimension of the instance the metrics are published for
+	instanceIDDimension = "InstanceId"
+
+	// FileName is the name of the file the metrics are written to by the file sink, in the log directory of the agent
+	FileName = "metrics.log"
+
+	// statsdPrefix is the prefix of the metric names sent to StatsD
+	statsdPrefix = "ssm_agent"
+
+	// maxStatsdPacketSize keeps the StatsD packets below the MTU of the network
+	maxStatsdPacketSize = 1432
+)
+
+// sink is where the data points of the metrics are published
+type sink interface {
+	publish(points []dataPoint, timestamp time.Time) error
+	close() error
+}
+
+// instanceID returns the instance id, the value of the InstanceId dimension
+var instanceID = platform.InstanceID
+
+// newCloudWatchClient creates the CloudWatch client the metrics are published with
+var newCloudWatchClient = func(log log.T, appConfig appconfig.SsmagentConfig) cloudwatchiface.CloudWatchAPI {
+	config := sdkutil.AwsConfig()
+	config = request.WithRetryer(config, retryer.New(cloudwatch.ServiceName))
+	if defaultEndpoint := appconfig.GetDefaultEndPoint(aws.StringValue(config.Region), cloudwatch.ServiceName); defaultEndpoint != "" {
+		config.Endpoint = &defaultEndpoint
+	}
+	proxyconfig.ConfigureAwsProxy(log, config, appconfig.ProxyCfg{})
+
+	sess := session.New(config)
+	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
+	return cloudwatch.New(sess)
+}
+
+// filePath is the path of the file the metrics are written to when no file is configured
+var filePath = filepath.Join(log.DefaultLogDir, FileName)
+
+// newSink creates the sink configured in appconfig
+func newSink(log log.T, appConfig appconfig.SsmagentConfig) (sink, error) {
+	config := appConfig.Metrics
+	switch config.Sink {
+	case appconfig.MetricsSinkFile:
+		path := config.FilePath
+		if path == "" {
+			path = filePath
+		}
+		return newFileSink(path)
+	case appconfig.MetricsSinkStatsd:
+		return newStatsdSink(config.StatsdAddress)
+	default:
+		return &cloudWatchSink{
+			client:    newCloudWatchClient(log, appConfig),
+			namespace: config.CloudWatchNamespace,
+		}, nil
+	}
+}
+
+// cloudWatchSink publishes the metrics to a CloudWatch namespace, with the instance id as dimension
+type cloudWatchSink struct {
+	client    cloudwatchiface.CloudWatchAPI
+	namespace string
+}
+
+func (s *cloudWatchSink) publish(points []dataPoint, timestamp time.Time) error {
+	instance, err := instanceID()
+	if err != nil {
+		return fmt.Errorf("the instance id is not available: %v", err)
+	}
+	var datums []*cloudwatch.MetricDatum
+	for _, point := range points {
+		dimensions := []*cloudwatch.Dimension{
+			{Name: aws.String(instanceIDDimension), Value: aws.String(instance)},
+		}
+		if point.metric.dimension != "" {
+			dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String(point.metric.dimension), Value: aws.String(point.labelValue)})
+		}
+		datums = append(datums, &cloudwatch.MetricDatum{
+			MetricName: aws.String(point.metric.name),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(timestamp),
+			Unit:       aws.String(point.metric.unit),
+			Value:      aws.Float64(float64(point.value)),
+		})
+	}
+	for start := 0; start < len(datums); start += maxDatumsPerRequest {
+		end := start + maxDatumsPerRequest
+		if end > len(datums) {
+			end = len(datums)
+		}
+		if _, err = s.client.PutMetricData(&cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(s.namespace),
+			MetricData: datums[start:end],
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *cloudWatchSink) close() error {
+	return nil
+}
+
+// fileSink writes the metrics to a rotating file on the instance, one JSON object per data point
+type fileSink struct {
+	file *log.RotatingFileReceiver
+}
+
+// fileRecord is a data point written by the file sink
+type fileRecord struct {
+	Timestamp  string            `json:"timestamp"`
+	Name       string            `json:"name"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Unit       string            `json:"unit"`
+	Value      int64             `json:"value"`
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	file := &log.RotatingFileReceiver{}
+	if err := file.AfterParse(seelog.CustomReceiverInitArgs{
+		XmlCustomAttrs: map[string]string{"filename": path},
+	}); err != nil {
+		return nil, err
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (s *fileSink) publish(points []dataPoint, timestamp time.Time) error {
+	var buf bytes.Buffer
+	for _, point := range points {
+		record := fileRecord{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Name:      point.metric.name,
+			Unit:      point.metric.unit,
+			Value:     point.value,
+		}
+		if point.metric.dimension != "" {
+			record.Dimensions = map[string]string{point.metric.dimension: point.labelValue}
+		}
+		line, err := jsonutil.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(line + "\n")
+	}
+	return s.file.ReceiveMessage(buf.String(), seelog.InfoLvl, nil)
+}
+
+func (s *fileSink) close() error {
+	return s.file.Close()
+}
+
+// statsdSink sends the metrics to a StatsD daemon, the counters as counts and the gauges as gauges
+type statsdSink struct {
+	conn net.Conn
+}
+
+func newStatsdSink(address string) (*statsdSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{conn: conn}, nil
+}
+
+func (s *statsdSink) publish(points []dataPoint, timestamp time.Time) error {
+	var packet bytes.Buffer
+	send := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, point := range points {
+		line := statsdLine(point)
+		if packet.Len()+len(line) > maxStatsdPacketSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		packet.WriteString(line)
+	}
+	return send()
+}
+
+func (s *statsdSink) close() error {
+	return s.conn.Close()
+}
+
+// statsdLine formats the data point in the StatsD line protocol, the label value is part of the metric name
+func statsdLine(point dataPoint) string {
+	name := statsdPrefix + "." + point.metric.name
+	if point.metric.dimension != "" {
+		name += "." + statsdNameReplacer.Replace(point.labelValue)
+	}
+	metricType := "g"
+	if point.metric.metric.metricType == counterType {
+		metricType = "c"
+	}
+	return fmt.Sprintf("%v:%v|%v\n", name, point.value, metricType)
+}
+
+// statsdNameReplacer replaces the characters of the StatsD line protocol in the metric names
+var statsdNameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_", " ", "_")
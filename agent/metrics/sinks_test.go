@@ -0,0 +1,80 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPoints = []dataPoint{
+	{metric: published[0], labelValue: "", value: 3},
+	{metric: published[3], labelValue: "Success", value: 2},
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	appConfig := appconfig.DefaultConfig()
+	appConfig.Metrics.Sink = appconfig.MetricsSinkFile
+	appConfig.Metrics.FilePath = filepath.Join(dir, FileName)
+	sink, err := newSink(log.NewMockLog(), appConfig)
+	assert.NoError(t, err)
+	timestamp := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, sink.publish(testPoints, timestamp))
+	assert.NoError(t, sink.close())
+
+	content, err := ioutil.ReadFile(appConfig.Metrics.FilePath)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"timestamp":"2019-05-01T10:00:00Z","name":"ActiveSessions","unit":"Count","value":3}`+"\n"+
+			`{"timestamp":"2019-05-01T10:00:00Z","name":"CommandExecutions","dimensions":{"Status":"Success"},"unit":"Count","value":2}`+"\n",
+		string(content))
+}
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	appConfig := appconfig.DefaultConfig()
+	appConfig.Metrics.Sink = appconfig.MetricsSinkStatsd
+	appConfig.Metrics.StatsdAddress = conn.LocalAddr().String()
+	sink, err := newSink(log.NewMockLog(), appConfig)
+	assert.NoError(t, err)
+	defer sink.close()
+	assert.NoError(t, sink.publish(testPoints, time.Now()))
+
+	buf := make([]byte, maxStatsdPacketSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm_agent.ActiveSessions:3|g\nssm_agent.CommandExecutions.Success:2|c\n", string(buf[:n]))
+}
+
+func TestStatsdLine_EscapesLabelValue(t *testing.T) {
+	point := dataPoint{metric: published[6], labelValue: "control channel:1", value: 1}
+	assert.Equal(t, "ssm_agent.Reconnects.control_channel_1:1|c\n", statsdLine(point))
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package netns runs the processes of the documents and of the sessions in a named Linux network namespace.
+package netns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Check returns an error when the name of the network namespace is invalid or the platform cannot enter it,
+// the empty name is the network namespace of the agent
+func Check(name string) error {
+	if name == "" {
+		return nil
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("invalid network namespace name %v", name)
+	}
+	return supported()
+}
+
+// Run calls start in the named network namespace, the processes start creates are in that namespace.
+// The processes are started before start returns, start is called in the network namespace of the agent when name is empty.
+func Run(name string, start func() error) error {
+	if name == "" {
+		return start()
+	}
+	if err := Check(name); err != nil {
+		return err
+	}
+	return runInNamespace(name, start)
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package netns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// namespacesDir is where ip netns mounts the named network namespaces
+var namespacesDir = "/var/run/netns"
+
+func supported() error {
+	return nil
+}
+
+// runInNamespace moves the thread of the goroutine to the network namespace while start runs, the processes forked by the
+// thread inherit its namespace
+func runInNamespace(name string, start func() error) error {
+	target, err := os.Open(filepath.Join(namespacesDir, name))
+	if err != nil {
+		return fmt.Errorf("failed to open the network namespace %v: %v", name, err)
+	}
+	defer target.Close()
+
+	runtime.LockOSThread()
+	origin, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to open the network namespace of the agent: %v", err)
+	}
+	defer origin.Close()
+
+	if err = unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter the network namespace %v: %v", name, err)
+	}
+	defer func() {
+		// the thread stays locked and exits with the goroutine when it cannot return to the namespace of the agent
+		if err := unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); err == nil {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	return start()
+}
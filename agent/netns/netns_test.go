@@ -0,0 +1,46 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package netns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(""))
+	for _, name := range []string{".", "..", "../../proc/1/ns/net", "blue/red"} {
+		assert.Error(t, Check(name), name)
+	}
+}
+
+func TestRun(t *testing.T) {
+	started := false
+	assert.NoError(t, Run("", func() error {
+		started = true
+		return nil
+	}))
+	assert.True(t, started)
+
+	started = false
+	assert.Error(t, Run("ssm-agent-test-missing", func() error {
+		started = true
+		return nil
+	}))
+	assert.False(t, started, "start is not called when the namespace cannot be entered")
+
+	assert.Error(t, Run("../net", func() error { return nil }))
+}
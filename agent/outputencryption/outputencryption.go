@@ -0,0 +1,321 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package outputencryption envelope encrypts the session transcripts and the command output with a customer managed
+// KMS key before their S3 upload and their CloudWatch Logs streaming, so their confidentiality does not rest on the
+// bucket and log group policies alone.
+//
+// Every file is encrypted with its own AES-256 data key generated by KMS. An encrypted file starts with the magic
+// line, the length of the JSON header and the header, which holds the KMS key id, the encryption context and the
+// data key encrypted by KMS. The frames follow, each made of the length of its content, the random nonce and the
+// AES-GCM sealed chunk of the file. The additional data of a frame is its index and whether it is the final one, so
+// the frames cannot be reordered, dropped or truncated without the decryption failing.
+package outputencryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/crypto"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// FileExtension is appended to the name of the encrypted copy of a file
+	FileExtension = ".kms"
+	// ArmoredFileExtension is appended to the name of the base64 copy of an encrypted file
+	ArmoredFileExtension = ".b64"
+	// ContextOutputKey is the encryption context entry holding the path of the output on the instance
+	ContextOutputKey = "aws:ssm:Output"
+
+	magic         = "SSMKMS1\n"
+	chunkSize     = 64 * 1024
+	maxHeaderSize = 64 * 1024
+	// armoredLineBytes is a multiple of 3, so every line of the base64 copy decodes on its own
+	armoredLineBytes = 12 * 1024
+)
+
+// header describes the data key of an encrypted file
+type header struct {
+	KeyId             string
+	EncryptionContext map[string]string
+	EncryptedDataKey  []byte
+}
+
+// newKMSService creates the KMS client, it is stubbed in the tests
+var newKMSService = func(log log.T) (crypto.IKMSService, error) {
+	kmsService, err := crypto.NewKMSService(log)
+	if err != nil {
+		return nil, err
+	}
+	return kmsService, nil
+}
+
+// Enabled returns true when the output is encrypted before it leaves the instance
+func Enabled(config appconfig.OutputEncryptionCfg) bool {
+	return config.KmsKeyId != ""
+}
+
+// OutputFiles returns the files sent in place of an output file, its encrypted copy for S3 and the base64 copy of the
+// encrypted copy for CloudWatch Logs
+func OutputFiles(log log.T, config appconfig.OutputEncryptionCfg, filePath string) (s3Path, cloudWatchPath string, err error) {
+	if s3Path, err = EncryptFile(log, config, filePath); err != nil {
+		return "", "", err
+	}
+	if cloudWatchPath, err = ArmorFile(s3Path); err != nil {
+		return "", "", err
+	}
+	return s3Path, cloudWatchPath, nil
+}
+
+// EncryptFile writes the encrypted copy of a file next to it, the path of the file is the encryption context of the
+// data key
+func EncryptFile(log log.T, config appconfig.OutputEncryptionCfg, filePath string) (encryptedPath string, err error) {
+	kmsService, err := newKMSService(log)
+	if err != nil {
+		return "", err
+	}
+	encryptionContext := map[string]string{ContextOutputKey: filePath}
+	plainTextKey, cipherTextKey, err := kmsService.GenerateDataKey(config.KmsKeyId, contextPointers(encryptionContext))
+	if err != nil {
+		return "", err
+	}
+
+	source, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	encryptedPath = filePath + FileExtension
+	destination, err := os.OpenFile(encryptedPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, appconfig.ReadWriteAccess)
+	if err != nil {
+		return "", err
+	}
+	h := header{KeyId: config.KmsKeyId, EncryptionContext: encryptionContext, EncryptedDataKey: cipherTextKey}
+	if err = encrypt(destination, source, h, plainTextKey); err == nil {
+		err = destination.Close()
+	} else {
+		destination.Close()
+	}
+	if err != nil {
+		os.Remove(encryptedPath)
+		return "", fmt.Errorf("failed to encrypt %s: %v", filePath, err)
+	}
+	log.Debugf("Encrypted %s with a data key of KMS key %s", filePath, config.KmsKeyId)
+	return encryptedPath, nil
+}
+
+// ArmorFile writes the base64 copy of an encrypted file next to it, for CloudWatch Logs, the lines decode on their own
+// and the encrypted file is the concatenation of the decoded lines
+func ArmorFile(encryptedPath string) (armoredPath string, err error) {
+	source, err := os.Open(encryptedPath)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	armoredPath = encryptedPath + ArmoredFileExtension
+	destination, err := os.OpenFile(armoredPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, appconfig.ReadWriteAccess)
+	if err != nil {
+		return "", err
+	}
+	if err = armor(destination, source); err == nil {
+		err = destination.Close()
+	} else {
+		destination.Close()
+	}
+	if err != nil {
+		os.Remove(armoredPath)
+		return "", err
+	}
+	return armoredPath, nil
+}
+
+// Decrypt reads an encrypted file and writes its plaintext, the data key is decrypted by KMS
+func Decrypt(kmsService crypto.IKMSService, w io.Writer, r io.Reader) error {
+	reader := bufio.NewReader(r)
+	prefix := make([]byte, len(magic))
+	if _, err := io.ReadFull(reader, prefix); err != nil || string(prefix) != magic {
+		return errors.New("not an encrypted output")
+	}
+	var headerSize uint32
+	if err := binary.Read(reader, binary.BigEndian, &headerSize); err != nil {
+		return fmt.Errorf("failed to read the header: %v", err)
+	}
+	if headerSize > maxHeaderSize {
+		return fmt.Errorf("header of %d bytes exceeds the maximum of %d", headerSize, maxHeaderSize)
+	}
+	headerBytes := make([]byte, headerSize)
+	if _, err := io.ReadFull(reader, headerBytes); err != nil {
+		return fmt.Errorf("failed to read the header: %v", err)
+	}
+	var h header
+	if err := json.Unmarshal(headerBytes, &h); err != nil {
+		return fmt.Errorf("failed to parse the header: %v", err)
+	}
+	plainTextKey, err := kmsService.Decrypt(h.EncryptedDataKey, contextPointers(h.EncryptionContext))
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(plainTextKey)
+	if err != nil {
+		return err
+	}
+
+	maxFrameSize := uint32(aead.NonceSize() + chunkSize + aead.Overhead())
+	for index := uint64(0); ; index++ {
+		var frameSize uint32
+		if err := binary.Read(reader, binary.BigEndian, &frameSize); err != nil {
+			if err == io.EOF {
+				return errors.New("encrypted output is truncated")
+			}
+			return err
+		}
+		if frameSize < uint32(aead.NonceSize()+aead.Overhead()) || frameSize > maxFrameSize {
+			return fmt.Errorf("frame %d has an invalid size of %d bytes", index, frameSize)
+		}
+		frame := make([]byte, frameSize)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return fmt.Errorf("failed to read frame %d: %v", index, err)
+		}
+		nonce, sealed := frame[:aead.NonceSize()], frame[aead.NonceSize():]
+		final := false
+		plainText, err := aead.Open(nil, nonce, sealed, additionalData(index, false))
+		if err != nil {
+			if plainText, err = aead.Open(nil, nonce, sealed, additionalData(index, true)); err != nil {
+				return fmt.Errorf("failed to decrypt frame %d: %v", index, err)
+			}
+			final = true
+		}
+		if _, err := w.Write(plainText); err != nil {
+			return err
+		}
+		if final {
+			if _, err := reader.Peek(1); err != io.EOF {
+				return errors.New("encrypted output has data after its final frame")
+			}
+			return nil
+		}
+	}
+}
+
+// encrypt writes the header and the frames of the encrypted reader
+func encrypt(w io.Writer, r io.Reader, h header, plainTextKey []byte) error {
+	aead, err := newAEAD(plainTextKey)
+	if err != nil {
+		return err
+	}
+	headerBytes, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(w)
+	writer.WriteString(magic)
+	binary.Write(writer, binary.BigEndian, uint32(len(headerBytes)))
+	writer.Write(headerBytes)
+
+	reader := bufio.NewReaderSize(r, chunkSize)
+	chunk := make([]byte, chunkSize)
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		final := err != nil
+		if !final {
+			if _, err = reader.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return err
+		}
+		frame := aead.Seal(nonce, nonce, chunk[:n], additionalData(index, final))
+		binary.Write(writer, binary.BigEndian, uint32(len(frame)))
+		if _, err = writer.Write(frame); err != nil {
+			return err
+		}
+		if final {
+			return writer.Flush()
+		}
+	}
+}
+
+// armor writes the base64 lines of the reader
+func armor(w io.Writer, r io.Reader) error {
+	writer := bufio.NewWriter(w)
+	line := make([]byte, armoredLineBytes)
+	for {
+		n, err := io.ReadFull(r, line)
+		if n > 0 {
+			writer.WriteString(base64.StdEncoding.EncodeToString(line[:n]))
+			if _, werr := writer.WriteString("\n"); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return writer.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newAEAD returns the AES-GCM cipher of a data key
+func newAEAD(plainTextKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(plainTextKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData authenticates the position of a frame
+func additionalData(index uint64, final bool) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.BigEndian, index)
+	if final {
+		data.WriteByte(1)
+	} else {
+		data.WriteByte(0)
+	}
+	return data.Bytes()
+}
+
+// contextPointers converts an encryption context to the form of the KMS client
+func contextPointers(encryptionContext map[string]string) map[string]*string {
+	pointers := make(map[string]*string, len(encryptionContext))
+	for key := range encryptionContext {
+		value := encryptionContext[key]
+		pointers[key] = &value
+	}
+	return pointers
+}
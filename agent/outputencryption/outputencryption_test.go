@@ -0,0 +1,152 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package outputencryption
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/crypto"
+	"github.com/aws/amazon-ssm-agent/agent/crypto/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	plainTextKey  = bytes.Repeat([]byte{7}, 32)
+	cipherTextKey = []byte("encrypted data key")
+)
+
+// stubKMS makes the KMS client return the same data key for every file
+func stubKMS(t *testing.T) *mocks.IKMSService {
+	kmsService := new(mocks.IKMSService)
+	kmsService.On("GenerateDataKey", "alias/transcripts", mock.Anything).Return(plainTextKey, cipherTextKey, nil)
+	kmsService.On("Decrypt", cipherTextKey, mock.Anything).Return(plainTextKey, nil)
+	newKMSServiceOriginal := newKMSService
+	t.Cleanup(func() { newKMSService = newKMSServiceOriginal })
+	newKMSService = func(log.T) (crypto.IKMSService, error) { return kmsService, nil }
+	return kmsService
+}
+
+func encryptFile(t *testing.T, filePath string, content []byte) (encrypted []byte, encryptedPath string) {
+	assert.NoError(t, ioutil.WriteFile(filePath, content, 0600))
+	config := appconfig.OutputEncryptionCfg{KmsKeyId: "alias/transcripts"}
+	encryptedPath, err := EncryptFile(log.NewMockLog(), config, filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, filePath+FileExtension, encryptedPath)
+	encrypted, err = ioutil.ReadFile(encryptedPath)
+	assert.NoError(t, err)
+	return encrypted, encryptedPath
+}
+
+func TestEncryptFile(t *testing.T) {
+	kmsService := stubKMS(t)
+	filePath := filepath.Join(t.TempDir(), "stdout")
+	for _, size := range []int{0, 10, chunkSize, chunkSize + 1, 3*chunkSize + 100} {
+		content := bytes.Repeat([]byte("session output "), size/15+1)[:size]
+		encrypted, _ := encryptFile(t, filePath, content)
+		assert.False(t, size > 0 && bytes.Contains(encrypted, content))
+
+		var decrypted bytes.Buffer
+		assert.NoError(t, Decrypt(kmsService, &decrypted, bytes.NewReader(encrypted)), "size %d", size)
+		assert.Equal(t, string(content), decrypted.String(), "size %d", size)
+	}
+
+	value := filePath
+	kmsService.AssertCalled(t, "GenerateDataKey", "alias/transcripts", map[string]*string{ContextOutputKey: &value})
+	kmsService.AssertCalled(t, "Decrypt", cipherTextKey, map[string]*string{ContextOutputKey: &value})
+}
+
+func TestDecryptTampered(t *testing.T) {
+	kmsService := stubKMS(t)
+	content := bytes.Repeat([]byte{'a'}, 2*chunkSize+10)
+	encrypted, _ := encryptFile(t, filepath.Join(t.TempDir(), "stdout"), content)
+	headerEnd := len(magic) + 4 + int(encrypted[len(magic)+3]) + int(encrypted[len(magic)+2])<<8
+	frameSize := 4 + 12 + chunkSize + 16
+
+	var output bytes.Buffer
+	assert.Error(t, Decrypt(kmsService, &output, bytes.NewReader([]byte("plain output"))))
+
+	flipped := append([]byte{}, encrypted...)
+	flipped[len(flipped)-1] ^= 1
+	assert.Error(t, Decrypt(kmsService, &output, bytes.NewReader(flipped)))
+
+	// dropping the final frame makes the previous one the last of the file
+	truncated := encrypted[:headerEnd+2*frameSize]
+	assert.Error(t, Decrypt(kmsService, &output, bytes.NewReader(truncated)))
+
+	swapped := append([]byte{}, encrypted[:headerEnd]...)
+	swapped = append(swapped, encrypted[headerEnd+frameSize:headerEnd+2*frameSize]...)
+	swapped = append(swapped, encrypted[headerEnd:headerEnd+frameSize]...)
+	swapped = append(swapped, encrypted[headerEnd+2*frameSize:]...)
+	assert.Error(t, Decrypt(kmsService, &output, bytes.NewReader(swapped)))
+
+	appended := append(append([]byte{}, encrypted...), encrypted[headerEnd:headerEnd+frameSize]...)
+	assert.Error(t, Decrypt(kmsService, &output, bytes.NewReader(appended)))
+}
+
+func TestOutputFiles(t *testing.T) {
+	stubKMS(t)
+	filePath := filepath.Join(t.TempDir(), "stdout")
+	assert.NoError(t, ioutil.WriteFile(filePath, bytes.Repeat([]byte("output"), 10000), 0600))
+	config := appconfig.OutputEncryptionCfg{KmsKeyId: "alias/transcripts"}
+	encryptedPath, armoredPath, err := OutputFiles(log.NewMockLog(), config, filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, filePath+FileExtension, encryptedPath)
+	assert.Equal(t, encryptedPath+ArmoredFileExtension, armoredPath)
+	encrypted, err := ioutil.ReadFile(encryptedPath)
+	assert.NoError(t, err)
+
+	file, err := os.Open(armoredPath)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var decoded []byte
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, err := base64.StdEncoding.DecodeString(scanner.Text())
+		assert.NoError(t, err)
+		decoded = append(decoded, line...)
+		lines++
+	}
+	assert.True(t, lines > 1)
+	assert.Equal(t, encrypted, decoded)
+}
+
+func TestEncryptFileFailure(t *testing.T) {
+	kmsService := new(mocks.IKMSService)
+	kmsService.On("GenerateDataKey", "alias/missing", mock.Anything).Return(nil, nil, assert.AnError)
+	newKMSServiceOriginal := newKMSService
+	defer func() { newKMSService = newKMSServiceOriginal }()
+	newKMSService = func(log.T) (crypto.IKMSService, error) { return kmsService, nil }
+
+	filePath := filepath.Join(t.TempDir(), "stdout")
+	assert.NoError(t, ioutil.WriteFile(filePath, []byte("output"), 0600))
+	config := appconfig.OutputEncryptionCfg{KmsKeyId: "alias/missing"}
+	_, err := EncryptFile(log.NewMockLog(), config, filePath)
+	assert.Error(t, err)
+	_, err = os.Stat(filePath + FileExtension)
+	assert.True(t, os.IsNotExist(err))
+	assert.False(t, Enabled(appconfig.OutputEncryptionCfg{}))
+	assert.True(t, Enabled(config))
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package outputfailover sends the command output and the session transcripts to their secondary destination when
+// the primary one fails: the output the S3 upload failed for is streamed to CloudWatch Logs, the output the
+// CloudWatch Logs streaming did not complete for is uploaded to S3.
+package outputfailover
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+)
+
+// logStreamNameReplacer replaces the characters the log stream names do not accept
+var logStreamNameReplacer = strings.NewReplacer(":", "_", "*", "_")
+
+// newCloudWatchLogsService creates the service streaming to the failover log group, it is stubbed in the tests
+var newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+	return cloudwatchlogspublisher.NewCloudWatchLogsService()
+}
+
+// uploadFile uploads a file to S3, it is stubbed in the tests
+var uploadFile = func(log log.T, bucketName, objectKey, filePath string) error {
+	return s3util.NewAmazonS3Util(log, bucketName).S3Upload(log, bucketName, objectKey, filePath)
+}
+
+// S3Enabled returns true when the output that could not be streamed to CloudWatch Logs is uploaded to S3
+func S3Enabled(config appconfig.OutputFailoverCfg) bool {
+	return config.S3BucketName != ""
+}
+
+// CloudWatchEnabled returns true when the output that could not be uploaded to S3 is streamed to CloudWatch Logs
+func CloudWatchEnabled(config appconfig.OutputFailoverCfg) bool {
+	return config.CloudWatchLogGroup != ""
+}
+
+// StreamFile streams a complete file to CloudWatch Logs, it returns false when the streaming has not completed
+// within the failover timeout, the streaming goes on in the background
+func StreamFile(log log.T,
+	config appconfig.OutputFailoverCfg,
+	cwl cloudwatchlogsinterface.ICloudWatchLogsService,
+	logGroupName, logStreamName, filePath string) bool {
+
+	done := make(chan bool, 1)
+	go func() {
+		cwl.StreamData(log, logGroupName, logStreamName, filePath, true, false)
+		done <- true
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Duration(config.CloudWatchTimeoutSeconds) * time.Second):
+		log.Warnf("Streaming of %s to log group %s did not complete within %d seconds",
+			filePath, logGroupName, config.CloudWatchTimeoutSeconds)
+		return false
+	}
+}
+
+// ToCloudWatch streams a file the S3 upload failed for to the failover log group, the log stream is named after
+// the object key of the file
+func ToCloudWatch(log log.T, config appconfig.OutputFailoverCfg, objectKey, filePath string) error {
+	if !CloudWatchEnabled(config) {
+		return fmt.Errorf("no failover log group is configured")
+	}
+
+	logStreamName := logStreamNameReplacer.Replace(strings.TrimPrefix(objectKey, "/"))
+	log.Infof("Streaming %s to failover log group %s, log stream %s", filePath, config.CloudWatchLogGroup, logStreamName)
+	cwl := newCloudWatchLogsService()
+	if !cwl.IsLogGroupPresent(log, config.CloudWatchLogGroup) {
+		if err := cwl.CreateLogGroup(log, config.CloudWatchLogGroup); err != nil {
+			return fmt.Errorf("failed to create failover log group %s: %v", config.CloudWatchLogGroup, err)
+		}
+	}
+	if !StreamFile(log, config, cwl, config.CloudWatchLogGroup, logStreamName, filePath) {
+		return fmt.Errorf("streaming to failover log group %s did not complete", config.CloudWatchLogGroup)
+	}
+	return nil
+}
+
+// ToS3 uploads a file the CloudWatch Logs streaming failed for to the failover bucket, under the failover prefix
+func ToS3(log log.T, config appconfig.OutputFailoverCfg, objectName, filePath string) error {
+	if !S3Enabled(config) {
+		return fmt.Errorf("no failover bucket is configured")
+	}
+
+	objectKey := fileutil.BuildS3Path(config.S3KeyPrefix, objectName)
+	log.Infof("Uploading %s to failover bucket %s, key %s", filePath, config.S3BucketName, objectKey)
+	return uploadFile(log, config.S3BucketName, objectKey, filePath)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package outputfailover
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStreamFile(t *testing.T) {
+	logger := log.NewMockLog()
+	config := appconfig.OutputFailoverCfg{CloudWatchTimeoutSeconds: 1}
+
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwl.On("StreamData", logger, "group", "stream", "stdout", true, false).Return()
+	assert.True(t, StreamFile(logger, config, cwl, "group", "stream", "stdout"))
+
+	// the streaming of a log group CloudWatch Logs keeps failing for never returns
+	blocked := make(chan bool)
+	defer close(blocked)
+	cwl = new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwl.On("StreamData", logger, "group", "stream", "stdout", true, false).Run(func(mock.Arguments) { <-blocked }).Return()
+	assert.False(t, StreamFile(logger, config, cwl, "group", "stream", "stdout"))
+}
+
+func TestToCloudWatch(t *testing.T) {
+	logger := log.NewMockLog()
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	newCloudWatchLogsServiceOriginal := newCloudWatchLogsService
+	defer func() { newCloudWatchLogsService = newCloudWatchLogsServiceOriginal }()
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService { return cwl }
+
+	assert.Error(t, ToCloudWatch(logger, appconfig.OutputFailoverCfg{}, "prefix/stdout", "stdout"))
+
+	config := appconfig.OutputFailoverCfg{CloudWatchLogGroup: "failover", CloudWatchTimeoutSeconds: 1}
+	cwl.On("IsLogGroupPresent", logger, "failover").Return(false)
+	cwl.On("CreateLogGroup", logger, "failover").Return(nil)
+	cwl.On("StreamData", logger, "failover", "prefix/aws_runShellScript/stdout", "stdout", true, false).Return()
+	assert.NoError(t, ToCloudWatch(logger, config, "/prefix/aws:runShellScript/stdout", "stdout"))
+	cwl.AssertExpectations(t)
+}
+
+func TestToCloudWatchCreateLogGroupFailure(t *testing.T) {
+	logger := log.NewMockLog()
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	newCloudWatchLogsServiceOriginal := newCloudWatchLogsService
+	defer func() { newCloudWatchLogsService = newCloudWatchLogsServiceOriginal }()
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService { return cwl }
+
+	config := appconfig.OutputFailoverCfg{CloudWatchLogGroup: "failover", CloudWatchTimeoutSeconds: 1}
+	cwl.On("IsLogGroupPresent", logger, "failover").Return(false)
+	cwl.On("CreateLogGroup", logger, "failover").Return(errors.New("AccessDeniedException"))
+	assert.Error(t, ToCloudWatch(logger, config, "prefix/stdout", "stdout"))
+	cwl.AssertNotCalled(t, "StreamData", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestToS3(t *testing.T) {
+	logger := log.NewMockLog()
+	var bucket, key, path string
+	uploadFileOriginal := uploadFile
+	defer func() { uploadFile = uploadFileOriginal }()
+	uploadFile = func(log log.T, bucketName, objectKey, filePath string) error {
+		bucket, key, path = bucketName, objectKey, filePath
+		return nil
+	}
+
+	assert.Error(t, ToS3(logger, appconfig.OutputFailoverCfg{}, "command/instance/stdout", "stdout"))
+	assert.Equal(t, "", bucket)
+
+	config := appconfig.OutputFailoverCfg{S3BucketName: "failover", S3KeyPrefix: "output"}
+	assert.NoError(t, ToS3(logger, config, "command/instance/stdout", "stdout"))
+	assert.Equal(t, "failover", bucket)
+	assert.Equal(t, "output/command/instance/stdout", key)
+	assert.Equal(t, "stdout", path)
+}
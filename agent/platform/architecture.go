@@ -0,0 +1,68 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Image file machine types of the Windows operating system
+const (
+	imageFileMachineI386  = 0x014c
+	imageFileMachineAMD64 = 0x8664
+	imageFileMachineARM64 = 0xaa64
+)
+
+// Architecture returns the architecture of the operating system with the GOARCH names, it differs from the
+// architecture of the agent binary when the agent runs emulated, such as the amd64 agent on Windows on ARM.
+func Architecture() string {
+	if arch := nativeArchitecture(); arch != "" {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+// machineArchitecture returns the GOARCH name of a Windows image file machine type, empty when it is unknown
+func machineArchitecture(machine uint16) string {
+	switch machine {
+	case imageFileMachineI386:
+		return "386"
+	case imageFileMachineAMD64:
+		return "amd64"
+	case imageFileMachineARM64:
+		return "arm64"
+	}
+	return ""
+}
+
+// environmentArchitecture returns the GOARCH name of the Windows processor architecture environment variables,
+// PROCESSOR_ARCHITEW6432 is set to the native architecture for the 32 bit processes emulated on a 64 bit system
+func environmentArchitecture(wow64Architecture, processorArchitecture string) string {
+	architecture := wow64Architecture
+	if architecture == "" {
+		architecture = processorArchitecture
+	}
+	switch strings.ToUpper(architecture) {
+	case "X86":
+		return "386"
+	case "AMD64":
+		return "amd64"
+	case "ARM64":
+		return "arm64"
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachineArchitecture(t *testing.T) {
+	assert.Equal(t, "386", machineArchitecture(0x014c))
+	assert.Equal(t, "amd64", machineArchitecture(0x8664))
+	assert.Equal(t, "arm64", machineArchitecture(0xaa64))
+	assert.Equal(t, "", machineArchitecture(0))
+}
+
+func TestEnvironmentArchitecture(t *testing.T) {
+	assert.Equal(t, "amd64", environmentArchitecture("", "AMD64"))
+	assert.Equal(t, "arm64", environmentArchitecture("", "ARM64"))
+	assert.Equal(t, "386", environmentArchitecture("", "x86"))
+	assert.Equal(t, "amd64", environmentArchitecture("AMD64", "x86"))
+	assert.Equal(t, "", environmentArchitecture("", ""))
+}
+
+func TestArchitecture(t *testing.T) {
+	assert.NotEmpty(t, Architecture())
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var isWow64Process2 = syscall.NewLazyDLL("kernel32.dll").NewProc("IsWow64Process2")
+
+// nativeArchitecture returns the architecture of the Windows operating system
+func nativeArchitecture() string {
+	if isWow64Process2.Find() == nil {
+		var processMachine, nativeMachine uint16
+		process, _ := syscall.GetCurrentProcess()
+		if r1, _, _ := isWow64Process2.Call(uintptr(process), uintptr(unsafe.Pointer(&processMachine)), uintptr(unsafe.Pointer(&nativeMachine))); r1 != 0 {
+			if arch := machineArchitecture(nativeMachine); arch != "" {
+				return arch
+			}
+		}
+	}
+
+	// IsWow64Process2 is not available before Windows 10 version 1511, which only emulates 32 bit processes
+	return environmentArchitecture(os.Getenv("PROCESSOR_ARCHITEW6432"), os.Getenv("PROCESSOR_ARCHITECTURE"))
+}
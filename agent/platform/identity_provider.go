@@ -0,0 +1,158 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// IdentityProvider resolves the identity of the machine the agent runs on,
+// a provider returns empty values when it has no identity for the machine.
+type IdentityProvider interface {
+	InstanceID() (string, error)
+	InstanceType() (string, error)
+	Region() (string, error)
+	AvailabilityZone() (string, error)
+}
+
+var (
+	identityProvidersLock sync.RWMutex
+	identityProviders     = map[string]IdentityProvider{
+		appconfig.IdentityProviderOnPrem:     onPremIdentityProvider{},
+		appconfig.IdentityProviderEC2:        ec2IdentityProvider{},
+		appconfig.IdentityProviderSimulation: simulationIdentityProvider{},
+	}
+)
+
+// RegisterIdentityProvider registers an identity provider, it is used when its name is in the Identity Providers configuration.
+// Providers have to be registered before the instance identity is first resolved.
+func RegisterIdentityProvider(name string, provider IdentityProvider) error {
+	identityProvidersLock.Lock()
+	defer identityProvidersLock.Unlock()
+	if _, ok := identityProviders[name]; ok {
+		return fmt.Errorf("identity provider %v is already registered", name)
+	}
+	identityProviders[name] = provider
+	return nil
+}
+
+// identityProviderNames returns the names of the configured identity providers in order of preference
+var identityProviderNames = func() []string {
+	appConfig, err := appconfig.Config(false)
+	if err == nil && appConfig.Simulation.Endpoint != "" {
+		return []string{appconfig.IdentityProviderSimulation}
+	}
+	if err != nil || len(appConfig.Identity.Providers) == 0 {
+		return appconfig.DefaultIdentityProviders()
+	}
+	return appConfig.Identity.Providers
+}
+
+// fetchIdentity returns the first value found by the configured identity providers,
+// the error of the last provider that failed otherwise
+func fetchIdentity(name string, get func(IdentityProvider) (string, error)) (string, error) {
+	var err error
+	for _, providerName := range identityProviderNames() {
+		identityProvidersLock.RLock()
+		provider, ok := identityProviders[providerName]
+		identityProvidersLock.RUnlock()
+		if !ok {
+			err = fmt.Errorf("unknown identity provider %v", providerName)
+			continue
+		}
+
+		value, providerErr := get(provider)
+		if value != "" && providerErr == nil {
+			return value, nil
+		}
+		if providerErr != nil {
+			err = providerErr
+		}
+	}
+
+	// return combined error messages
+	return "", fmt.Errorf(errorMessage, name, err)
+}
+
+// onPremIdentityProvider resolves the identity of a managed instance from its registration
+type onPremIdentityProvider struct{}
+
+func (onPremIdentityProvider) InstanceID() (string, error) { return managedInstance.InstanceID(), nil }
+
+func (onPremIdentityProvider) InstanceType() (string, error) {
+	return managedInstance.InstanceType(), nil
+}
+
+func (onPremIdentityProvider) Region() (string, error) { return managedInstance.Region(), nil }
+
+func (onPremIdentityProvider) AvailabilityZone() (string, error) {
+	return managedInstance.AvailabilityZone(), nil
+}
+
+// ec2IdentityProvider resolves the identity of an EC2 instance from the instance metadata and dynamic data
+type ec2IdentityProvider struct{}
+
+func (ec2IdentityProvider) InstanceID() (string, error) { return metadata.GetMetadata("instance-id") }
+
+func (ec2IdentityProvider) InstanceType() (string, error) {
+	return metadata.GetMetadata("instance-type")
+}
+
+func (ec2IdentityProvider) Region() (string, error) {
+	// trying to get region from metadata
+	if region, err := metadata.Region(); region != "" && err == nil {
+		return region, nil
+	}
+
+	// trying to get region from dynamic data
+	return dynamicData.Region()
+}
+
+func (ec2IdentityProvider) AvailabilityZone() (string, error) {
+	// trying to get availability zone from metadata
+	if availabilityZone, err := metadata.GetMetadata("placement/availability-zone"); availabilityZone != "" && err == nil {
+		return availabilityZone, nil
+	}
+
+	// trying to get availability zone from dynamic data
+	return dynamicData.Region()
+}
+
+// simulationIdentityProvider resolves the identity configured for the local simulation mode
+type simulationIdentityProvider struct{}
+
+func (simulationIdentityProvider) InstanceID() (string, error) {
+	appConfig, err := appconfig.Config(false)
+	return appConfig.Simulation.InstanceID, err
+}
+
+func (simulationIdentityProvider) InstanceType() (string, error) { return "simulation", nil }
+
+func (simulationIdentityProvider) Region() (string, error) {
+	appConfig, err := appconfig.Config(false)
+	return appConfig.Agent.Region, err
+}
+
+func (p simulationIdentityProvider) AvailabilityZone() (string, error) {
+	region, err := p.Region()
+	if region == "" || err != nil {
+		return "", err
+	}
+	return region + "a", nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// identityProviderStub returns the same identity for every attribute
+type identityProviderStub struct {
+	value string
+	err   error
+}
+
+func (p identityProviderStub) InstanceID() (string, error) { return p.value, p.err }
+
+func (p identityProviderStub) InstanceType() (string, error) { return p.value, p.err }
+
+func (p identityProviderStub) Region() (string, error) { return p.value, p.err }
+
+func (p identityProviderStub) AvailabilityZone() (string, error) { return p.value, p.err }
+
+func setIdentityProviders(t *testing.T, names ...string) {
+	identityProviderNames = func() []string { return names }
+	t.Cleanup(func() {
+		identityProviderNames = func() []string { return appconfig.DefaultIdentityProviders() }
+	})
+}
+
+func TestRegisterIdentityProvider(t *testing.T) {
+	assert.Nil(t, RegisterIdentityProvider("TestCustom", identityProviderStub{value: "custom-id"}))
+	defer delete(identityProviders, "TestCustom")
+	assert.NotNil(t, RegisterIdentityProvider("TestCustom", identityProviderStub{}))
+	assert.NotNil(t, RegisterIdentityProvider(appconfig.IdentityProviderEC2, identityProviderStub{}))
+
+	metadata = validMetadata
+	managedInstance = validRegistration
+	setIdentityProviders(t, "TestCustom", appconfig.IdentityProviderOnPrem, appconfig.IdentityProviderEC2)
+	instanceID, err := fetchInstanceID()
+	assert.Nil(t, err)
+	assert.Equal(t, "custom-id", instanceID)
+}
+
+func TestFetchIdentity_ProviderOrder(t *testing.T) {
+	metadata = validMetadata
+	managedInstance = validRegistration
+	setIdentityProviders(t, appconfig.IdentityProviderEC2, appconfig.IdentityProviderOnPrem)
+	instanceID, err := fetchInstanceID()
+	assert.Nil(t, err)
+	assert.Equal(t, sampleInstanceID, instanceID)
+
+	setIdentityProviders(t, appconfig.IdentityProviderOnPrem)
+	metadata = validMetadata
+	managedInstance = invalidRegistration
+	instanceID, err = fetchInstanceID()
+	assert.Equal(t, "", instanceID)
+	assert.Equal(t, fmt.Errorf(errorMessage, "instance ID", nil), err)
+}
+
+func TestFetchIdentity_Errors(t *testing.T) {
+	assert.Nil(t, RegisterIdentityProvider("TestFailing", identityProviderStub{err: errors.New("provider error")}))
+	defer delete(identityProviders, "TestFailing")
+
+	setIdentityProviders(t, "TestFailing", "TestUnknown")
+	_, err := fetchRegion()
+	assert.Equal(t, fmt.Errorf(errorMessage, "region", errors.New("unknown identity provider TestUnknown")), err)
+
+	setIdentityProviders(t, "TestUnknown", "TestFailing")
+	_, err = fetchRegion()
+	assert.Equal(t, fmt.Errorf(errorMessage, "region", errors.New("provider error")), err)
+}
@@ -23,19 +23,14 @@ import (
 var cachedRegion, cachedAvailabilityZone, cachedInstanceType, cachedInstanceID string
 var lock sync.RWMutex
 
+// fetch locks let concurrent callers share a single fetch of each value
+var regionFetch, availabilityZoneFetch, instanceTypeFetch, instanceIDFetch sync.Mutex
+
 const errorMessage = "Failed to fetch %s. Data from vault is empty. %v"
 
 // InstanceID returns the current instance id
 func InstanceID() (string, error) {
-	lock.RLock()
-	defer lock.RUnlock()
-	if cachedInstanceID != "" {
-		return cachedInstanceID, nil
-	} else {
-		var err error
-		cachedInstanceID, err = fetchInstanceID()
-		return cachedInstanceID, err
-	}
+	return getCached(&cachedInstanceID, &instanceIDFetch, fetchInstanceID)
 }
 
 // SetInstanceID overrides the platform instanceID
@@ -51,15 +46,7 @@ func SetInstanceID(instanceID string) error {
 
 // InstanceType returns the current instance type
 func InstanceType() (string, error) {
-	lock.RLock()
-	defer lock.RUnlock()
-	if cachedInstanceType != "" {
-		return cachedInstanceType, nil
-	} else {
-		var err error
-		cachedInstanceType, err = fetchInstanceType()
-		return cachedInstanceType, err
-	}
+	return getCached(&cachedInstanceType, &instanceTypeFetch, fetchInstanceType)
 }
 
 // SetInstanceType overrides the platform instance type
@@ -75,16 +62,7 @@ func SetInstanceType(instanceType string) error {
 
 // Region returns the instance region
 func Region() (string, error) {
-	var err error
-	lock.RLock()
-	defer lock.RUnlock()
-	if cachedRegion != "" {
-		return cachedRegion, nil
-	}
-
-	cachedRegion, err = fetchRegion()
-	return cachedRegion, err
-
+	return getCached(&cachedRegion, &regionFetch, fetchRegion)
 }
 
 // SetRegion overrides the platform region
@@ -100,15 +78,7 @@ func SetRegion(region string) error {
 
 // AvailabilityZone returns the instance availability zone
 func AvailabilityZone() (string, error) {
-	var err error
-	lock.RLock()
-	defer lock.RUnlock()
-	if cachedAvailabilityZone != "" {
-		return cachedAvailabilityZone, nil
-	}
-
-	cachedAvailabilityZone, err = fetchAvailabilityZone()
-	return cachedAvailabilityZone, err
+	return getCached(&cachedAvailabilityZone, &availabilityZoneFetch, fetchAvailabilityZone)
 }
 
 // SetAvailabilityZone overrides the platform availability zone
@@ -122,6 +92,31 @@ func SetAvailabilityZone(availabilityZone string) error {
 	return nil
 }
 
+// getCached returns the cached value, fetching it when it's not cached yet
+func getCached(cached *string, fetchLock *sync.Mutex, fetch func() (string, error)) (string, error) {
+	lock.RLock()
+	value := *cached
+	lock.RUnlock()
+	if value != "" {
+		return value, nil
+	}
+
+	fetchLock.Lock()
+	defer fetchLock.Unlock()
+	lock.RLock()
+	value = *cached
+	lock.RUnlock()
+	if value != "" {
+		return value, nil
+	}
+
+	value, err := fetch()
+	lock.Lock()
+	*cached = value
+	lock.Unlock()
+	return value, err
+}
+
 // IsManagedInstance returns if the current instance is managed instance
 func IsManagedInstance() (bool, error) {
 	instanceId, err := InstanceID()
@@ -135,98 +130,32 @@ func IsManagedInstance() (bool, error) {
 	return false, nil
 }
 
-// fetchInstanceID fetches the instance id with the following preference order.
+// fetchInstanceID fetches the instance id from the identity providers, by default with the following preference order.
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
 func fetchInstanceID() (string, error) {
-	var err error
-	var instanceID string
-
-	// trying to get instance id from managed instance registration
-	if instanceID = managedInstance.InstanceID(); instanceID != "" {
-		return instanceID, nil
-	}
-
-	// trying to get instance id from ec2 metadata
-	if instanceID, err = metadata.GetMetadata("instance-id"); instanceID != "" && err == nil {
-		return instanceID, nil
-	}
-
-	// return combined error messages
-	return "", fmt.Errorf(errorMessage, "instance ID", err)
+	return fetchIdentity("instance ID", IdentityProvider.InstanceID)
 }
 
-// fetchInstanceType fetches the instance type with the following preference order.
+// fetchInstanceType fetches the instance type from the identity providers, by default with the following preference order.
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
 func fetchInstanceType() (string, error) {
-	var err error
-	var instanceType string
-
-	// trying to get region from managed instance registration
-	if instanceType = managedInstance.InstanceType(); instanceType != "" {
-		return instanceType, nil
-	}
-
-	// trying to get instance id from ec2 metadata
-	if instanceType, err = metadata.GetMetadata("instance-type"); instanceType != "" && err == nil {
-		return instanceType, nil
-	}
-
-	// return combined error messages
-	return "", fmt.Errorf(errorMessage, "instance Type", err)
+	return fetchIdentity("instance Type", IdentityProvider.InstanceType)
 }
 
-// fetchRegion fetches the region with the following preference order.
+// fetchRegion fetches the region from the identity providers, by default with the following preference order.
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
 // 3. EC2 Instance Dynamic Data
 func fetchRegion() (string, error) {
-	var err error
-	var region string
-
-	// trying to get region from managed instance registration
-	if region = managedInstance.Region(); region != "" {
-		return region, nil
-	}
-
-	// trying to get region from metadata
-	if region, err = metadata.Region(); region != "" && err == nil {
-		return region, nil
-	}
-
-	// trying to get region from dynamic data
-	if region, err = dynamicData.Region(); region != "" && err == nil {
-		return region, nil
-	}
-
-	// return combined error messages
-	return "", fmt.Errorf(errorMessage, "region", err)
+	return fetchIdentity("region", IdentityProvider.Region)
 }
 
-// fetchAvailabilityZone fetches the  availability zone with the following preference order.
+// fetchAvailabilityZone fetches the availability zone from the identity providers, by default with the following preference order.
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
 // 3. EC2 Instance Dynamic Data
 func fetchAvailabilityZone() (string, error) {
-	var err error
-	var availabilityZone string
-
-	// trying to get region from managed instance registration
-	if availabilityZone = managedInstance.AvailabilityZone(); availabilityZone != "" {
-		return availabilityZone, nil
-	}
-
-	// trying to get instance id from ec2 metadata
-	if availabilityZone, err = metadata.GetMetadata("placement/availability-zone"); availabilityZone != "" && err == nil {
-		return availabilityZone, nil
-	}
-
-	// trying to get region from dynamic data
-	if availabilityZone, err = dynamicData.Region(); availabilityZone != "" && err == nil {
-		return availabilityZone, nil
-	}
-
-	// return combined error messages
-	return "", fmt.Errorf(errorMessage, "availability zone", err)
+	return fetchIdentity("availability zone", IdentityProvider.AvailabilityZone)
 }
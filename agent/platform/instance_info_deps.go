@@ -20,7 +20,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
-	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 // dependency for managed instance registration
@@ -49,7 +48,7 @@ func (instanceInfo) AvailabilityZone() string { return registration.Availability
 
 // dependency for metadata
 var metadata metadataClient = instanceMetadata{
-	Client: ec2metadata.New(session.New(aws.NewConfig().WithMaxRetries(10).WithEC2MetadataDisableTimeoutOverride(false))),
+	Client: NewEC2MetadataService(aws.NewConfig().WithMaxRetries(10).WithEC2MetadataDisableTimeoutOverride(false)),
 }
 
 type metadataClient interface {
@@ -64,7 +63,7 @@ func init() {
 	// Macs don't have instance metadata
 	if runtime.GOOS == "darwin" {
 		metadata = instanceMetadata{
-			Client: ec2metadata.New(session.New(aws.NewConfig().WithMaxRetries(0).WithEC2MetadataDisableTimeoutOverride(true))),
+			Client: NewEC2MetadataService(aws.NewConfig().WithMaxRetries(0).WithEC2MetadataDisableTimeoutOverride(true)),
 		}
 	}
 }
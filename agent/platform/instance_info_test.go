@@ -3,6 +3,8 @@ package platform
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -241,3 +243,40 @@ func TestFetchAvailabilityZoneForEc2Instance(t *testing.T) {
 	assert.Equal(t, value, actualOutput)
 	assert.Equal(t, nil, actualError)
 }
+
+func TestGetCachedFetchesOnceForConcurrentCallers(t *testing.T) {
+	var cached string
+	var fetchLock sync.Mutex
+	var fetches int32
+	fetch := func() (string, error) {
+		atomic.AddInt32(&fetches, 1)
+		return sampleInstanceID, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := getCached(&cached, &fetchLock, fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, sampleInstanceID, value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches)
+}
+
+func TestGetCachedFetchesAgainAfterError(t *testing.T) {
+	var cached string
+	var fetchLock sync.Mutex
+	value, err := getCached(&cached, &fetchLock, func() (string, error) {
+		return "", errors.New(sampleInstanceError)
+	})
+	assert.Error(t, err)
+	assert.Empty(t, value)
+
+	value, err = getCached(&cached, &fetchLock, func() (string, error) { return sampleInstanceID, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, sampleInstanceID, value)
+}
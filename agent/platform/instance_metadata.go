@@ -16,13 +16,18 @@ package platform
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 )
 
 const (
 	// EC2MetadataServiceURL is url for instance metadata.
 	EC2MetadataServiceURL = "http://169.254.169.254"
+	// EC2MetadataServiceIPv6URL is url for instance metadata on instances without IPv4 addresses.
+	EC2MetadataServiceIPv6URL = "http://[fd00:ec2::254]"
 	// SecurityCredentialsResource provides iam credentials
 	SecurityCredentialsResource = "/latest/meta-data/iam/security-credentials/"
 	// InstanceIdentityDocumentResource provides instance information like instance id, region, availability
@@ -81,9 +86,9 @@ func (iid *InstanceIdentityDocument) SetPendingTime(pendingTime time.Time) {
 	iid.PendingTimeAsString = pendingTime.UTC().Format(time.RFC3339)
 }
 
-// httpClient is used to make Get web requests to a url endpoint
+// httpClient is used to make web requests to a url endpoint
 type httpClient interface {
-	Get(string) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 }
 
 // EC2MetadataClient is used to make requests to instance metadata
@@ -115,14 +120,64 @@ func (c EC2MetadataClient) InstanceIdentityDocument() (*InstanceIdentityDocument
 }
 
 func (c EC2MetadataClient) resourceServiceURL(path string) string {
-	return EC2MetadataServiceURL + path
+	return MetadataServiceURL() + path
+}
+
+// MetadataServiceURL returns the url of instance metadata for the configured endpoint mode.
+// Without a configured mode the IPv6 endpoint is used when the instance has no IPv4 address.
+func MetadataServiceURL() string {
+	switch metadataEndpointMode() {
+	case appconfig.MetadataEndpointModeIPv4:
+		return EC2MetadataServiceURL
+	case appconfig.MetadataEndpointModeIPv6:
+		return EC2MetadataServiceIPv6URL
+	}
+	if hasIPv4Address() {
+		return EC2MetadataServiceURL
+	}
+	return EC2MetadataServiceIPv6URL
+}
+
+var metadataEndpointMode = func() string {
+	appConfig, _ := appconfig.Config(false)
+	return appConfig.InstanceMetadata.EndpointMode
+}
+
+// hasIPv4Address returns true if a network interface has a routable IPv4 address,
+// when the interfaces cannot be listed the IPv4 endpoint is assumed to be reachable
+var hasIPv4Address = func() bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return true
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ip := ipNet.IP.To4()
+			if ip != nil && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // ReadResource reads from the url path
 func (c EC2MetadataClient) ReadResource(path string) ([]byte, error) {
 	endpoint := c.resourceServiceURL(path)
 
-	resp, err := c.client.Get(endpoint)
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := metadataToken.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set(metadataTokenHeader, token)
+	}
+
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
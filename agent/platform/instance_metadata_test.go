@@ -22,6 +22,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,10 +59,10 @@ var testResponse = map[string]string{
 	testClient.resourceServiceURL(InstanceIdentityDocumentResource): string(ignoreError(json.Marshal(expectediid)).([]byte)),
 }
 
-// Get is a mock of the http.Client.Get that reads its responses from the map
+// Do is a mock of the http.Client.Do that reads its responses from the map
 // above and defaults to erroring.
-func (c testHTTPClient) Get(url string) (*http.Response, error) {
-	resp, ok := testResponse[url]
+func (c testHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, ok := testResponse[req.URL.String()]
 	if ok {
 		return &http.Response{
 			Status:     "200 OK",
@@ -72,6 +75,11 @@ func (c testHTTPClient) Get(url string) (*http.Response, error) {
 }
 
 func TestInstanceIdentityDocument(t *testing.T) {
+	metadataTokenOrig := metadataToken
+	metadataToken = &tokenProviderStub{}
+	defer func() { metadataToken = metadataTokenOrig }()
+	defer setMetadataEndpointMode(appconfig.MetadataEndpointModeIPv4)()
+
 	iid, err := testClient.InstanceIdentityDocument()
 	assert.Nil(t, err)
 	assert.Equal(t, &expectediid, iid)
@@ -119,3 +127,35 @@ func testFormatPendingTime(t *testing.T, pendingTimeAsTime time.Time, pendingTim
 
 	assert.Equal(t, pendingTimeAsString, iid.PendingTimeAsString)
 }
+
+func setMetadataEndpointMode(mode string) func() {
+	metadataEndpointModeOrig := metadataEndpointMode
+	metadataEndpointMode = func() string { return mode }
+	return func() { metadataEndpointMode = metadataEndpointModeOrig }
+}
+
+func TestMetadataServiceURL(t *testing.T) {
+	hasIPv4AddressOrig := hasIPv4Address
+	defer func() { hasIPv4Address = hasIPv4AddressOrig }()
+	hasIPv4Address = func() bool { return false }
+
+	restore := setMetadataEndpointMode(appconfig.MetadataEndpointModeIPv4)
+	assert.Equal(t, EC2MetadataServiceURL, MetadataServiceURL())
+	restore()
+
+	restore = setMetadataEndpointMode("")
+	defer restore()
+	assert.Equal(t, EC2MetadataServiceIPv6URL, MetadataServiceURL())
+	hasIPv4Address = func() bool { return true }
+	assert.Equal(t, EC2MetadataServiceURL, MetadataServiceURL())
+}
+
+func TestMetadataEndpointHandler(t *testing.T) {
+	defer setMetadataEndpointMode(appconfig.MetadataEndpointModeIPv6)()
+	client := NewEC2MetadataService(aws.NewConfig())
+	req := client.NewRequest(&request.Operation{Name: "GetMetadata", HTTPMethod: "GET", HTTPPath: "/meta-data/instance-id"}, nil, nil)
+
+	MetadataEndpointHandler.Fn(req)
+
+	assert.Equal(t, "http://[fd00:ec2::254]/latest/meta-data/instance-id", req.HTTPRequest.URL.String())
+}
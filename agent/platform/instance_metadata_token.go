@@ -0,0 +1,262 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	// MetadataTokenResource provides session tokens for IMDSv2 requests
+	MetadataTokenResource = "/latest/api/token"
+
+	// MetadataModeV2Only is the instance metadata mode where requests without a session token are never made
+	MetadataModeV2Only = "IMDSv2-only"
+	// MetadataModeV1Fallback is the instance metadata mode where IMDSv1 is used when no session token can be fetched
+	MetadataModeV1Fallback = "IMDSv2 with IMDSv1 fallback"
+
+	metadataTokenHeader    = "X-aws-ec2-metadata-token"
+	metadataTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	metadataTokenTTL       = 6 * time.Hour
+
+	// metadataTokenExpiryWindow is how long before its expiry a token is refreshed
+	metadataTokenExpiryWindow = time.Minute
+	// metadataTokenFallbackPeriod is how long IMDSv1 is used without retrying to fetch a token
+	metadataTokenFallbackPeriod = 5 * time.Minute
+)
+
+// MetadataTokenHandler adds the IMDSv2 session token to requests of the sdk ec2metadata client
+var MetadataTokenHandler = request.NamedHandler{
+	Name: "ssmagent.MetadataTokenHandler",
+	Fn: func(r *request.Request) {
+		if r.ClientInfo.ServiceName != ec2metadata.ServiceName {
+			return
+		}
+		token, err := metadataToken.Token()
+		if err != nil {
+			r.Error = err
+			return
+		}
+		if token != "" {
+			r.HTTPRequest.Header.Set(metadataTokenHeader, token)
+		}
+	},
+}
+
+// MetadataEndpointHandler sends requests of the sdk ec2metadata client to the selected instance metadata endpoint
+var MetadataEndpointHandler = request.NamedHandler{
+	Name: "ssmagent.MetadataEndpointHandler",
+	Fn: func(r *request.Request) {
+		if r.ClientInfo.ServiceName != ec2metadata.ServiceName || r.HTTPRequest.URL.Host != metadataServiceHost(EC2MetadataServiceURL) {
+			return
+		}
+		r.HTTPRequest.URL.Host = metadataServiceHost(MetadataServiceURL())
+	},
+}
+
+func metadataServiceHost(serviceURL string) string {
+	return strings.TrimPrefix(serviceURL, "http://")
+}
+
+// metadataTokenExpiredHandler drops the cached token when instance metadata rejects it
+var metadataTokenExpiredHandler = request.NamedHandler{
+	Name: "ssmagent.MetadataTokenExpiredHandler",
+	Fn: func(r *request.Request) {
+		if r.HTTPResponse != nil && r.HTTPResponse.StatusCode == http.StatusUnauthorized {
+			metadataToken.Invalidate()
+		}
+	},
+}
+
+// NewEC2MetadataService returns an sdk ec2metadata client using IMDSv2 session tokens and the selected metadata endpoint
+func NewEC2MetadataService(config *aws.Config) *ec2metadata.EC2Metadata {
+	client := ec2metadata.New(session.New(config))
+	client.Handlers.Build.PushBackNamed(MetadataEndpointHandler)
+	client.Handlers.Build.PushBackNamed(MetadataTokenHandler)
+	client.Handlers.Complete.PushBackNamed(metadataTokenExpiredHandler)
+	return client
+}
+
+// MetadataMode returns the configured instance metadata mode
+func MetadataMode() string {
+	if isIMDSv2Only() {
+		return MetadataModeV2Only
+	}
+	return MetadataModeV1Fallback
+}
+
+// MetadataTokenError returns the error of the last failed session token request, nil if it succeeded
+func MetadataTokenError() error {
+	return metadataToken.LastError()
+}
+
+var isIMDSv2Only = func() bool {
+	appConfig, _ := appconfig.Config(false)
+	return appConfig.InstanceMetadata.RequireIMDSv2
+}
+
+// dependency for IMDSv2 session tokens
+var metadataToken tokenProvider = newMetadataTokenProvider()
+
+type tokenProvider interface {
+	Token() (string, error)
+	Invalidate()
+	LastError() error
+}
+
+type tokenHTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type metadataTokenProvider struct {
+	lock        sync.Mutex
+	client      tokenHTTPClient
+	now         func() time.Time
+	inContainer func() bool
+	token       string
+	expiry      time.Time
+	retryAfter  time.Time
+	lastErr     error
+}
+
+func newMetadataTokenProvider() *metadataTokenProvider {
+	return &metadataTokenProvider{
+		client:      &http.Client{Timeout: EC2MetadataRequestTimeout},
+		now:         time.Now,
+		inContainer: isInContainer,
+	}
+}
+
+// Token returns a session token for instance metadata requests.
+// In IMDSv2-only mode a failure to fetch a token is an error, otherwise an empty token is returned so IMDSv1 is used.
+func (p *metadataTokenProvider) Token() (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	if p.token != "" && now.Before(p.expiry.Add(-metadataTokenExpiryWindow)) {
+		return p.token, nil
+	}
+	v2Only := isIMDSv2Only()
+	if !v2Only && now.Before(p.retryAfter) {
+		return "", nil
+	}
+
+	token, err := p.fetchToken()
+	p.lastErr = err
+	if err != nil {
+		p.token = ""
+		if v2Only {
+			return "", err
+		}
+		p.retryAfter = now.Add(metadataTokenFallbackPeriod)
+		return "", nil
+	}
+	p.token = token
+	p.expiry = now.Add(metadataTokenTTL)
+	return token, nil
+}
+
+// Invalidate drops the cached token, the next request fetches a new one
+func (p *metadataTokenProvider) Invalidate() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.token = ""
+	p.retryAfter = time.Time{}
+}
+
+// LastError returns the error of the last token request
+func (p *metadataTokenProvider) LastError() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lastErr
+}
+
+func (p *metadataTokenProvider) fetchToken() (string, error) {
+	req, err := http.NewRequest(http.MethodPut, MetadataServiceURL()+MetadataTokenResource, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(metadataTokenTTLHeader, strconv.Itoa(int(metadataTokenTTL.Seconds())))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", diagnoseTokenError(err, 0, p.inContainer())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", diagnoseTokenError(nil, resp.StatusCode, p.inContainer())
+	}
+	token, err := ioutil.ReadAll(resp.Body)
+	if err != nil || len(token) == 0 {
+		return "", fmt.Errorf("IMDSv2 token response could not be read: %v", err)
+	}
+	return string(token), nil
+}
+
+// diagnoseTokenError turns a failed token request into an error suggesting how to fix it
+func diagnoseTokenError(err error, statusCode int, inContainer bool) error {
+	var netErr net.Error
+	isTimeout := err != nil && errors.As(err, &netErr) && netErr.Timeout()
+	switch {
+	case isTimeout && inContainer:
+		return errors.New("IMDSv2 token request timed out. The agent runs in a container, which requires an instance metadata " +
+			"response hop limit of at least 2, set it with: aws ec2 modify-instance-metadata-options --instance-id <id> --http-put-response-hop-limit 2")
+	case isTimeout:
+		return errors.New("IMDSv2 token request timed out. Check that the instance metadata endpoint is enabled and that its " +
+			"response hop limit covers the network path to the agent")
+	case err != nil:
+		return fmt.Errorf("IMDSv2 token request failed: %v", err)
+	case statusCode == http.StatusForbidden:
+		return errors.New("IMDSv2 token request was denied (403). The instance metadata endpoint is disabled or blocked, " +
+			"check the http-endpoint instance metadata option")
+	case statusCode == http.StatusNotFound || statusCode == http.StatusMethodNotAllowed:
+		return fmt.Errorf("IMDSv2 is not supported by this instance metadata service (status %v)", statusCode)
+	}
+	return fmt.Errorf("IMDSv2 token request failed with status %v", statusCode)
+}
+
+// isInContainer returns true if the agent runs inside a container
+func isInContainer() bool {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return true
+	}
+	cgroup, err := ioutil.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, name := range []string{"docker", "kubepods", "containerd", "lxc", "ecs"} {
+		if strings.Contains(string(cgroup), name) {
+			return true
+		}
+	}
+	return false
+}
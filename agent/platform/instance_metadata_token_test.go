@@ -0,0 +1,165 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenProviderStub struct {
+	token string
+	err   error
+}
+
+func (p *tokenProviderStub) Token() (string, error) { return p.token, p.err }
+func (p *tokenProviderStub) Invalidate()            {}
+func (p *tokenProviderStub) LastError() error       { return p.err }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type tokenHTTPClientStub struct {
+	statusCode int
+	err        error
+	requests   []*http.Request
+}
+
+func (c *tokenHTTPClientStub) Do(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &http.Response{
+		StatusCode: c.statusCode,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("token-value"))),
+	}, nil
+}
+
+func setIMDSv2Only(v2Only bool) func() {
+	isIMDSv2OnlyOrig := isIMDSv2Only
+	isIMDSv2Only = func() bool { return v2Only }
+	return func() { isIMDSv2Only = isIMDSv2OnlyOrig }
+}
+
+func newTestTokenProvider(client tokenHTTPClient, now *time.Time, inContainer bool) *metadataTokenProvider {
+	return &metadataTokenProvider{
+		client:      client,
+		now:         func() time.Time { return *now },
+		inContainer: func() bool { return inContainer },
+	}
+}
+
+func TestMetadataToken(t *testing.T) {
+	defer setIMDSv2Only(true)()
+	defer setMetadataEndpointMode(appconfig.MetadataEndpointModeIPv4)()
+	now := time.Now()
+	client := &tokenHTTPClientStub{statusCode: http.StatusOK}
+	provider := newTestTokenProvider(client, &now, false)
+
+	token, err := provider.Token()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "token-value", token)
+	assert.Len(t, client.requests, 1)
+	assert.Equal(t, http.MethodPut, client.requests[0].Method)
+	assert.Equal(t, EC2MetadataServiceURL+MetadataTokenResource, client.requests[0].URL.String())
+	assert.Equal(t, "21600", client.requests[0].Header.Get(metadataTokenTTLHeader))
+
+	// cached until it is about to expire
+	now = now.Add(metadataTokenTTL - 2*metadataTokenExpiryWindow)
+	provider.Token()
+	assert.Len(t, client.requests, 1)
+	now = now.Add(metadataTokenExpiryWindow)
+	provider.Token()
+	assert.Len(t, client.requests, 2)
+
+	provider.Invalidate()
+	provider.Token()
+	assert.Len(t, client.requests, 3)
+}
+
+func TestMetadataToken_V2OnlyFailure(t *testing.T) {
+	defer setIMDSv2Only(true)()
+	now := time.Now()
+	client := &tokenHTTPClientStub{statusCode: http.StatusForbidden}
+	provider := newTestTokenProvider(client, &now, false)
+
+	token, err := provider.Token()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "denied (403)")
+	assert.Equal(t, "", token)
+	assert.Equal(t, err, provider.LastError())
+
+	// every request retries to fetch a token
+	provider.Token()
+	assert.Len(t, client.requests, 2)
+}
+
+func TestMetadataToken_V1Fallback(t *testing.T) {
+	defer setIMDSv2Only(false)()
+	now := time.Now()
+	client := &tokenHTTPClientStub{err: timeoutError{}}
+	provider := newTestTokenProvider(client, &now, false)
+
+	token, err := provider.Token()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", token)
+	assert.Error(t, provider.LastError())
+
+	// IMDSv1 is used for a while before retrying
+	provider.Token()
+	assert.Len(t, client.requests, 1)
+	now = now.Add(metadataTokenFallbackPeriod)
+	provider.Token()
+	assert.Len(t, client.requests, 2)
+}
+
+func TestDiagnoseTokenError(t *testing.T) {
+	assert.Contains(t, diagnoseTokenError(timeoutError{}, 0, true).Error(), "--http-put-response-hop-limit 2")
+	assert.Contains(t, diagnoseTokenError(timeoutError{}, 0, false).Error(), "response hop limit covers the network path")
+	assert.Contains(t, diagnoseTokenError(errors.New("connection refused"), 0, false).Error(), "connection refused")
+	assert.Contains(t, diagnoseTokenError(nil, http.StatusNotFound, false).Error(), "not supported")
+	assert.Contains(t, diagnoseTokenError(nil, http.StatusInternalServerError, false).Error(), "status 500")
+}
+
+func TestReadResourceSendsToken(t *testing.T) {
+	metadataTokenOrig := metadataToken
+	defer func() { metadataToken = metadataTokenOrig }()
+	client := &tokenHTTPClientStub{statusCode: http.StatusOK}
+	metadataClient := EC2MetadataClient{client: client}
+
+	metadataToken = &tokenProviderStub{token: "session-token"}
+	_, err := metadataClient.ReadResource(InstanceIdentityDocumentResource)
+	assert.NoError(t, err)
+	assert.Equal(t, "session-token", client.requests[0].Header.Get(metadataTokenHeader))
+
+	metadataToken = &tokenProviderStub{err: errors.New("IMDSv2 token request timed out")}
+	_, err = metadataClient.ReadResource(InstanceIdentityDocumentResource)
+	assert.Error(t, err)
+	assert.Len(t, client.requests, 1)
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	// instanceTagsPath is the instance metadata path of the tags of the instance, served when the metadata options of
+	// the instance allow the access to the tags
+	instanceTagsPath = "tags/instance"
+
+	// imageIDPath is the instance metadata path of the AMI id of the instance
+	imageIDPath = "ami-id"
+)
+
+// InstanceTag returns the value of a tag of the EC2 instance from the instance metadata
+func InstanceTag(key string) (string, error) {
+	value, err := metadata.GetMetadata(instanceTagsPath + "/" + url.PathEscape(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch instance tag %v, the tag must exist and the instance metadata must allow the access to the tags: %v", key, err)
+	}
+	return value, nil
+}
+
+// ImageID returns the id of the AMI the EC2 instance was launched from
+func ImageID() (string, error) {
+	value, err := metadata.GetMetadata(imageIDPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the AMI id from the instance metadata: %v", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pathMetadataStub returns the values of the metadata paths it knows, and an error for the others
+type pathMetadataStub map[string]string
+
+func (c pathMetadataStub) GetMetadata(p string) (string, error) {
+	if value, ok := c[p]; ok {
+		return value, nil
+	}
+	return "", errors.New("EC2MetadataError: failed to make EC2Metadata request, status code: 404")
+}
+
+func (c pathMetadataStub) Region() (string, error) { return "", errors.New("no region") }
+
+func TestInstanceTag(t *testing.T) {
+	metadataOrig := metadata
+	defer func() { metadata = metadataOrig }()
+	metadata = pathMetadataStub{
+		"tags/instance/Environment":   "production",
+		"tags/instance/Cost%20Center": "platform",
+		"ami-id":                      "ami-0123456789abcdef0",
+	}
+
+	value, err := InstanceTag("Environment")
+	assert.NoError(t, err)
+	assert.Equal(t, "production", value)
+
+	value, err = InstanceTag("Cost Center")
+	assert.NoError(t, err)
+	assert.Equal(t, "platform", value)
+
+	_, err = InstanceTag("Team")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "instance tag Team")
+
+	value, err = ImageID()
+	assert.NoError(t, err)
+	assert.Equal(t, "ami-0123456789abcdef0", value)
+}
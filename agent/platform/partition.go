@@ -0,0 +1,63 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package platform provides instance information
+package platform
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// DetectPartition sets the partition of the region of the instance from the configuration or from the instance metadata,
+// the partition of other regions is derived from their name
+func DetectPartition(log log.T) (partition appconfig.Partition, err error) {
+	region, err := Region()
+	if err != nil {
+		return
+	}
+
+	appConfig, _ := appconfig.Config(false)
+	if configured, ok := appconfig.KnownPartition(appConfig.Agent.Partition); ok {
+		appconfig.SetRegionPartition(region, configured)
+		return configured, nil
+	}
+
+	// managed instances have no instance metadata
+	if managedInstance.InstanceID() == "" {
+		if detected, ok := metadataPartition(log); ok {
+			appconfig.SetRegionPartition(region, detected)
+		}
+	}
+	return appconfig.GetPartition(region), nil
+}
+
+// metadataPartition returns the partition of the instance from the instance metadata
+func metadataPartition(log log.T) (appconfig.Partition, bool) {
+	id, err := metadata.GetMetadata("services/partition")
+	if err != nil || id == "" {
+		return appconfig.Partition{}, false
+	}
+	if partition, known := appconfig.KnownPartition(id); known {
+		return partition, true
+	}
+
+	// the domain of partitions the agent does not know is also in the instance metadata
+	domain, err := metadata.GetMetadata("services/domain")
+	if err != nil || domain == "" {
+		log.Warnf("Failed to get the domain of partition %v from the instance metadata, %v", id, err)
+		return appconfig.Partition{}, false
+	}
+	return appconfig.Partition{ID: id, DnsSuffix: domain}, true
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package platform
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataPartition(t *testing.T) {
+	metadataOrig := metadata
+	t.Cleanup(func() { metadata = metadataOrig })
+
+	metadata = &metadataStub{instanceID: appconfig.PartitionChina}
+	partition, ok := metadataPartition(log.NewMockLog())
+	assert.True(t, ok)
+	assert.Equal(t, "amazonaws.com.cn", partition.DnsSuffix)
+
+	// an unknown partition takes its domain from the instance metadata
+	metadata = &metadataStub{instanceID: "aws-new"}
+	partition, ok = metadataPartition(log.NewMockLog())
+	assert.True(t, ok)
+	assert.Equal(t, appconfig.Partition{ID: "aws-new", DnsSuffix: "aws-new"}, partition)
+
+	metadata = &metadataStub{}
+	_, ok = metadataPartition(log.NewMockLog())
+	assert.False(t, ok)
+}
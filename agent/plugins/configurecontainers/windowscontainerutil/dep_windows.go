@@ -1,3 +1,4 @@
+//go:build windows
 // +build windows
 
 // Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
@@ -12,7 +12,7 @@
 // implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-//package archive holds the resources for the archive
+// package archive holds the resources for the archive
 package archive
 
 import (
@@ -28,6 +28,7 @@ import (
 const (
 	PackageArchiveBirdwatcher = "birdwatcher"
 	PackageArchiveDocument    = "document"
+	PackageArchiveLocal       = "local"
 )
 
 type File struct {
@@ -48,6 +49,11 @@ type IPackageArchive interface {
 	WriteManifestToCache(packageArn string, version string, manifest []byte) error
 }
 
+// VersionLister is implemented by the archives able to list all the versions available for a package
+type VersionLister interface {
+	ListVersions(tracer trace.Tracer, packageName string) ([]string, error)
+}
+
 func ParseManifest(data *[]byte) (*birdwatcher.Manifest, error) {
 	var manifest birdwatcher.Manifest
 
@@ -17,18 +17,24 @@ package birdwatcherservice
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"strings"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/archive"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/birdwatcherarchive"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/documentarchive"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/localarchive"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagesigning"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/aws-sdk-go/service/ssm"
 )
@@ -67,6 +73,17 @@ func NewDocumentArchive(facadeClient facade.BirdwatcherFacade, manifestCache pac
 	return New(pkgArchive, facadeClient, manifestCache, packageservice.PackageServiceName_document)
 }
 
+// NewLocalArchive creates a package service reading the manifest and the artifacts from a local source
+// The source is pre-staged on the instance (e.g. in the AMI or on a network share) for instances without S3 access.
+func NewLocalArchive(facadeClient facade.BirdwatcherFacade, manifestCache packageservice.ManifestCache, source string) (packageservice.PackageService, error) {
+	pkgArchive, err := localarchive.New(source)
+	if err != nil {
+		return nil, err
+	}
+	pkgArchive.SetManifestCache(manifestCache)
+	return New(pkgArchive, facadeClient, manifestCache, packageservice.PackageServiceName_local), nil
+}
+
 // New constructor for PackageService
 func New(pkgArchive archive.IPackageArchive, facadeClient facade.BirdwatcherFacade, manifestCache packageservice.ManifestCache, name string) packageservice.PackageService {
 
@@ -97,6 +114,24 @@ func (ds *PackageService) DownloadManifest(tracer trace.Tracer, packageName stri
 	return ds.packageArchive.GetResourceArn(packageName, version), manifest.Version, isSameAsCache, nil
 }
 
+// ListVersions returns the versions of a given package when the archive of the service can list them
+func (ds *PackageService) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	versionLister, ok := ds.packageArchive.(archive.VersionLister)
+	if !ok {
+		return nil, fmt.Errorf("version constraints are not supported for the packages of the %v archive", ds.packageArchive.Name())
+	}
+
+	versiontrace := tracer.BeginSection(fmt.Sprintf("listing versions of %v", packageName))
+	versions, err := versionLister.ListVersions(tracer, packageName)
+	if err != nil {
+		versiontrace.WithError(err).End()
+		return nil, err
+	}
+
+	versiontrace.AppendInfof("versions: %v", versions).End()
+	return versions, nil
+}
+
 // DownloadArtifact downloads the platform matching artifact specified in the manifest
 func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
 	trace := tracer.BeginSection("download artifact")
@@ -171,7 +206,7 @@ func (ds *PackageService) ReportResult(tracer trace.Tracer, result packageservic
 	return nil
 }
 
-//utils
+// utils
 func downloadManifest(tracer trace.Tracer, ds *PackageService, packageName string, version string) (*birdwatcher.Manifest, bool, error) {
 	isSameAsCache := false
 	if ds == nil {
@@ -188,6 +223,9 @@ func downloadManifest(tracer trace.Tracer, ds *PackageService, packageName strin
 	if err != nil {
 		return nil, isSameAsCache, err
 	}
+	if err = verifyManifestSignature(parsedManifest); err != nil {
+		return nil, isSameAsCache, err
+	}
 	ds.packageArchive.SetResource(packageName, version, parsedManifest)
 
 	cachedManifest, err := ds.packageArchive.ReadManifestFromCache(parsedManifest.PackageArn, parsedManifest.Version)
@@ -209,6 +247,11 @@ func (ds *PackageService) findFileFromManifest(tracer trace.Tracer, manifest *bi
 	var file archive.File
 	var filename string
 
+	// manifests cached before signing keys were pinned are verified too
+	if err := verifyManifestSignature(manifest); err != nil {
+		return nil, err
+	}
+
 	pkginfo, err := ds.extractPackageInfo(tracer, manifest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find platform: %v", err)
@@ -231,6 +274,18 @@ func (ds *PackageService) findFileFromManifest(tracer trace.Tracer, manifest *bi
 	return &file, nil
 }
 
+// verifyManifestSignature checks the signature of the manifest when signing keys are pinned in appconfig
+func verifyManifestSignature(manifest *birdwatcher.Manifest) error {
+	keys, err := packagesigning.PinnedKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return packagesigning.VerifyManifest(keys, manifest)
+}
+
 func downloadFile(ds *PackageService, tracer trace.Tracer, file *archive.File, packagename string, version string) (string, error) {
 	if ds == nil || ds.packageArchive == nil || file == nil {
 		return "", fmt.Errorf("Either package service does not exist or does not have archive information or the file information does not exist")
@@ -258,9 +313,32 @@ func downloadFile(ds *PackageService, tracer trace.Tracer, file *archive.File, p
 		return "", errors.New(errMessage)
 	}
 
+	// local sources are verified in place, the caller deletes the artifact after use so it gets a copy
+	if downloadOutput.LocalFilePath == sourceUrl {
+		return copyToDownloadRoot(sourceUrl)
+	}
+
 	return downloadOutput.LocalFilePath, nil
 }
 
+// copyToDownloadRoot copies a local artifact to a new file in the download directory
+func copyToDownloadRoot(sourcePath string) (string, error) {
+	if err := fileutil.MakeDirs(appconfig.DownloadRoot); err != nil {
+		return "", err
+	}
+	destination, err := ioutil.TempFile(appconfig.DownloadRoot, "local-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a copy of %v: %v", sourcePath, err)
+	}
+	destination.Close()
+
+	if err = copyFile(sourcePath, destination.Name()); err != nil {
+		os.Remove(destination.Name())
+		return "", fmt.Errorf("failed to create a copy of %v: %v", sourcePath, err)
+	}
+	return destination.Name(), nil
+}
+
 // ExtractPackageInfo returns the correct PackageInfo for the current instances platform/version/arch
 func (ds *PackageService) extractPackageInfo(tracer trace.Tracer, manifest *birdwatcher.Manifest) (*birdwatcher.PackageInfo, error) {
 	log := tracer.CurrentTrace().Logger
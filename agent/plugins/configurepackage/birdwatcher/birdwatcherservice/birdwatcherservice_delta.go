@@ -0,0 +1,164 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package birdwatcherservice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+// DownloadArtifactDelta populates targetDirectory with the content of the package, copying the files that did not change
+// from baseDirectory and downloading the others. Delta downloads are only supported when the manifest lists the
+// checksum and download location of every file of the package.
+func (ds *PackageService) DownloadArtifactDelta(tracer trace.Tracer, packageName string, version string, baseDirectory string, targetDirectory string) (supported bool, err error) {
+	deltaTrace := tracer.BeginSection("download changed files")
+	defer deltaTrace.EndWithError(&err)
+
+	manifest, err := ds.packageArchive.ReadManifestFromCache(packageName, version)
+	if err != nil {
+		deltaTrace.AppendInfof("error when reading the manifest from cache %v", err)
+		if manifest, _, err = downloadManifest(tracer, ds, packageName, version); err != nil {
+			return false, fmt.Errorf("failed to download the manifest: %v", err)
+		}
+	}
+
+	file, err := ds.findFileFromManifest(tracer, manifest)
+	if err != nil {
+		return false, err
+	}
+	if !isDeltaSupported(file.Info.Contents) {
+		deltaTrace.AppendDebugf("manifest does not list the content of %v, delta download not supported", file.Name)
+		return false, nil
+	}
+
+	log := tracer.CurrentTrace().Logger
+	var reused, downloaded int
+	for _, content := range file.Info.Contents {
+		basePath := filepath.Join(baseDirectory, content.Path)
+		targetPath := filepath.Join(targetDirectory, content.Path)
+		if err = fileutil.MakeDirs(filepath.Dir(targetPath)); err != nil {
+			return true, err
+		}
+
+		if isUnchanged(log, basePath, content) {
+			if err = copyFile(basePath, targetPath); err != nil {
+				return true, fmt.Errorf("failed to copy unchanged file %v: %v", content.Path, err)
+			}
+			reused++
+			continue
+		}
+
+		if err = downloadContent(log, content, targetPath); err != nil {
+			return true, err
+		}
+		downloaded++
+	}
+
+	deltaTrace.AppendInfof("reused %v unchanged files, downloaded %v files", reused, downloaded)
+	return true, nil
+}
+
+// isDeltaSupported checks that every file has a safe relative path, a checksum to compare and a download location
+func isDeltaSupported(contents []*birdwatcher.ContentInfo) bool {
+	if len(contents) == 0 {
+		return false
+	}
+	for _, content := range contents {
+		if content == nil || content.DownloadLocation == "" || len(content.Checksums) == 0 || !isRelativePath(content.Path) {
+			return false
+		}
+		for algorithm, checksum := range content.Checksums {
+			if algorithm == "" || checksum == "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isRelativePath returns true if path stays within the directory it is relative to
+func isRelativePath(path string) bool {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) {
+		return false
+	}
+	cleaned := filepath.Clean(path)
+	return cleaned != ".." && !strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+// isUnchanged returns true if the file of the base version has the checksum expected for the new version
+func isUnchanged(log log.T, basePath string, content *birdwatcher.ContentInfo) bool {
+	if !fileutil.Exists(basePath) {
+		return false
+	}
+	matched, err := artifact.VerifyHash(log, artifact.DownloadInput{SourceChecksums: content.Checksums}, artifact.DownloadOutput{LocalFilePath: basePath})
+	return err == nil && matched
+}
+
+// downloadContent downloads a single file of the package and moves it to its location in the package
+func downloadContent(log log.T, content *birdwatcher.ContentInfo, targetPath string) error {
+	downloadInput := artifact.DownloadInput{
+		SourceURL:       content.DownloadLocation,
+		SourceChecksums: content.Checksums,
+	}
+	downloadOutput, err := birdwatcher.Networkdep.Download(log, downloadInput)
+	if err != nil || downloadOutput.LocalFilePath == "" || !downloadOutput.IsHashMatched {
+		errMessage := fmt.Sprintf("failed to download %v reliably, %v", content.Path, content.DownloadLocation)
+		if err != nil {
+			errMessage = fmt.Sprintf("%v, %v", errMessage, err.Error())
+		}
+		return errors.New(errMessage)
+	}
+
+	if err = os.Rename(downloadOutput.LocalFilePath, targetPath); err == nil {
+		// the download keeps the SELinux label of the download directory
+		return fileutil.RestoreSELinuxLabel(targetPath)
+	}
+	// rename fails when the download directory is on a different volume
+	if err = copyFile(downloadOutput.LocalFilePath, targetPath); err != nil {
+		return fmt.Errorf("failed to move %v to the package directory: %v", content.Path, err)
+	}
+	os.Remove(downloadOutput.LocalFilePath)
+	return nil
+}
+
+// copyFile copies the content of the source file to the destination file, keeping the file mode
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, appconfig.FileFlagsCreateOrTruncate, fileutil.GetFileMode(src))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
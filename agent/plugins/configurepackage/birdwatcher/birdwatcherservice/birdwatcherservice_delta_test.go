@@ -0,0 +1,174 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package birdwatcherservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/birdwatcherarchive"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect/ec2infradetect"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect/osdetect"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fileNetworkMock serves files with the given content and records the downloaded urls
+type fileNetworkMock struct {
+	dir        string
+	files      map[string]string
+	downloaded []string
+}
+
+func (n *fileNetworkMock) Download(log log.T, input artifact.DownloadInput) (artifact.DownloadOutput, error) {
+	n.downloaded = append(n.downloaded, input.SourceURL)
+	content, ok := n.files[input.SourceURL]
+	if !ok {
+		return artifact.DownloadOutput{}, fmt.Errorf("not found %v", input.SourceURL)
+	}
+	localPath := filepath.Join(n.dir, fmt.Sprintf("download-%v", len(n.downloaded)))
+	if err := ioutil.WriteFile(localPath, []byte(content), 0600); err != nil {
+		return artifact.DownloadOutput{}, err
+	}
+	return artifact.DownloadOutput{LocalFilePath: localPath, IsHashMatched: input.SourceChecksums["sha256"] == sha256Of(content)}, nil
+}
+
+func sha256Of(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func deltaManifest(contents string) string {
+	return fmt.Sprintf(`
+	{
+		"packages": {
+			"platformName": {
+				"platformVersion": {
+					"architecture": {
+						"file": "test.zip"
+					}
+				}
+			}
+		},
+		"files": {
+			"test.zip": {
+				"downloadLocation": "https://example.com/test.zip",
+				"contents": %v
+			}
+		}
+	}
+	`, contents)
+}
+
+func deltaPackageService(packageName string, version string, manifest string) *PackageService {
+	cache := packageservice.ManifestCacheMemNew()
+	cache.WriteManifest(packageName, version, []byte(manifest))
+	context := map[string]string{"packageName": packageName, "packageVersion": version, "manifest": manifest}
+	testArchive := birdwatcherarchive.New(&facade.FacadeStub{}, context)
+	testArchive.SetManifestCache(cache)
+	mockedCollector := envdetect.CollectorMock{}
+	mockedCollector.On("CollectData", mock.Anything).Return(&envdetect.Environment{
+		OperatingSystem:   &osdetect.OperatingSystem{Platform: "platformName", PlatformVersion: "platformVersion", Architecture: "architecture"},
+		Ec2Infrastructure: &ec2infradetect.Ec2Infrastructure{InstanceID: "instanceID", Region: "region"},
+	}, nil)
+	return &PackageService{manifestCache: cache, collector: &mockedCollector, packageArchive: testArchive}
+}
+
+func TestDownloadArtifactDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delta")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	baseDirectory := filepath.Join(dir, "1.0")
+	targetDirectory := filepath.Join(dir, "2.0")
+	assert.NoError(t, os.MkdirAll(filepath.Join(baseDirectory, "bin"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(baseDirectory, "install.sh"), []byte("install"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(baseDirectory, "bin", "tool"), []byte("tool v1"), 0700))
+
+	contents := fmt.Sprintf(`[
+		{"path": "install.sh", "checksums": {"sha256": "%v"}, "downloadLocation": "https://example.com/install.sh"},
+		{"path": "bin/tool", "checksums": {"sha256": "%v"}, "downloadLocation": "https://example.com/bin/tool"}
+	]`, sha256Of("install"), sha256Of("tool v2"))
+	network := &fileNetworkMock{dir: dir, files: map[string]string{"https://example.com/bin/tool": "tool v2"}}
+	birdwatcher.Networkdep = network
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	supported, err := deltaPackageService("packageName", "2.0", deltaManifest(contents)).DownloadArtifactDelta(tracer, "packageName", "2.0", baseDirectory, targetDirectory)
+
+	assert.NoError(t, err)
+	assert.True(t, supported)
+	assert.Equal(t, []string{"https://example.com/bin/tool"}, network.downloaded)
+	installContent, _ := ioutil.ReadFile(filepath.Join(targetDirectory, "install.sh"))
+	assert.Equal(t, "install", string(installContent))
+	toolContent, _ := ioutil.ReadFile(filepath.Join(targetDirectory, "bin", "tool"))
+	assert.Equal(t, "tool v2", string(toolContent))
+}
+
+func TestDownloadArtifactDeltaNotSupported(t *testing.T) {
+	data := []struct {
+		name     string
+		contents string
+	}{
+		{"no contents", `[]`},
+		{"missing checksums", `[{"path": "install.sh", "downloadLocation": "https://example.com/install.sh"}]`},
+		{"missing download location", `[{"path": "install.sh", "checksums": {"sha256": "abc"}}]`},
+		{"absolute path", `[{"path": "/etc/passwd", "checksums": {"sha256": "abc"}, "downloadLocation": "https://example.com/passwd"}]`},
+		{"path escaping the package", `[{"path": "../../passwd", "checksums": {"sha256": "abc"}, "downloadLocation": "https://example.com/passwd"}]`},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			network := &fileNetworkMock{}
+			birdwatcher.Networkdep = network
+			tracer := trace.NewTracer(log.NewMockLog())
+			tracer.BeginSection("test segment root")
+
+			supported, err := deltaPackageService("packageName", "2.0", deltaManifest(testdata.contents)).DownloadArtifactDelta(tracer, "packageName", "2.0", "base", "target")
+
+			assert.NoError(t, err)
+			assert.False(t, supported)
+			assert.Empty(t, network.downloaded)
+		})
+	}
+}
+
+func TestDownloadArtifactDeltaHashMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delta")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := fmt.Sprintf(`[{"path": "install.sh", "checksums": {"sha256": "%v"}, "downloadLocation": "https://example.com/install.sh"}]`, sha256Of("install"))
+	network := &fileNetworkMock{dir: dir, files: map[string]string{"https://example.com/install.sh": "tampered"}}
+	birdwatcher.Networkdep = network
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	supported, err := deltaPackageService("packageName", "2.0", deltaManifest(contents)).DownloadArtifactDelta(tracer, "packageName", "2.0", filepath.Join(dir, "1.0"), filepath.Join(dir, "2.0"))
+
+	assert.Error(t, err)
+	assert.True(t, supported)
+}
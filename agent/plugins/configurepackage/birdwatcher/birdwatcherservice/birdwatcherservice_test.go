@@ -15,6 +15,10 @@
 package birdwatcherservice
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"testing"
 
@@ -31,6 +35,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect/osdetect"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	cache_mock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagesigning"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/stretchr/testify/assert"
@@ -503,6 +508,31 @@ func TestReportResult(t *testing.T) {
 	}
 }
 
+func TestListVersions(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	versionName := "1.0.0"
+	facadeSession := &facade.FacadeStub{
+		ListDocumentVersionsOutput: &ssm.ListDocumentVersionsOutput{
+			DocumentVersions: []*ssm.DocumentVersionInfo{{VersionName: &versionName}},
+		},
+	}
+	ds := New(documentarchive.New(facadeSession), facadeSession, packageservice.ManifestCacheMemNew(), packageservice.PackageServiceName_document).(*PackageService)
+
+	versions, err := ds.ListVersions(tracer, "packageName")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{versionName}, versions)
+
+	// the birdwatcher archive has no way to list the versions of a package
+	context := map[string]string{"packageName": "packageName", "packageVersion": "", "manifest": ""}
+	ds = New(birdwatcherarchive.New(facadeSession, context), facadeSession, packageservice.ManifestCacheMemNew(), packageservice.PackageServiceName_birdwatcher).(*PackageService)
+
+	_, err = ds.ListVersions(tracer, "packageName")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "version constraints are not supported")
+}
+
 func TestDownloadManifest(t *testing.T) {
 	manifestStrErr := "xkj]{}["
 	manifestStr := "{\"version\": \"1234\",\"packageArn\":\"packagearn\"}"
@@ -1240,3 +1270,50 @@ func TestDownloadArtifact(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadManifestWithPinnedKeys(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pinnedKeysOrig := packagesigning.PinnedKeys
+	packagesigning.PinnedKeys = func() ([]crypto.PublicKey, error) { return []crypto.PublicKey{publicKey}, nil }
+	defer func() { packagesigning.PinnedKeys = pinnedKeysOrig }()
+
+	signedManifest := &birdwatcher.Manifest{PackageArn: "packagearn", Version: "1234"}
+	payload, _ := packagesigning.SigningPayload(signedManifest)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, payload))
+	signedManifestStr := "{\"version\": \"1234\",\"packageArn\":\"packagearn\",\"signature\":\"" + signature + "\"}"
+	unsignedManifestStr := "{\"version\": \"1234\",\"packageArn\":\"packagearn\"}"
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	data := []struct {
+		name        string
+		manifest    string
+		expectedErr bool
+	}{
+		{"signed manifest", signedManifestStr, false},
+		{"unsigned manifest", unsignedManifestStr, true},
+	}
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			facadeClient := facade.FacadeStub{
+				GetManifestOutput: &ssm.GetManifestOutput{
+					Manifest: &testdata.manifest,
+				},
+			}
+			testArchive := birdwatcherarchive.New(&facadeClient, map[string]string{})
+			cache := packageservice.ManifestCacheMemNew()
+			testArchive.SetManifestCache(cache)
+			ds := &PackageService{facadeClient: &facadeClient, manifestCache: cache, packageArchive: testArchive}
+
+			_, _, _, err := ds.DownloadManifest(tracer, "packagename", "1234")
+
+			cachedManifest, _ := cache.ReadManifest("packagearn", "1234")
+			if testdata.expectedErr {
+				assert.Error(t, err)
+				assert.Nil(t, cachedManifest)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []byte(testdata.manifest), cachedManifest)
+			}
+		})
+	}
+}
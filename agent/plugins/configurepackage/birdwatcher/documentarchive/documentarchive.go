@@ -43,7 +43,7 @@ type PackageArchive struct {
 	timeUnit      int
 }
 
-//constructors
+// constructors
 // New is a constructor for PackageArchive struct
 func New(facadeClientSession facade.BirdwatcherFacade) archive.IPackageArchive {
 	return &PackageArchive{
@@ -74,7 +74,7 @@ func (da *PackageArchive) Name() string {
 	return da.archiveType
 }
 
-//setters
+// setters
 // SetPackageName sets the document arn. The manifest and version is not required
 // since we use the document name and document version
 func (da *PackageArchive) SetResource(packageName string, version string, manifest *birdwatcher.Manifest) {
@@ -89,7 +89,7 @@ func (da *PackageArchive) SetManifestCache(manifestCache packageservice.Manifest
 	da.manifestCache = manifestCache
 }
 
-//getters
+// getters
 // GetResourceVersion makes a call to birdwatcher API to figure the right version of the resource that needs to be installed
 func (da *PackageArchive) GetResourceVersion(packageName string, packageVersion string) (name string, version string) {
 	// Return the packageVersion as "" if empty and return version if specified.
@@ -269,6 +269,30 @@ func (da *PackageArchive) getDocument(packageName, version string) (manifest str
 	return da.manifest, err
 }
 
+// ListVersions returns the version names of the package document
+func (da *PackageArchive) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	versions := []string{}
+	input := &ssm.ListDocumentVersionsInput{Name: &packageName}
+	for {
+		response, err := da.facadeClient.ListDocumentVersions(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the versions of package document: %v", err)
+		}
+		if response == nil {
+			return nil, fmt.Errorf("failed to list the versions of package document")
+		}
+		for _, documentVersion := range response.DocumentVersions {
+			if documentVersion != nil && documentVersion.VersionName != nil && *documentVersion.VersionName != "" {
+				versions = append(versions, *documentVersion.VersionName)
+			}
+		}
+		if response.NextToken == nil || *response.NextToken == "" {
+			return versions, nil
+		}
+		input.NextToken = response.NextToken
+	}
+}
+
 func getRandomBackOffTime(timeInSeconds int) int {
 	rand.Seed(time.Now().UnixNano())
 	delay := rand.Intn(timeInSeconds)
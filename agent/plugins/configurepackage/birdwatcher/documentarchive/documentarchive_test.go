@@ -51,6 +51,37 @@ func TestArchiveName(t *testing.T) {
 
 }
 
+func TestListVersions(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	packageName := "ABC_package"
+	versionName := "1.0.0"
+	otherVersionName := "1.1.0"
+	facadeSession := facade.FacadeStub{
+		ListDocumentVersionsOutput: &ssm.ListDocumentVersionsOutput{
+			DocumentVersions: []*ssm.DocumentVersionInfo{
+				{VersionName: &versionName},
+				{},
+				nil,
+				{VersionName: &otherVersionName},
+			},
+		},
+	}
+	testArchive := New(&facadeSession)
+
+	versions, err := testArchive.(archive.VersionLister).ListVersions(tracer, packageName)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{versionName, otherVersionName}, versions)
+	assert.Equal(t, packageName, *facadeSession.ListDocumentVersionsInput.Name)
+
+	facadeSession = facade.FacadeStub{ListDocumentVersionsError: errors.New("testerror")}
+	testArchive = New(&facadeSession)
+
+	_, err = testArchive.(archive.VersionLister).ListVersions(tracer, packageName)
+
+	assert.Error(t, err)
+}
+
 func TestGetResourceVersion(t *testing.T) {
 
 	packageName := "Test Package"
@@ -20,6 +20,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	retry "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade/retryer"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	sdkretryer "github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 
 	"github.com/aws/aws-sdk-go/aws/client"
@@ -28,16 +29,12 @@ import (
 	"github.com/aws/aws-sdk-go/service/ssm"
 )
 
-const (
-	maxRetries = 3
-)
-
 func NewBirdwatcherFacade() BirdwatcherFacade {
 	awsConfig := sdkutil.AwsConfig()
 	// overriding the retry strategy
 	retryer := retry.BirdwatcherRetryer{
 		DefaultRetryer: client.DefaultRetryer{
-			NumMaxRetries: maxRetries,
+			NumMaxRetries: sdkretryer.PolicyFor(ssm.ServiceName).MaxRetries,
 		},
 	}
 
@@ -154,6 +154,54 @@ func (_m *BirdwatcherFacade) GetManifestRequest(_a0 *ssm.GetManifestInput) (*req
 	return r0, r1
 }
 
+// ListDocumentVersions provides a mock function with given fields: _a0
+func (_m *BirdwatcherFacade) ListDocumentVersions(_a0 *ssm.ListDocumentVersionsInput) (*ssm.ListDocumentVersionsOutput, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *ssm.ListDocumentVersionsOutput
+	if rf, ok := ret.Get(0).(func(*ssm.ListDocumentVersionsInput) *ssm.ListDocumentVersionsOutput); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ssm.ListDocumentVersionsOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*ssm.ListDocumentVersionsInput) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDocumentVersionsRequest provides a mock function with given fields: _a0
+func (_m *BirdwatcherFacade) ListDocumentVersionsRequest(_a0 *ssm.ListDocumentVersionsInput) (*request.Request, *ssm.ListDocumentVersionsOutput) {
+	ret := _m.Called(_a0)
+
+	var r0 *request.Request
+	if rf, ok := ret.Get(0).(func(*ssm.ListDocumentVersionsInput) *request.Request); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*request.Request)
+		}
+	}
+
+	var r1 *ssm.ListDocumentVersionsOutput
+	if rf, ok := ret.Get(1).(func(*ssm.ListDocumentVersionsInput) *ssm.ListDocumentVersionsOutput); ok {
+		r1 = rf(_a0)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*ssm.ListDocumentVersionsOutput)
+		}
+	}
+
+	return r0, r1
+}
+
 // PutConfigurePackageResult provides a mock function with given fields: _a0
 func (_m *BirdwatcherFacade) PutConfigurePackageResult(_a0 *ssm.PutConfigurePackageResultInput) (*ssm.PutConfigurePackageResultOutput, error) {
 	ret := _m.Called(_a0)
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build integration
 // +build integration
 
 // Package retryer overrides the default ssm retryer delay logic to suit GetManifest, DescribeDocument and GetDocument
@@ -0,0 +1,180 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localarchive contains the struct that is called when the package is staged in a local directory
+package localarchive
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/archive"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+// ManifestFileName is the name of the manifest in a local source directory
+const ManifestFileName = "manifest.json"
+
+const fileURLScheme = "file://"
+
+type PackageArchive struct {
+	archiveType     string
+	sourceDirectory string
+	manifestPath    string
+	cache           packageservice.ManifestCache
+	packageArns     map[string]string
+}
+
+// New is a constructor for PackageArchive struct
+// source is a local directory containing the manifest and the files of the package, the path of the manifest itself,
+// or a file:// URL to either of them.
+func New(source string) (archive.IPackageArchive, error) {
+	sourcePath, err := ParseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access local source %v: %v", source, err)
+	}
+
+	manifestPath := sourcePath
+	if fileInfo.IsDir() {
+		manifestPath = filepath.Join(sourcePath, ManifestFileName)
+	}
+
+	return &PackageArchive{
+		archiveType:     archive.PackageArchiveLocal,
+		sourceDirectory: filepath.Dir(manifestPath),
+		manifestPath:    manifestPath,
+		packageArns:     make(map[string]string),
+	}, nil
+}
+
+// ParseSource returns the local path of a source given as an absolute path or as a file:// URL
+func ParseSource(source string) (string, error) {
+	sourcePath := source
+	if strings.HasPrefix(strings.ToLower(source), fileURLScheme) {
+		sourceURL, err := url.Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("invalid source url %v: %v", source, err)
+		}
+		if sourceURL.Host != "" && sourceURL.Host != "localhost" {
+			return "", fmt.Errorf("source url %v must refer to a local path", source)
+		}
+		sourcePath = sourceURL.Path
+		// file:///C:/packages is parsed with a leading slash before the drive letter
+		if runtime.GOOS == "windows" && len(sourcePath) > 2 && sourcePath[0] == '/' && sourcePath[2] == ':' {
+			sourcePath = sourcePath[1:]
+		}
+		sourcePath = filepath.FromSlash(sourcePath)
+	}
+
+	if !filepath.IsAbs(sourcePath) {
+		return "", fmt.Errorf("source %v must be an absolute path or a file:// url", source)
+	}
+	return filepath.Clean(sourcePath), nil
+}
+
+// Name of archive type
+func (la *PackageArchive) Name() string {
+	return la.archiveType
+}
+
+// SetManifestCache sets the manifest cache
+func (la *PackageArchive) SetManifestCache(manifestCache packageservice.ManifestCache) {
+	la.cache = manifestCache
+}
+
+// SetResource sets the package arn found in the manifest
+func (la *PackageArchive) SetResource(packageName string, version string, manifest *birdwatcher.Manifest) {
+	la.packageArns[archive.FormKey(packageName, version)] = manifest.PackageArn
+}
+
+// GetResourceArn returns the packageArn that is found in the manifest file
+func (la *PackageArchive) GetResourceArn(packageName string, version string) string {
+	return la.packageArns[archive.FormKey(packageName, version)]
+}
+
+// GetResourceVersion returns the version
+func (la *PackageArchive) GetResourceVersion(packageName string, packageVersion string) (name string, version string) {
+	version = packageVersion
+	if packageservice.IsLatest(packageVersion) {
+		version = packageservice.Latest
+	}
+
+	return packageName, version
+}
+
+// GetFileDownloadLocation obtains the location of the file in the source directory
+// The download location of the manifest is ignored, the file is expected next to the manifest.
+func (la *PackageArchive) GetFileDownloadLocation(file *archive.File, packageName string, version string) (string, error) {
+	if file == nil {
+		return "", fmt.Errorf("file is empty")
+	}
+	if file.Name == "" || filepath.IsAbs(file.Name) || filepath.Base(file.Name) != file.Name {
+		return "", fmt.Errorf("invalid file name %v in local source", file.Name)
+	}
+	return filepath.Join(la.sourceDirectory, file.Name), nil
+}
+
+// DownloadArchiveInfo reads the manifest from the local source
+func (la *PackageArchive) DownloadArchiveInfo(tracer trace.Tracer, packageName string, version string) (string, error) {
+	trace := tracer.BeginSection("Reading local archive info")
+	defer trace.End()
+
+	trace.AppendDebugf("Reading manifest from local source %v", la.manifestPath)
+	data, err := ioutil.ReadFile(la.manifestPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest from local source: %v", err)
+	}
+
+	manifest, err := archive.ParseManifest(&data)
+	if err != nil {
+		return "", err
+	}
+	// the manifest is staged by hand, make sure it identifies the package the same way the service would
+	if manifest.PackageArn == "" || manifest.Version == "" {
+		return "", fmt.Errorf("manifest in local source must contain the packageArn and the version")
+	}
+	// a local source holds a single version, which must be the one requested
+	if !packageservice.IsLatest(version) && manifest.Version != version {
+		return "", fmt.Errorf("local source contains version %v of package %v, version %v was requested", manifest.Version, packageName, version)
+	}
+
+	return string(data), nil
+}
+
+// ReadManifestFromCache to read the manifest from cache
+func (la *PackageArchive) ReadManifestFromCache(packageArn string, version string) (*birdwatcher.Manifest, error) {
+	data, err := la.cache.ReadManifest(packageArn, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return archive.ParseManifest(&data)
+}
+
+// WriteManifestToCache stores the manifest in cache
+func (la *PackageArchive) WriteManifestToCache(packageArn string, version string, manifest []byte) error {
+	return la.cache.WriteManifest(packageArn, version, manifest)
+}
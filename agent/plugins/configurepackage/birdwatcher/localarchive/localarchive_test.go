@@ -0,0 +1,143 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package localarchive contains the struct that is called when the package is staged in a local directory
+package localarchive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/archive"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `{
+	"schemaVersion": "2.0",
+	"packageArn": "arn:aws:ssm:::package/PVDriver",
+	"version": "1.2.3",
+	"packages": {"_any": {"_any": {"_any": {"file": "PVDriver.zip"}}}},
+	"files": {"PVDriver.zip": {"checksums": {"sha256": "abc"}}}
+}`
+
+func stageSource(t *testing.T, manifest string) string {
+	source, err := ioutil.TempDir("", "localarchive")
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(source, ManifestFileName), []byte(manifest), 0600))
+	return source
+}
+
+func TestParseSource(t *testing.T) {
+	absolute := filepath.Join(os.TempDir(), "packages")
+	data := []struct {
+		name          string
+		source        string
+		expected      string
+		errorExpected bool
+	}{
+		{"absolute path", absolute, absolute, false},
+		{"file url", "file://" + filepath.ToSlash(absolute), absolute, false},
+		{"relative path", "packages", "", true},
+		{"remote url", "https://example.com/packages", "", true},
+		{"remote file url", "file://server/packages", "", true},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			result, err := ParseSource(testdata.source)
+
+			if testdata.errorExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testdata.expected, result)
+			}
+		})
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	source := stageSource(t, testManifest)
+	defer os.RemoveAll(source)
+
+	testArchive, err := New(source)
+
+	assert.NoError(t, err)
+	assert.Equal(t, archive.PackageArchiveLocal, testArchive.Name())
+}
+
+func TestDownloadArchiveInfo(t *testing.T) {
+	source := stageSource(t, testManifest)
+	defer os.RemoveAll(source)
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	data := []struct {
+		name          string
+		source        string
+		version       string
+		errorExpected bool
+	}{
+		{"source directory", source, "1.2.3", false},
+		{"manifest file", filepath.Join(source, ManifestFileName), "1.2.3", false},
+		{"latest version", source, "latest", false},
+		{"other version", source, "2.0.0", true},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.name, func(t *testing.T) {
+			testArchive, err := New(testdata.source)
+			assert.NoError(t, err)
+
+			manifest, err := testArchive.DownloadArchiveInfo(tracer, "PVDriver", testdata.version)
+
+			if testdata.errorExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testManifest, manifest)
+			}
+		})
+	}
+}
+
+func TestDownloadArchiveInfoRequiresPackageArn(t *testing.T) {
+	source := stageSource(t, `{"version": "1.2.3"}`)
+	defer os.RemoveAll(source)
+	tracer := trace.NewTracer(log.NewMockLog())
+	testArchive, err := New(source)
+	assert.NoError(t, err)
+
+	_, err = testArchive.DownloadArchiveInfo(tracer, "PVDriver", "1.2.3")
+
+	assert.Error(t, err)
+}
+
+func TestGetFileDownloadLocation(t *testing.T) {
+	source := stageSource(t, testManifest)
+	defer os.RemoveAll(source)
+	testArchive, err := New(source)
+	assert.NoError(t, err)
+
+	location, err := testArchive.GetFileDownloadLocation(&archive.File{Name: "PVDriver.zip"}, "PVDriver", "1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(source, "PVDriver.zip"), location)
+
+	_, err = testArchive.GetFileDownloadLocation(&archive.File{Name: "../PVDriver.zip"}, "PVDriver", "1.2.3")
+	assert.Error(t, err)
+}
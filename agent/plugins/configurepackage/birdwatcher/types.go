@@ -19,6 +19,17 @@ type FileInfo struct {
 	Checksums        map[string]string `json:"checksums"`
 	DownloadLocation string            `json:"downloadLocation"`
 	Size             int               `json:"size"`
+
+	// optional list of the files in the package, used to download only the files that changed
+	Contents []*ContentInfo `json:"contents,omitempty"`
+}
+
+// ContentInfo contains data for one file inside an SSM package
+type ContentInfo struct {
+	Path             string            `json:"path"`
+	Checksums        map[string]string `json:"checksums"`
+	DownloadLocation string            `json:"downloadLocation"`
+	Size             int               `json:"size"`
 }
 
 // PackageInfo contains references to Files matching the current platform/version/arch
@@ -35,4 +46,7 @@ type Manifest struct {
 	// platform -> version -> arch -> file
 	Packages map[string]map[string]map[string]*PackageInfo `json:"packages"`
 	Files    map[string]*FileInfo                          `json:"files"`
+
+	// optional base64 signature of the manifest, required when signing keys are pinned in appconfig
+	Signature string `json:"signature,omitempty"`
 }
@@ -29,8 +29,10 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/birdwatcherservice"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/localarchive"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/ssms3"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
@@ -54,6 +56,7 @@ const documentArnPattern = "^arn:[a-z0-9][-.a-z0-9]{0,62}:[a-z0-9][-.a-z0-9]{0,6
 type Plugin struct {
 	packageServiceSelector func(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository, appCfg *appconfig.SsmagentConfig, bwfacade facade.BirdwatcherFacade, isDocumentArchive *bool) (packageservice.PackageService, error)
 	localRepository        localpackages.Repository
+	packageCache           packagecache.Cache
 	birdwatcherfacade      facade.BirdwatcherFacade
 	isDocumentArchive      bool
 }
@@ -74,6 +77,7 @@ func NewPlugin() (*Plugin, error) {
 
 	plugin.birdwatcherfacade = facade.NewBirdwatcherFacade()
 	plugin.localRepository = localpackages.NewRepository()
+	plugin.packageCache = packagecache.NewCache()
 	plugin.packageServiceSelector = selectService
 	plugin.isDocumentArchive = false
 
@@ -86,6 +90,7 @@ func prepareConfigurePackage(
 	config contracts.Configuration,
 	repository localpackages.Repository,
 	packageService packageservice.PackageService,
+	packageCache packagecache.Cache,
 	input *ConfigurePackagePluginInput,
 	packageArn string,
 	version string,
@@ -105,7 +110,7 @@ func prepareConfigurePackage(
 		// ensure manifest file and package
 		var err error
 		trace = tracer.BeginSection("ensure package is locally available")
-		inst, err = ensurePackage(tracer, repository, packageService, packageArn, version, isSameAsCache, config)
+		inst, err = ensurePackage(tracer, repository, packageService, packageCache, packageArn, version, isSameAsCache, config)
 		if err != nil {
 			trace.WithError(err).End()
 			output.MarkAsFailed(nil, nil)
@@ -117,11 +122,14 @@ func prepareConfigurePackage(
 		// * Check if the version is already installed using the packageArn
 		// * If the version exists, but the local manifest is different, reinstall the package
 		// * Return success if the package is already installed
+		// the old package is also needed to roll back a failed install, its content comes from the package cache if needed
+		// the manifest of a different installed version was not downloaded again, so its cached artifact is still valid
 		trace = tracer.BeginSection("ensure old package is locally available")
 		if !(installedVersion == "" || installState == localpackages.None) && (installedVersion != version || !isSameAsCache) {
-			uninst, err = ensurePackage(tracer, repository, packageService, packageArn, installedVersion, isSameAsCache, config)
+			uninst, err = ensurePackage(tracer, repository, packageService, packageCache, packageArn, installedVersion, isSameAsCache || installedVersion != version, config)
 			if err != nil {
 				trace.WithError(err)
+				trace.AppendErrorf("%v %v is not available, a failed install cannot be rolled back", packageArn, installedVersion)
 			}
 		}
 		trace.End()
@@ -153,7 +161,7 @@ func prepareConfigurePackage(
 
 		// ensure manifest file and package
 		trace = tracer.BeginSection("ensure package is locally available")
-		uninst, err = ensurePackage(tracer, repository, packageService, packageArn, installedVersion, isSameAsCache, config)
+		uninst, err = ensurePackage(tracer, repository, packageService, packageCache, packageArn, installedVersion, isSameAsCache, config)
 		if err != nil {
 			trace.WithError(err)
 			output.MarkAsFailed(nil, nil)
@@ -174,6 +182,7 @@ func ensurePackage(
 	tracer trace.Tracer,
 	repository localpackages.Repository,
 	packageService packageservice.PackageService,
+	packageCache packagecache.Cache,
 	packageName string,
 	version string,
 	isSameAsCache bool,
@@ -186,7 +195,12 @@ func ensurePackage(
 		(currentVersion == version && (currentState == localpackages.Failed || !isSameAsCache)) {
 		pkgTrace.AppendDebugf("Current %v Target %v State %v", currentVersion, version, currentState).End()
 		pkgTrace.AppendDebugf("Refreshing package content for %v %v", packageName, version).End()
-		if err = repository.RefreshPackage(tracer, packageName, version, packageService.PackageServiceName(), buildDownloadDelegate(tracer, packageService, packageName, version)); err != nil {
+		// when updating, the files of the installed version that did not change do not need to be downloaded again
+		var baseDirectory string
+		if installedVersion := repository.GetInstalledVersion(tracer, packageName); installedVersion != "" && installedVersion != version {
+			baseDirectory = repository.GetPackageVersionPath(tracer, packageName, installedVersion)
+		}
+		if err = repository.RefreshPackage(tracer, packageName, version, packageService.PackageServiceName(), buildDownloadDelegate(tracer, packageService, packageCache, packageName, version, isSameAsCache, baseDirectory)); err != nil {
 			pkgTrace.WithError(err).End()
 			return nil, err
 		}
@@ -202,13 +216,36 @@ func ensurePackage(
 }
 
 // buildDownloadDelegate constructs the delegate used by the repository to download a package from the service
-func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.PackageService, packageName string, version string) func(trace.Tracer, string) error {
+// The artifact is taken from the package cache if present, otherwise it is downloaded and added to the cache.
+// The cache is bypassed when the manifest changed, since the same version may then have different content.
+// When baseDirectory is set and the service supports it, only the files that changed since that version are downloaded.
+func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.PackageService, packageCache packagecache.Cache, packageName string, version string, isSameAsCache bool, baseDirectory string) func(trace.Tracer, string) error {
 	return func(tracer trace.Tracer, targetDirectory string) error {
 		trace := tracer.BeginSection("download artifact")
-		filePath, err := packageService.DownloadArtifact(tracer, packageName, version)
-		if err != nil {
-			trace.WithError(err).End()
-			return err
+		var filePath string
+		var found bool
+		if isSameAsCache {
+			filePath, found = packageCache.Get(tracer, packageName, version)
+		} else {
+			trace.AppendInfof("manifest is not the same as the cached one, not using cached artifact for package %v version %v", packageName, version)
+		}
+
+		if found {
+			trace.AppendInfof("using cached artifact for package %v version %v", packageName, version)
+		} else if downloadDelta(tracer, packageService, packageName, version, baseDirectory, targetDirectory) {
+			trace.End()
+			return nil
+		} else {
+			var err error
+			if filePath, err = packageService.DownloadArtifact(tracer, packageName, version); err != nil {
+				trace.WithError(err).End()
+				return err
+			}
+
+			// a failure to cache the artifact is not fatal, it is downloaded again next time
+			if cacheErr := packageCache.Add(tracer, packageName, version, filePath); cacheErr != nil {
+				trace.AppendErrorf("failed to add package %v version %v to the cache, %v", packageName, version, cacheErr.Error())
+			}
 		}
 
 		// TODO: Consider putting uncompress into the ssminstaller new and not deleting it (since the zip is the repository-validatable artifact)
@@ -218,7 +255,7 @@ func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.Pa
 		}
 
 		// NOTE: this could be considered a warning - it likely points to a real problem, but if uncompress succeeded, we could continue
-		// delete compressed package after using
+		// delete compressed package after using, the cache keeps its own copy
 		if cleanupErr := filesysdep.RemoveAll(filePath); cleanupErr != nil {
 			trace.WithError(cleanupErr).End()
 			return fmt.Errorf("failed to delete compressed package %v, %v", filePath, cleanupErr.Error())
@@ -229,6 +266,23 @@ func buildDownloadDelegate(tracer trace.Tracer, packageService packageservice.Pa
 	}
 }
 
+// downloadDelta tries to populate targetDirectory by downloading only the files that changed since the version in baseDirectory
+// It returns false if the full artifact has to be downloaded instead.
+func downloadDelta(tracer trace.Tracer, packageService packageservice.PackageService, packageName string, version string, baseDirectory string, targetDirectory string) bool {
+	deltaDownloader, ok := packageService.(packageservice.DeltaDownloader)
+	if baseDirectory == "" || !ok {
+		return false
+	}
+
+	supported, err := deltaDownloader.DownloadArtifactDelta(tracer, packageName, version, baseDirectory, targetDirectory)
+	if err != nil {
+		// the target directory is overwritten by the full artifact
+		tracer.CurrentTrace().AppendErrorf("failed to download changed files of package %v version %v, downloading the full artifact, %v", packageName, version, err.Error())
+		return false
+	}
+	return supported
+}
+
 // getVersionToInstall decides which version to install and whether there is an existing version (that is not in the process of installing)
 func getVersionToInstall(
 	tracer trace.Tracer,
@@ -278,9 +332,11 @@ func parseAndValidateInput(rawPluginInput interface{}) (*ConfigurePackagePluginI
 
 // validateInput ensures the plugin input matches the defined schema
 func validateInput(input *ConfigurePackagePluginInput) (valid bool, err error) {
-	// source not yet supported
+	// only local sources are supported, the package is otherwise obtained from the package service
 	if input.Source != "" {
-		return false, errors.New("source parameter is not supported in this version")
+		if _, err := localarchive.ParseSource(input.Source); err != nil {
+			return false, fmt.Errorf("unsupported source parameter, %v", err)
+		}
 	}
 
 	// ensure non-empty name
@@ -288,6 +344,16 @@ func validateInput(input *ConfigurePackagePluginInput) (valid bool, err error) {
 		return false, errors.New("empty name field")
 	}
 
+	// a version constraint is resolved to the best available version, which only makes sense when installing
+	if packageservice.IsVersionConstraint(input.Version) {
+		if _, err := packageservice.ParseVersionConstraint(input.Version); err != nil {
+			return false, err
+		}
+		if input.Action != InstallAction {
+			return false, fmt.Errorf("version constraint %v is only supported for the %v action", input.Version, InstallAction)
+		}
+	}
+
 	// dump any unsupported value for Repository
 	if input.Repository != "beta" && input.Repository != "gamma" {
 		input.Repository = ""
@@ -341,7 +407,7 @@ func checkAlreadyInstalled(
 					}
 					output.MarkAsSucceeded()
 				} else if installState == localpackages.RollbackInstall {
-					validateTrace.AppendInfof("Failed to install %v %v, successfully rolled back to %v %v", uninst.PackageName(), uninst.Version(), inst.PackageName(), inst.Version())
+					reportRollback(validateTrace, uninst, inst)
 					cleanupAfterUninstall(tracer, repository, inst, output)
 					output.MarkAsFailed(nil, nil)
 				} else if installState == localpackages.Unknown {
@@ -380,6 +446,12 @@ func checkAlreadyInstalled(
 
 // selectService chooses the implementation of PackageService to use for a given execution of the plugin
 func selectService(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository, appCfg *appconfig.SsmagentConfig, birdwatcherFacade facade.BirdwatcherFacade, isDocumentArchive *bool) (packageservice.PackageService, error) {
+	// a local source does not need any remote service, not even to find the region
+	if input.Source != "" {
+		tracer.CurrentTrace().AppendInfof("Using local source %v", input.Source)
+		return birdwatcherservice.NewLocalArchive(birdwatcherFacade, localrepo, input.Source)
+	}
+
 	region, _ := platform.Region()
 	serviceEndpoint := input.Repository
 	response := &ssm.GetManifestOutput{}
@@ -393,7 +465,7 @@ func selectService(tracer trace.Tracer, input *ConfigurePackagePluginInput, loca
 			// return a new object of type document
 			return birdwatcherservice.NewDocumentArchive(birdwatcherFacade, localrepo), nil
 		}
-		if input.Version != "" {
+		if input.Version != "" && !packageservice.IsVersionConstraint(input.Version) {
 			// Birdwatcher version pattern and document version name pattern is different. If the pattern doesn't match Birdwatcher,
 			// we assume document and continue, since birdwatcher will error out with ValidationException.
 			// This could also happen if there is a typo in the birdwatcher version, but we assume Document and continue.
@@ -406,7 +478,7 @@ func selectService(tracer trace.Tracer, input *ConfigurePackagePluginInput, loca
 
 		// If not, make a call to GetManifest and try to figure out if it is birdwatcher or document archive.
 		version := input.Version
-		if packageservice.IsLatest(version) {
+		if packageservice.IsLatest(version) || packageservice.IsVersionConstraint(version) {
 			version = packageservice.Latest
 		}
 		response, err = birdwatcherFacade.GetManifest(
@@ -443,6 +515,39 @@ func selectService(tracer trace.Tracer, input *ConfigurePackagePluginInput, loca
 	return ssms3.New(serviceEndpoint, region), nil
 }
 
+// resolveVersionConstraint returns the highest version of the package available from the service that satisfies the constraint
+func resolveVersionConstraint(tracer trace.Tracer, packageService packageservice.PackageService, packageName string, version string) (string, error) {
+	resolveTrace := tracer.BeginSection(fmt.Sprintf("resolve version constraint %v", version))
+
+	constraint, err := packageservice.ParseVersionConstraint(version)
+	if err != nil {
+		resolveTrace.WithError(err).End()
+		return "", err
+	}
+
+	versionLister, ok := packageService.(packageservice.VersionLister)
+	if !ok {
+		err = fmt.Errorf("version constraints are not supported by the %v package service", packageService.PackageServiceName())
+		resolveTrace.WithError(err).End()
+		return "", err
+	}
+	candidates, err := versionLister.ListVersions(tracer, packageName)
+	if err != nil {
+		resolveTrace.WithError(err).End()
+		return "", fmt.Errorf("failed to list versions of package %v: %v", packageName, err)
+	}
+
+	resolved := constraint.BestMatch(candidates)
+	if resolved == "" {
+		err = fmt.Errorf("no available version of package %v satisfies %v, available versions: %v", packageName, constraint, candidates)
+		resolveTrace.WithError(err).End()
+		return "", err
+	}
+
+	resolveTrace.AppendInfof("resolved version constraint %v to version %v", constraint, resolved).End()
+	return resolved, nil
+}
+
 // Execute runs the plugin operation and returns output
 func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	p.execute(context, config, cancelFlag, output)
@@ -476,6 +581,11 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 		if err != nil {
 			tracer.CurrentTrace().WithError(err).End()
 			out.MarkAsFailed(nil, nil)
+		} else if packageservice.IsVersionConstraint(input.Version) {
+			if input.Version, err = resolveVersionConstraint(tracer, packageService, input.Name, input.Version); err != nil {
+				tracer.CurrentTrace().WithError(err).End()
+				out.MarkAsFailed(nil, nil)
+			}
 		}
 		if out.GetStatus() != contracts.ResultStatusFailed {
 			//Return failure if the manifest cannot be accessed
@@ -506,6 +616,7 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 					config,
 					p.localRepository,
 					packageService,
+					p.packageCache,
 					input,
 					packageArn,
 					manifestVersion,
@@ -553,7 +664,8 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 							startTime = trace.Start
 						}
 					}
-					if !p.isDocumentArchive {
+					// packages installed from a local source are installed offline, there is no service to report to
+					if !p.isDocumentArchive && input.Source == "" {
 						err := packageService.ReportResult(tracer, packageservice.PackageResult{
 							Exitcode:               int64(out.GetExitCode()),
 							Operation:              input.Action,
@@ -575,6 +687,8 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	output.SetExitCode(out.GetExitCode())
 	output.SetStatus(out.GetStatus())
 
+	collectPackageCache(tracer, p.packageCache, p.localRepository)
+
 	// convert trace
 	traceout := tracer.ToPluginOutput()
 	output.AppendInfo(traceout.GetStdout())
@@ -583,6 +697,21 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	return
 }
 
+// collectPackageCache removes the cached artifacts of package versions that are not in use, according to the cache policy
+func collectPackageCache(tracer trace.Tracer, packageCache packagecache.Cache, repository localpackages.Repository) {
+	cacheCfg := appconfig.DefaultConfig().PackageCache
+	if appCfg, err := appconfig.Config(false); err == nil {
+		cacheCfg = appCfg.PackageCache
+	}
+
+	isReferenced := func(packageArn string, version string) bool {
+		return localpackages.IsVersionInUse(tracer, repository, packageArn, version)
+	}
+	if _, err := packageCache.Collect(tracer, packagecache.PolicyFromConfig(cacheCfg), isReferenced); err != nil {
+		tracer.CurrentTrace().AppendErrorf("failed to collect package cache, %v", err.Error())
+	}
+}
+
 // Name returns the name of the plugin.
 func Name() string {
 	return appconfig.PluginNameAwsConfigurePackage
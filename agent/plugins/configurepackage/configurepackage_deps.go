@@ -18,7 +18,9 @@ package configurepackage
 
 import (
 	"os"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 )
 
@@ -50,3 +52,16 @@ func (fileSysDepImp) Uncompress(src, dest string) error {
 func (fileSysDepImp) RemoveAll(path string) error {
 	return os.RemoveAll(path)
 }
+
+var hookdep hookDep = &hookDepImp{}
+
+// dependency on running administrator-defined package hooks
+type hookDep interface {
+	Run(path string, env []string, timeout time.Duration) (output string, err error)
+}
+
+type hookDepImp struct{}
+
+func (hookDepImp) Run(path string, env []string, timeout time.Duration) (output string, err error) {
+	return executers.RunHook(path, env, timeout)
+}
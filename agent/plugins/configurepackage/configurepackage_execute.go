@@ -86,14 +86,18 @@ func executeInstall(
 		setNewInstallState(tracer, repository, inst, localpackages.Installing)
 	}
 
-	result := inst.Install(tracer, context)
+	result := runPreActionHook(tracer, context, inst, hookActionInstall, isRollback)
+	if result == nil {
+		result = inst.Install(tracer, context)
 
-	installtrace.WithExitcode(int64(result.GetExitCode()))
+		installtrace.WithExitcode(int64(result.GetExitCode()))
 
-	if result.GetStatus() == contracts.ResultStatusSuccess {
-		validatetrace := tracer.BeginSection(fmt.Sprintf("validate %s/%s - rollback: %t", inst.PackageName(), inst.Version(), isRollback))
-		result = inst.Validate(tracer, context)
-		validatetrace.WithExitcode(int64(result.GetExitCode()))
+		if result.GetStatus() == contracts.ResultStatusSuccess {
+			validatetrace := tracer.BeginSection(fmt.Sprintf("validate %s/%s - rollback: %t", inst.PackageName(), inst.Version(), isRollback))
+			result = inst.Validate(tracer, context)
+			validatetrace.WithExitcode(int64(result.GetExitCode()))
+		}
+		runPostActionHook(tracer, context, inst, hookActionInstall, isRollback, result.GetStatus())
 	}
 	if result.GetStatus().IsReboot() {
 		tracer.BeginSection(fmt.Sprintf("Rebooting to finish installation of %v %v - rollback: %t", inst.PackageName(), inst.Version(), isRollback))
@@ -102,6 +106,9 @@ func executeInstall(
 	}
 	if !result.GetStatus().IsSuccess() {
 		installtrace.AppendErrorf("Failed to install package; install status %v", result.GetStatus())
+		if isRollback {
+			installtrace.AppendErrorf("Failed to roll back to %v %v", inst.PackageName(), inst.Version())
+		}
 		if isRollback || uninst == nil {
 			output.MarkAsFailed(nil, nil)
 			// TODO: Remove from repository if this isn't the last successfully installed version?  Run uninstall to clean up?
@@ -109,6 +116,7 @@ func executeInstall(
 			return
 		}
 		// Execute rollback
+		installtrace.AppendInfof("Rolling back to previously installed version %v %v", uninst.PackageName(), uninst.Version())
 		executeUninstall(tracer, context, repository, uninst, inst, true, output)
 		return
 	}
@@ -116,7 +124,7 @@ func executeInstall(
 		cleanupAfterUninstall(tracer, repository, uninst, output)
 	}
 	if isRollback {
-		installtrace.AppendInfof("Failed to install %v %v, successfully rolled back to %v %v", uninst.PackageName(), uninst.Version(), inst.PackageName(), inst.Version())
+		reportRollback(installtrace, uninst, inst)
 		setNewInstallState(tracer, repository, inst, localpackages.Installed)
 		output.MarkAsFailed(nil, nil)
 		return
@@ -127,6 +135,12 @@ func executeInstall(
 	return
 }
 
+// reportRollback reports the failed install and the successful rollback separately, so both show in the plugin output
+func reportRollback(trace *trace.Trace, failed installer.Installer, restored installer.Installer) {
+	trace.AppendErrorf("Failed to install %v %v", failed.PackageName(), failed.Version())
+	trace.AppendInfof("Successfully rolled back to %v %v", restored.PackageName(), restored.Version())
+}
+
 // executeUninstall performs uninstall of a package
 func executeUninstall(
 	tracer trace.Tracer,
@@ -150,8 +164,12 @@ func executeUninstall(
 		}
 	}
 
-	result := uninst.Uninstall(tracer, context)
-	installtrace.WithExitcode(int64(result.GetExitCode()))
+	result := runPreActionHook(tracer, context, uninst, hookActionUninstall, isRollback)
+	if result == nil {
+		result = uninst.Uninstall(tracer, context)
+		installtrace.WithExitcode(int64(result.GetExitCode()))
+		runPostActionHook(tracer, context, uninst, hookActionUninstall, isRollback, result.GetStatus())
+	}
 
 	if !result.GetStatus().IsSuccess() {
 		installtrace.AppendErrorf("Failed to uninstall version %v of package; uninstall status %v", uninst.Version(), result.GetStatus())
@@ -15,12 +15,18 @@
 package configurepackage
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
 	repository_mock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -157,6 +163,9 @@ func TestRollback(t *testing.T) {
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "Failed to install SsmTest 0.0.2")
+	assert.Contains(t, output.GetStdout(), "Successfully rolled back to SsmTest 0.0.1")
 }
 
 func TestRollbackFailed(t *testing.T) {
@@ -177,6 +186,9 @@ func TestRollbackFailed(t *testing.T) {
 	installerMock.AssertExpectations(t)
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "Failed to roll back to SsmTest 0.0.1")
+	assert.NotContains(t, output.GetStdout(), "Successfully rolled back")
 }
 
 func TestUninstallReboot(t *testing.T) {
@@ -311,3 +323,88 @@ func TestRollbackAfterInstallReboot(t *testing.T) {
 	uninstallerMock.AssertExpectations(t)
 	repoMock.AssertExpectations(t)
 }
+
+func contextWithHooksMock(preAction string, postAction string) context.T {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{
+		PackageHooks: appconfig.PackageHooksCfg{PreAction: preAction, PostAction: postAction, TimeoutSeconds: 10},
+	}
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	ctx.On("CurrentContext").Return([]string{})
+	return ctx
+}
+
+func TestInstallRunsHooks(t *testing.T) {
+	installerMock := installerSuccessMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installing).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installed).Return(nil)
+	env := []string{"SSM_PACKAGE_NAME=SsmTest", "SSM_PACKAGE_VERSION=0.0.1", "SSM_PACKAGE_ACTION=Install", "SSM_PACKAGE_ROLLBACK=false"}
+	hookMock := &hookDepMock{}
+	hookMock.On("Run", "/opt/hooks/pre", env, 10*time.Second).Return("snapshot taken", nil).Once()
+	hookMock.On("Run", "/opt/hooks/post", append(env, "SSM_PACKAGE_STATUS=Success"), 10*time.Second).Return("", nil).Once()
+	stubs := &ConfigurePackageStubs{hookDepStub: hookMock}
+	stubs.Set()
+	defer stubs.Clear()
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextWithHooksMock("/opt/hooks/pre", "/opt/hooks/post"), repoMock, installerMock, nil, localpackages.New, output)
+
+	installerMock.AssertExpectations(t)
+	repoMock.AssertExpectations(t)
+	hookMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "snapshot taken")
+}
+
+func TestInstall_FailedPreActionHook(t *testing.T) {
+	installerMock := installerNameVersionOnlyMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Installing).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Failed).Return(nil)
+	hookMock := &hookDepMock{}
+	hookMock.On("Run", "/opt/hooks/pre", mock.Anything, mock.Anything).Return("", errors.New("exit status 1")).Once()
+	stubs := &ConfigurePackageStubs{hookDepStub: hookMock}
+	stubs.Set()
+	defer stubs.Clear()
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextWithHooksMock("/opt/hooks/pre", "/opt/hooks/post"), repoMock, installerMock, nil, localpackages.New, output)
+
+	installerMock.AssertExpectations(t)
+	repoMock.AssertExpectations(t)
+	hookMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "Package pre-action hook /opt/hooks/pre failed: exit status 1")
+}
+
+func TestUninstall_FailedPostActionHookIsReported(t *testing.T) {
+	uninstallerMock := uninstallerSuccessMock("SsmTest", "0.0.1")
+	repoMock := &repository_mock.MockedRepository{}
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.Uninstalling).Return(nil)
+	repoMock.On("SetInstallState", mock.Anything, "SsmTest", "0.0.1", localpackages.None).Return(nil)
+	repoMock.On("RemovePackage", mock.Anything, "SsmTest", "0.0.1").Return(nil)
+	env := []string{"SSM_PACKAGE_NAME=SsmTest", "SSM_PACKAGE_VERSION=0.0.1", "SSM_PACKAGE_ACTION=Uninstall", "SSM_PACKAGE_ROLLBACK=false", "SSM_PACKAGE_STATUS=Success"}
+	hookMock := &hookDepMock{}
+	hookMock.On("Run", "/opt/hooks/post", env, 10*time.Second).Return("", errors.New("exit status 2")).Once()
+	stubs := &ConfigurePackageStubs{hookDepStub: hookMock}
+	stubs.Set()
+	defer stubs.Clear()
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+	output := &trace.PluginOutputTrace{Tracer: tracer}
+
+	executeConfigurePackage(tracer, contextWithHooksMock("", "/opt/hooks/post"), repoMock, nil, uninstallerMock, localpackages.Installed, output)
+
+	uninstallerMock.AssertExpectations(t)
+	repoMock.AssertExpectations(t)
+	hookMock.AssertExpectations(t)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "Package post-action hook /opt/hooks/post failed: exit status 2")
+}
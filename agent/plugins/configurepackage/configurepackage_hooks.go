@@ -0,0 +1,93 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package configurepackage implements the ConfigurePackage plugin.
+package configurepackage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+const (
+	hookActionInstall   = "Install"
+	hookActionUninstall = "Uninstall"
+
+	hookEnvPackageName    = "SSM_PACKAGE_NAME"
+	hookEnvPackageVersion = "SSM_PACKAGE_VERSION"
+	hookEnvPackageAction  = "SSM_PACKAGE_ACTION"
+	hookEnvRollback       = "SSM_PACKAGE_ROLLBACK"
+	hookEnvStatus         = "SSM_PACKAGE_STATUS"
+)
+
+// runPreActionHook runs the administrator-defined pre-action hook, if any,
+// and returns a failed result when the install or uninstall must not be performed
+func runPreActionHook(tracer trace.Tracer, context context.T, inst installer.Installer, action string, isRollback bool) contracts.PluginOutputter {
+	hooks := context.AppConfig().PackageHooks
+	if hooks.PreAction == "" {
+		return nil
+	}
+
+	env := hookEnvironment(inst, action, isRollback)
+	if err := runHook(tracer, "pre-action", hooks.PreAction, hooks.TimeoutSeconds, env); err != nil {
+		result := &trace.PluginOutputTrace{Tracer: tracer}
+		result.SetStatus(contracts.ResultStatusFailed)
+		return result
+	}
+	return nil
+}
+
+// runPostActionHook runs the administrator-defined post-action hook, if any, with the status of the install or uninstall
+func runPostActionHook(tracer trace.Tracer, context context.T, inst installer.Installer, action string, isRollback bool, status contracts.ResultStatus) {
+	hooks := context.AppConfig().PackageHooks
+	if hooks.PostAction == "" {
+		return
+	}
+
+	env := append(hookEnvironment(inst, action, isRollback), fmt.Sprintf("%v=%v", hookEnvStatus, status))
+	// the action has already been performed, a failing post-action hook is only reported
+	runHook(tracer, "post-action", hooks.PostAction, hooks.TimeoutSeconds, env)
+}
+
+// hookEnvironment returns the environment variables describing the package action to a hook
+func hookEnvironment(inst installer.Installer, action string, isRollback bool) []string {
+	return []string{
+		fmt.Sprintf("%v=%v", hookEnvPackageName, inst.PackageName()),
+		fmt.Sprintf("%v=%v", hookEnvPackageVersion, inst.Version()),
+		fmt.Sprintf("%v=%v", hookEnvPackageAction, action),
+		fmt.Sprintf("%v=%v", hookEnvRollback, strconv.FormatBool(isRollback)),
+	}
+}
+
+// runHook runs a hook and adds its output to the trace
+func runHook(tracer trace.Tracer, stage string, path string, timeoutSeconds int, env []string) (err error) {
+	hooktrace := tracer.BeginSection(fmt.Sprintf("run %v hook %v", stage, path))
+	defer hooktrace.EndWithError(&err)
+
+	output, err := hookdep.Run(path, env, time.Duration(timeoutSeconds)*time.Second)
+	if output = strings.TrimSpace(output); output != "" {
+		hooktrace.AppendInfo(output)
+	}
+	if err != nil {
+		hooktrace.AppendErrorf("Package %v hook %v failed: %v", stage, path, err)
+	}
+	return err
+}
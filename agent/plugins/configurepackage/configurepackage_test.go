@@ -17,6 +17,8 @@ package configurepackage
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"io/ioutil"
@@ -28,7 +30,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade"
 	facadeMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	cacheMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -140,6 +144,7 @@ func TestPrepareNewInstall(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"0.0.1",
@@ -173,6 +178,7 @@ func TestAlreadyInstalled(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"0.0.1",
@@ -206,6 +212,7 @@ func TestPrepareUpgrade(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"0.0.2",
@@ -239,6 +246,7 @@ func TestPrepareUninstall(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"0.0.1",
@@ -272,6 +280,7 @@ func TestPrepareUninstallCurrent(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"0.0.1",
@@ -305,6 +314,7 @@ func TestPrepareUninstallCurrentWithLatest(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"0.0.1",
@@ -338,6 +348,7 @@ func TestPrepareUninstallWrongVersion(t *testing.T) {
 		buildConfigSimple(pluginInformation),
 		repoMock,
 		serviceMock,
+		&cacheMock.MockedCache{},
 		pluginInformation,
 		"packageArn",
 		"2.3.4",
@@ -354,6 +365,143 @@ func TestPrepareUninstallWrongVersion(t *testing.T) {
 	installerMock.AssertExpectations(t)
 }
 
+func TestDownloadDelegateUsesCachedArtifact(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &serviceMock.Mock{}
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Get", mock.Anything, "packageArn", "0.0.1").Return("cache/staging/artifact", true).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.1", true, "")(tracer, "target")
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "DownloadArtifact", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDownloadDelegateAddsArtifactToCache(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &serviceMock.Mock{}
+	mockService.On("DownloadArtifact", mock.Anything, "packageArn", "0.0.1").Return("download/package.zip", nil).Once()
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Get", mock.Anything, "packageArn", "0.0.1").Return("", false).Once()
+	mockCache.On("Add", mock.Anything, "packageArn", "0.0.1", "download/package.zip").Return(nil).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.1", true, "")(tracer, "target")
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadDelegateBypassesCacheWhenManifestChanged(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &serviceMock.Mock{}
+	mockService.On("DownloadArtifact", mock.Anything, "packageArn", "0.0.1").Return("download/package.zip", nil).Once()
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Add", mock.Anything, "packageArn", "0.0.1", "download/package.zip").Return(nil).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.1", false, "")(tracer, "target")
+
+	// the stale cached artifact must not be used, the new content replaces it in the cache
+	assert.NoError(t, err)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything, mock.Anything)
+	mockCache.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadDelegateIgnoresCacheFailure(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &serviceMock.Mock{}
+	mockService.On("DownloadArtifact", mock.Anything, "packageArn", "0.0.1").Return("download/package.zip", nil).Once()
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Get", mock.Anything, "packageArn", "0.0.1").Return("", false).Once()
+	mockCache.On("Add", mock.Anything, "packageArn", "0.0.1", "download/package.zip").Return(errors.New("disk full")).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.1", true, "")(tracer, "target")
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
+
+// deltaServiceMock is a package service that also supports delta downloads
+type deltaServiceMock struct {
+	serviceMock.Mock
+}
+
+func (ds *deltaServiceMock) DownloadArtifactDelta(tracer trace.Tracer, packageName string, version string, baseDirectory string, targetDirectory string) (bool, error) {
+	args := ds.Called(tracer, packageName, version, baseDirectory, targetDirectory)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestDownloadDelegateUsesDeltaDownload(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &deltaServiceMock{}
+	mockService.On("DownloadArtifactDelta", mock.Anything, "packageArn", "0.0.2", "packages/packageArn/0.0.1", "target").Return(true, nil).Once()
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Get", mock.Anything, "packageArn", "0.0.2").Return("", false).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.2", true, "packages/packageArn/0.0.1")(tracer, "target")
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "DownloadArtifact", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDownloadDelegateFallsBackWhenDeltaFails(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &deltaServiceMock{}
+	mockService.On("DownloadArtifactDelta", mock.Anything, "packageArn", "0.0.2", "packages/packageArn/0.0.1", "target").Return(true, errors.New("hash mismatch")).Once()
+	mockService.On("DownloadArtifact", mock.Anything, "packageArn", "0.0.2").Return("download/package.zip", nil).Once()
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Get", mock.Anything, "packageArn", "0.0.2").Return("", false).Once()
+	mockCache.On("Add", mock.Anything, "packageArn", "0.0.2", "download/package.zip").Return(nil).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.2", true, "packages/packageArn/0.0.1")(tracer, "target")
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadDelegateFallsBackWhenDeltaNotSupported(t *testing.T) {
+	stubs := setSuccessStubs()
+	defer stubs.Clear()
+
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &deltaServiceMock{}
+	mockService.On("DownloadArtifactDelta", mock.Anything, "packageArn", "0.0.2", "packages/packageArn/0.0.1", "target").Return(false, nil).Once()
+	mockService.On("DownloadArtifact", mock.Anything, "packageArn", "0.0.2").Return("download/package.zip", nil).Once()
+	mockCache := &cacheMock.MockedCache{}
+	mockCache.On("Get", mock.Anything, "packageArn", "0.0.2").Return("", false).Once()
+	mockCache.On("Add", mock.Anything, "packageArn", "0.0.2", "download/package.zip").Return(nil).Once()
+
+	err := buildDownloadDelegate(tracer, mockService, mockCache, "packageArn", "0.0.2", true, "packages/packageArn/0.0.1")(tracer, "target")
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+}
+
 func TestInstalledValid(t *testing.T) {
 	pluginInformation := createStubPluginInputInstall()
 	installerMock := installerSuccessMock(pluginInformation.Name, pluginInformation.Version)
@@ -530,6 +678,7 @@ func TestExecute(t *testing.T) {
 
 	plugin := &Plugin{
 		localRepository:        repoMock,
+		packageCache:           cacheCollectMock(),
 		packageServiceSelector: selectMockService(serviceMock),
 	}
 	plugin.execute(contextMock, buildConfigSimple(pluginInformation), createMockCancelFlag(), createMockIOHandler())
@@ -547,6 +696,7 @@ func TestExecuteArrayInput(t *testing.T) {
 
 	plugin := &Plugin{
 		localRepository:        repoMock,
+		packageCache:           cacheCollectMock(),
 		packageServiceSelector: selectMockService(serviceMock),
 	}
 
@@ -567,6 +717,7 @@ func TestConfigurePackage_InvalidAction(t *testing.T) {
 
 	plugin := &Plugin{
 		localRepository:        repoMock,
+		packageCache:           cacheCollectMock(),
 		packageServiceSelector: selectMockService(serviceMock),
 	}
 	plugin.execute(contextMock, buildConfigSimple(pluginInformation), createMockCancelFlag(), createMockIOHandler())
@@ -597,7 +748,56 @@ func TestValidateInput_Source(t *testing.T) {
 
 	assert.False(t, result)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "source parameter is not supported")
+	assert.Contains(t, err.Error(), "unsupported source parameter")
+}
+
+func TestValidateInput_LocalSource(t *testing.T) {
+	input := ConfigurePackagePluginInput{}
+
+	input.Version = "1.0.0"
+	input.Name = "PVDriver"
+	input.Action = "Install"
+
+	for _, source := range []string{filepath.Join(os.TempDir(), "packages", "PVDriver"), "file://" + filepath.ToSlash(filepath.Join(os.TempDir(), "packages"))} {
+		input.Source = source
+
+		result, err := validateInput(&input)
+
+		assert.True(t, result)
+		assert.NoError(t, err)
+	}
+
+	input.Source = "packages/PVDriver"
+	result, err := validateInput(&input)
+
+	assert.False(t, result)
+	assert.Error(t, err)
+}
+
+func TestValidateInput_VersionConstraint(t *testing.T) {
+	input := ConfigurePackagePluginInput{}
+
+	input.Version = ">=2.3 <3.0"
+	input.Name = "PVDriver"
+	input.Action = "Install"
+
+	result, err := validateInput(&input)
+
+	assert.True(t, result)
+	assert.NoError(t, err)
+
+	input.Action = "Uninstall"
+	result, err = validateInput(&input)
+
+	assert.False(t, result)
+	assert.Contains(t, err.Error(), "only supported for the Install action")
+
+	input.Action = "Install"
+	input.Version = ">=2.3 3.0"
+	result, err = validateInput(&input)
+
+	assert.False(t, result)
+	assert.Error(t, err)
 }
 
 func TestValidateInput_NameEmpty(t *testing.T) {
@@ -781,6 +981,76 @@ func TestSelectService(t *testing.T) {
 	}
 }
 
+func TestSelectServiceLocalSource(t *testing.T) {
+	isDocumentArchive := false
+	source, err := ioutil.TempDir("", "localsource")
+	assert.NoError(t, err)
+	defer os.RemoveAll(source)
+
+	tracer := trace.NewTracer(contextMock.Log())
+	defer tracer.BeginSection("test").End()
+	input := &ConfigurePackagePluginInput{
+		Name:    "package",
+		Version: "1.2.3.4",
+		Source:  source,
+	}
+
+	result, err := selectService(tracer, input, localpackages.NewRepository(), nil, &facade.FacadeStub{}, &isDocumentArchive)
+
+	assert.NoError(t, err)
+	assert.Equal(t, packageservice.PackageServiceName_local, result.PackageServiceName())
+
+	input.Source = filepath.Join(source, "missing")
+	_, err = selectService(tracer, input, localpackages.NewRepository(), nil, &facade.FacadeStub{}, &isDocumentArchive)
+
+	assert.Error(t, err)
+}
+
+// versionListerMock is a package service that also lists the versions of a package
+type versionListerMock struct {
+	serviceMock.Mock
+}
+
+func (ds *versionListerMock) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	args := ds.Called(tracer, packageName)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestResolveVersionConstraint(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &versionListerMock{}
+	mockService.On("ListVersions", mock.Anything, "PVDriver").Return([]string{"2.2.0", "2.3.1", "2.9.0", "3.0.0"}, nil).Once()
+
+	version, err := resolveVersionConstraint(tracer, mockService, "PVDriver", ">=2.3 <3.0")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2.9.0", version)
+	mockService.AssertExpectations(t)
+}
+
+func TestResolveVersionConstraint_NoMatch(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &versionListerMock{}
+	mockService.On("ListVersions", mock.Anything, "PVDriver").Return([]string{"2.2.0", "3.0.0"}, nil).Once()
+
+	_, err := resolveVersionConstraint(tracer, mockService, "PVDriver", ">=2.3 <3.0")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no available version")
+}
+
+func TestResolveVersionConstraint_Unsupported(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	mockService := &serviceMock.Mock{}
+	mockService.On("PackageServiceName").Return(packageservice.PackageServiceName_document).Once()
+
+	_, err := resolveVersionConstraint(tracer, mockService, "PVDriver", ">=2.3 <3.0")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "version constraints are not supported")
+	mockService.AssertExpectations(t)
+}
+
 // Integration tests
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	result, err := ioutil.ReadFile(fileName)
@@ -816,6 +1086,7 @@ func TestExecuteConfigurePackagePlugin_BirdwatcherService(t *testing.T) {
 	plugin := &Plugin{
 		birdwatcherfacade:      &bwFacade,
 		localRepository:        repoMock,
+		packageCache:           cacheCollectMock(),
 		packageServiceSelector: selectService,
 	}
 	plugin.execute(contextMock, buildConfigSimple(pluginInformation), createMockCancelFlag(), createMockIOHandler())
@@ -971,6 +1242,7 @@ func TestExecuteConfigurePackagePlugin_DocumentService(t *testing.T) {
 			plugin := &Plugin{
 				birdwatcherfacade:      &bwFacade,
 				localRepository:        repoMock,
+				packageCache:           cacheCollectMock(),
 				packageServiceSelector: selectService,
 			}
 
@@ -30,6 +30,7 @@ import (
 	installerMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
 	repoMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages/mock"
+	cacheMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	serviceMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
@@ -37,6 +38,12 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+func cacheCollectMock() *cacheMock.MockedCache {
+	mockCache := cacheMock.MockedCache{}
+	mockCache.On("Collect", mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+	return &mockCache
+}
+
 func repoInstallMock(pluginInformation *ConfigurePackagePluginInput, installerMock installer.Installer) *repoMock.MockedRepository {
 	mockRepo := repoMock.MockedRepository{}
 	mockRepo.On("GetInstalledVersion", mock.Anything, mock.Anything).Return("")
@@ -338,6 +345,8 @@ type ConfigurePackageStubs struct {
 	// individual stub functions or interfaces go here with a temp variable for the original version
 	fileSysDepStub fileSysDep
 	fileSysDepOrig fileSysDep
+	hookDepStub    hookDep
+	hookDepOrig    hookDep
 	stubsSet       bool
 }
 
@@ -348,6 +357,10 @@ func (m *ConfigurePackageStubs) Set() {
 		m.fileSysDepOrig = filesysdep
 		filesysdep = m.fileSysDepStub
 	}
+	if m.hookDepStub != nil {
+		m.hookDepOrig = hookdep
+		hookdep = m.hookDepStub
+	}
 	m.stubsSet = true
 }
 
@@ -356,6 +369,9 @@ func (m *ConfigurePackageStubs) Clear() {
 	if m.fileSysDepStub != nil {
 		filesysdep = m.fileSysDepOrig
 	}
+	if m.hookDepStub != nil {
+		hookdep = m.hookDepOrig
+	}
 	m.stubsSet = false
 }
 
@@ -387,3 +403,12 @@ func (m *FileSysDepStub) RemoveAll(path string) error {
 func (m *FileSysDepStub) WriteFile(filename string, content string) error {
 	return m.writeError
 }
+
+type hookDepMock struct {
+	mock.Mock
+}
+
+func (m *hookDepMock) Run(path string, env []string, timeout time.Duration) (output string, err error) {
+	args := m.Called(path, env, timeout)
+	return args.String(0), args.Error(1)
+}
@@ -90,6 +90,7 @@ type Repository interface {
 	RemovePackage(tracer trace.Tracer, packageArn string, version string) error
 	GetInventoryData(log log.T) []model.ApplicationData
 	GetInstaller(tracer trace.Tracer, configuration contracts.Configuration, packageArn string, version string) installer.Installer
+	GetPackageVersionPath(tracer trace.Tracer, packageArn string, version string) string
 
 	LockPackage(tracer trace.Tracer, packageArn string, action string) error
 	UnlockPackage(tracer trace.Tracer, packageArn string)
@@ -114,6 +115,15 @@ func NewRepository() Repository {
 	}
 }
 
+// IsVersionInUse returns true if a version of a package is installed or in the middle of being installed or uninstalled
+func IsVersionInUse(tracer trace.Tracer, repository Repository, packageArn string, version string) bool {
+	if repository.GetInstalledVersion(tracer, packageArn) == version {
+		return true
+	}
+	state, currentVersion := repository.GetInstallState(tracer, packageArn)
+	return currentVersion == version && state != None && state != Uninstalled
+}
+
 // PackageInstallState represents the json structure of the current package state
 type PackageInstallState struct {
 	Name                 string       `json:"name"`
@@ -444,6 +454,11 @@ func (repo *localRepository) getInstallStatePathByDirectoryName(directoryName st
 	return filepath.Join(repo.getPackageRootByDirectoryName(directoryName), "installstate")
 }
 
+// GetPackageVersionPath returns the directory containing the content of the given version of a package
+func (repo *localRepository) GetPackageVersionPath(tracer trace.Tracer, packageArn string, version string) string {
+	return repo.getPackageVersionPath(tracer, packageArn, version)
+}
+
 // getPackageVersionPath is a helper function that builds a path to the directory containing the given version of a package
 func (repo *localRepository) getPackageVersionPath(tracer trace.Tracer, packageArn string, version string) string {
 	return filepath.Join(repo.getPackageRoot(packageArn), normalizeDirectory(version))
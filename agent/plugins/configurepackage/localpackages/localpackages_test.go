@@ -174,6 +174,34 @@ func TestGetInstalledVersionInstalling(t *testing.T) {
 	assert.Equal(t, version, version)
 }
 
+func TestIsVersionInUse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		state    PackageInstallState
+		version  string
+		expected bool
+	}{
+		{"installed version", PackageInstallState{Name: testPackage, Version: "1.0.0", State: Installed}, "1.0.0", true},
+		{"other version", PackageInstallState{Name: testPackage, Version: "1.0.0", State: Installed}, "2.0.0", false},
+		{"installing version", PackageInstallState{Name: testPackage, Version: "2.0.0", State: Installing, LastInstalledVersion: "1.0.0"}, "2.0.0", true},
+		{"previous version while installing", PackageInstallState{Name: testPackage, Version: "2.0.0", State: Installing, LastInstalledVersion: "1.0.0"}, "1.0.0", true},
+		{"uninstalling version", PackageInstallState{Name: testPackage, Version: "1.0.0", State: Uninstalling, LastInstalledVersion: "1.0.0"}, "1.0.0", true},
+		{"uninstalled version", PackageInstallState{Name: testPackage, Version: "1.0.0", State: Uninstalled}, "1.0.0", false},
+		{"no version", PackageInstallState{Name: testPackage, State: None}, "1.0.0", false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			stateContent, _ := jsonutil.Marshal(testCase.state)
+			mockFileSys := MockedFileSys{}
+			mockFileSys.On("Exists", path.Join(testRepoRoot, testPackage, "installstate")).Return(true)
+			mockFileSys.On("ReadFile", path.Join(testRepoRoot, testPackage, "installstate")).Return([]byte(stateContent), nil)
+			repo := localRepository{filesysdep: &mockFileSys, repoRoot: testRepoRoot, lockRoot: testLockRoot}
+
+			assert.Equal(t, testCase.expected, IsVersionInUse(tracerMock, &repo, testPackage, testCase.version))
+		})
+	}
+}
+
 func TestValidatePackage(t *testing.T) {
 	version := "0.0.1"
 	// Setup mock with expectations
@@ -87,6 +87,11 @@ func (repoMock *MockedRepository) GetInstaller(tracer trace.Tracer,
 	return args.Get(0).(installer.Installer)
 }
 
+func (repoMock *MockedRepository) GetPackageVersionPath(tracer trace.Tracer, packageName string, version string) string {
+	args := repoMock.Called(tracer, packageName, version)
+	return args.String(0)
+}
+
 func (repoMock *MockedRepository) ReadManifest(packageName string, packageVersion string) ([]byte, error) {
 	args := repoMock.Called(packageName, packageVersion)
 	return args.Get(0).([]byte), args.Error(1)
@@ -0,0 +1,46 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package packagecache_mock implements the mock for Cache.
+package packagecache_mock
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedCache struct {
+	mock.Mock
+}
+
+func (cacheMock *MockedCache) Get(tracer trace.Tracer, packageArn string, version string) (string, bool) {
+	args := cacheMock.Called(tracer, packageArn, version)
+	return args.String(0), args.Bool(1)
+}
+
+func (cacheMock *MockedCache) Add(tracer trace.Tracer, packageArn string, version string, artifactPath string) error {
+	args := cacheMock.Called(tracer, packageArn, version, artifactPath)
+	return args.Error(0)
+}
+
+func (cacheMock *MockedCache) Collect(tracer trace.Tracer, policy packagecache.Policy, isReferenced packagecache.ReferenceChecker) (int, error) {
+	args := cacheMock.Called(tracer, policy, isReferenced)
+	return args.Int(0), args.Error(1)
+}
+
+func (cacheMock *MockedCache) Purge(tracer trace.Tracer, isReferenced packagecache.ReferenceChecker) (int, error) {
+	args := cacheMock.Called(tracer, isReferenced)
+	return args.Int(0), args.Error(1)
+}
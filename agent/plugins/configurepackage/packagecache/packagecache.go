@@ -0,0 +1,509 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package packagecache implements a content addressed cache for the artifacts downloaded by the ConfigurePackage plugin.
+//
+// Artifacts are stored once per content digest, no matter how many package versions reference them.
+// Artifacts of package versions that are still in use are never collected, the others are kept
+// according to a garbage collection policy so they can be reused without downloading them again.
+package packagecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+const (
+	blobsDirName         = "blobs"
+	stagingDirName       = "staging"
+	indexFileName        = "index.json"
+	lockFileName         = "index.lockfile"
+	lockTimeoutInSeconds = 5 * 60 // 5 minutes
+	lockRetryCount       = 60
+	bytesPerMB           = 1024 * 1024
+	hoursPerDay          = 24
+	staleStagedFileAge   = 24 * time.Hour
+)
+
+// Policy controls which artifacts that are no longer in use are kept by the garbage collection
+type Policy struct {
+	MaxVersionsPerPackage int
+	MaxSizeBytes          int64
+	MaxAge                time.Duration
+}
+
+// PolicyFromConfig builds the garbage collection policy from the agent configuration
+func PolicyFromConfig(config appconfig.PackageCacheCfg) Policy {
+	return Policy{
+		MaxVersionsPerPackage: config.MaxVersionsPerPackage,
+		MaxSizeBytes:          int64(config.MaxSizeMB) * bytesPerMB,
+		MaxAge:                time.Duration(config.MaxAgeDays) * hoursPerDay * time.Hour,
+	}
+}
+
+// ReferenceChecker returns true if the given version of a package is in use and its artifact must be kept
+type ReferenceChecker func(packageArn string, version string) bool
+
+// Cache stores downloaded package artifacts by content
+type Cache interface {
+	Get(tracer trace.Tracer, packageArn string, version string) (artifactPath string, found bool)
+	Add(tracer trace.Tracer, packageArn string, version string, artifactPath string) (err error)
+	Collect(tracer trace.Tracer, policy Policy, isReferenced ReferenceChecker) (removed int, err error)
+	Purge(tracer trace.Tracer, isReferenced ReferenceChecker) (removed int, err error)
+}
+
+// NewCache is the factory method for the package cache with the default location
+func NewCache() Cache {
+	return newCache(appconfig.PackageCacheRoot)
+}
+
+func newCache(root string) *localCache {
+	return &localCache{
+		root:       root,
+		fileLocker: filelock.NewFileLocker(),
+	}
+}
+
+// cacheEntry associates a version of a package to the digest of its artifact
+type cacheEntry struct {
+	PackageArn string    `json:"packagearn"`
+	Version    string    `json:"version"`
+	Digest     string    `json:"digest"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modtime"`
+	LastUsed   time.Time `json:"lastused"`
+}
+
+// matches checks the blob against the size and modification time recorded when it was added,
+// blobs are never modified in place so a difference means the blob was tampered with or is missing
+func (entry *cacheEntry) matches(blobPath string) bool {
+	info, err := os.Stat(blobPath)
+	return err == nil && info.Size() == entry.Size && info.ModTime().Equal(entry.ModTime)
+}
+
+// cacheIndex is the json structure persisted next to the blobs
+type cacheIndex struct {
+	Entries map[string]*cacheEntry `json:"entries"`
+}
+
+type localCache struct {
+	root       string
+	fileLocker filelock.FileLocker
+}
+
+// Prevent concurrent index updates from the same process, the file lock protects against other processes
+var indexLock = &sync.Mutex{}
+
+// Get returns a private copy of the cached artifact for a package version, if it is present and not corrupt
+// The copy is taken while the cache is locked so it cannot be collected while in use, the caller must delete it
+func (c *localCache) Get(tracer trace.Tracer, packageArn string, version string) (artifactPath string, found bool) {
+	getTrace := tracer.BeginSection(fmt.Sprintf("look up cached artifact for %s/%s", packageArn, version))
+	defer getTrace.End()
+
+	// the index is replaced atomically, so a cache miss can be detected without locking
+	key := entryKey(packageArn, version)
+	if _, ok := c.loadIndex().Entries[key]; !ok {
+		getTrace.AppendDebugf("cached artifact found: false")
+		return "", false
+	}
+
+	err := c.updateIndex(func(index *cacheIndex) (bool, error) {
+		entry, ok := index.Entries[key]
+		if !ok {
+			return false, nil
+		}
+		path := c.blobPath(entry.Digest)
+		if !entry.matches(path) {
+			getTrace.AppendInfof("cached artifact %v is missing or corrupt, discarding it", path)
+			delete(index.Entries, key)
+			if !isDigestInUse(index, entry.Digest) {
+				os.Remove(path)
+			}
+			return true, nil
+		}
+		checkoutPath, err := c.checkout(path)
+		if err != nil {
+			return false, err
+		}
+		entry.LastUsed = time.Now()
+		artifactPath, found = checkoutPath, true
+		return true, nil
+	})
+	if err != nil {
+		getTrace.WithError(err)
+		return "", false
+	}
+	getTrace.AppendDebugf("cached artifact found: %v", found)
+	return artifactPath, found
+}
+
+// Add stores a copy of a downloaded artifact in the cache, the caller keeps ownership of artifactPath
+// If an artifact with the same content is already cached, it is shared by both package versions
+func (c *localCache) Add(tracer trace.Tracer, packageArn string, version string, artifactPath string) (err error) {
+	addTrace := tracer.BeginSection(fmt.Sprintf("cache artifact for %s/%s", packageArn, version))
+	defer addTrace.EndWithError(&err)
+
+	var digest string
+	if digest, err = fileDigest(artifactPath); err != nil {
+		return err
+	}
+	if err = fileutil.MakeDirs(filepath.Join(c.root, blobsDirName)); err != nil {
+		return err
+	}
+
+	// copy outside of the lock, blobs are only ever created by renaming a complete copy
+	var stagedPath string
+	if stagedPath, err = c.stage(artifactPath, copyFile); err != nil {
+		return err
+	}
+	defer os.Remove(stagedPath)
+
+	return c.updateIndex(func(index *cacheIndex) (bool, error) {
+		cachedPath := c.blobPath(digest)
+		if fileutil.Exists(cachedPath) {
+			addTrace.AppendDebugf("artifact with digest %v is already cached", digest)
+		} else if err := os.Rename(stagedPath, cachedPath); err != nil {
+			return false, err
+		}
+
+		info, err := os.Stat(cachedPath)
+		if err != nil {
+			return false, err
+		}
+		index.Entries[entryKey(packageArn, version)] = &cacheEntry{
+			PackageArn: packageArn,
+			Version:    version,
+			Digest:     digest,
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			LastUsed:   time.Now(),
+		}
+		return true, nil
+	})
+}
+
+// Collect removes the artifacts that are not in use and fall outside of the policy, returning the number of removed blobs
+func (c *localCache) Collect(tracer trace.Tracer, policy Policy, isReferenced ReferenceChecker) (removed int, err error) {
+	collectTrace := tracer.BeginSection("collect package cache")
+	defer collectTrace.EndWithError(&err)
+
+	err = c.updateIndex(func(index *cacheIndex) (bool, error) {
+		evictByVersionCount(index, policy.MaxVersionsPerPackage, isReferenced)
+		evictByAge(index, policy.MaxAge, isReferenced)
+		c.evictBySize(index, policy.MaxSizeBytes, isReferenced)
+		removed = c.removeUnusedBlobs(tracer, index)
+		c.removeStaleStagedFiles(tracer)
+		return true, nil
+	})
+	collectTrace.AppendInfof("removed %v cached artifacts", removed)
+	return removed, err
+}
+
+// Purge removes every artifact that is not in use, returning the number of removed blobs
+func (c *localCache) Purge(tracer trace.Tracer, isReferenced ReferenceChecker) (removed int, err error) {
+	purgeTrace := tracer.BeginSection("purge package cache")
+	defer purgeTrace.EndWithError(&err)
+
+	err = c.updateIndex(func(index *cacheIndex) (bool, error) {
+		for key, entry := range index.Entries {
+			if !isReferenced(entry.PackageArn, entry.Version) {
+				delete(index.Entries, key)
+			}
+		}
+		removed = c.removeUnusedBlobs(tracer, index)
+		c.removeStaleStagedFiles(tracer)
+		return true, nil
+	})
+	purgeTrace.AppendInfof("removed %v cached artifacts", removed)
+	return removed, err
+}
+
+// evictByVersionCount keeps the most recently used versions of each package
+// Versions in use are always kept and count towards the limit
+func evictByVersionCount(index *cacheIndex, maxVersions int, isReferenced ReferenceChecker) {
+	unreferenced := make(map[string][]*cacheEntry)
+	kept := make(map[string]int)
+	for _, entry := range index.Entries {
+		if isReferenced(entry.PackageArn, entry.Version) {
+			kept[entry.PackageArn]++
+		} else {
+			unreferenced[entry.PackageArn] = append(unreferenced[entry.PackageArn], entry)
+		}
+	}
+	for packageArn, entries := range unreferenced {
+		sort.Sort(byMostRecentlyUsed(entries))
+		for _, entry := range entries {
+			if kept[packageArn] < maxVersions {
+				kept[packageArn]++
+				continue
+			}
+			delete(index.Entries, entryKey(entry.PackageArn, entry.Version))
+		}
+	}
+}
+
+// evictByAge removes versions that are not in use and were not used within maxAge
+func evictByAge(index *cacheIndex, maxAge time.Duration, isReferenced ReferenceChecker) {
+	cutoff := time.Now().Add(-maxAge)
+	for key, entry := range index.Entries {
+		if entry.LastUsed.Before(cutoff) && !isReferenced(entry.PackageArn, entry.Version) {
+			delete(index.Entries, key)
+		}
+	}
+}
+
+// evictBySize removes the least recently used versions that are not in use until the cache fits in maxSize
+func (c *localCache) evictBySize(index *cacheIndex, maxSize int64, isReferenced ReferenceChecker) {
+	sizes := make(map[string]int64)
+	var total int64
+	entries := make([]*cacheEntry, 0, len(index.Entries))
+	for _, entry := range index.Entries {
+		entries = append(entries, entry)
+		if _, ok := sizes[entry.Digest]; ok {
+			continue
+		}
+		if info, err := os.Stat(c.blobPath(entry.Digest)); err == nil {
+			sizes[entry.Digest] = info.Size()
+			total += info.Size()
+		}
+	}
+
+	sort.Sort(sort.Reverse(byMostRecentlyUsed(entries)))
+	for _, entry := range entries {
+		if total <= maxSize {
+			return
+		}
+		if isReferenced(entry.PackageArn, entry.Version) {
+			continue
+		}
+		delete(index.Entries, entryKey(entry.PackageArn, entry.Version))
+		if !isDigestInUse(index, entry.Digest) {
+			total -= sizes[entry.Digest]
+		}
+	}
+}
+
+// removeUnusedBlobs deletes the blobs no longer associated to any package version
+func (c *localCache) removeUnusedBlobs(tracer trace.Tracer, index *cacheIndex) (removed int) {
+	blobs, err := fileutil.GetFileNames(filepath.Join(c.root, blobsDirName))
+	if err != nil {
+		return 0
+	}
+	for _, digest := range blobs {
+		if isDigestInUse(index, digest) {
+			continue
+		}
+		if err := os.Remove(c.blobPath(digest)); err != nil {
+			tracer.CurrentTrace().AppendErrorf("failed to remove cached artifact %v: %v", digest, err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// removeStaleStagedFiles deletes the copies left behind by a process that stopped before cleaning them up
+func (c *localCache) removeStaleStagedFiles(tracer trace.Tracer) {
+	files, err := ioutil.ReadDir(filepath.Join(c.root, stagingDirName))
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-staleStagedFileAge)
+	for _, file := range files {
+		// staged files may be links to blobs, so the time they were staged is taken from their name
+		if staged, ok := stagedFileTime(file.Name()); ok && staged.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.root, stagingDirName, file.Name())); err != nil {
+			tracer.CurrentTrace().AppendErrorf("failed to remove staged artifact %v: %v", file.Name(), err)
+		}
+	}
+}
+
+// checkout links the blob to a new staged file owned by the caller, falling back to a copy
+func (c *localCache) checkout(blobPath string) (string, error) {
+	return c.stage(blobPath, func(src string, dst string) error {
+		if err := os.Link(src, dst); err == nil {
+			return nil
+		}
+		return copyFile(src, dst)
+	})
+}
+
+// stage creates a uniquely named file in the staging directory with the content of the source file
+func (c *localCache) stage(src string, transfer func(src string, dst string) error) (string, error) {
+	stagingDir := filepath.Join(c.root, stagingDirName)
+	if err := fileutil.MakeDirs(stagingDir); err != nil {
+		return "", err
+	}
+	file, err := ioutil.TempFile(stagingDir, fmt.Sprintf("%v-", time.Now().Unix()))
+	if err != nil {
+		return "", err
+	}
+	dst := file.Name()
+	file.Close()
+	if err = os.Remove(dst); err != nil {
+		return "", err
+	}
+	if err = transfer(src, dst); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
+
+// updateIndex loads the index under lock, applies the update and persists the result if the update changed it
+func (c *localCache) updateIndex(update func(index *cacheIndex) (changed bool, err error)) (err error) {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+
+	if err = fileutil.MakeDirs(c.root); err != nil {
+		return err
+	}
+	lockPath := filepath.Join(c.root, lockFileName)
+	ownerId := filelock.GetOwnerIdForProcess()
+	if err = c.lockIndex(lockPath, ownerId); err != nil {
+		return err
+	}
+	defer c.fileLocker.Unlock(lockPath, ownerId)
+
+	index := c.loadIndex()
+	changed, err := update(index)
+	if err != nil || !changed {
+		return err
+	}
+	return c.saveIndex(index)
+}
+
+func (c *localCache) lockIndex(lockPath string, ownerId string) error {
+	for i := 0; i < lockRetryCount; i++ {
+		locked, err := c.fileLocker.Lock(lockPath, ownerId, lockTimeoutInSeconds)
+		if err != nil {
+			return fmt.Errorf("error locking package cache: %v", err)
+		}
+		if locked {
+			return nil
+		}
+	}
+	return fmt.Errorf("package cache is locked by another process")
+}
+
+// loadIndex reads the index, a missing or corrupt index is treated as empty
+func (c *localCache) loadIndex() *cacheIndex {
+	index := &cacheIndex{}
+	if content, err := ioutil.ReadFile(filepath.Join(c.root, indexFileName)); err == nil {
+		json.Unmarshal(content, index)
+	}
+	if index.Entries == nil {
+		index.Entries = make(map[string]*cacheEntry)
+	}
+	return index
+}
+
+// saveIndex writes the index to a temporary file before replacing the previous one
+func (c *localCache) saveIndex(index *cacheIndex) error {
+	content, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	indexPath := filepath.Join(c.root, indexFileName)
+	if err = ioutil.WriteFile(indexPath+".tmp", content, appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	return os.Rename(indexPath+".tmp", indexPath)
+}
+
+func (c *localCache) blobPath(digest string) string {
+	return filepath.Join(c.root, blobsDirName, digest)
+}
+
+// stagedFileTime parses the time a file was staged from its name
+func stagedFileTime(name string) (time.Time, bool) {
+	parts := strings.SplitN(name, "-", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) != 2 || err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+func entryKey(packageArn string, version string) string {
+	return packageArn + "/" + version
+}
+
+func isDigestInUse(index *cacheIndex, digest string) bool {
+	for _, entry := range index.Entries {
+		if entry.Digest == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// fileDigest returns the hex encoded sha256 of the file content
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// copyFile copies the content of the source file to a new destination file
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, appconfig.FileFlagsCreateOrTruncate, appconfig.ReadWriteAccess)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// byMostRecentlyUsed sorts cache entries from the most to the least recently used
+type byMostRecentlyUsed []*cacheEntry
+
+func (a byMostRecentlyUsed) Len() int           { return len(a) }
+func (a byMostRecentlyUsed) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byMostRecentlyUsed) Less(i, j int) bool { return a[i].LastUsed.After(a[j].LastUsed) }
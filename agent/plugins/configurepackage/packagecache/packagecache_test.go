@@ -0,0 +1,268 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packagecache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+type noopLocker struct{}
+
+func (noopLocker) Lock(lockPath string, ownerId string, timeoutSeconds int) (bool, error) {
+	return true, nil
+}
+
+func (noopLocker) Unlock(lockPath string, ownerId string) (bool, error) {
+	return true, nil
+}
+
+func setupCache(t *testing.T) (*localCache, string, func()) {
+	dir, err := ioutil.TempDir("", "packagecache")
+	assert.NoError(t, err)
+	cache := newCache(filepath.Join(dir, "cache"))
+	cache.fileLocker = noopLocker{}
+	return cache, dir, func() { os.RemoveAll(dir) }
+}
+
+func writeArtifact(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func nothingReferenced(packageArn string, version string) bool {
+	return false
+}
+
+func addArtifact(t *testing.T, cache *localCache, dir string, packageArn string, version string, content string, lastUsed time.Time) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	err := cache.Add(tracer, packageArn, version, writeArtifact(t, dir, packageArn+version, content))
+	assert.NoError(t, err)
+	cache.updateIndex(func(index *cacheIndex) (bool, error) {
+		index.Entries[entryKey(packageArn, version)].LastUsed = lastUsed
+		return true, nil
+	})
+}
+
+func TestAddAndGet(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	artifact := writeArtifact(t, dir, "package.zip", "content")
+	err := cache.Add(tracer, "package", "1.0.0", artifact)
+	assert.NoError(t, err)
+	assert.True(t, fileExists(artifact), "the caller keeps ownership of the added artifact")
+
+	path, found := cache.Get(tracer, "package", "1.0.0")
+	assert.True(t, found)
+	assert.NotEqual(t, artifact, path)
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	_, found = cache.Get(tracer, "package", "2.0.0")
+	assert.False(t, found)
+}
+
+func TestAddReplacesChangedContent(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	assert.NoError(t, cache.Add(tracer, "package", "1.0.0", writeArtifact(t, dir, "a.zip", "old")))
+	assert.NoError(t, cache.Add(tracer, "package", "1.0.0", writeArtifact(t, dir, "b.zip", "new")))
+
+	path, found := cache.Get(tracer, "package", "1.0.0")
+	assert.True(t, found)
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestAddDeduplicatesContent(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	assert.NoError(t, cache.Add(tracer, "package", "1.0.0", writeArtifact(t, dir, "a.zip", "content")))
+	assert.NoError(t, cache.Add(tracer, "other", "1.0.0", writeArtifact(t, dir, "b.zip", "content")))
+
+	blobs, _ := ioutil.ReadDir(filepath.Join(cache.root, blobsDirName))
+	assert.Equal(t, 1, len(blobs))
+}
+
+func TestGetDiscardsCorruptArtifact(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	assert.NoError(t, cache.Add(tracer, "package", "1.0.0", writeArtifact(t, dir, "a.zip", "content")))
+	blobs, _ := ioutil.ReadDir(filepath.Join(cache.root, blobsDirName))
+	cachedPath := cache.blobPath(blobs[0].Name())
+	assert.NoError(t, ioutil.WriteFile(cachedPath, []byte("tampered"), 0600))
+
+	_, found := cache.Get(tracer, "package", "1.0.0")
+	assert.False(t, found)
+	assert.False(t, fileExists(cachedPath))
+}
+
+func TestGetMissDoesNotWriteIndex(t *testing.T) {
+	cache, _, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	_, found := cache.Get(tracer, "package", "1.0.0")
+	assert.False(t, found)
+	assert.False(t, fileExists(filepath.Join(cache.root, indexFileName)))
+}
+
+func TestGetArtifactSurvivesPurge(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	assert.NoError(t, cache.Add(tracer, "package", "1.0.0", writeArtifact(t, dir, "a.zip", "content")))
+	path, found := cache.Get(tracer, "package", "1.0.0")
+	assert.True(t, found)
+
+	// another process purges the cache before the artifact is extracted
+	removed, err := cache.Purge(tracer, nothingReferenced)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestCollectRemovesStaleStagedFiles(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+
+	assert.NoError(t, cache.Add(tracer, "package", "1.0.0", writeArtifact(t, dir, "a.zip", "content")))
+	recent, _ := cache.Get(tracer, "package", "1.0.0")
+	stale := filepath.Join(cache.root, stagingDirName, fmt.Sprintf("%v-123", time.Now().Add(-2*staleStagedFileAge).Unix()))
+	assert.NoError(t, ioutil.WriteFile(stale, []byte("content"), 0600))
+
+	_, err := cache.Collect(tracer, Policy{MaxVersionsPerPackage: 5, MaxSizeBytes: 1024, MaxAge: time.Hour * 24}, nothingReferenced)
+	assert.NoError(t, err)
+	assert.False(t, fileExists(stale))
+	assert.True(t, fileExists(recent))
+}
+
+func TestCollectMaxVersions(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+	now := time.Now()
+
+	addArtifact(t, cache, dir, "package", "1.0.0", "v1", now.Add(-3*time.Hour))
+	addArtifact(t, cache, dir, "package", "2.0.0", "v2", now.Add(-2*time.Hour))
+	addArtifact(t, cache, dir, "package", "3.0.0", "v3", now.Add(-1*time.Hour))
+
+	isReferenced := func(packageArn string, version string) bool { return version == "1.0.0" }
+	removed, err := cache.Collect(tracer, Policy{MaxVersionsPerPackage: 2, MaxSizeBytes: 1024, MaxAge: time.Hour * 24}, isReferenced)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, found := cache.Get(tracer, "package", "1.0.0")
+	assert.True(t, found, "referenced version must be kept")
+	_, found = cache.Get(tracer, "package", "2.0.0")
+	assert.False(t, found)
+	_, found = cache.Get(tracer, "package", "3.0.0")
+	assert.True(t, found)
+}
+
+func TestCollectMaxAge(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+	now := time.Now()
+
+	addArtifact(t, cache, dir, "old", "1.0.0", "old", now.Add(-48*time.Hour))
+	addArtifact(t, cache, dir, "new", "1.0.0", "new", now)
+
+	removed, err := cache.Collect(tracer, Policy{MaxVersionsPerPackage: 5, MaxSizeBytes: 1024, MaxAge: time.Hour * 24}, nothingReferenced)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, found := cache.Get(tracer, "new", "1.0.0")
+	assert.True(t, found)
+}
+
+func TestCollectMaxSize(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+	now := time.Now()
+
+	addArtifact(t, cache, dir, "a", "1.0.0", "0123456789", now.Add(-3*time.Hour))
+	addArtifact(t, cache, dir, "b", "1.0.0", "abcdefghij", now.Add(-2*time.Hour))
+	addArtifact(t, cache, dir, "c", "1.0.0", "ABCDEFGHIJ", now.Add(-1*time.Hour))
+
+	removed, err := cache.Collect(tracer, Policy{MaxVersionsPerPackage: 5, MaxSizeBytes: 20, MaxAge: time.Hour * 24}, nothingReferenced)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, found := cache.Get(tracer, "a", "1.0.0")
+	assert.False(t, found, "least recently used artifact must be evicted first")
+}
+
+func TestCollectKeepsSharedBlobReferencedByOtherPackage(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+	now := time.Now()
+
+	addArtifact(t, cache, dir, "old", "1.0.0", "shared", now.Add(-48*time.Hour))
+	addArtifact(t, cache, dir, "new", "1.0.0", "shared", now)
+
+	removed, err := cache.Collect(tracer, Policy{MaxVersionsPerPackage: 5, MaxSizeBytes: 1024, MaxAge: time.Hour * 24}, nothingReferenced)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	_, found := cache.Get(tracer, "new", "1.0.0")
+	assert.True(t, found)
+}
+
+func TestPurge(t *testing.T) {
+	cache, dir, cleanup := setupCache(t)
+	defer cleanup()
+	tracer := trace.NewTracer(log.NewMockLog())
+	now := time.Now()
+
+	addArtifact(t, cache, dir, "installed", "1.0.0", "installed", now)
+	addArtifact(t, cache, dir, "removed", "1.0.0", "removed", now)
+	assert.NoError(t, ioutil.WriteFile(cache.blobPath("stray"), []byte("stray"), 0600))
+
+	isReferenced := func(packageArn string, version string) bool { return packageArn == "installed" }
+	removed, err := cache.Purge(tracer, isReferenced)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	_, found := cache.Get(tracer, "installed", "1.0.0")
+	assert.True(t, found)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -49,10 +49,24 @@ type PackageService interface {
 	ReportResult(tracer trace.Tracer, result PackageResult) error
 }
 
+// DeltaDownloader is implemented by the package services able to download only the files of a package version
+// that differ from the files of another version already present locally
+type DeltaDownloader interface {
+	// DownloadArtifactDelta populates targetDirectory with the content of the package, reusing the unchanged files
+	// found in baseDirectory. It returns false if the package does not support delta downloads.
+	DownloadArtifactDelta(tracer trace.Tracer, packageName string, version string, baseDirectory string, targetDirectory string) (supported bool, err error)
+}
+
+// VersionLister is implemented by the package services able to list all the versions available for a package
+type VersionLister interface {
+	ListVersions(tracer trace.Tracer, packageName string) ([]string, error)
+}
+
 const (
 	PackageServiceName_ssms3       = "ssms3"
 	PackageServiceName_birdwatcher = "birdwatcherUsingBirdwatcherArchive"
 	PackageServiceName_document    = "birdwatcherUsingDocumentArchive"
+	PackageServiceName_local       = "birdwatcherUsingLocalArchive"
 )
 
 // ByTiming implements sort.Interface for []*packageservice.Trace based on the
@@ -0,0 +1,128 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packageservice
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/versionutil"
+)
+
+// versionConstraintOperators are the comparison operators allowed in a version constraint, longest first
+var versionConstraintOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+var constraintVersionPattern = regexp.MustCompile("^[0-9][A-Za-z0-9.+-]*$")
+
+// versionCondition is a single comparison of a version constraint, e.g. >=2.3
+type versionCondition struct {
+	operator string
+	version  string
+}
+
+// VersionConstraint is a set of conditions that a package version must all satisfy, e.g. `>=2.3 <3.0`
+type VersionConstraint struct {
+	conditions []versionCondition
+}
+
+// IsVersionConstraint returns true if the requested version is a constraint rather than an exact version
+func IsVersionConstraint(version string) bool {
+	return strings.ContainsAny(version, "<>=!")
+}
+
+// ParseVersionConstraint parses conditions separated by spaces or commas, each made of an operator and a version
+func ParseVersionConstraint(constraint string) (*VersionConstraint, error) {
+	terms := strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	result := &VersionConstraint{}
+	for _, term := range terms {
+		condition, err := parseVersionCondition(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %v: %v", constraint, err)
+		}
+		result.conditions = append(result.conditions, condition)
+	}
+	return result, nil
+}
+
+// parseVersionCondition parses a single condition like >=2.3
+func parseVersionCondition(term string) (versionCondition, error) {
+	for _, operator := range versionConstraintOperators {
+		if strings.HasPrefix(term, operator) {
+			version := strings.TrimPrefix(term, operator)
+			if !constraintVersionPattern.MatchString(version) {
+				return versionCondition{}, fmt.Errorf("invalid version %v in condition %v", version, term)
+			}
+			return versionCondition{operator: operator, version: version}, nil
+		}
+	}
+	return versionCondition{}, fmt.Errorf("missing comparison operator in condition %v", term)
+}
+
+// Matches returns true if the version satisfies all the conditions of the constraint
+// Versions are compared ignoring insignificant trailing zeros, so 2.3 matches =2.3.0
+func (c *VersionConstraint) Matches(version string) bool {
+	if !constraintVersionPattern.MatchString(version) {
+		return false
+	}
+	for _, condition := range c.conditions {
+		comparison := versionutil.Compare(version, condition.version, false)
+		var satisfied bool
+		switch condition.operator {
+		case ">=":
+			satisfied = comparison >= 0
+		case "<=":
+			satisfied = comparison <= 0
+		case ">":
+			satisfied = comparison > 0
+		case "<":
+			satisfied = comparison < 0
+		case "!=":
+			satisfied = comparison != 0
+		case "=":
+			satisfied = comparison == 0
+		}
+		if !satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// BestMatch returns the highest of the versions satisfying the constraint, or an empty string if none does
+func (c *VersionConstraint) BestMatch(versions []string) string {
+	var best string
+	for _, version := range versions {
+		if c.Matches(version) && (best == "" || versionutil.Compare(version, best, false) > 0) {
+			best = version
+		}
+	}
+	return best
+}
+
+// String returns the constraint in its normalized form
+func (c *VersionConstraint) String() string {
+	terms := make([]string, 0, len(c.conditions))
+	for _, condition := range c.conditions {
+		terms = append(terms, condition.operator+condition.version)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packageservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsVersionConstraint(t *testing.T) {
+	assert.True(t, IsVersionConstraint(">=2.3 <3.0"))
+	assert.True(t, IsVersionConstraint("=1.0"))
+	assert.True(t, IsVersionConstraint("!=1.0"))
+	assert.False(t, IsVersionConstraint("1.0.0"))
+	assert.False(t, IsVersionConstraint("latest"))
+	assert.False(t, IsVersionConstraint(""))
+}
+
+func TestParseVersionConstraint(t *testing.T) {
+	data := []struct {
+		constraint    string
+		normalized    string
+		errorExpected bool
+	}{
+		{">=2.3 <3.0", ">=2.3 <3.0", false},
+		{">=2.3, <3.0", ">=2.3 <3.0", false},
+		{"  =1.2.3  ", "=1.2.3", false},
+		{"!=2.0 >1", "!=2.0 >1", false},
+		{"", "", true},
+		{"2.3", "", true},
+		{">=", "", true},
+		{">=2.3 3.0", "", true},
+		{">=2.3 <3.0$", "", true},
+		{">=latest", "", true},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.constraint, func(t *testing.T) {
+			result, err := ParseVersionConstraint(testdata.constraint)
+
+			if testdata.errorExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testdata.normalized, result.String())
+			}
+		})
+	}
+}
+
+func TestVersionConstraintMatches(t *testing.T) {
+	data := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=2.3 <3.0", "2.3", true},
+		{">=2.3 <3.0", "2.3.0", true},
+		{">=2.3 <3.0", "2.10.1", true},
+		{">=2.3 <3.0", "2.2.9", false},
+		{">=2.3 <3.0", "3.0.0", false},
+		{">2.3", "2.3.0", false},
+		{"<=2.3", "2.3.0", true},
+		{"=2.3", "2.3.0.0", true},
+		{"!=2.3", "2.3.0", false},
+		{"!=2.3", "2.4", true},
+		{">=1.0", "latest", false},
+		{">=1.0", "not a version!", false},
+	}
+
+	for _, testdata := range data {
+		t.Run(testdata.constraint+" "+testdata.version, func(t *testing.T) {
+			constraint, err := ParseVersionConstraint(testdata.constraint)
+			assert.NoError(t, err)
+
+			assert.Equal(t, testdata.expected, constraint.Matches(testdata.version))
+		})
+	}
+}
+
+func TestVersionConstraintBestMatch(t *testing.T) {
+	constraint, err := ParseVersionConstraint(">=2.3 <3.0")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "2.10.0", constraint.BestMatch([]string{"1.0.0", "2.3.0", "2.10.0", "2.9.5", "3.0.0"}))
+	assert.Equal(t, "", constraint.BestMatch([]string{"1.0.0", "3.0.0"}))
+	assert.Equal(t, "", constraint.BestMatch(nil))
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package packagesigning verifies package manifests against signing keys pinned by the administrator.
+//
+// A manifest is signed over its signing payload, which lists the package arn and version,
+// the file selected for each platform, version and architecture and the sha256 checksum of every file and file content.
+// Since every downloaded file is verified against its checksum, a valid manifest signature covers the package files.
+package packagesigning
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
+)
+
+// PinnedKeys returns the public keys pinned in appconfig, packages don't need to be signed when there are none
+var PinnedKeys = func() ([]crypto.PublicKey, error) {
+	appConfig, err := appconfig.Config(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read appconfig: %v", err)
+	}
+	return LoadPublicKeys(appConfig.PackageSigning.PublicKeys)
+}
+
+// LoadPublicKeys reads PEM encoded public keys from files
+func LoadPublicKeys(paths []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package signing key %v: %v", path, err)
+		}
+		key, err := ParsePublicKey(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid package signing key %v: %v", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParsePublicKey parses a PEM encoded PKIX RSA, ECDSA or Ed25519 public key
+func ParsePublicKey(content []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+	return parsePublicKeyBlock(block)
+}
+
+// ParsePublicKeys parses a bundle of PEM encoded PKIX RSA, ECDSA or Ed25519 public keys
+func ParsePublicKeys(content []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := parsePublicKeyBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+	return keys, nil
+}
+
+// parsePublicKeyBlock parses the public key of a PEM block
+func parsePublicKeyBlock(block *pem.Block) (crypto.PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// VerifyManifest checks that the manifest carries a valid signature by one of keys
+func VerifyManifest(keys []crypto.PublicKey, manifest *birdwatcher.Manifest) error {
+	if manifest.Signature == "" {
+		return fmt.Errorf("manifest of %v %v is not signed", manifest.PackageArn, manifest.Version)
+	}
+	payload, err := SigningPayload(manifest)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(manifest.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding in manifest of %v %v: %v", manifest.PackageArn, manifest.Version, err)
+	}
+	if VerifySignature(keys, payload, signature) {
+		return nil
+	}
+	return fmt.Errorf("manifest of %v %v is not signed by a pinned key", manifest.PackageArn, manifest.Version)
+}
+
+// SigningPayload returns the content a manifest signature is computed over
+func SigningPayload(manifest *birdwatcher.Manifest) ([]byte, error) {
+	var packages []string
+	for platform, versions := range manifest.Packages {
+		for version, arches := range versions {
+			for arch, info := range arches {
+				if info != nil {
+					packages = append(packages, fmt.Sprintf("package:%v/%v/%v:%v\n", platform, version, arch, info.FileName))
+				}
+			}
+		}
+	}
+	var files []string
+	for name, info := range manifest.Files {
+		if info == nil || info.Checksums["sha256"] == "" {
+			return nil, fmt.Errorf("file %v of a signed manifest has no sha256 checksum", name)
+		}
+		files = append(files, fmt.Sprintf("file:%v:%v\n", name, strings.ToLower(info.Checksums["sha256"])))
+		for _, content := range info.Contents {
+			if content == nil || content.Checksums["sha256"] == "" {
+				return nil, fmt.Errorf("content of file %v of a signed manifest has no sha256 checksum", name)
+			}
+			files = append(files, fmt.Sprintf("content:%v:%v:%v\n", name, content.Path, strings.ToLower(content.Checksums["sha256"])))
+		}
+	}
+	sort.Strings(packages)
+	sort.Strings(files)
+
+	var payload strings.Builder
+	fmt.Fprintf(&payload, "packageArn:%v\nversion:%v\n", manifest.PackageArn, manifest.Version)
+	payload.WriteString(strings.Join(packages, ""))
+	payload.WriteString(strings.Join(files, ""))
+	return []byte(payload.String()), nil
+}
+
+// VerifySignature checks that signature is a valid signature of payload by one of keys
+func VerifySignature(keys []crypto.PublicKey, payload []byte, signature []byte) bool {
+	for _, key := range keys {
+		if verify(key, payload, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks signature against payload with a RSA PKCS#1 v1.5, ECDSA or Ed25519 key, using sha256 digests
+func verify(key crypto.PublicKey, payload []byte, signature []byte) bool {
+	digest := sha256.Sum256(payload)
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pub, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, payload, signature)
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packagesigning
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func testManifest() *birdwatcher.Manifest {
+	return &birdwatcher.Manifest{
+		SchemaVersion: "2.0",
+		PackageArn:    "arn:aws:ssm:us-east-1:123456789012:package/Test",
+		Version:       "1.0.0",
+		Packages: map[string]map[string]map[string]*birdwatcher.PackageInfo{
+			"amazon":  {"_any": {"x86_64": {FileName: "test-linux.zip"}}},
+			"windows": {"_any": {"_any": {FileName: "test-windows.zip"}}},
+		},
+		Files: map[string]*birdwatcher.FileInfo{
+			"test-linux.zip":   {Checksums: map[string]string{"sha256": "AAAA"}},
+			"test-windows.zip": {Checksums: map[string]string{"sha256": "bbbb"}},
+		},
+	}
+}
+
+func sign(t *testing.T, key crypto.Signer, manifest *birdwatcher.Manifest) {
+	payload, err := SigningPayload(manifest)
+	assert.NoError(t, err)
+	var signature []byte
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		signature, err = key.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(payload)
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	assert.NoError(t, err)
+	manifest.Signature = base64.StdEncoding.EncodeToString(signature)
+}
+
+func testKeys(t *testing.T) []crypto.Signer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return []crypto.Signer{rsaKey, ecdsaKey, ed25519Key}
+}
+
+func TestSigningPayload(t *testing.T) {
+	payload, err := SigningPayload(testManifest())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "packageArn:arn:aws:ssm:us-east-1:123456789012:package/Test\n"+
+		"version:1.0.0\n"+
+		"package:amazon/_any/x86_64:test-linux.zip\n"+
+		"package:windows/_any/_any:test-windows.zip\n"+
+		"file:test-linux.zip:aaaa\n"+
+		"file:test-windows.zip:bbbb\n", string(payload))
+}
+
+func TestSigningPayload_Contents(t *testing.T) {
+	manifest := testManifest()
+	manifest.Files["test-linux.zip"].Contents = []*birdwatcher.ContentInfo{
+		{Path: "install.sh", Checksums: map[string]string{"sha256": "cccc"}},
+	}
+
+	payload, err := SigningPayload(manifest)
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), "content:test-linux.zip:install.sh:cccc\n")
+}
+
+func TestSigningPayload_MissingChecksum(t *testing.T) {
+	manifest := testManifest()
+	manifest.Files["test-linux.zip"].Checksums = map[string]string{"md5": "dddd"}
+
+	_, err := SigningPayload(manifest)
+
+	assert.Error(t, err)
+}
+
+func TestVerifyManifest(t *testing.T) {
+	for _, key := range testKeys(t) {
+		manifest := testManifest()
+		sign(t, key, manifest)
+
+		assert.NoError(t, VerifyManifest([]crypto.PublicKey{key.Public()}, manifest), "%T", key)
+	}
+}
+
+func TestVerifyManifest_AnyPinnedKey(t *testing.T) {
+	keys := testKeys(t)
+	manifest := testManifest()
+	sign(t, keys[1], manifest)
+
+	assert.NoError(t, VerifyManifest([]crypto.PublicKey{keys[0].Public(), keys[1].Public()}, manifest))
+}
+
+func TestVerifyManifest_Unsigned(t *testing.T) {
+	keys := testKeys(t)
+
+	err := VerifyManifest([]crypto.PublicKey{keys[0].Public()}, testManifest())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not signed")
+}
+
+func TestVerifyManifest_UnpinnedKey(t *testing.T) {
+	keys := testKeys(t)
+	manifest := testManifest()
+	sign(t, keys[0], manifest)
+
+	err := VerifyManifest([]crypto.PublicKey{keys[1].Public(), keys[2].Public()}, manifest)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not signed by a pinned key")
+}
+
+func TestVerifyManifest_Tampered(t *testing.T) {
+	keys := testKeys(t)
+	manifest := testManifest()
+	sign(t, keys[0], manifest)
+	manifest.Files["test-linux.zip"].Checksums["sha256"] = "eeee"
+
+	assert.Error(t, VerifyManifest([]crypto.PublicKey{keys[0].Public()}, manifest))
+}
+
+func TestLoadPublicKeys(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "packagesigning")
+	defer os.RemoveAll(tmpDir)
+	var paths []string
+	for i, key := range testKeys(t) {
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		assert.NoError(t, err)
+		path := filepath.Join(tmpDir, string(rune('a'+i))+".pem")
+		ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+		paths = append(paths, path)
+	}
+
+	keys, err := LoadPublicKeys(paths)
+
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
+func TestLoadPublicKeys_Invalid(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "packagesigning")
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "invalid.pem")
+	ioutil.WriteFile(path, []byte("not a key"), 0600)
+
+	_, err := LoadPublicKeys([]string{path})
+	assert.Error(t, err)
+
+	_, err = LoadPublicKeys([]string{filepath.Join(tmpDir, "missing.pem")})
+	assert.Error(t, err)
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	var bundle []byte
+	for _, key := range testKeys(t) {
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		assert.NoError(t, err)
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+
+	keys, err := ParsePublicKeys(bundle)
+
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
+func TestParsePublicKeys_Empty(t *testing.T) {
+	_, err := ParsePublicKeys([]byte("not a key"))
+
+	assert.Error(t, err)
+}
+
+func TestVerifySignature(t *testing.T) {
+	keys := testKeys(t)
+	payload := []byte("payload")
+	signature, err := keys[2].Sign(rand.Reader, payload, crypto.Hash(0))
+	assert.NoError(t, err)
+
+	assert.True(t, VerifySignature([]crypto.PublicKey{keys[0].Public(), keys[2].Public()}, payload, signature))
+	assert.False(t, VerifySignature([]crypto.PublicKey{keys[0].Public()}, payload, signature))
+	assert.False(t, VerifySignature([]crypto.PublicKey{keys[2].Public()}, []byte("tampered"), signature))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/envdetect"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/aws/amazon-ssm-agent/agent/privsep"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 )
 
@@ -167,7 +168,13 @@ func (inst *Installer) readShAction(context context.T, action *Action, workingDi
 		runCommand = append(runCommand, fmt.Sprintf("export %v=%v", k, v))
 	}
 
-	runCommand = append(runCommand, fmt.Sprintf("sh %v.sh", action.actionName))
+	// the unprivileged agent installs the packages through the helper, which runs the script as root
+	if privsep.Required() {
+		runCommand = append(runCommand, fmt.Sprintf("%v %v %v %v.sh", executers.QuoteShString(privsep.HelperPath()),
+			privsep.OpInstallPackage, executers.QuoteShString(workingDir), action.actionName))
+	} else {
+		runCommand = append(runCommand, fmt.Sprintf("sh %v.sh", action.actionName))
+	}
 
 	return inst.readScriptAction(action, workingDir, orchestrationDir, "runShellScript", runCommand)
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagesigning"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
@@ -145,7 +146,31 @@ func (ds *PackageService) DownloadManifest(tracer trace.Tracer, packageName stri
 	return packageName, targetVersion, isSameAsCache, err
 }
 
+// ListVersions returns the versions of a given package available in S3 for this platform/arch
+func (ds *PackageService) ListVersions(tracer trace.Tracer, packageName string) ([]string, error) {
+	logger := tracer.CurrentTrace().Logger
+	amazonS3URL := s3util.ParseAmazonS3URL(logger, getS3Url(ds.packageURL, packageName))
+
+	versiontrace := tracer.BeginSection(fmt.Sprintf("listing versions of %v from %v", packageName, amazonS3URL.String()))
+	folders, err := networkdep.ListS3Folders(logger, amazonS3URL)
+	if err != nil {
+		versiontrace.WithError(err).End()
+		return nil, err
+	}
+
+	versiontrace.AppendInfof("versions: %v", folders).End()
+	return folders, nil
+}
+
 func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
+	// packages of this service have no manifest which could carry a signature
+	keys, err := packagesigning.PinnedKeys()
+	if err != nil {
+		return "", err
+	}
+	if len(keys) > 0 {
+		return "", fmt.Errorf("package %v can't be verified with the pinned signing keys, it has no signed manifest", packageName)
+	}
 	s3Location := getS3Location(packageName, version, ds.packageURL)
 	return downloadPackageFromS3(tracer, s3Location)
 }
@@ -158,6 +158,22 @@ func TestDownloadManifestWithLatest(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestListVersions(t *testing.T) {
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	mockObj := new(SSMS3Mock)
+	mockObj.On("ListS3Folders", mock.Anything, mock.Anything).Return([]string{"1.0.0", "2.0.0"}, nil)
+
+	networkdep = mockObj
+
+	ds := &PackageService{packageURL: "https://abc.s3.mock-region.amazonaws.com/"}
+	versions, err := ds.ListVersions(tracer, "packageName")
+
+	assert.Equal(t, []string{"1.0.0", "2.0.0"}, versions)
+	assert.NoError(t, err)
+}
+
 func TestDownloadManifestWithError(t *testing.T) {
 	tracer := trace.NewTracer(log.NewMockLog())
 	tracer.BeginSection("test segment root")
@@ -235,11 +235,11 @@ func (t *Trace) End() error {
 // EndWithError just combines two commonly used methods to be able to use it in
 // combination with defer
 //
-// func asdf(tracer Tracer) {
-//		var err error
-//		defer tracer.BeginSection("testtracemsg").EndWithError(err)
-//		...
-//	}
+//	func asdf(tracer Tracer) {
+//			var err error
+//			defer tracer.BeginSection("testtracemsg").EndWithError(err)
+//			...
+//		}
 func (t *Trace) EndWithError(err *error) *Trace {
 	t.WithError(*err)
 	t.End()
@@ -19,6 +19,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/diskguard"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
@@ -147,6 +148,12 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 		return
 	}
 
+	if err := diskguard.Check(destinationPath); err != nil {
+		output.SetExitCode(diskguard.ExitCode)
+		output.MarkAsFailed(err)
+		return
+	}
+
 	var result *remoteresource.DownloadResult
 	log.Debug("Downloading resource")
 	if err, result = remoteResource.DownloadRemoteResource(log, p.filesys, destinationPath); err != nil {
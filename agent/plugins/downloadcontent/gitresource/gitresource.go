@@ -96,7 +96,7 @@ func (git *GitResource) DownloadRemoteResource(log log.T, filesys filemanager.Fi
 	return nil, result
 }
 
-//download pulls down either the file or directory specified and stores it on disk
+// download pulls down either the file or directory specified and stores it on disk
 func (git *GitResource) download(log log.T, filesys filemanager.FileSystem, info GitInfo, destinationDir string, isDirTypeDownload bool, result *remoteresource.DownloadResult) (err error) {
 
 	opt, err := git.client.ParseGetOptions(log, info.GetOptions)
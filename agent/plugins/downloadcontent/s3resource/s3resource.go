@@ -105,7 +105,12 @@ func (s3 *S3Resource) DownloadRemoteResource(log log.T, filesys filemanager.File
 
 	s3.s3Object.Region = s3util.GetBucketRegion(log, s3.s3Object.Bucket, s3util.HttpProviderImpl{})
 	// Create an object for the source URL. This can be used to list the objects in the folder
-	if folders, err = dep.ListS3Directory(log, s3.s3Object); err != nil {
+	if folders, err = dep.ListS3Directory(log, s3.s3Object); err == artifact.ErrMirrorListing && s3.s3Object.Key != "" && !isPathType(s3.s3Object.Key) {
+		// a mirror can't be listed, the path is downloaded as a single file
+		log.Debugf("Downloading %v as a file, downloads are redirected to a mirror", s3.s3Object)
+		folders, err = nil, nil
+	}
+	if err != nil {
 		return err, nil
 	}
 	if len(folders) == 0 {
@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"path/filepath"
 	"strings"
@@ -167,6 +168,60 @@ func TestS3Resource_Download(t *testing.T) {
 	assert.Equal(t, "destination/file.rb", result.Files[0])
 }
 
+func TestS3Resource_DownloadFromMirror(t *testing.T) {
+	depMock := new(s3DepMock)
+	locationInfo := `{
+		"path" : "https://s3.amazonaws.com/my-bucket/mydummyfolder/file.rb"
+	}`
+	fileMock := filemock.FileSystemMock{}
+
+	fileMock.On("IsDirectory", "destination").Return(true)
+	fileMock.On("Exists", "destination").Return(true)
+	resource, _ := NewS3Resource(logMock, locationInfo)
+
+	input := artifact.DownloadInput{
+		DestinationDirectory: "destination",
+		SourceURL:            "https://s3.amazonaws.com/my-bucket/mydummyfolder/file.rb",
+	}
+	output := artifact.DownloadOutput{
+		LocalFilePath: input.DestinationDirectory,
+	}
+	var folders []string
+	depMock.On("Download", logMock, input).Return(output, nil)
+	depMock.On("ListS3Directory", logMock, mock.Anything).Return(folders, artifact.ErrMirrorListing)
+
+	fileMock.On("MoveAndRenameFile", ".", "destination", ".", "file.rb").Return(true, nil)
+
+	dep = depMock
+	err, result := resource.DownloadRemoteResource(logMock, fileMock, "destination")
+
+	// the file is downloaded without listing its folder
+	assert.NoError(t, err)
+	depMock.AssertExpectations(t)
+	assert.NotNil(t, result)
+	assert.Equal(t, []string{"destination/file.rb"}, result.Files)
+}
+
+func TestS3Resource_DownloadDirectoryFromMirror(t *testing.T) {
+	depMock := new(s3DepMock)
+	locationInfo := `{
+		"path" : "https://s3.amazonaws.com/my-bucket/foldername/"
+	}`
+	fileMock := filemock.FileSystemMock{}
+	resource, _ := NewS3Resource(logMock, locationInfo)
+
+	var folders []string
+	depMock.On("ListS3Directory", logMock, mock.Anything).Return(folders, artifact.ErrMirrorListing)
+
+	dep = depMock
+	err, _ := resource.DownloadRemoteResource(logMock, fileMock, "destination")
+
+	// a folder can't be downloaded without listing it
+	assert.Equal(t, artifact.ErrMirrorListing, err)
+	depMock.AssertExpectations(t)
+	depMock.AssertNotCalled(t, "Download", mock.Anything, mock.Anything)
+}
+
 func TestS3Resource_DownloadDirectory(t *testing.T) {
 	depMock := new(s3DepMock)
 	locationInfo := `{
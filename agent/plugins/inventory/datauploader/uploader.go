@@ -15,8 +15,6 @@
 package datauploader
 
 import (
-	"crypto/md5"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
@@ -26,9 +24,13 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -90,6 +92,9 @@ func NewInventoryUploader(context context.T) (*InventoryUploader, error) {
 	}
 	sess := session.New(cfg)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appCfg.Agent.Name, appCfg.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
 
 	uploader.ssm = ssm.New(sess)
 
@@ -167,9 +172,7 @@ func (u *InventoryUploader) updateContentHash(context context.T, items []*ssm.In
 }
 
 func calculateCheckSum(data []byte) (checkSum string) {
-	sum := md5.Sum(data)
-	checkSum = base64.StdEncoding.EncodeToString(sum[:])
-	return
+	return fips.ContentHash(data)
 }
 
 // ConvertToSsmInventoryItems converts given array of inventory.Item into an array of *ssm.InventoryItem. It returns 2 such arrays - one is optimized array
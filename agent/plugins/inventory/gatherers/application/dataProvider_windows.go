@@ -13,6 +13,7 @@
 
 // Package application contains application gatherer.
 
+//go:build windows
 // +build windows
 
 package application
@@ -35,6 +36,7 @@ const (
 	KeywordFor64BitArchitectureReportedByPowershell = "64"
 	KeywordFor32BitArchitectureReportedByPowershell = "32"
 	Architecture64BitReportedByGoRuntime            = "amd64"
+	ArchitectureArm64BitReportedByGoRuntime         = "arm64"
 
 	ConvertGuidToCompressedGuidCmd = `function Convert-GuidToCompressedGuid {
 						[CmdletBinding()]
@@ -177,7 +179,7 @@ func collectPlatformDependentApplicationData(context context.T) []model.Applicat
 
 	if strings.Contains(osArch, KeywordFor32BitArchitectureReportedByPowershell) {
 		//os architecture is 32 bit
-		if exeArch != Architecture64BitReportedByGoRuntime {
+		if !is64BitExe(exeArch) {
 			//exe architecture is also 32 bit
 			//since both exe & os are 32 bit - we need to detect only 32 bit apps
 			cmd = ConvertGuidToCompressedGuidCmd + ArgsToReadRegistryFromProducts + ArgsToReadRegistryFromWindowsCurrentVersionUninstall
@@ -188,7 +190,7 @@ func collectPlatformDependentApplicationData(context context.T) []model.Applicat
 		}
 	} else if strings.Contains(osArch, KeywordFor64BitArchitectureReportedByPowershell) {
 		//os architecture is 64 bit
-		if exeArch == Architecture64BitReportedByGoRuntime {
+		if is64BitExe(exeArch) {
 			//both exe & os architecture is 64 bit
 
 			//detecting 32 bit apps by querying Wow6432Node path in registry
@@ -221,6 +223,11 @@ func collectPlatformDependentApplicationData(context context.T) []model.Applicat
 	return data
 }
 
+// is64BitExe returns true if the go runtime architecture of the agent is a 64 bit one
+func is64BitExe(exeArch string) bool {
+	return exeArch == Architecture64BitReportedByGoRuntime || exeArch == ArchitectureArm64BitReportedByGoRuntime
+}
+
 // detectOSArch detects OS architecture; decouple for unit test
 var detectOSArch = detectOSArchFun
 
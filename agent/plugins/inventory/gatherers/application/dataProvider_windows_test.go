@@ -13,6 +13,7 @@
 
 // Package application contains a application gatherer.
 
+//go:build windows
 // +build windows
 
 package application
@@ -215,3 +216,9 @@ func TestCollectAndMergePackages(t *testing.T) {
 		assert.True(t, 2*len(sampleDataSetsParsed[i])+1 <= len(data))
 	}
 }
+
+func TestIs64BitExe(t *testing.T) {
+	assert.True(t, is64BitExe("amd64"))
+	assert.True(t, is64BitExe("arm64"))
+	assert.False(t, is64BitExe("386"))
+}
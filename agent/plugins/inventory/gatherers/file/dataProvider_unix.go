@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package file contains file gatherer.
@@ -33,7 +34,7 @@ func expand(str string, mapping func(string) string) (newStr string, err error)
 	return
 }
 
-//getMetaData gets metadata for the specified file paths
+// getMetaData gets metadata for the specified file paths
 func getMetaData(log log.T, paths []string) (fileInfo []model.FileData, err error) {
 	for _, p := range paths {
 		fi, err := os.Stat(p)
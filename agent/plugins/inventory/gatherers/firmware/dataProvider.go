@@ -0,0 +1,31 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firmware
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// firmwareTypeUEFI is reported when the machine booted through UEFI firmware
+	firmwareTypeUEFI = "UEFI"
+	// firmwareTypeBIOS is reported when the machine booted through legacy BIOS
+	firmwareTypeBIOS = "BIOS"
+)
+
+// CollectFirmwareData collects firmware, secure boot and TPM data from the system using platform specific queries.
+func CollectFirmwareData(context context.T) []model.FirmwareData {
+	return collectPlatformDependentFirmwareData(context)
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package firmware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	dmiDir              = "class/dmi/id"
+	efiDir              = "firmware/efi"
+	secureBootVariable  = "firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+	tpmDir              = "class/tpm/tpm0"
+	tpmVersionMajorFile = "class/tpm/tpm0/tpm_version_major"
+	tpmCapsFile         = "class/tpm/tpm0/caps"
+	tpmResourceManager  = "tpmrm0"
+	tcgVersionKey       = "TCG version"
+)
+
+// sysRoot and devRoot are decoupled for easy testability
+var sysRoot = "/sys"
+var devRoot = "/dev"
+
+// collectPlatformDependentFirmwareData collects data from sysfs.
+func collectPlatformDependentFirmwareData(context context.T) (data []model.FirmwareData) {
+	log := context.Log()
+	log.Infof("Getting %v data", GathererName)
+
+	if !pathExists(filepath.Join(sysRoot, dmiDir)) {
+		log.Infof("DMI information is not exposed at %v, no firmware data to return", filepath.Join(sysRoot, dmiDir))
+		return
+	}
+
+	item := model.FirmwareData{
+		BIOSVendor:         readDmiField("bios_vendor"),
+		BIOSVersion:        readDmiField("bios_version"),
+		BIOSReleaseDate:    readDmiField("bios_date"),
+		SystemManufacturer: readDmiField("sys_vendor"),
+		SystemProductName:  readDmiField("product_name"),
+		FirmwareType:       firmwareTypeBIOS,
+		SecureBootEnabled:  boolToStr(false),
+		TPMPresent:         boolToStr(false),
+	}
+
+	if pathExists(filepath.Join(sysRoot, efiDir)) {
+		item.FirmwareType = firmwareTypeUEFI
+		item.SecureBootEnabled = boolToStr(isSecureBootEnabled())
+	}
+
+	if pathExists(filepath.Join(sysRoot, tpmDir)) {
+		item.TPMPresent = boolToStr(true)
+		item.TPMVersion = getTpmVersion()
+	}
+
+	data = append(data, item)
+	str, _ := json.Marshal(data)
+	log.Debugf("%v gathered: %v", GathererName, string(str))
+	return
+}
+
+// readDmiField returns the trimmed content of a DMI attribute, or empty string if it can't be read
+func readDmiField(name string) string {
+	content, err := ioutil.ReadFile(filepath.Join(sysRoot, dmiDir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// isSecureBootEnabled reads the SecureBoot efi variable, which is made of 4 bytes of attributes followed by
+// a single byte holding 1 if secure boot is enforced
+func isSecureBootEnabled() bool {
+	content, err := ioutil.ReadFile(filepath.Join(sysRoot, secureBootVariable))
+	if err != nil || len(content) < 5 {
+		return false
+	}
+	return content[len(content)-1] == 1
+}
+
+// getTpmVersion returns the TPM specification version, preferring the value exposed by the kernel, then the
+// presence of the TPM 2.0 resource manager device and finally the capabilities exposed by TPM 1.2 drivers.
+// An empty string is returned when the version can't be determined.
+func getTpmVersion() string {
+	if content, err := ioutil.ReadFile(filepath.Join(sysRoot, tpmVersionMajorFile)); err == nil {
+		switch strings.TrimSpace(string(content)) {
+		case "2":
+			return "2.0"
+		case "1":
+			return "1.2"
+		}
+	}
+	if pathExists(filepath.Join(devRoot, tpmResourceManager)) {
+		return "2.0"
+	}
+	return readTcgVersion()
+}
+
+// readTcgVersion parses the "TCG version" line of the capabilities file exposed by TPM 1.x drivers
+func readTcgVersion() string {
+	content, err := ioutil.ReadFile(filepath.Join(sysRoot, tpmCapsFile))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if parts := strings.SplitN(line, ":", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == tcgVersionKey {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func boolToStr(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package firmware
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func createFakeFile(t *testing.T, root, name string, content []byte) {
+	path := filepath.Join(root, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, content, 0644))
+}
+
+func setupFakeRoots(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "firmware")
+	assert.NoError(t, err)
+	sysRoot = filepath.Join(dir, "sys")
+	devRoot = filepath.Join(dir, "dev")
+	createFakeFile(t, sysRoot, "class/dmi/id/bios_vendor", []byte("Amazon EC2\n"))
+	createFakeFile(t, sysRoot, "class/dmi/id/bios_version", []byte("1.0\n"))
+	createFakeFile(t, sysRoot, "class/dmi/id/bios_date", []byte("10/16/2017\n"))
+	createFakeFile(t, sysRoot, "class/dmi/id/sys_vendor", []byte("Amazon EC2\n"))
+	createFakeFile(t, sysRoot, "class/dmi/id/product_name", []byte("m5.large\n"))
+	return dir, func() {
+		sysRoot = "/sys"
+		devRoot = "/dev"
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCollectFirmwareDataLegacyBios(t *testing.T) {
+	_, cleanup := setupFakeRoots(t)
+	defer cleanup()
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, []model.FirmwareData{
+		{
+			BIOSVendor:         "Amazon EC2",
+			BIOSVersion:        "1.0",
+			BIOSReleaseDate:    "10/16/2017",
+			SystemManufacturer: "Amazon EC2",
+			SystemProductName:  "m5.large",
+			FirmwareType:       "BIOS",
+			SecureBootEnabled:  "false",
+			TPMPresent:         "false",
+		},
+	}, data)
+}
+
+func TestCollectFirmwareDataUefiSecureBootAndTpm(t *testing.T) {
+	_, cleanup := setupFakeRoots(t)
+	defer cleanup()
+	createFakeFile(t, sysRoot, secureBootVariable, []byte{0x06, 0x00, 0x00, 0x00, 0x01})
+	createFakeFile(t, sysRoot, tpmVersionMajorFile, []byte("2\n"))
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "UEFI", data[0].FirmwareType)
+	assert.Equal(t, "true", data[0].SecureBootEnabled)
+	assert.Equal(t, "true", data[0].TPMPresent)
+	assert.Equal(t, "2.0", data[0].TPMVersion)
+}
+
+func TestCollectFirmwareDataSecureBootDisabled(t *testing.T) {
+	_, cleanup := setupFakeRoots(t)
+	defer cleanup()
+	createFakeFile(t, sysRoot, secureBootVariable, []byte{0x06, 0x00, 0x00, 0x00, 0x00})
+	createFakeFile(t, sysRoot, tpmCapsFile, []byte("Manufacturer: 0x49465800\nTCG version: 1.2\nFirmware version: 6.40\n"))
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "UEFI", data[0].FirmwareType)
+	assert.Equal(t, "false", data[0].SecureBootEnabled)
+	assert.Equal(t, "1.2", data[0].TPMVersion)
+}
+
+func TestCollectFirmwareDataTpmResourceManager(t *testing.T) {
+	_, cleanup := setupFakeRoots(t)
+	defer cleanup()
+	assert.NoError(t, os.MkdirAll(filepath.Join(sysRoot, tpmDir), 0755))
+	createFakeFile(t, devRoot, tpmResourceManager, []byte{})
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, "2.0", data[0].TPMVersion)
+}
+
+func TestCollectFirmwareDataUnknownTpmVersion(t *testing.T) {
+	_, cleanup := setupFakeRoots(t)
+	defer cleanup()
+	assert.NoError(t, os.MkdirAll(filepath.Join(sysRoot, tpmDir), 0755))
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, "true", data[0].TPMPresent)
+	assert.Empty(t, data[0].TPMVersion, "the version must not be guessed")
+}
+
+func TestCollectFirmwareDataNoDmi(t *testing.T) {
+	dir, cleanup := setupFakeRoots(t)
+	defer cleanup()
+	sysRoot = filepath.Join(dir, "missing")
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Empty(t, data)
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package firmware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const (
+	PowershellCmd = "powershell"
+)
+
+var (
+	startMarker        = "<start" + randomString(8) + ">"
+	endMarker          = "<end" + randomString(8) + ">"
+	FirmwareInfoScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$bios = Get-WmiObject -Class Win32_BIOS
+$system = Get-WmiObject -Class Win32_ComputerSystem
+$FirmwareType = "BIOS"
+$SecureBoot = "false"
+try {
+    $SecureBoot = "$(Confirm-SecureBootUEFI -ErrorAction Stop)".ToLower()
+    $FirmwareType = "UEFI"
+} catch [System.PlatformNotSupportedException] {
+} catch {
+    $FirmwareType = "UEFI"
+}
+$TPMPresent = "false"
+$TPMVersion = ""
+$tpm = Get-WmiObject -Namespace root\cimv2\security\microsofttpm -Class Win32_Tpm -ErrorAction SilentlyContinue
+if ($tpm) {
+    $TPMPresent = "true"
+    $TPMVersion = @($tpm)[0].SpecVersion.Split(",")[0].Trim()
+}
+$BIOSDate = ""
+if ($bios.ReleaseDate) {
+    $BIOSDate = [System.Management.ManagementDateTimeConverter]::ToDateTime($bios.ReleaseDate).ToString("MM/dd/yyyy")
+}
+$BIOSVendor = $bios.Manufacturer
+$BIOSVersion = $bios.SMBIOSBIOSVersion
+$SystemManufacturer = $system.Manufacturer
+$SystemProductName = $system.Model
+[Console]::WriteLine(@"
+{"BIOSVendor":"` + mark(`$BIOSVendor`) + `","BIOSVersion":"` + mark(`$BIOSVersion`) + `","BIOSReleaseDate":"$BIOSDate","SystemManufacturer":"` + mark(`$SystemManufacturer`) + `","SystemProductName":"` + mark(`$SystemProductName`) + `","FirmwareType":"$FirmwareType","SecureBootEnabled":"$SecureBoot","TPMPresent":"$TPMPresent","TPMVersion":"` + mark(`$TPMVersion`) + `"}
+"@)
+`
+)
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+// mark surrounds free text fields with markers so they can be escaped before the output is parsed as json
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+// decoupling exec.Command for easy testability
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).CombinedOutput()
+}
+
+// collectPlatformDependentFirmwareData collects data from the system.
+func collectPlatformDependentFirmwareData(context context.T) (data []model.FirmwareData) {
+	log := context.Log()
+	log.Infof("Getting %v data", GathererName)
+
+	var firmwareData model.FirmwareData
+	output, err := cmdExecutor(PowershellCmd, FirmwareInfoScript)
+	if err != nil {
+		log.Errorf("Failed to execute command : %v; error: %v", FirmwareInfoScript, err.Error())
+		log.Debugf("Command Stderr: %v", string(output))
+		return
+	}
+	log.Debugf("Command output before clean up: %v", string(output))
+
+	cleanOutput, err := pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	log.Debugf("Command output: %v", cleanOutput)
+
+	if err = json.Unmarshal([]byte(cleanOutput), &firmwareData); err != nil {
+		err = fmt.Errorf("Unable to parse command output - %v", err.Error())
+		log.Error(err.Error())
+		log.Infof("Error parsing command output - no data to return")
+		return
+	}
+
+	data = append(data, firmwareData)
+	return
+}
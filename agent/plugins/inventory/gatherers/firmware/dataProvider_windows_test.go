@@ -0,0 +1,87 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package firmware
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func createMockExecutor(stdout string) func(string, ...string) ([]byte, error) {
+	return func(string, ...string) ([]byte, error) {
+		return []byte(stdout), nil
+	}
+}
+
+func mockExecutorWithError(command string, args ...string) ([]byte, error) {
+	return []byte("error"), fmt.Errorf("Random Error")
+}
+
+func TestCollectPlatformDependentFirmwareData(t *testing.T) {
+	defer func() { cmdExecutor = executeCommand }()
+	cmdExecutor = createMockExecutor(`{"BIOSVendor":"` + mark(`Amazon EC2`) + `","BIOSVersion":"` + mark(`1.0`) + `",` +
+		`"BIOSReleaseDate":"10/16/2017","SystemManufacturer":"` + mark(`Amazon EC2`) + `","SystemProductName":"` + mark(`m5.large`) + `",` +
+		`"FirmwareType":"UEFI","SecureBootEnabled":"true","TPMPresent":"true","TPMVersion":"` + mark(`2.0`) + `"}` + "\r\n")
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, []model.FirmwareData{
+		{
+			BIOSVendor:         "Amazon EC2",
+			BIOSVersion:        "1.0",
+			BIOSReleaseDate:    "10/16/2017",
+			SystemManufacturer: "Amazon EC2",
+			SystemProductName:  "m5.large",
+			FirmwareType:       "UEFI",
+			SecureBootEnabled:  "true",
+			TPMPresent:         "true",
+			TPMVersion:         "2.0",
+		},
+	}, data)
+}
+
+func TestCollectPlatformDependentFirmwareDataEscapesFreeText(t *testing.T) {
+	defer func() { cmdExecutor = executeCommand }()
+	cmdExecutor = createMockExecutor(`{"BIOSVendor":"` + mark(`Vendor "Quoted"`) + `","BIOSVersion":"` + mark(`V1\2`) + `",` +
+		`"BIOSReleaseDate":"","SystemManufacturer":"` + mark(`Maker`) + `","SystemProductName":"` + mark(`Model`) + `",` +
+		`"FirmwareType":"BIOS","SecureBootEnabled":"false","TPMPresent":"false","TPMVersion":"` + mark(``) + `"}`)
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, `Vendor "Quoted"`, data[0].BIOSVendor)
+	assert.Equal(t, `V1\2`, data[0].BIOSVersion)
+	assert.Empty(t, data[0].TPMVersion)
+}
+
+func TestCollectPlatformDependentFirmwareDataWithError(t *testing.T) {
+	defer func() { cmdExecutor = executeCommand }()
+	cmdExecutor = mockExecutorWithError
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Empty(t, data)
+}
+
+func TestCollectPlatformDependentFirmwareDataInvalidOutput(t *testing.T) {
+	defer func() { cmdExecutor = executeCommand }()
+	cmdExecutor = createMockExecutor("not json")
+
+	data := collectPlatformDependentFirmwareData(context.NewMockDefault())
+	assert.Empty(t, data)
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package firmware
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func DataGenerator(context context.T) []model.FirmwareData {
+	return []model.FirmwareData{
+		{
+			BIOSVendor:         "Amazon EC2",
+			BIOSVersion:        "1.0",
+			BIOSReleaseDate:    "10/16/2017",
+			SystemManufacturer: "Amazon EC2",
+			SystemProductName:  "m5.large",
+			FirmwareType:       "UEFI",
+			SecureBootEnabled:  "true",
+			TPMPresent:         "true",
+			TPMVersion:         "2.0",
+		},
+	}
+}
+
+func TestGatherer(t *testing.T) {
+	c := context.NewMockDefault()
+	g := Gatherer(c)
+	collectData = DataGenerator
+	items, err := g.Run(c, model.Config{})
+	assert.Nil(t, err, "Unexpected error thrown")
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, items[0].Name, g.Name())
+	assert.Equal(t, "Custom:FirmwareInformation", items[0].Name)
+	assert.Equal(t, items[0].SchemaVersion, SchemaVersion)
+	assert.Equal(t, items[0].Content, DataGenerator(c))
+	assert.NotNil(t, items[0].CaptureTime)
+}
@@ -15,14 +15,18 @@
 package gatherers
 
 import (
+	"sync"
+
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/firmware"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/performancecounters"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
@@ -48,24 +52,64 @@ type SupportedGatherer map[string]T
 // InstalledGatherer is a map of gatherers of all platforms
 type InstalledGatherer map[string]T
 
-// InitializeGatherers collects supported and installed gatherers
+// gathererConstructors creates the gatherers of all platforms
+var gathererConstructors = map[string]func(context context.T) T{
+	application.GathererName:                 func(context context.T) T { return application.Gatherer(context) },
+	awscomponent.GathererName:                func(context context.T) T { return awscomponent.Gatherer(context) },
+	custom.GathererName:                      func(context context.T) T { return custom.Gatherer(context) },
+	network.GathererName:                     func(context context.T) T { return network.Gatherer(context) },
+	windowsUpdate.GathererName:               func(context context.T) T { return windowsUpdate.Gatherer(context) },
+	file.GathererName:                        func(context context.T) T { return file.Gatherer(context) },
+	firmware.GathererName:                    func(context context.T) T { return firmware.Gatherer(context) },
+	performancecounters.GathererName:         func(context context.T) T { return performancecounters.Gatherer(context) },
+	instancedetailedinformation.GathererName: func(context context.T) T { return instancedetailedinformation.Gatherer(context) },
+	role.GathererName:                        func(context context.T) T { return role.Gatherer(context) },
+	service.GathererName:                     func(context context.T) T { return service.Gatherer(context) },
+	registry.GathererName:                    func(context context.T) T { return registry.Gatherer(context) },
+}
+
+// lazyGatherer creates its gatherer when it first runs, the gatherers the inventory policy doesn't enable are never created
+type lazyGatherer struct {
+	name     string
+	context  context.T
+	create   func(context context.T) T
+	lock     sync.Mutex
+	gatherer T
+}
+
+func (g *lazyGatherer) Name() string {
+	return g.name
+}
+
+func (g *lazyGatherer) Run(context context.T, configuration model.Config) ([]model.Item, error) {
+	g.lock.Lock()
+	if g.gatherer == nil {
+		g.gatherer = g.create(g.context)
+	}
+	gatherer := g.gatherer
+	g.lock.Unlock()
+	return gatherer.Run(context, configuration)
+}
+
+func (g *lazyGatherer) RequestStop(stopType contracts.StopType) error {
+	g.lock.Lock()
+	gatherer := g.gatherer
+	g.lock.Unlock()
+	if gatherer == nil {
+		return nil
+	}
+	return gatherer.RequestStop(stopType)
+}
+
+// InitializeGatherers collects supported and installed gatherers, which are created when they first run
 func InitializeGatherers(context context.T) (SupportedGatherer, InstalledGatherer) {
 	log := context.Log()
 	var installedGathererNames []string
 
-	installedGatherer := InstalledGatherer{
-		application.GathererName:                 application.Gatherer(context),
-		awscomponent.GathererName:                awscomponent.Gatherer(context),
-		custom.GathererName:                      custom.Gatherer(context),
-		network.GathererName:                     network.Gatherer(context),
-		windowsUpdate.GathererName:               windowsUpdate.Gatherer(context),
-		file.GathererName:                        file.Gatherer(context),
-		instancedetailedinformation.GathererName: instancedetailedinformation.Gatherer(context),
-		role.GathererName:                        role.Gatherer(context),
-		service.GathererName:                     service.Gatherer(context),
-		registry.GathererName:                    registry.Gatherer(context),
+	installedGatherer := InstalledGatherer{}
+	for name, create := range gathererConstructors {
+		installedGatherer[name] = &lazyGatherer{name: name, context: context, create: create}
 	}
-
 	for key := range installedGatherer {
 		installedGathererNames = append(installedGathererNames, key)
 	}
@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gatherers
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeGatherersCreatesGatherersOnRun(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	created := 0
+	origConstructors := gathererConstructors
+	defer func() { gathererConstructors = origConstructors }()
+	gathererMock := NewMockDefault()
+	gathererMock.On("Run", contextMock, model.Config{}).Return([]model.Item{{Name: "AWS:Test"}}, nil)
+	gathererConstructors = map[string]func(context context.T) T{
+		"AWS:Test": func(context context.T) T {
+			created++
+			return gathererMock
+		},
+	}
+
+	_, installedGatherer := InitializeGatherers(contextMock)
+
+	gatherer := installedGatherer["AWS:Test"]
+	assert.Equal(t, "AWS:Test", gatherer.Name())
+	// stopping a gatherer that never ran doesn't create it
+	assert.NoError(t, gatherer.RequestStop(contracts.StopTypeSoftStop))
+	assert.Equal(t, 0, created)
+
+	items, err := gatherer.Run(contextMock, model.Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, "AWS:Test", items[0].Name)
+	gatherer.Run(contextMock, model.Config{})
+	assert.Equal(t, 1, created)
+	gathererMock.AssertNumberOfCalls(t, "Run", 2)
+}
@@ -0,0 +1,228 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package performancecounters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	PowershellCmd = "powershell"
+
+	defaultSampleIntervalSeconds = 1
+	maxSampleIntervalSeconds     = 60
+	defaultMaxSamples            = 5
+	maxMaxSamples                = 60
+)
+
+// defaultCounters are sampled when the collection policy is Enabled instead of a list of filters
+var defaultCounters = []string{
+	`\PhysicalDisk(_Total)\Current Disk Queue Length`,
+	`\PhysicalDisk(_Total)\Avg. Disk Queue Length`,
+	`\Memory\Available MBytes`,
+	`\Memory\% Committed Bytes In Use`,
+	`\Processor(_Total)\% Processor Time`,
+}
+
+// getCounterScript samples the counters and writes each sample as a json line. Its format parameters are the
+// quoted counter paths, the sample interval and the number of samples.
+const getCounterScript = `[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+Get-Counter -Counter @(%v) -SampleInterval %v -MaxSamples %v -ErrorAction Stop | ForEach-Object {
+    foreach ($sample in $_.CounterSamples) {
+        [Console]::WriteLine((ConvertTo-Json -Compress -InputObject @{ Path = $sample.Path; Value = [double]$sample.CookedValue }))
+    }
+}`
+
+// filterObj is one counter of the filters, the path may contain wildcards in the instance
+type filterObj struct {
+	Path                  string
+	SampleIntervalSeconds int
+	MaxSamples            int
+}
+
+// sample is one value of a counter written by getCounterScript
+type sample struct {
+	Path  string
+	Value float64
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).CombinedOutput()
+}
+
+// CollectPerformanceCounterData samples the counters of the filters with Get-Counter and aggregates their values.
+func CollectPerformanceCounterData(context context.T, configuration model.Config) (data []model.PerformanceCounterData, err error) {
+	log := context.Log()
+	log.Infof("Getting %v data", GathererName)
+
+	filters, err := parseFilters(configuration.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []sample
+	for _, group := range groupFilters(filters) {
+		command := fmt.Sprintf(getCounterScript, quoteList(group.paths), group.interval, group.samples)
+		output, err := cmdExecutor(PowershellCmd, command)
+		if err != nil {
+			log.Errorf("Failed to execute command : %v; error: %v", command, err.Error())
+			log.Debugf("Command Stderr: %v", string(output))
+			return nil, fmt.Errorf("failed to sample the performance counters %v: %v", group.paths, err)
+		}
+		samples = append(samples, parseSamples(output)...)
+	}
+
+	data = aggregate(samples)
+	log.Infof("%v performance counters sampled", len(data))
+	return data, nil
+}
+
+// parseFilters returns the counters of the json filters, or the default counters for Enabled.
+func parseFilters(filters string) (result []filterObj, err error) {
+	if filters == model.Enabled {
+		for _, path := range defaultCounters {
+			result = append(result, filterObj{Path: path})
+		}
+	} else if err = json.Unmarshal([]byte(filters), &result); err != nil {
+		return nil, fmt.Errorf("invalid performance counter filters %v: %v", filters, err)
+	}
+
+	for i := range result {
+		if !strings.HasPrefix(result[i].Path, `\`) {
+			return nil, fmt.Errorf("%q is not a performance counter path", result[i].Path)
+		}
+		result[i].SampleIntervalSeconds = bound(result[i].SampleIntervalSeconds, defaultSampleIntervalSeconds, maxSampleIntervalSeconds)
+		result[i].MaxSamples = bound(result[i].MaxSamples, defaultMaxSamples, maxMaxSamples)
+	}
+	return result, nil
+}
+
+// bound returns the default value when value is not positive and caps it to max.
+func bound(value, defaultValue, max int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+type counterGroup struct {
+	interval int
+	samples  int
+	paths    []string
+}
+
+// groupFilters groups the counters sampled at the same interval so one Get-Counter samples each group.
+func groupFilters(filters []filterObj) (groups []*counterGroup) {
+	for _, filter := range filters {
+		var group *counterGroup
+		for _, g := range groups {
+			if g.interval == filter.SampleIntervalSeconds && g.samples == filter.MaxSamples {
+				group = g
+			}
+		}
+		if group == nil {
+			group = &counterGroup{interval: filter.SampleIntervalSeconds, samples: filter.MaxSamples}
+			groups = append(groups, group)
+		}
+		group.paths = append(group.paths, filter.Path)
+	}
+	return groups
+}
+
+// parseSamples returns the samples of the json lines of the output, skipping the other lines.
+func parseSamples(output []byte) (samples []sample) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var s sample
+		if err := json.Unmarshal(bytes.TrimSpace(scanner.Bytes()), &s); err == nil && s.Path != "" {
+			s.Path = trimComputerName(s.Path)
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// trimComputerName removes the \\computer prefix of the paths of the samples.
+func trimComputerName(path string) string {
+	if strings.HasPrefix(path, `\\`) {
+		if i := strings.Index(path[2:], `\`); i >= 0 {
+			return path[i+2:]
+		}
+	}
+	return path
+}
+
+// aggregate returns the number of samples, minimum, maximum and average of each counter sorted by path.
+func aggregate(samples []sample) (data []model.PerformanceCounterData) {
+	values := make(map[string][]float64)
+	for _, s := range samples {
+		values[s.Path] = append(values[s.Path], s.Value)
+	}
+
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		min, max, sum := values[path][0], values[path][0], 0.0
+		for _, value := range values[path] {
+			if value < min {
+				min = value
+			}
+			if value > max {
+				max = value
+			}
+			sum += value
+		}
+		data = append(data, model.PerformanceCounterData{
+			Counter: path,
+			Samples: strconv.Itoa(len(values[path])),
+			Minimum: formatValue(min),
+			Maximum: formatValue(max),
+			Average: formatValue(sum / float64(len(values[path]))),
+		})
+	}
+	return data
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// quoteList returns the values as the elements of a PowerShell array of single-quoted strings.
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
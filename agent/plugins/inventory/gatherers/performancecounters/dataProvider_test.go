@@ -0,0 +1,84 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package performancecounters
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleOutput = `{"Path":"\\\\web01\\physicaldisk(_total)\\current disk queue length","Value":2}
+{"Path":"\\\\web01\\memory\\available mbytes","Value":1024}
+
+WARNING: something unrelated
+{"Path":"\\\\web01\\physicaldisk(_total)\\current disk queue length","Value":0}
+{"Path":"\\\\web01\\memory\\available mbytes","Value":2049}
+`
+
+func TestParseFilters(t *testing.T) {
+	filters, err := parseFilters(model.Enabled)
+	assert.NoError(t, err)
+	assert.Equal(t, len(defaultCounters), len(filters))
+	assert.Equal(t, defaultSampleIntervalSeconds, filters[0].SampleIntervalSeconds)
+	assert.Equal(t, defaultMaxSamples, filters[0].MaxSamples)
+
+	filters, err = parseFilters(`[{"Path": "\\LogicalDisk(*)\\% Free Space", "SampleIntervalSeconds": 120, "MaxSamples": 3}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []filterObj{{Path: `\LogicalDisk(*)\% Free Space`, SampleIntervalSeconds: maxSampleIntervalSeconds, MaxSamples: 3}}, filters)
+
+	_, err = parseFilters(`[{"Path": "Memory"}]`)
+	assert.Error(t, err)
+	_, err = parseFilters(`Disabled`)
+	assert.Error(t, err)
+}
+
+func TestCollectPerformanceCounterData(t *testing.T) {
+	var commands []string
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		commands = append(commands, args[0])
+		if len(commands) > 1 {
+			return nil, nil
+		}
+		return []byte(sampleOutput), nil
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	filters := `[{"Path": "\\PhysicalDisk(_Total)\\Current Disk Queue Length"}, {"Path": "\\Memory\\Available MBytes"}, {"Path": "\\Custom's\\Counter", "MaxSamples": 1}]`
+	data, err := CollectPerformanceCounterData(context.NewMockDefault(), model.Config{Filters: filters})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.PerformanceCounterData{
+		{Counter: `\memory\available mbytes`, Samples: "2", Minimum: "1024.00", Maximum: "2049.00", Average: "1536.50"},
+		{Counter: `\physicaldisk(_total)\current disk queue length`, Samples: "2", Minimum: "0.00", Maximum: "2.00", Average: "1.00"},
+	}, data)
+
+	assert.Equal(t, 2, len(commands))
+	assert.True(t, strings.Contains(commands[0], `-Counter @('\PhysicalDisk(_Total)\Current Disk Queue Length', '\Memory\Available MBytes') -SampleInterval 1 -MaxSamples 5`))
+	assert.True(t, strings.Contains(commands[1], `-Counter @('\Custom''s\Counter') -SampleInterval 1 -MaxSamples 1`))
+}
+
+func TestCollectPerformanceCounterDataError(t *testing.T) {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte("Get-Counter : The specified object was not found on the computer."), errors.New("exit status 1")
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	_, err := CollectPerformanceCounterData(context.NewMockDefault(), model.Config{Filters: model.Enabled})
+	assert.Error(t, err)
+}
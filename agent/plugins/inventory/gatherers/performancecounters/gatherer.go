@@ -0,0 +1,74 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package performancecounters contains a gatherer sampling Windows performance counters for the
+// Custom:PerformanceCounters inventory type.
+package performancecounters
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// GathererName captures name of gatherer, the aggregates are reported as custom inventory
+	GathererName = "Custom:PerformanceCounters"
+	// SchemaVersion represents the schema version of this gatherer
+	SchemaVersion = "1.0"
+)
+
+// T represents the gatherer type, which implements all contracts for gatherers.
+type T struct{}
+
+// decoupling for easy testability
+var collectData = CollectPerformanceCounterData
+
+// Gatherer returns new performance counters gatherer
+func Gatherer(context context.T) *T {
+	return new(T)
+}
+
+// Name returns name of performance counters gatherer
+func (t *T) Name() string {
+	return GathererName
+}
+
+// Run samples the counters of the configuration filters and returns their aggregates as an inventory.Item
+func (t *T) Run(context context.T, configuration model.Config) (items []model.Item, err error) {
+	var data []model.PerformanceCounterData
+	if data, err = collectData(context, configuration); err != nil {
+		return
+	}
+
+	//CaptureTime must comply with format: 2016-07-30T18:15:37Z to comply with regex at SSM.
+	currentTime := time.Now().UTC()
+	captureTime := currentTime.Format(time.RFC3339)
+
+	items = append(items, model.Item{
+		Name:          t.Name(),
+		SchemaVersion: SchemaVersion,
+		Content:       data,
+		CaptureTime:   captureTime,
+	})
+	return
+}
+
+// RequestStop stops the execution of gatherer
+func (t *T) RequestStop(stopType contracts.StopType) error {
+	return errors.New("gatherer stop not supported")
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package performancecounters
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func DataGenerator(context context.T, configuration model.Config) ([]model.PerformanceCounterData, error) {
+	return []model.PerformanceCounterData{
+		{
+			Counter: `\memory\available mbytes`,
+			Samples: "5",
+			Minimum: "1024.00",
+			Maximum: "2048.00",
+			Average: "1536.00",
+		},
+	}, nil
+}
+
+func TestGatherer(t *testing.T) {
+	c := context.NewMockDefault()
+	g := Gatherer(c)
+	collectData = DataGenerator
+	defer func() { collectData = CollectPerformanceCounterData }()
+
+	items, err := g.Run(c, model.Config{Filters: model.Enabled})
+	assert.Nil(t, err, "Unexpected error thrown")
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, items[0].Name, g.Name())
+	assert.Equal(t, items[0].SchemaVersion, SchemaVersion)
+	data, _ := DataGenerator(c, model.Config{})
+	assert.Equal(t, items[0].Content, data)
+	assert.NotNil(t, items[0].CaptureTime)
+}
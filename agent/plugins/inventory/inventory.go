@@ -35,8 +35,10 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/firmware"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/performancecounters"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
@@ -72,6 +74,8 @@ type PluginInput struct {
 	WindowsRegistry             string
 	WindowsUpdates              string
 	InstanceDetailedInformation string
+	FirmwareInformation         string
+	PerformanceCounters         string
 	CustomInventory             string
 	CustomInventoryDirectory    string
 }
@@ -382,11 +386,13 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 		network.GathererName:                     input.NetworkConfig,
 		windowsUpdate.GathererName:               input.WindowsUpdates,
 		instancedetailedinformation.GathererName: input.InstanceDetailedInformation,
+		firmware.GathererName:                    input.FirmwareInformation,
 	}
 
 	predefinedGatherersWithFilters := map[string]string{
-		file.GathererName:     input.Files,
-		registry.GathererName: input.WindowsRegistry,
+		file.GathererName:                input.Files,
+		registry.GathererName:            input.WindowsRegistry,
+		performancecounters.GathererName: input.PerformanceCounters,
 	}
 
 	//NOTE:
@@ -169,6 +169,30 @@ type InstanceDetailedInformation struct {
 	OSServicePack         string
 }
 
+// FirmwareData captures all attributes present in the Custom:FirmwareInformation inventory type, custom inventory
+// only takes string attributes
+type FirmwareData struct {
+	BIOSVendor         string
+	BIOSVersion        string
+	BIOSReleaseDate    string
+	SystemManufacturer string
+	SystemProductName  string
+	FirmwareType       string
+	SecureBootEnabled  string
+	TPMPresent         string
+	TPMVersion         string `json:",omitempty"`
+}
+
+// PerformanceCounterData captures the aggregates of the samples of one counter reported in the
+// Custom:PerformanceCounters inventory type, custom inventory only takes string attributes
+type PerformanceCounterData struct {
+	Counter string
+	Samples string
+	Minimum string
+	Maximum string
+	Average string
+}
+
 // Config captures all various properties (including optional) that can be supplied to a gatherer.
 // NOTE: Not all properties will be applicable to all gatherers.
 // E.g: Applications gatherer uses Collection, Files use Filters, Custom uses Collection & Location.
@@ -0,0 +1,327 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runlua
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/yuin/gopher-lua"
+)
+
+// maxJSONDepth bounds the nesting of the tables json.encode converts, a table containing itself is an error
+const maxJSONDepth = 64
+
+// unsafeBaseFunctions are the functions of the base library reading files or loading modules, removed from the scripts
+var unsafeBaseFunctions = []string{"dofile", "loadfile", "require", "module", "_printregs"}
+
+// sandbox is the state the modules of the agent share: the working directory of the relative paths, the commands
+// exec can run and the output print writes to
+type sandbox struct {
+	ctx             gocontext.Context
+	workingDir      string
+	allowedCommands map[string]bool
+	output          iohandler.IOHandler
+}
+
+// newInterpreter returns an interpreter stopped with the context, with the audited libraries only.
+func newInterpreter(ctx gocontext.Context, luaConfig appconfig.LuaCfg, workingDir string, output iohandler.IOHandler) *lua.LState {
+	s := &sandbox{
+		ctx:             ctx,
+		workingDir:      workingDir,
+		allowedCommands: make(map[string]bool),
+		output:          output,
+	}
+	for _, command := range luaConfig.AllowedCommands {
+		s.allowedCommands[command] = true
+	}
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeBaseFunctions {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("print", L.NewFunction(s.print))
+
+	L.SetGlobal("file", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"read":   s.fileRead,
+		"write":  s.fileWrite,
+		"append": s.fileAppend,
+		"exists": s.fileExists,
+		"remove": s.fileRemove,
+		"list":   s.fileList,
+	}))
+	L.SetGlobal("exec", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"run": s.execRun,
+	}))
+	L.SetGlobal("json", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"encode": jsonEncode,
+		"decode": jsonDecode,
+	}))
+
+	L.SetContext(ctx)
+	return L
+}
+
+// run compiles and runs the script, its errors are reported at their line of the script.
+func run(L *lua.LState, script string) error {
+	fn, err := L.Load(strings.NewReader(script), scriptName)
+	if err != nil {
+		return err
+	}
+	L.Push(fn)
+	return L.PCall(0, lua.MultRet, nil)
+}
+
+// fail returns nil and the message of the error to the script, the Lua convention for the failures a script handles.
+func fail(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// path returns the path of the argument, relative to the working directory when it is not absolute.
+func (s *sandbox) path(L *lua.LState, n int) string {
+	path := L.CheckString(n)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(s.workingDir, path)
+}
+
+// print writes its arguments separated by tabs as a line of the standard output of the plugin.
+func (s *sandbox) print(L *lua.LState) int {
+	values := make([]string, L.GetTop())
+	for i := range values {
+		values[i] = L.ToStringMeta(L.Get(i + 1)).String()
+	}
+	s.output.AppendInfo(strings.Join(values, "\t"))
+	return 0
+}
+
+// fileRead returns the content of a file.
+func (s *sandbox) fileRead(L *lua.LState) int {
+	content, err := ioutil.ReadFile(s.path(L, 1))
+	if err != nil {
+		return fail(L, err)
+	}
+	L.Push(lua.LString(content))
+	return 1
+}
+
+// fileWrite replaces the content of a file, the file is created readable by its owner only.
+func (s *sandbox) fileWrite(L *lua.LState) int {
+	if err := ioutil.WriteFile(s.path(L, 1), []byte(L.CheckString(2)), appconfig.ReadWriteAccess); err != nil {
+		return fail(L, err)
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// fileAppend appends to a file, the file is created readable by its owner only.
+func (s *sandbox) fileAppend(L *lua.LState) int {
+	file, err := os.OpenFile(s.path(L, 1), os.O_APPEND|os.O_CREATE|os.O_WRONLY, appconfig.ReadWriteAccess)
+	if err != nil {
+		return fail(L, err)
+	}
+	defer file.Close()
+	if _, err = file.WriteString(L.CheckString(2)); err != nil {
+		return fail(L, err)
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// fileExists returns whether a file or a directory exists.
+func (s *sandbox) fileExists(L *lua.LState) int {
+	_, err := os.Stat(s.path(L, 1))
+	L.Push(lua.LBool(err == nil))
+	return 1
+}
+
+// fileRemove removes a file or an empty directory.
+func (s *sandbox) fileRemove(L *lua.LState) int {
+	if err := os.Remove(s.path(L, 1)); err != nil {
+		return fail(L, err)
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// fileList returns the sorted names of the entries of a directory.
+func (s *sandbox) fileList(L *lua.LState) int {
+	entries, err := ioutil.ReadDir(s.path(L, 1))
+	if err != nil {
+		return fail(L, err)
+	}
+	names := L.NewTable()
+	for _, entry := range entries {
+		names.Append(lua.LString(entry.Name()))
+	}
+	L.Push(names)
+	return 1
+}
+
+// execRun runs one of the allowed commands with the string arguments in the working directory and returns its
+// standard output, its standard error and its exit code. The command is killed when the script is stopped.
+func (s *sandbox) execRun(L *lua.LState) int {
+	command := L.CheckString(1)
+	if !s.allowedCommands[command] {
+		return fail(L, fmt.Errorf("%v is not one of the Lua.AllowedCommands of the agent config", command))
+	}
+	arguments := make([]string, 0, L.GetTop())
+	for i := 2; i <= L.GetTop(); i++ {
+		arguments = append(arguments, L.CheckString(i))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(s.ctx, command, arguments...)
+	cmd.Dir = s.workingDir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return fail(L, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	L.Push(lua.LString(stdout.String()))
+	L.Push(lua.LString(stderr.String()))
+	L.Push(lua.LNumber(exitCode))
+	return 3
+}
+
+// jsonEncode returns the JSON of a value, the tables with the keys 1 to n are arrays and the others objects.
+func jsonEncode(L *lua.LState) int {
+	value, err := fromLua(L.CheckAny(1), 0)
+	if err != nil {
+		return fail(L, err)
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fail(L, err)
+	}
+	L.Push(lua.LString(content))
+	return 1
+}
+
+// jsonDecode returns the value of a JSON document, null is nil.
+func jsonDecode(L *lua.LState) int {
+	var value interface{}
+	if err := json.Unmarshal([]byte(L.CheckString(1)), &value); err != nil {
+		return fail(L, err)
+	}
+	L.Push(toLua(L, value))
+	return 1
+}
+
+// fromLua converts a Lua value to the value of its JSON.
+func fromLua(value lua.LValue, depth int) (interface{}, error) {
+	if depth > maxJSONDepth {
+		return nil, errors.New("the value is nested too deeply or contains itself")
+	}
+	switch value := value.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(value), nil
+	case lua.LNumber:
+		return float64(value), nil
+	case lua.LString:
+		return string(value), nil
+	case *lua.LTable:
+		if length := value.MaxN(); length > 0 && countKeys(value) == length {
+			array := make([]interface{}, 0, length)
+			for i := 1; i <= length; i++ {
+				element, err := fromLua(value.RawGetInt(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, element)
+			}
+			return array, nil
+		}
+		object := make(map[string]interface{})
+		var err error
+		value.ForEach(func(key lua.LValue, field lua.LValue) {
+			if err != nil {
+				return
+			}
+			name, ok := key.(lua.LString)
+			if !ok {
+				err = fmt.Errorf("the key %v of an object is not a string", key)
+				return
+			}
+			object[string(name)], err = fromLua(field, depth+1)
+		})
+		return object, err
+	}
+	return nil, fmt.Errorf("a %v cannot be converted to JSON", value.Type())
+}
+
+// countKeys returns the number of keys of a table.
+func countKeys(table *lua.LTable) (count int) {
+	table.ForEach(func(lua.LValue, lua.LValue) { count++ })
+	return count
+}
+
+// toLua converts the value of a JSON document to a Lua value.
+func toLua(L *lua.LState, value interface{}) lua.LValue {
+	switch value := value.(type) {
+	case bool:
+		return lua.LBool(value)
+	case float64:
+		return lua.LNumber(value)
+	case string:
+		return lua.LString(value)
+	case []interface{}:
+		array := L.NewTable()
+		for i, element := range value {
+			array.RawSetInt(i+1, toLua(L, element))
+		}
+		return array
+	case map[string]interface{}:
+		object := L.NewTable()
+		for name, field := range value {
+			object.RawSetString(name, toLua(L, field))
+		}
+		return object
+	}
+	return lua.LNil
+}
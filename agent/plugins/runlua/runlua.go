@@ -0,0 +1,139 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runlua implements the aws:runLuaScript plugin, running Lua scripts in an embedded interpreter which only
+// offers the base, table, string and math libraries of Lua and the file, exec and json modules of the agent.
+package runlua
+
+import (
+	gocontext "context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	// scriptName is the chunk name of the script in the Lua errors
+	scriptName = "script.lua"
+)
+
+// Plugin is the type for the aws:runLuaScript plugin.
+type Plugin struct {
+}
+
+// RunLuaPluginInput represents one script run by the plugin.
+type RunLuaPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Script are the lines of the Lua script
+	Script []string
+	// WorkingDirectory is the directory the relative paths of the file module and the commands of the exec module
+	// start from
+	WorkingDirectory string
+	TimeoutSeconds   interface{}
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	return &Plugin{}, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameAwsRunLuaScript
+}
+
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.runScriptRawInput(log, context.AppConfig().Lua, config, cancelFlag, output)
+	}
+	return
+}
+
+// runScriptRawInput validates the raw plugin input and runs the script.
+func (p *Plugin) runScriptRawInput(log log.T, luaConfig appconfig.LuaCfg, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var pluginInput RunLuaPluginInput
+	err := jsonutil.Remarshal(config.Properties, &pluginInput)
+	log.Debugf("Plugin input %v", pluginInput)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", config.Properties, err))
+		return
+	}
+	p.runScript(log, luaConfig, config, pluginInput, cancelFlag, output)
+}
+
+// runScript runs the script until it ends, fails, times out or the document is canceled.
+func (p *Plugin) runScript(log log.T, luaConfig appconfig.LuaCfg, config contracts.Configuration, pluginInput RunLuaPluginInput, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	orchestrationDir := fileutil.BuildPath(config.OrchestrationDirectory, pluginInput.ID)
+	if err := fileutil.MakeDirsWithExecuteAccess(orchestrationDir); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to create orchestrationDir directory, %v", orchestrationDir))
+		return
+	}
+
+	workingDir := pluginInput.WorkingDirectory
+	if !filepath.IsAbs(workingDir) {
+		workingDir = filepath.Join(strings.TrimSuffix(config.OrchestrationDirectory, config.PluginID), downloadsDir, pluginInput.WorkingDirectory)
+		if !fileutil.Exists(workingDir) {
+			workingDir = config.DefaultWorkingDirectory
+		}
+		if workingDir == "" {
+			workingDir = orchestrationDir
+		}
+	}
+
+	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), time.Duration(executionTimeout)*time.Second)
+	defer cancel()
+	go func() {
+		if state := cancelFlag.Wait(); state == task.Canceled || state == task.ShutDown {
+			cancel()
+		}
+	}()
+
+	interpreter := newInterpreter(ctx, luaConfig, workingDir, output)
+	defer interpreter.Close()
+
+	log.Debugf("Running the Lua script in workingDirectory %v", workingDir)
+	exitCode := appconfig.SuccessExitCode
+	if err := run(interpreter, strings.Join(pluginInput.Script, "\n")); err != nil {
+		if ctx.Err() != nil {
+			exitCode = appconfig.CommandStoppedPreemptivelyExitCode
+		} else {
+			exitCode = 1
+			output.AppendErrorf("failed to run the Lua script: %v", err)
+		}
+	}
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+}
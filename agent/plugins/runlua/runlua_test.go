@@ -0,0 +1,98 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runlua
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// runWith runs the script in a temporary orchestration directory and returns the output of the plugin and the directory.
+func runWith(t *testing.T, luaConfig appconfig.LuaCfg, cancelFlag task.CancelFlag, script ...string) (*iohandler.DefaultIOHandler, string) {
+	orchestrationDirectory, err := ioutil.TempDir("", "runlua")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(orchestrationDirectory) })
+
+	output := &iohandler.DefaultIOHandler{}
+	config := contracts.Configuration{OrchestrationDirectory: orchestrationDirectory, PluginID: "aws:runLuaScript"}
+	input := RunLuaPluginInput{ID: "0.aws:runLuaScript", Script: script, WorkingDirectory: orchestrationDirectory}
+	(&Plugin{}).runScript(logger, luaConfig, config, input, cancelFlag, output)
+	return output, orchestrationDirectory
+}
+
+func TestRunScript(t *testing.T) {
+	output, dir := runWith(t, appconfig.LuaCfg{}, task.NewChanneledCancelFlag(),
+		`local config = json.decode('{"team": "payments", "ports": [80, 443], "debug": false}')`,
+		`assert(file.write("config.json", json.encode({team = config.team, ports = config.ports})))`,
+		`print("team", config.team, #config.ports)`,
+		`print(file.read("config.json"))`,
+		`-- the working directory holds the file and the orchestration directory of the plugin`,
+		`print(file.exists("config.json"), file.exists("missing.json"), #file.list("."))`)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, "team\tpayments\t2\n{\"ports\":[80,443],\"team\":\"payments\"}\ntrue\tfalse\t2", output.GetStdout())
+	assert.True(t, fileutil.Exists(filepath.Join(dir, "config.json")))
+}
+
+func TestRunScriptSandbox(t *testing.T) {
+	output, _ := runWith(t, appconfig.LuaCfg{}, task.NewChanneledCancelFlag(),
+		`assert(os == nil and io == nil and debug == nil and package == nil)`,
+		`assert(dofile == nil and loadfile == nil and require == nil)`,
+		`local stdout, err = exec.run("sh", "-c", "id")`,
+		`assert(stdout == nil and err:find("AllowedCommands"))`)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+}
+
+func TestRunScriptAllowedCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test runs sh")
+	}
+	output, _ := runWith(t, appconfig.LuaCfg{AllowedCommands: []string{"sh"}}, task.NewChanneledCancelFlag(),
+		`local stdout, stderr, code = exec.run("sh", "-c", "echo out; echo err >&2; exit 3")`,
+		`print(stdout, stderr, code)`)
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, "out\n\terr\n\t3", output.GetStdout())
+}
+
+func TestRunScriptError(t *testing.T) {
+	output, _ := runWith(t, appconfig.LuaCfg{}, task.NewChanneledCancelFlag(),
+		`print("before")`,
+		`error("the service is not running")`)
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, 1, output.GetExitCode())
+	assert.Equal(t, "before", output.GetStdout())
+	assert.Contains(t, output.GetStderr(), "script.lua:2: the service is not running")
+}
+
+func TestRunScriptCanceled(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	time.AfterFunc(100*time.Millisecond, func() { cancelFlag.Set(task.Canceled) })
+	output, _ := runWith(t, appconfig.LuaCfg{}, cancelFlag, `while true do end`)
+	assert.Equal(t, contracts.ResultStatusCancelled, output.GetStatus())
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+// process contains the umask and the scheduling priorities the agent applies to the scripts
+package runscript
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ioNiceClasses maps the IoNiceClass inputs to the scheduling classes of ionice
+var ioNiceClasses = map[string]string{
+	"realtime":    "1",
+	"best-effort": "2",
+	"idle":        "3",
+}
+
+// processSettings are the validated umask and scheduling priorities of the input, empty when not set
+type processSettings struct {
+	umask       string
+	nice        string
+	ioNiceClass string
+	ioNiceLevel string
+}
+
+// empty returns true if none of the settings is set.
+func (settings processSettings) empty() bool {
+	return settings == processSettings{}
+}
+
+// parseProcessSettings validates the Umask, Nice, IoNiceClass and IoNiceLevel of the input.
+func parseProcessSettings(pluginInput RunScriptPluginInput) (settings processSettings, err error) {
+	if pluginInput.Umask != "" {
+		var umask uint64
+		if umask, err = strconv.ParseUint(pluginInput.Umask, 8, 32); err != nil || umask > 0777 {
+			return settings, fmt.Errorf("invalid Umask %v, it must be an octal mode between 0000 and 0777", pluginInput.Umask)
+		}
+		settings.umask = fmt.Sprintf("%04o", umask)
+	}
+	if settings.nice, err = intSetting("Nice", pluginInput.Nice, -20, 19); err != nil {
+		return
+	}
+	if settings.ioNiceLevel, err = intSetting("IoNiceLevel", pluginInput.IoNiceLevel, 0, 7); err != nil {
+		return
+	}
+	if pluginInput.IoNiceClass != "" {
+		var ok bool
+		if settings.ioNiceClass, ok = ioNiceClasses[pluginInput.IoNiceClass]; !ok {
+			return settings, fmt.Errorf("invalid IoNiceClass %v, it must be realtime, best-effort or idle", pluginInput.IoNiceClass)
+		}
+	}
+	if settings.ioNiceLevel != "" && (settings.ioNiceClass == "" || pluginInput.IoNiceClass == "idle") {
+		return settings, fmt.Errorf("IoNiceLevel requires the realtime or best-effort IoNiceClass")
+	}
+	return
+}
+
+// intSetting returns the integer value of the input as a string, empty when the input is not set.
+func intSetting(name string, value interface{}, min int, max int) (string, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		text = strings.TrimSpace(v)
+		if text == "" {
+			return "", nil
+		}
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		text = fmt.Sprint(v)
+	}
+	number, err := strconv.Atoi(text)
+	if err != nil || number < min || number > max {
+		return "", fmt.Errorf("invalid %v %v, it must be an integer between %v and %v", name, value, min, max)
+	}
+	return strconv.Itoa(number), nil
+}
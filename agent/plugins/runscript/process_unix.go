@@ -0,0 +1,51 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// applyProcessSettings returns the command and the arguments starting the shell through ionice, nice and a shell
+// setting the umask, so that the settings apply to the shell and everything it starts.
+func applyProcessSettings(settings processSettings, commandName string, commandArguments []string) (string, []string, error) {
+	if settings.empty() {
+		return commandName, commandArguments, nil
+	}
+	command := []string{commandName}
+	command = append(command, commandArguments...)
+	if settings.umask != "" {
+		command = append([]string{"sh", "-c", fmt.Sprintf(`umask %v && exec "$@"`, settings.umask), "sh"}, command...)
+	}
+	if settings.nice != "" {
+		command = append([]string{"nice", "-n", settings.nice}, command...)
+	}
+	if settings.ioNiceClass != "" {
+		if runtime.GOOS != "linux" {
+			return "", nil, fmt.Errorf("IoNiceClass is only supported on Linux")
+		}
+		ionice := []string{"ionice", "-c", settings.ioNiceClass}
+		if settings.ioNiceLevel != "" {
+			ionice = append(ionice, "-n", settings.ioNiceLevel)
+		}
+		command = append(ionice, command...)
+	}
+	return command[0], command[1:], nil
+}
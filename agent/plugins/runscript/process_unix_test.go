@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcessSettings(t *testing.T) {
+	settings, err := parseProcessSettings(RunScriptPluginInput{Umask: "27", Nice: float64(10), IoNiceClass: "best-effort", IoNiceLevel: "7"})
+	assert.NoError(t, err)
+	assert.Equal(t, processSettings{umask: "0027", nice: "10", ioNiceClass: "2", ioNiceLevel: "7"}, settings)
+
+	settings, err = parseProcessSettings(RunScriptPluginInput{Nice: " -5 "})
+	assert.NoError(t, err)
+	assert.Equal(t, processSettings{nice: "-5"}, settings)
+
+	settings, err = parseProcessSettings(RunScriptPluginInput{Nice: ""})
+	assert.NoError(t, err)
+	assert.True(t, settings.empty())
+
+	for _, input := range []RunScriptPluginInput{
+		{Umask: "0999"},
+		{Umask: "1777"},
+		{Nice: "-21"},
+		{Nice: 1.5},
+		{IoNiceClass: "low"},
+		{IoNiceLevel: 3},
+		{IoNiceClass: "idle", IoNiceLevel: 3},
+		{IoNiceClass: "realtime", IoNiceLevel: 8},
+	} {
+		_, err = parseProcessSettings(input)
+		assert.Error(t, err, "%v", input)
+	}
+}
+
+func TestApplyProcessSettings(t *testing.T) {
+	commandName, commandArguments, err := applyProcessSettings(processSettings{}, "sh", []string{"-c", "_script.sh"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sh", commandName)
+	assert.Equal(t, []string{"-c", "_script.sh"}, commandArguments)
+
+	commandName, commandArguments, err = applyProcessSettings(processSettings{umask: "0027", nice: "10"}, "sh", []string{"-c", "_script.sh"})
+	assert.NoError(t, err)
+	assert.Equal(t, "nice", commandName)
+	assert.Equal(t, []string{"-n", "10", "sh", "-c", `umask 0027 && exec "$@"`, "sh", "sh", "-c", "_script.sh"}, commandArguments)
+
+	commandName, commandArguments, err = applyProcessSettings(processSettings{ioNiceClass: "2", ioNiceLevel: "7"}, "sh", []string{"-c", "_script.sh"})
+	if runtime.GOOS != "linux" {
+		assert.Error(t, err)
+		return
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, "ionice", commandName)
+	assert.Equal(t, []string{"-c", "2", "-n", "7", "sh", "-c", "_script.sh"}, commandArguments)
+}
@@ -0,0 +1,31 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"fmt"
+)
+
+// applyProcessSettings fails when any setting is set, the umask and the scheduling priorities are not supported on Windows.
+func applyProcessSettings(settings processSettings, commandName string, commandArguments []string) (string, []string, error) {
+	if !settings.empty() {
+		return "", nil, fmt.Errorf("Umask, Nice, IoNiceClass and IoNiceLevel are not supported on Windows")
+	}
+	return commandName, commandArguments, nil
+}
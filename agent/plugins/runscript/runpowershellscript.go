@@ -21,6 +21,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/powershell"
 )
 
 // powerShellScriptName is the script name where all downloaded or provided commands will be stored
@@ -35,14 +36,21 @@ type runPowerShellPlugin struct {
 func NewRunPowerShellPlugin() (*runPowerShellPlugin, error) {
 	psplugin := runPowerShellPlugin{
 		Plugin{
-			Name:            appconfig.PluginNameAwsRunPowerShellScript,
-			ScriptName:      powerShellScriptName,
-			ShellCommand:    appconfig.PowerShellPluginCommandName,
-			ShellArguments:  strings.Split(appconfig.PowerShellPluginCommandArgs, " "),
-			ByteOrderMark:   fileutil.ByteOrderMarkEmit,
-			CommandExecuter: executers.ShellCommandExecuter{},
+			Name:                appconfig.PluginNameAwsRunPowerShellScript,
+			ScriptName:          powerShellScriptName,
+			ShellCommand:        appconfig.PowerShellPluginCommandName,
+			ShellArguments:      strings.Split(appconfig.PowerShellPluginCommandArgs, " "),
+			ByteOrderMark:       fileutil.ByteOrderMarkEmit,
+			CommandExecuter:     executers.ShellCommandExecuter{},
+			Restrict:            restrictPowerShell,
+			EnvironmentVariable: powerShellVariable,
 		},
 	}
 
 	return &psplugin, nil
 }
+
+// restrictPowerShell returns the arguments of powershell and the prologue of the scripts for the PowerShell config
+func restrictPowerShell(config appconfig.SsmagentConfig, shellArguments []string) ([]string, []string) {
+	return powershell.Arguments(config.PowerShell, shellArguments), powershell.Prologue(config.PowerShell)
+}
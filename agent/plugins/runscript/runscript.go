@@ -16,25 +16,42 @@ package runscript
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
-
+	"strconv"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/netns"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/scopedcreds"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
 )
 
 const (
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
 )
 
+// credentialsFileName is the shared credentials file of the scoped down credentials under the orchestration directory
+const credentialsFileName = "credentials"
+
+// isDirectory returns true if the path is an existing directory
+var isDirectory = fileutil.IsDirectory
+
+// mintCredentials assumes the role of the request
+var mintCredentials = scopedcreds.Mint
+
 // Plugin is the type for the runscript plugin.
 type Plugin struct {
 	// ExecuteCommand is an object that can execute commands.
@@ -45,6 +62,23 @@ type Plugin struct {
 	ShellCommand   string
 	ShellArguments []string
 	ByteOrderMark  fileutil.ByteOrderMark
+	// Restrict returns the arguments of the shell and the lines of the script run before the commands
+	// for the agent config, it is nil for the shells the agent does not restrict
+	Restrict func(config appconfig.SsmagentConfig, shellArguments []string) ([]string, []string)
+	// Prologue are the lines of the script run before the commands
+	Prologue []string
+	// Command returns the arguments of ShellCommand running the script in the working directory,
+	// it is nil for the shells taking the script after ShellArguments
+	Command func(workingDir string, scriptPath string) ([]string, error)
+	// EnvironmentVariable returns the reference of the shell to an environment variable, the references to
+	// SecureString parameters are replaced with, it is nil for the shells not getting the environment of the agent
+	EnvironmentVariable func(name string) string
+	// HonorShebang runs the commands with the interpreter of their shebang line when the agent config allows it
+	HonorShebang bool
+	// allowedInterpreters are the interpreters the shebang lines of the commands can name
+	allowedInterpreters []string
+	// tempDirectory is the temporary directory of the execution exported to the commands
+	tempDirectory string
 }
 
 // RunScriptPluginInput represents one set of commands executed by the RunScript plugin.
@@ -54,6 +88,26 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	// NetworkNamespace is the named Linux network namespace the commands run in, the one of the agent when empty
+	NetworkNamespace string
+	// Stdin is fed to the standard input of the commands
+	Stdin string
+	// StdinSource is the s3 or http(s) url of the object fed to the standard input of the commands instead of Stdin
+	StdinSource string
+	// Umask is the octal file mode creation mask of the commands
+	Umask string
+	// Nice is the niceness the commands run with, from -20 to 19
+	Nice interface{}
+	// IoNiceClass is the I/O scheduling class of the commands on Linux, realtime, best-effort or idle
+	IoNiceClass string
+	// IoNiceLevel is the I/O scheduling priority of the commands in the realtime and best-effort classes, from 0 to 7
+	IoNiceLevel interface{}
+	// RoleArn is the role the agent assumes to expose its temporary credentials to the commands
+	RoleArn string
+	// SessionPolicy is the json policy, as a string or an object, scoping down the permissions of RoleArn
+	SessionPolicy interface{}
+	// SessionDurationSeconds is the lifetime of the credentials of RoleArn, from 900 to 43200
+	SessionDurationSeconds interface{}
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -68,7 +122,15 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 	} else if cancelFlag.Canceled() {
 		output.MarkAsCancelled()
 	} else {
-		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+		plugin := *p
+		plugin.tempDirectory = config.TempDirectory
+		if p.HonorShebang {
+			plugin.allowedInterpreters = context.AppConfig().ShellScript.AllowedInterpreters
+		}
+		if p.Restrict != nil {
+			plugin.ShellArguments, plugin.Prologue = p.Restrict(context.AppConfig(), p.ShellArguments)
+		}
+		plugin.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
 	}
 }
 
@@ -90,8 +152,23 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 	var err error
 	var workingDir string
 
+	if err = netns.Check(pluginInput.NetworkNamespace); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	settings, err := parseProcessSettings(pluginInput)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	if filepath.IsAbs(pluginInput.WorkingDirectory) {
 		workingDir = pluginInput.WorkingDirectory
+		if !isDirectory(workingDir) {
+			output.MarkAsFailed(fmt.Errorf("working directory %v does not exist or is not a directory", workingDir))
+			return
+		}
 	} else {
 		orchestrationDir := strings.TrimSuffix(orchestrationDirectory, pluginID)
 		// The Document path is expected to have the name of the document
@@ -115,8 +192,23 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 	scriptPath := filepath.Join(orchestrationDir, p.ScriptName)
 	log.Debugf("Writing commands %v to file %v", pluginInput, scriptPath)
 
+	// Run the commands with the interpreter of their shebang line
+	commandName, shellArguments, environmentVariable, err := p.interpreter(pluginInput.RunCommand)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	// Replace the references to SecureString parameters by the environment variables holding their values
+	runCommand, secureEnvironment, secureValues, err := secureParameters(log, pluginInput.RunCommand, environmentVariable)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	// Create script file
-	if err = pluginutil.CreateScriptFile(log, scriptPath, pluginInput.RunCommand, p.ByteOrderMark); err != nil {
+	commands := append(append([]string{}, p.Prologue...), runCommand...)
+	if err = pluginutil.CreateScriptFile(log, scriptPath, commands, p.ByteOrderMark); err != nil {
 		output.MarkAsFailed(fmt.Errorf("failed to create script file. %v", err))
 		return
 	}
@@ -125,11 +217,83 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
 
 	// Construct Command Name and Arguments
-	commandName := p.ShellCommand
-	commandArguments := append(p.ShellArguments, scriptPath)
+	commandArguments := append(append([]string{}, shellArguments...), scriptPath)
+	if p.Command != nil {
+		if commandArguments, err = p.Command(workingDir, scriptPath); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+	}
+
+	// Apply the umask and the scheduling priorities of the input
+	if commandName, commandArguments, err = applyProcessSettings(settings, commandName, commandArguments); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	// Feed the standard input of the commands
+	commandExecuter := p.CommandExecuter
+	if pluginInput.Stdin != "" || pluginInput.StdinSource != "" {
+		stdin, err := openStdin(log, pluginInput, orchestrationDir)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		defer stdin.Close()
+		stdinExecuter, ok := commandExecuter.(executers.StdinExecuter)
+		if !ok {
+			output.MarkAsFailed(fmt.Errorf("%v cannot feed the standard input of the commands", p.Name))
+			return
+		}
+		commandExecuter = stdinExecuter.WithStdin(stdin)
+	}
+
+	// Export the temporary directory of the execution to the commands
+	var environment []string
+	if p.tempDirectory != "" {
+		environment = append(environment, tempdir.Environment(p.tempDirectory)...)
+	}
+
+	// Expose the scoped down credentials of the input to the commands
+	if pluginInput.RoleArn != "" || pluginInput.SessionPolicy != nil {
+		credentialsPath := filepath.Join(orchestrationDir, credentialsFileName)
+		credentialsEnvironment, err := scopedCredentials(pluginInput, credentialsPath)
+		defer fileutil.DeleteFile(credentialsPath)
+		if err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		environment = append(environment, credentialsEnvironment...)
+	}
+	environment = append(environment, secureEnvironment...)
 
-	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	if len(environment) > 0 {
+		environmentExecuter, ok := commandExecuter.(executers.EnvironmentExecuter)
+		if !ok {
+			output.MarkAsFailed(fmt.Errorf("%v cannot set the environment of the commands", p.Name))
+			return
+		}
+		commandExecuter = environmentExecuter.WithEnvironment(environment)
+	}
+
+	// Execute Command, in the network namespace of the input
+	var exitCode int
+	if nsErr := netns.Run(pluginInput.NetworkNamespace, func() error {
+		var stdoutWriter, stderrWriter io.Writer = output.GetStdoutWriter(), output.GetStderrWriter()
+		if len(secureValues) == 0 {
+			exitCode, err = commandExecuter.NewExecute(log, workingDir, stdoutWriter, stderrWriter, cancelFlag, executionTimeout, commandName, commandArguments)
+			return nil
+		}
+		// Mask the values of the SecureString parameters in the output
+		maskedStdout, maskedStderr := newMaskingWriter(stdoutWriter, secureValues), newMaskingWriter(stderrWriter, secureValues)
+		exitCode, err = commandExecuter.NewExecute(log, workingDir, maskedStdout, maskedStderr, cancelFlag, executionTimeout, commandName, commandArguments)
+		maskedStdout.Flush()
+		maskedStderr.Flush()
+		return nil
+	}); nsErr != nil {
+		output.MarkAsFailed(nsErr)
+		return
+	}
 
 	// Set output status
 	output.SetExitCode(exitCode)
@@ -144,3 +308,55 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		}
 	}
 }
+
+// scopedCredentials mints the credentials of RoleArn scoped down by SessionPolicy, writes them to credentialsPath and
+// returns the environment variables exposing them.
+func scopedCredentials(pluginInput RunScriptPluginInput, credentialsPath string) ([]string, error) {
+	if pluginInput.RoleArn == "" {
+		return nil, fmt.Errorf("SessionPolicy requires RoleArn")
+	}
+	request := scopedcreds.Request{RoleArn: pluginInput.RoleArn}
+	var err error
+	switch policy := pluginInput.SessionPolicy.(type) {
+	case nil:
+	case string:
+		request.SessionPolicy = policy
+	default:
+		if request.SessionPolicy, err = jsonutil.Marshal(policy); err != nil {
+			return nil, fmt.Errorf("invalid SessionPolicy: %v", err)
+		}
+	}
+	durationSeconds, err := intSetting("SessionDurationSeconds", pluginInput.SessionDurationSeconds, 900, 43200)
+	if err != nil {
+		return nil, err
+	}
+	if durationSeconds != "" {
+		request.DurationSeconds, _ = strconv.ParseInt(durationSeconds, 10, 64)
+	}
+	credentials, err := mintCredentials(request)
+	if err != nil {
+		return nil, err
+	}
+	if err = scopedcreds.WriteFile(credentialsPath, credentials); err != nil {
+		return nil, err
+	}
+	return scopedcreds.Environment(credentials, credentialsPath), nil
+}
+
+// openStdin returns the reader of the standard input of the commands, downloading StdinSource to orchestrationDir.
+func openStdin(log log.T, pluginInput RunScriptPluginInput, orchestrationDir string) (io.ReadCloser, error) {
+	if pluginInput.StdinSource == "" {
+		return ioutil.NopCloser(strings.NewReader(pluginInput.Stdin)), nil
+	}
+	if pluginInput.Stdin != "" {
+		return nil, fmt.Errorf("only one of Stdin and StdinSource can be specified")
+	}
+	downloadOutput, err := artifact.Download(log, artifact.DownloadInput{
+		SourceURL:            pluginInput.StdinSource,
+		DestinationDirectory: orchestrationDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the standard input from %v: %v", pluginInput.StdinSource, err)
+	}
+	return os.Open(downloadOutput.LocalFilePath)
+}
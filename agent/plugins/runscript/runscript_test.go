@@ -15,17 +15,24 @@ package runscript
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/scopedcreds"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/twinj/uuid"
@@ -305,6 +312,11 @@ func testExecution(t *testing.T, commandtester CommandTester) {
 	mockExecuter := new(executers.MockCommandExecuter)
 	mockIOHandler := new(iohandlermocks.MockIOHandler)
 
+	// the working directories of the test cases do not exist
+	isDirectoryOrig := isDirectory
+	isDirectory = func(string) bool { return true }
+	defer func() { isDirectory = isDirectoryOrig }()
+
 	// create plugin
 	p := new(Plugin)
 	p.CommandExecuter = mockExecuter
@@ -343,3 +355,209 @@ func setCancelFlagExpectations(mockCancelFlag *task.MockCancelFlag, times int) {
 	mockCancelFlag.On("Canceled").Return(false).Times(times)
 	mockCancelFlag.On("ShutDown").Return(false).Times(times)
 }
+
+func TestWslPath(t *testing.T) {
+	path, err := wslPath(`C:\ProgramData\Amazon\SSM\InstanceData\_script.sh`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/mnt/c/ProgramData/Amazon/SSM/InstanceData/_script.sh", path)
+
+	_, err = wslPath(`\\server\share\_script.sh`)
+	assert.Error(t, err)
+	_, err = wslPath(`scripts\_script.sh`)
+	assert.Error(t, err)
+}
+
+func TestWslShellCommand(t *testing.T) {
+	command := wslShellCommand(appconfig.WslCfg{Distribution: "Ubuntu", User: "ssm-user"})
+	arguments, err := command(`D:\work`, `C:\orchestration\_script.sh`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--distribution", "Ubuntu", "--user", "ssm-user", "--cd", "/mnt/d/work", "--exec", "sh", "/mnt/c/orchestration/_script.sh"}, arguments)
+
+	command = wslShellCommand(appconfig.WslCfg{Distribution: "Ubuntu"})
+	arguments, err = command("", `C:\orchestration\_script.sh`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--distribution", "Ubuntu", "--exec", "sh", "/mnt/c/orchestration/_script.sh"}, arguments)
+}
+
+func TestNetworkNamespaceNotEntered(t *testing.T) {
+	for _, namespace := range []string{"../net", "ssm-agent-test-missing"} {
+		executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+			setCancelFlagExpectations(mockCancelFlag, 1)
+			// the commands are not run when the network namespace cannot be entered
+			mockIOHandler.On("MarkAsFailed", mock.Anything).Return().Once()
+
+			testCase := generateTestCaseOk("0")
+			testCase.Input.NetworkNamespace = namespace
+			p.Execute(
+				context.NewMockDefault(),
+				contracts.Configuration{
+					Properties:             singleValuePropertyBuilder(t, testCase),
+					OrchestrationDirectory: orchestrationDirectory,
+					PluginID:               pluginID,
+				}, mockCancelFlag, mockIOHandler)
+		}
+		testExecution(t, executeTester)
+	}
+}
+
+func TestStdin(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		testCase := generateTestCaseOk("0")
+		testCase.Input.Stdin = "yes\n"
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		var stdin string
+		mockExecuter.On("WithStdin", mock.Anything).Run(func(args mock.Arguments) {
+			content, _ := ioutil.ReadAll(args.Get(0).(io.Reader))
+			stdin = string(content)
+		}).Return(mockExecuter).Once()
+
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+		assert.Equal(t, "yes\n", stdin)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestStdinAndStdinSource(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		// the commands are not run when the standard input is ambiguous
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("only one of Stdin and StdinSource can be specified")).Return().Once()
+
+		testCase := generateTestCaseOk("0")
+		testCase.Input.Stdin = "yes\n"
+		testCase.Input.StdinSource = "https://example.com/answers.txt"
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestWorkingDirectoryMissing(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		isDirectory = func(string) bool { return false }
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		// the commands are not run in a missing working directory
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("working directory /Dir0 does not exist or is not a directory")).Return().Once()
+
+		testCase := generateTestCaseOk("0")
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestInvalidProcessSettings(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("invalid Nice 20, it must be an integer between -20 and 19")).Return().Once()
+
+		testCase := generateTestCaseOk("0")
+		testCase.Input.Nice = 20
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestScopedCredentials(t *testing.T) {
+	mintCredentialsOrig := mintCredentials
+	defer func() { mintCredentials = mintCredentialsOrig }()
+	var request scopedcreds.Request
+	mintCredentials = func(r scopedcreds.Request) (scopedcreds.Credentials, error) {
+		request = r
+		return scopedcreds.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN"}, nil
+	}
+
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		testCase := generateTestCaseOk("0")
+		testCase.Input.RoleArn = "arn:aws:iam::123456789012:role/scripts"
+		testCase.Input.SessionPolicy = map[string]interface{}{"Version": "2012-10-17"}
+		testCase.Input.SessionDurationSeconds = "900"
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+
+		credentialsPath := filepath.Join(fileutil.BuildPath(orchestrationDirectory, testCase.Input.ID), credentialsFileName)
+		var environment []string
+		mockExecuter.On("WithEnvironment", mock.Anything).Run(func(args mock.Arguments) {
+			environment = args.Get(0).([]string)
+			assert.True(t, fileutil.Exists(credentialsPath))
+		}).Return(mockExecuter).Once()
+
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+		assert.Equal(t, scopedcreds.Request{RoleArn: "arn:aws:iam::123456789012:role/scripts", SessionPolicy: `{"Version":"2012-10-17"}`, DurationSeconds: 900}, request)
+		assert.Contains(t, environment, "AWS_ACCESS_KEY_ID=AKID")
+		assert.Contains(t, environment, "AWS_SHARED_CREDENTIALS_FILE="+credentialsPath)
+		// the credentials do not outlive the commands
+		assert.False(t, fileutil.Exists(credentialsPath))
+	}
+	testExecution(t, executeTester)
+}
+
+func TestSessionPolicyWithoutRole(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("SessionPolicy requires RoleArn")).Return().Once()
+
+		testCase := generateTestCaseOk("0")
+		testCase.Input.SessionPolicy = `{"Version":"2012-10-17"}`
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestTempDirectory(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		testCase := generateTestCaseOk("0")
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		setExecuterExpectations(mockExecuter, testCase, mockCancelFlag, p)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+		mockExecuter.On("WithEnvironment", tempdir.Environment("/tmpdir")).Return(mockExecuter).Once()
+
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+				TempDirectory:          "/tmpdir",
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
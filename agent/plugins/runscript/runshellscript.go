@@ -35,12 +35,14 @@ var shellArgs = []string{"-c"}
 func NewRunShellPlugin(log log.T) (*runShellPlugin, error) {
 	shplugin := runShellPlugin{
 		Plugin{
-			Name:            appconfig.PluginNameAwsRunShellScript,
-			ScriptName:      shellScriptName,
-			ShellCommand:    shellCommand,
-			ShellArguments:  shellArgs,
-			ByteOrderMark:   fileutil.ByteOrderMarkSkip,
-			CommandExecuter: executers.ShellCommandExecuter{},
+			Name:                appconfig.PluginNameAwsRunShellScript,
+			ScriptName:          shellScriptName,
+			ShellCommand:        shellCommand,
+			ShellArguments:      shellArgs,
+			ByteOrderMark:       fileutil.ByteOrderMarkSkip,
+			CommandExecuter:     executers.ShellCommandExecuter{},
+			EnvironmentVariable: shVariable,
+			HonorShebang:        true,
 		},
 	}
 
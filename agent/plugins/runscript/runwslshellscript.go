@@ -0,0 +1,87 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+// RunWslShellScript contains implementation of the plugin that runs shell scripts in a WSL distribution on windows
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+// wslCommand is the path of wsl.exe, present when the Windows Subsystem for Linux is installed
+var wslCommand = filepath.Join(os.Getenv("SystemRoot"), "System32", "wsl.exe")
+
+// wslMountRoot is the directory the distributions mount the Windows drives under
+var wslMountRoot = "/mnt"
+
+// WslAvailable returns true if WSL is installed and a distribution is configured to run the shell scripts.
+func WslAvailable(config appconfig.WslCfg) bool {
+	return config.Distribution != "" && fileutil.Exists(wslCommand)
+}
+
+// NewRunWslShellPlugin returns a new instance of the RunShellScript plugin running the scripts in the WSL distribution.
+func NewRunWslShellPlugin(config appconfig.WslCfg) (*runShellPlugin, error) {
+	shplugin := runShellPlugin{
+		Plugin{
+			Name:            appconfig.PluginNameAwsRunShellScript,
+			ScriptName:      shellScriptName,
+			ShellCommand:    wslCommand,
+			ByteOrderMark:   fileutil.ByteOrderMarkSkip,
+			CommandExecuter: executers.ShellCommandExecuter{},
+			Command:         wslShellCommand(config),
+		},
+	}
+
+	return &shplugin, nil
+}
+
+// wslShellCommand returns the arguments of wsl.exe running the script with sh as the configured user of the
+// distribution, in the working directory mapped under the mount of the Windows drives.
+func wslShellCommand(config appconfig.WslCfg) func(workingDir string, scriptPath string) ([]string, error) {
+	return func(workingDir string, scriptPath string) ([]string, error) {
+		arguments := []string{"--distribution", config.Distribution}
+		if config.User != "" {
+			arguments = append(arguments, "--user", config.User)
+		}
+		if workingDir != "" {
+			linuxWorkingDir, err := wslPath(workingDir)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, "--cd", linuxWorkingDir)
+		}
+		linuxScriptPath, err := wslPath(scriptPath)
+		if err != nil {
+			return nil, err
+		}
+		return append(arguments, "--exec", shellCommand, linuxScriptPath), nil
+	}
+}
+
+// wslPath returns the path of a file of a Windows drive in the distributions, C:\Windows is /mnt/c/Windows.
+func wslPath(windowsPath string) (string, error) {
+	if len(windowsPath) < 3 || !unicode.IsLetter(rune(windowsPath[0])) || windowsPath[1] != ':' || (windowsPath[2] != '\\' && windowsPath[2] != '/') {
+		return "", fmt.Errorf("%v is not an absolute path on a drive mounted in WSL", windowsPath)
+	}
+	return wslMountRoot + "/" + strings.ToLower(windowsPath[:1]) + strings.Replace(windowsPath[2:], `\`, "/", -1), nil
+}
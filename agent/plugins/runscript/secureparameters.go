@@ -0,0 +1,161 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+// secureparameters contains the resolution of the SecureString parameters referenced by the commands
+package runscript
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+)
+
+const (
+	// secureParameterVariablePrefix starts the names of the environment variables holding the SecureString parameters
+	secureParameterVariablePrefix = "SSM_SECURE_"
+
+	// secureParameterMask replaces the values of the SecureString parameters in the output of the commands
+	secureParameterMask = "********"
+
+	// maxMaskedLineLength is the length of output after which a line without end is masked and written
+	maxMaskedLineLength = 64 * 1024
+)
+
+// secureParameterReference matches the references to SecureString parameters, {{ ssm-secure:name }}
+var secureParameterReference = regexp.MustCompile(`{{\s*(ssm-secure:[\w-/]+)\s*}}`)
+
+// resolveSecureParameters returns the decrypted SecureString parameters of the references, it is stubbed in the tests
+var resolveSecureParameters = func(log log.T, references []string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+	service := ssmparameterresolver.NewService()
+	return ssmparameterresolver.ResolveParameterReferenceList(&service, log, references, ssmparameterresolver.ResolveOptions{})
+}
+
+// shVariable returns the reference of sh to the environment variable
+func shVariable(name string) string {
+	return "${" + name + "}"
+}
+
+// powerShellVariable returns the reference of PowerShell to the environment variable
+func powerShellVariable(name string) string {
+	return "$env:" + name
+}
+
+// secureParameters replaces the references to SecureString parameters in the commands with references to environment
+// variables, so that the script files never hold the values. It returns the commands, the name=value environment
+// variables holding the decrypted values and the values.
+func secureParameters(log log.T, commands []string, variable func(name string) string) ([]string, []string, []string, error) {
+	references := map[string]bool{}
+	for _, command := range commands {
+		for _, match := range secureParameterReference.FindAllStringSubmatch(command, -1) {
+			references[match[1]] = true
+		}
+	}
+	if len(references) == 0 {
+		return commands, nil, nil, nil
+	}
+	if variable == nil {
+		return nil, nil, nil, fmt.Errorf("SecureString parameters cannot be referenced by the commands of this plugin")
+	}
+
+	sortedReferences := make([]string, 0, len(references))
+	for reference := range references {
+		sortedReferences = append(sortedReferences, reference)
+	}
+	sort.Strings(sortedReferences)
+
+	parameters, err := resolveSecureParameters(log, sortedReferences)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to resolve the SecureString parameters: %v", err)
+	}
+
+	names := map[string]string{}
+	var environment, values []string
+	for i, reference := range sortedReferences {
+		parameter, ok := parameters[reference]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("failed to resolve the SecureString parameter %v", reference)
+		}
+		names[reference] = secureParameterVariablePrefix + strconv.Itoa(i+1)
+		environment = append(environment, names[reference]+"="+parameter.Value)
+		if parameter.Value != "" {
+			values = append(values, parameter.Value)
+		}
+	}
+
+	resolved := make([]string, len(commands))
+	for i, command := range commands {
+		resolved[i] = secureParameterReference.ReplaceAllStringFunc(command, func(match string) string {
+			return variable(names[secureParameterReference.FindStringSubmatch(match)[1]])
+		})
+	}
+	return resolved, environment, values, nil
+}
+
+// maskingWriter replaces the values in the lines written to the underlying writer by secureParameterMask
+type maskingWriter struct {
+	writer io.Writer
+	values []string
+	line   []byte
+}
+
+// newMaskingWriter returns a writer masking the values in the output written to writer
+func newMaskingWriter(writer io.Writer, values []string) *maskingWriter {
+	// the longest values are masked first, in case they contain shorter ones
+	values = append([]string{}, values...)
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return &maskingWriter{writer: writer, values: values}
+}
+
+// Write writes the complete lines of the output with the values masked, and keeps the rest until the line ends.
+func (w *maskingWriter) Write(p []byte) (int, error) {
+	w.line = append(w.line, p...)
+	end := bytes.LastIndexByte(w.line, '\n') + 1
+	if end == 0 && len(w.line) > maxMaskedLineLength {
+		end = len(w.line)
+	}
+	if end > 0 {
+		if err := w.write(w.line[:end]); err != nil {
+			return 0, err
+		}
+		w.line = append(w.line[:0], w.line[end:]...)
+	}
+	return len(p), nil
+}
+
+// Flush writes the rest of the output with the values masked.
+func (w *maskingWriter) Flush() error {
+	if len(w.line) == 0 {
+		return nil
+	}
+	err := w.write(w.line)
+	w.line = w.line[:0]
+	return err
+}
+
+func (w *maskingWriter) write(output []byte) error {
+	text := string(output)
+	for _, value := range w.values {
+		text = strings.Replace(text, value, secureParameterMask, -1)
+	}
+	_, err := io.WriteString(w.writer, text)
+	return err
+}
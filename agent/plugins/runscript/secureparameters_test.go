@@ -0,0 +1,126 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubSecureParameters resolves the references to the values and returns the function restoring the resolver
+func stubSecureParameters(values map[string]string) func() {
+	resolveSecureParametersOrig := resolveSecureParameters
+	resolveSecureParameters = func(log log.T, references []string) (map[string]ssmparameterresolver.SsmParameterInfo, error) {
+		parameters := map[string]ssmparameterresolver.SsmParameterInfo{}
+		for _, reference := range references {
+			if value, ok := values[reference]; ok {
+				parameters[reference] = ssmparameterresolver.SsmParameterInfo{Name: reference, Type: "SecureString", Value: value}
+			}
+		}
+		return parameters, nil
+	}
+	return func() { resolveSecureParameters = resolveSecureParametersOrig }
+}
+
+func TestSecureParameters(t *testing.T) {
+	defer stubSecureParameters(map[string]string{"ssm-secure:/db/password": "hunter2", "ssm-secure:token": "t0ken"})()
+
+	commands, environment, values, err := secureParameters(logger, []string{
+		`mysql --password="{{ ssm-secure:/db/password }}"`,
+		`curl -H "Authorization: {{ssm-secure:token}}" -u "{{ssm-secure:/db/password}}"`,
+		"echo {{ ssm:plain }}",
+	}, shVariable)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`mysql --password="${SSM_SECURE_1}"`,
+		`curl -H "Authorization: ${SSM_SECURE_2}" -u "${SSM_SECURE_1}"`,
+		"echo {{ ssm:plain }}",
+	}, commands)
+	assert.Equal(t, []string{"SSM_SECURE_1=hunter2", "SSM_SECURE_2=t0ken"}, environment)
+	assert.Equal(t, []string{"hunter2", "t0ken"}, values)
+
+	commands, _, _, err = secureParameters(logger, []string{`Connect -Password "{{ ssm-secure:token }}"`}, powerShellVariable)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`Connect -Password "$env:SSM_SECURE_1"`}, commands)
+
+	_, _, _, err = secureParameters(logger, []string{"echo {{ ssm-secure:missing }}"}, shVariable)
+	assert.Error(t, err)
+
+	_, _, _, err = secureParameters(logger, []string{"echo {{ ssm-secure:token }}"}, nil)
+	assert.Error(t, err)
+
+	commands, environment, _, err = secureParameters(logger, []string{"echo plain"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo plain"}, commands)
+	assert.Empty(t, environment)
+}
+
+func TestMaskingWriter(t *testing.T) {
+	var output bytes.Buffer
+	writer := newMaskingWriter(&output, []string{"hunter2", "hunter2hunter2"})
+
+	writer.Write([]byte("password: hun"))
+	assert.Equal(t, "", output.String())
+	writer.Write([]byte("ter2\ndouble: hunter2hunter2\nrest: hunter2"))
+	assert.Equal(t, "password: ********\ndouble: ********\n", output.String())
+	writer.Flush()
+	assert.Equal(t, "password: ********\ndouble: ********\nrest: ********", output.String())
+}
+
+func TestSecureParametersNotWritten(t *testing.T) {
+	defer stubSecureParameters(map[string]string{"ssm-secure:/db/password": "hunter2"})()
+
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.EnvironmentVariable = shVariable
+		p.ByteOrderMark = fileutil.ByteOrderMarkSkip
+		testCase := generateTestCaseOk("0")
+		testCase.Input.RunCommand = []string{`echo "{{ ssm-secure:/db/password }}"`}
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		mockIOHandler.On("GetStdoutWriter").Return(testCase.Output.StdoutWriter)
+		mockIOHandler.On("GetStderrWriter").Return(testCase.Output.StderrWriter)
+		mockIOHandler.On("SetExitCode", 0).Return()
+		mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+		mockExecuter.On("WithEnvironment", []string{"SSM_SECURE_1=hunter2"}).Return(mockExecuter).Once()
+		// the commands write to the writers masking the values
+		mockExecuter.On("NewExecute", mock.Anything, testCase.Input.WorkingDirectory, mock.AnythingOfType("*runscript.maskingWriter"), mock.AnythingOfType("*runscript.maskingWriter"), mockCancelFlag, mock.Anything, mock.Anything, mock.Anything).Return(0, nil)
+
+		p.Execute(
+			context.NewMockDefault(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+
+		script, err := ioutil.ReadFile(filepath.Join(orchestrationDirectory, "0.awsrunScript", p.ScriptName))
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintln(`echo "${SSM_SECURE_1}"`), string(script))
+	}
+	testExecution(t, executeTester)
+}
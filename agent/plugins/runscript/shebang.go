@@ -0,0 +1,89 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// posixShells are the interpreters the references to SecureString parameters are replaced with shell variables for
+var posixShells = map[string]bool{"sh": true, "ash": true, "bash": true, "dash": true, "ksh": true, "mksh": true, "zsh": true}
+
+// shebang returns the interpreter and its arguments named by the shebang line of the commands, the arguments are split on
+// white spaces, and an empty interpreter when the commands do not start with a shebang line.
+func shebang(commands []string) (string, []string) {
+	line := strings.SplitN(strings.Join(commands, "\n"), "\n", 2)[0]
+	if !strings.HasPrefix(line, "#!") {
+		return "", nil
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// program returns the program the interpreter runs the script with, the first argument of env which is not an option
+// when the interpreter is env.
+func program(interpreter string, arguments []string) (string, error) {
+	if filepath.Base(interpreter) != "env" {
+		return interpreter, nil
+	}
+	for _, argument := range arguments {
+		if !strings.HasPrefix(argument, "-") {
+			return argument, nil
+		}
+	}
+	return "", fmt.Errorf("shebang line %v names no interpreter", interpreter)
+}
+
+// isAllowedInterpreter returns true if the program is one of the allowed interpreters, the entries naming a file name
+// allow the program in any directory.
+func isAllowedInterpreter(program string, allowedInterpreters []string) bool {
+	for _, allowed := range allowedInterpreters {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == program || (!strings.ContainsRune(allowed, '/') && allowed == filepath.Base(program)) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpreter returns the command and the arguments before the script path running the commands, and the references of
+// the interpreter to the environment variables. The commands run with the interpreter of their shebang line when it is
+// allowed, and with the shell of the plugin otherwise.
+func (p *Plugin) interpreter(commands []string) (string, []string, func(name string) string, error) {
+	if len(p.allowedInterpreters) == 0 {
+		return p.ShellCommand, p.ShellArguments, p.EnvironmentVariable, nil
+	}
+	interpreter, arguments := shebang(commands)
+	if interpreter == "" {
+		return p.ShellCommand, p.ShellArguments, p.EnvironmentVariable, nil
+	}
+	name, err := program(interpreter, arguments)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if !isAllowedInterpreter(name, p.allowedInterpreters) {
+		return "", nil, nil, fmt.Errorf("interpreter %v of the shebang line is not one of the ShellScript.AllowedInterpreters of the agent config", name)
+	}
+	if !posixShells[filepath.Base(name)] {
+		return interpreter, arguments, nil, nil
+	}
+	return interpreter, arguments, p.EnvironmentVariable, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShebang(t *testing.T) {
+	interpreter, arguments := shebang([]string{"#!/usr/bin/env  python3 -u\nprint(1)", "print(2)"})
+	assert.Equal(t, "/usr/bin/env", interpreter)
+	assert.Equal(t, []string{"python3", "-u"}, arguments)
+
+	interpreter, _ = shebang([]string{"echo '#!/bin/bash'"})
+	assert.Empty(t, interpreter)
+	interpreter, _ = shebang([]string{"#!", "echo 1"})
+	assert.Empty(t, interpreter)
+	interpreter, _ = shebang(nil)
+	assert.Empty(t, interpreter)
+}
+
+func TestProgram(t *testing.T) {
+	name, err := program("/bin/bash", []string{"-e"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin/bash", name)
+
+	name, err = program("/usr/bin/env", []string{"-S", "python3", "-u"})
+	assert.NoError(t, err)
+	assert.Equal(t, "python3", name)
+
+	_, err = program("/usr/bin/env", []string{"-i"})
+	assert.Error(t, err)
+}
+
+func TestIsAllowedInterpreter(t *testing.T) {
+	allowed := []string{"bash", " python3 ", "/opt/perl/bin/perl"}
+	assert.True(t, isAllowedInterpreter("/bin/bash", allowed))
+	assert.True(t, isAllowedInterpreter("bash", allowed))
+	assert.True(t, isAllowedInterpreter("/usr/bin/python3", allowed))
+	assert.True(t, isAllowedInterpreter("/opt/perl/bin/perl", allowed))
+	assert.False(t, isAllowedInterpreter("/usr/bin/perl", allowed))
+	assert.False(t, isAllowedInterpreter("perl", allowed))
+	assert.False(t, isAllowedInterpreter("/bin/zsh", allowed))
+}
+
+// shebangContext returns a context whose agent config allows the interpreters
+func shebangContext(allowedInterpreters ...string) context.T {
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(appconfig.SsmagentConfig{ShellScript: appconfig.ShellScriptCfg{AllowedInterpreters: allowedInterpreters}})
+	return ctx
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.HonorShebang = true
+		testCase := generateTestCaseOk("0")
+		testCase.Input.RunCommand = []string{"#!/usr/bin/env python3", "print(0)"}
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+		mockExecuter.On("NewExecute", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter, mockCancelFlag, mock.Anything, "/usr/bin/env",
+			mock.MatchedBy(func(arguments []string) bool {
+				return len(arguments) == 2 && arguments[0] == "python3" && arguments[1] != "-c"
+			})).Return(testCase.Output.ExitCode, nil).Once()
+
+		p.Execute(
+			shebangContext("python3"),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestShebangIgnored(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.HonorShebang = true
+		testCase := generateTestCaseOk("0")
+		testCase.Input.RunCommand = []string{"#!/bin/bash", "echo 0"}
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		setIOHandlerExpectations(mockIOHandler, testCase)
+		// the commands run with sh when the agent config allows no interpreter
+		mockExecuter.On("NewExecute", mock.Anything, testCase.Input.WorkingDirectory, testCase.Output.StdoutWriter, testCase.Output.StderrWriter, mockCancelFlag, mock.Anything, "sh",
+			mock.MatchedBy(func(arguments []string) bool {
+				return len(arguments) == 2 && arguments[0] == "-c"
+			})).Return(testCase.Output.ExitCode, nil).Once()
+
+		p.Execute(
+			shebangContext(),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestShebangInterpreterNotAllowed(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.HonorShebang = true
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		// the commands are not run with an interpreter the agent config does not allow
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("interpreter /usr/bin/perl of the shebang line is not one of the ShellScript.AllowedInterpreters of the agent config")).Return().Once()
+
+		testCase := generateTestCaseOk("0")
+		testCase.Input.RunCommand = []string{"#!/usr/bin/perl", "print 0"}
+		p.Execute(
+			shebangContext("bash", "python3"),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
+
+func TestShebangSecureParameters(t *testing.T) {
+	executeTester := func(p *Plugin, mockCancelFlag *task.MockCancelFlag, mockExecuter *executers.MockCommandExecuter, mockIOHandler *iohandlermocks.MockIOHandler) {
+		p.HonorShebang = true
+		p.EnvironmentVariable = shVariable
+		setCancelFlagExpectations(mockCancelFlag, 1)
+		// the interpreters which are not shells cannot reference SecureString parameters
+		mockIOHandler.On("MarkAsFailed", fmt.Errorf("SecureString parameters cannot be referenced by the commands of this plugin")).Return().Once()
+
+		testCase := generateTestCaseOk("0")
+		testCase.Input.RunCommand = []string{"#!/usr/bin/python3", "print('{{ssm-secure:password}}')"}
+		p.Execute(
+			shebangContext("python3"),
+			contracts.Configuration{
+				Properties:             singleValuePropertyBuilder(t, testCase),
+				OrchestrationDirectory: orchestrationDirectory,
+				PluginID:               pluginID,
+			}, mockCancelFlag, mockIOHandler)
+	}
+	testExecution(t, executeTester)
+}
@@ -0,0 +1,223 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runwasm implements the aws:runWasmModule plugin, running WebAssembly modules downloaded by aws:downloadContent
+// in the WASI runtime embedded in the agent. The modules only get the directories and the environment variables the
+// document grants, and no socket.
+package runwasm
+
+import (
+	gocontext "context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	// guestDirSeparator separates the host directory from the directory the module sees it as, /data::/mnt/data
+	guestDirSeparator = "::"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Plugin is the type for the aws:runWasmModule plugin.
+type Plugin struct{}
+
+// RunWasmPluginInput represents one module run by the plugin.
+type RunWasmPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Module is the path of the .wasm file relative to the downloads of aws:downloadContent
+	Module    string
+	Arguments []string
+	// PreopenedDirectories are the host directories the module can access, as <host> or <host>::<guest>
+	PreopenedDirectories []string
+	EnvironmentVariables map[string]string
+	TimeoutSeconds       interface{}
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	return &Plugin{}, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameAwsRunWasmModule
+}
+
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.runModuleRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, cancelFlag, output)
+	}
+	return
+}
+
+// runModuleRawInput validates the raw plugin input and runs the module.
+func (p *Plugin) runModuleRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var pluginInput RunWasmPluginInput
+	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
+	log.Debugf("Plugin input %v", pluginInput)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err))
+		return
+	}
+	p.runModule(log, pluginID, pluginInput, orchestrationDirectory, cancelFlag, output)
+}
+
+// runModule runs the module until it exits, times out or the document is canceled, and reports its exit code.
+func (p *Plugin) runModule(log log.T, pluginID string, pluginInput RunWasmPluginInput, orchestrationDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	modulePath, err := downloadedModule(filepath.Join(strings.TrimSuffix(orchestrationDirectory, pluginID), downloadsDir), pluginInput.Module)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	module, err := ioutil.ReadFile(modulePath)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("WebAssembly module %v not found, download it with %v", pluginInput.Module, appconfig.PluginDownloadContent))
+		return
+	}
+
+	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), time.Duration(executionTimeout)*time.Second)
+	defer cancel()
+	go func() {
+		if state := cancelFlag.Wait(); state == task.Canceled || state == task.ShutDown {
+			cancel()
+		}
+	}()
+
+	moduleConfig, err := moduleConfig(pluginInput, output)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	log.Debugf("Running the WebAssembly module %v", modulePath)
+	exitCode := appconfig.SuccessExitCode
+	if err = run(ctx, module, moduleConfig); err != nil {
+		var exitErr *sys.ExitError
+		switch {
+		case ctx.Err() != nil:
+			exitCode = appconfig.CommandStoppedPreemptivelyExitCode
+		case errors.As(err, &exitErr):
+			exitCode = int(exitErr.ExitCode())
+		default:
+			exitCode = 1
+			output.AppendErrorf("failed to run the WebAssembly module: %v", err)
+		}
+	}
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+}
+
+// downloadedModule returns the path of the module under the downloads folder of the document, the modules cannot be
+// read from anywhere else on the host, through a link either.
+func downloadedModule(downloadsPath string, module string) (string, error) {
+	if module == "" || filepath.IsAbs(module) {
+		return "", fmt.Errorf("WebAssembly module %q is not a path relative to the downloads of %v", module, appconfig.PluginDownloadContent)
+	}
+	downloadsPath, err := filepath.EvalSymlinks(downloadsPath)
+	if err != nil {
+		return "", fmt.Errorf("WebAssembly module %v not found, download it with %v", module, appconfig.PluginDownloadContent)
+	}
+	modulePath, err := filepath.EvalSymlinks(filepath.Join(downloadsPath, module))
+	if err != nil {
+		return "", fmt.Errorf("WebAssembly module %v not found, download it with %v", module, appconfig.PluginDownloadContent)
+	}
+	if relativePath, err := filepath.Rel(downloadsPath, modulePath); err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("WebAssembly module %v is outside of the downloads of %v", module, appconfig.PluginDownloadContent)
+	}
+	return modulePath, nil
+}
+
+// moduleConfig returns the config granting the module the capabilities of the input, the module gets no directory and
+// no environment variable of the host without them.
+func moduleConfig(pluginInput RunWasmPluginInput, output iohandler.IOHandler) (wazero.ModuleConfig, error) {
+	fsConfig := wazero.NewFSConfig()
+	for _, dir := range pluginInput.PreopenedDirectories {
+		dirs := strings.SplitN(dir, guestDirSeparator, 2)
+		hostDir, guestDir := dirs[0], dirs[0]
+		if len(dirs) == 2 {
+			guestDir = dirs[1]
+		}
+		if !filepath.IsAbs(hostDir) {
+			return nil, fmt.Errorf("preopened directory %v is not an absolute path", hostDir)
+		}
+		fsConfig = fsConfig.WithDirMount(hostDir, guestDir)
+	}
+
+	config := wazero.NewModuleConfig().
+		WithArgs(append([]string{filepath.Base(pluginInput.Module)}, pluginInput.Arguments...)...).
+		WithStdout(output.GetStdoutWriter()).
+		WithStderr(output.GetStderrWriter()).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+
+	names := make([]string, 0, len(pluginInput.EnvironmentVariables))
+	for name := range pluginInput.EnvironmentVariables {
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%q is not a valid environment variable name", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		config = config.WithEnv(name, pluginInput.EnvironmentVariables[name])
+	}
+	return config, nil
+}
+
+// run compiles and runs the module in a runtime of its own, which is closed when the context is done.
+func run(ctx gocontext.Context, module []byte, config wazero.ModuleConfig) error {
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer runtime.Close(gocontext.Background())
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	compiled, err := runtime.CompileModule(ctx, module)
+	if err != nil {
+		return err
+	}
+	_, err = runtime.InstantiateModule(ctx, compiled, config)
+	return err
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runwasm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+const pluginID = "aws:runWasmModule"
+
+// exitModule is a WASI module whose _start calls proc_exit with exitCode, below 64.
+func exitModule(exitCode byte) []byte {
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	// types (i32) -> () and () -> ()
+	module = append(module, 0x01, 0x08, 0x02, 0x60, 0x01, 0x7f, 0x00, 0x60, 0x00, 0x00)
+	// import wasi_snapshot_preview1.proc_exit
+	module = append(module, 0x02, 0x24, 0x01, 0x16)
+	module = append(module, "wasi_snapshot_preview1"...)
+	module = append(module, 0x09)
+	module = append(module, "proc_exit"...)
+	module = append(module, 0x00, 0x00)
+	// function 1 of type () -> (), exported as _start
+	module = append(module, 0x03, 0x02, 0x01, 0x01, 0x07, 0x0a, 0x01, 0x06)
+	module = append(module, "_start"...)
+	module = append(module, 0x00, 0x01)
+	// i32.const exitCode, call 0
+	return append(module, 0x0a, 0x08, 0x01, 0x06, 0x00, 0x41, exitCode, 0x10, 0x00, 0x0b)
+}
+
+// loopModule is a module whose _start never returns.
+var loopModule = []byte("\x00asm\x01\x00\x00\x00" +
+	"\x01\x04\x01\x60\x00\x00" +
+	"\x03\x02\x01\x00" +
+	"\x07\x0a\x01\x06_start\x00\x00" +
+	"\x0a\x09\x01\x07\x00\x03\x40\x0c\x00\x0b\x0b")
+
+// runWith runs the plugin with a module downloaded under the orchestration directory of the document.
+func runWith(t *testing.T, input RunWasmPluginInput, module []byte, cancelFlag task.CancelFlag) *iohandler.DefaultIOHandler {
+	documentDir, err := ioutil.TempDir("", "runwasm")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(documentDir) })
+	modulePath := filepath.Join(documentDir, downloadsDir, "check.wasm")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(modulePath), 0700))
+	assert.NoError(t, ioutil.WriteFile(modulePath, module, 0600))
+
+	output := &iohandler.DefaultIOHandler{}
+	(&Plugin{}).runModule(logger, pluginID, input, filepath.Join(documentDir, pluginID), cancelFlag, output)
+	return output
+}
+
+func TestRunModule(t *testing.T) {
+	output := runWith(t, RunWasmPluginInput{ID: "0.aws:runWasmModule", Module: "check.wasm"}, exitModule(0), task.NewChanneledCancelFlag())
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, 0, output.GetExitCode())
+
+	output = runWith(t, RunWasmPluginInput{ID: "0.aws:runWasmModule", Module: "check.wasm"}, exitModule(3), task.NewChanneledCancelFlag())
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, 3, output.GetExitCode())
+
+	output = runWith(t, RunWasmPluginInput{ID: "0.aws:runWasmModule", Module: "check.wasm"}, []byte("\x00asm"), task.NewChanneledCancelFlag())
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), "failed to run the WebAssembly module")
+}
+
+func TestRunModuleCanceled(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	time.AfterFunc(100*time.Millisecond, func() { cancelFlag.Set(task.Canceled) })
+	output := runWith(t, RunWasmPluginInput{ID: "0.aws:runWasmModule", Module: "check.wasm"}, loopModule, cancelFlag)
+	assert.Equal(t, contracts.ResultStatusCancelled, output.GetStatus())
+}
+
+func TestRunModuleNotDownloaded(t *testing.T) {
+	for _, module := range []string{"", "missing.wasm", "../check.wasm", "../../etc/passwd", "/etc/passwd"} {
+		output := runWith(t, RunWasmPluginInput{ID: "0.aws:runWasmModule", Module: module}, exitModule(0), task.NewChanneledCancelFlag())
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus(), module)
+	}
+}
+
+func TestDownloadedModule(t *testing.T) {
+	documentDir, err := ioutil.TempDir("", "runwasm")
+	assert.NoError(t, err)
+	defer os.RemoveAll(documentDir)
+	downloadsPath := filepath.Join(documentDir, downloadsDir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(downloadsPath, "bin"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(downloadsPath, "bin", "check.wasm"), exitModule(0), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(documentDir, "outside.wasm"), exitModule(0), 0600))
+
+	modulePath, err := downloadedModule(downloadsPath, "bin/../bin/check.wasm")
+	assert.NoError(t, err)
+	assert.Equal(t, "check.wasm", filepath.Base(modulePath))
+
+	_, err = downloadedModule(downloadsPath, "../outside.wasm")
+	assert.Error(t, err)
+	_, err = downloadedModule(downloadsPath, filepath.Join(documentDir, "outside.wasm"))
+	assert.Error(t, err)
+
+	if os.Symlink(filepath.Join(documentDir, "outside.wasm"), filepath.Join(downloadsPath, "link.wasm")) == nil {
+		_, err = downloadedModule(downloadsPath, "link.wasm")
+		assert.Error(t, err, "links out of the downloads are not followed")
+	}
+}
+
+func TestModuleConfig(t *testing.T) {
+	output := &iohandler.DefaultIOHandler{}
+	_, err := moduleConfig(RunWasmPluginInput{
+		PreopenedDirectories: []string{"/var/lib/app", "/var/log/app::/logs"},
+		EnvironmentVariables: map[string]string{"STAGE": "prod", "LEVEL": "debug"},
+	}, output)
+	assert.NoError(t, err)
+
+	_, err = moduleConfig(RunWasmPluginInput{PreopenedDirectories: []string{"data"}}, output)
+	assert.Error(t, err)
+	_, err = moduleConfig(RunWasmPluginInput{EnvironmentVariables: map[string]string{"A=B": "c"}}, output)
+	assert.Error(t, err)
+}
@@ -11,7 +11,8 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-//+build windows
+//go:build windows
+// +build windows
 
 // Package updateec2config implements the UpdateEC2Config plugin.
 package updateec2config
@@ -60,11 +61,8 @@ const (
 	//S3 format for updater
 	S3Format = "https://s3.amazonaws.com/aws-ssm-{Region}"
 
-	// CommonManifestURL is the URL for the manifest file in regular regions
-	CommonManifestURL = "https://s3.{Region}.amazonaws.com/aws-ssm-{Region}/manifest.json"
-
-	// ChinaManifestURL is the URL for the manifest in regions in China
-	ChinaManifestURL = "https://s3.{Region}.amazonaws.com.cn/aws-ssm-{Region}/manifest.json"
+	// ManifestURL is the URL for the manifest file, the domain is the one of the partition of the region
+	ManifestURL = "https://s3.{Region}.{DnsSuffix}/aws-ssm-{Region}/manifest.json"
 )
 
 // update context constant strings
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package updateec2config implements the UpdateEC2Config plugin.
@@ -35,7 +36,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
-	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 )
@@ -63,7 +63,7 @@ type UpdatePluginConfig struct {
 
 type updateManager struct{}
 
-//TODO move the interface and structs into a separate file to reduce the size of this main file
+// TODO move the interface and structs into a separate file to reduce the size of this main file
 // pluginHelper is a interface that has helper functions for update manager
 type pluginHelper interface {
 	generateSetupUpdateCmd(log log.T,
@@ -545,12 +545,7 @@ func GetUpdatePluginConfig(context context.T) UpdatePluginConfig {
 		log.Errorf("Error retrieving agent region in update plugin config. error: %v", err)
 	}
 
-	var manifestURL string
-	if strings.HasPrefix(region, s3util.ChinaRegionPrefix) {
-		manifestURL = ChinaManifestURL
-	} else {
-		manifestURL = CommonManifestURL
-	}
+	manifestURL := strings.Replace(ManifestURL, updateutil.DnsSuffixHolder, appconfig.GetPartition(region).DnsSuffix, -1)
 
 	return UpdatePluginConfig{
 		ManifestLocation: manifestURL,
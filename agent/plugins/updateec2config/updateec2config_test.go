@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build windows
 // +build windows
 
 // Package updateec2config implements the UpdateEC2Config plugin.
@@ -25,10 +26,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-//mock log for testing
+// mock log for testing
 var logger = log.NewMockLog()
 
-//TestGenerateUpdateCmd tests the function generateUpdateCmd
+// TestGenerateUpdateCmd tests the function generateUpdateCmd
 func TestGenerateUpdateCmd(t *testing.T) {
 	manager := updateManager{}
 
@@ -39,7 +40,7 @@ func TestGenerateUpdateCmd(t *testing.T) {
 	assert.Contains(t, result, "history")
 }
 
-//TestValidateUpdate tests the function validateUpdate
+// TestValidateUpdate tests the function validateUpdate
 func TestValidateUpdate(t *testing.T) {
 	plugin := createStubPluginInput()
 	context := createStubInstanceContext()
@@ -55,7 +56,7 @@ func TestValidateUpdate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-//TestValidateUpdate_GetLatestTargetVersionWhenTargetVersionIsEmpty tests negative case
+// TestValidateUpdate_GetLatestTargetVersionWhenTargetVersionIsEmpty tests negative case
 func TestValidateUpdate_GetLatestTargetVersionWhenTargetVersionIsEmpty(t *testing.T) {
 	plugin := createStubPluginInput()
 	context := createStubInstanceContext()
@@ -73,7 +74,7 @@ func TestValidateUpdate_GetLatestTargetVersionWhenTargetVersionIsEmpty(t *testin
 	assert.NoError(t, err)
 }
 
-//TestValidateUpdate_DowngradeVersion tests negative case
+// TestValidateUpdate_DowngradeVersion tests negative case
 func TestValidateUpdate_DowngradeVersion(t *testing.T) {
 	plugin := createStubPluginInput()
 	plugin.AllowDowngrade = "false"
@@ -92,7 +93,7 @@ func TestValidateUpdate_DowngradeVersion(t *testing.T) {
 	assert.Contains(t, err.Error(), "please enable allow downgrade to proceed")
 }
 
-//TestValidateUpdate_UnsupportedTargetVersion tests negative case
+// TestValidateUpdate_UnsupportedTargetVersion tests negative case
 func TestValidateUpdate_UnsupportedTargetVersion(t *testing.T) {
 	plugin := createStubPluginInput()
 	plugin.TargetVersion = "1.2.3"
@@ -110,7 +111,7 @@ func TestValidateUpdate_UnsupportedTargetVersion(t *testing.T) {
 	assert.Contains(t, err.Error(), "is unsupported")
 }
 
-//TestValidateUpdate_TargetVersionSameAsCurrentVersion tests invalid case
+// TestValidateUpdate_TargetVersionSameAsCurrentVersion tests invalid case
 func TestValidateUpdate_TargetVersionSameAsCurrentVersion(t *testing.T) {
 	plugin := createStubPluginInput()
 	//plugin.TargetVersion = fakeAgentVersion
@@ -127,7 +128,7 @@ func TestValidateUpdate_TargetVersionSameAsCurrentVersion(t *testing.T) {
 	assert.Contains(t, out.GetStdout(), "already been installed, update skipped")
 }
 
-//TestValidateUpdate_UnsupportedCurrentVersion tests negative case
+// TestValidateUpdate_UnsupportedCurrentVersion tests negative case
 func TestValidateUpdate_UnsupportedCurrentVersion(t *testing.T) {
 	plugin := createStubPluginInput()
 	context := createStubInstanceContext()
@@ -142,7 +143,7 @@ func TestValidateUpdate_UnsupportedCurrentVersion(t *testing.T) {
 	assert.Contains(t, err.Error(), "is unsupported on current platform")
 }
 
-//createStubPluginInput is a helper function to create a stub plugin for testing
+// createStubPluginInput is a helper function to create a stub plugin for testing
 func createStubPluginInput() *UpdatePluginInput {
 	input := new(UpdatePluginInput)
 
@@ -153,14 +154,14 @@ func createStubPluginInput() *UpdatePluginInput {
 	return input
 }
 
-//createStubManifest is a helper function to create a stub manifest for testing
+// createStubManifest is a helper function to create a stub manifest for testing
 func createStubManifest() *Manifest {
 	manifest := &Manifest{}
 	manifest, _ = ParseManifest(logger, "testData/testManifest.json")
 	return manifest
 }
 
-//createStubInstanceContext is a helper function to create a stub instance for testing
+// createStubInstanceContext is a helper function to create a stub instance for testing
 func createStubInstanceContext() *updateutil.InstanceContext {
 	context := updateutil.InstanceContext{}
 	context.Region = "region"
@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 )
@@ -47,16 +48,24 @@ type FileContent struct {
 type PackageVersion struct {
 	Version  string `json:"Version"`
 	Checksum string `json:"Checksum"`
+	// Channel is the update channel the version is published to, stable when empty
+	Channel string `json:"Channel"`
+	// Deltas are the bsdiff patches producing this version from the package of earlier versions
+	Deltas []*PackageDelta `json:"Deltas"`
+}
+
+// PackageDelta holds the file name and checksum of a patch from an earlier version
+type PackageDelta struct {
+	FromVersion string `json:"FromVersion"`
+	Name        string `json:"Name"`
+	Checksum    string `json:"Checksum"`
 }
 
 const (
 	minimumVersion = "0"
 
-	// CommonManifestURL is the Manifest URL for regular regions
-	CommonManifestURL = "https://s3.{Region}.amazonaws.com/amazon-ssm-{Region}/ssm-agent-manifest.json"
-
-	// ChinaManifestURL is the manifest URL for regions in China
-	ChinaManifestURL = "https://s3.{Region}.amazonaws.com.cn/amazon-ssm-{Region}/ssm-agent-manifest.json"
+	// ManifestURL is the Manifest URL, the domain is the one of the partition of the region
+	ManifestURL = "https://s3.{Region}.{DnsSuffix}/amazon-ssm-{Region}/ssm-agent-manifest.json"
 )
 
 // ParseManifest parses the public manifest file to provide agent update information.
@@ -97,8 +106,13 @@ func (m *Manifest) HasVersion(context *updateutil.InstanceContext, packageName s
 	return false
 }
 
-// LatestVersion returns latest version for specific package
+// LatestVersion returns latest stable version for specific package
 func (m *Manifest) LatestVersion(log log.T, context *updateutil.InstanceContext, packageName string) (result string, err error) {
+	return m.LatestChannelVersion(log, context, packageName, appconfig.UpdateChannelStable)
+}
+
+// LatestChannelVersion returns latest version for specific package available to the update channel
+func (m *Manifest) LatestChannelVersion(log log.T, context *updateutil.InstanceContext, packageName string, channel string) (result string, err error) {
 	var version = minimumVersion
 	var compareResult = 0
 	for _, p := range m.Packages {
@@ -106,6 +120,9 @@ func (m *Manifest) LatestVersion(log log.T, context *updateutil.InstanceContext,
 			for _, f := range p.Files {
 				if f.Name == context.FileName(packageName) {
 					for _, v := range f.AvailableVersions {
+						if !isInChannel(v.Channel, channel) {
+							continue
+						}
 						if compareResult, err = updateutil.VersionCompare(v.Version, version); err != nil {
 							return version, err
 						}
@@ -127,6 +144,11 @@ func (m *Manifest) LatestVersion(log log.T, context *updateutil.InstanceContext,
 	return version, nil
 }
 
+// isInChannel returns if a version published to versionChannel is available to the update channel
+func isInChannel(versionChannel string, channel string) bool {
+	return versionChannel == "" || versionChannel == appconfig.UpdateChannelStable || versionChannel == channel
+}
+
 // DownloadURLAndHash returns download source url and hash value
 func (m *Manifest) DownloadURLAndHash(
 	context *updateutil.InstanceContext,
@@ -140,11 +162,7 @@ func (m *Manifest) DownloadURLAndHash(
 				if f.Name == fileName {
 					for _, v := range f.AvailableVersions {
 						if version == v.Version || version == updateutil.PipelineTestVersion {
-							result = m.URIFormat
-							result = strings.Replace(result, updateutil.RegionHolder, context.Region, -1)
-							result = strings.Replace(result, updateutil.PackageNameHolder, packageName, -1)
-							result = strings.Replace(result, updateutil.PackageVersionHolder, version, -1)
-							result = strings.Replace(result, updateutil.FileNameHolder, f.Name, -1)
+							result = m.downloadURL(context, packageName, version, f.Name)
 							if version == updateutil.PipelineTestVersion {
 								return result, "", nil
 							}
@@ -159,6 +177,45 @@ func (m *Manifest) DownloadURLAndHash(
 	return "", "", fmt.Errorf("incorrect package name or version, %v, %v", packageName, version)
 }
 
+// DeltaURLAndHash returns download source url and hash value of the delta from sourceVersion to targetVersion
+func (m *Manifest) DeltaURLAndHash(
+	context *updateutil.InstanceContext,
+	packageName string,
+	sourceVersion string,
+	targetVersion string) (result string, hash string, err error) {
+	fileName := context.FileName(packageName)
+
+	for _, p := range m.Packages {
+		if p.Name == packageName {
+			for _, f := range p.Files {
+				if f.Name == fileName {
+					for _, v := range f.AvailableVersions {
+						if v.Version != targetVersion {
+							continue
+						}
+						for _, d := range v.Deltas {
+							if d.FromVersion == sourceVersion && d.Name != "" && d.Checksum != "" {
+								return m.downloadURL(context, packageName, targetVersion, d.Name), d.Checksum, nil
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return "", "", fmt.Errorf("no delta of package %v from %v to %v", packageName, sourceVersion, targetVersion)
+}
+
+// downloadURL builds the url of a file of the package from the uri format of the manifest
+func (m *Manifest) downloadURL(context *updateutil.InstanceContext, packageName string, version string, fileName string) string {
+	result := m.URIFormat
+	result = strings.Replace(result, updateutil.RegionHolder, context.Region, -1)
+	result = strings.Replace(result, updateutil.PackageNameHolder, packageName, -1)
+	result = strings.Replace(result, updateutil.PackageVersionHolder, version, -1)
+	return strings.Replace(result, updateutil.FileNameHolder, fileName, -1)
+}
+
 // validateManifest makes sure all the fields are provided.
 func validateManifest(log log.T, parsedManifest *Manifest, context *updateutil.InstanceContext, packageName string) error {
 	if len(parsedManifest.URIFormat) == 0 {
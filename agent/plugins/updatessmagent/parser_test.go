@@ -19,17 +19,18 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/stretchr/testify/assert"
 )
 
-//Valid manifest files
+// Valid manifest files
 var sampleManifests = []string{
 	"testdata/sampleManifest.json",
 }
 
-//Invalid manifest files
+// Invalid manifest files
 var errorManifests = []string{
 	"testdata/errorManifest.json",
 }
@@ -39,7 +40,7 @@ type testCase struct {
 	Output *Manifest
 }
 
-//TestParseManifest testing parses valid manifest files
+// TestParseManifest testing parses valid manifest files
 func TestParseManifest(t *testing.T) {
 	// generate test cases
 	var testCases []testCase
@@ -73,7 +74,7 @@ func TestParseManifest(t *testing.T) {
 	}
 }
 
-//Test ParseManifest with invalid manifest files
+// Test ParseManifest with invalid manifest files
 func TestParseManifestWithError(t *testing.T) {
 	// generate test cases
 	var testCases []testCase
@@ -97,7 +98,42 @@ func TestParseManifestWithError(t *testing.T) {
 	}
 }
 
-//Load specified file from file system
+func TestDeltaURLAndHash(t *testing.T) {
+	context := mockInstanceContext()
+	manifest := loadManifestFromFile(t, "testdata/sampleManifest.json")
+	target := manifest.Packages[0].Files[0].AvailableVersions[1]
+	target.Deltas = []*PackageDelta{
+		{FromVersion: "1.0.178.0", Name: "amazon-ssm-agent-linux-amd64-1.0.178.0.bsdiff", Checksum: "deltahash"},
+	}
+
+	result, hash, err := manifest.DeltaURLAndHash(context, "amazon-ssm-agent", "1.0.178.0", "1.1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://s3.amazonaws.com/ssm-agent-alpha/amazon-ssm-agent/1.1.0.0/amazon-ssm-agent-linux-amd64-1.0.178.0.bsdiff", result)
+	assert.Equal(t, "deltahash", hash)
+
+	_, _, err = manifest.DeltaURLAndHash(context, "amazon-ssm-agent", "0.9.0.0", "1.1.0.0")
+	assert.Error(t, err)
+	_, _, err = manifest.DeltaURLAndHash(context, "amazon-ssm-agent", "1.0.178.0", "1.1.43.0")
+	assert.Error(t, err)
+}
+
+func TestLatestChannelVersion(t *testing.T) {
+	context := mockInstanceContext()
+	manifest := loadManifestFromFile(t, "testdata/sampleManifest.json")
+	file := manifest.Packages[0].Files[0]
+	file.AvailableVersions = append(file.AvailableVersions,
+		&PackageVersion{Version: "9.0.0.0", Channel: appconfig.UpdateChannelCandidate})
+
+	stable, err := manifest.LatestVersion(logger, context, "amazon-ssm-agent")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.43.0", stable)
+
+	candidate, err := manifest.LatestChannelVersion(logger, context, "amazon-ssm-agent", appconfig.UpdateChannelCandidate)
+	assert.NoError(t, err)
+	assert.Equal(t, "9.0.0.0", candidate)
+}
+
+// Load specified file from file system
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	var err error
 	if result, err = ioutil.ReadFile(fileName); err != nil {
@@ -106,7 +142,7 @@ func loadFile(t *testing.T, fileName string) (result []byte) {
 	return
 }
 
-//Parse manifest file
+// Parse manifest file
 func loadManifestFromFile(t *testing.T, fileName string) (manifest *Manifest) {
 	b := loadFile(t, fileName)
 	if err := json.Unmarshal(b, &manifest); err != nil {
@@ -0,0 +1,53 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const rolloutBuckets = 100
+
+// rolloutBucket returns the bucket of the instance in [0, 100) for the rollout of targetVersion.
+// The bucket only depends on the instance and the version, so an instance taking the version at a
+// given percentage keeps taking it in the later waves with a higher percentage, while the instances
+// canarying each version differ from one version to the next.
+func rolloutBucket(instanceID string, targetVersion string) int {
+	sum := sha256.Sum256([]byte(instanceID + "/" + targetVersion))
+	return int(binary.BigEndian.Uint32(sum[:4]) % rolloutBuckets)
+}
+
+// parseRolloutPercentage parses the rollout percentage of the plugin input, empty means the whole fleet
+func parseRolloutPercentage(rolloutPercentage string) (int, error) {
+	rolloutPercentage = strings.TrimSpace(rolloutPercentage)
+	if len(rolloutPercentage) == 0 {
+		return rolloutBuckets, nil
+	}
+	percentage, err := strconv.Atoi(rolloutPercentage)
+	if err != nil || percentage < 0 || percentage > rolloutBuckets {
+		return 0, fmt.Errorf("invalid rollout percentage %v, it must be an integer between 0 and 100", rolloutPercentage)
+	}
+	return percentage, nil
+}
+
+// isInRollout returns true when the instance belongs to the first percentage buckets of the rollout of targetVersion
+func isInRollout(instanceID string, targetVersion string, percentage int) bool {
+	return rolloutBucket(instanceID, targetVersion) < percentage
+}
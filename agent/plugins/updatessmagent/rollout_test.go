@@ -0,0 +1,69 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolloutBucketIsStablePerVersion(t *testing.T) {
+	bucket := rolloutBucket("i-1234567890abcdef0", "2.3.0.0")
+	assert.True(t, bucket >= 0 && bucket < rolloutBuckets)
+	assert.Equal(t, bucket, rolloutBucket("i-1234567890abcdef0", "2.3.0.0"))
+
+	differs := false
+	for i := 0; i < 10 && !differs; i++ {
+		differs = rolloutBucket("i-1234567890abcdef0", fmt.Sprintf("2.3.%v.0", i)) != bucket
+	}
+	assert.True(t, differs)
+}
+
+func TestIsInRolloutSpreadsInstances(t *testing.T) {
+	inRollout := 0
+	for i := 0; i < 1000; i++ {
+		if isInRollout(fmt.Sprintf("i-%017x", i), "2.3.0.0", 20) {
+			inRollout++
+		}
+	}
+	assert.InDelta(t, 200, inRollout, 50)
+
+	for i := 0; i < 100; i++ {
+		instanceID := fmt.Sprintf("i-%017x", i)
+		assert.False(t, isInRollout(instanceID, "2.3.0.0", 0))
+		assert.True(t, isInRollout(instanceID, "2.3.0.0", 100))
+		if isInRollout(instanceID, "2.3.0.0", 20) {
+			assert.True(t, isInRollout(instanceID, "2.3.0.0", 50))
+		}
+	}
+}
+
+func TestParseRolloutPercentage(t *testing.T) {
+	percentage, err := parseRolloutPercentage("")
+	assert.NoError(t, err)
+	assert.Equal(t, 100, percentage)
+
+	percentage, err = parseRolloutPercentage(" 25 ")
+	assert.NoError(t, err)
+	assert.Equal(t, 25, percentage)
+
+	for _, invalid := range []string{"-1", "101", "ten", "12.5"} {
+		_, err = parseRolloutPercentage(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
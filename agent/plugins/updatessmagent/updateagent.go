@@ -30,7 +30,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
-	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -50,6 +49,8 @@ type UpdatePluginInput struct {
 	TargetVersion  string `json:"targetVersion"`
 	Source         string `json:"source"`
 	UpdaterName    string `json:"-"`
+	// RolloutPercentage limits the update to the given percentage of the instances, empty updates all of them
+	RolloutPercentage string `json:"rolloutPercentage"`
 }
 
 // UpdatePluginConfig is used for initializing update agent plugin with default values
@@ -96,6 +97,7 @@ var getAppConfig = appconfig.Config
 var fileDownload = artifact.Download
 var fileUncompress = fileutil.Uncompress
 var updateAgent = runUpdateAgent
+var getInstanceID = platform.InstanceID
 
 // NewPlugin returns a new instance of the plugin.
 func NewPlugin(updatePluginConfig UpdatePluginConfig) (*Plugin, error) {
@@ -131,10 +133,21 @@ func runUpdateAgent(
 		return
 	}
 
+	rolloutPercentage, err := parseRolloutPercentage(pluginInput.RolloutPercentage)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	//Use default manifest location is the override is not present
 	if len(pluginInput.Source) == 0 {
 		pluginInput.Source = p.ManifestLocation
 	}
+	//Use the update source of the agent configuration instead of the public buckets in disconnected environments
+	updateSource := getUpdateSource(log)
+	if len(updateSource) != 0 {
+		pluginInput.Source = updateSourcePath(updateSource, manifestFileName)
+	}
 	//Calculate manifest location base on current instance's region
 	pluginInput.Source = strings.Replace(pluginInput.Source, updateutil.RegionHolder, context.Region, -1)
 	//Calculate updater package name base on agent name
@@ -155,6 +168,7 @@ func runUpdateAgent(
 		output.MarkAsFailed(downloadErr)
 		return
 	}
+	useUpdateSource(manifest, updateSource)
 
 	//Validate update details
 	noNeedToUpdate := false
@@ -165,6 +179,29 @@ func runUpdateAgent(
 		return
 	}
 
+	//Skip the update when the instance is outside of the rollout of the target version
+	if rolloutPercentage < rolloutBuckets {
+		instanceID, err := getInstanceID()
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to get the instance id for the rollout: %v", err))
+			return
+		}
+		if !isInRollout(instanceID, pluginInput.TargetVersion, rolloutPercentage) {
+			output.AppendInfof("%v is not part of the %v%% rollout of %v %v, update skipped\n",
+				instanceID,
+				rolloutPercentage,
+				pluginInput.AgentName,
+				pluginInput.TargetVersion)
+			output.MarkAsSucceeded()
+			return
+		}
+	}
+
+	//Defer the update to the next update window of the agent configuration
+	if !waitForUpdateWindow(log, cancelFlag, output) {
+		return
+	}
+
 	//Download updater and retrieve the version number
 	updaterVersion := ""
 	if updaterVersion, err = manager.downloadUpdater(
@@ -230,7 +267,7 @@ func runUpdateAgent(
 	return
 }
 
-//generateUpdateCmd generates cmd for the updater
+// generateUpdateCmd generates cmd for the updater
 func (m *updateManager) generateUpdateCmd(log log.T,
 	manifest *Manifest,
 	pluginInput *UpdatePluginInput,
@@ -263,6 +300,20 @@ func (m *updateManager) generateUpdateCmd(log log.T,
 	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.TargetLocationCmd, source)
 	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.TargetHashCmd, hash)
 
+	//Get download url and hash value of the delta from the current version, the updater falls back to the full package without it
+	if deltaSource, deltaHash, deltaErr := manifest.DeltaURLAndHash(
+		context, pluginInput.AgentName, version.Version, pluginInput.TargetVersion); deltaErr == nil {
+		cmd = updateutil.BuildUpdateCommand(cmd, updateutil.TargetDeltaLocationCmd, deltaSource)
+		cmd = updateutil.BuildUpdateCommand(cmd, updateutil.TargetDeltaHashCmd, deltaHash)
+	}
+
+	//The updater refuses downgrades unless explicitly allowed, they passed the validation of the update at this point
+	res, err := updateutil.CompareVersion(pluginInput.TargetVersion, version.Version)
+	if err != nil {
+		return
+	}
+	cmd = updateutil.BuildUpdateFlag(cmd, updateutil.AllowDowngradeCmd, res < 0)
+
 	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.PackageNameCmd, pluginInput.AgentName)
 	cmd = updateutil.BuildUpdateCommand(cmd, updateutil.MessageIDCmd, messageID)
 
@@ -275,7 +326,7 @@ func (m *updateManager) generateUpdateCmd(log log.T,
 	return
 }
 
-//downloadManifest downloads manifest file from s3 bucket
+// downloadManifest downloads manifest file from s3 bucket
 func (m *updateManager) downloadManifest(log log.T,
 	util updateutil.T,
 	pluginInput *UpdatePluginInput,
@@ -300,10 +351,31 @@ func (m *updateManager) downloadManifest(log log.T,
 		return nil, downloadErr
 	}
 	out.AppendInfof("Successfully downloaded %v\n", downloadInput.SourceURL)
+
+	keys, err := updateSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		log.Warnf("No update signing key is installed or pinned, the signature of %v is not checked", downloadInput.SourceURL)
+		return ParseManifest(log, downloadOutput.LocalFilePath, context, pluginInput.AgentName)
+	}
+	signatureInput := artifact.DownloadInput{
+		SourceURL:            pluginInput.Source + signatureFileSuffix,
+		DestinationDirectory: updateDownload,
+	}
+	signatureOutput, downloadErr := fileDownload(log, signatureInput)
+	if downloadErr != nil || signatureOutput.LocalFilePath == "" {
+		return nil, fmt.Errorf("failed to download the manifest signature %v: %v", signatureInput.SourceURL, downloadErr)
+	}
+	if err = verifyManifestSignature(keys, downloadOutput.LocalFilePath, signatureOutput.LocalFilePath); err != nil {
+		return nil, err
+	}
+	out.AppendInfof("Verified the signature of %v\n", downloadInput.SourceURL)
 	return ParseManifest(log, downloadOutput.LocalFilePath, context, pluginInput.AgentName)
 }
 
-//downloadUpdater downloads updater from the s3 bucket
+// downloadUpdater downloads updater from the s3 bucket
 func (m *updateManager) downloadUpdater(log log.T,
 	util updateutil.T,
 	updaterPackageName string,
@@ -355,7 +427,7 @@ func (m *updateManager) downloadUpdater(log log.T,
 	return version, nil
 }
 
-//validateUpdate validates manifest against update request
+// validateUpdate validates manifest against update request
 func (m *updateManager) validateUpdate(log log.T,
 	pluginInput *UpdatePluginInput,
 	context *updateutil.InstanceContext,
@@ -364,14 +436,19 @@ func (m *updateManager) validateUpdate(log log.T,
 	currentVersion := version.Version
 	var allowDowngrade = false
 	if len(pluginInput.TargetVersion) == 0 {
-		if pluginInput.TargetVersion, err = manifest.LatestVersion(log, context, pluginInput.AgentName); err != nil {
+		channel := getUpdateChannel(log)
+		if pluginInput.TargetVersion, err = manifest.LatestChannelVersion(log, context, pluginInput.AgentName, channel); err != nil {
 			return true, err
 		}
+		if channel != appconfig.UpdateChannelStable {
+			out.AppendInfof("Resolved the latest version from the %v channel\n", channel)
+		}
 	}
 
 	if allowDowngrade, err = strconv.ParseBool(pluginInput.AllowDowngrade); err != nil {
 		return true, err
 	}
+	allowDowngrade = allowDowngrade || isDowngradeAllowed(log)
 
 	res, err := updateutil.CompareVersion(pluginInput.TargetVersion, currentVersion)
 	if err != nil {
@@ -448,12 +525,7 @@ func GetUpdatePluginConfig(context context.T) UpdatePluginConfig {
 		log.Errorf("Error retrieving agent region in update plugin config. error: %v\n", err)
 	}
 
-	var manifestUrl string
-	if strings.HasPrefix(region, s3util.ChinaRegionPrefix) {
-		manifestUrl = ChinaManifestURL
-	} else {
-		manifestUrl = CommonManifestURL
-	}
+	manifestUrl := strings.Replace(ManifestURL, updateutil.DnsSuffixHolder, appconfig.GetPartition(region).DnsSuffix, -1)
 
 	return UpdatePluginConfig{
 		ManifestLocation: manifestUrl,
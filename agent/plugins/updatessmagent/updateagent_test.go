@@ -16,14 +16,18 @@ package updatessmagent
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -50,6 +54,49 @@ func TestGenerateUpdateCmd(t *testing.T) {
 	assert.Contains(t, result, "bucket")
 }
 
+func TestGenerateUpdateCmdAllowDowngrade(t *testing.T) {
+	plugin := createStubPluginInput()
+	context := createStubInstanceContext()
+	manager := updateManager{}
+
+	result, err := manager.generateUpdateCmd(logger, createStubManifest(plugin, context, true, true), plugin, context,
+		"path", "messageID", "stdout", "stderr", "prefix", "bucket")
+	assert.NoError(t, err)
+	assert.NotContains(t, result, updateutil.AllowDowngradeCmd)
+
+	plugin.TargetVersion = "0.0.0.1"
+	result, err = manager.generateUpdateCmd(logger, createStubManifest(plugin, context, true, true), plugin, context,
+		"path", "messageID", "stdout", "stderr", "prefix", "bucket")
+	assert.NoError(t, err)
+	assert.Contains(t, result, "-"+updateutil.AllowDowngradeCmd)
+}
+
+func TestGenerateUpdateCmdWithDelta(t *testing.T) {
+	plugin := createStubPluginInput()
+	context := createStubInstanceContext()
+	manifest := createStubManifest(plugin, context, true, true)
+	manager := updateManager{}
+
+	result, err := manager.generateUpdateCmd(logger, manifest, plugin, context,
+		"path", "messageID", "stdout", "stderr", "prefix", "bucket")
+	assert.NoError(t, err)
+	assert.NotContains(t, result, updateutil.TargetDeltaLocationCmd)
+
+	for _, f := range manifest.Packages[0].Files {
+		for _, v := range f.AvailableVersions {
+			if f.Name == context.FileName(plugin.AgentName) && v.Version == plugin.TargetVersion {
+				v.Deltas = []*PackageDelta{{FromVersion: version.Version, Name: "delta.bsdiff", Checksum: "deltahash"}}
+			}
+		}
+	}
+	result, err = manager.generateUpdateCmd(logger, manifest, plugin, context,
+		"path", "messageID", "stdout", "stderr", "prefix", "bucket")
+	assert.NoError(t, err)
+	assert.Contains(t, result, updateutil.TargetDeltaLocationCmd)
+	assert.Contains(t, result, "delta.bsdiff")
+	assert.Contains(t, result, "deltahash")
+}
+
 func TestDownloadManifest(t *testing.T) {
 	plugin := createStubPluginInput()
 	context := createStubInstanceContext()
@@ -57,8 +104,60 @@ func TestDownloadManifest(t *testing.T) {
 	manager := updateManager{}
 	util := fakeUtility{}
 	out := iohandler.DefaultIOHandler{}
+	signaturePath := stubUpdateSigning(t, "testdata/sampleManifest.json")
+
+	fileDownload = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		result := artifact.DownloadOutput{}
+		result.IsHashMatched = true
+		result.LocalFilePath = "testdata/sampleManifest.json"
+		if strings.HasSuffix(input.SourceURL, signatureFileSuffix) {
+			result.LocalFilePath = signaturePath
+		}
+		return result, nil
+	}
+
+	manifest, err := manager.downloadManifest(logger, &util, plugin, context, &out)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, manifest)
+}
+
+func TestDownloadManifest_InvalidSignature(t *testing.T) {
+	plugin := createStubPluginInput()
+	context := createStubInstanceContext()
+
+	manager := updateManager{}
+	util := fakeUtility{}
+	out := iohandler.DefaultIOHandler{}
+	stubUpdateSigning(t, "testdata/sampleManifest.json")
+
+	fileDownload = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		result := artifact.DownloadOutput{}
+		result.IsHashMatched = true
+		result.LocalFilePath = "testdata/sampleManifest.json"
+		return result, nil
+	}
+
+	manifest, err := manager.downloadManifest(logger, &util, plugin, context, &out)
+
+	assert.Error(t, err)
+	assert.Nil(t, manifest)
+}
+
+func TestDownloadManifest_NoSigningKey(t *testing.T) {
+	plugin := createStubPluginInput()
+	context := createStubInstanceContext()
+
+	manager := updateManager{}
+	util := fakeUtility{}
+	out := iohandler.DefaultIOHandler{}
+	stubUpdateSigning(t, "testdata/sampleManifest.json")
+	awsUpdateSigningKeysPath = filepath.Join(t.TempDir(), "missing.pem")
 
 	fileDownload = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		if strings.HasSuffix(input.SourceURL, signatureFileSuffix) {
+			return artifact.DownloadOutput{}, fmt.Errorf("404")
+		}
 		result := artifact.DownloadOutput{}
 		result.IsHashMatched = true
 		result.LocalFilePath = "testdata/sampleManifest.json"
@@ -71,6 +170,31 @@ func TestDownloadManifest(t *testing.T) {
 	assert.NotNil(t, manifest)
 }
 
+func TestDownloadManifest_MissingSignature(t *testing.T) {
+	plugin := createStubPluginInput()
+	context := createStubInstanceContext()
+
+	manager := updateManager{}
+	util := fakeUtility{}
+	out := iohandler.DefaultIOHandler{}
+	stubUpdateSigning(t, "testdata/sampleManifest.json")
+
+	fileDownload = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		if strings.HasSuffix(input.SourceURL, signatureFileSuffix) {
+			return artifact.DownloadOutput{}, fmt.Errorf("404")
+		}
+		result := artifact.DownloadOutput{}
+		result.IsHashMatched = true
+		result.LocalFilePath = "testdata/sampleManifest.json"
+		return result, nil
+	}
+
+	manifest, err := manager.downloadManifest(logger, &util, plugin, context, &out)
+
+	assert.Error(t, err)
+	assert.Nil(t, manifest)
+}
+
 func TestDownloadUpdater(t *testing.T) {
 	plugin := createStubPluginInput()
 	context := createStubInstanceContext()
@@ -208,6 +332,28 @@ func TestValidateUpdate_DowngradeVersion(t *testing.T) {
 	assert.Contains(t, err.Error(), "please enable allow downgrade to proceed")
 }
 
+func TestValidateUpdate_DowngradeAllowedByConfiguration(t *testing.T) {
+	plugin := createStubPluginInput()
+	plugin.AllowDowngrade = "false"
+	plugin.TargetVersion = "0.0.0.1"
+	context := createStubInstanceContext()
+	manifest := createStubManifest(plugin, context, true, true)
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Update.AllowDowngrade = true
+		return config, nil
+	}
+	defer func() { getAppConfig = appconfig.Config }()
+
+	manager := updateManager{}
+	out := iohandler.DefaultIOHandler{}
+
+	noNeedToUpdate, err := manager.validateUpdate(logger, plugin, context, manifest, &out)
+
+	assert.False(t, noNeedToUpdate)
+	assert.NoError(t, err)
+}
+
 func TestValidateUpdate_TargetVersionNotSupport(t *testing.T) {
 	plugin := createStubPluginInput()
 	plugin.TargetVersion = "1.1.1.999"
@@ -287,6 +433,32 @@ func TestUpdateAgent(t *testing.T) {
 	}
 }
 
+func TestUpdateAgent_OutsideOfRollout(t *testing.T) {
+	getInstanceID = func() (string, error) { return "i-1234567890abcdef0", nil }
+	t.Cleanup(func() { getInstanceID = platform.InstanceID })
+
+	pluginInput := createStubPluginInput()
+	context := createStubInstanceContext()
+	manager := fakeUpdateManager{
+		downloadManifestResult: createStubManifest(pluginInput, context, true, true),
+		downloadUpdaterError:   fmt.Errorf("updater should not be downloaded"),
+	}
+	mockCancelFlag := new(task.MockCancelFlag)
+	util := fakeUtility{}
+
+	pluginInput.RolloutPercentage = "0"
+	out := iohandler.DefaultIOHandler{}
+	updateAgent(&Plugin{}, contracts.Configuration{}, logger, &manager, &util, pluginInput, mockCancelFlag, &out, time.Now())
+	assert.Empty(t, out.GetStderr())
+	assert.Equal(t, contracts.ResultStatusSuccess, out.GetStatus())
+	assert.Contains(t, out.GetStdout(), "update skipped")
+
+	pluginInput.RolloutPercentage = "150"
+	out = iohandler.DefaultIOHandler{}
+	updateAgent(&Plugin{}, contracts.Configuration{}, logger, &manager, &util, pluginInput, mockCancelFlag, &out, time.Now())
+	assert.Contains(t, out.GetStderr(), "invalid rollout percentage")
+}
+
 func TestUpdateAgent_NegativeTestCases(t *testing.T) {
 	pluginInput := createStubPluginInput()
 	pluginInput.TargetVersion = ""
@@ -0,0 +1,84 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagesigning"
+)
+
+// signatureFileSuffix is appended to the manifest location to get the location of its detached signature
+const signatureFileSuffix = ".sig"
+
+// awsUpdateSigningKeysPath is the PEM bundle of the AWS update signing keys, once installed with the agent
+var awsUpdateSigningKeysPath = filepath.Join(appconfig.DefaultProgramFolder, "update-signing-keys.pem")
+
+// updateSigningKeys returns the AWS update signing keys and the keys pinned in the agent configuration.
+// The manifest lists the checksum of every package, the updater and the packages are verified against them
+// once downloaded, so a valid manifest signature covers the binaries as well.
+// No key is returned while the AWS bundle is not installed and no key is pinned, the manifest signature is not
+// checked then since the manifests are not signed everywhere yet.
+var updateSigningKeys = func() ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	content, err := ioutil.ReadFile(awsUpdateSigningKeysPath)
+	if err == nil {
+		if keys, err = packagesigning.ParsePublicKeys(content); err != nil {
+			return nil, fmt.Errorf("invalid update signing keys %v: %v", awsUpdateSigningKeysPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read the update signing keys: %v", err)
+	}
+	config, err := getAppConfig(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read appconfig: %v", err)
+	}
+	pinnedKeys, err := packagesigning.LoadPublicKeys(config.Update.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, pinnedKeys...), nil
+}
+
+// verifyManifestSignature checks that the detached base64 signature of the manifest is valid for one of the update signing keys
+func verifyManifestSignature(keys []crypto.PublicKey, manifestPath string, signaturePath string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no update signing key to verify the manifest signature with")
+	}
+	manifest, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	encodedSignature, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return fmt.Errorf("invalid manifest signature encoding: %v", err)
+	}
+	if !packagesigning.VerifySignature(keys, manifest, signature) {
+		return fmt.Errorf("manifest signature is not valid for the update signing keys, update refused")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePublicKey writes the PEM encoded public key of key to path
+func writePublicKey(t *testing.T, path string, key crypto.Signer) {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+}
+
+// stubUpdateSigning installs a generated AWS update signing key and returns the path of a signature of manifestPath by it
+func stubUpdateSigning(t *testing.T, manifestPath string) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	tmpDir := t.TempDir()
+	keysPath := filepath.Join(tmpDir, "update-signing-keys.pem")
+	writePublicKey(t, keysPath, key)
+	awsUpdateSigningKeysPath = keysPath
+	t.Cleanup(func() {
+		awsUpdateSigningKeysPath = filepath.Join(appconfig.DefaultProgramFolder, "update-signing-keys.pem")
+	})
+	return writeSignature(t, tmpDir, key, manifestPath)
+}
+
+// writeSignature signs the content of manifestPath with key and returns the path of the signature
+func writeSignature(t *testing.T, dir string, key ed25519.PrivateKey, manifestPath string) string {
+	manifest, err := ioutil.ReadFile(manifestPath)
+	assert.NoError(t, err)
+	signaturePath := filepath.Join(dir, "ssm-agent-manifest.json.sig")
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest))
+	assert.NoError(t, ioutil.WriteFile(signaturePath, []byte(signature+"\n"), 0600))
+	return signaturePath
+}
+
+// signingKeys returns the update signing keys of the stubbed installation
+func signingKeys(t *testing.T) []crypto.PublicKey {
+	keys, err := updateSigningKeys()
+	assert.NoError(t, err)
+	return keys
+}
+
+func TestVerifyManifestSignature(t *testing.T) {
+	signaturePath := stubUpdateSigning(t, "testdata/sampleManifest.json")
+
+	assert.NoError(t, verifyManifestSignature(signingKeys(t), "testdata/sampleManifest.json", signaturePath))
+}
+
+func TestVerifyManifestSignatureTampered(t *testing.T) {
+	signaturePath := stubUpdateSigning(t, "testdata/sampleManifest.json")
+	tampered := filepath.Join(t.TempDir(), "ssm-agent-manifest.json")
+	content, _ := ioutil.ReadFile("testdata/sampleManifest.json")
+	ioutil.WriteFile(tampered, append(content, ' '), 0600)
+
+	assert.Error(t, verifyManifestSignature(signingKeys(t), tampered, signaturePath))
+}
+
+func TestVerifyManifestSignaturePinnedKey(t *testing.T) {
+	stubUpdateSigning(t, "testdata/sampleManifest.json")
+	_, pinnedKey, _ := ed25519.GenerateKey(rand.Reader)
+	tmpDir := t.TempDir()
+	pinnedKeyPath := filepath.Join(tmpDir, "pinned.pem")
+	writePublicKey(t, pinnedKeyPath, pinnedKey)
+	signaturePath := writeSignature(t, tmpDir, pinnedKey, "testdata/sampleManifest.json")
+
+	assert.Error(t, verifyManifestSignature(signingKeys(t), "testdata/sampleManifest.json", signaturePath))
+
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Update.PublicKeys = []string{pinnedKeyPath}
+		return config, nil
+	}
+	defer func() { getAppConfig = appconfig.Config }()
+
+	assert.NoError(t, verifyManifestSignature(signingKeys(t), "testdata/sampleManifest.json", signaturePath))
+}
+
+func TestVerifyManifestSignatureWithoutKeys(t *testing.T) {
+	signaturePath := stubUpdateSigning(t, "testdata/sampleManifest.json")
+	awsUpdateSigningKeysPath = filepath.Join(t.TempDir(), "missing.pem")
+
+	keys := signingKeys(t)
+	assert.Empty(t, keys)
+	assert.Error(t, verifyManifestSignature(keys, "testdata/sampleManifest.json", signaturePath))
+}
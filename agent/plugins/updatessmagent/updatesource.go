@@ -0,0 +1,79 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+const (
+	// manifestFileName is the name of the manifest at the root of an update source
+	manifestFileName = "ssm-agent-manifest.json"
+
+	// packageURIFormat is the layout of the packages in an update source, the same as in the public buckets
+	packageURIFormat = updateutil.PackageNameHolder + "/" + updateutil.PackageVersionHolder + "/" + updateutil.FileNameHolder
+)
+
+// getUpdateSource returns the internal url or local directory of the agent configuration
+// the manifest and packages are fetched from instead of the public buckets, empty when not configured
+func getUpdateSource(log log.T) string {
+	config, err := getAppConfig(false)
+	if err != nil {
+		log.Warnf("failed to load the update source, using the public buckets: %v", err)
+		return ""
+	}
+	return config.Update.Source
+}
+
+// getUpdateChannel returns the update channel of the agent configuration the latest version is resolved from
+func getUpdateChannel(log log.T) string {
+	config, err := getAppConfig(false)
+	if err != nil {
+		log.Warnf("failed to load the update channel, using the stable channel: %v", err)
+		return appconfig.UpdateChannelStable
+	}
+	return config.Update.Channel
+}
+
+// isDowngradeAllowed returns if the agent configuration allows updates to older versions
+func isDowngradeAllowed(log log.T) bool {
+	config, err := getAppConfig(false)
+	if err != nil {
+		log.Warnf("failed to load the agent configuration, downgrades are not allowed: %v", err)
+		return false
+	}
+	return config.Update.AllowDowngrade
+}
+
+// updateSourcePath returns the location of a file relative to the root of the update source
+func updateSourcePath(source string, relativePath string) string {
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		return strings.TrimSuffix(source, "/") + "/" + relativePath
+	}
+	return filepath.Join(source, filepath.FromSlash(relativePath))
+}
+
+// useUpdateSource points the package downloads of the manifest to the update source
+func useUpdateSource(manifest *Manifest, source string) {
+	if manifest != nil && source != "" {
+		manifest.URIFormat = updateSourcePath(source, packageURIFormat)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubUpdateSource(t *testing.T, source string) {
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Update.Source = source
+		return config, nil
+	}
+	t.Cleanup(func() {
+		getAppConfig = appconfig.Config
+	})
+}
+
+func TestGetUpdateSource(t *testing.T) {
+	stubUpdateSource(t, "https://mirror.example.com/ssm-agent")
+
+	assert.Equal(t, "https://mirror.example.com/ssm-agent", getUpdateSource(logger))
+}
+
+func TestUpdateSourcePathWithUrl(t *testing.T) {
+	assert.Equal(t, "https://mirror.example.com/ssm-agent/ssm-agent-manifest.json",
+		updateSourcePath("https://mirror.example.com/ssm-agent/", manifestFileName))
+}
+
+func TestUpdateSourcePathWithLocalDirectory(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "srv", "ssm-agent")
+
+	assert.Equal(t, filepath.Join(root, "amazon-ssm-agent", "2.3.0.0", "amazon-ssm-agent-linux-amd64.tar.gz"),
+		updateSourcePath(root, "amazon-ssm-agent/2.3.0.0/amazon-ssm-agent-linux-amd64.tar.gz"))
+}
+
+func TestUseUpdateSource(t *testing.T) {
+	manifest := &Manifest{URIFormat: "https://s3.{Region}.amazonaws.com/amazon-ssm-{Region}/{PackageName}/{PackageVersion}/{FileName}"}
+
+	useUpdateSource(manifest, "https://mirror.example.com/ssm-agent")
+
+	assert.Equal(t, "https://mirror.example.com/ssm-agent/{PackageName}/{PackageVersion}/{FileName}", manifest.URIFormat)
+}
+
+func TestUseUpdateSourceWithoutSource(t *testing.T) {
+	manifest := &Manifest{URIFormat: "https://s3.{Region}.amazonaws.com/amazon-ssm-{Region}/{PackageName}/{PackageVersion}/{FileName}"}
+
+	useUpdateSource(manifest, "")
+	useUpdateSource(nil, "https://mirror.example.com/ssm-agent")
+
+	assert.Equal(t, "https://s3.{Region}.amazonaws.com/amazon-ssm-{Region}/{PackageName}/{PackageVersion}/{FileName}", manifest.URIFormat)
+}
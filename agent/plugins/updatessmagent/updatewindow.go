@@ -0,0 +1,86 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+)
+
+// updateWindowPollInterval is how often a deferred update checks for its window and for cancellation
+var updateWindowPollInterval = time.Minute
+
+var updateWindowClock times.Clock = times.DefaultClock
+
+// waitForUpdateWindow defers the update until one of the update windows of the agent configuration opens,
+// it returns false if the command was canceled while waiting
+func waitForUpdateWindow(log log.T, cancelFlag task.CancelFlag, output iohandler.IOHandler) bool {
+	config, err := getAppConfig(false)
+	if err != nil {
+		log.Warnf("failed to load the update windows, updating now: %v", err)
+		return true
+	}
+
+	var windows []times.Window
+	for _, spec := range config.Update.Windows {
+		if window, err := times.ParseWindow(spec); err == nil {
+			windows = append(windows, window)
+		}
+	}
+	if len(windows) == 0 {
+		return true
+	}
+
+	deferred := false
+	for {
+		now := updateWindowClock.Now()
+		var next time.Time
+		for _, window := range windows {
+			if window.Contains(now) {
+				if deferred {
+					output.AppendInfof("Update window opened at %v\n", now.Format(time.RFC3339))
+				}
+				return true
+			}
+			if start := window.NextStart(now); next.IsZero() || start.Before(next) {
+				next = start
+			}
+		}
+
+		if !deferred {
+			output.AppendInfof("Outside of the update windows, update deferred to %v\n", next.Format(time.RFC3339))
+			deferred = true
+		}
+		if cancelFlag.ShutDown() {
+			output.MarkAsShutdown()
+			return false
+		}
+		if cancelFlag.Canceled() {
+			output.MarkAsCancelled()
+			return false
+		}
+
+		wait := next.Sub(now)
+		if wait > updateWindowPollInterval {
+			wait = updateWindowPollInterval
+		}
+		<-updateWindowClock.After(wait)
+	}
+}
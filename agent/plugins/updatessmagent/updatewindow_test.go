@@ -0,0 +1,93 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updatessmagent implements the UpdateSsmAgent plugin.
+package updatessmagent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func stubUpdateWindows(t *testing.T, windows ...string) *times.MockedClock {
+	clock := times.NewMockedClock()
+	getAppConfig = func(bool) (appconfig.SsmagentConfig, error) {
+		config := appconfig.DefaultConfig()
+		config.Update.Windows = windows
+		return config, nil
+	}
+	updateWindowClock = clock
+	t.Cleanup(func() {
+		getAppConfig = appconfig.Config
+		updateWindowClock = times.DefaultClock
+	})
+	return clock
+}
+
+func TestWaitForUpdateWindowWithoutWindows(t *testing.T) {
+	stubUpdateWindows(t)
+	out := iohandler.DefaultIOHandler{}
+
+	assert.True(t, waitForUpdateWindow(logger, new(task.MockCancelFlag), &out))
+	assert.Empty(t, out.GetStdout())
+}
+
+func TestWaitForUpdateWindowInsideWindow(t *testing.T) {
+	clock := stubUpdateWindows(t, "Mon-Fri 22:00-04:00")
+	// 2019-06-04 is a Tuesday
+	clock.On("Now").Return(time.Date(2019, time.June, 4, 23, 0, 0, 0, time.Local))
+	out := iohandler.DefaultIOHandler{}
+
+	assert.True(t, waitForUpdateWindow(logger, new(task.MockCancelFlag), &out))
+	assert.Empty(t, out.GetStdout())
+}
+
+func TestWaitForUpdateWindowDefersToNextWindow(t *testing.T) {
+	clock := stubUpdateWindows(t, "Sat 01:00-05:00", "Mon-Fri 22:00-04:00")
+	clock.On("Now").Return(time.Date(2019, time.June, 4, 12, 0, 0, 0, time.Local)).Once()
+	clock.On("Now").Return(time.Date(2019, time.June, 4, 22, 0, 0, 0, time.Local)).Once()
+	clock.On("After", updateWindowPollInterval).Return(clock.AfterChannel)
+	clock.AfterChannel <- struct{}{}
+	cancelFlag := new(task.MockCancelFlag)
+	cancelFlag.On("ShutDown").Return(false)
+	cancelFlag.On("Canceled").Return(false)
+	out := iohandler.DefaultIOHandler{}
+
+	assert.True(t, waitForUpdateWindow(logger, cancelFlag, &out))
+	assert.Contains(t, out.GetStdout(), "update deferred to 2019-06-04T22:00:00")
+	assert.Contains(t, out.GetStdout(), "Update window opened")
+	clock.AssertExpectations(t)
+}
+
+func TestWaitForUpdateWindowCanceled(t *testing.T) {
+	clock := stubUpdateWindows(t, "Sat 01:00-05:00")
+	clock.On("Now").Return(time.Date(2019, time.June, 4, 12, 0, 0, 0, time.Local))
+	clock.On("After", mock.Anything).Return(clock.AfterChannel)
+	cancelFlag := new(task.MockCancelFlag)
+	cancelFlag.On("ShutDown").Return(false)
+	cancelFlag.On("Canceled").Return(true)
+	out := iohandler.DefaultIOHandler{}
+
+	assert.False(t, waitForUpdateWindow(logger, cancelFlag, &out))
+	assert.Equal(t, contracts.ResultStatusCancelled, out.GetStatus())
+	assert.Contains(t, out.GetStdout(), "update deferred to 2019-06-08T01:00:00")
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package windowsupdate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// installScript searches the missing software updates with the Windows Update Agent, installs the ones selected
+// by the KB lists and writes their result codes to the results file. Its format parameters are the included KBs,
+// the excluded KBs and the path of the results file.
+const installScript = `[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$ErrorActionPreference = 'Stop'
+$includeKbs = @(%v)
+$excludeKbs = @(%v)
+$resultsPath = %v
+
+$session = New-Object -ComObject Microsoft.Update.Session
+$searcher = $session.CreateUpdateSearcher()
+Write-Output 'Searching for missing updates.'
+$search = $searcher.Search("IsInstalled=0 and Type='Software' and IsHidden=0")
+
+$selected = New-Object -ComObject Microsoft.Update.UpdateColl
+foreach ($update in $search.Updates) {
+    $kbs = @($update.KBArticleIDs | ForEach-Object { "KB$_" })
+    if ($includeKbs.Count -gt 0 -and -not ($kbs | Where-Object { $includeKbs -contains $_ })) {
+        continue
+    }
+    if ($kbs | Where-Object { $excludeKbs -contains $_ }) {
+        Write-Output "Skipping the excluded update $($kbs -join ',') $($update.Title)."
+        continue
+    }
+    if (-not $update.EulaAccepted) {
+        $update.AcceptEula()
+    }
+    [void]$selected.Add($update)
+}
+
+$results = @()
+$rebootRequired = $false
+if ($selected.Count -gt 0) {
+    Write-Output "Downloading $($selected.Count) updates."
+    $downloader = $session.CreateUpdateDownloader()
+    $downloader.Updates = $selected
+    [void]$downloader.Download()
+
+    Write-Output "Installing $($selected.Count) updates."
+    $installer = $session.CreateUpdateInstaller()
+    $installer.Updates = $selected
+    $installation = $installer.Install()
+    for ($i = 0; $i -lt $selected.Count; $i++) {
+        $update = $selected.Item($i)
+        $result = $installation.GetUpdateResult($i)
+        $results += New-Object PSObject -Property @{
+            KB = (@($update.KBArticleIDs | ForEach-Object { "KB$_" }) -join ',')
+            Title = $update.Title
+            ResultCode = [int]$result.ResultCode
+            RebootRequired = [bool]$result.RebootRequired
+        }
+    }
+    $rebootRequired = [bool]$installation.RebootRequired
+}
+
+$json = ConvertTo-Json -Depth 3 -InputObject (New-Object PSObject -Property @{ RebootRequired = $rebootRequired; Updates = @($results) })
+[System.IO.File]::WriteAllText($resultsPath, $json)
+`
+
+// buildScript returns the script installing the missing updates selected by the KB lists.
+func buildScript(includeKbs []string, excludeKbs []string, resultsPath string) string {
+	return fmt.Sprintf(installScript, quoteList(includeKbs), quoteList(excludeKbs), quote(resultsPath))
+}
+
+// quoteList returns the values as the elements of a PowerShell array.
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quote(value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quote returns the value as a single-quoted PowerShell string.
+func quote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
@@ -0,0 +1,287 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package windowsupdate implements the aws:installWindowsUpdates plugin.
+package windowsupdate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// RebootNever leaves the reboot required by the installed updates to the administrator
+	RebootNever = "Never"
+	// RebootIfRequired reboots the instance after the document when an installed update requires it
+	RebootIfRequired = "IfRequired"
+	// RebootScheduled schedules the reboot required by the installed updates at the RebootTime
+	RebootScheduled = "Scheduled"
+
+	// scriptName is the name of the script installing the updates in the orchestration directory
+	scriptName = "_script.ps1"
+	// resultsName is the name of the file the script writes the results of the updates to
+	resultsName = "updates.json"
+	// rebootTimeLayout is the layout of the RebootTime of the input
+	rebootTimeLayout = "15:04"
+	// shutdownTimeoutSeconds is the execution timeout of the command scheduling the reboot
+	shutdownTimeoutSeconds = 60
+)
+
+// resultCodes are the names of the OperationResultCode values of the Windows Update Agent
+var resultCodes = map[int]string{
+	0: "NotStarted",
+	1: "InProgress",
+	2: "Succeeded",
+	3: "SucceededWithErrors",
+	4: "Failed",
+	5: "Aborted",
+}
+
+var kbPattern = regexp.MustCompile(`^(?i:KB)?([0-9]+)$`)
+
+// now and shutdownCommand are stubbed by the tests
+var now = time.Now
+var shutdownCommand = "shutdown.exe"
+
+// Plugin is the type for the aws:installWindowsUpdates plugin.
+type Plugin struct {
+	// CommandExecuter runs powershell and shutdown.
+	CommandExecuter executers.T
+}
+
+// WindowsUpdatePluginInput represents one set of updates installed by the plugin.
+type WindowsUpdatePluginInput struct {
+	contracts.PluginInput
+	ID             string
+	IncludeKbs     []string
+	ExcludeKbs     []string
+	RebootOption   string
+	RebootTime     string
+	TimeoutSeconds interface{}
+}
+
+// UpdateResult is the result of the installation of one update, reported in the plugin output.
+type UpdateResult struct {
+	KB             string
+	Title          string
+	ResultCode     int
+	Result         string
+	RebootRequired bool
+}
+
+// installation is the content of the results file written by the script.
+type installation struct {
+	RebootRequired bool
+	Updates        []UpdateResult
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.CommandExecuter = executers.ShellCommandExecuter{}
+	return &plugin, nil
+}
+
+// Name returns the name of the plugin
+func Name() string {
+	return appconfig.PluginNameAwsInstallWindowsUpdates
+}
+
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.installUpdatesRawInput(log, config.Properties, config.OrchestrationDirectory, cancelFlag, output)
+	}
+	return
+}
+
+// installUpdatesRawInput validates the raw plugin input and installs the selected updates.
+func (p *Plugin) installUpdatesRawInput(log log.T, rawPluginInput interface{}, orchestrationDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var pluginInput WindowsUpdatePluginInput
+	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
+	log.Debugf("Plugin input %v", pluginInput)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("Invalid format in plugin properties %v;\nerror %v", rawPluginInput, err))
+		return
+	}
+
+	if err = normalizeInput(&pluginInput); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	p.installUpdates(log, pluginInput, orchestrationDirectory, cancelFlag, output)
+}
+
+// normalizeInput validates the reboot option and time and turns the KB lists into the KB<number> form.
+func normalizeInput(pluginInput *WindowsUpdatePluginInput) (err error) {
+	switch pluginInput.RebootOption {
+	case "":
+		pluginInput.RebootOption = RebootIfRequired
+	case RebootNever, RebootIfRequired:
+	case RebootScheduled:
+		if _, err = time.Parse(rebootTimeLayout, pluginInput.RebootTime); err != nil {
+			return fmt.Errorf("RebootTime %q is not a HH:MM time of the day", pluginInput.RebootTime)
+		}
+	default:
+		return fmt.Errorf("RebootOption %q is not one of %v, %v or %v", pluginInput.RebootOption, RebootNever, RebootIfRequired, RebootScheduled)
+	}
+
+	if pluginInput.IncludeKbs, err = normalizeKbs(pluginInput.IncludeKbs); err != nil {
+		return err
+	}
+	pluginInput.ExcludeKbs, err = normalizeKbs(pluginInput.ExcludeKbs)
+	return err
+}
+
+// normalizeKbs accepts the KB ids with or without their KB prefix and returns them as KB<number>.
+func normalizeKbs(kbs []string) ([]string, error) {
+	normalized := make([]string, 0, len(kbs))
+	for _, kb := range kbs {
+		match := kbPattern.FindStringSubmatch(strings.TrimSpace(kb))
+		if match == nil {
+			return nil, fmt.Errorf("%q is not a KB article id", kb)
+		}
+		normalized = append(normalized, "KB"+match[1])
+	}
+	return normalized, nil
+}
+
+// installUpdates runs the script installing the updates, reports their results and handles the reboot.
+func (p *Plugin) installUpdates(log log.T, pluginInput WindowsUpdatePluginInput, orchestrationDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	orchestrationDir := fileutil.BuildPath(orchestrationDirectory, pluginInput.ID)
+	if err := fileutil.MakeDirsWithExecuteAccess(orchestrationDir); err != nil {
+		log.Debug("failed to create orchestrationDir directory", orchestrationDir)
+		output.MarkAsFailed(err)
+		return
+	}
+
+	scriptPath := filepath.Join(orchestrationDir, scriptName)
+	resultsPath := filepath.Join(orchestrationDir, resultsName)
+	script := buildScript(pluginInput.IncludeKbs, pluginInput.ExcludeKbs, resultsPath)
+	if err := pluginutil.CreateScriptFile(log, scriptPath, []string{script}, fileutil.ByteOrderMarkEmit); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to create script file. %v", err))
+		return
+	}
+
+	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
+	commandArguments := append(strings.Split(appconfig.PowerShellPluginCommandArgs, " "), scriptPath)
+	exitCode, err := p.CommandExecuter.NewExecute(log, orchestrationDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, appconfig.PowerShellPluginCommandName, commandArguments)
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	if err != nil || exitCode != 0 {
+		if status := output.GetStatus(); status != contracts.ResultStatusCancelled && status != contracts.ResultStatusTimedOut {
+			output.MarkAsFailed(fmt.Errorf("failed to install the updates: exit code %v, %v", exitCode, err))
+		}
+		return
+	}
+
+	result, err := readResults(resultsPath)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	output.SetOutput(result.Updates)
+
+	failed := 0
+	for _, update := range result.Updates {
+		output.AppendInfof("%v %v: %v", update.KB, update.Title, update.Result)
+		if update.ResultCode != 2 && update.ResultCode != 3 {
+			failed++
+		}
+	}
+	if len(result.Updates) == 0 {
+		output.AppendInfo("No update to install.")
+	}
+
+	if result.RebootRequired {
+		p.reboot(log, pluginInput, cancelFlag, output)
+	}
+	if failed > 0 {
+		output.MarkAsFailed(fmt.Errorf("%v of %v updates were not installed", failed, len(result.Updates)))
+	}
+}
+
+// readResults reads the results of the updates written by the script.
+func readResults(resultsPath string) (result installation, err error) {
+	content, err := ioutil.ReadFile(resultsPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read the results of the updates: %v", err)
+	}
+	// Windows PowerShell writes UTF-8 files with a byte order mark
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if err = json.Unmarshal(content, &result); err != nil {
+		return result, fmt.Errorf("failed to parse the results of the updates: %v", err)
+	}
+	for i, update := range result.Updates {
+		if name, ok := resultCodes[update.ResultCode]; ok {
+			result.Updates[i].Result = name
+		} else {
+			result.Updates[i].Result = strconv.Itoa(update.ResultCode)
+		}
+	}
+	return result, nil
+}
+
+// reboot applies the reboot option once the installed updates require a reboot.
+func (p *Plugin) reboot(log log.T, pluginInput WindowsUpdatePluginInput, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	switch pluginInput.RebootOption {
+	case RebootNever:
+		output.AppendInfo("The installed updates require a reboot, the instance is not rebooted with RebootOption Never.")
+	case RebootIfRequired:
+		output.AppendInfo("The installed updates require a reboot, the instance reboots after the document.")
+		output.MarkAsSuccessWithReboot()
+	case RebootScheduled:
+		delay := rebootDelay(pluginInput.RebootTime)
+		output.AppendInfof("The installed updates require a reboot, the instance reboots at %v.", now().Add(delay).Format(time.RFC3339))
+		arguments := []string{"/r", "/t", strconv.Itoa(int(delay.Seconds())), "/d", "p:2:17", "/c", "Reboot scheduled by " + Name()}
+		if exitCode, err := p.CommandExecuter.NewExecute(log, "", output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, shutdownTimeoutSeconds, shutdownCommand, arguments); err != nil || exitCode != 0 {
+			output.MarkAsFailed(fmt.Errorf("failed to schedule the reboot: exit code %v, %v", exitCode, err))
+		}
+	}
+}
+
+// rebootDelay returns the time until the next occurrence of the HH:MM reboot time in the local time zone.
+func rebootDelay(rebootTime string) time.Duration {
+	at, _ := time.Parse(rebootTimeLayout, rebootTime)
+	current := now()
+	next := time.Date(current.Year(), current.Month(), current.Day(), at.Hour(), at.Minute(), 0, 0, current.Location())
+	if !next.After(current) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next.Sub(current)
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package windowsupdate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewMockLog()
+
+func TestNormalizeInput(t *testing.T) {
+	input := WindowsUpdatePluginInput{IncludeKbs: []string{"kb4012212", " 4012215"}, ExcludeKbs: []string{"KB890830"}}
+	assert.NoError(t, normalizeInput(&input))
+	assert.Equal(t, RebootIfRequired, input.RebootOption)
+	assert.Equal(t, []string{"KB4012212", "KB4012215"}, input.IncludeKbs)
+	assert.Equal(t, []string{"KB890830"}, input.ExcludeKbs)
+
+	assert.Error(t, normalizeInput(&WindowsUpdatePluginInput{RebootOption: "Always"}))
+	assert.Error(t, normalizeInput(&WindowsUpdatePluginInput{RebootOption: RebootScheduled}))
+	assert.NoError(t, normalizeInput(&WindowsUpdatePluginInput{RebootOption: RebootScheduled, RebootTime: "02:30"}))
+	assert.Error(t, normalizeInput(&WindowsUpdatePluginInput{ExcludeKbs: []string{"MS17-010"}}))
+}
+
+func TestRebootDelay(t *testing.T) {
+	current := time.Date(2020, 3, 1, 22, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	assert.Equal(t, 30*time.Minute, rebootDelay("22:30"))
+	assert.Equal(t, 4*time.Hour+30*time.Minute, rebootDelay("02:30"))
+	assert.Equal(t, 24*time.Hour, rebootDelay("22:00"))
+}
+
+func TestBuildScript(t *testing.T) {
+	script := buildScript([]string{"KB1", "KB2"}, nil, `C:\orchestration\it's\updates.json`)
+	assert.Contains(t, script, "$includeKbs = @('KB1', 'KB2')")
+	assert.Contains(t, script, "$excludeKbs = @()")
+	assert.Contains(t, script, `$resultsPath = 'C:\orchestration\it''s\updates.json'`)
+}
+
+// installWith runs the plugin with an executer writing the results file of the script in its working directory.
+func installWith(t *testing.T, input WindowsUpdatePluginInput, results string) (*executers.MockCommandExecuter, *iohandler.DefaultIOHandler) {
+	orchestrationDirectory, err := ioutil.TempDir("", "windowsupdate")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(orchestrationDirectory) })
+
+	executer := new(executers.MockCommandExecuter)
+	executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, appconfig.PowerShellPluginCommandName, mock.Anything).
+		Run(func(args mock.Arguments) {
+			resultsPath := filepath.Join(args.String(1), resultsName)
+			assert.NoError(t, ioutil.WriteFile(resultsPath, []byte(results), 0600))
+		}).Return(0, nil)
+	executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, shutdownCommand, mock.Anything).Return(0, nil)
+
+	output := &iohandler.DefaultIOHandler{}
+	plugin := &Plugin{CommandExecuter: executer}
+	plugin.installUpdates(logger, input, orchestrationDirectory, task.NewChanneledCancelFlag(), output)
+	return executer, output
+}
+
+func TestInstallUpdates(t *testing.T) {
+	results := "\xef\xbb\xbf" + `{"RebootRequired": true, "Updates": [
+		{"KB": "KB4012212", "Title": "Security Monthly Quality Rollup", "ResultCode": 2, "RebootRequired": true},
+		{"KB": "KB890830", "Title": "Malicious Software Removal Tool", "ResultCode": 3, "RebootRequired": false}]}`
+	_, output := installWith(t, WindowsUpdatePluginInput{ID: "0.aws:installWindowsUpdates", RebootOption: RebootIfRequired}, results)
+
+	assert.Equal(t, contracts.ResultStatusSuccessAndReboot, output.GetStatus())
+	assert.Equal(t, []UpdateResult{
+		{KB: "KB4012212", Title: "Security Monthly Quality Rollup", ResultCode: 2, Result: "Succeeded", RebootRequired: true},
+		{KB: "KB890830", Title: "Malicious Software Removal Tool", ResultCode: 3, Result: "SucceededWithErrors"},
+	}, output.GetOutput())
+	assert.Contains(t, output.GetStdout(), "KB4012212 Security Monthly Quality Rollup: Succeeded")
+}
+
+func TestInstallUpdatesFailed(t *testing.T) {
+	results := `{"RebootRequired": true, "Updates": [
+		{"KB": "KB4012212", "Title": "Security Monthly Quality Rollup", "ResultCode": 2},
+		{"KB": "KB4012215", "Title": "Monthly Rollup", "ResultCode": 4}]}`
+	_, output := installWith(t, WindowsUpdatePluginInput{ID: "0.aws:installWindowsUpdates", RebootOption: RebootNever}, results)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStdout(), "KB4012215 Monthly Rollup: Failed")
+	assert.Contains(t, output.GetStdout(), "RebootOption Never")
+	assert.Contains(t, output.GetStderr(), "1 of 2 updates were not installed")
+}
+
+func TestInstallUpdatesScheduledReboot(t *testing.T) {
+	current := time.Date(2020, 3, 1, 22, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	results := `{"RebootRequired": true, "Updates": [{"KB": "KB4012212", "Title": "Security Monthly Quality Rollup", "ResultCode": 2}]}`
+	executer, output := installWith(t, WindowsUpdatePluginInput{ID: "0.aws:installWindowsUpdates", RebootOption: RebootScheduled, RebootTime: "23:00"}, results)
+
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus())
+	executer.AssertCalled(t, "NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, shutdownCommand,
+		[]string{"/r", "/t", "3600", "/d", "p:2:17", "/c", "Reboot scheduled by " + Name()})
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package pollhint lets the agent components hint the message poller that new messages are waiting.
+package pollhint
+
+var hints = make(chan bool, 1)
+
+// Notify hints the message poller that new messages are waiting,
+// hints received while the poller is busy are coalesced into one.
+func Notify() {
+	select {
+	case hints <- true:
+	default:
+	}
+}
+
+// Hints returns the channel on which the hints are received.
+func Hints() <-chan bool {
+	return hints
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package powershell restricts the PowerShell sessions and runPowerShellScript commands the way JEA endpoints do:
+// the commands of the administrator run in the constrained language, with an execution policy and an allowlist
+// of modules set by the agent before their first command.
+package powershell
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// executionPolicyArgument is the argument of powershell selecting the execution policy
+const executionPolicyArgument = "-ExecutionPolicy"
+
+// Restricted returns true when the sessions and the scripts run a prologue before the commands of the administrator
+func Restricted(config appconfig.PowerShellCfg) bool {
+	return config.LanguageMode == appconfig.PowerShellLanguageModeConstrained || len(config.AllowedModules) > 0
+}
+
+// Arguments returns the arguments of powershell with the configured execution policy in place of the one
+// of the arguments, they are returned unchanged when the config has no execution policy
+func Arguments(config appconfig.PowerShellCfg, args []string) []string {
+	if config.ExecutionPolicy == "" {
+		return args
+	}
+	result := []string{executionPolicyArgument, config.ExecutionPolicy}
+	for i := 0; i < len(args); i++ {
+		if strings.EqualFold(args[i], executionPolicyArgument) {
+			i++
+			continue
+		}
+		result = append(result, args[i])
+	}
+	return result
+}
+
+// Prologue returns the lines run before the commands of the administrator, nil when the config restricts nothing.
+// The allowed modules are imported first, then the auto-loading is turned off and Import-Module only accepts
+// the allowed modules, and the language mode is switched last since the constrained language cannot switch back.
+// The session exits when any of these steps fails rather than running the commands unrestricted.
+func Prologue(config appconfig.PowerShellCfg) []string {
+	if !Restricted(config) {
+		return nil
+	}
+	lines := []string{"try {"}
+	if len(config.AllowedModules) > 0 {
+		modules := make([]string, len(config.AllowedModules))
+		for i, module := range config.AllowedModules {
+			modules[i] = quote(module)
+			lines = append(lines, fmt.Sprintf("    Import-Module -Name %v -ErrorAction Stop", modules[i]))
+		}
+		lines = append(lines,
+			"    New-Variable -Name PSModuleAutoLoadingPreference -Value 'None' -Option Constant, AllScope -Force",
+			"    New-Item -Path Function:\\Import-Module -Options Constant, AllScope -Force -Value {",
+			"        param([Parameter(Mandatory = $true, Position = 0)][string[]] $Name)",
+			"        foreach ($module in $Name) {",
+			fmt.Sprintf("            if (@(%v) -notcontains $module) { throw \"module $module is not allowed\" }", strings.Join(modules, ", ")),
+			"        }",
+			"        Microsoft.PowerShell.Core\\Import-Module -Name $Name",
+			"    } | Out-Null")
+	}
+	if config.LanguageMode == appconfig.PowerShellLanguageModeConstrained {
+		lines = append(lines, fmt.Sprintf("    $ExecutionContext.SessionState.LanguageMode = '%v'", appconfig.PowerShellLanguageModeConstrained))
+	}
+	return append(lines,
+		"} catch {",
+		"    [Console]::Error.WriteLine(\"failed to restrict the PowerShell session: $_\")",
+		"    exit 1",
+		"}")
+}
+
+// EncodedCommand returns the lines as the value of the -EncodedCommand argument of powershell
+func EncodedCommand(lines []string) string {
+	encoded := utf16.Encode([]rune(strings.Join(lines, "\n")))
+	bytes := make([]byte, 2*len(encoded))
+	for i, unit := range encoded {
+		bytes[2*i] = byte(unit)
+		bytes[2*i+1] = byte(unit >> 8)
+	}
+	return base64.StdEncoding.EncodeToString(bytes)
+}
+
+// SessionArguments returns the arguments of the powershell of a session running the command, or of an
+// interactive session when the command is empty
+func SessionArguments(config appconfig.PowerShellCfg, command string) []string {
+	args := Arguments(config, []string{})
+	command = strings.TrimSpace(command)
+	if !Restricted(config) {
+		if command != "" {
+			args = append(args, command)
+		}
+		return args
+	}
+	lines := Prologue(config)
+	if command == "" {
+		return append(args, "-NoExit", "-EncodedCommand", EncodedCommand(lines))
+	}
+	return append(args, "-EncodedCommand", EncodedCommand(append(lines, command)))
+}
+
+// quote returns the value as a single-quoted PowerShell string
+func quote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package powershell
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArguments(t *testing.T) {
+	args := []string{"-NoProfile", "-ExecutionPolicy", "unrestricted", "-f"}
+	assert.Equal(t, args, Arguments(appconfig.PowerShellCfg{}, args))
+	assert.Equal(t, []string{"-ExecutionPolicy", "AllSigned", "-NoProfile", "-f"},
+		Arguments(appconfig.PowerShellCfg{ExecutionPolicy: "AllSigned"}, args))
+}
+
+func TestPrologue(t *testing.T) {
+	assert.Nil(t, Prologue(appconfig.PowerShellCfg{LanguageMode: appconfig.PowerShellLanguageModeFull}))
+
+	prologue := strings.Join(Prologue(appconfig.PowerShellCfg{LanguageMode: appconfig.PowerShellLanguageModeConstrained}), "\n")
+	assert.Contains(t, prologue, "$ExecutionContext.SessionState.LanguageMode = 'ConstrainedLanguage'")
+	assert.NotContains(t, prologue, "Import-Module")
+
+	prologue = strings.Join(Prologue(appconfig.PowerShellCfg{
+		LanguageMode:   appconfig.PowerShellLanguageModeConstrained,
+		AllowedModules: []string{"DnsClient", "Ops'Tools"},
+	}), "\n")
+	assert.Contains(t, prologue, "Import-Module -Name 'DnsClient' -ErrorAction Stop")
+	assert.Contains(t, prologue, "if (@('DnsClient', 'Ops''Tools') -notcontains $module)")
+	assert.Contains(t, prologue, "PSModuleAutoLoadingPreference -Value 'None'")
+	// the language mode is switched once the modules are imported
+	assert.True(t, strings.Index(prologue, "LanguageMode =") > strings.Index(prologue, "Microsoft.PowerShell.Core\\Import-Module"))
+}
+
+func TestSessionArguments(t *testing.T) {
+	assert.Equal(t, []string{}, SessionArguments(appconfig.PowerShellCfg{}, " "))
+	assert.Equal(t, []string{"-ExecutionPolicy", "RemoteSigned", "Get-Process"},
+		SessionArguments(appconfig.PowerShellCfg{ExecutionPolicy: "RemoteSigned"}, "Get-Process"))
+
+	config := appconfig.PowerShellCfg{LanguageMode: appconfig.PowerShellLanguageModeConstrained}
+	args := SessionArguments(config, "")
+	assert.Equal(t, []string{"-NoExit", "-EncodedCommand", EncodedCommand(Prologue(config))}, args)
+
+	args = SessionArguments(config, "Get-Process")
+	assert.Equal(t, "-EncodedCommand", args[0])
+	assert.Equal(t, strings.Join(Prologue(config), "\n")+"\nGet-Process", decode(t, args[1]))
+}
+
+func decode(t *testing.T, encoded string) string {
+	bytes, err := base64.StdEncoding.DecodeString(encoded)
+	assert.NoError(t, err)
+	units := make([]uint16, len(bytes)/2)
+	for i := range units {
+		units[i] = uint16(bytes[2*i]) | uint16(bytes[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+// Package main implements ssm-agent-helper, the setuid helper performing the operations needing root
+// for the agent running unprivileged. It only accepts the requests validated by the privsep package, from the agent user.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/log/ssmlog"
+	"github.com/aws/amazon-ssm-agent/agent/privsep"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+)
+
+const defaultHelperContextName = "[ssm-agent-helper]"
+
+// safePath is the PATH of the scripts the helper runs as root, the PATH of the caller is not trusted
+const safePath = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// keptVariablePrefixes are the environment variables of the caller the package scripts receive
+var keptVariablePrefixes = []string{"BWS_", "http_proxy=", "https_proxy=", "no_proxy="}
+
+func main() {
+	// the environment and the Parameter Store overrides belong to the caller, only the config of root is read
+	config, configErr := privsep.TrustedConfig()
+	log := ssmlog.SSMLogger(false)
+	log = context.Default(log, config).With(defaultHelperContextName).Log()
+	defer log.Close()
+	if configErr != nil {
+		log.Warnf("Using the default config: %v", configErr)
+	}
+
+	if err := privsep.CheckCaller(config.PrivilegeSeparation.User); err != nil {
+		exit(log, err)
+	}
+	request, err := privsep.ParseRequest(os.Args[1:], config.PrivilegeSeparation.RunAsUsers)
+	if err != nil {
+		exit(log, err)
+	}
+	log.Infof("Running %v for the user %v", request.Operation, os.Getuid())
+	switch request.Operation {
+	case privsep.OpCreateUser:
+		if err = becomeRoot(); err != nil {
+			exit(log, err)
+		}
+		u := &utility.SessionUtil{}
+		if _, err = u.CreateLocalAdminUser(log); err != nil {
+			exit(log, err)
+		}
+	case privsep.OpGrantSudo, privsep.OpRevokeSudo:
+		if err = becomeRoot(); err != nil {
+			exit(log, err)
+		}
+		// the session is run by the caller of the helper
+		u := &utility.SessionUtil{}
+		if request.Operation == privsep.OpGrantSudo {
+			err = u.GrantSudoToProcess(log, os.Getppid())
+		} else {
+			err = u.RevokeSudoFromProcess(log, os.Getppid())
+		}
+		if err != nil {
+			exit(log, err)
+		}
+	case privsep.OpRunAs:
+		runAs(log, config, request)
+	case privsep.OpInstallPackage:
+		installPackage(log, config, request)
+	}
+}
+
+// runAs replaces the helper by the command running as the user, with the supplementary groups of the agent config
+func runAs(log log.T, config appconfig.SsmagentConfig, request privsep.Request) {
+	adjustGroups := func(groups []int) ([]int, error) {
+		gids := make([]uint32, len(groups))
+		for i, group := range groups {
+			gids[i] = uint32(group)
+		}
+		gids, err := utility.AdjustGroups(gids, config.Mgs.AddGroups, config.Mgs.DropGroups)
+		if err != nil {
+			return nil, err
+		}
+		groups = make([]int, len(gids))
+		for i, gid := range gids {
+			groups[i] = int(gid)
+		}
+		return groups, nil
+	}
+	if err := privsep.SwitchUser(request.User, adjustGroups); err != nil {
+		exit(log, err)
+	}
+	path, err := exec.LookPath(request.Command[0])
+	if err != nil {
+		exit(log, err)
+	}
+	log.Flush()
+	exit(log, syscall.Exec(path, request.Command, os.Environ()))
+}
+
+// installPackage runs the action script of the package as root, with the exit code of the script. The package
+// folder belongs to root while the script runs, so that the agent user can't change it, and is given back afterwards.
+// The package must match its manifest signed by one of the package signing keys of the config.
+func installPackage(log log.T, config appconfig.SsmagentConfig, request privsep.Request) {
+	caller, callerGroup := os.Getuid(), os.Getgid()
+	if err := becomeRoot(); err != nil {
+		exit(log, err)
+	}
+	if err := privsep.SealPackage(request.PackageDir); err != nil {
+		exit(log, err)
+	}
+	// the package folder is the working directory, its path could lead to another folder by now
+	for _, path := range []string{".", request.Script} {
+		if err := privsep.CheckRootOwned(path); err != nil {
+			releasePackage(log, caller, callerGroup)
+			exit(log, fmt.Errorf("refusing to run %v of %v: %v", request.Script, request.PackageDir, err))
+		}
+	}
+	keys, err := privsep.LoadTrustedKeys(config.PackageSigning.PublicKeys)
+	if err == nil {
+		err = privsep.VerifyPackage(keys)
+	}
+	if err != nil {
+		releasePackage(log, caller, callerGroup)
+		exit(log, fmt.Errorf("refusing to run %v of %v: %v", request.Script, request.PackageDir, err))
+	}
+	cmd := exec.Command("sh", "./"+request.Script)
+	cmd.Env = []string{safePath}
+	for _, variable := range os.Environ() {
+		for _, prefix := range keptVariablePrefixes {
+			if strings.HasPrefix(variable, prefix) {
+				cmd.Env = append(cmd.Env, variable)
+				break
+			}
+		}
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	err = cmd.Run()
+	releasePackage(log, caller, callerGroup)
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			log.Close()
+			os.Exit(exitErr.ExitCode())
+		}
+		exit(log, err)
+	}
+}
+
+// releasePackage gives the package folder back to the caller, a failure only prevents the agent from removing it
+func releasePackage(log log.T, uid int, gid int) {
+	if err := privsep.ReleasePackage(uid, gid); err != nil {
+		log.Warnf("Failed to give the package folder back to the user %v: %v", uid, err)
+	}
+}
+
+// becomeRoot sets the real user of the helper to root, the shells drop the effective user when it differs
+func becomeRoot() error {
+	if err := syscall.Setgid(0); err != nil {
+		return err
+	}
+	return syscall.Setuid(0)
+}
+
+// exit logs the error and exits with a failure
+func exit(log log.T, err error) {
+	log.Errorf("ssm-agent-helper failed: %v", err)
+	log.Close()
+	os.Exit(1)
+}
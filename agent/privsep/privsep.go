@@ -0,0 +1,118 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package privsep separates the privileges of the agent: the long-running agent and its workers run as an
+// unprivileged user, and the few operations needing root go through ssm-agent-helper, a setuid helper which
+// only accepts the requests listed here, from the agent user.
+package privsep
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// Operations of the helper
+const (
+	// OpCreateUser creates the Session Manager user and its sudoers file
+	OpCreateUser = "create-user"
+	// OpRunAs runs a command as one of the run as users of the agent config
+	OpRunAs = "runas"
+	// OpInstallPackage runs the install or uninstall script of a package downloaded by the agent
+	OpInstallPackage = "install-package"
+	// OpGrantSudo creates the sudoers file of the Session Manager user for the session run by the caller
+	OpGrantSudo = "grant-sudo"
+	// OpRevokeSudo removes the sudoers file of the Session Manager user when the session run by the caller was the last one
+	OpRevokeSudo = "revoke-sudo"
+)
+
+// scriptPattern matches the action scripts of the packages, they are run from the package folder
+var scriptPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+\.sh$`)
+
+// Request is a validated request of the helper
+type Request struct {
+	// Operation is the operation to perform
+	Operation string
+	// User is the user of the runas operation
+	User string
+	// Command is the command of the runas operation
+	Command []string
+	// PackageDir and Script are the package folder and the action script of the install-package operation
+	PackageDir string
+	Script     string
+}
+
+// ParseRequest validates the arguments of the helper, the operation followed by its arguments, runAsUsers are the
+// users the runas operation runs commands as
+func ParseRequest(args []string, runAsUsers []string) (request Request, err error) {
+	if len(args) == 0 {
+		return request, fmt.Errorf("no operation, expected one of %v, %v, %v, %v or %v", OpCreateUser, OpRunAs, OpInstallPackage, OpGrantSudo, OpRevokeSudo)
+	}
+	request.Operation = args[0]
+	args = args[1:]
+	switch request.Operation {
+	case OpCreateUser, OpGrantSudo, OpRevokeSudo:
+		if len(args) != 0 {
+			return request, fmt.Errorf("%v takes no argument", request.Operation)
+		}
+	case OpRunAs:
+		if len(args) < 2 {
+			return request, fmt.Errorf("%v takes a user and a command", OpRunAs)
+		}
+		// the sessions only run as the users of the agent config, the helper must not open root shells
+		if !isRunAsUser(args[0], runAsUsers) {
+			return request, fmt.Errorf("%v only runs commands as %v, not as %v", OpRunAs, strings.Join(runAsUsers, ", "), args[0])
+		}
+		request.User, request.Command = args[0], args[1:]
+	case OpInstallPackage:
+		if len(args) != 2 {
+			return request, fmt.Errorf("%v takes a package folder and a script", OpInstallPackage)
+		}
+		if !withinFolder(args[0], appconfig.PackageRoot) {
+			return request, fmt.Errorf("%v is not a package folder of the agent", args[0])
+		}
+		if !scriptPattern.MatchString(args[1]) {
+			return request, fmt.Errorf("%v is not an action script", args[1])
+		}
+		request.PackageDir, request.Script = filepath.Clean(args[0]), args[1]
+	default:
+		return request, fmt.Errorf("unknown operation %v", request.Operation)
+	}
+	return request, nil
+}
+
+// isRunAsUser returns true when the user is one of the run as users and is not root
+func isRunAsUser(userName string, runAsUsers []string) bool {
+	if userName == "root" {
+		return false
+	}
+	for _, runAsUser := range runAsUsers {
+		if userName == runAsUser {
+			return true
+		}
+	}
+	return false
+}
+
+// withinFolder returns true when the absolute path is inside the folder
+func withinFolder(path, folder string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(folder), filepath.Clean(path))
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
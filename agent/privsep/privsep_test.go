@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package privsep
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// runAsUsers are the run as users of the agent config, root is never allowed even when it is listed
+var runAsUsers = []string{appconfig.DefaultRunAsUserName, "deploy", "root"}
+
+func TestParseRequest(t *testing.T) {
+	packageDir := filepath.Join(appconfig.PackageRoot, "AWSPVDriver", "1.0.0")
+
+	request, err := ParseRequest([]string{OpCreateUser}, runAsUsers)
+	assert.NoError(t, err)
+	assert.Equal(t, OpCreateUser, request.Operation)
+
+	request, err = ParseRequest([]string{OpGrantSudo}, runAsUsers)
+	assert.NoError(t, err)
+	assert.Equal(t, OpGrantSudo, request.Operation)
+
+	request, err = ParseRequest([]string{OpRunAs, appconfig.DefaultRunAsUserName, "sh", "-c", "id"}, runAsUsers)
+	assert.NoError(t, err)
+	assert.Equal(t, appconfig.DefaultRunAsUserName, request.User)
+	assert.Equal(t, []string{"sh", "-c", "id"}, request.Command)
+
+	request, err = ParseRequest([]string{OpRunAs, "deploy", "sh"}, runAsUsers)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", request.User)
+
+	request, err = ParseRequest([]string{OpInstallPackage, packageDir, "install.sh"}, runAsUsers)
+	assert.NoError(t, err)
+	assert.Equal(t, packageDir, request.PackageDir)
+	assert.Equal(t, "install.sh", request.Script)
+}
+
+func TestParseRequest_Rejected(t *testing.T) {
+	packageDir := filepath.Join(appconfig.PackageRoot, "AWSPVDriver", "1.0.0")
+	for _, args := range [][]string{
+		{},
+		{"chmod"},
+		{OpCreateUser, "root"},
+		{OpRevokeSudo, "1"},
+		{OpRunAs, appconfig.DefaultRunAsUserName},
+		{OpRunAs, "root", "sh"},
+		{OpRunAs, "alice", "sh"},
+		{OpInstallPackage, packageDir},
+		{OpInstallPackage, "/tmp", "install.sh"},
+		{OpInstallPackage, appconfig.PackageRoot, "install.sh"},
+		{OpInstallPackage, filepath.Join(appconfig.PackageRoot, "..", "..", "tmp"), "install.sh"},
+		{OpInstallPackage, "AWSPVDriver", "install.sh"},
+		{OpInstallPackage, packageDir, "../install.sh"},
+		{OpInstallPackage, packageDir, "install.sh; reboot"},
+	} {
+		_, err := ParseRequest(args, runAsUsers)
+		assert.Error(t, err, "%v", args)
+	}
+}
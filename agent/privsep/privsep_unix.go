@@ -0,0 +1,296 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package privsep
+
+import (
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagesigning"
+)
+
+// othersWriteMask is the permissions letting the group or the other users write to a file
+const othersWriteMask os.FileMode = 0022
+
+// Required returns true when the agent runs unprivileged and goes through the helper for the operations needing root,
+// it is stubbed in the tests
+var Required = func() bool {
+	return os.Geteuid() != 0
+}
+
+// HelperPath returns the path of the helper
+func HelperPath() string {
+	return appconfig.DefaultPrivilegedHelper
+}
+
+// Command returns the command running the operation through the helper
+func Command(operation string, args ...string) *exec.Cmd {
+	return exec.Command(HelperPath(), append([]string{operation}, args...)...)
+}
+
+// RunAsCommand wraps the command so that the helper runs it as the user, the environment of the command is kept
+func RunAsCommand(userName string, cmd *exec.Cmd) *exec.Cmd {
+	wrapped := Command(OpRunAs, append([]string{userName}, cmd.Args...)...)
+	wrapped.Env, wrapped.Dir = cmd.Env, cmd.Dir
+	return wrapped
+}
+
+// DropPrivileges switches the agent to the unprivileged user, every thread of the process switches with it.
+// prepare does the setup needing root beforehand, it receives the uid and the gid of the user.
+func DropPrivileges(userName string, prepare func(uid int, gid int)) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("the agent must start as root to drop its privileges to %v", userName)
+	}
+	uid, gid, _, err := lookupUser(userName)
+	if err != nil {
+		return err
+	}
+	prepare(uid, gid)
+	return SwitchUser(userName, nil)
+}
+
+// SwitchUser sets the groups, the group and the user of the process to the ones of the user, adjustGroups changes the
+// supplementary groups of the user when it is not nil
+func SwitchUser(userName string, adjustGroups func(groups []int) ([]int, error)) error {
+	uid, gid, groups, err := lookupUser(userName)
+	if err != nil {
+		return err
+	}
+	if adjustGroups != nil {
+		if groups, err = adjustGroups(groups); err != nil {
+			return fmt.Errorf("failed to adjust the groups of %v: %v", userName, err)
+		}
+	}
+	// the groups must be changed first, the user loses the right to change them afterwards
+	if err = syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("failed to set the groups of %v: %v", userName, err)
+	}
+	if err = syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("failed to set the group of %v: %v", userName, err)
+	}
+	if err = syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("failed to switch to %v: %v", userName, err)
+	}
+	if os.Geteuid() == 0 {
+		return fmt.Errorf("the process still runs as root after switching to %v", userName)
+	}
+	return nil
+}
+
+// CheckCaller fails when the user running the helper is neither root nor the agent user
+func CheckCaller(agentUser string) error {
+	caller := os.Getuid()
+	if caller == 0 {
+		return nil
+	}
+	uid, _, _, err := lookupUser(agentUser)
+	if err != nil {
+		return err
+	}
+	if caller != uid {
+		return fmt.Errorf("the user %v is not allowed to use the helper, only the agent user %v is", caller, agentUser)
+	}
+	return nil
+}
+
+// lookupUser returns the uid, the gid and the groups of the user
+func lookupUser(userName string) (uid int, gid int, groups []int, err error) {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to look up the user %v: %v", userName, err)
+	}
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid uid %v of %v", u.Uid, userName)
+	}
+	if gid, err = strconv.Atoi(u.Gid); err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid gid %v of %v", u.Gid, userName)
+	}
+	groupIds, err := u.GroupIds()
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to look up the groups of %v: %v", userName, err)
+	}
+	for _, groupId := range groupIds {
+		if id, err := strconv.Atoi(groupId); err == nil {
+			groups = append(groups, id)
+		}
+	}
+	return uid, gid, groups, nil
+}
+
+// SealPackage gives the package folder and its content to root and makes them read-only for the other users, and
+// makes the folder the working directory of the helper. The folder is opened once, without following links, so that
+// the agent user can neither swap nor change the package while its script runs as root.
+func SealPackage(packageDir string) error {
+	dir, err := os.OpenFile(packageDir, os.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the package folder %v: %v", packageDir, err)
+	}
+	defer dir.Close()
+	info, err := dir.Stat()
+	if err != nil {
+		return err
+	}
+	if err = dir.Chown(0, 0); err != nil {
+		return fmt.Errorf("failed to give %v to root: %v", packageDir, err)
+	}
+	if err = dir.Chmod(info.Mode().Perm() &^ othersWriteMask); err != nil {
+		return fmt.Errorf("failed to make %v read-only: %v", packageDir, err)
+	}
+	if err = dir.Chdir(); err != nil {
+		return err
+	}
+	return changeOwner(".", 0, 0, true)
+}
+
+// ReleasePackage gives the package folder sealed in the working directory back to the user once its script ran,
+// so that the agent can update or remove the package
+func ReleasePackage(uid int, gid int) error {
+	if err := os.Lchown(".", uid, gid); err != nil {
+		return err
+	}
+	return changeOwner(".", uid, gid, false)
+}
+
+// changeOwner changes the owner of the content of a folder already owned by the new owner, the content can't be
+// swapped meanwhile. Sealing makes the content read-only for the other users and fails on anything but regular
+// files and folders.
+func changeOwner(dir string, uid int, gid int, seal bool) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if seal && !entry.Mode().IsRegular() && !entry.IsDir() {
+			return fmt.Errorf("%v is not a regular file or a folder", path)
+		}
+		if err = os.Lchown(path, uid, gid); err != nil {
+			return err
+		}
+		if seal {
+			if err = os.Chmod(path, entry.Mode().Perm()&^othersWriteMask); err != nil {
+				return err
+			}
+		}
+		if entry.IsDir() {
+			if err = changeOwner(path, uid, gid, seal); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckRootOwned fails when the file is a link, or is not owned by root, or can be written by the other users
+func CheckRootOwned(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%v is a link", path)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || stat.Uid != 0 {
+		return fmt.Errorf("%v is not owned by root", path)
+	}
+	if info.Mode().Perm()&othersWriteMask != 0 {
+		return fmt.Errorf("%v is writable by other users, its mode is %v", path, info.Mode().Perm())
+	}
+	return nil
+}
+
+// TrustedConfig returns the agent config the helper can trust: the defaults overridden by the config file when root
+// owns the file and its folder and the other users can't write them. The environment and the Parameter Store
+// overrides, which the agent user controls, are not applied.
+func TrustedConfig() (appconfig.SsmagentConfig, error) {
+	for _, path := range appconfig.AppConfigPaths() {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			continue
+		}
+		for _, checked := range []string{filepath.Dir(path), path} {
+			if err := CheckRootOwned(checked); err != nil {
+				return appconfig.DefaultConfig(), fmt.Errorf("ignoring the config file %v: %v", path, err)
+			}
+		}
+		return appconfig.FileConfig(path)
+	}
+	return appconfig.DefaultConfig(), nil
+}
+
+// LoadTrustedKeys reads the package signing keys, they must be owned by root and not writable by the other users
+func LoadTrustedKeys(paths []string) ([]crypto.PublicKey, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no package signing key is pinned in PackageSigning.PublicKeys")
+	}
+	for _, path := range paths {
+		if err := CheckRootOwned(path); err != nil {
+			return nil, fmt.Errorf("untrusted package signing key: %v", err)
+		}
+	}
+	return packagesigning.LoadPublicKeys(paths)
+}
+
+// VerifyPackage checks the package sealed in the working directory against its manifest, which must carry a valid
+// signature by one of the keys. Every file of the package must be listed with its sha256 checksum, so that the script
+// run as root and everything it reads from the package are signed.
+func VerifyPackage(keys []crypto.PublicKey) error {
+	manifest, err := integrity.LoadManifest(keys, appconfig.PackageManifestFileName)
+	if err != nil {
+		return err
+	}
+	signature := appconfig.PackageManifestFileName + appconfig.IntegritySignatureExtension
+	verified := map[string]bool{}
+	err = filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || path == appconfig.PackageManifestFileName || path == signature {
+			return err
+		}
+		name := filepath.ToSlash(path)
+		expected, found := manifest.Files[name]
+		if !found {
+			return fmt.Errorf("%v is not listed in the package manifest", name)
+		}
+		actual, err := integrity.Checksum(path)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("sha256 of %v is %v, the package manifest expects %v", name, actual, expected)
+		}
+		verified[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name := range manifest.Files {
+		if !verified[name] {
+			return fmt.Errorf("%v of the package manifest is missing", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package privsep
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/integrity"
+	"github.com/stretchr/testify/assert"
+)
+
+// owner returns the uid of the file
+func owner(t *testing.T, path string) uint32 {
+	info, err := os.Lstat(path)
+	assert.NoError(t, err)
+	return info.Sys().(*syscall.Stat_t).Uid
+}
+
+func TestSealPackage(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("giving the package folder to root requires root")
+	}
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(wd)
+	dir, err := ioutil.TempDir("", "package")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	packageDir := filepath.Join(dir, "1.0.0")
+	assert.NoError(t, os.MkdirAll(filepath.Join(packageDir, "files"), 0777))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(packageDir, "install.sh"), []byte("echo installed"), 0666))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(packageDir, "files", "agent.rpm"), nil, 0644))
+	for _, path := range []string{packageDir, filepath.Join(packageDir, "files"), filepath.Join(packageDir, "install.sh"), filepath.Join(packageDir, "files", "agent.rpm")} {
+		assert.NoError(t, os.Chown(path, 1234, 1234))
+		assert.Error(t, CheckRootOwned(path))
+	}
+
+	assert.NoError(t, SealPackage(packageDir))
+	for _, path := range []string{".", "files", "install.sh", filepath.Join("files", "agent.rpm")} {
+		assert.NoError(t, CheckRootOwned(path), path)
+	}
+	info, err := os.Stat("install.sh")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	assert.NoError(t, ReleasePackage(1234, 1234))
+	for _, path := range []string{packageDir, filepath.Join(packageDir, "files"), filepath.Join(packageDir, "install.sh"), filepath.Join(packageDir, "files", "agent.rpm")} {
+		assert.Equal(t, uint32(1234), owner(t, path), path)
+	}
+}
+
+func TestSealPackage_Link(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("giving the package folder to root requires root")
+	}
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(wd)
+	dir, err := ioutil.TempDir("", "package")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	packageDir := filepath.Join(dir, "1.0.0")
+	assert.NoError(t, os.Mkdir(packageDir, 0755))
+	assert.NoError(t, os.Symlink("/etc/shadow", filepath.Join(packageDir, "install.sh")))
+
+	// the links could give root files away, the package folder itself can't be a link either
+	assert.Error(t, SealPackage(packageDir))
+	assert.NoError(t, os.Symlink(packageDir, filepath.Join(dir, "latest")))
+	assert.Error(t, SealPackage(filepath.Join(dir, "latest")))
+	assert.Error(t, CheckRootOwned(filepath.Join(packageDir, "install.sh")))
+}
+
+// writePackage writes the files of a package and its manifest signed by the key in the working directory
+func writePackage(t *testing.T, files map[string]string, manifestFiles map[string]string, privateKey ed25519.PrivateKey) {
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		assert.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+	content, err := json.Marshal(integrity.Manifest{Version: "1.0.0", Files: manifestFiles})
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(appconfig.PackageManifestFileName, content, 0644))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, content))
+	assert.NoError(t, ioutil.WriteFile(appconfig.PackageManifestFileName+appconfig.IntegritySignatureExtension, []byte(signature), 0644))
+}
+
+func TestVerifyPackage(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	defer os.Chdir(wd)
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	keys := []crypto.PublicKey{publicKey}
+
+	files := map[string]string{"install.sh": "echo installed", "files/agent.rpm": "rpm"}
+	checksums := map[string]string{}
+	for name, content := range files {
+		checksums[name] = checksum(t, content)
+	}
+	for name, test := range map[string]struct {
+		files     map[string]string
+		checksums map[string]string
+		key       ed25519.PrivateKey
+		valid     bool
+	}{
+		"signed":     {files, checksums, privateKey, true},
+		"other key":  {files, checksums, otherKey, false},
+		"unlisted":   {map[string]string{"install.sh": "echo installed", "files/agent.rpm": "rpm", "extra.sh": "reboot"}, checksums, privateKey, false},
+		"changed":    {map[string]string{"install.sh": "reboot", "files/agent.rpm": "rpm"}, checksums, privateKey, false},
+		"missing":    {map[string]string{"install.sh": "echo installed"}, checksums, privateKey, false},
+		"no content": {files, map[string]string{}, privateKey, false},
+	} {
+		dir, err := ioutil.TempDir("", "package")
+		assert.NoError(t, err)
+		assert.NoError(t, os.Chdir(dir))
+		writePackage(t, test.files, test.checksums, test.key)
+		err = VerifyPackage(keys)
+		assert.Equal(t, test.valid, err == nil, "%v: %v", name, err)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadTrustedKeys_Untrusted(t *testing.T) {
+	_, err := LoadTrustedKeys(nil)
+	assert.Error(t, err)
+
+	file, err := ioutil.TempFile("", "key")
+	assert.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+	assert.NoError(t, os.Chmod(file.Name(), 0666))
+	_, err = LoadTrustedKeys([]string{file.Name()})
+	assert.Error(t, err)
+}
+
+// checksum returns the hex encoded sha256 of the content
+func checksum(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "content")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(content)
+	file.Close()
+	sum, err := integrity.Checksum(file.Name())
+	assert.NoError(t, err)
+	return sum
+}
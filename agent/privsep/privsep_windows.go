@@ -0,0 +1,35 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package privsep
+
+import "errors"
+
+// Required returns false, the agent runs as LocalSystem on Windows
+var Required = func() bool {
+	return false
+}
+
+// HelperPath returns an empty path, there is no helper on Windows
+func HelperPath() string {
+	return ""
+}
+
+// DropPrivileges is not supported, the agent runs as LocalSystem on Windows
+func DropPrivileges(userName string, prepare func(uid int, gid int)) error {
+	return errors.New("the privilege separation is not supported on Windows")
+}
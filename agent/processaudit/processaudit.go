@@ -0,0 +1,219 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processaudit records in the audit log the processes started within the sessions, with their command line,
+// their user and the chain of their parents, so that the commands run in a session are known even when the shell
+// history is removed. The processes are followed from the session worker with the proc connector on Linux and by
+// listing the processes on Windows.
+package processaudit
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// maxParents is the number of parents recorded with a process, from its direct parent up to the session worker
+const maxParents = 16
+
+// tracked is a process started within a session
+type tracked struct {
+	sessionID string
+	parents   []int
+}
+
+// tracker follows the processes of the sessions from their session worker, the processes keep their parents
+// when their parent exits
+type tracker struct {
+	sync.Mutex
+	processes map[int]tracked
+}
+
+// process is a process of the instance, listed where the process events are not available
+type process struct {
+	pid       int
+	parentPid int
+}
+
+// Session follows the processes of a session, the methods of a nil Session do nothing
+type Session struct {
+	id   string
+	once sync.Once
+}
+
+// listener is the process event listener shared by the sessions, it is started with the first session and stopped
+// with the last one, unless it is kept listening
+var listener struct {
+	sync.Mutex
+	sessions int
+	stop     func()
+	kept     bool
+}
+
+// processes are the processes of the sessions followed by the listener
+var processes = &tracker{processes: make(map[int]tracked)}
+
+// Watch records the processes started under the session worker of the session, it returns nil when the processes
+// are not recorded or the process events are not available
+func Watch(log log.T, config appconfig.AuditCfg, sessionID string, workerPid int) *Session {
+	if !config.Enabled || !config.SessionProcesses || workerPid == 0 {
+		return nil
+	}
+	listener.Lock()
+	defer listener.Unlock()
+	if listener.sessions == 0 && !listener.kept {
+		stop, err := startListener(log, processes)
+		if err != nil {
+			log.Warnf("Processes of session %v not recorded: %v", sessionID, err)
+			return nil
+		}
+		listener.stop = stop
+	}
+	listener.sessions++
+	processes.add(sessionID, workerPid)
+	return &Session{id: sessionID}
+}
+
+// KeepListening starts the process event listener for the lifetime of the agent. The agent running unprivileged
+// starts it before it drops its privileges, the proc connector only accepts the subscriptions of root.
+func KeepListening(log log.T, config appconfig.AuditCfg) {
+	if !config.Enabled || !config.SessionProcesses {
+		return
+	}
+	listener.Lock()
+	defer listener.Unlock()
+	if listener.kept {
+		return
+	}
+	if listener.sessions == 0 {
+		stop, err := startListener(log, processes)
+		if err != nil {
+			log.Warnf("Processes of the sessions not recorded: %v", err)
+			return
+		}
+		listener.stop = stop
+	}
+	listener.kept = true
+}
+
+// End stops recording the processes of the session
+func (s *Session) End() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		listener.Lock()
+		defer listener.Unlock()
+		processes.remove(s.id)
+		listener.sessions--
+		if listener.sessions == 0 && !listener.kept {
+			listener.stop()
+			listener.stop = nil
+		}
+	})
+}
+
+// record writes the ProcessStarted event of a process of a session
+func record(sessionID string, pid int, parents []int, argv []string, uid string) {
+	audit.Record(audit.Event{
+		Type:       audit.ProcessStarted,
+		SessionID:  sessionID,
+		Pid:        pid,
+		ParentPids: parents,
+		Argv:       argv,
+		Uid:        uid,
+	})
+}
+
+// add follows the session worker of a session
+func (t *tracker) add(sessionID string, pid int) {
+	t.Lock()
+	defer t.Unlock()
+	t.processes[pid] = tracked{sessionID: sessionID}
+}
+
+// remove forgets the processes of a session
+func (t *tracker) remove(sessionID string) {
+	t.Lock()
+	defer t.Unlock()
+	for pid, p := range t.processes {
+		if p.sessionID == sessionID {
+			delete(t.processes, pid)
+		}
+	}
+}
+
+// fork follows the child when its parent is followed
+func (t *tracker) fork(parentPid int, childPid int) {
+	t.Lock()
+	defer t.Unlock()
+	parent, found := t.processes[parentPid]
+	if !found {
+		return
+	}
+	parents := append([]int{parentPid}, parent.parents...)
+	if len(parents) > maxParents {
+		parents = parents[:maxParents]
+	}
+	t.processes[childPid] = tracked{sessionID: parent.sessionID, parents: parents}
+}
+
+// exec returns the session and the parents of the process when it is followed
+func (t *tracker) exec(pid int) (sessionID string, parents []int, found bool) {
+	t.Lock()
+	defer t.Unlock()
+	p, found := t.processes[pid]
+	return p.sessionID, p.parents, found
+}
+
+// exit forgets the process so that its id can be reused
+func (t *tracker) exit(pid int) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.processes, pid)
+}
+
+// update follows the listed processes whose parent is followed and forgets the followed processes which are not
+// listed anymore, it returns the processes it started following
+func (t *tracker) update(listed []process) (started []int) {
+	running := make(map[int]bool, len(listed))
+	for _, p := range listed {
+		running[p.pid] = true
+	}
+	t.Lock()
+	for pid := range t.processes {
+		if !running[pid] {
+			delete(t.processes, pid)
+		}
+	}
+	t.Unlock()
+
+	// the parents are not always listed before their children
+	for found := true; found; {
+		found = false
+		for _, p := range listed {
+			if _, _, followed := t.exec(p.pid); followed {
+				continue
+			}
+			if _, _, followed := t.exec(p.parentPid); followed {
+				t.fork(p.parentPid, p.pid)
+				started = append(started, p.pid)
+				found = true
+			}
+		}
+	}
+	return started
+}
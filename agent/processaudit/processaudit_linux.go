@@ -0,0 +1,210 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package processaudit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Values of the proc connector, see linux/connector.h and linux/cn_proc.h
+const (
+	netlinkConnector  = 11
+	cnIdxProc         = 1
+	cnValProc         = 1
+	procCnMcastListen = 1
+	procCnMcastIgnore = 2
+
+	procEventFork = 0x00000001
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+
+	// cnMsgSize is the size of the cn_msg header preceding the proc_event
+	cnMsgSize = 20
+	// procEventDataOffset is the offset of the event data in the proc_event, after its what, cpu and timestamp
+	procEventDataOffset = 16
+)
+
+// nativeEndian is the byte order of the netlink messages, the agent is built for little endian architectures
+var nativeEndian = binary.LittleEndian
+
+// readTimeout is the timeout of the reads of the socket, the interval at which the listener checks that it is stopped
+var readTimeout = time.Second
+
+// procPath is the path of the proc filesystem the command line and the user of the processes are read from
+var procPath = "/proc"
+
+// procEvent is a fork, exec or exit event of the proc connector
+type procEvent struct {
+	what      uint32
+	pid       int
+	parentPid int
+}
+
+var startListener = func(log log.T, t *tracker) (stop func(), err error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM, netlinkConnector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the proc connector: %v", err)
+	}
+	timeout := syscall.NsecToTimeval(readTimeout.Nanoseconds())
+	if err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err == nil {
+		err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc})
+	}
+	if err == nil {
+		err = subscribe(fd, procCnMcastListen)
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to listen to the proc connector: %v", err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		listen(log, t, fd, done)
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		if err := subscribe(fd, procCnMcastIgnore); err != nil {
+			log.Debugf("failed to stop listening to the proc connector: %v", err)
+		}
+		syscall.Close(fd)
+	}, nil
+}
+
+// subscribe sends the multicast operation to the proc connector
+func subscribe(fd int, op uint32) error {
+	var message bytes.Buffer
+	header := syscall.NlMsghdr{
+		Len:  syscall.NLMSG_HDRLEN + cnMsgSize + 4,
+		Type: syscall.NLMSG_DONE,
+		Pid:  uint32(os.Getpid()),
+	}
+	binary.Write(&message, nativeEndian, header)
+	// cn_msg: idx, val, seq, ack, len and flags
+	binary.Write(&message, nativeEndian, []uint32{cnIdxProc, cnValProc, 0, 0})
+	binary.Write(&message, nativeEndian, []uint16{4, 0})
+	binary.Write(&message, nativeEndian, op)
+	return syscall.Sendto(fd, message.Bytes(), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+// listen follows the processes of the sessions with the events of the proc connector until done is closed
+func listen(log log.T, t *tracker, fd int, done chan struct{}) {
+	buffer := make([]byte, syscall.Getpagesize())
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		n, _, err := syscall.Recvfrom(fd, buffer, 0)
+		if err != nil {
+			if err != syscall.EAGAIN && err != syscall.EINTR {
+				// ENOBUFS when the events came faster than they were read, the lost processes are not recorded
+				log.Debugf("failed to read the proc connector: %v", err)
+			}
+			continue
+		}
+		messages, err := syscall.ParseNetlinkMessage(buffer[:n])
+		if err != nil {
+			log.Debugf("failed to parse the proc connector message: %v", err)
+			continue
+		}
+		for _, message := range messages {
+			if event, ok := parseProcEvent(message.Data); ok {
+				handle(t, event)
+			}
+		}
+	}
+}
+
+// parseProcEvent returns the fork, exec or exit event of the data of a proc connector message, the events of the
+// threads are ignored
+func parseProcEvent(data []byte) (event procEvent, ok bool) {
+	if len(data) < cnMsgSize+procEventDataOffset {
+		return event, false
+	}
+	data = data[cnMsgSize:]
+	event.what = nativeEndian.Uint32(data)
+	fields := data[procEventDataOffset:]
+	field := func(i int) int {
+		return int(nativeEndian.Uint32(fields[4*i:]))
+	}
+	switch event.what {
+	case procEventFork:
+		// parent pid and tgid, child pid and tgid
+		if len(fields) < 16 || field(2) != field(3) {
+			return event, false
+		}
+		event.parentPid, event.pid = field(1), field(3)
+	case procEventExec, procEventExit:
+		// pid and tgid
+		if len(fields) < 8 || field(0) != field(1) {
+			return event, false
+		}
+		event.pid = field(1)
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// handle applies the event to the followed processes and records the processes executed within the sessions
+func handle(t *tracker, event procEvent) {
+	switch event.what {
+	case procEventFork:
+		t.fork(event.parentPid, event.pid)
+	case procEventExec:
+		if sessionID, parents, found := t.exec(event.pid); found {
+			argv, uid := processDetails(event.pid)
+			record(sessionID, event.pid, parents, argv, uid)
+		}
+	case procEventExit:
+		t.exit(event.pid)
+	}
+}
+
+// processDetails returns the command line and the real user id of the process, they are empty when the process
+// already exited
+func processDetails(pid int) (argv []string, uid string) {
+	dir := filepath.Join(procPath, strconv.Itoa(pid))
+	if cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+		argv = strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+	}
+	if status, err := ioutil.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "Uid:" {
+				uid = fields[1]
+				break
+			}
+		}
+	}
+	return argv, uid
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package processaudit
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+// procEventData returns the data of a proc connector message with the event and its fields
+func procEventData(what uint32, fields ...uint32) []byte {
+	data := make([]byte, cnMsgSize+procEventDataOffset+4*len(fields))
+	binary.LittleEndian.PutUint32(data[cnMsgSize:], what)
+	for i, field := range fields {
+		binary.LittleEndian.PutUint32(data[cnMsgSize+procEventDataOffset+4*i:], field)
+	}
+	return data
+}
+
+func TestParseProcEvent(t *testing.T) {
+	event, ok := parseProcEvent(procEventData(procEventFork, 100, 100, 101, 101))
+	assert.True(t, ok)
+	assert.Equal(t, procEvent{what: procEventFork, pid: 101, parentPid: 100}, event)
+
+	// the thread of a thread is attributed to the process
+	event, ok = parseProcEvent(procEventData(procEventFork, 103, 100, 104, 104))
+	assert.True(t, ok)
+	assert.Equal(t, 100, event.parentPid)
+
+	event, ok = parseProcEvent(procEventData(procEventExec, 101, 101))
+	assert.True(t, ok)
+	assert.Equal(t, procEvent{what: procEventExec, pid: 101}, event)
+
+	event, ok = parseProcEvent(procEventData(procEventExit, 101, 101, 0, 0))
+	assert.True(t, ok)
+	assert.Equal(t, procEvent{what: procEventExit, pid: 101}, event)
+
+	// the threads, the other events and the truncated messages are ignored
+	_, ok = parseProcEvent(procEventData(procEventFork, 100, 100, 102, 100))
+	assert.False(t, ok)
+	_, ok = parseProcEvent(procEventData(procEventExit, 102, 100))
+	assert.False(t, ok)
+	_, ok = parseProcEvent(procEventData(0x00000004, 101, 101))
+	assert.False(t, ok)
+	_, ok = parseProcEvent(procEventData(procEventFork, 100))
+	assert.False(t, ok)
+	_, ok = parseProcEvent(make([]byte, cnMsgSize))
+	assert.False(t, ok)
+}
+
+func TestHandle_RecordsTheExecutedProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	procPathOrig := procPath
+	defer func() { procPath = procPathOrig }()
+	procPath = dir
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "101"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "101", "cmdline"), []byte("cat\x00/etc/shadow\x00"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "101", "status"), []byte("Name:\tcat\nUid:\t1001\t0\t0\t0\n"), 0600))
+
+	var events []audit.Event
+	audit.SetObserver("processaudit", func(event audit.Event) { events = append(events, event) })
+	defer audit.SetObserver("processaudit", nil)
+
+	tr := &tracker{processes: make(map[int]tracked)}
+	tr.add("session-id", 100)
+	handle(tr, procEvent{what: procEventFork, pid: 101, parentPid: 100})
+	handle(tr, procEvent{what: procEventExec, pid: 101})
+	handle(tr, procEvent{what: procEventExec, pid: 200})
+	handle(tr, procEvent{what: procEventExit, pid: 101})
+	handle(tr, procEvent{what: procEventExec, pid: 101})
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, audit.ProcessStarted, events[0].Type)
+	assert.Equal(t, "session-id", events[0].SessionID)
+	assert.Equal(t, 101, events[0].Pid)
+	assert.Equal(t, []int{100}, events[0].ParentPids)
+	assert.Equal(t, []string{"cat", "/etc/shadow"}, events[0].Argv)
+	assert.Equal(t, "1001", events[0].Uid)
+}
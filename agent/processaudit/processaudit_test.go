@@ -0,0 +1,161 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package processaudit
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var enabled = appconfig.AuditCfg{Enabled: true, SessionProcesses: true}
+
+// stubListener counts the starts and the stops of the listener, it returns the function restoring the listener
+func stubListener(err error) (started *int, stopped *int, restore func()) {
+	started, stopped = new(int), new(int)
+	startListenerOrig := startListener
+	restore = func() { startListener = startListenerOrig }
+	startListener = func(log log.T, t *tracker) (func(), error) {
+		if err != nil {
+			return nil, err
+		}
+		*started++
+		return func() { *stopped++ }, nil
+	}
+	return started, stopped, restore
+}
+
+func TestWatch_Disabled(t *testing.T) {
+	started, _, restore := stubListener(nil)
+	defer restore()
+	assert.Nil(t, Watch(log.NewMockLog(), appconfig.AuditCfg{Enabled: true}, "session-id", 100))
+	assert.Nil(t, Watch(log.NewMockLog(), appconfig.AuditCfg{SessionProcesses: true}, "session-id", 100))
+	assert.Equal(t, 0, *started)
+
+	// the methods of a nil session do nothing
+	var session *Session
+	session.End()
+}
+
+func TestWatch_ListenerUnavailable(t *testing.T) {
+	_, _, restore := stubListener(errors.New("unavailable"))
+	defer restore()
+	assert.Nil(t, Watch(log.NewMockLog(), enabled, "session-id", 100))
+	_, _, found := processes.exec(100)
+	assert.False(t, found)
+}
+
+func TestWatch_SharesTheListener(t *testing.T) {
+	started, stopped, restore := stubListener(nil)
+	defer restore()
+	first := Watch(log.NewMockLog(), enabled, "first", 100)
+	second := Watch(log.NewMockLog(), enabled, "second", 200)
+	processes.fork(100, 101)
+	assert.Equal(t, 1, *started)
+
+	first.End()
+	first.End()
+	assert.Equal(t, 0, *stopped)
+	_, _, found := processes.exec(101)
+	assert.False(t, found)
+	sessionID, _, found := processes.exec(200)
+	assert.True(t, found)
+	assert.Equal(t, "second", sessionID)
+
+	second.End()
+	assert.Equal(t, 1, *stopped)
+	assert.Empty(t, processes.processes)
+}
+
+func TestKeepListening(t *testing.T) {
+	started, stopped, restore := stubListener(nil)
+	defer restore()
+	defer func() { listener.kept, listener.stop = false, nil }()
+	KeepListening(log.NewMockLog(), appconfig.AuditCfg{Enabled: true})
+	assert.Equal(t, 0, *started)
+	KeepListening(log.NewMockLog(), enabled)
+	KeepListening(log.NewMockLog(), enabled)
+	assert.Equal(t, 1, *started)
+
+	// the sessions share the listener started ahead, which keeps listening after them
+	session := Watch(log.NewMockLog(), enabled, "session-id", 100)
+	assert.NotNil(t, session)
+	session.End()
+	assert.Equal(t, 1, *started)
+	assert.Equal(t, 0, *stopped)
+}
+
+func TestTracker_FollowsTheDescendants(t *testing.T) {
+	tr := &tracker{processes: make(map[int]tracked)}
+	tr.add("session-id", 100)
+	tr.fork(100, 101)
+	tr.fork(101, 102)
+	tr.fork(1, 50)
+
+	sessionID, parents, found := tr.exec(102)
+	assert.True(t, found)
+	assert.Equal(t, "session-id", sessionID)
+	assert.Equal(t, []int{101, 100}, parents)
+	_, _, found = tr.exec(50)
+	assert.False(t, found)
+
+	// the children keep their parents when the parents exit
+	tr.exit(101)
+	_, parents, found = tr.exec(102)
+	assert.True(t, found)
+	assert.Equal(t, []int{101, 100}, parents)
+	_, _, found = tr.exec(101)
+	assert.False(t, found)
+}
+
+func TestTracker_LimitsTheParents(t *testing.T) {
+	tr := &tracker{processes: make(map[int]tracked)}
+	tr.add("session-id", 1)
+	for pid := 2; pid <= maxParents+10; pid++ {
+		tr.fork(pid-1, pid)
+	}
+	_, parents, _ := tr.exec(maxParents + 10)
+	assert.Len(t, parents, maxParents)
+	assert.Equal(t, maxParents+9, parents[0])
+}
+
+func TestTracker_Update(t *testing.T) {
+	tr := &tracker{processes: make(map[int]tracked)}
+	tr.add("session-id", 100)
+	tr.fork(100, 90)
+
+	// the children are listed before their parents, the exited processes are forgotten
+	started := tr.update([]process{
+		{pid: 102, parentPid: 101},
+		{pid: 101, parentPid: 100},
+		{pid: 100, parentPid: 4},
+		{pid: 50, parentPid: 4},
+	})
+	sort.Ints(started)
+	assert.Equal(t, []int{101, 102}, started)
+	_, parents, found := tr.exec(102)
+	assert.True(t, found)
+	assert.Equal(t, []int{101, 100}, parents)
+	_, _, found = tr.exec(90)
+	assert.False(t, found)
+	_, _, found = tr.exec(50)
+	assert.False(t, found)
+
+	assert.Empty(t, tr.update([]process{{pid: 100, parentPid: 4}, {pid: 101, parentPid: 100}, {pid: 102, parentPid: 101}}))
+}
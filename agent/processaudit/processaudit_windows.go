@@ -0,0 +1,174 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package processaudit
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	// processCommandLineInformation is the PROCESSINFOCLASS of the command line, available from Windows 8.1
+	processCommandLineInformation = 60
+	statusInfoLengthMismatch      = 0xC0000004
+	statusBufferTooSmall          = 0xC0000023
+)
+
+// listInterval is the interval the processes are listed at, the processes living less than this interval may not
+// be recorded
+var listInterval = time.Second
+
+var (
+	ntdll                         = syscall.NewLazyDLL("ntdll.dll")
+	ntQueryInformationProcessProc = ntdll.NewProc("NtQueryInformationProcess")
+)
+
+// unicodeString is the UNICODE_STRING returned for the command line
+type unicodeString struct {
+	length        uint16
+	maximumLength uint16
+	buffer        *uint16
+}
+
+var startListener = func(log log.T, t *tracker) (stop func(), err error) {
+	if err = ntQueryInformationProcessProc.Find(); err != nil {
+		return nil, fmt.Errorf("failed to find NtQueryInformationProcess: %v", err)
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		listen(log, t, done)
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}, nil
+}
+
+// listen lists the processes at every interval and records the processes started within the sessions
+func listen(log log.T, t *tracker, done chan struct{}) {
+	ticker := time.NewTicker(listInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		listed, err := listProcesses()
+		if err != nil {
+			log.Debugf("failed to list the processes: %v", err)
+			continue
+		}
+		for _, pid := range t.update(listed) {
+			if sessionID, parents, found := t.exec(pid); found {
+				argv, uid := processDetails(pid)
+				record(sessionID, pid, parents, argv, uid)
+			}
+		}
+	}
+}
+
+func listProcesses() ([]process, error) {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var processes []process
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		processes = append(processes, process{pid: int(entry.ProcessID), parentPid: int(entry.ParentProcessID)})
+	}
+	if err != syscall.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+	return processes, nil
+}
+
+// processDetails returns the command line and the user SID of the process, they are empty when the process
+// already exited
+func processDetails(pid int) (argv []string, uid string) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return nil, ""
+	}
+	defer syscall.CloseHandle(handle)
+	if commandLine, err := commandLine(handle); err == nil && commandLine != "" {
+		argv = splitCommandLine(commandLine)
+	}
+	var token syscall.Token
+	if err = syscall.OpenProcessToken(handle, syscall.TOKEN_QUERY, &token); err == nil {
+		defer token.Close()
+		if user, err := token.GetTokenUser(); err == nil {
+			uid, _ = user.User.Sid.String()
+		}
+	}
+	return argv, uid
+}
+
+// commandLine returns the command line of the process
+func commandLine(handle syscall.Handle) (string, error) {
+	buffer := make([]byte, 1024)
+	for {
+		var size uint32
+		status, _, _ := ntQueryInformationProcessProc.Call(uintptr(handle), processCommandLineInformation,
+			uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)), uintptr(unsafe.Pointer(&size)))
+		if (status == statusInfoLengthMismatch || status == statusBufferTooSmall) && int(size) > len(buffer) {
+			buffer = make([]byte, size)
+			continue
+		}
+		if status != 0 {
+			return "", fmt.Errorf("NtQueryInformationProcess failed with status %#x", status)
+		}
+		break
+	}
+	value := (*unicodeString)(unsafe.Pointer(&buffer[0]))
+	if value.length == 0 || value.buffer == nil {
+		return "", nil
+	}
+	chars := (*[1 << 29]uint16)(unsafe.Pointer(value.buffer))[: value.length/2 : value.length/2]
+	return syscall.UTF16ToString(chars), nil
+}
+
+// splitCommandLine splits the command line into its arguments the way the C runtime does
+func splitCommandLine(commandLine string) []string {
+	utf16, err := syscall.UTF16PtrFromString(commandLine)
+	if err != nil {
+		return []string{commandLine}
+	}
+	var argc int32
+	argv, err := syscall.CommandLineToArgv(utf16, &argc)
+	if err != nil {
+		return []string{commandLine}
+	}
+	defer syscall.LocalFree(syscall.Handle(uintptr(unsafe.Pointer(argv))))
+	args := make([]string, argc)
+	for i := range args {
+		args[i] = syscall.UTF16ToString(argv[i][:])
+	}
+	return args
+}
@@ -0,0 +1,210 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package profiling serves the runtime profiles of the agent with net/http/pprof on localhost or on a Unix socket,
+// when they are turned on in appconfig, and captures them for ssm-cli.
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
+)
+
+const (
+	name = "Profiling"
+
+	// Path is the path the profiles are served under
+	Path = "/debug/pprof/"
+
+	// captureTimeout is how long ssm-cli waits for a profile on top of its duration
+	captureTimeout = 30 * time.Second
+)
+
+// Profiles are the names of the profiles ssm-cli captures by the names of their endpoints
+var Profiles = map[string]string{
+	"cpu":       "profile",
+	"heap":      "heap",
+	"goroutine": "goroutine",
+}
+
+// Server is the core module serving the profiles of the agent
+type Server struct {
+	context    context.T
+	address    string
+	tokenFile  string
+	token      []byte
+	socketPath string
+	servers    []*http.Server
+}
+
+// NewServer creates the profiling endpoints, nil when neither a port nor a socket is configured in appconfig
+func NewServer(context context.T) *Server {
+	config := context.AppConfig().Profiling
+	if config.Port == 0 && config.SocketPath == "" {
+		return nil
+	}
+	server := &Server{
+		context:    context.With("[" + name + "]"),
+		socketPath: config.SocketPath,
+	}
+	if config.Port != 0 {
+		server.address = fmt.Sprintf("127.0.0.1:%v", config.Port)
+		server.tokenFile = config.TokenFile
+	}
+	return server
+}
+
+// ModuleName returns the name of the module
+func (s *Server) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts serving the profiles
+func (s *Server) ModuleExecute(context context.T) (err error) {
+	log := s.context.Log()
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, pprof.Index)
+	mux.HandleFunc(Path+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(Path+"profile", pprof.Profile)
+	mux.HandleFunc(Path+"symbol", pprof.Symbol)
+	mux.HandleFunc(Path+"trace", pprof.Trace)
+
+	if s.socketPath != "" {
+		// a socket left by a previous agent would fail the listen
+		os.Remove(s.socketPath)
+		listener, err := net.Listen("unix", s.socketPath)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %v for the profiling endpoints: %v", s.socketPath, err)
+		}
+		if err = os.Chmod(s.socketPath, appconfig.ReadWriteAccess); err != nil {
+			log.Warnf("Failed to restrict the access to the profiling socket %v: %v", s.socketPath, err)
+		}
+		s.serve(healthendpoint.NewPeerCheckListener(listener, log), mux)
+		log.Infof("Serving the profiles on unix socket %v at %v", s.socketPath, Path)
+	}
+	if s.address != "" {
+		// any local user can connect to the port, only the requests with the token are served
+		if s.token, err = healthendpoint.ReadBearerToken(s.tokenFile); err != nil {
+			s.ModuleRequestStop(contracts.StopTypeSoftStop)
+			return err
+		}
+		listener, err := net.Listen("tcp", s.address)
+		if err != nil {
+			s.ModuleRequestStop(contracts.StopTypeSoftStop)
+			return fmt.Errorf("failed to listen on %v for the profiling endpoints: %v", s.address, err)
+		}
+		s.serve(listener, s.requireToken(mux))
+		log.Infof("Serving the profiles on http://%v%v", s.address, Path)
+	}
+	return nil
+}
+
+// serve serves the profiles on a listener until the module stops
+func (s *Server) serve(listener net.Listener, handler http.Handler) {
+	server := &http.Server{Handler: handler}
+	s.servers = append(s.servers, server)
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.context.Log().Errorf("profiling endpoints stopped: %v", err)
+		}
+	}()
+}
+
+// requireToken rejects the requests without the bearer token of the profiling endpoints
+func (s *Server) requireToken(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthendpoint.HasBearerToken(r, s.token) {
+			s.context.Log().Warnf("Rejecting a profiling request from %v without a valid token", r.RemoteAddr)
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ModuleRequestStop stops serving the profiles
+func (s *Server) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	for _, server := range s.servers {
+		if closeErr := server.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	if s.socketPath != "" && len(s.servers) > 0 {
+		os.Remove(s.socketPath)
+	}
+	s.servers = nil
+	return err
+}
+
+// Capture writes a profile of the agent running on the instance, the socket is preferred to the port, the cpu
+// profile lasts the seconds
+func Capture(config appconfig.ProfilingCfg, profile string, seconds int, w io.Writer) error {
+	endpoint, found := Profiles[profile]
+	if !found {
+		return fmt.Errorf("unknown profile %v", profile)
+	}
+	client := &http.Client{Timeout: time.Duration(seconds)*time.Second + captureTimeout}
+	address, host := config.SocketPath, "agent"
+	var token []byte
+	switch {
+	case config.SocketPath != "":
+		client.Transport = &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return net.Dial("unix", config.SocketPath)
+			},
+		}
+	case config.Port != 0:
+		address = fmt.Sprintf("127.0.0.1:%v", config.Port)
+		host = address
+		var err error
+		if token, err = healthendpoint.ReadBearerToken(config.TokenFile); err != nil {
+			return fmt.Errorf("failed to query the agent on %v, make sure that you have admin rights: %v", address, err)
+		}
+	default:
+		return fmt.Errorf("the profiling endpoints of the agent are not turned on, set Profiling.SocketPath, or Profiling.Port and Profiling.TokenFile, in amazon-ssm-agent.json")
+	}
+	url := "http://" + host + Path + endpoint
+	if endpoint == Profiles["cpu"] {
+		url += fmt.Sprintf("?seconds=%v", seconds)
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token != nil {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query the agent on %v, make sure the agent is running and that you have admin rights: %v", address, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("the agent returned %v: %s", resp.Status, body)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package profiling
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// gzipMagic starts the profiles in the format of pprof
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func TestNewServer_Disabled(t *testing.T) {
+	assert.Nil(t, NewServer(context.NewMockDefault()))
+}
+
+func TestServer_ServesTheProfilesOnTheSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiling")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config := appconfig.ProfilingCfg{SocketPath: filepath.Join(dir, "pprof.sock")}
+
+	server := &Server{context: context.NewMockDefault(), socketPath: config.SocketPath}
+	assert.NoError(t, server.ModuleExecute(server.context))
+	for _, profile := range []string{"heap", "goroutine"} {
+		var profileData bytes.Buffer
+		assert.NoError(t, Capture(config, profile, 1, &profileData))
+		assert.True(t, bytes.HasPrefix(profileData.Bytes(), gzipMagic), profile)
+	}
+	assert.Error(t, Capture(config, "threadcreate", 1, ioutil.Discard))
+
+	assert.NoError(t, server.ModuleRequestStop(contracts.StopTypeSoftStop))
+	_, err = os.Stat(config.SocketPath)
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, Capture(config, "heap", 1, ioutil.Discard))
+}
+
+func TestServer_ServesTheProfilesOnThePortWithTheToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiling")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	config := appconfig.ProfilingCfg{Port: port, TokenFile: filepath.Join(dir, "token")}
+
+	// the port is not served without the token
+	server := &Server{context: context.NewMockDefault(), address: listener.Addr().String(), tokenFile: config.TokenFile}
+	assert.Error(t, server.ModuleExecute(server.context))
+
+	assert.NoError(t, ioutil.WriteFile(config.TokenFile, []byte("secret\n"), 0600))
+	assert.NoError(t, server.ModuleExecute(server.context))
+	defer server.ModuleRequestStop(contracts.StopTypeSoftStop)
+
+	var profileData bytes.Buffer
+	assert.NoError(t, Capture(config, "heap", 1, &profileData))
+	assert.True(t, bytes.HasPrefix(profileData.Bytes(), gzipMagic))
+
+	resp, err := http.Get("http://" + listener.Addr().String() + Path + "heap")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestCapture_NotTurnedOn(t *testing.T) {
+	err := Capture(appconfig.ProfilingCfg{}, "heap", 1, ioutil.Discard)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Profiling.Port")
+}
@@ -0,0 +1,500 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// pacScript is a proxy auto-config file. Only the subset of JavaScript commonly used by
+// proxy auto-config files is supported: if/else statements, return statements, the
+// !, &&, ||, == and != operators, string literals and the functions shExpMatch,
+// dnsDomainIs, isPlainHostName, localHostOrDomainIs and isInNet.
+type pacScript struct {
+	urlParam  string
+	hostParam string
+	body      pacStatement
+}
+
+// pacStatement returns the result of a return statement and true when the statement returned
+type pacStatement func(env map[string]pacValue) (string, bool, error)
+
+// pacExpression evaluates to a string or a bool
+type pacExpression func(env map[string]pacValue) (pacValue, error)
+
+type pacValue interface{}
+
+// FindProxyForURL evaluates the FindProxyForURL function of the script
+func (s *pacScript) FindProxyForURL(url string, host string) (string, error) {
+	env := map[string]pacValue{s.urlParam: url, s.hostParam: host}
+	result, returned, err := s.body(env)
+	if err != nil {
+		return "", err
+	}
+	if !returned {
+		return "", fmt.Errorf("FindProxyForURL returned no result")
+	}
+	return result, nil
+}
+
+// parsePac parses the FindProxyForURL function of a proxy auto-config file
+func parsePac(content string) (*pacScript, error) {
+	tokens, err := tokenizePac(content)
+	if err != nil {
+		return nil, err
+	}
+	p := &pacParser{tokens: tokens}
+	script := &pacScript{}
+	if err = p.expect("function", "FindProxyForURL", "("); err != nil {
+		return nil, err
+	}
+	if script.urlParam, err = p.identifier(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(","); err != nil {
+		return nil, err
+	}
+	if script.hostParam, err = p.identifier(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if script.body, err = p.block(); err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unsupported proxy auto-config content after FindProxyForURL: %q", p.peek())
+	}
+	return script, nil
+}
+
+type pacParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *pacParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *pacParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *pacParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *pacParser) accept(token string) bool {
+	if p.peek() == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *pacParser) expect(tokens ...string) error {
+	for _, token := range tokens {
+		if !p.accept(token) {
+			return fmt.Errorf("expected %q in proxy auto-config, found %q", token, p.peek())
+		}
+	}
+	return nil
+}
+
+func (p *pacParser) identifier() (string, error) {
+	token := p.next()
+	if token == "" || !isPacIdentifierStart(rune(token[0])) {
+		return "", fmt.Errorf("expected identifier in proxy auto-config, found %q", token)
+	}
+	return token, nil
+}
+
+func (p *pacParser) block() (pacStatement, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var statements []pacStatement
+	for !p.accept("}") {
+		if p.done() {
+			return nil, fmt.Errorf("unterminated block in proxy auto-config")
+		}
+		statement, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return func(env map[string]pacValue) (string, bool, error) {
+		for _, statement := range statements {
+			if result, returned, err := statement(env); err != nil || returned {
+				return result, returned, err
+			}
+		}
+		return "", false, nil
+	}, nil
+}
+
+func (p *pacParser) statement() (pacStatement, error) {
+	switch {
+	case p.peek() == "{":
+		return p.block()
+	case p.accept(";"):
+		return func(env map[string]pacValue) (string, bool, error) { return "", false, nil }, nil
+	case p.accept("return"):
+		expression, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		p.accept(";")
+		return func(env map[string]pacValue) (string, bool, error) {
+			value, err := expression(env)
+			if err != nil {
+				return "", false, err
+			}
+			result, ok := value.(string)
+			if !ok {
+				return "", false, fmt.Errorf("FindProxyForURL returned %v instead of a string", value)
+			}
+			return result, true, nil
+		}, nil
+	case p.accept("if"):
+		return p.ifStatement()
+	}
+	return nil, fmt.Errorf("unsupported statement in proxy auto-config at %q", p.peek())
+}
+
+func (p *pacParser) ifStatement() (pacStatement, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	then, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	otherwise := func(env map[string]pacValue) (string, bool, error) { return "", false, nil }
+	if p.accept("else") {
+		if otherwise, err = p.statement(); err != nil {
+			return nil, err
+		}
+	}
+	return func(env map[string]pacValue) (string, bool, error) {
+		value, err := condition(env)
+		if err != nil {
+			return "", false, err
+		}
+		if isPacTrue(value) {
+			return then(env)
+		}
+		return otherwise(env)
+	}, nil
+}
+
+func (p *pacParser) expression() (pacExpression, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = pacOr(left, right)
+	}
+	return left, nil
+}
+
+func (p *pacParser) and() (pacExpression, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = pacAnd(left, right)
+	}
+	return left, nil
+}
+
+func (p *pacParser) comparison() (pacExpression, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	switch operator := p.peek(); operator {
+	case "==", "===", "!=", "!==":
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		equal := operator == "==" || operator == "==="
+		return func(env map[string]pacValue) (pacValue, error) {
+			l, err := left(env)
+			if err != nil {
+				return nil, err
+			}
+			r, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return (l == r) == equal, nil
+		}, nil
+	}
+	return left, nil
+}
+
+func (p *pacParser) unary() (pacExpression, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env map[string]pacValue) (pacValue, error) {
+			value, err := operand(env)
+			if err != nil {
+				return nil, err
+			}
+			return !isPacTrue(value), nil
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *pacParser) primary() (pacExpression, error) {
+	token := p.next()
+	switch {
+	case token == "(":
+		expression, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return expression, p.expect(")")
+	case token == "true" || token == "false":
+		value := token == "true"
+		return func(env map[string]pacValue) (pacValue, error) { return value, nil }, nil
+	case strings.HasPrefix(token, "\""):
+		value := token[1:]
+		return func(env map[string]pacValue) (pacValue, error) { return value, nil }, nil
+	case token != "" && isPacIdentifierStart(rune(token[0])):
+		if p.accept("(") {
+			return p.call(token)
+		}
+		return func(env map[string]pacValue) (pacValue, error) {
+			value, ok := env[token]
+			if !ok {
+				return nil, fmt.Errorf("unknown variable %v", token)
+			}
+			return value, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported expression in proxy auto-config at %q", token)
+}
+
+func (p *pacParser) call(name string) (pacExpression, error) {
+	function, ok := pacFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported function %v in proxy auto-config", name)
+	}
+	var args []pacExpression
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return func(env map[string]pacValue) (pacValue, error) {
+		values := make([]string, len(args))
+		for i, arg := range args {
+			value, err := arg(env)
+			if err != nil {
+				return nil, err
+			}
+			str, isString := value.(string)
+			if !isString {
+				return nil, fmt.Errorf("%v expects string arguments", name)
+			}
+			values[i] = str
+		}
+		return function(values)
+	}, nil
+}
+
+func pacOr(left pacExpression, right pacExpression) pacExpression {
+	return func(env map[string]pacValue) (pacValue, error) {
+		value, err := left(env)
+		if err != nil || isPacTrue(value) {
+			return value, err
+		}
+		return right(env)
+	}
+}
+
+func pacAnd(left pacExpression, right pacExpression) pacExpression {
+	return func(env map[string]pacValue) (pacValue, error) {
+		value, err := left(env)
+		if err != nil || !isPacTrue(value) {
+			return value, err
+		}
+		return right(env)
+	}
+}
+
+func isPacTrue(value pacValue) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return false
+}
+
+// pacFunctions are the supported proxy auto-config functions
+var pacFunctions = map[string]func(args []string) (pacValue, error){
+	"shExpMatch": func(args []string) (pacValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("shExpMatch expects 2 arguments")
+		}
+		// path.Match treats / as a separator, shell expressions match it with *
+		pattern := strings.Replace(args[1], "/", "\x00", -1)
+		matched, err := path.Match(pattern, strings.Replace(args[0], "/", "\x00", -1))
+		return matched, err
+	},
+	"dnsDomainIs": func(args []string) (pacValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("dnsDomainIs expects 2 arguments")
+		}
+		return strings.HasSuffix(strings.ToLower(args[0]), strings.ToLower(args[1])), nil
+	},
+	"isPlainHostName": func(args []string) (pacValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("isPlainHostName expects 1 argument")
+		}
+		return !strings.Contains(args[0], "."), nil
+	},
+	"localHostOrDomainIs": func(args []string) (pacValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("localHostOrDomainIs expects 2 arguments")
+		}
+		host := strings.ToLower(args[0])
+		hostDomain := strings.ToLower(args[1])
+		return host == hostDomain || (!strings.Contains(host, ".") && strings.HasPrefix(hostDomain, host+".")), nil
+	},
+	"isInNet": func(args []string) (pacValue, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("isInNet expects 3 arguments")
+		}
+		ip := net.ParseIP(args[0])
+		if ip == nil {
+			ips, err := lookupIP(args[0])
+			if err != nil || len(ips) == 0 {
+				return false, nil
+			}
+			ip = ips[0]
+		}
+		pattern := net.ParseIP(args[1]).To4()
+		mask := net.ParseIP(args[2]).To4()
+		if pattern == nil || mask == nil || ip.To4() == nil {
+			return false, nil
+		}
+		return ip.To4().Mask(net.IPMask(mask)).Equal(pattern.Mask(net.IPMask(mask))), nil
+	},
+}
+
+// lookupIP resolves host names for isInNet
+var lookupIP = net.LookupIP
+
+// tokenizePac splits a proxy auto-config file into tokens, string literals are returned with a leading "
+func tokenizePac(content string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(content); {
+		c := rune(content[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			i += end
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment in proxy auto-config")
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := strings.IndexRune(content[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in proxy auto-config")
+			}
+			tokens = append(tokens, "\""+content[i+1:i+1+end])
+			i += end + 2
+		case isPacIdentifierStart(c):
+			start := i
+			for i < len(content) && (isPacIdentifierStart(rune(content[i])) || unicode.IsDigit(rune(content[i]))) {
+				i++
+			}
+			tokens = append(tokens, content[start:i])
+		default:
+			operator := string(c)
+			for _, op := range []string{"===", "!==", "==", "!=", "&&", "||"} {
+				if strings.HasPrefix(content[i:], op) {
+					operator = op
+					break
+				}
+			}
+			if !strings.Contains("(){};,!", operator) && len(operator) == 1 {
+				return nil, fmt.Errorf("unsupported character %q in proxy auto-config", operator)
+			}
+			tokens = append(tokens, operator)
+			i += len(operator)
+		}
+	}
+	return tokens, nil
+}
+
+func isPacIdentifierStart(c rune) bool {
+	return c == '_' || c == '$' || unicode.IsLetter(c)
+}
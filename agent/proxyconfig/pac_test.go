@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePac_Unsupported(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"function FindProxyForURL(url, host) { var h = host; return \"DIRECT\"; }",
+		"function FindProxyForURL(url, host) { return myProxy(host); }",
+		"function FindProxyForURL(url, host) { return \"DIRECT\"; } function helper() {}",
+		"function FindProxyForURL(url, host) { return \"DIRECT\";",
+	} {
+		_, err := parsePac(content)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestFindProxyForURL_NoResult(t *testing.T) {
+	script, err := parsePac("function FindProxyForURL(u, h) { if (h == \"a\") return \"DIRECT\"; }")
+	assert.NoError(t, err)
+
+	_, err = script.FindProxyForURL("https://b/", "b")
+	assert.Error(t, err)
+}
+
+func TestPacFunctions(t *testing.T) {
+	lookupIPOrig := lookupIP
+	defer func() { lookupIP = lookupIPOrig }()
+	lookupIP = func(host string) ([]net.IP, error) { return []net.IP{net.ParseIP("10.1.2.3")}, nil }
+
+	for _, test := range []struct {
+		call     string
+		expected bool
+	}{
+		{`shExpMatch("s3.amazonaws.com", "*.amazonaws.com")`, true},
+		{`shExpMatch("https://host/a/b", "*/a/*")`, true},
+		{`shExpMatch("host", "h?st")`, true},
+		{`shExpMatch("host", "*.com")`, false},
+		{`dnsDomainIs("www.Example.com", ".example.com")`, true},
+		{`dnsDomainIs("www.example.org", ".example.com")`, false},
+		{`isPlainHostName("intranet")`, true},
+		{`isPlainHostName("intranet.corp")`, false},
+		{`localHostOrDomainIs("www", "www.example.com")`, true},
+		{`localHostOrDomainIs("www.example.com", "www.example.com")`, true},
+		{`localHostOrDomainIs("www.example.org", "www.example.com")`, false},
+		{`isInNet("10.0.0.5", "10.0.0.0", "255.0.0.0")`, true},
+		{`isInNet("192.168.0.5", "10.0.0.0", "255.0.0.0")`, false},
+		{`isInNet("internal.example.com", "10.1.0.0", "255.255.0.0")`, true},
+	} {
+		script, err := parsePac("function FindProxyForURL(url, host) { if (" + test.call + ") return \"DIRECT\"; return \"PROXY p:1\"; }")
+		assert.NoError(t, err, test.call)
+		result, err := script.FindProxyForURL("", "")
+		assert.NoError(t, err, test.call)
+		assert.Equal(t, test.expected, result == "DIRECT", test.call)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/dnscache"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const (
+	// DirectProxy is the proxy url connecting to a service without a proxy
+	DirectProxy = "direct"
+
+	pacDownloadTimeout = 10 * time.Second
+	pacRetryPeriod     = 5 * time.Minute
+)
+
+// ProxyFunc returns the function selecting the proxy of requests to a service.
+// The service configuration takes precedence over the network configuration, and the
+// proxy environment variables are used when neither selects a proxy. The network configuration
+// is read on each request so a reloaded network proxy applies to existing clients.
+func ProxyFunc(log log.T, serviceCfg appconfig.ProxyCfg) func(*http.Request) (*url.URL, error) {
+	selector := &proxySelector{log: log, serviceCfg: serviceCfg}
+	return selector.proxy
+}
+
+// IsConfigured returns true if the proxy configuration replaces the proxy environment variables
+func IsConfigured(cfg appconfig.ProxyCfg) bool {
+	return cfg.Url != "" || cfg.PacUrl != "" || len(cfg.NoProxy) > 0
+}
+
+// ConfigureAwsProxy sends the requests of an aws sdk client through the proxy of its service,
+// the client is left unchanged when no proxy is configured
+func ConfigureAwsProxy(log log.T, config *aws.Config, serviceCfg appconfig.ProxyCfg) {
+	if !IsConfigured(effectiveProxyCfg(serviceCfg)) {
+		return
+	}
+	if config.HTTPClient == nil || config.HTTPClient == http.DefaultClient {
+		config.HTTPClient = &http.Client{Transport: Transport(log, serviceCfg)}
+		return
+	}
+	if tr, ok := config.HTTPClient.Transport.(*http.Transport); ok {
+		tr.Proxy = ProxyFunc(log, serviceCfg)
+	} else if config.HTTPClient.Transport == nil {
+		config.HTTPClient.Transport = Transport(log, serviceCfg)
+	}
+}
+
+// Transport returns a transport sending requests of a service through its proxy
+func Transport(log log.T, serviceCfg appconfig.ProxyCfg) *http.Transport {
+	return &http.Transport{
+		Proxy: ProxyFunc(log, serviceCfg),
+		Dial: dnscache.Dial(&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}),
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     fips.TLSConfig(nil),
+	}
+}
+
+// effectiveProxyCfg returns the service configuration completed by the network configuration
+var effectiveProxyCfg = func(serviceCfg appconfig.ProxyCfg) appconfig.ProxyCfg {
+	var networkCfg appconfig.ProxyCfg
+	if appConfig, err := appconfig.Config(false); err == nil {
+		networkCfg = appConfig.Network.Proxy
+	}
+	return mergeProxyCfg(serviceCfg, networkCfg)
+}
+
+// mergeProxyCfg fills the empty fields of the service configuration from the network configuration
+func mergeProxyCfg(serviceCfg appconfig.ProxyCfg, networkCfg appconfig.ProxyCfg) appconfig.ProxyCfg {
+	if serviceCfg.Url == "" && serviceCfg.PacUrl == "" {
+		serviceCfg.Url = networkCfg.Url
+		serviceCfg.PacUrl = networkCfg.PacUrl
+	}
+	if len(serviceCfg.NoProxy) == 0 {
+		serviceCfg.NoProxy = networkCfg.NoProxy
+	}
+	return serviceCfg
+}
+
+type proxySelector struct {
+	log        log.T
+	serviceCfg appconfig.ProxyCfg
+}
+
+// pacScripts caches the parsed proxy auto-config files by url
+var pacScripts = struct {
+	sync.Mutex
+	scripts map[string]pacCacheEntry
+}{scripts: make(map[string]pacCacheEntry)}
+
+type pacCacheEntry struct {
+	script *pacScript
+	// retryAfter is when a file that could not be read is read again
+	retryAfter time.Time
+}
+
+func (s *proxySelector) proxy(req *http.Request) (*url.URL, error) {
+	cfg := effectiveProxyCfg(s.serviceCfg)
+	if !IsConfigured(cfg) {
+		return http.ProxyFromEnvironment(req)
+	}
+
+	host := req.URL.Hostname()
+	if matchNoProxy(host, cfg.NoProxy) {
+		return nil, nil
+	}
+
+	if cfg.PacUrl != "" {
+		if script := s.loadPac(cfg.PacUrl); script != nil {
+			result, err := script.FindProxyForURL(req.URL.String(), host)
+			if err == nil {
+				return parsePacResult(result)
+			}
+			s.log.Warnf("Failed to evaluate proxy auto-config %v for %v: %v", cfg.PacUrl, host, err)
+		}
+	}
+
+	switch {
+	case strings.EqualFold(cfg.Url, DirectProxy):
+		return nil, nil
+	case cfg.Url != "":
+		return parseProxyURL(cfg.Url)
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// loadPac returns the parsed proxy auto-config file, nil if it cannot be loaded
+func (s *proxySelector) loadPac(pacUrl string) *pacScript {
+	pacScripts.Lock()
+	defer pacScripts.Unlock()
+	if entry, ok := pacScripts.scripts[pacUrl]; ok && (entry.retryAfter.IsZero() || time.Now().Before(entry.retryAfter)) {
+		return entry.script
+	}
+
+	content, err := readPac(pacUrl)
+	if err != nil {
+		s.log.Warnf("Failed to load proxy auto-config %v, the configured proxy url is used instead: %v", pacUrl, err)
+		pacScripts.scripts[pacUrl] = pacCacheEntry{retryAfter: time.Now().Add(pacRetryPeriod)}
+		return nil
+	}
+	script, err := parsePac(string(content))
+	if err != nil {
+		s.log.Warnf("Failed to parse proxy auto-config %v, the configured proxy url is used instead: %v", pacUrl, err)
+	}
+	// unsupported files are cached as nil so they are not parsed again
+	pacScripts.scripts[pacUrl] = pacCacheEntry{script: script}
+	return script
+}
+
+// readPac reads a proxy auto-config file from a file path or http url, downloads never use a proxy
+var readPac = func(pacUrl string) ([]byte, error) {
+	if !strings.HasPrefix(pacUrl, "http://") && !strings.HasPrefix(pacUrl, "https://") {
+		return ioutil.ReadFile(pacUrl)
+	}
+	client := &http.Client{Timeout: pacDownloadTimeout, Transport: &http.Transport{}}
+	resp, err := client.Get(pacUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parsePacResult returns the first proxy of a FindProxyForURL result, nil for DIRECT
+func parsePacResult(result string) (*url.URL, error) {
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY":
+			if len(fields) == 2 {
+				return parseProxyURL("http://" + fields[1])
+			}
+		case "HTTPS":
+			if len(fields) == 2 {
+				return parseProxyURL("https://" + fields[1])
+			}
+		}
+	}
+	return nil, fmt.Errorf("proxy auto-config returned no usable proxy: %v", result)
+}
+
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address %q: %v", proxy, err)
+	}
+	return proxyURL, nil
+}
+
+// matchNoProxy returns true if the host matches an entry of the list, an entry is * to match all hosts,
+// a host name also matching its subdomains, an IP address or a CIDR range
+func matchNoProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		entry = strings.Trim(entry, "[]")
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
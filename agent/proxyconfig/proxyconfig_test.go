@@ -0,0 +1,180 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proxyconfig
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPac = `
+// proxy auto-config used by the tests
+function FindProxyForURL(url, host) {
+	if (isPlainHostName(host) || dnsDomainIs(host, ".corp.example.com"))
+		return "DIRECT";
+	if (shExpMatch(host, "ssmmessages.*.amazonaws.com")) {
+		return "DIRECT";
+	} else if (host == "s3.amazonaws.com" && !shExpMatch(url, "*/public/*")) {
+		return "HTTPS s3proxy:3129; DIRECT";
+	}
+	return 'PROXY proxy.example.com:3128';
+}
+`
+
+func newRequest(t *testing.T, url string) *http.Request {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	return req
+}
+
+func setNetworkProxy(networkCfg appconfig.ProxyCfg) func() {
+	effectiveProxyCfgOrig := effectiveProxyCfg
+	effectiveProxyCfg = func(serviceCfg appconfig.ProxyCfg) appconfig.ProxyCfg {
+		return mergeProxyCfg(serviceCfg, networkCfg)
+	}
+	return func() { effectiveProxyCfg = effectiveProxyCfgOrig }
+}
+
+func setPac(content string, err error) func() {
+	readPacOrig := readPac
+	readPac = func(pacUrl string) ([]byte, error) { return []byte(content), err }
+	pacScripts.scripts = make(map[string]pacCacheEntry)
+	return func() { readPac = readPacOrig }
+}
+
+func TestProxyFunc_ServiceProxy(t *testing.T) {
+	defer setNetworkProxy(appconfig.ProxyCfg{Url: "http://network-proxy:3128"})()
+	proxy := ProxyFunc(log.NewMockLog(), appconfig.ProxyCfg{Url: "service-proxy:8080", NoProxy: []string{".internal"}})
+
+	proxyURL, err := proxy(newRequest(t, "https://ssm.us-east-1.amazonaws.com/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://service-proxy:8080", proxyURL.String())
+
+	proxyURL, err = proxy(newRequest(t, "https://ssm.vpce.internal/"))
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+}
+
+func TestProxyFunc_NetworkProxy(t *testing.T) {
+	defer setNetworkProxy(appconfig.ProxyCfg{Url: "http://network-proxy:3128", NoProxy: []string{"10.0.0.0/8"}})()
+
+	proxyURL, err := ProxyFunc(log.NewMockLog(), appconfig.ProxyCfg{})(newRequest(t, "https://s3.amazonaws.com/bucket"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://network-proxy:3128", proxyURL.String())
+
+	proxyURL, err = ProxyFunc(log.NewMockLog(), appconfig.ProxyCfg{})(newRequest(t, "https://10.1.2.3/bucket"))
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+
+	proxyURL, err = ProxyFunc(log.NewMockLog(), appconfig.ProxyCfg{Url: DirectProxy})(newRequest(t, "wss://ssmmessages.us-east-1.amazonaws.com/v1"))
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+}
+
+func TestProxyFunc_Pac(t *testing.T) {
+	defer setNetworkProxy(appconfig.ProxyCfg{})()
+	defer setPac(testPac, nil)()
+	proxy := ProxyFunc(log.NewMockLog(), appconfig.ProxyCfg{PacUrl: "/etc/proxy.pac"})
+
+	proxyURL, err := proxy(newRequest(t, "wss://ssmmessages.us-east-1.amazonaws.com/v1/data-channel"))
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+
+	proxyURL, err = proxy(newRequest(t, "https://s3.amazonaws.com/bucket/key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://s3proxy:3129", proxyURL.String())
+
+	proxyURL, err = proxy(newRequest(t, "https://s3.amazonaws.com/public/key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxyURL.String())
+
+	proxyURL, err = proxy(newRequest(t, "https://build.corp.example.com/"))
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+}
+
+func TestProxyFunc_PacFailureUsesProxyUrl(t *testing.T) {
+	defer setNetworkProxy(appconfig.ProxyCfg{})()
+	defer setPac("", errors.New("connection refused"))()
+	mockLog := log.NewMockLog()
+	proxy := ProxyFunc(mockLog, appconfig.ProxyCfg{Url: "http://fallback:3128", PacUrl: "http://wpad/proxy.pac"})
+
+	proxyURL, err := proxy(newRequest(t, "https://ssm.us-east-1.amazonaws.com/"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://fallback:3128", proxyURL.String())
+	mockLog.AssertCalled(t, "Warnf", "Failed to load proxy auto-config %v, the configured proxy url is used instead: %v", []interface{}{"http://wpad/proxy.pac", errors.New("connection refused")})
+}
+
+func TestProxyFunc_NetworkProxyChanged(t *testing.T) {
+	restore := setNetworkProxy(appconfig.ProxyCfg{})
+	proxy := ProxyFunc(log.NewMockLog(), appconfig.ProxyCfg{})
+	restore()
+
+	defer setNetworkProxy(appconfig.ProxyCfg{Url: "http://reloaded-proxy:3128"})()
+	proxyURL, err := proxy(newRequest(t, "https://ssm.us-east-1.amazonaws.com/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://reloaded-proxy:3128", proxyURL.String())
+}
+
+func TestConfigureAwsProxy(t *testing.T) {
+	defer setNetworkProxy(appconfig.ProxyCfg{})()
+
+	config := &aws.Config{}
+	ConfigureAwsProxy(log.NewMockLog(), config, appconfig.ProxyCfg{})
+	assert.Nil(t, config.HTTPClient)
+
+	ConfigureAwsProxy(log.NewMockLog(), config, appconfig.ProxyCfg{Url: "http://proxy:3128"})
+	assert.NotNil(t, config.HTTPClient.Transport.(*http.Transport).Proxy)
+
+	tr := &http.Transport{}
+	config = &aws.Config{HTTPClient: &http.Client{Transport: tr}}
+	ConfigureAwsProxy(log.NewMockLog(), config, appconfig.ProxyCfg{Url: "http://proxy:3128"})
+	assert.Equal(t, tr, config.HTTPClient.Transport)
+	assert.NotNil(t, tr.Proxy)
+}
+
+func TestMatchNoProxy(t *testing.T) {
+	noProxy := []string{"169.254.169.254", ".amazonaws.com", "example.org:443", "fd00::/8", "[2001:db8::1]"}
+
+	assert.True(t, matchNoProxy("169.254.169.254", noProxy))
+	assert.True(t, matchNoProxy("ssm.us-east-1.amazonaws.com", noProxy))
+	assert.True(t, matchNoProxy("amazonaws.com", noProxy))
+	assert.True(t, matchNoProxy("www.example.org", noProxy))
+	assert.True(t, matchNoProxy("fd00:ec2::254", noProxy))
+	assert.True(t, matchNoProxy("2001:db8::1", noProxy))
+	assert.False(t, matchNoProxy("notamazonaws.com", noProxy))
+	assert.False(t, matchNoProxy("10.0.0.1", noProxy))
+	assert.True(t, matchNoProxy("anything", []string{"*"}))
+}
+
+func TestParsePacResult(t *testing.T) {
+	proxyURL, err := parsePacResult("PROXY a:1; PROXY b:2")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://a:1", proxyURL.String())
+
+	proxyURL, err = parsePacResult("SOCKS s:1080; DIRECT")
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+
+	_, err = parsePacResult("SOCKS s:1080")
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package resourcelimits caps the CPU and memory of the agent, with cgroups on Linux and a job object on Windows,
+// and reports when it approaches its limits so that the non-urgent work is deferred. The workers and the documents
+// they run are not held to the limits of the agent.
+package resourcelimits
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const name = "ResourceLimits"
+
+// DeferInterval is the time the non-urgent work is deferred by while the agent is approaching its limits
+var DeferInterval = 5 * time.Minute
+
+// sampleInterval is the interval at which the usage of the agent is compared to its limits
+var sampleInterval = 10 * time.Second
+
+// apply limits the current process, it is stubbed in the tests
+var apply = applyLimits
+
+// release moves a process started by the agent out of its limits, it is stubbed in the tests
+var release = releaseProcess
+
+// sample returns the memory used by the limited processes and whether their CPU was throttled since the last sample
+var sample = sampleUsage
+
+// applied records the limits applied ahead of the core module, see Apply
+var applied struct {
+	sync.Mutex
+	done bool
+	err  error
+}
+
+// pressure records whether the agent is approaching its limits
+var pressure struct {
+	sync.RWMutex
+	under bool
+}
+
+// UnderPressure returns true when the agent is approaching its limits and the non-urgent work should be deferred
+func UnderPressure() bool {
+	pressure.RLock()
+	defer pressure.RUnlock()
+	return pressure.under
+}
+
+// Apply limits the agent as configured in appconfig ahead of the core module. The agent running unprivileged applies
+// its limits before it drops its privileges, only root can move it to its cgroup, and gives the user it runs as
+// afterwards, uid and gid, the cgroup its workers are released to.
+func Apply(context context.T, uid int, gid int) {
+	config := context.AppConfig().ResourceLimits
+	if config.CPUPercent == 0 && config.MemoryMB == 0 {
+		return
+	}
+	err := apply(config.CPUPercent, uint64(config.MemoryMB)*1024*1024)
+	if err == nil {
+		err = delegateRelease(uid, gid)
+	}
+	applied.Lock()
+	defer applied.Unlock()
+	applied.done, applied.err = true, err
+}
+
+// Release moves a worker started by the agent out of the limits of the agent, before it starts running documents
+func Release(pid int) error {
+	return release(pid)
+}
+
+func setUnderPressure(under bool) {
+	pressure.Lock()
+	defer pressure.Unlock()
+	pressure.under = under
+}
+
+// ResourceLimits is the core module limiting the agent and monitoring its usage
+type ResourceLimits struct {
+	context         context.T
+	memoryLimit     uint64
+	sheddingPercent int
+	stop            chan bool
+	done            chan bool
+}
+
+// NewResourceLimits limits the CPU and memory of the agent, it returns nil when no limit is configured in appconfig
+// or when the limits could not be applied
+func NewResourceLimits(context context.T) *ResourceLimits {
+	config := context.AppConfig().ResourceLimits
+	if config.CPUPercent == 0 && config.MemoryMB == 0 {
+		return nil
+	}
+	log := context.Log()
+	memoryLimit := uint64(config.MemoryMB) * 1024 * 1024
+	applied.Lock()
+	err := applied.err
+	if !applied.done {
+		err = apply(config.CPUPercent, memoryLimit)
+	}
+	applied.Unlock()
+	if err != nil {
+		log.Errorf("Failed to limit the resources of the agent: %v", err)
+		return nil
+	}
+	log.Infof("Limited the agent to %v%% of a CPU and %v MB of memory, 0 being unlimited", config.CPUPercent, config.MemoryMB)
+	return &ResourceLimits{
+		context:         context.With("[" + name + "]"),
+		memoryLimit:     memoryLimit,
+		sheddingPercent: config.LoadSheddingPercent,
+		stop:            make(chan bool),
+		done:            make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (r *ResourceLimits) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts monitoring the usage of the agent
+func (r *ResourceLimits) ModuleExecute(context context.T) (err error) {
+	go r.monitor()
+	return nil
+}
+
+// ModuleRequestStop stops monitoring the usage of the agent, the limits stay applied until the agent exits
+func (r *ResourceLimits) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	close(r.stop)
+	<-r.done
+	setUnderPressure(false)
+	return nil
+}
+
+func (r *ResourceLimits) monitor() {
+	defer close(r.done)
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+// check samples the usage of the agent and updates the pressure, the transitions are logged
+func (r *ResourceLimits) check() {
+	log := r.context.Log()
+	memoryUsage, throttled, err := sample()
+	if err != nil {
+		log.Debugf("Failed to sample the resource usage of the agent: %v", err)
+		return
+	}
+	memoryPressure := r.memoryLimit > 0 && memoryUsage*100 >= r.memoryLimit*uint64(r.sheddingPercent)
+	under := memoryPressure || throttled
+	if under == UnderPressure() {
+		return
+	}
+	if under {
+		log.Warnf("The agent is approaching its limits (memory %v of %v bytes, CPU throttled: %v), deferring the non-urgent work",
+			memoryUsage, r.memoryLimit, throttled)
+	} else {
+		log.Infof("The agent is back under its limits, resuming the non-urgent work")
+	}
+	setUnderPressure(under)
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package resourcelimits
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// cgroupName is the name of the cgroup created for the agent when it runs in the root cgroup
+	cgroupName = "amazon-ssm-agent"
+
+	// agentCgroupName and workersCgroupName are the leaves of the unified hierarchy the agent and its workers are moved to,
+	// a cgroup which enables controllers for its children can't hold processes itself
+	agentCgroupName   = "agent"
+	workersCgroupName = "workers"
+
+	// cpuPeriodMicroseconds is the period the CPU quota of the cgroup applies to
+	cpuPeriodMicroseconds = 100000
+)
+
+// cgroupRoot is the mount point of the cgroup hierarchies, it is stubbed in the tests
+var cgroupRoot = "/sys/fs/cgroup"
+
+// procSelfCgroup lists the cgroups of the agent, it is stubbed in the tests
+var procSelfCgroup = "/proc/self/cgroup"
+
+// lastThrottled is the number of throttled periods of the cgroup at the last sample
+var lastThrottled uint64
+
+// limitedCgroup holds the files of the cgroup limiting the agent and the cgroups its workers are released to
+var limitedCgroup struct {
+	memoryFile   string
+	cpuStatFile  string
+	releaseProcs []string
+	// moveProcs are the procs files the agent writes to when it releases a worker, see delegateRelease
+	moveProcs []string
+}
+
+// isCgroupV2 returns true when the unified cgroup hierarchy is mounted
+func isCgroupV2() bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// ownCgroup returns the path of the cgroup of the agent in the hierarchy of controller, the unified hierarchy when empty
+func ownCgroup(controller string) (string, error) {
+	content, err := ioutil.ReadFile(procSelfCgroup)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if controller == "" && fields[0] == "0" && fields[1] == "" {
+			return fields[2], nil
+		}
+		for _, name := range strings.Split(fields[1], ",") {
+			if controller != "" && name == controller {
+				return fields[2], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("the cgroup of the agent for controller %q is not listed in %v", controller, procSelfCgroup)
+}
+
+// applyLimits places the agent in a cgroup with the cpu and memory limits, created under its own cgroup which the
+// service manager delegates to it. The workers are released from it once started, see releaseProcess.
+func applyLimits(cpuPercent int, memoryBytes uint64) error {
+	pid := strconv.Itoa(os.Getpid())
+	if isCgroupV2() {
+		own, err := ownCgroup("")
+		if err != nil {
+			return err
+		}
+		if path.Base(own) == agentCgroupName {
+			// the limits are applied again
+			own = path.Dir(own)
+		}
+		base := filepath.Join(cgroupRoot, own)
+		if own == "/" {
+			// the processes of the root cgroup aren't moved, the agent gets a subtree of its own
+			base = filepath.Join(cgroupRoot, cgroupName)
+		}
+		agentDir := filepath.Join(base, agentCgroupName)
+		workersDir := filepath.Join(base, workersCgroupName)
+		for _, dir := range []string{agentDir, workersDir} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		// the workers left over by a previous agent share its cgroup, they move to the unlimited leaf
+		if err := writeCgroupFile(filepath.Join(agentDir, "cgroup.procs"), pid); err != nil {
+			return err
+		}
+		if own != "/" {
+			if err := moveProcesses(filepath.Join(base, "cgroup.procs"), filepath.Join(workersDir, "cgroup.procs")); err != nil {
+				return err
+			}
+		}
+		if err := writeCgroupFile(filepath.Join(base, "cgroup.subtree_control"), "+cpu +memory"); err != nil {
+			return err
+		}
+		cpuMax := fmt.Sprintf("max %v", cpuPeriodMicroseconds)
+		if cpuPercent > 0 {
+			cpuMax = fmt.Sprintf("%v %v", cpuPercent*cpuPeriodMicroseconds/100, cpuPeriodMicroseconds)
+		}
+		memoryMax := "max"
+		if memoryBytes > 0 {
+			memoryMax = strconv.FormatUint(memoryBytes, 10)
+		}
+		for file, value := range map[string]string{"cpu.max": cpuMax, "memory.max": memoryMax} {
+			if err := writeCgroupFile(filepath.Join(agentDir, file), value); err != nil {
+				return err
+			}
+		}
+		limitedCgroup.memoryFile = filepath.Join(agentDir, "memory.current")
+		limitedCgroup.cpuStatFile = filepath.Join(agentDir, "cpu.stat")
+		limitedCgroup.releaseProcs = []string{filepath.Join(workersDir, "cgroup.procs")}
+		// moving a process requires the right to write to the procs of the common parent of the cgroups
+		limitedCgroup.moveProcs = []string{filepath.Join(base, "cgroup.procs"), filepath.Join(workersDir, "cgroup.procs")}
+		return nil
+	}
+
+	var cpuBase, memoryBase string
+	for _, hierarchy := range []struct {
+		controller string
+		base       *string
+	}{{"cpu", &cpuBase}, {"memory", &memoryBase}} {
+		own, err := ownCgroup(hierarchy.controller)
+		if err != nil {
+			return err
+		}
+		*hierarchy.base = filepath.Join(cgroupRoot, hierarchy.controller, own)
+	}
+	cpuDir := filepath.Join(cpuBase, cgroupName)
+	memoryDir := filepath.Join(memoryBase, cgroupName)
+	// the children of a cgroup are held to its limits, the workers are released to a sibling of the limited cgroup
+	cpuWorkersDir := filepath.Join(cpuBase, cgroupName+"-"+workersCgroupName)
+	memoryWorkersDir := filepath.Join(memoryBase, cgroupName+"-"+workersCgroupName)
+	for _, dir := range []string{cpuDir, memoryDir, cpuWorkersDir, memoryWorkersDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	cpuQuota := "-1"
+	if cpuPercent > 0 {
+		cpuQuota = strconv.Itoa(cpuPercent * cpuPeriodMicroseconds / 100)
+	}
+	memoryLimit := "-1"
+	if memoryBytes > 0 {
+		memoryLimit = strconv.FormatUint(memoryBytes, 10)
+	}
+	for _, setting := range []struct{ file, value string }{
+		{filepath.Join(cpuDir, "cpu.cfs_period_us"), strconv.Itoa(cpuPeriodMicroseconds)},
+		{filepath.Join(cpuDir, "cpu.cfs_quota_us"), cpuQuota},
+		{filepath.Join(cpuDir, "cgroup.procs"), pid},
+		{filepath.Join(memoryDir, "memory.limit_in_bytes"), memoryLimit},
+		{filepath.Join(memoryDir, "cgroup.procs"), pid},
+	} {
+		if err := writeCgroupFile(setting.file, setting.value); err != nil {
+			return err
+		}
+	}
+	limitedCgroup.memoryFile = filepath.Join(memoryDir, "memory.usage_in_bytes")
+	limitedCgroup.cpuStatFile = filepath.Join(cpuDir, "cpu.stat")
+	limitedCgroup.releaseProcs = []string{filepath.Join(cpuWorkersDir, "cgroup.procs"), filepath.Join(memoryWorkersDir, "cgroup.procs")}
+	limitedCgroup.moveProcs = limitedCgroup.releaseProcs
+	return nil
+}
+
+// moveProcesses writes the processes listed in the procs file of a cgroup to the procs file of another one
+func moveProcesses(fromProcs string, toProcs string) error {
+	content, err := ioutil.ReadFile(fromProcs)
+	if err != nil {
+		return err
+	}
+	for _, pid := range strings.Fields(string(content)) {
+		if err := writeCgroupFile(toProcs, pid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delegateRelease gives the user the procs files the agent writes to when it releases a worker, the agent can then
+// release its workers once it dropped its privileges. The user could move the agent out of its limits as well,
+// the limits contain the agent, they don't confine it.
+func delegateRelease(uid int, gid int) error {
+	for _, procs := range limitedCgroup.moveProcs {
+		if err := os.Chown(procs, uid, gid); err != nil {
+			return fmt.Errorf("failed to give %v to the user %v: %v", procs, uid, err)
+		}
+	}
+	return nil
+}
+
+// releaseProcess moves a process started by the agent out of the cgroup limiting the agent
+func releaseProcess(pid int) error {
+	for _, procs := range limitedCgroup.releaseProcs {
+		if err := writeCgroupFile(procs, strconv.Itoa(pid)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleUsage reads the memory usage and the throttled CPU periods of the cgroup of the agent
+func sampleUsage() (memoryBytes uint64, throttled bool, err error) {
+	if limitedCgroup.memoryFile == "" {
+		return 0, false, fmt.Errorf("the agent is not limited")
+	}
+	content, err := ioutil.ReadFile(limitedCgroup.memoryFile)
+	if err != nil {
+		return 0, false, err
+	}
+	if memoryBytes, err = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64); err != nil {
+		return 0, false, err
+	}
+
+	nrThrottled, err := readCPUStat(limitedCgroup.cpuStatFile, "nr_throttled")
+	if err != nil {
+		return 0, false, err
+	}
+	throttled = nrThrottled > lastThrottled
+	lastThrottled = nrThrottled
+	return memoryBytes, throttled, nil
+}
+
+// readCPUStat returns the value of a field of a cpu.stat file
+func readCPUStat(path string, field string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == field {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%v not found in %v", field, path)
+}
+
+func writeCgroupFile(path string, value string) error {
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %v to %v: %v", value, path, err)
+	}
+	return nil
+}
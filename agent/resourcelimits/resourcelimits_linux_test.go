@@ -0,0 +1,176 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package resourcelimits
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stubCgroupRoot(t *testing.T, files map[string]string) func() {
+	dir, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	cgroupRoot = dir
+	procSelfCgroup = filepath.Join(dir, "self.cgroup")
+	lastThrottled = 0
+	limitedCgroup.memoryFile, limitedCgroup.cpuStatFile, limitedCgroup.releaseProcs, limitedCgroup.moveProcs = "", "", nil, nil
+	return func() { os.RemoveAll(dir) }
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(filepath.Join(cgroupRoot, path))
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestApplyLimits_CgroupV2(t *testing.T) {
+	defer stubCgroupRoot(t, map[string]string{
+		"self.cgroup":        "0::/system.slice/amazon-ssm-agent.service\n",
+		"cgroup.controllers": "cpu memory",
+		"system.slice/amazon-ssm-agent.service/cgroup.procs": "4242\n",
+	})()
+
+	assert.NoError(t, applyLimits(50, 256*1024*1024))
+	assert.Equal(t, "+cpu +memory", readFile(t, "system.slice/amazon-ssm-agent.service/cgroup.subtree_control"))
+	assert.Equal(t, "50000 100000", readFile(t, "system.slice/amazon-ssm-agent.service/agent/cpu.max"))
+	assert.Equal(t, "268435456", readFile(t, "system.slice/amazon-ssm-agent.service/agent/memory.max"))
+	assert.Equal(t, strconv.Itoa(os.Getpid()), readFile(t, "system.slice/amazon-ssm-agent.service/agent/cgroup.procs"))
+	// the workers left over by a previous agent aren't limited
+	assert.Equal(t, "4242", readFile(t, "system.slice/amazon-ssm-agent.service/workers/cgroup.procs"))
+
+	assert.NoError(t, Release(4343))
+	assert.Equal(t, "4343", readFile(t, "system.slice/amazon-ssm-agent.service/workers/cgroup.procs"))
+
+	// the agent is already in its limited cgroup when the limits are applied again
+	assert.NoError(t, ioutil.WriteFile(procSelfCgroup, []byte("0::/system.slice/amazon-ssm-agent.service/agent\n"), 0644))
+	assert.NoError(t, applyLimits(0, 256*1024*1024))
+	assert.Equal(t, "max 100000", readFile(t, "system.slice/amazon-ssm-agent.service/agent/cpu.max"))
+}
+
+func TestApplyLimits_CgroupV2Root(t *testing.T) {
+	defer stubCgroupRoot(t, map[string]string{
+		"self.cgroup":        "0::/\n",
+		"cgroup.controllers": "cpu memory",
+	})()
+
+	assert.NoError(t, applyLimits(50, 0))
+	assert.Equal(t, "+cpu +memory", readFile(t, "amazon-ssm-agent/cgroup.subtree_control"))
+	assert.Equal(t, "max", readFile(t, "amazon-ssm-agent/agent/memory.max"))
+	assert.Equal(t, strconv.Itoa(os.Getpid()), readFile(t, "amazon-ssm-agent/agent/cgroup.procs"))
+
+	assert.NoError(t, Release(4343))
+	assert.Equal(t, "4343", readFile(t, "amazon-ssm-agent/workers/cgroup.procs"))
+}
+
+func TestApplyLimits_CgroupV1(t *testing.T) {
+	defer stubCgroupRoot(t, map[string]string{
+		"self.cgroup": "5:memory:/system.slice\n4:cpu,cpuacct:/system.slice\n1:name=systemd:/system.slice/amazon-ssm-agent.service\n",
+	})()
+
+	assert.NoError(t, applyLimits(200, 0))
+	assert.Equal(t, "100000", readFile(t, "cpu/system.slice/amazon-ssm-agent/cpu.cfs_period_us"))
+	assert.Equal(t, "200000", readFile(t, "cpu/system.slice/amazon-ssm-agent/cpu.cfs_quota_us"))
+	assert.Equal(t, "-1", readFile(t, "memory/system.slice/amazon-ssm-agent/memory.limit_in_bytes"))
+	assert.Equal(t, strconv.Itoa(os.Getpid()), readFile(t, "memory/system.slice/amazon-ssm-agent/cgroup.procs"))
+
+	// the workers are released to siblings of the limited cgroups
+	assert.NoError(t, Release(4343))
+	assert.Equal(t, "4343", readFile(t, "cpu/system.slice/amazon-ssm-agent-workers/cgroup.procs"))
+	assert.Equal(t, "4343", readFile(t, "memory/system.slice/amazon-ssm-agent-workers/cgroup.procs"))
+}
+
+func TestDelegateRelease(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("giving the cgroups away requires root")
+	}
+	defer stubCgroupRoot(t, map[string]string{
+		"self.cgroup":        "0::/system.slice/amazon-ssm-agent.service\n",
+		"cgroup.controllers": "cpu memory",
+		"system.slice/amazon-ssm-agent.service/cgroup.procs":         "",
+		"system.slice/amazon-ssm-agent.service/workers/cgroup.procs": "",
+	})()
+	assert.NoError(t, applyLimits(50, 0))
+
+	assert.NoError(t, delegateRelease(1234, 1234))
+	for path, uid := range map[string]uint32{
+		"system.slice/amazon-ssm-agent.service/cgroup.procs":         1234,
+		"system.slice/amazon-ssm-agent.service/workers/cgroup.procs": 1234,
+		"system.slice/amazon-ssm-agent.service/agent/cgroup.procs":   0,
+		"system.slice/amazon-ssm-agent.service/agent/memory.max":     0,
+	} {
+		info, err := os.Stat(filepath.Join(cgroupRoot, path))
+		if assert.NoError(t, err) {
+			assert.Equal(t, uid, info.Sys().(*syscall.Stat_t).Uid, path)
+		}
+	}
+}
+
+func TestApplyLimits_NoOwnCgroup(t *testing.T) {
+	defer stubCgroupRoot(t, map[string]string{"self.cgroup": "1:name=systemd:/\n"})()
+
+	assert.Error(t, applyLimits(50, 0))
+}
+
+func TestSampleUsage_CgroupV2(t *testing.T) {
+	defer stubCgroupRoot(t, map[string]string{
+		"self.cgroup":        "0::/\n",
+		"cgroup.controllers": "cpu memory",
+	})()
+	assert.NoError(t, applyLimits(50, 0))
+	assert.NoError(t, ioutil.WriteFile(limitedCgroup.memoryFile, []byte("1048576\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(limitedCgroup.cpuStatFile, []byte("usage_usec 100\nnr_periods 10\nnr_throttled 2\nthrottled_usec 5\n"), 0644))
+
+	memory, throttled, err := sampleUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1048576), memory)
+	assert.True(t, throttled)
+
+	// the cpu is only reported as throttled when it was throttled since the last sample
+	_, throttled, err = sampleUsage()
+	assert.NoError(t, err)
+	assert.False(t, throttled)
+}
+
+func TestSampleUsage_CgroupV1(t *testing.T) {
+	defer stubCgroupRoot(t, map[string]string{"self.cgroup": "5:memory:/\n4:cpu,cpuacct:/\n"})()
+	assert.NoError(t, applyLimits(50, 0))
+	assert.NoError(t, ioutil.WriteFile(limitedCgroup.memoryFile, []byte("2048"), 0644))
+	assert.NoError(t, ioutil.WriteFile(limitedCgroup.cpuStatFile, []byte("nr_periods 10\nnr_throttled 0\nthrottled_time 0\n"), 0644))
+
+	memory, throttled, err := sampleUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2048), memory)
+	assert.False(t, throttled)
+}
+
+func TestSampleUsage_NotLimited(t *testing.T) {
+	defer stubCgroupRoot(t, nil)()
+
+	_, _, err := sampleUsage()
+	assert.Error(t, err)
+}
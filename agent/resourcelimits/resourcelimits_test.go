@@ -0,0 +1,124 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package resourcelimits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithLimits(cpuPercent int, memoryMB int) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.ResourceLimits.CPUPercent = cpuPercent
+	config.ResourceLimits.MemoryMB = memoryMB
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func stubApply(err error) *[]interface{} {
+	var calls []interface{}
+	apply = func(cpuPercent int, memoryBytes uint64) error {
+		calls = append(calls, cpuPercent, memoryBytes)
+		return err
+	}
+	applied.done, applied.err = false, nil
+	return &calls
+}
+
+func TestNewResourceLimits_NotConfigured(t *testing.T) {
+	applied := stubApply(nil)
+	assert.Nil(t, NewResourceLimits(mockContextWithLimits(0, 0)))
+	assert.Empty(t, *applied)
+}
+
+func TestNewResourceLimits_ApplyFails(t *testing.T) {
+	stubApply(errors.New("permission denied"))
+	assert.Nil(t, NewResourceLimits(mockContextWithLimits(50, 0)))
+}
+
+func TestNewResourceLimits_AppliesLimits(t *testing.T) {
+	applied := stubApply(nil)
+	limits := NewResourceLimits(mockContextWithLimits(50, 256))
+	assert.NotNil(t, limits)
+	assert.Equal(t, name, limits.ModuleName())
+	assert.Equal(t, []interface{}{50, uint64(256 * 1024 * 1024)}, *applied)
+}
+
+func TestApply_AheadOfTheModule(t *testing.T) {
+	calls := stubApply(nil)
+	ctx := mockContextWithLimits(50, 256)
+	Apply(ctx, 1234, 1234)
+	assert.NotNil(t, NewResourceLimits(ctx))
+	// the limits are applied once, before the agent drops its privileges
+	assert.Equal(t, []interface{}{50, uint64(256 * 1024 * 1024)}, *calls)
+
+	calls = stubApply(errors.New("permission denied"))
+	Apply(ctx, 1234, 1234)
+	assert.Nil(t, NewResourceLimits(ctx))
+	assert.Len(t, *calls, 2)
+}
+
+func TestApply_NotConfigured(t *testing.T) {
+	calls := stubApply(nil)
+	Apply(mockContextWithLimits(0, 0), 1234, 1234)
+	assert.Empty(t, *calls)
+	assert.False(t, applied.done)
+}
+
+func TestResourceLimits_Check(t *testing.T) {
+	stubApply(nil)
+	limits := NewResourceLimits(mockContextWithLimits(0, 100))
+	defer setUnderPressure(false)
+
+	for _, testCase := range []struct {
+		memoryMB  uint64
+		throttled bool
+		err       error
+		expected  bool
+	}{
+		{memoryMB: 50, expected: false},
+		{memoryMB: 80, expected: true},
+		{memoryMB: 50, err: errors.New("no cgroup"), expected: true},
+		{memoryMB: 79, expected: false},
+		{memoryMB: 10, throttled: true, expected: true},
+		{memoryMB: 10, expected: false},
+	} {
+		sample = func() (uint64, bool, error) {
+			return testCase.memoryMB * 1024 * 1024, testCase.throttled, testCase.err
+		}
+		limits.check()
+		assert.Equal(t, testCase.expected, UnderPressure(), "%+v", testCase)
+	}
+}
+
+func TestResourceLimits_StopResetsPressure(t *testing.T) {
+	stubApply(nil)
+	ctx := mockContextWithLimits(0, 100)
+	limits := NewResourceLimits(ctx)
+	assert.NoError(t, limits.ModuleExecute(ctx))
+	setUnderPressure(true)
+	assert.NoError(t, limits.ModuleRequestStop(contracts.StopTypeSoftStop))
+	assert.False(t, UnderPressure())
+}
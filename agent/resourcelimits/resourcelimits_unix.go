@@ -0,0 +1,42 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || netbsd || openbsd
+// +build darwin freebsd netbsd openbsd
+
+package resourcelimits
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// applyLimits fails, the resource limits are only supported on Linux and Windows
+func applyLimits(cpuPercent int, memoryBytes uint64) error {
+	return fmt.Errorf("resource limits are not supported on %v", runtime.GOOS)
+}
+
+// delegateRelease has nothing to do, the limits are never applied
+func delegateRelease(uid int, gid int) error {
+	return nil
+}
+
+// releaseProcess has nothing to do, the limits are never applied
+func releaseProcess(pid int) error {
+	return nil
+}
+
+func sampleUsage() (memoryBytes uint64, throttled bool, err error) {
+	return 0, false, fmt.Errorf("resource limits are not supported on %v", runtime.GOOS)
+}
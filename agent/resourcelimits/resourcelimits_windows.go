@@ -0,0 +1,137 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package resourcelimits
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/jobobject"
+)
+
+const (
+	jobObjectCpuRateControlInformation = 15
+	jobObjectCpuRateControlEnable      = 0x1
+	jobObjectCpuRateControlHardCap     = 0x4
+	jobObjectLimitJobMemory            = 0x200
+	jobObjectLimitSilentBreakawayOk    = 0x1000
+)
+
+var getProcessMemoryInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// jobObjectCpuRateControl is the JOBOBJECT_CPU_RATE_CONTROL_INFORMATION structure
+type jobObjectCpuRateControl struct {
+	ControlFlags uint32
+	CpuRate      uint32
+}
+
+// processMemoryCounters is the PROCESS_MEMORY_COUNTERS structure
+type processMemoryCounters struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// applyLimits assigns the agent to a job object with the cpu rate and memory limits
+func applyLimits(cpuPercent int, memoryBytes uint64) error {
+	r1, _, e1 := jobobject.CreateJobObjectW.Call(0, 0)
+	if r1 == 0 {
+		return e1
+	}
+	job := syscall.Handle(r1)
+
+	// the processes started by the agent break away from the job, the workers aren't held to the limits of the agent
+	var limit jobobject.JobObjectExtendedLimit
+	limit.BasicLimitInformation.LimitFlags = jobObjectLimitSilentBreakawayOk
+	if memoryBytes > 0 {
+		limit.BasicLimitInformation.LimitFlags |= jobObjectLimitJobMemory
+		limit.JobMemoryLimit = uintptr(memoryBytes)
+	}
+	if err := setInformation(job, jobobject.JobObjectExtendedLimitInformation, unsafe.Pointer(&limit), unsafe.Sizeof(limit)); err != nil {
+		syscall.CloseHandle(job)
+		return err
+	}
+	if cpuPercent > 0 {
+		// the cpu rate is the share of all the processors in hundredths of a percent
+		rate := cpuPercent * 100 / runtime.NumCPU()
+		if rate < 1 {
+			rate = 1
+		} else if rate > 10000 {
+			rate = 10000
+		}
+		control := jobObjectCpuRateControl{
+			ControlFlags: jobObjectCpuRateControlEnable | jobObjectCpuRateControlHardCap,
+			CpuRate:      uint32(rate),
+		}
+		if err := setInformation(job, jobObjectCpuRateControlInformation, unsafe.Pointer(&control), unsafe.Sizeof(control)); err != nil {
+			syscall.CloseHandle(job)
+			return err
+		}
+	}
+
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		syscall.CloseHandle(job)
+		return err
+	}
+	// the job object stays open until the agent exits
+	if r1, _, e1 = jobobject.AssignProcessToJobObject.Call(uintptr(job), uintptr(process)); r1 == 0 {
+		syscall.CloseHandle(job)
+		return e1
+	}
+	return nil
+}
+
+// delegateRelease has nothing to do, the agent doesn't drop its privileges on Windows
+func delegateRelease(uid int, gid int) error {
+	return nil
+}
+
+// releaseProcess has nothing to do, the processes started by the agent break away from its job
+func releaseProcess(pid int) error {
+	return nil
+}
+
+// sampleUsage reads the committed memory of the agent process, the cpu rate is enforced without being reported
+func sampleUsage() (memoryBytes uint64, throttled bool, err error) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, false, err
+	}
+	var counters processMemoryCounters
+	counters.Cb = uint32(unsafe.Sizeof(counters))
+	if r1, _, e1 := getProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(counters.Cb)); r1 == 0 {
+		return 0, false, e1
+	}
+	return uint64(counters.PagefileUsage), false, nil
+}
+
+func setInformation(job syscall.Handle, infoClass uint32, info unsafe.Pointer, infoLen uintptr) error {
+	if r1, _, e1 := jobobject.SetInformationJobObject.Call(uintptr(job), uintptr(infoClass), uintptr(info), infoLen); r1 == 0 {
+		return e1
+	}
+	return nil
+}
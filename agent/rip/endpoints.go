@@ -0,0 +1,47 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rip
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+)
+
+// ServiceEndpoint is the endpoint the agent uses to reach a service
+type ServiceEndpoint struct {
+	Service    string
+	Endpoint   string
+	Overridden bool
+}
+
+// GetServiceEndpoints returns the endpoints the agent uses in a region, overridden endpoints come from appconfig
+func GetServiceEndpoints(region string) []ServiceEndpoint {
+	appConfig, _ := appconfig.Config(false)
+	return []ServiceEndpoint{
+		serviceEndpoint(region, "ssm", appConfig.Ssm.Endpoint),
+		serviceEndpoint(region, "ec2messages", appConfig.Mds.Endpoint),
+		{Service: MgsServiceName, Endpoint: GetMgsEndpoint(region), Overridden: appConfig.Mgs.Endpoint != ""},
+		{Service: "s3", Endpoint: s3util.GetS3Endpoint(region), Overridden: appConfig.S3.Endpoint != ""},
+		serviceEndpoint(region, "kms", appConfig.Kms.Endpoint),
+		serviceEndpoint(region, "logs", appConfig.Logs.Endpoint),
+	}
+}
+
+func serviceEndpoint(region string, service string, override string) ServiceEndpoint {
+	if override != "" {
+		return ServiceEndpoint{Service: service, Endpoint: override, Overridden: true}
+	}
+	return ServiceEndpoint{Service: service, Endpoint: GetDefaultServiceEndpoint(region, service)}
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetServiceEndpoints(t *testing.T) {
+	endpoints := GetServiceEndpoints("us-west-2")
+
+	services := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		services[i] = endpoint.Service
+		assert.False(t, endpoint.Overridden)
+		assert.NotEmpty(t, endpoint.Endpoint)
+	}
+	assert.Equal(t, []string{"ssm", "ec2messages", "ssmmessages", "s3", "kms", "logs"}, services)
+	assert.Equal(t, "ssm.us-west-2.amazonaws.com", endpoints[0].Endpoint)
+	assert.Equal(t, "ssmmessages.us-west-2.amazonaws.com", endpoints[2].Endpoint)
+}
+
+func TestServiceEndpoint(t *testing.T) {
+	assert.Equal(t,
+		ServiceEndpoint{Service: "kms", Endpoint: "kms.cn-north-1.amazonaws.com.cn"},
+		serviceEndpoint("cn-north-1", "kms", ""))
+	assert.Equal(t,
+		ServiceEndpoint{Service: "logs", Endpoint: "https://vpce-1.logs.us-east-1.vpce.amazonaws.com", Overridden: true},
+		serviceEndpoint("us-east-1", "logs", "https://vpce-1.logs.us-east-1.vpce.amazonaws.com"))
+}
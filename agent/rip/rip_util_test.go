@@ -35,3 +35,11 @@ func TestGetMgsEndpointForCnRegion(t *testing.T) {
 
 	assert.Equal(t, expected, endpoint)
 }
+
+func TestParseMgsEndpoint(t *testing.T) {
+	assert.Equal(t, "ssmmessages.us-east-1.amazonaws.com", parseMgsEndpoint("ssmmessages.us-east-1.amazonaws.com"))
+	assert.Equal(t, "ssmmessages.us-east-1.amazonaws.com", parseMgsEndpoint("https://ssmmessages.us-east-1.amazonaws.com"))
+	assert.Equal(t, "[2001:db8::1]", parseMgsEndpoint("[2001:db8::1]"))
+	assert.Equal(t, "[2001:db8::1]:8443", parseMgsEndpoint("[2001:db8::1]:8443"))
+	assert.Equal(t, "[2001:db8::1]:443", parseMgsEndpoint("https://[2001:db8::1]:443"))
+}
@@ -25,6 +25,8 @@ import (
 
 const (
 	MgsServiceName = "ssmmessages"
+
+	mgsEndpointScheme = "https://"
 )
 
 // TODO: remove rip-gen from s3util and use this shared file.
@@ -51,25 +53,35 @@ var awsMessageGatewayServiceEndpointMap = map[string]string{
 	//AUTOGEN_END_MessageGatewayService
 }
 
-/* This function returns the mgs endpoint specified by the user in appconfig.
+/*
+	This function returns the mgs endpoint specified by the user in appconfig.
+
 If the user didn't specify one, it will return the Amazon MGS endpoint in a certain region
 */
 func GetMgsEndpoint(region string) (mgsEndpoint string) {
 	if appConfig, err := appconfig.Config(false); err == nil {
-		if appConfig.Mgs.Endpoint != "" {
-			// use net/url package to parse endpoint, if endpoint doesn't contain protocol,
-			// fullUrl.Host is empty, should return fullUrl.Path. For backwards compatible, return the non-empty one.
-			fullUrl, err := url.Parse(appConfig.Mgs.Endpoint)
-			if err != nil {
-				return ""
+		// the endpoint override of the primary region does not apply to the failover regions
+		for _, target := range appConfig.Failover.Targets {
+			if target.Region != region || region == appConfig.Mgs.Region {
+				continue
+			}
+			if target.MgsEndpoint != "" {
+				return parseMgsEndpoint(target.MgsEndpoint)
 			}
-			if fullUrl.Host != "" {
-				return fullUrl.Host
+			if mgsEndpoint, ok := awsMessageGatewayServiceEndpointMap[region]; ok && !appconfig.UseDualStackEndpoints() {
+				return mgsEndpoint
 			}
-			return fullUrl.Path
+			return GetDefaultServiceEndpoint(region, MgsServiceName)
+		}
+		if appConfig.Mgs.Endpoint != "" {
+			return parseMgsEndpoint(appConfig.Mgs.Endpoint)
 		}
 	}
 
+	if appconfig.UseDualStackEndpoints() {
+		return GetDefaultServiceEndpoint(region, MgsServiceName)
+	}
+
 	if mgsEndpoint, ok := awsMessageGatewayServiceEndpointMap[region]; ok {
 		return mgsEndpoint
 	}
@@ -81,13 +93,30 @@ func GetMgsEndpoint(region string) (mgsEndpoint string) {
 	return mgsEndpoint
 }
 
+// parseMgsEndpoint returns the host name of an mgs endpoint.
+func parseMgsEndpoint(endpoint string) string {
+	// use net/url package to parse endpoint, if endpoint doesn't contain protocol,
+	// fullUrl.Host is empty, should return fullUrl.Path. For backwards compatible, return the non-empty one.
+	fullUrl, err := url.Parse(endpoint)
+	if err != nil {
+		// IPv6 literal addresses like [2001:db8::1]:443 can only be parsed with a protocol
+		if fullUrl, err = url.Parse(mgsEndpointScheme + endpoint); err != nil {
+			return ""
+		}
+	}
+	if fullUrl.Host != "" {
+		return fullUrl.Host
+	}
+	return fullUrl.Path
+}
+
 // GetDefaultServiceEndpoint returns the default endpoint for a service, it should not be empty.
 func GetDefaultServiceEndpoint(region string, service string) (endpoint string) {
 	defaultEndpoint := appconfig.GetDefaultEndPoint(region, service)
 	if defaultEndpoint != "" {
 		endpoint = defaultEndpoint
 	} else {
-		endpoint = service + "." + region + ".amazonaws.com"
+		endpoint = appconfig.GetRegionalEndpoint(region, service)
 	}
 	return endpoint
 }
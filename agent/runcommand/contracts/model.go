@@ -47,7 +47,17 @@ type SendReplyPayload struct {
 	RuntimeStatus       map[string]*contracts.PluginRuntimeStatus `json:"runtimeStatus"`
 }
 
-//getCommandID gets CommandID from given MessageID
+// ReplyContentEncodingGzip is the content encoding of a reply payload compressed with gzip.
+const ReplyContentEncodingGzip = "gzip"
+
+// CompressedReplyPayload represents the json structure of a compressed reply sent to MDS,
+// Content is the base64 encoded SendReplyPayload compressed with ContentEncoding.
+type CompressedReplyPayload struct {
+	ContentEncoding string `json:"contentEncoding"`
+	Content         string `json:"content"`
+}
+
+// getCommandID gets CommandID from given MessageID
 func getCommandID(messageID string) string {
 	// MdsMessageID is in the format of : aws.ssm.CommandId.InstanceId
 	// E.g (aws.ssm.2b196342-d7d4-436e-8f09-3883a1116ac3.i-57c0a7be)
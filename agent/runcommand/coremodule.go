@@ -21,14 +21,18 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 	"github.com/aws/aws-sdk-go/service/ssmmds"
 	"github.com/carlescere/scheduler"
 )
@@ -65,7 +69,11 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 		return
 	}
 
-	go s.listenReply(resultChan)
+	s.repliesDone = make(chan bool)
+	go func() {
+		defer close(s.repliesDone)
+		s.listenReply(resultChan)
+	}()
 
 	if err = s.processor.InitialProcessing(); err != nil {
 		log.Errorf("initial processing in EngineProcessor encountered error: %v", err)
@@ -75,6 +83,8 @@ func (s *RunCommandService) ModuleExecute(context context.T) (err error) {
 	log.Info("Starting message polling")
 	if s.messagePollJob, err = scheduler.Every(pollMessageFrequencyMinutes).Minutes().Run(s.messagePollLoop); err != nil {
 		context.Log().Errorf("unable to schedule message poll job. %v", err)
+	} else {
+		watchdog.Register(s.messagePollLoopName(), s.restartMessagePoll)
 	}
 
 	log.Info("Starting send replies to MDS")
@@ -95,6 +105,12 @@ func (s *RunCommandService) ModuleRequestStop(stopType contracts.StopType) (err
 	//second stop the message processor
 	s.processor.Stop(stopType)
 
+	//when draining, send the results of the stopped documents and the replies which failed to reach the service
+	if stopType == contracts.StopTypeSoftStop && s.repliesDone != nil {
+		<-s.repliesDone
+		s.sendFailedReplies()
+	}
+
 	//TODO move this out once we have association moved to a different core module
 	if s.assocProcessor != nil {
 		s.assocProcessor.ModuleRequestStop(stopType)
@@ -109,10 +125,16 @@ func (s *RunCommandService) listenReply(resultChan chan contracts.DocumentResult
 		//cloudwatch and refresh association needs to trigger the in-memory component, adding filter here
 		s.handleSpecialPlugin(res.LastPlugin, res.PluginResults, res.MessageID)
 
+		span := tracing.Tracked(res.MessageID)
 		if res.LastPlugin != "" {
 			log.Infof("received plugin: %v result from Processor", res.LastPlugin)
+			tracePluginResult(span, res.PluginResults[res.LastPlugin])
 		} else {
 			log.Infof("command: %v complete", res.MessageID)
+			metrics.DocumentExecutions.Inc(string(res.Status))
+			auditDocumentResult(res)
+			tracing.Untrack(res.MessageID)
+			span.SetAttribute("ssm.document_status", string(res.Status))
 			//Deleting Old Log Files after the execution is over and files have been moved to completed folder
 			//clean completed document state files and orchestration dirs. Takes care of only files generated by RunCommand in the folder
 			instanceID, _ := platform.InstanceID()
@@ -122,11 +144,50 @@ func (s *RunCommandService) listenReply(resultChan chan contracts.DocumentResult
 				s.context.AppConfig().Ssm.RunCommandLogsRetentionDurationHours,
 				s.context.AppConfig().Ssm.AssociationLogsRetentionDurationHours)
 		}
+		uploadSpan := span.StartChild("document.upload")
 		s.sendResponse(res.MessageID, res)
+		uploadSpan.End(nil)
+		if res.LastPlugin == "" {
+			span.End(tracing.StatusError(res.Status, ""))
+		}
+	}
+}
+
+// tracePluginResult records the execution of a plugin as a child span of the document span
+func tracePluginResult(span *tracing.Span, pluginRes *contracts.PluginResult) {
+	if pluginRes == nil {
+		return
 	}
+	pluginSpan := span.StartChildAt("plugin "+pluginRes.PluginName, pluginRes.StartDateTime)
+	pluginSpan.SetAttribute("ssm.plugin_id", pluginRes.PluginID)
+	pluginSpan.SetAttribute("ssm.plugin_status", string(pluginRes.Status))
+	pluginSpan.EndAt(pluginRes.EndDateTime, tracing.StatusError(pluginRes.Status, pluginRes.Error))
 }
 
-//temporary solution on plugins with shared responsibility with agent
+// auditDocumentResult records the completion of the document and the agent updates it performed in the audit log
+func auditDocumentResult(res contracts.DocumentResult) {
+	commandID, _ := messageContracts.GetCommandID(res.MessageID)
+	for _, pluginRes := range res.PluginResults {
+		if pluginRes.PluginName == appconfig.PluginNameAwsAgentUpdate {
+			audit.Record(audit.Event{
+				Type:      audit.AgentUpdate,
+				MessageID: res.MessageID,
+				CommandID: commandID,
+				Status:    string(pluginRes.Status),
+				Detail:    pluginRes.Error,
+			})
+		}
+	}
+	audit.Record(audit.Event{
+		Type:         audit.DocumentExecutionCompleted,
+		MessageID:    res.MessageID,
+		CommandID:    commandID,
+		DocumentName: res.DocumentName,
+		Status:       string(res.Status),
+	})
+}
+
+// temporary solution on plugins with shared responsibility with agent
 func (s *RunCommandService) handleSpecialPlugin(lastPluginID string, pluginRes map[string]*contracts.PluginResult, messageID string) {
 	var newRes contracts.PluginResult
 
@@ -157,15 +218,28 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	log := context.Log()
 	log.Debug("Processing message")
 
+	span := tracing.StartSpan("document")
+	span.SetAttribute("ssm.message_id", *msg.MessageId)
+	span.SetAttribute("ssm.service", s.name)
+
+	receiveSpan := span.StartChild("document.receive")
 	if err = validate(msg); err != nil {
 		log.Error("message not valid, ignoring: ", err)
+		receiveSpan.End(err)
+		span.End(err)
 		return
 	}
+	receiveSpan.End(nil)
+	metrics.MessagesReceived.Inc(s.name)
+	span.SetAttribute("ssm.topic", *msg.Topic)
 
+	parseSpan := span.StartChild("document.parse")
 	if strings.HasPrefix(*msg.Topic, string(SendCommandTopicPrefix)) {
 		docState, err = loadDocStateFromSendCommand(context, msg, s.orchestrationRootDir)
 		if err != nil {
 			log.Error(err)
+			parseSpan.End(err)
+			span.End(err)
 			s.sendDocLevelResponse(*msg.MessageId, contracts.ResultStatusFailed, err.Error())
 			return
 		}
@@ -174,18 +248,27 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	} else {
 		err = fmt.Errorf("unexpected topic name %v", *msg.Topic)
 	}
+	parseSpan.End(err)
 
 	if err != nil {
 		log.Error("format of received message is invalid ", err)
+		span.End(err)
 		if err = s.service.FailMessage(log, *msg.MessageId, mdsService.InternalHandlerException); err != nil {
 			sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
 		}
 		return
 	}
+	span.SetAttribute("ssm.command_id", docState.DocumentInformation.CommandID)
+	span.SetAttribute("ssm.document_name", docState.DocumentInformation.DocumentName)
+
+	ackSpan := span.StartChild("document.acknowledge")
 	if err = s.service.AcknowledgeMessage(log, *msg.MessageId); err != nil {
+		ackSpan.End(err)
+		span.End(err)
 		sdkutil.HandleAwsError(log, err, s.processorStopPolicy)
 		return
 	}
+	ackSpan.End(nil)
 
 	log.Debugf("Ack done. Received message - messageId - %v", *msg.MessageId)
 
@@ -197,12 +280,27 @@ func (s *RunCommandService) processMessage(msg *ssmmds.Message) {
 	log.Debugf("SendReply done. Received message - messageId - %v", *msg.MessageId)
 	switch docState.DocumentType {
 	case contracts.SendCommand, contracts.SendCommandOffline:
+		audit.Record(audit.Event{
+			Type:         audit.DocumentExecutionRequested,
+			MessageID:    *msg.MessageId,
+			CommandID:    docState.DocumentInformation.CommandID,
+			DocumentName: docState.DocumentInformation.DocumentName,
+		})
+		// the document span ends once the processor replies the document result
+		tracing.Track(*msg.MessageId, span)
 		s.processor.Submit(*docState)
 	case contracts.CancelCommand, contracts.CancelCommandOffline:
+		audit.Record(audit.Event{
+			Type:      audit.DocumentExecutionCanceled,
+			MessageID: *msg.MessageId,
+			CommandID: docState.CancelInformation.CancelCommandID,
+		})
 		s.processor.Cancel(*docState)
+		span.End(nil)
 
 	default:
 		log.Error("unexpected document type ", docState.DocumentType)
+		span.End(fmt.Errorf("unexpected document type %v", docState.DocumentType))
 	}
 
 }
@@ -19,7 +19,7 @@ import (
 
 var systemInfo system = &systemImp{}
 
-//Represents dependency for platform
+// Represents dependency for platform
 type system interface {
 	InstanceID() (string, error)
 }
@@ -0,0 +1,148 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/failover"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+)
+
+// messageRegionRetention is how long the region a message was received from is remembered for its replies
+const messageRegionRetention = 48 * time.Hour
+
+// failoverService polls MDS in the active region of the tracker, the replies to a message
+// are sent to the region it was received from
+type failoverService struct {
+	tracker  *failover.Tracker
+	services []Service
+	now      func() time.Time
+
+	lock     sync.Mutex
+	messages map[string]messageRegion
+}
+
+type messageRegion struct {
+	index    int
+	received time.Time
+}
+
+// NewFailoverService creates a service failing over between the services of the tracker regions,
+// services are in the order of the tracker regions
+func NewFailoverService(tracker *failover.Tracker, services []Service) Service {
+	return &failoverService{
+		tracker:  tracker,
+		services: services,
+		now:      time.Now,
+		messages: make(map[string]messageRegion),
+	}
+}
+
+// GetMessages polls MDS in the active region
+func (f *failoverService) GetMessages(log log.T, instanceID string) (messages *ssmmds.GetMessagesOutput, err error) {
+	index := f.tracker.Index(log)
+	if messages, err = f.services[index].GetMessages(log, instanceID); err != nil {
+		f.tracker.ReportFailure(log)
+		return
+	}
+	f.tracker.ReportSuccess()
+	if messages == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	now := f.now()
+	for messageID, message := range f.messages {
+		if now.Sub(message.received) > messageRegionRetention {
+			delete(f.messages, messageID)
+		}
+	}
+	for _, message := range messages.Messages {
+		f.messages[aws.StringValue(message.MessageId)] = messageRegion{index: index, received: now}
+	}
+	return
+}
+
+// serviceFor returns the service of the region a message was received from, the active one if it is unknown
+func (f *failoverService) serviceFor(log log.T, messageID string) Service {
+	f.lock.Lock()
+	message, ok := f.messages[messageID]
+	f.lock.Unlock()
+	if ok {
+		return f.services[message.index]
+	}
+	return f.services[f.tracker.Index(log)]
+}
+
+// AcknowledgeMessage acknowledges a message in the region it was received from
+func (f *failoverService) AcknowledgeMessage(log log.T, messageID string) error {
+	return f.serviceFor(log, messageID).AcknowledgeMessage(log, messageID)
+}
+
+// SendReply sends a reply to the region the message was received from
+func (f *failoverService) SendReply(log log.T, messageID string, payload string) error {
+	return f.serviceFor(log, messageID).SendReply(log, messageID, payload)
+}
+
+// SendReplyWithInput sends a reply to the region the message was received from
+func (f *failoverService) SendReplyWithInput(log log.T, sendReply *ssmmds.SendReplyInput) error {
+	return f.serviceFor(log, aws.StringValue(sendReply.MessageId)).SendReplyWithInput(log, sendReply)
+}
+
+// FailMessage fails a message in the region it was received from
+func (f *failoverService) FailMessage(log log.T, messageID string, failureType FailureType) error {
+	return f.serviceFor(log, messageID).FailMessage(log, messageID, failureType)
+}
+
+// DeleteMessage deletes a message in the region it was received from
+func (f *failoverService) DeleteMessage(log log.T, messageID string) error {
+	err := f.serviceFor(log, messageID).DeleteMessage(log, messageID)
+	f.lock.Lock()
+	delete(f.messages, messageID)
+	f.lock.Unlock()
+	return err
+}
+
+// LoadFailedReplies loads the replies persisted on disk, they are shared by all regions
+func (f *failoverService) LoadFailedReplies(log log.T) []string {
+	return f.services[0].LoadFailedReplies(log)
+}
+
+// DeleteFailedReply deletes a reply persisted on disk
+func (f *failoverService) DeleteFailedReply(log log.T, replyId string) {
+	f.services[0].DeleteFailedReply(log, replyId)
+}
+
+// PersistFailedReply persists a reply on disk
+func (f *failoverService) PersistFailedReply(log log.T, sendReply ssmmds.SendReplyInput) error {
+	return f.services[0].PersistFailedReply(log, sendReply)
+}
+
+// GetFailedReply reads a reply persisted on disk
+func (f *failoverService) GetFailedReply(log log.T, replyId string) (*ssmmds.SendReplyInput, error) {
+	return f.services[0].GetFailedReply(log, replyId)
+}
+
+// Stop stops the services of all regions
+func (f *failoverService) Stop() {
+	for _, service := range f.services {
+		service.Stop()
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/failover"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/stretchr/testify/assert"
+)
+
+// regionServiceStub records the calls made to the service of a region
+type regionServiceStub struct {
+	Service
+	messages *ssmmds.GetMessagesOutput
+	err      error
+	acked    []string
+	replied  []string
+	deleted  []string
+}
+
+func (s *regionServiceStub) GetMessages(log log.T, instanceID string) (*ssmmds.GetMessagesOutput, error) {
+	return s.messages, s.err
+}
+
+func (s *regionServiceStub) AcknowledgeMessage(log log.T, messageID string) error {
+	s.acked = append(s.acked, messageID)
+	return nil
+}
+
+func (s *regionServiceStub) SendReply(log log.T, messageID string, payload string) error {
+	s.replied = append(s.replied, messageID)
+	return nil
+}
+
+func (s *regionServiceStub) DeleteMessage(log log.T, messageID string) error {
+	s.deleted = append(s.deleted, messageID)
+	return nil
+}
+
+func messagesOutput(messageIDs ...string) *ssmmds.GetMessagesOutput {
+	output := &ssmmds.GetMessagesOutput{}
+	for _, messageID := range messageIDs {
+		output.Messages = append(output.Messages, &ssmmds.Message{MessageId: aws.String(messageID)})
+	}
+	return output
+}
+
+func TestFailoverService_RepliesToReceivingRegion(t *testing.T) {
+	primary := &regionServiceStub{messages: messagesOutput("primary-message")}
+	secondary := &regionServiceStub{messages: messagesOutput("secondary-message")}
+	tracker := failover.NewTracker("test", "us-east-1", appconfig.FailoverCfg{
+		Targets:          []appconfig.FailoverTarget{{Region: "us-west-2"}},
+		FailureThreshold: 1,
+		FailbackMinutes:  30,
+	})
+	service := NewFailoverService(tracker, []Service{primary, secondary})
+
+	messages, err := service.GetMessages(logger, "i-bar")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages.Messages))
+
+	primary.err = errors.New("unreachable")
+	_, err = service.GetMessages(logger, "i-bar")
+	assert.NotNil(t, err)
+	assert.Equal(t, "us-west-2", tracker.Region(logger))
+
+	messages, err = service.GetMessages(logger, "i-bar")
+	assert.Nil(t, err)
+	assert.Equal(t, "secondary-message", *messages.Messages[0].MessageId)
+
+	service.AcknowledgeMessage(logger, "primary-message")
+	service.SendReply(logger, "primary-message", "payload")
+	service.AcknowledgeMessage(logger, "secondary-message")
+	service.SendReply(logger, "secondary-message", "payload")
+	service.DeleteMessage(logger, "primary-message")
+
+	assert.Equal(t, []string{"primary-message"}, primary.acked)
+	assert.Equal(t, []string{"primary-message"}, primary.replied)
+	assert.Equal(t, []string{"primary-message"}, primary.deleted)
+	assert.Equal(t, []string{"secondary-message"}, secondary.acked)
+	assert.Equal(t, []string{"secondary-message"}, secondary.replied)
+
+	// unknown messages go to the active region
+	service.SendReply(logger, "primary-message", "payload")
+	assert.Equal(t, []string{"secondary-message", "primary-message"}, secondary.replied)
+}
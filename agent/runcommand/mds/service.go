@@ -25,11 +25,17 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/dnscache"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -95,7 +101,11 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 	if endpoint != "" {
 		config.Endpoint = &endpoint
 	} else {
-		if region, err := platform.Region(); err == nil {
+		var err error
+		if region == "" {
+			region, err = platform.Region()
+		}
+		if err == nil {
 			if defaultEndpoint := appconfig.GetDefaultEndPoint(region, "ec2messages"); defaultEndpoint != "" {
 				config.Endpoint = &defaultEndpoint
 			}
@@ -106,20 +116,25 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 		config.Credentials = creds
 	}
 
+	appConfig, _ := appconfig.Config(false)
+
 	// capture Transport so we can use it to cancel requests
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
+		Proxy: proxyconfig.ProxyFunc(log.DefaultLogger(), appConfig.Mds.Proxy),
+		Dial: dnscache.Dial(&net.Dialer{
 			Timeout:   connectionTimeout,
 			KeepAlive: 0,
-		}).Dial,
+		}),
 		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     fips.TLSConfig(nil),
 	}
 	config.HTTPClient = &http.Client{Transport: tr, Timeout: connectionTimeout}
 
-	appConfig, _ := appconfig.Config(false)
 	sess := session.New(config)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
 
 	msgSvc := ssmmds.New(sess)
 
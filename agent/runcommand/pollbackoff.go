@@ -0,0 +1,66 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// pollBackoff computes the delay between message polls, the delay doubles every time a poll
+// returns no message, up to a maximum, and goes back to the minimum as soon as work is flowing.
+type pollBackoff struct {
+	lock    sync.Mutex
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+// newPollBackoff creates a backoff between min and max
+func newPollBackoff(min time.Duration, max time.Duration) *pollBackoff {
+	if max < min {
+		max = min
+	}
+	return &pollBackoff{
+		min:     min,
+		max:     max,
+		current: min,
+	}
+}
+
+// Reset goes back to the minimum delay
+func (b *pollBackoff) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.current = b.min
+}
+
+// Next returns the delay before the next poll, with a jitter of up to half of it
+// so that idle instances don't poll in lockstep, and doubles the delay for the poll after.
+func (b *pollBackoff) Next() time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delay := b.current
+	if b.current *= 2; b.current > b.max {
+		b.current = b.max
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollBackoffDoublesUpToMax(t *testing.T) {
+	backoff := newPollBackoff(time.Second, 5*time.Second)
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		delay := backoff.Next()
+		assert.True(t, delay >= expected/2 && delay <= expected, "delay %v not within the jitter of %v", delay, expected)
+	}
+
+	backoff.Reset()
+	assert.True(t, backoff.Next() <= time.Second)
+}
+
+func TestWaitBeforeNextPollWakesOnHint(t *testing.T) {
+	hints := make(chan bool, 1)
+	pollHintsOrig := pollHints
+	t.Cleanup(func() { pollHints = pollHintsOrig })
+	pollHints = func() <-chan bool { return hints }
+
+	svc := RunCommandService{pollBackoff: newPollBackoff(time.Minute, time.Hour)}
+	hints <- true
+
+	start := time.Now()
+	svc.waitBeforeNextPoll(log.NewMockLog(), 0)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, time.Minute, svc.pollBackoff.current)
+}
+
+func TestWaitBeforeNextPollWithMessages(t *testing.T) {
+	svc := RunCommandService{pollBackoff: newPollBackoff(time.Minute, time.Hour)}
+	svc.pollBackoff.current = time.Hour
+
+	start := time.Now()
+	svc.waitBeforeNextPoll(log.NewMockLog(), 3)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, time.Minute, svc.pollBackoff.current)
+}
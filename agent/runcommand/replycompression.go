@@ -0,0 +1,59 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+)
+
+// compressReplyPayload replaces a reply payload of at least minBytes with a gzip compressed envelope,
+// the payload is returned unchanged when it is smaller or the compression doesn't reduce its size.
+func compressReplyPayload(log log.T, payload string, minBytes int) string {
+	if len(payload) < minBytes {
+		return payload
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(payload)); err != nil {
+		log.Warnf("failed to compress reply payload, sending it uncompressed: %v", err)
+		return payload
+	}
+	if err := writer.Close(); err != nil {
+		log.Warnf("failed to compress reply payload, sending it uncompressed: %v", err)
+		return payload
+	}
+
+	compressed, err := jsonutil.Marshal(messageContracts.CompressedReplyPayload{
+		ContentEncoding: messageContracts.ReplyContentEncodingGzip,
+		Content:         base64.StdEncoding.EncodeToString(buf.Bytes()),
+	})
+	if err != nil {
+		log.Warnf("failed to marshal compressed reply payload, sending it uncompressed: %v", err)
+		return payload
+	}
+	if len(compressed) >= len(payload) {
+		return payload
+	}
+	log.Debugf("compressed reply payload from %v to %v bytes", len(payload), len(compressed))
+	return compressed
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runcommand implements runcommand core processing module
+package runcommand
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressReplyPayloadBelowThreshold(t *testing.T) {
+	payload := `{"documentStatus":"Success"}`
+	assert.Equal(t, payload, compressReplyPayload(log.NewMockLog(), payload, 1024))
+}
+
+func TestCompressReplyPayloadRoundTrip(t *testing.T) {
+	payload := `{"runtimeStatus":{"output":"` + strings.Repeat("line of output\n", 1000) + `"}}`
+
+	compressed := compressReplyPayload(log.NewMockLog(), payload, 1024)
+	assert.True(t, len(compressed) < len(payload))
+
+	var envelope messageContracts.CompressedReplyPayload
+	assert.NoError(t, jsonutil.Unmarshal(compressed, &envelope))
+	assert.Equal(t, messageContracts.ReplyContentEncodingGzip, envelope.ContentEncoding)
+
+	content, err := base64.StdEncoding.DecodeString(envelope.Content)
+	assert.NoError(t, err)
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	assert.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, string(decompressed))
+}
+
+func TestCompressReplyPayloadKeepsIncompressiblePayload(t *testing.T) {
+	// base64 of random looking bytes grows once compressed and encoded again
+	random := make([]byte, 2048)
+	rand.New(rand.NewSource(1)).Read(random)
+	payload := base64.StdEncoding.EncodeToString(random)
+	assert.Equal(t, payload, compressReplyPayload(log.NewMockLog(), payload, 1024))
+}
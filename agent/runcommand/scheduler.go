@@ -21,7 +21,9 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/pollhint"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/watchdog"
 	"github.com/carlescere/scheduler"
 )
 
@@ -62,21 +64,24 @@ func (s *RunCommandService) messagePollLoop() {
 	// this is extra insurance to prevent any race condition
 	pollStartTime := time.Now()
 	updateLastPollTime(s.name, pollStartTime)
+	watchdog.Heartbeat(s.messagePollLoopName())
 
 	log := s.context.Log()
 	if err := s.checkStopPolicy(log); err != nil {
 		return
 	}
 
-	s.pollOnce()
+	received := s.pollOnce()
 	if s.name == mdsName {
 		log.Debugf("%v's stoppolicy after polling is %v", s.name, s.processorStopPolicy)
 	}
 
-	// Slow down a bit in case GetMessages returns
-	// without blocking, which may cause us to
-	// flood the service with requests.
-	if time.Since(pollStartTime) < 1*time.Second {
+	if s.pollBackoff != nil {
+		s.waitBeforeNextPoll(log, received)
+	} else if time.Since(pollStartTime) < 1*time.Second {
+		// Slow down a bit in case GetMessages returns
+		// without blocking, which may cause us to
+		// flood the service with requests.
 		time.Sleep(time.Duration(2000+rand.Intn(500)) * time.Millisecond)
 	}
 
@@ -88,6 +93,28 @@ func (s *RunCommandService) messagePollLoop() {
 	}
 }
 
+// waitBeforeNextPoll polls again right away while messages are received, otherwise it backs off
+// until the next poll or until a hint that new messages are waiting is received.
+func (s *RunCommandService) waitBeforeNextPoll(log log.T, received int) {
+	if received > 0 {
+		s.pollBackoff.Reset()
+		return
+	}
+
+	delay := s.pollBackoff.Next()
+	log.Debugf("No message received, waiting %v before polling again", delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-pollHints():
+		log.Debug("Received a hint that new messages are waiting, polling now")
+		s.pollBackoff.Reset()
+	}
+}
+
+var pollHints = pollhint.Hints
+
 func (s *RunCommandService) checkStopPolicy(log log.T) error {
 	if s.processorStopPolicy != nil {
 		if s.name == mdsName {
@@ -110,6 +137,17 @@ var scheduleNextRun = func(j *scheduler.Job) {
 	j.SkipWait <- true
 }
 
+// messagePollLoopName is the name the message poll loop of the processor heartbeats to the watchdog with
+func (s *RunCommandService) messagePollLoopName() string {
+	return s.name + "MessagePoll"
+}
+
+// restartMessagePoll starts a new message poll loop, the stalled loop does not schedule the next run when it resumes
+func (s *RunCommandService) restartMessagePoll() {
+	s.context.Log().Warnf("Restarting the message poll loop of %v", s.name)
+	scheduleNextRun(s.messagePollJob)
+}
+
 func (s *RunCommandService) reset() {
 	log := s.context.Log()
 	log.Debugf("Resetting processor:%v", s.name)
@@ -130,6 +168,7 @@ func (s *RunCommandService) stop() {
 	log.Debugf("Stopping processor:%v", s.name)
 	s.service.Stop()
 
+	watchdog.Unregister(s.messagePollLoopName())
 	if s.messagePollJob != nil {
 		s.messagePollJob.Quit <- true
 	}
@@ -138,8 +177,8 @@ func (s *RunCommandService) stop() {
 	}
 }
 
-// pollOnce calls GetMessages once and processes the result.
-func (s *RunCommandService) pollOnce() {
+// pollOnce calls GetMessages once, processes the result and returns the number of messages received.
+func (s *RunCommandService) pollOnce() (received int) {
 	log := s.context.Log()
 	if s.name == mdsName {
 		log.Debugf("Polling for messages")
@@ -159,4 +198,5 @@ func (s *RunCommandService) pollOnce() {
 	if s.name == mdsName {
 		log.Debugf("Done poll once")
 	}
+	return len(messages.Messages)
 }
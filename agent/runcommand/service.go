@@ -23,10 +23,12 @@ import (
 	associationProcessor "github.com/aws/amazon-ssm-agent/agent/association/processor"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/failover"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	mdsService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
@@ -71,6 +73,9 @@ const (
 
 	// CloudWatch output's log group name prefix
 	CloudWatchLogGroupNamePrefix = "/aws/ssm/"
+
+	// replyUploadDestination is the destination label of the reply upload failure metric
+	replyUploadDestination = "mds"
 )
 
 type persistData func(state *contracts.DocumentState, bookkeeping string)
@@ -97,6 +102,10 @@ type RunCommandService struct {
 	processorStopPolicy *sdkutil.StopPolicy
 	pollAssociations    bool
 	processor           processor.Processor
+	// pollBackoff is the adaptive delay between polls, nil to poll again as soon as a poll completes
+	pollBackoff *pollBackoff
+	// repliesDone is closed when the results of the processor have all been sent
+	repliesDone chan bool
 }
 
 // NewOfflineProcessor initialize a new offline command document processor
@@ -119,7 +128,13 @@ func NewMDSService(context context.T) *RunCommandService {
 	mdsService := newMdsService(context.AppConfig())
 	config := context.AppConfig()
 
-	return NewService(messageContext, mdsName, mdsService, config.Mds.CommandWorkersLimit, CancelWorkersLimit, true, []contracts.DocumentType{contracts.SendCommand, contracts.CancelCommand})
+	service := NewService(messageContext, mdsName, mdsService, config.Mds.CommandWorkersLimit, CancelWorkersLimit, true, []contracts.DocumentType{contracts.SendCommand, contracts.CancelCommand})
+	if service != nil {
+		service.pollBackoff = newPollBackoff(
+			time.Duration(config.Mds.PollBackoffMinMillis)*time.Millisecond,
+			time.Duration(config.Mds.PollBackoffMaxMillis)*time.Millisecond)
+	}
+	return service
 }
 
 // NewProcessor performs common initialization for Mds and Offline processors
@@ -152,16 +167,22 @@ func NewService(ctx context.T, serviceName string, service mdsService.Service, c
 	// create a stop policy where we will stop after 10 consecutive errors and if time period expires.
 	stopPolicy := newStopPolicy(serviceName)
 
+	// only the MDS accepts compressed replies, the offline service writes them as they are
+	compressionMinBytes := 0
+	if serviceName == mdsName {
+		compressionMinBytes = config.Mds.ReplyCompressionMinBytes
+	}
+
 	// SendDocLevelResponse is used to send document level update
 	// Specify a new status of the document
 	sendDocLevelResponse := func(messageID string, resultStatus contracts.ResultStatus, documentTraceOutput string) {
 		payloadDoc := prepareReplyPayloadToUpdateDocumentStatus(agentInfo, resultStatus, documentTraceOutput)
-		processSendReply(log, messageID, service, payloadDoc, stopPolicy)
+		processSendReply(log, messageID, service, payloadDoc, compressionMinBytes, stopPolicy)
 	}
 
 	sendResponse := func(messageID string, res contracts.DocumentResult) {
 		pluginID := res.LastPlugin
-		processSendReply(log, messageID, service, FormatPayload(log, pluginID, agentInfo, res.PluginResults), stopPolicy)
+		processSendReply(log, messageID, service, FormatPayload(log, pluginID, agentInfo, res.PluginResults), compressionMinBytes, stopPolicy)
 	}
 
 	var assocProc *associationProcessor.Processor
@@ -200,15 +221,19 @@ func prepareReplyPayloadToUpdateDocumentStatus(agentInfo contracts.AgentInfo, do
 	return
 }
 
-func processSendReply(log log.T, messageID string, mdsService mdsService.Service, payloadDoc messageContracts.SendReplyPayload, processorStopPolicy *sdkutil.StopPolicy) {
+func processSendReply(log log.T, messageID string, mdsService mdsService.Service, payloadDoc messageContracts.SendReplyPayload, compressionMinBytes int, processorStopPolicy *sdkutil.StopPolicy) {
 	payloadB, err := json.Marshal(payloadDoc)
 	if err != nil {
 		log.Error("could not marshal reply payload!", err)
 	}
 	payload := string(payloadB)
 	log.Info("Sending reply ", jsonutil.Indent(payload))
+	if compressionMinBytes > 0 {
+		payload = compressReplyPayload(log, payload, compressionMinBytes)
+	}
 	err = mdsService.SendReply(log, messageID, payload)
 	if err != nil {
+		metrics.UploadFailures.Inc(replyUploadDestination)
 		sdkutil.HandleAwsError(log, err, processorStopPolicy)
 	}
 }
@@ -220,12 +245,25 @@ var newOfflineService = func(log log.T) (mdsService.Service, error) {
 var newMdsService = func(config appconfig.SsmagentConfig) mdsService.Service {
 	connectionTimeout := time.Duration(config.Mds.StopTimeoutMillis) * time.Millisecond
 
-	return mdsService.NewService(
+	service := mdsService.NewService(
 		config.Agent.Region,
 		config.Mds.Endpoint,
 		nil,
 		connectionTimeout,
 	)
+	if len(config.Failover.Targets) == 0 {
+		return service
+	}
+
+	region := config.Agent.Region
+	if region == "" {
+		region, _ = platform.Region()
+	}
+	services := []mdsService.Service{service}
+	for _, target := range config.Failover.Targets {
+		services = append(services, mdsService.NewService(target.Region, target.MdsEndpoint, nil, connectionTimeout))
+	}
+	return mdsService.NewFailoverService(failover.NewTracker(mdsName, region, config.Failover), services)
 }
 
 var newStopPolicy = func(name string) *sdkutil.StopPolicy {
@@ -15,7 +15,6 @@
 package runcommand
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"path"
@@ -84,7 +83,7 @@ func parseCancelCommandMessage(context context.T, msg *ssmmds.Message, messagesO
 	log.Debug("Processing cancel command message - ", *msg.MessageId)
 
 	var payload messageContracts.CancelPayload
-	err := json.Unmarshal([]byte(*msg.Payload), &payload)
+	err := jsonutil.UnmarshalReader(strings.NewReader(*msg.Payload), &payload)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +119,7 @@ func parseCancelCommandMessage(context context.T, msg *ssmmds.Message, messagesO
 	return &docState, nil
 }
 
-//generateCloudWatchLogStreamPrefix creates the LogStreamPrefix for cloudWatch output. LogStreamPrefix = <CommandID>/<InstanceID>
+// generateCloudWatchLogStreamPrefix creates the LogStreamPrefix for cloudWatch output. LogStreamPrefix = <CommandID>/<InstanceID>
 func generateCloudWatchLogStreamPrefix(commandID string) (string, error) {
 
 	instanceID, err := systemInfo.InstanceID()
@@ -157,7 +156,7 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 
 	// parse message to retrieve parameters
 	var parsedMessage messageContracts.SendCommandPayload
-	err := json.Unmarshal([]byte(*msg.Payload), &parsedMessage)
+	err := jsonutil.UnmarshalReader(strings.NewReader(*msg.Payload), &parsedMessage)
 	if err != nil {
 		errorMsg := "Encountered error while parsing input - internal error"
 		log.Errorf(errorMsg)
@@ -40,7 +40,9 @@ var awsS3EndpointMap = map[string]string{
 	//AUTOGEN_END
 }
 
-/* This function returns the s3 endpoint specified by the user in appconfig.
+/*
+	This function returns the s3 endpoint specified by the user in appconfig.
+
 If the user didn't specify one, it will return the Amazon S3 endpoint in a certain region
 */
 func GetS3Endpoint(region string) (s3Endpoint string) {
@@ -50,6 +52,12 @@ func GetS3Endpoint(region string) (s3Endpoint string) {
 		}
 	}
 
+	if appconfig.UseDualStackEndpoints() {
+		if dualStackEndpoint := appconfig.GetDualStackEndPoint(region, "s3"); dualStackEndpoint != "" {
+			return dualStackEndpoint
+		}
+	}
+
 	if s3Endpoint, ok := awsS3EndpointMap[region]; ok {
 		return s3Endpoint
 	}
@@ -64,14 +72,11 @@ func GetS3Endpoint(region string) (s3Endpoint string) {
 
 /*
 This function will get the generic S3 endpoint for a certain region.
-Most regions will use us-east-1 endpoint except special ones
+Regions of the aws partition will use us-east-1 endpoint, the other partitions use the endpoint of the region
 */
 func GetS3GenericEndPoint(region string) (s3Endpoint string) {
-	if region == "us-gov-west-1" {
-		return GetS3Endpoint(region) // Restricted regions
-	}
-	if region == "cn-north-1" {
-		return GetS3Endpoint("cn-north-1") // Use cn-north-1 for China
+	if appconfig.GetPartition(region).ID != appconfig.PartitionAws {
+		return GetS3Endpoint(region) // Restricted and China regions
 	}
 	return GetS3Endpoint("us-east-1") // For all other regions, use us-east-1
 }
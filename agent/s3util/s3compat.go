@@ -0,0 +1,80 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package s3util contains methods for interacting with S3.
+package s3util
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/util"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// defaultCompatibleRegion is the signing region used for S3-compatible object stores when none is configured
+const defaultCompatibleRegion = "us-east-1"
+
+// IsS3Compatible returns true if the agent is configured to use an S3-compatible object store
+// (MinIO, on-premises appliances, ...) rather than Amazon S3.
+func IsS3Compatible(s3Cfg appconfig.S3Cfg) bool {
+	return s3Cfg.Endpoint != "" && s3Cfg.ForcePathStyle
+}
+
+// CompatibleRegion returns the region requests to the S3-compatible object store are signed for
+func CompatibleRegion(s3Cfg appconfig.S3Cfg) string {
+	if s3Cfg.Region != "" {
+		return s3Cfg.Region
+	}
+	return defaultCompatibleRegion
+}
+
+// ApplyCompatibleConfig sets path-style addressing and the custom CA bundle of an S3-compatible object store on config
+func ApplyCompatibleConfig(config *aws.Config, s3Cfg appconfig.S3Cfg) error {
+	if s3Cfg.ForcePathStyle {
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	if s3Cfg.CABundle == "" {
+		return nil
+	}
+	client, err := util.NewHTTPClientWithCABundle(s3Cfg.CABundle)
+	if err != nil {
+		return err
+	}
+	config.HTTPClient = client
+	return nil
+}
+
+// parseCompatibleURL parses a URL on the configured S3-compatible endpoint, which always uses path-style addressing
+func parseCompatibleURL(s3Cfg appconfig.S3Cfg, s3URL *url.URL) (output AmazonS3URL, ok bool) {
+	if !IsS3Compatible(s3Cfg) || !strings.EqualFold(s3URL.Host, compatibleHost(s3Cfg.Endpoint)) {
+		return output, false
+	}
+	output.IsValidS3URI = true
+	output.IsPathStyle = true
+	output.Bucket, output.Key = parsePathStyle(s3URL.Path)
+	output.Region = CompatibleRegion(s3Cfg)
+	return output, true
+}
+
+// compatibleHost returns the host (and port) of an endpoint configured with or without a scheme
+func compatibleHost(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		if parsed, err := url.Parse(endpoint); err == nil {
+			return parsed.Host
+		}
+	}
+	return strings.TrimRight(endpoint, "/")
+}
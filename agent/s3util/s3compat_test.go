@@ -0,0 +1,117 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package s3util
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsS3Compatible(t *testing.T) {
+	assert.False(t, IsS3Compatible(appconfig.S3Cfg{}))
+	assert.False(t, IsS3Compatible(appconfig.S3Cfg{Endpoint: "s3.example.com"}))
+	assert.False(t, IsS3Compatible(appconfig.S3Cfg{ForcePathStyle: true}))
+	assert.True(t, IsS3Compatible(appconfig.S3Cfg{Endpoint: "minio.example.com:9000", ForcePathStyle: true}))
+}
+
+func TestCompatibleRegion(t *testing.T) {
+	assert.Equal(t, "us-east-1", CompatibleRegion(appconfig.S3Cfg{}))
+	assert.Equal(t, "on-prem-1", CompatibleRegion(appconfig.S3Cfg{Region: "on-prem-1"}))
+}
+
+func TestParseCompatibleURL(t *testing.T) {
+	s3Cfg := appconfig.S3Cfg{Endpoint: "minio.example.com:9000", ForcePathStyle: true}
+	tests := []struct {
+		url    string
+		ok     bool
+		output AmazonS3URL
+	}{
+		{"https://minio.example.com:9000/bucket/path/to/key", true, AmazonS3URL{true, true, "bucket", "path/to/key", "us-east-1"}},
+		{"https://MINIO.example.com:9000/bucket/", true, AmazonS3URL{true, true, "bucket", "", "us-east-1"}},
+		{"https://minio.example.com:9000/bucket", true, AmazonS3URL{true, true, "bucket", "", "us-east-1"}},
+		{"https://minio.example.com/bucket/key", false, AmazonS3URL{}},
+		{"https://s3.amazonaws.com/bucket/key", false, AmazonS3URL{}},
+	}
+	for _, test := range tests {
+		parsed, err := url.Parse(test.url)
+		assert.NoError(t, err)
+		output, ok := parseCompatibleURL(s3Cfg, parsed)
+		assert.Equal(t, test.ok, ok, test.url)
+		assert.Equal(t, test.output, output, test.url)
+	}
+}
+
+func TestParseCompatibleURL_EndpointWithScheme(t *testing.T) {
+	s3Cfg := appconfig.S3Cfg{Endpoint: "https://minio.example.com:9000/", ForcePathStyle: true, Region: "on-prem-1"}
+	parsed, _ := url.Parse("https://minio.example.com:9000/bucket/key")
+
+	output, ok := parseCompatibleURL(s3Cfg, parsed)
+
+	assert.True(t, ok)
+	assert.Equal(t, AmazonS3URL{true, true, "bucket", "key", "on-prem-1"}, output)
+}
+
+func TestApplyCompatibleConfig(t *testing.T) {
+	config := &aws.Config{}
+
+	err := ApplyCompatibleConfig(config, appconfig.S3Cfg{Endpoint: "minio.example.com:9000", ForcePathStyle: true})
+
+	assert.NoError(t, err)
+	assert.True(t, *config.S3ForcePathStyle)
+	assert.Nil(t, config.HTTPClient)
+}
+
+func TestApplyCompatibleConfig_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	tmpDir, _ := ioutil.TempDir("", "s3compat")
+	defer os.RemoveAll(tmpDir)
+	caBundle := filepath.Join(tmpDir, "ca.pem")
+	ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	config := &aws.Config{}
+
+	err := ApplyCompatibleConfig(config, appconfig.S3Cfg{CABundle: caBundle})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, config.HTTPClient)
+	resp, err := config.HTTPClient.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	_, err = http.Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestApplyCompatibleConfig_InvalidCABundle(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "s3compat")
+	defer os.RemoveAll(tmpDir)
+	caBundle := filepath.Join(tmpDir, "ca.pem")
+	ioutil.WriteFile(caBundle, []byte("not a certificate"), 0600)
+
+	err := ApplyCompatibleConfig(&aws.Config{}, appconfig.S3Cfg{CABundle: caBundle})
+	assert.Error(t, err)
+
+	err = ApplyCompatibleConfig(&aws.Config{}, appconfig.S3Cfg{CABundle: filepath.Join(tmpDir, "missing.pem")})
+	assert.Error(t, err)
+}
@@ -11,7 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-//Package s3util contains utilities for working with the file system.
+// Package s3util contains utilities for working with the file system.
 package s3util
 
 import (
@@ -20,15 +20,13 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
 const (
 	// EndpointPattern is a valid regular expression for s3 url pattern
 	EndpointPattern = "^(.+\\.)?s3[.-]([a-z0-9-]+)\\."
-
-	// cn- is a prefix for China region
-	ChinaRegionPrefix = "cn-"
 )
 
 // AmazonS3URL holds interesting pieces after parsing a s3 URL
@@ -55,6 +53,13 @@ func ParseAmazonS3URL(log log.T, s3URL *url.URL) (output AmazonS3URL) {
 		Region:       "",
 	}
 
+	if appConfig, err := appconfig.Config(false); err == nil {
+		if compatibleURL, ok := parseCompatibleURL(appConfig.S3, s3URL); ok {
+			log.Debugf("%v is a valid url of the S3-compatible endpoint", s3URL.String())
+			return compatibleURL
+		}
+	}
+
 	match, _ := regexp.MatchString(EndpointPattern, s3URL.Host)
 	if match == false {
 		// Invalid S3 URI - hostname does not appear to be a valid S3 endpoint
@@ -88,25 +93,7 @@ func ParseAmazonS3URL(log log.T, s3URL *url.URL) (output AmazonS3URL) {
 		// no bucket name in the authority, parse it from the path
 		output.IsPathStyle = true
 
-		// grab the encoded path so we don't run afoul of '/'s in the bucket name
-		if path == "/" {
-		} else {
-			path = path[1:]
-			index := strings.Index(path, "/")
-			if index == -1 {
-				// https://s3.amazonaws.com/bucket
-				output.Bucket = path
-				output.Key = ""
-			} else if index == (len(path) - 1) {
-				// https://s3.amazonaws.com/bucket/
-				output.Bucket = strings.TrimRight(path, "/")
-				output.Key = ""
-			} else {
-				// https://s3.amazonaws.com/bucket/key
-				output.Bucket = path[:index]
-				output.Key = path[index+1:]
-			}
-		}
+		output.Bucket, output.Key = parsePathStyle(path)
 	} else {
 		// bucket name in the host, path is the object key
 		output.IsPathStyle = false
@@ -136,6 +123,25 @@ func ParseAmazonS3URL(log log.T, s3URL *url.URL) (output AmazonS3URL) {
 	return
 }
 
+// parsePathStyle returns the bucket and key of a path-style URL path
+func parsePathStyle(path string) (bucket string, key string) {
+	// grab the encoded path so we don't run afoul of '/'s in the bucket name
+	if path == "/" || path == "" {
+		return "", ""
+	}
+	path = path[1:]
+	index := strings.Index(path, "/")
+	if index == -1 {
+		// https://s3.amazonaws.com/bucket
+		return path, ""
+	} else if index == (len(path) - 1) {
+		// https://s3.amazonaws.com/bucket/
+		return strings.TrimRight(path, "/"), ""
+	}
+	// https://s3.amazonaws.com/bucket/key
+	return path[:index], path[index+1:]
+}
+
 // String returns the string representation of the AmazonS3URL
 func (output AmazonS3URL) String() string {
 	return fmt.Sprintf("{Region: %s; Bucket: %s; Key: %s; IsValidS3URI: %v; IsPathStyle: %v}",
@@ -21,10 +21,16 @@ import (
 	"os"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/accounting"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -58,6 +64,10 @@ func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
 	if errConfig != nil {
 		log.Error("failed to read appconfig.")
 	} else {
+		if err := ApplyCompatibleConfig(config, appConfig.S3); err != nil {
+			log.Errorf("failed to configure the S3 endpoint, %v", err)
+		}
+		proxyconfig.ConfigureAwsProxy(log, config, appConfig.S3.Proxy)
 		if appConfig.S3.Endpoint != "" {
 			config.Endpoint = &appConfig.S3.Endpoint
 		} else {
@@ -71,12 +81,37 @@ func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
 		}
 	}
 	config.Region = &bucketRegion
+	if useAccelerate(appConfig.S3, bucketRegion) {
+		log.Debugf("Using the S3 Transfer Acceleration endpoint for bucket %v", bucketName)
+		config.S3UseAccelerate = aws.Bool(true)
+	}
 
 	sess := session.New(config)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
 
 	return &AmazonS3Util{
-		myUploader: s3manager.NewUploader(sess),
+		myUploader: s3manager.NewUploader(sess, uploaderOptions(appConfig.S3)),
+	}
+}
+
+// useAccelerate returns whether the uploads go through the S3 Transfer Acceleration endpoints, which only exist
+// in the aws partition and cannot be combined with a custom endpoint
+func useAccelerate(s3Cfg appconfig.S3Cfg, bucketRegion string) bool {
+	return s3Cfg.UseAccelerate && s3Cfg.Endpoint == "" && appconfig.GetPartition(bucketRegion).ID == appconfig.PartitionAws
+}
+
+// uploaderOptions returns the option setting the part size and the concurrency of the multipart uploads
+func uploaderOptions(s3Cfg appconfig.S3Cfg) func(*s3manager.Uploader) {
+	return func(uploader *s3manager.Uploader) {
+		if s3Cfg.UploadPartSizeMB > 0 {
+			uploader.PartSize = int64(s3Cfg.UploadPartSizeMB) * 1024 * 1024
+		}
+		if s3Cfg.UploadConcurrency > 0 {
+			uploader.Concurrency = s3Cfg.UploadConcurrency
+		}
 	}
 }
 
@@ -93,11 +128,14 @@ func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string,
 	params := &s3manager.UploadInput{
 		Bucket:      aws.String(bucketName),
 		Key:         aws.String(objectKey),
-		Body:        file,
+		Body:        bandwidth.NewReader(bandwidth.Upload, file),
 		ContentType: aws.String("text/plain"),
 	}
 	if result, err := u.myUploader.Upload(params); err == nil {
 		log.Infof("Successfully uploaded file to ", result.Location)
+		if info, statErr := file.Stat(); statErr == nil {
+			accounting.RecordUpload(info.Size())
+		}
 		if _, aclErr := u.myUploader.S3.PutObjectAcl(&s3.PutObjectAclInput{
 			Bucket: aws.String(bucketName),
 			Key:    aws.String(objectKey),
@@ -117,6 +155,10 @@ func (u *AmazonS3Util) S3Upload(log log.T, bucketName string, objectKey string,
 // This function returns the Amazon S3 Bucket region based on its name and the EC2 instance region.
 // It will return the same instance region if it failed to guess the bucket region.
 func GetBucketRegion(log log.T, bucketName string, httpProvider HttpProvider) (region string) {
+	if appConfig, err := appconfig.Config(false); err == nil && IsS3Compatible(appConfig.S3) {
+		// S3-compatible object stores don't report the region of their buckets
+		return CompatibleRegion(appConfig.S3)
+	}
 	instanceRegion, err := getRegion()
 	if err != nil {
 		log.Error("Cannot get the current instance region information")
@@ -132,7 +174,7 @@ func GetBucketRegion(log log.T, bucketName string, httpProvider HttpProvider) (r
 	}
 }
 
-//IsBucketEncrypted checks if the bucket is encrypted
+// IsBucketEncrypted checks if the bucket is encrypted
 func (u *AmazonS3Util) IsBucketEncrypted(log log.T, bucketName string) bool {
 	input := &s3.GetBucketEncryptionInput{
 		Bucket: aws.String(bucketName),
@@ -6,6 +6,8 @@ import (
 
 	"errors"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -113,3 +115,22 @@ func (m *MockedHttpProvider) Head(url string) (*http.Response, error) {
 	args := m.Called(url)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
+
+func TestUseAccelerate(t *testing.T) {
+	assert.False(t, useAccelerate(appconfig.S3Cfg{}, "us-east-1"))
+	assert.True(t, useAccelerate(appconfig.S3Cfg{UseAccelerate: true}, "us-east-1"))
+	assert.False(t, useAccelerate(appconfig.S3Cfg{UseAccelerate: true, Endpoint: "minio.example.com"}, "us-east-1"))
+	assert.False(t, useAccelerate(appconfig.S3Cfg{UseAccelerate: true}, "cn-north-1"))
+}
+
+func TestUploaderOptions(t *testing.T) {
+	uploader := &s3manager.Uploader{PartSize: s3manager.DefaultUploadPartSize, Concurrency: s3manager.DefaultUploadConcurrency}
+	uploaderOptions(appconfig.S3Cfg{UploadPartSizeMB: 64, UploadConcurrency: 2})(uploader)
+	assert.Equal(t, int64(64*1024*1024), uploader.PartSize)
+	assert.Equal(t, 2, uploader.Concurrency)
+
+	uploader = &s3manager.Uploader{PartSize: s3manager.DefaultUploadPartSize, Concurrency: s3manager.DefaultUploadConcurrency}
+	uploaderOptions(appconfig.S3Cfg{})(uploader)
+	assert.Equal(t, s3manager.DefaultUploadPartSize, uploader.PartSize)
+	assert.Equal(t, s3manager.DefaultUploadConcurrency, uploader.Concurrency)
+}
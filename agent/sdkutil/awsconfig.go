@@ -17,9 +17,11 @@ package sdkutil
 import (
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/rolecreds"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/processcreds"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -27,6 +29,12 @@ import (
 	"github.com/aws/aws-sdk-go/aws/defaults"
 )
 
+// credentials signing the requests sent to the mock service in the simulation mode
+const (
+	simulationAccessKeyID     = "SIMULATION"
+	simulationSecretAccessKey = "SIMULATION"
+)
+
 // AwsConfig returns the default aws.Config object while the appropriate
 // credentials. Callers should override returned config properties with any
 // values they want for service specific overrides.
@@ -43,6 +51,18 @@ func AwsConfig() (awsConfig *aws.Config) {
 		awsConfig.Region = &region
 	}
 
+	// the mock service of the simulation mode does not check the signature of the requests
+	if appConfig, err := appconfig.Config(false); err == nil && appConfig.Simulation.Endpoint != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(simulationAccessKeyID, simulationSecretAccessKey, "")
+		return
+	}
+
+	// credentials printed by the configured credential process take precedence
+	if processCredentials := processcreds.Configured(); processCredentials != nil {
+		awsConfig.Credentials = processCredentials
+		return
+	}
+
 	// load managed credentials if applicable
 	if isManaged, err := registration.HasManagedInstancesCredentials(); isManaged && err == nil {
 		awsConfig.Credentials =
@@ -63,15 +83,15 @@ func AwsConfig() (awsConfig *aws.Config) {
 func defaultRemoteCredentials() *credentials.Credentials {
 	cfg := defaults.Config()
 	handlers := defaults.Handlers()
+	handlers.Build.PushBackNamed(platform.MetadataEndpointHandler)
+	handlers.Build.PushBackNamed(platform.MetadataTokenHandler)
 	remotecreds := defaults.RemoteCredProvider(*cfg, handlers)
 
 	return credentials.NewCredentials(remotecreds)
 }
 
 var newRetryer = func() aws.RequestRetryer {
-	r := retryer.SsmRetryer{}
-	r.NumMaxRetries = 3
-	return r
+	return retryer.New("")
 }
 
 var sleepDelay = func(d time.Duration) {
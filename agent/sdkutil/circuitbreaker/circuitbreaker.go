@@ -0,0 +1,184 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package circuitbreaker stops calling an AWS service after consecutive throttling or server errors,
+// and resumes calling it after a jittered cooldown so a fleet of agents doesn't hit the service in lockstep
+// when it recovers from an outage.
+package circuitbreaker
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ErrCodeCircuitOpen is the error code of requests rejected while the circuit of their service is open
+const ErrCodeCircuitOpen = "CircuitOpen"
+
+// breaker is the circuit of a service in a region
+type breaker struct {
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	// probing is true while the request testing whether the service recovered is in flight
+	probing bool
+}
+
+var (
+	lock     sync.Mutex
+	breakers = map[string]*breaker{}
+
+	now = time.Now
+	// jitter returns a random duration in [0, d)
+	jitter = func(d time.Duration) time.Duration {
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d)))
+	}
+	loadConfig = func() appconfig.CircuitBreakerCfg {
+		config, err := appconfig.Config(false)
+		if err != nil {
+			config = appconfig.DefaultConfig()
+		}
+		return config.CircuitBreaker
+	}
+)
+
+// circuitKey returns the key of the circuit of a request
+func circuitKey(r *request.Request) string {
+	return r.ClientInfo.ServiceName + "/" + aws.StringValue(r.Config.Region)
+}
+
+// allow returns true when a request can be sent to a service, once the cooldown of an open circuit
+// elapsed a single request is allowed to probe the service
+func allow(key string) bool {
+	lock.Lock()
+	defer lock.Unlock()
+
+	b, ok := breakers[key]
+	if !ok || b.openUntil.IsZero() {
+		return true
+	}
+	if b.probing || now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record records the outcome of a request, opening the circuit after consecutive failures
+func record(key string, failed bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	b, ok := breakers[key]
+	if !ok {
+		b = &breaker{}
+		breakers[key] = b
+	}
+	if !failed {
+		if !b.openUntil.IsZero() {
+			log.DefaultLogger().Infof("Service %v recovered, closing its circuit", key)
+		}
+		*b = breaker{}
+		return
+	}
+
+	config := loadConfig()
+	b.failures++
+	wasProbing := b.probing
+	b.probing = false
+	if !wasProbing && b.failures < config.FailureThreshold {
+		return
+	}
+
+	// the cooldown doubles every time the probe fails
+	maxCooldown := time.Duration(config.MaxCooldownSeconds) * time.Second
+	if b.cooldown == 0 {
+		b.cooldown = time.Duration(config.CooldownSeconds) * time.Second
+	} else if wasProbing {
+		b.cooldown *= 2
+	}
+	if b.cooldown > maxCooldown {
+		b.cooldown = maxCooldown
+	}
+	b.openUntil = now().Add(b.cooldown + jitter(b.cooldown))
+	log.DefaultLogger().Warnf("Opening the circuit of service %v after %v consecutive failures, calls resume after %v",
+		key, b.failures, b.openUntil.Format(time.RFC3339))
+}
+
+// release lets another request probe the service when the probe failed before reaching it
+func release(key string) {
+	lock.Lock()
+	defer lock.Unlock()
+	if b, ok := breakers[key]; ok {
+		b.probing = false
+	}
+}
+
+// isFailure returns true when the outcome of a request shows the service is throttling or unavailable,
+// errors caused by the request itself don't count
+func isFailure(r *request.Request) bool {
+	if r.Error == nil {
+		return false
+	}
+	if awsErr, ok := r.Error.(awserr.Error); ok && awsErr.Code() == ErrCodeCircuitOpen {
+		return false
+	}
+	if r.IsErrorThrottle() {
+		return true
+	}
+	if r.HTTPResponse == nil || r.HTTPResponse.StatusCode == 0 {
+		// the service could not be reached
+		return r.IsErrorRetryable()
+	}
+	return r.HTTPResponse.StatusCode >= http.StatusInternalServerError || r.HTTPResponse.StatusCode == http.StatusTooManyRequests
+}
+
+// SignHandler rejects requests to services whose circuit is open, it runs before the request is signed
+// so that rejected requests are not retried
+var SignHandler = request.NamedHandler{Name: "circuitbreaker.SignHandler", Fn: func(r *request.Request) {
+	if !allow(circuitKey(r)) {
+		r.Error = awserr.New(ErrCodeCircuitOpen,
+			"calls to "+circuitKey(r)+" are suspended after consecutive failures", nil)
+	}
+}}
+
+// CompleteHandler records the outcome of requests once all their retries are done
+var CompleteHandler = request.NamedHandler{Name: "circuitbreaker.CompleteHandler", Fn: func(r *request.Request) {
+	if awsErr, ok := r.Error.(awserr.Error); ok && awsErr.Code() == ErrCodeCircuitOpen {
+		return
+	}
+	failed := isFailure(r)
+	if !failed && r.Error != nil && (r.HTTPResponse == nil || r.HTTPResponse.StatusCode == 0) {
+		// the request failed before reaching the service, which tells nothing about the service
+		release(circuitKey(r))
+		return
+	}
+	record(circuitKey(r), failed)
+}}
+
+// AddHandlers adds the circuit breaker handlers to the handlers of a session or a service client
+func AddHandlers(handlers *request.Handlers) {
+	handlers.Sign.PushFrontNamed(SignHandler)
+	handlers.Complete.PushBackNamed(CompleteHandler)
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package circuitbreaker
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubBreakers resets the circuits and stubs the clock, the jitter and the configuration
+func stubBreakers(t *testing.T) *time.Time {
+	nowOrig, jitterOrig, loadConfigOrig := now, jitter, loadConfig
+	t.Cleanup(func() {
+		now, jitter, loadConfig = nowOrig, jitterOrig, loadConfigOrig
+		breakers = map[string]*breaker{}
+	})
+
+	current := time.Now()
+	now = func() time.Time { return current }
+	jitter = func(d time.Duration) time.Duration { return d / 2 }
+	loadConfig = func() appconfig.CircuitBreakerCfg {
+		return appconfig.CircuitBreakerCfg{FailureThreshold: 2, CooldownSeconds: 10, MaxCooldownSeconds: 25}
+	}
+	breakers = map[string]*breaker{}
+	return &current
+}
+
+// newRequest returns a completed request to ssm with an http status code and an error
+func newRequest(statusCode int, err error) *request.Request {
+	r := &request.Request{
+		ClientInfo: metadata.ClientInfo{ServiceName: "ssm"},
+		Config:     aws.Config{Region: aws.String("us-east-1")},
+		Error:      err,
+	}
+	if statusCode != 0 {
+		r.HTTPResponse = &http.Response{StatusCode: statusCode}
+	}
+	return r
+}
+
+func TestCircuitOpensAfterConsecutiveFailures(t *testing.T) {
+	current := stubBreakers(t)
+	throttled := awserr.New("ThrottlingException", "Rate exceeded", nil)
+
+	CompleteHandler.Fn(newRequest(http.StatusBadRequest, throttled))
+	assert.True(t, allow("ssm/us-east-1"))
+	CompleteHandler.Fn(newRequest(http.StatusBadRequest, throttled))
+	assert.False(t, allow("ssm/us-east-1"))
+	assert.True(t, allow("ssm/us-west-2"))
+
+	rejected := newRequest(0, nil)
+	SignHandler.Fn(rejected)
+	assert.Equal(t, ErrCodeCircuitOpen, rejected.Error.(awserr.Error).Code())
+
+	// calls resume after the cooldown and its jitter, with a single probe
+	*current = current.Add(14 * time.Second)
+	assert.False(t, allow("ssm/us-east-1"))
+	*current = current.Add(time.Second)
+	assert.True(t, allow("ssm/us-east-1"))
+	assert.False(t, allow("ssm/us-east-1"))
+
+	CompleteHandler.Fn(newRequest(http.StatusOK, nil))
+	assert.True(t, allow("ssm/us-east-1"))
+	assert.True(t, allow("ssm/us-east-1"))
+}
+
+func TestCircuitCooldownDoublesWhenProbeFails(t *testing.T) {
+	current := stubBreakers(t)
+	unavailable := awserr.New("ServiceUnavailable", "unavailable", nil)
+	CompleteHandler.Fn(newRequest(http.StatusServiceUnavailable, unavailable))
+	CompleteHandler.Fn(newRequest(http.StatusServiceUnavailable, unavailable))
+
+	*current = current.Add(15 * time.Second)
+	assert.True(t, allow("ssm/us-east-1"))
+	CompleteHandler.Fn(newRequest(http.StatusServiceUnavailable, unavailable))
+
+	assert.Equal(t, 20*time.Second, breakers["ssm/us-east-1"].cooldown)
+	*current = current.Add(29 * time.Second)
+	assert.False(t, allow("ssm/us-east-1"))
+	*current = current.Add(time.Second)
+	assert.True(t, allow("ssm/us-east-1"))
+	CompleteHandler.Fn(newRequest(http.StatusServiceUnavailable, unavailable))
+
+	// the cooldown is capped
+	assert.Equal(t, 25*time.Second, breakers["ssm/us-east-1"].cooldown)
+}
+
+func TestCircuitIgnoresClientErrors(t *testing.T) {
+	stubBreakers(t)
+
+	for i := 0; i < 5; i++ {
+		CompleteHandler.Fn(newRequest(http.StatusBadRequest, awserr.New("ValidationException", "invalid", nil)))
+		CompleteHandler.Fn(newRequest(0, errors.New("no credentials")))
+	}
+	assert.True(t, allow("ssm/us-east-1"))
+}
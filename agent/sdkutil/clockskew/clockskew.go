@@ -0,0 +1,110 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package clockskew corrects the signing time of AWS requests when the clock of the instance is skewed,
+// so instances with a broken time synchronization can still call AWS services.
+package clockskew
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// correctionThreshold is the difference with the current correction under which the correction is kept,
+// the date of responses has a precision of a second and includes the latency of the request
+const correctionThreshold = time.Minute
+
+// skewErrorCodes are the error codes returned by AWS services when the signing time of a request is off
+var skewErrorCodes = map[string]bool{
+	"RequestTimeTooSkewed":      true,
+	"RequestExpired":            true,
+	"RequestInTheFuture":        true,
+	"InvalidSignatureException": true,
+	"SignatureDoesNotMatch":     true,
+	"AuthFailure":               true,
+}
+
+// offset is the correction added to the clock of the instance, in nanoseconds
+var offset int64
+
+// now returns the time of the clock of the instance
+var now = time.Now
+
+// Offset returns the correction added to the clock of the instance
+func Offset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&offset))
+}
+
+// Now returns the corrected current time, to be used to sign requests
+func Now() time.Time {
+	return now().Add(Offset())
+}
+
+// IsSkewError returns true when an error code denotes a signing time that is off
+func IsSkewError(code string) bool {
+	return skewErrorCodes[code]
+}
+
+// Correct updates the correction from the date header of a response, it returns true when the correction changed
+func Correct(log log.T, date string) bool {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return false
+	}
+	skew := serverTime.Sub(now())
+	if difference := skew - Offset(); difference > -correctionThreshold && difference < correctionThreshold {
+		return false
+	}
+
+	atomic.StoreInt64(&offset, int64(skew))
+	log.Warnf("CLOCK SKEW DETECTED: the clock of the instance is off by %v from AWS. "+
+		"Requests are signed with the corrected time, synchronize the clock of the instance with NTP to fix it.", skew)
+	return true
+}
+
+// SignHandler signs requests with the corrected time, it has to run before the request is signed
+var SignHandler = request.NamedHandler{Name: "clockskew.SignHandler", Fn: func(r *request.Request) {
+	if Offset() == 0 {
+		return
+	}
+	r.Time = Now()
+	r.LastSignedAt = time.Time{}
+	// the signer signs requests that are already signed with the time of the clock of the instance
+	r.HTTPRequest.Header.Del("Authorization")
+}}
+
+// CorrectHandler corrects the time from the response of requests failed with a signing time error,
+// and retries them with the corrected time
+var CorrectHandler = request.NamedHandler{Name: "clockskew.CorrectHandler", Fn: func(r *request.Request) {
+	awsErr, ok := r.Error.(awserr.Error)
+	if !ok || !IsSkewError(awsErr.Code()) || r.HTTPResponse == nil {
+		return
+	}
+	if Correct(log.DefaultLogger(), r.HTTPResponse.Header.Get("Date")) {
+		r.Retryable = aws.Bool(true)
+	}
+}}
+
+// AddHandlers adds the clock skew handlers to the handlers of a session or a service client,
+// it has to be called after the signing handlers are set
+func AddHandlers(handlers *request.Handlers) {
+	handlers.Sign.PushFrontNamed(SignHandler)
+	handlers.UnmarshalError.PushBackNamed(CorrectHandler)
+}
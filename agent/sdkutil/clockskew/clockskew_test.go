@@ -0,0 +1,99 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clockskew
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+func setClock(t *testing.T, local time.Time) {
+	now = func() time.Time { return local }
+	atomic.StoreInt64(&offset, 0)
+	t.Cleanup(func() {
+		now = time.Now
+		atomic.StoreInt64(&offset, 0)
+	})
+}
+
+func TestCorrect(t *testing.T) {
+	local := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	setClock(t, local)
+
+	assert.False(t, Correct(logger, "not a date"))
+	assert.False(t, Correct(logger, local.Add(30*time.Second).Format(http.TimeFormat)))
+	assert.Equal(t, time.Duration(0), Offset())
+
+	assert.True(t, Correct(logger, local.Add(-20*time.Minute).Format(http.TimeFormat)))
+	assert.Equal(t, -20*time.Minute, Offset())
+	assert.Equal(t, local.Add(-20*time.Minute), Now())
+
+	// the correction is kept while it is close to the date of the responses
+	assert.False(t, Correct(logger, local.Add(-20*time.Minute+10*time.Second).Format(http.TimeFormat)))
+	assert.Equal(t, -20*time.Minute, Offset())
+}
+
+func newRequest(code string, date string) *request.Request {
+	httpRequest, _ := http.NewRequest("POST", "https://ssm.us-east-1.amazonaws.com", nil)
+	httpRequest.Header.Set("Authorization", "AWS4-HMAC-SHA256 signature")
+	return &request.Request{
+		HTTPRequest:  httpRequest,
+		HTTPResponse: &http.Response{Header: http.Header{"Date": []string{date}}},
+		Error:        awserr.New(code, "message", nil),
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	local := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	setClock(t, local)
+	serverDate := local.Add(time.Hour).Format(http.TimeFormat)
+
+	r := newRequest("ThrottlingException", serverDate)
+	CorrectHandler.Fn(r)
+	assert.Nil(t, r.Retryable)
+	assert.Equal(t, time.Duration(0), Offset())
+
+	SignHandler.Fn(r)
+	assert.NotEmpty(t, r.HTTPRequest.Header.Get("Authorization"))
+
+	r = newRequest("InvalidSignatureException", serverDate)
+	CorrectHandler.Fn(r)
+	assert.Equal(t, aws.Bool(true), r.Retryable)
+	assert.Equal(t, time.Hour, Offset())
+
+	r.LastSignedAt = local
+	SignHandler.Fn(r)
+	assert.Equal(t, local.Add(time.Hour), r.Time)
+	assert.True(t, r.LastSignedAt.IsZero())
+	assert.Empty(t, r.HTTPRequest.Header.Get("Authorization"))
+}
+
+func TestAddHandlers(t *testing.T) {
+	handlers := request.Handlers{}
+	handlers.Sign.PushBack(func(*request.Request) {})
+	AddHandlers(&handlers)
+	assert.Equal(t, 2, handlers.Sign.Len())
+	assert.Equal(t, 1, handlers.UnmarshalError.Len())
+}
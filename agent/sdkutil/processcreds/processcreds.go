@@ -0,0 +1,160 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processcreds provides AWS credentials printed by an external command,
+// in the format of the credential_process setting of the AWS CLI.
+package processcreds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+const (
+	// ProviderName is the name of the credentials provider
+	ProviderName = "ProcessProvider"
+
+	// outputVersion is the only supported version of the command output
+	outputVersion = 1
+
+	// expiryWindow expires the credentials before their expiration to avoid using them while they expire
+	expiryWindow = 5 * time.Minute
+)
+
+// processOutput is the json printed by the command
+type processOutput struct {
+	Version         int
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      *time.Time
+}
+
+// Provider retrieves credentials from the output of a command, the credentials are only kept in memory
+type Provider struct {
+	credentials.Expiry
+
+	// Command is run by the shell of the platform
+	Command string
+	Timeout time.Duration
+
+	// static is true when the command returned credentials without expiration
+	static bool
+}
+
+// NewCredentials returns the credentials of a command
+func NewCredentials(command string, timeout time.Duration) *credentials.Credentials {
+	return credentials.NewCredentials(&Provider{Command: command, Timeout: timeout})
+}
+
+var (
+	lock              sync.Mutex
+	configured        *credentials.Credentials
+	configuredCommand string
+)
+
+// Configured returns the credentials of the command configured in appconfig, nil when none is configured.
+// The credentials are shared so the command only runs when they expire.
+func Configured() *credentials.Credentials {
+	appConfig, err := appconfig.Config(false)
+	if err != nil || appConfig.Profile.CredentialProcess == "" {
+		return nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if configured == nil || configuredCommand != appConfig.Profile.CredentialProcess {
+		configuredCommand = appConfig.Profile.CredentialProcess
+		configured = NewCredentials(
+			appConfig.Profile.CredentialProcess,
+			time.Duration(appConfig.Profile.CredentialProcessTimeoutSeconds)*time.Second)
+	}
+	return configured
+}
+
+// shellCommand returns the command running a command line with the shell of the platform
+var shellCommand = func(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// IsExpired returns true when the credentials have to be retrieved again
+func (p *Provider) IsExpired() bool {
+	if p.static {
+		return false
+	}
+	return p.Expiry.IsExpired()
+}
+
+// Retrieve runs the command and parses the credentials it prints
+func (p *Provider) Retrieve() (credentials.Value, error) {
+	value := credentials.Value{ProviderName: ProviderName}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(ctx, p.Command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return value, fmt.Errorf("failed to start credential process: %v", err)
+	}
+
+	// children of the command may keep its output open after it is killed, so the wait is not awaited on timeout
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-ctx.Done():
+		return value, fmt.Errorf("credential process timed out after %v", p.Timeout)
+	case err := <-done:
+		if err != nil {
+			// the output is not part of the error since it may contain credentials
+			return value, fmt.Errorf("credential process failed: %v %v", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	var output processOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return value, fmt.Errorf("credential process printed invalid json: %v", err)
+	}
+	if output.Version != outputVersion {
+		return value, fmt.Errorf("credential process printed unsupported version %v, expected %v", output.Version, outputVersion)
+	}
+	if output.AccessKeyId == "" || output.SecretAccessKey == "" {
+		return value, fmt.Errorf("credential process printed no AccessKeyId or SecretAccessKey")
+	}
+
+	p.static = output.Expiration == nil
+	if output.Expiration != nil {
+		p.SetExpiration(*output.Expiration, expiryWindow)
+	}
+
+	value.AccessKeyID = output.AccessKeyId
+	value.SecretAccessKey = output.SecretAccessKey
+	value.SessionToken = output.SessionToken
+	return value, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package processcreds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetrieve(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	provider := &Provider{
+		Command: `echo '{"Version": 1, "AccessKeyId": "AKID", "SecretAccessKey": "SECRET", "SessionToken": "TOKEN", "Expiration": "` + expiration + `"}'`,
+		Timeout: 10 * time.Second,
+	}
+
+	value, err := provider.Retrieve()
+	assert.Nil(t, err)
+	assert.Equal(t, "AKID", value.AccessKeyID)
+	assert.Equal(t, "SECRET", value.SecretAccessKey)
+	assert.Equal(t, "TOKEN", value.SessionToken)
+	assert.Equal(t, ProviderName, value.ProviderName)
+	assert.False(t, provider.IsExpired())
+
+	provider.CurrentTime = func() time.Time { return time.Now().Add(56 * time.Minute) }
+	assert.True(t, provider.IsExpired())
+}
+
+func TestRetrieve_WithoutExpiration(t *testing.T) {
+	provider := &Provider{
+		Command: `echo '{"Version": 1, "AccessKeyId": "AKID", "SecretAccessKey": "SECRET"}'`,
+		Timeout: 10 * time.Second,
+	}
+
+	_, err := provider.Retrieve()
+	assert.Nil(t, err)
+	assert.False(t, provider.IsExpired())
+}
+
+func TestRetrieve_Errors(t *testing.T) {
+	for _, command := range []string{
+		`echo 'not json'`,
+		`echo '{"Version": 2, "AccessKeyId": "AKID", "SecretAccessKey": "SECRET"}'`,
+		`echo '{"Version": 1, "AccessKeyId": "AKID"}'`,
+		`echo 'vault unavailable' >&2; exit 1`,
+	} {
+		provider := &Provider{Command: command, Timeout: 10 * time.Second}
+		_, err := provider.Retrieve()
+		assert.NotNil(t, err, command)
+		assert.True(t, provider.IsExpired(), command)
+	}
+}
+
+func TestRetrieve_Timeout(t *testing.T) {
+	provider := &Provider{Command: "sleep 5", Timeout: 100 * time.Millisecond}
+	_, err := provider.Retrieve()
+	assert.EqualError(t, err, "credential process timed out after 100ms")
+}
+
+func TestRetrieve_ErrorDoesNotContainOutput(t *testing.T) {
+	provider := &Provider{Command: `echo '{"Version": 1, "SecretAccessKey": "SECRET"}'`, Timeout: 10 * time.Second}
+	_, err := provider.Retrieve()
+	assert.NotContains(t, err.Error(), "SECRET")
+}
@@ -20,12 +20,80 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/request"
 )
 
 type SsmRetryer struct {
 	client.DefaultRetryer
+
+	// BaseDelay is the delay before the first retry, before jitter, 1 second when not set
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries, not capped when not set
+	MaxDelay time.Duration
+}
+
+// Policy is the retry policy of the calls to an AWS service
+type Policy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var loadConfig = func() appconfig.RetryCfg {
+	config, err := appconfig.Config(false)
+	if err != nil {
+		config = appconfig.DefaultConfig()
+	}
+	return config.Retry
+}
+
+// PolicyFor returns the retry policy of a service, by its endpoint prefix such as ssm or ec2messages,
+// the overrides of the service in appconfig take precedence over the default policy
+func PolicyFor(service string) Policy {
+	config := loadConfig()
+	policy := config.RetryPolicyCfg
+	if override, ok := config.Services[service]; ok {
+		if override.MaxRetries > 0 {
+			policy.MaxRetries = override.MaxRetries
+		}
+		if override.BaseDelayMillis > 0 {
+			policy.BaseDelayMillis = override.BaseDelayMillis
+		}
+		if override.MaxDelayMillis > 0 {
+			policy.MaxDelayMillis = override.MaxDelayMillis
+		}
+	}
+	return Policy{
+		MaxRetries: policy.MaxRetries,
+		BaseDelay:  time.Duration(policy.BaseDelayMillis) * time.Millisecond,
+		MaxDelay:   time.Duration(policy.MaxDelayMillis) * time.Millisecond,
+	}
+}
+
+// New returns the retryer following the retry policy of a service
+func New(service string) SsmRetryer {
+	policy := PolicyFor(service)
+	r := SsmRetryer{
+		BaseDelay: policy.BaseDelay,
+		MaxDelay:  policy.MaxDelay,
+	}
+	r.NumMaxRetries = policy.MaxRetries
+	return r
+}
+
+// PolicyHandler applies the retry policy of the service of a request to the request,
+// the requests of clients with their own retryer keep it
+var PolicyHandler = request.NamedHandler{Name: "retryer.PolicyHandler", Fn: func(r *request.Request) {
+	if _, ok := r.Retryer.(SsmRetryer); ok {
+		r.Retryer = New(r.ClientInfo.ServiceName)
+	}
+}}
+
+// AddHandlers adds the retry policy handler to the handlers of a session or a service client
+func AddHandlers(handlers *request.Handlers) {
+	handlers.Validate.PushBackNamed(PolicyHandler)
 }
 
 // RetryRules returns the delay duration before retrying this request again
@@ -36,8 +104,15 @@ func (s SsmRetryer) RetryRules(r *request.Request) time.Duration {
 		return time.Duration(100 * time.Millisecond)
 	}
 
-	// retry after a > 1 sec timeout, increasing exponentially with each retry
-	rand.Seed(time.Now().UnixNano())
-	delay := int(math.Pow(2, float64(r.RetryCount))) * (rand.Intn(500) + 1000)
-	return time.Duration(delay) * time.Millisecond
+	// retry after a > base delay timeout, increasing exponentially with each retry
+	base := s.BaseDelay
+	if base <= 0 {
+		base = time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base)/2 + 1))
+	delay := time.Duration(math.Pow(2, float64(r.RetryCount))) * (base + jitter)
+	if s.MaxDelay > 0 && delay > s.MaxDelay {
+		delay = s.MaxDelay
+	}
+	return delay
 }
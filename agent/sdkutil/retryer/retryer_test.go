@@ -0,0 +1,72 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retryer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubRetryConfig(t *testing.T) {
+	loadConfigOrig := loadConfig
+	t.Cleanup(func() { loadConfig = loadConfigOrig })
+	loadConfig = func() appconfig.RetryCfg {
+		return appconfig.RetryCfg{
+			RetryPolicyCfg: appconfig.RetryPolicyCfg{MaxRetries: 3, BaseDelayMillis: 1000, MaxDelayMillis: 5000},
+			Services: map[string]appconfig.RetryPolicyCfg{
+				"logs": {MaxRetries: 5},
+				"s3":   {BaseDelayMillis: 200, MaxDelayMillis: 800},
+			},
+		}
+	}
+}
+
+func TestPolicyFor(t *testing.T) {
+	stubRetryConfig(t)
+
+	assert.Equal(t, Policy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}, PolicyFor("ssm"))
+	assert.Equal(t, Policy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}, PolicyFor("logs"))
+	assert.Equal(t, Policy{MaxRetries: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 800 * time.Millisecond}, PolicyFor("s3"))
+}
+
+func TestRetryRulesFollowPolicy(t *testing.T) {
+	stubRetryConfig(t)
+	r := New("s3")
+	assert.Equal(t, 3, r.MaxRetries())
+
+	for retryCount, min := range []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond} {
+		delay := r.RetryRules(&request.Request{Operation: &request.Operation{Name: "GetObject"}, RetryCount: retryCount})
+		assert.True(t, delay >= min && delay <= 800*time.Millisecond, "delay %v of retry %v", delay, retryCount)
+	}
+}
+
+func TestPolicyHandler(t *testing.T) {
+	stubRetryConfig(t)
+
+	r := &request.Request{ClientInfo: metadata.ClientInfo{ServiceName: "logs"}, Retryer: New("")}
+	PolicyHandler.Fn(r)
+	assert.Equal(t, 5, r.MaxRetries())
+
+	// clients with their own retryer keep it
+	r = &request.Request{ClientInfo: metadata.ClientInfo{ServiceName: "logs"}, Retryer: client.DefaultRetryer{NumMaxRetries: 1}}
+	PolicyHandler.Fn(r)
+	assert.Equal(t, 1, r.MaxRetries())
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package scopedcreds mints the scoped down temporary credentials of the scripts,
+// assuming a role with a session policy limiting its permissions.
+package scopedcreds
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+// maxSessionNameLength is the maximum length of the role session names of STS
+const maxSessionNameLength = 64
+
+// invalidSessionNameCharacters matches the characters STS does not accept in the role session names
+var invalidSessionNameCharacters = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// Request is the role and the session policy of the credentials requested by a document
+type Request struct {
+	RoleArn string
+	// SessionPolicy is the json policy intersected with the policies of the role, none when empty
+	SessionPolicy string
+	// DurationSeconds is the lifetime of the credentials, the default of STS when 0
+	DurationSeconds int64
+}
+
+// Credentials are the temporary credentials minted for a script
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// newClient returns the STS client signing the requests with the credentials of the agent
+var newClient = func() (stsiface.STSAPI, error) {
+	appConfig, _ := appconfig.Config(false)
+	stsSession, err := session.NewSession(sdkutil.AwsConfig())
+	if err != nil {
+		return nil, fmt.Errorf("Error creating new aws sdk session: %s", err)
+	}
+	stsSession.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	return sts.New(stsSession), nil
+}
+
+// instanceID returns the id of the instance, which names the role sessions
+var instanceID = platform.InstanceID
+
+// Mint assumes the role of the request with the credentials of the agent, in a session named after the instance.
+func Mint(request Request) (credentials Credentials, err error) {
+	client, err := newClient()
+	if err != nil {
+		return
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(request.RoleArn),
+		RoleSessionName: aws.String(sessionName()),
+	}
+	if request.SessionPolicy != "" {
+		input.Policy = aws.String(request.SessionPolicy)
+	}
+	if request.DurationSeconds != 0 {
+		input.DurationSeconds = aws.Int64(request.DurationSeconds)
+	}
+	output, err := client.AssumeRole(input)
+	if err != nil {
+		return credentials, fmt.Errorf("failed to assume role %v: %v", request.RoleArn, err)
+	}
+	return Credentials{
+		AccessKeyID:     aws.StringValue(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(output.Credentials.SessionToken),
+		Expiration:      aws.TimeValue(output.Credentials.Expiration),
+	}, nil
+}
+
+// sessionName returns the role session name identifying the instance in CloudTrail.
+func sessionName() string {
+	name := "ssm-agent"
+	if id, err := instanceID(); err == nil && id != "" {
+		name = "ssm-" + invalidSessionNameCharacters.ReplaceAllString(id, "-")
+	}
+	if len(name) > maxSessionNameLength {
+		name = name[:maxSessionNameLength]
+	}
+	return name
+}
+
+// Environment returns the environment variables exposing the credentials and the shared credentials file
+// at credentialsPath to the AWS SDKs and CLI.
+func Environment(credentials Credentials, credentialsPath string) []string {
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + credentials.SecretAccessKey,
+		"AWS_SESSION_TOKEN=" + credentials.SessionToken,
+		"AWS_SHARED_CREDENTIALS_FILE=" + credentialsPath,
+		"AWS_PROFILE=default",
+	}
+}
+
+// WriteFile writes the credentials as the default profile of a shared credentials file only readable by its owner.
+func WriteFile(path string, credentials Credentials) error {
+	content := fmt.Sprintf("[default]\naws_access_key_id = %v\naws_secret_access_key = %v\naws_session_token = %v\n",
+		credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken)
+	if _, err := fileutil.WriteIntoFileWithPermissions(path, content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to write the credentials file %v: %v", path, err)
+	}
+	return nil
+}
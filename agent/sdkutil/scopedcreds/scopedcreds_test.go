@@ -0,0 +1,114 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scopedcreds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// stsStub records the AssumeRole requests and returns the credentials of output
+type stsStub struct {
+	stsiface.STSAPI
+	input  *sts.AssumeRoleInput
+	output *sts.AssumeRoleOutput
+	err    error
+}
+
+func (s *stsStub) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	s.input = input
+	return s.output, s.err
+}
+
+func stubClient(stub *stsStub) func() {
+	newClientOrig, instanceIDOrig := newClient, instanceID
+	newClient = func() (stsiface.STSAPI, error) { return stub, nil }
+	instanceID = func() (string, error) { return "i-0123456789abcdef0", nil }
+	return func() { newClient, instanceID = newClientOrig, instanceIDOrig }
+}
+
+func TestMint(t *testing.T) {
+	expiration := time.Now().Add(time.Hour)
+	stub := &stsStub{output: &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("AKID"),
+		SecretAccessKey: aws.String("SECRET"),
+		SessionToken:    aws.String("TOKEN"),
+		Expiration:      aws.Time(expiration),
+	}}}
+	defer stubClient(stub)()
+
+	credentials, err := Mint(Request{RoleArn: "arn:aws:iam::123456789012:role/scripts", SessionPolicy: `{"Version":"2012-10-17"}`, DurationSeconds: 900})
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN", Expiration: expiration}, credentials)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/scripts", aws.StringValue(stub.input.RoleArn))
+	assert.Equal(t, "ssm-i-0123456789abcdef0", aws.StringValue(stub.input.RoleSessionName))
+	assert.Equal(t, `{"Version":"2012-10-17"}`, aws.StringValue(stub.input.Policy))
+	assert.Equal(t, int64(900), aws.Int64Value(stub.input.DurationSeconds))
+
+	_, err = Mint(Request{RoleArn: "arn:aws:iam::123456789012:role/scripts"})
+	assert.NoError(t, err)
+	assert.Nil(t, stub.input.Policy)
+	assert.Nil(t, stub.input.DurationSeconds)
+}
+
+func TestMintError(t *testing.T) {
+	defer stubClient(&stsStub{err: fmt.Errorf("AccessDenied")})()
+
+	_, err := Mint(Request{RoleArn: "arn:aws:iam::123456789012:role/scripts"})
+	assert.EqualError(t, err, "failed to assume role arn:aws:iam::123456789012:role/scripts: AccessDenied")
+}
+
+func TestSessionName(t *testing.T) {
+	defer stubClient(&stsStub{})()
+
+	instanceID = func() (string, error) { return "mi-0123456789abcdef0:" + strings.Repeat("x", 64), nil }
+	name := sessionName()
+	assert.Equal(t, maxSessionNameLength, len(name))
+	assert.True(t, strings.HasPrefix(name, "ssm-mi-0123456789abcdef0-x"))
+
+	instanceID = func() (string, error) { return "", fmt.Errorf("no instance id") }
+	assert.Equal(t, "ssm-agent", sessionName())
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scopedcreds")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials")
+
+	credentials := Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "TOKEN"}
+	assert.NoError(t, WriteFile(path, credentials))
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "[default]\naws_access_key_id = AKID\naws_secret_access_key = SECRET\naws_session_token = TOKEN\n", string(content))
+
+	assert.Equal(t, []string{
+		"AWS_ACCESS_KEY_ID=AKID",
+		"AWS_SECRET_ACCESS_KEY=SECRET",
+		"AWS_SESSION_TOKEN=TOKEN",
+		"AWS_SHARED_CREDENTIALS_FILE=" + path,
+		"AWS_PROFILE=default",
+	}, Environment(credentials, path))
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package securityevents writes the session and command lifecycle events of the audit log in the CEF (ArcSight)
+// or LEEF (QRadar) format, one event per line, to a file or to the collector of a SIEM.
+package securityevents
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// Vendor and product of the events
+const (
+	vendor  = "Amazon"
+	product = "SSM Agent"
+)
+
+// eventNames are the names of the events, by audit event type
+var eventNames = map[string]string{
+	audit.SessionStarted:             "Session started",
+	audit.SessionEnded:               "Session ended",
+	audit.DocumentExecutionRequested: "Document execution requested",
+	audit.DocumentExecutionCanceled:  "Document execution canceled",
+	audit.DocumentExecutionCompleted: "Document execution completed",
+}
+
+// isLifecycleEvent returns true for the session and command lifecycle events
+func isLifecycleEvent(event audit.Event) bool {
+	_, found := eventNames[event.Type]
+	return found
+}
+
+// severity returns the severity of the event from 0 to 10, the failed executions are high and the canceled ones medium
+func severity(event audit.Event) int {
+	switch {
+	case event.Status == string(contracts.ResultStatusFailed),
+		event.Status == string(contracts.ResultStatusTimedOut):
+		return 7
+	case event.Type == audit.DocumentExecutionCanceled:
+		return 5
+	}
+	return 3
+}
+
+// eventTime returns the time of the event in milliseconds since the epoch
+func eventTime(event audit.Event) string {
+	recorded, err := time.Parse(time.RFC3339Nano, event.Time)
+	if err != nil {
+		recorded = time.Now()
+	}
+	return strconv.FormatInt(recorded.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// field is an extension of an event
+type field struct {
+	key   string
+	value string
+}
+
+// format returns the line of the event in the format of the config
+func format(eventFormat string, hostname string, event audit.Event) string {
+	if eventFormat == appconfig.SecurityEventsFormatLEEF {
+		return formatLEEF(hostname, event)
+	}
+	return formatCEF(hostname, event)
+}
+
+// formatCEF returns the CEF line of the event, the custom strings carry the ids of the session and of the command
+func formatCEF(hostname string, event audit.Event) string {
+	header := []string{
+		"CEF:0",
+		cefHeaderEscaper.Replace(vendor),
+		cefHeaderEscaper.Replace(product),
+		cefHeaderEscaper.Replace(version.Version),
+		cefHeaderEscaper.Replace(event.Type),
+		cefHeaderEscaper.Replace(eventNames[event.Type]),
+		strconv.Itoa(severity(event)),
+	}
+	fields := []field{
+		{"rt", eventTime(event)},
+		{"dvchost", hostname},
+		{"suser", event.RunAsUser},
+		{"outcome", event.Status},
+		{"msg", event.Detail},
+	}
+	for i, custom := range []field{
+		{"sessionId", event.SessionID},
+		{"commandId", event.CommandID},
+		{"documentName", event.DocumentName},
+		{"messageId", event.MessageID},
+	} {
+		if custom.value != "" {
+			label := "cs" + strconv.Itoa(i+1)
+			fields = append(fields, field{label + "Label", custom.key}, field{label, custom.value})
+		}
+	}
+	var extensions []string
+	for _, f := range fields {
+		if f.value != "" {
+			extensions = append(extensions, f.key+"="+cefExtensionEscaper.Replace(f.value))
+		}
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(extensions, " ")
+}
+
+// formatLEEF returns the LEEF 1.0 line of the event, its attributes are separated by tabs
+func formatLEEF(hostname string, event audit.Event) string {
+	header := []string{
+		"LEEF:1.0",
+		leefHeaderEscaper.Replace(vendor),
+		leefHeaderEscaper.Replace(product),
+		leefHeaderEscaper.Replace(version.Version),
+		leefHeaderEscaper.Replace(event.Type),
+	}
+	fields := []field{
+		{"devTime", eventTime(event)},
+		{"sev", strconv.Itoa(severity(event))},
+		{"cat", eventNames[event.Type]},
+		{"identHostName", hostname},
+		{"usrName", event.RunAsUser},
+		{"sessionId", event.SessionID},
+		{"commandId", event.CommandID},
+		{"documentName", event.DocumentName},
+		{"messageId", event.MessageID},
+		{"status", event.Status},
+		{"detail", event.Detail},
+	}
+	var attributes []string
+	for _, f := range fields {
+		if f.value != "" {
+			attributes = append(attributes, f.key+"="+leefValueEscaper.Replace(f.value))
+		}
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(attributes, "\t")
+}
+
+// Escapers of the fields, CEF escapes its separators and LEEF 1.0 has no escaping so its separators are replaced
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r", `\r`, "\n", `\n`)
+	leefHeaderEscaper   = strings.NewReplacer("|", " ", "\t", " ", "\r", " ", "\n", " ")
+	leefValueEscaper    = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+)
@@ -0,0 +1,61 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package securityevents
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatCEF(t *testing.T) {
+	event := audit.Event{
+		Time:         "2019-03-01T10:00:00Z",
+		Type:         audit.DocumentExecutionCompleted,
+		CommandID:    "command-id",
+		DocumentName: "AWS-RunShellScript",
+		Status:       "Failed",
+		Detail:       "exit=1\nsee the output",
+	}
+	assert.Equal(t,
+		"CEF:0|Amazon|SSM Agent|"+version.Version+"|DocumentExecutionCompleted|Document execution completed|7|"+
+			`rt=1551434400000 dvchost=host outcome=Failed msg=exit\=1\nsee the output `+
+			"cs2Label=commandId cs2=command-id cs3Label=documentName cs3=AWS-RunShellScript",
+		format(appconfig.SecurityEventsFormatCEF, "host", event))
+}
+
+func TestFormatLEEF(t *testing.T) {
+	event := audit.Event{
+		Time:      "2019-03-01T10:00:00Z",
+		Type:      audit.SessionStarted,
+		SessionID: "session-id",
+		RunAsUser: "ssm-user",
+		Detail:    "a\tb",
+	}
+	assert.Equal(t,
+		"LEEF:1.0|Amazon|SSM Agent|"+version.Version+"|SessionStarted|"+
+			"devTime=1551434400000\tsev=3\tcat=Session started\tidentHostName=host\tusrName=ssm-user\tsessionId=session-id\tdetail=a b",
+		format(appconfig.SecurityEventsFormatLEEF, "host", event))
+}
+
+func TestIsLifecycleEvent(t *testing.T) {
+	assert.True(t, isLifecycleEvent(audit.Event{Type: audit.SessionEnded}))
+	assert.True(t, isLifecycleEvent(audit.Event{Type: audit.DocumentExecutionCanceled}))
+	assert.False(t, isLifecycleEvent(audit.Event{Type: audit.AgentUpdate}))
+	assert.Equal(t, 5, severity(audit.Event{Type: audit.DocumentExecutionCanceled}))
+}
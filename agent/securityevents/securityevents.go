@@ -0,0 +1,163 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package securityevents
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	name = "SecurityEvents"
+
+	// FileName is the name of the file of the events in the log directory of the agent
+	FileName = "security-events.log"
+
+	// queueSize is the number of events buffered for the output, events are dropped when it is full
+	queueSize = 1024
+
+	// dialTimeout is the timeout of the connection to the collector
+	dialTimeout = 10 * time.Second
+
+	// writeTimeout is the timeout of an event sent to the collector
+	writeTimeout = 10 * time.Second
+)
+
+// defaultPath is the path of the file of the events when none is configured
+var defaultPath = filepath.Join(log.DefaultLogDir, FileName)
+
+// Writer is the core module writing the session and command lifecycle events in the CEF or LEEF format
+type Writer struct {
+	context  context.T
+	config   appconfig.SecurityEventsCfg
+	path     string
+	hostname string
+	lines    chan string
+	output   io.WriteCloser
+	stop     chan bool
+	done     chan bool
+}
+
+// NewWriter creates the writer, nil when no format is configured in appconfig
+func NewWriter(context context.T) *Writer {
+	config := context.AppConfig().SecurityEvents
+	if config.Format == "" {
+		return nil
+	}
+	path := config.Path
+	if path == "" {
+		path = defaultPath
+	}
+	hostname, _ := os.Hostname()
+	return &Writer{
+		context:  context.With("[" + name + "]"),
+		config:   config,
+		path:     path,
+		hostname: hostname,
+		lines:    make(chan string, queueSize),
+		stop:     make(chan bool),
+		done:     make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (w *Writer) ModuleName() string {
+	return name
+}
+
+// ModuleExecute observes the audit events and starts writing the lifecycle events
+func (w *Writer) ModuleExecute(context context.T) (err error) {
+	if w.config.Address != "" {
+		w.context.Log().Infof("Sending the %v security events to the %v collector %v", w.config.Format, w.config.Transport, w.config.Address)
+	} else {
+		w.context.Log().Infof("Writing the %v security events to %v", w.config.Format, w.path)
+	}
+	audit.SetObserver(name, func(event audit.Event) {
+		if !isLifecycleEvent(event) {
+			return
+		}
+		select {
+		case w.lines <- format(w.config.Format, w.hostname, event):
+		default:
+		}
+	})
+	go w.run()
+	return nil
+}
+
+// ModuleRequestStop stops observing the events, writes the buffered events and closes the output
+func (w *Writer) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	audit.SetObserver(name, nil)
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+// run writes the events as they are recorded
+func (w *Writer) run() {
+	defer close(w.done)
+	for {
+		select {
+		case line := <-w.lines:
+			w.write(line)
+		case <-w.stop:
+			for len(w.lines) > 0 {
+				w.write(<-w.lines)
+			}
+			if w.output != nil {
+				w.output.Close()
+			}
+			return
+		}
+	}
+}
+
+// write writes an event, the output is opened again for the next event when it fails
+func (w *Writer) write(line string) {
+	log := w.context.Log()
+	if w.output == nil {
+		output, err := w.open()
+		if err != nil {
+			log.Warnf("Failed to open the security events output, dropping an event: %v", err)
+			return
+		}
+		w.output = output
+	}
+	if conn, ok := w.output.(net.Conn); ok {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	if _, err := io.WriteString(w.output, line+"\n"); err != nil {
+		log.Warnf("Failed to write a security event: %v", err)
+		w.output.Close()
+		w.output = nil
+	}
+}
+
+// open connects to the collector, or opens the file of the events
+func (w *Writer) open() (io.WriteCloser, error) {
+	if w.config.Address != "" {
+		return net.DialTimeout(w.config.Transport, w.config.Address, dialTimeout)
+	}
+	return os.OpenFile(w.path, appconfig.FileFlagsCreateOrAppend, appconfig.ReadWriteAccess)
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package securityevents
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithFormat(format string, path string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.SecurityEvents.Format = format
+	config.SecurityEvents.Path = path
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func TestNewWriter_Disabled(t *testing.T) {
+	assert.Nil(t, NewWriter(mockContextWithFormat("", "")))
+}
+
+func TestWriter_WritesLifecycleEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "securityevents")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, FileName)
+
+	ctx := mockContextWithFormat(appconfig.SecurityEventsFormatCEF, path)
+	writer := NewWriter(ctx)
+	assert.NotNil(t, writer)
+	assert.Equal(t, name, writer.ModuleName())
+	assert.NoError(t, writer.ModuleExecute(ctx))
+
+	audit.Record(audit.Event{Type: audit.SessionStarted, SessionID: "session-id"})
+	audit.Record(audit.Event{Type: audit.AgentUpdate, Status: "Success"})
+	audit.Record(audit.Event{Type: audit.SessionEnded, SessionID: "session-id"})
+	assert.NoError(t, writer.ModuleRequestStop(contracts.StopTypeSoftStop))
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "|SessionStarted|Session started|3|")
+	assert.Contains(t, lines[1], "|SessionEnded|Session ended|3|")
+}
@@ -0,0 +1,223 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package selfmonitor periodically checks the goroutines, open file descriptors and heap of the agent and warns
+// when they exceed their thresholds, long-running agents occasionally leak them after network churn. The agent can
+// optionally be restarted when a threshold stays exceeded.
+package selfmonitor
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+)
+
+const name = "SelfMonitor"
+
+// resources checked by the self-monitor, they label the process resources metric
+const (
+	goroutinesResource = "goroutines"
+	openFilesResource  = "open_files"
+	heapResource       = "heap_bytes"
+)
+
+// descriptions are the descriptions of the resources in the logs, by resource
+var descriptions = map[string]string{
+	goroutinesResource: "goroutines",
+	openFilesResource:  "open files",
+	heapResource:       "heap bytes",
+}
+
+// usage is the resource usage of the agent process
+type usage struct {
+	goroutines  int
+	openFiles   int
+	heapBytes   uint64
+	heapObjects uint64
+}
+
+// takeUsage returns the resource usage of the agent process, stubbed in tests
+var takeUsage = processUsage
+
+// restart is closed when the agent should exit for the service manager to restart it
+var restart = struct {
+	sync.Mutex
+	requested chan bool
+	closed    bool
+}{requested: make(chan bool)}
+
+// RestartRequested returns a channel closed when a threshold stayed exceeded and the agent should exit
+// with an error for the service manager to restart it
+func RestartRequested() <-chan bool {
+	restart.Lock()
+	defer restart.Unlock()
+	return restart.requested
+}
+
+func requestRestart() {
+	restart.Lock()
+	defer restart.Unlock()
+	if !restart.closed {
+		restart.closed = true
+		close(restart.requested)
+	}
+}
+
+// SelfMonitor is the core module checking the resource usage of the agent at every check interval
+type SelfMonitor struct {
+	context            context.T
+	interval           time.Duration
+	thresholds         map[string]int64
+	consecutiveChecks  int
+	restartOnThreshold bool
+	exceeded           map[string]int
+	stop               chan bool
+	done               chan bool
+}
+
+// NewSelfMonitor creates the self-monitor, nil when the self-monitor is not enabled in appconfig
+func NewSelfMonitor(context context.T) *SelfMonitor {
+	config := context.AppConfig().SelfMonitor
+	if !config.Enabled {
+		return nil
+	}
+	return &SelfMonitor{
+		context:  context.With("[" + name + "]"),
+		interval: time.Duration(config.CheckIntervalSeconds) * time.Second,
+		thresholds: map[string]int64{
+			goroutinesResource: int64(config.MaxGoroutines),
+			openFilesResource:  int64(config.MaxOpenFiles),
+			heapResource:       int64(config.MaxHeapMB) * 1024 * 1024,
+		},
+		consecutiveChecks:  config.ConsecutiveChecks,
+		restartOnThreshold: config.RestartOnThreshold,
+		exceeded:           make(map[string]int),
+		stop:               make(chan bool),
+		done:               make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (m *SelfMonitor) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts checking the resource usage of the agent
+func (m *SelfMonitor) ModuleExecute(context context.T) (err error) {
+	m.context.Log().Infof("Checking the goroutines, open files and heap of the agent every %v", m.interval)
+	go m.run()
+	return nil
+}
+
+// ModuleRequestStop stops the self-monitor
+func (m *SelfMonitor) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	close(m.stop)
+	<-m.done
+	return nil
+}
+
+func (m *SelfMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// check records the resource usage of the agent, warns about the resources above their threshold and
+// requests a restart when one of them stayed above its threshold for the consecutive checks
+func (m *SelfMonitor) check() {
+	log := m.context.Log()
+	u := takeUsage()
+	log.Debugf("The agent has %v goroutines, %v open files and a heap of %v bytes in %v objects",
+		u.goroutines, u.openFiles, u.heapBytes, u.heapObjects)
+
+	values := map[string]int64{
+		goroutinesResource: int64(u.goroutines),
+		heapResource:       int64(u.heapBytes),
+	}
+	// the open files are not counted on every platform
+	if u.openFiles >= 0 {
+		values[openFilesResource] = int64(u.openFiles)
+	}
+	restartNeeded := false
+	for _, resource := range []string{goroutinesResource, openFilesResource, heapResource} {
+		value, found := values[resource]
+		if !found {
+			continue
+		}
+		metrics.ProcessResources.Set(resource, value)
+		if m.exceeds(log, resource, value) {
+			restartNeeded = true
+		}
+	}
+	if restartNeeded && m.restartOnThreshold {
+		log.Errorf("The resource usage of the agent stayed above its thresholds for %v checks, restarting the agent", m.consecutiveChecks)
+		log.Warnf("Goroutines of the agent:\n%v", goroutineDump())
+		requestRestart()
+	}
+}
+
+// exceeds warns when the resource is above its threshold and returns whether it was above it for the consecutive checks
+func (m *SelfMonitor) exceeds(log log.T, resource string, value int64) bool {
+	threshold := m.thresholds[resource]
+	if threshold <= 0 || value <= threshold {
+		if m.exceeded[resource] > 0 {
+			log.Infof("The %v of the agent are back under their threshold of %v", descriptions[resource], threshold)
+		}
+		m.exceeded[resource] = 0
+		return false
+	}
+	m.exceeded[resource]++
+	log.Warnf("The %v of the agent are at %v, above their threshold of %v for %v consecutive checks",
+		descriptions[resource], value, threshold, m.exceeded[resource])
+	return m.exceeded[resource] >= m.consecutiveChecks
+}
+
+// processUsage returns the resource usage of the agent process, the open files are -1 when they cannot be counted
+func processUsage() (u usage) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	u.goroutines = runtime.NumGoroutine()
+	u.heapBytes = memStats.HeapAlloc
+	u.heapObjects = memStats.HeapObjects
+	openFiles, err := countOpenFiles()
+	if err != nil {
+		openFiles = -1
+	}
+	u.openFiles = openFiles
+	return u
+}
+
+// goroutineDump returns the stacks of the goroutines grouped by stack, which points at the leaking code
+func goroutineDump() string {
+	var buf bytes.Buffer
+	if profile := pprof.Lookup("goroutine"); profile != nil {
+		profile.WriteTo(&buf, 1)
+	}
+	return buf.String()
+}
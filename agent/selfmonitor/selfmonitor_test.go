@@ -0,0 +1,142 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package selfmonitor
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithSelfMonitor(enabled bool, restartOnThreshold bool) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.SelfMonitor.Enabled = enabled
+	config.SelfMonitor.MaxGoroutines = 100
+	config.SelfMonitor.MaxOpenFiles = 50
+	config.SelfMonitor.MaxHeapMB = 0
+	config.SelfMonitor.ConsecutiveChecks = 2
+	config.SelfMonitor.RestartOnThreshold = restartOnThreshold
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+// stubUsage makes the self-checks return the usage and resets the requested restart
+func stubUsage(t *testing.T, u *usage) {
+	original := takeUsage
+	t.Cleanup(func() { takeUsage = original })
+	takeUsage = func() usage { return *u }
+	restart.Lock()
+	defer restart.Unlock()
+	restart.requested = make(chan bool)
+	restart.closed = false
+}
+
+func restartRequested() bool {
+	select {
+	case <-RestartRequested():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestNewSelfMonitor_Disabled(t *testing.T) {
+	assert.Nil(t, NewSelfMonitor(mockContextWithSelfMonitor(false, true)))
+}
+
+func TestSelfMonitor_RecordsUsage(t *testing.T) {
+	u := usage{goroutines: 42, openFiles: -1, heapBytes: 4096}
+	stubUsage(t, &u)
+	m := NewSelfMonitor(mockContextWithSelfMonitor(true, false))
+	assert.NotNil(t, m)
+	assert.Equal(t, name, m.ModuleName())
+
+	metrics.ProcessResources.Set(openFilesResource, 7)
+	m.check()
+	assert.Equal(t, int64(42), metrics.ProcessResources.Value(goroutinesResource))
+	assert.Equal(t, int64(4096), metrics.ProcessResources.Value(heapResource))
+	// the open files are not recorded when they cannot be counted
+	assert.Equal(t, int64(7), metrics.ProcessResources.Value(openFilesResource))
+}
+
+func TestSelfMonitor_RestartsAfterConsecutiveChecks(t *testing.T) {
+	u := usage{goroutines: 101, openFiles: 10}
+	stubUsage(t, &u)
+	m := NewSelfMonitor(mockContextWithSelfMonitor(true, true))
+
+	m.check()
+	assert.False(t, restartRequested())
+
+	// a check under the threshold resets the consecutive checks
+	u.goroutines = 100
+	m.check()
+	u.goroutines = 101
+	m.check()
+	assert.False(t, restartRequested())
+
+	m.check()
+	assert.True(t, restartRequested())
+	// the restart is only requested once
+	m.check()
+	assert.True(t, restartRequested())
+}
+
+func TestSelfMonitor_WarnsWithoutRestart(t *testing.T) {
+	u := usage{goroutines: 10, openFiles: 51}
+	stubUsage(t, &u)
+	m := NewSelfMonitor(mockContextWithSelfMonitor(true, false))
+
+	for i := 0; i < 3; i++ {
+		m.check()
+	}
+	assert.Equal(t, 3, m.exceeded[openFilesResource])
+	assert.Equal(t, 0, m.exceeded[goroutinesResource])
+	assert.False(t, restartRequested())
+}
+
+func TestSelfMonitor_NoThreshold(t *testing.T) {
+	u := usage{goroutines: 10, openFiles: 10, heapBytes: 1 << 40}
+	stubUsage(t, &u)
+	m := NewSelfMonitor(mockContextWithSelfMonitor(true, true))
+
+	for i := 0; i < 3; i++ {
+		m.check()
+	}
+	assert.False(t, restartRequested())
+}
+
+func TestCountOpenFiles(t *testing.T) {
+	before, err := countOpenFiles()
+	assert.NoError(t, err)
+
+	file, err := ioutil.TempFile("", "selfmonitor")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	after, err := countOpenFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, before+1, after)
+}
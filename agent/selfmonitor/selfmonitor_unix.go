@@ -0,0 +1,42 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package selfmonitor
+
+import (
+	"os"
+	"runtime"
+)
+
+// countOpenFiles returns the number of open file descriptors of the process
+func countOpenFiles() (int, error) {
+	fdDirectory := "/dev/fd"
+	if runtime.GOOS == "linux" {
+		fdDirectory = "/proc/self/fd"
+	}
+	dir, err := os.Open(fdDirectory)
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	// the directory being listed is itself an open file descriptor
+	return len(names) - 1, nil
+}
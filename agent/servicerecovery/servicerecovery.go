@@ -0,0 +1,80 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package servicerecovery applies the restart policy of the Recovery config to the agent service, as the
+// failure actions of the Windows service and as a drop-in of the systemd unit on Linux. The installers apply it
+// with the -configure-recovery flag of the agent so that every package gets the same policy.
+package servicerecovery
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	// windowsServiceName is the name of the agent service on Windows
+	windowsServiceName = "AmazonSSMAgent"
+	// rebootMessage is the message logged by Windows before the reboot of a failure action
+	rebootMessage = "The Amazon SSM Agent failed repeatedly, restarting the instance"
+)
+
+// dropInTemplate is the systemd drop-in of the agent unit, the start rate limit only applies with the reboot so that
+// systemd keeps restarting the agent otherwise, like the Windows service manager does
+var dropInTemplate = template.Must(template.New("dropIn").Parse(`# Generated by amazon-ssm-agent -configure-recovery from the Recovery config, changes are overwritten
+[Unit]
+{{- if .RebootOnRepeatedFailure}}
+StartLimitIntervalSec={{.ResetPeriodSeconds}}
+StartLimitBurst={{.FailureThreshold}}
+StartLimitAction=reboot
+{{- else}}
+StartLimitIntervalSec=0
+{{- end}}
+
+[Service]
+Restart=on-failure
+RestartSec={{.RestartDelaySeconds}}
+`))
+
+// dropIn returns the systemd drop-in of the restart policy
+func dropIn(cfg appconfig.RecoveryCfg) string {
+	var buffer bytes.Buffer
+	dropInTemplate.Execute(&buffer, cfg)
+	return buffer.String()
+}
+
+// failureArguments returns the sc.exe arguments of the failure actions of the agent service, the service manager
+// repeats the last action for the failures past the list
+func failureArguments(cfg appconfig.RecoveryCfg) []string {
+	delay := strconv.Itoa(cfg.RestartDelaySeconds * 1000)
+	actions := []string{"restart", delay}
+	arguments := []string{"failure", windowsServiceName, "reset=", strconv.Itoa(cfg.ResetPeriodSeconds)}
+	if cfg.RebootOnRepeatedFailure {
+		for i := 1; i < cfg.FailureThreshold; i++ {
+			actions = append(actions, "restart", delay)
+		}
+		actions = append(actions, "reboot", delay)
+		arguments = append(arguments, "reboot=", rebootMessage)
+	}
+	return append(arguments, "actions=", strings.Join(actions, "/"))
+}
+
+// failureFlagArguments returns the sc.exe arguments running the failure actions when the agent stops with an error,
+// not only when it crashes
+func failureFlagArguments() []string {
+	return []string{"failureflag", windowsServiceName, "1"}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build linux
+// +build linux
+
+package servicerecovery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// systemdRuntimeDir only exists when systemd is the init system
+const systemdRuntimeDir = "/run/systemd/system"
+
+// dropInDir is the drop-in folder of the agent unit, it is stubbed in the tests
+var dropInDir = "/etc/systemd/system/amazon-ssm-agent.service.d"
+
+// dropInFile is the name of the drop-in of the restart policy
+const dropInFile = "recovery.conf"
+
+// execCommand runs systemctl, it is stubbed in the tests
+var execCommand = exec.Command
+
+// Configure writes the systemd drop-in of the restart policy and reloads the units, it does nothing without systemd
+func Configure(log log.T, cfg appconfig.RecoveryCfg) error {
+	if !fileutil.Exists(systemdRuntimeDir) {
+		log.Infof("systemd is not running, the restart policy is not applied")
+		return nil
+	}
+	if err := os.MkdirAll(dropInDir, appconfig.ReadWriteExecuteAccess); err != nil {
+		return fmt.Errorf("failed to create %v: %v", dropInDir, err)
+	}
+	path := filepath.Join(dropInDir, dropInFile)
+	if err := ioutil.WriteFile(path, []byte(dropIn(cfg)), appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to write %v: %v", path, err)
+	}
+	if output, err := execCommand("systemctl", "daemon-reload").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to reload the systemd units: %v, %s", err, output)
+	}
+	log.Infof("Applied the restart policy to the agent unit with %v", path)
+	return nil
+}
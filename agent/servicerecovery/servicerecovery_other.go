@@ -0,0 +1,31 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package servicerecovery
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Configure returns an error, the restart policy is set by the launchd job of the agent on the other platforms
+func Configure(log log.T, cfg appconfig.RecoveryCfg) error {
+	return fmt.Errorf("the restart policy is not supported on %v", runtime.GOOS)
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package servicerecovery
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropIn(t *testing.T) {
+	cfg := appconfig.RecoveryCfg{RestartDelaySeconds: 30, FailureThreshold: 3, ResetPeriodSeconds: 86400}
+	assert.Equal(t, `# Generated by amazon-ssm-agent -configure-recovery from the Recovery config, changes are overwritten
+[Unit]
+StartLimitIntervalSec=0
+
+[Service]
+Restart=on-failure
+RestartSec=30
+`, dropIn(cfg))
+
+	cfg.RebootOnRepeatedFailure = true
+	assert.Equal(t, `# Generated by amazon-ssm-agent -configure-recovery from the Recovery config, changes are overwritten
+[Unit]
+StartLimitIntervalSec=86400
+StartLimitBurst=3
+StartLimitAction=reboot
+
+[Service]
+Restart=on-failure
+RestartSec=30
+`, dropIn(cfg))
+}
+
+func TestFailureArguments(t *testing.T) {
+	cfg := appconfig.RecoveryCfg{RestartDelaySeconds: 30, FailureThreshold: 3, ResetPeriodSeconds: 86400}
+	assert.Equal(t,
+		[]string{"failure", "AmazonSSMAgent", "reset=", "86400", "actions=", "restart/30000"},
+		failureArguments(cfg))
+
+	cfg.RebootOnRepeatedFailure = true
+	assert.Equal(t,
+		[]string{"failure", "AmazonSSMAgent", "reset=", "86400", "reboot=", rebootMessage,
+			"actions=", "restart/30000/restart/30000/restart/30000/reboot/30000"},
+		failureArguments(cfg))
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package servicerecovery
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// execCommand runs sc.exe, it is stubbed in the tests
+var execCommand = exec.Command
+
+// Configure sets the failure actions of the agent service
+func Configure(log log.T, cfg appconfig.RecoveryCfg) error {
+	for _, arguments := range [][]string{failureArguments(cfg), failureFlagArguments()} {
+		if output, err := execCommand("sc.exe", arguments...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set the %v of the agent service: %v, %s", arguments[0], err, output)
+		}
+	}
+	log.Infof("Applied the restart policy to the %v service", windowsServiceName)
+	return nil
+}
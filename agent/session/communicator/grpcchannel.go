@@ -0,0 +1,427 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// communicator package implement base communicator for network connections.
+package communicator
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	mgsconfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// GrpcStreamMethod is the bidirectional streaming method of the gateway,
+	// both directions stream Frame messages:
+	//   message Frame {
+	//     bytes payload = 1; // the message exchanged with the service
+	//     bool text = 2;     // whether the message is UTF-8 text rather than binary
+	//   }
+	GrpcStreamMethod = "/ssm.messagegateway.v1.ChannelGateway/Stream"
+
+	// metadata of the stream, identifying the channel the gateway relays to the service
+	GrpcChannelTypeHeader = "X-Ssm-Channel-Type"
+	GrpcChannelIdHeader   = "X-Ssm-Channel-Id"
+	GrpcChannelRoleHeader = "X-Ssm-Channel-Role"
+	GrpcRegionHeader      = "X-Ssm-Region"
+
+	grpcContentType      = "application/grpc+proto"
+	grpcFrameHeaderSize  = 5
+	grpcMaxMessageSize   = 16 * 1024 * 1024
+	grpcPayloadFieldTag  = 1<<3 | 2 // field 1, length delimited
+	grpcTextFieldTag     = 2<<3 | 0 // field 2, varint
+	grpcStatusOK         = "0"
+	grpcOpenTimeout      = 30 * time.Second
+	grpcUnsignedPayload  = "UNSIGNED-PAYLOAD"
+	amzContentSha256Name = "X-Amz-Content-Sha256"
+)
+
+// GrpcChannel is a channel streaming the messages over gRPC through a gateway to the service,
+// HTTP/2 gives it flow control per stream and a binary framing without websocket and JSON overhead.
+type GrpcChannel struct {
+	OnMessage    func([]byte)
+	OnError      func(error)
+	Context      context.T
+	ChannelToken string
+	Gateway      string
+	Url          string
+	Signer       *v4.Signer
+	Region       string
+	IsOpen       bool
+	metadata     http.Header
+	transport    http.RoundTripper
+	writer       *io.PipeWriter
+	response     *http.Response
+	writeLock    *sync.Mutex
+}
+
+// grpcTransport returns the HTTP/2 transport to the gateway
+var grpcTransport = func(log log.T) http.RoundTripper {
+	appConfig, _ := appconfig.Config(false)
+	transport := proxyconfig.Transport(log, appConfig.Mgs.Proxy)
+	// the custom dialer of the transport disables HTTP/2 unless it's forced
+	transport.ForceAttemptHTTP2 = true
+	return transport
+}
+
+// Initialize a GrpcChannel object.
+func (grpcChannel *GrpcChannel) Initialize(context context.T,
+	channelId string,
+	channelType string,
+	channelRole string,
+	channelToken string,
+	region string,
+	signer *v4.Signer,
+	onMessageHandler func([]byte),
+	onErrorHandler func(error)) error {
+
+	gateway := grpcChannel.Gateway
+	if gateway == "" {
+		return fmt.Errorf("no gRPC gateway configured")
+	}
+	if !strings.Contains(gateway, "://") {
+		gateway = mgsconfig.HttpsPrefix + gateway
+	}
+	channelUrl, err := url.Parse(gateway)
+	if err != nil {
+		return err
+	}
+	if channelUrl.Scheme != "https" {
+		return fmt.Errorf("the gRPC gateway %v must use https", grpcChannel.Gateway)
+	}
+	channelUrl.Path = GrpcStreamMethod
+
+	grpcChannel.metadata = http.Header{}
+	grpcChannel.metadata.Set(GrpcChannelTypeHeader, channelType)
+	grpcChannel.metadata.Set(GrpcChannelIdHeader, channelId)
+	grpcChannel.metadata.Set(GrpcChannelRoleHeader, channelRole)
+	grpcChannel.metadata.Set(GrpcRegionHeader, region)
+
+	grpcChannel.Url = channelUrl.String()
+	grpcChannel.Context = context
+	grpcChannel.Region = region
+	grpcChannel.Signer = signer
+	grpcChannel.ChannelToken = channelToken
+	grpcChannel.OnError = onErrorHandler
+	grpcChannel.OnMessage = onMessageHandler
+
+	return nil
+}
+
+// SetUrl sets the url for the GrpcChannel.
+func (grpcChannel *GrpcChannel) SetUrl(url string) {
+	grpcChannel.Url = url
+}
+
+// SetSubProtocol is a no-op, gRPC streams have no subprotocol.
+func (grpcChannel *GrpcChannel) SetSubProtocol(subProtocol string) {
+}
+
+// GetChannelToken returns channelToken field.
+func (grpcChannel *GrpcChannel) GetChannelToken() string {
+	return grpcChannel.ChannelToken
+}
+
+// SetChannelToken updates the token field.
+func (grpcChannel *GrpcChannel) SetChannelToken(token string) {
+	grpcChannel.ChannelToken = token
+}
+
+// Open starts the gRPC stream, the request body streams the outgoing messages and the
+// response body the incoming messages.
+func (grpcChannel *GrpcChannel) Open(log log.T) error {
+
+	// initialize the write mutex
+	grpcChannel.writeLock = &sync.Mutex{}
+
+	if grpcChannel.transport == nil {
+		grpcChannel.transport = grpcTransport(log)
+	}
+	request, err := http.NewRequest(http.MethodPost, grpcChannel.Url, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range grpcChannel.metadata {
+		request.Header[name] = values
+	}
+	request.Header.Set("Content-Type", grpcContentType)
+	request.Header.Set("Te", "trailers")
+	// the stream is signed like the websocket upgrade, its content can't be part of the signature
+	request.Header.Set(amzContentSha256Name, grpcUnsignedPayload)
+	if grpcChannel.Signer != nil {
+		if _, err = grpcChannel.Signer.Sign(request, nil, mgsconfig.ServiceName, grpcChannel.Region, clockskew.Now()); err != nil {
+			log.Errorf("Failed to get the v4 signature, %v", err)
+		}
+	}
+
+	reader, writer := io.Pipe()
+	request.Body = reader
+
+	responses := make(chan *http.Response, 1)
+	errs := make(chan error, 1)
+	go func() {
+		response, err := grpcChannel.transport.RoundTrip(request)
+		if err != nil {
+			errs <- err
+			return
+		}
+		responses <- response
+	}()
+
+	var response *http.Response
+	select {
+	case response = <-responses:
+	case err = <-errs:
+		writer.Close()
+		return err
+	case <-time.After(grpcOpenTimeout):
+		writer.CloseWithError(errors.New("timed out opening the gRPC stream"))
+		return fmt.Errorf("timed out opening the gRPC stream to %v", grpcChannel.Url)
+	}
+	if err = checkGrpcResponse(response); err != nil {
+		writer.Close()
+		response.Body.Close()
+		return err
+	}
+
+	grpcChannel.writer = writer
+	grpcChannel.response = response
+	grpcChannel.IsOpen = true
+	grpcChannel.StartPings(log, mgsconfig.WebSocketPingInterval)
+
+	// spin up a different routine to listen to the incoming traffic
+	go func() {
+
+		defer func() {
+			if msg := recover(); msg != nil {
+				log.Errorf("GrpcChannel listener run panic: %v", msg)
+				log.Errorf("%s: %s", msg, debug.Stack())
+			}
+		}()
+
+		for {
+			payload, err := readGrpcFrame(response.Body)
+			if grpcChannel.IsOpen == false {
+				log.Info("Ending the channel listening routine since the channel is closed")
+				break
+			}
+			if err == io.EOF {
+				if err = grpcStatusError(response.Trailer); err == nil {
+					err = errors.New("gRPC stream closed by the gateway")
+				}
+			}
+			if err != nil {
+				log.Warnf("Failed to receive messages from the gRPC stream. Error: %v", err)
+				grpcChannel.OnError(err)
+				break
+			}
+			if len(payload) == 0 {
+				// keepalive
+				continue
+			}
+
+			bandwidth.Wait(bandwidth.Session, len(payload))
+			grpcChannel.OnMessage(payload)
+		}
+	}()
+
+	return nil
+}
+
+// StartPings sends empty frames to keep the gRPC stream alive.
+func (grpcChannel *GrpcChannel) StartPings(log log.T, pingInterval time.Duration) {
+
+	go func() {
+		for {
+			time.Sleep(pingInterval)
+			if grpcChannel.IsOpen == false {
+				return
+			}
+
+			log.Debug("GrpcChannel: Send keepalive.")
+			if err := grpcChannel.writeFrame(nil, false); err != nil {
+				log.Warnf("Error while sending gRPC keepalive: %v", err)
+				return
+			}
+		}
+	}()
+}
+
+// Close ends the gRPC stream.
+func (grpcChannel *GrpcChannel) Close(log log.T) error {
+
+	log.Info("Closing gRPC channel stream to: " + grpcChannel.Url)
+	if grpcChannel.IsOpen == true {
+		// Send signal to stop receiving message
+		grpcChannel.IsOpen = false
+		grpcChannel.writer.Close()
+		return grpcChannel.response.Body.Close()
+	}
+
+	log.Debugf("gRPC channel stream to: " + grpcChannel.Url + " is already Closed!")
+	return nil
+}
+
+// SendMessage sends a byte message through the gRPC stream.
+// The message type is websocket.TextMessage or websocket.BinaryMessage like for the websocket channel.
+func (grpcChannel *GrpcChannel) SendMessage(log log.T, input []byte, inputType int) error {
+	if grpcChannel.IsOpen == false {
+		return errors.New("Can't send message: Connection is closed.")
+	}
+
+	if len(input) < 1 {
+		return errors.New("Can't send message: Empty input.")
+	}
+
+	bandwidth.Wait(bandwidth.Session, len(input))
+	return grpcChannel.writeFrame(input, inputType == websocket.TextMessage)
+}
+
+// writeFrame writes a frame to the request stream.
+func (grpcChannel *GrpcChannel) writeFrame(payload []byte, text bool) error {
+	frame := encodeGrpcFrame(payload, text)
+	grpcChannel.writeLock.Lock()
+	defer grpcChannel.writeLock.Unlock()
+	_, err := grpcChannel.writer.Write(frame)
+	return err
+}
+
+// checkGrpcResponse checks that the gateway accepted the stream.
+func checkGrpcResponse(response *http.Response) error {
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("gRPC gateway responded with status %v", response.Status)
+	}
+	if response.ProtoMajor != 2 {
+		return fmt.Errorf("gRPC gateway doesn't support HTTP/2, protocol %v", response.Proto)
+	}
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "application/grpc") {
+		return fmt.Errorf("gRPC gateway responded with content type %v", response.Header.Get("Content-Type"))
+	}
+	// a trailers-only response carries the status in the headers
+	return grpcStatusError(response.Header)
+}
+
+// grpcStatusError returns the error carried by the gRPC status metadata.
+func grpcStatusError(metadata http.Header) error {
+	status := metadata.Get("Grpc-Status")
+	if status == "" || status == grpcStatusOK {
+		return nil
+	}
+	return fmt.Errorf("gRPC stream failed with status %v: %v", status, metadata.Get("Grpc-Message"))
+}
+
+// encodeGrpcFrame encodes a Frame message in a gRPC length-prefixed message.
+func encodeGrpcFrame(payload []byte, text bool) []byte {
+	message := make([]byte, grpcFrameHeaderSize, grpcFrameHeaderSize+binary.MaxVarintLen64+len(payload)+2)
+	if len(payload) > 0 {
+		message = append(message, grpcPayloadFieldTag)
+		message = appendVarint(message, uint64(len(payload)))
+		message = append(message, payload...)
+	}
+	if text {
+		message = append(message, grpcTextFieldTag, 1)
+	}
+	// uncompressed message
+	message[0] = 0
+	binary.BigEndian.PutUint32(message[1:grpcFrameHeaderSize], uint32(len(message)-grpcFrameHeaderSize))
+	return message
+}
+
+// readGrpcFrame reads a gRPC length-prefixed Frame message and returns its payload.
+func readGrpcFrame(reader io.Reader) (payload []byte, err error) {
+	header := make([]byte, grpcFrameHeaderSize)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed gRPC messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > grpcMaxMessageSize {
+		return nil, fmt.Errorf("gRPC message of %v bytes exceeds the maximum size", length)
+	}
+	message := make([]byte, length)
+	if _, err = io.ReadFull(reader, message); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return decodeGrpcFrame(message)
+}
+
+// decodeGrpcFrame returns the payload of a Frame message, skipping the unknown fields.
+func decodeGrpcFrame(message []byte) (payload []byte, err error) {
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return nil, errors.New("invalid gRPC frame field")
+		}
+		message = message[n:]
+		switch key & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(message); n <= 0 {
+				return nil, errors.New("invalid gRPC frame varint")
+			}
+			message = message[n:]
+		case 1: // 64 bits
+			if len(message) < 8 {
+				return nil, errors.New("truncated gRPC frame")
+			}
+			message = message[8:]
+		case 2: // length delimited
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return nil, errors.New("truncated gRPC frame")
+			}
+			value := message[n : n+int(length)]
+			message = message[n+int(length):]
+			if key>>3 == 1 {
+				payload = value
+			}
+		case 5: // 32 bits
+			if len(message) < 4 {
+				return nil, errors.New("truncated gRPC frame")
+			}
+			message = message[4:]
+		default:
+			return nil, fmt.Errorf("unsupported gRPC frame wire type %v", key&7)
+		}
+	}
+	return payload, nil
+}
+
+// appendVarint appends a protobuf varint.
+func appendVarint(buffer []byte, value uint64) []byte {
+	var encoded [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(encoded[:], value)
+	return append(buffer, encoded[:n]...)
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// communicator package implement base communicator for network connections.
+package communicator
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// grpcEchoHandler streams back every frame received, adding the word "echo" to the payload.
+func grpcEchoHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, GrpcStreamMethod, req.URL.Path)
+		assert.Equal(t, mgsConfig.ControlChannel, req.Header.Get(GrpcChannelTypeHeader))
+		assert.Equal(t, channelId, req.Header.Get(GrpcChannelIdHeader))
+		assert.NotEmpty(t, req.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", grpcContentType)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			payload, err := readGrpcFrame(req.Body)
+			if err != nil {
+				return
+			}
+			w.Write(encodeGrpcFrame(append([]byte("echo "), payload...), false))
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func newGrpcGateway(t *testing.T, handler http.Handler) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	original := grpcTransport
+	grpcTransport = func(log log.T) http.RoundTripper { return server.Client().Transport }
+	t.Cleanup(func() { grpcTransport = original })
+	return server
+}
+
+func TestGrpcInitialize(t *testing.T) {
+	grpcChannel := &GrpcChannel{Gateway: "gateway.example.com:8443"}
+	err := grpcChannel.Initialize(context.NewMockDefault(), channelId, mgsConfig.ControlChannel, role, token, region, signer, onMessageHandler, onErrorHandler)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gateway.example.com:8443"+GrpcStreamMethod, grpcChannel.Url)
+	assert.Equal(t, role, grpcChannel.metadata.Get(GrpcChannelRoleHeader))
+	assert.Equal(t, region, grpcChannel.metadata.Get(GrpcRegionHeader))
+	assert.Equal(t, token, grpcChannel.GetChannelToken())
+}
+
+func TestGrpcInitializeRequiresHttpsGateway(t *testing.T) {
+	grpcChannel := &GrpcChannel{Gateway: "http://gateway.example.com:8080"}
+	err := grpcChannel.Initialize(context.NewMockDefault(), channelId, mgsConfig.ControlChannel, role, token, region, signer, onMessageHandler, onErrorHandler)
+	assert.Error(t, err)
+
+	grpcChannel = &GrpcChannel{}
+	err = grpcChannel.Initialize(context.NewMockDefault(), channelId, mgsConfig.ControlChannel, role, token, region, signer, onMessageHandler, onErrorHandler)
+	assert.Error(t, err)
+}
+
+func TestGrpcSendMessage(t *testing.T) {
+	server := newGrpcGateway(t, grpcEchoHandler(t))
+	received := make(chan []byte, 1)
+
+	grpcChannel := &GrpcChannel{Gateway: server.URL}
+	err := grpcChannel.Initialize(context.NewMockDefault(), channelId, mgsConfig.ControlChannel, role, token, region, signer,
+		func(input []byte) { received <- input }, onErrorHandler)
+	assert.NoError(t, err)
+
+	err = grpcChannel.Open(log.NewMockLog())
+	assert.NoError(t, err)
+	defer grpcChannel.Close(log.NewMockLog())
+
+	err = grpcChannel.SendMessage(log.NewMockLog(), []byte("channel test"), websocket.BinaryMessage)
+	assert.NoError(t, err)
+	select {
+	case message := <-received:
+		assert.Equal(t, "echo channel test", string(message))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no message received from the gRPC stream")
+	}
+}
+
+func TestGrpcOpenFailsOnGrpcStatus(t *testing.T) {
+	server := newGrpcGateway(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", grpcContentType)
+		w.Header().Set("Grpc-Status", "16")
+		w.Header().Set("Grpc-Message", "unauthenticated")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	grpcChannel := &GrpcChannel{Gateway: server.URL}
+	grpcChannel.Initialize(context.NewMockDefault(), channelId, mgsConfig.ControlChannel, role, token, region, signer, onMessageHandler, onErrorHandler)
+	err := grpcChannel.Open(log.NewMockLog())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unauthenticated")
+	assert.False(t, grpcChannel.IsOpen)
+}
+
+func TestGrpcSendMessageWhenClosed(t *testing.T) {
+	grpcChannel := &GrpcChannel{}
+	err := grpcChannel.SendMessage(log.NewMockLog(), []byte("input"), websocket.TextMessage)
+	assert.Error(t, err)
+}
+
+func TestGrpcFrameEncoding(t *testing.T) {
+	frame := encodeGrpcFrame([]byte("payload"), true)
+	assert.Equal(t, []byte{0, 0, 0, 0, 11, 0x0a, 7, 'p', 'a', 'y', 'l', 'o', 'a', 'd', 0x10, 1}, frame)
+
+	payload, err := readGrpcFrame(bytes.NewReader(frame))
+	assert.NoError(t, err)
+	assert.Equal(t, "payload", string(payload))
+
+	// keepalive frames are empty
+	payload, err = readGrpcFrame(bytes.NewReader(encodeGrpcFrame(nil, false)))
+	assert.NoError(t, err)
+	assert.Empty(t, payload)
+}
+
+func TestGrpcFrameDecodingSkipsUnknownFields(t *testing.T) {
+	message := []byte{3<<3 | 0, 150, 1, 4<<3 | 5, 1, 2, 3, 4, 5<<3 | 2, 1, 'x', 0x0a, 2, 'o', 'k'}
+	payload, err := decodeGrpcFrame(message)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(payload))
+
+	_, err = decodeGrpcFrame([]byte{0x0a, 5, 'o'})
+	assert.Error(t, err)
+}
+
+func TestGrpcFrameRejectsCompressedMessages(t *testing.T) {
+	frame := encodeGrpcFrame([]byte("payload"), false)
+	frame[0] = 1
+	_, err := readGrpcFrame(bytes.NewReader(frame))
+	assert.Error(t, err)
+}
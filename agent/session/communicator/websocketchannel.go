@@ -17,6 +17,7 @@ package communicator
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"path"
@@ -24,8 +25,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bandwidth"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/dnscache"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
 	mgsconfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	"github.com/aws/amazon-ssm-agent/agent/websocketutil"
 	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
@@ -130,7 +137,7 @@ func (webSocketChannel *WebSocketChannel) getV4SignatureHeader(log log.T, Url st
 	request, err := http.NewRequest("GET", Url, nil)
 
 	if webSocketChannel.Signer != nil {
-		_, err = webSocketChannel.Signer.Sign(request, nil, mgsconfig.ServiceName, webSocketChannel.Region, time.Now())
+		_, err = webSocketChannel.Signer.Sign(request, nil, mgsconfig.ServiceName, webSocketChannel.Region, clockskew.Now())
 	}
 	return request.Header, err
 }
@@ -156,7 +163,13 @@ func (webSocketChannel *WebSocketChannel) Open(log log.T) error {
 		log.Errorf("Failed to get the v4 signature, %v", err)
 	}
 
-	ws, err := websocketutil.NewWebsocketUtil(log, nil).OpenConnection(webSocketChannel.Url, header)
+	appConfig, _ := appconfig.Config(false)
+	dialer := &websocket.Dialer{
+		Proxy:           proxyconfig.ProxyFunc(log, appConfig.Mgs.Proxy),
+		NetDial:         dnscache.Dial(&net.Dialer{}),
+		TLSClientConfig: fips.TLSConfig(nil),
+	}
+	ws, err := websocketutil.NewWebsocketUtil(log, dialer).OpenConnection(webSocketChannel.Url, header)
 	if err != nil {
 		return err
 	}
@@ -204,6 +217,7 @@ func (webSocketChannel *WebSocketChannel) Open(log log.T) error {
 			} else {
 				retryCount = 0
 
+				bandwidth.Wait(bandwidth.Session, len(rawMessage))
 				webSocketChannel.OnMessage(rawMessage)
 			}
 		}
@@ -259,6 +273,7 @@ func (webSocketChannel *WebSocketChannel) SendMessage(log log.T, input []byte, i
 		return errors.New("Can't send message: Empty input.")
 	}
 
+	bandwidth.Wait(bandwidth.Session, len(input))
 	webSocketChannel.writeLock.Lock()
 	err := webSocketChannel.Connection.WriteMessage(inputType, input)
 	webSocketChannel.writeLock.Unlock()
@@ -47,6 +47,11 @@ const (
 	OutgoingMessageBufferCapacity = 100000
 	IncomingMessageBufferCapacity = 100000
 
+	// Interval at which the acknowledgements are checked while the output reads are paused by the output window
+	OutputWindowPollInterval = 10 * time.Millisecond
+	// Maximum output credit the window_update messages of a client can accumulate
+	MaxOutputCredit = 1 << 30
+
 	// Round trip time constant
 	RTTConstant = 1.0 / 8.0
 	// Round trip time variation constant
@@ -28,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/docparser"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	logger "github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/twinj/uuid"
@@ -217,12 +218,11 @@ func (agentMessage *AgentMessage) Serialize(log logger.T) (result []byte, err er
 		return make([]byte, 1), err
 	}
 
-	hasher := sha256.New()
-	hasher.Write(agentMessage.Payload)
+	digest := sha256.Sum256(agentMessage.Payload)
 
 	startPosition = AgentMessage_PayloadDigestOffset
 	endPosition = AgentMessage_PayloadDigestOffset + AgentMessage_PayloadDigestLength - 1
-	if err = putBytes(log, result, startPosition, endPosition, hasher.Sum(nil)); err != nil {
+	if err = putBytes(log, result, startPosition, endPosition, digest[:]); err != nil {
 		log.Errorf("Could not serialize PayloadDigest with error: %v", err)
 		return make([]byte, 1), err
 	}
@@ -318,8 +318,7 @@ func deserializeAgentTaskPayload(log logger.T, agentMessage AgentMessage) (agent
 		err = errors.New("AgentMessage is not of type interactive_shell")
 		return
 	}
-	whatstring := string(agentMessage.Payload)
-	log.Debug(whatstring)
+	log.Debugf("%s", agentMessage.Payload)
 
 	var mgsPayload MGSPayload
 	if err = json.Unmarshal(agentMessage.Payload, &mgsPayload); err != nil {
@@ -327,7 +326,7 @@ func deserializeAgentTaskPayload(log logger.T, agentMessage AgentMessage) (agent
 	}
 
 	// workaround to unmarshal the real payload (should be fixed from the service side)
-	if err = json.Unmarshal([]byte(mgsPayload.Payload), &agentTaskPayload); err != nil {
+	if err = jsonutil.UnmarshalReader(strings.NewReader(mgsPayload.Payload), &agentTaskPayload); err != nil {
 		log.Errorf("Could not deserialize AgentTask payload rawMessage: %s", string(mgsPayload.Payload))
 	}
 	return
@@ -534,13 +533,7 @@ func putLong(log logger.T, byteArray []byte, offset int, value int64) (err error
 		return errors.New("Offset is outside the byte array.")
 	}
 
-	mbytes, err := longToBytes(log, value)
-	if err != nil {
-		log.Error("putLong failed: getBytesFromInteger Failed.")
-		return err
-	}
-
-	copy(byteArray[offset:offset+8], mbytes)
+	binary.BigEndian.PutUint64(byteArray[offset:offset+8], uint64(value))
 	return nil
 }
 
@@ -562,62 +555,40 @@ func putInteger(log logger.T, byteArray []byte, offset int, value int32) (err er
 		return errors.New("Offset is outside the byte array.")
 	}
 
-	bytes, err := integerToBytes(log, value)
-	if err != nil {
-		log.Error("putInteger failed: getBytesFromInteger Failed.")
-		return err
-	}
-
-	copy(byteArray[offset:offset+4], bytes)
+	binary.BigEndian.PutUint32(byteArray[offset:offset+4], uint32(value))
 	return nil
 }
 
 // bytesToLong gets a Long integer from a byte array.
 func bytesToLong(log logger.T, input []byte) (result int64, err error) {
-	var res int64
 	inputLength := len(input)
 	if inputLength != 8 {
 		log.Error("bytesToLong failed: input array size is not equal to 8.")
 		return 0, errors.New("Input array size is not equal to 8.")
 	}
-	buf := bytes.NewBuffer(input)
-	binary.Read(buf, binary.BigEndian, &res)
-	return res, nil
+	return int64(binary.BigEndian.Uint64(input)), nil
 }
 
 // longToBytes gets bytes array from a long integer.
 func longToBytes(log logger.T, input int64) (result []byte, err error) {
-	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.BigEndian, input)
-	if buf.Len() != 8 {
-		log.Error("longToBytes failed: buffer output length is not equal to 8.")
-		return make([]byte, 8), errors.New("Input array size is not equal to 8.")
-	}
-
-	return buf.Bytes(), nil
+	result = make([]byte, 8)
+	binary.BigEndian.PutUint64(result, uint64(input))
+	return result, nil
 }
 
 // integerToBytes gets bytes array from an integer.
 func integerToBytes(log logger.T, input int32) (result []byte, err error) {
-	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.BigEndian, input)
-	if buf.Len() != 4 {
-		log.Error("integerToBytes failed: buffer output length is not equal to 4.")
-		return make([]byte, 4), errors.New("Input array size is not equal to 4.")
-	}
-
-	return buf.Bytes(), nil
+	result = make([]byte, 4)
+	binary.BigEndian.PutUint32(result, uint32(input))
+	return result, nil
 }
 
 // bytesToInteger gets an integer from a byte array.
 func bytesToInteger(log logger.T, input []byte) (result int32, err error) {
-	var res int32
 	inputLength := len(input)
 	if inputLength != 4 {
 		log.Error("bytesToInteger failed: input array size is not equal to 4.")
 		return 0, errors.New("Input array size is not equal to 4.")
 	}
-	buf := bytes.NewBuffer(input)
-	binary.Read(buf, binary.BigEndian, &res)
-	return res, nil
+	return int32(binary.BigEndian.Uint32(input)), nil
 }
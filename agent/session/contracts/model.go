@@ -44,6 +44,10 @@ const (
 	PausePublicationMessage string = "pause_publication"
 	// StartPublicationMessage message type for start sending data packages.
 	StartPublicationMessage string = "start_publication"
+	// PollHintMessage represents message type hinting that new messages are waiting in MDS
+	PollHintMessage string = "poll_hint"
+	// WindowUpdateMessage represents message type granting the agent credit to send more output
+	WindowUpdateMessage string = "window_update"
 )
 
 type IMessage interface {
@@ -174,6 +178,34 @@ func (channelClose *ChannelClosed) Serialize(log logger.T) (result []byte, err e
 	return
 }
 
+// WindowUpdateContent is sent by the client to grant the agent credit to send more output.
+// * WindowIncrement is the number of output bytes the client is ready to receive on top of its previous grants.
+type WindowUpdateContent struct {
+	WindowIncrement int64 `json:"WindowIncrement"`
+}
+
+// Deserialize parses WindowUpdateContent message from payload of AgentMessage.
+func (windowUpdate *WindowUpdateContent) Deserialize(log logger.T, agentMessage AgentMessage) (err error) {
+	if agentMessage.MessageType != WindowUpdateMessage {
+		err = fmt.Errorf("AgentMessage is not of type WindowUpdate. Found message type: %s", agentMessage.MessageType)
+		return
+	}
+
+	if err = json.Unmarshal(agentMessage.Payload, windowUpdate); err != nil {
+		log.Errorf("Could not deserialize rawMessage to WindowUpdate: %s", err)
+	}
+	return
+}
+
+// Serialize marshals WindowUpdateContent as payloads into bytes.
+func (windowUpdate *WindowUpdateContent) Serialize(log logger.T) (result []byte, err error) {
+	result, err = json.Marshal(windowUpdate)
+	if err != nil {
+		log.Errorf("Could not serialize WindowUpdateContent message: %v, err: %s", windowUpdate, err)
+	}
+	return
+}
+
 // AgentTaskCompletePayload is sent by the agent to inform the task is complete and what the overall result was.
 type AgentTaskCompletePayload struct {
 	SchemaVersion    int    `json:"SchemaVersion"`
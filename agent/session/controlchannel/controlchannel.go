@@ -21,22 +21,35 @@ import (
 	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
+	"github.com/aws/amazon-ssm-agent/agent/healthendpoint"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/aws/amazon-ssm-agent/agent/pollhint"
 	"github.com/aws/amazon-ssm-agent/agent/session/communicator"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
 	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gorilla/websocket"
 	"github.com/twinj/uuid"
 )
 
+const (
+	// metricsServiceName is the service label under which messages received from MGS are counted
+	metricsServiceName = "MessageGatewayService"
+	// metricsChannelName is the channel label under which control channel reconnects are counted
+	metricsChannelName = "controlchannel"
+)
+
 type IControlChannel interface {
 	Initialize(context context.T, mgsService service.Service, processor processor.Processor, instanceId string)
 	SetWebSocket(context context.T, mgsService service.Service, processor processor.Processor, instanceId string) error
@@ -44,6 +57,7 @@ type IControlChannel interface {
 	Reconnect(log log.T) error
 	Close(log log.T) error
 	Open(log log.T) error
+	GetRegion() string
 }
 
 // ControlChannel used for communication between the message gateway service and the agent.
@@ -53,6 +67,7 @@ type ControlChannel struct {
 	ChannelId   string
 	Service     service.Service
 	channelType string
+	region      string
 }
 
 // Initialize populates controlchannel object and opens controlchannel to communicate with mgs.
@@ -67,11 +82,19 @@ func (controlChannel *ControlChannel) Initialize(context context.T,
 	controlChannel.ChannelId = instanceId
 	controlChannel.channelType = mgsConfig.RoleSubscribe
 	controlChannel.Processor = processor
-	controlChannel.wsChannel = &communicator.WebSocketChannel{}
+	controlChannel.wsChannel = newChannel(context.AppConfig())
 
 	log.Debug("Initialized controlchannel for instance: %s", instanceId)
 }
 
+// newChannel returns the channel of the configured control channel transport.
+func newChannel(config appconfig.SsmagentConfig) communicator.IWebSocketChannel {
+	if config.Mgs.ControlChannelTransport == appconfig.ControlChannelTransportGrpc {
+		return &communicator.GrpcChannel{Gateway: config.Mgs.GrpcGateway}
+	}
+	return &communicator.WebSocketChannel{}
+}
+
 // SetWebSocket populates webchannel object.
 func (controlChannel *ControlChannel) SetWebSocket(context context.T,
 	mgsService service.Service,
@@ -82,7 +105,8 @@ func (controlChannel *ControlChannel) SetWebSocket(context context.T,
 	uuid.SwitchFormat(uuid.CleanHyphen)
 	uid := uuid.NewV4().String()
 
-	log.Infof("Setting up websocket for controlchannel for instance: %s, requestId: %s", instanceId, uid)
+	region := mgsService.GetRegion()
+	log.Infof("Setting up websocket for controlchannel for instance: %s, region: %s, requestId: %s", instanceId, region, uid)
 	tokenValue, err := getControlChannelToken(log, mgsService, instanceId, uid)
 	if err != nil {
 		log.Errorf("Failed to get controlchannel token, error: %s", err)
@@ -95,7 +119,22 @@ func (controlChannel *ControlChannel) SetWebSocket(context context.T,
 		controlChannelIncomingMessageHandler(context, processor, input, orchestrationRootDir, instanceId)
 	}
 	onErrorHandler := func(err error) {
+		healthendpoint.SetControlChannelConnected(false)
 		callable := func() (channel interface{}, err error) {
+			if region := mgsService.GetRegion(); region != controlChannel.region {
+				// the service failed over or back to another region, the websocket has to be set up again
+				log.Infof("Moving controlchannel from region %s to region %s", controlChannel.region, region)
+				if err := controlChannel.wsChannel.Close(log); err != nil {
+					log.Warnf("closing controlchannel failed with error: %s", err)
+				}
+				if err := controlChannel.SetWebSocket(context, mgsService, processor, instanceId); err != nil {
+					return controlChannel, err
+				}
+				if err := controlChannel.Open(log); err != nil {
+					return controlChannel, err
+				}
+				return controlChannel, nil
+			}
 			uuid.SwitchFormat(uuid.CleanHyphen)
 			requestId := uuid.NewV4().String()
 			tokenValue, err := getControlChannelToken(log, mgsService, instanceId, requestId)
@@ -127,13 +166,14 @@ func (controlChannel *ControlChannel) SetWebSocket(context context.T,
 		mgsConfig.ControlChannel,
 		mgsConfig.RoleSubscribe,
 		tokenValue,
-		mgsService.GetRegion(),
+		region,
 		mgsService.GetV4Signer(),
 		onMessageHandler,
 		onErrorHandler); err != nil {
 		log.Errorf("failed to initialize websocket channel for controlchannel, error: %s", err)
 		return err
 	}
+	controlChannel.region = region
 	return nil
 }
 
@@ -153,14 +193,21 @@ func (controlChannel *ControlChannel) Reconnect(log log.T) error {
 	if err := controlChannel.Open(log); err != nil {
 		return fmt.Errorf("failed to reconnect controlchannel with error: %s", err)
 	}
+	metrics.Reconnects.Inc(metricsChannelName)
 
 	log.Debugf("Successfully reconnected with controlchannel with type %s", controlChannel.channelType)
 	return nil
 }
 
+// GetRegion returns the region of the web socket connection.
+func (controlChannel *ControlChannel) GetRegion() string {
+	return controlChannel.region
+}
+
 // Close closes controlchannel - its web socket connection.
 func (controlChannel *ControlChannel) Close(log log.T) error {
 	log.Infof("Closing controlchannel with channel Id %s", controlChannel.ChannelId)
+	healthendpoint.SetControlChannelConnected(false)
 	if controlChannel.wsChannel != nil {
 		return controlChannel.wsChannel.Close(log)
 	}
@@ -188,7 +235,12 @@ func (controlChannel *ControlChannel) Open(log log.T) error {
 		return fmt.Errorf("error serializing openControlChannelInput: %s", err)
 	}
 
-	return controlChannel.SendMessage(log, jsonValue, websocket.TextMessage)
+	if err = controlChannel.SendMessage(log, jsonValue, websocket.TextMessage); err != nil {
+		return err
+	}
+	updateutil.RecordControlChannelConnected(log)
+	healthendpoint.SetControlChannelConnected(true)
+	return nil
 }
 
 // controlChannelIncomingMessageHandler handles the incoming messages coming to the agent.
@@ -210,6 +262,7 @@ func controlChannelIncomingMessageHandler(context context.T,
 		log.Debugf("Invalid AgentMessage: %s, err: %v.", agentMessage.MessageId, err)
 		return err
 	}
+	metrics.MessagesReceived.Inc(metricsServiceName)
 
 	if agentMessage.MessageType == mgsContracts.InteractiveShellMessage {
 		uuid.SwitchFormat(uuid.CleanHyphen)
@@ -217,6 +270,10 @@ func controlChannelIncomingMessageHandler(context context.T,
 		return sendStartSessionMessageToProcessor(processor, context, agentMessage, orchestrationRootDir, instanceId, clientId)
 	} else if agentMessage.MessageType == mgsContracts.ChannelClosedMessage {
 		return sendTerminateSessionMessageToProcessor(processor, context, instanceId, *agentMessage)
+	} else if agentMessage.MessageType == mgsContracts.PollHintMessage {
+		log.Debugf("Received a hint that new messages are waiting in MDS, messageId: %s", agentMessage.MessageId)
+		pollhint.Notify()
+		return nil
 	}
 
 	return fmt.Errorf("invalid message type: %s", agentMessage.MessageType)
@@ -234,17 +291,42 @@ func sendStartSessionMessageToProcessor(
 	log := context.Log()
 	log.Debugf("Processing StartSession message %s", agentMessage.MessageId.String())
 
+	span := tracing.StartSpan("session")
+	span.SetAttribute("ssm.message_id", agentMessage.MessageId.String())
+	parseSpan := span.StartChild("session.parse")
 	docState, err := agentMessage.ParseAgentMessage(context, orchestrationRootDir, instanceId, clientId)
+	parseSpan.End(err)
 	if err != nil {
 		log.Errorf("Cannot parse AgentTask message to documentState: %s, err: %v.", agentMessage.MessageId, err)
+		span.End(err)
 		return err
 	}
+	span.SetAttribute("ssm.session_id", docState.DocumentInformation.DocumentID)
+	span.SetAttribute("ssm.document_name", docState.DocumentInformation.DocumentName)
 
-	// Submit message to processor
+	// Submit message to processor, the session span ends once the processor replies the session result
+	tracing.Track(docState.DocumentInformation.MessageID, span)
 	processor.Submit(*docState)
+	metrics.SessionStarted(docState.DocumentInformation.DocumentID)
+	auditSessionStarted(docState)
 	return nil
 }
 
+// auditSessionStarted records the start of the session and the user its shell runs as in the audit log
+func auditSessionStarted(docState *contracts.DocumentState) {
+	event := audit.Event{
+		Type:         audit.SessionStarted,
+		SessionID:    docState.DocumentInformation.DocumentID,
+		DocumentName: docState.DocumentInformation.DocumentName,
+	}
+	for _, plugin := range docState.InstancePluginsInformation {
+		if plugin.Name == appconfig.PluginNameStandardStream {
+			event.RunAsUser = audit.RunAsUser(plugin.Configuration.RunAsElevated)
+		}
+	}
+	audit.Record(event)
+}
+
 // sendTerminateSessionMessageToProcessor sends a TerminateSession message to the processor.
 func sendTerminateSessionMessageToProcessor(
 	processor processor.Processor,
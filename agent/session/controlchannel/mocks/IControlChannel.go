@@ -39,6 +39,20 @@ func (_m *IControlChannel) Close(_a0 log.T) error {
 	return r0
 }
 
+// GetRegion provides a mock function with given fields:
+func (_m *IControlChannel) GetRegion() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // Initialize provides a mock function with given fields: _a0, mgsService, _a2, instanceId
 func (_m *IControlChannel) Initialize(_a0 context.T, mgsService service.Service, _a2 processor.Processor, instanceId string) {
 	_m.Called(_a0, mgsService, _a2, instanceId)
@@ -24,8 +24,10 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/bufferpool"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/crypto"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -69,6 +71,7 @@ type IDataChannel interface {
 	RemoveDataFromIncomingMessageBuffer(sequenceNumber int64)
 	SkipHandshake(log log.T)
 	PerformHandshake(log log.T, kmsKeyId string) (err error)
+	WaitForOutputWindow(log log.T, size int) int
 }
 
 // DataChannel used for session communication between the message gateway service and the agent.
@@ -88,6 +91,23 @@ type DataChannel struct {
 	//buffer to store outgoing stream messages until acknowledged
 	//using linked list for this buffer as access to oldest message is required and it support faster deletion from any position of list
 	OutgoingMessageBuffer ListMessageBuffer
+	//size in bytes of the messages in OutgoingMessageBuffer, guarded by its mutex
+	outgoingBytes int
+	//maximum size in bytes of the unacknowledged output before the output reads are paused, 0 does not pause them
+	outputWindow int
+	//output bytes the client granted with window_update messages and did not receive yet, once the client sent one
+	//the output reads wait for its credit instead of the acknowledgements, guarded by creditMutex
+	outputCredit  int64
+	creditGranted bool
+	creditMutex   sync.Mutex
+	//last output of the session replayed to a client resuming the session, of at most scrollbackSize bytes
+	scrollback     []byte
+	scrollbackSize int
+	//publicationPaused is true between a pause_publication message and the start_publication message of the client
+	//resuming the session
+	publicationPaused bool
+	//streamMutex serializes the stream data messages of the plugin and of the replays, it guards scrollback
+	streamMutex sync.Mutex
 	//buffer to store incoming stream messages if received out of sequence
 	//using map for this buffer as incoming messages can be out of order and retrieval would be faster by sequenceId
 	IncomingMessageBuffer MapMessageBuffer
@@ -107,6 +127,8 @@ type DataChannel struct {
 	blockCipher crypto.IBlockCipher
 	// Indicates whether encryption was enabled
 	encryptionEnabled bool
+	//sessionType is the session plugin the handshake announces to the client, Standard_Stream when empty
+	sessionType string
 }
 
 type ListMessageBuffer struct {
@@ -149,6 +171,7 @@ type Handshake struct {
 func NewDataChannel(context context.T,
 	channelId string,
 	clientId string,
+	sessionType string,
 	inputStreamMessageHandler InputStreamMessageHandler,
 	cancelFlag task.CancelFlag) (*DataChannel, error) {
 
@@ -190,6 +213,11 @@ func NewDataChannel(context context.T,
 		mgsConfig.RolePublishSubscribe,
 		cancelFlag,
 		inputStreamMessageHandler)
+	dataChannel.sessionType = sessionType
+	if sessionType != appconfig.PluginNameStandardStream {
+		// a replayed output would corrupt the streams of the sessions which are not shells
+		dataChannel.scrollbackSize = 0
+	}
 
 	streamMessageHandler := func(input []byte) {
 		if err := dataChannel.dataChannelIncomingMessageHandler(log, input); err != nil {
@@ -230,6 +258,9 @@ func (dataChannel *DataChannel) Initialize(context context.T,
 		mgsConfig.OutgoingMessageBufferCapacity,
 		&sync.Mutex{},
 	}
+	dataChannel.outgoingBytes = 0
+	dataChannel.outputWindow = context.AppConfig().Mgs.MaxOutstandingOutputBytes
+	dataChannel.scrollbackSize = context.AppConfig().Mgs.ScrollbackBytes
 	dataChannel.IncomingMessageBuffer = MapMessageBuffer{
 		make(map[int64]StreamingMessage),
 		mgsConfig.IncomingMessageBufferCapacity,
@@ -244,11 +275,11 @@ func (dataChannel *DataChannel) Initialize(context context.T,
 	dataChannel.handshake = Handshake{
 		responseChan:            make(chan bool),
 		encryptionConfirmedChan: make(chan bool),
-		error:              nil,
-		complete:           false,
-		skipped:            false,
-		handshakeEndTime:   time.Now(),
-		handshakeStartTime: time.Now(),
+		error:                   nil,
+		complete:                false,
+		skipped:                 false,
+		handshakeEndTime:        time.Now(),
+		handshakeStartTime:      time.Now(),
 	}
 }
 
@@ -367,10 +398,23 @@ func (dataChannel *DataChannel) Close(log log.T) error {
 
 // SendStreamDataMessage sends a data message in a form of AgentMessage for streaming.
 func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgsContracts.PayloadType, inputData []byte) (err error) {
+	dataChannel.streamMutex.Lock()
+	defer dataChannel.streamMutex.Unlock()
+	if payloadType == mgsContracts.Output {
+		dataChannel.recordScrollback(inputData)
+	}
+	return dataChannel.sendStreamDataMessage(log, payloadType, inputData)
+}
+
+// sendStreamDataMessage sends a stream data message, the caller holds streamMutex.
+func (dataChannel *DataChannel) sendStreamDataMessage(log log.T, payloadType mgsContracts.PayloadType, inputData []byte) (err error) {
 	if len(inputData) == 0 {
 		log.Debugf("Ignoring empty stream data payload. PayloadType: %d", payloadType)
 		return nil
 	}
+	if payloadType == mgsContracts.Output {
+		dataChannel.consumeCredit(len(inputData))
+	}
 
 	var flag uint64 = 0
 	if dataChannel.StreamDataSequenceNumber == 0 {
@@ -421,6 +465,46 @@ func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgs
 	return nil
 }
 
+// recordScrollback keeps the last output of the session, the caller holds streamMutex.
+func (dataChannel *DataChannel) recordScrollback(output []byte) {
+	if dataChannel.scrollbackSize <= 0 {
+		return
+	}
+	dataChannel.scrollback = append(dataChannel.scrollback, output...)
+	if excess := len(dataChannel.scrollback) - dataChannel.scrollbackSize; excess > 0 {
+		// do not keep the end of a character cut in half
+		for excess < len(dataChannel.scrollback) && !utf8.RuneStart(dataChannel.scrollback[excess]) {
+			excess++
+		}
+		dataChannel.scrollback = dataChannel.scrollback[:copy(dataChannel.scrollback, dataChannel.scrollback[excess:])]
+	}
+}
+
+// replayScrollback sends the last output of the session again, in stream data messages which do not cut characters.
+func (dataChannel *DataChannel) replayScrollback(log log.T) {
+	dataChannel.streamMutex.Lock()
+	defer dataChannel.streamMutex.Unlock()
+	if len(dataChannel.scrollback) == 0 {
+		return
+	}
+	log.Debugf("Replaying the last %d bytes of output to client %s", len(dataChannel.scrollback), dataChannel.ClientId)
+	replay := append([]byte{}, dataChannel.scrollback...)
+	for len(replay) > 0 {
+		size := len(replay)
+		if size > mgsConfig.StreamDataPayloadSize {
+			size = mgsConfig.StreamDataPayloadSize
+			for size > 1 && !utf8.RuneStart(replay[size]) {
+				size--
+			}
+		}
+		if err := dataChannel.sendStreamDataMessage(log, mgsContracts.Output, replay[:size]); err != nil {
+			log.Errorf("Failed to replay the output: %v", err)
+			return
+		}
+		replay = replay[size:]
+	}
+}
+
 // ResendStreamDataMessageScheduler spawns a separate go thread which keeps checking OutgoingMessageBuffer at fixed interval
 // and resends first message if time elapsed since lastSentTime of the message is more than acknowledge wait time
 func (dataChannel *DataChannel) ResendStreamDataMessageScheduler(log log.T) error {
@@ -547,16 +631,87 @@ func (dataChannel *DataChannel) AddDataToOutgoingMessageBuffer(streamMessage Str
 	}
 	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 	dataChannel.OutgoingMessageBuffer.Messages.PushBack(streamMessage)
+	dataChannel.outgoingBytes += len(streamMessage.Content)
 	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
 }
 
 // RemoveDataFromOutgoingMessageBuffer removes given element from OutgoingMessageBuffer.
 func (dataChannel *DataChannel) RemoveDataFromOutgoingMessageBuffer(streamMessageElement *list.Element) {
 	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
-	dataChannel.OutgoingMessageBuffer.Messages.Remove(streamMessageElement)
+	streamMessage := dataChannel.OutgoingMessageBuffer.Messages.Remove(streamMessageElement).(StreamingMessage)
+	dataChannel.outgoingBytes -= len(streamMessage.Content)
 	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
 }
 
+// outstandingBytes returns the size in bytes of the stream messages sent and not acknowledged yet.
+func (dataChannel *DataChannel) outstandingBytes() int {
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	defer dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+	return dataChannel.outgoingBytes
+}
+
+// WaitForOutputWindow blocks until the client can take more output and returns the number of bytes, at most size,
+// the next output read may take. Once the client granted credit with a window_update message the reads wait for its
+// credit and take no more than it. Until then the reads are paused while the unacknowledged stream messages exceed
+// the output window, until half of the window is acknowledged, which applies backpressure to a command writing faster
+// than the client acknowledges. The wait ends when the session is cancelled.
+func (dataChannel *DataChannel) WaitForOutputWindow(log log.T, size int) int {
+	if granted, _ := dataChannel.credit(); granted {
+		return dataChannel.waitForCredit(log, size)
+	}
+	if dataChannel.outputWindow <= 0 || dataChannel.outstandingBytes() < dataChannel.outputWindow {
+		return size
+	}
+	log.Debugf("Pausing the output reads, %d unacknowledged bytes reached the output window of %d bytes",
+		dataChannel.outstandingBytes(), dataChannel.outputWindow)
+	pausedAt := time.Now()
+	for dataChannel.outstandingBytes() > dataChannel.outputWindow/2 {
+		if dataChannel.cancelFlag != nil && dataChannel.cancelFlag.Canceled() {
+			return size
+		}
+		time.Sleep(mgsConfig.OutputWindowPollInterval)
+	}
+	log.Debugf("Resuming the output reads after %v", time.Since(pausedAt))
+	return size
+}
+
+// waitForCredit blocks until the client granted credit for more output and returns the size the next read may take.
+func (dataChannel *DataChannel) waitForCredit(log log.T, size int) int {
+	_, credit := dataChannel.credit()
+	if credit <= 0 {
+		log.Debugf("Pausing the output reads until the client grants more credit")
+		pausedAt := time.Now()
+		for ; credit <= 0; _, credit = dataChannel.credit() {
+			if dataChannel.cancelFlag != nil && dataChannel.cancelFlag.Canceled() {
+				return size
+			}
+			time.Sleep(mgsConfig.OutputWindowPollInterval)
+		}
+		log.Debugf("Resuming the output reads after %v", time.Since(pausedAt))
+	}
+	if credit < int64(size) {
+		return int(credit)
+	}
+	return size
+}
+
+// credit returns whether the client grants credit for the output and how much of it is left.
+func (dataChannel *DataChannel) credit() (granted bool, credit int64) {
+	dataChannel.creditMutex.Lock()
+	defer dataChannel.creditMutex.Unlock()
+	return dataChannel.creditGranted, dataChannel.outputCredit
+}
+
+// consumeCredit takes the output sent from the credit of the client.
+// The utf8 bytes held back by a previous read can take it a few bytes below zero.
+func (dataChannel *DataChannel) consumeCredit(size int) {
+	dataChannel.creditMutex.Lock()
+	defer dataChannel.creditMutex.Unlock()
+	if dataChannel.creditGranted {
+		dataChannel.outputCredit -= int64(size)
+	}
+}
+
 // AddDataToIncomingMessageBuffer adds given message to IncomingMessageBuffer if it has capacity.
 func (dataChannel *DataChannel) AddDataToIncomingMessageBuffer(streamMessage StreamingMessage) {
 	if len(dataChannel.IncomingMessageBuffer.Messages) == dataChannel.IncomingMessageBuffer.Capacity {
@@ -602,6 +757,8 @@ func (dataChannel *DataChannel) dataChannelIncomingMessageHandler(log log.T, raw
 	case mgsContracts.StartPublicationMessage:
 		dataChannel.handleStartPublicationMessage(log, *streamDataMessage)
 		return nil
+	case mgsContracts.WindowUpdateMessage:
+		return dataChannel.handleWindowUpdateMessage(log, *streamDataMessage)
 	default:
 		log.Warn("Invalid message type received: %s", streamDataMessage.MessageType)
 	}
@@ -717,13 +874,44 @@ func (dataChannel *DataChannel) handleChannelClosedMessage(log log.T, streamData
 // handlePausePublicationMessage sets pause status of datachannel to true.
 func (dataChannel *DataChannel) handlePausePublicationMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) {
 	dataChannel.Pause = true
+	dataChannel.publicationPaused = true
 	log.Debugf("Processed %s message. Datachannel pause status set to %s", streamDataMessage.MessageType, dataChannel.Pause)
 }
 
 // handleStartPublicationMessage sets pause status of datachannel to false.
+// The client resuming a paused session gets the last output of the session again.
 func (dataChannel *DataChannel) handleStartPublicationMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) {
 	dataChannel.Pause = false
 	log.Debugf("Processed %s message. Datachannel pause status set to %s", streamDataMessage.MessageType, dataChannel.Pause)
+	if dataChannel.publicationPaused {
+		dataChannel.publicationPaused = false
+		dataChannel.replayScrollback(log)
+	}
+}
+
+// handleWindowUpdateMessage deserialize window_update content and adds the credit it grants to the output credit.
+func (dataChannel *DataChannel) handleWindowUpdateMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) (err error) {
+	windowUpdate := &mgsContracts.WindowUpdateContent{}
+	if err = windowUpdate.Deserialize(log, streamDataMessage); err != nil {
+		log.Errorf("Cannot deserialize payload to WindowUpdate message: %s, err: %v.", string(streamDataMessage.Payload), err)
+		return err
+	}
+	if windowUpdate.WindowIncrement <= 0 {
+		return fmt.Errorf("invalid window increment %d", windowUpdate.WindowIncrement)
+	}
+
+	dataChannel.creditMutex.Lock()
+	defer dataChannel.creditMutex.Unlock()
+	if !dataChannel.creditGranted {
+		log.Debugf("Client %s grants credit for the output, the output reads wait for its credit", dataChannel.ClientId)
+		dataChannel.creditGranted = true
+	}
+	dataChannel.outputCredit += windowUpdate.WindowIncrement
+	if dataChannel.outputCredit > mgsConfig.MaxOutputCredit {
+		dataChannel.outputCredit = mgsConfig.MaxOutputCredit
+	}
+	log.Tracef("Processed %s message. Output credit is %d bytes", streamDataMessage.MessageType, dataChannel.outputCredit)
+	return nil
 }
 
 // processIncomingMessageBufferItems checks if new expected sequence stream data is present in IncomingMessageBuffer.
@@ -949,11 +1137,15 @@ func (dataChannel *DataChannel) PerformHandshake(log log.T, kmsKeyId string) (er
 func (dataChannel *DataChannel) buildHandshakeRequestPayload(log log.T, encryptionRequested bool) mgsContracts.HandshakeRequestPayload {
 	handshakeRequest := mgsContracts.HandshakeRequestPayload{}
 	handshakeRequest.AgentVersion = version.Version
+	sessionType := dataChannel.sessionType
+	if sessionType == "" {
+		sessionType = appconfig.PluginNameStandardStream
+	}
 	handshakeRequest.RequestedClientActions = []mgsContracts.RequestedClientAction{
 		{
 			ActionType: mgsContracts.SessionType,
 			ActionParameters: mgsContracts.SessionTypeRequest{
-				SessionType: appconfig.PluginNameStandardStream,
+				SessionType: sessionType,
 			},
 		}}
 	if encryptionRequested {
@@ -1035,12 +1227,15 @@ func (dataChannel *DataChannel) sendEncryptionChallenge(log log.T) (err error) {
 // sendStreamDataMessageJson is utility method that serializes a struct into json and sends with the given payload type
 func (dataChannel *DataChannel) sendStreamDataMessageJson(log log.T,
 	payloadType mgsContracts.PayloadType, serializableStruct interface{}) (err error) {
-	var messageBytes []byte
-	if messageBytes, err = json.Marshal(serializableStruct); err != nil {
+	// the payload is copied when the message is serialized, so the buffer can be reused right after sending
+	buffer := bufferpool.Get()
+	defer bufferpool.Put(buffer)
+	if err = json.NewEncoder(buffer).Encode(serializableStruct); err != nil {
 		return fmt.Errorf("Could not serialize message %v, err: %s", serializableStruct, err)
 	}
 	log.Tracef("Sending message with content %v", serializableStruct)
-	err = dataChannel.SendStreamDataMessage(log, payloadType, messageBytes)
+	// the encoder terminates the value with a newline
+	err = dataChannel.SendStreamDataMessage(log, payloadType, bytes.TrimSuffix(buffer.Bytes(), []byte("\n")))
 	return err
 }
 
@@ -19,10 +19,13 @@ import (
 	"encoding/json"
 	"errors"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/crypto"
 	cryptoMocks "github.com/aws/amazon-ssm-agent/agent/crypto/mocks"
@@ -278,6 +281,135 @@ func TestRemoveDataFromOutgoingMessageBuffer(t *testing.T) {
 	assert.Equal(t, 2, dataChannel.OutgoingMessageBuffer.Messages.Len())
 }
 
+func TestOutgoingMessageBufferBytes(t *testing.T) {
+	dataChannel := getDataChannel()
+	for i := 0; i < 3; i++ {
+		dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[i])
+	}
+	assert.Equal(t, len(streamingMessages[0].Content)+len(streamingMessages[1].Content)+len(streamingMessages[2].Content),
+		dataChannel.outstandingBytes())
+
+	dataChannel.RemoveDataFromOutgoingMessageBuffer(dataChannel.OutgoingMessageBuffer.Messages.Front())
+	assert.Equal(t, len(streamingMessages[1].Content)+len(streamingMessages[2].Content), dataChannel.outstandingBytes())
+}
+
+func TestWaitForOutputWindowUnderWindow(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.outputWindow = 2 * len(streamingMessages[0].Content)
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+
+	// returns without checking the cancel flag
+	assert.Equal(t, mgsConfig.StreamDataPayloadSize, dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize))
+
+	dataChannel.outputWindow = 0
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[1])
+	assert.Equal(t, mgsConfig.StreamDataPayloadSize, dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize))
+}
+
+func TestWaitForOutputWindowResumesOnAcknowledgement(t *testing.T) {
+	dataChannel := getDataChannel()
+	cancelFlag := &task.MockCancelFlag{}
+	cancelFlag.On("Canceled").Return(false)
+	dataChannel.cancelFlag = cancelFlag
+	for i := 0; i < 4; i++ {
+		dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[i])
+	}
+	dataChannel.outputWindow = 3 * len(streamingMessages[0].Content)
+
+	resumed := make(chan bool)
+	go func() {
+		dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize)
+		close(resumed)
+	}()
+
+	// one acknowledgement is not enough to resume the reads
+	dataChannel.RemoveDataFromOutgoingMessageBuffer(dataChannel.OutgoingMessageBuffer.Messages.Front())
+	select {
+	case <-resumed:
+		assert.Fail(t, "the output reads resumed before half of the window was acknowledged")
+	case <-time.After(5 * mgsConfig.OutputWindowPollInterval):
+	}
+
+	dataChannel.RemoveDataFromOutgoingMessageBuffer(dataChannel.OutgoingMessageBuffer.Messages.Front())
+	dataChannel.RemoveDataFromOutgoingMessageBuffer(dataChannel.OutgoingMessageBuffer.Messages.Front())
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the output reads did not resume after half of the window was acknowledged")
+	}
+}
+
+func TestWaitForOutputWindowReturnsOnCancel(t *testing.T) {
+	dataChannel := getDataChannel()
+	cancelFlag := &task.MockCancelFlag{}
+	cancelFlag.On("Canceled").Return(true)
+	dataChannel.cancelFlag = cancelFlag
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+	dataChannel.outputWindow = dataChannel.outstandingBytes()
+
+	dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize)
+	assert.Equal(t, 1, dataChannel.OutgoingMessageBuffer.Messages.Len())
+	cancelFlag.AssertExpectations(t)
+}
+
+func TestWaitForOutputWindowWaitsForCredit(t *testing.T) {
+	dataChannel := getDataChannel()
+	cancelFlag := &task.MockCancelFlag{}
+	cancelFlag.On("Canceled").Return(false)
+	dataChannel.cancelFlag = cancelFlag
+	dataChannel.Pause = true
+	// the credit replaces the output window, the unacknowledged output no longer pauses the reads
+	dataChannel.outputWindow = 1
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, getWindowUpdateMessage(t, 100)))
+	assert.Equal(t, 100, dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize))
+	assert.NoError(t, dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, make([]byte, 60)))
+	assert.Equal(t, 40, dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize))
+	assert.NoError(t, dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, make([]byte, 40)))
+
+	resumed := make(chan int)
+	go func() {
+		resumed <- dataChannel.WaitForOutputWindow(mockLog, mgsConfig.StreamDataPayloadSize)
+	}()
+	select {
+	case <-resumed:
+		assert.Fail(t, "the output reads resumed without credit")
+	case <-time.After(5 * mgsConfig.OutputWindowPollInterval):
+	}
+
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, getWindowUpdateMessage(t, 4096)))
+	select {
+	case size := <-resumed:
+		assert.Equal(t, mgsConfig.StreamDataPayloadSize, size)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the output reads did not resume after the client granted credit")
+	}
+}
+
+func TestDataChannelIncomingMessageHandlerForInvalidWindowUpdateMessage(t *testing.T) {
+	dataChannel := getDataChannel()
+
+	assert.Error(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, getWindowUpdateMessage(t, 0)))
+	granted, _ := dataChannel.credit()
+	assert.False(t, granted)
+
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, getWindowUpdateMessage(t, mgsConfig.MaxOutputCredit)))
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, getWindowUpdateMessage(t, mgsConfig.MaxOutputCredit)))
+	_, credit := dataChannel.credit()
+	assert.Equal(t, int64(mgsConfig.MaxOutputCredit), credit)
+}
+
+func getWindowUpdateMessage(t *testing.T, windowIncrement int64) []byte {
+	windowUpdate := &mgsContracts.WindowUpdateContent{WindowIncrement: windowIncrement}
+	windowUpdatePayload, err := windowUpdate.Serialize(mockLog)
+	assert.NoError(t, err)
+	agentMessage := getAgentMessage(0, mgsContracts.WindowUpdateMessage, uint32(0), windowUpdatePayload)
+	serializedAgentMessage, err := agentMessage.Serialize(mockLog)
+	assert.NoError(t, err)
+	return serializedAgentMessage
+}
+
 func TestAddDataToIncomingMessageBuffer(t *testing.T) {
 	dataChannel := getDataChannel()
 	dataChannel.IncomingMessageBuffer.Capacity = 2
@@ -456,6 +588,67 @@ func TestDataChannelIncomingMessageHandlerForStartPublicationMessage(t *testing.
 	assert.Equal(t, false, dataChannel.Pause)
 }
 
+func TestBuildHandshakeRequestPayloadSessionType(t *testing.T) {
+	dataChannel := getDataChannel()
+	request := dataChannel.buildHandshakeRequestPayload(mockLog, false)
+	assert.Equal(t, appconfig.PluginNameStandardStream, request.RequestedClientActions[0].ActionParameters.(mgsContracts.SessionTypeRequest).SessionType)
+
+	dataChannel.sessionType = appconfig.PluginNamePort
+	request = dataChannel.buildHandshakeRequestPayload(mockLog, false)
+	assert.Equal(t, appconfig.PluginNamePort, request.RequestedClientActions[0].ActionParameters.(mgsContracts.SessionTypeRequest).SessionType)
+}
+
+func TestRecordScrollback(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.scrollbackSize = 5
+
+	dataChannel.recordScrollback([]byte("aé"))
+	assert.Equal(t, "aé", string(dataChannel.scrollback))
+	// the oldest output goes, with the end of the character cut in half
+	dataChannel.recordScrollback([]byte("éé"))
+	assert.Equal(t, "éé", string(dataChannel.scrollback))
+
+	dataChannel.scrollbackSize = 0
+	dataChannel.recordScrollback([]byte("output"))
+	assert.Equal(t, "éé", string(dataChannel.scrollback))
+}
+
+func TestDataChannelIncomingMessageHandlerReplaysScrollbackOnResume(t *testing.T) {
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	dataChannel.scrollbackSize = 2 * mgsConfig.StreamDataPayloadSize
+	output := "a" + strings.Repeat("é", mgsConfig.StreamDataPayloadSize-1)
+	dataChannel.recordScrollback([]byte(output))
+
+	var replayed []string
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		agentMessage := &mgsContracts.AgentMessage{}
+		assert.NoError(t, agentMessage.Deserialize(mockLog, args.Get(1).([]byte)))
+		assert.True(t, utf8.Valid(agentMessage.Payload))
+		replayed = append(replayed, string(agentMessage.Payload))
+	}).Return(nil)
+
+	// a start_publication message without a pause replays nothing
+	agentMessage := getAgentMessage(0, mgsContracts.StartPublicationMessage, uint32(0), payload)
+	serializedAgentMessage, _ := agentMessage.Serialize(mockLog)
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessage))
+	assert.Empty(t, replayed)
+
+	agentMessage = getAgentMessage(0, mgsContracts.PausePublicationMessage, uint32(0), payload)
+	serializedAgentMessage, _ = agentMessage.Serialize(mockLog)
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessage))
+	agentMessage = getAgentMessage(0, mgsContracts.StartPublicationMessage, uint32(0), payload)
+	serializedAgentMessage, _ = agentMessage.Serialize(mockLog)
+	assert.NoError(t, dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessage))
+
+	assert.Equal(t, 2, len(replayed))
+	assert.Equal(t, output, strings.Join(replayed, ""))
+	assert.Equal(t, int64(2), dataChannel.StreamDataSequenceNumber)
+	// the replay is not recorded again
+	assert.Equal(t, output, string(dataChannel.scrollback))
+}
+
 func TestDataChannelHandshakeResponse(t *testing.T) {
 	dataChannel := getDataChannel()
 
@@ -202,3 +202,17 @@ func (_m *IDataChannel) SetWebSocket(_a0 context.T, mgsService service.Service,
 func (_m *IDataChannel) SkipHandshake(_a0 log.T) {
 	_m.Called(_a0)
 }
+
+// WaitForOutputWindow provides a mock function with given fields: _a0, size
+func (_m *IDataChannel) WaitForOutputWindow(_a0 log.T, size int) int {
+	ret := _m.Called(_a0, size)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(log.T, int) int); ok {
+		r0 = rf(_a0, size)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	"io"
+	"net"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/xtaci/smux"
+)
+
+// MultiplexedPortForwarding is the type of the Port sessions multiplexing the connections of the client with smux,
+// every stream of the client is forwarded to a connection of its own
+const MultiplexedPortForwarding = "MultiplexedPortForwarding"
+
+// muxConn carries the multiplexed session over the data channel, the input of the client is written to its pipe and
+// the frames of the session are sent to the client
+type muxConn struct {
+	log         log.T
+	dataChannel datachannel.IDataChannel
+	reader      *io.PipeReader
+}
+
+// Read reads the input of the client
+func (c *muxConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// Write sends the frames of the session to the client, pausing until the client can take more data
+func (c *muxConn) Write(b []byte) (int, error) {
+	for written := 0; written < len(b); {
+		size := len(b) - written
+		if size > mgsConfig.StreamDataPayloadSize {
+			size = mgsConfig.StreamDataPayloadSize
+		}
+		size = c.dataChannel.WaitForOutputWindow(c.log, size)
+		if err := c.dataChannel.SendStreamDataMessage(c.log, mgsContracts.Output, b[written:written+size]); err != nil {
+			return written, err
+		}
+		written += size
+	}
+	return len(b), nil
+}
+
+// Close closes the input of the client
+func (c *muxConn) Close() error {
+	return c.reader.Close()
+}
+
+// muxConfig returns the config of the multiplexed sessions, the streams have a flow control of their own so a slow
+// connection does not hold the others back, and the data channel checks the client is alive instead of keep alives
+func muxConfig() *smux.Config {
+	config := smux.DefaultConfig()
+	config.Version = 2
+	config.KeepAliveDisabled = true
+	return config
+}
+
+// forwardStreams accepts the streams of the client until the session is closed, and forwards each of them to a new
+// connection to the address.
+func (p *PortPlugin) forwardStreams(log log.T, address string, cancelFlag task.CancelFlag) error {
+	reader, writer := io.Pipe()
+	session, err := smux.Server(&muxConn{log: log, dataChannel: p.dataChannel, reader: reader}, muxConfig())
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	p.setConnected(writer)
+
+	go func() {
+		if state := cancelFlag.Wait(); state == task.Canceled || state == task.ShutDown {
+			session.Close()
+		}
+	}()
+
+	// a stream denied by the SSH session policy closes the session, the others would be denied as well
+	denied := make(chan error, 1)
+	deny := func(err error) {
+		select {
+		case denied <- err:
+		default:
+		}
+		session.Close()
+	}
+
+	for {
+		stream, err := session.AcceptStream()
+		if err != nil {
+			log.Debugf("Multiplexed session to %s closed: %s", address, err)
+			select {
+			case err = <-denied:
+				return err
+			default:
+				return nil
+			}
+		}
+		go forwardStream(log, stream, address, p.sshCheck, deny)
+	}
+}
+
+// forwardStream forwards a stream of the client to a new connection to the address until either of them is closed.
+func forwardStream(log log.T, stream *smux.Stream, address string, sshCheck *sshCheck, deny func(error)) {
+	defer stream.Close()
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		log.Warnf("Unable to forward stream %d to %s: %v", stream.ID(), address, err)
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		io.Copy(conn, stream)
+		conn.Close()
+	}()
+
+	// the greeting of the server is held until the session is checked
+	buf := make([]byte, mgsConfig.StreamDataPayloadSize)
+	n, err := conn.Read(buf)
+	if checkErr := sshCheck.checkGreeting(buf[:n]); checkErr != nil {
+		deny(checkErr)
+		return
+	}
+	if n > 0 {
+		if _, err := stream.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+	if err == nil {
+		io.Copy(stream, conn)
+	}
+}
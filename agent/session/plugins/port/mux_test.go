@@ -0,0 +1,195 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/xtaci/smux"
+)
+
+// pipeDataChannel is a data channel writing the output sent to the client to a pipe
+type pipeDataChannel struct {
+	*dataChannelMock.IDataChannel
+	writer *io.PipeWriter
+}
+
+func (d *pipeDataChannel) WaitForOutputWindow(log log.T, size int) int {
+	return size
+}
+
+func (d *pipeDataChannel) SendStreamDataMessage(log log.T, payloadType mgsContracts.PayloadType, data []byte) error {
+	_, err := d.writer.Write(data)
+	return err
+}
+
+func (d *pipeDataChannel) SendAgentSessionStateMessage(log log.T, sessionStatus mgsContracts.SessionStatus) error {
+	return nil
+}
+
+// clientConn is the client side of a multiplexed session, it writes to the input handler of the plugin and reads the
+// output the plugin sends to the client
+type clientConn struct {
+	plugin sessionplugin.ISessionPlugin
+	reader *io.PipeReader
+}
+
+func (c *clientConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *clientConn) Write(b []byte) (int, error) {
+	if err := c.plugin.InputStreamMessageHandler(log.NewMockLog(), inputMessage(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *clientConn) Close() error {
+	return c.reader.Close()
+}
+
+// echoServer listens on a local port and echoes what every connection sends
+func echoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestExecuteMultiplexesTheStreams(t *testing.T) {
+	listener := echoServer(t)
+	defer listener.Close()
+
+	reader, writer := io.Pipe()
+	plugin, _ := NewPlugin()
+	output := &iohandler.DefaultIOHandler{}
+	config := contracts.Configuration{
+		SessionId: "alice-0123456789",
+		Properties: map[string]interface{}{
+			"portNumber": strconv.Itoa(listener.Addr().(*net.TCPAddr).Port),
+			"type":       MultiplexedPortForwarding,
+		},
+	}
+	cancelFlag := task.NewChanneledCancelFlag()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		plugin.Execute(newContext(appconfig.SsmagentConfig{}), config, cancelFlag, output, &pipeDataChannel{writer: writer})
+	}()
+
+	session, err := smux.Client(&clientConn{plugin: plugin, reader: reader}, muxConfig())
+	assert.NoError(t, err)
+	defer session.Close()
+
+	// the streams are open at the same time, each on a connection of its own
+	var streams []*smux.Stream
+	for i := 0; i < 3; i++ {
+		stream, err := session.OpenStream()
+		assert.NoError(t, err)
+		defer stream.Close()
+		streams = append(streams, stream)
+	}
+	for i, stream := range streams {
+		message := []byte("ping " + strconv.Itoa(i))
+		// larger than a stream data message, the frames are split across messages
+		message = append(message, make([]byte, 2*mgsConfig.StreamDataPayloadSize)...)
+		_, err := stream.Write(message)
+		assert.NoError(t, err)
+		echo := make([]byte, len(message))
+		_, err = io.ReadFull(stream, echo)
+		assert.NoError(t, err)
+		assert.Equal(t, message, echo)
+	}
+
+	cancelFlag.Set(task.Canceled)
+	<-done
+	assert.Equal(t, contracts.ResultStatusCancelled, output.GetStatus())
+}
+
+func TestExecuteInvalidSessionType(t *testing.T) {
+	plugin, _ := NewPlugin()
+	output := &iohandler.DefaultIOHandler{}
+	config := contracts.Configuration{Properties: map[string]interface{}{"portNumber": "80", "type": "RemotePortForwarding"}}
+	plugin.Execute(newContext(appconfig.SsmagentConfig{}), config, task.NewChanneledCancelFlag(), output, &dataChannelMock.IDataChannel{})
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), `invalid session type "RemotePortForwarding"`)
+}
+
+func TestExecuteMultiplexedSshSessionDenied(t *testing.T) {
+	sshListener, _ := sshServer(t)
+
+	reader, writer := io.Pipe()
+	plugin, _ := NewPlugin()
+	output := &iohandler.DefaultIOHandler{}
+	agentConfig := appconfig.SsmagentConfig{SshSession: appconfig.SshSessionCfg{Port: 22, AllowedUsers: []string{"ec2-user"}}}
+	config := contracts.Configuration{
+		SessionId: "alice-0123456789",
+		Properties: map[string]interface{}{
+			"portNumber": strconv.Itoa(sshListener.Addr().(*net.TCPAddr).Port),
+			"type":       MultiplexedPortForwarding,
+			"sshUser":    "root",
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		plugin.Execute(newContext(agentConfig), config, task.NewChanneledCancelFlag(), output, &pipeDataChannel{writer: writer})
+	}()
+
+	session, err := smux.Client(&clientConn{plugin: plugin, reader: reader}, muxConfig())
+	assert.NoError(t, err)
+	defer session.Close()
+	stream, err := session.OpenStream()
+	assert.NoError(t, err)
+	defer stream.Close()
+
+	_, err = stream.Write([]byte("SSH-2.0-OpenSSH_9.0\r\n"))
+	assert.NoError(t, err)
+	<-done
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), `SSH user "root" is not allowed`)
+
+	// the greeting of the server is not forwarded to the client
+	stream.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	greeting := make([]byte, 1)
+	_, err = stream.Read(greeting)
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package port implements the session port plugin, forwarding the session to a TCP port of the instance.
+package port
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// dialTimeout is the time the plugin waits for the name of the host to resolve and for the port to accept the connection
+const dialTimeout = 10 * time.Second
+
+// PortParameters are the properties of the Port session documents
+type PortParameters struct {
+	// Host is the host the session is forwarded to, a name or an address, localhost when it is empty
+	Host string `json:"host"`
+	// PortNumber is the port of the host the session is forwarded to
+	PortNumber string `json:"portNumber"`
+	// SshUser and SshPublicKey are the user and the public key the SSH client logs in with, in the sessions
+	// forwarded to the SSH server
+	SshUser      string `json:"sshUser"`
+	SshPublicKey string `json:"sshPublicKey"`
+	// Type is MultiplexedPortForwarding for the sessions multiplexing the connections of the client, the session is
+	// forwarded to a single connection when it is empty
+	Type string `json:"type"`
+}
+
+// PortPlugin is the type for the port plugin.
+type PortPlugin struct {
+	dataChannel datachannel.IDataChannel
+	// input is where the data of the client is written, the connection to the port or the multiplexed session, it
+	// is set when connected is closed and nil if the session failed to connect
+	input       io.Writer
+	connected   chan struct{}
+	connectOnce sync.Once
+	// sshCheck checks the session against the SSH session policy, nil when the session is not forwarded to the
+	// instance
+	sshCheck *sshCheck
+}
+
+// dialPort connects to the port of an address, it is stubbed in the tests
+var dialPort = func(address string, port int) (net.Conn, error) {
+	return net.DialTimeout("tcp", net.JoinHostPort(address, strconv.Itoa(port)), dialTimeout)
+}
+
+// NewPlugin returns a new instance of the Port Plugin
+func NewPlugin() (sessionplugin.ISessionPlugin, error) {
+	var plugin = PortPlugin{connected: make(chan struct{})}
+	return &plugin, nil
+}
+
+// name returns the name of Port Plugin
+func (p *PortPlugin) name() string {
+	return appconfig.PluginNamePort
+}
+
+// Execute connects to the port and forwards the session to it.
+// It reads incoming message from data channel and writes to the connection.
+// It reads from the connection and writes to data channel.
+func (p *PortPlugin) Execute(context context.T,
+	config agentContracts.Configuration,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler,
+	dataChannel datachannel.IDataChannel) {
+
+	p.dataChannel = dataChannel
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.execute(context, config, cancelFlag, output)
+	}
+}
+
+// execute forwards the session until the connection or the session is closed.
+func (p *PortPlugin) execute(context context.T,
+	config agentContracts.Configuration,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler) {
+
+	log := context.Log()
+	// the input of the client is dropped when the session fails before it is connected
+	defer p.setConnected(nil)
+
+	parameters, conn, err := p.connect(context, config)
+	if err != nil {
+		var failure *ConnectFailure
+		if errors.As(err, &failure) {
+			p.sendConnectFailure(log, failure)
+		}
+		errorString := fmt.Errorf("Unable to start %s session %s: %s", p.name(), config.SessionId, err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+
+	if parameters.Type == MultiplexedPortForwarding {
+		// the connection only checked the target is reachable, every stream of the client connects to it
+		conn.Close()
+		err = p.forwardStreams(log, conn.RemoteAddr().String(), cancelFlag)
+	} else {
+		err = p.forwardConnection(log, conn, cancelFlag)
+	}
+	if err != nil {
+		errorString := fmt.Errorf("Unable to forward %s session %s: %s", p.name(), config.SessionId, err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+
+	if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+	if err = p.dataChannel.SendAgentSessionStateMessage(log, mgsContracts.Terminating); err != nil {
+		log.Errorf("Unable to send AgentSessionState message with session status %s. %v", mgsContracts.Terminating, err)
+	}
+	output.SetExitCode(appconfig.SuccessExitCode)
+	output.SetStatus(agentContracts.ResultStatusSuccess)
+}
+
+// setConnected sets where the input of the client is written and releases the input waiting for the connection,
+// only the first call has an effect
+func (p *PortPlugin) setConnected(input io.Writer) {
+	p.connectOnce.Do(func() {
+		p.input = input
+		close(p.connected)
+	})
+}
+
+// forwardConnection forwards the session to the connection until the connection or the session is closed.
+func (p *PortPlugin) forwardConnection(log log.T, conn net.Conn, cancelFlag task.CancelFlag) error {
+	defer conn.Close()
+	p.setConnected(conn)
+
+	go func() {
+		if state := cancelFlag.Wait(); state == task.Canceled || state == task.ShutDown {
+			conn.Close()
+		}
+	}()
+
+	buf := make([]byte, mgsConfig.StreamDataPayloadSize)
+	for first := true; ; first = false {
+		// pause the reads until the client can take more data
+		readSize := p.dataChannel.WaitForOutputWindow(log, len(buf))
+		n, err := conn.Read(buf[:readSize])
+		if first {
+			if err := p.sshCheck.checkGreeting(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if n > 0 {
+			if err := p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, buf[:n]); err != nil {
+				return fmt.Errorf("unable to send the data of port %s: %s", conn.RemoteAddr(), err)
+			}
+		}
+		if err != nil {
+			log.Debugf("Connection to port %s closed: %s", conn.RemoteAddr(), err)
+			return nil
+		}
+	}
+}
+
+// sendConnectFailure sends the reason the session could not be forwarded to the client, instead of ending a session
+// the client is waiting for the first bytes of
+func (p *PortPlugin) sendConnectFailure(log log.T, failure *ConnectFailure) {
+	payload, err := json.Marshal(failure)
+	if err != nil {
+		log.Errorf("Unable to marshal the connect failure %v: %v", failure, err)
+		return
+	}
+	if err = p.dataChannel.SendStreamDataMessage(log, mgsContracts.Error, payload); err != nil {
+		log.Errorf("Unable to send the connect failure to the client: %v", err)
+	}
+}
+
+// connect validates the properties of the session and connects to its host and port.
+func (p *PortPlugin) connect(context context.T, config agentContracts.Configuration) (parameters PortParameters, conn net.Conn, err error) {
+	if err = jsonutil.Remarshal(config.Properties, &parameters); err != nil {
+		return parameters, nil, fmt.Errorf("invalid format in session properties %v: %v", config.Properties, err)
+	}
+	if parameters.Type != "" && parameters.Type != MultiplexedPortForwarding {
+		return parameters, nil, fmt.Errorf("invalid session type %q", parameters.Type)
+	}
+	port, err := strconv.Atoi(parameters.PortNumber)
+	if err != nil || port < 1 || port > 65535 {
+		return parameters, nil, fmt.Errorf("invalid port number %q", parameters.PortNumber)
+	}
+
+	host := parameters.Host
+	if host == "" {
+		host = "localhost"
+	}
+	if conn, err = dialTarget(host, port); err != nil {
+		return parameters, nil, err
+	}
+
+	// the names and the addresses of the instance all lead to its SSH server, the check applies to the address
+	// connected to rather than to the host of the session
+	sshConfig := context.AppConfig().SshSession
+	p.sshCheck = newSshCheck(conn.RemoteAddr(), func() error {
+		return checkSshSession(context.Log(), sshConfig, config.SessionId, parameters)
+	})
+	if port == sshConfig.Port {
+		if err = p.sshCheck.run(); err != nil {
+			conn.Close()
+			return parameters, nil, err
+		}
+	}
+	return parameters, conn, nil
+}
+
+// InputStreamMessageHandler passes payload byte stream to the connection
+func (p *PortPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+	// the client may send data before the connection is open, it is written once connected
+	<-p.connected
+	if p.input == nil {
+		log.Tracef("Connection unavailable. Reject incoming message packet")
+		return nil
+	}
+
+	switch mgsContracts.PayloadType(streamDataMessage.PayloadType) {
+	case mgsContracts.Output:
+		log.Tracef("Output message received: %d", streamDataMessage.SequenceNumber)
+		if _, err := p.input.Write(streamDataMessage.Payload); err != nil {
+			log.Errorf("Unable to write to port, err: %v.", err)
+			return err
+		}
+	}
+	return nil
+}
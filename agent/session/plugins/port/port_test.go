@@ -0,0 +1,219 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mockLog = log.NewMockLog()
+
+// newContext returns a mocked context with the agent config
+func newContext(config appconfig.SsmagentConfig) *context.Mock {
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(mockLog)
+	ctx.On("AppConfig").Return(config)
+	return ctx
+}
+
+// newDataChannel returns a mocked data channel collecting the output sent to the client
+func newDataChannel(output *[]byte, mutex *sync.Mutex) *dataChannelMock.IDataChannel {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	dataChannel.On("WaitForOutputWindow", mock.Anything, mock.Anything).Return(mgsConfig.StreamDataPayloadSize)
+	dataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, mock.Anything).Run(func(args mock.Arguments) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		*output = append(*output, args.Get(2).([]byte)...)
+	}).Return(nil)
+	dataChannel.On("SendAgentSessionStateMessage", mock.Anything, mgsContracts.Terminating).Return(nil)
+	return dataChannel
+}
+
+// inputMessage returns a message of the client carrying data for the port
+func inputMessage(data string) mgsContracts.AgentMessage {
+	return mgsContracts.AgentMessage{
+		MessageType: mgsContracts.InputStreamDataMessage,
+		PayloadType: uint32(mgsContracts.Output),
+		Payload:     []byte(data),
+	}
+}
+
+func TestName(t *testing.T) {
+	plugin, _ := NewPlugin()
+	assert.Equal(t, appconfig.PluginNamePort, plugin.(*PortPlugin).name())
+}
+
+func TestExecuteForwardsThePort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, 4)
+		if _, err = io.ReadFull(conn, request); err == nil && string(request) == "ping" {
+			conn.Write([]byte("pong"))
+		}
+	}()
+
+	var sent []byte
+	var mutex sync.Mutex
+	dataChannel := newDataChannel(&sent, &mutex)
+	plugin, _ := NewPlugin()
+	output := &iohandler.DefaultIOHandler{}
+	config := contracts.Configuration{
+		SessionId:  "alice-0123456789",
+		Properties: map[string]interface{}{"portNumber": strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		plugin.Execute(newContext(appconfig.SsmagentConfig{}), config, task.NewChanneledCancelFlag(), output, dataChannel)
+	}()
+	assert.NoError(t, plugin.InputStreamMessageHandler(log.NewMockLog(), inputMessage("ping")))
+	<-done
+
+	assert.Equal(t, contracts.ResultStatusSuccess, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, "pong", string(sent))
+	dataChannel.AssertCalled(t, "SendAgentSessionStateMessage", mock.Anything, mgsContracts.Terminating)
+}
+
+func TestExecuteInvalidPortNumber(t *testing.T) {
+	for _, portNumber := range []string{"", "http", "0", "65536"} {
+		plugin, _ := NewPlugin()
+		output := &iohandler.DefaultIOHandler{}
+		config := contracts.Configuration{Properties: map[string]interface{}{"portNumber": portNumber}}
+		plugin.Execute(newContext(appconfig.SsmagentConfig{}), config, task.NewChanneledCancelFlag(), output, &dataChannelMock.IDataChannel{})
+
+		assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus(), portNumber)
+		// the input of a session which failed to connect is dropped
+		assert.NoError(t, plugin.InputStreamMessageHandler(log.NewMockLog(), inputMessage("ping")))
+	}
+}
+
+// sshServer listens on a local port and greets every connection like an SSH server, it returns the data received on
+// the first connection
+func sshServer(t *testing.T) (net.Listener, chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan []byte, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("SSH-2.0-OpenSSH_8.7\r\n"))
+				data, _ := ioutil.ReadAll(conn)
+				select {
+				case received <- data:
+				default:
+				}
+			}()
+		}
+	}()
+	return listener, received
+}
+
+// executeSshSession forwards a session of the user to the SSH server, with ec2-user the only allowed user
+func executeSshSession(t *testing.T, listener net.Listener, sshPort int, sshUser string) (output *iohandler.DefaultIOHandler, sent []byte) {
+	var mutex sync.Mutex
+	dataChannel := newDataChannel(&sent, &mutex)
+	agentConfig := appconfig.SsmagentConfig{SshSession: appconfig.SshSessionCfg{Port: sshPort, AllowedUsers: []string{"ec2-user"}}}
+	plugin, _ := NewPlugin()
+	output = &iohandler.DefaultIOHandler{}
+	config := contracts.Configuration{
+		SessionId:  "alice-0123456789",
+		Properties: map[string]interface{}{"portNumber": strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), "sshUser": sshUser},
+	}
+	cancelFlag := task.NewChanneledCancelFlag()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		plugin.Execute(newContext(agentConfig), config, cancelFlag, output, dataChannel)
+	}()
+	// the input fails when the session was denied before it is written
+	err := plugin.InputStreamMessageHandler(log.NewMockLog(), inputMessage("SSH-2.0-OpenSSH_9.0\r\n"))
+	if sshUser == "ec2-user" {
+		assert.NoError(t, err)
+		// the allowed session is forwarded until it is canceled
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			mutex.Lock()
+			received := len(sent) > 0
+			mutex.Unlock()
+			if received {
+				break
+			}
+		}
+		cancelFlag.Set(task.Canceled)
+	}
+	<-done
+	mutex.Lock()
+	defer mutex.Unlock()
+	return output, sent
+}
+
+func TestExecuteSshSessionDenied(t *testing.T) {
+	listener, received := sshServer(t)
+	output, sent := executeSshSession(t, listener, listener.Addr().(*net.TCPAddr).Port, "root")
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), `SSH user "root" is not allowed`)
+	assert.Empty(t, sent)
+	assert.Empty(t, <-received, "a denied SSH session must not be forwarded")
+}
+
+func TestExecuteSshSessionDeniedOnTheGreeting(t *testing.T) {
+	listener, _ := sshServer(t)
+	// the SSH server does not listen on SshSession.Port
+	output, sent := executeSshSession(t, listener, 22, "root")
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), `SSH user "root" is not allowed`)
+	assert.Empty(t, sent, "the greeting of the server is not sent to a denied client")
+}
+
+func TestExecuteSshSessionAllowedOnTheGreeting(t *testing.T) {
+	listener, received := sshServer(t)
+	output, sent := executeSshSession(t, listener, 22, "ec2-user")
+
+	assert.Equal(t, contracts.ResultStatusCancelled, output.GetStatus(), output.GetStderr())
+	assert.Equal(t, "SSH-2.0-OpenSSH_8.7\r\n", string(sent))
+	assert.Equal(t, "SSH-2.0-OpenSSH_9.0\r\n", string(<-received))
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// sshGreeting starts the identification string an SSH server sends first
+var sshGreeting = []byte("SSH-")
+
+// interfaceAddrs returns the addresses of the network interfaces of the instance, it is stubbed in the tests
+var interfaceAddrs = net.InterfaceAddrs
+
+// sshCheck checks a session forwarded to the local SSH server against the SshSession config once. A session is
+// checked before it is forwarded when it connects to SshSession.Port, and on the greeting of the server otherwise, so
+// that an SSH server listening on another port of the instance is covered as well.
+type sshCheck struct {
+	once  sync.Once
+	check func() error
+	err   error
+}
+
+// newSshCheck returns the check of a session connected to the address, nil when the address is not one of the
+// instance since the policy only applies to its own SSH server
+func newSshCheck(address net.Addr, check func() error) *sshCheck {
+	if !isLocalAddress(address) {
+		return nil
+	}
+	return &sshCheck{check: check}
+}
+
+// run checks the session the first time it is called and returns the same decision afterwards
+func (c *sshCheck) run() error {
+	if c == nil {
+		return nil
+	}
+	c.once.Do(func() { c.err = c.check() })
+	return c.err
+}
+
+// checkGreeting checks the session when the first data the server sends is the greeting of an SSH server
+func (c *sshCheck) checkGreeting(data []byte) error {
+	if c == nil || !bytes.HasPrefix(data, sshGreeting) {
+		return nil
+	}
+	return c.run()
+}
+
+// isLocalAddress returns whether the address is a loopback address or an address of the network interfaces
+func isLocalAddress(address net.Addr) bool {
+	tcpAddress, ok := address.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	if tcpAddress.IP.IsLoopback() || tcpAddress.IP.IsUnspecified() {
+		return true
+	}
+	addresses, err := interfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, interfaceAddress := range addresses {
+		if ipNet, ok := interfaceAddress.(*net.IPNet); ok && ipNet.IP.Equal(tcpAddress.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSshSession checks the user and the public key of a session forwarded to the SSH server against the SshSession
+// config and records the decision in the audit log. The check is advisory: the agent only sees the encrypted SSH
+// stream, so the user and the key are the ones the client announces in the session properties, not the ones it logs
+// in with. The SSH server authenticates the session afterwards and its own configuration is what enforces the policy.
+func checkSshSession(log log.T, sshConfig appconfig.SshSessionCfg, sessionId string, parameters PortParameters) error {
+	fingerprint, err := checkSshPolicy(sshConfig, parameters)
+	event := audit.Event{
+		Type:      audit.SshSessionAllowed,
+		SessionID: sessionId,
+		RunAsUser: parameters.SshUser,
+		Detail:    fingerprint,
+	}
+	if err != nil {
+		event.Type = audit.SshSessionDenied
+		event.Detail = err.Error()
+		audit.Record(event)
+		return err
+	}
+	audit.Record(event)
+	log.Infof("SSH session %s allowed for the user %q and the key %s announced by the client", sessionId, parameters.SshUser, fingerprint)
+	return nil
+}
+
+// checkSshPolicy returns the fingerprint of the public key the session announces, or an error when the user is not
+// one of the allowed users or the key is not one of the authorized keys
+func checkSshPolicy(sshConfig appconfig.SshSessionCfg, parameters PortParameters) (fingerprint string, err error) {
+	if len(sshConfig.AllowedUsers) > 0 && !isAllowedUser(sshConfig.AllowedUsers, parameters.SshUser) {
+		return "", fmt.Errorf("SSH user %q is not allowed", parameters.SshUser)
+	}
+	if parameters.SshPublicKey == "" {
+		if sshConfig.AuthorizedKeysFile != "" {
+			return "", errors.New("the SSH public key of the session is required")
+		}
+		return "", nil
+	}
+
+	keyType, key, err := parsePublicKey(parameters.SshPublicKey)
+	if err != nil {
+		return "", err
+	}
+	fingerprint = keyFingerprint(key)
+	if sshConfig.AuthorizedKeysFile == "" {
+		return fingerprint, nil
+	}
+	authorized, err := isAuthorizedKey(sshConfig.AuthorizedKeysFile, keyType, key)
+	if err != nil {
+		return fingerprint, fmt.Errorf("unable to read the authorized keys: %v", err)
+	}
+	if !authorized {
+		return fingerprint, fmt.Errorf("SSH key %s is not authorized", fingerprint)
+	}
+	return fingerprint, nil
+}
+
+// isAllowedUser returns whether the user is one of the allowed users
+func isAllowedUser(allowedUsers []string, user string) bool {
+	for _, allowedUser := range allowedUsers {
+		if user != "" && user == allowedUser {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuthorizedKey returns whether the key is one of the keys of the authorized keys file
+func isAuthorizedKey(authorizedKeysFile string, keyType string, key []byte) (bool, error) {
+	file, err := os.Open(authorizedKeysFile)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if authorizedType, authorizedKey, err := parsePublicKey(line); err == nil && authorizedType == keyType && bytes.Equal(authorizedKey, key) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// parsePublicKey returns the type and the wire format of a public key in the authorized_keys format, the options
+// before the key and the comment after it are skipped
+func parsePublicKey(line string) (keyType string, key []byte, err error) {
+	fields := strings.Fields(line)
+	for i := 0; i+1 < len(fields); i++ {
+		key, err := base64.StdEncoding.DecodeString(fields[i+1])
+		if err != nil {
+			continue
+		}
+		// the wire format of the key starts with its type
+		if len(key) >= 4 {
+			typeLength := binary.BigEndian.Uint32(key)
+			if uint64(typeLength) <= uint64(len(key)-4) && string(key[4:4+typeLength]) == fields[i] {
+				return fields[i], key, nil
+			}
+		}
+	}
+	return "", nil, errors.New("invalid SSH public key")
+}
+
+// keyFingerprint returns the SHA256 fingerprint of the key, as ssh-keygen -l displays it
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
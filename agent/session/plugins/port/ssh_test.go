@@ -0,0 +1,128 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	aliceKey         = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID2pmg6B9kQDoJJH3RXZYNFk7iJ0iKYVEKCvO6tppRIa alice@laptop"
+	aliceFingerprint = "SHA256:rp1HT6U/qy9fC0RDu2zI1YkGO8h4LpBvKbEun8/eTCU"
+	bobKey           = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIC8CXBhnKOY/Yjsabsi9AB28jsYzkQs8bUZHIKROpfRy bob@laptop"
+)
+
+// writeAuthorizedKeys writes the authorized keys file of the tests
+func writeAuthorizedKeys(t *testing.T, lines string) string {
+	dir, err := ioutil.TempDir("", "port")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "authorized_keys")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(lines), 0600))
+	return path
+}
+
+func TestParsePublicKey(t *testing.T) {
+	keyType, key, err := parsePublicKey(aliceKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519", keyType)
+	assert.Equal(t, aliceFingerprint, keyFingerprint(key))
+
+	keyType, _, err = parsePublicKey(`from="10.0.0.0/8",no-agent-forwarding ` + aliceKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519", keyType)
+
+	_, _, err = parsePublicKey("ssh-rsa AAAAC3NzaC1lZDI1NTE5AAAAID2pmg6B9kQDoJJH3RXZYNFk7iJ0iKYVEKCvO6tppRIa")
+	assert.Error(t, err, "the type of the key does not match its wire format")
+	_, _, err = parsePublicKey("ssh-ed25519 not-a-key")
+	assert.Error(t, err)
+}
+
+func TestCheckSshPolicy(t *testing.T) {
+	authorizedKeys := writeAuthorizedKeys(t, "# team keys\n\n"+`no-pty `+aliceKey+"\n")
+
+	fingerprint, err := checkSshPolicy(appconfig.SshSessionCfg{}, PortParameters{})
+	assert.NoError(t, err, "the sessions are not checked without a policy")
+	assert.Empty(t, fingerprint)
+
+	sshConfig := appconfig.SshSessionCfg{AllowedUsers: []string{"ec2-user"}, AuthorizedKeysFile: authorizedKeys}
+	fingerprint, err = checkSshPolicy(sshConfig, PortParameters{SshUser: "ec2-user", SshPublicKey: aliceKey})
+	assert.NoError(t, err)
+	assert.Equal(t, aliceFingerprint, fingerprint)
+
+	_, err = checkSshPolicy(sshConfig, PortParameters{SshUser: "root", SshPublicKey: aliceKey})
+	assert.Error(t, err)
+	_, err = checkSshPolicy(sshConfig, PortParameters{SshUser: "ec2-user", SshPublicKey: bobKey})
+	assert.Error(t, err)
+	_, err = checkSshPolicy(sshConfig, PortParameters{SshUser: "ec2-user"})
+	assert.Error(t, err, "the key is required with an authorized keys file")
+
+	sshConfig.AuthorizedKeysFile = filepath.Join(filepath.Dir(authorizedKeys), "missing")
+	_, err = checkSshPolicy(sshConfig, PortParameters{SshUser: "ec2-user", SshPublicKey: aliceKey})
+	assert.Error(t, err)
+}
+
+func TestCheckSshSessionAudit(t *testing.T) {
+	var events []audit.Event
+	audit.SetObserver("port", func(event audit.Event) { events = append(events, event) })
+	defer audit.SetObserver("port", nil)
+
+	sshConfig := appconfig.SshSessionCfg{AllowedUsers: []string{"ec2-user"}}
+	assert.NoError(t, checkSshSession(mockLog, sshConfig, "alice-0123456789", PortParameters{SshUser: "ec2-user", SshPublicKey: aliceKey}))
+	assert.Error(t, checkSshSession(mockLog, sshConfig, "alice-0123456789", PortParameters{SshUser: "root"}))
+
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, audit.SshSessionAllowed, events[0].Type)
+		assert.Equal(t, "ec2-user", events[0].RunAsUser)
+		assert.Equal(t, aliceFingerprint, events[0].Detail)
+		assert.Equal(t, audit.SshSessionDenied, events[1].Type)
+		assert.Equal(t, "root", events[1].RunAsUser)
+	}
+}
+
+func TestIsLocalAddress(t *testing.T) {
+	defer func(addrs func() ([]net.Addr, error)) { interfaceAddrs = addrs }(interfaceAddrs)
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(24, 32)}}, nil
+	}
+
+	assert.True(t, isLocalAddress(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}))
+	assert.True(t, isLocalAddress(&net.TCPAddr{IP: net.ParseIP("::1"), Port: 22}))
+	assert.True(t, isLocalAddress(&net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 22}))
+	assert.False(t, isLocalAddress(&net.TCPAddr{IP: net.ParseIP("10.0.0.6"), Port: 22}))
+}
+
+func TestSshCheck(t *testing.T) {
+	checks := 0
+	check := func() error {
+		checks++
+		return assert.AnError
+	}
+	assert.Nil(t, newSshCheck(&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}, check), "the remote SSH servers are not checked")
+
+	sshCheck := newSshCheck(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2222}, check)
+	assert.NoError(t, sshCheck.checkGreeting([]byte("HTTP/1.1 200 OK\r\n")))
+	assert.Equal(t, 0, checks)
+	assert.Equal(t, assert.AnError, sshCheck.checkGreeting([]byte("SSH-2.0-OpenSSH_8.7\r\n")))
+	assert.Equal(t, assert.AnError, sshCheck.run())
+	assert.Equal(t, 1, checks, "the session is checked once")
+}
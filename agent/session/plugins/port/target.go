@@ -0,0 +1,85 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Reasons the sessions could not be forwarded to their target
+const (
+	ReasonDNSResolutionFailed = "DNSResolutionFailed"
+	ReasonConnectionRefused   = "ConnectionRefused"
+	ReasonConnectionTimedOut  = "ConnectionTimedOut"
+	ReasonConnectionFailed    = "ConnectionFailed"
+)
+
+// ConnectFailure is the reason a session could not be forwarded to its target, sent to the client in an Error stream
+// data message before the session ends
+type ConnectFailure struct {
+	Reason  string `json:"reason"`
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Message string `json:"message"`
+}
+
+// Error returns the reason and the message of the failure
+func (failure *ConnectFailure) Error() string {
+	return fmt.Sprintf("%s: %s", failure.Reason, failure.Message)
+}
+
+// lookupHost resolves the names of the hosts, it is stubbed in the tests
+var lookupHost = net.DefaultResolver.LookupHost
+
+// dialTarget resolves the host and connects to the first of its addresses accepting the connection, it returns a
+// ConnectFailure when the host does not resolve or none of its addresses accepts the connection
+func dialTarget(host string, port int) (net.Conn, error) {
+	addresses := []string{host}
+	if net.ParseIP(host) == nil {
+		ctx, cancel := gocontext.WithTimeout(gocontext.Background(), dialTimeout)
+		defer cancel()
+		resolved, err := lookupHost(ctx, host)
+		if err == nil && len(resolved) == 0 {
+			err = fmt.Errorf("no address found for %s", host)
+		}
+		if err != nil {
+			return nil, &ConnectFailure{Reason: ReasonDNSResolutionFailed, Host: host, Port: port, Message: err.Error()}
+		}
+		addresses = resolved
+	}
+
+	var err error
+	for _, address := range addresses {
+		var conn net.Conn
+		if conn, err = dialPort(address, port); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, &ConnectFailure{Reason: failureReason(err), Host: host, Port: port, Message: err.Error()}
+}
+
+// failureReason returns the reason of a failed connection
+func failureReason(err error) string {
+	var netErr net.Error
+	switch {
+	case isConnectionRefused(err):
+		return ReasonConnectionRefused
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonConnectionTimedOut
+	}
+	return ReasonConnectionFailed
+}
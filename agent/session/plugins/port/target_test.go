@@ -0,0 +1,109 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package port
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// closedPort returns a port nothing listens on
+func closedPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+// timeoutError is a network error which timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestDialTargetResolvesTheHost(t *testing.T) {
+	defer func(lookup func(ctx gocontext.Context, host string) ([]string, error)) { lookupHost = lookup }(lookupHost)
+	lookupHost = func(ctx gocontext.Context, host string) ([]string, error) {
+		assert.Equal(t, "db.example.internal", host)
+		return []string{"127.0.0.1"}, nil
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	conn, err := dialTarget("db.example.internal", listener.Addr().(*net.TCPAddr).Port)
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+}
+
+func TestDialTargetFailures(t *testing.T) {
+	defer func(lookup func(ctx gocontext.Context, host string) ([]string, error)) { lookupHost = lookup }(lookupHost)
+	lookupHost = func(ctx gocontext.Context, host string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	_, err := dialTarget("missing.example.internal", 5432)
+	var failure *ConnectFailure
+	if assert.True(t, errors.As(err, &failure)) {
+		assert.Equal(t, ReasonDNSResolutionFailed, failure.Reason)
+		assert.Equal(t, "missing.example.internal", failure.Host)
+		assert.Equal(t, 5432, failure.Port)
+	}
+
+	port := closedPort(t)
+	_, err = dialTarget("127.0.0.1", port)
+	if assert.True(t, errors.As(err, &failure)) {
+		assert.Equal(t, ReasonConnectionRefused, failure.Reason)
+		assert.Equal(t, port, failure.Port)
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	assert.Equal(t, ReasonConnectionTimedOut, failureReason(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}))
+	assert.Equal(t, ReasonConnectionFailed, failureReason(errors.New("network is unreachable")))
+}
+
+func TestExecuteSendsTheConnectFailure(t *testing.T) {
+	dataChannel := &dataChannelMock.IDataChannel{}
+	dataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Error, mock.Anything).Return(nil)
+	plugin, _ := NewPlugin()
+	output := &iohandler.DefaultIOHandler{}
+	port := closedPort(t)
+	config := contracts.Configuration{Properties: map[string]interface{}{"host": "127.0.0.1", "portNumber": strconv.Itoa(port)}}
+	plugin.Execute(newContext(appconfig.SsmagentConfig{}), config, task.NewChanneledCancelFlag(), output, dataChannel)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Contains(t, output.GetStderr(), ReasonConnectionRefused)
+	if assert.Len(t, dataChannel.Calls, 1) {
+		var failure ConnectFailure
+		assert.NoError(t, json.Unmarshal(dataChannel.Calls[0].Arguments.Get(2).([]byte), &failure))
+		assert.Equal(t, ConnectFailure{Reason: ReasonConnectionRefused, Host: "127.0.0.1", Port: port, Message: failure.Message}, failure)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+//go:build windows
+// +build windows
+
+package port
+
+import (
+	"errors"
+	"syscall"
+)
+
+// wsaeConnRefused is the WSAECONNREFUSED error of Winsock, returned when nothing listens on the port
+const wsaeConnRefused = syscall.Errno(10061)
+
+// isConnectionRefused returns whether nothing listens on the port of a failed connection
+func isConnectionRefused(err error) bool {
+	return errors.Is(err, wsaeConnRefused) || errors.Is(err, syscall.ECONNREFUSED)
+}
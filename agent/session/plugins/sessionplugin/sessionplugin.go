@@ -57,7 +57,15 @@ func (p *SessionPlugin) Execute(context context.T,
 	log := context.Log()
 	kmsKeyId := config.KmsKeyId
 
-	dataChannel, err := getDataChannelForSessionPlugin(context, config.SessionId, config.ClientId, cancelFlag, p.sessionPlugin.InputStreamMessageHandler)
+	if err := runPreSessionHook(context, config); err != nil {
+		errorString := fmt.Errorf("Session %s refused: %s", config.SessionId, err)
+		output.MarkAsFailed(errorString)
+		log.Error(errorString)
+		return
+	}
+	defer runPostSessionHook(context, config, output)
+
+	dataChannel, err := getDataChannelForSessionPlugin(context, config.SessionId, config.ClientId, config.PluginName, cancelFlag, p.sessionPlugin.InputStreamMessageHandler)
 	if err != nil {
 		errorString := fmt.Errorf("Setting up data channel with id %s failed: %s", config.SessionId, err)
 		output.MarkAsFailed(errorString)
@@ -90,13 +98,14 @@ func (p *SessionPlugin) isEncryptionEnabled(kmsKeyId string) bool {
 }
 
 // getDataChannelForSessionPlugin opens new data channel to MGS service
-var getDataChannelForSessionPlugin = func(context context.T, sessionId string, clientId string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+var getDataChannelForSessionPlugin = func(context context.T, sessionId string, clientId string, sessionType string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
 	retryer := retry.ExponentialRetryer{
 		CallableFunc: func() (channel interface{}, err error) {
 			return datachannel.NewDataChannel(
 				context,
 				sessionId,
 				clientId,
+				sessionType,
 				inputStreamMessageHandler,
 				cancelFlag)
 		},
@@ -0,0 +1,91 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sessionplugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+)
+
+const (
+	hookEnvSessionID = "SSM_SESSION_ID"
+	hookEnvPrincipal = "SSM_SESSION_PRINCIPAL"
+	hookEnvRunAsUser = "SSM_SESSION_RUN_AS_USER"
+	hookEnvStatus    = "SSM_SESSION_STATUS"
+)
+
+// runHookCommand runs a hook executable with env added to the environment of the agent, it is stubbed in the tests
+var runHookCommand = executers.RunHook
+
+// runPreSessionHook runs the administrator-defined pre-session hook, if any, the session must be refused when it fails
+func runPreSessionHook(context context.T, config contracts.Configuration) error {
+	hooks := context.AppConfig().SessionHooks
+	if hooks.PreSession == "" {
+		return nil
+	}
+	return runSessionHook(context, "pre-session", hooks.PreSession, hooks.TimeoutSeconds, hookEnvironment(config))
+}
+
+// runPostSessionHook runs the administrator-defined post-session hook, if any, with the status of the session
+func runPostSessionHook(context context.T, config contracts.Configuration, output iohandler.IOHandler) {
+	hooks := context.AppConfig().SessionHooks
+	if hooks.PostSession == "" {
+		return
+	}
+	env := append(hookEnvironment(config), fmt.Sprintf("%v=%v", hookEnvStatus, output.GetStatus()))
+	// the session has already ended, a failing post-session hook is only logged
+	if err := runSessionHook(context, "post-session", hooks.PostSession, hooks.TimeoutSeconds, env); err != nil {
+		context.Log().Warn(err)
+	}
+}
+
+// hookEnvironment returns the environment variables describing the session to a hook
+func hookEnvironment(config contracts.Configuration) []string {
+	return []string{
+		fmt.Sprintf("%v=%v", hookEnvSessionID, config.SessionId),
+		fmt.Sprintf("%v=%v", hookEnvPrincipal, sessionPrincipal(config.SessionId)),
+		fmt.Sprintf("%v=%v", hookEnvRunAsUser, audit.RunAsUser(config.RunAsElevated)),
+	}
+}
+
+// sessionPrincipal returns the principal which started the session, Session Manager names the sessions after the
+// principal followed by a random suffix
+func sessionPrincipal(sessionId string) string {
+	if i := strings.LastIndex(sessionId, "-"); i > 0 {
+		return sessionId[:i]
+	}
+	return ""
+}
+
+// runSessionHook runs a hook and logs its output
+func runSessionHook(context context.T, stage string, path string, timeoutSeconds int, env []string) error {
+	log := context.Log()
+	log.Infof("Running %v hook %v", stage, path)
+	output, err := runHookCommand(path, env, time.Duration(timeoutSeconds)*time.Second)
+	if output = strings.TrimSpace(output); output != "" {
+		log.Infof("%v hook output: %v", stage, output)
+	}
+	if err != nil {
+		return fmt.Errorf("%v hook %v failed: %v", stage, path, err)
+	}
+	return nil
+}
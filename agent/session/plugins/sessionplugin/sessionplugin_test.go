@@ -17,7 +17,9 @@ package sessionplugin
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	iohandlerMock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
@@ -27,6 +29,7 @@ import (
 	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
 	sessionPluginMock "github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -54,7 +57,7 @@ func (suite *SessionPluginTestSuite) SetupTest() {
 	}
 }
 
-//Execute the test suite
+// Execute the test suite
 func TestShellTestSuite(t *testing.T) {
 	suite.Run(t, new(SessionPluginTestSuite))
 }
@@ -62,7 +65,7 @@ func TestShellTestSuite(t *testing.T) {
 // Testing Execute
 func (suite *SessionPluginTestSuite) TestExecute() {
 	getDataChannelForSessionPlugin =
-		func(context context.T, sessionId string, clientId string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+		func(context context.T, sessionId string, clientId string, sessionType string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
 			return suite.mockDataChannel, nil
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
@@ -81,7 +84,7 @@ func (suite *SessionPluginTestSuite) TestExecute() {
 
 func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeSuccess() {
 	getDataChannelForSessionPlugin =
-		func(context context.T, sessionId string, clientId string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+		func(context context.T, sessionId string, clientId string, sessionType string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
 			return suite.mockDataChannel, nil
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
@@ -101,7 +104,7 @@ func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeSuccess() {
 
 func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeFailed() {
 	getDataChannelForSessionPlugin =
-		func(context context.T, sessionId string, clientId string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+		func(context context.T, sessionId string, clientId string, sessionType string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
 			return suite.mockDataChannel, nil
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
@@ -119,3 +122,75 @@ func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeFailed() {
 	suite.mockDataChannel.AssertExpectations(suite.T())
 	suite.mockSessionPlugin.AssertExpectations(suite.T())
 }
+
+// mockContextWithSessionHooks returns a context with the session hooks in its configuration
+func mockContextWithSessionHooks(log log.T, preSession string, postSession string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.SessionHooks.PreSession = preSession
+	config.SessionHooks.PostSession = postSession
+	ctx.On("Log").Return(log)
+	ctx.On("AppConfig").Return(config)
+	return ctx
+}
+
+func (suite *SessionPluginTestSuite) TestExecutePreSessionHookFailureRefusesSession() {
+	ctx := mockContextWithSessionHooks(suite.mockLog, "/opt/hooks/pre-session", "/opt/hooks/post-session")
+	var ran []string
+	runHookCommand = func(path string, env []string, timeout time.Duration) (string, error) {
+		ran = append(ran, path)
+		return "denied by local policy", errors.New("exit status 1")
+	}
+	getDataChannelForSessionPlugin =
+		func(context context.T, sessionId string, clientId string, sessionType string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+			suite.Fail("the data channel must not be opened")
+			return suite.mockDataChannel, nil
+		}
+	suite.mockIohandler.On("MarkAsFailed", mock.Anything).Return()
+
+	suite.sessionPlugin.Execute(ctx,
+		contracts.Configuration{SessionId: "alice-0123456789abcdef0"},
+		suite.mockCancelFlag,
+		suite.mockIohandler)
+
+	assert.Equal(suite.T(), []string{"/opt/hooks/pre-session"}, ran)
+	suite.mockIohandler.AssertCalled(suite.T(), "MarkAsFailed", mock.Anything)
+	suite.mockSessionPlugin.AssertNotCalled(suite.T(), "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *SessionPluginTestSuite) TestExecuteRunsSessionHooks() {
+	ctx := mockContextWithSessionHooks(suite.mockLog, "/opt/hooks/pre-session", "/opt/hooks/post-session")
+	envs := map[string][]string{}
+	runHookCommand = func(path string, env []string, timeout time.Duration) (string, error) {
+		assert.Equal(suite.T(), time.Duration(appconfig.DefaultSessionHooksTimeoutSeconds)*time.Second, timeout)
+		envs[path] = env
+		return "", nil
+	}
+	getDataChannelForSessionPlugin =
+		func(context context.T, sessionId string, clientId string, sessionType string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+			return suite.mockDataChannel, nil
+		}
+	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockLog, mgsContracts.Connected).Return(nil)
+	suite.mockDataChannel.On("Close", suite.mockLog).Return(nil)
+	suite.mockDataChannel.On("SkipHandshake", suite.mockLog).Return()
+	suite.mockSessionPlugin.On("Execute", ctx, mock.Anything, suite.mockCancelFlag, suite.mockIohandler, suite.mockDataChannel).Return()
+	suite.mockIohandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
+
+	suite.sessionPlugin.Execute(ctx,
+		contracts.Configuration{SessionId: "alice-0123456789abcdef0"},
+		suite.mockCancelFlag,
+		suite.mockIohandler)
+
+	suite.mockSessionPlugin.AssertExpectations(suite.T())
+	preSessionEnv := envs["/opt/hooks/pre-session"]
+	assert.Contains(suite.T(), preSessionEnv, "SSM_SESSION_ID=alice-0123456789abcdef0")
+	assert.Contains(suite.T(), preSessionEnv, "SSM_SESSION_PRINCIPAL=alice")
+	assert.Contains(suite.T(), preSessionEnv, "SSM_SESSION_RUN_AS_USER="+appconfig.DefaultRunAsUserName)
+	assert.Contains(suite.T(), envs["/opt/hooks/post-session"], "SSM_SESSION_STATUS="+string(contracts.ResultStatusSuccess))
+}
+
+func (suite *SessionPluginTestSuite) TestSessionPrincipal() {
+	assert.Equal(suite.T(), "alice", sessionPrincipal("alice-0123456789abcdef0"))
+	assert.Equal(suite.T(), "my-role-session", sessionPrincipal("my-role-session-0123456789abcdef0"))
+	assert.Equal(suite.T(), "", sessionPrincipal("session"))
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package shell
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// typescriptFileExtension is the extension of the typescript of the session output
+	typescriptFileExtension = ".typescript"
+	// timingFileExtension is the extension of the timing file of the typescript
+	timingFileExtension = ".timing"
+)
+
+// scriptRecorder records the session output as the typescript and the timing file script --timing writes, the
+// typescript starts with a header line scriptreplay skips and each line of the timing file holds the delay in seconds
+// since the previous output and the number of bytes of the output.
+type scriptRecorder struct {
+	typescript *os.File
+	timing     *os.File
+	last       time.Time
+}
+
+// newScriptRecorder creates the typescript and the timing file of the session output.
+func newScriptRecorder(typescriptPath string, timingPath string) (recorder *scriptRecorder, err error) {
+	recorder = &scriptRecorder{last: time.Now()}
+	if recorder.typescript, err = os.Create(typescriptPath); err != nil {
+		return nil, err
+	}
+	if recorder.timing, err = os.Create(timingPath); err != nil {
+		recorder.typescript.Close()
+		return nil, err
+	}
+	if _, err = fmt.Fprintf(recorder.typescript, "Script started on %s\n", recorder.last.Format("2006-01-02 15:04:05-07:00")); err != nil {
+		recorder.Close()
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// Write appends the output to the typescript and its delay and size to the timing file.
+func (r *scriptRecorder) Write(output []byte) (int, error) {
+	if len(output) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	n, err := r.typescript.Write(output)
+	if err != nil {
+		return n, err
+	}
+	if _, err = fmt.Fprintf(r.timing, "%.6f %d\n", now.Sub(r.last).Seconds(), n); err != nil {
+		return n, err
+	}
+	r.last = now
+	return n, nil
+}
+
+// Close closes the typescript and the timing file.
+func (r *scriptRecorder) Close() error {
+	timingErr := r.timing.Close()
+	if err := r.typescript.Close(); err != nil {
+		return err
+	}
+	return timingErr
+}
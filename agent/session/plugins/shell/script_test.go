@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	typescriptPath, timingPath := filepath.Join(dir, "session.typescript"), filepath.Join(dir, "session.timing")
+
+	recorder, err := newScriptRecorder(typescriptPath, timingPath)
+	assert.NoError(t, err)
+	for _, output := range []string{"$ ls\r\n", "", "file\r\n$ "} {
+		_, err = recorder.Write([]byte(output))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, recorder.Close())
+
+	typescript, _ := ioutil.ReadFile(typescriptPath)
+	lines := strings.SplitN(string(typescript), "\n", 2)
+	assert.True(t, strings.HasPrefix(lines[0], "Script started on "))
+	assert.Equal(t, "$ ls\r\nfile\r\n$ ", lines[1])
+
+	timing, _ := ioutil.ReadFile(timingPath)
+	assert.Regexp(t, regexp.MustCompile(`^\d+\.\d{6} 6\n\d+\.\d{6} 8\n$`), string(timing))
+}
+
+func TestScriptRecorderMissingDirectory(t *testing.T) {
+	_, err := newScriptRecorder("/nonexistent/session.typescript", "/nonexistent/session.timing")
+	assert.Error(t, err)
+}
@@ -28,26 +28,49 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/diskguard"
+	"github.com/aws/amazon-ssm-agent/agent/etw"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/firehose"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/netns"
+	"github.com/aws/amazon-ssm-agent/agent/outputencryption"
+	"github.com/aws/amazon-ssm-agent/agent/outputfailover"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+	"github.com/aws/amazon-ssm-agent/agent/syslog"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
 )
 
+// transcriptMsgID is the MSGID of the transcript lines forwarded to syslog
+const transcriptMsgID = "SessionTranscript"
+
+// isDirectory returns true if the path is an existing directory
+var isDirectory = fileutil.IsDirectory
+
 // Plugin is the type for the plugin.
 type ShellPlugin struct {
 	stdin       *os.File
 	stdout      *os.File
 	ipcFilePath string
 	logFilePath string
-	dataChannel datachannel.IDataChannel
+	// typescriptPath and timingPath are the typescript and the timing file of the session output, empty when the
+	// session output is not recorded for scriptreplay
+	typescriptPath string
+	timingPath     string
+	recorder       *scriptRecorder
+	dataChannel    datachannel.IDataChannel
+	etwSession     *etw.Session
 }
 
 // NewPlugin returns a new instance of the Shell Plugin
@@ -112,8 +135,38 @@ func (p *ShellPlugin) Execute(context context.T,
 	}
 }
 
-var startPty = func(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, stdout *os.File, err error) {
-	return StartPty(log, runAsSsmUser, shellCmd)
+var startPty = func(log log.T, runAsSsmUser bool, shellCmd string, tokenElevation string, workingDir string, sessionDir string) (stdin *os.File, stdout *os.File, err error) {
+	return StartPty(log, runAsSsmUser, shellCmd, tokenElevation, workingDir, sessionDir)
+}
+
+// tokenElevation returns the token elevation of the session, the one of the session document or else the one of the agent config
+func tokenElevation(context context.T, config agentContracts.Configuration) (string, error) {
+	switch config.TokenElevation {
+	case "":
+		return context.AppConfig().Mgs.TokenElevation, nil
+	case appconfig.TokenElevationFull, appconfig.TokenElevationLimited:
+		return config.TokenElevation, nil
+	}
+	return "", fmt.Errorf("unknown token elevation %v, expecting %v or %v", config.TokenElevation, appconfig.TokenElevationFull, appconfig.TokenElevationLimited)
+}
+
+// workingDirectory returns the directory the shell of the session starts in, the one of the session document or else the
+// one of the agent config, an empty directory for the directory of the agent
+func workingDirectory(context context.T, config agentContracts.Configuration) (string, error) {
+	dir := config.WorkingDirectory
+	if dir == "" {
+		dir = context.AppConfig().Mgs.WorkingDirectory
+	}
+	if dir == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("working directory %v is not an absolute path", dir)
+	}
+	if !isDirectory(dir) {
+		return "", fmt.Errorf("working directory %v does not exist or is not a directory", dir)
+	}
+	return dir, nil
 }
 
 // execute starts pseudo terminal.
@@ -145,13 +198,54 @@ func (p *ShellPlugin) execute(context context.T,
 		return
 	}
 
-	p.stdin, p.stdout, err = startPty(log, !config.RunAsElevated, config.Commands)
+	// the session output and transcript are written to the orchestration directory
+	if err = diskguard.Check(config.OrchestrationDirectory); err != nil {
+		output.SetExitCode(diskguard.ExitCode)
+		output.SetStatus(agentContracts.ResultStatusFailed)
+		sessionPluginResultOutput.Output = err.Error()
+		output.SetOutput(sessionPluginResultOutput)
+		log.Errorf("Unable to start shell: %s", err)
+		return
+	}
+
+	elevation, err := tokenElevation(context, config)
+	var workingDir string
+	if err == nil {
+		workingDir, err = workingDirectory(context, config)
+	}
+	if err == nil {
+		// every session gets its own scratch directory, removed when the session ends
+		sessionDir, sessionDirErr := tempdir.CreateSession()
+		if sessionDirErr != nil {
+			log.Warnf("The session has no scratch directory: %s", sessionDirErr)
+		} else {
+			defer tempdir.Remove(log, sessionDir)
+		}
+		// the ETW events of the session report its runas identity, the volume of its input and output and its processes
+		p.etwSession = etw.StartSession(log, context.AppConfig().Etw, config.SessionId, audit.RunAsUser(config.RunAsElevated), elevation)
+		defer p.etwSession.End()
+		// the shell forked in the network namespace of the session stays in it
+		err = netns.Run(config.NetworkNamespace, func() (err error) {
+			p.stdin, p.stdout, err = startPty(log, !config.RunAsElevated, config.Commands, elevation, workingDir, sessionDir)
+			return err
+		})
+	}
 	if err != nil {
 		errorString := fmt.Errorf("Unable to start shell: %s", err)
 		log.Error(errorString)
 		output.MarkAsFailed(errorString)
 		return
 	}
+	p.etwSession.WatchProcesses(shellProcessID())
+
+	// the sudoers file of ssm-user only exists while sessions run as ssm-user when it is ephemeral
+	if !config.RunAsElevated && context.AppConfig().Mgs.EphemeralSudo {
+		u := &utility.SessionUtil{}
+		if err = u.GrantSudo(log); err != nil {
+			log.Errorf("Failed to grant sudo to %s, the session runs without it: %s", appconfig.DefaultRunAsUserName, err)
+		}
+		defer u.RevokeSudo(log)
+	}
 
 	// Generate ipc file path
 	p.ipcFilePath = filepath.Join(config.OrchestrationDirectory, mgsConfig.IpcFileName+mgsConfig.LogFileExtension)
@@ -160,6 +254,12 @@ func (p *ShellPlugin) execute(context context.T,
 	logFileName := config.SessionId + mgsConfig.LogFileExtension
 	p.logFilePath = filepath.Join(config.OrchestrationDirectory, logFileName)
 
+	// Record the session output for scriptreplay when the transcripts are uploaded to S3
+	if config.OutputS3BucketName != "" && context.AppConfig().Mgs.TranscriptTiming {
+		p.typescriptPath = filepath.Join(config.OrchestrationDirectory, config.SessionId+typescriptFileExtension)
+		p.timingPath = filepath.Join(config.OrchestrationDirectory, config.SessionId+timingFileExtension)
+	}
+
 	cancelled := make(chan bool, 1)
 	go func() {
 		cancelState := cancelFlag.Wait()
@@ -199,9 +299,12 @@ func (p *ShellPlugin) execute(context context.T,
 		}
 	}
 
-	// Generate log data only if customer has enabled logging.
+	// Generate log data only if customer has enabled logging, or the agent forwards the transcripts to syslog or Firehose.
 	// TODO: Move below logic of uploading logs to S3 and cloudwatch to IOHandler
-	if config.OutputS3BucketName != "" || config.CloudWatchLogGroup != "" {
+	syslogConfig := context.AppConfig().Syslog
+	firehoseConfig := context.AppConfig().Firehose
+	if config.OutputS3BucketName != "" || config.CloudWatchLogGroup != "" || syslog.TranscriptsEnabled(syslogConfig) ||
+		firehose.TranscriptsEnabled(firehoseConfig) {
 		log.Debugf("Creating log file for shell session id %s at %s", config.SessionId, p.logFilePath)
 		if err = p.generateLogData(log, config); err != nil {
 			errorString := fmt.Errorf("unable to generate log data: %s", err)
@@ -210,33 +313,101 @@ func (p *ShellPlugin) execute(context context.T,
 			return
 		}
 
+		// The encrypted copies of the transcript are sent in its place, nothing is sent when the encryption fails
+		encryptionConfig := context.AppConfig().OutputEncryption
+		s3Path, cloudWatchPath := p.logFilePath, p.logFilePath
+		if outputencryption.Enabled(encryptionConfig) && (config.OutputS3BucketName != "" || config.CloudWatchLogGroup != "") {
+			if s3Path, cloudWatchPath, err = outputencryption.OutputFiles(log, encryptionConfig, p.logFilePath); err != nil {
+				log.Errorf("Failed to encrypt the session transcript, it is not sent to S3 and CloudWatch Logs: %s", err)
+			}
+		}
+
+		failoverConfig := context.AppConfig().OutputFailover
 		log.Debug("Starting S3 logging")
-		if config.OutputS3BucketName != "" {
+		if config.OutputS3BucketName != "" && s3Path != "" {
 			s3KeyPrefix := fileutil.BuildS3Path(config.OutputS3KeyPrefix, logFileName)
-			p.uploadShellSessionLogsToS3(log, s3Util, config, s3KeyPrefix)
+			if err = p.uploadShellSessionLogsToS3(log, s3Util, config, s3KeyPrefix, s3Path); err != nil &&
+				outputfailover.CloudWatchEnabled(failoverConfig) {
+				if err = outputfailover.ToCloudWatch(log, failoverConfig, s3KeyPrefix, cloudWatchPath); err != nil {
+					log.Errorf("Failed to send shell session logs to the failover log group: %s", err)
+				}
+			}
+			if p.typescriptPath != "" {
+				p.uploadTypescriptToS3(log, s3Util, config, encryptionConfig)
+			}
 			sessionPluginResultOutput.S3Bucket = config.OutputS3BucketName
 			sessionPluginResultOutput.S3UrlSuffix = s3KeyPrefix
 		}
 
 		log.Debug("Starting CloudWatch logging")
-		if config.CloudWatchLogGroup != "" {
-			cwl.StreamData(log, config.CloudWatchLogGroup, config.SessionId, p.logFilePath, true, false)
+		if config.CloudWatchLogGroup != "" && cloudWatchPath != "" {
+			if !outputfailover.S3Enabled(failoverConfig) {
+				cwl.StreamData(log, config.CloudWatchLogGroup, config.SessionId, cloudWatchPath, true, false)
+			} else if !outputfailover.StreamFile(log, failoverConfig, cwl, config.CloudWatchLogGroup, config.SessionId, cloudWatchPath) {
+				if err = outputfailover.ToS3(log, failoverConfig, logFileName, s3Path); err != nil {
+					log.Errorf("Failed to upload shell session logs to the failover bucket: %s", err)
+				}
+			}
 			sessionPluginResultOutput.CwlGroup = config.CloudWatchLogGroup
 			sessionPluginResultOutput.CwlStream = config.SessionId
 		}
+
+		if syslog.TranscriptsEnabled(syslogConfig) {
+			log.Debugf("Forwarding the session transcript to syslog server %s", syslogConfig.Address)
+			if err = syslog.SendFile(syslogConfig, transcriptMsgID, config.SessionId+": ", p.logFilePath); err != nil {
+				log.Errorf("Failed to forward the session transcript to syslog: %s", err)
+			}
+		}
+
+		if firehose.TranscriptsEnabled(firehoseConfig) {
+			log.Debugf("Sending the session transcript to delivery stream %s", firehoseConfig.DeliveryStream)
+			instanceID, _ := platform.InstanceID()
+			partitionKeys := firehose.PartitionKeys(firehoseConfig, map[string]string{
+				firehose.KeyInstanceID: instanceID,
+				firehose.KeySessionID:  config.SessionId,
+				firehose.KeyStream:     firehose.StreamTranscript,
+			})
+			if err = firehose.SendFile(log, firehoseConfig.DeliveryStream, partitionKeys, p.logFilePath); err != nil {
+				log.Errorf("Failed to send the session transcript to Firehose: %s", err)
+			}
+		}
 	}
 	output.SetOutput(sessionPluginResultOutput)
 
 	log.Debug("Shell session execution complete")
 }
 
-// uploadShellSessionLogsToS3 uploads shell session logs to S3 bucket specified.
-func (p *ShellPlugin) uploadShellSessionLogsToS3(log log.T, s3UploaderUtil s3util.IAmazonS3Util, config agentContracts.Configuration, s3KeyPrefix string) {
+// uploadShellSessionLogsToS3 uploads shell session logs, or their encrypted copy, to S3 bucket specified.
+func (p *ShellPlugin) uploadShellSessionLogsToS3(log log.T, s3UploaderUtil s3util.IAmazonS3Util, config agentContracts.Configuration, s3KeyPrefix, filePath string) (err error) {
 	log.Debugf("Preparing to upload session logs to S3 bucket %s and prefix %s", config.OutputS3BucketName, s3KeyPrefix)
 
-	if err := s3UploaderUtil.S3Upload(log, config.OutputS3BucketName, s3KeyPrefix, p.logFilePath); err != nil {
+	if err = s3UploaderUtil.S3Upload(log, config.OutputS3BucketName, s3KeyPrefix, filePath); err != nil {
 		log.Errorf("Failed to upload shell session logs to S3: %s", err)
 	}
+	return err
+}
+
+// uploadTypescriptToS3 uploads the typescript and the timing file of the session output next to the session logs,
+// encrypted when the output encryption is enabled.
+func (p *ShellPlugin) uploadTypescriptToS3(log log.T,
+	s3UploaderUtil s3util.IAmazonS3Util,
+	config agentContracts.Configuration,
+	encryptionConfig appconfig.OutputEncryptionCfg) {
+
+	for _, filePath := range []string{p.typescriptPath, p.timingPath} {
+		s3Key := fileutil.BuildS3Path(config.OutputS3KeyPrefix, filepath.Base(filePath))
+		uploadPath := filePath
+		if outputencryption.Enabled(encryptionConfig) {
+			var err error
+			if uploadPath, err = outputencryption.EncryptFile(log, encryptionConfig, filePath); err != nil {
+				log.Errorf("Failed to encrypt %s, it is not uploaded to S3: %s", filepath.Base(filePath), err)
+				continue
+			}
+		}
+		if err := s3UploaderUtil.S3Upload(log, config.OutputS3BucketName, s3Key, uploadPath); err != nil {
+			log.Errorf("Failed to upload %s to S3: %s", filepath.Base(filePath), err)
+		}
+	}
 }
 
 // writePump reads from pty stdout and writes to data channel.
@@ -259,12 +430,23 @@ func (p *ShellPlugin) writePump(log log.T) (errorCode int) {
 	}
 	defer file.Close()
 
+	// Create the typescript and the timing file of the output
+	if p.typescriptPath != "" {
+		if p.recorder, err = newScriptRecorder(p.typescriptPath, p.timingPath); err != nil {
+			log.Errorf("Failed to create the typescript of the session: %s", err)
+		} else {
+			defer p.recorder.Close()
+		}
+	}
+
 	// Wait for all input commands to run.
 	time.Sleep(time.Second)
 
 	var unprocessedBuf bytes.Buffer
 	for {
-		stdoutBytesLen, err := reader.Read(stdoutBytes)
+		// pause the pty reads until the client can take more output
+		readSize := p.dataChannel.WaitForOutputWindow(log, len(stdoutBytes))
+		stdoutBytesLen, err := reader.Read(stdoutBytes[:readSize])
 		if err != nil {
 			// Terminating session
 			log.Debugf("Failed to read from pty master: %s", err)
@@ -317,10 +499,16 @@ func (p *ShellPlugin) processStdoutData(
 	if err := p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, processedBuf.Bytes()); err != nil {
 		return processedBuf, fmt.Errorf("unable to send stream data message: %s", err)
 	}
+	p.etwSession.AddOutput(processedBuf.Len())
 
 	if _, err := file.Write(processedBuf.Bytes()); err != nil {
 		return processedBuf, fmt.Errorf("encountered an error while writing to file: %s", err)
 	}
+	if p.recorder != nil {
+		if _, err := p.recorder.Write(processedBuf.Bytes()); err != nil {
+			log.Errorf("Failed to record the session output in the typescript: %s", err)
+		}
+	}
 
 	// return incomplete utf8 encoded unicode bytes to be processed with next batch of stdoutBytes
 	unprocessedBuf.Reset()
@@ -346,6 +534,7 @@ func (p *ShellPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgs
 			log.Errorf("Unable to write to stdin, err: %v.", err)
 			return err
 		}
+		p.etwSession.AddInput(len(streamDataMessage.Payload))
 	case mgsContracts.Size:
 		var size mgsContracts.SizeData
 		if err := json.Unmarshal(streamDataMessage.Payload, &size); err != nil {
@@ -133,10 +133,12 @@ func (suite *ShellTestSuite) TestExecute() {
 	suite.mockIohandler.On("SetExitCode", 0).Return(nil)
 	suite.mockIohandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
 	suite.mockIohandler.On("SetOutput", mock.Anything).Return()
+	suite.mockDataChannel.On("WaitForOutputWindow", mock.Anything, mock.Anything).Return(
+		func(_ log.T, size int) int { return size })
 
 	stdout, stdin, _ := os.Pipe()
 	stdin.Write(payload)
-	startPty = func(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, stdout *os.File, err error) {
+	startPty = func(log log.T, runAsSsmUser bool, shellCmd string, tokenElevation string, workingDir string, sessionDir string) (stdin *os.File, stdout *os.File, err error) {
 		return stdin, stdout, nil
 	}
 	plugin := &ShellPlugin{
@@ -165,6 +167,8 @@ func (suite *ShellTestSuite) TestWritePump() {
 	//suite.mockDataChannel := &dataChannelMock.IDataChannel{}
 	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", mock.Anything, mgsContracts.Terminating).Return(nil)
+	suite.mockDataChannel.On("WaitForOutputWindow", mock.Anything, mock.Anything).Return(
+		func(_ log.T, size int) int { return size })
 
 	plugin := &ShellPlugin{
 		stdout:      stdout,
@@ -222,7 +226,55 @@ func (suite *ShellTestSuite) TestProcessStreamMessage() {
 	assert.Equal(suite.T(), "testPayload", string(stdinFileContent))
 }
 
-//Execute the test suite
+func TestTokenElevation(t *testing.T) {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.Mgs.TokenElevation = appconfig.TokenElevationLimited
+	ctx.On("AppConfig").Return(config)
+
+	elevation, err := tokenElevation(ctx, contracts.Configuration{})
+	assert.NoError(t, err)
+	assert.Equal(t, appconfig.TokenElevationLimited, elevation)
+
+	elevation, err = tokenElevation(ctx, contracts.Configuration{TokenElevation: appconfig.TokenElevationFull})
+	assert.NoError(t, err)
+	assert.Equal(t, appconfig.TokenElevationFull, elevation)
+
+	_, err = tokenElevation(ctx, contracts.Configuration{TokenElevation: "Elevated"})
+	assert.Error(t, err)
+}
+
+func TestWorkingDirectory(t *testing.T) {
+	isDirectoryOrig := isDirectory
+	defer func() { isDirectory = isDirectoryOrig }()
+	isDirectory = func(dir string) bool { return dir != "/missing" }
+
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.Mgs.WorkingDirectory = "/srv/app"
+	ctx.On("AppConfig").Return(config)
+
+	dir, err := workingDirectory(ctx, contracts.Configuration{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/srv/app", dir)
+
+	dir, err = workingDirectory(ctx, contracts.Configuration{WorkingDirectory: "/scratch/team"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/scratch/team", dir)
+
+	_, err = workingDirectory(ctx, contracts.Configuration{WorkingDirectory: "scratch"})
+	assert.Error(t, err)
+	_, err = workingDirectory(ctx, contracts.Configuration{WorkingDirectory: "/missing"})
+	assert.Error(t, err)
+
+	ctx = new(context.Mock)
+	ctx.On("AppConfig").Return(appconfig.SsmagentConfig{})
+	dir, err = workingDirectory(ctx, contracts.Configuration{})
+	assert.NoError(t, err)
+	assert.Empty(t, dir)
+}
+
+// Execute the test suite
 func TestShellTestSuite(t *testing.T) {
 	suite.Run(t, new(ShellTestSuite))
 }
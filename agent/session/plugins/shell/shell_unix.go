@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package shell implements session shell plugin.
@@ -21,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -29,12 +31,15 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/privsep"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
 	"github.com/kr/pty"
 )
 
 var ptyFile *os.File
+var ptyCmd *exec.Cmd
 
 const (
 	termEnvVariable       = "TERM=xterm-256color"
@@ -46,8 +51,12 @@ const (
 	homeEnvVariable       = "HOME=/home/" + appconfig.DefaultRunAsUserName
 )
 
-//StartPty starts pty and provides handles to stdin and stdout
-func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, stdout *os.File, err error) {
+// shellExitGracePeriod is the time the shell has to exit after the hang up before its process group is killed
+var shellExitGracePeriod = 5 * time.Second
+
+// StartPty starts pty and provides handles to stdin and stdout, the token elevation only applies to Windows.
+// The shell starts in workingDir, the directory of the agent when it is empty, and gets sessionDir as its scratch directory.
+func StartPty(log log.T, runAsSsmUser bool, shellCmd string, tokenElevation string, workingDir string, sessionDir string) (stdin *os.File, stdout *os.File, err error) {
 	log.Info("Starting pty")
 	//Start the command with a pty
 	var cmd *exec.Cmd
@@ -58,6 +67,8 @@ func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, st
 		cmd = exec.Command("sh", commandArgs...)
 	}
 
+	cmd.Dir = workingDir
+
 	//TERM is set as linux by pty which has an issue where vi editor screen does not get cleared.
 	//Setting TERM as xterm-256color as used by standard terminals to fix this issue
 	cmd.Env = append(os.Environ(),
@@ -78,12 +89,37 @@ func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, st
 		u := &utility.SessionUtil{}
 		u.CreateLocalAdminUser(log)
 
-		uid, gid, groups, err := getUserCredentials(log)
-		if err != nil {
-			return nil, nil, err
+		// the shell cannot start in a directory the runas user cannot access
+		if workingDir != "" {
+			if err = checkUserAccess(log, workingDir); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// the scratch directory of the session belongs to the runas user, the unprivileged agent cannot hand it over
+		if sessionDir != "" {
+			if err = giveToUser(log, sessionDir); err != nil {
+				log.Warnf("The session has no scratch directory, failed to hand %s over to %s: %v", sessionDir, appconfig.DefaultRunAsUserName, err)
+				sessionDir = ""
+			}
+		}
+	}
+	if sessionDir != "" {
+		cmd.Env = append(cmd.Env, tempdir.SessionEnvironment(sessionDir)...)
+	}
+
+	if runAsSsmUser {
+		// the unprivileged agent cannot set the credentials of the shell, the helper switches to the runas user
+		if privsep.Required() {
+			cmd = privsep.RunAsCommand(appconfig.DefaultRunAsUserName, cmd)
+		} else {
+			uid, gid, groups, err := sessionCredentials(log)
+			if err != nil {
+				return nil, nil, err
+			}
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+			cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid, Groups: groups, NoSetGroups: false}
 		}
-		cmd.SysProcAttr = &syscall.SysProcAttr{}
-		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid, Groups: groups, NoSetGroups: false}
 	}
 
 	ptyFile, err = pty.Start(cmd)
@@ -91,20 +127,54 @@ func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, st
 		log.Errorf("Failed to start pty: %s\n", err)
 		return nil, nil, fmt.Errorf("Failed to start pty: %s\n", err)
 	}
+	ptyCmd = cmd
 
 	return ptyFile, ptyFile, nil
 }
 
-//Stop closes pty file.
+// shellProcessID returns 0, the processes of the sessions are only reported through ETW on Windows
+func shellProcessID() uint32 {
+	return 0
+}
+
+// Stop closes pty file.
 func Stop(log log.T) (err error) {
 	log.Info("Stopping pty")
 	if err := ptyFile.Close(); err != nil {
 		return fmt.Errorf("unable to close ptyFile. %s", err)
 	}
+	terminateShell(log, ptyCmd)
+	ptyCmd = nil
 	return nil
 }
 
-//SetSize sets size of console terminal window.
+// terminateShell hangs up the process group of the shell, kills it when the shell does not exit
+// within the grace period and reaps the shell so that no zombie process is left behind.
+func terminateShell(log log.T, cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	// pty.Start runs the shell as the leader of a new session, its process group id is its pid
+	pgid := cmd.Process.Pid
+	if err := syscall.Kill(-pgid, syscall.SIGHUP); err != nil && err != syscall.ESRCH {
+		log.Debugf("Failed to hang up the shell process group %d: %v", pgid, err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(shellExitGracePeriod):
+		log.Warnf("Shell did not exit after the hang up, killing its process group %d", pgid)
+		syscall.Kill(-pgid, syscall.SIGKILL)
+		<-exited
+	}
+}
+
+// SetSize sets size of console terminal window.
 func SetSize(log log.T, ws_col, ws_row uint32) (err error) {
 	winSize := pty.Winsize{
 		Cols: uint16(ws_col),
@@ -117,6 +187,74 @@ func SetSize(log log.T, ws_col, ws_row uint32) (err error) {
 	return nil
 }
 
+// giveToUser makes the runas user the owner of the file.
+func giveToUser(log log.T, path string) error {
+	if privsep.Required() {
+		return fmt.Errorf("the unprivileged agent cannot change the owner of %v", path)
+	}
+	uid, gid, _, err := getUserCredentials(log)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, int(uid), int(gid))
+}
+
+// checkUserAccess returns an error if the runas user cannot access the directory.
+func checkUserAccess(log log.T, dir string) error {
+	uid, gid, groups, err := sessionCredentials(log)
+	if err != nil {
+		return err
+	}
+	return checkAccess(dir, uid, append(groups, gid))
+}
+
+// checkAccess returns an error if the user with the uid and the gids has no search permission on the directory
+// or on one of its parent directories.
+func checkAccess(dir string, uid uint32, gids []uint32) error {
+	for path := filepath.Clean(dir); ; path = filepath.Dir(path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("failed to get the owner of %v", path)
+		}
+		var permission os.FileMode = 01
+		if stat.Uid == uid {
+			permission = 0100
+		} else {
+			for _, gid := range gids {
+				if stat.Gid == gid {
+					permission = 010
+					break
+				}
+			}
+		}
+		if info.Mode().Perm()&permission == 0 {
+			return fmt.Errorf("%v cannot access the working directory %v", appconfig.DefaultRunAsUserName, dir)
+		}
+		if path == filepath.Dir(path) {
+			return nil
+		}
+	}
+}
+
+// sessionCredentials returns the uid, gid and groups of the sessions run as the runas user, the supplementary groups
+// of the user adjusted with the agent config.
+func sessionCredentials(log log.T) (uint32, uint32, []uint32, error) {
+	uid, gid, groups, err := getUserCredentials(log)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	config, _ := appconfig.Config(false)
+	if groups, err = utility.AdjustGroups(groups, config.Mgs.AddGroups, config.Mgs.DropGroups); err != nil {
+		log.Errorf("Failed to adjust the groups of %s: %v", appconfig.DefaultRunAsUserName, err)
+		return 0, 0, nil, err
+	}
+	return uid, gid, groups, nil
+}
+
 // getUserCredentials returns the uid, gid and groups associated to the runas user.
 func getUserCredentials(log log.T) (uint32, uint32, []uint32, error) {
 	uidCmdArgs := append(utility.ShellPluginCommandArgs, fmt.Sprintf("id -u %s", appconfig.DefaultRunAsUserName))
@@ -147,38 +285,19 @@ func getUserCredentials(log log.T) (uint32, uint32, []uint32, error) {
 		return 0, 0, nil, err
 	}
 
-	// Get the list of associated groups
-	groupNamesCmdArgs := append(utility.ShellPluginCommandArgs, fmt.Sprintf("groups %s", appconfig.DefaultRunAsUserName))
-	cmd = exec.Command(utility.ShellPluginCommandName, groupNamesCmdArgs...)
+	// Get the ids of the associated groups, id -G prints them on Linux, macOS and the BSDs alike
+	groupIdsCmdArgs := append(utility.ShellPluginCommandArgs, fmt.Sprintf("id -G %s", appconfig.DefaultRunAsUserName))
+	cmd = exec.Command(utility.ShellPluginCommandName, groupIdsCmdArgs...)
 	out, err = cmd.Output()
 	if err != nil {
 		log.Errorf("Failed to retrieve groups for %s: %v", appconfig.DefaultRunAsUserName, err)
 		return 0, 0, nil, err
 	}
 
-	groupNames := strings.Split(string(out), " ")
-	var groupIds []uint32
-
-	// Skip the first two elements. Group names start from the third element.
-	// Format ex: ssm-user : ssm-user test
-	for i := 2; i < len(groupNames); i++ {
-		groupIdFromNameCmdArgs := append(utility.ShellPluginCommandArgs, fmt.Sprintf("getent group %s", groupNames[i]))
-		cmd = exec.Command(utility.ShellPluginCommandName, groupIdFromNameCmdArgs...)
-		out, err = cmd.Output()
-		if err != nil {
-			log.Errorf("Failed to retrieve group id for %s: %v", groupNames[i], err)
-			return 0, 0, nil, err
-		}
-
-		// Get the third element from the array which contains the id and convert it to int
-		// Format ex: test:x:1004:ssm-user
-		groupIdFromName, err := strconv.Atoi(strings.TrimSpace(strings.Split(string(out), ":")[2]))
-		if err != nil {
-			log.Errorf("%s group id not found: %v", groupNames[i], err)
-			return 0, 0, nil, err
-		}
-
-		groupIds = append(groupIds, uint32(groupIdFromName))
+	groupIds, err := parseIds(string(out))
+	if err != nil {
+		log.Errorf("%s group ids not found: %v", appconfig.DefaultRunAsUserName, err)
+		return 0, 0, nil, err
 	}
 
 	// Make sure they are non-zero valid positive ids
@@ -189,9 +308,23 @@ func getUserCredentials(log log.T) (uint32, uint32, []uint32, error) {
 	return 0, 0, nil, errors.New("invalid uid and gid")
 }
 
+// parseIds parses the whitespace separated numeric ids printed by id -G
+// Format ex: 1001 1004
+func parseIds(output string) ([]uint32, error) {
+	var ids []uint32
+	for _, field := range strings.Fields(output) {
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %v", field)
+		}
+		ids = append(ids, uint32(id))
+	}
+	return ids, nil
+}
+
 // generateLogData generates a log file with the executed commands.
 func (p *ShellPlugin) generateLogData(log log.T, config agentContracts.Configuration) error {
-	shadowShellInput, _, err := StartPty(log, false, "")
+	shadowShellInput, _, err := StartPty(log, false, "", "", "", "")
 	if err != nil {
 		return err
 	}
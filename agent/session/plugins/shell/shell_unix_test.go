@@ -0,0 +1,93 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIds(t *testing.T) {
+	ids, err := parseIds("1001 4 27 1004\n")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1001, 4, 27, 1004}, ids)
+
+	ids, err = parseIds("")
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
+
+	_, err = parseIds("1001 wheel")
+	assert.NotNil(t, err)
+}
+
+func TestCheckAccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workdir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Chmod(dir, 0750))
+	info, _ := os.Stat(dir)
+	stat := info.Sys().(*syscall.Stat_t)
+
+	assert.NoError(t, checkAccess(dir, stat.Uid, nil))
+	assert.NoError(t, checkAccess(dir, stat.Uid+1, []uint32{stat.Gid}))
+	assert.Error(t, checkAccess(dir, stat.Uid+1, []uint32{stat.Gid + 1}))
+
+	// the search permission of the parent directories is needed as well
+	child := filepath.Join(dir, "child")
+	assert.NoError(t, os.Mkdir(child, 0777))
+	assert.NoError(t, os.Chmod(child, 0777))
+	assert.Error(t, checkAccess(child, stat.Uid+1, []uint32{stat.Gid + 1}))
+
+	assert.Error(t, checkAccess(filepath.Join(dir, "missing"), stat.Uid, nil))
+}
+
+func startSessionLeader(t *testing.T, script string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	assert.Nil(t, cmd.Start())
+	// let the shell install its traps
+	time.Sleep(100 * time.Millisecond)
+	return cmd
+}
+
+func TestTerminateShell_HangUp(t *testing.T) {
+	cmd := startSessionLeader(t, "sleep 30")
+	terminateShell(log.NewMockLog(), cmd)
+	assert.NotNil(t, cmd.ProcessState)
+	assert.Equal(t, syscall.SIGHUP, cmd.ProcessState.Sys().(syscall.WaitStatus).Signal())
+}
+
+func TestTerminateShell_KillsProcessGroup(t *testing.T) {
+	defer func(period time.Duration) { shellExitGracePeriod = period }(shellExitGracePeriod)
+	shellExitGracePeriod = 100 * time.Millisecond
+
+	cmd := startSessionLeader(t, `trap "" HUP; sleep 30`)
+	terminateShell(log.NewMockLog(), cmd)
+	assert.NotNil(t, cmd.ProcessState)
+	assert.Equal(t, syscall.SIGKILL, cmd.ProcessState.Sys().(syscall.WaitStatus).Signal())
+
+	// nothing to terminate without a started shell
+	terminateShell(log.NewMockLog(), nil)
+}
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build windows
 // +build windows
 
 // Package shell implements session shell plugin.
@@ -35,9 +36,11 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/powershell"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	"github.com/aws/amazon-ssm-agent/agent/session/utility"
 	"github.com/aws/amazon-ssm-agent/agent/session/winpty"
+	"github.com/aws/amazon-ssm-agent/agent/tempdir"
 )
 
 var pty *winpty.WinPTY
@@ -54,6 +57,20 @@ const (
 	screenBufferSizeCmd    = "$host.UI.RawUI.BufferSize = New-Object System.Management.Automation.Host.Size($host.UI.RawUI.BufferSize.Width,%d)%s"
 	logon32LogonNetwork    = uintptr(3)
 	logon32ProviderDefault = uintptr(0)
+
+	// token information classes and elevation types
+	tokenElevationTypeClass   = 18
+	tokenLinkedTokenClass     = 19
+	tokenElevationTypeDefault = 1
+	tokenElevationTypeFull    = 2
+	tokenElevationTypeLimited = 3
+
+	// CreateRestrictedToken flags
+	disableMaxPrivilege = uintptr(0x1)
+	luaToken            = uintptr(0x4)
+
+	// administratorsSid is the SID of the BUILTIN\Administrators group
+	administratorsSid = "S-1-5-32-544"
 )
 
 var (
@@ -61,22 +78,28 @@ var (
 	logonProc         = advapi32.NewProc("LogonUserW")
 	impersonateProc   = advapi32.NewProc("ImpersonateLoggedOnUser")
 	revertSelfProc    = advapi32.NewProc("RevertToSelf")
+	restrictTokenProc = advapi32.NewProc("CreateRestrictedToken")
 	winptyDllDir      = fileutil.BuildPath(appconfig.DefaultPluginPath, winptyDllFolderName)
 	winptyDllFilePath = filepath.Join(winptyDllDir, winptyDllName)
 )
 
-//StartPty starts winpty agent and provides handles to stdin and stdout.
-func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, stdout *os.File, err error) {
+// StartPty starts winpty agent and provides handles to stdin and stdout.
+// The sessions run as ssm-user get the administrator token for the Full token elevation and the filtered one for Limited.
+// The shell starts in workingDir, the directory of the agent when it is empty, and gets sessionDir as its scratch directory.
+func StartPty(log log.T, runAsSsmUser bool, shellCmd string, tokenElevation string, workingDir string, sessionDir string) (stdin *os.File, stdout *os.File, err error) {
 	log.Info("Starting winpty")
 	if _, err := os.Stat(winptyDllFilePath); os.IsNotExist(err) {
 		return nil, nil, fmt.Errorf("Missing %s file.", winptyDllFilePath)
 	}
 
-	var finalCmd string
-	if strings.TrimSpace(shellCmd) == "" {
-		finalCmd = winptyCmd
-	} else {
-		finalCmd = winptyCmd + " " + shellCmd
+	// the session runs with the execution policy, language mode and modules of the PowerShell config
+	config, _ := appconfig.Config(false)
+	finalCmd := strings.Join(append([]string{winptyCmd}, powershell.SessionArguments(config.PowerShell, shellCmd)...), " ")
+
+	// the shell inherits the environment of the agent without a scratch directory
+	var env []string
+	if sessionDir != "" {
+		env = append(os.Environ(), tempdir.SessionEnvironment(sessionDir)...)
 	}
 
 	if runAsSsmUser {
@@ -108,11 +131,11 @@ func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, st
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err = startPtyAsUser(log, appconfig.DefaultRunAsUserName, newPassword, finalCmd)
+			err = startPtyAsUser(log, appconfig.DefaultRunAsUserName, newPassword, finalCmd, tokenElevation, workingDir, env)
 		}()
 		wg.Wait()
 	} else {
-		pty, err = winpty.Start(winptyDllFilePath, finalCmd, defaultConsoleCol, defaultConsoleRow, winpty.DEFAULT_WINPTY_FLAGS)
+		pty, err = winpty.Start(winptyDllFilePath, finalCmd, workingDir, env, defaultConsoleCol, defaultConsoleRow, winpty.DEFAULT_WINPTY_FLAGS)
 	}
 
 	if err != nil {
@@ -122,7 +145,15 @@ func StartPty(log log.T, runAsSsmUser bool, shellCmd string) (stdin *os.File, st
 	return pty.StdIn, pty.StdOut, err
 }
 
-//Stop closes winpty process handle and stdin/stdout.
+// shellProcessID returns the id of the shell process of the session.
+func shellProcessID() uint32 {
+	if pty == nil {
+		return 0
+	}
+	return pty.ProcessID()
+}
+
+// Stop closes winpty process handle and stdin/stdout.
 func Stop(log log.T) (err error) {
 	log.Info("Stopping winpty")
 	if err = pty.Close(); err != nil {
@@ -134,7 +165,7 @@ func Stop(log log.T) (err error) {
 	return nil
 }
 
-//SetSize sets size of console terminal window.
+// SetSize sets size of console terminal window.
 func SetSize(log log.T, ws_col, ws_row uint32) (err error) {
 	if err = pty.SetSize(ws_col, ws_row); err != nil {
 		return fmt.Errorf("Set winpty size failed: %s", err)
@@ -143,19 +174,19 @@ func SetSize(log log.T, ws_col, ws_row uint32) (err error) {
 	return nil
 }
 
-//startPtyAsUser starts a winpty process in runas user context.
-func startPtyAsUser(log log.T, user string, pass string, shellCmd string) (err error) {
+// startPtyAsUser starts a winpty process in runas user context.
+func startPtyAsUser(log log.T, user string, pass string, shellCmd string, tokenElevation string, workingDir string, env []string) (err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
 	log.Debugf("Impersonating %s", appconfig.DefaultRunAsUserName)
-	if err = impersonate(log, user, pass); err != nil {
+	if err = impersonate(log, user, pass, tokenElevation); err != nil {
 		log.Error(err)
 		return
 	}
 
 	// Start Winpty under the user context thread.
-	if pty, err = winpty.Start(winptyDllFilePath, shellCmd, defaultConsoleCol, defaultConsoleRow, winpty.WINPTY_FLAG_IMPERSONATE_THREAD); err != nil {
+	if pty, err = winpty.Start(winptyDllFilePath, shellCmd, workingDir, env, defaultConsoleCol, defaultConsoleRow, winpty.WINPTY_FLAG_IMPERSONATE_THREAD); err != nil {
 		log.Error(err)
 		return
 	}
@@ -169,13 +200,21 @@ func startPtyAsUser(log log.T, user string, pass string, shellCmd string) (err e
 	return
 }
 
-//impersonate attempts to impersonate the user.
-func impersonate(log log.T, user string, pass string) error {
-	token, err := logonUser(user, pass)
+// impersonate attempts to impersonate the user with the token elevation.
+func impersonate(log log.T, user string, pass string, tokenElevation string) error {
+	logonToken, err := logonUser(user, pass)
 	if err != nil {
 		return err
 	}
-	defer mustCloseHandle(log, token)
+	defer mustCloseHandle(log, logonToken)
+
+	token, err := elevateToken(logonToken, tokenElevation)
+	if err != nil {
+		return fmt.Errorf("failed to get the %v token of %s: %v", tokenElevation, user, err)
+	}
+	if token != logonToken {
+		defer mustCloseHandle(log, token)
+	}
 
 	if rc, _, ec := syscall.Syscall(impersonateProc.Addr(), 1, uintptr(token), 0, 0); rc == 0 {
 		return error(ec)
@@ -183,7 +222,7 @@ func impersonate(log log.T, user string, pass string) error {
 	return nil
 }
 
-//logonUser attempts to log a user on to the local computer to generate a token.
+// logonUser attempts to log a user on to the local computer to generate a token.
 func logonUser(user, pass string) (token syscall.Handle, err error) {
 	// ".\0" meaning "this computer:
 	domain := [2]uint16{uint16('.'), 0}
@@ -208,7 +247,48 @@ func logonUser(user, pass string) (token syscall.Handle, err error) {
 	return
 }
 
-//revertToSelf reverts the impersonation process.
+// elevateToken returns the token with the elevation, the logon token itself when the elevation is empty or when it already has it.
+// The administrators get a split token under UAC whose linked token is the other half, without UAC the filtered token
+// is made from the logon token with the Administrators group for deny only and the privileges removed.
+func elevateToken(token syscall.Handle, tokenElevation string) (syscall.Handle, error) {
+	if tokenElevation == "" {
+		return token, nil
+	}
+	var elevationType, size uint32
+	if err := syscall.GetTokenInformation(syscall.Token(token), tokenElevationTypeClass, (*byte)(unsafe.Pointer(&elevationType)), uint32(unsafe.Sizeof(elevationType)), &size); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case tokenElevation == appconfig.TokenElevationFull && elevationType == tokenElevationTypeLimited,
+		tokenElevation == appconfig.TokenElevationLimited && elevationType == tokenElevationTypeFull:
+		var linkedToken syscall.Handle
+		if err := syscall.GetTokenInformation(syscall.Token(token), tokenLinkedTokenClass, (*byte)(unsafe.Pointer(&linkedToken)), uint32(unsafe.Sizeof(linkedToken)), &size); err != nil {
+			return 0, err
+		}
+		return linkedToken, nil
+	case tokenElevation == appconfig.TokenElevationLimited && elevationType == tokenElevationTypeDefault:
+		sid, err := syscall.StringToSid(administratorsSid)
+		if err != nil {
+			return 0, err
+		}
+		sidsToDisable := []syscall.SIDAndAttributes{{Sid: sid}}
+		var restrictedToken syscall.Handle
+		if rc, _, ec := syscall.Syscall9(restrictTokenProc.Addr(), 9,
+			uintptr(token),
+			disableMaxPrivilege|luaToken,
+			uintptr(len(sidsToDisable)),
+			uintptr(unsafe.Pointer(&sidsToDisable[0])),
+			0, 0, 0, 0,
+			uintptr(unsafe.Pointer(&restrictedToken))); rc == 0 {
+			return 0, error(ec)
+		}
+		return restrictedToken, nil
+	}
+	return token, nil
+}
+
+// revertToSelf reverts the impersonation process.
 func revertToSelf() error {
 	if rc, _, ec := syscall.Syscall(revertSelfProc.Addr(), 0, 0, 0, 0); rc == 0 {
 		return error(ec)
@@ -216,7 +296,7 @@ func revertToSelf() error {
 	return nil
 }
 
-//mustCloseHandle ensures to close the user token handle.
+// mustCloseHandle ensures to close the user token handle.
 func mustCloseHandle(log log.T, handle syscall.Handle) {
 	if err := syscall.CloseHandle(handle); err != nil {
 		log.Error(err)
@@ -264,7 +344,7 @@ func (p *ShellPlugin) generateLogData(log log.T, config agentContracts.Configura
 
 // generateTranscriptFile generates a transcript file using PowerShell
 func generateTranscriptFile(log log.T, transcriptFile string, loggerFile string, enableVirtualTerminalProcessingForWindows bool) error {
-	shadowShellInput, _, err := StartPty(log, false, "")
+	shadowShellInput, _, err := StartPty(log, false, "", "", "", "")
 	if err != nil {
 		return err
 	}
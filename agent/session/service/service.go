@@ -25,13 +25,20 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/dnscache"
+	"github.com/aws/amazon-ssm-agent/agent/failover"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/registration"
 	"github.com/aws/amazon-ssm-agent/agent/managedInstances/rolecreds"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/processcreds"
 	mgsconfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -55,13 +62,21 @@ type Service interface {
 
 // MessageGatewayService is a service wrapper that delegates to the message gateway service sdk.
 type MessageGatewayService struct {
-	region string
-	tr     *http.Transport
-	signer *v4.Signer
+	log     log.T
+	region  string
+	tracker *failover.Tracker
+	tr      *http.Transport
+	signer  *v4.Signer
 }
 
 // NewService creates a new service instance.
 func NewService(log log.T, mgsConfig appconfig.MgsConfig, connectionTimeout time.Duration) Service {
+	return NewFailoverService(log, mgsConfig, nil, connectionTimeout)
+}
+
+// NewFailoverService creates a new service instance whose region is selected by the failover tracker,
+// the tracker may be nil when no failover region is configured.
+func NewFailoverService(log log.T, mgsConfig appconfig.MgsConfig, tracker *failover.Tracker, connectionTimeout time.Duration) Service {
 
 	var region *string
 	if mgsConfig.Region != "" {
@@ -93,21 +108,38 @@ func NewService(log log.T, mgsConfig appconfig.MgsConfig, connectionTimeout time
 
 	// capture Transport so we can use it to cancel requests
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
+		Proxy: proxyconfig.ProxyFunc(log, mgsConfig.Proxy),
+		Dial: dnscache.Dial(&net.Dialer{
 			Timeout:   connectionTimeout,
 			KeepAlive: 0,
-		}).Dial,
+		}),
 		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     fips.TLSConfig(nil),
 	}
 
 	return &MessageGatewayService{
-		region: aws.StringValue(region),
-		tr:     tr,
-		signer: v4Signer,
+		log:     log,
+		region:  aws.StringValue(region),
+		tracker: tracker,
+		tr:      tr,
+		signer:  v4Signer,
 	}
 }
 
+// restTransport returns the transport of rest api calls, it sends them through the configured mgs proxy.
+func restTransport() http.RoundTripper {
+	restTransportOnce.Do(func() {
+		appConfig, _ := appconfig.Config(false)
+		restTransportInstance = proxyconfig.Transport(log.DefaultLogger(), appConfig.Mgs.Proxy)
+	})
+	return restTransportInstance
+}
+
+var (
+	restTransportOnce     sync.Once
+	restTransportInstance http.RoundTripper
+)
+
 // makeRestcall triggers rest api call.
 var makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer) ([]byte, error) {
 	httpRequest, err := http.NewRequest(methodType, url, bytes.NewBuffer(request))
@@ -116,13 +148,14 @@ var makeRestcall = func(request []byte, methodType string, url string, region st
 	}
 
 	httpRequest.Header.Set("Content-Type", "application/json")
-	_, err = signer.Sign(httpRequest, bytes.NewReader(request), mgsconfig.ServiceName, region, time.Now())
+	_, err = signer.Sign(httpRequest, bytes.NewReader(request), mgsconfig.ServiceName, region, clockskew.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign the request: %s", err)
 	}
 
 	client := &http.Client{
-		Timeout: mgsClientTimeout,
+		Timeout:   mgsClientTimeout,
+		Transport: restTransport(),
 	}
 
 	resp, err := client.Do(httpRequest)
@@ -138,6 +171,10 @@ var makeRestcall = func(request []byte, methodType string, url string, region st
 	if resp.StatusCode == httpStatusCodeCreated {
 		return body, nil
 	} else {
+		if resp.StatusCode == http.StatusForbidden {
+			// signature errors may be caused by the clock of the instance
+			clockskew.Correct(log.DefaultLogger(), resp.Header.Get("Date"))
+		}
 		return nil, fmt.Errorf("unexpected response from the service %s", body)
 	}
 }
@@ -166,6 +203,11 @@ func getMGSBaseUrl(log log.T, channelType string, channelId string, region strin
 
 // getCredentials gets the current active credentials.
 func getCredentials() (*credentials.Credentials, error) {
+	// credentials printed by the configured credential process take precedence
+	if processCredentials := processcreds.Configured(); processCredentials != nil {
+		return processCredentials, nil
+	}
+
 	// load managed instance credentials if applicable
 	isManaged, err := registration.HasManagedInstancesCredentials()
 
@@ -181,15 +223,19 @@ func (mgsService *MessageGatewayService) GetV4Signer() *v4.Signer {
 	return mgsService.signer
 }
 
-// GetRegion gets the region.
+// GetRegion gets the region, the active failover region when failover regions are configured.
 func (mgsService *MessageGatewayService) GetRegion() string {
+	if mgsService.tracker != nil {
+		return mgsService.tracker.Region(mgsService.log)
+	}
 	return mgsService.region
 }
 
 // CreateControlChannel calls the CreateControlChannel MGS API
 func (mgsService *MessageGatewayService) CreateControlChannel(log log.T, createControlChannelInput *CreateControlChannelInput, channelId string) (createControlChannelOutput *CreateControlChannelOutput, err error) {
 
-	url, err := getMGSBaseUrl(log, mgsconfig.ControlChannel, channelId, mgsService.region)
+	region := mgsService.GetRegion()
+	url, err := getMGSBaseUrl(log, mgsconfig.ControlChannel, channelId, region)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the mgs base url with error: %s", err)
 	}
@@ -203,10 +249,16 @@ func (mgsService *MessageGatewayService) CreateControlChannel(log log.T, createC
 		return nil, errors.New("unable to marshal the createControlChannelInput")
 	}
 
-	resp, err := makeRestcall(jsonValue, "POST", url, mgsService.region, mgsService.signer)
+	resp, err := makeRestcall(jsonValue, "POST", url, region, mgsService.signer)
 	if err != nil {
+		if mgsService.tracker != nil {
+			mgsService.tracker.ReportFailure(log)
+		}
 		return nil, fmt.Errorf("createControlChannel request failed: %s", err)
 	}
+	if mgsService.tracker != nil {
+		mgsService.tracker.ReportSuccess()
+	}
 
 	var output CreateControlChannelOutput
 	if resp != nil {
@@ -221,7 +273,8 @@ func (mgsService *MessageGatewayService) CreateControlChannel(log log.T, createC
 // CreateDataChannel calls the CreateDataChannel MGS API
 func (mgsService *MessageGatewayService) CreateDataChannel(log log.T, createDataChannelInput *CreateDataChannelInput, sessionId string) (createDataChannelOutput *CreateDataChannelOutput, err error) {
 
-	url, err := getMGSBaseUrl(log, mgsconfig.DataChannel, sessionId, mgsService.region)
+	region := mgsService.GetRegion()
+	url, err := getMGSBaseUrl(log, mgsconfig.DataChannel, sessionId, region)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the mgs base url with error: %s", err)
 	}
@@ -235,7 +288,7 @@ func (mgsService *MessageGatewayService) CreateDataChannel(log log.T, createData
 		return nil, errors.New("unable to marshal the createDataChannelInput")
 	}
 
-	resp, err := makeRestcall(jsonValue, "POST", url, mgsService.region, mgsService.signer)
+	resp, err := makeRestcall(jsonValue, "POST", url, region, mgsService.signer)
 	if err != nil {
 		return nil, fmt.Errorf("createDataChannel request failed: %s", err)
 	}
@@ -23,18 +23,22 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/failover"
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/controlchannel"
 	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
+	"github.com/aws/amazon-ssm-agent/agent/tracing"
 	"github.com/gorilla/websocket"
 	"github.com/twinj/uuid"
 )
@@ -48,8 +52,13 @@ type Session struct {
 	service        service.Service
 	controlChannel controlchannel.IControlChannel
 	processor      processor.Processor
+	failover       bool
+	stopFailback   chan bool
 }
 
+// failbackCheckInterval is how often the control channel region is compared with the active failover region
+var failbackCheckInterval = time.Minute
+
 // NewSession gets session core module that manages the web-socket connection between Agent and message gateway service.
 func NewSession(context context.T) *Session {
 	sessionContext := context.With("[" + mgsConfig.SessionServiceName + "]")
@@ -69,6 +78,12 @@ func NewSession(context context.T) *Session {
 		return nil
 	}
 
+	// The message gateway service is not simulated, sessions are not available in the simulation mode.
+	if appConfig.Simulation.Endpoint != "" {
+		log.Info("Session core module is not supported in the simulation mode.")
+		return nil
+	}
+
 	agentInfo := contracts.AgentInfo{
 		Lang:      appConfig.Os.Lang,
 		Name:      appConfig.Agent.Name,
@@ -103,7 +118,8 @@ func NewSession(context context.T) *Session {
 
 	connectionTimeout := time.Duration(messageGatewayServiceConfig.StopTimeoutMillis) * time.Millisecond
 
-	mgsService := service.NewService(log, messageGatewayServiceConfig, connectionTimeout)
+	tracker := failover.NewTracker(mgsConfig.SessionServiceName, messageGatewayServiceConfig.Region, appConfig.Failover)
+	mgsService := service.NewFailoverService(log, messageGatewayServiceConfig, tracker, connectionTimeout)
 	processor := processor.NewEngineProcessor(
 		sessionContext,
 		messageGatewayServiceConfig.SessionWorkersLimit,
@@ -120,6 +136,8 @@ func NewSession(context context.T) *Session {
 		service:        mgsService,
 		processor:      processor,
 		controlChannel: controlChannel,
+		failover:       tracker != nil,
+		stopFailback:   make(chan bool, 1),
 	}
 }
 
@@ -191,6 +209,10 @@ func (s *Session) ModuleExecute(context context.T) (err error) {
 
 	log.Info("Starting receiving message from control channel")
 
+	if s.failover {
+		go s.monitorFailback(instanceId)
+	}
+
 	if err = s.processor.InitialProcessing(); err != nil {
 		log.Errorf("initial processing in EngineProcessor encountered error: %v", err)
 		return
@@ -210,6 +232,10 @@ func (s *Session) ModuleRequestStop(stopType contracts.StopType) (err error) {
 		}
 	}()
 
+	if s.failover {
+		s.stopFailback <- true
+	}
+
 	if s.controlChannel != nil {
 		if err = s.controlChannel.Close(log); err != nil {
 			log.Errorf("stopping controlchannel with error, %s", err)
@@ -221,6 +247,37 @@ func (s *Session) ModuleRequestStop(stopType contracts.StopType) (err error) {
 	return nil
 }
 
+// monitorFailback moves the control channel to the active region of the service when they differ,
+// which happens when the primary region is due to be tried again after a failover.
+func (s *Session) monitorFailback(instanceId string) {
+	log := s.context.Log()
+	ticker := time.NewTicker(failbackCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopFailback:
+			return
+		case <-ticker.C:
+			region := s.service.GetRegion()
+			if region == s.controlChannel.GetRegion() {
+				continue
+			}
+			log.Infof("Moving control channel from region %s to region %s", s.controlChannel.GetRegion(), region)
+			if err := s.controlChannel.Close(log); err != nil {
+				log.Warnf("closing control channel failed with error: %s", err)
+			}
+			if err := s.controlChannel.SetWebSocket(s.context, s.service, s.processor, instanceId); err != nil {
+				log.Errorf("failed to set up control channel in region %s, error: %s", region, err)
+				continue
+			}
+			if err := s.controlChannel.Open(log); err != nil {
+				log.Errorf("failed to open control channel in region %s, error: %s", region, err)
+			}
+		}
+	}
+}
+
 // listenReply listens document result of session execution.
 func (s *Session) listenReply(resultChan chan contracts.DocumentResult, instanceId string) {
 	log := s.context.Log()
@@ -228,10 +285,21 @@ func (s *Session) listenReply(resultChan chan contracts.DocumentResult, instance
 
 	//processor guarantees to close this channel upon stop
 	for res := range resultChan {
+		span := tracing.Tracked(res.MessageID)
 		if res.LastPlugin != "" {
 			log.Infof("received plugin: %s result from Processor", res.LastPlugin)
 		} else {
 			log.Infof("session: %s complete", res.MessageID)
+			metrics.SessionEnded(res.MessageID, string(res.Status))
+			audit.Record(audit.Event{
+				Type:         audit.SessionEnded,
+				SessionID:    res.MessageID,
+				DocumentName: res.DocumentName,
+				Status:       string(res.Status),
+			})
+			tracing.Untrack(res.MessageID)
+			span.SetAttribute("ssm.session_status", string(res.Status))
+			span.End(tracing.StatusError(res.Status, ""))
 
 			//Deleting Old Log Files
 			instanceID, _ := platform.InstanceID()
@@ -248,7 +316,9 @@ func (s *Session) listenReply(resultChan chan contracts.DocumentResult, instance
 
 		// For last document level result, no need to send reply because there will be only one plugin for shell plugin case.
 		if msg != nil {
+			replySpan := span.StartChild("session.reply")
 			err = s.controlChannel.SendMessage(log, msg, websocket.BinaryMessage)
+			replySpan.End(err)
 			if err != nil {
 				log.Errorf("Error sending reply message %v", err)
 			}
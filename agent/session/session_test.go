@@ -348,7 +348,7 @@ func (suite *SessionTestSuite) TestGetMgsEndpoint() {
 	assert.Equal(suite.T(), "https://ssmmessages.cn-north-1.amazonaws.com.cn", bjsHost)
 }
 
-//Execute the test suite
+// Execute the test suite
 func TestSessionTestSuite(t *testing.T) {
 	suite.Run(t, new(SessionTestSuite))
 }
@@ -0,0 +1,71 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package utility
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// lookupGroup returns the group with the name, it is stubbed in the tests
+var lookupGroup = user.LookupGroup
+
+// AdjustGroups adds the groups of add to the supplementary groups of the runas user and removes the groups of drop,
+// the groups are names or ids. The groups of drop which do not exist are ignored.
+func AdjustGroups(groups []uint32, add []string, drop []string) ([]uint32, error) {
+	dropped := map[uint32]bool{}
+	for _, group := range drop {
+		if gid, err := groupID(group); err == nil {
+			dropped[gid] = true
+		}
+	}
+	adjusted := []uint32{}
+	present := map[uint32]bool{}
+	for _, gid := range groups {
+		if !dropped[gid] && !present[gid] {
+			adjusted, present[gid] = append(adjusted, gid), true
+		}
+	}
+	for _, group := range add {
+		gid, err := groupID(group)
+		if err != nil {
+			return nil, err
+		}
+		if !dropped[gid] && !present[gid] {
+			adjusted, present[gid] = append(adjusted, gid), true
+		}
+	}
+	return adjusted, nil
+}
+
+// groupID returns the id of the group named by its name or its id.
+func groupID(group string) (uint32, error) {
+	if gid, err := strconv.ParseUint(group, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+	g, err := lookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up the group %v: %v", group, err)
+	}
+	gid, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gid %v of %v", g.Gid, group)
+	}
+	return uint32(gid), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package utility
+
+import (
+	"fmt"
+	"os/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustGroups(t *testing.T) {
+	lookupGroupOrig := lookupGroup
+	defer func() { lookupGroup = lookupGroupOrig }()
+	gids := map[string]string{"wheel": "10", "docker": "995", "adm": "4"}
+	lookupGroup = func(name string) (*user.Group, error) {
+		if gid, ok := gids[name]; ok {
+			return &user.Group{Gid: gid, Name: name}, nil
+		}
+		return nil, fmt.Errorf("unknown group %v", name)
+	}
+
+	groups, err := AdjustGroups([]uint32{1001, 10, 4}, []string{"docker", "4"}, []string{"wheel", "video"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1001, 4, 995}, groups)
+
+	// the dropped groups are not added back
+	groups, err = AdjustGroups([]uint32{1001}, []string{"wheel"}, []string{"10"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1001}, groups)
+
+	groups, err = AdjustGroups([]uint32{1001, 10}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1001, 10}, groups)
+
+	_, err = AdjustGroups([]uint32{1001}, []string{"video"}, nil)
+	assert.Error(t, err)
+}
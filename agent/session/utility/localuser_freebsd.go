@@ -0,0 +1,25 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package utility
+
+import "fmt"
+
+// sudoersFile grants the administrator permissions to the runas user, sudo is installed from the ports under /usr/local
+var sudoersFile = "/usr/local/etc/sudoers.d/ssm-agent-users"
+
+// addUserCommand returns the shell command creating a local user with a home directory, FreeBSD has no useradd
+func addUserCommand(username string) string {
+	return fmt.Sprintf("pw useradd -n %s -m -s /bin/sh", username)
+}
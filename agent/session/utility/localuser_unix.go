@@ -0,0 +1,37 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+//go:build darwin || linux || netbsd || openbsd
+// +build darwin linux netbsd openbsd
+
+package utility
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// sudoersFile grants the administrator permissions to the runas user
+var sudoersFile = "/etc/sudoers.d/ssm-agent-users"
+
+// addUserCommand returns the shell command creating a local user with a home directory
+func addUserCommand(username string) string {
+	// /etc/passwd is read-only on Ubuntu Core, the account-control interface of a strictly confined snap
+	// only allows to create the users in the extrausers database
+	if appconfig.StrictSnapConfinement() {
+		return fmt.Sprintf("useradd --extrausers -m %s", username)
+	}
+	return fmt.Sprintf("useradd -m %s", username)
+}
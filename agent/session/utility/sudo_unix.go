@@ -0,0 +1,128 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package utility
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/privsep"
+)
+
+// sudoSessionsDir holds a file per process running a session as the runas user, named by its pid, while the sudoers
+// file is ephemeral
+var sudoSessionsDir = filepath.Join(appconfig.DefaultDataStorePath, "sudo-sessions")
+
+// sudoSessionsLock serializes the grants and the revocations of the processes in sudoSessionsDir
+const sudoSessionsLock = ".lock"
+
+// processExists returns true if the process with the pid is running, it is stubbed in the tests
+var processExists = func(log log.T, pid int) bool {
+	return proc.IsProcessExists(log, pid, time.Time{})
+}
+
+// GrantSudo creates the sudoers file of the runas user for the session run by the process, through the helper when
+// the agent is unprivileged.
+func (u *SessionUtil) GrantSudo(log log.T) error {
+	if privsep.Required() {
+		if output, err := privsep.Command(privsep.OpGrantSudo).CombinedOutput(); err != nil {
+			log.Errorf("Failed to grant sudo to %s through the helper: %v, %s", appconfig.DefaultRunAsUserName, err, output)
+			return err
+		}
+		return nil
+	}
+	return u.GrantSudoToProcess(log, os.Getpid())
+}
+
+// RevokeSudo removes the sudoers file of the runas user when the session run by the process was the last one, through
+// the helper when the agent is unprivileged.
+func (u *SessionUtil) RevokeSudo(log log.T) error {
+	if privsep.Required() {
+		if output, err := privsep.Command(privsep.OpRevokeSudo).CombinedOutput(); err != nil {
+			log.Errorf("Failed to revoke sudo from %s through the helper: %v, %s", appconfig.DefaultRunAsUserName, err, output)
+			return err
+		}
+		return nil
+	}
+	return u.RevokeSudoFromProcess(log, os.Getpid())
+}
+
+// GrantSudoToProcess records the session of the process and creates the sudoers file of the runas user.
+func (u *SessionUtil) GrantSudoToProcess(log log.T, pid int) error {
+	return withSudoSessionsLock(func() error {
+		if err := ioutil.WriteFile(filepath.Join(sudoSessionsDir, strconv.Itoa(pid)), nil, 0600); err != nil {
+			return err
+		}
+		return u.createSudoersFileIfNotPresent(log)
+	})
+}
+
+// RevokeSudoFromProcess forgets the session of the process and the sessions of the processes which are gone, and
+// removes the sudoers file of the runas user when no session is left.
+func (u *SessionUtil) RevokeSudoFromProcess(log log.T, pid int) error {
+	return withSudoSessionsLock(func() error {
+		if err := os.Remove(filepath.Join(sudoSessionsDir, strconv.Itoa(pid))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		files, err := ioutil.ReadDir(sudoSessionsDir)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			sessionPid, err := strconv.Atoi(file.Name())
+			if err != nil {
+				continue
+			}
+			if processExists(log, sessionPid) {
+				log.Debugf("Keeping the sudoers file of %s for the session of process %d", appconfig.DefaultRunAsUserName, sessionPid)
+				return nil
+			}
+			os.Remove(filepath.Join(sudoSessionsDir, file.Name()))
+		}
+		if err = os.Remove(sudoersFile); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to remove %s: %v", sudoersFile, err)
+			return err
+		}
+		log.Infof("Successfully removed file %s", sudoersFile)
+		return nil
+	})
+}
+
+// withSudoSessionsLock runs the function holding the exclusive lock of sudoSessionsDir.
+func withSudoSessionsLock(function func() error) error {
+	if err := os.MkdirAll(sudoSessionsDir, 0700); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(filepath.Join(sudoSessionsDir, sudoSessionsLock), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err = syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+	return function()
+}
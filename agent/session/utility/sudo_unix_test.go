@@ -0,0 +1,80 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package utility
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSudo points the sudoers file and the sessions to a temporary directory, the processes in running are alive
+func stubSudo(t *testing.T, running map[int]bool) func() {
+	dir, err := ioutil.TempDir("", "sudo")
+	assert.NoError(t, err)
+	sudoersFileOrig, sudoSessionsDirOrig, processExistsOrig := sudoersFile, sudoSessionsDir, processExists
+	sudoersFile, sudoSessionsDir = filepath.Join(dir, "ssm-agent-users"), filepath.Join(dir, "sessions")
+	processExists = func(log log.T, pid int) bool { return running[pid] }
+	return func() {
+		sudoersFile, sudoSessionsDir, processExists = sudoersFileOrig, sudoSessionsDirOrig, processExistsOrig
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEphemeralSudo(t *testing.T) {
+	running := map[int]bool{}
+	defer stubSudo(t, running)()
+	u := &SessionUtil{}
+	logger := log.NewMockLog()
+
+	running[100], running[200] = true, true
+	assert.NoError(t, u.GrantSudoToProcess(logger, 100))
+	assert.True(t, fileutil.Exists(sudoersFile))
+	assert.NoError(t, u.GrantSudoToProcess(logger, 200))
+
+	// the sudoers file is kept while a session runs
+	running[100] = false
+	assert.NoError(t, u.RevokeSudoFromProcess(logger, 100))
+	assert.True(t, fileutil.Exists(sudoersFile))
+
+	running[200] = false
+	assert.NoError(t, u.RevokeSudoFromProcess(logger, 200))
+	assert.False(t, fileutil.Exists(sudoersFile))
+	assert.NoError(t, u.RevokeSudoFromProcess(logger, 200))
+}
+
+func TestEphemeralSudo_ProcessGone(t *testing.T) {
+	running := map[int]bool{}
+	defer stubSudo(t, running)()
+	u := &SessionUtil{}
+	logger := log.NewMockLog()
+
+	// the session of a process which is gone without revoking sudo does not keep the sudoers file
+	running[300] = true
+	assert.NoError(t, u.GrantSudoToProcess(logger, 300))
+	assert.NoError(t, u.GrantSudoToProcess(logger, 400))
+	running[300] = false
+	assert.NoError(t, u.RevokeSudoFromProcess(logger, 400))
+	assert.False(t, fileutil.Exists(sudoersFile))
+	assert.False(t, fileutil.Exists(filepath.Join(sudoSessionsDir, "300")))
+}
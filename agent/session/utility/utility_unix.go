@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // utility package implements all the shared methods between clients.
@@ -24,12 +25,12 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/privsep"
 )
 
 var ShellPluginCommandName = "sh"
 var ShellPluginCommandArgs = []string{"-c"}
 
-const sudoersFile = "/etc/sudoers.d/ssm-agent-users"
 const sudoersFileMode = 0440
 
 // ResetPasswordIfDefaultUserExists resets default RunAs user password if user exists
@@ -55,6 +56,15 @@ func (u *SessionUtil) DoesUserExist(username string) (bool, error) {
 // createLocalAdminUser creates a local OS user on the instance with admin permissions. The password will alway be empty
 func (u *SessionUtil) CreateLocalAdminUser(log log.T) (newPassword string, err error) {
 
+	// the unprivileged agent cannot create users, the helper creates it and its sudoers file
+	if privsep.Required() {
+		if output, err := privsep.Command(privsep.OpCreateUser).CombinedOutput(); err != nil {
+			log.Errorf("Failed to create %s through the helper: %v, %s", appconfig.DefaultRunAsUserName, err, output)
+			return "", err
+		}
+		return "", nil
+	}
+
 	userExists, _ := u.DoesUserExist(appconfig.DefaultRunAsUserName)
 
 	if userExists {
@@ -73,7 +83,7 @@ func (u *SessionUtil) CreateLocalAdminUser(log log.T) (newPassword string, err e
 // createLocalUser creates an OS local user.
 func (u *SessionUtil) createLocalUser(log log.T) error {
 
-	commandArgs := append(ShellPluginCommandArgs, fmt.Sprintf("useradd -m %s", appconfig.DefaultRunAsUserName))
+	commandArgs := append(ShellPluginCommandArgs, addUserCommand(appconfig.DefaultRunAsUserName))
 	cmd := exec.Command(ShellPluginCommandName, commandArgs...)
 	if err := cmd.Run(); err != nil {
 		log.Errorf("Failed to create %s: %v", appconfig.DefaultRunAsUserName, err)
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build windows
 // +build windows
 
 // utility package implements all the shared methods between clients.
@@ -386,6 +387,16 @@ func (u *SessionUtil) EnableLocalUser(log log.T) (err error) {
 	return nil
 }
 
+// GrantSudo does nothing, the runas user is an administrator on Windows.
+func (u *SessionUtil) GrantSudo(log log.T) error {
+	return nil
+}
+
+// RevokeSudo does nothing, the runas user is disabled when the sessions end on Windows.
+func (u *SessionUtil) RevokeSudo(log log.T) error {
+	return nil
+}
+
 func (u *SessionUtil) DisableLocalUser(log log.T) (err error) {
 	if err = u.userAddFlags(log, appconfig.DefaultRunAsUserName, USER_UF_ACCOUNTDISABLE); err != nil {
 		log.Errorf("error occurred disabling %s: %v", appconfig.DefaultRunAsUserName, err)
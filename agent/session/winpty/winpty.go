@@ -11,9 +11,10 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build windows
 // +build windows
 
-//winpty package is wrapper package for calling procedures of winpty.dll
+// winpty package is wrapper package for calling procedures of winpty.dll
 package winpty
 
 import (
@@ -32,7 +33,7 @@ type IWinPTY interface {
 	Close() error
 }
 
-//WinPTY contains handlers and pointers needed for launching winpty agent process
+// WinPTY contains handlers and pointers needed for launching winpty agent process
 type WinPTY struct {
 	IWinPTY
 	StdIn  *os.File
@@ -44,8 +45,9 @@ type WinPTY struct {
 	closed        bool
 }
 
-//Start launches winpty agent as a separate process
-func Start(winptyDllFilePath, cmdLine string, window_size_cols, window_size_rows uint32, winptyFlag int32) (*WinPTY, error) {
+// Start launches winpty agent as a separate process, the process spawned in the pty starts in workingDir, the directory of
+// the agent when it is empty, with the environment variables of env, the ones of the agent when it is empty
+func Start(winptyDllFilePath, cmdLine string, workingDir string, env []string, window_size_cols, window_size_rows uint32, winptyFlag int32) (*WinPTY, error) {
 
 	var winpty WinPTY = WinPTY{}
 
@@ -64,14 +66,20 @@ func Start(winptyDllFilePath, cmdLine string, window_size_cols, window_size_rows
 		return nil, err
 	}
 
-	if err := winpty.spawnProcess(cmdLine); err != nil {
+	if err := winpty.spawnProcess(cmdLine, workingDir, env); err != nil {
 		return nil, err
 	}
 
 	return &winpty, nil
 }
 
-//configureAgent configures agent and sets initial window size.
+// ProcessID returns the id of the process spawned in the pty, 0 when it is unknown.
+func (winpty *WinPTY) ProcessID() uint32 {
+	pid, _, _ := getProcessId.Call(winpty.processHandle)
+	return uint32(pid)
+}
+
+// configureAgent configures agent and sets initial window size.
 func (winpty *WinPTY) configureAgent(window_size_cols, window_size_rows uint32, winptyFlag int32) (err error) {
 	var errorPtr uintptr
 	defer winpty_error_free.Call(errorPtr)
@@ -99,7 +107,7 @@ func (winpty *WinPTY) configureAgent(window_size_cols, window_size_rows uint32,
 	return nil
 }
 
-//startAgent launches winpty agent.
+// startAgent launches winpty agent.
 func (winpty *WinPTY) startAgent() (err error) {
 	var errorPtr uintptr
 	defer winpty_error_free.Call(errorPtr)
@@ -117,7 +125,7 @@ func (winpty *WinPTY) startAgent() (err error) {
 	return nil
 }
 
-//getIOPipes gets handle for stdin and stdout.
+// getIOPipes gets handle for stdin and stdout.
 func (winpty *WinPTY) getIOPipes() (err error) {
 	conin_name, _, lastErr := winpty_conin_name.Call(winpty.agent)
 	if conin_name == uintptr(NIL_POINTER_VALUE) {
@@ -162,8 +170,8 @@ func (winpty *WinPTY) getIOPipes() (err error) {
 	return nil
 }
 
-//spawnProcess creates a new winpty agent process.
-func (winpty *WinPTY) spawnProcess(cmdLine string) (err error) {
+// spawnProcess creates a new winpty agent process.
+func (winpty *WinPTY) spawnProcess(cmdLine string, workingDir string, env []string) (err error) {
 	var errorPtr uintptr
 	defer winpty_error_free.Call(errorPtr)
 
@@ -172,12 +180,30 @@ func (winpty *WinPTY) spawnProcess(cmdLine string) (err error) {
 		return fmt.Errorf("Failed to convert cmd to pointer. %s", err)
 	}
 
+	// a nil working directory spawns the process in the directory of the agent
+	var workingDirUTF16Ptr *uint16
+	if workingDir != "" {
+		if workingDirUTF16Ptr, err = syscall.UTF16PtrFromString(workingDir); err != nil {
+			return fmt.Errorf("Failed to convert working directory to pointer. %s", err)
+		}
+	}
+
+	// the environment block holds the NUL terminated variables followed by a NUL
+	var envBlock []uint16
+	if len(env) > 0 {
+		envBlock = utf16.Encode([]rune(strings.Join(env, "\x00") + "\x00\x00"))
+	}
+	var envPtr *uint16
+	if envBlock != nil {
+		envPtr = &envBlock[0]
+	}
+
 	spawnConfig, _, lastErr := winpty_spawn_config_new.Call(
 		uintptr(uint64(WINPTY_SPAWN_FLAG_AUTO_SHUTDOWN)),
 		uintptr(0),
 		uintptr(unsafe.Pointer(cmdLineUTF16Ptr)),
-		uintptr(0),
-		uintptr(0),
+		uintptr(unsafe.Pointer(workingDirUTF16Ptr)),
+		uintptr(unsafe.Pointer(envPtr)),
 		uintptr(unsafe.Pointer(&errorPtr)))
 	if spawnConfig == uintptr(NIL_POINTER_VALUE) {
 		return winpty.getFormattedErrorMessage(
@@ -214,7 +240,7 @@ func (winpty *WinPTY) spawnProcess(cmdLine string) (err error) {
 	return nil
 }
 
-//SetSize sets given console window size.
+// SetSize sets given console window size.
 func (winpty *WinPTY) SetSize(ws_col, ws_row uint32) (err error) {
 	var errorPtr uintptr
 	defer winpty_error_free.Call(errorPtr)
@@ -238,7 +264,7 @@ func (winpty *WinPTY) SetSize(ws_col, ws_row uint32) (err error) {
 	return nil
 }
 
-//Close closes stdin, stdout and winpty process handle.
+// Close closes stdin, stdout and winpty process handle.
 func (winpty *WinPTY) Close() (err error) {
 	if winpty == nil || winpty.closed {
 		return
@@ -262,7 +288,7 @@ func (winpty *WinPTY) Close() (err error) {
 	return nil
 }
 
-//getWinptyErrorMessage returns string error message for given error pointer.
+// getWinptyErrorMessage returns string error message for given error pointer.
 func (winpty *WinPTY) getWinptyErrorMessage(winptyErr uintptr) string {
 	winptyErrorMsgPtr, _, lastErr := winpty_error_msg.Call(winptyErr)
 	if winptyErrorMsgPtr == uintptr(NIL_POINTER_VALUE) {
@@ -271,7 +297,7 @@ func (winpty *WinPTY) getWinptyErrorMessage(winptyErr uintptr) string {
 	return convertUTF16PtrToString((*uint16)(unsafe.Pointer(winptyErrorMsgPtr)))
 }
 
-//getWinptyErrorCode gets winpty error code for give error pointer.
+// getWinptyErrorCode gets winpty error code for give error pointer.
 func (winpty *WinPTY) getWinptyErrorCode(winptyErr uintptr) (errCode uint32, err error) {
 	winptyErrorCodePtr, _, lastErr := winpty_error_code.Call(uintptr(unsafe.Pointer(&winptyErr)))
 	if winptyErrorCodePtr == uintptr(NIL_POINTER_VALUE) {
@@ -283,7 +309,7 @@ func (winpty *WinPTY) getWinptyErrorCode(winptyErr uintptr) (errCode uint32, err
 	return *(*uint32)(unsafe.Pointer(winptyErrorCodePtr)), nil
 }
 
-//convertUTF16PtrToString converts utf16 pointer to string
+// convertUTF16PtrToString converts utf16 pointer to string
 func convertUTF16PtrToString(UTF16Ptr *uint16) string {
 
 	var inputStrChar uint16
@@ -301,7 +327,7 @@ func convertUTF16PtrToString(UTF16Ptr *uint16) string {
 	}
 }
 
-//getWindowsErrorMessage fetches windows error message for given error code
+// getWindowsErrorMessage fetches windows error message for given error code
 func getWindowsErrorMessage(errorCode uint32) string {
 	flags := uint32(windows.FORMAT_MESSAGE_FROM_SYSTEM | windows.FORMAT_MESSAGE_IGNORE_INSERTS)
 	langId := uint32(windows.SUBLANG_ENGLISH_US)<<10 | uint32(windows.LANG_ENGLISH)
@@ -314,7 +340,7 @@ func getWindowsErrorMessage(errorCode uint32) string {
 	return strings.TrimSpace(syscall.UTF16ToString(buf))
 }
 
-//getFormattedErrorMessage returns formatted error containing custom error string, syscall error and winpty error
+// getFormattedErrorMessage returns formatted error containing custom error string, syscall error and winpty error
 func (winpty *WinPTY) getFormattedErrorMessage(errString string, syscallErr error, winptyErr uintptr) (err error) {
 	return fmt.Errorf(
 		"%s Error returned by syscall: %s Error returned by winpty: %s",
@@ -11,9 +11,10 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build windows
 // +build windows
 
-//winpty package is wrapper package for calling procedures of winpty.dll
+// winpty package is wrapper package for calling procedures of winpty.dll
 package winpty
 
 import (
@@ -55,12 +56,15 @@ var (
 
 var winptyModule *syscall.LazyDLL
 
-//loadDll gets lazydll for winpty.dll which gets loaded once it's procedures are called
+// getProcessId gets the id of the process spawned in the pty from its handle
+var getProcessId = syscall.NewLazyDLL("kernel32.dll").NewProc("GetProcessId")
+
+// loadDll gets lazydll for winpty.dll which gets loaded once it's procedures are called
 func loadDll(winptyDllFilePath string) {
 	winptyModule = syscall.NewLazyDLL(winptyDllFilePath)
 }
 
-//defineProcedures gets lazyproc for winpty.dll procedures
+// defineProcedures gets lazyproc for winpty.dll procedures
 func defineProcedures() {
 
 	// Error handling.
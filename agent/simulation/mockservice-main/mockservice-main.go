@@ -0,0 +1,46 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package main represents the entry point of the mock service of the agent simulation mode.
+//
+// Run the mock service and the agent against it:
+//
+//	ssm-mock-service -address 127.0.0.1:8090
+//	amazon-ssm-agent -simulation http://127.0.0.1:8090
+//
+// then send a command and read its replies:
+//
+//	curl -d '{"DocumentContent": {...}, "Parameters": {...}}' http://127.0.0.1:8090/commands
+//	curl http://127.0.0.1:8090/commands/<command id>
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/simulation/mockservice"
+)
+
+func main() {
+	address := flag.String("address", "127.0.0.1:8090", "address the mock service listens on")
+	flag.Parse()
+
+	fmt.Printf("Serving the mock message delivery service on http://%v, commands are sent to %v\n", *address, mockservice.CommandsPath)
+	if err := http.ListenAndServe(*address, mockservice.NewServer()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to serve the mock service: %v\n", err)
+		os.Exit(1)
+	}
+}
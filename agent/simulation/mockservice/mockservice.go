@@ -0,0 +1,366 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package mockservice implements a mock of the message delivery and systems manager services, the agent
+// started in the simulation mode runs the commands sent to the mock service without AWS credentials.
+package mockservice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ssmmds"
+	"github.com/twinj/uuid"
+)
+
+const (
+	// CommandsPath is the path commands are sent to, the state of a command is served at CommandsPath/<command id>
+	CommandsPath = "/commands"
+
+	// DefaultPollWait is the time a GetMessages call waits for a message before returning none
+	DefaultPollWait = 10 * time.Second
+
+	mdsTargetPrefix  = "EC2WindowsMessageDeliveryService."
+	ssmTargetPrefix  = "AmazonSSM."
+	sendCommandTopic = "aws.ssm.sendCommand.simulation"
+	amzJSONType      = "application/x-amz-json-1.1"
+)
+
+// Command statuses, as reported by the message delivery calls of the agent
+const (
+	CommandStatusPending    = "Pending"
+	CommandStatusDelivered  = "Delivered"
+	CommandStatusInProgress = "InProgress"
+	CommandStatusCompleted  = "Completed"
+	CommandStatusFailed     = "Failed"
+)
+
+// CommandRequest is the body of the requests sending a command to the agent
+type CommandRequest struct {
+	DocumentName    string
+	DocumentContent contracts.DocumentContent
+	Parameters      map[string]interface{}
+}
+
+// Command is the state of a command sent to the agent
+type Command struct {
+	CommandID      string
+	Status         string
+	DocumentStatus contracts.ResultStatus
+	Replies        []messageContracts.SendReplyPayload
+}
+
+// Server serves the message delivery and systems manager calls of the agent and the commands api
+type Server struct {
+	// PollWait is the time a GetMessages call waits for a message before returning none
+	PollWait time.Duration
+
+	lock     sync.Mutex
+	pending  []string
+	commands map[string]*command
+	notify   chan struct{}
+}
+
+// command is a command sent to the agent with its payload
+type command struct {
+	Command
+	payload messageContracts.SendCommandPayload
+}
+
+// NewServer creates a mock service without commands
+func NewServer() *Server {
+	return &Server{
+		PollWait: DefaultPollWait,
+		commands: make(map[string]*command),
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// SendCommand queues a command for the agent and returns its id
+func (s *Server) SendCommand(request CommandRequest) (string, error) {
+	if len(request.DocumentContent.MainSteps) == 0 && len(request.DocumentContent.RuntimeConfig) == 0 {
+		return "", fmt.Errorf("the document has no steps")
+	}
+	commandID := uuid.NewV4().String()
+	documentName := request.DocumentName
+	if documentName == "" {
+		documentName = "SimulationDocument"
+	}
+
+	s.lock.Lock()
+	s.commands[commandID] = &command{
+		Command: Command{CommandID: commandID, Status: CommandStatusPending},
+		payload: messageContracts.SendCommandPayload{
+			Parameters:      request.Parameters,
+			DocumentContent: request.DocumentContent,
+			CommandID:       commandID,
+			DocumentName:    documentName,
+		},
+	}
+	s.pending = append(s.pending, commandID)
+	s.lock.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return commandID, nil
+}
+
+// Command returns the state of a command sent to the agent
+func (s *Server) Command(commandID string) (Command, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cmd, ok := s.commands[commandID]
+	if !ok {
+		return Command{}, false
+	}
+	state := cmd.Command
+	state.Replies = append([]messageContracts.SendReplyPayload(nil), cmd.Replies...)
+	return state, true
+}
+
+// ServeHTTP dispatches the service calls on their target header, and the other requests to the commands api
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	switch {
+	case strings.HasPrefix(target, mdsTargetPrefix):
+		s.serveMessageDelivery(w, r, strings.TrimPrefix(target, mdsTargetPrefix))
+	case strings.HasPrefix(target, ssmTargetPrefix):
+		// the systems manager calls of the agent succeed without side effects
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	case target != "":
+		writeError(w, "UnknownOperationException", fmt.Sprintf("unknown operation %v", target))
+	default:
+		s.serveCommands(w, r)
+	}
+}
+
+// serveMessageDelivery serves the message delivery service operations used by the agent
+func (s *Server) serveMessageDelivery(w http.ResponseWriter, r *http.Request, operation string) {
+	var err error
+	var output interface{}
+	switch operation {
+	case "GetMessages":
+		input := &ssmmds.GetMessagesInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			output = s.getMessages(r, aws.StringValue(input.Destination))
+		}
+	case "AcknowledgeMessage":
+		input := &ssmmds.AcknowledgeMessageInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			err = s.setStatus(aws.StringValue(input.MessageId), CommandStatusInProgress)
+			output = &ssmmds.AcknowledgeMessageOutput{}
+		}
+	case "SendReply":
+		input := &ssmmds.SendReplyInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			err = s.addReply(aws.StringValue(input.MessageId), aws.StringValue(input.Payload))
+			output = &ssmmds.SendReplyOutput{}
+		}
+	case "FailMessage":
+		input := &ssmmds.FailMessageInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			err = s.setStatus(aws.StringValue(input.MessageId), CommandStatusFailed)
+			output = &ssmmds.FailMessageOutput{}
+		}
+	case "DeleteMessage":
+		input := &ssmmds.DeleteMessageInput{}
+		if err = jsonutil.UnmarshalJSON(input, r.Body); err == nil {
+			err = s.setStatus(aws.StringValue(input.MessageId), CommandStatusCompleted)
+			output = &ssmmds.DeleteMessageOutput{}
+		}
+	default:
+		writeError(w, "UnknownOperationException", fmt.Sprintf("unknown operation %v", operation))
+		return
+	}
+	if err != nil {
+		writeError(w, "InvalidMessageException", err.Error())
+		return
+	}
+	body, err := jsonutil.BuildJSON(output)
+	if err != nil {
+		writeError(w, "InternalServerError", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", amzJSONType)
+	w.Write(body)
+}
+
+// getMessages returns the pending commands as messages for the destination, it waits for
+// a command to be sent during the poll wait time when none is pending
+func (s *Server) getMessages(r *http.Request, destination string) *ssmmds.GetMessagesOutput {
+	output := &ssmmds.GetMessagesOutput{Destination: aws.String(destination), MessagesRequestId: aws.String(uuid.NewV4().String())}
+	timer := time.NewTimer(s.PollWait)
+	defer timer.Stop()
+	for {
+		if messages := s.deliver(destination); len(messages) > 0 {
+			output.Messages = messages
+			return output
+		}
+		select {
+		case <-s.notify:
+		case <-timer.C:
+			return output
+		case <-r.Context().Done():
+			return output
+		}
+	}
+}
+
+// deliver builds the messages of the pending commands
+func (s *Server) deliver(destination string) []*ssmmds.Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var messages []*ssmmds.Message
+	for _, commandID := range s.pending {
+		cmd := s.commands[commandID]
+		payload, err := json.Marshal(cmd.payload)
+		if err != nil {
+			cmd.Status = CommandStatusFailed
+			continue
+		}
+		cmd.Status = CommandStatusDelivered
+		messages = append(messages, &ssmmds.Message{
+			CreatedDate: aws.String(times.ToIso8601UTC(time.Now())),
+			Destination: aws.String(destination),
+			MessageId:   aws.String(fmt.Sprintf("aws.ssm.%v.%v", commandID, destination)),
+			Payload:     aws.String(string(payload)),
+			Topic:       aws.String(sendCommandTopic),
+		})
+	}
+	s.pending = nil
+	return messages
+}
+
+// findCommand returns the command of a message, the lock has to be held
+func (s *Server) findCommand(messageID string) (*command, error) {
+	commandID, err := messageContracts.GetCommandID(messageID)
+	if err != nil {
+		return nil, err
+	}
+	cmd, ok := s.commands[commandID]
+	if !ok {
+		return nil, fmt.Errorf("unknown message %v", messageID)
+	}
+	return cmd, nil
+}
+
+// setStatus updates the status of the command of a message
+func (s *Server) setStatus(messageID string, status string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cmd, err := s.findCommand(messageID)
+	if err != nil {
+		return err
+	}
+	if cmd.Status != CommandStatusFailed {
+		cmd.Status = status
+	}
+	return nil
+}
+
+// addReply records a reply of the agent, decompressing its payload when needed
+func (s *Server) addReply(messageID string, payload string) error {
+	content, err := replyContent(payload)
+	if err != nil {
+		return err
+	}
+	var reply messageContracts.SendReplyPayload
+	if err = json.Unmarshal(content, &reply); err != nil {
+		return fmt.Errorf("invalid reply payload: %v", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cmd, err := s.findCommand(messageID)
+	if err != nil {
+		return err
+	}
+	cmd.Replies = append(cmd.Replies, reply)
+	cmd.DocumentStatus = reply.DocumentStatus
+	return nil
+}
+
+// replyContent returns the content of a reply payload, which may be sent compressed
+func replyContent(payload string) ([]byte, error) {
+	var compressed messageContracts.CompressedReplyPayload
+	if err := json.Unmarshal([]byte(payload), &compressed); err != nil || compressed.ContentEncoding != messageContracts.ReplyContentEncodingGzip {
+		return []byte(payload), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(compressed.Content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed reply payload: %v", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed reply payload: %v", err)
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// serveCommands serves the commands api: POST CommandsPath sends a command, GET CommandsPath/<id> returns its state
+func (s *Server) serveCommands(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == CommandsPath && r.Method == http.MethodPost:
+		var request CommandRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"Error": fmt.Sprintf("invalid command: %v", err)})
+			return
+		}
+		commandID, err := s.SendCommand(request)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"Error": fmt.Sprintf("invalid command: %v", err)})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"CommandId": commandID})
+	case strings.HasPrefix(r.URL.Path, CommandsPath+"/") && r.Method == http.MethodGet:
+		cmd, ok := s.Command(strings.TrimPrefix(r.URL.Path, CommandsPath+"/"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"Error": "unknown command"})
+			return
+		}
+		writeJSON(w, http.StatusOK, cmd)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeError writes an error response of the json protocol of the services
+func writeError(w http.ResponseWriter, errorType string, message string) {
+	w.Header().Set("Content-Type", amzJSONType)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"__type": errorType, "message": message})
+}
+
+// writeJSON writes a json response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
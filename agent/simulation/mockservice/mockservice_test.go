@@ -0,0 +1,189 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mockservice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
+	messageService "github.com/aws/amazon-ssm-agent/agent/runcommand/mds"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInstanceID = "i-00000000000000000"
+
+var testDocument = contracts.DocumentContent{
+	SchemaVersion: "2.2",
+	MainSteps: []*contracts.InstancePluginConfig{
+		{Action: "aws:runShellScript", Name: "run", Inputs: map[string]interface{}{"runCommand": []interface{}{"echo hello"}}},
+	},
+}
+
+func newTestService(t *testing.T) (*Server, messageService.Service) {
+	server := NewServer()
+	server.PollWait = 100 * time.Millisecond
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	// the region is set so that the sdk config does not look it up in the instance metadata
+	platform.SetRegion("us-east-1")
+	service := messageService.NewService("us-east-1", httpServer.URL, credentials.NewStaticCredentials("id", "secret", ""), 5*time.Second)
+	return server, service
+}
+
+func TestMessageDelivery(t *testing.T) {
+	logger := log.NewMockLog()
+	server, service := newTestService(t)
+
+	output, err := service.GetMessages(logger, testInstanceID)
+	assert.Nil(t, err)
+	assert.Empty(t, output.Messages)
+
+	commandID, err := server.SendCommand(CommandRequest{DocumentContent: testDocument, Parameters: map[string]interface{}{"name": "value"}})
+	assert.Nil(t, err)
+	cmd, _ := server.Command(commandID)
+	assert.Equal(t, CommandStatusPending, cmd.Status)
+
+	output, err = service.GetMessages(logger, testInstanceID)
+	assert.Nil(t, err)
+	assert.Len(t, output.Messages, 1)
+	message := output.Messages[0]
+	assert.Equal(t, testInstanceID, *message.Destination)
+	assert.True(t, strings.HasPrefix(*message.Topic, "aws.ssm.sendCommand."))
+	messageCommandID, err := messageContracts.GetCommandID(*message.MessageId)
+	assert.Nil(t, err)
+	assert.Equal(t, commandID, messageCommandID)
+	var payload messageContracts.SendCommandPayload
+	assert.Nil(t, json.Unmarshal([]byte(*message.Payload), &payload))
+	assert.Equal(t, commandID, payload.CommandID)
+	assert.Equal(t, "aws:runShellScript", payload.DocumentContent.MainSteps[0].Action)
+	assert.Equal(t, "value", payload.Parameters["name"])
+	cmd, _ = server.Command(commandID)
+	assert.Equal(t, CommandStatusDelivered, cmd.Status)
+
+	assert.Nil(t, service.AcknowledgeMessage(logger, *message.MessageId))
+	cmd, _ = server.Command(commandID)
+	assert.Equal(t, CommandStatusInProgress, cmd.Status)
+
+	reply, _ := json.Marshal(messageContracts.SendReplyPayload{DocumentStatus: contracts.ResultStatusInProgress})
+	assert.Nil(t, service.SendReply(logger, *message.MessageId, string(reply)))
+	reply, _ = json.Marshal(messageContracts.SendReplyPayload{DocumentStatus: contracts.ResultStatusSuccess})
+	assert.Nil(t, service.SendReply(logger, *message.MessageId, compress(t, reply)))
+	assert.Nil(t, service.DeleteMessage(logger, *message.MessageId))
+
+	cmd, ok := server.Command(commandID)
+	assert.True(t, ok)
+	assert.Equal(t, CommandStatusCompleted, cmd.Status)
+	assert.Equal(t, contracts.ResultStatusSuccess, cmd.DocumentStatus)
+	assert.Len(t, cmd.Replies, 2)
+	assert.Equal(t, contracts.ResultStatusInProgress, cmd.Replies[0].DocumentStatus)
+}
+
+func TestMessageDelivery_FailMessage(t *testing.T) {
+	logger := log.NewMockLog()
+	server, service := newTestService(t)
+
+	commandID, _ := server.SendCommand(CommandRequest{DocumentContent: testDocument})
+	output, err := service.GetMessages(logger, testInstanceID)
+	assert.Nil(t, err)
+	assert.Nil(t, service.FailMessage(logger, *output.Messages[0].MessageId, messageService.InternalHandlerException))
+	assert.Nil(t, service.DeleteMessage(logger, *output.Messages[0].MessageId))
+	cmd, _ := server.Command(commandID)
+	assert.Equal(t, CommandStatusFailed, cmd.Status)
+
+	assert.NotNil(t, service.AcknowledgeMessage(logger, "aws.ssm.unknown."+testInstanceID))
+}
+
+func TestGetMessages_WaitsForCommand(t *testing.T) {
+	server, service := newTestService(t)
+	server.PollWait = 5 * time.Second
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.SendCommand(CommandRequest{DocumentContent: testDocument})
+	}()
+	output, err := service.GetMessages(log.NewMockLog(), testInstanceID)
+	assert.Nil(t, err)
+	assert.Len(t, output.Messages, 1)
+}
+
+func TestServeHTTP(t *testing.T) {
+	server := NewServer()
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set("X-Amz-Target", "AmazonSSM.UpdateInstanceInformation")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	request.Header.Set("X-Amz-Target", "AmazonSSMMessageGatewayService.CreateControlChannel")
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "UnknownOperationException")
+}
+
+func TestCommandsAPI(t *testing.T) {
+	server := NewServer()
+
+	body, _ := json.Marshal(CommandRequest{DocumentName: "Test", DocumentContent: testDocument})
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, CommandsPath, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var sent map[string]string
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &sent))
+	assert.NotEmpty(t, sent["CommandId"])
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, CommandsPath+"/"+sent["CommandId"], nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var cmd Command
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &cmd))
+	assert.Equal(t, sent["CommandId"], cmd.CommandID)
+	assert.Equal(t, CommandStatusPending, cmd.Status)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, CommandsPath+"/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, CommandsPath, strings.NewReader(`{"DocumentContent": {}}`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func compress(t *testing.T, content []byte) string {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write(content)
+	assert.Nil(t, writer.Close())
+	payload, _ := json.Marshal(messageContracts.CompressedReplyPayload{
+		ContentEncoding: messageContracts.ReplyContentEncodingGzip,
+		Content:         base64.StdEncoding.EncodeToString(buffer.Bytes()),
+	})
+	return string(payload)
+}
@@ -141,6 +141,29 @@ func (_m *Service) GetDecryptedParameters(_a0 log.T, paramNames []string) (*ssm.
 	return r0, r1
 }
 
+// GetParametersByPath provides a mock function with given fields: _a0, path
+func (_m *Service) GetParametersByPath(_a0 log.T, path string) ([]*ssm.Parameter, error) {
+	ret := _m.Called(_a0, path)
+
+	var r0 []*ssm.Parameter
+	if rf, ok := ret.Get(0).(func(log.T, string) []*ssm.Parameter); ok {
+		r0 = rf(_a0, path)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ssm.Parameter)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(log.T, string) error); ok {
+		r1 = rf(_a0, path)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetDocument provides a mock function with given fields: _a0, docName, docVersion
 func (_m *Service) GetDocument(_a0 log.T, docName string, docVersion string) (*ssm.GetDocumentOutput, error) {
 	ret := _m.Called(_a0, docName, docVersion)
@@ -16,6 +16,9 @@ package rsaauth
 
 import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/amazon-ssm-agent/agent/ssm/util"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -54,6 +57,9 @@ func NewRsaService(serverId string, region string, encodedPrivateKey string) Rsa
 	// whenever we update sdk, we need to make sure it's using Beagle's RSA signing protocol
 	ssmService.Handlers.Sign.Clear()
 	ssmService.Handlers.Sign.PushBack(v4.SignRsa)
+	clockskew.AddHandlers(&ssmService.Handlers)
+	circuitbreaker.AddHandlers(&ssmService.Handlers)
+	retryer.AddHandlers(&ssmService.Handlers)
 	return &sdkService{sdk: ssmService}
 }
 
@@ -21,9 +21,14 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/circuitbreaker"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/clockskew"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -72,6 +77,7 @@ type Service interface {
 	UpdateEmptyInstanceInformation(log log.T, agentVersion, agentName string) (response *ssm.UpdateInstanceInformationOutput, err error)
 	GetParameters(log log.T, paramNames []string) (response *ssm.GetParametersOutput, err error)
 	GetDecryptedParameters(log log.T, paramNames []string) (response *ssm.GetParametersOutput, err error)
+	GetParametersByPath(log log.T, path string) (parameters []*ssm.Parameter, err error)
 }
 
 var ssmStopPolicy *sdkutil.StopPolicy
@@ -109,13 +115,17 @@ func NewService() Service {
 		// this is to skip ssl verification for the beta self signed certs
 		if appConfig.Ssm.InsecureSkipVerify {
 			tr := &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				TLSClientConfig: fips.TLSConfig(&tls.Config{InsecureSkipVerify: true}),
 			}
 			awsConfig.HTTPClient = &http.Client{Transport: tr}
 		}
+		proxyconfig.ConfigureAwsProxy(log.DefaultLogger(), awsConfig, appConfig.Ssm.Proxy)
 	}
 	sess := session.New(awsConfig)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	clockskew.AddHandlers(&sess.Handlers)
+	circuitbreaker.AddHandlers(&sess.Handlers)
+	retryer.AddHandlers(&sess.Handlers)
 
 	ssmService := ssm.New(sess)
 	return NewSSMService(ssmService)
@@ -133,7 +143,7 @@ func makeAwsStrings(strings []string) []*string {
 	return out
 }
 
-//ListAssociations calls the ListAssociations SSM API.
+// ListAssociations calls the ListAssociations SSM API.
 func (svc *sdkService) ListAssociations(log log.T, instanceID string) (response *ssm.ListAssociationsOutput, err error) {
 	params := ssm.ListAssociationsInput{
 		AssociationFilterList: []*ssm.AssociationFilter{
@@ -153,7 +163,7 @@ func (svc *sdkService) ListAssociations(log log.T, instanceID string) (response
 	return
 }
 
-//ListInstanceAssociations calls the ListAssociations SSM API.
+// ListInstanceAssociations calls the ListAssociations SSM API.
 func (svc *sdkService) ListInstanceAssociations(log log.T, instanceID string, nextToken *string) (response *ssm.ListInstanceAssociationsOutput, err error) {
 	params := ssm.ListInstanceAssociationsInput{
 		InstanceId: &instanceID,
@@ -209,7 +219,7 @@ func (svc *sdkService) PutComplianceItems(
 	return
 }
 
-//UpdateInstanceAssociationStatus calls the ListAssociations SSM API.
+// UpdateInstanceAssociationStatus calls the ListAssociations SSM API.
 func (svc *sdkService) UpdateInstanceAssociationStatus(log log.T, associationID string, instanceID string, executionResult *ssm.InstanceAssociationExecutionResult) (response *ssm.UpdateInstanceAssociationStatusOutput, err error) {
 	params := ssm.UpdateInstanceAssociationStatusInput{
 		InstanceId:      &instanceID,
@@ -226,7 +236,7 @@ func (svc *sdkService) UpdateInstanceAssociationStatus(log log.T, associationID
 	return
 }
 
-//UpdateAssociationStatus calls the UpdateAssociationStatus SSM API.
+// UpdateAssociationStatus calls the UpdateAssociationStatus SSM API.
 func (svc *sdkService) UpdateAssociationStatus(
 	log log.T,
 	instanceID string,
@@ -247,7 +257,7 @@ func (svc *sdkService) UpdateAssociationStatus(
 	return
 }
 
-//UpdateInstanceInformation calls the UpdateInstanceInformation SSM API.
+// UpdateInstanceInformation calls the UpdateInstanceInformation SSM API.
 func (svc *sdkService) UpdateInstanceInformation(
 	log log.T,
 	agentVersion,
@@ -312,7 +322,7 @@ func (svc *sdkService) UpdateInstanceInformation(
 	return
 }
 
-//UpdateEmptyInstanceInformation calls the UpdateInstanceInformation SSM API with an empty ping.
+// UpdateEmptyInstanceInformation calls the UpdateInstanceInformation SSM API with an empty ping.
 func (svc *sdkService) UpdateEmptyInstanceInformation(
 	log log.T,
 	agentVersion,
@@ -363,7 +373,7 @@ func (svc *sdkService) CreateDocument(log log.T, docName string, docContent stri
 	return
 }
 
-//GetDocument calls the GetDocument SSM API to retrieve document with given document name
+// GetDocument calls the GetDocument SSM API to retrieve document with given document name
 func (svc *sdkService) GetDocument(log log.T, docName string, docVersion string) (response *ssm.GetDocumentOutput, err error) {
 	params := ssm.GetDocumentInput{
 		Name: aws.String(docName),
@@ -382,7 +392,7 @@ func (svc *sdkService) GetDocument(log log.T, docName string, docVersion string)
 	return
 }
 
-//DescribeAssociation calls the DescribeAssociation SSM API to retrieve parameters information
+// DescribeAssociation calls the DescribeAssociation SSM API to retrieve parameters information
 func (svc *sdkService) DescribeAssociation(log log.T, instanceID string, docName string) (response *ssm.DescribeAssociationOutput, err error) {
 	params := ssm.DescribeAssociationInput{
 		InstanceId: aws.String(instanceID),
@@ -516,6 +526,29 @@ func (svc *sdkService) GetParameters(log log.T, paramNames []string) (response *
 	return
 }
 
+// GetParametersByPath returns the String and StringList parameters under the path and its sub-paths,
+// the SecureString parameters are returned encrypted
+func (svc *sdkService) GetParametersByPath(log log.T, path string) (parameters []*ssm.Parameter, err error) {
+	serviceParams := ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(false),
+	}
+
+	log.Debugf("Calling GetParametersByPath API with params - %v", serviceParams)
+
+	err = svc.sdk.GetParametersByPathPages(&serviceParams, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		parameters = append(parameters, page.Parameters...)
+		return true
+	})
+	if err != nil {
+		log.Debug(err)
+		sdkutil.HandleAwsError(log, err, ssmStopPolicy)
+		return nil, fmt.Errorf("Encountered error while calling GetParametersByPath API. Error: %v", err)
+	}
+	return parameters, nil
+}
+
 func (svc *sdkService) GetDecryptedParameters(log log.T, paramNames []string) (response *ssm.GetParametersOutput, err error) {
 	serviceParams := ssm.GetParametersInput{
 		Names:          aws.StringSlice(paramNames),
@@ -145,6 +145,12 @@ func (m *Mock) GetDecryptedParameters(log log.T, paramNames []string) (response
 	return args.Get(0).(*ssm.GetParametersOutput), args.Error(1)
 }
 
+// GetParametersByPath mocks the GetParametersByPath function.
+func (m *Mock) GetParametersByPath(log log.T, path string) (parameters []*ssm.Parameter, err error) {
+	args := m.Called(log, path)
+	return args.Get(0).([]*ssm.Parameter), args.Error(1)
+}
+
 // PutComplianceItem mocks the PutComplianceItem function
 func (m *Mock) PutComplianceItems(
 	log log.T,
@@ -20,7 +20,10 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil/retryer"
 	"github.com/aws/aws-sdk-go/aws"
 )
@@ -53,19 +56,18 @@ func AwsConfig() *aws.Config {
 	// this is to skip ssl verification for the beta self signed certs
 	if appConfig.Ssm.InsecureSkipVerify {
 		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			TLSClientConfig: fips.TLSConfig(&tls.Config{InsecureSkipVerify: true}),
 		}
 		awsConfig.HTTPClient = &http.Client{Transport: tr}
 	}
+	proxyconfig.ConfigureAwsProxy(log.DefaultLogger(), awsConfig, appConfig.Ssm.Proxy)
 
 	return awsConfig
 
 }
 
 var newRetryer = func() aws.RequestRetryer {
-	r := retryer.SsmRetryer{}
-	r.NumMaxRetries = 3
-	return r
+	return retryer.New("")
 }
 
 var sleepDelay = func(d time.Duration) {
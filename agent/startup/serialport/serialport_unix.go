@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build linux
 // +build linux
 
 // Package serialport implements serial port capabilities
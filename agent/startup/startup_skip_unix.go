@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build freebsd || netbsd || openbsd || darwin
 // +build freebsd netbsd openbsd darwin
 
 package startup
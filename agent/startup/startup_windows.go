@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 //
+//go:build windows
 // +build windows
 
 // Package startup implements startup plugin processor
@@ -31,8 +32,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/startup/serialport"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/ec2metadata"
-	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 const (
@@ -160,7 +159,7 @@ func (p *Processor) IsAllowed() bool {
 
 	// check if metadata is rechable which indicates the instance is in EC2.
 	// maximum retry is 10 to ensure the failure/error is not caused by arbitrary reason.
-	ec2MetadataService := ec2metadata.New(session.New(aws.NewConfig().WithMaxRetries(10)))
+	ec2MetadataService := platform.NewEC2MetadataService(aws.NewConfig().WithMaxRetries(10))
 	if metadata, err := ec2MetadataService.GetMetadata(""); err != nil || metadata == "" {
 		// This is as designed to check if instance is in EC2, so it is not an error
 		return false
@@ -0,0 +1,193 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package syslog forwards the audit events and the session transcripts to a syslog server, as RFC 5424 messages
+// framed by their length over TCP or TLS (RFC 5425), for the sites collecting their security events with syslog.
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fips"
+	"github.com/aws/amazon-ssm-agent/agent/util"
+)
+
+// Severities of the messages
+const (
+	SeverityNotice = 5
+	SeverityInfo   = 6
+)
+
+const (
+	// appName is the APP-NAME of the messages
+	appName = "amazon-ssm-agent"
+	// timestampFormat is the TIMESTAMP of the messages, RFC 5424 allows up to microseconds
+	timestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+	// nilValue replaces the empty fields of the messages
+	nilValue = "-"
+	// dialTimeout is the timeout of the connection to the server
+	dialTimeout = 10 * time.Second
+	// writeTimeout is the timeout of a message
+	writeTimeout = 10 * time.Second
+	// maxLineSize is the size of the longest transcript line forwarded, longer lines are split
+	maxLineSize = 64 * 1024
+)
+
+// timeNow returns the current time, it is stubbed in the tests
+var timeNow = time.Now
+
+// AuditEnabled returns whether the audit events are forwarded
+func AuditEnabled(cfg appconfig.SyslogCfg) bool {
+	return cfg.Address != "" && cfg.Audit
+}
+
+// TranscriptsEnabled returns whether the session transcripts are forwarded
+func TranscriptsEnabled(cfg appconfig.SyslogCfg) bool {
+	return cfg.Address != "" && cfg.SessionTranscripts
+}
+
+// Writer sends messages to the syslog server, it connects on the first message and reconnects once when a
+// message fails
+type Writer struct {
+	mutex    sync.Mutex
+	cfg      appconfig.SyslogCfg
+	hostname string
+	procID   string
+	conn     net.Conn
+}
+
+// NewWriter creates the writer of the syslog server of the config
+func NewWriter(cfg appconfig.SyslogCfg) *Writer {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = nilValue
+	}
+	return &Writer{cfg: cfg, hostname: hostname, procID: strconv.Itoa(os.Getpid())}
+}
+
+// Write sends a message with its MSGID
+func (w *Writer) Write(severity int, msgID string, message string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	frame := frame(w.format(severity, msgID, message))
+	for attempt := 0; ; attempt++ {
+		if w.conn == nil {
+			conn, err := w.dial()
+			if err != nil {
+				return fmt.Errorf("failed to connect to the syslog server %v: %v", w.cfg.Address, err)
+			}
+			w.conn = conn
+		}
+		w.conn.SetWriteDeadline(timeNow().Add(writeTimeout))
+		_, err := io.WriteString(w.conn, frame)
+		if err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		if attempt > 0 {
+			return fmt.Errorf("failed to write to the syslog server %v: %v", w.cfg.Address, err)
+		}
+	}
+}
+
+// Close closes the connection to the server
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// dial connects to the server with the transport of the config
+func (w *Writer) dial() (net.Conn, error) {
+	if w.cfg.Transport == appconfig.SyslogTransportTCP {
+		return net.DialTimeout("tcp", w.cfg.Address, dialTimeout)
+	}
+	config := &tls.Config{}
+	if w.cfg.CABundle != "" {
+		roots, err := util.CertPoolWithCABundle(w.cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = roots
+	}
+	return tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", w.cfg.Address, fips.TLSConfig(config))
+}
+
+// format returns the RFC 5424 message, without structured data
+func (w *Writer) format(severity int, msgID string, message string) string {
+	if msgID == "" {
+		msgID = nilValue
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		w.cfg.Facility*8+severity,
+		timeNow().UTC().Format(timestampFormat),
+		w.hostname,
+		appName,
+		w.procID,
+		msgID,
+		nilValue,
+		message)
+}
+
+// frame prefixes a message with its length, the octet counting framing of RFC 5425
+func frame(message string) string {
+	return strconv.Itoa(len(message)) + " " + message
+}
+
+// SendFile sends the lines of a file with a MSGID, each line prefixed, the empty lines are skipped
+func SendFile(cfg appconfig.SyslogCfg, msgID string, prefix string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := NewWriter(cfg)
+	defer writer.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	scanner.Split(scanLines)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			if err = writer.Write(SeverityInfo, msgID, prefix+line); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// scanLines splits the lines like bufio.ScanLines and the lines longer than maxLineSize in several lines
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if advance, token, err = bufio.ScanLines(data, atEOF); advance == 0 && token == nil && len(data) >= maxLineSize {
+		return maxLineSize, data[:maxLineSize], nil
+	}
+	return
+}
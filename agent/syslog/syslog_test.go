@@ -0,0 +1,109 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen accepts a connection and returns the frames it receives
+func listen(t *testing.T) (address string, frames chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	frames = make(chan string, 16)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				close(frames)
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(length))
+			message := make([]byte, size)
+			if _, err = io.ReadFull(reader, message); err != nil {
+				close(frames)
+				return
+			}
+			frames <- string(message)
+		}
+	}()
+	return listener.Addr().String(), frames
+}
+
+func TestFormat(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	writer := &Writer{cfg: appconfig.SyslogCfg{Facility: 13}, hostname: "host", procID: "42"}
+	assert.Equal(t,
+		`<109>1 2020-01-02T03:04:05.123456Z host amazon-ssm-agent 42 SessionStarted - {"type":"SessionStarted"}`,
+		writer.format(SeverityNotice, "SessionStarted", `{"type":"SessionStarted"}`))
+	assert.Equal(t,
+		`<110>1 2020-01-02T03:04:05.123456Z host amazon-ssm-agent 42 - - line`,
+		writer.format(SeverityInfo, "", "line"))
+	assert.Equal(t, "4 line", frame("line"))
+}
+
+func TestSendFile(t *testing.T) {
+	address, frames := listen(t)
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("$ whoami\r\n\nssm-user\n"), 0600))
+
+	cfg := appconfig.SyslogCfg{Address: address, Transport: appconfig.SyslogTransportTCP, Facility: 13, SessionTranscripts: true}
+	assert.True(t, TranscriptsEnabled(cfg))
+	assert.False(t, AuditEnabled(cfg))
+	assert.NoError(t, SendFile(cfg, "SessionTranscript", "session-id: ", path))
+
+	assert.True(t, strings.HasSuffix(<-frames, " SessionTranscript - session-id: $ whoami"))
+	assert.True(t, strings.HasSuffix(<-frames, " SessionTranscript - session-id: ssm-user"))
+	_, more := <-frames
+	assert.False(t, more)
+}
+
+func TestScanLines_SplitsLongLines(t *testing.T) {
+	line := strings.Repeat("a", maxLineSize+10)
+	scanner := bufio.NewScanner(strings.NewReader(line + "\nb\n"))
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	scanner.Split(scanLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{line[:maxLineSize], line[maxLineSize:], "b"}, lines)
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package systemd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const name = "SystemdNotifier"
+
+// Statuses of the agent shown by systemctl
+const (
+	statusConnected    = "Connected to the Systems Manager service"
+	statusNotConnected = "Not connected to the Systems Manager service"
+)
+
+// checkInterval is the interval at which the heartbeat of the agent is checked
+var checkInterval = time.Second
+
+// Notifier is the core module reporting the connection status of the agent to systemd, the readiness and the
+// watchdog pings are sent by Started from the start of the agent on
+type Notifier struct {
+	context   context.T
+	connected func() error
+	stop      chan bool
+	done      chan bool
+}
+
+// NewNotifier creates the notifier, nil when the agent does not run as a systemd service of type notify.
+// lastHeartbeat returns the last UpdateInstanceInformation call, the agent is connected when it succeeded.
+func NewNotifier(context context.T, connected func() error) *Notifier {
+	if !NotifyEnabled() {
+		return nil
+	}
+	return &Notifier{
+		context:   context.With("[" + name + "]"),
+		connected: connected,
+		stop:      make(chan bool),
+		done:      make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (n *Notifier) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts reporting the connection status of the agent
+func (n *Notifier) ModuleExecute(context context.T) (err error) {
+	go n.run()
+	return nil
+}
+
+// ModuleRequestStop stops reporting the connection status, the agent notifies systemd that it stops
+func (n *Notifier) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	close(n.stop)
+	<-n.done
+	return nil
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+	log := n.context.Log()
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	var status string
+	for {
+		if currentStatus := n.connectionStatus(); currentStatus != status {
+			if err := Status(currentStatus); err != nil {
+				log.Debugf("Failed to update the systemd status of the agent: %v", err)
+			}
+			status = currentStatus
+		}
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// connectionStatus returns the status matching the connection of the agent
+func (n *Notifier) connectionStatus() string {
+	if err := n.connected(); err != nil {
+		return fmt.Sprintf("%v: %v", statusNotConnected, err)
+	}
+	return statusConnected
+}
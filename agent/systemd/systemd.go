@@ -0,0 +1,158 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package systemd implements the notification protocol and the socket activation of systemd, so that the agent
+// reports its readiness once it started and its connection to the service, keeps the systemd watchdog fed and serves
+// its local endpoints on the sockets passed by the socket units.
+package systemd
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables set by systemd for the main process of a service
+const (
+	notifySocketEnvironmentVariable  = "NOTIFY_SOCKET"
+	watchdogUsecEnvironmentVariable  = "WATCHDOG_USEC"
+	watchdogPidEnvironmentVariable   = "WATCHDOG_PID"
+	listenPidEnvironmentVariable     = "LISTEN_PID"
+	listenFdsEnvironmentVariable     = "LISTEN_FDS"
+	listenFdNamesEnvironmentVariable = "LISTEN_FDNAMES"
+)
+
+// listenFdsStart is the first file descriptor passed by the socket activation
+const listenFdsStart = 3
+
+// Notification states sent to systemd
+const (
+	stateReady    = "READY=1"
+	stateStopping = "STOPPING=1"
+	stateWatchdog = "WATCHDOG=1"
+	stateStatus   = "STATUS="
+)
+
+// environment holds the systemd environment of the agent, it is read once since it is removed from the environment
+// of the agent so that the worker processes do not inherit it
+var environment struct {
+	sync.Once
+	notifySocket     string
+	watchdogInterval time.Duration
+	listenFdNames    []string
+}
+
+// getpid returns the process id of the agent, it is stubbed in the tests
+var getpid = os.Getpid
+
+// loadEnvironment reads and removes the systemd environment variables
+func loadEnvironment() {
+	environment.Do(func() {
+		environment.notifySocket = os.Getenv(notifySocketEnvironmentVariable)
+		environment.watchdogInterval = parseWatchdogInterval(
+			os.Getenv(watchdogUsecEnvironmentVariable),
+			os.Getenv(watchdogPidEnvironmentVariable))
+		environment.listenFdNames = parseListenFds(
+			os.Getenv(listenPidEnvironmentVariable),
+			os.Getenv(listenFdsEnvironmentVariable),
+			os.Getenv(listenFdNamesEnvironmentVariable))
+		for _, variable := range []string{
+			notifySocketEnvironmentVariable,
+			watchdogUsecEnvironmentVariable,
+			watchdogPidEnvironmentVariable,
+			listenPidEnvironmentVariable,
+			listenFdsEnvironmentVariable,
+			listenFdNamesEnvironmentVariable,
+		} {
+			os.Unsetenv(variable)
+		}
+	})
+}
+
+// parseWatchdogInterval returns the watchdog interval of systemd, zero when the watchdog is not enabled for the agent
+func parseWatchdogInterval(usec string, pid string) time.Duration {
+	if pid != "" && pid != strconv.Itoa(getpid()) {
+		return 0
+	}
+	microseconds, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || microseconds <= 0 {
+		return 0
+	}
+	return time.Duration(microseconds) * time.Microsecond
+}
+
+// parseListenFds returns the names of the file descriptors passed to the agent by the socket activation,
+// the file descriptors without a name are named after their index
+func parseListenFds(pid string, fds string, names string) []string {
+	if pid != strconv.Itoa(getpid()) {
+		return nil
+	}
+	count, err := strconv.Atoi(fds)
+	if err != nil || count <= 0 {
+		return nil
+	}
+	fdNames := make([]string, count)
+	splitNames := strings.Split(names, ":")
+	for i := range fdNames {
+		if names != "" && i < len(splitNames) && splitNames[i] != "" {
+			fdNames[i] = splitNames[i]
+		} else {
+			fdNames[i] = strconv.Itoa(i)
+		}
+	}
+	return fdNames
+}
+
+// NotifyEnabled returns true if the agent runs as a systemd service of type notify
+func NotifyEnabled() bool {
+	loadEnvironment()
+	return environment.notifySocket != ""
+}
+
+// WatchdogInterval returns the interval at which systemd expects the watchdog pings, zero when the watchdog is not enabled
+func WatchdogInterval() time.Duration {
+	loadEnvironment()
+	return environment.watchdogInterval
+}
+
+// Ready notifies systemd that the agent is ready, with a status describing its state
+func Ready(status string) error {
+	return notify(stateReady, stateStatus+status)
+}
+
+// Status updates the status of the agent shown by systemctl
+func Status(status string) error {
+	return notify(stateStatus + status)
+}
+
+// Stopping notifies systemd that the agent is stopping and stops pinging the watchdog
+func Stopping() error {
+	stopWatchdog()
+	return notify(stateStopping)
+}
+
+// Watchdog pings the systemd watchdog
+func Watchdog() error {
+	return notify(stateWatchdog)
+}
+
+// notify sends the states to systemd, it does nothing when the agent is not a systemd service of type notify
+func notify(states ...string) error {
+	if !NotifyEnabled() {
+		return nil
+	}
+	return send(environment.notifySocket, strings.Join(states, "\n"))
+}
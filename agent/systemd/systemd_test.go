@@ -0,0 +1,47 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package systemd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stubGetpid(t *testing.T, pid int) {
+	original := getpid
+	t.Cleanup(func() { getpid = original })
+	getpid = func() int { return pid }
+}
+
+func TestParseWatchdogInterval(t *testing.T) {
+	stubGetpid(t, 42)
+	assert.Equal(t, 30*time.Second, parseWatchdogInterval("30000000", "42"))
+	assert.Equal(t, 30*time.Second, parseWatchdogInterval("30000000", ""))
+	assert.Equal(t, time.Duration(0), parseWatchdogInterval("30000000", "43"))
+	assert.Equal(t, time.Duration(0), parseWatchdogInterval("", ""))
+	assert.Equal(t, time.Duration(0), parseWatchdogInterval("0", "42"))
+}
+
+func TestParseListenFds(t *testing.T) {
+	stubGetpid(t, 42)
+	assert.Equal(t, []string{"status", "health"}, parseListenFds("42", "2", "status:health"))
+	assert.Equal(t, []string{"0", "1"}, parseListenFds("42", "2", ""))
+	assert.Equal(t, []string{"status", "1"}, parseListenFds("42", "2", "status"))
+	assert.Nil(t, parseListenFds("43", "2", "status:health"))
+	assert.Nil(t, parseListenFds("", "", ""))
+	assert.Nil(t, parseListenFds("42", "0", ""))
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// listeners holds the listeners passed by the socket activation which were not taken yet
+var listeners struct {
+	sync.Mutex
+	loaded bool
+	byName map[string]net.Listener
+}
+
+// send writes the notification to the datagram socket of systemd, a leading @ is an abstract socket
+func send(socket string, notification string) error {
+	address := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if socket[0] == '@' {
+		address.Name = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the systemd notification socket %v: %v", socket, err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(notification)); err != nil {
+		return fmt.Errorf("failed to notify systemd: %v", err)
+	}
+	return nil
+}
+
+// Listener returns the listener passed by the socket activation with the name set by FileDescriptorName
+// in the socket unit, nil when the agent was not passed such a socket. A listener is returned only once.
+func Listener(name string) net.Listener {
+	loadEnvironment()
+	listeners.Lock()
+	defer listeners.Unlock()
+	if !listeners.loaded {
+		listeners.loaded = true
+		listeners.byName = make(map[string]net.Listener)
+		for i, fdName := range environment.listenFdNames {
+			fd := listenFdsStart + i
+			// the worker processes must not inherit the sockets of the agent
+			syscall.CloseOnExec(fd)
+			file := os.NewFile(uintptr(fd), fdName)
+			if listener, err := net.FileListener(file); err == nil {
+				listeners.byName[fdName] = listener
+			}
+			file.Close()
+		}
+	}
+	listener := listeners.byName[name]
+	delete(listeners.byName, name)
+	return listener
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubNotifySocket listens on a notification socket for the agent and returns it
+func stubNotifySocket(t *testing.T, watchdogInterval time.Duration) *net.UnixConn {
+	dir, err := ioutil.TempDir("", "systemd")
+	require.NoError(t, err)
+	socket := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+
+	environment.Do(func() {})
+	original := environment.notifySocket
+	originalInterval := environment.watchdogInterval
+	environment.notifySocket = socket
+	environment.watchdogInterval = watchdogInterval
+	t.Cleanup(func() {
+		environment.notifySocket = original
+		environment.watchdogInterval = originalInterval
+		conn.Close()
+		os.RemoveAll(dir)
+	})
+	return conn
+}
+
+// receive returns the next notification sent to systemd
+func receive(t *testing.T, conn *net.UnixConn) string {
+	buffer := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buffer)
+	require.NoError(t, err)
+	return string(buffer[:n])
+}
+
+func TestNotify(t *testing.T) {
+	conn := stubNotifySocket(t, 0)
+	assert.True(t, NotifyEnabled())
+
+	assert.NoError(t, Ready("Connected"))
+	assert.Equal(t, "READY=1\nSTATUS=Connected", receive(t, conn))
+	assert.NoError(t, Watchdog())
+	assert.Equal(t, "WATCHDOG=1", receive(t, conn))
+	assert.NoError(t, Stopping())
+	assert.Equal(t, "STOPPING=1", receive(t, conn))
+}
+
+func TestStarted_PingsTheWatchdogUntilStopping(t *testing.T) {
+	conn := stubNotifySocket(t, 20*time.Millisecond)
+
+	Started(context.NewMockDefault().Log(), "Hibernating")
+
+	assert.Equal(t, "READY=1\nSTATUS=Hibernating", receive(t, conn))
+	// the watchdog is pinged without any core module running
+	assert.Equal(t, "WATCHDOG=1", receive(t, conn))
+	assert.Equal(t, "WATCHDOG=1", receive(t, conn))
+	assert.NoError(t, Stopping())
+	for notification := receive(t, conn); notification != "STOPPING=1"; notification = receive(t, conn) {
+		assert.Equal(t, "WATCHDOG=1", notification)
+	}
+	assert.Nil(t, watchdog.stop)
+}
+
+func TestNotifier_ReportsTheConnectionStatus(t *testing.T) {
+	conn := stubNotifySocket(t, 0)
+	originalInterval := checkInterval
+	t.Cleanup(func() { checkInterval = originalInterval })
+	checkInterval = 10 * time.Millisecond
+
+	var connected int32
+	n := NewNotifier(context.NewMockDefault(), func() error {
+		if atomic.LoadInt32(&connected) == 1 {
+			return nil
+		}
+		return assert.AnError
+	})
+	require.NotNil(t, n)
+	assert.NoError(t, n.ModuleExecute(nil))
+
+	assert.Equal(t, "STATUS="+statusNotConnected+": "+assert.AnError.Error(), receive(t, conn))
+	atomic.StoreInt32(&connected, 1)
+	assert.Equal(t, "STATUS="+statusConnected, receive(t, conn))
+	assert.NoError(t, n.ModuleRequestStop(contracts.StopTypeSoftStop))
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package systemd
+
+import "net"
+
+// send is not supported on Windows, the agent never runs as a systemd service
+func send(socket string, notification string) error {
+	return nil
+}
+
+// Listener returns nil on Windows, the agent is never passed sockets by systemd
+func Listener(name string) net.Listener {
+	return nil
+}
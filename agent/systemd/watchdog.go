@@ -0,0 +1,79 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package systemd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// watchdog is the pinger of the systemd watchdog, from the start of the agent until it stops
+var watchdog struct {
+	sync.Mutex
+	stop chan bool
+}
+
+// Started reports the readiness of the agent to systemd with its status and pings the watchdog every half watchdog
+// interval from then on. It is called once the agent is set up, before it hibernates or starts its core modules,
+// so that neither the hibernation nor a failing core manager let systemd time out the start or the watchdog.
+func Started(log log.T, status string) {
+	if !NotifyEnabled() {
+		return
+	}
+	if err := Ready(status); err != nil {
+		log.Warnf("Failed to notify systemd of the readiness of the agent: %v", err)
+	} else {
+		log.Infof("Notified systemd of the readiness of the agent: %v", status)
+	}
+	interval := WatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	watchdog.Lock()
+	defer watchdog.Unlock()
+	if watchdog.stop == nil {
+		log.Infof("Pinging the systemd watchdog every %v", interval/2)
+		watchdog.stop = make(chan bool)
+		go pingWatchdog(log, interval/2, watchdog.stop)
+	}
+}
+
+// stopWatchdog stops pinging the systemd watchdog, which does not apply to a stopping service
+func stopWatchdog() {
+	watchdog.Lock()
+	defer watchdog.Unlock()
+	if watchdog.stop != nil {
+		close(watchdog.stop)
+		watchdog.stop = nil
+	}
+}
+
+// pingWatchdog pings the systemd watchdog every interval until stop is closed
+func pingWatchdog(log log.T, interval time.Duration, stop chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := Watchdog(); err != nil {
+			log.Warnf("Failed to ping the systemd watchdog: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tempdir gives every plugin execution its own temporary directory under its orchestration directory, and
+// every session its own scratch directory in the temporary directory of the system. The directories are removed once
+// the plugin or the session completes, and swept at startup when the process running them stopped before removing them.
+package tempdir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// dirPrefix starts the names of the temporary directories, followed by the pid of the process running the plugin
+const dirPrefix = ".tmp-"
+
+// sessionDirPrefix starts the names of the scratch directories of the sessions, followed by the pid of the process
+// running the session and a random suffix
+const sessionDirPrefix = "ssm-session-"
+
+// SessionDirVariable is the environment variable holding the scratch directory of a session
+const SessionDirVariable = "SSM_SESSION_DIR"
+
+// sessionRoot returns the directory holding the scratch directories of the sessions, it is stubbed in the tests
+var sessionRoot = os.TempDir
+
+// sweepPatterns match the temporary directories under the orchestration roots, in the orchestration directories
+// of the plugins of the commands and sessions (<id>/<plugin>) and of the associations (<id>/<run>/<plugin>)
+var sweepPatterns = []string{
+	filepath.Join("*", "*", dirPrefix+"*"),
+	filepath.Join("*", "*", "*", dirPrefix+"*"),
+}
+
+// processExists returns true if the process with the pid is running, it is stubbed in the tests
+var processExists = func(log log.T, pid int) bool {
+	return proc.IsProcessExists(log, pid, time.Time{})
+}
+
+// Create creates the empty temporary directory of the plugin with the orchestration directory.
+func Create(orchestrationDir string) (dir string, err error) {
+	dir = filepath.Join(orchestrationDir, dirPrefix+strconv.Itoa(os.Getpid()))
+	if err = os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to empty the temporary directory %v, %v", dir, err)
+	}
+	if err = fileutil.MakeDirsWithExecuteAccess(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Remove removes the temporary directory with everything the plugin left in it.
+func Remove(log log.T, dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warnf("failed to remove the temporary directory %v, %v", dir, err)
+	}
+}
+
+// Environment returns the environment variables pointing the temporary files of the child processes to the directory.
+func Environment(dir string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"TEMP=" + dir, "TMP=" + dir}
+	}
+	return []string{"TMPDIR=" + dir}
+}
+
+// CreateSession creates the scratch directory of a session, accessible to the creator only. Its name cannot be guessed
+// by the other users of the temporary directory of the system.
+func CreateSession() (dir string, err error) {
+	if dir, err = ioutil.TempDir(sessionRoot(), sessionDirPrefix+strconv.Itoa(os.Getpid())+"-"); err != nil {
+		return "", fmt.Errorf("failed to create the scratch directory of the session, %v", err)
+	}
+	return dir, nil
+}
+
+// SessionEnvironment returns the environment variables pointing the shell of a session and its temporary files to its
+// scratch directory.
+func SessionEnvironment(dir string) []string {
+	return append([]string{SessionDirVariable + "=" + dir}, Environment(dir)...)
+}
+
+// SweepSessions removes the scratch directories of the sessions whose process is not running anymore.
+func SweepSessions(log log.T) {
+	dirs, _ := filepath.Glob(filepath.Join(sessionRoot(), sessionDirPrefix+"*"))
+	for _, dir := range dirs {
+		fields := strings.SplitN(strings.TrimPrefix(filepath.Base(dir), sessionDirPrefix), "-", 2)
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil || !fileutil.IsDirectory(dir) || processExists(log, pid) {
+			continue
+		}
+		log.Debugf("Removing the scratch directory %v left behind", dir)
+		Remove(log, dir)
+	}
+}
+
+// Sweep removes the temporary directories under the orchestration roots of the processes which are not running anymore.
+func Sweep(log log.T, orchestrationRoots ...string) {
+	for _, root := range orchestrationRoots {
+		for _, pattern := range sweepPatterns {
+			dirs, _ := filepath.Glob(filepath.Join(root, pattern))
+			for _, dir := range dirs {
+				pid, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), dirPrefix))
+				if err != nil || !fileutil.IsDirectory(dir) || processExists(log, pid) {
+					continue
+				}
+				log.Debugf("Removing the temporary directory %v left behind", dir)
+				Remove(log, dir)
+			}
+		}
+	}
+}
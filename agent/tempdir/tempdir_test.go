@@ -0,0 +1,98 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tempdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+func TestCreateRemove(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "tempdir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	dir, err := Create(orchestrationDir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(orchestrationDir, ".tmp-"+strconv.Itoa(os.Getpid())), dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "left"), []byte("behind"), 0600))
+
+	// the directory of a previous execution is emptied
+	dir, err = Create(orchestrationDir)
+	assert.NoError(t, err)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+
+	Remove(logger, dir)
+	assert.False(t, fileutil.Exists(dir))
+}
+
+func TestSession(t *testing.T) {
+	root, err := ioutil.TempDir("", "tempdir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	sessionRootOrig, processExistsOrig := sessionRoot, processExists
+	defer func() { sessionRoot, processExists = sessionRootOrig, processExistsOrig }()
+	sessionRoot = func() string { return root }
+	processExists = func(log log.T, pid int) bool { return pid == os.Getpid() }
+
+	dir, err := CreateSession()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(filepath.Base(dir), "ssm-session-"+strconv.Itoa(os.Getpid())+"-"))
+	assert.Contains(t, SessionEnvironment(dir), "SSM_SESSION_DIR="+dir)
+
+	stopped := filepath.Join(root, "ssm-session-2-123456")
+	other := filepath.Join(root, "ssm-session-dir")
+	for _, dir := range []string{stopped, other} {
+		assert.NoError(t, os.MkdirAll(dir, 0700))
+	}
+	SweepSessions(logger)
+	assert.True(t, fileutil.Exists(dir))
+	assert.False(t, fileutil.Exists(stopped))
+	assert.True(t, fileutil.Exists(other))
+}
+
+func TestSweep(t *testing.T) {
+	root, err := ioutil.TempDir("", "tempdir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	processExistsOrig := processExists
+	defer func() { processExists = processExistsOrig }()
+	processExists = func(log log.T, pid int) bool { return pid == 1 }
+
+	running := filepath.Join(root, "command", "aws-runShellScript", ".tmp-1")
+	stopped := filepath.Join(root, "command", "aws-runShellScript", ".tmp-2")
+	association := filepath.Join(root, "association", "2019-05-14T12-00-00.000Z", "aws-runShellScript", ".tmp-3")
+	other := filepath.Join(root, "command", "aws-runShellScript", "tmp")
+	for _, dir := range []string{running, stopped, association, other} {
+		assert.NoError(t, os.MkdirAll(dir, 0700))
+	}
+
+	Sweep(logger, root)
+	assert.True(t, fileutil.Exists(running))
+	assert.False(t, fileutil.Exists(stopped))
+	assert.False(t, fileutil.Exists(association))
+	assert.True(t, fileutil.Exists(other))
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package times provides a set of utilities related to processing time.
+package times
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a weekly recurring window of local time, such as a maintenance window.
+// A window ending before its start ends on the next day.
+type Window struct {
+	days     [7]bool
+	start    time.Duration
+	duration time.Duration
+}
+
+// ParseWindow parses a window in the "[days ]HH:MM-HH:MM" format, days being a comma separated list
+// of days and day ranges such as "Mon-Fri" or "Sat,Sun", every day when omitted.
+func ParseWindow(spec string) (window Window, err error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return window, fmt.Errorf("invalid window %q, expected [days ]HH:MM-HH:MM", spec)
+	}
+
+	if len(fields) == 1 {
+		for day := range window.days {
+			window.days[day] = true
+		}
+	} else if err = window.parseDays(fields[0]); err != nil {
+		return window, fmt.Errorf("invalid window %q, %v", spec, err)
+	}
+
+	hours := strings.Split(fields[len(fields)-1], "-")
+	if len(hours) != 2 {
+		return window, fmt.Errorf("invalid window %q, expected a HH:MM-HH:MM range", spec)
+	}
+	var end time.Duration
+	if window.start, err = parseTimeOfDay(hours[0]); err != nil {
+		return window, fmt.Errorf("invalid window %q, %v", spec, err)
+	}
+	if end, err = parseTimeOfDay(hours[1]); err != nil {
+		return window, fmt.Errorf("invalid window %q, %v", spec, err)
+	}
+	window.duration = end - window.start
+	if window.duration <= 0 {
+		window.duration += 24 * time.Hour
+	}
+	return window, nil
+}
+
+// parseDays parses a comma separated list of days and day ranges
+func (w *Window) parseDays(days string) error {
+	for _, item := range strings.Split(strings.ToLower(days), ",") {
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid day range %q", item)
+		}
+		first, ok := weekdays[bounds[0]]
+		if !ok {
+			return fmt.Errorf("invalid day %q", bounds[0])
+		}
+		last, ok := weekdays[bounds[len(bounds)-1]]
+		if !ok {
+			return fmt.Errorf("invalid day %q", bounds[len(bounds)-1])
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseTimeOfDay parses a HH:MM time of day as the duration since midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// startOn returns the start of the window on the day of t
+func (w Window) startOn(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location()).Add(w.start)
+}
+
+// Contains returns true if t is inside the window
+func (w Window) Contains(t time.Time) bool {
+	// a window started the day before may still be open
+	for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		start := w.startOn(day)
+		if !t.Before(start) && t.Before(start.Add(w.duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStart returns the next time the window opens after t
+func (w Window) NextStart(t time.Time) time.Time {
+	for i := 0; i <= 7; i++ {
+		day := t.AddDate(0, 0, i)
+		if start := w.startOn(day); w.days[day.Weekday()] && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
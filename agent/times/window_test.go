@@ -0,0 +1,70 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package times provides a set of utilities related to processing time.
+package times
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 2019-06-03 is a Monday
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2019, time.June, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestParseWindowInvalid(t *testing.T) {
+	for _, spec := range []string{"", "22:00", "Mon 22:00-04:00 extra", "Funday 22:00-04:00", "Mon-Fri-Sat 01:00-02:00", "25:00-26:00", "Mon 1:00pm-2:00pm"} {
+		_, err := ParseWindow(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestWindowDaily(t *testing.T) {
+	window, err := ParseWindow("01:00-05:00")
+	assert.NoError(t, err)
+
+	assert.True(t, window.Contains(at(4, 1, 0)))
+	assert.True(t, window.Contains(at(8, 4, 59)))
+	assert.False(t, window.Contains(at(4, 5, 0)))
+	assert.False(t, window.Contains(at(4, 0, 59)))
+	assert.Equal(t, at(5, 1, 0), window.NextStart(at(4, 1, 30)))
+	assert.Equal(t, at(4, 1, 0), window.NextStart(at(4, 0, 30)))
+}
+
+func TestWindowDaysAcrossMidnight(t *testing.T) {
+	window, err := ParseWindow("Fri,Sat 22:00-02:00")
+	assert.NoError(t, err)
+
+	assert.True(t, window.Contains(at(7, 23, 0)))  // Friday night
+	assert.True(t, window.Contains(at(9, 1, 0)))   // Saturday window on Sunday morning
+	assert.False(t, window.Contains(at(10, 1, 0))) // no Sunday window on Monday morning
+	assert.False(t, window.Contains(at(6, 23, 0))) // Thursday
+	assert.Equal(t, at(7, 22, 0), window.NextStart(at(3, 12, 0)))
+	assert.Equal(t, at(14, 22, 0), window.NextStart(at(8, 22, 30)))
+}
+
+func TestWindowDayRange(t *testing.T) {
+	window, err := ParseWindow("Sat-Mon 00:00-00:00")
+	assert.NoError(t, err)
+
+	assert.True(t, window.Contains(at(8, 12, 0)))
+	assert.True(t, window.Contains(at(9, 12, 0)))
+	assert.True(t, window.Contains(at(10, 23, 59)))
+	assert.False(t, window.Contains(at(11, 0, 0)))
+	assert.Equal(t, at(15, 0, 0), window.NextStart(at(11, 0, 0)))
+}
@@ -0,0 +1,270 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+const (
+	name = "TracingExporter"
+
+	// serviceName is the service.name resource attribute of the exported spans
+	serviceName = "amazon-ssm-agent"
+
+	// queueSize is the number of ended spans buffered for export, spans are dropped when it is full
+	queueSize = 2048
+
+	// maxBatchSize is the maximum number of spans exported in a single request
+	maxBatchSize = 256
+
+	// exportTimeout is the timeout of an export request to the collector
+	exportTimeout = 10 * time.Second
+
+	// OTLP span kind and status codes
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+// exportInterval is the interval at which the buffered spans are exported
+var exportInterval = 5 * time.Second
+
+// queue buffers the ended spans until they are exported, nil when tracing is not enabled
+var queue struct {
+	sync.RWMutex
+	spans chan *Span
+}
+
+// Enabled returns whether the spans are exported
+func Enabled() bool {
+	queue.RLock()
+	defer queue.RUnlock()
+	return queue.spans != nil
+}
+
+// enqueue queues an ended span for export, the span is dropped when the queue is full
+func enqueue(span *Span) {
+	queue.RLock()
+	defer queue.RUnlock()
+	if queue.spans == nil {
+		return
+	}
+	select {
+	case queue.spans <- span:
+	default:
+	}
+}
+
+// Exporter is the core module exporting the spans to an OTLP/HTTP collector
+type Exporter struct {
+	context  context.T
+	endpoint string
+	client   *http.Client
+	spans    chan *Span
+	stop     chan bool
+	done     chan bool
+}
+
+// NewExporter creates the span exporter, nil when tracing is not enabled in appconfig
+func NewExporter(context context.T) *Exporter {
+	config := context.AppConfig().Tracing
+	if !config.Enabled {
+		return nil
+	}
+	spans := make(chan *Span, queueSize)
+	queue.Lock()
+	queue.spans = spans
+	queue.Unlock()
+	return &Exporter{
+		context:  context.With("[" + name + "]"),
+		endpoint: config.Endpoint,
+		client:   &http.Client{Timeout: exportTimeout},
+		spans:    spans,
+		stop:     make(chan bool),
+		done:     make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (e *Exporter) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts exporting the spans
+func (e *Exporter) ModuleExecute(context context.T) (err error) {
+	e.context.Log().Infof("Exporting traces to %v", e.endpoint)
+	go e.run()
+	return nil
+}
+
+// ModuleRequestStop exports the buffered spans and stops the exporter
+func (e *Exporter) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	close(e.stop)
+	<-e.done
+	return nil
+}
+
+// run exports the spans in batches, when the batch is full or at every export interval
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.spans:
+			if batch = append(batch, span); len(batch) >= maxBatchSize {
+				e.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.export(batch)
+			batch = nil
+		case <-e.stop:
+			for len(e.spans) > 0 {
+				if batch = append(batch, <-e.spans); len(batch) >= maxBatchSize {
+					e.export(batch)
+					batch = nil
+				}
+			}
+			e.export(batch)
+			return
+		}
+	}
+}
+
+// export sends a batch of spans to the collector
+func (e *Exporter) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	log := e.context.Log()
+	payload, err := json.Marshal(newExportRequest(batch))
+	if err != nil {
+		log.Warnf("failed to encode %v spans: %v", len(batch), err)
+		return
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Warnf("failed to export %v spans: %v", len(batch), err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		log.Warnf("failed to export %v spans: collector returned %v", len(batch), resp.Status)
+	}
+}
+
+// OTLP/HTTP JSON encoding of the export request
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// newExportRequest encodes the spans in an OTLP export request
+func newExportRequest(batch []*Span) exportRequest {
+	spans := make([]spanData, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, span.data())
+	}
+	return exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: []keyValue{{Key: "service.name", Value: anyValue{StringValue: serviceName}}},
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: serviceName},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+// data returns the OTLP encoding of an ended span
+func (s *Span) data() spanData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data := spanData{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentSpanID,
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            status{Code: statusCodeOk},
+	}
+	for _, attr := range s.attributes {
+		data.Attributes = append(data.Attributes, keyValue{Key: attr.key, Value: anyValue{StringValue: attr.value}})
+	}
+	if s.err != nil {
+		data.Status = status{Code: statusCodeError, Message: fmt.Sprint(s.err)}
+	}
+	return data
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithTracing(enabled bool, endpoint string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{}
+	config.Tracing.Enabled = enabled
+	config.Tracing.Endpoint = endpoint
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func TestNewExporter_Disabled(t *testing.T) {
+	assert.Nil(t, NewExporter(mockContextWithTracing(false, appconfig.DefaultTracingEndpoint)))
+	assert.False(t, Enabled())
+}
+
+func TestExporter_ExportsSpansOnStop(t *testing.T) {
+	requests := make(chan exportRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var request exportRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests <- request
+	}))
+	defer collector.Close()
+
+	ctx := mockContextWithTracing(true, collector.URL)
+	exporter := NewExporter(ctx)
+	assert.NotNil(t, exporter)
+	defer func() {
+		queue.Lock()
+		queue.spans = nil
+		queue.Unlock()
+	}()
+	assert.True(t, Enabled())
+	assert.Equal(t, name, exporter.ModuleName())
+	assert.NoError(t, exporter.ModuleExecute(ctx))
+
+	root := StartSpan("document")
+	root.StartChild("document.parse").End(nil)
+	root.End(nil)
+	assert.NoError(t, exporter.ModuleRequestStop(contracts.StopTypeSoftStop))
+
+	request := <-requests
+	assert.Len(t, request.ResourceSpans, 1)
+	assert.Equal(t, serviceName, request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "document.parse", spans[0].Name)
+	assert.Equal(t, "document", spans[1].Name)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, statusCodeOk, spans[1].Status.Code)
+}
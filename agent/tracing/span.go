@@ -0,0 +1,159 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Package tracing traces the document and session lifecycles with OpenTelemetry spans exported via OTLP.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+)
+
+// Span is a timed operation of a trace, all its methods are no-ops on a nil span
+type Span struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	name         string
+	start        time.Time
+
+	mutex      sync.Mutex
+	end        time.Time
+	attributes []attribute
+	err        error
+}
+
+type attribute struct {
+	key   string
+	value string
+}
+
+// StartSpan starts the root span of a new trace, nil when tracing is not enabled
+func StartSpan(name string) *Span {
+	if !Enabled() {
+		return nil
+	}
+	return &Span{
+		traceID: newID(16),
+		spanID:  newID(8),
+		name:    name,
+		start:   time.Now(),
+	}
+}
+
+// StartChild starts a child span now
+func (s *Span) StartChild(name string) *Span {
+	return s.StartChildAt(name, time.Now())
+}
+
+// StartChildAt starts a child span at the given time
+func (s *Span) StartChildAt(name string, start time.Time) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{
+		traceID:      s.traceID,
+		spanID:       newID(8),
+		parentSpanID: s.spanID,
+		name:         name,
+		start:        start,
+	}
+}
+
+// SetAttribute sets a string attribute of the span
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.attributes {
+		if s.attributes[i].key == key {
+			s.attributes[i].value = value
+			return
+		}
+	}
+	s.attributes = append(s.attributes, attribute{key: key, value: value})
+}
+
+// End ends the span now and queues it for export, err marks the span as failed
+func (s *Span) End(err error) {
+	s.EndAt(time.Now(), err)
+}
+
+// EndAt ends the span at the given time and queues it for export, err marks the span as failed
+func (s *Span) EndAt(end time.Time, err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.end = end
+	s.err = err
+	s.mutex.Unlock()
+	enqueue(s)
+}
+
+// newID returns a random hex encoded id of the given number of bytes
+func newID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// inFlight holds the spans of the lifecycles that are still running, by message id
+var inFlight = struct {
+	sync.Mutex
+	spans map[string]*Span
+}{spans: make(map[string]*Span)}
+
+// Track holds the span of a running lifecycle until it is untracked
+func Track(id string, span *Span) {
+	if span == nil {
+		return
+	}
+	inFlight.Lock()
+	defer inFlight.Unlock()
+	inFlight.spans[id] = span
+}
+
+// Tracked returns the span of a running lifecycle, nil when it is not tracked
+func Tracked(id string) *Span {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+	return inFlight.spans[id]
+}
+
+// Untrack removes and returns the span of a lifecycle, nil when it is not tracked
+func Untrack(id string) *Span {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+	span := inFlight.spans[id]
+	delete(inFlight.spans, id)
+	return span
+}
+
+// StatusError returns the error a document or plugin span with the given result status ends with, nil unless it failed
+func StatusError(status contracts.ResultStatus, message string) error {
+	if status != contracts.ResultStatusFailed && status != contracts.ResultStatusTimedOut {
+		return nil
+	}
+	if message == "" {
+		return fmt.Errorf("%v", status)
+	}
+	return fmt.Errorf("%v: %v", status, message)
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package tracing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// enableTracing routes the ended spans to a test queue for the duration of the test
+func enableTracing(t *testing.T) chan *Span {
+	spans := make(chan *Span, queueSize)
+	queue.Lock()
+	queue.spans = spans
+	queue.Unlock()
+	t.Cleanup(func() {
+		queue.Lock()
+		queue.spans = nil
+		queue.Unlock()
+	})
+	return spans
+}
+
+func TestStartSpan_Disabled(t *testing.T) {
+	span := StartSpan("document")
+	assert.Nil(t, span)
+
+	// all the span methods are no-ops on the nil span
+	child := span.StartChild("document.parse")
+	assert.Nil(t, child)
+	child.SetAttribute("key", "value")
+	child.End(nil)
+	Track("message", span)
+	assert.Nil(t, Tracked("message"))
+}
+
+func TestStartChild(t *testing.T) {
+	spans := enableTracing(t)
+
+	root := StartSpan("document")
+	child := root.StartChild("document.parse")
+	assert.Len(t, root.traceID, 32)
+	assert.Len(t, root.spanID, 16)
+	assert.Empty(t, root.parentSpanID)
+	assert.Equal(t, root.traceID, child.traceID)
+	assert.Equal(t, root.spanID, child.parentSpanID)
+	assert.NotEqual(t, root.spanID, child.spanID)
+
+	child.End(nil)
+	root.End(nil)
+	assert.Equal(t, child, <-spans)
+	assert.Equal(t, root, <-spans)
+}
+
+func TestSetAttribute(t *testing.T) {
+	enableTracing(t)
+
+	span := StartSpan("document")
+	span.SetAttribute("ssm.status", "InProgress")
+	span.SetAttribute("ssm.command_id", "command")
+	span.SetAttribute("ssm.status", "Success")
+	assert.Equal(t, []attribute{{"ssm.status", "Success"}, {"ssm.command_id", "command"}}, span.attributes)
+}
+
+func TestTrack(t *testing.T) {
+	enableTracing(t)
+
+	span := StartSpan("session")
+	Track("session-id", span)
+	assert.Equal(t, span, Tracked("session-id"))
+	assert.Equal(t, span, Untrack("session-id"))
+	assert.Nil(t, Tracked("session-id"))
+	assert.Nil(t, Untrack("session-id"))
+}
+
+func TestStatusError(t *testing.T) {
+	assert.NoError(t, StatusError(contracts.ResultStatusSuccess, ""))
+	assert.NoError(t, StatusError(contracts.ResultStatusCancelled, "cancelled"))
+	assert.EqualError(t, StatusError(contracts.ResultStatusFailed, ""), "Failed")
+	assert.EqualError(t, StatusError(contracts.ResultStatusTimedOut, "step timed out"), "TimedOut: step timed out")
+}
+
+func TestSpanData(t *testing.T) {
+	enableTracing(t)
+
+	start := time.Unix(10, 5)
+	root := StartSpan("document")
+	span := root.StartChildAt("plugin aws:runShellScript", start)
+	span.SetAttribute("ssm.plugin_id", "runShellScript")
+	span.EndAt(start.Add(time.Second), errors.New("exit status 1"))
+
+	data := span.data()
+	assert.Equal(t, root.traceID, data.TraceID)
+	assert.Equal(t, root.spanID, data.ParentSpanID)
+	assert.Equal(t, "plugin aws:runShellScript", data.Name)
+	assert.Equal(t, "10000000005", data.StartTimeUnixNano)
+	assert.Equal(t, "11000000005", data.EndTimeUnixNano)
+	assert.Equal(t, []keyValue{{Key: "ssm.plugin_id", Value: anyValue{StringValue: "runShellScript"}}}, data.Attributes)
+	assert.Equal(t, status{Code: statusCodeError, Message: "exit status 1"}, data.Status)
+}
@@ -96,6 +96,15 @@ type UpdateDetail struct {
 	MessageID          string                 `json:"MessageId"`
 	UpdateRoot         string                 `json:"UpdateRoot"`
 	RequiresUninstall  bool                   `json:"RequiresUninstall"`
+	AllowDowngrade     bool                   `json:"AllowDowngrade"`
+
+	// the optional bsdiff patch producing the target package from the source package
+	TargetDeltaLocation string `json:"TargetDeltaLocation"`
+	TargetDeltaHash     string `json:"TargetDeltaHash"`
+
+	// the health verified after the update, required only when the source version reports its health
+	VerifyAgentStarted   bool `json:"VerifyAgentStarted"`
+	VerifyControlChannel bool `json:"VerifyControlChannel"`
 }
 
 // UpdateContext holds the book keeping details for Update context
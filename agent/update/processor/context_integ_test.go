@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build integration
 // +build integration
 
 // Package processor contains the methods for update ssm agent.
@@ -28,7 +29,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-//Valid context files
+// Valid context files
 var sampleFiles = []string{
 	"testdata/updatecontext.json",
 }
@@ -55,7 +56,7 @@ func (s *FakeServiceWithFailure) UpdateHealthCheck(log log.T, update *UpdateDeta
 	return fmt.Errorf("Failed to update health check")
 }
 
-//Test loadContextFromFile file with valid context files
+// Test loadContextFromFile file with valid context files
 func TestParseContext(t *testing.T) {
 	// generate test cases
 	var testCases []testCase
@@ -118,7 +119,7 @@ func TestLoadUpdateContext(t *testing.T) {
 	assert.True(t, len(context.Histories) > 0)
 }
 
-//Load specified file from file system
+// Load specified file from file system
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	result, err := ioutil.ReadFile(fileName)
 	if err != nil {
@@ -127,7 +128,7 @@ func loadFile(t *testing.T, fileName string) (result []byte) {
 	return
 }
 
-//Parse manifest file
+// Parse manifest file
 func loadContextFromFile(t *testing.T, fileName string) (context UpdateContext) {
 	b := loadFile(t, fileName)
 	err := json.Unmarshal(b, &context)
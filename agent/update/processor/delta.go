@@ -0,0 +1,107 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/bspatch"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+// prepareTargetFromDelta prepares the target package by patching the source package with the delta of the update,
+// it returns false when there is no delta or it can't be applied so that the full target package gets downloaded
+func prepareTargetFromDelta(log log.T, context *UpdateContext, updateDownload string) bool {
+	detail := context.Current
+	if detail.TargetDeltaLocation == "" || detail.TargetHash == "" {
+		return false
+	}
+
+	if err := applyTargetDelta(log, detail, updateDownload); err != nil {
+		detail.AppendInfo(log, "Failed to apply the delta %v, downloading the full package: %v", detail.TargetDeltaLocation, err)
+		return false
+	}
+	detail.AppendInfo(log, "Successfully applied the delta %v", detail.TargetDeltaLocation)
+	return true
+}
+
+// applyTargetDelta patches the source package into the target package and uncompresses it
+func applyTargetDelta(log log.T, detail *UpdateDetail, updateDownload string) (err error) {
+	// the source package was just downloaded, downloading it again only revalidates the local copy
+	sourcePath, err := downloadVerified(log, detail.SourceLocation, detail.SourceHash, updateDownload)
+	if err != nil {
+		return err
+	}
+	deltaPath, err := downloadVerified(log, detail.TargetDeltaLocation, detail.TargetDeltaHash, updateDownload)
+	if err != nil {
+		return err
+	}
+
+	var source, delta, target []byte
+	if source, err = ioutil.ReadFile(sourcePath); err != nil {
+		return err
+	}
+	if delta, err = ioutil.ReadFile(deltaPath); err != nil {
+		return err
+	}
+	if target, err = bspatch.Patch(source, delta); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(target)
+	if hex.EncodeToString(sum[:]) != strings.ToLower(detail.TargetHash) {
+		return fmt.Errorf("the patched package doesn't match the hash of %v", detail.TargetVersion)
+	}
+
+	targetPath := filepath.Join(updateDownload, detail.PackageName+"-"+detail.TargetVersion)
+	if err = ioutil.WriteFile(targetPath, target, appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	if err = uncompress(
+		log,
+		targetPath,
+		updateutil.UpdateArtifactFolder(detail.UpdateRoot, detail.PackageName, detail.TargetVersion)); err != nil {
+		return fmt.Errorf("failed to uncompress installation package, %v", err.Error())
+	}
+	return nil
+}
+
+// downloadVerified downloads the file and returns its local path if it matches its sha256 hash
+func downloadVerified(log log.T, sourceURL string, hash string, destination string) (string, error) {
+	output, err := downloadArtifact(log, artifact.DownloadInput{
+		SourceURL: sourceURL,
+		SourceChecksums: map[string]string{
+			updateutil.HashType: hash,
+		},
+		DestinationDirectory: destination,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download file reliably, %v, %v", sourceURL, err.Error())
+	}
+	if !output.IsHashMatched || output.LocalFilePath == "" {
+		return "", fmt.Errorf("failed to download file reliably, %v", sourceURL)
+	}
+	return output.LocalFilePath, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDelta is a bsdiff patch turning "the quick brown fox jumps over the lazy dog" into
+// "the quick red fox jumps over the lazy cat! the quick"
+const testDelta = "QlNESUZGNDA6AAAAAAAAADgAAAAAAAAANAAAAAAAAABCWmg5MUFZJlNZtFUR3gAAEvBAWhAIQACAQAAgADEGTECST0T0TLnx4JF0iia2bwu5IpwoSFoqiO8AQlpoOTFBWSZTWRvCEE8AAABkEMkEAAECCAAEgEQgACIBoZCAaaaMh7bVQXErxdyRThQkBvCEE8BCWmg5MUFZJlNZL4zucAAAAxGAYAAuABQAIAAxDAghpo2ppzhDxdyRThQkC+M7nAA="
+
+// stubDeltaDownloads serves the source package and the delta from a temporary directory
+func stubDeltaDownloads(t *testing.T) (detail *UpdateDetail, uncompressed *[]byte) {
+	dir, err := ioutil.TempDir("", "delta")
+	assert.NoError(t, err)
+	delta, err := base64.StdEncoding.DecodeString(testDelta)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "source"), []byte("the quick brown fox jumps over the lazy dog"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "delta"), delta, 0600))
+
+	download, uncompressFile := downloadArtifact, uncompress
+	uncompressed = new([]byte)
+	downloadArtifact = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		return artifact.DownloadOutput{LocalFilePath: filepath.Join(dir, input.SourceURL), IsHashMatched: true}, nil
+	}
+	uncompress = func(log log.T, src, dest string) error {
+		*uncompressed, err = ioutil.ReadFile(src)
+		return err
+	}
+	t.Cleanup(func() {
+		downloadArtifact, uncompress = download, uncompressFile
+		os.RemoveAll(dir)
+	})
+
+	target := sha256.Sum256([]byte("the quick red fox jumps over the lazy cat! the quick"))
+	detail = createUpdateContext(Initialized).Current
+	detail.PackageName = "amazon-ssm-agent"
+	detail.SourceLocation = "source"
+	detail.TargetDeltaLocation = "delta"
+	detail.TargetHash = hex.EncodeToString(target[:])
+	return detail, uncompressed
+}
+
+func TestPrepareTargetFromDelta(t *testing.T) {
+	detail, uncompressed := stubDeltaDownloads(t)
+	downloadDir, err := ioutil.TempDir("", "download")
+	assert.NoError(t, err)
+	defer os.RemoveAll(downloadDir)
+
+	assert.True(t, prepareTargetFromDelta(logger, &UpdateContext{Current: detail}, downloadDir))
+	assert.Equal(t, "the quick red fox jumps over the lazy cat! the quick", string(*uncompressed))
+	assert.Contains(t, detail.StandardOut, "Successfully applied the delta")
+}
+
+func TestPrepareTargetFromDeltaHashMismatch(t *testing.T) {
+	detail, uncompressed := stubDeltaDownloads(t)
+	detail.TargetHash = "0000"
+	downloadDir, err := ioutil.TempDir("", "download")
+	assert.NoError(t, err)
+	defer os.RemoveAll(downloadDir)
+
+	assert.False(t, prepareTargetFromDelta(logger, &UpdateContext{Current: detail}, downloadDir))
+	assert.Empty(t, *uncompressed)
+	assert.Contains(t, detail.StandardOut, "downloading the full package")
+}
+
+func TestPrepareTargetFromDeltaWithoutDelta(t *testing.T) {
+	detail, _ := stubDeltaDownloads(t)
+	detail.TargetDeltaLocation = ""
+
+	assert.False(t, prepareTargetFromDelta(logger, &UpdateContext{Current: detail}, "unused"))
+	assert.Empty(t, detail.StandardOut)
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+)
+
+var (
+	// healthVerificationWindow is how long the updated agent must keep running before the update succeeds
+	healthVerificationWindow   = 2 * time.Minute
+	healthVerificationInterval = 10 * time.Second
+
+	loadAgentHealth = func() (*updateutil.AgentHealth, error) {
+		return updateutil.LoadAgentHealth(appconfig.UpdaterArtifactsRoot)
+	}
+)
+
+// requireAgentHealth decides which health checks the updated agent must pass. Agents report their health
+// since a given version, so the checks only apply when upgrading from an agent that reported it, and the
+// control channel is only required when it was connected before the update.
+func requireAgentHealth(log log.T, detail *UpdateDetail) {
+	health, err := loadAgentHealth()
+	if err != nil {
+		log.Debugf("agent health is not available, skipping its verification: %v", err)
+		return
+	}
+	if health.Version != detail.SourceVersion || detail.RequiresUninstall {
+		return
+	}
+	detail.VerifyAgentStarted = true
+	detail.VerifyControlChannel = health.ControlChannelConnected()
+}
+
+// verifyAgentHealth verifies the updated agent keeps running during the verification window,
+// reports that it started and connects its control channel when required
+func verifyAgentHealth(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext) (err error) {
+	detail := context.Current
+	log.Infof("Verifying the health of %v %v for %v", detail.PackageName, detail.TargetVersion, healthVerificationWindow)
+
+	deadline := time.Now().Add(healthVerificationWindow)
+	for {
+		isRunning, err := mgr.util.IsServiceRunning(log, instanceContext)
+		if err != nil {
+			return fmt.Errorf("failed to check if the agent is running: %v", err)
+		}
+		if !isRunning {
+			return fmt.Errorf("the agent stopped running")
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(healthVerificationInterval)
+	}
+
+	if !detail.VerifyAgentStarted {
+		return nil
+	}
+	health, err := loadAgentHealth()
+	if err != nil {
+		return fmt.Errorf("failed to load the agent health: %v", err)
+	}
+	if health.Version != detail.TargetVersion || health.StartDateTime.Before(detail.StartDateTime) {
+		return fmt.Errorf("the agent didn't report that it started")
+	}
+	if detail.VerifyControlChannel && !health.ControlChannelConnected() {
+		return fmt.Errorf("the agent didn't connect its control channel")
+	}
+
+	log.Infof("%v %v is healthy", detail.PackageName, detail.TargetVersion)
+	return nil
+}
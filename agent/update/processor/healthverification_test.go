@@ -0,0 +1,120 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/updateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubAgentHealth(t *testing.T, health *updateutil.AgentHealth) {
+	window, interval, load := healthVerificationWindow, healthVerificationInterval, loadAgentHealth
+	healthVerificationWindow, healthVerificationInterval = 0, time.Millisecond
+	loadAgentHealth = func() (*updateutil.AgentHealth, error) {
+		if health == nil {
+			return nil, fmt.Errorf("no agent health")
+		}
+		return health, nil
+	}
+	t.Cleanup(func() {
+		healthVerificationWindow, healthVerificationInterval, loadAgentHealth = window, interval, load
+	})
+}
+
+func TestRequireAgentHealth(t *testing.T) {
+	started := time.Now()
+	testCases := []struct {
+		health               *updateutil.AgentHealth
+		requiresUninstall    bool
+		verifyAgentStarted   bool
+		verifyControlChannel bool
+	}{
+		{nil, false, false, false},
+		{&updateutil.AgentHealth{Version: "4.0.0.0", StartDateTime: started}, false, false, false},
+		{&updateutil.AgentHealth{Version: "5.0.0.0", StartDateTime: started}, false, true, false},
+		{&updateutil.AgentHealth{Version: "5.0.0.0", StartDateTime: started, ControlChannelConnectedDateTime: started.Add(time.Second)}, false, true, true},
+		{&updateutil.AgentHealth{Version: "5.0.0.0", StartDateTime: started, ControlChannelConnectedDateTime: started.Add(time.Second)}, true, false, false},
+	}
+
+	for _, testCase := range testCases {
+		stubAgentHealth(t, testCase.health)
+		detail := createUpdateContext(Staged).Current
+		detail.RequiresUninstall = testCase.requiresUninstall
+
+		requireAgentHealth(logger, detail)
+
+		assert.Equal(t, testCase.verifyAgentStarted, detail.VerifyAgentStarted)
+		assert.Equal(t, testCase.verifyControlChannel, detail.VerifyControlChannel)
+	}
+}
+
+func TestVerifyAgentHealth(t *testing.T) {
+	updateStart := time.Now()
+	context := createUpdateContext(Installed)
+	context.Current.StartDateTime = updateStart
+	context.Current.VerifyAgentStarted = true
+	context.Current.VerifyControlChannel = true
+	mgr := createUpdaterStubs(&stubControl{serviceIsRunning: true}).mgr
+
+	stubAgentHealth(t, &updateutil.AgentHealth{
+		Version:                         "6.0.0.0",
+		StartDateTime:                   updateStart.Add(time.Second),
+		ControlChannelConnectedDateTime: updateStart.Add(2 * time.Second),
+	})
+	assert.NoError(t, verifyAgentHealth(mgr, logger, context, nil))
+}
+
+func TestVerifyAgentHealthFailures(t *testing.T) {
+	updateStart := time.Now()
+	testCases := []struct {
+		serviceIsRunning bool
+		health           *updateutil.AgentHealth
+		expectedError    string
+	}{
+		{false, nil, "stopped running"},
+		{true, nil, "failed to load the agent health"},
+		{true, &updateutil.AgentHealth{Version: "5.0.0.0", StartDateTime: updateStart.Add(time.Second)}, "didn't report that it started"},
+		{true, &updateutil.AgentHealth{Version: "6.0.0.0", StartDateTime: updateStart.Add(-time.Second)}, "didn't report that it started"},
+		{true, &updateutil.AgentHealth{Version: "6.0.0.0", StartDateTime: updateStart.Add(time.Second)}, "didn't connect its control channel"},
+	}
+
+	for _, testCase := range testCases {
+		context := createUpdateContext(Installed)
+		context.Current.StartDateTime = updateStart
+		context.Current.VerifyAgentStarted = true
+		context.Current.VerifyControlChannel = true
+		mgr := createUpdaterStubs(&stubControl{serviceIsRunning: testCase.serviceIsRunning}).mgr
+		stubAgentHealth(t, testCase.health)
+
+		err := verifyAgentHealth(mgr, logger, context, nil)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), testCase.expectedError)
+	}
+}
+
+func TestVerifyAgentHealthWithoutHealthReport(t *testing.T) {
+	context := createUpdateContext(Installed)
+	mgr := createUpdaterStubs(&stubControl{serviceIsRunning: true}).mgr
+	stubAgentHealth(t, nil)
+
+	assert.NoError(t, verifyAgentHealth(mgr, logger, context, nil))
+}
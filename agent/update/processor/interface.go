@@ -39,19 +39,21 @@ type verify func(mgr *updateManager, log log.T, context *UpdateContext, isRollba
 type rollback func(mgr *updateManager, log log.T, context *UpdateContext) (err error)
 type uninstall func(mgr *updateManager, log log.T, version string, context *UpdateContext) (err error)
 type install func(mgr *updateManager, log log.T, version string, context *UpdateContext) (err error)
+type verifyHealth func(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext) (err error)
 type download func(mgr *updateManager, log log.T, downloadInput artifact.DownloadInput, context *UpdateContext, version string) (err error)
 
 type updateManager struct {
-	util      updateutil.T
-	svc       Service
-	ctxMgr    ContextMgr
-	prepare   prepare
-	update    update
-	verify    verify
-	rollback  rollback
-	uninstall uninstall
-	install   install
-	download  download
+	util         updateutil.T
+	svc          Service
+	ctxMgr       ContextMgr
+	prepare      prepare
+	update       update
+	verify       verify
+	verifyHealth verifyHealth
+	rollback     rollback
+	uninstall    uninstall
+	install      install
+	download     download
 }
 
 // Updater contains logic for performing agent update
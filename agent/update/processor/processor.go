@@ -27,6 +27,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/updateutil"
 )
 
+// downloadProgressStep is the percentage of the installation package downloads between progress messages
+const downloadProgressStep = 10
+
 var minimumSupportedVersions map[string]string
 var once sync.Once
 
@@ -39,16 +42,17 @@ var (
 func NewUpdater() *Updater {
 	updater := &Updater{
 		mgr: &updateManager{
-			util:      &updateutil.Utility{},
-			svc:       &svcManager{},
-			ctxMgr:    &contextManager{},
-			prepare:   prepareInstallationPackages,
-			update:    proceedUpdate,
-			verify:    verifyInstallation,
-			rollback:  rollbackInstallation,
-			uninstall: uninstallAgent,
-			install:   installAgent,
-			download:  downloadAndUnzipArtifact,
+			util:         &updateutil.Utility{},
+			svc:          &svcManager{},
+			ctxMgr:       &contextManager{},
+			prepare:      prepareInstallationPackages,
+			update:       proceedUpdate,
+			verify:       verifyInstallation,
+			verifyHealth: verifyAgentHealth,
+			rollback:     rollbackInstallation,
+			uninstall:    uninstallAgent,
+			install:      installAgent,
+			download:     downloadAndUnzipArtifact,
 		},
 	}
 
@@ -174,19 +178,23 @@ func prepareInstallationPackages(mgr *updateManager, log log.T, context *UpdateC
 		return mgr.failed(context, log, updateutil.ErrorInvalidPackage, err.Error(), true)
 	}
 
-	// Download target
-	downloadInput = artifact.DownloadInput{
-		SourceURL: context.Current.TargetLocation,
-		SourceChecksums: map[string]string{
-			updateutil.HashType: context.Current.TargetHash,
-		},
-		DestinationDirectory: updateDownload,
-	}
+	// Download target, unless it can be patched from the source with a delta
+	if !prepareTargetFromDelta(log, context, updateDownload) {
+		downloadInput = artifact.DownloadInput{
+			SourceURL: context.Current.TargetLocation,
+			SourceChecksums: map[string]string{
+				updateutil.HashType: context.Current.TargetHash,
+			},
+			DestinationDirectory: updateDownload,
+		}
 
-	if err = mgr.download(mgr, log, downloadInput, context, context.Current.TargetVersion); err != nil {
-		return mgr.failed(context, log, updateutil.ErrorInvalidPackage, err.Error(), true)
+		if err = mgr.download(mgr, log, downloadInput, context, context.Current.TargetVersion); err != nil {
+			return mgr.failed(context, log, updateutil.ErrorInvalidPackage, err.Error(), true)
+		}
 	}
 
+	requireAgentHealth(log, context.Current)
+
 	// Update stdout
 	context.Current.AppendInfo(
 		log,
@@ -210,6 +218,11 @@ func proceedUpdate(mgr *updateManager, log log.T, context *UpdateContext) (err e
 		context.Current.SourceVersion,
 		context.Current.TargetVersion)
 
+	// Let the administrator prepare the instance before the old agent stops
+	if err = runPreUpdateHook(log, context.Current); err != nil {
+		return mgr.failed(context, log, updateutil.ErrorPreUpdateHookFailed, err.Error(), true)
+	}
+
 	// Uninstall only when the target version is lower than the source version
 	if context.Current.RequiresUninstall {
 		if err = mgr.uninstall(mgr, log, context.Current.SourceVersion, context); err != nil {
@@ -229,19 +242,7 @@ func proceedUpdate(mgr *updateManager, log log.T, context *UpdateContext) (err e
 			"failed to install %v %v",
 			context.Current.PackageName,
 			context.Current.TargetVersion)
-		context.Current.AppendError(log, message)
-
-		context.Current.AppendInfo(
-			log,
-			"Initiating rollback %v to %v",
-			context.Current.PackageName,
-			context.Current.SourceVersion)
-		// Update state to Rollback to indicate updater has initiated the rollback process
-		if err = mgr.inProgress(context, log, Rollback); err != nil {
-			return err
-		}
-		// Rollback
-		return mgr.rollback(mgr, log, context)
+		return initiateRollback(mgr, log, context, message)
 	}
 
 	// Update state to installed to indicate there is no error occur during installation
@@ -272,18 +273,7 @@ func verifyInstallation(mgr *updateManager, log log.T, context *UpdateContext, i
 				context.Current.PackageName,
 				context.Current.TargetVersion,
 				"failed to start the agent")
-
-			context.Current.AppendError(log, message)
-			context.Current.AppendInfo(
-				log,
-				"Initiating rollback %v to %v",
-				context.Current.PackageName,
-				context.Current.SourceVersion)
-			// Update state to rollback
-			if err = mgr.inProgress(context, log, Rollback); err != nil {
-				return err
-			}
-			return mgr.rollback(mgr, log, context)
+			return initiateRollback(mgr, log, context, message)
 		}
 
 		message := updateutil.BuildMessage(err,
@@ -297,6 +287,15 @@ func verifyInstallation(mgr *updateManager, log log.T, context *UpdateContext, i
 
 	log.Infof("%v is running", context.Current.PackageName)
 	if !isRollback {
+		if err = mgr.verifyHealth(mgr, log, context, instanceContext); err != nil {
+			message := updateutil.BuildMessage(err,
+				"failed to update %v to %v, %v",
+				context.Current.PackageName,
+				context.Current.TargetVersion,
+				"the agent failed the health verification")
+			return initiateRollback(mgr, log, context, message)
+		}
+		runPostUpdateHook(log, context.Current)
 		return mgr.succeeded(context, log)
 	}
 
@@ -305,6 +304,21 @@ func verifyInstallation(mgr *updateManager, log log.T, context *UpdateContext, i
 	return mgr.failed(context, log, updateutil.ErrorCannotStartService, message, false)
 }
 
+// initiateRollback reports the failure of the update and rolls back to the source version
+func initiateRollback(mgr *updateManager, log log.T, context *UpdateContext, message string) (err error) {
+	context.Current.AppendError(log, "%v", message)
+	context.Current.AppendInfo(
+		log,
+		"Initiating rollback %v to %v",
+		context.Current.PackageName,
+		context.Current.SourceVersion)
+	// Update state to Rollback to indicate updater has initiated the rollback process
+	if err = mgr.inProgress(context, log, Rollback); err != nil {
+		return err
+	}
+	return mgr.rollback(mgr, log, context)
+}
+
 // rollbackInstallation rollback installation to the source version
 func rollbackInstallation(mgr *updateManager, log log.T, context *UpdateContext) (err error) {
 	if err = mgr.uninstall(mgr, log, context.Current.TargetVersion, context); err != nil {
@@ -405,7 +419,9 @@ func downloadAndUnzipArtifact(
 	version string) (err error) {
 
 	log.Infof("Preparing source for version %v", version)
-	// download installation zip files
+	// download installation zip files, resuming the download of an interrupted update
+	downloadInput.Resumable = true
+	downloadInput.Progress = downloadProgress(log, context.Current, downloadInput.SourceURL)
 	downloadOutput, err := downloadArtifact(log, downloadInput)
 	if err != nil ||
 		downloadOutput.IsHashMatched == false ||
@@ -429,3 +445,18 @@ func downloadAndUnzipArtifact(
 
 	return nil
 }
+
+// downloadProgress returns a progress func appending every downloadProgressStep percent of a download to the update output
+func downloadProgress(log log.T, update *UpdateDetail, sourceURL string) artifact.ProgressFunc {
+	var reported int64
+	return func(downloaded int64, total int64) {
+		if total <= 0 {
+			return
+		}
+		percent := downloaded * 100 / total / downloadProgressStep * downloadProgressStep
+		if percent > reported {
+			reported = percent
+			update.AppendInfo(log, "Downloaded %v%% of %v (%v of %v bytes)", percent, sourceURL, downloaded, total)
+		}
+	}
+}
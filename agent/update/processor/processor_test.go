@@ -17,6 +17,7 @@ package processor
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
@@ -397,6 +398,29 @@ func TestVerifyInstallationCannotStartAgent(t *testing.T) {
 	assert.Equal(t, context.Current.State, Rollback)
 }
 
+func TestVerifyInstallationFailedHealthVerification(t *testing.T) {
+	// setup
+	control := &stubControl{serviceIsRunning: true, failHealthVerification: true}
+	updater := createUpdaterStubs(control)
+	context := createUpdateContext(Installed)
+	isRollbackCalled := false
+
+	updater.mgr.rollback = func(mgr *updateManager, log log.T, context *UpdateContext) (err error) {
+		isRollbackCalled = true
+		return nil
+	}
+
+	// action
+	err := verifyInstallation(updater.mgr, logger, context, false)
+
+	// assert
+	assert.NoError(t, err)
+	assert.True(t, isRollbackCalled)
+	assert.Equal(t, context.Current.State, Rollback)
+	assert.Contains(t, context.Current.StandardError, "the agent failed the health verification")
+	assert.Empty(t, context.Histories)
+}
+
 func TestVerifyRollback(t *testing.T) {
 	// setup
 	control := &stubControl{serviceIsRunning: true}
@@ -599,6 +623,46 @@ func TestDownloadAndUnzipArtifact(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDownloadAndUnzipArtifactResumable(t *testing.T) {
+	// setup
+	control := &stubControl{failExeCommand: true}
+	updater := createUpdaterStubs(control)
+	context := createUpdateContext(Initialized)
+
+	downloadArtifact = func(log log.T, input artifact.DownloadInput) (output artifact.DownloadOutput, err error) {
+		assert.True(t, input.Resumable)
+		input.Progress(50, 100)
+		return artifact.DownloadOutput{IsHashMatched: true, LocalFilePath: "filepath"}, nil
+	}
+	uncompress = func(log log.T, src, dest string) error {
+		return nil
+	}
+
+	// action
+	err := downloadAndUnzipArtifact(updater.mgr, logger, artifact.DownloadInput{SourceURL: "https://target"}, context, context.Current.TargetVersion)
+
+	// assert
+	assert.NoError(t, err)
+	assert.Contains(t, context.Current.StandardOut, "Downloaded 50% of https://target")
+}
+
+func TestDownloadProgress(t *testing.T) {
+	// setup
+	context := createUpdateContext(Initialized)
+	progress := downloadProgress(logger, context.Current, "https://target")
+
+	// action
+	for _, downloaded := range []int64{5, 12, 18, 25, 100} {
+		progress(downloaded, 100)
+	}
+
+	// assert
+	assert.NotContains(t, context.Current.StandardOut, "Downloaded 0%")
+	assert.Equal(t, 1, strings.Count(context.Current.StandardOut, "Downloaded 10%"))
+	assert.Contains(t, context.Current.StandardOut, "Downloaded 20% of https://target (25 of 100 bytes)")
+	assert.Contains(t, context.Current.StandardOut, "Downloaded 100%")
+}
+
 func TestDownloadWithError(t *testing.T) {
 	// setup
 	control := &stubControl{failExeCommand: true}
@@ -630,6 +694,12 @@ func createUpdaterStubs(control *stubControl) *Updater {
 	updater.mgr.svc = &serviceStub{}
 	updater.mgr.util = &utilityStub{controller: control}
 	updater.mgr.ctxMgr = &contextMgrStub{}
+	updater.mgr.verifyHealth = func(mgr *updateManager, log log.T, context *UpdateContext, instanceContext *updateutil.InstanceContext) (err error) {
+		if control.failHealthVerification {
+			return fmt.Errorf("the agent stopped running")
+		}
+		return nil
+	}
 
 	return updater
 }
@@ -639,6 +709,7 @@ type stubControl struct {
 	failCreateUpdateDownloadFolder bool
 	serviceIsRunning               bool
 	failExeCommand                 bool
+	failHealthVerification         bool
 }
 
 type utilityStub struct {
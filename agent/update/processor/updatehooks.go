@@ -0,0 +1,85 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	hookEnvPackageName   = "SSM_UPDATE_PACKAGE_NAME"
+	hookEnvSourceVersion = "SSM_UPDATE_SOURCE_VERSION"
+	hookEnvTargetVersion = "SSM_UPDATE_TARGET_VERSION"
+)
+
+// loadUpdateHooks returns the update hooks of the agent configuration
+var loadUpdateHooks = func(log log.T) appconfig.UpdateHooksCfg {
+	config, err := getAppConfig(false)
+	if err != nil {
+		log.Warnf("failed to load the update hooks: %v", err)
+		return appconfig.UpdateHooksCfg{}
+	}
+	return config.Update.Hooks
+}
+
+// runHookCommand runs a hook executable with env added to the environment of the updater, it is stubbed in the tests
+var runHookCommand = executers.RunHook
+
+// runPreUpdateHook runs the administrator-defined pre-update hook, if any, the update must not be performed when it fails
+func runPreUpdateHook(log log.T, update *UpdateDetail) error {
+	hooks := loadUpdateHooks(log)
+	if hooks.PreUpdate == "" {
+		return nil
+	}
+	return runUpdateHook(log, update, "pre-update", hooks.PreUpdate, hooks.TimeoutSeconds)
+}
+
+// runPostUpdateHook runs the administrator-defined post-update hook, if any, once the new agent started
+func runPostUpdateHook(log log.T, update *UpdateDetail) {
+	hooks := loadUpdateHooks(log)
+	if hooks.PostUpdate == "" {
+		return
+	}
+	// the agent has already been updated, a failing post-update hook is only reported
+	if err := runUpdateHook(log, update, "post-update", hooks.PostUpdate, hooks.TimeoutSeconds); err != nil {
+		update.AppendError(log, "%v", err)
+	}
+}
+
+// runUpdateHook runs a hook with the versions of the update in its environment and adds its output to the update output
+func runUpdateHook(log log.T, update *UpdateDetail, stage string, path string, timeoutSeconds int) error {
+	env := []string{
+		fmt.Sprintf("%v=%v", hookEnvPackageName, update.PackageName),
+		fmt.Sprintf("%v=%v", hookEnvSourceVersion, update.SourceVersion),
+		fmt.Sprintf("%v=%v", hookEnvTargetVersion, update.TargetVersion),
+	}
+	update.AppendInfo(log, "Running %v hook %v", stage, path)
+	output, err := runHookCommand(path, env, time.Duration(timeoutSeconds)*time.Second)
+	if output = strings.TrimSpace(output); output != "" {
+		update.AppendInfo(log, "%v", output)
+	}
+	if err != nil {
+		return fmt.Errorf("%v hook %v failed: %v", stage, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package processor contains the methods for update ssm agent.
+// It also provides methods for sendReply and updateInstanceInfo
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type hookCall struct {
+	path    string
+	env     []string
+	timeout time.Duration
+}
+
+// stubUpdateHooks configures the update hooks and records their runs, failing the hooks of failPaths
+func stubUpdateHooks(t *testing.T, hooks appconfig.UpdateHooksCfg, failPaths ...string) *[]hookCall {
+	calls := &[]hookCall{}
+	load, run := loadUpdateHooks, runHookCommand
+	loadUpdateHooks = func(log log.T) appconfig.UpdateHooksCfg {
+		return hooks
+	}
+	runHookCommand = func(path string, env []string, timeout time.Duration) (output string, err error) {
+		*calls = append(*calls, hookCall{path, env, timeout})
+		for _, failPath := range failPaths {
+			if path == failPath {
+				return "monitoring busy", fmt.Errorf("exit status 1")
+			}
+		}
+		return "hook output", nil
+	}
+	t.Cleanup(func() {
+		loadUpdateHooks, runHookCommand = load, run
+	})
+	return calls
+}
+
+func TestRunPreUpdateHook(t *testing.T) {
+	calls := stubUpdateHooks(t, appconfig.UpdateHooksCfg{PreUpdate: "/opt/hooks/pre", TimeoutSeconds: 30})
+	detail := createUpdateContext(Staged).Current
+
+	err := runPreUpdateHook(logger, detail)
+
+	assert.NoError(t, err)
+	assert.Len(t, *calls, 1)
+	assert.Equal(t, "/opt/hooks/pre", (*calls)[0].path)
+	assert.Equal(t, 30*time.Second, (*calls)[0].timeout)
+	assert.Contains(t, (*calls)[0].env, hookEnvSourceVersion+"="+detail.SourceVersion)
+	assert.Contains(t, (*calls)[0].env, hookEnvTargetVersion+"="+detail.TargetVersion)
+	assert.Contains(t, (*calls)[0].env, hookEnvPackageName+"="+detail.PackageName)
+	assert.Contains(t, detail.StandardOut, "hook output")
+}
+
+func TestRunPreUpdateHookFailed(t *testing.T) {
+	stubUpdateHooks(t, appconfig.UpdateHooksCfg{PreUpdate: "/opt/hooks/pre", TimeoutSeconds: 30}, "/opt/hooks/pre")
+	detail := createUpdateContext(Staged).Current
+
+	err := runPreUpdateHook(logger, detail)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "pre-update hook /opt/hooks/pre failed")
+	assert.Contains(t, detail.StandardOut, "monitoring busy")
+}
+
+func TestRunUpdateHooksWithoutHooks(t *testing.T) {
+	calls := stubUpdateHooks(t, appconfig.UpdateHooksCfg{TimeoutSeconds: 30})
+	detail := createUpdateContext(Staged).Current
+
+	assert.NoError(t, runPreUpdateHook(logger, detail))
+	runPostUpdateHook(logger, detail)
+
+	assert.Empty(t, *calls)
+}
+
+func TestRunPostUpdateHookFailed(t *testing.T) {
+	stubUpdateHooks(t, appconfig.UpdateHooksCfg{PostUpdate: "/opt/hooks/post", TimeoutSeconds: 30}, "/opt/hooks/post")
+	detail := createUpdateContext(Installed).Current
+
+	runPostUpdateHook(logger, detail)
+
+	assert.Contains(t, detail.StandardError, "post-update hook /opt/hooks/post failed")
+}
+
+func TestProceedUpdateFailPreUpdateHook(t *testing.T) {
+	// setup
+	stubUpdateHooks(t, appconfig.UpdateHooksCfg{PreUpdate: "/opt/hooks/pre", TimeoutSeconds: 30}, "/opt/hooks/pre")
+	updater := createDefaultUpdaterStub()
+	context := createUpdateContext(Staged)
+	isInstallCalled := false
+
+	updater.mgr.install = func(mgr *updateManager, log log.T, version string, context *UpdateContext) (err error) {
+		isInstallCalled = true
+		return nil
+	}
+
+	// action
+	err := proceedUpdate(updater.mgr, logger, context)
+
+	// assert
+	assert.NoError(t, err)
+	assert.False(t, isInstallCalled)
+	assert.Equal(t, context.Histories[0].Result, contracts.ResultStatusFailed)
+}
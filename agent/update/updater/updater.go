@@ -46,6 +46,9 @@ var (
 	targetVersion   *string
 	targetLocation  *string
 	targetHash      *string
+	targetDelta     *string
+	targetDeltaHash *string
+	allowDowngrade  *bool
 	packageName     *string
 	messageID       *string
 	stdout          *string
@@ -70,6 +73,9 @@ func init() {
 	targetVersion = flag.String(updateutil.TargetVersionCmd, "", "target Agent Version")
 	targetLocation = flag.String(updateutil.TargetLocationCmd, "", "target Agent installer source")
 	targetHash = flag.String(updateutil.TargetHashCmd, "", "target Agent installer hash")
+	targetDelta = flag.String(updateutil.TargetDeltaLocationCmd, "", "target Agent installer delta from the current installer")
+	targetDeltaHash = flag.String(updateutil.TargetDeltaHashCmd, "", "target Agent installer delta hash")
+	allowDowngrade = flag.Bool(updateutil.AllowDowngradeCmd, false, "allow the target Agent Version to be older than the current one")
 	packageName = flag.String(updateutil.PackageNameCmd, "", "target Agent Version")
 	messageID = flag.String(updateutil.MessageIDCmd, "", "target Agent Version")
 	stdout = flag.String(updateutil.StdoutFileName, "", "standard output file path")
@@ -110,22 +116,25 @@ func main() {
 
 	// Create new UpdateDetail
 	detail := &processor.UpdateDetail{
-		State:              processor.NotStarted,
-		Result:             contracts.ResultStatusInProgress,
-		SourceVersion:      *sourceVersion,
-		SourceLocation:     *sourceLocation,
-		SourceHash:         *sourceHash,
-		TargetVersion:      *targetVersion,
-		TargetLocation:     *targetLocation,
-		TargetHash:         *targetHash,
-		StdoutFileName:     *stdout,
-		StderrFileName:     *stderr,
-		OutputS3KeyPrefix:  *outputKeyPrefix,
-		OutputS3BucketName: *outputBucket,
-		PackageName:        *packageName,
-		MessageID:          *messageID,
-		StartDateTime:      time.Now().UTC(),
-		RequiresUninstall:  false,
+		State:               processor.NotStarted,
+		Result:              contracts.ResultStatusInProgress,
+		SourceVersion:       *sourceVersion,
+		SourceLocation:      *sourceLocation,
+		SourceHash:          *sourceHash,
+		TargetVersion:       *targetVersion,
+		TargetLocation:      *targetLocation,
+		TargetHash:          *targetHash,
+		TargetDeltaLocation: *targetDelta,
+		TargetDeltaHash:     *targetDeltaHash,
+		StdoutFileName:      *stdout,
+		StderrFileName:      *stderr,
+		OutputS3KeyPrefix:   *outputKeyPrefix,
+		OutputS3BucketName:  *outputBucket,
+		PackageName:         *packageName,
+		MessageID:           *messageID,
+		StartDateTime:       time.Now().UTC(),
+		RequiresUninstall:   false,
+		AllowDowngrade:      *allowDowngrade,
 	}
 
 	if err := resolveUpdateDetail(detail); err != nil {
@@ -162,6 +171,12 @@ func resolveUpdateDetail(detail *processor.UpdateDetail) error {
 	}
 	// if performing a downgrade
 	if compareResult > 0 {
+		if !detail.AllowDowngrade {
+			return fmt.Errorf("refusing to downgrade from %v to %v, use -%v for an intentional rollback",
+				detail.SourceVersion,
+				detail.TargetVersion,
+				updateutil.AllowDowngradeCmd)
+		}
 		detail.RequiresUninstall = true
 	}
 
@@ -117,6 +117,34 @@ func TestUpdaterWithDowngrade(t *testing.T) {
 	assert.Equal(t, *targetVersion, "1.0.0.0")
 }
 
+func TestResolveUpdateDetailRefusesDowngrade(t *testing.T) {
+	detail := &processor.UpdateDetail{SourceVersion: "5.0.0.0", TargetVersion: "1.0.0.0"}
+
+	err := resolveUpdateDetail(detail)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), updateutil.AllowDowngradeCmd)
+	assert.False(t, detail.RequiresUninstall)
+}
+
+func TestResolveUpdateDetailAllowDowngrade(t *testing.T) {
+	detail := &processor.UpdateDetail{SourceVersion: "5.0.0.0", TargetVersion: "1.0.0.0", AllowDowngrade: true}
+
+	err := resolveUpdateDetail(detail)
+
+	assert.NoError(t, err)
+	assert.True(t, detail.RequiresUninstall)
+}
+
+func TestResolveUpdateDetailUpgrade(t *testing.T) {
+	detail := &processor.UpdateDetail{SourceVersion: "1.0.0.0", TargetVersion: "5.0.0.0"}
+
+	err := resolveUpdateDetail(detail)
+
+	assert.NoError(t, err)
+	assert.False(t, detail.RequiresUninstall)
+}
+
 func TestUpdaterFailedWithoutSourceTargetCmd(t *testing.T) {
 	// setup
 	log = logger.NewMockLog()
@@ -0,0 +1,102 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/version"
+)
+
+// AgentHealth represents the health reported by the running agent, the updater reads it to verify an update
+type AgentHealth struct {
+	Version                         string    `json:"Version"`
+	StartDateTime                   time.Time `json:"StartDateTime"`
+	ControlChannelConnectedDateTime time.Time `json:"ControlChannelConnectedDateTime"`
+}
+
+// ControlChannelConnected returns true if the control channel connected since the agent started
+func (health *AgentHealth) ControlChannelConnected() bool {
+	return !health.ControlChannelConnectedDateTime.Before(health.StartDateTime)
+}
+
+var agentHealthLock sync.Mutex
+var agentHealth *AgentHealth
+
+// AgentHealthFilePath returns the agent health file path
+func AgentHealthFilePath(updateRoot string) (filePath string) {
+	return filepath.Join(updateRoot, AgentHealthFileName)
+}
+
+// LoadAgentHealth loads the health last reported by the agent from local storage
+func LoadAgentHealth(updateRoot string) (health *AgentHealth, err error) {
+	content, err := ioutil.ReadFile(AgentHealthFilePath(updateRoot))
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// RecordAgentStarted reports to the updater that the current agent version started
+func RecordAgentStarted(log log.T) {
+	agentHealthLock.Lock()
+	defer agentHealthLock.Unlock()
+
+	agentHealth = &AgentHealth{
+		Version:       version.Version,
+		StartDateTime: time.Now().UTC(),
+	}
+	saveAgentHealth(log, appconfig.UpdaterArtifactsRoot, agentHealth)
+}
+
+// RecordControlChannelConnected reports to the updater that the control channel of the current agent connected,
+// only the first connection since the agent started is recorded
+func RecordControlChannelConnected(log log.T) {
+	agentHealthLock.Lock()
+	defer agentHealthLock.Unlock()
+
+	if agentHealth == nil || agentHealth.ControlChannelConnected() {
+		return
+	}
+	agentHealth.ControlChannelConnectedDateTime = time.Now().UTC()
+	saveAgentHealth(log, appconfig.UpdaterArtifactsRoot, agentHealth)
+}
+
+// saveAgentHealth saves the agent health to local storage, failures are only logged as they don't affect the agent
+func saveAgentHealth(log log.T, updateRoot string, health *AgentHealth) {
+	content, err := json.Marshal(health)
+	if err != nil {
+		log.Warnf("failed to marshal agent health: %v", err)
+		return
+	}
+	if err = fileutil.MakeDirs(updateRoot); err != nil {
+		log.Warnf("failed to create update directory %v: %v", updateRoot, err)
+		return
+	}
+	if err = ioutil.WriteFile(AgentHealthFilePath(updateRoot), content, appconfig.ReadWriteAccess); err != nil {
+		log.Warnf("failed to save agent health: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package updateutil contains updater specific utilities.
+package updateutil
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoadAgentHealth(t *testing.T) {
+	updateRoot, err := ioutil.TempDir("", "agenthealth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(updateRoot)
+
+	started := time.Now().UTC()
+	saveAgentHealth(logger, updateRoot, &AgentHealth{Version: "2.3.0.0", StartDateTime: started})
+
+	health, err := LoadAgentHealth(updateRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.3.0.0", health.Version)
+	assert.True(t, started.Equal(health.StartDateTime))
+	assert.False(t, health.ControlChannelConnected())
+
+	health.ControlChannelConnectedDateTime = started.Add(time.Second)
+	assert.True(t, health.ControlChannelConnected())
+}
+
+func TestLoadAgentHealthMissing(t *testing.T) {
+	_, err := LoadAgentHealth("nonexistent")
+	assert.Error(t, err)
+}
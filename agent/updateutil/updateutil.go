@@ -19,7 +19,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"sync"
 	"syscall"
@@ -52,6 +51,9 @@ const (
 	// UpdatePluginResultFileName represents Update plugin result file name
 	UpdatePluginResultFileName = "updatepluginresult.json"
 
+	// AgentHealthFileName represents the file where the running agent reports its health to the updater
+	AgentHealthFileName = "agenthealth.json"
+
 	// DefaultOutputFolder represents default location for storing output files
 	DefaultOutputFolder = "awsupdateSsmAgent"
 
@@ -64,6 +66,9 @@ const (
 	// RegionHolder represents Place holder for Region
 	RegionHolder = "{Region}"
 
+	// DnsSuffixHolder represents Place holder for the domain of the partition of the region
+	DnsSuffixHolder = "{DnsSuffix}"
+
 	// PackageNameHolder represents Place holder for package name
 	PackageNameHolder = "{PackageName}"
 
@@ -119,7 +124,7 @@ const (
 	PipelineTestVersion = "255.0.0.0"
 )
 
-//ErrorCode is types of Error Codes
+// ErrorCode is types of Error Codes
 type ErrorCode string
 
 const (
@@ -173,6 +178,9 @@ const (
 
 	// ErrorLoadingAgentVersion represents failed for loading agent version
 	ErrorLoadingAgentVersion ErrorCode = "ErrorLoadingAgentVersion"
+
+	// ErrorPreUpdateHookFailed represents the failure of the administrator-defined pre-update hook
+	ErrorPreUpdateHookFailed ErrorCode = "ErrorPreUpdateHookFailed"
 )
 
 // MinimumDiskSpaceForUpdate represents 100 Mb in bytes
@@ -315,7 +323,7 @@ func (util *Utility) CreateInstanceContext(log log.T) (context *InstanceContext,
 		Platform:        platformName,
 		PlatformVersion: platformVersion,
 		InstallerName:   installerName,
-		Arch:            runtime.GOARCH,
+		Arch:            platform.Architecture(),
 		CompressFormat:  CompressFormat,
 	}
 
@@ -648,6 +656,14 @@ func BuildUpdateCommand(cmd string, arg string, value string) string {
 	return fmt.Sprintf("%v -%v %v", cmd, arg, value)
 }
 
+// BuildUpdateFlag builds command string with a boolean argument, set only when enabled
+func BuildUpdateFlag(cmd string, arg string, enabled bool) string {
+	if !enabled || arg == "" {
+		return cmd
+	}
+	return fmt.Sprintf("%v -%v", cmd, arg)
+}
+
 // UpdateArtifactFolder returns the folder path for storing all the update artifacts
 func UpdateArtifactFolder(updateRoot string, packageName string, version string) (folder string) {
 	return filepath.Join(updateRoot, packageName, version)
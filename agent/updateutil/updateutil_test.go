@@ -208,6 +208,12 @@ func TestBuildUpdateCommand(t *testing.T) {
 	}
 }
 
+func TestBuildUpdateFlag(t *testing.T) {
+	assert.Equal(t, "Cmd -test", BuildUpdateFlag("Cmd", "test", true))
+	assert.Equal(t, "Cmd", BuildUpdateFlag("Cmd", "test", false))
+	assert.Equal(t, "Cmd", BuildUpdateFlag("Cmd", "", true))
+}
+
 func TestUpdateArtifactFolder(t *testing.T) {
 	testCases := []struct {
 		pkgname string
@@ -11,6 +11,7 @@
 // either express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
+//go:build darwin || freebsd || linux || netbsd || openbsd
 // +build darwin freebsd linux netbsd openbsd
 
 // Package updateutil contains updater specific utilities.
@@ -43,6 +44,15 @@ const (
 	// TargetHashCmd represents the command argument for target hash value
 	TargetHashCmd = "target.hash"
 
+	// TargetDeltaLocationCmd represents the command argument for the location of the delta from source to target
+	TargetDeltaLocationCmd = "target.delta.location"
+
+	// TargetDeltaHashCmd represents the command argument for the delta hash value
+	TargetDeltaHashCmd = "target.delta.hash"
+
+	// AllowDowngradeCmd represents the command argument allowing the target version to be older than the source version
+	AllowDowngradeCmd = "allow.downgrade"
+
 	// PackageNameCmd represents the command argument for package name
 	PackageNameCmd = "package.name"
 
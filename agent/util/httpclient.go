@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// This package is for project's utilities
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aws/amazon-ssm-agent/agent/fips"
+)
+
+// CertPoolWithCABundle returns the system roots with the PEM certificates in caBundle added to them
+func CertPoolWithCABundle(caBundle string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %v: %v", caBundle, err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil || roots == nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %v", caBundle)
+	}
+	return roots, nil
+}
+
+// NewHTTPClientWithCABundle returns an http client trusting the PEM certificates in caBundle in addition to the system roots
+func NewHTTPClientWithCABundle(caBundle string) (*http.Client, error) {
+	roots, err := CertPoolWithCABundle(caBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = fips.TLSConfig(&tls.Config{RootCAs: roots})
+	return &http.Client{Transport: transport}, nil
+}
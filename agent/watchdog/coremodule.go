@@ -0,0 +1,125 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package watchdog
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+)
+
+const name = "Watchdog"
+
+// Watchdog is the core module checking the watched workers and core loops at every check interval
+type Watchdog struct {
+	context  context.T
+	interval time.Duration
+	stop     chan bool
+	done     chan bool
+}
+
+// NewWatchdog creates the watchdog, nil when the watchdog is not enabled in appconfig
+func NewWatchdog(context context.T) *Watchdog {
+	config := context.AppConfig().Watchdog
+	if !config.Enabled {
+		return nil
+	}
+	// the loops and workers are watched from the creation of the module, the other core modules start concurrently
+	enable(
+		time.Duration(config.HeartbeatTimeoutMinutes)*time.Minute,
+		map[string]time.Duration{
+			DocumentWorker: time.Duration(config.DocumentWorkerTimeoutHours) * time.Hour,
+			SessionWorker:  time.Duration(config.SessionWorkerTimeoutHours) * time.Hour,
+		})
+	return &Watchdog{
+		context:  context.With("[" + name + "]"),
+		interval: time.Duration(config.CheckIntervalSeconds) * time.Second,
+		stop:     make(chan bool),
+		done:     make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (w *Watchdog) ModuleName() string {
+	return name
+}
+
+// ModuleExecute starts checking the watched workers and core loops
+func (w *Watchdog) ModuleExecute(context context.T) (err error) {
+	w.context.Log().Infof("Checking the workers and core loops every %v", w.interval)
+	go w.run()
+	return nil
+}
+
+// ModuleRequestStop stops the watchdog
+func (w *Watchdog) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	close(w.stop)
+	<-w.done
+	disable()
+	return nil
+}
+
+func (w *Watchdog) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			check(w.context.Log())
+		}
+	}
+}
+
+// check kills the workers past their deadline and restarts the loops which stopped heartbeating
+func check(log log.T) {
+	now := timeNow()
+	var restarts []func()
+	var kills []*worker
+
+	state.Lock()
+	for loopName, l := range state.loops {
+		if since := now.Sub(l.lastHeartbeat); since > state.heartbeatTimeout {
+			log.Warnf("Core loop %v did not heartbeat for %v, restarting it", loopName, since)
+			metrics.WatchdogRecoveries.Inc(loopName)
+			// the loop is given another heartbeat timeout to recover after the restart
+			l.lastHeartbeat = now
+			restarts = append(restarts, l.restart)
+		}
+	}
+	for id, wk := range state.workers {
+		if now.After(wk.deadline) {
+			delete(state.workers, id)
+			kills = append(kills, wk)
+		}
+	}
+	state.Unlock()
+
+	for _, restart := range restarts {
+		go restart()
+	}
+	for _, wk := range kills {
+		log.Warnf("The %v worker process %v is still running past its deadline %v, killing it", wk.kind, wk.pid, wk.deadline)
+		metrics.WatchdogRecoveries.Inc(wk.kind)
+		if err := wk.kill(); err != nil {
+			log.Errorf("Failed to kill the %v worker process %v: %v", wk.kind, wk.pid, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package watchdog kills the document and session workers stuck past their deadline and restarts
+// the core loops of the agent which stopped heartbeating, so that stalled instances recover on their own.
+package watchdog
+
+import (
+	"sync"
+	"time"
+)
+
+// Worker kinds watched by the watchdog, they match the worker label of the metrics
+const (
+	DocumentWorker = "document"
+	SessionWorker  = "session"
+)
+
+// loop is a core loop expected to heartbeat at least once per heartbeat timeout
+type loop struct {
+	lastHeartbeat time.Time
+	restart       func()
+}
+
+// worker is a worker process expected to exit before its deadline
+type worker struct {
+	kind     string
+	pid      int
+	deadline time.Time
+	kill     func() error
+}
+
+// timeNow returns the current time, it is stubbed in the tests
+var timeNow = time.Now
+
+// state holds the watched loops and workers, nothing is watched when the watchdog is not enabled
+var state struct {
+	sync.Mutex
+	enabled          bool
+	heartbeatTimeout time.Duration
+	workerTimeouts   map[string]time.Duration
+	loops            map[string]*loop
+	workers          map[int]*worker
+	nextWorkerID     int
+}
+
+// enable starts watching the loops and workers registered from now on
+func enable(heartbeatTimeout time.Duration, workerTimeouts map[string]time.Duration) {
+	state.Lock()
+	defer state.Unlock()
+	state.enabled = true
+	state.heartbeatTimeout = heartbeatTimeout
+	state.workerTimeouts = workerTimeouts
+	state.loops = make(map[string]*loop)
+	state.workers = make(map[int]*worker)
+}
+
+// disable stops watching the loops and workers
+func disable() {
+	state.Lock()
+	defer state.Unlock()
+	state.enabled = false
+	state.loops = nil
+	state.workers = nil
+}
+
+// Register starts watching the heartbeats of a core loop, restart is called when the loop stopped heartbeating
+func Register(name string, restart func()) {
+	state.Lock()
+	defer state.Unlock()
+	if !state.enabled {
+		return
+	}
+	state.loops[name] = &loop{
+		lastHeartbeat: timeNow(),
+		restart:       restart,
+	}
+}
+
+// Unregister stops watching a core loop, it is called when the loop is stopped
+func Unregister(name string) {
+	state.Lock()
+	defer state.Unlock()
+	if !state.enabled {
+		return
+	}
+	delete(state.loops, name)
+}
+
+// Heartbeat records that a core loop is alive
+func Heartbeat(name string) {
+	state.Lock()
+	defer state.Unlock()
+	if !state.enabled {
+		return
+	}
+	if l, ok := state.loops[name]; ok {
+		l.lastHeartbeat = timeNow()
+	}
+}
+
+// WatchWorker starts watching a worker process of the given kind, kill is called when the process is still running
+// past the worker timeout of its kind. The returned function stops watching the process, it is called when it exited.
+func WatchWorker(kind string, pid int, kill func() error) (stop func()) {
+	state.Lock()
+	defer state.Unlock()
+	timeout := state.workerTimeouts[kind]
+	if !state.enabled || timeout <= 0 {
+		return func() {}
+	}
+	state.nextWorkerID++
+	id := state.nextWorkerID
+	state.workers[id] = &worker{
+		kind:     kind,
+		pid:      pid,
+		deadline: timeNow().Add(timeout),
+		kill:     kill,
+	}
+	return func() {
+		state.Lock()
+		defer state.Unlock()
+		if state.enabled {
+			delete(state.workers, id)
+		}
+	}
+}
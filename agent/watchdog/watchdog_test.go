@@ -0,0 +1,144 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package watchdog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithWatchdog(enabled bool) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.Watchdog.Enabled = enabled
+	config.Watchdog.SessionWorkerTimeoutHours = 0
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+// stubTime sets the current time of the watchdog and returns a function advancing it
+func stubTime() func(time.Duration) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestNewWatchdog_Disabled(t *testing.T) {
+	assert.Nil(t, NewWatchdog(mockContextWithWatchdog(false)))
+
+	killed := false
+	stop := WatchWorker(DocumentWorker, 1, func() error { killed = true; return nil })
+	stop()
+	Register("loop", func() {})
+	Heartbeat("loop")
+	check(log.NewMockLog())
+	assert.False(t, killed)
+}
+
+func TestWatchdog_RestartsStalledLoop(t *testing.T) {
+	advance := stubTime()
+	ctx := mockContextWithWatchdog(true)
+	w := NewWatchdog(ctx)
+	assert.NotNil(t, w)
+	assert.Equal(t, name, w.ModuleName())
+	defer disable()
+
+	restarted := make(chan bool, 1)
+	Register("TestLoop", func() { restarted <- true })
+	before := metrics.WatchdogRecoveries.Value("TestLoop")
+
+	advance(29 * time.Minute)
+	Heartbeat("TestLoop")
+	advance(29 * time.Minute)
+	check(log.NewMockLog())
+	assert.Empty(t, restarted)
+
+	advance(2 * time.Minute)
+	check(log.NewMockLog())
+	select {
+	case <-restarted:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the stalled loop was not restarted")
+	}
+	assert.Equal(t, before+1, metrics.WatchdogRecoveries.Value("TestLoop"))
+
+	// the loop is not restarted again before another heartbeat timeout
+	check(log.NewMockLog())
+	assert.Empty(t, restarted)
+
+	Unregister("TestLoop")
+	advance(time.Hour)
+	check(log.NewMockLog())
+	assert.Empty(t, restarted)
+}
+
+func TestWatchdog_KillsStuckWorker(t *testing.T) {
+	advance := stubTime()
+	w := NewWatchdog(mockContextWithWatchdog(true))
+	assert.NotNil(t, w)
+	defer disable()
+
+	var killed []int
+	kill := func(pid int) func() error {
+		return func() error {
+			killed = append(killed, pid)
+			return errors.New("process already exited")
+		}
+	}
+	WatchWorker(DocumentWorker, 100, kill(100))
+	stop := WatchWorker(DocumentWorker, 200, kill(200))
+	// the session workers are not watched without a session worker timeout
+	WatchWorker(SessionWorker, 300, kill(300))
+	before := metrics.WatchdogRecoveries.Value(DocumentWorker)
+
+	advance(47 * time.Hour)
+	check(log.NewMockLog())
+	assert.Empty(t, killed)
+
+	stop()
+	advance(2 * time.Hour)
+	check(log.NewMockLog())
+	assert.Equal(t, []int{100}, killed)
+	assert.Equal(t, before+1, metrics.WatchdogRecoveries.Value(DocumentWorker))
+
+	// a killed worker is not watched anymore
+	check(log.NewMockLog())
+	assert.Equal(t, []int{100}, killed)
+}
+
+func TestWatchdog_ModuleExecuteAndStop(t *testing.T) {
+	ctx := mockContextWithWatchdog(true)
+	w := NewWatchdog(ctx)
+	w.interval = time.Millisecond
+	assert.NoError(t, w.ModuleExecute(ctx))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, w.ModuleRequestStop(contracts.StopTypeSoftStop))
+
+	killed := false
+	WatchWorker(DocumentWorker, 1, func() error { killed = true; return nil })
+	check(log.NewMockLog())
+	assert.False(t, killed)
+}
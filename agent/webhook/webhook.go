@@ -0,0 +1,251 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package webhook posts the session and command lifecycle events of the audit log as JSON to a webhook,
+// for the ChatOps and ticketing integrations.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/proxyconfig"
+	"github.com/aws/amazon-ssm-agent/agent/ssm"
+)
+
+const (
+	name = "Webhook"
+
+	// queueSize is the number of events buffered for the webhook, events are dropped when it is full
+	queueSize = 256
+)
+
+// Events of the notifications
+const (
+	SessionStart   = "session.start"
+	SessionEnd     = "session.end"
+	CommandStart   = "command.start"
+	CommandEnd     = "command.end"
+	CommandFailure = "command.failure"
+)
+
+// notification is the JSON body posted for an event
+type notification struct {
+	Name       string `json:"event"`
+	InstanceID string `json:"instanceId,omitempty"`
+	audit.Event
+}
+
+// instanceID returns the id of the instance in the notifications, it is stubbed in the tests
+var instanceID = platform.InstanceID
+
+// fetchAuthHeader returns the value of the Authorization header from Parameter Store, it is stubbed in the tests
+var fetchAuthHeader = func(log log.T, parameter string) (string, error) {
+	output, err := ssm.NewService().GetDecryptedParameters(log, []string{parameter})
+	if err != nil {
+		return "", err
+	}
+	if len(output.Parameters) == 0 || output.Parameters[0].Value == nil {
+		return "", fmt.Errorf("parameter %v not found", parameter)
+	}
+	return *output.Parameters[0].Value, nil
+}
+
+// Notifier is the core module posting the session and command lifecycle events to the webhook
+type Notifier struct {
+	context       context.T
+	config        appconfig.WebhookCfg
+	client        *http.Client
+	authHeader    string
+	instanceID    string
+	notifications chan notification
+	stop          chan bool
+	done          chan bool
+}
+
+// NewNotifier creates the notifier, nil when no webhook url is configured in appconfig
+func NewNotifier(context context.T) *Notifier {
+	config := context.AppConfig().Webhook
+	if config.Url == "" {
+		return nil
+	}
+	context = context.With("[" + name + "]")
+	return &Notifier{
+		context: context,
+		config:  config,
+		client: &http.Client{
+			Transport: proxyconfig.Transport(context.Log(), appconfig.ProxyCfg{}),
+			Timeout:   time.Duration(config.TimeoutSeconds) * time.Second,
+		},
+		notifications: make(chan notification, queueSize),
+		stop:          make(chan bool),
+		done:          make(chan bool),
+	}
+}
+
+// ModuleName returns the name of the module
+func (n *Notifier) ModuleName() string {
+	return name
+}
+
+// ModuleExecute observes the audit events and starts posting the lifecycle events
+func (n *Notifier) ModuleExecute(context context.T) (err error) {
+	n.context.Log().Infof("Posting the session and command lifecycle events to %v", n.config.Url)
+	audit.SetObserver(name, func(event audit.Event) {
+		eventName := eventName(event)
+		if eventName == "" {
+			return
+		}
+		select {
+		case n.notifications <- notification{Name: eventName, Event: event}:
+		default:
+		}
+	})
+	go n.run()
+	return nil
+}
+
+// ModuleRequestStop stops observing the events and posts the buffered events without retrying
+func (n *Notifier) ModuleRequestStop(stopType contracts.StopType) (err error) {
+	audit.SetObserver(name, nil)
+	close(n.stop)
+	<-n.done
+	return nil
+}
+
+// eventName returns the event of the notification of an audit event, empty for the events which are not posted
+func eventName(event audit.Event) string {
+	switch event.Type {
+	case audit.SessionStarted:
+		return SessionStart
+	case audit.SessionEnded:
+		return SessionEnd
+	case audit.DocumentExecutionRequested:
+		return CommandStart
+	case audit.DocumentExecutionCanceled:
+		return CommandEnd
+	case audit.DocumentExecutionCompleted:
+		if event.Status == string(contracts.ResultStatusFailed) || event.Status == string(contracts.ResultStatusTimedOut) {
+			return CommandFailure
+		}
+		return CommandEnd
+	}
+	return ""
+}
+
+// run posts the events as they are recorded
+func (n *Notifier) run() {
+	defer close(n.done)
+	for {
+		select {
+		case notification := <-n.notifications:
+			n.post(notification)
+		case <-n.stop:
+			for len(n.notifications) > 0 {
+				n.post(<-n.notifications)
+			}
+			return
+		}
+	}
+}
+
+// post posts a notification, retrying the failed posts with an exponential backoff until the agent stops
+func (n *Notifier) post(notification notification) {
+	log := n.context.Log()
+	if n.instanceID == "" {
+		n.instanceID, _ = instanceID()
+	}
+	notification.InstanceID = n.instanceID
+	body, err := json.Marshal(notification)
+	if err != nil {
+		log.Warnf("Failed to encode the %v webhook notification: %v", notification.Name, err)
+		return
+	}
+	for attempt := 0; ; attempt++ {
+		retryable, err := n.send(body)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt >= n.config.Retry.MaxRetries {
+			log.Warnf("Failed to post the %v webhook notification, dropping it: %v", notification.Name, err)
+			return
+		}
+		log.Debugf("Failed to post the %v webhook notification, retrying: %v", notification.Name, err)
+		select {
+		case <-time.After(retryDelay(n.config.Retry, attempt)):
+		case <-n.stop:
+			log.Warnf("Failed to post the %v webhook notification before stopping: %v", notification.Name, err)
+			return
+		}
+	}
+}
+
+// send posts the body once, it returns whether a failed post is worth retrying
+func (n *Notifier) send(body []byte) (retryable bool, err error) {
+	request, err := http.NewRequest(http.MethodPost, n.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if n.config.AuthHeaderParameter != "" {
+		if n.authHeader == "" {
+			if n.authHeader, err = fetchAuthHeader(n.context.Log(), n.config.AuthHeaderParameter); err != nil {
+				return true, fmt.Errorf("failed to get the auth header: %v", err)
+			}
+		}
+		request.Header.Set("Authorization", n.authHeader)
+	}
+	response, err := n.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return false, nil
+	case response.StatusCode == http.StatusUnauthorized, response.StatusCode == http.StatusForbidden:
+		// the parameter may have been rotated, it is read again on the next post
+		n.authHeader = ""
+		return true, fmt.Errorf("webhook returned %v", response.Status)
+	case response.StatusCode == http.StatusRequestTimeout, response.StatusCode == http.StatusTooManyRequests,
+		response.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %v", response.Status)
+	}
+	return false, fmt.Errorf("webhook returned %v", response.Status)
+}
+
+// retryDelay returns the delay before the retry following an attempt, doubling from the base delay up to the max delay
+func retryDelay(policy appconfig.RetryPolicyCfg, attempt int) time.Duration {
+	delay := time.Duration(policy.BaseDelayMillis) * time.Millisecond
+	maxDelay := time.Duration(policy.MaxDelayMillis) * time.Millisecond
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
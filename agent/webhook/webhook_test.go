@@ -0,0 +1,162 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/audit"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockContextWithWebhook(url string, authHeaderParameter string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.DefaultConfig()
+	config.Webhook.Url = url
+	config.Webhook.AuthHeaderParameter = authHeaderParameter
+	config.Webhook.Retry.BaseDelayMillis = 10
+	config.Webhook.Retry.MaxDelayMillis = 10
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func stubInstanceID() func() {
+	original := instanceID
+	instanceID = func() (string, error) { return "i-1234567890abcdef0", nil }
+	return func() { instanceID = original }
+}
+
+// webhookServer records the posted notifications, answering the first failures with the status
+type webhookServer struct {
+	sync.Mutex
+	failures      int
+	status        int
+	notifications []map[string]interface{}
+	authHeaders   []string
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.authHeaders = append(s.authHeaders, r.Header.Get("Authorization"))
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(s.status)
+		return
+	}
+	var notification map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&notification)
+	s.notifications = append(s.notifications, notification)
+}
+
+func TestNewNotifier_Disabled(t *testing.T) {
+	assert.Nil(t, NewNotifier(mockContextWithWebhook("", "")))
+}
+
+func TestEventName(t *testing.T) {
+	assert.Equal(t, SessionStart, eventName(audit.Event{Type: audit.SessionStarted}))
+	assert.Equal(t, SessionEnd, eventName(audit.Event{Type: audit.SessionEnded}))
+	assert.Equal(t, CommandStart, eventName(audit.Event{Type: audit.DocumentExecutionRequested}))
+	assert.Equal(t, CommandEnd, eventName(audit.Event{Type: audit.DocumentExecutionCanceled}))
+	assert.Equal(t, CommandEnd, eventName(audit.Event{Type: audit.DocumentExecutionCompleted, Status: string(contracts.ResultStatusSuccess)}))
+	assert.Equal(t, CommandFailure, eventName(audit.Event{Type: audit.DocumentExecutionCompleted, Status: string(contracts.ResultStatusFailed)}))
+	assert.Equal(t, CommandFailure, eventName(audit.Event{Type: audit.DocumentExecutionCompleted, Status: string(contracts.ResultStatusTimedOut)}))
+	assert.Empty(t, eventName(audit.Event{Type: audit.AgentUpdate}))
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := appconfig.RetryPolicyCfg{MaxRetries: 5, BaseDelayMillis: 1000, MaxDelayMillis: 5000}
+	assert.Equal(t, time.Second, retryDelay(policy, 0))
+	assert.Equal(t, 2*time.Second, retryDelay(policy, 1))
+	assert.Equal(t, 4*time.Second, retryDelay(policy, 2))
+	assert.Equal(t, 5*time.Second, retryDelay(policy, 3))
+	assert.Equal(t, 5*time.Second, retryDelay(policy, 10))
+}
+
+func TestNotifier_PostsLifecycleEvents(t *testing.T) {
+	defer stubInstanceID()()
+	server := &webhookServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	ctx := mockContextWithWebhook(httpServer.URL, "")
+	notifier := NewNotifier(ctx)
+	assert.NotNil(t, notifier)
+	assert.Equal(t, name, notifier.ModuleName())
+	assert.NoError(t, notifier.ModuleExecute(ctx))
+
+	audit.Record(audit.Event{Type: audit.DocumentExecutionRequested, CommandID: "command-id", DocumentName: "AWS-RunShellScript"})
+	audit.Record(audit.Event{Type: audit.AgentUpdate, Status: "Success"})
+	audit.Record(audit.Event{Type: audit.DocumentExecutionCompleted, CommandID: "command-id", Status: string(contracts.ResultStatusFailed)})
+	assert.NoError(t, notifier.ModuleRequestStop(contracts.StopTypeSoftStop))
+
+	assert.Len(t, server.notifications, 2)
+	assert.Equal(t, CommandStart, server.notifications[0]["event"])
+	assert.Equal(t, "i-1234567890abcdef0", server.notifications[0]["instanceId"])
+	assert.Equal(t, "command-id", server.notifications[0]["commandId"])
+	assert.Equal(t, "AWS-RunShellScript", server.notifications[0]["documentName"])
+	assert.Equal(t, CommandFailure, server.notifications[1]["event"])
+	assert.Equal(t, string(contracts.ResultStatusFailed), server.notifications[1]["status"])
+	assert.Empty(t, server.authHeaders[0])
+}
+
+func TestNotifier_RetriesAndRefreshesAuthHeader(t *testing.T) {
+	defer stubInstanceID()()
+	fetches := 0
+	original := fetchAuthHeader
+	fetchAuthHeader = func(log log.T, parameter string) (string, error) {
+		assert.Equal(t, "/webhook/auth", parameter)
+		fetches++
+		return "Bearer token", nil
+	}
+	defer func() { fetchAuthHeader = original }()
+
+	server := &webhookServer{failures: 2, status: http.StatusUnauthorized}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	notifier := NewNotifier(mockContextWithWebhook(httpServer.URL, "/webhook/auth"))
+	notifier.post(notification{Name: SessionStart, Event: audit.Event{Type: audit.SessionStarted, SessionID: "session-id"}})
+
+	assert.Len(t, server.notifications, 1)
+	assert.Equal(t, "session-id", server.notifications[0]["sessionId"])
+	assert.Equal(t, []string{"Bearer token", "Bearer token", "Bearer token"}, server.authHeaders)
+	assert.Equal(t, 3, fetches)
+}
+
+func TestNotifier_DoesNotRetryClientErrors(t *testing.T) {
+	defer stubInstanceID()()
+	server := &webhookServer{failures: 1, status: http.StatusBadRequest}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	notifier := NewNotifier(mockContextWithWebhook(httpServer.URL, ""))
+	notifier.post(notification{Name: SessionEnd, Event: audit.Event{Type: audit.SessionEnded}})
+
+	assert.Empty(t, server.notifications)
+	assert.Len(t, server.authHeaders, 1)
+}
@@ -59,14 +59,29 @@ cp packaging/linux/amazon-ssm-agent.service %{buildroot}%{_unitdir}/
 cp packaging/linux/amazon-ssm-agent.conf %{buildroot}%{_sysconfdir}/init/
 %endif
 cp amazon-ssm-agent.json.template %{buildroot}%{_sysconfdir}/amazon/ssm/amazon-ssm-agent.json.template
+cp amazon-ssm-agent.schema.json %{buildroot}%{_sysconfdir}/amazon/ssm/amazon-ssm-agent.schema.json
 cp seelog_unix.xml %{buildroot}%{_sysconfdir}/amazon/ssm/seelog.xml.template
 
 strip --strip-unneeded %{buildroot}%{_prefix}/bin/{amazon-ssm-agent,ssm-document-worker,ssm-session-worker,ssm-session-logger,ssm-cli}
 
+# the binary manifest of the stripped binaries, signed when the build defines integrity_signing_key
+export GOPATH=`pwd`/vendor:`pwd`
+go run agent/integrity/manifestgen/manifest-gen.go %{version} %{buildroot}%{_prefix}/bin %{?integrity_signing_key}
+mv %{buildroot}%{_prefix}/bin/amazon-ssm-agent.manifest %{buildroot}%{_sysconfdir}/amazon/ssm/
+%if 0%{?integrity_signing_key:1}
+mv %{buildroot}%{_prefix}/bin/{amazon-ssm-agent.manifest.sig,amazon-ssm-agent-release.pem} %{buildroot}%{_sysconfdir}/amazon/ssm/
+%endif
+
 %files
 %defattr(-,root,root,-)
 %{_sysconfdir}/amazon/ssm/amazon-ssm-agent.json.template
+%{_sysconfdir}/amazon/ssm/amazon-ssm-agent.schema.json
 %{_sysconfdir}/amazon/ssm/seelog.xml.template
+%{_sysconfdir}/amazon/ssm/amazon-ssm-agent.manifest
+%if 0%{?integrity_signing_key:1}
+%{_sysconfdir}/amazon/ssm/amazon-ssm-agent.manifest.sig
+%{_sysconfdir}/amazon/ssm/amazon-ssm-agent-release.pem
+%endif
 %{_sysconfdir}/amazon/ssm/README.md
 %{_sysconfdir}/amazon/ssm/RELEASENOTES.md
 %if 0%{?amzn} >= 2
@@ -16,18 +16,27 @@ This package provides Amazon SSM Agent for managing EC2 Instances using SSM APIs
 %files
 %defattr(-,root,root,-)
 /etc/amazon/ssm/amazon-ssm-agent.json.template
+/etc/amazon/ssm/amazon-ssm-agent.schema.json
 /etc/amazon/ssm/seelog.xml.template
+/etc/amazon/ssm/amazon-ssm-agent.manifest
+%if 0%{?signed}
+/etc/amazon/ssm/amazon-ssm-agent.manifest.sig
+/etc/amazon/ssm/amazon-ssm-agent-release.pem
+%endif
 /usr/bin/amazon-ssm-agent
 /usr/bin/ssm-cli
 /usr/bin/ssm-document-worker
 /usr/bin/ssm-session-worker
 /usr/bin/ssm-session-logger
+%attr(4750,root,ssm-agent) /usr/bin/ssm-agent-helper
 /var/lib/amazon/ssm/
+/usr/share/selinux/packages/amazon_ssm_agent.pp
 %doc /etc/amazon/ssm/RELEASENOTES.md
 %doc /etc/amazon/ssm/README.md
 
 %config(noreplace) /etc/init/amazon-ssm-agent.conf
 %config(noreplace) /etc/systemd/system/amazon-ssm-agent.service
+%config(noreplace) /etc/systemd/system/amazon-ssm-agent.socket
 
 # The scriptlets in %pre and %post are run before and after a package is installed.
 # The scriptlets %preun and %postun are run before and after a package is uninstalled.
@@ -40,6 +49,10 @@ This package provides Amazon SSM Agent for managing EC2 Instances using SSM APIs
 # Upgrade:       %pre, %posttrans
 
 %pre
+# Create the unprivileged user of the privilege separation, the agent only runs as it when it is enabled
+getent group ssm-agent &> /dev/null || groupadd -r ssm-agent
+getent passwd ssm-agent &> /dev/null || useradd -r -g ssm-agent -d /var/lib/amazon/ssm -s /sbin/nologin ssm-agent
+
 # Stop the agent before the upgrade
 if [ $1 -ge 2 ]; then
     /sbin/init --version &> stdout.txt
@@ -62,11 +75,25 @@ if [ $1 -eq 0 ] ; then
     elif [[ `systemctl` =~ -\.mount ]]; then
         systemctl stop amazon-ssm-agent
         systemctl disable amazon-ssm-agent
+        rm -f /etc/systemd/system/amazon-ssm-agent.service.d/recovery.conf
         systemctl daemon-reload
     fi
     rm stdout.txt
 fi
 
+%post
+# Load the SELinux policy module of the agent and label its files
+if /usr/sbin/selinuxenabled &> /dev/null; then
+    /usr/sbin/semodule -i /usr/share/selinux/packages/amazon_ssm_agent.pp &> /dev/null || :
+    /sbin/restorecon -R /usr/bin/amazon-ssm-agent /usr/bin/ssm-document-worker /usr/bin/ssm-session-worker /usr/bin/ssm-session-logger /etc/amazon/ssm /var/lib/amazon/ssm /var/log/amazon/ssm &> /dev/null || :
+fi
+
+%postun
+# Unload the SELinux policy module of the agent after uninstall
+if [ $1 -eq 0 ] && /usr/sbin/selinuxenabled &> /dev/null; then
+    /usr/sbin/semodule -r amazon_ssm_agent &> /dev/null || :
+fi
+
 %posttrans
 # Start the agent after initial install or upgrade
 if [ $1 -ge 0 ]; then
@@ -75,6 +102,7 @@ if [ $1 -ge 0 ]; then
         /sbin/start amazon-ssm-agent
     elif [[ `systemctl` =~ -\.mount ]]; then
         systemctl enable amazon-ssm-agent
+        /usr/bin/amazon-ssm-agent -configure-recovery || true
         systemctl start amazon-ssm-agent
         systemctl daemon-reload
     fi